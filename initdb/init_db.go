@@ -1,21 +1,182 @@
 package initdb
 
 import (
+	"crypto/rand"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/juju/errors"
+	log "github.com/sirupsen/logrus"
 	"github.com/zhukovaskychina/xmysql-server/server/conf"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/innodb_store/store"
-	"os"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/privilege/privileges"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/auth"
+	"github.com/zhukovaskychina/xmysql-server/util"
 )
 
-func InitDBDir(cfg *conf.Cfg) {
-	InitSysSpace(cfg)
+/**
+初始化数据目录
+
+--initialize 在一个不存在或者为空的数据目录中创建：目录结构、系统表空间 ibdata1、
+redo 和 undo 目录、mysql 库和它的权限表 mysql.user、mysql.db，为 root@localhost 生成
+随机密码并打印到日志（--initialize-insecure 时密码为空），root@localhost 有全部全局权限，
+写入 mysql.user，最后写入完成标记文件，然后退出。
+
+完成标记最后写入，中途崩溃的目录没有标记：正常启动时拒绝使用没有标记的目录并提示
+--initialize，初始化时拒绝已经有数据的目录，需要先清空目录再初始化。
+**/
+
+const (
+	// MarkerFile is written in the data directory when it is initialized.
+	MarkerFile = "xmysql.initialized"
+	// RedoDir is the directory of the redo logs in the data directory.
+	RedoDir = "#innodb_redo"
+	// UndoDir is the directory of the undo tablespaces in the data directory.
+	UndoDir = "undo"
+	// SystemDB is the database of the privilege tables.
+	SystemDB = "mysql"
+)
+
+// rootPasswordLength is the length of the random password of root.
+const rootPasswordLength = 16
+
+const passwordChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789#%&()*+,-.:;<=>?@[]^_{}~"
+
+// IsInitialized reports whether the data directory of cfg was initialized
+// to the end.
+func IsInitialized(cfg *conf.Cfg) bool {
+	ok, _ := util.PathExists(filepath.Join(cfg.DataDir, MarkerFile))
+	return ok
+}
+
+// CheckInitialized returns an error suggesting --initialize when the data
+// directory of cfg wasn't initialized to the end, and the error of
+// store.CheckSysTableSpace when its ibdata1 is damaged.
+func CheckInitialized(cfg *conf.Cfg) error {
+	if IsInitialized(cfg) {
+		return store.CheckSysTableSpace(cfg)
+	}
+	if empty, err := isEmptyDir(cfg.DataDir); err == nil && !empty {
+		return errors.Errorf("数据目录 %s 没有初始化完成，请清空该目录后使用 --initialize 重新初始化", cfg.DataDir)
+	}
+	return errors.Errorf("数据目录 %s 没有初始化，请先使用 --initialize 初始化", cfg.DataDir)
+}
+
+// Initialize creates the data directory of cfg and returns the password of
+// root@localhost, random unless insecure, where it is empty. It refuses a
+// data directory which already contains data.
+func Initialize(cfg *conf.Cfg, insecure bool) (string, error) {
+	if cfg.DataDir == "" {
+		return "", errors.New("没有配置数据目录 datadir")
+	}
+	if IsInitialized(cfg) {
+		return "", errors.Errorf("数据目录 %s 已经初始化", cfg.DataDir)
+	}
+	for _, dir := range []string{cfg.DataDir, cfg.BaseDir} {
+		if dir == "" {
+			continue
+		}
+		empty, err := isEmptyDir(dir)
+		if err != nil {
+			return "", errors.Trace(err)
+		}
+		if !empty && (dir == cfg.DataDir || fileExists(filepath.Join(dir, "ibdata1"))) {
+			return "", errors.Errorf("目录 %s 已经有数据，不能初始化", dir)
+		}
+	}
+	for _, dir := range []string{cfg.BaseDir, cfg.DataDir, filepath.Join(cfg.DataDir, RedoDir), filepath.Join(cfg.DataDir, UndoDir)} {
+		if dir == "" {
+			continue
+		}
+		if err := os.MkdirAll(dir, 0750); err != nil {
+			return "", errors.Trace(err)
+		}
+	}
+	password := ""
+	if !insecure {
+		var err error
+		if password, err = randomPassword(rootPasswordLength); err != nil {
+			return "", errors.Trace(err)
+		}
+	}
+	store.NewSysTableSpace(cfg, true)
+	if err := createSystemDB(cfg, password); err != nil {
+		return "", errors.Trace(err)
+	}
+	if !insecure {
+		log.Warnf("为 root@localhost 生成了临时密码: %s", password)
+	} else {
+		log.Warnf("root@localhost 的密码为空，请尽快修改密码")
+	}
+	marker := fmt.Sprintf("initialized at %s\n", time.Now().UTC().Format(time.RFC3339))
+	if err := ioutil.WriteFile(filepath.Join(cfg.DataDir, MarkerFile), []byte(marker), 0640); err != nil {
+		return "", errors.Trace(err)
+	}
+	return password, nil
 }
 
-func InitSysSpace(conf *conf.Cfg) {
+// createSystemDB creates the mysql database and its privilege tables, where
+// root@localhost, identified by password, has every global privilege.
+func createSystemDB(cfg *conf.Cfg, password string) error {
+	dir := filepath.Join(cfg.DataDir, SystemDB)
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return errors.Trace(err)
+	}
+	opt := "default-character-set=utf8mb4\ndefault-collation=utf8mb4_general_ci\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "db.opt"), []byte(opt), 0640); err != nil {
+		return errors.Trace(err)
+	}
+	root := privileges.NewRootPrivilege(auth.EncodePassword(password))
+	for _, t := range privileges.Tables {
+		info, err := t.TableInfo()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		tbl, err := store.CreateOrdinaryTable(cfg, nil, SystemDB, info, t.SpaceID)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		for _, row := range root.Rows(info) {
+			if err = tbl.AddRow(row); err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+	return nil
+}
 
-	os.Remove(conf.BaseDir + "/" + "ibdata1")
+// randomPassword returns a password of n characters drawn from
+// passwordChars.
+func randomPassword(n int) (string, error) {
+	b := make([]byte, n)
+	max := big.NewInt(int64(len(passwordChars)))
+	for i := range b {
+		r, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", errors.Trace(err)
+		}
+		b[i] = passwordChars[r.Int64()]
+	}
+	return string(b), nil
+}
 
-	store.NewSysTableSpace(conf, true)
+// isEmptyDir reports whether dir doesn't exist or has no entries.
+func isEmptyDir(dir string) (bool, error) {
+	fs, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	return len(fs) == 0, nil
+}
 
-	//storebytes.NewTableSpaceFile(conf,"mysql","innodb_index_stats",13,true)
-	//storebytes.NewTableSpaceFile(conf,"mysql","innodb_table_stats",14,true)
+func fileExists(path string) bool {
+	ok, _ := util.PathExists(path)
+	return ok
 }
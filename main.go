@@ -27,6 +27,7 @@ const help = `
 *1. -- help																				 
 *2. -- configPath   指定my.ini配置文件													 
 *3. -- initialize   初始化数据库															 
+*4. -- initialize-insecure   初始化数据库，root 密码为空											 
 ******************************************************************************************
 `
 
@@ -34,6 +35,7 @@ func main() {
 	var (
 		configPath = flag.String("configPath", "", "指定配置文件配置路径")
 		initialize = flag.Bool("initialize", false, "初始化數據庫")
+		insecure   = flag.Bool("initialize-insecure", false, "初始化數據庫，root 密碼為空")
 	)
 	flag.Usage = func() {
 		fmt.Print(help)
@@ -50,8 +52,16 @@ func main() {
 	var cfg *conf.Cfg
 	cfg = conf.NewCfg()
 	cfg.Load(&conf.CommandLineArgs{ConfigPath: *configPath})
-	if *initialize == true {
-		initdb.InitDBDir(cfg)
+	if *initialize || *insecure {
+		if _, err := initdb.Initialize(cfg, *insecure); err != nil {
+			fmt.Println("初始化数据库异常", err)
+			os.Exit(1)
+		}
+		fmt.Println("初始化数据库完成")
+		os.Exit(0)
+	}
+	if err := initdb.CheckInitialized(cfg); err != nil {
+		fmt.Println(err)
 		os.Exit(1)
 	}
 
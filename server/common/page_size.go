@@ -0,0 +1,127 @@
+package common
+
+import "fmt"
+
+/**
+页面大小 innodb_page_size
+
+页面大小在启动时由 innodb_page_size 设置，之后不再改变，所有表空间的页面大小都相同。
+支持 4K、8K、16K（默认）、32K 和 64K，其他的值启动时报错。
+
+和 InnoDB 一样，页面大小决定了表空间的几何结构：
+	区（extent）的页面数：16K 及以下的页面，一个区为 1MB；32K 和 64K 的页面，一个区为 64 个页面（2MB、4MB）
+	XDES entry：段号 8 字节、链表节点 12 字节、状态 4 字节，再加上区中每个页面 2 个 bit 的位图
+	一个 XDES 页面（第一个为 FSP_HDR 页面）描述 页面大小/区的页面数 个区，即 页面大小 个页面，
+		所以第 n 个 XDES 页面的页号是 n*页面大小
+	INODE entry：64 字节的头，再加上 区的页面数/2 个碎片页的页号
+	一个 INODE 页面存放 (页面大小-50-10)/INODE entry 大小 个 INODE entry
+**/
+
+// Supported values of innodb_page_size.
+const (
+	MinPageSize = 4096
+	MaxPageSize = 65536
+)
+
+// Sizes of the parts of the XDES and INODE entries that don't depend on the
+// page size.
+const (
+	// XDesBitmapOffset is the offset of the page bitmap in an XDES entry,
+	// after the segment id, the list node and the state.
+	XDesBitmapOffset = 24
+	// INodeFragArrOffset is the offset of the fragment page array in an
+	// INODE entry, FSEG_FRAG_ARR.
+	INodeFragArrOffset = 64
+	// INodeArrOffset is the offset of the first INODE entry in an INODE
+	// page, after the file header and the list node.
+	INodeArrOffset = 50
+)
+
+var pageSize = PAGE_SIZE
+
+// ValidatePageSize returns an error unless size is a power of two from
+// MinPageSize to MaxPageSize.
+func ValidatePageSize(size int) error {
+	if size < MinPageSize || size > MaxPageSize || size&(size-1) != 0 {
+		return fmt.Errorf("innodb_page_size %d is not 4K, 8K, 16K, 32K or 64K", size)
+	}
+	return nil
+}
+
+// SetPageSize sets the size of the pages of all the tablespaces. It is set
+// once at startup, before any page is read or created.
+func SetPageSize(size int) error {
+	if err := ValidatePageSize(size); err != nil {
+		return err
+	}
+	pageSize = size
+	return nil
+}
+
+// PageSize returns the size in bytes of the pages, innodb_page_size.
+func PageSize() int {
+	return pageSize
+}
+
+// ExtentPages returns the number of pages of an extent, FSP_EXTENT_SIZE.
+func ExtentPages() int {
+	if pageSize <= PAGE_SIZE {
+		return 1 << 20 / pageSize
+	}
+	return 64
+}
+
+// XDesBitmapSize returns the size of the page bitmap of an XDES entry, two
+// bits a page.
+func XDesBitmapSize() int {
+	return ExtentPages() * 2 / 8
+}
+
+// XDesEntrySize returns the size of an XDES entry, XDES_SIZE.
+func XDesEntrySize() int {
+	return XDesBitmapOffset + XDesBitmapSize()
+}
+
+// ExtentsPerXDesPage returns the number of extents an XDES page describes.
+func ExtentsPerXDesPage() int {
+	return pageSize / ExtentPages()
+}
+
+// INodeFragSlots returns the number of fragment pages of a segment,
+// FSEG_FRAG_ARR_N_SLOTS.
+func INodeFragSlots() int {
+	return ExtentPages() / 2
+}
+
+// INodeEntrySize returns the size of an INODE entry, FSEG_INODE_SIZE.
+func INodeEntrySize() int {
+	return INodeFragArrOffset + INodeFragSlots()*4
+}
+
+// INodesPerPage returns the number of INODE entries of an INODE page,
+// FSP_SEG_INODES_PER_PAGE.
+func INodesPerPage() int {
+	return (pageSize - INodeArrOffset - 10) / INodeEntrySize()
+}
+
+// PageSSize returns the page size as the PAGE_SSIZE of FSP_SPACE_FLAGS:
+// 0 for 16K, otherwise log2 of the size less 9.
+func PageSSize(size int) byte {
+	if size == PAGE_SIZE {
+		return 0
+	}
+	var ssize byte
+	for size > 1<<9 {
+		size >>= 1
+		ssize++
+	}
+	return ssize
+}
+
+// PageSizeOfSSize returns the page size the PAGE_SSIZE ssize stands for.
+func PageSizeOfSSize(ssize byte) int {
+	if ssize == 0 {
+		return PAGE_SIZE
+	}
+	return 1 << (9 + uint(ssize))
+}
@@ -0,0 +1,62 @@
+package common
+
+import "testing"
+
+func TestValidatePageSize(t *testing.T) {
+	for _, size := range []int{4096, 8192, 16384, 32768, 65536} {
+		if err := ValidatePageSize(size); err != nil {
+			t.Errorf("%d: %v", size, err)
+		}
+	}
+	for _, size := range []int{0, 1000, 2048, 12288, 131072} {
+		if ValidatePageSize(size) == nil {
+			t.Errorf("%d: expect an error", size)
+		}
+	}
+	if SetPageSize(12288) == nil || PageSize() != PAGE_SIZE {
+		t.Fatalf("an invalid page size changed it to %d", PageSize())
+	}
+}
+
+func TestPageGeometry(t *testing.T) {
+	defer SetPageSize(PAGE_SIZE)
+	for _, size := range []int{4096, 8192, 16384, 32768, 65536} {
+		if err := SetPageSize(size); err != nil {
+			t.Fatal(err)
+		}
+		// The XDES entries of the FSP_HDR page start at 150, the INODE
+		// entries at 50, both followed by the 8 bytes of the trailer.
+		if n := 150 + ExtentsPerXDesPage()*XDesEntrySize() + 8; n > size {
+			t.Errorf("%d: XDES entries take %d bytes", size, n)
+		}
+		if n := INodeArrOffset + INodesPerPage()*INodeEntrySize() + 8; n > size {
+			t.Errorf("%d: INODE entries take %d bytes", size, n)
+		}
+		if ExtentsPerXDesPage()*ExtentPages() != size {
+			t.Errorf("%d: an XDES page describes %d pages", size, ExtentsPerXDesPage()*ExtentPages())
+		}
+		if XDesBitmapSize()*4 != ExtentPages() {
+			t.Errorf("%d: bitmap of %d bytes for %d pages", size, XDesBitmapSize(), ExtentPages())
+		}
+		if PageSizeOfSSize(PageSSize(size)) != size {
+			t.Errorf("%d: PAGE_SSIZE %d", size, PageSSize(size))
+		}
+	}
+
+	SetPageSize(PAGE_SIZE)
+	got := []int{ExtentPages(), XDesEntrySize(), ExtentsPerXDesPage(), INodesPerPage(), INodeFragSlots(), int(PageSSize(PAGE_SIZE))}
+	want := []int{64, 40, 256, 85, 32, 0}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("16K geometry: expect %v, got %v", want, got)
+		}
+	}
+	SetPageSize(8192)
+	if ExtentPages() != 128 || PageSSize(8192) != 4 {
+		t.Fatalf("8K: %d pages an extent, PAGE_SSIZE %d", ExtentPages(), PageSSize(8192))
+	}
+	SetPageSize(65536)
+	if ExtentPages() != 64 || PageSSize(65536) != 7 {
+		t.Fatalf("64K: %d pages an extent, PAGE_SSIZE %d", ExtentPages(), PageSSize(65536))
+	}
+}
@@ -3,11 +3,15 @@ package conf
 import (
 	"errors"
 	"fmt"
+	"github.com/zhukovaskychina/xmysql-server/server/common"
 	"gopkg.in/ini.v1"
 	"net"
 	"os"
 	"path"
 	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -28,13 +32,93 @@ tmpdir		= /tmp
 lc-messages-dir	= /usr/share/mysql
 */
 type Cfg struct {
-	Raw         *ini.File
+	Raw *ini.File
+	// ConfigFile is the file Raw was loaded from.
+	ConfigFile  string
 	User        string
 	BindAddress string
 	Port        int
 	BaseDir     string
 	DataDir     string
 	AppName     string
+	// SecureFilePriv limits SELECT ... INTO OUTFILE and LOAD DATA to the
+	// files under this directory. "NULL" disables both, "" means no limit.
+	SecureFilePriv string
+	// MaxAllowedPacket is the largest packet accepted from a client.
+	MaxAllowedPacket int
+	// DefaultStorageEngine is the storage engine of the tables created
+	// without ENGINE. InnoDB is the only one.
+	DefaultStorageEngine string
+	// InnodbDefaultRowFormat is the row format of the tables created
+	// without ROW_FORMAT: REDUNDANT, COMPACT or DYNAMIC.
+	InnodbDefaultRowFormat string
+	// InnodbAdaptiveHashIndex turns on the adaptive hash index of the
+	// buffer pool, innodb_adaptive_hash_index.
+	InnodbAdaptiveHashIndex bool
+	// InnodbChangeBuffering is what the change buffer buffers: none,
+	// inserts, deletes, changes, purges or all.
+	InnodbChangeBuffering string
+	// InnodbChecksumAlgorithm is how the pages are checksummed: crc32 or
+	// none.
+	InnodbChecksumAlgorithm string
+	// InnodbCorruptPageAction is what reading a page whose checksum
+	// doesn't match does: error or log.
+	InnodbCorruptPageAction string
+	// InnodbBufferPoolDumpAtShutdown writes the pages of the buffer pool to
+	// InnodbBufferPoolFilename at shutdown.
+	InnodbBufferPoolDumpAtShutdown bool
+	// InnodbBufferPoolLoadAtStartup reads the pages dumped back into the
+	// buffer pool at startup, in the background.
+	InnodbBufferPoolLoadAtStartup bool
+	// InnodbBufferPoolFilename is the dump file, relative to DataDir.
+	InnodbBufferPoolFilename string
+	// InnodbPageSize is the size of the pages of all the tablespaces,
+	// 4K, 8K, 16K, 32K or 64K. It can't change once they are created.
+	InnodbPageSize int
+	// LowerCaseTableNames is how the names of databases and tables are
+	// stored and compared, 0, 1 or 2. It can't change once the data
+	// directory is initialized.
+	LowerCaseTableNames int
+	// InnodbBufferPoolSize is the size of the buffer pool in bytes, which
+	// SET GLOBAL innodb_buffer_pool_size changes online.
+	InnodbBufferPoolSize int
+	// InnodbBufferPoolInstances is the number of instances the buffer pool
+	// is split into, each with its own lists and mutex.
+	InnodbBufferPoolInstances int
+	// ReadOnly rejects the statements changing data or schema from the
+	// users without SUPER, SuperReadOnly from every user.
+	ReadOnly      bool
+	SuperReadOnly bool
+	// AuditLog turns on the audit log of the connections and statements,
+	// which SET GLOBAL audit_log switches at runtime.
+	AuditLog bool
+	// AuditLogFile is the JSON lines file of the audit log, relative to
+	// DataDir.
+	AuditLogFile string
+	// AuditLogPolicy is FULL to log the SQL of the statements as sent,
+	// NORMALIZED to log it with its literals replaced by ?.
+	AuditLogPolicy string
+	// AuditLogRotateOnSize is the size in bytes above which the audit log
+	// starts a new file, 0 for never.
+	AuditLogRotateOnSize int
+	// AuditLogBufferSize is the number of events waiting to be written
+	// above which new events are dropped.
+	AuditLogBufferSize int
+	// MetricsPort is the port of the HTTP endpoint serving the metrics of
+	// the server in the Prometheus text format, 0 to turn it off.
+	MetricsPort int
+	// HealthPort is the port of the HTTP endpoint serving the liveness
+	// probe /healthz and the readiness probe /readyz, 0 to turn it off.
+	HealthPort int
+	// MaxServerMemory is the most bytes the statements of all the sessions
+	// may take together, 0 for no limit.
+	MaxServerMemory int64
+	// ThreadHandling is how the connections are served:
+	// one-thread-per-connection gives each its own goroutines,
+	// pool-of-threads parks the idle ones in a poller and handles the
+	// others with ThreadPoolSize workers.
+	ThreadHandling string
+	ThreadPoolSize int
 
 	ProfilePort int
 	// session
@@ -69,15 +153,56 @@ type MySQLSessionParam struct {
 	SessionName             string `default:"echo-server" yaml:"session_name" json:"session_name,omitempty"`
 }
 
+// defaultLowerCaseTableNames is the default of lower_case_table_names, by
+// whether the file system is case-insensitive, as in MySQL.
+func defaultLowerCaseTableNames() int {
+	switch runtime.GOOS {
+	case "windows":
+		return 1
+	case "darwin":
+		return 2
+	}
+	return 0
+}
+
 func NewCfg() *Cfg {
 	return &Cfg{
 		Raw:         ini.Empty(),
 		User:        "mysql",
 		BindAddress: "127.0.0.1",
 		Port:        3308,
+
+		MaxAllowedPacket:        64 << 20,
+		DefaultStorageEngine:    "InnoDB",
+		InnodbDefaultRowFormat:  "DYNAMIC",
+		InnodbAdaptiveHashIndex: true,
+		InnodbChangeBuffering:   "all",
+		InnodbChecksumAlgorithm: "crc32",
+		InnodbCorruptPageAction: "error",
+
+		InnodbBufferPoolDumpAtShutdown: true,
+		InnodbBufferPoolLoadAtStartup:  true,
+		InnodbBufferPoolFilename:       "ib_buffer_pool",
+		InnodbPageSize:                 common.PAGE_SIZE,
+		LowerCaseTableNames:            defaultLowerCaseTableNames(),
+		InnodbBufferPoolSize:           256 * 16384,
+		InnodbBufferPoolInstances:      8,
+
+		AuditLogFile:       "audit.log",
+		AuditLogPolicy:     "FULL",
+		AuditLogBufferSize: 4096,
+
+		ThreadHandling: ThreadHandlingPerConnection,
+		ThreadPoolSize: 16,
 	}
 }
 
+// The values of thread_handling.
+const (
+	ThreadHandlingPerConnection = "one-thread-per-connection"
+	ThreadHandlingPoolOfThreads = "pool-of-threads"
+)
+
 func (cfg *Cfg) Load(args *CommandLineArgs) *Cfg {
 	setHomePath(args)
 	iniFile, err := cfg.loadConfiguration(args)
@@ -86,9 +211,11 @@ func (cfg *Cfg) Load(args *CommandLineArgs) *Cfg {
 		os.Exit(1)
 	}
 	cfg.Raw = iniFile
+	cfg.ConfigFile = args.ConfigPath
 
 	cfg.parseMysqldCfg(cfg.Raw.Section("mysqld"))
 	cfg.parseMysqlSessionCfg(cfg.Raw.Section("session"))
+	cfg.parseBufferCfg(cfg.Raw.Section("buffer"))
 	return cfg
 
 }
@@ -291,6 +418,116 @@ func (cfg *Cfg) parseMysqldCfg(section *ini.Section) *Cfg {
 
 	cfg.BaseDir = baseDirValue.Value()
 	cfg.DataDir = dataDirValue.Value()
+	cfg.SecureFilePriv, err = valueAsString(section, "secure_file_priv", "NULL")
+	if err != nil {
+		fmt.Println("secure_file_priv配置异常", err)
+		os.Exit(1)
+	}
+	cfg.DefaultStorageEngine, err = valueAsStorageEngine(section, "default_storage_engine", "InnoDB")
+	if err != nil {
+		fmt.Println("default_storage_engine配置异常", err)
+		os.Exit(1)
+	}
+	cfg.InnodbDefaultRowFormat, err = valueAsRowFormat(section, "innodb_default_row_format", "DYNAMIC")
+	if err != nil {
+		fmt.Println("innodb_default_row_format配置异常", err)
+		os.Exit(1)
+	}
+	cfg.InnodbAdaptiveHashIndex = section.Key("innodb_adaptive_hash_index").MustBool(true)
+	cfg.InnodbChangeBuffering, err = valueAsChangeBuffering(section, "innodb_change_buffering", "all")
+	if err != nil {
+		fmt.Println("innodb_change_buffering配置异常", err)
+		os.Exit(1)
+	}
+	cfg.InnodbChecksumAlgorithm, err = valueAsOneOf(section, "innodb_checksum_algorithm", "crc32", "crc32", "none")
+	if err != nil {
+		fmt.Println("innodb_checksum_algorithm配置异常", err)
+		os.Exit(1)
+	}
+	cfg.InnodbCorruptPageAction, err = valueAsOneOf(section, "innodb_corrupt_page_action", "error", "error", "log")
+	if err != nil {
+		fmt.Println("innodb_corrupt_page_action配置异常", err)
+		os.Exit(1)
+	}
+	cfg.InnodbBufferPoolDumpAtShutdown = section.Key("innodb_buffer_pool_dump_at_shutdown").MustBool(true)
+	cfg.InnodbBufferPoolLoadAtStartup = section.Key("innodb_buffer_pool_load_at_startup").MustBool(true)
+	cfg.InnodbBufferPoolFilename, err = valueAsString(section, "innodb_buffer_pool_filename", "ib_buffer_pool")
+	if err != nil {
+		fmt.Println("innodb_buffer_pool_filename配置异常", err)
+		os.Exit(1)
+	}
+	cfg.InnodbPageSize, err = valueAsBytes(section, "innodb_page_size", common.PAGE_SIZE)
+	if err == nil {
+		err = common.ValidatePageSize(cfg.InnodbPageSize)
+	}
+	if err != nil {
+		fmt.Println("innodb_page_size配置异常", err)
+		os.Exit(1)
+	}
+	cfg.LowerCaseTableNames = section.Key("lower_case_table_names").MustInt(defaultLowerCaseTableNames())
+	if cfg.LowerCaseTableNames < 0 || cfg.LowerCaseTableNames > 2 {
+		fmt.Println("lower_case_table_names配置异常，取值为 0、1 或 2")
+		os.Exit(1)
+	}
+	cfg.InnodbBufferPoolSize, err = valueAsBytes(section, "innodb_buffer_pool_size", 256*16384)
+	if err != nil {
+		fmt.Println("innodb_buffer_pool_size配置异常", err)
+		os.Exit(1)
+	}
+	cfg.InnodbBufferPoolInstances = section.Key("innodb_buffer_pool_instances").MustInt(8)
+	if cfg.InnodbBufferPoolInstances < 1 || cfg.InnodbBufferPoolInstances > 64 {
+		fmt.Println("innodb_buffer_pool_instances配置异常，取值范围 1 到 64")
+		os.Exit(1)
+	}
+	cfg.SuperReadOnly = section.Key("super_read_only").MustBool(false)
+	cfg.ReadOnly = section.Key("read_only").MustBool(false) || cfg.SuperReadOnly
+	cfg.AuditLog = section.Key("audit_log").MustBool(false)
+	cfg.AuditLogFile, err = valueAsString(section, "audit_log_file", "audit.log")
+	if err != nil {
+		fmt.Println("audit_log_file配置异常", err)
+		os.Exit(1)
+	}
+	cfg.AuditLogPolicy = strings.ToUpper(strings.TrimSpace(section.Key("audit_log_policy").MustString("FULL")))
+	if cfg.AuditLogPolicy != "FULL" && cfg.AuditLogPolicy != "NORMALIZED" {
+		fmt.Println("audit_log_policy配置异常，取值为 FULL 或 NORMALIZED")
+		os.Exit(1)
+	}
+	cfg.AuditLogRotateOnSize, err = valueAsBytes(section, "audit_log_rotate_on_size", 0)
+	if err != nil {
+		fmt.Println("audit_log_rotate_on_size配置异常", err)
+		os.Exit(1)
+	}
+	cfg.AuditLogBufferSize = section.Key("audit_log_buffer_size").MustInt(4096)
+	if cfg.AuditLogBufferSize < 1 {
+		fmt.Println("audit_log_buffer_size配置异常，至少为 1")
+		os.Exit(1)
+	}
+	cfg.MetricsPort = section.Key("metrics_port").MustInt(0)
+	if cfg.MetricsPort < 0 || cfg.MetricsPort > 65535 {
+		fmt.Println("metrics_port配置异常，取值范围 0 到 65535")
+		os.Exit(1)
+	}
+	cfg.HealthPort = section.Key("health_port").MustInt(0)
+	if cfg.HealthPort < 0 || cfg.HealthPort > 65535 {
+		fmt.Println("health_port配置异常，取值范围 0 到 65535")
+		os.Exit(1)
+	}
+	cfg.MaxServerMemory = section.Key("max_server_memory").MustInt64(0)
+	if cfg.MaxServerMemory < 0 {
+		fmt.Println("max_server_memory配置异常，不能小于 0")
+		os.Exit(1)
+	}
+	cfg.ThreadHandling, err = valueAsOneOf(section, "thread_handling", ThreadHandlingPerConnection,
+		ThreadHandlingPerConnection, ThreadHandlingPoolOfThreads)
+	if err != nil {
+		fmt.Println("thread_handling配置异常，取值为 one-thread-per-connection 或 pool-of-threads")
+		os.Exit(1)
+	}
+	cfg.ThreadPoolSize = section.Key("thread_pool_size").MustInt(16)
+	if cfg.ThreadPoolSize < 1 || cfg.ThreadPoolSize > 512 {
+		fmt.Println("thread_pool_size配置异常，取值范围 1 到 512")
+		os.Exit(1)
+	}
 	failFastTimeout, err := section.GetKey("fail_fast_timeout")
 
 	cfg.FailFastTimeout = failFastTimeout.Value()
@@ -311,6 +548,16 @@ func (cfg *Cfg) parseMysqldCfg(section *ini.Section) *Cfg {
 	return cfg
 }
 
+func (cfg *Cfg) parseBufferCfg(section *ini.Section) *Cfg {
+	var err error
+	cfg.MaxAllowedPacket, err = valueAsBytes(section, "max_allowed_packet", 64<<20)
+	if err != nil {
+		fmt.Println("max_allowed_packet配置异常", err)
+		os.Exit(1)
+	}
+	return cfg
+}
+
 func (cfg *Cfg) loadConfiguration(args *CommandLineArgs) (*ini.File, error) {
 	var err error
 
@@ -341,3 +588,103 @@ func valueAsString(section *ini.Section, keyName string, defaultValue string) (v
 
 	return section.Key(keyName).MustString(defaultValue), nil
 }
+
+// valueAsRowFormat reads a row format name, REDUNDANT, COMPACT or DYNAMIC in
+// any case.
+func valueAsRowFormat(section *ini.Section, keyName string, defaultValue string) (string, error) {
+	value := strings.ToUpper(strings.TrimSpace(section.Key(keyName).MustString(defaultValue)))
+	switch value {
+	case "REDUNDANT", "COMPACT", "DYNAMIC":
+		return value, nil
+	}
+	return "", errors.New("Invalid valueImpl for key '" + keyName + "' in configuration file")
+}
+
+// valueAsStorageEngine reads a storage engine name, InnoDB in any case.
+func valueAsStorageEngine(section *ini.Section, keyName string, defaultValue string) (string, error) {
+	value := strings.TrimSpace(section.Key(keyName).MustString(defaultValue))
+	if strings.EqualFold(value, "InnoDB") {
+		return "InnoDB", nil
+	}
+	return "", errors.New("Unknown storage engine '" + value + "' for key '" + keyName + "' in configuration file")
+}
+
+func valueAsChangeBuffering(section *ini.Section, keyName string, defaultValue string) (string, error) {
+	value := strings.ToLower(strings.TrimSpace(section.Key(keyName).MustString(defaultValue)))
+	switch value {
+	case "none", "inserts", "deletes", "changes", "purges", "all":
+		return value, nil
+	}
+	return "", errors.New("Invalid valueImpl for key '" + keyName + "' in configuration file")
+}
+
+// valueAsOneOf reads a value that must be one of values, case insensitive.
+func valueAsOneOf(section *ini.Section, keyName string, defaultValue string, values ...string) (string, error) {
+	value := strings.ToLower(strings.TrimSpace(section.Key(keyName).MustString(defaultValue)))
+	for _, v := range values {
+		if value == v {
+			return value, nil
+		}
+	}
+	return "", errors.New("Invalid valueImpl for key '" + keyName + "' in configuration file")
+}
+
+// valueAsBytes reads a size such as 16M, with an optional K, M or G suffix.
+func valueAsBytes(section *ini.Section, keyName string, defaultValue int) (int, error) {
+	value := strings.TrimSpace(section.Key(keyName).String())
+	if value == "" {
+		return defaultValue, nil
+	}
+	n, err := parseBytes(value)
+	if err != nil {
+		return 0, errors.New("Invalid valueImpl for key '" + keyName + "' in configuration file")
+	}
+	return n, nil
+}
+
+// parseBytes parses a size such as 16M, with an optional K, M or G suffix.
+func parseBytes(value string) (int, error) {
+	unit := 1
+	switch value[len(value)-1] {
+	case 'k', 'K':
+		unit = 1 << 10
+	case 'm', 'M':
+		unit = 1 << 20
+	case 'g', 'G':
+		unit = 1 << 30
+	}
+	if unit > 1 {
+		value = value[:len(value)-1]
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, errors.New("invalid size " + value)
+	}
+	return n * unit, nil
+}
+
+// ReloadSettings reads ConfigFile again and returns the settings of the
+// [mysqld] and [buffer] sections whose value changed since it was loaded,
+// by name, with sizes such as 16M in bytes. Raw becomes the new file.
+func (cfg *Cfg) ReloadSettings() (map[string]string, error) {
+	file, err := ini.Load(cfg.ConfigFile)
+	if err != nil {
+		return nil, err
+	}
+	changed := make(map[string]string)
+	for _, name := range []string{"mysqld", "buffer"} {
+		old := cfg.Raw.Section(name)
+		for _, key := range file.Section(name).Keys() {
+			value := strings.TrimSpace(key.String())
+			if old.HasKey(key.Name()) && strings.TrimSpace(old.Key(key.Name()).String()) == value {
+				continue
+			}
+			if n, err := parseBytes(value); err == nil {
+				value = strconv.Itoa(n)
+			}
+			changed[key.Name()] = value
+		}
+	}
+	cfg.Raw = file
+	return changed, nil
+}
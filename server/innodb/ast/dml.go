@@ -27,6 +27,7 @@ var (
 	_ DMLNode = &SelectStmt{}
 	_ DMLNode = &ShowStmt{}
 	_ DMLNode = &LoadDataStmt{}
+	_ DMLNode = &ImportTableStmt{}
 
 	_ Node = &Assignment{}
 	_ Node = &ByItem{}
@@ -74,6 +75,8 @@ type Join struct {
 	Using []*ColumnName
 	// NaturalJoin represents join is natural join
 	NaturalJoin bool
+	// StraightJoin reads Left before Right, whatever their costs.
+	StraightJoin bool
 }
 
 // Accept implements Node Accept interface.
@@ -214,6 +217,10 @@ type TableSource struct {
 
 	// AsName is the alias name of the table source.
 	AsName model.CIStr
+
+	// ColumnNames renames the columns of a derived table, as the column
+	// list of a common table expression does.
+	ColumnNames []model.CIStr
 }
 
 // Accept implements Node Accept interface.
@@ -392,6 +399,8 @@ func (n *ByItem) Accept(v Visitor) (Node, bool) {
 type GroupByClause struct {
 	node
 	Items []*ByItem
+	// Rollup is true for GROUP BY ... WITH ROLLUP.
+	Rollup bool
 }
 
 // Accept implements Node Accept interface.
@@ -484,6 +493,10 @@ type SelectStmt struct {
 	LockTp SelectLockType
 	// TableHints represents the level Optimizer Hint
 	TableHints []*TableOptimizerHint
+	// SelectIntoOpt is the INTO clause of the select statement.
+	SelectIntoOpt *SelectIntoOption
+	// With is the WITH clause of the select statement.
+	With *WithClause
 }
 
 // Accept implements Node Accept interface.
@@ -565,6 +578,23 @@ func (n *SelectStmt) Accept(v Visitor) (Node, bool) {
 	return v.Leave(n)
 }
 
+// CommonTableExpression is a named query of a WITH clause.
+type CommonTableExpression struct {
+	Name        model.CIStr
+	ColNameList []model.CIStr
+	// Query is the SelectStmt or UnionStmt of the expression, its text is
+	// parsed again for every reference to the name.
+	Query ResultSetNode
+}
+
+// WithClause is the WITH clause of a SELECT or UNION statement.
+// The queries are not visited by Accept: their references are replaced by
+// derived tables before the statement is resolved.
+type WithClause struct {
+	IsRecursive bool
+	CTEs        []*CommonTableExpression
+}
+
 // UnionSelectList represents the select list in a union statement.
 type UnionSelectList struct {
 	node
@@ -599,6 +629,7 @@ type UnionStmt struct {
 	SelectList *UnionSelectList
 	OrderBy    *OrderByClause
 	Limit      *Limit
+	With       *WithClause
 }
 
 // Accept implements Node Accept interface.
@@ -698,6 +729,43 @@ func (n *LoadDataStmt) Accept(v Visitor) (Node, bool) {
 	return v.Leave(n)
 }
 
+// DefaultImportMaxErrors is the number of bad lines an IMPORT TABLE skips
+// before it fails, without MAX_ERRORS.
+const DefaultImportMaxErrors = 100
+
+// ImportTableStmt is a statement to import the rows of a server side CSV
+// file into a table:
+// IMPORT TABLE t FROM CSV '/path' [WITH HEADER] [DELIMITER ','] [MAX_ERRORS n]
+type ImportTableStmt struct {
+	dmlNode
+
+	Table *TableName
+	Path  string
+	// Header tells that the first line of the file names the columns of
+	// the fields, which otherwise are the columns of the table in order.
+	Header    bool
+	Delimiter string
+	// MaxErrors is the number of bad lines skipped before the import fails.
+	MaxErrors uint64
+}
+
+// Accept implements Node Accept interface.
+func (n *ImportTableStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*ImportTableStmt)
+	if n.Table != nil {
+		node, ok := n.Table.Accept(v)
+		if !ok {
+			return n, false
+		}
+		n.Table = node.(*TableName)
+	}
+	return v.Leave(n)
+}
+
 // FieldsClause represents fields references clause in load data statement.
 type FieldsClause struct {
 	Terminated string
@@ -711,6 +779,23 @@ type LinesClause struct {
 	Terminated string
 }
 
+// SelectIntoType is the type of SELECT ... INTO.
+type SelectIntoType int
+
+// Select into types.
+const (
+	SelectIntoOutfile SelectIntoType = iota + 1
+)
+
+// SelectIntoOption represents the INTO clause of a select statement.
+// See https://dev.mysql.com/doc/refman/5.7/en/select-into.html
+type SelectIntoOption struct {
+	Tp         SelectIntoType
+	FileName   string
+	FieldsInfo *FieldsClause
+	LinesInfo  *LinesClause
+}
+
 // InsertStmt is a statement to insert new rows into an existing table.
 // See https://dev.mysql.com/doc/refman/5.7/en/insert.html
 type InsertStmt struct {
@@ -964,6 +1049,10 @@ const (
 	ShowStatsHistograms
 	ShowStatsBuckets
 	ShowPlugins
+	ShowEngineStatus
+	ShowOpenTables
+	ShowProfiles
+	ShowProfile
 )
 
 // ShowStmt is a statement to provide information about databases, tables, columns and so on.
@@ -979,6 +1068,10 @@ type ShowStmt struct {
 	Flag   int         // Some flag parsed from sql, such as FULL.
 	Full   bool
 	User   *auth.UserIdentity // Used for show grants.
+	Engine string             // Used for show engine status.
+	// QueryID is the statement of show profile for query, 0 for the last
+	// statement profiled. The statements are numbered from 1.
+	QueryID uint64
 
 	// GlobalScope is used by show variables
 	GlobalScope bool
@@ -29,6 +29,7 @@ var (
 	_ StmtNode = &AlterUserStmt{}
 	_ StmtNode = &BeginStmt{}
 	_ StmtNode = &BinlogStmt{}
+	_ StmtNode = &ChecksumTableStmt{}
 	_ StmtNode = &CommitStmt{}
 	_ StmtNode = &CreateUserStmt{}
 	_ StmtNode = &DeallocateStmt{}
@@ -43,6 +44,7 @@ var (
 	_ StmtNode = &UseStmt{}
 	_ StmtNode = &FlushStmt{}
 	_ StmtNode = &KillStmt{}
+	_ StmtNode = &ResetPersistStmt{}
 
 	_ Node = &PrivElem{}
 	_ Node = &VariableAssignment{}
@@ -276,6 +278,9 @@ const (
 	// SetNames is the const for set names/charset stmt.
 	// If VariableAssignment.Name == Names, it should be set names/charset stmt.
 	SetNames = "SetNAMES"
+	// SetCharset is the const for set character set stmt, which sets the
+	// connection charset to the one of the database.
+	SetCharset = "SetCHARSET"
 )
 
 // VariableAssignment is a variable assignment struct.
@@ -285,6 +290,8 @@ type VariableAssignment struct {
 	Value    ExprNode
 	IsGlobal bool
 	IsSystem bool
+	// IsPersist is set by SET PERSIST, which also sets the global value.
+	IsPersist bool
 
 	// ExtendValue is a way to storebytes extended info.
 	// VariableAssignment should be able to storebytes information for SetCharset/SetPWD Stmt.
@@ -316,6 +323,7 @@ const (
 	FlushNone FlushStmtType = iota
 	FlushTables
 	FlushPrivileges
+	FlushConfig
 )
 
 // FlushStmt is a statement to flush tables/privileges/optimizer costs and so on.
@@ -368,6 +376,26 @@ func (n *KillStmt) Accept(v Visitor) (Node, bool) {
 	return v.Leave(n)
 }
 
+// ResetPersistStmt is RESET PERSIST [[IF EXISTS] name], which removes the
+// variable name, or all of them when Name is empty, from the persisted
+// config file.
+type ResetPersistStmt struct {
+	stmtNode
+
+	IfExists bool
+	Name     string
+}
+
+// Accept implements Node Accept interface.
+func (n *ResetPersistStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*ResetPersistStmt)
+	return v.Leave(n)
+}
+
 // SetStmt is the statement to set variables.
 type SetStmt struct {
 	stmtNode
@@ -600,6 +628,33 @@ func (n *AdminStmt) Accept(v Visitor) (Node, bool) {
 	return v.Leave(n)
 }
 
+// ChecksumTableStmt is a statement to compute the checksum of the rows of
+// tables. See https://dev.mysql.com/doc/refman/5.7/en/checksum-table.html
+type ChecksumTableStmt struct {
+	stmtNode
+
+	Tables []*TableName
+	// Quick asks for the live checksum the table keeps, without reading it.
+	Quick bool
+}
+
+// Accept implements Node Accept interface.
+func (n *ChecksumTableStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*ChecksumTableStmt)
+	for i, val := range n.Tables {
+		node, ok := val.Accept(v)
+		if !ok {
+			return n, false
+		}
+		n.Tables[i] = node.(*TableName)
+	}
+	return v.Leave(n)
+}
+
 // PrivElem is the privilege type and optional column list.
 type PrivElem struct {
 	node
@@ -754,6 +809,8 @@ type SelectStmtOpts struct {
 	CalcFoundRows bool
 	Priority      mysql.PriorityEnum
 	TableHints    []*TableOptimizerHint
+	// StraightJoin keeps the tables joined in the order of the FROM clause.
+	StraightJoin bool
 }
 
 // TableOptimizerHint is Table level optimizer hint
@@ -764,6 +821,8 @@ type TableOptimizerHint struct {
 	// It allows only table name or alias (if table has an alias)
 	HintName model.CIStr
 	Tables   []model.CIStr
+	// Indexes are the indices named by an index hint like INDEX(t idx).
+	Indexes []model.CIStr
 }
 
 // Accept implements Node Accept interface.
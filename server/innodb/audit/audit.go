@@ -0,0 +1,306 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/juju/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+/**
+审计日志
+
+审计日志记录谁连接了服务器、执行了什么语句，每个事件是 JSON 文件中的一行：
+连接建立和断开（用户、主机、是否成功、是否使用 TLS）、认证失败、语句执行（用户、
+当前库、语句类型、SQL、影响的行数、耗时、错误码）。
+
+事件先放入有界的队列，由后台的写线程写入文件，队列满时丢弃事件并计数，审计日志
+不会阻塞语句的执行。文件超过 audit_log_rotate_on_size 时改名为 文件名.序号，再写
+新的文件。audit_log_policy 为 FULL 时记录完整的 SQL，为 NORMALIZED 时记录把常量
+替换为 ? 之后的 SQL。SET GLOBAL audit_log = ON/OFF 在运行时打开和关闭审计日志。
+**/
+
+// The classes of the events of the audit log.
+const (
+	EventConnect     = "connect"
+	EventDisconnect  = "disconnect"
+	EventAuthFailure = "auth_failure"
+	EventQuery       = "query"
+)
+
+// The values of audit_log_policy.
+const (
+	// PolicyFull logs the SQL of the statements as they were sent.
+	PolicyFull = "FULL"
+	// PolicyNormalized logs the SQL of the statements with their literals
+	// replaced by ?.
+	PolicyNormalized = "NORMALIZED"
+)
+
+// DefaultQueueSize is the number of events waiting to be written above
+// which new events are dropped.
+const DefaultQueueSize = 4096
+
+// Event is a line of the audit log.
+type Event struct {
+	Time         string `json:"time"`
+	Class        string `json:"class"`
+	ConnectionID uint64 `json:"connection_id"`
+	User         string `json:"user"`
+	Host         string `json:"host"`
+	DB           string `json:"db,omitempty"`
+	// Status is "success" or "failure" for the connections, the statements
+	// report ErrorCode instead.
+	Status        string `json:"status,omitempty"`
+	TLS           bool   `json:"tls"`
+	StatementType string `json:"statement_type,omitempty"`
+	SQL           string `json:"sql,omitempty"`
+	AffectedRows  uint64 `json:"affected_rows"`
+	DurationUs    int64  `json:"duration_us"`
+	ErrorCode     uint16 `json:"error_code"`
+	// Retries counts the times the statement was run again after a
+	// deadlock.
+	Retries int `json:"retries,omitempty"`
+}
+
+// queued is an event of the queue, or a flush request when flushed is set.
+type queued struct {
+	event   *Event
+	flushed chan struct{}
+}
+
+// Logger writes the events of the audit log in the background. A nil
+// Logger logs nothing.
+type Logger struct {
+	path string
+
+	enabled    int32
+	policy     atomic.Value
+	rotateSize int64
+
+	queue   chan queued
+	done    chan struct{}
+	closing sync.Once
+
+	written int64
+	lost    int64
+
+	// file and size belong to the writer goroutine.
+	file *os.File
+	size int64
+}
+
+// NewLogger returns a disabled logger writing to path, which starts a new
+// file when it grows over rotateSize bytes, never when rotateSize is 0.
+// At most queueSize events wait to be written.
+func NewLogger(path string, rotateSize int64, queueSize int) *Logger {
+	if queueSize <= 0 {
+		queueSize = DefaultQueueSize
+	}
+	l := &Logger{
+		path:       path,
+		rotateSize: rotateSize,
+		queue:      make(chan queued, queueSize),
+		done:       make(chan struct{}),
+	}
+	l.policy.Store(PolicyFull)
+	go l.run()
+	return l
+}
+
+// Path returns the file the events are written to.
+func (l *Logger) Path() string {
+	if l == nil {
+		return ""
+	}
+	return l.path
+}
+
+// Enabled reports whether events are logged.
+func (l *Logger) Enabled() bool {
+	return l != nil && atomic.LoadInt32(&l.enabled) == 1
+}
+
+// SetEnabled turns the audit log on or off.
+func (l *Logger) SetEnabled(on bool) {
+	if on {
+		atomic.StoreInt32(&l.enabled, 1)
+	} else {
+		atomic.StoreInt32(&l.enabled, 0)
+	}
+}
+
+// Policy returns PolicyFull or PolicyNormalized.
+func (l *Logger) Policy() string {
+	return l.policy.Load().(string)
+}
+
+// SetPolicy sets audit_log_policy, PolicyFull or PolicyNormalized in any
+// case.
+func (l *Logger) SetPolicy(policy string) error {
+	policy = strings.ToUpper(policy)
+	switch policy {
+	case PolicyFull, PolicyNormalized:
+		l.policy.Store(policy)
+		return nil
+	}
+	return errors.Errorf("unknown audit log policy %s", policy)
+}
+
+// RotateSize returns the size above which the file is rotated.
+func (l *Logger) RotateSize() int64 {
+	return atomic.LoadInt64(&l.rotateSize)
+}
+
+// SetRotateSize sets the size above which the file is rotated, 0 for
+// never.
+func (l *Logger) SetRotateSize(size int64) {
+	atomic.StoreInt64(&l.rotateSize, size)
+}
+
+// Written returns the number of events written to the file.
+func (l *Logger) Written() int64 {
+	if l == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&l.written)
+}
+
+// Lost returns the number of events dropped because the queue was full or
+// the file couldn't be written.
+func (l *Logger) Lost() int64 {
+	if l == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&l.lost)
+}
+
+// Log queues e to be written when the audit log is on. It never waits:
+// when the queue is full e is dropped and counted as lost.
+func (l *Logger) Log(e *Event) {
+	if !l.Enabled() {
+		return
+	}
+	if e.Time == "" {
+		e.Time = time.Now().UTC().Format("2006-01-02T15:04:05.000000Z")
+	}
+	select {
+	case l.queue <- queued{event: e}:
+	default:
+		atomic.AddInt64(&l.lost, 1)
+	}
+}
+
+// Flush waits until the events queued so far are written.
+func (l *Logger) Flush() {
+	if l == nil {
+		return
+	}
+	flushed := make(chan struct{})
+	l.queue <- queued{flushed: flushed}
+	<-flushed
+}
+
+// Close writes the events queued and closes the file. The logger isn't
+// used after.
+func (l *Logger) Close() {
+	if l == nil {
+		return
+	}
+	l.closing.Do(func() {
+		close(l.queue)
+		<-l.done
+	})
+}
+
+// run writes the queued events until the queue is closed.
+func (l *Logger) run() {
+	defer close(l.done)
+	for q := range l.queue {
+		if q.flushed != nil {
+			if l.file != nil {
+				l.file.Sync()
+			}
+			close(q.flushed)
+			continue
+		}
+		if err := l.write(q.event); err != nil {
+			atomic.AddInt64(&l.lost, 1)
+			log.Warnf("写审计日志 %s 失败: %v", l.path, err)
+		}
+	}
+	if l.file != nil {
+		l.file.Close()
+	}
+}
+
+// write appends e to the file, rotating it first when e would take it over
+// the rotation size.
+func (l *Logger) write(e *Event) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	line = append(line, '\n')
+	if l.file == nil {
+		if err = l.open(); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	if rotateSize := l.RotateSize(); rotateSize > 0 && l.size > 0 && l.size+int64(len(line)) > rotateSize {
+		if err = l.rotate(); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	atomic.AddInt64(&l.written, 1)
+	return nil
+}
+
+// open opens the file for appending.
+func (l *Logger) open() error {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0750); err != nil {
+		return errors.Trace(err)
+	}
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0640)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return errors.Trace(err)
+	}
+	l.file, l.size = f, info.Size()
+	return nil
+}
+
+// rotate renames the file to the first path.N not in use and opens a new
+// one.
+func (l *Logger) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return errors.Trace(err)
+	}
+	l.file = nil
+	for n := 1; ; n++ {
+		rotated := fmt.Sprintf("%s.%d", l.path, n)
+		if _, err := os.Stat(rotated); os.IsNotExist(err) {
+			if err = os.Rename(l.path, rotated); err != nil {
+				return errors.Trace(err)
+			}
+			break
+		}
+	}
+	return errors.Trace(l.open())
+}
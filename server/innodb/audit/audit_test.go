@@ -0,0 +1,128 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readEvents(t *testing.T, path string) []Event {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var events []Event
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("line %q: %v", scanner.Text(), err)
+		}
+		events = append(events, e)
+	}
+	return events
+}
+
+func TestLogger(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+	l := NewLogger(path, 0, 0)
+	defer l.Close()
+
+	l.Log(&Event{Class: EventConnect, User: "root"})
+	l.Flush()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("the disabled log wrote %s", path)
+	}
+	l.SetEnabled(true)
+	l.Log(&Event{Class: EventConnect, ConnectionID: 7, User: "root", Host: "127.0.0.1", Status: "success"})
+	l.Log(&Event{Class: EventQuery, ConnectionID: 7, User: "root", DB: "test", StatementType: "Select",
+		SQL: "select ?", ErrorCode: 1146})
+	l.SetEnabled(false)
+	l.Log(&Event{Class: EventDisconnect, ConnectionID: 7})
+	l.Flush()
+
+	events := readEvents(t, path)
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2: %+v", len(events), events)
+	}
+	if e := events[0]; e.Class != EventConnect || e.User != "root" || e.Host != "127.0.0.1" || e.Status != "success" || e.Time == "" {
+		t.Errorf("connect event %+v", e)
+	}
+	if e := events[1]; e.Class != EventQuery || e.DB != "test" || e.SQL != "select ?" || e.ErrorCode != 1146 {
+		t.Errorf("query event %+v", e)
+	}
+	if l.Written() != 2 || l.Lost() != 0 {
+		t.Errorf("written %d lost %d", l.Written(), l.Lost())
+	}
+}
+
+func TestLoggerDropsWhenFull(t *testing.T) {
+	// No writer drains the queue.
+	l := &Logger{queue: make(chan queued, 2)}
+	l.SetEnabled(true)
+	for i := 0; i < 5; i++ {
+		l.Log(&Event{Class: EventQuery})
+	}
+	if l.Lost() != 3 {
+		t.Errorf("lost %d events, want 3", l.Lost())
+	}
+}
+
+func TestLoggerRotates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+	l := NewLogger(path, 300, 0)
+	l.SetEnabled(true)
+	for i := 0; i < 10; i++ {
+		l.Log(&Event{Class: EventQuery, SQL: "select ? from t"})
+	}
+	l.Close()
+
+	total := 0
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if info.Size() > 300 {
+			t.Errorf("%s has %d bytes, more than 300", name, info.Size())
+		}
+		total += len(readEvents(t, name))
+	}
+	matches, _ := filepath.Glob(path + "*")
+	for _, name := range matches[3:] {
+		total += len(readEvents(t, name))
+	}
+	if total != 10 {
+		t.Errorf("%d events in the rotated files, want 10", total)
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	l := &Logger{}
+	l.policy.Store(PolicyFull)
+	if err := l.SetPolicy("normalized"); err != nil || l.Policy() != PolicyNormalized {
+		t.Errorf("policy %s, error %v", l.Policy(), err)
+	}
+	if err := l.SetPolicy("queries"); err == nil || l.Policy() != PolicyNormalized {
+		t.Errorf("policy %s, error %v", l.Policy(), err)
+	}
+	var nilLogger *Logger
+	nilLogger.Log(&Event{})
+	if nilLogger.Enabled() || nilLogger.Lost() != 0 {
+		t.Error("a nil logger logs")
+	}
+}
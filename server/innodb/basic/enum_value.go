@@ -0,0 +1,112 @@
+package basic
+
+import "strconv"
+
+/**
+ENUM 列在记录中保存的是值在成员列表中的序号，成员不超过 255 个时占 1 个字节，
+否则占 2 个字节，低字节在前。序号从 1 开始，0 是非严格模式下插入非法值时保存的
+空字符串。比较和排序按照序号进行，读出时再转换为成员的字符串。
+**/
+
+// EnumValue is the value of an ENUM column read from a record: the ordinal
+// of the member stored in 1 or 2 bytes.
+type EnumValue struct {
+	Value
+	value []byte
+	elems []string
+}
+
+// NewEnumValue returns the value of the ordinal stored in value of an ENUM
+// column with members elems.
+func NewEnumValue(value []byte, elems []string) Value {
+	var enumValue = new(EnumValue)
+	enumValue.value = value
+	enumValue.elems = elems
+	return enumValue
+}
+
+// EnumOrdinalBytes returns the bytes of ordinal stored in size bytes.
+func EnumOrdinalBytes(ordinal uint64, size int) []byte {
+	buff := make([]byte, size)
+	for i := 0; i < size; i++ {
+		buff[i] = byte(ordinal >> (8 * uint(i)))
+	}
+	return buff
+}
+
+func (e *EnumValue) ordinal() uint64 {
+	var ordinal uint64
+	for i, b := range e.value {
+		ordinal |= uint64(b) << (8 * uint(i))
+	}
+	return ordinal
+}
+
+func (e *EnumValue) enum() Enum {
+	enum, err := ParseEnumValue(e.elems, e.ordinal())
+	if err != nil {
+		return Enum{}
+	}
+	return enum
+}
+
+// ToString returns the member, empty for the ordinal 0.
+func (e *EnumValue) ToString() string {
+	if e.elems == nil {
+		return strconv.FormatUint(e.ordinal(), 10)
+	}
+	return e.enum().Name
+}
+
+func (e *EnumValue) ToDatum() Datum {
+	d := Datum{}
+	d.SetMysqlEnum(e.enum())
+	return d
+}
+
+func (e *EnumValue) Raw() interface{} {
+	return e.ordinal()
+}
+
+func (e *EnumValue) ToByte() []byte {
+	return e.value
+}
+
+func (e *EnumValue) DataType() ValType {
+	return IntVal
+}
+
+func (e *EnumValue) compare(value Value) int {
+	first, second := e.ordinal(), value.(*EnumValue).ordinal()
+	switch {
+	case first < second:
+		return -1
+	case first > second:
+		return 1
+	}
+	return 0
+}
+
+func (e *EnumValue) Equal(value Value) (Value, error) {
+	return NewBoolValue(e.compare(value) == 0), nil
+}
+
+func (e *EnumValue) NotEqual(value Value) (Value, error) {
+	return NewBoolValue(e.compare(value) != 0), nil
+}
+
+func (e *EnumValue) GreaterThan(value Value) (Value, error) {
+	return NewBoolValue(e.compare(value) > 0), nil
+}
+
+func (e *EnumValue) LessThan(value Value) (Value, error) {
+	return NewBoolValue(e.compare(value) < 0), nil
+}
+
+func (e *EnumValue) GreaterOrEqual(value Value) (Value, error) {
+	return NewBoolValue(e.compare(value) >= 0), nil
+}
+
+func (e *EnumValue) LessOrEqual(value Value) (Value, error) {
+	return NewBoolValue(e.compare(value) <= 0), nil
+}
@@ -49,6 +49,8 @@ var (
 	ErrInvalidDefault = terror.ClassTypes.New(codeInvalidDefault, "Invalid default value for '%s'")
 	// ErrMBiggerThanD is returned when precision less than the scale.
 	ErrMBiggerThanD = terror.ClassTypes.New(codeMBiggerThanD, mysql.MySQLErrName[mysql.ErrMBiggerThanD])
+	// ErrDuplicatedValueInType is returned when an ENUM or SET lists a member twice.
+	ErrDuplicatedValueInType = terror.ClassTypes.New(codeDuplicatedValueInType, mysql.MySQLErrName[mysql.ErrDuplicatedValueInType])
 )
 
 const (
@@ -67,6 +69,8 @@ const (
 	codeUnknown             terror.ErrCode = terror.ErrCode(mysql.ErrUnknown)
 	codeInvalidDefault      terror.ErrCode = terror.ErrCode(mysql.ErrInvalidDefault)
 	codeMBiggerThanD        terror.ErrCode = terror.ErrCode(mysql.ErrMBiggerThanD)
+
+	codeDuplicatedValueInType terror.ErrCode = terror.ErrCode(mysql.ErrDuplicatedValueInType)
 )
 
 var (
@@ -91,6 +95,8 @@ func init() {
 		codeUnknown:             mysql.ErrUnknown,
 		codeInvalidDefault:      mysql.ErrInvalidDefault,
 		codeMBiggerThanD:        mysql.ErrMBiggerThanD,
+
+		codeDuplicatedValueInType: mysql.ErrDuplicatedValueInType,
 	}
 	terror.ErrClassToMySQLCodes[terror.ClassTypes] = typesMySQLErrCodes
 }
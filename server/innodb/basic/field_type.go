@@ -210,7 +210,8 @@ func (ft *FieldType) CompactStr() string {
 // returns a string.
 func (ft *FieldType) InfoSchemaStr() string {
 	suffix := ""
-	if mysql.HasUnsignedFlag(ft.Flag) {
+	// BIT carries the unsigned flag for the clients, but isn't declared so.
+	if mysql.HasUnsignedFlag(ft.Flag) && ft.Tp != mysql.TypeBit {
 		suffix = " unsigned"
 	}
 	return ft.CompactStr() + suffix
@@ -220,7 +221,7 @@ func (ft *FieldType) InfoSchemaStr() string {
 // Note: when flen or decimal is unspecified, this function will use the default value instead of -1.
 func (ft *FieldType) String() string {
 	strs := []string{ft.CompactStr()}
-	if mysql.HasUnsignedFlag(ft.Flag) {
+	if mysql.HasUnsignedFlag(ft.Flag) && ft.Tp != mysql.TypeBit {
 		strs = append(strs, "UNSIGNED")
 	}
 	if mysql.HasZerofillFlag(ft.Flag) {
@@ -1306,6 +1306,12 @@ func (d *MyDecimal) FromBin(bin []byte, precision, frac int) (binSize int, err e
 	return binSize, err
 }
 
+// DecimalBinSize returns the number of bytes a decimal of precision and frac
+// takes in its binary representation.
+func DecimalBinSize(precision, frac int) int {
+	return decimalBinSize(precision, frac)
+}
+
 // decimalBinSize returns the size of array to hold a binary representation of a decimal.
 func decimalBinSize(precision, frac int) int {
 	digitsInt := precision - frac
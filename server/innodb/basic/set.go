@@ -70,7 +70,8 @@ func ParseSetName(elems []string, name string) (Set, error) {
 		return ParseSetValue(elems, num)
 	}
 
-	return Set{}, errors.Errorf("item %s is not in Set %v", name, elems)
+	// The members found are kept for a caller taking the error as a warning.
+	return Set{Name: strings.Join(items, ","), Value: value}, errors.Errorf("item %s is not in Set %v", name, elems)
 }
 
 var (
@@ -0,0 +1,250 @@
+package buffer_pool
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+/**
+自适应哈希索引（AHI）
+
+B+树每次点查都要从根页面一路下降到叶子页面。AHI 观察每个叶子页面上的查找：
+同一页面上以相同的前缀模式（查找用到的键列数）连续查找超过 ahiBuildLimit 次后，
+该页面被认为是热点页面，此后在该页面上找到的键都会加入哈希表，
+下次查找同一个键时直接得到记录所在的页面和槽位，不再下降。
+
+页面分裂、合并、被修改或被淘汰出缓冲池时，该页面上的键全部从哈希表中删除。
+
+哈希表按键分成多个分区，每个分区有自己的读写锁；页面的统计信息按页面分区，
+任何时候只持有一个分区的锁。
+**/
+
+// ahiBuildLimit is the number of lookups with the same prefix a leaf page
+// takes before the keys found on it are hashed, BTR_SEARCH_BUILD_LIMIT.
+const ahiBuildLimit = 100
+
+// DefaultAHIPartitions is the number of partitions of the adaptive hash
+// index of a buffer pool, innodb_adaptive_hash_index_parts.
+const DefaultAHIPartitions = 8
+
+// AHIRecordRef is where the adaptive hash index found a key: the page and
+// the slot of its record.
+type AHIRecordRef struct {
+	SpaceId uint32
+	PageNo  uint32
+	Slot    int
+}
+
+// AHIStats are the counters of an adaptive hash index.
+type AHIStats struct {
+	// Hits and Misses count the lookups answered by the hash table and the
+	// ones that had to descend the tree.
+	Hits   uint64
+	Misses uint64
+	// RowsAdded and RowsRemoved count the keys added and removed.
+	RowsAdded   uint64
+	RowsRemoved uint64
+	// Cells is the number of keys in the hash table.
+	Cells int
+}
+
+// AdaptiveHashIndex maps the keys looked up on the hot leaf pages of the
+// B+trees to their records.
+type AdaptiveHashIndex struct {
+	enabled    int32
+	partitions []*ahiPartition
+
+	hits, misses, rowsAdded, rowsRemoved uint64
+}
+
+type ahiPartition struct {
+	mu sync.RWMutex
+	// entries are the records by index and key.
+	entries map[string]AHIRecordRef
+	// pages are the pages whose lookups are watched.
+	pages map[uint64]*ahiPage
+}
+
+// ahiPage watches the lookups landing on a leaf page.
+type ahiPage struct {
+	// prefix is the number of key fields of the last lookups and potential
+	// how many of them used it in a row.
+	prefix    int
+	potential int
+	// keys are the entries of the page in the hash table.
+	keys map[string]struct{}
+}
+
+// NewAdaptiveHashIndex returns an enabled adaptive hash index in partitions
+// partitions.
+func NewAdaptiveHashIndex(partitions int) *AdaptiveHashIndex {
+	if partitions < 1 {
+		partitions = 1
+	}
+	ahi := &AdaptiveHashIndex{enabled: 1, partitions: make([]*ahiPartition, partitions)}
+	for i := range ahi.partitions {
+		ahi.partitions[i] = newAHIPartition()
+	}
+	return ahi
+}
+
+func newAHIPartition() *ahiPartition {
+	return &ahiPartition{entries: make(map[string]AHIRecordRef), pages: make(map[uint64]*ahiPage)}
+}
+
+// Enabled reports whether the adaptive hash index is used.
+func (ahi *AdaptiveHashIndex) Enabled() bool {
+	return atomic.LoadInt32(&ahi.enabled) == 1
+}
+
+// SetEnabled turns the adaptive hash index on or off, innodb_adaptive_hash_index.
+// Turning it off drops every entry.
+func (ahi *AdaptiveHashIndex) SetEnabled(enabled bool) {
+	if enabled {
+		atomic.StoreInt32(&ahi.enabled, 1)
+		return
+	}
+	if !atomic.CompareAndSwapInt32(&ahi.enabled, 1, 0) {
+		return
+	}
+	for _, part := range ahi.partitions {
+		part.mu.Lock()
+		atomic.AddUint64(&ahi.rowsRemoved, uint64(len(part.entries)))
+		part.entries = make(map[string]AHIRecordRef)
+		part.pages = make(map[uint64]*ahiPage)
+		part.mu.Unlock()
+	}
+}
+
+// Lookup returns the record of key in the index of the space, if the hash
+// table has it.
+func (ahi *AdaptiveHashIndex) Lookup(spaceId uint32, index string, key []byte) (AHIRecordRef, bool) {
+	if !ahi.Enabled() {
+		return AHIRecordRef{}, false
+	}
+	entry := ahiEntryKey(spaceId, index, key)
+	part := ahi.partition(entry)
+	part.mu.RLock()
+	ref, ok := part.entries[entry]
+	part.mu.RUnlock()
+	if ok {
+		atomic.AddUint64(&ahi.hits, 1)
+	} else {
+		atomic.AddUint64(&ahi.misses, 1)
+	}
+	return ref, ok
+}
+
+// Observe records that a tree descent found key, using its first prefix
+// fields, at ref. Once the page of ref is hot, key is added to the hash
+// table.
+func (ahi *AdaptiveHashIndex) Observe(spaceId uint32, index string, prefix int, key []byte, ref AHIRecordRef) {
+	if !ahi.Enabled() {
+		return
+	}
+	entry := ahiEntryKey(spaceId, index, key)
+	page := ahiPageKey(ref.SpaceId, ref.PageNo)
+	pagePart := ahi.pagePartition(page)
+	pagePart.mu.Lock()
+	info, ok := pagePart.pages[page]
+	if !ok {
+		info = &ahiPage{keys: make(map[string]struct{})}
+		pagePart.pages[page] = info
+	}
+	if info.prefix != prefix {
+		info.prefix, info.potential = prefix, 0
+	}
+	info.potential++
+	hot := info.potential >= ahiBuildLimit
+	if hot {
+		info.keys[entry] = struct{}{}
+	}
+	pagePart.mu.Unlock()
+	if !hot {
+		return
+	}
+
+	part := ahi.partition(entry)
+	part.mu.Lock()
+	if _, ok := part.entries[entry]; !ok {
+		atomic.AddUint64(&ahi.rowsAdded, 1)
+	}
+	part.entries[entry] = ref
+	part.mu.Unlock()
+}
+
+// InvalidatePage removes the entries of the page from the hash table and
+// forgets its lookups, for a page split, merged, modified or evicted.
+func (ahi *AdaptiveHashIndex) InvalidatePage(spaceId uint32, pageNo uint32) {
+	page := ahiPageKey(spaceId, pageNo)
+	pagePart := ahi.pagePartition(page)
+	pagePart.mu.Lock()
+	info, ok := pagePart.pages[page]
+	delete(pagePart.pages, page)
+	pagePart.mu.Unlock()
+	if !ok {
+		return
+	}
+	for entry := range info.keys {
+		part := ahi.partition(entry)
+		part.mu.Lock()
+		// The key may have moved to another page since.
+		if ref, ok := part.entries[entry]; ok && ref.SpaceId == spaceId && ref.PageNo == pageNo {
+			delete(part.entries, entry)
+			atomic.AddUint64(&ahi.rowsRemoved, 1)
+		}
+		part.mu.Unlock()
+	}
+}
+
+// Stats returns the counters of the adaptive hash index.
+func (ahi *AdaptiveHashIndex) Stats() AHIStats {
+	stats := AHIStats{
+		Hits:        atomic.LoadUint64(&ahi.hits),
+		Misses:      atomic.LoadUint64(&ahi.misses),
+		RowsAdded:   atomic.LoadUint64(&ahi.rowsAdded),
+		RowsRemoved: atomic.LoadUint64(&ahi.rowsRemoved),
+	}
+	for _, part := range ahi.partitions {
+		part.mu.RLock()
+		stats.Cells += len(part.entries)
+		part.mu.RUnlock()
+	}
+	return stats
+}
+
+// Status returns the lines of SHOW ENGINE INNODB STATUS on the adaptive
+// hash index.
+func (ahi *AdaptiveHashIndex) Status() string {
+	stats := ahi.Stats()
+	state := "OFF"
+	if ahi.Enabled() {
+		state = "ON"
+	}
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "Adaptive hash index %s, %d partitions, %d cells\n", state, len(ahi.partitions), stats.Cells)
+	fmt.Fprintf(&buf, "%d hash searches, %d non-hash searches\n", stats.Hits, stats.Misses)
+	fmt.Fprintf(&buf, "%d rows added, %d rows removed\n", stats.RowsAdded, stats.RowsRemoved)
+	return buf.String()
+}
+
+func (ahi *AdaptiveHashIndex) partition(entry string) *ahiPartition {
+	h := fnv.New32a()
+	h.Write([]byte(entry))
+	return ahi.partitions[h.Sum32()%uint32(len(ahi.partitions))]
+}
+
+func (ahi *AdaptiveHashIndex) pagePartition(page uint64) *ahiPartition {
+	return ahi.partitions[page%uint64(len(ahi.partitions))]
+}
+
+func ahiEntryKey(spaceId uint32, index string, key []byte) string {
+	return fmt.Sprintf("%d/%s/%s", spaceId, index, key)
+}
+
+func ahiPageKey(spaceId uint32, pageNo uint32) uint64 {
+	return uint64(spaceId)<<32 | uint64(pageNo)
+}
@@ -0,0 +1,116 @@
+package buffer_pool
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// observeHot observes keys on page until it is hot, then each of them once
+// more so they are hashed.
+func observeHot(ahi *AdaptiveHashIndex, pageNo uint32, keys ...string) {
+	for i := 0; i < ahiBuildLimit+len(keys); i++ {
+		key := keys[i%len(keys)]
+		ahi.Observe(1, "PRIMARY", 1, []byte(key), AHIRecordRef{SpaceId: 1, PageNo: pageNo, Slot: i % len(keys)})
+	}
+}
+
+func TestAdaptiveHashIndex(t *testing.T) {
+	ahi := NewAdaptiveHashIndex(4)
+	ahi.Observe(1, "PRIMARY", 1, []byte("a"), AHIRecordRef{SpaceId: 1, PageNo: 3})
+	if _, ok := ahi.Lookup(1, "PRIMARY", []byte("a")); ok {
+		t.Fatal("expect a cold page not to be hashed")
+	}
+
+	// A prefix change starts counting again.
+	for i := 0; i < ahiBuildLimit-2; i++ {
+		ahi.Observe(1, "PRIMARY", 1, []byte("a"), AHIRecordRef{SpaceId: 1, PageNo: 3})
+	}
+	ahi.Observe(1, "PRIMARY", 2, []byte("a"), AHIRecordRef{SpaceId: 1, PageNo: 3})
+	if _, ok := ahi.Lookup(1, "PRIMARY", []byte("a")); ok {
+		t.Fatal("expect the page to cool down on a prefix change")
+	}
+
+	observeHot(ahi, 3, "a", "b")
+	observeHot(ahi, 4, "c")
+	if ref, ok := ahi.Lookup(1, "PRIMARY", []byte("b")); !ok || ref.PageNo != 3 || ref.Slot != 1 {
+		t.Fatalf("expect b at page 3 slot 1, got %+v, %v", ref, ok)
+	}
+	if _, ok := ahi.Lookup(1, "idx", []byte("b")); ok {
+		t.Fatal("expect the entries to be per index")
+	}
+	stats := ahi.Stats()
+	if stats.Hits != 1 || stats.Misses != 3 || stats.RowsAdded != 3 || stats.Cells != 3 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	// A split, merge, change or eviction of the page drops its entries.
+	ahi.InvalidatePage(1, 3)
+	if _, ok := ahi.Lookup(1, "PRIMARY", []byte("a")); ok {
+		t.Fatal("expect the entries of page 3 to be dropped")
+	}
+	if _, ok := ahi.Lookup(1, "PRIMARY", []byte("c")); !ok {
+		t.Fatal("expect the entries of page 4 to stay")
+	}
+	ahi.Observe(1, "PRIMARY", 1, []byte("a"), AHIRecordRef{SpaceId: 1, PageNo: 3})
+	if _, ok := ahi.Lookup(1, "PRIMARY", []byte("a")); ok {
+		t.Fatal("expect page 3 to be cold again")
+	}
+	if stats = ahi.Stats(); stats.RowsRemoved != 2 || stats.Cells != 1 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	ahi.SetEnabled(false)
+	if _, ok := ahi.Lookup(1, "PRIMARY", []byte("c")); ok || ahi.Stats().Cells != 0 {
+		t.Fatal("expect a disabled index to be empty")
+	}
+	observeHot(ahi, 4, "c")
+	if ahi.Stats().Cells != 0 {
+		t.Fatal("expect a disabled index not to learn")
+	}
+	ahi.SetEnabled(true)
+	status := ahi.Status()
+	if !strings.Contains(status, "Adaptive hash index ON, 4 partitions, 0 cells") ||
+		!strings.Contains(status, "2 hash searches, 5 non-hash searches") ||
+		!strings.Contains(status, "3 rows added, 3 rows removed") {
+		t.Fatalf("unexpected status %s", status)
+	}
+}
+
+func TestAdaptiveHashIndexEviction(t *testing.T) {
+	pool := NewBufferPool(16*16384, 0.75, 0.25, 1000, nil)
+	observeHot(pool.AHI, 3, "a")
+	block := NewBufferBlock(&[]byte{}, 1, 3)
+	pool.instance(1, 3).lruCache.Set(1, 3, block)
+	pool.instance(1, 3).lruCache.Remove(1, 3)
+	if _, ok := pool.AHI.Lookup(1, "PRIMARY", []byte("a")); ok {
+		t.Fatal("expect an evicted page to drop its entries")
+	}
+}
+
+func TestAdaptiveHashIndexConcurrency(t *testing.T) {
+	ahi := NewAdaptiveHashIndex(DefaultAHIPartitions)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			pageNo := uint32(g)
+			for i := 0; i < 1000; i++ {
+				key := []byte(fmt.Sprintf("%d-%d", g, i%10))
+				if _, ok := ahi.Lookup(1, "PRIMARY", key); !ok {
+					ahi.Observe(1, "PRIMARY", 1, key, AHIRecordRef{SpaceId: 1, PageNo: pageNo, Slot: i % 10})
+				}
+				if i%300 == 0 {
+					ahi.InvalidatePage(1, pageNo)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	stats := ahi.Stats()
+	if stats.Hits+stats.Misses != 8000 || stats.RowsAdded-stats.RowsRemoved != uint64(stats.Cells) {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
@@ -1,5 +1,12 @@
 package buffer_pool
 
+import (
+	"sync/atomic"
+
+	"github.com/zhukovaskychina/xmysql-server/server/common"
+	"github.com/zhukovaskychina/xmysql-server/util"
+)
+
 /**
 这个就是数据页的控制体，用来描述数据页部分的信息(大部分信息在buf_page_t中)。buf_block_t中第一字段就是buf_page_t，这个不是随意放的，
 是必须放在第一字段，因为只有这样buf_block_t和buf_page_t两种类型的指针可以相互转换。第二个字段是frame字段，指向真正存数据的数据页。
@@ -23,14 +30,47 @@ func NewBufferBlock(frame *[]byte, spaceId, pageNo uint32) *BufferBlock {
 	return bufferBlock
 }
 
-func (bb BufferBlock) GetFrame() *[]byte {
+func (bb *BufferBlock) GetFrame() *[]byte {
 	return bb.Frame
 }
 
-func (bb BufferBlock) GetSpaceId() uint32 {
+func (bb *BufferBlock) GetSpaceId() uint32 {
 	return bb.BufferPage.spaceId
 }
 
-func (bb BufferBlock) GetPageNo() uint32 {
+func (bb *BufferBlock) GetPageNo() uint32 {
 	return bb.BufferPage.pageNo
 }
+
+// SetIndex records that the page is a page of the B+tree of index indexId
+// with dataSize bytes of records.
+func (bb *BufferBlock) SetIndex(indexId uint64, dataSize int) {
+	atomic.StoreUint64(&bb.BufferPage.indexId, indexId)
+	atomic.StoreInt64(&bb.BufferPage.dataSize, int64(dataSize))
+	atomic.StoreUint32(&bb.BufferPage.pageType, pageTypeRecorded|common.FILE_PAGE_INDEX)
+}
+
+// recordPageType records the type of the page in frame. The caller owns
+// the frame: it has just read it, or writes it.
+func (bb *BufferBlock) recordPageType() {
+	if bb.Frame == nil || len(*bb.Frame) < 26 {
+		return
+	}
+	atomic.StoreUint32(&bb.BufferPage.pageType, pageTypeRecorded|uint32(util.ReadUB2Byte2Int((*bb.Frame)[24:26])))
+}
+
+// pageTypeRecorded marks the recorded page types, the pages whose frame
+// is too short to hold one having none.
+const pageTypeRecorded = 1 << 16
+
+// getPageType returns the type of the page last recorded, false when none
+// was.
+func (bb *BufferBlock) getPageType() (uint16, bool) {
+	tp := atomic.LoadUint32(&bb.BufferPage.pageType)
+	return uint16(tp), tp&pageTypeRecorded != 0
+}
+
+// GetIndexId returns the index the page belongs to, 0 when it isn't known.
+func (bb *BufferBlock) GetIndexId() uint64 {
+	return atomic.LoadUint64(&bb.BufferPage.indexId)
+}
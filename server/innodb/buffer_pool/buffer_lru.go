@@ -35,6 +35,21 @@ type LRUCache interface {
 	GetOld(spaceId uint32, pageNo uint32) (*BufferBlock, error)
 
 	Len() uint32
+
+	// Blocks returns the blocks of the cache, the young ones first.
+	Blocks() []*BufferBlock
+
+	// OldBlocks returns the blocks of the old sublist, none until the
+	// cache is large enough to be split.
+	OldBlocks() []*BufferBlock
+
+	// Resize sets the number of blocks the cache holds. The blocks over the
+	// new size are left for EvictOldest.
+	Resize(size int)
+
+	// EvictOldest evicts up to count of the least recently used blocks not
+	// pinned and returns the number evicted.
+	EvictOldest(count int) int
 }
 
 type (
@@ -149,15 +164,15 @@ func (L *LRUCacheImpl) Set(spaceId uint32, pageNo uint32, value *BufferBlock) er
 }
 
 func (L *LRUCacheImpl) Get(spaceId uint32, pageNo uint32) (*BufferBlock, error) {
-	if result, keyNotFoundError := L.GetYoung(spaceId, pageNo); keyNotFoundError == nil {
-		return result, nil
-	}
-	if result, keyNotFoundError := L.GetOld(spaceId, pageNo); keyNotFoundError == nil {
-		return result, nil
-	}
-	if result, keyNotFoundError := L.getOrdinary(spaceId, pageNo); keyNotFoundError == nil {
-		return result, nil
+	var buff = append(util.ConvertUInt4Bytes(spaceId), util.ConvertUInt4Bytes(pageNo)...)
+	hashCode := util.HashCode(buff)
+	for _, get := range []func(uint64, bool) (*BufferBlock, error){L.getYoungValue, L.getOldValue, L.getOrdinaryValue} {
+		if result, keyNotFoundError := get(hashCode, true); keyNotFoundError == nil {
+			L.stats.IncrHitCount()
+			return result, nil
+		}
 	}
+	L.stats.IncrMissCount()
 	return nil, KeyNotFoundError
 }
 
@@ -177,6 +192,10 @@ func (L *LRUCacheImpl) remove(key uint64) bool {
 		L.removeOldElement(ent)
 		return true
 	}
+	if ent, ok := L.items[key]; ok {
+		L.removeElement(ent)
+		return true
+	}
 	return false
 }
 
@@ -199,8 +218,65 @@ func (L *LRUCacheImpl) Purge() {
 
 }
 
+func (L *LRUCacheImpl) Blocks() []*BufferBlock {
+	L.mu.RLock()
+	defer L.mu.RUnlock()
+	blocks := make([]*BufferBlock, 0, len(L.youngItems)+len(L.oldItems)+len(L.items))
+	for _, l := range []*list.List{L.evictYoungList, L.evictOldList, L.evictList} {
+		for e := l.Front(); e != nil; e = e.Next() {
+			blocks = append(blocks, e.Value.(*lruItem).value)
+		}
+	}
+	return blocks
+}
+
+func (L *LRUCacheImpl) OldBlocks() []*BufferBlock {
+	L.mu.RLock()
+	defer L.mu.RUnlock()
+	blocks := make([]*BufferBlock, 0, L.evictOldList.Len())
+	for e := L.evictOldList.Front(); e != nil; e = e.Next() {
+		blocks = append(blocks, e.Value.(*lruItem).value)
+	}
+	return blocks
+}
+
+func (L *LRUCacheImpl) Resize(size int) {
+	L.mu.Lock()
+	defer L.mu.Unlock()
+	L.size = size
+}
+
+func (L *LRUCacheImpl) EvictOldest(count int) int {
+	L.mu.Lock()
+	defer L.mu.Unlock()
+	n := 0
+	for _, l := range []*list.List{L.evictList, L.evictOldList, L.evictYoungList} {
+		for e := l.Back(); n < count && e != nil; {
+			item := e.Value.(*lruItem)
+			e = e.Prev()
+			if item.value.Pinned() {
+				continue
+			}
+			L.remove(item.key)
+			n++
+		}
+	}
+	return n
+}
+
 func (L *LRUCacheImpl) Has(spaceId uint32, pageNo uint32) bool {
-	panic("implement me")
+	L.mu.RLock()
+	defer L.mu.RUnlock()
+	var buff = append(util.ConvertUInt4Bytes(spaceId), util.ConvertUInt4Bytes(pageNo)...)
+	hashCode := util.HashCode(buff)
+	if _, ok := L.youngItems[hashCode]; ok {
+		return true
+	}
+	if _, ok := L.oldItems[hashCode]; ok {
+		return true
+	}
+	_, ok := L.items[hashCode]
+	return ok
 }
 
 //TODO 校验这里的hashcode的安全性
@@ -242,7 +318,7 @@ func (L *LRUCacheImpl) evictYoung(count int) {
 func (c *LRUCacheImpl) removeYoungElement(e *list.Element) {
 	c.evictYoungList.Remove(e)
 	entry := e.Value.(*lruItem)
-	delete(c.items, entry.key)
+	delete(c.youngItems, entry.key)
 	if c.evictedFunc != nil {
 		entry := e.Value.(*lruItem)
 		c.evictedFunc(entry.key, entry.value)
@@ -284,7 +360,7 @@ func (L *LRUCacheImpl) evictOld(count int) {
 func (c *LRUCacheImpl) removeOldElement(e *list.Element) {
 	c.evictOldList.Remove(e)
 	entry := e.Value.(*lruItem)
-	delete(c.items, entry.key)
+	delete(c.oldItems, entry.key)
 	if c.evictedFunc != nil {
 		entry := e.Value.(*lruItem)
 		c.evictedFunc(entry.key, entry.value)
@@ -292,8 +368,6 @@ func (c *LRUCacheImpl) removeOldElement(e *list.Element) {
 }
 
 func (L *LRUCacheImpl) GetYoung(spaceId uint32, pageNo uint32) (*BufferBlock, error) {
-	L.mu.Lock()
-	defer L.mu.Unlock()
 	var buff = append(util.ConvertUInt4Bytes(spaceId), util.ConvertUInt4Bytes(pageNo)...)
 	hashCode := util.HashCode(buff)
 	return L.getYoungValue(hashCode, false)
@@ -366,8 +440,6 @@ func (L *LRUCacheImpl) setOrdinary(spaceId uint32, pageNo uint32, value *BufferB
 
 }
 func (L *LRUCacheImpl) getOrdinary(spaceId uint32, pageNo uint32) (*BufferBlock, error) {
-	L.mu.Lock()
-	defer L.mu.Unlock()
 	var buff = append(util.ConvertUInt4Bytes(spaceId), util.ConvertUInt4Bytes(pageNo)...)
 	hashCode := util.HashCode(buff)
 	return L.getOrdinaryValue(hashCode, false)
@@ -394,13 +466,13 @@ func (L *LRUCacheImpl) getOrdinaryValue(key uint64, onLoad bool) (*BufferBlock,
 }
 
 func (L *LRUCacheImpl) GetOld(spaceId uint32, pageNo uint32) (*BufferBlock, error) {
-	L.mu.Lock()
-	defer L.mu.Unlock()
 	var buff = append(util.ConvertUInt4Bytes(spaceId), util.ConvertUInt4Bytes(pageNo)...)
 	hashCode := util.HashCode(buff)
 	return L.getOldValue(hashCode, false)
 }
 func (L *LRUCacheImpl) Len() uint32 {
+	L.mu.RLock()
+	defer L.mu.RUnlock()
 	if L.evictList.Len() > 0 {
 		return uint32(L.evictList.Len())
 	}
@@ -437,6 +509,7 @@ func NewLRUCacheImpl(size int, youngPercent float64, oldPercent float64, innodbO
 	lrucache.youngPercent = youngPercent
 	lrucache.oldPercent = oldPercent
 	lrucache.innodbOldBlocksTime = innodbOldBlocksTime
+	lrucache.stats = new(stats)
 	return lrucache
 }
 
@@ -25,6 +25,21 @@ type BufferPage struct {
 	oldestModification common.LSNT
 
 	accessTime uint64
+
+	// fixCount is the number of pins on the page, buf_fix_count: a pinned
+	// page isn't evicted.
+	fixCount int32
+
+	// indexId is the index a B+tree page belongs to, set when the tree
+	// reads or creates it, 0 for the other pages. dataSize is the number
+	// of bytes of the records of the page then.
+	indexId  uint64
+	dataSize int64
+
+	// pageType is the FIL_PAGE_TYPE of the frame with pageTypeRecorded,
+	// recorded when the frame is read or written so that it is reported
+	// without reading the frame.
+	pageType uint32
 }
 
 func NewBufferPage(spaceId uint32, pageNo uint32) *BufferPage {
@@ -2,8 +2,11 @@ package buffer_pool
 
 import (
 	"container/list"
+	"github.com/zhukovaskychina/xmysql-server/server/common"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/zhukovaskychina/xmysql-server/util"
 )
@@ -11,33 +14,146 @@ import (
 type BufferPool struct {
 	innodbBufferPoolSize uint64 //字节数量
 
-	lruCache LRUCache
+	// instances 各自缓存一部分页面，见 buffer_pool_instance.go
+	instances []*bufferPoolInstance
 
-	freeBlockList *FreeBlockList //
+	// nextFlush 是下一个取脏页刷新的实例
+	nextFlush uint32
 
-	flushBlockList *FlushBlockList
+	// resize 是在线调整大小的进度，见 buffer_pool_resize.go
+	resize resizeState
 
 	FileSystem basic.FileSystem
+
+	// AHI is the adaptive hash index over the hot B+tree pages of the pool.
+	AHI *AdaptiveHashIndex
+
+	// ChangeBuffer keeps the inserts into the secondary index pages that
+	// aren't in the pool.
+	ChangeBuffer *ChangeBuffer
+
+	// pins 是所有页面上的 pin 的总数，见 buffer_pool_pin.go
+	pins int64
 }
 type FlushToDisk func(system basic.FileSystem, spaceId uint32, pageNo uint32, block BufferBlock)
 
-//TODO 暂时实现一个，后面再有接着实现多个buffer instance
 func NewBufferPool(innodbBufferPoolSize uint64, youngPercent float64, oldPercent float64, innodbOldBlocksTime int, system basic.FileSystem) *BufferPool {
+	return NewBufferPoolInstances(innodbBufferPoolSize, 1, youngPercent, oldPercent, innodbOldBlocksTime, system)
+}
+
+// NewBufferPoolInstances returns a pool of innodbBufferPoolSize bytes split
+// into instances instances, innodb_buffer_pool_instances.
+func NewBufferPoolInstances(innodbBufferPoolSize uint64, instances int, youngPercent float64, oldPercent float64, innodbOldBlocksTime int, system basic.FileSystem) *BufferPool {
+	pages := int(innodbBufferPoolSize / uint64(common.PageSize()))
+	if instances > MaxBufferPoolInstances {
+		instances = MaxBufferPoolInstances
+	}
+	if instances > pages {
+		instances = pages
+	}
+	if instances < 1 {
+		instances = 1
+	}
 	var bufferPool = new(BufferPool)
-	bufferPool.innodbBufferPoolSize = innodbBufferPoolSize
-	bufferPool.lruCache = NewLRUCacheImpl(int(innodbBufferPoolSize/16384), youngPercent, oldPercent, innodbOldBlocksTime)
-	bufferPool.flushBlockList = NewFlushBlockList()
-	bufferPool.freeBlockList = NewFreeBlockList(system)
+	bufferPool.innodbBufferPoolSize = uint64(pages) * uint64(common.PageSize())
 	bufferPool.FileSystem = system
+	bufferPool.AHI = NewAdaptiveHashIndex(DefaultAHIPartitions)
+	bufferPool.ChangeBuffer = NewChangeBuffer(ChangeBufferingAll)
+	for i := 0; i < instances; i++ {
+		bufferPool.instances = append(bufferPool.instances,
+			newBufferPoolInstance(bufferPool, instanceCapacity(pages, instances, i), youngPercent, oldPercent, innodbOldBlocksTime))
+	}
 	return bufferPool
 }
 
 func (bufferPool *BufferPool) GetPageBlock(space uint32, pageNumber uint32) *BufferBlock {
-	bufferBlock := bufferPool.freeBlockList.GetPage(space, pageNumber)
+	instance := bufferPool.instance(space, pageNumber)
+	instance.mu.Lock()
+	defer instance.mu.Unlock()
+	atomic.AddUint64(&instance.readRequests, 1)
+	if bufferBlock, err := instance.lruCache.Get(space, pageNumber); err == nil {
+		return bufferBlock
+	}
+	atomic.AddUint64(&instance.pageReads, 1)
+	bufferBlock := instance.freeBlockList.GetPage(space, pageNumber)
 	bufferBlock.BufferPage.pageState = BUF_BLOCK_READY_FOR_USE
-	bufferPool.lruCache.Set(space, pageNumber, bufferBlock)
+	//读入页面时，合并写缓冲中该页面的插入，页面变成脏页
+	if merged, _ := bufferPool.ChangeBuffer.Merge(bufferBlock); merged {
+		bufferPool.AHI.InvalidatePage(space, pageNumber)
+		instance.flushBlockList.AddBlock(bufferBlock)
+	}
+	bufferBlock.recordPageType()
+	instance.lruCache.Set(space, pageNumber, bufferBlock)
 	return bufferBlock
 }
+
+// HoldsPage reports whether the pool has the page, clean or dirty, or
+// inserts buffered for it.
+func (bufferPool *BufferPool) HoldsPage(space uint32, pageNumber uint32) bool {
+	instance := bufferPool.instance(space, pageNumber)
+	return instance.lruCache.Has(space, pageNumber) ||
+		instance.flushBlockList.Has(space, pageNumber) ||
+		bufferPool.ChangeBuffer.Has(space, pageNumber)
+}
+
+// DiscardPage drops the clean copy of the page, which was written around
+// the pool, so that it is read again.
+func (bufferPool *BufferPool) DiscardPage(space uint32, pageNumber uint32) {
+	bufferPool.AHI.InvalidatePage(space, pageNumber)
+	bufferPool.instance(space, pageNumber).lruCache.Remove(space, pageNumber)
+}
+
+// BufferInsert buffers the insert of record into page pageNumber of a
+// non-unique secondary index when the page isn't in the pool. It returns
+// false when the page must be read and record inserted into it.
+func (bufferPool *BufferPool) BufferInsert(space uint32, pageNumber uint32, index string, record []byte) bool {
+	if bufferPool.instance(space, pageNumber).lruCache.Has(space, pageNumber) {
+		return false
+	}
+	return bufferPool.ChangeBuffer.Buffer(space, pageNumber, index, record)
+}
+
+// MergeChangeBuffer reads up to limit pages with buffered inserts, all of
+// them when limit is 0, which merges their inserts. It returns the number
+// of pages read.
+func (bufferPool *BufferPool) MergeChangeBuffer(limit int) int {
+	pages := bufferPool.ChangeBuffer.pendingPages(0, true, limit)
+	for _, page := range pages {
+		bufferPool.GetPageBlock(page[0], page[1])
+	}
+	return len(pages)
+}
+
+// MergeAllChangeBuffer reads every page with buffered inserts, at shutdown
+// or on demand, so that no insert is left only in the change buffer. It
+// returns the number of inserts merged.
+func (bufferPool *BufferPool) MergeAllChangeBuffer() int {
+	merged := bufferPool.ChangeBuffer.Stats().MergedInserts
+	bufferPool.MergeChangeBuffer(0)
+	return int(bufferPool.ChangeBuffer.Stats().MergedInserts - merged)
+}
+
+// MergeChangeBufferOfSpace reads every page of space with buffered inserts,
+// so that the indexes of the space are complete on disk and in the pool.
+func (bufferPool *BufferPool) MergeChangeBufferOfSpace(space uint32) int {
+	pages := bufferPool.ChangeBuffer.pendingPages(space, false, 0)
+	for _, page := range pages {
+		bufferPool.GetPageBlock(page[0], page[1])
+	}
+	return len(pages)
+}
+
+// InsertBufferStatus returns the section of SHOW ENGINE INNODB STATUS on
+// the change buffer and the adaptive hash index.
+func (bufferPool *BufferPool) InsertBufferStatus() string {
+	var buf strings.Builder
+	buf.WriteString("-------------------------------------\n")
+	buf.WriteString("INSERT BUFFER AND ADAPTIVE HASH INDEX\n")
+	buf.WriteString("-------------------------------------\n")
+	buf.WriteString(bufferPool.ChangeBuffer.Status())
+	buf.WriteString(bufferPool.AHI.Status())
+	return buf.String()
+}
 func (bufferPool *BufferPool) RangePageLoad(space uint32, pageNumberStart, pageNumberEnd uint32) {
 	for i := pageNumberStart; i < pageNumberEnd; i++ {
 		bufferPool.GetPageBlock(space, i)
@@ -49,14 +165,13 @@ func (bufferPool *BufferPool) GetDirtyPageBlock(space uint32, pageNumber uint32)
 	return nil
 }
 
-func (bufferPool *BufferPool) GetFlushDiskList() *FlushBlockList {
-	return bufferPool.flushBlockList
-}
-
 //更新脏页面
 func (bufferPool *BufferPool) UpdateBlock(space uint32, pageNumber uint32, block *BufferBlock) {
-	bufferPool.lruCache.Remove(space, pageNumber)
-	bufferPool.flushBlockList.AddBlock(block)
+	block.recordPageType()
+	bufferPool.AHI.InvalidatePage(space, pageNumber)
+	instance := bufferPool.instance(space, pageNumber)
+	instance.lruCache.Remove(space, pageNumber)
+	instance.flushBlockList.AddBlock(block)
 }
 
 type FreeBlockList struct {
@@ -128,6 +243,38 @@ func (flb *FlushBlockList) AddBlock(block *BufferBlock) {
 	flb.list.PushFront(block)
 }
 
+// Has reports whether a dirty page of the list is the page pageNo of space
+// spaceId.
+func (flb *FlushBlockList) Has(spaceId uint32, pageNo uint32) bool {
+	flb.mu.RLock()
+	defer flb.mu.RUnlock()
+	for e := flb.list.Front(); e != nil; e = e.Next() {
+		block := e.Value.(*BufferBlock)
+		if block.GetSpaceId() == spaceId && block.GetPageNo() == pageNo {
+			return true
+		}
+	}
+	return false
+}
+
+// Blocks returns the dirty pages of the list, the latest first.
+func (flb *FlushBlockList) Blocks() []*BufferBlock {
+	flb.mu.RLock()
+	defer flb.mu.RUnlock()
+	blocks := make([]*BufferBlock, 0, flb.list.Len())
+	for e := flb.list.Front(); e != nil; e = e.Next() {
+		blocks = append(blocks, e.Value.(*BufferBlock))
+	}
+	return blocks
+}
+
+// Len returns the number of dirty pages of the list.
+func (flb *FlushBlockList) Len() int {
+	flb.mu.RLock()
+	defer flb.mu.RUnlock()
+	return flb.list.Len()
+}
+
 func (flb *FlushBlockList) IsEmpty() bool {
 	return flb.list.Len() == 0
 }
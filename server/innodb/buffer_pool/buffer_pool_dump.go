@@ -0,0 +1,131 @@
+package buffer_pool
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+/**
+缓冲池预热
+
+关闭时把缓冲池中的页面按 LRU 顺序（先 young 区再 old 区）写入 ib_buffer_pool 文件，
+每行一个 "space_id,page_no"，和 MySQL 的格式相同；脏页也算在内。
+
+启动时在后台按文件中的顺序读入这些页面，已经在缓冲池中的页面和表空间已不存在的
+页面跳过，不阻塞服务启动。
+**/
+
+// DefaultDumpFilename is the name of the file the pool is dumped to,
+// innodb_buffer_pool_filename.
+const DefaultDumpFilename = "ib_buffer_pool"
+
+// PageID identifies a page of the pool.
+type PageID struct {
+	SpaceId uint32
+	PageNo  uint32
+}
+
+// ResidentPages returns the pages of the pool, the hot ones first.
+func (bufferPool *BufferPool) ResidentPages() []PageID {
+	seen := make(map[PageID]bool)
+	var pages []PageID
+	add := func(block *BufferBlock) {
+		id := PageID{block.GetSpaceId(), block.GetPageNo()}
+		if !seen[id] {
+			seen[id] = true
+			pages = append(pages, id)
+		}
+	}
+	for _, instance := range bufferPool.instances {
+		for _, block := range instance.flushBlockList.Blocks() {
+			add(block)
+		}
+	}
+	for _, instance := range bufferPool.instances {
+		for _, block := range instance.lruCache.Blocks() {
+			add(block)
+		}
+	}
+	return pages
+}
+
+// Dump writes the pages of the pool to path and returns their number. The
+// file is replaced only once it is complete.
+func (bufferPool *BufferPool) Dump(path string) (int, error) {
+	pages := bufferPool.ResidentPages()
+	var buf strings.Builder
+	for _, page := range pages {
+		fmt.Fprintf(&buf, "%d,%d\n", page.SpaceId, page.PageNo)
+	}
+	tmp := path + ".incomplete"
+	if err := ioutil.WriteFile(tmp, []byte(buf.String()), 0644); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return 0, err
+	}
+	return len(pages), nil
+}
+
+// ReadDumpFile reads the pages of a file written by Dump.
+func ReadDumpFile(path string) ([]PageID, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var pages []PageID
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		fields := strings.Split(text, ",")
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expect space_id,page_no, got %q", path, line, text)
+		}
+		space, err := strconv.ParseUint(fields[0], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+		pageNo, err := strconv.ParseUint(fields[1], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+		pages = append(pages, PageID{uint32(space), uint32(pageNo)})
+	}
+	return pages, scanner.Err()
+}
+
+// Load reads the pages dumped to path into the pool, up to the capacity of
+// the pool, and returns the number of pages read.
+func (bufferPool *BufferPool) Load(path string) (int, error) {
+	pages, err := ReadDumpFile(path)
+	if err != nil {
+		return 0, err
+	}
+	capacity := bufferPool.Stats().PoolSize
+	if len(pages) > capacity {
+		pages = pages[:capacity]
+	}
+	// 先读冷的页面，热的页面最后读入，留在 LRU 的头部
+	n := 0
+	for i := len(pages) - 1; i >= 0; i-- {
+		page := pages[i]
+		if bufferPool.HoldsPage(page.SpaceId, page.PageNo) {
+			continue
+		}
+		if bufferPool.FileSystem == nil || bufferPool.FileSystem.GetTableSpaceById(page.SpaceId) == nil {
+			continue
+		}
+		bufferPool.GetPageBlock(page.SpaceId, page.PageNo)
+		n++
+	}
+	return n, nil
+}
@@ -0,0 +1,69 @@
+package buffer_pool
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestBufferPoolDump(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ib_buffer_pool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, DefaultDumpFilename)
+
+	pool := NewBufferPool(16*16384, 0.75, 0.25, 1000, &testFileSystem{})
+	for _, page := range []uint32{3, 4, 5} {
+		pool.GetPageBlock(1, page)
+	}
+	dirty := pool.GetPageBlock(2, 7)
+	pool.UpdateBlock(2, 7, dirty)
+	n, err := pool.Dump(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages, err := ReadDumpFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []PageID{{2, 7}, {1, 5}, {1, 4}, {1, 3}}
+	if n != 4 || !reflect.DeepEqual(pages, expect) {
+		t.Fatalf("expect %v dumped, got %d %v", expect, n, pages)
+	}
+
+	// A fresh pool reads the dumped pages back, except the one it has.
+	fs := &testFileSystem{}
+	restarted := NewBufferPool(16*16384, 0.75, 0.25, 1000, fs)
+	restarted.GetPageBlock(1, 4)
+	if n, err = restarted.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 || fs.reads != 4 {
+		t.Fatalf("expect 3 pages prefetched, got %d after %d reads", n, fs.reads)
+	}
+	for _, page := range expect {
+		if !restarted.HoldsPage(page.SpaceId, page.PageNo) {
+			t.Fatalf("expect page %v in the pool", page)
+		}
+	}
+
+	// Only as many pages as the pool holds are read.
+	small := NewBufferPool(2*16384, 0.75, 0.25, 1000, &testFileSystem{})
+	if n, _ = small.Load(path); n != 2 || !small.HoldsPage(2, 7) || small.HoldsPage(1, 4) {
+		t.Fatalf("expect the 2 hottest pages prefetched, got %d", n)
+	}
+
+	if err = ioutil.WriteFile(path, []byte("1,2\nbroken\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ReadDumpFile(path); err == nil {
+		t.Fatal("expect an error reading a malformed dump")
+	}
+	if _, err = restarted.Load(filepath.Join(dir, "missing")); !os.IsNotExist(err) {
+		t.Fatalf("expect a missing file, got %v", err)
+	}
+}
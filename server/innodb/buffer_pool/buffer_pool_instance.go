@@ -0,0 +1,222 @@
+package buffer_pool
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+/**
+缓冲池实例
+
+缓冲池分成 innodb_buffer_pool_instances 个实例，每个页面按 (space_id, page_no) 的哈希
+固定属于其中一个实例。每个实例有自己的 LRU 链表、空闲链表、脏页链表和互斥锁，
+读入、淘汰和刷新页面只锁住页面所在的实例，访问不同实例的页面互不阻塞。
+自适应哈希索引和写缓冲仍由所有实例共用，它们有自己的锁。
+**/
+
+// MaxBufferPoolInstances is the largest innodb_buffer_pool_instances.
+const MaxBufferPoolInstances = 64
+
+type bufferPoolInstance struct {
+	// mu 保证同一个页面不会被同时读入两次
+	mu sync.Mutex
+
+	lruCache LRUCache
+
+	freeBlockList *FreeBlockList
+
+	flushBlockList *FlushBlockList
+
+	// capacity 是实例最多缓存的页面数，调整缓冲池大小时改变
+	capacity int64
+
+	readRequests, pageReads uint64
+}
+
+func newBufferPoolInstance(bufferPool *BufferPool, capacity int, youngPercent float64, oldPercent float64, innodbOldBlocksTime int) *bufferPoolInstance {
+	instance := &bufferPoolInstance{
+		lruCache:       NewLRUCacheImpl(capacity, youngPercent, oldPercent, innodbOldBlocksTime),
+		freeBlockList:  NewFreeBlockList(bufferPool.FileSystem),
+		flushBlockList: NewFlushBlockList(),
+		capacity:       int64(capacity),
+	}
+	if lru, ok := instance.lruCache.(*LRUCacheImpl); ok {
+		// 页面被淘汰后，哈希索引中指向它的记录失效
+		lru.evictedFunc = func(key interface{}, value interface{}) {
+			block := value.(*BufferBlock)
+			bufferPool.AHI.InvalidatePage(block.GetSpaceId(), block.GetPageNo())
+		}
+	}
+	return instance
+}
+
+// instanceCapacity returns the number of pages instance i of instances
+// holds in a pool of pages pages: the pages are split evenly, the first
+// instances taking what is left over.
+func instanceCapacity(pages int, instances int, i int) int {
+	capacity := pages / instances
+	if i < pages%instances {
+		capacity++
+	}
+	return capacity
+}
+
+// instanceOf returns the instance, among n, page pageNo of space belongs to.
+func instanceOf(space uint32, pageNo uint32, n int) int {
+	h := uint64(space)<<32 | uint64(pageNo)
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	return int(h % uint64(n))
+}
+
+// InstanceOf returns the number of the instance page pageNo of space
+// belongs to. A page always belongs to the same instance.
+func (bufferPool *BufferPool) InstanceOf(space uint32, pageNo uint32) int {
+	return instanceOf(space, pageNo, len(bufferPool.instances))
+}
+
+func (bufferPool *BufferPool) instance(space uint32, pageNo uint32) *bufferPoolInstance {
+	return bufferPool.instances[bufferPool.InstanceOf(space, pageNo)]
+}
+
+// Instances returns the number of instances of the pool.
+func (bufferPool *BufferPool) Instances() int {
+	return len(bufferPool.instances)
+}
+
+// GetLastDirtyBlock takes the oldest dirty page of an instance out of its
+// flush list, from the instances in turn. It returns nil when no page is
+// dirty.
+func (bufferPool *BufferPool) GetLastDirtyBlock() *BufferBlock {
+	n := len(bufferPool.instances)
+	start := int(atomic.AddUint32(&bufferPool.nextFlush, 1))
+	for i := 0; i < n; i++ {
+		if block := bufferPool.instances[(start+i)%n].flushBlockList.GetLastBlock(); block != nil {
+			return block
+		}
+	}
+	return nil
+}
+
+// FlushDirtyPages writes the dirty pages of all the instances to their
+// spaces, at shutdown, and returns their number.
+func (bufferPool *BufferPool) FlushDirtyPages() int {
+	n := 0
+	for block := bufferPool.GetLastDirtyBlock(); block != nil; block = bufferPool.GetLastDirtyBlock() {
+		if bufferPool.FlushBlock(block) {
+			n++
+		}
+	}
+	return n
+}
+
+// FlushBlock writes the dirty page of block to its space. The pages of a
+// space dropped since they were changed are discarded: it reports whether
+// the page was written.
+func (bufferPool *BufferPool) FlushBlock(block *BufferBlock) bool {
+	if bufferPool.FileSystem == nil {
+		return false
+	}
+	space := bufferPool.FileSystem.GetTableSpaceById(block.GetSpaceId())
+	if space == nil {
+		return false
+	}
+	space.FlushToDisk(block.GetPageNo(), *block.GetFrame())
+	return true
+}
+
+// BufferPoolStats are the counters of an instance of the pool, or their
+// sums over the instances.
+type BufferPoolStats struct {
+	// PoolSize is the number of pages the pool holds at most.
+	PoolSize int
+	// DatabasePages is the number of clean pages in the LRU lists.
+	DatabasePages int
+	// ModifiedPages is the number of dirty pages waiting to be flushed.
+	ModifiedPages int
+	// ReadRequests is the number of pages asked for, PageReads the number
+	// of those not in the pool and read into it.
+	ReadRequests uint64
+	PageReads    uint64
+}
+
+// FreePages is the number of pages the pool can still take.
+func (stats BufferPoolStats) FreePages() int {
+	if free := stats.PoolSize - stats.DatabasePages - stats.ModifiedPages; free > 0 {
+		return free
+	}
+	return 0
+}
+
+// HitRate is the share of the pages asked for found in the pool, 1 before
+// any is asked for.
+func (stats BufferPoolStats) HitRate() float64 {
+	if stats.ReadRequests == 0 {
+		return 1
+	}
+	return 1 - float64(stats.PageReads)/float64(stats.ReadRequests)
+}
+
+func (instance *bufferPoolInstance) stats() BufferPoolStats {
+	return BufferPoolStats{
+		PoolSize:      int(atomic.LoadInt64(&instance.capacity)),
+		DatabasePages: int(instance.lruCache.Len()),
+		ModifiedPages: instance.flushBlockList.Len(),
+		ReadRequests:  atomic.LoadUint64(&instance.readRequests),
+		PageReads:     atomic.LoadUint64(&instance.pageReads),
+	}
+}
+
+// InstanceStats returns the counters of each instance of the pool.
+func (bufferPool *BufferPool) InstanceStats() []BufferPoolStats {
+	stats := make([]BufferPoolStats, len(bufferPool.instances))
+	for i, instance := range bufferPool.instances {
+		stats[i] = instance.stats()
+	}
+	return stats
+}
+
+// Stats returns the counters of the pool, summed over its instances.
+func (bufferPool *BufferPool) Stats() BufferPoolStats {
+	var total BufferPoolStats
+	for _, stats := range bufferPool.InstanceStats() {
+		total.PoolSize += stats.PoolSize
+		total.DatabasePages += stats.DatabasePages
+		total.ModifiedPages += stats.ModifiedPages
+		total.ReadRequests += stats.ReadRequests
+		total.PageReads += stats.PageReads
+	}
+	return total
+}
+
+func (stats BufferPoolStats) write(buf *strings.Builder) {
+	fmt.Fprintf(buf, "Buffer pool size   %d\n", stats.PoolSize)
+	fmt.Fprintf(buf, "Database pages     %d\n", stats.DatabasePages)
+	fmt.Fprintf(buf, "Modified db pages  %d\n", stats.ModifiedPages)
+	fmt.Fprintf(buf, "Pages read %d\n", stats.PageReads)
+	fmt.Fprintf(buf, "Buffer pool hit rate %d / 1000\n", int(stats.HitRate()*1000))
+}
+
+// BufferPoolStatus returns the section of SHOW ENGINE INNODB STATUS on the
+// buffer pool, followed by the counters of each instance when there are
+// several.
+func (bufferPool *BufferPool) BufferPoolStatus() string {
+	var buf strings.Builder
+	buf.WriteString("----------------------\n")
+	buf.WriteString("BUFFER POOL AND MEMORY\n")
+	buf.WriteString("----------------------\n")
+	bufferPool.Stats().write(&buf)
+	if len(bufferPool.instances) > 1 {
+		buf.WriteString("----------------------\n")
+		buf.WriteString("INDIVIDUAL BUFFER POOL INFO\n")
+		buf.WriteString("----------------------\n")
+		for i, stats := range bufferPool.InstanceStats() {
+			fmt.Fprintf(&buf, "---BUFFER POOL %d\n", i)
+			stats.write(&buf)
+		}
+	}
+	return buf.String()
+}
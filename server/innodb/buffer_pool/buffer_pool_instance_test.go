@@ -0,0 +1,182 @@
+package buffer_pool
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+)
+
+// concurrentFileSystem is a file system safe for concurrent reads.
+type concurrentFileSystem struct {
+	reads uint64
+}
+
+func (fs *concurrentFileSystem) AddTableSpace(ts basic.FileTableSpace) {}
+
+func (fs *concurrentFileSystem) GetTableSpaceById(spaceId uint32) basic.FileTableSpace {
+	return concurrentTableSpace{fs: fs, spaceId: spaceId}
+}
+
+type concurrentTableSpace struct {
+	fs      *concurrentFileSystem
+	spaceId uint32
+}
+
+func (ts concurrentTableSpace) FlushToDisk(pageNo uint32, content []byte) {}
+
+func (ts concurrentTableSpace) LoadPageByPageNumber(pageNo uint32) ([]byte, error) {
+	atomic.AddUint64(&ts.fs.reads, 1)
+	return []byte{byte(pageNo)}, nil
+}
+
+func (ts concurrentTableSpace) GetSpaceId() uint32 {
+	return ts.spaceId
+}
+
+func TestBufferPoolInstances(t *testing.T) {
+	pool := NewBufferPoolInstances(64*16384, 8, 0.75, 0.25, 1000, &concurrentFileSystem{})
+	if pool.Instances() != 8 || pool.Stats().PoolSize != 64 {
+		t.Fatalf("expect 8 instances of 64 pages, got %d of %d", pool.Instances(), pool.Stats().PoolSize)
+	}
+	other := NewBufferPoolInstances(64*16384, 8, 0.75, 0.25, 1000, &concurrentFileSystem{})
+	used := make(map[int]int)
+	for page := uint32(0); page < 1000; page++ {
+		i := pool.InstanceOf(3, page)
+		if i != pool.InstanceOf(3, page) || i != other.InstanceOf(3, page) {
+			t.Fatalf("expect page %d always in instance %d", page, i)
+		}
+		used[i]++
+	}
+	for i := 0; i < 8; i++ {
+		if used[i] < 50 {
+			t.Fatalf("expect the pages spread over the instances, got %v", used)
+		}
+	}
+
+	for page := uint32(0); page < 16; page++ {
+		pool.GetPageBlock(3, page)
+	}
+	dirty := pool.GetPageBlock(3, 20)
+	pool.UpdateBlock(3, 20, dirty)
+	stats := pool.Stats()
+	if stats.PageReads != 17 || stats.DatabasePages != 16 || stats.ModifiedPages != 1 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	perInstance := pool.InstanceStats()
+	if perInstance[pool.InstanceOf(3, 20)].ModifiedPages != 1 {
+		t.Fatalf("expect the dirty page counted in its instance, got %+v", perInstance)
+	}
+	if !pool.HoldsPage(3, 5) || !pool.HoldsPage(3, 20) || pool.HoldsPage(3, 30) {
+		t.Fatal("expect the pages read to be held")
+	}
+	if pool.GetLastDirtyBlock() != dirty || pool.GetLastDirtyBlock() != nil {
+		t.Fatal("expect the dirty page taken once")
+	}
+	if status := pool.BufferPoolStatus(); !strings.Contains(status, "---BUFFER POOL 7\n") ||
+		!strings.Contains(status, "Buffer pool size   64\n") {
+		t.Fatalf("unexpected status %s", status)
+	}
+
+	// No more instances than pages.
+	if small := NewBufferPoolInstances(2*16384, 8, 0.75, 0.25, 1000, nil); small.Instances() != 2 {
+		t.Fatalf("expect 2 instances, got %d", small.Instances())
+	}
+}
+
+func TestBufferPoolHitRate(t *testing.T) {
+	fs := &concurrentFileSystem{}
+	pool := NewBufferPoolInstances(64*16384, 2, 0.75, 0.25, 1000, fs)
+	for i := 0; i < 4; i++ {
+		for page := uint32(0); page < 4; page++ {
+			pool.GetPageBlock(3, page)
+		}
+	}
+	stats := pool.Stats()
+	if stats.ReadRequests != 16 || stats.PageReads != 4 || fs.reads != 4 {
+		t.Fatalf("expect the pages read once, got %+v after %d reads", stats, fs.reads)
+	}
+	if stats.HitRate() != 0.75 || stats.FreePages() != 60 {
+		t.Fatalf("expect hit rate 0.75 and 60 free pages, got %v and %d", stats.HitRate(), stats.FreePages())
+	}
+	if status := pool.BufferPoolStatus(); !strings.Contains(status, "Buffer pool hit rate 750 / 1000\n") {
+		t.Fatalf("unexpected status %s", status)
+	}
+}
+
+func TestBufferPoolInstancesDontContend(t *testing.T) {
+	pool := NewBufferPoolInstances(64*16384, 8, 0.75, 0.25, 1000, &concurrentFileSystem{})
+	var busy, free uint32
+	for page := uint32(1); pool.InstanceOf(1, busy) == pool.InstanceOf(1, free); page++ {
+		free = page
+	}
+	locked := pool.instance(1, busy)
+	locked.mu.Lock()
+
+	done := make(chan struct{})
+	go func() {
+		pool.GetPageBlock(1, free)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expect a page of another instance read while an instance is locked")
+	}
+
+	done = make(chan struct{})
+	go func() {
+		pool.GetPageBlock(1, busy)
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("expect a page of the locked instance to wait")
+	case <-time.After(50 * time.Millisecond):
+	}
+	locked.mu.Unlock()
+	<-done
+}
+
+func TestBufferPoolInstancesConcurrency(t *testing.T) {
+	fs := &concurrentFileSystem{}
+	pool := NewBufferPoolInstances(256*16384, 8, 0.75, 0.25, 1000, fs)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for page := uint32(0); page < 200; page++ {
+				block := pool.GetPageBlock(uint32(g), page)
+				if block.GetSpaceId() != uint32(g) || block.GetPageNo() != page {
+					t.Errorf("expect page %d of space %d, got %d of %d", page, g, block.GetPageNo(), block.GetSpaceId())
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	if stats := pool.Stats(); stats.PageReads != 1600 || fs.reads != 1600 || stats.DatabasePages > stats.PoolSize {
+		t.Fatalf("unexpected stats %+v after %d reads", stats, fs.reads)
+	}
+}
+
+func BenchmarkBufferPoolInstances(b *testing.B) {
+	for _, instances := range []int{1, 8} {
+		b.Run(fmt.Sprintf("instances=%d", instances), func(b *testing.B) {
+			pool := NewBufferPoolInstances(1024*16384, instances, 0.75, 0.25, 1000, &concurrentFileSystem{})
+			var next uint32
+			b.RunParallel(func(pb *testing.PB) {
+				space := atomic.AddUint32(&next, 1)
+				page := uint32(0)
+				for pb.Next() {
+					pool.GetPageBlock(space, page%64)
+					page++
+				}
+			})
+		})
+	}
+}
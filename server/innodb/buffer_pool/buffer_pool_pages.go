@@ -0,0 +1,121 @@
+package buffer_pool
+
+import (
+	"sync/atomic"
+
+	"github.com/zhukovaskychina/xmysql-server/server/common"
+)
+
+/**
+缓冲池中的页面
+
+PageInfos 列出缓冲池中的每个页面：所在的实例、表空间和页号、页面类型、属于哪个索引、
+记录占用的字节数，以及是否是脏页、是否在 LRU 的 old 区。B+ 树读入或新建页面时在
+页面的控制体里记下索引的 ID（SetIndex），表和索引的名字由调用方按 ID 从数据字典中查。
+页面类型在读入页面和写回页面（UpdateBlock）时记在控制体里，生成报告时不读页面本身，
+不会和正在修改页面的线程冲突。
+
+逐个实例生成：锁住一个实例，取出它的 LRU 链表和脏页链表中的页面，记下各页的信息后
+就释放，再看下一个实例。读入页面只在生成它所在实例的那一小段时间里等待，
+不会被整个报告阻塞；各实例不是同一时刻的快照。
+**/
+
+// PageInfo describes a page of the pool, a row of
+// information_schema.INNODB_BUFFER_PAGE.
+type PageInfo struct {
+	// PoolId is the instance of the pool holding the page.
+	PoolId  int
+	SpaceId uint32
+	PageNo  uint32
+	// PageType is the name of the type of the page, INDEX for the pages of
+	// the B+trees.
+	PageType string
+	// IndexId is the index the page belongs to, 0 when it isn't known.
+	// DataSize is the number of bytes of its records.
+	IndexId  uint64
+	DataSize int
+	// Dirty reports whether the page waits to be flushed, Old whether it
+	// is in the old sublist of the LRU list.
+	Dirty bool
+	Old   bool
+}
+
+// pageTypeNames are the names of the page types, as MySQL reports them.
+var pageTypeNames = map[uint16]string{
+	common.FILE_PAGE_TYPE_ALLOCATED: "ALLOCATED",
+	common.FILE_PAGE_UNDO_LOG:       "UNDO_LOG",
+	common.FILE_PAGE_INODE:          "INODE",
+	common.FILE_PAGE_BUF_FREE_LIST:  "IBUF_FREE_LIST",
+	common.FILE_PAGE_IBUF_BITMAP:    "IBUF_BITMAP",
+	common.FILE_PAGE_TYPE_SYS:       "SYSTEM",
+	common.FILE_PAGE_TYPE_TRX_SYS:   "TRX_SYSTEM",
+	common.FILE_PAGE_TYPE_FSP_HDR:   "FILE_SPACE_HEADER",
+	common.FILE_PAGE_TYPE_XDES:      "EXTENT_DESCRIPTOR",
+	common.FILE_PAGE_TYPE_BLOB:      "BLOB",
+	common.FILE_PAGE_INDEX:          "INDEX",
+}
+
+// pageTypeName returns the name of the type of the page of block, as it
+// was recorded when the page was read or written: the frame itself is
+// only read by the holders of the page.
+func pageTypeName(block *BufferBlock) string {
+	if tp, ok := block.getPageType(); ok {
+		if name, ok := pageTypeNames[tp]; ok {
+			return name
+		}
+	}
+	return "UNKNOWN"
+}
+
+func pageInfo(poolId int, block *BufferBlock, dirty bool, old bool) PageInfo {
+	return PageInfo{
+		PoolId:   poolId,
+		SpaceId:  block.GetSpaceId(),
+		PageNo:   block.GetPageNo(),
+		PageType: pageTypeName(block),
+		IndexId:  block.GetIndexId(),
+		DataSize: int(atomic.LoadInt64(&block.BufferPage.dataSize)),
+		Dirty:    dirty,
+		Old:      old,
+	}
+}
+
+// pageInfos returns the pages of the instance, the dirty ones first,
+// holding its mutex while it reads them.
+func (instance *bufferPoolInstance) pageInfos(poolId int) []PageInfo {
+	instance.mu.Lock()
+	defer instance.mu.Unlock()
+	dirty := instance.flushBlockList.Blocks()
+	clean := instance.lruCache.Blocks()
+	old := make(map[*BufferBlock]bool)
+	for _, block := range instance.lruCache.OldBlocks() {
+		old[block] = true
+	}
+	seen := make(map[PageID]bool, len(dirty)+len(clean))
+	infos := make([]PageInfo, 0, len(dirty)+len(clean))
+	for _, block := range dirty {
+		id := PageID{block.GetSpaceId(), block.GetPageNo()}
+		if !seen[id] {
+			seen[id] = true
+			infos = append(infos, pageInfo(poolId, block, true, old[block]))
+		}
+	}
+	for _, block := range clean {
+		id := PageID{block.GetSpaceId(), block.GetPageNo()}
+		if !seen[id] {
+			seen[id] = true
+			infos = append(infos, pageInfo(poolId, block, false, old[block]))
+		}
+	}
+	return infos
+}
+
+// PageInfos returns the pages of the pool, instance by instance, locking
+// each instance only while its pages are read.
+func (bufferPool *BufferPool) PageInfos() []PageInfo {
+	var infos []PageInfo
+	for i, instance := range bufferPool.instances {
+		infos = append(infos, instance.pageInfos(i)...)
+	}
+	return infos
+}
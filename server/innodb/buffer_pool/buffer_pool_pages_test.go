@@ -0,0 +1,69 @@
+package buffer_pool
+
+import (
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/common"
+	"github.com/zhukovaskychina/xmysql-server/util"
+)
+
+func TestBufferPoolPageInfos(t *testing.T) {
+	pool := NewBufferPoolInstances(2048*16384, 1, 0.75, 0.25, 1000, &testFileSystem{})
+	for page := uint32(0); page < 600; page++ {
+		pool.GetPageBlock(1, page)
+	}
+	block := pool.GetPageBlock(2, 7)
+	frame := make([]byte, 64)
+	copy(frame[24:26], util.ConvertUInt2Bytes(common.FILE_PAGE_INDEX))
+	block.Frame = &frame
+	block.SetIndex(42, 100)
+	pool.UpdateBlock(2, 7, block)
+
+	infos := pool.PageInfos()
+	stats := pool.Stats()
+	if len(infos) != stats.DatabasePages+stats.ModifiedPages {
+		t.Fatalf("expect %d pages, got %d", stats.DatabasePages+stats.ModifiedPages, len(infos))
+	}
+	var old, young int
+	for _, info := range infos {
+		if info.PoolId != pool.InstanceOf(info.SpaceId, info.PageNo) {
+			t.Fatalf("expect page %d:%d in instance %d, got %d", info.SpaceId, info.PageNo,
+				pool.InstanceOf(info.SpaceId, info.PageNo), info.PoolId)
+		}
+		if info.SpaceId == 2 {
+			expect := PageInfo{PoolId: info.PoolId, SpaceId: 2, PageNo: 7, PageType: "INDEX", IndexId: 42, DataSize: 100, Dirty: true}
+			if info != expect {
+				t.Fatalf("expect %+v, got %+v", expect, info)
+			}
+			continue
+		}
+		if info.Dirty || info.IndexId != 0 || info.PageType != "UNKNOWN" {
+			t.Fatalf("expect a clean page of no index, got %+v", info)
+		}
+		if info.Old {
+			old++
+		} else {
+			young++
+		}
+	}
+	// The LRU list is split once it holds 512 pages.
+	if old == 0 || young == 0 {
+		t.Fatalf("expect old and young pages, got %d old and %d young", old, young)
+	}
+
+	// The report doesn't read the frames the writers replace.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			frame := make([]byte, 64)
+			copy(frame[24:26], util.ConvertUInt2Bytes(common.FILE_PAGE_INDEX))
+			block.Frame = &frame
+			pool.UpdateBlock(2, 7, block)
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		pool.PageInfos()
+	}
+	<-done
+}
@@ -0,0 +1,44 @@
+package buffer_pool
+
+import (
+	"sync/atomic"
+
+	log "github.com/sirupsen/logrus"
+)
+
+/**
+页面的 pin
+
+执行器扫描表或索引时 pin 住当前行所在的页面，页面在 unpin 之前不会被淘汰，调整缓冲池
+大小时也不会被收回。每个 PinPage 必须有对应的 UnpinPage，否则页面一直留在缓冲池中，
+泄漏的页面多了缓冲池就会被占满；PinnedPages 返回当前 pin 的总数，用来检查扫描结束
+之后是否都已经释放。
+**/
+
+// PinPage reads the page like GetPageBlock and pins it until UnpinPage.
+func (bufferPool *BufferPool) PinPage(space uint32, pageNumber uint32) *BufferBlock {
+	block := bufferPool.GetPageBlock(space, pageNumber)
+	atomic.AddInt32(&block.BufferPage.fixCount, 1)
+	atomic.AddInt64(&bufferPool.pins, 1)
+	return block
+}
+
+// UnpinPage releases a pin of PinPage on block.
+func (bufferPool *BufferPool) UnpinPage(block *BufferBlock) {
+	if atomic.AddInt32(&block.BufferPage.fixCount, -1) < 0 {
+		atomic.AddInt32(&block.BufferPage.fixCount, 1)
+		log.Warnf("页面 (%d, %d) 没有被 pin", block.GetSpaceId(), block.GetPageNo())
+		return
+	}
+	atomic.AddInt64(&bufferPool.pins, -1)
+}
+
+// PinnedPages returns the number of pins held on the pages of the pool.
+func (bufferPool *BufferPool) PinnedPages() int64 {
+	return atomic.LoadInt64(&bufferPool.pins)
+}
+
+// Pinned reports whether the block is pinned.
+func (bb *BufferBlock) Pinned() bool {
+	return bb != nil && atomic.LoadInt32(&bb.BufferPage.fixCount) > 0
+}
@@ -0,0 +1,26 @@
+package buffer_pool
+
+import "testing"
+
+func TestPinPage(t *testing.T) {
+	pool := NewBufferPoolInstances(16*16384, 1, 0.75, 0.25, 1000, &concurrentFileSystem{})
+	block := pool.PinPage(1, 0)
+	for page := uint32(1); page < 16; page++ {
+		pool.GetPageBlock(1, page)
+	}
+	if pool.PinnedPages() != 1 || !block.Pinned() {
+		t.Fatalf("expect the page pinned, got %d pins", pool.PinnedPages())
+	}
+
+	// Shrinking the pool evicts every page but the pinned one.
+	<-pool.Resize(1 * 16384)
+	if !pool.HoldsPage(1, 0) {
+		t.Fatal("expect the pinned page kept")
+	}
+	pool.UnpinPage(block)
+	// A page unpinned twice doesn't take the count below zero.
+	pool.UnpinPage(block)
+	if pool.PinnedPages() != 0 || block.Pinned() {
+		t.Fatalf("expect no pins left, got %d", pool.PinnedPages())
+	}
+}
@@ -0,0 +1,148 @@
+package buffer_pool
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zhukovaskychina/xmysql-server/server/common"
+)
+
+/**
+在线调整缓冲池大小
+
+新的容量立即平均分给各个实例，Stats 马上报告新的容量。扩大后各实例的 LRU 链表可以
+容纳更多页面，空闲链表按需读入页面；缩小时后台从各实例 LRU 链表的尾部淘汰干净页面，
+干净页面不够时把脏页刷盘后移出。每次只锁住一个实例处理一批（ResizeChunkPages 个）页面，
+期间读取页面照常进行，页面数逐渐降到新的容量。
+
+缩小还没有完成时再次调整大小，正在进行的调整放弃，按新的大小重新开始；设回原来的
+大小即取消缩小。进度见 ResizeStatus，即 innodb_buffer_pool_resize_status。
+**/
+
+// ResizeChunkPages is the number of pages withdrawn from an instance at a
+// time while shrinking, holding its mutex.
+const ResizeChunkPages = 16
+
+type resizeState struct {
+	mu     sync.Mutex
+	status string
+	// generation 每次调整加一，旧的调整发现它变了就停止
+	generation uint64
+}
+
+// Size returns the size of the pool in bytes.
+func (bufferPool *BufferPool) Size() uint64 {
+	return atomic.LoadUint64(&bufferPool.innodbBufferPoolSize)
+}
+
+// ResizeStatus returns the progress of the last resize, empty when the
+// pool was never resized.
+func (bufferPool *BufferPool) ResizeStatus() string {
+	bufferPool.resize.mu.Lock()
+	defer bufferPool.resize.mu.Unlock()
+	return bufferPool.resize.status
+}
+
+func (bufferPool *BufferPool) setResizeStatus(generation uint64, format string, args ...interface{}) {
+	bufferPool.resize.mu.Lock()
+	defer bufferPool.resize.mu.Unlock()
+	if bufferPool.resize.generation == generation {
+		bufferPool.resize.status = fmt.Sprintf(format, args...)
+	}
+}
+
+// Resize sets the size of the pool to size bytes, rounded down to whole
+// pages and to at least a page per instance. The new capacity takes effect
+// at once; the pages over it are withdrawn in the background, and the
+// channel returned is closed once they are or a later Resize takes over.
+func (bufferPool *BufferPool) Resize(size uint64) <-chan struct{} {
+	pages := int(size / uint64(common.PageSize()))
+	if pages < len(bufferPool.instances) {
+		pages = len(bufferPool.instances)
+	}
+	old := bufferPool.Stats().PoolSize
+	atomic.StoreUint64(&bufferPool.innodbBufferPoolSize, uint64(pages)*uint64(common.PageSize()))
+	for i, instance := range bufferPool.instances {
+		capacity := instanceCapacity(pages, len(bufferPool.instances), i)
+		atomic.StoreInt64(&instance.capacity, int64(capacity))
+		instance.lruCache.Resize(capacity)
+	}
+
+	bufferPool.resize.mu.Lock()
+	bufferPool.resize.generation++
+	generation := bufferPool.resize.generation
+	bufferPool.resize.status = fmt.Sprintf("Resizing buffer pool from %d to %d (unit=%d).", old, pages, common.PageSize())
+	bufferPool.resize.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		bufferPool.withdraw(generation)
+	}()
+	return done
+}
+
+// withdraw evicts or flushes the pages of each instance over its capacity,
+// a chunk at a time, until there are none or resize generation is over.
+func (bufferPool *BufferPool) withdraw(generation uint64) {
+	total := bufferPool.excessPages()
+	withdrawn := 0
+	for withdrawn < total {
+		n := 0
+		for _, instance := range bufferPool.instances {
+			if atomic.LoadUint64(&bufferPool.resize.generation) != generation {
+				return
+			}
+			n += bufferPool.withdrawChunk(instance)
+		}
+		if n == 0 {
+			break
+		}
+		withdrawn += n
+		bufferPool.setResizeStatus(generation, "Withdrawing blocks to be shrunken. (%d/%d)", withdrawn, total)
+	}
+	bufferPool.setResizeStatus(generation, "Completed resizing buffer pool at %s.", time.Now().Format("060102 15:04:05"))
+}
+
+// excessPages returns the number of pages held over the capacity of the
+// instances.
+func (bufferPool *BufferPool) excessPages() int {
+	excess := 0
+	for _, instance := range bufferPool.instances {
+		excess += instance.excessPages()
+	}
+	return excess
+}
+
+func (instance *bufferPoolInstance) excessPages() int {
+	excess := int(instance.lruCache.Len()) + instance.flushBlockList.Len() - int(atomic.LoadInt64(&instance.capacity))
+	if excess < 0 {
+		return 0
+	}
+	return excess
+}
+
+// withdrawChunk evicts up to ResizeChunkPages pages of instance over its
+// capacity, the clean pages at the tail of the LRU list first, then the
+// oldest dirty pages once flushed.
+func (bufferPool *BufferPool) withdrawChunk(instance *bufferPoolInstance) int {
+	instance.mu.Lock()
+	defer instance.mu.Unlock()
+	n := 0
+	for n < ResizeChunkPages && instance.excessPages() > 0 {
+		if instance.lruCache.EvictOldest(1) == 1 {
+			n++
+			continue
+		}
+		block := instance.flushBlockList.GetLastBlock()
+		if block == nil {
+			break
+		}
+		bufferPool.FlushBlock(block)
+		bufferPool.AHI.InvalidatePage(block.GetSpaceId(), block.GetPageNo())
+		n++
+	}
+	return n
+}
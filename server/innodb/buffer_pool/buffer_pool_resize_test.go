@@ -0,0 +1,85 @@
+package buffer_pool
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestBufferPoolResize(t *testing.T) {
+	pool := NewBufferPoolInstances(64*16384, 4, 0.75, 0.25, 1000, &concurrentFileSystem{})
+	for page := uint32(0); page < 200; page++ {
+		pool.GetPageBlock(1, page)
+	}
+	for page := uint32(0); page < 2; page++ {
+		pool.UpdateBlock(1, page, pool.GetPageBlock(1, page))
+	}
+	before := pool.Stats()
+	if before.DatabasePages+before.ModifiedPages <= 16 {
+		t.Fatalf("expect the pool filled, got %+v", before)
+	}
+
+	// Shrinking reports the new capacity at once while pages are read.
+	done := pool.Resize(16 * 16384)
+	if stats := pool.Stats(); stats.PoolSize != 16 || pool.Size() != 16*16384 {
+		t.Fatalf("expect a capacity of 16 pages, got %+v", stats)
+	}
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for page := uint32(0); page < 100; page++ {
+				pool.GetPageBlock(uint32(2+g), page)
+			}
+		}(g)
+	}
+	wg.Wait()
+	<-done
+	if stats := pool.Stats(); stats.DatabasePages > 16 {
+		t.Fatalf("expect at most 16 clean pages left, got %+v", stats)
+	}
+	if !strings.HasPrefix(pool.ResizeStatus(), "Completed resizing buffer pool at ") {
+		t.Fatalf("unexpected status %s", pool.ResizeStatus())
+	}
+
+	// Once the clean pages are gone the dirty ones are flushed.
+	<-pool.Resize(4 * 16384)
+	if stats := pool.Stats(); stats.DatabasePages+stats.ModifiedPages > 4 {
+		t.Fatalf("expect at most 4 pages left, got %+v", stats)
+	}
+
+	// Growing lets the instances hold more pages.
+	<-pool.Resize(128 * 16384)
+	for page := uint32(0); page < 200; page++ {
+		pool.GetPageBlock(6, page)
+	}
+	if stats := pool.Stats(); stats.PoolSize != 128 || stats.DatabasePages <= 16 {
+		t.Fatalf("expect the pool grown, got %+v", stats)
+	}
+}
+
+func TestBufferPoolResizeAbort(t *testing.T) {
+	pool := NewBufferPoolInstances(64*16384, 4, 0.75, 0.25, 1000, &concurrentFileSystem{})
+	for page := uint32(0); page < 200; page++ {
+		pool.GetPageBlock(1, page)
+	}
+	before := pool.Stats()
+
+	// Setting the size back before the shrink gets anywhere cancels it.
+	pool.instances[0].mu.Lock()
+	shrink := pool.Resize(16 * 16384)
+	if !strings.HasPrefix(pool.ResizeStatus(), "Resizing buffer pool from 64 to 16") {
+		t.Fatalf("unexpected status %s", pool.ResizeStatus())
+	}
+	back := pool.Resize(64 * 16384)
+	pool.instances[0].mu.Unlock()
+	<-shrink
+	<-back
+	if stats := pool.Stats(); stats.PoolSize != 64 || stats.DatabasePages != before.DatabasePages {
+		t.Fatalf("expect the pages kept, got %+v instead of %+v", stats, before)
+	}
+	if !strings.HasPrefix(pool.ResizeStatus(), "Completed resizing buffer pool at ") {
+		t.Fatalf("unexpected status %s", pool.ResizeStatus())
+	}
+}
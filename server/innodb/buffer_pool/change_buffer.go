@@ -0,0 +1,252 @@
+package buffer_pool
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+/**
+写缓冲（change buffer，即 insert buffer）
+
+向非唯一二级索引插入记录时，如果目标叶子页面不在缓冲池中，不去读取该页面，
+而是把这条插入记到写缓冲中，按页面归集。之后：
+	页面被读入缓冲池时，先合并该页面上缓冲的插入
+	后台在空闲时按批读入有缓冲的页面并合并
+	CHECK TABLE 之前合并表空间的全部缓冲，保证校验看到完整的索引
+	关闭时合并全部缓冲并刷新脏页，写缓冲只在内存中，不合并重启后会丢失这些插入；
+	SET GLOBAL innodb_change_buffer_merge_now = ON 也立即合并全部缓冲
+
+innodb_change_buffering 控制缓冲哪些操作，这里只缓冲插入：
+none、deletes、purges 不缓冲，inserts、changes、all 缓冲插入。
+**/
+
+// ChangeBuffering is the value of innodb_change_buffering.
+type ChangeBuffering int32
+
+// Values of innodb_change_buffering.
+const (
+	ChangeBufferingNone ChangeBuffering = iota
+	ChangeBufferingInserts
+	ChangeBufferingDeletes
+	ChangeBufferingChanges
+	ChangeBufferingPurges
+	ChangeBufferingAll
+)
+
+var changeBufferingNames = []string{"none", "inserts", "deletes", "changes", "purges", "all"}
+
+func (b ChangeBuffering) String() string {
+	return changeBufferingNames[b]
+}
+
+// BuffersInserts reports whether inserts are buffered.
+func (b ChangeBuffering) BuffersInserts() bool {
+	return b == ChangeBufferingInserts || b == ChangeBufferingChanges || b == ChangeBufferingAll
+}
+
+// ParseChangeBuffering returns the value of innodb_change_buffering called
+// name, in any case.
+func ParseChangeBuffering(name string) (ChangeBuffering, error) {
+	for i, bName := range changeBufferingNames {
+		if strings.EqualFold(name, bName) {
+			return ChangeBuffering(i), nil
+		}
+	}
+	return 0, errors.Errorf("unknown innodb_change_buffering %s", name)
+}
+
+// ChangeBufferMerger applies the buffered records of an index to frame,
+// one of its leaf pages, and returns the new content of the page.
+type ChangeBufferMerger func(frame []byte, records [][]byte) ([]byte, error)
+
+// ChangeBufferStats are the counters of a change buffer.
+type ChangeBufferStats struct {
+	// Size is the number of buffered operations and Pages the number of
+	// pages they go to.
+	Size  int
+	Pages int
+	// Inserts counts the inserts buffered and MergedInserts the ones merged.
+	Inserts       uint64
+	MergedInserts uint64
+	// Merges counts the pages merged.
+	Merges uint64
+}
+
+// ChangeBuffer keeps the inserts into the non-resident leaf pages of the
+// secondary indexes until their pages are read.
+type ChangeBuffer struct {
+	mode int32
+	// active is set when an insert is buffered and cleared by Idle.
+	active int32
+
+	mu sync.Mutex
+	// pages are the buffered inserts by page.
+	pages   map[uint64]*ibufPage
+	mergers map[string]ChangeBufferMerger
+
+	inserts, mergedInserts, merges uint64
+}
+
+// ibufPage is the inserts buffered for a page, in the order they came.
+type ibufPage struct {
+	spaceId uint32
+	pageNo  uint32
+	index   string
+	records [][]byte
+}
+
+// NewChangeBuffer returns an empty change buffer buffering what mode says.
+func NewChangeBuffer(mode ChangeBuffering) *ChangeBuffer {
+	return &ChangeBuffer{
+		mode:    int32(mode),
+		pages:   make(map[uint64]*ibufPage),
+		mergers: make(map[string]ChangeBufferMerger),
+	}
+}
+
+// Mode returns what the change buffer buffers.
+func (cb *ChangeBuffer) Mode() ChangeBuffering {
+	return ChangeBuffering(atomic.LoadInt32(&cb.mode))
+}
+
+// SetMode changes what the change buffer buffers, innodb_change_buffering.
+// What is already buffered stays until it is merged.
+func (cb *ChangeBuffer) SetMode(mode ChangeBuffering) {
+	atomic.StoreInt32(&cb.mode, int32(mode))
+}
+
+// RegisterMerger makes merger apply the inserts buffered for the index of
+// the space.
+func (cb *ChangeBuffer) RegisterMerger(spaceId uint32, index string, merger ChangeBufferMerger) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.mergers[ibufIndexKey(spaceId, index)] = merger
+}
+
+// Buffer records the insert of record into page pageNo of the index of the
+// space. It returns false when inserts aren't buffered or the index has no
+// merger, and the page must be read.
+func (cb *ChangeBuffer) Buffer(spaceId uint32, pageNo uint32, index string, record []byte) bool {
+	if !cb.Mode().BuffersInserts() {
+		return false
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if _, ok := cb.mergers[ibufIndexKey(spaceId, index)]; !ok {
+		return false
+	}
+	key := ahiPageKey(spaceId, pageNo)
+	page, ok := cb.pages[key]
+	if !ok {
+		page = &ibufPage{spaceId: spaceId, pageNo: pageNo, index: index}
+		cb.pages[key] = page
+	}
+	page.records = append(page.records, record)
+	atomic.AddUint64(&cb.inserts, 1)
+	atomic.StoreInt32(&cb.active, 1)
+	return true
+}
+
+// Idle reports whether nothing was buffered since it was last called.
+func (cb *ChangeBuffer) Idle() bool {
+	return atomic.SwapInt32(&cb.active, 0) == 0
+}
+
+// Has reports whether inserts are buffered for the page.
+func (cb *ChangeBuffer) Has(spaceId uint32, pageNo uint32) bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	_, ok := cb.pages[ahiPageKey(spaceId, pageNo)]
+	return ok
+}
+
+// Merge applies the inserts buffered for the page of block to its frame.
+// It reports whether there were any.
+func (cb *ChangeBuffer) Merge(block *BufferBlock) (bool, error) {
+	key := ahiPageKey(block.GetSpaceId(), block.GetPageNo())
+	cb.mu.Lock()
+	page, ok := cb.pages[key]
+	if !ok {
+		cb.mu.Unlock()
+		return false, nil
+	}
+	merger := cb.mergers[ibufIndexKey(page.spaceId, page.index)]
+	delete(cb.pages, key)
+	cb.mu.Unlock()
+
+	frame, err := merger(*block.Frame, page.records)
+	if err != nil {
+		// Keep the inserts for the next read of the page.
+		cb.mu.Lock()
+		if later, ok := cb.pages[key]; ok {
+			page.records = append(page.records, later.records...)
+		}
+		cb.pages[key] = page
+		cb.mu.Unlock()
+		return false, err
+	}
+	block.Frame = &frame
+	atomic.AddUint64(&cb.mergedInserts, uint64(len(page.records)))
+	atomic.AddUint64(&cb.merges, 1)
+	return true, nil
+}
+
+// pendingPages returns up to limit pages with buffered inserts, all of them
+// when limit is 0, in page order. Only the pages of spaceId are returned
+// unless all is set.
+func (cb *ChangeBuffer) pendingPages(spaceId uint32, all bool, limit int) [][2]uint32 {
+	cb.mu.Lock()
+	var keys []uint64
+	for key, page := range cb.pages {
+		if all || page.spaceId == spaceId {
+			keys = append(keys, key)
+		}
+	}
+	cb.mu.Unlock()
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	if limit > 0 && len(keys) > limit {
+		keys = keys[:limit]
+	}
+	pages := make([][2]uint32, 0, len(keys))
+	for _, key := range keys {
+		pages = append(pages, [2]uint32{uint32(key >> 32), uint32(key)})
+	}
+	return pages
+}
+
+// Stats returns the counters of the change buffer.
+func (cb *ChangeBuffer) Stats() ChangeBufferStats {
+	stats := ChangeBufferStats{
+		Inserts:       atomic.LoadUint64(&cb.inserts),
+		MergedInserts: atomic.LoadUint64(&cb.mergedInserts),
+		Merges:        atomic.LoadUint64(&cb.merges),
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	stats.Pages = len(cb.pages)
+	for _, page := range cb.pages {
+		stats.Size += len(page.records)
+	}
+	return stats
+}
+
+// Status returns the lines of SHOW ENGINE INNODB STATUS on the change
+// buffer.
+func (cb *ChangeBuffer) Status() string {
+	stats := cb.Stats()
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "Ibuf: size %d, %d pages, %d merges, buffering %s\n", stats.Size, stats.Pages, stats.Merges, cb.Mode())
+	buf.WriteString("merged operations:\n")
+	fmt.Fprintf(&buf, " insert %d, delete mark 0, delete 0\n", stats.MergedInserts)
+	fmt.Fprintf(&buf, "buffered operations:\n insert %d\n", stats.Inserts)
+	return buf.String()
+}
+
+func ibufIndexKey(spaceId uint32, index string) string {
+	return fmt.Sprintf("%d/%s", spaceId, index)
+}
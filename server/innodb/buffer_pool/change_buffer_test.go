@@ -0,0 +1,159 @@
+package buffer_pool
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+)
+
+// testFileSystem has spaces whose pages are filled with their number until
+// they are written.
+type testFileSystem struct {
+	reads   int
+	written map[[2]uint32][]byte
+}
+
+func (fs *testFileSystem) AddTableSpace(ts basic.FileTableSpace) {}
+
+func (fs *testFileSystem) GetTableSpaceById(spaceId uint32) basic.FileTableSpace {
+	return testTableSpace{fs: fs, spaceId: spaceId}
+}
+
+type testTableSpace struct {
+	fs      *testFileSystem
+	spaceId uint32
+}
+
+func (ts testTableSpace) FlushToDisk(pageNo uint32, content []byte) {
+	if ts.fs.written == nil {
+		ts.fs.written = make(map[[2]uint32][]byte)
+	}
+	ts.fs.written[[2]uint32{ts.spaceId, pageNo}] = append([]byte(nil), content...)
+}
+
+func (ts testTableSpace) LoadPageByPageNumber(pageNo uint32) ([]byte, error) {
+	ts.fs.reads++
+	if content, ok := ts.fs.written[[2]uint32{ts.spaceId, pageNo}]; ok {
+		return append([]byte(nil), content...), nil
+	}
+	return []byte{byte(pageNo)}, nil
+}
+
+func (ts testTableSpace) GetSpaceId() uint32 {
+	return ts.spaceId
+}
+
+// appendMerger appends the records to the page.
+func appendMerger(frame []byte, records [][]byte) ([]byte, error) {
+	for _, record := range records {
+		frame = append(frame, record...)
+	}
+	return frame, nil
+}
+
+func TestChangeBuffer(t *testing.T) {
+	fs := &testFileSystem{}
+	pool := NewBufferPool(16*16384, 0.75, 0.25, 1000, fs)
+	pool.ChangeBuffer.RegisterMerger(1, "idx", appendMerger)
+
+	// A resident page takes the insert itself.
+	pool.GetPageBlock(1, 3)
+	if pool.BufferInsert(1, 3, "idx", []byte("a")) {
+		t.Fatal("expect no buffering for a page in the pool")
+	}
+	if pool.BufferInsert(1, 4, "other", []byte("a")) {
+		t.Fatal("expect no buffering for an index without a merger")
+	}
+	for _, record := range []string{"a", "b"} {
+		if !pool.BufferInsert(1, 4, "idx", []byte(record)) {
+			t.Fatalf("expect %s to be buffered", record)
+		}
+	}
+	pool.BufferInsert(1, 5, "idx", []byte("c"))
+	if stats := pool.ChangeBuffer.Stats(); stats.Size != 3 || stats.Pages != 2 || stats.Inserts != 3 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	// Reading the page merges its inserts and makes it dirty.
+	block := pool.GetPageBlock(1, 4)
+	if string(*block.Frame) != "\x04ab" {
+		t.Fatalf("unexpected page %q", *block.Frame)
+	}
+	if pool.GetLastDirtyBlock() != block {
+		t.Fatal("expect the merged page to be dirty")
+	}
+	if stats := pool.ChangeBuffer.Stats(); stats.Size != 1 || stats.MergedInserts != 2 || stats.Merges != 1 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	// The background merge reads the other pages when nothing is buffered
+	// for a while; CHECK TABLE merges all those of the space.
+	if pool.ChangeBuffer.Idle() || !pool.ChangeBuffer.Idle() {
+		t.Fatal("expect the change buffer to be idle after the inserts")
+	}
+	pool.ChangeBuffer.RegisterMerger(2, "idx", appendMerger)
+	pool.ChangeBuffer.Buffer(2, 9, "idx", []byte("d"))
+	if pool.ChangeBuffer.Idle() {
+		t.Fatal("expect a new insert to count as activity")
+	}
+	if n := pool.MergeChangeBufferOfSpace(1); n != 1 || pool.ChangeBuffer.Has(1, 5) {
+		t.Fatalf("expect page 5 merged, got %d pages", n)
+	}
+	if n := pool.MergeChangeBuffer(10); n != 1 || pool.ChangeBuffer.Stats().Size != 0 {
+		t.Fatalf("expect page 9 merged, got %d pages", n)
+	}
+
+	// innodb_change_buffering=none stops buffering.
+	mode, err := ParseChangeBuffering("NONE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.ChangeBuffer.SetMode(mode)
+	if pool.BufferInsert(1, 6, "idx", []byte("e")) {
+		t.Fatal("expect no buffering when it is off")
+	}
+	if _, err = ParseChangeBuffering("sometimes"); err == nil {
+		t.Fatal("expect an error for an unknown value")
+	}
+	status := pool.InsertBufferStatus()
+	if !strings.Contains(status, "Ibuf: size 0, 0 pages, 3 merges, buffering none") ||
+		!strings.Contains(status, " insert 4, delete mark 0, delete 0") ||
+		!strings.Contains(status, "Adaptive hash index ON") {
+		t.Fatalf("unexpected status %s", status)
+	}
+}
+
+func TestChangeBufferMergeAll(t *testing.T) {
+	fs := &testFileSystem{}
+	pool := NewBufferPool(16*16384, 0.75, 0.25, 1000, fs)
+	pool.ChangeBuffer.RegisterMerger(1, "idx", appendMerger)
+	for _, insert := range []struct {
+		page   uint32
+		record string
+	}{{4, "a"}, {4, "b"}, {5, "c"}} {
+		if !pool.BufferInsert(1, insert.page, "idx", []byte(insert.record)) {
+			t.Fatalf("expect %s to be buffered", insert.record)
+		}
+	}
+
+	// Shutting down merges every insert and writes the pages.
+	if n := pool.MergeAllChangeBuffer(); n != 3 || pool.ChangeBuffer.Stats().Size != 0 {
+		t.Fatalf("expect 3 inserts merged, got %d", n)
+	}
+	if n := pool.FlushDirtyPages(); n != 2 || pool.GetLastDirtyBlock() != nil {
+		t.Fatalf("expect 2 pages written, got %d", n)
+	}
+	if n := pool.MergeAllChangeBuffer(); n != 0 {
+		t.Fatalf("expect nothing left to merge, got %d", n)
+	}
+
+	// The index has the inserts after a restart, with an empty change buffer.
+	restarted := NewBufferPool(16*16384, 0.75, 0.25, 1000, fs)
+	restarted.ChangeBuffer.RegisterMerger(1, "idx", appendMerger)
+	for page, expect := range map[uint32]string{4: "\x04ab", 5: "\x05c"} {
+		if got := string(*restarted.GetPageBlock(1, page).Frame); got != expect {
+			t.Errorf("page %d: expect %q, got %q", page, expect, got)
+		}
+	}
+}
@@ -22,9 +22,11 @@ import (
 	_ "github.com/sirupsen/logrus"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/expression"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/charset"
 
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
-	_ "github.com/zhukovaskychina/xmysql-server/server/mysql"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
 )
 
 // adjustColumnInfoInAddColumn is used to set the correct position of column info when adding column.
@@ -447,23 +449,50 @@ func (d *ddl) doModifyColumn(t *meta.Meta, job *model.Job, newCol *model.ColumnI
 		job.State = model.JobCancelled
 		return ver, schemas.ErrColumnNotExists.GenByArgs(oldName, tblInfo.Name)
 	}
+	if err = checkModifyElems(oldCol, newCol); err != nil {
+		job.State = model.JobCancelled
+		return ver, errors.Trace(err)
+	}
+	if err = ModifyTableColumn(tblInfo, newCol, *oldName, pos); err != nil {
+		job.State = model.JobCancelled
+		return ver, errors.Trace(err)
+	}
+
+	originalState := job.SchemaState
+	job.SchemaState = model.StatePublic
+	ver, err = updateTableInfo(t, job, tblInfo, originalState)
+	if err != nil {
+		job.State = model.JobCancelled
+		return ver, errors.Trace(err)
+	}
 
+	job.State = model.JobDone
+	job.BinlogInfo.AddTableInfo(ver, tblInfo)
+	return ver, nil
+}
+
+// ModifyTableColumn replaces the column oldName of tblInfo with newCol,
+// moved to pos, and updates the offsets and names of the columns of the
+// indices.
+func ModifyTableColumn(tblInfo *model.TableInfo, newCol *model.ColumnInfo, oldName model.CIStr, pos *ast.ColumnPosition) error {
+	oldCol := findCol(tblInfo.Columns, oldName.L)
+	if oldCol == nil || oldCol.State != model.StatePublic {
+		return schemas.ErrColumnNotExists.GenByArgs(oldName, tblInfo.Name)
+	}
 	// We need the latest column's offset and state. This information can be obtained from the storebytes.
 	newCol.Offset = oldCol.Offset
 	newCol.State = oldCol.State
 	// Calculate column's new position.
 	oldPos, newPos := oldCol.Offset, oldCol.Offset
-	if pos.Tp == ast.ColumnPositionAfter {
+	if pos != nil && pos.Tp == ast.ColumnPositionAfter {
 		if oldName.L == pos.RelativeColumn.Name.L {
-			// `alter table tableName modify column b int after b` will return ver,ErrColumnNotExists.
-			job.State = model.JobCancelled
-			return ver, schemas.ErrColumnNotExists.GenByArgs(oldName, tblInfo.Name)
+			// `alter table tableName modify column b int after b` will return ErrColumnNotExists.
+			return schemas.ErrColumnNotExists.GenByArgs(oldName, tblInfo.Name)
 		}
 
 		relative := findCol(tblInfo.Columns, pos.RelativeColumn.Name.L)
 		if relative == nil || relative.State != model.StatePublic {
-			job.State = model.JobCancelled
-			return ver, schemas.ErrColumnNotExists.GenByArgs(pos.RelativeColumn, tblInfo.Name)
+			return schemas.ErrColumnNotExists.GenByArgs(pos.RelativeColumn, tblInfo.Name)
 		}
 
 		if relative.Offset < oldPos {
@@ -471,7 +500,7 @@ func (d *ddl) doModifyColumn(t *meta.Meta, job *model.Job, newCol *model.ColumnI
 		} else {
 			newPos = relative.Offset
 		}
-	} else if pos.Tp == ast.ColumnPositionFirst {
+	} else if pos != nil && pos.Tp == ast.ColumnPositionFirst {
 		newPos = 0
 	}
 
@@ -508,18 +537,247 @@ func (d *ddl) doModifyColumn(t *meta.Meta, job *model.Job, newCol *model.ColumnI
 			}
 		}
 	}
+	return nil
+}
 
-	originalState := job.SchemaState
-	job.SchemaState = model.StatePublic
-	ver, err = updateTableInfo(t, job, tblInfo, originalState)
-	if err != nil {
-		job.State = model.JobCancelled
-		return ver, errors.Trace(err)
+// DropTableColumn removes the column colName from tblInfo, from the indices
+// on it with others and with the indices on it alone, and updates the
+// offsets of the columns after it. A column needed by a foreign key or a
+// generated column, or the only column of the table, can't be dropped.
+func DropTableColumn(tblInfo *model.TableInfo, colName model.CIStr) error {
+	col := findCol(tblInfo.Columns, colName.L)
+	if col == nil || col.State != model.StatePublic {
+		return ErrCantDropFieldOrKey.Gen("column %s doesn't exist", colName)
+	}
+	for _, fk := range tblInfo.ForeignKeys {
+		for _, c := range fk.Cols {
+			if c.L == colName.L {
+				return ErrFkColumnCannotDrop.GenByArgs(col.Name.O, fk.Name.O)
+			}
+		}
+	}
+	for _, c := range tblInfo.Columns {
+		if _, ok := c.Dependences[colName.L]; ok {
+			return errDependentByGeneratedColumn.GenByArgs(colName)
+		}
+	}
+	if len(tblInfo.Columns) == 1 {
+		return ErrCantRemoveAllFields.Gen("can't drop only column %s in table %s", colName, tblInfo.Name)
+	}
+	indices := make([]*model.IndexInfo, 0, len(tblInfo.Indices))
+	for _, idx := range tblInfo.Indices {
+		// As MySQL does, the column leaves the indices on others too; the
+		// caller checks the rows for duplicates of the keys left.
+		idxCols := make([]*model.IndexColumn, 0, len(idx.Columns))
+		for _, c := range idx.Columns {
+			if c.Name.L != colName.L {
+				idxCols = append(idxCols, c)
+			}
+		}
+		if len(idxCols) > 0 {
+			idx.Columns = idxCols
+			indices = append(indices, idx)
+		}
 	}
 
-	job.State = model.JobDone
-	job.BinlogInfo.AddTableInfo(ver, tblInfo)
-	return ver, nil
+	cols := make([]*model.ColumnInfo, 0, len(tblInfo.Columns)-1)
+	for _, c := range tblInfo.Columns {
+		if c != col {
+			c.Offset = len(cols)
+			cols = append(cols, c)
+		}
+	}
+	tblInfo.Columns, tblInfo.Indices = cols, indices
+	for _, idx := range tblInfo.Indices {
+		for _, c := range idx.Columns {
+			c.Offset = findCol(cols, c.Name.L).Offset
+		}
+	}
+	if tblInfo.PKIsHandle && mysql.HasPriKeyFlag(col.Flag) {
+		tblInfo.PKIsHandle = false
+	}
+	return nil
+}
+
+// ModifiedColumn returns the column originalColName of tblInfo as the
+// MODIFY COLUMN or CHANGE COLUMN spec defines it, keeping its id, offset
+// and index flags. Its type may change in any way, the rows being converted
+// to it; making it AUTO_INCREMENT or generated isn't supported.
+func ModifiedColumn(ctx context.Context, tblInfo *model.TableInfo, originalColName model.CIStr, spec *ast.AlterTableSpec) (*model.ColumnInfo, error) {
+	col := findCol(tblInfo.Columns, originalColName.L)
+	if col == nil {
+		return nil, schemas.ErrColumnNotExists.GenByArgs(originalColName, tblInfo.Name)
+	}
+	// Constraints in the new column means adding new constraints. Errors should thrown,
+	// which will be done by `setDefaultAndComment` later.
+	if spec.NewColumn.Tp == nil {
+		// Make sure the column definition is simple field type.
+		return nil, errors.Trace(errUnsupportedModifyColumn.GenByArgs("without a type"))
+	}
+	if newName := spec.NewColumn.Name.Name; newName.L != col.Name.L && findCol(tblInfo.Columns, newName.L) != nil {
+		return nil, schemas.ErrColumnExists.GenByArgs(newName)
+	}
+
+	newCol := schemas.ToColumn(&model.ColumnInfo{
+		ID:                 col.ID,
+		Offset:             col.Offset,
+		State:              col.State,
+		OriginDefaultValue: col.OriginDefaultValue,
+		FieldType:          *spec.NewColumn.Tp,
+		Name:               spec.NewColumn.Name.Name,
+	})
+	if err := setCharsetCollationFlenDecimal(&newCol.FieldType); err != nil {
+		return nil, errors.Trace(err)
+	}
+	// Strings without a character set take the one of the table.
+	if spec.NewColumn.Tp.Charset == "" && newCol.Charset != charset.CharsetBin && tblInfo.Charset != "" {
+		newCol.Charset, newCol.Collate = tblInfo.Charset, tblInfo.Collate
+	}
+	if err := setDefaultAndComment(ctx, newCol, spec.NewColumn.Options); err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	// Copy index related options to the new spec.
+	indexFlags := col.FieldType.Flag & (mysql.PriKeyFlag | mysql.UniqueKeyFlag | mysql.MultipleKeyFlag)
+	newCol.FieldType.Flag |= indexFlags
+	if mysql.HasPriKeyFlag(col.FieldType.Flag) {
+		newCol.FieldType.Flag |= mysql.NotNullFlag
+		if tblInfo.PKIsHandle && !mysql.IsIntegerType(newCol.Tp) {
+			return nil, errUnsupportedModifyColumn.GenByArgs("type of the integer primary key")
+		}
+	}
+	if newCol.Charset == charset.CharsetBin {
+		newCol.Flag |= mysql.BinaryFlag
+	}
+
+	// We don't support modifying column from not_auto_increment to auto_increment.
+	if !mysql.HasAutoIncrementFlag(col.Flag) && mysql.HasAutoIncrementFlag(newCol.Flag) {
+		return nil, errUnsupportedModifyColumn.GenByArgs("set auto_increment")
+	}
+	if col.IsGenerated() {
+		return nil, errUnsupportedOnGeneratedColumn.GenByArgs("Changing the definition of a generated column")
+	}
+	// The indices on the column must still be able to index it.
+	for _, idx := range tblInfo.Indices {
+		for _, ic := range idx.Columns {
+			if ic.Name.L != col.Name.L {
+				continue
+			}
+			if newCol.Tp == mysql.TypeJSON {
+				return nil, errors.Trace(errJSONUsedAsKey.GenByArgs(newCol.Name.O))
+			}
+			if types.IsTypeBlob(newCol.Tp) && ic.Length == types.UnspecifiedLength {
+				return nil, errors.Trace(errBlobKeyWithoutLength)
+			}
+		}
+	}
+	return newCol.ToInfo(), nil
+}
+
+// setDefaultAndComment sets the options of a column ModifiedColumn changes.
+func setDefaultAndComment(ctx context.Context, col *schemas.Column, options []*ast.ColumnOption) error {
+	if len(options) == 0 {
+		return nil
+	}
+	var hasDefaultValue, setOnUpdateNow bool
+	for _, opt := range options {
+		switch opt.Tp {
+		case ast.ColumnOptionDefaultValue:
+			value, err := getDefaultValue(ctx, opt, col.Tp, col.Decimal)
+			if err != nil {
+				return ErrColumnBadNull.Gen("invalid default value - %s", err)
+			}
+			if err = checkColumnCantHaveDefaultValue(col, value); err != nil {
+				return errors.Trace(err)
+			}
+			col.DefaultValue = value
+			hasDefaultValue = true
+		case ast.ColumnOptionComment:
+			value, err := expression.EvalAstExpr(opt.Expr, ctx)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			if col.Comment, err = value.ToString(); err != nil {
+				return errors.Trace(err)
+			}
+		case ast.ColumnOptionNotNull:
+			col.Flag |= mysql.NotNullFlag
+		case ast.ColumnOptionNull:
+			col.Flag &= ^mysql.NotNullFlag
+		case ast.ColumnOptionAutoIncrement:
+			col.Flag |= mysql.AutoIncrementFlag
+		case ast.ColumnOptionOnUpdate:
+			// TODO: Support other time functions.
+			if !expression.IsCurrentTimestampExpr(opt.Expr) {
+				return ErrInvalidOnUpdate.Gen("invalid ON UPDATE for - %s", col.Name)
+			}
+			col.Flag |= mysql.OnUpdateNowFlag
+			setOnUpdateNow = true
+		default:
+			// Keys and generated columns can't be added by a modification.
+			return errors.Trace(errUnsupportedModifyColumn.GenByArgs("constraint"))
+		}
+	}
+
+	setTimestampDefaultValue(col, hasDefaultValue, setOnUpdateNow)
+
+	// Set `NoDefaultValueFlag` if this field doesn't have a default value and
+	// it is `not null` and not an `AUTO_INCREMENT` field or `TIMESTAMP` field.
+	setNoDefaultValueFlag(col, hasDefaultValue)
+
+	if hasDefaultValue {
+		return errors.Trace(checkDefaultValue(ctx, col, true))
+	}
+	return nil
+}
+
+// ModifyColumnRebuilds reports whether changing oldCol to newCol rewrites
+// the rows of the table, as MySQL does. Renames, defaults, comments,
+// NULL-ability, integer display widths, members appended to an ENUM or SET
+// and longer VARCHARs whose length still takes as many bytes in the record
+// only change the metadata; other changes of type, length, sign or
+// character set convert every value.
+func ModifyColumnRebuilds(oldCol, newCol *model.ColumnInfo) bool {
+	if oldCol.Tp != newCol.Tp || oldCol.Charset != newCol.Charset || oldCol.Collate != newCol.Collate ||
+		mysql.HasUnsignedFlag(oldCol.Flag) != mysql.HasUnsignedFlag(newCol.Flag) {
+		return true
+	}
+	switch oldCol.Tp {
+	case mysql.TypeTiny, mysql.TypeShort, mysql.TypeInt24, mysql.TypeLong, mysql.TypeLonglong, mysql.TypeYear:
+		return false
+	case mysql.TypeVarchar, mysql.TypeVarString:
+		// The length of a value takes 1 byte up to 255 bytes, else 2.
+		oldBytes := oldCol.Flen * charset.GetMaxBytesPerChar(oldCol.Charset)
+		newBytes := newCol.Flen * charset.GetMaxBytesPerChar(newCol.Charset)
+		return newCol.Flen < oldCol.Flen || (oldBytes > 255) != (newBytes > 255)
+	case mysql.TypeEnum, mysql.TypeSet:
+		return checkModifyElems(oldCol, newCol) != nil
+	case mysql.TypeTinyBlob, mysql.TypeBlob, mysql.TypeMediumBlob, mysql.TypeLongBlob, mysql.TypeJSON:
+		return false
+	}
+	return oldCol.Flen != newCol.Flen || oldCol.Decimal != newCol.Decimal
+}
+
+// checkModifyElems checks a change of the members of an ENUM or SET column
+// only needs its metadata changed. The rows keep the ordinal or bitmask of
+// their members, so members may only be appended, and only as long as the
+// values take as many bytes as before.
+func checkModifyElems(oldCol, newCol *model.ColumnInfo) error {
+	if oldCol.Tp != newCol.Tp || (oldCol.Tp != mysql.TypeEnum && oldCol.Tp != mysql.TypeSet) {
+		return nil
+	}
+	if len(newCol.Elems) < len(oldCol.Elems) {
+		return errUnsupportedModifyColumn.GenByArgs("removing members of " + types.TypeStr(oldCol.Tp))
+	}
+	for i, elem := range oldCol.Elems {
+		if newCol.Elems[i] != elem {
+			return errUnsupportedModifyColumn.GenByArgs("changing members of " + types.TypeStr(oldCol.Tp))
+		}
+	}
+	if mysql.GetEnumSetStorageSize(oldCol.Tp, len(oldCol.Elems)) != mysql.GetEnumSetStorageSize(newCol.Tp, len(newCol.Elems)) {
+		return errUnsupportedModifyColumn.GenByArgs("changing the storage size of " + types.TypeStr(oldCol.Tp))
+	}
+	return nil
 }
 
 func (d *ddl) updateColumn(t *meta.Meta, job *model.Job, newCol *model.ColumnInfo, oldColName *model.CIStr) (ver int64, _ error) {
@@ -575,112 +575,166 @@ func checkConstraintNames(constraints []*ast.Constraint) error {
 	return nil
 }
 
-func (d *ddl) buildTableInfo(tableName model.CIStr, cols []*schemas.Column, constraints []*ast.Constraint, ctx context.Context) (tbInfo *model.TableInfo, err error) {
-	//tbInfo = &model.TableInfo{
-	//	Name: tableName,
-	//}
-	//tbInfo.ID, err = d.genGlobalID()
-	//if err != nil {
-	//	return nil, errors.Trace(err)
-	//}
-	//for _, v := range cols {
-	//	v.ID = allocateColumnID(tbInfo)
-	//	tbInfo.Columns = append(tbInfo.Columns, v.ToInfo())
-	//}
-	//for _, constr := range constraints {
-	//	if constr.Tp == ast.ConstraintForeignKey {
-	//		for _, fk := range tbInfo.ForeignKeys {
-	//			if fk.Name.L == strings.ToLower(constr.Name) {
-	//				return nil, infoschema.ErrCannotAddForeign
-	//			}
-	//		}
-	//		var fk model.FKInfo
-	//		fk.Name = model.NewCIStr(constr.Name)
-	//		fk.RefTable = constr.Refer.Table.Name
-	//		fk.State = model.StatePublic
-	//		for _, key := range constr.Keys {
-	//			fk.Cols = append(fk.Cols, key.Column.Name)
-	//		}
-	//		for _, key := range constr.Refer.IndexColNames {
-	//			fk.RefCols = append(fk.RefCols, key.Column.Name)
-	//		}
-	//		fk.OnDelete = int(constr.Refer.OnDelete.ReferOpt)
-	//		fk.OnUpdate = int(constr.Refer.OnUpdate.ReferOpt)
-	//		if len(fk.Cols) != len(fk.RefCols) {
-	//			return nil, infoschema.ErrForeignKeyNotMatch.GenByArgs(tbInfo.Name.O)
-	//		}
-	//		if len(fk.Cols) == 0 {
-	//			// TODO: In MySQL, this case will report a parse error.
-	//			return nil, infoschema.ErrCannotAddForeign
-	//		}
-	//		tbInfo.ForeignKeys = append(tbInfo.ForeignKeys, &fk)
-	//		continue
-	//	}
-	//	if constr.Tp == ast.ConstraintPrimaryKey {
-	//		for _, key := range constr.Keys {
-	//			col := table.FindCol(cols, key.Column.Name.O)
-	//			if col == nil {
-	//				return nil, errKeyColumnDoesNotExits.Gen("key column %s doesn't exist in table", key.Column.Name)
-	//			}
-	//			// Virtual columns cannot be used in primary key.
-	//			if col.IsGenerated() && !col.GeneratedStored {
-	//				return nil, errUnsupportedOnGeneratedColumn.GenByArgs("Defining a virtual generated column as primary key")
-	//			}
-	//		}
-	//		if len(constr.Keys) == 1 {
-	//			key := constr.Keys[0]
-	//			col := table.FindCol(cols, key.Column.Name.O)
-	//			if col == nil {
-	//				return nil, errKeyColumnDoesNotExits.Gen("key column %s doesn't exist in table", key.Column.Name)
-	//			}
-	//			switch col.Tp {
-	//			case mysql.TypeLong, mysql.TypeLonglong,
-	//				mysql.TypeTiny, mysql.TypeShort, mysql.TypeInt24:
-	//				tbInfo.PKIsHandle = true
-	//				// Avoid creating index for PK handle column.
-	//				continue
-	//			}
-	//		}
-	//	}
-	//	// build index info.
-	//	idxInfo, err := buildIndexInfo(tbInfo, model.NewCIStr(constr.Name), constr.Keys, model.StatePublic)
-	//	if err != nil {
-	//		return nil, errors.Trace(err)
-	//	}
-	//	//check if the index is primary or uniqiue.
-	//	switch constr.Tp {
-	//	case ast.ConstraintPrimaryKey:
-	//		idxInfo.Primary = true
-	//		idxInfo.Unique = true
-	//		idxInfo.Name = model.NewCIStr(mysql.PrimaryKeyName)
-	//	case ast.ConstraintUniq, ast.ConstraintUniqKey, ast.ConstraintUniqIndex:
-	//		idxInfo.Unique = true
-	//	}
-	//	// set index type.
-	//	if constr.Option != nil {
-	//		idxInfo.Comment, err = validateCommentLength(ctx.GetSessionVars(),
-	//			constr.Option.Comment,
-	//			maxCommentLength,
-	//			errTooLongIndexComment.GenByArgs(idxInfo.Name.String(), maxCommentLength))
-	//		if err != nil {
-	//			return nil, errors.Trace(err)
-	//		}
-	//		if constr.Option.Tp == model.IndexTypeInvalid {
-	//			// Use btree as default index type.
-	//			idxInfo.Tp = model.IndexTypeBtree
-	//		} else {
-	//			idxInfo.Tp = constr.Option.Tp
-	//		}
-	//	} else {
-	//		// Use btree as default index type.
-	//		idxInfo.Tp = model.IndexTypeBtree
-	//	}
-	//	idxInfo.ID = allocateIndexID(tbInfo)
-	//	tbInfo.Indices = append(tbInfo.Indices, idxInfo)
-	//}
+func buildTableInfo(tableName model.CIStr, cols []*schemas.Column, constraints []*ast.Constraint, ctx context.Context) (tbInfo *model.TableInfo, err error) {
+	tbInfo = &model.TableInfo{
+		Name: tableName,
+	}
+	for _, v := range cols {
+		v.ID = allocateColumnID(tbInfo)
+		tbInfo.Columns = append(tbInfo.Columns, v.ToInfo())
+	}
+	for _, constr := range constraints {
+		if constr.Tp == ast.ConstraintForeignKey {
+			for _, fk := range tbInfo.ForeignKeys {
+				if fk.Name.L == strings.ToLower(constr.Name) {
+					return nil, schemas.ErrCannotAddForeign
+				}
+			}
+			fk, err := buildFKInfo(tbInfo, model.NewCIStr(constr.Name), constr.Keys, constr.Refer)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			fk.State = model.StatePublic
+			tbInfo.ForeignKeys = append(tbInfo.ForeignKeys, fk)
+			continue
+		}
+		if constr.Tp == ast.ConstraintPrimaryKey {
+			for _, key := range constr.Keys {
+				col := schemas.FindCol(cols, key.Column.Name.O)
+				if col == nil {
+					return nil, errKeyColumnDoesNotExits.Gen("key column %s doesn't exist in table", key.Column.Name)
+				}
+				// Virtual columns cannot be used in primary key.
+				if col.IsGenerated() && !col.GeneratedStored {
+					return nil, errUnsupportedOnGeneratedColumn.GenByArgs("Defining a virtual generated column as primary key")
+				}
+			}
+			if len(constr.Keys) == 1 {
+				key := constr.Keys[0]
+				col := schemas.FindCol(cols, key.Column.Name.O)
+				if col == nil {
+					return nil, errKeyColumnDoesNotExits.Gen("key column %s doesn't exist in table", key.Column.Name)
+				}
+				switch col.Tp {
+				case mysql.TypeLong, mysql.TypeLonglong,
+					mysql.TypeTiny, mysql.TypeShort, mysql.TypeInt24:
+					tbInfo.PKIsHandle = true
+					// Avoid creating index for PK handle column.
+					continue
+				}
+			}
+		}
+		// build index info.
+		idxInfo, err := buildIndexInfo(tbInfo, model.NewCIStr(constr.Name), constr.Keys, model.StatePublic)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		//check if the index is primary or uniqiue.
+		switch constr.Tp {
+		case ast.ConstraintPrimaryKey:
+			idxInfo.Primary = true
+			idxInfo.Unique = true
+			idxInfo.Name = model.NewCIStr(mysql.PrimaryKeyName)
+		case ast.ConstraintUniq, ast.ConstraintUniqKey, ast.ConstraintUniqIndex:
+			idxInfo.Unique = true
+		}
+		// set index type.
+		if constr.Option != nil {
+			idxInfo.Comment, err = validateCommentLength(ctx.GetSessionVars(),
+				constr.Option.Comment,
+				maxCommentLength,
+				errTooLongIndexComment.GenByArgs(idxInfo.Name.String(), maxCommentLength))
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			if constr.Option.Tp == model.IndexTypeInvalid {
+				// Use btree as default index type.
+				idxInfo.Tp = model.IndexTypeBtree
+			} else {
+				idxInfo.Tp = constr.Option.Tp
+			}
+		} else {
+			// Use btree as default index type.
+			idxInfo.Tp = model.IndexTypeBtree
+		}
+		idxInfo.ID = allocateIndexID(tbInfo)
+		tbInfo.Indices = append(tbInfo.Indices, idxInfo)
+	}
+	for _, fk := range tbInfo.ForeignKeys {
+		if err = addForeignKeyIndex(tbInfo, fk); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
 	return
 }
 
+// addForeignKeyIndex adds to tbInfo the index InnoDB needs on the
+// referencing columns of fk to look up children, named after the
+// constraint, if tbInfo has none starting with them.
+func addForeignKeyIndex(tbInfo *model.TableInfo, fk *model.FKInfo) error {
+	if findIndexByPrefix(tbInfo.Indices, fk.Cols) != nil {
+		return nil
+	}
+	keys := make([]*ast.IndexColName, 0, len(fk.Cols))
+	for _, col := range fk.Cols {
+		keys = append(keys, &ast.IndexColName{Column: &ast.ColumnName{Name: col}, Length: types.UnspecifiedLength})
+	}
+	idxInfo, err := buildIndexInfo(tbInfo, fk.Name, keys, model.StatePublic)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	idxInfo.Tp = model.IndexTypeBtree
+	idxInfo.ID = allocateIndexID(tbInfo)
+	tbInfo.Indices = append(tbInfo.Indices, idxInfo)
+	return nil
+}
+
+// BuildTableInfo builds the definition of the table of stmt, a resolved
+// CREATE TABLE: its columns, indexes and foreign keys.
+func BuildTableInfo(ctx context.Context, stmt *ast.CreateTableStmt) (*model.TableInfo, error) {
+	if err := checkTooLongTable(stmt.Table.Name); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err := checkDuplicateColumn(stmt.Cols); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err := checkTooLongColumn(stmt.Cols); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err := checkTooManyColumns(stmt.Cols); err != nil {
+		return nil, errors.Trace(err)
+	}
+	cols, constraints, err := buildColumnsAndConstraints(ctx, stmt.Cols, stmt.Constraints)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err = checkConstraintNames(constraints); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return buildTableInfo(stmt.Table.Name, cols, constraints, ctx)
+}
+
+// AddTableForeignKey adds the foreign key of constr, the constraint of an
+// ALTER TABLE ... ADD FOREIGN KEY, to tblInfo, with the index it needs on
+// the referencing columns.
+func AddTableForeignKey(tblInfo *model.TableInfo, constr *ast.Constraint) error {
+	fkNames := make(map[string]bool, len(tblInfo.ForeignKeys))
+	for _, fk := range tblInfo.ForeignKeys {
+		fkNames[fk.Name.L] = true
+	}
+	if err := checkDuplicateConstraint(fkNames, constr.Name, true); err != nil {
+		return errors.Trace(err)
+	}
+	setEmptyConstraintName(fkNames, constr, true)
+	fk, err := buildFKInfo(tblInfo, model.NewCIStr(constr.Name), constr.Keys, constr.Refer)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	fk.State = model.StatePublic
+	tblInfo.ForeignKeys = append(tblInfo.ForeignKeys, fk)
+	return errors.Trace(addForeignKeyIndex(tblInfo, fk))
+}
+
 func (d *ddl) CreateTableWithLike(ctx context.Context, ident, referIdent ast.Ident) error {
 	//is := d.GetInformationSchema()
 	//_, ok := is.SchemaByName(referIdent.Schema)
@@ -756,7 +810,7 @@ func (d *ddl) CreateTable(ctx context.Context, ident ast.Ident, colDefs []*ast.C
 	//	return errors.Trace(err)
 	//}
 	//
-	//tbInfo, err := d.buildTableInfo(ident.Name, cols, newConstraints, ctx)
+	//tbInfo, err := buildTableInfo(ident.Name, cols, newConstraints, ctx)
 	//if err != nil {
 	//	return errors.Trace(err)
 	//}
@@ -801,6 +855,20 @@ func (d *ddl) handleAutoIncID(tbInfo *model.TableInfo, schemaID int64) error {
 	return nil
 }
 
+// rowFormatNames are the names of the ROW_FORMAT options, none for DEFAULT.
+var rowFormatNames = map[uint64]string{
+	ast.RowFormatDynamic:    "DYNAMIC",
+	ast.RowFormatFixed:      "FIXED",
+	ast.RowFormatCompressed: "COMPRESSED",
+	ast.RowFormatRedundant:  "REDUNDANT",
+	ast.RowFormatCompact:    "COMPACT",
+}
+
+// RowFormatName returns the name of ROW_FORMAT=option, empty for DEFAULT.
+func RowFormatName(option uint64) string {
+	return rowFormatNames[option]
+}
+
 // handleTableOptions updates tableInfo according to table options.
 func handleTableOptions(options []*ast.TableOption, tbInfo *model.TableInfo) {
 	for _, op := range options {
@@ -813,6 +881,8 @@ func handleTableOptions(options []*ast.TableOption, tbInfo *model.TableInfo) {
 			tbInfo.Charset = op.StrValue
 		case ast.TableOptionCollate:
 			tbInfo.Collate = op.StrValue
+		case ast.TableOptionRowFormat:
+			tbInfo.RowFormat = rowFormatNames[op.UintValue]
 		case ast.TableOptionShardRowID:
 			if !hasAutoIncrementColumn(tbInfo) {
 				tbInfo.ShardRowIDBits = op.UintValue
@@ -1553,29 +1623,81 @@ func (d *ddl) AddColumn(ctx context.Context, ti ast.Ident, spec *ast.AlterTableS
 //	err = d.callHookOnChanged(err)
 //	return errors.Trace(err)
 //}
-//
-//func buildFKInfo(fkName model.CIStr, keys []*ast.IndexColName, refer *ast.ReferenceDef) (*model.FKInfo, error) {
-//	var fkInfo model.FKInfo
-//	fkInfo.Name = fkName
-//	fkInfo.RefTable = refer.Table.Name
-//
-//	fkInfo.Cols = make([]model.CIStr, len(keys))
-//	for i, key := range keys {
-//		fkInfo.Cols[i] = key.Column.Name
-//	}
-//
-//	fkInfo.RefCols = make([]model.CIStr, len(refer.IndexColNames))
-//	for i, key := range refer.IndexColNames {
-//		fkInfo.RefCols[i] = key.Column.Name
-//	}
-//
-//	fkInfo.OnDelete = int(refer.OnDelete.ReferOpt)
-//	fkInfo.OnUpdate = int(refer.OnUpdate.ReferOpt)
-//
-//	return &fkInfo, nil
-//
-//}
-//
+
+// CheckForeignKeys checks the foreign keys of tbInfo, a table created in
+// schema, against the tables they reference. Each referenced table must
+// exist, unless it is tbInfo itself, and have an index starting with the
+// referenced columns, which InnoDB needs to look up parent rows. The
+// primary key of an integer handle counts as such an index.
+func CheckForeignKeys(is schemas.InfoSchema, schema model.CIStr, tbInfo *model.TableInfo) error {
+	for _, fk := range tbInfo.ForeignKeys {
+		refSchema := fk.RefSchema
+		if refSchema.L == "" {
+			refSchema = schema
+		}
+		refTbl := tbInfo
+		if refSchema.L != schema.L || fk.RefTable.L != tbInfo.Name.L {
+			tbl, err := is.TableByName(refSchema, fk.RefTable)
+			if err != nil || tbl == nil {
+				return schemas.ErrCannotAddForeign
+			}
+			refTbl = tbl.Meta()
+		}
+		if !hasReferencedIndex(refTbl, fk.RefCols) {
+			return schemas.ErrCannotAddForeign
+		}
+	}
+	return nil
+}
+
+func hasReferencedIndex(tbl *model.TableInfo, cols []model.CIStr) bool {
+	for _, col := range cols {
+		if findCol(tbl.Columns, col.L) == nil {
+			return false
+		}
+	}
+	if tbl.PKIsHandle && len(cols) == 1 {
+		if pk := tbl.GetPkColInfo(); pk != nil && pk.Name.L == cols[0].L {
+			return true
+		}
+	}
+	return findIndexByPrefix(tbl.Indices, cols) != nil
+}
+
+// buildFKInfo builds the foreign key meta of tbInfo from a FOREIGN KEY clause.
+func buildFKInfo(tbInfo *model.TableInfo, fkName model.CIStr, keys []*ast.IndexColName, refer *ast.ReferenceDef) (*model.FKInfo, error) {
+	if len(keys) != len(refer.IndexColNames) {
+		return nil, schemas.ErrForeignKeyNotMatch.GenByArgs(tbInfo.Name.O)
+	}
+	if len(keys) == 0 {
+		// TODO: In MySQL, this case will report a parse error.
+		return nil, schemas.ErrCannotAddForeign
+	}
+
+	var fkInfo model.FKInfo
+	fkInfo.Name = fkName
+	fkInfo.RefSchema = refer.Table.Schema
+	fkInfo.RefTable = refer.Table.Name
+
+	fkInfo.Cols = make([]model.CIStr, len(keys))
+	for i, key := range keys {
+		if findCol(tbInfo.Columns, key.Column.Name.L) == nil {
+			return nil, errKeyColumnDoesNotExits.Gen("key column %s doesn't exist in table", key.Column.Name)
+		}
+		fkInfo.Cols[i] = key.Column.Name
+	}
+
+	fkInfo.RefCols = make([]model.CIStr, len(refer.IndexColNames))
+	for i, key := range refer.IndexColNames {
+		fkInfo.RefCols[i] = key.Column.Name
+	}
+
+	fkInfo.OnDelete = int(refer.OnDelete.ReferOpt)
+	fkInfo.OnUpdate = int(refer.OnUpdate.ReferOpt)
+
+	return &fkInfo, nil
+}
+
 //func (d *ddl) CreateForeignKey(ctx context.Context, ti ast.Ident, fkName model.CIStr, keys []*ast.IndexColName, refer *ast.ReferenceDef) error {
 //	is := d.infoHandle.Get()
 //	schema, ok := is.SchemaByName(ti.Schema)
@@ -712,6 +712,26 @@ func findIndexByName(idxName string, indices []*model.IndexInfo) *model.IndexInf
 	return nil
 }
 
+// findIndexByPrefix returns an index whose leading columns are cols.
+func findIndexByPrefix(indices []*model.IndexInfo, cols []model.CIStr) *model.IndexInfo {
+	for _, idx := range indices {
+		if len(idx.Columns) < len(cols) {
+			continue
+		}
+		match := true
+		for i, col := range cols {
+			if idx.Columns[i].Name.L != col.L {
+				match = false
+				break
+			}
+		}
+		if match {
+			return idx
+		}
+	}
+	return nil
+}
+
 func allocateIndexID(tblInfo *model.TableInfo) int64 {
 	tblInfo.MaxIndexID++
 	return tblInfo.MaxIndexID
@@ -0,0 +1,63 @@
+package engine
+
+import (
+	"github.com/juju/errors"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ddl"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
+)
+
+/**
+ALTER TABLE ... ADD FOREIGN KEY
+
+给表的定义加上外键，引用列上没有以它们开头的索引时和 CREATE TABLE 一样建一个以约束
+命名的索引。被引用的表必须存在、并有以被引用列开头的索引（ddl.CheckForeignKeys，
+否则 1215）。foreign_key_checks 打开时表中已有的行都要找得到父行，否则语句失败（1452）。
+一条语句加多个外键时按顺序加，任何一个失败整个语句失败，表不变。
+**/
+
+// addForeignKeySpecs returns the ADD FOREIGN KEY specs of stmt, in order.
+func addForeignKeySpecs(stmt *ast.AlterTableStmt) []*ast.AlterTableSpec {
+	var specs []*ast.AlterTableSpec
+	for _, spec := range stmt.Specs {
+		if spec.Tp == ast.AlterTableAddConstraint && spec.Constraint.Tp == ast.ConstraintForeignKey {
+			specs = append(specs, spec)
+		}
+	}
+	return specs
+}
+
+// addForeignKeys applies specs, ADD FOREIGN KEY specs, to the table tn of a
+// resolved ALTER TABLE, checking the rows of the table in store against
+// their parents.
+func addForeignKeys(ctx context.Context, is schemas.InfoSchema, store fkRowStore, tn *ast.TableName, specs []*ast.AlterTableSpec) error {
+	tbl, err := is.TableByName(tn.Schema, tn.Name)
+	if err != nil || tbl == nil {
+		return schemas.ErrTableNotExists.GenByArgs(tn.Schema.O, tn.Name.O)
+	}
+	if tbl.Meta().IsView() {
+		return schemas.ErrWrongObject.GenByArgs(tn.Schema.O, tn.Name.O, "BASE TABLE")
+	}
+	info := tbl.Meta().Clone()
+	for _, spec := range specs {
+		if err = ddl.AddTableForeignKey(info, spec.Constraint); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	if err = ddl.CheckForeignKeys(is, tn.Schema, info); err != nil {
+		return errors.Trace(err)
+	}
+
+	_, rows, err := store.Rows(tbl.Meta())
+	if err != nil {
+		return errors.Trace(err)
+	}
+	fk := &fkChecker{ctx: ctx, store: store}
+	for _, row := range rows {
+		if err = fk.checkChildRow(info, row); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return errors.Trace(is.AlterTableColumns(tn.Schema, tn.Name, info, rows))
+}
@@ -0,0 +1,97 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// alterFKTestSchema is an fkTestSchema replacing the definitions of its
+// tables.
+type alterFKTestSchema struct {
+	*fkTestSchema
+}
+
+func (is *alterFKTestSchema) AlterTableColumns(schema, table model.CIStr, tbl *model.TableInfo, rows [][]basic.Datum) error {
+	is.tables[schema.L+"."+table.L] = &viewTestTable{meta: tbl}
+	return nil
+}
+
+func TestAddForeignKey(t *testing.T) {
+	fkIs, _ := newFKTestSchema()
+	is := &alterFKTestSchema{fkIs}
+	orders := newFKTestTable("orders", "id", "pid")
+	is.tables["test.orders"] = &viewTestTable{meta: orders}
+	s := newViewTestSession(t, is)
+	parent := is.tables["test.parent"].Meta()
+	store := newFKTestStore(parent, orders)
+	store.insert(parent, 1)
+	store.insert(orders, 1, 2)
+
+	exec := func(sql string) error {
+		stmt, _, err := compileView(s, sql)
+		if err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+		alter := stmt.(*ast.AlterTableStmt)
+		return addForeignKeys(s, is, store, alter.Table, addForeignKeySpecs(alter))
+	}
+	for _, tt := range []struct {
+		sql  string
+		code uint16
+	}{
+		// child.pid has no index.
+		{"ALTER TABLE orders ADD FOREIGN KEY (pid) REFERENCES child (pid)", mysql.ErrCannotAddForeign},
+		// The order with pid 2 has no parent.
+		{"ALTER TABLE orders ADD CONSTRAINT fk_o FOREIGN KEY (pid) REFERENCES parent (id)", mysql.ErrNoReferencedRow2},
+	} {
+		if err := exec(tt.sql); errCode(err) != tt.code {
+			t.Fatalf("%s: expect error %d, got %v", tt.sql, tt.code, err)
+		}
+		if info := is.tables["test.orders"].Meta(); len(info.ForeignKeys) != 0 || len(info.Indices) != 0 {
+			t.Fatalf("%s: expect the table unchanged, got %v %v", tt.sql, info.ForeignKeys, info.Indices)
+		}
+	}
+
+	store.insert(parent, 2)
+	if err := exec("ALTER TABLE orders ADD CONSTRAINT fk_o FOREIGN KEY (pid) REFERENCES parent (id)"); err != nil {
+		t.Fatal(err)
+	}
+	info := is.tables["test.orders"].Meta()
+	if len(info.ForeignKeys) != 1 || info.ForeignKeys[0].Name.L != "fk_o" || info.ForeignKeys[0].RefTable.L != "parent" {
+		t.Fatalf("expect the foreign key fk_o, got %v", info.ForeignKeys)
+	}
+	// The index the foreign key needs on pid.
+	if len(info.Indices) != 1 || info.Indices[0].Name.L != "fk_o" || info.Indices[0].Columns[0].Name.L != "pid" {
+		t.Fatalf("expect the index fk_o on pid, got %v", info.Indices)
+	}
+	if err := exec("ALTER TABLE orders ADD CONSTRAINT fk_o FOREIGN KEY (pid) REFERENCES parent (id)"); errCode(err) != mysql.ErrCannotAddForeign {
+		t.Fatalf("expect error %d, got %v", mysql.ErrCannotAddForeign, err)
+	}
+}
+
+func TestAlterTableAddForeignKey(t *testing.T) {
+	fkIs, _ := newFKTestSchema()
+	is := &alterFKTestSchema{fkIs}
+	is.tables["test.orders"] = &viewTestTable{meta: newFKTestTable("orders", "id", "pid")}
+	srv := &XMySQLEngine{infoSchemaManager: is}
+	s := &serverTestSession{session: newViewTestSession(t, is)}
+
+	// child.pid has no index.
+	srv.ExecuteQuery(s, "ALTER TABLE orders ADD FOREIGN KEY (pid) REFERENCES child (pid)")
+	if len(s.errs) != 1 || toSQLError(s.errs[0]).Code != mysql.ErrCannotAddForeign {
+		t.Fatalf("expect error %d, got %v", mysql.ErrCannotAddForeign, s.errs)
+	}
+	// orders has no rows to check. The constraint is named after its first
+	// column.
+	srv.ExecuteQuery(s, "ALTER TABLE orders ADD FOREIGN KEY (pid) REFERENCES parent (id)")
+	if len(s.errs) != 1 {
+		t.Fatalf("expect no other error, got %v", s.errs)
+	}
+	if fks := is.tables["test.orders"].Meta().ForeignKeys; len(fks) != 1 || fks[0].Name.L != "pid" {
+		t.Fatalf("expect the foreign key pid, got %v", fks[0].Name)
+	}
+}
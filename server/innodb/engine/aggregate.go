@@ -0,0 +1,213 @@
+package engine
+
+import (
+	"github.com/juju/errors"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/expression"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/expression/aggregation"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/distinct"
+)
+
+// aggregateRows returns the row of each group of rows of agg, in the order
+// of the group-by items, as MySQL sorts the groups. WITH ROLLUP, the rows
+// of the groups having the same first k items are followed by their
+// super-aggregate row, in which the items after the first k are NULL, and
+// the last row aggregates all the rows.
+func aggregateRows(ctx context.Context, agg *plan.PhysicalAggregation, rows [][]basic.Datum) ([][]basic.Datum, error) {
+	if agg.Distinct {
+		return distinctRows(ctx, agg, rows)
+	}
+	// A stream aggregation reads its rows in the order of the group-by
+	// items, from an index.
+	if agg.AggType != plan.StreamedAgg {
+		byItems := make([]*plan.ByItems, 0, len(agg.GroupByItems))
+		for _, item := range agg.GroupByItems {
+			byItems = append(byItems, &plan.ByItems{Expr: item})
+		}
+		var err error
+		if rows, err = sortRows(ctx, byItems, rows); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	// levels[k] aggregates the rows of the group of the first k items, the
+	// last one the rows of the group of all of them.
+	levels := make([]*groupAggregator, len(agg.GroupByItems)+1)
+	for k := range levels {
+		levels[k] = newGroupAggregator(ctx, agg, k)
+	}
+	// Without ROLLUP, only the groups of all the items are returned.
+	lowest := len(agg.GroupByItems)
+	if agg.Rollup {
+		lowest = 0
+	}
+	sc := ctx.GetSessionVars().StmtCtx
+	var (
+		result  [][]basic.Datum
+		lastKey []basic.Datum
+	)
+	for _, row := range rows {
+		key, err := evalRow(agg.GroupByItems, row)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if lastKey != nil {
+			changed, err := firstDifference(ctx, lastKey, key)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			// The groups of the items up to the one changed are complete.
+			for k := len(levels) - 1; k > changed && k >= lowest; k-- {
+				row := levels[k].row(sc, len(result)+1)
+				if err = consumeRow(ctx, row); err != nil {
+					return nil, errors.Trace(err)
+				}
+				result = append(result, row)
+				levels[k] = newGroupAggregator(ctx, agg, k)
+			}
+		}
+		lastKey = key
+		for _, level := range levels[lowest:] {
+			if err = level.update(sc, row); err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+	}
+	// Without GROUP BY, the rows make one group even when there are none.
+	if lastKey == nil && len(agg.GroupByItems) > 0 {
+		return nil, nil
+	}
+	for k := len(levels) - 1; k >= lowest; k-- {
+		row := levels[k].row(sc, len(result)+1)
+		if err := consumeRow(ctx, row); err != nil {
+			return nil, errors.Trace(err)
+		}
+		result = append(result, row)
+	}
+	return result, nil
+}
+
+// distinctRows returns the rows of agg, the aggregation of SELECT DISTINCT,
+// over rows: the first row of each group of rows, the groups in the order
+// they first come, as MySQL only orders them for an ORDER BY. The groups
+// seen are kept in a hash set, spilled to disk when they are too many or
+// take the statement past its memory limit.
+func distinctRows(ctx context.Context, agg *plan.PhysicalAggregation, rows [][]basic.Datum) ([][]basic.Datum, error) {
+	checker := distinct.CreateDistinctChecker()
+	checker.SetMemTracker(ctx.GetSessionVars().StmtCtx.MemTracker)
+	defer checker.Close()
+	args := make([]expression.Expression, 0, len(agg.AggFuncs))
+	for _, fun := range agg.AggFuncs {
+		args = append(args, fun.GetArgs()[0])
+	}
+	var result [][]basic.Datum
+	for _, row := range rows {
+		key, err := evalRow(agg.GroupByItems, row)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		first, err := checker.CheckDatums(key)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if !first {
+			continue
+		}
+		values, err := evalRow(args, row)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if err = consumeRow(ctx, values); err != nil {
+			return nil, errors.Trace(err)
+		}
+		result = append(result, values)
+	}
+	return result, nil
+}
+
+// groupAggregator computes the aggregate functions of agg over the rows of
+// a group of the first k group-by items.
+type groupAggregator struct {
+	funcs    []aggregation.Aggregation
+	contexts []*aggregation.AggEvaluateContext
+	// rolledUp tells the functions giving the value of a group-by item
+	// after the first k, NULL in the row of the group.
+	rolledUp []bool
+}
+
+func newGroupAggregator(ctx context.Context, agg *plan.PhysicalAggregation, k int) *groupAggregator {
+	g := &groupAggregator{
+		funcs:    agg.AggFuncs,
+		contexts: make([]*aggregation.AggEvaluateContext, len(agg.AggFuncs)),
+		rolledUp: make([]bool, len(agg.AggFuncs)),
+	}
+	for i, fun := range agg.AggFuncs {
+		g.contexts[i] = fun.CreateContext()
+		if fun.GetName() != ast.AggFuncFirstRow {
+			continue
+		}
+		for _, item := range agg.GroupByItems[k:] {
+			if fun.GetArgs()[0].Equal(item, ctx) {
+				g.rolledUp[i] = true
+			}
+		}
+	}
+	return g
+}
+
+func (g *groupAggregator) update(sc *variable.StatementContext, row []basic.Datum) error {
+	for i, fun := range g.funcs {
+		if err := fun.Update(g.contexts[i], sc, row); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// row returns the row of the group, the n-th of the result, with a warning
+// for each GROUP_CONCAT cut at group_concat_max_len.
+func (g *groupAggregator) row(sc *variable.StatementContext, n int) []basic.Datum {
+	row := make([]basic.Datum, len(g.funcs))
+	for i, fun := range g.funcs {
+		if g.rolledUp[i] {
+			continue
+		}
+		row[i] = fun.GetResult(g.contexts[i])
+		if g.contexts[i].Truncated {
+			sc.AppendWarning(ErrCutValueGroupConcat.GenByArgs(n))
+		}
+	}
+	return row
+}
+
+// evalRow evaluates exprs on row.
+func evalRow(exprs []expression.Expression, row []basic.Datum) ([]basic.Datum, error) {
+	values := make([]basic.Datum, 0, len(exprs))
+	for _, expr := range exprs {
+		d, err := expr.Eval(row)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		values = append(values, d)
+	}
+	return values, nil
+}
+
+// firstDifference returns the index of the first value differing in a and
+// b, len(a) when they are equal.
+func firstDifference(ctx context.Context, a, b []basic.Datum) (int, error) {
+	sc := ctx.GetSessionVars().StmtCtx
+	for i := range a {
+		cmp, err := a[i].CompareDatum(sc, &b[i])
+		if err != nil {
+			return 0, errors.Trace(err)
+		}
+		if cmp != 0 {
+			return i, nil
+		}
+	}
+	return len(a), nil
+}
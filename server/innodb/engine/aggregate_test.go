@@ -0,0 +1,175 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/varsutil"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// salesSQL is a derived table of the sales of cities, unordered.
+const salesSQL = `(SELECT 'US' country, 'NYC' city, 10 amount
+	UNION ALL SELECT 'CN', 'Shanghai', 5
+	UNION ALL SELECT 'CN', 'Beijing', 7
+	UNION ALL SELECT 'US', 'Boston', 4
+	UNION ALL SELECT 'CN', 'Beijing', 3) sales`
+
+// aggregateTestRows returns the rows of sql, a row a string of its values
+// separated by commas.
+func aggregateTestRows(t *testing.T, s *session, sql string) []string {
+	_, p, err := compileView(s, sql)
+	if err != nil {
+		t.Fatalf("%s: %v", sql, err)
+	}
+	rows, ok, err := dualRows(s, p)
+	if err != nil || !ok {
+		t.Fatalf("%s: %v %v", sql, ok, err)
+	}
+	var result []string
+	for _, row := range rows {
+		var values []string
+		for _, d := range row {
+			value := "NULL"
+			if !d.IsNull() {
+				value, _ = d.ToString()
+			}
+			values = append(values, value)
+		}
+		result = append(result, strings.Join(values, ","))
+	}
+	return result
+}
+
+func TestGroupByRollup(t *testing.T) {
+	s := newViewTestSession(t, newViewTestSchema())
+	for _, tt := range []struct {
+		sql  string
+		rows []string
+	}{
+		{"SELECT country, city, SUM(amount), COUNT(*) FROM " + salesSQL + " GROUP BY country, city", []string{
+			"CN,Beijing,10,2",
+			"CN,Shanghai,5,1",
+			"US,Boston,4,1",
+			"US,NYC,10,1",
+		}},
+		{"SELECT country, city, SUM(amount), COUNT(*) FROM " + salesSQL + " GROUP BY country, city WITH ROLLUP", []string{
+			"CN,Beijing,10,2",
+			"CN,Shanghai,5,1",
+			"CN,NULL,15,3",
+			"US,Boston,4,1",
+			"US,NYC,10,1",
+			"US,NULL,14,2",
+			"NULL,NULL,29,5",
+		}},
+		{"SELECT country, SUM(amount) FROM " + salesSQL + " GROUP BY country WITH ROLLUP HAVING SUM(amount) > 14", []string{
+			"CN,15",
+			"NULL,29",
+		}},
+		// The rows of the rollup can be filtered and sorted from a derived
+		// table.
+		{"SELECT * FROM (SELECT country, city, SUM(amount) total FROM " + salesSQL +
+			" GROUP BY country, city WITH ROLLUP) r WHERE city IS NULL ORDER BY total", []string{
+			"US,NULL,14",
+			"CN,NULL,15",
+			"NULL,NULL,29",
+		}},
+		{"SELECT SUM(amount) FROM " + salesSQL + " WHERE amount > 100", []string{"NULL"}},
+		{"SELECT country, SUM(amount) FROM " + salesSQL + " WHERE amount > 100 GROUP BY country WITH ROLLUP", nil},
+	} {
+		if got := aggregateTestRows(t, s, tt.sql); strings.Join(got, "\n") != strings.Join(tt.rows, "\n") {
+			t.Fatalf("%s: expect rows\n%s\ngot\n%s", tt.sql, strings.Join(tt.rows, "\n"), strings.Join(got, "\n"))
+		}
+	}
+
+	_, _, err := compileView(s, "SELECT country, SUM(amount) FROM "+salesSQL+" GROUP BY country WITH ROLLUP ORDER BY country")
+	if errCode(err) != mysql.ErrWrongUsage {
+		t.Fatalf("expect error %d, got %v", mysql.ErrWrongUsage, err)
+	}
+}
+
+func TestSelectDistinct(t *testing.T) {
+	s := newViewTestSession(t, newViewTestSchema())
+	for _, tt := range []struct {
+		sql  string
+		rows []string
+	}{
+		// The groups come in the order they are first seen.
+		{"SELECT DISTINCT country FROM " + salesSQL, []string{"US", "CN"}},
+		{"SELECT DISTINCT country, city FROM " + salesSQL, []string{
+			"US,NYC",
+			"CN,Shanghai",
+			"CN,Beijing",
+			"US,Boston",
+		}},
+		// ORDER BY sorts the rows left.
+		{"SELECT DISTINCT city FROM " + salesSQL + " ORDER BY city", []string{"Beijing", "Boston", "NYC", "Shanghai"}},
+		{"SELECT DISTINCT amount > 4 FROM " + salesSQL + " ORDER BY 1 DESC", []string{"1", "0"}},
+		{"SELECT COUNT(DISTINCT city), COUNT(city), SUM(DISTINCT amount) FROM " + salesSQL, []string{"4,5,29"}},
+		{"SELECT country, COUNT(DISTINCT city) FROM " + salesSQL + " GROUP BY country", []string{"CN,2", "US,2"}},
+	} {
+		if got := aggregateTestRows(t, s, tt.sql); strings.Join(got, "\n") != strings.Join(tt.rows, "\n") {
+			t.Errorf("%s: expect rows\n%s\ngot\n%s", tt.sql, strings.Join(tt.rows, "\n"), strings.Join(got, "\n"))
+		}
+	}
+}
+
+func TestGroupConcat(t *testing.T) {
+	s := newViewTestSession(t, newViewTestSchema())
+	for _, tt := range []struct {
+		sql  string
+		rows []string
+	}{
+		{"SELECT country, GROUP_CONCAT(city ORDER BY city SEPARATOR '|') FROM " + salesSQL + " GROUP BY country", []string{
+			"CN,Beijing|Beijing|Shanghai",
+			"US,Boston|NYC",
+		}},
+		{"SELECT GROUP_CONCAT(DISTINCT city ORDER BY amount DESC SEPARATOR '|'), COUNT(*) FROM " + salesSQL, []string{
+			"NYC|Beijing|Shanghai|Boston,5",
+		}},
+		// Positions are the arguments of GROUP_CONCAT, a row with a NULL
+		// argument is left out.
+		{"SELECT GROUP_CONCAT(amount, NULLIF(city, 'NYC') ORDER BY 1 SEPARATOR '') FROM " + salesSQL, []string{
+			"3Beijing4Boston5Shanghai7Beijing",
+		}},
+		{"SELECT country, GROUP_CONCAT(amount ORDER BY amount) FROM " + salesSQL + " GROUP BY country WITH ROLLUP", []string{
+			"CN,3,5,7",
+			"US,4,10",
+			"NULL,3,4,5,7,10",
+		}},
+		{"SELECT GROUP_CONCAT(city) FROM " + salesSQL + " WHERE amount > 100", []string{"NULL"}},
+	} {
+		if got := aggregateTestRows(t, s, tt.sql); strings.Join(got, "\n") != strings.Join(tt.rows, "\n") {
+			t.Errorf("%s: expect rows\n%s\ngot\n%s", tt.sql, strings.Join(tt.rows, "\n"), strings.Join(got, "\n"))
+		}
+	}
+
+	// The result is a TEXT, a BLOB for binary values.
+	stmt, p, err := compileView(s, "SELECT GROUP_CONCAT(city), GROUP_CONCAT(CAST(city AS BINARY)) FROM "+salesSQL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := ResultColumns(stmt, p)
+	if fields[0].Types != int(mysql.TypeBlob) || fields[0].Charset != 0 || fields[1].Types != int(mysql.TypeBlob) || fields[1].Charset != mysql.BinaryCollationID {
+		t.Fatalf("expect a TEXT and a BLOB, got %+v", fields)
+	}
+
+	if _, _, err := compileView(s, "SELECT GROUP_CONCAT(city ORDER BY 2) FROM "+salesSQL); errCode(err) != mysql.ErrBadField {
+		t.Fatalf("expect error %d, got %v", mysql.ErrBadField, err)
+	}
+
+	// The results are cut at group_concat_max_len bytes, with a warning.
+	if err := varsutil.SetSessionSystemVar(s.sessionVars, variable.GroupConcatMaxLen, basic.NewStringDatum("12")); err != nil {
+		t.Fatal(err)
+	}
+	rows := aggregateTestRows(t, s, "SELECT country, GROUP_CONCAT(city ORDER BY city DESC) FROM "+salesSQL+" GROUP BY country")
+	if strings.Join(rows, "\n") != "CN,Shanghai,Bei\nUS,NYC,Boston" {
+		t.Fatalf("unexpected rows %q", rows)
+	}
+	warnings := s.sessionVars.StmtCtx.GetWarnings()
+	if len(warnings) != 1 || errCode(warnings[0]) != mysql.ErrCutValueGroupConcat || warnings[0].Error() != "[executor:1260]Row 1 was cut by GROUP_CONCAT()" {
+		t.Fatalf("expect a warning for row 1, got %v", warnings)
+	}
+}
@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"github.com/juju/errors"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ddl"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/innodb_store/store"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
+)
+
+// alterTableRowFormat returns the row format ALTER TABLE ... ROW_FORMAT=X
+// rebuilds the table in, empty for DEFAULT, and false when stmt has no
+// ROW_FORMAT option. Of several ROW_FORMAT options the last one wins.
+func alterTableRowFormat(stmt *ast.AlterTableStmt) (string, bool) {
+	var rowFormat string
+	var found bool
+	for _, spec := range stmt.Specs {
+		if spec.Tp != ast.AlterTableOption {
+			continue
+		}
+		for _, op := range spec.Options {
+			if op.Tp == ast.TableOptionRowFormat {
+				rowFormat, found = ddl.RowFormatName(op.UintValue), true
+			}
+		}
+	}
+	return rowFormat, found
+}
+
+// rebuildTable rebuilds the table tn of a resolved ALTER TABLE in
+// rowFormat, in innodb_default_row_format when it is empty.
+func rebuildTable(is schemas.InfoSchema, tn *ast.TableName, rowFormat string) error {
+	if rowFormat != "" {
+		if _, err := store.ParseRowFormat(rowFormat); err != nil {
+			return ErrIllegalHaCreateOption.GenByArgs("InnoDB", "ROW_FORMAT")
+		}
+	}
+	if !is.TableExists(tn.Schema, tn.Name) {
+		return schemas.ErrTableNotExists.GenByArgs(tn.Schema.O, tn.Name.O)
+	}
+	return errors.Trace(is.AlterTableRowFormat(tn.Schema, tn.Name, rowFormat))
+}
+
+func init() {
+	registerStmtHandler(&ast.AlterTableStmt{}, &stmtHandler{name: "alter table", handle: (*XMySQLEngine).execAlterTable})
+}
+
+// execAlterTable runs the ROW_FORMAT, MODIFY COLUMN, CHANGE COLUMN, DROP
+// COLUMN and RENAME changes of an ALTER TABLE.
+func (srv *XMySQLEngine) execAlterTable(session innodb.MySQLServerSession, stmt ast.StmtNode, p plan.Plan) {
+	x := stmt.(*ast.AlterTableStmt)
+	rowFormat, rebuild := alterTableRowFormat(x)
+	if rebuild {
+		if err := rebuildTable(srv.infoSchemaManager, x.Table, rowFormat); err != nil {
+			session.SendError(toSQLError(err))
+			return
+		}
+	}
+	reader := &scanRowsReader{ctx: session, pool: srv.pool}
+	specs := modifyColumnSpecs(x)
+	if specs != nil {
+		if err := modifyColumns(session, srv.infoSchemaManager, reader, x.Table, specs); err != nil {
+			session.SendError(toSQLError(err))
+			return
+		}
+	}
+	drops := dropColumnSpecs(x)
+	if drops != nil {
+		if err := dropColumns(session, srv.infoSchemaManager, reader, x.Table, drops); err != nil {
+			session.SendError(toSQLError(err))
+			return
+		}
+	}
+	fks := addForeignKeySpecs(x)
+	if fks != nil {
+		store := newTableRowStore(session, srv.infoSchemaManager, srv.pool, x.Table.Schema)
+		if err := addForeignKeys(session, srv.infoSchemaManager, store, x.Table, fks); err != nil {
+			session.SendError(toSQLError(err))
+			return
+		}
+	}
+	if pairs := alterTableRenames(x); pairs != nil {
+		if err := renameTables(srv.infoSchemaManager, pairs); err != nil {
+			session.SendError(toSQLError(err))
+			return
+		}
+		session.SendOK()
+	} else if rebuild || specs != nil || drops != nil || fks != nil {
+		session.SendOK()
+	}
+}
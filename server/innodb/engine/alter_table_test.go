@@ -0,0 +1,118 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/conf"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/varsutil"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// rowFormatTestSchema is a fkTestSchema whose tables are rebuilt by
+// setting their row format.
+type rowFormatTestSchema struct {
+	*fkTestSchema
+}
+
+func (is *rowFormatTestSchema) TableExists(schema, table model.CIStr) bool {
+	_, ok := is.tables[schema.L+"."+table.L]
+	return ok
+}
+
+func (is *rowFormatTestSchema) AlterTableRowFormat(schema, table model.CIStr, rowFormat string) error {
+	is.tables[schema.L+"."+table.L].Meta().RowFormat = rowFormat
+	return nil
+}
+
+func TestAlterTableRowFormat(t *testing.T) {
+	is := &rowFormatTestSchema{&fkTestSchema{crossDBTestSchema{tables: map[string]schemas.Table{
+		"test.t":  &viewTestTable{meta: newFKTestTable("t", "id")},
+		"test.v":  schemas.NewView(&model.TableInfo{Name: model.NewCIStr("v"), View: &model.ViewInfo{}}),
+		"test.t2": &viewTestTable{meta: newFKTestTable("t2", "id")},
+	}}}}
+	s := newViewTestSession(t, is)
+
+	for _, c := range []struct {
+		sql       string
+		rowFormat string
+		code      uint16
+	}{
+		{"ALTER TABLE t ROW_FORMAT=COMPACT", "COMPACT", 0},
+		{"ALTER TABLE t ROW_FORMAT=REDUNDANT, ROW_FORMAT=DYNAMIC", "DYNAMIC", 0},
+		{"ALTER TABLE t ROW_FORMAT=FIXED", "", mysql.ErrIllegalHaCreateOption},
+	} {
+		stmt, _, err := compileView(s, c.sql)
+		if err != nil {
+			t.Fatalf("%s: %v", c.sql, err)
+		}
+		alter := stmt.(*ast.AlterTableStmt)
+		rowFormat, ok := alterTableRowFormat(alter)
+		if !ok {
+			t.Fatalf("%s: expect a ROW_FORMAT option", c.sql)
+		}
+		err = rebuildTable(is, alter.Table, rowFormat)
+		if errCode(err) != c.code {
+			t.Fatalf("%s: expect error %d, got %v", c.sql, c.code, err)
+		}
+		if c.code == 0 && is.tables["test.t"].Meta().RowFormat != c.rowFormat {
+			t.Fatalf("%s: expect %s, got %s", c.sql, c.rowFormat, is.tables["test.t"].Meta().RowFormat)
+		}
+	}
+	err := rebuildTable(is, &ast.TableName{Schema: model.NewCIStr("test"), Name: model.NewCIStr("t3")}, "COMPACT")
+	if errCode(err) != mysql.ErrNoSuchTable {
+		t.Fatalf("expect error %d, got %v", mysql.ErrNoSuchTable, err)
+	}
+	stmt, _, err := compileView(s, "ALTER TABLE t RENAME TO t4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := alterTableRowFormat(stmt.(*ast.AlterTableStmt)); ok {
+		t.Fatal("expect no ROW_FORMAT option in a rename")
+	}
+
+	rows := schemas.TablesRows(is, "dynamic")
+	if len(rows) != 3 {
+		t.Fatalf("expect 3 tables, got %d", len(rows))
+	}
+	for i, expect := range []struct {
+		name, tableType, rowFormat string
+	}{
+		{"t", "BASE TABLE", "Dynamic"},
+		{"t2", "BASE TABLE", "Dynamic"},
+		{"v", "VIEW", ""},
+	} {
+		if rows[i][2].GetString() != expect.name || rows[i][3].GetString() != expect.tableType || rows[i][6].GetString() != expect.rowFormat {
+			t.Fatalf("row %d: expect %v, got %v", i, expect, rows[i])
+		}
+	}
+	if rows[0][19].GetString() != "row_format=DYNAMIC" || !rows[1][19].IsNull() {
+		t.Fatalf("unexpected create options %v, %v", rows[0][19], rows[1][19])
+	}
+}
+
+func TestDefaultRowFormatVariable(t *testing.T) {
+	cfg := conf.NewCfg()
+	cfg.InnodbDefaultRowFormat = "COMPACT"
+	srv := &XMySQLEngine{conf: cfg}
+	srv.initDefaultRowFormat()
+	s := newViewTestSession(t, newViewTestSchema())
+	if val, _ := varsutil.GetGlobalSystemVar(s.sessionVars, variable.InnodbDefaultRowFormat); val != "compact" {
+		t.Fatalf("expect compact, got %s", val)
+	}
+	err := varsutil.SetGlobalSystemVar(s.sessionVars, variable.InnodbDefaultRowFormat, basic.NewStringDatum("redundant"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.InnodbDefaultRowFormat != "REDUNDANT" {
+		t.Fatalf("expect REDUNDANT, got %s", cfg.InnodbDefaultRowFormat)
+	}
+	err = varsutil.SetGlobalSystemVar(s.sessionVars, variable.InnodbDefaultRowFormat, basic.NewStringDatum("fixed"))
+	if errCode(err) != mysql.ErrWrongValueForVar {
+		t.Fatalf("expect error %d, got %v", mysql.ErrWrongValueForVar, err)
+	}
+}
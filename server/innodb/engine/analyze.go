@@ -0,0 +1,46 @@
+package engine
+
+import (
+	"github.com/juju/errors"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/statistics"
+)
+
+// rowReader reads all the rows of a table.
+type rowReader interface {
+	// Rows returns the handles and rows of tbl.
+	Rows(tbl *model.TableInfo) ([]int64, [][]basic.Datum, error)
+}
+
+// analyze runs ANALYZE TABLE compiled to p: the statistics of each table
+// it names are rebuilt from all the rows store reads and replace the ones
+// in h.
+func analyze(sc *variable.StatementContext, h *statistics.Handle, store rowReader, p *plan.Analyze) error {
+	var tables []*model.TableInfo
+	seen := make(map[int64]bool)
+	add := func(tbl *model.TableInfo) {
+		if !seen[tbl.ID] {
+			seen[tbl.ID] = true
+			tables = append(tables, tbl)
+		}
+	}
+	for _, task := range p.ColTasks {
+		add(task.TableInfo)
+	}
+	for _, task := range p.IdxTasks {
+		add(task.TableInfo)
+	}
+	for _, tbl := range tables {
+		_, rows, err := store.Rows(tbl)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if err = h.AnalyzeTable(sc, tbl, rows); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package engine
+
+import (
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/audit"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/parser"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// The status variables of the audit log.
+const (
+	StatusAuditLogEventsWritten = "Audit_log_events_written"
+	StatusAuditLogEventsLost    = "Audit_log_events_lost"
+)
+
+// auditLogStatistics reports the counters of the audit log as status
+// variables.
+type auditLogStatistics struct {
+	log *audit.Logger
+}
+
+// GetScope implements variable.Statistics GetScope interface.
+func (s auditLogStatistics) GetScope(status string) variable.ScopeFlag {
+	return variable.ScopeGlobal
+}
+
+// Stats implements variable.Statistics Stats interface.
+func (s auditLogStatistics) Stats(vars *variable.SessionVars) (map[string]interface{}, error) {
+	return map[string]interface{}{
+		StatusAuditLogEventsWritten: s.log.Written(),
+		StatusAuditLogEventsLost:    s.log.Lost(),
+	}, nil
+}
+
+// initAuditLog starts the audit log as configured, lets SET GLOBAL
+// audit_log, audit_log_policy and audit_log_rotate_on_size change it at
+// runtime and reports its counters as status variables.
+func (srv *XMySQLEngine) initAuditLog() {
+	cfg := srv.conf
+	path := cfg.AuditLogFile
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(cfg.DataDir, path)
+	}
+	l := audit.NewLogger(path, int64(cfg.AuditLogRotateOnSize), cfg.AuditLogBufferSize)
+	l.SetEnabled(cfg.AuditLog)
+	if cfg.AuditLogPolicy != "" {
+		l.SetPolicy(cfg.AuditLogPolicy)
+	}
+	srv.auditLog = l
+	registerSwitch(variable.AuditLog, l.Enabled, l.SetEnabled)
+	sv := variable.GetSysVar(variable.AuditLogFile)
+	variable.RegisterSysVar(sv, mysql.TypeVarString, func(*variable.SessionVars) (string, error) {
+		return l.Path(), nil
+	})
+	sv = variable.GetSysVar(variable.AuditLogPolicy)
+	variable.RegisterSysVar(sv, mysql.TypeVarString, func(*variable.SessionVars) (string, error) {
+		return l.Policy(), nil
+	})
+	variable.RegisterSysVarSetter(variable.AuditLogPolicy, func(_ *variable.SessionVars, value string) error {
+		if err := l.SetPolicy(value); err != nil {
+			return variable.ErrWrongValueForVar.GenByArgs(variable.AuditLogPolicy, value)
+		}
+		return nil
+	})
+	sv = variable.GetSysVar(variable.AuditLogRotateOnSize)
+	variable.RegisterSysVar(sv, mysql.TypeLonglong, func(*variable.SessionVars) (string, error) {
+		return strconv.FormatInt(l.RotateSize(), 10), nil
+	})
+	variable.RegisterSysVarSetter(variable.AuditLogRotateOnSize, func(_ *variable.SessionVars, value string) error {
+		size, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || size < 0 {
+			return variable.ErrWrongValueForVar.GenByArgs(variable.AuditLogRotateOnSize, value)
+		}
+		l.SetRotateSize(size)
+		return nil
+	})
+	variable.RegisterStatistics(auditLogStatistics{l})
+}
+
+// AuditLog returns the audit log of the server.
+func (srv *XMySQLEngine) AuditLog() *audit.Logger {
+	return srv.auditLog
+}
+
+// auditedSession is a session whose statement is audited: it remembers the
+// statement parsed and the error sent.
+type auditedSession struct {
+	innodb.MySQLServerSession
+	stmt      ast.StmtNode
+	errorCode uint16
+}
+
+func (s *auditedSession) ParseOneSQL(sql, charset, collation string) (ast.StmtNode, error) {
+	stmt, err := s.MySQLServerSession.ParseOneSQL(sql, charset, collation)
+	s.stmt = stmt
+	return stmt, err
+}
+
+func (s *auditedSession) SendError(err *mysql.SQLError) {
+	s.errorCode = err.Code
+	s.MySQLServerSession.SendError(err)
+}
+
+// queryEvent returns the audit event of query run by s in d.
+func queryEvent(s *auditedSession, query string, policy string, d time.Duration) *audit.Event {
+	vars := s.GetSessionVars()
+	e := &audit.Event{
+		Class:         audit.EventQuery,
+		ConnectionID:  vars.ConnectionID,
+		DB:            vars.CurrentDB,
+		StatementType: statementType(s.stmt),
+		SQL:           query,
+		DurationUs:    int64(d / time.Microsecond),
+		ErrorCode:     s.errorCode,
+	}
+	if vars.User != nil {
+		e.User, e.Host = vars.User.Username, vars.User.Hostname
+	}
+	if vars.StmtCtx != nil {
+		e.Retries = vars.StmtCtx.DeadlockRetries
+		if s.errorCode == 0 {
+			e.AffectedRows = vars.StmtCtx.AffectedRows()
+		}
+	}
+	if policy == audit.PolicyNormalized {
+		e.SQL = parser.Normalize(query)
+	}
+	return e
+}
+
+// statementType returns the kind of stmt in snake case, create_table for a
+// CREATE TABLE, empty when the statement didn't parse.
+func statementType(stmt ast.StmtNode) string {
+	if stmt == nil {
+		return ""
+	}
+	name := strings.TrimSuffix(reflect.TypeOf(stmt).Elem().Name(), "Stmt")
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,148 @@
+package engine
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/zhukovaskychina/xmysql-server/server/conf"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/audit"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/varsutil"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/auth"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+	"github.com/zhukovaskychina/xmysql-server/server/protocol"
+)
+
+// serverTestSession is a session of ExecuteQuery which records what is
+// sent to the client.
+type serverTestSession struct {
+	*session
+	errs []*mysql.SQLError
+	rows [][]basic.Datum
+}
+
+func (s *serverTestSession) GetLastActiveTime() time.Time { return time.Time{} }
+func (s *serverTestSession) SendOK()                      {}
+func (s *serverTestSession) SendHandleOk()                {}
+func (s *serverTestSession) SendError(err *mysql.SQLError) {
+	s.errs = append(s.errs, err)
+}
+func (s *serverTestSession) SendResultSet(fields []protocol.Field, rows innodb.RowIterator) error {
+	s.rows = nil
+	for {
+		row, err := rows.Next()
+		if row == nil || err != nil {
+			return err
+		}
+		s.rows = append(s.rows, row)
+	}
+}
+func (s *serverTestSession) SetPacketSequence(seq byte)     {}
+func (s *serverTestSession) GetCurrentDataBase() string     { return s.sessionVars.CurrentDB }
+func (s *serverTestSession) SetCurrentDatabase(name string) { s.sessionVars.CurrentDB = name }
+func (s *serverTestSession) ParseOneSQL(sql, charset, collation string) (ast.StmtNode, error) {
+	return s.ParseSingleSQL(sql, charset, collation)
+}
+func (s *serverTestSession) PrepareTxnCtx()     {}
+func (s *serverTestSession) Commit()            {}
+func (s *serverTestSession) RollbackTxn() error { return nil }
+
+func readAuditEvents(t *testing.T, path string) []audit.Event {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var events []audit.Event
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e audit.Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("line %q: %v", scanner.Text(), err)
+		}
+		events = append(events, e)
+	}
+	return events
+}
+
+func TestAuditLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "datadir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cfg := conf.NewCfg()
+	cfg.DataDir = dir
+	is := newViewTestSchema()
+	srv := &XMySQLEngine{conf: cfg, infoSchemaManager: is}
+	srv.initAuditLog()
+	defer srv.auditLog.Close()
+	s := &serverTestSession{session: newViewTestSession(t, is)}
+	s.sessionVars.ConnectionID = 3
+	s.sessionVars.User = &auth.UserIdentity{Username: "app", Hostname: "10.0.0.1"}
+
+	// Off by default.
+	srv.ExecuteQuery(s, "SELECT 1")
+	if err = varsutil.SetGlobalSystemVar(s.sessionVars, variable.AuditLog, basic.NewStringDatum("ON")); err != nil {
+		t.Fatal(err)
+	}
+	srv.ExecuteQuery(s, "SELECT 1 + 2, 'secret'")
+	srv.ExecuteQuery(s, "SELECT * FROM missing")
+	if err = varsutil.SetGlobalSystemVar(s.sessionVars, variable.AuditLogPolicy, basic.NewStringDatum("normalized")); err != nil {
+		t.Fatal(err)
+	}
+	srv.ExecuteQuery(s, "SELECT 'secret' FROM dual WHERE 1 = 1")
+	if err = varsutil.SetGlobalSystemVar(s.sessionVars, variable.AuditLogPolicy, basic.NewStringDatum("QUERIES")); err == nil {
+		t.Error("expect audit_log_policy to reject QUERIES")
+	}
+	if err = varsutil.SetGlobalSystemVar(s.sessionVars, variable.AuditLog, basic.NewStringDatum("OFF")); err != nil {
+		t.Fatal(err)
+	}
+	srv.ExecuteQuery(s, "SELECT 2")
+	srv.auditLog.Flush()
+
+	events := readAuditEvents(t, filepath.Join(dir, "audit.log"))
+	if len(events) != 3 {
+		t.Fatalf("expect 3 events, got %+v", events)
+	}
+	first := events[0]
+	if first.Class != audit.EventQuery || first.ConnectionID != 3 || first.User != "app" || first.Host != "10.0.0.1" ||
+		first.DB != "test" || first.StatementType != "select" || first.SQL != "SELECT 1 + 2, 'secret'" || first.ErrorCode != 0 {
+		t.Errorf("unexpected event %+v", first)
+	}
+	if events[1].ErrorCode != mysql.ErrNoSuchTable {
+		t.Errorf("expect error code %d, got %+v", mysql.ErrNoSuchTable, events[1])
+	}
+	if events[2].SQL != "select ? from dual where ? = ?" {
+		t.Errorf("expect the normalized SQL, got %q", events[2].SQL)
+	}
+
+	stats, err := auditLogStatistics{srv.auditLog}.Stats(s.sessionVars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats[StatusAuditLogEventsWritten] != int64(3) || stats[StatusAuditLogEventsLost] != int64(0) {
+		t.Errorf("unexpected status %v", stats)
+	}
+}
+
+func TestStatementType(t *testing.T) {
+	for stmt, want := range map[ast.StmtNode]string{
+		&ast.SelectStmt{}:      "select",
+		&ast.CreateTableStmt{}: "create_table",
+		&ast.ShowStmt{}:        "show",
+		nil:                    "",
+	} {
+		if got := statementType(stmt); got != want {
+			t.Errorf("expect %s, got %s", want, got)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package engine
+
+import (
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/buffer_pool"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
+)
+
+/**
+缓冲池中的页面属于哪些表
+
+information_schema.INNODB_BUFFER_PAGE 列出缓冲池中的每个页面和它所属的表、索引，
+INNODB_BUFFER_POOL_STATS_BY_TABLE 和 INNODB_BUFFER_POOL_STATS_BY_INDEX 按表、按索引
+汇总页面数、脏页数和占缓冲池的百分比，一条查询就能看出缓冲池被哪些表占满。
+
+缓冲池逐个实例列出页面，每个实例只在列出它的页面时短暂加锁，见
+buffer_pool/buffer_pool_pages.go；页面记下的索引 ID 按数据字典换成表名和索引名。
+**/
+
+// bufferPages reports the pages of a buffer pool, named by owners.
+type bufferPages struct {
+	pool   *buffer_pool.BufferPool
+	owners schemas.BufferPageOwners
+}
+
+// BufferPages implements schemas.BufferPages.
+func (b *bufferPages) BufferPages() ([]schemas.BufferPage, int) {
+	infos := b.pool.PageInfos()
+	pages := make([]schemas.BufferPage, len(infos))
+	for i, info := range infos {
+		page := schemas.BufferPage{
+			PoolID:     info.PoolId,
+			Space:      info.SpaceId,
+			PageNumber: info.PageNo,
+			PageType:   info.PageType,
+			DataSize:   info.DataSize,
+			Dirty:      info.Dirty,
+			Old:        info.Old,
+		}
+		if b.owners != nil {
+			page.Table, page.Index = b.owners.PageOwner(info.SpaceId, info.IndexId)
+		}
+		pages[i] = page
+	}
+	return pages, b.pool.Stats().PoolSize
+}
+
+// initBufferPages reports the pages of the pool in information_schema.
+func (srv *XMySQLEngine) initBufferPages() {
+	owners, _ := srv.infoSchemaManager.(schemas.BufferPageOwners)
+	schemas.RegisterBufferPages(&bufferPages{pool: srv.pool, owners: owners})
+}
@@ -0,0 +1,92 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/conf"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/buffer_pool"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
+)
+
+// testPageOwners names the pages of space 5 as pages of the table t1, and
+// those recorded as pages of index 7 as pages of its PRIMARY index.
+type testPageOwners struct{}
+
+func (testPageOwners) PageOwner(space uint32, indexId uint64) (string, string) {
+	switch {
+	case space != 5:
+		return "", ""
+	case indexId == 7:
+		return "`test`.`t1`", "PRIMARY"
+	}
+	return "`test`.`t1`", ""
+}
+
+func TestBufferPages(t *testing.T) {
+	fs := basic.NewFileSystem(conf.NewCfg())
+	fs.AddTableSpace(dumpTestSpace(5))
+	fs.AddTableSpace(dumpTestSpace(6))
+	pool := buffer_pool.NewBufferPool(16*16384, 0.75, 0.25, 1000, fs)
+	for _, page := range []uint32{1, 2, 3} {
+		pool.GetPageBlock(5, page)
+	}
+	pool.GetPageBlock(6, 1)
+	for _, page := range []uint32{1, 2} {
+		block := pool.GetPageBlock(5, page)
+		block.SetIndex(7, 100)
+		if page == 1 {
+			pool.UpdateBlock(5, page, block)
+		}
+	}
+	defer schemas.RegisterBufferPages(nil)
+	schemas.RegisterBufferPages(&bufferPages{pool: pool, owners: testPageOwners{}})
+
+	rows := schemas.BufferPageRows()
+	if len(rows) != 4 {
+		t.Fatalf("expect 4 pages, got %v", rows)
+	}
+	// The dirty page comes first.
+	if row := rows[0]; row[1].GetInt64() != 5 || row[2].GetInt64() != 1 || row[4].GetString() != "`test`.`t1`" ||
+		row[5].GetString() != "PRIMARY" || row[6].GetInt64() != 100 || row[7].GetString() != "YES" || row[8].GetString() != "NO" {
+		t.Fatalf("unexpected dirty page %v", row)
+	}
+	for _, row := range rows[1:] {
+		if row[7].GetString() != "NO" {
+			t.Fatalf("expect a clean page, got %v", row)
+		}
+		if row[1].GetInt64() == 6 && (!row[4].IsNull() || !row[5].IsNull()) {
+			t.Fatalf("expect a page of no table, got %v", row)
+		}
+	}
+
+	check := func(got [][]basic.Datum, expected [][]interface{}) {
+		if len(got) != len(expected) {
+			t.Fatalf("expect %d rows, got %v", len(expected), got)
+		}
+		for i, row := range got {
+			for j, v := range expected[i] {
+				if row[j].GetValue() != v {
+					t.Fatalf("row %d: expect %v, got %v", i, expected[i], row)
+				}
+			}
+		}
+	}
+	// 16 pages, 3 of t1 and 1 of no table.
+	check(schemas.BufferPoolStatsByTableRows(), [][]interface{}{
+		{"`test`.`t1`", int64(3), int64(1), int64(200), 18.75},
+		{nil, int64(1), int64(0), int64(0), 6.25},
+	})
+	check(schemas.BufferPoolStatsByIndexRows(), [][]interface{}{
+		{"`test`.`t1`", "PRIMARY", int64(2), int64(1), int64(200), 12.5},
+		{nil, nil, int64(1), int64(0), int64(0), 6.25},
+		{"`test`.`t1`", nil, int64(1), int64(0), int64(0), 6.25},
+	})
+
+	s := newViewTestSession(t, newViewTestSchema())
+	for _, table := range []string{"innodb_buffer_page", "innodb_buffer_pool_stats_by_table", "innodb_buffer_pool_stats_by_index"} {
+		if _, _, err := compileView(s, "SELECT * FROM information_schema."+table); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
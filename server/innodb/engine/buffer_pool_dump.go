@@ -0,0 +1,134 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// initBufferPoolDump lets SET GLOBAL change whether the buffer pool is
+// dumped at shutdown and where to, and dump or load it on demand with
+// innodb_buffer_pool_dump_now and innodb_buffer_pool_load_now.
+func (srv *XMySQLEngine) initBufferPoolDump() {
+	cfg := srv.conf
+	registerSwitch(variable.InnodbBufferPoolDumpAtShutdown, func() bool {
+		return cfg.InnodbBufferPoolDumpAtShutdown
+	}, func(on bool) {
+		cfg.InnodbBufferPoolDumpAtShutdown = on
+	})
+	registerSwitch(variable.InnodbBufferPoolLoadAtStartup, func() bool {
+		return cfg.InnodbBufferPoolLoadAtStartup
+	}, nil)
+	sv := variable.GetSysVar(variable.InnodbBufferPoolFilename)
+	variable.RegisterSysVar(sv, mysql.TypeVarString, func(*variable.SessionVars) (string, error) {
+		return cfg.InnodbBufferPoolFilename, nil
+	})
+	variable.RegisterSysVarSetter(variable.InnodbBufferPoolFilename, func(_ *variable.SessionVars, value string) error {
+		if value == "" {
+			return variable.ErrWrongValueForVar.GenByArgs(variable.InnodbBufferPoolFilename, value)
+		}
+		cfg.InnodbBufferPoolFilename = value
+		return nil
+	})
+	registerSwitch(variable.InnodbBufferPoolDumpNow, func() bool {
+		return false
+	}, func(on bool) {
+		if on {
+			srv.dumpBufferPool()
+		}
+	})
+	registerSwitch(variable.InnodbBufferPoolLoadNow, func() bool {
+		return false
+	}, func(on bool) {
+		if on {
+			srv.loadBufferPool()
+		}
+	})
+}
+
+// registerSwitch registers the ON/OFF variable name, read with get and set
+// with set, read-only when set is nil.
+func registerSwitch(name string, get func() bool, set func(on bool)) {
+	sv := variable.GetSysVar(name)
+	variable.RegisterSysVar(sv, mysql.TypeVarString, func(*variable.SessionVars) (string, error) {
+		if get() {
+			return "ON", nil
+		}
+		return "OFF", nil
+	})
+	if set == nil {
+		return
+	}
+	variable.RegisterSysVarSetter(name, func(_ *variable.SessionVars, value string) error {
+		switch strings.ToUpper(value) {
+		case "ON", "1", "TRUE":
+			set(true)
+		case "OFF", "0", "FALSE":
+			set(false)
+		default:
+			return variable.ErrWrongValueForVar.GenByArgs(name, value)
+		}
+		return nil
+	})
+}
+
+// bufferPoolDumpFile returns the path of innodb_buffer_pool_filename.
+func (srv *XMySQLEngine) bufferPoolDumpFile() string {
+	if filepath.IsAbs(srv.conf.InnodbBufferPoolFilename) {
+		return srv.conf.InnodbBufferPoolFilename
+	}
+	return filepath.Join(srv.conf.DataDir, srv.conf.InnodbBufferPoolFilename)
+}
+
+// dumpBufferPool writes the pages of the buffer pool to the dump file.
+func (srv *XMySQLEngine) dumpBufferPool() error {
+	path := srv.bufferPoolDumpFile()
+	n, err := srv.pool.Dump(path)
+	if err != nil {
+		log.Warnf("缓冲池页面写入 %s 失败: %v", path, err)
+		return err
+	}
+	log.Infof("缓冲池 %d 个页面写入 %s", n, path)
+	return nil
+}
+
+// loadBufferPool reads the pages of the dump file into the buffer pool in
+// the background. The channel is closed once they are read.
+func (srv *XMySQLEngine) loadBufferPool() <-chan struct{} {
+	path := srv.bufferPoolDumpFile()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		n, err := srv.pool.Load(path)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				log.Warnf("从 %s 读入缓冲池页面失败: %v", path, err)
+			}
+			return
+		}
+		log.Infof("从 %s 读入缓冲池 %d 个页面", path, n)
+	}()
+	return done
+}
+
+// Close saves the live row counts of the tables, merges the change buffer,
+// dumps the buffer pool when innodb_buffer_pool_dump_at_shutdown is on and
+// writes the dirty pages.
+func (srv *XMySQLEngine) Close() error {
+	if srv.statsHandle != nil {
+		if _, err := srv.statsHandle.SaveRowCounts(srv.rowCountsFile()); err != nil {
+			log.Warnf("表的行数写入 %s 失败: %v", srv.rowCountsFile(), err)
+		}
+	}
+	srv.mergeAllChangeBuffer()
+	var err error
+	if srv.conf.InnodbBufferPoolDumpAtShutdown {
+		err = srv.dumpBufferPool()
+	}
+	log.Infof("关闭时刷新 %d 个脏页面", srv.pool.FlushDirtyPages())
+	return err
+}
@@ -0,0 +1,84 @@
+package engine
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/conf"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/buffer_pool"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/varsutil"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// dumpTestSpace is a space whose pages are filled with their number.
+type dumpTestSpace uint32
+
+func (s dumpTestSpace) FlushToDisk(pageNo uint32, content []byte) {}
+
+func (s dumpTestSpace) LoadPageByPageNumber(pageNo uint32) ([]byte, error) {
+	return []byte{byte(pageNo)}, nil
+}
+
+func (s dumpTestSpace) GetSpaceId() uint32 {
+	return uint32(s)
+}
+
+// newDumpTestEngine returns an engine on space 5 whose data directory is
+// dir.
+func newDumpTestEngine(dir string) *XMySQLEngine {
+	cfg := conf.NewCfg()
+	cfg.DataDir = dir
+	fs := basic.NewFileSystem(cfg)
+	fs.AddTableSpace(dumpTestSpace(5))
+	srv := &XMySQLEngine{conf: cfg, pool: buffer_pool.NewBufferPool(16*16384, 0.75, 0.25, 1000, fs)}
+	srv.initBufferPoolDump()
+	return srv
+}
+
+func TestBufferPoolDumpAtShutdown(t *testing.T) {
+	dir, err := ioutil.TempDir("", "datadir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	srv := newDumpTestEngine(dir)
+	srv.pool.GetPageBlock(5, 1)
+	srv.pool.GetPageBlock(5, 2)
+	if err = srv.Close(); err != nil {
+		t.Fatal(err)
+	}
+	restarted := newDumpTestEngine(dir)
+	<-restarted.loadBufferPool()
+	if !restarted.pool.HoldsPage(5, 1) || !restarted.pool.HoldsPage(5, 2) {
+		t.Fatal("expect the dumped pages prefetched")
+	}
+
+	// With innodb_buffer_pool_dump_at_shutdown off the dump is kept.
+	s := newViewTestSession(t, newViewTestSchema())
+	err = varsutil.SetGlobalSystemVar(s.sessionVars, variable.InnodbBufferPoolDumpAtShutdown, basic.NewStringDatum("OFF"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	restarted.pool.GetPageBlock(5, 3)
+	if err = restarted.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if pages, _ := buffer_pool.ReadDumpFile(restarted.bufferPoolDumpFile()); len(pages) != 2 {
+		t.Fatalf("expect the dump of 2 pages kept, got %v", pages)
+	}
+	err = varsutil.SetGlobalSystemVar(s.sessionVars, variable.InnodbBufferPoolDumpNow, basic.NewStringDatum("ON"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pages, _ := buffer_pool.ReadDumpFile(restarted.bufferPoolDumpFile()); len(pages) != 3 {
+		t.Fatalf("expect 3 pages dumped now, got %v", pages)
+	}
+	err = varsutil.SetGlobalSystemVar(s.sessionVars, variable.InnodbBufferPoolDumpAtShutdown, basic.NewStringDatum("sometimes"))
+	if errCode(err) != mysql.ErrWrongValueForVar {
+		t.Fatalf("expect error %d, got %v", mysql.ErrWrongValueForVar, err)
+	}
+}
@@ -0,0 +1,69 @@
+package engine
+
+import (
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/buffer_pool"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// Buffer pool status variables.
+const (
+	StatusBufferPoolResizeStatus = "Innodb_buffer_pool_resize_status"
+	StatusBufferPoolPagesTotal   = "Innodb_buffer_pool_pages_total"
+	StatusBufferPoolPagesData    = "Innodb_buffer_pool_pages_data"
+	StatusBufferPoolPagesDirty   = "Innodb_buffer_pool_pages_dirty"
+	StatusBufferPoolPagesFree    = "Innodb_buffer_pool_pages_free"
+	StatusBufferPoolReadRequests = "Innodb_buffer_pool_read_requests"
+	StatusBufferPoolReads        = "Innodb_buffer_pool_reads"
+)
+
+// bufferPoolStatistics reports the counters of the buffer pool as status
+// variables.
+type bufferPoolStatistics struct {
+	pool *buffer_pool.BufferPool
+}
+
+// GetScope implements variable.Statistics GetScope interface.
+func (s bufferPoolStatistics) GetScope(status string) variable.ScopeFlag {
+	return variable.ScopeGlobal
+}
+
+// Stats implements variable.Statistics Stats interface.
+func (s bufferPoolStatistics) Stats(vars *variable.SessionVars) (map[string]interface{}, error) {
+	stats := s.pool.Stats()
+	return map[string]interface{}{
+		StatusBufferPoolResizeStatus: s.pool.ResizeStatus(),
+		StatusBufferPoolPagesTotal:   int64(stats.PoolSize),
+		StatusBufferPoolPagesData:    int64(stats.DatabasePages),
+		StatusBufferPoolPagesDirty:   int64(stats.ModifiedPages),
+		StatusBufferPoolPagesFree:    int64(stats.FreePages()),
+		StatusBufferPoolReadRequests: stats.ReadRequests,
+		StatusBufferPoolReads:        stats.PageReads,
+	}, nil
+}
+
+// initBufferPoolResize lets SET GLOBAL innodb_buffer_pool_size resize the
+// buffer pool online and reports its progress in the status variable
+// Innodb_buffer_pool_resize_status.
+func (srv *XMySQLEngine) initBufferPoolResize() {
+	pool := srv.pool
+	sv := variable.GetSysVar(variable.InnodbBufferPoolSize)
+	variable.RegisterSysVar(sv, mysql.TypeLonglong, func(*variable.SessionVars) (string, error) {
+		return strconv.FormatUint(pool.Size(), 10), nil
+	})
+	variable.RegisterSysVarSetter(variable.InnodbBufferPoolSize, func(_ *variable.SessionVars, value string) error {
+		size, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return variable.ErrWrongValueForVar.GenByArgs(variable.InnodbBufferPoolSize, value)
+		}
+		if size != pool.Size() {
+			log.Infof("缓冲池大小从 %d 调整为 %d", pool.Size(), size)
+			pool.Resize(size)
+		}
+		return nil
+	})
+	variable.RegisterStatistics(bufferPoolStatistics{pool})
+}
@@ -0,0 +1,55 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zhukovaskychina/xmysql-server/server/conf"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/buffer_pool"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/varsutil"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+func TestSetBufferPoolSize(t *testing.T) {
+	cfg := conf.NewCfg()
+	fs := basic.NewFileSystem(cfg)
+	fs.AddTableSpace(dumpTestSpace(5))
+	srv := &XMySQLEngine{conf: cfg, pool: buffer_pool.NewBufferPoolInstances(64*16384, 4, 0.75, 0.25, 1000, fs)}
+	srv.initBufferPoolResize()
+	for page := uint32(0); page < 64; page++ {
+		srv.pool.GetPageBlock(5, page)
+	}
+
+	s := newViewTestSession(t, newViewTestSchema())
+	err := varsutil.SetGlobalSystemVar(s.sessionVars, variable.InnodbBufferPoolSize, basic.NewStringDatum("262144"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := varsutil.GetGlobalSystemVar(s.sessionVars, variable.InnodbBufferPoolSize); value != "262144" {
+		t.Fatalf("expect the new size reported at once, got %s", value)
+	}
+	status := func() string {
+		vars, err := variable.GetStatusVars(s.sessionVars)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return vars[StatusBufferPoolResizeStatus].Value.(string)
+	}
+	for deadline := time.Now().Add(5 * time.Second); !strings.HasPrefix(status(), "Completed"); {
+		if time.Now().After(deadline) {
+			t.Fatalf("expect the resize to complete, got %s", status())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if stats := srv.pool.Stats(); stats.PoolSize != 16 || stats.DatabasePages > 16 {
+		t.Fatalf("expect the pool shrunk to 16 pages, got %+v", stats)
+	}
+
+	err = varsutil.SetGlobalSystemVar(s.sessionVars, variable.InnodbBufferPoolSize, basic.NewStringDatum("big"))
+	if errCode(err) != mysql.ErrWrongValueForVar {
+		t.Fatalf("expect error %d, got %v", mysql.ErrWrongValueForVar, err)
+	}
+}
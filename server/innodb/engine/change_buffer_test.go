@@ -0,0 +1,100 @@
+package engine
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/conf"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/buffer_pool"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/innodb_store/store"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/tuple"
+	"github.com/zhukovaskychina/xmysql-server/util"
+)
+
+// storedSpace is a space keeping the pages written to it across restarts.
+type storedSpace struct {
+	id    uint32
+	pages map[uint32][]byte
+}
+
+func (s *storedSpace) FlushToDisk(pageNo uint32, content []byte) {
+	s.pages[pageNo] = append([]byte(nil), content...)
+}
+
+func (s *storedSpace) LoadPageByPageNumber(pageNo uint32) ([]byte, error) {
+	if content, ok := s.pages[pageNo]; ok {
+		return append([]byte(nil), content...), nil
+	}
+	return []byte{byte(pageNo)}, nil
+}
+
+func (s *storedSpace) GetSpaceId() uint32 {
+	return s.id
+}
+
+// newChangeBufferTestEngine returns an engine on space, whose index idx
+// has the rows of SYS_TABLES, with its data directory in dir.
+func newChangeBufferTestEngine(dir string, space *storedSpace) *XMySQLEngine {
+	cfg := conf.NewCfg()
+	cfg.DataDir = dir
+	fs := basic.NewFileSystem(cfg)
+	fs.AddTableSpace(space)
+	srv := &XMySQLEngine{conf: cfg, pool: buffer_pool.NewBufferPool(16*16384, 0.75, 0.25, 1000, fs)}
+	srv.pool.ChangeBuffer.RegisterMerger(space.id, "idx", store.IndexPageMerger(store.NewSysTableTuple(),
+		func(content []byte, tableTuple tuple.TableRowTuple) basic.Row {
+			return store.NewClusterSysIndexLeafRowWithContent(content, tableTuple)
+		}))
+	return srv
+}
+
+// newSysTablesRow returns a row of SYS_TABLES for the table name.
+func newSysTablesRow(sysTuple tuple.TableRowTuple, name string) basic.Row {
+	row := store.NewClusterSysIndexLeafRow(sysTuple, false)
+	for i, value := range [][]byte{
+		util.ConvertULong8Bytes(1), util.ConvertULong8Bytes(1), util.ConvertULong8Bytes(1),
+		[]byte(name), make([]byte, 8), util.ConvertULong8Bytes(uint64(sysTuple.GetColumnLength())),
+		util.ConvertULong8Bytes(1), []byte("Antelope"), []byte("Redundant"), util.ConvertULong8Bytes(0), []byte("space"),
+	} {
+		row.WriteBytesWithNullWithsPos(value, byte(i))
+	}
+	return row
+}
+
+func TestChangeBufferMergedAtClose(t *testing.T) {
+	dir, err := ioutil.TempDir("", "datadir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sysTuple := store.NewSysTableTuple()
+	page := store.NewPageIndexWithTuple(10, 7, sysTuple).(*store.Index)
+	page.AddRow(newSysTablesRow(sysTuple, "test/t1"))
+	space := &storedSpace{id: 10, pages: map[uint32][]byte{7: page.ToByte()}}
+
+	srv := newChangeBufferTestEngine(dir, space)
+	for _, name := range []string{"test/t2", "test/t3"} {
+		if !srv.pool.BufferInsert(10, 7, "idx", newSysTablesRow(sysTuple, name).ToByte()) {
+			t.Fatalf("expect the insert of %s to be buffered", name)
+		}
+	}
+	// A dirty page of a space dropped since is discarded.
+	frame := []byte{1}
+	srv.pool.UpdateBlock(11, 1, buffer_pool.NewBufferBlock(&frame, 11, 1))
+	if err = srv.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// After a restart the index has the buffered rows, none left buffered.
+	restarted := newChangeBufferTestEngine(dir, space)
+	if restarted.pool.ChangeBuffer.Has(10, 7) {
+		t.Fatal("expect nothing buffered after the restart")
+	}
+	block := restarted.pool.GetPageBlock(10, 7)
+	index := store.NewPageIndexByLoadBytesWithTuple(*block.Frame, sysTuple).(*store.Index)
+	if index.GetRecordSize() != 3 {
+		t.Fatalf("expect 3 rows in the index, got %d", index.GetRecordSize())
+	}
+}
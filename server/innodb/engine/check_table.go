@@ -0,0 +1,49 @@
+package engine
+
+import (
+	"github.com/juju/errors"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
+)
+
+// changeBufferMerger merges the inserts buffered for the pages of a space.
+type changeBufferMerger interface {
+	// MergeChangeBufferOfSpace merges every page of space with buffered
+	// inserts and returns their number.
+	MergeChangeBufferOfSpace(space uint32) int
+}
+
+// checkTable runs CHECK TABLE compiled to p. The inserts buffered for the
+// indexes of each table are merged first, so that the check sees them
+// whole. It returns the rows of the result: Table, Op, Msg_type and
+// Msg_text.
+func checkTable(is schemas.InfoSchema, pool changeBufferMerger, p *plan.CheckTable) ([][]basic.Datum, error) {
+	rows := make([][]basic.Datum, 0, len(p.Tables))
+	for _, tn := range p.Tables {
+		tbl, err := schemas.TableByName(is, tn.Schema, tn.Name)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		pool.MergeChangeBufferOfSpace(tbl.SpaceId())
+		rows = append(rows, basic.MakeDatums(tn.Schema.O+"."+tn.Name.O, "check", "status", "OK"))
+	}
+	return rows, nil
+}
+
+func init() {
+	registerStmtHandler(&ast.AdminStmt{}, &stmtHandler{name: "admin check table", handle: (*XMySQLEngine).execAdmin})
+}
+
+// execAdmin runs an ADMIN CHECK TABLE.
+func (srv *XMySQLEngine) execAdmin(session innodb.MySQLServerSession, stmt ast.StmtNode, p plan.Plan) {
+	if v, ok := p.(*plan.CheckTable); ok {
+		if _, err := checkTable(srv.infoSchemaManager, srv.pool, v); err != nil {
+			session.SendError(toSQLError(err))
+			return
+		}
+		session.SendOK()
+	}
+}
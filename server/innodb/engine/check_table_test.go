@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/conf"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/buffer_pool"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/varsutil"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// spaceTestTable is a table in space spaceId.
+type spaceTestTable struct {
+	*viewTestTable
+	spaceId uint32
+}
+
+func (t *spaceTestTable) SpaceId() uint32 {
+	return t.spaceId
+}
+
+// testChangeBufferMerger records the spaces merged.
+type testChangeBufferMerger []uint32
+
+func (m *testChangeBufferMerger) MergeChangeBufferOfSpace(space uint32) int {
+	*m = append(*m, space)
+	return 1
+}
+
+func TestCheckTable(t *testing.T) {
+	is := newViewTestSchema(newFKTestTable("t1", "id"), newFKTestTable("t2", "id"))
+	is.tables["t1"] = &spaceTestTable{viewTestTable: is.tables["t1"].(*viewTestTable), spaceId: 11}
+	is.tables["t2"] = &spaceTestTable{viewTestTable: is.tables["t2"].(*viewTestTable), spaceId: 12}
+	s := newViewTestSession(t, is)
+
+	var merged testChangeBufferMerger
+	_, p, err := compileView(s, "CHECK TABLE t1, test.t2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := checkTable(is, &merged, p.(*plan.CheckTable))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(merged) != 2 || merged[0] != 11 || merged[1] != 12 {
+		t.Fatalf("expect the change buffer of spaces 11 and 12 merged, got %v", merged)
+	}
+	if len(rows) != 2 || rows[0][0].GetString() != "test.t1" || rows[1][3].GetString() != "OK" {
+		t.Fatalf("unexpected rows %v", rows)
+	}
+	if _, _, err = compileView(s, "CHECK TABLE t3"); err == nil {
+		t.Fatal("expect an error checking a missing table")
+	}
+}
+
+func TestChangeBufferingVariable(t *testing.T) {
+	cfg := conf.NewCfg()
+	cfg.InnodbChangeBuffering = "inserts"
+	srv := &XMySQLEngine{conf: cfg, pool: buffer_pool.NewBufferPool(16*16384, 0.75, 0.25, 1000, nil)}
+	srv.initChangeBuffer()
+	s := newViewTestSession(t, newViewTestSchema())
+	if val, _ := varsutil.GetGlobalSystemVar(s.sessionVars, variable.InnodbChangeBuffering); val != "inserts" {
+		t.Fatalf("expect inserts, got %s", val)
+	}
+	err := varsutil.SetGlobalSystemVar(s.sessionVars, variable.InnodbChangeBuffering, basic.NewStringDatum("NONE"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if srv.pool.ChangeBuffer.Mode() != buffer_pool.ChangeBufferingNone {
+		t.Fatalf("expect buffering off, got %s", srv.pool.ChangeBuffer.Mode())
+	}
+	err = varsutil.SetGlobalSystemVar(s.sessionVars, variable.InnodbChangeBuffering, basic.NewStringDatum("sometimes"))
+	if errCode(err) != mysql.ErrWrongValueForVar {
+		t.Fatalf("expect error %d, got %v", mysql.ErrWrongValueForVar, err)
+	}
+}
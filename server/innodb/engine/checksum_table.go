@@ -0,0 +1,268 @@
+package engine
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"math"
+
+	"github.com/juju/errors"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/innodb_store/store"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/charset"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+/**
+CHECKSUM TABLE
+
+按 MySQL 的算法计算表中的行的校验和，用来比较两个服务器上的数据是否相同。
+每行的校验和从 0 开始用 crc32 依次累加：
+	NULL 位图：每个可以为 NULL 的列一位，NULL 的列置 1，最后一个字节没有用到的高位都置 1
+	每个不为 NULL 的列：整数、浮点数、YEAR、CHAR、ENUM 和 SET 按它们在记录中的定长格式（小端序），
+	VARCHAR、BLOB、TEXT、BIT 和 JSON 按值的字节，DECIMAL 和时间类型按它们的字符串
+表的校验和是所有行的校验和之和的低 32 位，空表是 0。
+
+不存在的表返回 NULL 并产生一个警告。InnoDB 不维护实时的校验和，CHECKSUM TABLE ... QUICK 返回 NULL。
+**/
+
+// tableRowsReader reads all the rows of a table.
+type tableRowsReader interface {
+	// TableRows returns the rows of tbl in the order of its columns.
+	TableRows(tbl schemas.Table) ([][]basic.Datum, error)
+}
+
+// checksumTable runs CHECKSUM TABLE compiled to p, reading the rows with
+// reader. It returns the rows of the result: Table and Checksum.
+func checksumTable(sc *variable.StatementContext, is schemas.InfoSchema, reader tableRowsReader, p *plan.ChecksumTable) ([][]basic.Datum, error) {
+	result := make([][]basic.Datum, 0, len(p.Tables))
+	for _, tn := range p.Tables {
+		name := tn.Schema.O + "." + tn.Name.O
+		tbl, err := is.TableByName(tn.Schema, tn.Name)
+		if err != nil || tbl == nil {
+			sc.AppendWarning(schemas.ErrTableNotExists.GenByArgs(tn.Schema.O, tn.Name.O))
+			result = append(result, basic.MakeDatums(name, nil))
+			continue
+		}
+		if p.Quick {
+			result = append(result, basic.MakeDatums(name, nil))
+			continue
+		}
+		rows, err := reader.TableRows(tbl)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if store.IsSpaceCorrupted(tbl.SpaceId()) {
+			return nil, ErrCrashedOnUsage.GenByArgs(tn.Name.O)
+		}
+		var sum uint32
+		for _, row := range rows {
+			crc, err := rowChecksum(sc, tbl.Meta().Columns, row)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			sum += crc
+		}
+		result = append(result, basic.MakeDatums(name, int64(sum)))
+	}
+	return result, nil
+}
+
+// rowChecksum returns the checksum of row, whose columns are cols.
+func rowChecksum(sc *variable.StatementContext, cols []*model.ColumnInfo, row []basic.Datum) (uint32, error) {
+	var nulls []byte
+	nullable := 0
+	for i, col := range cols {
+		if mysql.HasNotNullFlag(col.Flag) {
+			continue
+		}
+		if nullable%8 == 0 {
+			nulls = append(nulls, 0)
+		}
+		if row[i].IsNull() {
+			nulls[nullable/8] |= 1 << uint(nullable%8)
+		}
+		nullable++
+	}
+	var crc uint32
+	if nullable > 0 {
+		// The bits after the last column are set.
+		if n := nullable % 8; n != 0 {
+			nulls[len(nulls)-1] |= ^byte(0) << uint(n)
+		}
+		crc = crc32.Update(crc, crc32.IEEETable, nulls)
+	}
+	for i, col := range cols {
+		if row[i].IsNull() {
+			continue
+		}
+		image, err := fieldImage(sc, col, row[i])
+		if err != nil {
+			return 0, errors.Trace(err)
+		}
+		crc = crc32.Update(crc, crc32.IEEETable, image)
+	}
+	return crc, nil
+}
+
+// fieldImage returns the bytes of the value d of the column col the
+// checksum of a row adds up.
+func fieldImage(sc *variable.StatementContext, col *model.ColumnInfo, d basic.Datum) ([]byte, error) {
+	switch col.Tp {
+	case mysql.TypeTiny:
+		return intImage(sc, d, 1)
+	case mysql.TypeShort:
+		return intImage(sc, d, 2)
+	case mysql.TypeInt24:
+		return intImage(sc, d, 3)
+	case mysql.TypeLong:
+		return intImage(sc, d, 4)
+	case mysql.TypeLonglong:
+		return intImage(sc, d, 8)
+	case mysql.TypeYear:
+		year, err := d.ToInt64(sc)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if year > 0 {
+			year -= 1900
+		}
+		return []byte{byte(year)}, nil
+	case mysql.TypeFloat, mysql.TypeDouble:
+		f, err := d.ToFloat64(sc)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if col.Tp == mysql.TypeFloat {
+			image := make([]byte, 4)
+			binary.LittleEndian.PutUint32(image, math.Float32bits(float32(f)))
+			return image, nil
+		}
+		image := make([]byte, 8)
+		binary.LittleEndian.PutUint64(image, math.Float64bits(f))
+		return image, nil
+	case mysql.TypeString:
+		// CHAR is padded with spaces to its length in bytes, BINARY with
+		// zeros.
+		str, err := d.ToString()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		pad := byte(' ')
+		if col.Charset == charset.CharsetBin {
+			pad = 0
+		}
+		image := []byte(str)
+		for n := col.Flen * charset.GetMaxBytesPerChar(col.Charset); len(image) < n; {
+			image = append(image, pad)
+		}
+		return image, nil
+	case mysql.TypeEnum:
+		size := 1
+		if len(col.Elems) > 255 {
+			size = 2
+		}
+		return uintImage(enumSetValue(sc, d), size), nil
+	case mysql.TypeSet:
+		size := (len(col.Elems) + 7) / 8
+		if size > 4 {
+			size = 8
+		}
+		return uintImage(enumSetValue(sc, d), size), nil
+	}
+	str, err := d.ToString()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return []byte(str), nil
+}
+
+// intImage returns the size low bytes of the integer d, little endian.
+func intImage(sc *variable.StatementContext, d basic.Datum, size int) ([]byte, error) {
+	var v uint64
+	switch d.Kind() {
+	case basic.KindUint64:
+		v = d.GetUint64()
+	case basic.KindInt64:
+		v = uint64(d.GetInt64())
+	default:
+		i, err := d.ToInt64(sc)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		v = uint64(i)
+	}
+	return uintImage(v, size), nil
+}
+
+// enumSetValue returns the index of the ENUM or the bits of the SET d.
+func enumSetValue(sc *variable.StatementContext, d basic.Datum) uint64 {
+	switch d.Kind() {
+	case basic.KindMysqlEnum:
+		return d.GetMysqlEnum().Value
+	case basic.KindMysqlSet:
+		return d.GetMysqlSet().Value
+	}
+	v, _ := d.ToInt64(sc)
+	return uint64(v)
+}
+
+func uintImage(v uint64, size int) []byte {
+	image := make([]byte, 8)
+	binary.LittleEndian.PutUint64(image, v)
+	return image[:size]
+}
+
+// scanRowsReader reads the rows of the tables through their clustered
+// index.
+type scanRowsReader struct {
+	ctx  context.Context
+	pool pagePinner
+	// consistent reads the versions of the rows the read view of the
+	// transaction of ctx sees, instead of the latest ones.
+	consistent bool
+}
+
+func (r *scanRowsReader) TableRows(tbl schemas.Table) ([][]basic.Datum, error) {
+	scan := NewTableScanExec(r.ctx, tbl, r.pool)
+	if err := scan.Open(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer scan.Close()
+	var rows [][]basic.Datum
+	for scan.Next() {
+		rows = append(rows, scan.GetRow().ToDatum())
+	}
+	if err := scan.Err(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if v, ok := r.ctx.Value(readViewKey).(*txnReadView); ok && r.consistent && v.versions != nil {
+		return v.versions.visible(tbl.Meta(), v.view, rows)
+	}
+	return rows, nil
+}
+
+func init() {
+	registerStmtHandler(&ast.ChecksumTableStmt{}, &stmtHandler{name: "checksum table", handle: (*XMySQLEngine).execChecksumTable})
+}
+
+// execChecksumTable sends the checksums of a CHECKSUM TABLE.
+func (srv *XMySQLEngine) execChecksumTable(session innodb.MySQLServerSession, stmt ast.StmtNode, p plan.Plan) {
+	if v, ok := p.(*plan.ChecksumTable); ok {
+		reader := &scanRowsReader{ctx: session, pool: srv.pool, consistent: true}
+		rows, err := checksumTable(session.GetSessionVars().StmtCtx, srv.infoSchemaManager, reader, v)
+		if err != nil {
+			session.SendError(toSQLError(err))
+			return
+		}
+		if err = session.SendResultSet(planColumns(p), innodb.SliceRows(rows)); err != nil {
+			session.SendError(toSQLError(err))
+		}
+	}
+}
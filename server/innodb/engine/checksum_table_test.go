@@ -0,0 +1,80 @@
+package engine
+
+import (
+	"hash/crc32"
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/innodb_store/store"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// testTableRows are the rows of the tables, by name.
+type testTableRows map[string][][]basic.Datum
+
+func (r testTableRows) TableRows(tbl schemas.Table) ([][]basic.Datum, error) {
+	return r[tbl.Meta().Name.L], nil
+}
+
+func TestChecksumTable(t *testing.T) {
+	t1 := newFKTestTable("t1", "id", "b")
+	t1.Columns[0].Flag |= mysql.NotNullFlag
+	t1.Columns[1].FieldType = *basic.NewFieldType(mysql.TypeString)
+	t1.Columns[1].Flen = 3
+	t1.Columns[1].Charset = "latin1"
+	is := newViewTestSchema(t1, newFKTestTable("t2", "id"))
+	is.tables["t1"] = &spaceTestTable{viewTestTable: is.tables["t1"].(*viewTestTable), spaceId: 902}
+	is.tables["t2"] = &spaceTestTable{viewTestTable: is.tables["t2"].(*viewTestTable), spaceId: 903}
+	s := newViewTestSession(t, is)
+	reader := testTableRows{
+		"t1": {basic.MakeDatums(int64(1), "ab"), basic.MakeDatums(int64(-1), nil)},
+		"t2": {basic.MakeDatums(int64(1))},
+	}
+
+	_, p, err := compileView(s, "CHECKSUM TABLE t1, test.t2, t3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := checksumTable(s.sessionVars.StmtCtx, is, reader, p.(*plan.ChecksumTable))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The NULL bitmap of b, then id as 4 bytes and b padded to 3 bytes.
+	want := crc32.ChecksumIEEE([]byte{0xfe, 1, 0, 0, 0, 'a', 'b', ' '}) +
+		crc32.ChecksumIEEE([]byte{0xff, 0xff, 0xff, 0xff, 0xff})
+	if len(rows) != 3 || rows[0][0].GetString() != "test.t1" || rows[0][1].GetInt64() != int64(want) {
+		t.Fatalf("expect checksum %d of test.t1, got %v", want, rows)
+	}
+	if want = crc32.ChecksumIEEE([]byte{0xfe, 1, 0, 0, 0}); rows[1][1].GetInt64() != int64(want) {
+		t.Errorf("expect checksum %d of test.t2, got %v", want, rows[1])
+	}
+	if rows[2][0].GetString() != "test.t3" || !rows[2][1].IsNull() {
+		t.Errorf("expect NULL for the missing table, got %v", rows[2])
+	}
+	if warns := s.sessionVars.StmtCtx.GetWarnings(); len(warns) != 1 || !schemas.ErrTableNotExists.Equal(warns[0]) {
+		t.Errorf("expect a warning for the missing table, got %v", warns)
+	}
+	if cols := planColumns(p); len(cols) != 2 || cols[0].Name != "Table" || cols[1].Name != "Checksum" {
+		t.Errorf("unexpected columns %v", cols)
+	}
+
+	_, p, err = compileView(s, "CHECKSUM TABLE t1 QUICK")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err = checksumTable(s.sessionVars.StmtCtx, is, reader, p.(*plan.ChecksumTable))
+	if err != nil || len(rows) != 1 || !rows[0][1].IsNull() {
+		t.Fatalf("expect NULL for QUICK, got %v, %v", rows, err)
+	}
+
+	store.MarkSpaceCorrupted(903)
+	_, p, err = compileView(s, "CHECKSUM TABLE t2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = checksumTable(s.sessionVars.StmtCtx, is, reader, p.(*plan.ChecksumTable)); !ErrCrashedOnUsage.Equal(err) {
+		t.Fatalf("expect the table marked as crashed, got %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+	"github.com/zhukovaskychina/xmysql-server/server/protocol"
+)
+
+// ResultColumns returns the column definitions of the result set of stmt,
+// compiled to p, nil when it doesn't return rows. The columns read from a
+// table carry its database and names, the ones computed only a name. Binary
+// strings are sent in the binary charset.
+func ResultColumns(stmt ast.StmtNode, p plan.Plan) []protocol.Field {
+	rs, ok := stmt.(ast.ResultSetNode)
+	if !ok {
+		return nil
+	}
+	rfs := rs.GetResultFields()
+	cols := p.Schema().Columns
+	if len(rfs) != len(cols) {
+		return nil
+	}
+	fields := make([]protocol.Field, 0, len(rfs))
+	for i, rf := range rfs {
+		tp := cols[i].RetType
+		field := protocol.Field{
+			Schema: rf.DBName.O,
+			Table:  rf.TableAsName.O,
+			Name:   rf.ColumnAsName.O,
+			Types:  int(tp.Tp),
+			Flags:  int(tp.Flag),
+		}
+		// ENUM and SET are sent as strings of their member names, told apart
+		// by their flag.
+		switch tp.Tp {
+		case mysql.TypeEnum:
+			field.Types, field.Flags = int(mysql.TypeString), field.Flags|int(mysql.EnumFlag)
+		case mysql.TypeSet:
+			field.Types, field.Flags = int(mysql.TypeString), field.Flags|int(mysql.SetFlag)
+		}
+		if basic.IsBinaryStr(tp) {
+			field.Charset = mysql.BinaryCollationID
+		}
+		if rf.Table != nil {
+			field.OrgTable = rf.Table.Name.O
+		}
+		if rf.Column != nil {
+			field.OrgName = rf.Column.Name.O
+		}
+		if field.Table == "" {
+			field.Table = field.OrgTable
+		}
+		if field.Name == "" {
+			field.Name = field.OrgName
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// planColumns returns the column definitions of the rows p returns, for the
+// statements other than result sets which return rows, such as CHECKSUM
+// TABLE.
+func planColumns(p plan.Plan) []protocol.Field {
+	cols := p.Schema().Columns
+	fields := make([]protocol.Field, 0, len(cols))
+	for _, col := range cols {
+		field := protocol.Field{
+			Name:  col.ColName.O,
+			Types: int(col.RetType.Tp),
+			Flags: int(col.RetType.Flag),
+		}
+		if basic.IsBinaryStr(col.RetType) {
+			field.Charset = mysql.BinaryCollationID
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
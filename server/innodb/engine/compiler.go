@@ -1,20 +1,40 @@
 package engine
 
 import (
+	"strconv"
+	"sync/atomic"
+	"time"
+
 	"github.com/juju/errors"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/resolver"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/varsutil"
 )
 
 // Compile is safe for concurrent use by multiple goroutines.
 func Compile(ctx context.Context, rawStmt ast.StmtNode) (plan.Plan, error) {
+	return compile(ctx, rawStmt, false)
+}
+
+// compile compiles rawStmt, which may hold the placeholders of a prepared
+// statement when inPrepare is set.
+func compile(ctx context.Context, rawStmt ast.StmtNode, inPrepare bool) (plan.Plan, error) {
 	info := ctx.GetSessionVars().TxnCtx.InfoSchema.(schemas.InfoSchema)
 
 	node := rawStmt
-	err := resolver.ResolveName(node, info, ctx)
+	err := plan.Validate(node, inPrepare)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	err = plan.ExpandWith(ctx, node)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	err = resolver.ResolveName(node, info, ctx)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -26,6 +46,56 @@ func Compile(ctx context.Context, rawStmt ast.StmtNode) (plan.Plan, error) {
 	return p, nil
 }
 
+// ResetStmtCtx resets the StatementContext before executing a statement.
+// The statement start time is fixed here so that NOW() and friends stay
+// stable for the whole statement, a kill of the previous statement is
+// forgotten, and the memory of the statement is tracked from here.
+func ResetStmtCtx(ctx context.Context, s ast.StmtNode) {
+	sessVars := ctx.GetSessionVars()
+	atomic.StoreUint32(&sessVars.Killed, 0)
+	sc := new(variable.StatementContext)
+	sc.TimeZone = sessVars.GetTimeZone()
+	sc.NowTs = time.Now()
+	sc.MemTracker = newStmtMemTracker(sessVars)
+	if value, err := varsutil.GetSessionSystemVar(sessVars, variable.GroupConcatMaxLen); err == nil {
+		sc.GroupConcatMaxLen, _ = strconv.ParseUint(value, 10, 64)
+	}
+
+	switch stmt := s.(type) {
+	case *ast.UpdateStmt:
+		sc.InUpdateOrDeleteStmt = true
+		sc.TruncateAsWarning = !sessVars.StrictSQLMode || stmt.IgnoreErr
+		sc.OverflowAsWarning = !sessVars.StrictSQLMode || stmt.IgnoreErr
+		sc.DividedByZeroAsWarning = !sessVars.StrictSQLMode || stmt.IgnoreErr
+	case *ast.DeleteStmt:
+		sc.InUpdateOrDeleteStmt = true
+		sc.TruncateAsWarning = !sessVars.StrictSQLMode || stmt.IgnoreErr
+		sc.DividedByZeroAsWarning = !sessVars.StrictSQLMode || stmt.IgnoreErr
+	case *ast.InsertStmt:
+		sc.InInsertStmt = true
+		sc.TruncateAsWarning = !sessVars.StrictSQLMode || stmt.IgnoreErr
+		sc.OverflowAsWarning = !sessVars.StrictSQLMode || stmt.IgnoreErr
+		sc.DividedByZeroAsWarning = !sessVars.StrictSQLMode || stmt.IgnoreErr
+	case *ast.ImportTableStmt:
+		// The rows of the file are checked like inserted ones.
+		sc.InInsertStmt = true
+		sc.TruncateAsWarning = !sessVars.StrictSQLMode
+		sc.OverflowAsWarning = !sessVars.StrictSQLMode
+	case *ast.AlterTableStmt:
+		// The rows an ALTER TABLE converts are checked like inserted ones.
+		sc.TruncateAsWarning = !sessVars.StrictSQLMode
+		sc.OverflowAsWarning = !sessVars.StrictSQLMode
+	case *ast.SelectStmt:
+		sc.InSelectStmt = true
+		sc.IgnoreTruncate = true
+		sc.DividedByZeroAsWarning = true
+	default:
+		sc.IgnoreTruncate = true
+		sc.IgnoreZeroInDate = true
+	}
+	sessVars.StmtCtx = sc
+}
+
 type PreparedStatement struct {
 }
 
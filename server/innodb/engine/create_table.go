@@ -0,0 +1,119 @@
+package engine
+
+import (
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ddl"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/varsutil"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// innodbEngine is the name of the only storage engine of the server.
+const innodbEngine = "InnoDB"
+
+// storageEngineName returns the name of the storage engine called name in
+// any case, ER_UNKNOWN_STORAGE_ENGINE when the server has no such engine.
+func storageEngineName(name string) (string, error) {
+	if strings.EqualFold(name, innodbEngine) {
+		return innodbEngine, nil
+	}
+	return "", ErrUnknownStorageEngine.GenByArgs(name)
+}
+
+// initDefaultStorageEngine makes default_storage_engine start at the value
+// of the configuration file.
+func (srv *XMySQLEngine) initDefaultStorageEngine() {
+	if srv.conf.DefaultStorageEngine != "" {
+		variable.GetSysVar(variable.DefaultStorageEngine).Value = srv.conf.DefaultStorageEngine
+	}
+}
+
+// tableEngine returns the storage engine of the table stmt creates: its
+// ENGINE option, the last one of several, else default_storage_engine. As
+// in MySQL, an engine the server doesn't have is replaced by InnoDB with a
+// warning, unless sql_mode has NO_ENGINE_SUBSTITUTION, which makes it an
+// error.
+func tableEngine(vars *variable.SessionVars, stmt *ast.CreateTableStmt) (string, error) {
+	var name string
+	for _, op := range stmt.Options {
+		if op.Tp == ast.TableOptionEngine {
+			name = op.StrValue
+		}
+	}
+	if name == "" {
+		defaultEngine, err := varsutil.GetSessionSystemVar(vars, variable.DefaultStorageEngine)
+		if err != nil {
+			return "", errors.Trace(err)
+		}
+		name = defaultEngine
+	}
+	engine, err := storageEngineName(name)
+	if err == nil {
+		return engine, nil
+	}
+	if vars.SQLMode&mysql.ModeNoEngineSubstitution != 0 {
+		return "", err
+	}
+	vars.StmtCtx.AppendWarning(ErrWarnUsingOtherHandler.GenByArgs(innodbEngine, stmt.Table.Name.O))
+	return innodbEngine, nil
+}
+
+// createTable checks a resolved CREATE TABLE statement and returns the
+// definition of its table, nil for CREATE TABLE IF NOT EXISTS of a table
+// that exists. The tables its foreign keys reference must exist and have an
+// index on the referenced columns.
+func createTable(ctx context.Context, is schemas.InfoSchema, stmt *ast.CreateTableStmt) (*model.TableInfo, error) {
+	schema, name := stmt.Table.Schema, stmt.Table.Name
+	if _, ok := is.SchemaByName(schema); !ok {
+		return nil, schemas.ErrDatabaseNotExists.GenByArgs(schema.O)
+	}
+	if old, err := is.TableByName(schema, name); err == nil && old != nil {
+		if !stmt.IfNotExists {
+			return nil, schemas.ErrTableExists.GenByArgs(name.O)
+		}
+		ctx.GetSessionVars().StmtCtx.AppendWarning(schemas.ErrTableExists.GenByArgs(name.O))
+		return nil, nil
+	}
+	engine, err := tableEngine(ctx.GetSessionVars(), stmt)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	info, err := ddl.BuildTableInfo(ctx, stmt)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err = ddl.CheckForeignKeys(is, schema, info); err != nil {
+		return nil, errors.Trace(err)
+	}
+	info.Name, info.Engine, info.State = model.StoredTableName(name), engine, model.StatePublic
+	return info, nil
+}
+
+func init() {
+	registerStmtHandler(&ast.CreateTableStmt{}, &stmtHandler{name: "create table", handle: (*XMySQLEngine).execCreateTable})
+}
+
+// execCreateTable runs a CREATE TABLE.
+func (srv *XMySQLEngine) execCreateTable(session innodb.MySQLServerSession, stmt ast.StmtNode, p plan.Plan) {
+	x := stmt.(*ast.CreateTableStmt)
+	info, err := createTable(session, srv.infoSchemaManager, x)
+	if err != nil {
+		session.SendError(toSQLError(err))
+		return
+	}
+	if info == nil {
+		session.SendOK()
+		return
+	}
+	// The store has no way yet to write the pages and the .frm of a
+	// new table.
+	session.SendError(mysql.NewErrf(mysql.ErrNotSupportedYet, "CREATE TABLE"))
+}
@@ -0,0 +1,125 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+func TestCreateTableEngine(t *testing.T) {
+	is := newViewTestSchema(&model.TableInfo{Name: model.NewCIStr("t")})
+	s := newViewTestSession(t, is)
+	create := func(sql string) (*model.TableInfo, error) {
+		stmt, _, err := compileView(s, sql)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return createTable(s, is, stmt.(*ast.CreateTableStmt))
+	}
+	warnings := func() []error {
+		return s.sessionVars.StmtCtx.GetWarnings()
+	}
+
+	for _, sql := range []string{
+		"CREATE TABLE a (id INT) ENGINE=InnoDB",
+		"CREATE TABLE a (id INT) ENGINE=innodb",
+		"CREATE TABLE a (id INT)",
+	} {
+		info, err := create(sql)
+		if err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+		if info.Engine != "InnoDB" || len(warnings()) != 0 {
+			t.Fatalf("%s: expect InnoDB without warning, got %s %v", sql, info.Engine, warnings())
+		}
+	}
+
+	// NO_ENGINE_SUBSTITUTION, in the default sql_mode, refuses the engines
+	// the server doesn't have.
+	if _, err := create("CREATE TABLE a (id INT) ENGINE=MEMORY"); errCode(err) != mysql.ErrUnknownStorageEngine {
+		t.Fatalf("expect error %d, got %v", mysql.ErrUnknownStorageEngine, err)
+	}
+
+	// Without it they are replaced by InnoDB with a warning.
+	if err := execSet(t, s, "SET sql_mode = 'STRICT_TRANS_TABLES'"); err != nil {
+		t.Fatal(err)
+	}
+	info, err := create("CREATE TABLE a (id INT) ENGINE=MEMORY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Engine != "InnoDB" || len(warnings()) != 1 || errCode(warnings()[0]) != mysql.ErrWarnUsingOtherHandler {
+		t.Fatalf("expect InnoDB with warning %d, got %s %v", mysql.ErrWarnUsingOtherHandler, info.Engine, warnings())
+	}
+
+	if _, err = create("CREATE TABLE t (id INT)"); errCode(err) != mysql.ErrTableExists {
+		t.Fatalf("expect error %d, got %v", mysql.ErrTableExists, err)
+	}
+	if info, err = create("CREATE TABLE IF NOT EXISTS t (id INT)"); err != nil || info != nil || len(warnings()) != 1 {
+		t.Fatalf("expect a warning only, got %v %v %v", info, err, warnings())
+	}
+}
+
+func TestDefaultStorageEngine(t *testing.T) {
+	defer func(value string) {
+		variable.SysVars[variable.DefaultStorageEngine].Value = value
+	}(variable.SysVars[variable.DefaultStorageEngine].Value)
+	s := newViewTestSession(t, newViewTestSchema())
+
+	if err := execSet(t, s, "SET default_storage_engine = innodb"); err != nil {
+		t.Fatal(err)
+	}
+	if got := sessionVar(t, s, variable.DefaultStorageEngine); got != "InnoDB" {
+		t.Fatalf("expect InnoDB, got %s", got)
+	}
+	for _, sql := range []string{
+		"SET default_storage_engine = MyISAM",
+		"SET GLOBAL default_storage_engine = MEMORY",
+	} {
+		if err := execSet(t, s, sql); errCode(err) != mysql.ErrUnknownStorageEngine {
+			t.Fatalf("%s: expect error %d, got %v", sql, mysql.ErrUnknownStorageEngine, err)
+		}
+	}
+	if got := variable.SysVars[variable.DefaultStorageEngine].Value; got != "InnoDB" {
+		t.Fatalf("expect the global value unchanged, got %s", got)
+	}
+}
+
+func TestCreateTableForeignKeys(t *testing.T) {
+	is, _ := newFKTestSchema()
+	s := newViewTestSession(t, is)
+	create := func(sql string) (*model.TableInfo, error) {
+		stmt, _, err := compileView(s, sql)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return createTable(s, is, stmt.(*ast.CreateTableStmt))
+	}
+
+	info, err := create("CREATE TABLE a (id INT PRIMARY KEY, pid INT, CONSTRAINT fk_a FOREIGN KEY (pid) REFERENCES parent (id))")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info.ForeignKeys) != 1 || info.ForeignKeys[0].RefTable.L != "parent" {
+		t.Fatalf("expect the foreign key on parent, got %v", info.ForeignKeys)
+	}
+	if len(info.Indices) != 1 || info.Indices[0].Name.L != "fk_a" {
+		t.Fatalf("expect the index fk_a, got %v", info.Indices)
+	}
+	// A table may reference itself.
+	if _, err = create("CREATE TABLE a (id INT PRIMARY KEY, up INT, FOREIGN KEY (up) REFERENCES a (id))"); err != nil {
+		t.Fatal(err)
+	}
+	for _, sql := range []string{
+		// child.pid has no index.
+		"CREATE TABLE a (id INT, pid INT, FOREIGN KEY (pid) REFERENCES child (pid))",
+		"CREATE TABLE a (id INT, pid INT, FOREIGN KEY (pid) REFERENCES parent (nope))",
+	} {
+		if _, err = create(sql); errCode(err) != mysql.ErrCannotAddForeign {
+			t.Fatalf("%s: expect error %d, got %v", sql, mysql.ErrCannotAddForeign, err)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+	"github.com/zhukovaskychina/xmysql-server/server/protocol"
+)
+
+// crossDBTestSchema keeps tables of several databases, by "db.table".
+type crossDBTestSchema struct {
+	schemas.InfoSchema
+	tables map[string]schemas.Table
+}
+
+// newCrossDBTestSession returns a session in the database test on
+//
+//	CREATE TABLE test.users (id INT, name INT);
+//	CREATE TABLE shop.users (id INT, email INT);
+//	CREATE TABLE shop.orders (id INT, user_id INT);
+func newCrossDBTestSession(t *testing.T) *session {
+	return newViewTestSession(t, &crossDBTestSchema{tables: map[string]schemas.Table{
+		"test.users":  &viewTestTable{meta: newFKTestTable("users", "id", "name")},
+		"shop.users":  &viewTestTable{meta: newFKTestTable("users", "id", "email")},
+		"shop.orders": &viewTestTable{meta: newFKTestTable("orders", "id", "user_id")},
+	}})
+}
+
+func (is *crossDBTestSchema) SchemaByName(schema model.CIStr) (*model.DBInfo, bool) {
+	for name := range is.tables {
+		if strings.HasPrefix(name, schema.L+".") {
+			return &model.DBInfo{Name: schema}, true
+		}
+	}
+	return nil, false
+}
+
+func (is *crossDBTestSchema) TableByID(id int64) (schemas.Table, bool) {
+	for _, tbl := range is.tables {
+		if tbl.Meta().ID == id {
+			return tbl, true
+		}
+	}
+	return nil, false
+}
+
+func (is *crossDBTestSchema) TableByName(schema, table model.CIStr) (schemas.Table, error) {
+	if tbl, ok := is.tables[schema.L+"."+table.L]; ok {
+		return tbl, nil
+	}
+	return nil, schemas.ErrTableNotExists.GenByArgs(schema.O, table.O)
+}
+
+func TestCrossDatabaseSelect(t *testing.T) {
+	s := newCrossDBTestSession(t)
+	tests := []struct {
+		sql     string
+		columns []string
+	}{
+		{"SELECT * FROM shop.orders", []string{"shop.orders.id", "shop.orders.user_id"}},
+		{"SELECT orders.* FROM shop.orders", []string{"shop.orders.id", "shop.orders.user_id"}},
+		{"SELECT shop.orders.id FROM shop.orders", []string{"shop.orders.id"}},
+		{"SELECT name FROM users", []string{"test.users.name"}},
+		{"SELECT test.users.name, shop.users.email FROM test.users JOIN shop.users ON test.users.id = shop.users.id",
+			[]string{"test.users.name", "shop.users.email"}},
+		{"SELECT u.name, o.id FROM users u JOIN shop.orders o ON u.id = o.user_id", []string{"u.name", "o.id"}},
+	}
+	for _, tt := range tests {
+		_, p, err := compileView(s, tt.sql)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.sql, err)
+		}
+		cols := p.Schema().Columns
+		if len(cols) != len(tt.columns) {
+			t.Fatalf("%s: unexpected columns %v", tt.sql, cols)
+		}
+		for i, col := range cols {
+			if col.String() != tt.columns[i] {
+				t.Fatalf("%s: expect column %s, got %s", tt.sql, tt.columns[i], col)
+			}
+		}
+	}
+
+	for _, tt := range []struct {
+		sql, table string
+	}{
+		{"SELECT * FROM orders", "'test.orders'"},
+		{"SELECT * FROM shop.nope", "'shop.nope'"},
+		{"INSERT INTO nope.orders VALUES (1, 2)", "'nope.orders'"},
+	} {
+		_, _, err := compileView(s, tt.sql)
+		if errCode(err) != mysql.ErrNoSuchTable || !strings.Contains(err.Error(), tt.table) {
+			t.Fatalf("%s: expect error %d on %s, got %v", tt.sql, mysql.ErrNoSuchTable, tt.table, err)
+		}
+	}
+
+	// information_schema resolves from any database.
+	s.sessionVars.CurrentDB = "shop"
+	if _, _, err := compileView(s, "SELECT * FROM information_schema.optimizer_trace"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCrossDatabaseDML(t *testing.T) {
+	s := newCrossDBTestSession(t)
+	_, p, err := compileView(s, "INSERT INTO shop.orders (id, user_id) VALUES (1, 2)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if insert := p.(*plan.Insert); insert.Table.Meta().Name.L != "orders" {
+		t.Fatalf("unexpected table %s", insert.Table.Meta().Name)
+	}
+	for _, sql := range []string{
+		"UPDATE shop.orders SET user_id = 3 WHERE id = 1",
+		"UPDATE shop.orders o SET o.user_id = 3 WHERE o.id = 1",
+		"DELETE FROM shop.orders WHERE id = 1",
+		"DELETE shop.orders FROM shop.orders WHERE shop.orders.id = 1",
+	} {
+		_, p, err := compileView(s, sql)
+		if err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+		if !strings.Contains(plan.ToString(p), "Table(orders)") {
+			t.Fatalf("%s: unexpected plan %s", sql, plan.ToString(p))
+		}
+	}
+}
+
+func TestResultColumns(t *testing.T) {
+	s := newCrossDBTestSession(t)
+	stmt, p, err := compileView(s, "SELECT o.id AS oid, o.user_id, u.*, 1 + 1 FROM shop.orders o JOIN users u ON o.user_id = u.id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []protocol.Field{
+		{Schema: "shop", Table: "o", OrgTable: "orders", Name: "oid", OrgName: "id", Types: int(mysql.TypeLong)},
+		{Schema: "shop", Table: "o", OrgTable: "orders", Name: "user_id", OrgName: "user_id", Types: int(mysql.TypeLong)},
+		{Schema: "test", Table: "u", OrgTable: "users", Name: "id", OrgName: "id", Types: int(mysql.TypeLong)},
+		{Schema: "test", Table: "u", OrgTable: "users", Name: "name", OrgName: "name", Types: int(mysql.TypeLong)},
+		{Name: "1 + 1", Types: int(mysql.TypeLonglong), Flags: int(mysql.BinaryFlag)},
+	}
+	fields := ResultColumns(stmt, p)
+	if len(fields) != len(expected) {
+		t.Fatalf("unexpected columns %+v", fields)
+	}
+	for i, field := range fields {
+		if field != expected[i] {
+			t.Fatalf("column %d: expect %+v, got %+v", i, expected[i], field)
+		}
+	}
+}
+
+// initDBTestSession counts the OK packets sent.
+type initDBTestSession struct {
+	*serverTestSession
+	oks int
+}
+
+func (s *initDBTestSession) SendOK() { s.oks++ }
+
+func TestInitDB(t *testing.T) {
+	s := newCrossDBTestSession(t)
+	srv := &XMySQLEngine{infoSchemaManager: s.sessionVars.TxnCtx.InfoSchema.(schemas.InfoSchema)}
+	ss := &initDBTestSession{serverTestSession: &serverTestSession{session: s}}
+
+	srv.InitDB(ss, "shop")
+	if ss.oks != 1 || len(ss.errs) != 0 || s.sessionVars.CurrentDB != "shop" {
+		t.Fatalf("expect OK and shop as the current database, got %v, %q", ss.errs, s.sessionVars.CurrentDB)
+	}
+	for _, tt := range []struct {
+		db   string
+		code uint16
+	}{
+		{"nosuchdb", mysql.ErrBadDB},
+		{"", mysql.ErrNoDB},
+	} {
+		ss.errs = nil
+		srv.InitDB(ss, tt.db)
+		if len(ss.errs) != 1 || ss.errs[0].Code != tt.code {
+			t.Fatalf("%q: expect error %d, got %v", tt.db, tt.code, ss.errs)
+		}
+		if ss.oks != 1 || s.sessionVars.CurrentDB != "shop" {
+			t.Fatalf("%q: expect the current database kept, got %q", tt.db, s.sessionVars.CurrentDB)
+		}
+	}
+}
@@ -12,24 +12,31 @@ type baseCursor struct {
 	ctx      context.Context
 }
 
+// Open opens the children, closing the ones already open when one fails so
+// that they release what they hold.
 func (b baseCursor) Open() error {
-	for _, child := range b.children {
+	for i, child := range b.children {
 		err := child.Open()
 		if err != nil {
+			for _, opened := range b.children[:i] {
+				opened.Close()
+			}
 			return errors.Trace(err)
 		}
 	}
 	return nil
 }
 
+// Close closes every child, even after one fails, and returns the first
+// error.
 func (b *baseCursor) Close() error {
+	var firstErr error
 	for _, child := range b.children {
-		err := child.Close()
-		if err != nil {
-			return errors.Trace(err)
+		if err := child.Close(); err != nil && firstErr == nil {
+			firstErr = errors.Trace(err)
 		}
 	}
-	return nil
+	return firstErr
 }
 
 func NewBaseCursor(ctx context.Context, children ...basic.Cursor) baseCursor {
@@ -0,0 +1,128 @@
+package engine
+
+import (
+	"math/rand"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/varsutil"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+/**
+死锁重试
+
+xmysql_autocommit_deadlock_retries 大于 0 时，autocommit 下不在事务中的 INSERT、UPDATE、DELETE、LOAD DATA
+遇到死锁（ER_LOCK_DEADLOCK）不立即返回错误：回滚语句已做的修改，随机等待一段时间后重新执行，最多重试设置的次数。
+重试时 NOW() 等语句开始时间保持不变，其他部分按新语句重新求值。重试次数都用完后返回最后一次的死锁错误。
+
+默认 0，不重试；显式事务中的语句从不重试，由应用决定是否重做整个事务。
+状态变量 Xmysql_autocommit_deadlock_retries 统计重试的次数，审计日志的 retries 记录每条语句重试的次数。
+打开 tidb_general_log 时每次重试写一行通用日志；重试过的语句总耗时超过 long_query_time 时写一行慢日志。
+
+语句写行时加行锁（见 row_locks.go），行锁等待形成环时锁管理器返回 ER_LOCK_DEADLOCK。
+回滚不是基于 undo 日志的：语句写过的行由 tableRowStore 撤销，再丢弃语句记下的行数变化并回滚事务，
+重试在新的事务中进行，旧事务的行锁释放给与它死锁的事务。
+**/
+
+// deadlockRetryBackoff is the longest wait before the first retry after a
+// deadlock, each retry may wait one more of it.
+var deadlockRetryBackoff = 10 * time.Millisecond
+
+// autocommitDeadlockRetries returns the times stmt may be run again after a
+// deadlock: xmysql_autocommit_deadlock_retries for a DML statement committing
+// on its own, 0 for the other statements.
+func autocommitDeadlockRetries(session innodb.MySQLServerSession, stmt ast.StmtNode) int {
+	vars := session.GetSessionVars()
+	if !vars.IsAutocommit() || vars.InTxn() {
+		return 0
+	}
+	if !changesRows(stmt) {
+		return 0
+	}
+	value, err := varsutil.GetSessionSystemVar(vars, variable.AutocommitDeadlockRetries)
+	if err != nil {
+		return 0
+	}
+	retries, err := strconv.Atoi(value)
+	if err != nil || retries < 0 {
+		return 0
+	}
+	return retries
+}
+
+// deadlockRetrySession is a session whose statement may be retried: it holds
+// back a deadlock error while retries are left.
+type deadlockRetrySession struct {
+	innodb.MySQLServerSession
+	retriable bool
+	deadlock  bool
+}
+
+func (s *deadlockRetrySession) SendError(err *mysql.SQLError) {
+	if s.retriable && err.Code == mysql.ErrLockDeadlock {
+		s.deadlock = true
+		return
+	}
+	s.MySQLServerSession.SendError(err)
+}
+
+// handleRetryingDeadlock runs stmt with h, rolling it back and running it
+// again up to retries times when it fails with a deadlock.
+func (srv *XMySQLEngine) handleRetryingDeadlock(h *stmtHandler, session innodb.MySQLServerSession, stmt ast.StmtNode, p plan.Plan, retries int) {
+	s := &deadlockRetrySession{MySQLServerSession: session}
+	start := time.Now()
+	for attempt := 0; ; attempt++ {
+		s.retriable, s.deadlock = attempt < retries, false
+		h.handle(srv, s, stmt, p)
+		if !s.deadlock {
+			if attempt > 0 {
+				logSlowRetriedStmt(session, stmt, attempt, time.Since(start))
+			}
+			return
+		}
+		rollbackRowDeltas(session)
+		if err := session.RollbackTxn(); err != nil {
+			session.SendError(toSQLError(err))
+			return
+		}
+		// The retry is a new transaction, the locks of this one are
+		// released for the one it deadlocked with.
+		srv.openReadView(session)
+		vars := session.GetSessionVars()
+		if atomic.LoadUint32(&variable.ProcessGeneralLog) != 0 {
+			log.Infof("[GENERAL_LOG] con:%d %s rolled back after a deadlock, retry %d of %d", vars.ConnectionID, statementType(stmt), attempt+1, retries)
+		}
+		atomic.AddUint64(&srv.serverStatus.deadlockRetries, 1)
+		if deadlockRetryBackoff > 0 {
+			time.Sleep(time.Duration(rand.Int63n(int64(deadlockRetryBackoff) * int64(attempt+1))))
+		}
+		// The retry is a new statement started at the same time.
+		sc := vars.StmtCtx
+		sc.MemTracker.Detach()
+		ResetStmtCtx(session, stmt)
+		vars.StmtCtx.NowTs = sc.NowTs
+		vars.StmtCtx.DeadlockRetries = attempt + 1
+	}
+}
+
+// logSlowRetriedStmt writes stmt, retried retries times after deadlocks, to
+// the slow log when it ran longer than long_query_time in all.
+func logSlowRetriedStmt(session innodb.MySQLServerSession, stmt ast.StmtNode, retries int, d time.Duration) {
+	vars := session.GetSessionVars()
+	value, err := varsutil.GetSessionSystemVar(vars, "long_query_time")
+	if err != nil {
+		return
+	}
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil || d.Seconds() <= seconds {
+		return
+	}
+	log.Warnf("[SLOW_QUERY] con:%d cost_time:%v %s retries:%d", vars.ConnectionID, d, statementType(stmt), retries)
+}
@@ -0,0 +1,122 @@
+package engine
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zhukovaskychina/xmysql-server/server/conf"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/parser"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/varsutil"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+func TestAutocommitDeadlockRetry(t *testing.T) {
+	defer func(backoff time.Duration) { deadlockRetryBackoff = backoff }(deadlockRetryBackoff)
+	deadlockRetryBackoff = time.Millisecond
+	srv := &XMySQLEngine{conf: conf.NewCfg(), infoSchemaManager: newViewTestSchema()}
+	s := &serverTestSession{session: newViewTestSession(t, newViewTestSchema())}
+	s.sessionVars.SetStatusFlag(mysql.ServerStatusAutocommit, true)
+	stmt, err := parser.New().ParseOneStmt("INSERT INTO t VALUES (NOW())", mysql.UTF8Charset, mysql.UTF8DefaultCollation)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Off by default.
+	if n := autocommitDeadlockRetries(s, stmt); n != 0 {
+		t.Fatalf("retries should be off by default, got %d", n)
+	}
+	if err = varsutil.SetSessionSystemVar(s.sessionVars, variable.AutocommitDeadlockRetries, basic.NewStringDatum("2")); err != nil {
+		t.Fatal(err)
+	}
+	if n := autocommitDeadlockRetries(s, stmt); n != 2 {
+		t.Fatalf("expected 2 retries, got %d", n)
+	}
+	sel, err := parser.New().ParseOneStmt("SELECT 1", mysql.UTF8Charset, mysql.UTF8DefaultCollation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := autocommitDeadlockRetries(s, sel); n != 0 {
+		t.Errorf("a SELECT should not be retried, got %d", n)
+	}
+	s.sessionVars.SetStatusFlag(mysql.ServerStatusAutocommit, false)
+	if n := autocommitDeadlockRetries(s, stmt); n != 0 {
+		t.Errorf("a statement with autocommit off should not be retried, got %d", n)
+	}
+	s.sessionVars.SetStatusFlag(mysql.ServerStatusAutocommit, true)
+
+	// deadlocking fails the statement with a deadlock its first n runs.
+	deadlocking := func(n int, nows *[]time.Time) *stmtHandler {
+		return &stmtHandler{name: "deadlocking", handle: func(srv *XMySQLEngine, session innodb.MySQLServerSession, stmt ast.StmtNode, p plan.Plan) {
+			sc := session.GetSessionVars().StmtCtx
+			*nows = append(*nows, sc.NowTs)
+			if len(*nows) <= n {
+				sc.AddAffectedRows(1)
+				session.SendError(toSQLError(ErrLockDeadlock))
+				return
+			}
+			session.SendOK()
+		}}
+	}
+
+	// Two deadlocks are retried with the start time of the statement, the
+	// retries go to the general log and the slow statement to the slow log.
+	defer func(on uint32) { atomic.StoreUint32(&variable.ProcessGeneralLog, on) }(atomic.LoadUint32(&variable.ProcessGeneralLog))
+	atomic.StoreUint32(&variable.ProcessGeneralLog, 1)
+	if err = varsutil.SetSessionSystemVar(s.sessionVars, "long_query_time", basic.NewStringDatum("0")); err != nil {
+		t.Fatal(err)
+	}
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+	var nows []time.Time
+	ResetStmtCtx(s, stmt)
+	srv.handleRetryingDeadlock(deadlocking(2, &nows), s, stmt, nil, 2)
+	for _, line := range []string{"[GENERAL_LOG] con:0 insert rolled back after a deadlock, retry 2 of 2", "[SLOW_QUERY] con:0"} {
+		if !strings.Contains(logged.String(), line) {
+			t.Errorf("the log should have %q, got %q", line, logged.String())
+		}
+	}
+	if len(s.errs) != 0 {
+		t.Fatalf("the retried statement should succeed, got %v", s.errs)
+	}
+	if len(nows) != 3 {
+		t.Fatalf("expected 3 runs, got %d", len(nows))
+	}
+	for _, now := range nows[1:] {
+		if !now.Equal(nows[0]) {
+			t.Errorf("a retry should keep the statement time %v, got %v", nows[0], now)
+		}
+	}
+	if sc := s.sessionVars.StmtCtx; sc.DeadlockRetries != 2 || sc.AffectedRows() != 0 {
+		t.Errorf("expected 2 retries and no rows of the failed runs, got %d and %d", sc.DeadlockRetries, sc.AffectedRows())
+	}
+	stats, err := srv.serverStatus.Stats(s.sessionVars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats[StatusDeadlockRetries] != uint64(2) {
+		t.Errorf("%s: expected 2, got %v", StatusDeadlockRetries, stats[StatusDeadlockRetries])
+	}
+
+	// The last deadlock is sent once the retries run out.
+	nows = nil
+	ResetStmtCtx(s, stmt)
+	srv.handleRetryingDeadlock(deadlocking(3, &nows), s, stmt, nil, 2)
+	if len(nows) != 3 {
+		t.Fatalf("expected 3 runs, got %d", len(nows))
+	}
+	if len(s.errs) != 1 || s.errs[0].Code != mysql.ErrLockDeadlock {
+		t.Fatalf("expected error 1213, got %v", s.errs)
+	}
+}
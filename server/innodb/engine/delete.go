@@ -0,0 +1,59 @@
+package engine
+
+import (
+	"github.com/juju/errors"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// deleteRows runs the single-table DELETE compiled to p on tbl of store:
+// the rows its WHERE clause matches, in the order of its ORDER BY and up to
+// its LIMIT, are removed after the ON DELETE actions of the foreign keys
+// referencing them. It returns the number of rows removed.
+func deleteRows(ctx context.Context, store fkRowStore, tbl *model.TableInfo, p *plan.Delete) (uint64, error) {
+	handles, rows, err := matchedRows(ctx, store, tbl, p)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	fk := &fkChecker{ctx: ctx, store: store}
+	var affected uint64
+	for i, row := range rows {
+		if err = fk.onDeleteRow(tbl, row); err != nil {
+			break
+		}
+		if err = store.DeleteRow(tbl, handles[i]); err != nil {
+			break
+		}
+		affected++
+	}
+	addRowDelta(ctx, tbl.ID, -int64(affected))
+	return affected, errors.Trace(err)
+}
+
+func init() {
+	registerStmtHandler(&ast.DeleteStmt{}, &stmtHandler{name: "delete", handle: (*XMySQLEngine).execDelete})
+}
+
+// execDelete runs a single-table DELETE.
+func (srv *XMySQLEngine) execDelete(session innodb.MySQLServerSession, stmt ast.StmtNode, p plan.Plan) {
+	if v, ok := p.(*plan.Delete); ok {
+		tbl, db := dmlTable(v)
+		if v.IsMultiTable || tbl == nil {
+			session.SendError(mysql.NewErrf(mysql.ErrNotSupportedYet, "DELETE of several tables"))
+			return
+		}
+		store := newTableRowStore(session, srv.infoSchemaManager, srv.pool, db)
+		affected, err := deleteRows(session, store, tbl, v)
+		if err != nil {
+			store.rollback()
+			session.SendError(toSQLError(err))
+			return
+		}
+		session.GetSessionVars().StmtCtx.AddAffectedRows(affected)
+		session.SendOK()
+	}
+}
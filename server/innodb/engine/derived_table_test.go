@@ -0,0 +1,197 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// compileColumns compiles sql and returns the names of its columns.
+func compileColumns(t *testing.T, sql string) string {
+	s := newViewTestSession(t, newViewTestSchema())
+	_, p, err := compileView(s, sql)
+	if err != nil {
+		t.Fatalf("%s: %v", sql, err)
+	}
+	var names []string
+	for _, col := range p.Schema().Columns {
+		names = append(names, col.TblName.L+"."+col.ColName.L)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestDerivedTable(t *testing.T) {
+	for _, tt := range []struct {
+		sql  string
+		cols string
+	}{
+		{"SELECT * FROM (SELECT 1 a, COUNT(*) c) t WHERE c > 5", "t.a,t.c"},
+		{"SELECT t.a FROM (SELECT 1 a UNION SELECT 2) AS t", "t.a"},
+		{"SELECT x.a, y.a FROM (SELECT 1 a) x, (SELECT 2 a) y WHERE x.a = y.a", "x.a,y.a"},
+	} {
+		if cols := compileColumns(t, tt.sql); cols != tt.cols {
+			t.Fatalf("%s: expect columns %s, got %s", tt.sql, tt.cols, cols)
+		}
+	}
+
+	s := newViewTestSession(t, newViewTestSchema())
+	_, _, err := compileView(s, "SELECT * FROM (SELECT 1 a)")
+	if errCode(err) != mysql.ErrDerivedMustHaveAlias {
+		t.Fatalf("expect error %d, got %v", mysql.ErrDerivedMustHaveAlias, err)
+	}
+}
+
+func TestDerivedTableOfAggregate(t *testing.T) {
+	s := newViewTestSession(t, newViewTestSchema(newFKTestTable("t", "a", "b")))
+	for _, tt := range []struct {
+		sql  string
+		plan string
+	}{
+		// The filter on the aggregate stays above it, the one on the group
+		// column is pushed into the derived table.
+		{"SELECT * FROM (SELECT a, SUM(b) s FROM t GROUP BY a) d WHERE a > 1 AND s > 10",
+			"Table(t)->Selection->HashAgg->Selection->Projection"},
+		// Nothing is pushed under the LIMIT of the derived table.
+		{"SELECT * FROM (SELECT a, COUNT(*) c FROM t GROUP BY a ORDER BY c DESC LIMIT 2) d WHERE a > 1 AND c > 1",
+			"Table(t)->HashAgg->Sort + Limit(2) + Offset(0)->Selection->Projection"},
+		{"SELECT * FROM (SELECT a FROM t LIMIT 3) d WHERE a > 1", "Table(t)->Limit->Selection"},
+		{"SELECT (SELECT a FROM t ORDER BY a LIMIT 1)", "LeftHashJoin{Dual->Table(t)->Sort + Limit(1) + Offset(0)->MaxOneRow}"},
+	} {
+		_, p, err := compileView(s, tt.sql)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.sql, err)
+		}
+		if got := plan.ToString(p); got != tt.plan {
+			t.Fatalf("%s: expect plan %s, got %s", tt.sql, tt.plan, got)
+		}
+	}
+}
+
+func TestDerivedTableRows(t *testing.T) {
+	s := newViewTestSession(t, newViewTestSchema())
+	for _, tt := range []struct {
+		sql  string
+		rows string
+	}{
+		{"SELECT * FROM (SELECT 1 a UNION ALL SELECT 3 UNION ALL SELECT 2) d WHERE a > 1", "3,2"},
+		{"SELECT * FROM (SELECT 1 a UNION ALL SELECT 3 UNION ALL SELECT 2 ORDER BY a DESC LIMIT 2) d", "3,2"},
+		{"SELECT a * 10 FROM (SELECT 1 a UNION ALL SELECT 3 UNION ALL SELECT 2 ORDER BY a LIMIT 1, 5) d WHERE a < 3", "20"},
+		{"SELECT a FROM (SELECT 1 a UNION ALL SELECT 3 UNION ALL SELECT 2) d ORDER BY a DESC LIMIT 2", "3,2"},
+		{"SELECT * FROM (SELECT 1 a LIMIT 0) d", ""},
+		{"SELECT b FROM (SELECT a + 1 b FROM (SELECT 1 a ORDER BY a LIMIT 1) x) y", "2"},
+		// The selects of a union are cast to the type of its columns.
+		{"SELECT a FROM (SELECT 1 a UNION ALL SELECT 1.5) d", "1.0,1.5"},
+		{"SELECT a FROM (SELECT 2.25 a UNION ALL SELECT 1) d", "2.25,1.00"},
+		{"SELECT a FROM (SELECT 1 a UNION SELECT 1.0) d", "1.0"},
+		{"SELECT a FROM (SELECT 0.5 a UNION ALL SELECT 2.5e0) d", "0.5,2.5"},
+	} {
+		_, p, err := compileView(s, tt.sql)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.sql, err)
+		}
+		rows, ok, err := dualRows(s, p)
+		if err != nil || !ok {
+			t.Fatalf("%s: %v %v", tt.sql, ok, err)
+		}
+		var values []string
+		for _, row := range rows {
+			value, _ := row[0].ToString()
+			values = append(values, value)
+		}
+		if got := strings.Join(values, ","); got != tt.rows {
+			t.Fatalf("%s: expect %q, got %q", tt.sql, tt.rows, got)
+		}
+	}
+}
+
+func TestWithClause(t *testing.T) {
+	for _, tt := range []struct {
+		sql  string
+		cols string
+	}{
+		{"WITH t AS (SELECT 1 a) SELECT * FROM t", "t.a"},
+		{"WITH t AS (SELECT 1 a), u AS (SELECT a + 1 b FROM t) SELECT b FROM u WHERE b > 1", "u.b"},
+		{"WITH t (x, y) AS (SELECT 1, 2) SELECT y, x FROM t", "t.y,t.x"},
+		{"WITH t AS (SELECT 1 a) SELECT x.a, y.a FROM t x, t y WHERE x.a = y.a", "x.a,y.a"},
+		{"WITH t AS (SELECT 1 a) SELECT a FROM t WHERE a IN (SELECT a FROM t)", "t.a"},
+		{"SELECT * FROM (WITH t AS (SELECT 1 a) SELECT a FROM t) d", "d.a"},
+		{"WITH t AS (SELECT 1 a UNION SELECT 2) SELECT a FROM t", "t.a"},
+	} {
+		if cols := compileColumns(t, tt.sql); cols != tt.cols {
+			t.Fatalf("%s: expect columns %s, got %s", tt.sql, tt.cols, cols)
+		}
+	}
+}
+
+func TestWithClauseErrors(t *testing.T) {
+	s := newViewTestSession(t, newViewTestSchema())
+	for _, tt := range []struct {
+		sql  string
+		code uint16
+	}{
+		{"WITH t (x) AS (SELECT 1, 2) SELECT x FROM t", mysql.ErrViewWrongList},
+		{"WITH t AS (SELECT 1 a), t AS (SELECT 2) SELECT * FROM t", mysql.ErrNonuniqTable},
+		{"WITH RECURSIVE t AS (SELECT 1 a) SELECT * FROM t", mysql.ErrNotSupportedYet},
+		// An expression can't see the ones defined after it.
+		{"WITH u AS (SELECT * FROM t), t AS (SELECT 1 a) SELECT * FROM u", mysql.ErrNoSuchTable},
+	} {
+		_, _, err := compileView(s, tt.sql)
+		if errCode(err) != tt.code {
+			t.Fatalf("%s: expect error %d, got %v", tt.sql, tt.code, err)
+		}
+	}
+}
+
+func TestViewWithClause(t *testing.T) {
+	is := newViewTestSchema()
+	s := newViewTestSession(t, is)
+	if err := execView(t, s, "CREATE VIEW v AS WITH t AS (SELECT 1 a) SELECT a FROM t"); err != nil {
+		t.Fatal(err)
+	}
+	_, p, err := compileView(s, "SELECT a FROM v")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cols := p.Schema().Columns; len(cols) != 1 || cols[0].ColName.L != "a" {
+		t.Fatalf("unexpected columns %v", cols)
+	}
+}
+
+func TestUnionNumericTypes(t *testing.T) {
+	s := newViewTestSession(t, newViewTestSchema())
+	for _, tt := range []struct {
+		sql string
+		tp  string
+	}{
+		{"SELECT 1 a UNION ALL SELECT 1.5", "decimal(3,1) BINARY"},
+		{"SELECT 1.5 a UNION ALL SELECT 2.5e0", "double(5,1) BINARY"},
+		{"SELECT 18446744073709551614 a UNION ALL SELECT 18446744073709551615", "bigint(20) UNSIGNED BINARY"},
+		// A signed and an unsigned BIGINT merge to a DECIMAL holding both.
+		{"SELECT -1 a UNION ALL SELECT 18446744073709551615", "decimal(20) BINARY"},
+	} {
+		_, p, err := compileView(s, tt.sql)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.sql, err)
+		}
+		if got := p.Schema().Columns[0].RetType.String(); got != tt.tp {
+			t.Fatalf("%s: expect %s, got %s", tt.sql, tt.tp, got)
+		}
+		rows, ok, err := dualRows(s, p)
+		if err != nil || !ok || len(rows) != 2 {
+			t.Fatalf("%s: %v %v %v", tt.sql, rows, ok, err)
+		}
+	}
+
+	// 9223372036854775807 is the largest BIGINT, not a BIGINT UNSIGNED, so
+	// adding 1 to it is out of range.
+	_, p, err := compileView(s, "SELECT 9223372036854775807 + 1")
+	if err == nil {
+		_, _, err = dualRows(s, p)
+	}
+	if !basic.ErrOverflow.Equal(err) {
+		t.Fatalf("expect BIGINT out of range, got %v", err)
+	}
+}
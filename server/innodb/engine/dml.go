@@ -0,0 +1,151 @@
+package engine
+
+import (
+	"sort"
+
+	"github.com/juju/errors"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/expression"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+)
+
+/**
+单表 UPDATE / DELETE 修改的行
+
+UPDATE 和 DELETE 只修改 WHERE 匹配的行，有 ORDER BY 时按它排序，有 LIMIT 时只修改
+前 N 行，影响的行数也只算这 N 行。
+
+计划中 WHERE 是 Selection 或扫描的 AccessCondition，ORDER BY 是 Sort，LIMIT 是
+Limit 或 Sort 的 ExecLimit。ORDER BY 的列是主键或索引的前缀时优化器去掉了 Sort，
+改为按主键或那个索引的顺序扫描，这里就按扫描的顺序排列行；否则把匹配的行按主键的
+顺序读出来再排序。排序是稳定的，ORDER BY 的值相同的行按主键的顺序，所以同样的数据
+总是修改同样的行。
+**/
+
+// handleRows sorts rows by their handles.
+type handleRows struct {
+	handles []int64
+	rows    [][]basic.Datum
+}
+
+func (r handleRows) Len() int           { return len(r.handles) }
+func (r handleRows) Less(i, j int) bool { return r.handles[i] < r.handles[j] }
+func (r handleRows) Swap(i, j int) {
+	r.handles[i], r.handles[j] = r.handles[j], r.handles[i]
+	r.rows[i], r.rows[j] = r.rows[j], r.rows[i]
+}
+
+// matchedRows returns the handles and rows of tbl in store the single-table
+// UPDATE or DELETE compiled to p changes, in the order it changes them.
+func matchedRows(ctx context.Context, store rowReader, tbl *model.TableInfo, p plan.Plan) ([]int64, [][]basic.Datum, error) {
+	handles, rows, err := store.Rows(tbl)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	sort.Sort(handleRows{handles, rows})
+	// The handle of each row follows its columns while the plan reorders
+	// them, out of reach of the expressions.
+	n := len(tbl.Columns)
+	withHandles := make([][]basic.Datum, len(rows))
+	for i, row := range rows {
+		withHandles[i] = append(row[:n:n], basic.NewIntDatum(handles[i]))
+	}
+	if withHandles, err = dmlRows(ctx, p.Children()[0], withHandles); err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	handles, rows = make([]int64, len(withHandles)), make([][]basic.Datum, len(withHandles))
+	for i, row := range withHandles {
+		handles[i], rows[i] = row[n].GetInt64(), row[:n]
+	}
+	return handles, rows, nil
+}
+
+// dmlTable returns the table the single-table UPDATE or DELETE compiled to p
+// changes and its database, or nil for a statement on several tables.
+func dmlTable(p plan.Plan) (*model.TableInfo, model.CIStr) {
+	for q := p.Children()[0]; ; q = q.Children()[0] {
+		switch x := q.(type) {
+		case *plan.PhysicalTableScan:
+			return x.Table, x.DBName
+		case *plan.PhysicalIndexScan:
+			return x.Table, x.DBName
+		case *plan.Selection, *plan.Sort, *plan.Limit:
+		default:
+			return nil, model.CIStr{}
+		}
+	}
+}
+
+// dmlRows returns the rows the scan, WHERE, ORDER BY and LIMIT of p keep of
+// the rows of its table, in handle order.
+func dmlRows(ctx context.Context, p plan.Plan, rows [][]basic.Datum) ([][]basic.Datum, error) {
+	var err error
+	switch x := p.(type) {
+	case *plan.PhysicalTableScan:
+		if rows, err = selectRows(ctx, x.AccessCondition, rows); err != nil {
+			return nil, errors.Trace(err)
+		}
+		if x.Desc {
+			reverseRows(rows)
+		}
+		if rows, err = sortRows(ctx, x.SortItems(), rows); err != nil {
+			return nil, errors.Trace(err)
+		}
+		return scanLimitRows(x.LimitCount, rows), nil
+	case *plan.PhysicalIndexScan:
+		if rows, err = selectRows(ctx, x.AccessCondition, rows); err != nil {
+			return nil, errors.Trace(err)
+		}
+		byItems := make([]*plan.ByItems, 0, len(x.Index.Columns))
+		for _, col := range x.Index.Columns {
+			byItems = append(byItems, &plan.ByItems{Expr: &expression.Column{
+				Index:   col.Offset,
+				RetType: &x.Table.Columns[col.Offset].FieldType,
+			}})
+		}
+		if rows, err = sortRows(ctx, byItems, rows); err != nil {
+			return nil, errors.Trace(err)
+		}
+		if x.Desc {
+			reverseRows(rows)
+		}
+		if rows, err = sortRows(ctx, x.SortItems(), rows); err != nil {
+			return nil, errors.Trace(err)
+		}
+		return scanLimitRows(x.LimitCount, rows), nil
+	case *plan.Selection, *plan.Sort, *plan.Limit:
+		if rows, err = dmlRows(ctx, p.Children()[0], rows); err != nil {
+			return nil, errors.Trace(err)
+		}
+	default:
+		return nil, errors.Errorf("unexpected %T in a single-table UPDATE or DELETE", p)
+	}
+	switch x := p.(type) {
+	case *plan.Selection:
+		rows, err = selectRows(ctx, x.Conditions, rows)
+	case *plan.Sort:
+		if rows, err = sortRows(ctx, x.ByItems, rows); err == nil && x.ExecLimit != nil {
+			rows = limitRows(x.ExecLimit, rows)
+		}
+	case *plan.Limit:
+		rows = limitRows(x, rows)
+	}
+	return rows, errors.Trace(err)
+}
+
+// scanLimitRows returns the first count rows, all of them when count is nil.
+// A LIMIT may be pushed down to the scan, with the ORDER BY before it.
+func scanLimitRows(count *int64, rows [][]basic.Datum) [][]basic.Datum {
+	if count != nil && *count < int64(len(rows)) {
+		rows = rows[:*count]
+	}
+	return rows
+}
+
+func reverseRows(rows [][]basic.Datum) {
+	for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+		rows[i], rows[j] = rows[j], rows[i]
+	}
+}
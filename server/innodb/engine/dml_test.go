@@ -0,0 +1,175 @@
+package engine
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/conf"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/buffer_pool"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+func TestDMLOrderByLimit(t *testing.T) {
+	parent, items := newIgnoreTestTables()
+	s := newViewTestSession(t, newViewTestSchema(parent, items))
+	store := newFKTestStore(parent, items)
+	store.insert(parent, 1)
+	store.insert(parent, 2)
+	// The rows are added in the order of their ids, the order of the
+	// primary key.
+	reset := func() {
+		store.rows["items"] = make(map[int64][]basic.Datum)
+		store.insert(items, 1, 50, 1)
+		store.insert(items, 2, 40, 1)
+		store.insert(items, 3, 30, 2)
+		store.insert(items, 4, 20, 2)
+		store.insert(items, 5, 10, 1)
+	}
+	run := func(sql string) (uint64, error) {
+		_, p, err := compileView(s, sql)
+		if err != nil {
+			return 0, err
+		}
+		switch x := p.(type) {
+		case *plan.Update:
+			return NewUpdateValues(s, x, items).update(store, items, x)
+		case *plan.Delete:
+			return deleteRows(s, store, items, x)
+		}
+		t.Fatalf("%s: unexpected plan %T", sql, p)
+		return 0, nil
+	}
+	rows := func() [][]int64 {
+		var got [][]int64
+		_, all, _ := store.Rows(items)
+		for _, row := range all {
+			got = append(got, []int64{row[0].GetInt64(), row[1].GetInt64(), row[2].GetInt64()})
+		}
+		sort.Slice(got, func(i, j int) bool { return got[i][0] < got[j][0] })
+		return got
+	}
+
+	tests := []struct {
+		sql      string
+		err      uint16
+		affected uint64
+		rows     [][]int64
+	}{
+		// The first rows in the order of an index, of the primary key, and
+		// of a sort of the matching rows.
+		{"DELETE FROM items ORDER BY code LIMIT 2", 0, 2, [][]int64{{1, 50, 1}, {2, 40, 1}, {3, 30, 2}}},
+		{"DELETE FROM items WHERE code > 15 ORDER BY code LIMIT 1", 0, 1, [][]int64{{1, 50, 1}, {2, 40, 1}, {3, 30, 2}, {5, 10, 1}}},
+		{"DELETE FROM items WHERE id < 4 ORDER BY id DESC LIMIT 2", 0, 2, [][]int64{{1, 50, 1}, {4, 20, 2}, {5, 10, 1}}},
+		{"DELETE FROM items WHERE pid = 1 ORDER BY code DESC LIMIT 2", 0, 2, [][]int64{{3, 30, 2}, {4, 20, 2}, {5, 10, 1}}},
+		// Rows equal on the ORDER BY keep the order of the primary key, and
+		// without ORDER BY LIMIT takes the first rows of the primary key.
+		{"DELETE FROM items WHERE pid = 2 ORDER BY pid LIMIT 1", 0, 1, [][]int64{{1, 50, 1}, {2, 40, 1}, {4, 20, 2}, {5, 10, 1}}},
+		{"DELETE FROM items LIMIT 2", 0, 2, [][]int64{{3, 30, 2}, {4, 20, 2}, {5, 10, 1}}},
+		{"DELETE FROM items WHERE pid = 1", 0, 3, [][]int64{{3, 30, 2}, {4, 20, 2}}},
+		{"DELETE FROM items LIMIT 0", 0, 0, [][]int64{{1, 50, 1}, {2, 40, 1}, {3, 30, 2}, {4, 20, 2}, {5, 10, 1}}},
+
+		{"UPDATE items SET pid = 2 WHERE pid = 1 ORDER BY code LIMIT 2", 0, 2, [][]int64{{1, 50, 1}, {2, 40, 2}, {3, 30, 2}, {4, 20, 2}, {5, 10, 2}}},
+		{"UPDATE items SET code = code + 100 ORDER BY (pid + 1) DESC LIMIT 1", 0, 1, [][]int64{{1, 50, 1}, {2, 40, 1}, {3, 130, 2}, {4, 20, 2}, {5, 10, 1}}},
+		// The order decides whether a row takes the unique key of one not
+		// updated yet.
+		{"UPDATE items SET code = code + 10 ORDER BY code", mysql.ErrDupEntry, 0, nil},
+		{"UPDATE items SET code = code + 10 ORDER BY code DESC", 0, 5, [][]int64{{1, 60, 1}, {2, 50, 1}, {3, 40, 2}, {4, 30, 2}, {5, 20, 1}}},
+
+		// Multi-table statements take neither.
+		{"UPDATE items JOIN parent ON items.pid = parent.id SET code = 1 ORDER BY code", mysql.ErrWrongUsage, 0, nil},
+		{"UPDATE items JOIN parent ON items.pid = parent.id SET code = 1 LIMIT 1", mysql.ErrWrongUsage, 0, nil},
+		{"UPDATE items, parent SET code = 1 LIMIT 1", mysql.ErrParse, 0, nil},
+		{"DELETE items FROM items, parent LIMIT 1", mysql.ErrParse, 0, nil},
+		{"DELETE FROM items USING items, parent ORDER BY code", mysql.ErrParse, 0, nil},
+	}
+	for _, tt := range tests {
+		reset()
+		affected, err := run(tt.sql)
+		if tt.err != 0 {
+			if errCode(err) != tt.err {
+				t.Fatalf("%s: expect error %d, got %v", tt.sql, tt.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tt.sql, err)
+		}
+		if affected != tt.affected {
+			t.Fatalf("%s: expect %d affected rows, got %d", tt.sql, tt.affected, affected)
+		}
+		if got := rows(); !reflect.DeepEqual(got, tt.rows) {
+			t.Fatalf("%s: expect rows %v, got %v", tt.sql, tt.rows, got)
+		}
+	}
+}
+
+func TestExecUpdateDelete(t *testing.T) {
+	is := newViewTestSchema(newTraceTestTable())
+	tree := &indexTestTree{entries: [][]basic.Datum{
+		basic.MakeDatums(int64(1), int64(10), int64(100)),
+		basic.MakeDatums(int64(2), int64(20), int64(200)),
+	}}
+	is.tables["t"] = &scanTestTable{spaceTestTable: &spaceTestTable{viewTestTable: is.tables["t"].(*viewTestTable), spaceId: 5}, tree: tree}
+	fs := basic.NewFileSystem(conf.NewCfg())
+	fs.AddTableSpace(dumpTestSpace(5))
+	srv := &XMySQLEngine{conf: conf.NewCfg(), infoSchemaManager: is, pool: buffer_pool.NewBufferPool(16*16384, 0.75, 0.25, 1000, fs)}
+	s := &serverTestSession{session: newViewTestSession(t, is)}
+
+	for _, tt := range []struct {
+		sql string
+		err uint16
+	}{
+		// Nothing to change, nothing to write.
+		{"DELETE FROM t WHERE id = 5", 0},
+		{"UPDATE t SET a = 10 WHERE id = 1", 0},
+		// The checks run on the stored rows.
+		{"UPDATE t SET id = 2 WHERE id = 1", mysql.ErrDupEntry},
+		// The table can't be written.
+		{"UPDATE t SET a = 11 WHERE id = 1", mysql.ErrNotSupportedYet},
+		{"DELETE FROM t WHERE id = 1", mysql.ErrNotSupportedYet},
+	} {
+		s.errs = nil
+		srv.ExecuteQuery(s, tt.sql)
+		if tt.err == 0 && len(s.errs) > 0 || tt.err != 0 && (len(s.errs) != 1 || s.errs[0].Code != tt.err) {
+			t.Fatalf("%s: expect error %d, got %v", tt.sql, tt.err, s.errs)
+		}
+		if n := s.sessionVars.StmtCtx.AffectedRows(); n != 0 {
+			t.Fatalf("%s: expect no rows affected, got %d", tt.sql, n)
+		}
+	}
+}
+
+func TestDMLOrderByLimitStored(t *testing.T) {
+	srv, s := newStoredTestEngine(t, newFKTestTable("t", "id", "code"))
+	execStored(t, srv, s, "INSERT INTO t VALUES (1, 50), (2, 40), (3, 30), (4, 20), (5, 10)", 0)
+	for _, tt := range []struct {
+		sql      string
+		err      uint16
+		affected uint64
+		rows     string
+	}{
+		{"DELETE FROM t ORDER BY code LIMIT 2", 0, 2, "1,50;2,40;3,30"},
+		{"UPDATE t SET code = code + 100 ORDER BY code DESC LIMIT 1", 0, 1, "1,150;2,40;3,30"},
+		// The rows change in the order given, the keys they move to are
+		// free.
+		{"UPDATE t SET id = id + 10 ORDER BY id DESC LIMIT 2", 0, 2, "1,150;12,40;13,30"},
+		// A failed change leaves every row as it was.
+		{"UPDATE t SET id = 13 ORDER BY id LIMIT 2", mysql.ErrDupEntry, 0, "1,150;12,40;13,30"},
+		{"DELETE FROM t WHERE code < 100 ORDER BY id DESC LIMIT 1", 0, 1, "1,150;12,40"},
+	} {
+		execStored(t, srv, s, tt.sql, tt.err)
+		if n := s.sessionVars.StmtCtx.AffectedRows(); tt.err == 0 && n != tt.affected {
+			t.Fatalf("%s: expect %d rows affected, got %d", tt.sql, tt.affected, n)
+		}
+		if got := execStored(t, srv, s, "SELECT * FROM t", 0); got != tt.rows {
+			t.Fatalf("%s: expect rows %s, got %s", tt.sql, tt.rows, got)
+		}
+	}
+	// A SELECT sorts the rows before the limit pushed down to the scan too.
+	if got := execStored(t, srv, s, "SELECT id FROM t ORDER BY code LIMIT 1", 0); got != "12" {
+		t.Fatalf("expect the row with the lowest code, got %s", got)
+	}
+}
@@ -0,0 +1,100 @@
+package engine
+
+import (
+	"github.com/juju/errors"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ddl"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+)
+
+/**
+ALTER TABLE ... DROP COLUMN
+
+从表的定义里删掉列，只建在这一列上的索引一起删掉；和别的列共用的索引和 MySQL 一样
+只去掉这一列，留下其余的列。去掉之后唯一索引剩下的列如果在已有的行里有重复的值，
+语句失败（1062）。列被外键或生成列用到、或者是表的最后一列时不能删除
+（1090/1091/1828/3108）。
+表中已有的行不再带这一列的值：按新的定义把每行剩下的列按顺序取出来，和 MODIFY COLUMN
+一样一次性替换旧表的定义和行，之后的 DESCRIBE 和 SELECT * 都看不到这一列。
+一条语句删多列时按顺序删除，任何一列失败整个语句失败，表不变。
+**/
+
+// dropColumnSpecs returns the DROP COLUMN specs of stmt, in order.
+func dropColumnSpecs(stmt *ast.AlterTableStmt) []*ast.AlterTableSpec {
+	var specs []*ast.AlterTableSpec
+	for _, spec := range stmt.Specs {
+		if spec.Tp == ast.AlterTableDropColumn {
+			specs = append(specs, spec)
+		}
+	}
+	return specs
+}
+
+// dropColumns applies specs, DROP COLUMN specs, to the table tn of a
+// resolved ALTER TABLE, reading its rows with reader to take the values of
+// the dropped columns out.
+func dropColumns(ctx context.Context, is schemas.InfoSchema, reader tableRowsReader, tn *ast.TableName, specs []*ast.AlterTableSpec) error {
+	tbl, err := is.TableByName(tn.Schema, tn.Name)
+	if err != nil || tbl == nil {
+		return schemas.ErrTableNotExists.GenByArgs(tn.Schema.O, tn.Name.O)
+	}
+	if tbl.Meta().IsView() {
+		return schemas.ErrWrongObject.GenByArgs(tn.Schema.O, tn.Name.O, "BASE TABLE")
+	}
+	info := tbl.Meta().Clone()
+	origin := make(map[*model.ColumnInfo]int, len(info.Columns))
+	for i, col := range info.Columns {
+		origin[col] = i
+	}
+	for _, spec := range specs {
+		if err = ddl.DropTableColumn(info, spec.OldColumnName.Name); err != nil {
+			return errors.Trace(err)
+		}
+	}
+
+	rows, err := reader.TableRows(tbl)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	newRows, _, err := convertRows(ctx, info, rows, origin, nil)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err = checkUniqueRows(ctx.GetSessionVars().StmtCtx, info, newRows); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(is.AlterTableColumns(tn.Schema, tn.Name, info, newRows))
+}
+
+// checkUniqueRows returns ER_DUP_ENTRY if two of rows, the rows of tbl, have
+// the same value on a unique key of tbl. A key with a NULL column matches
+// no row.
+func checkUniqueRows(sc *variable.StatementContext, tbl *model.TableInfo, rows [][]basic.Datum) error {
+	names, keys := uniqueKeys(tbl)
+	for k, key := range keys {
+		for i, row := range rows {
+			vals, ok := keyValues(row, key)
+			if !ok {
+				continue
+			}
+			for _, other := range rows[:i] {
+				otherVals, ok := keyValues(other, key)
+				if !ok {
+					continue
+				}
+				same, err := datumsEqual(sc, vals, otherVals)
+				if err != nil {
+					return errors.Trace(err)
+				}
+				if same {
+					return ErrDupEntry.GenByArgs(keyString(vals), names[k])
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+func execDropColumn(t *testing.T, s *session, is *modifyTestSchema, sql string) error {
+	stmt, _, err := compileView(s, sql)
+	if err != nil {
+		t.Fatalf("%s: %v", sql, err)
+	}
+	alter := stmt.(*ast.AlterTableStmt)
+	return dropColumns(s, is, is.rows, alter.Table, dropColumnSpecs(alter))
+}
+
+func TestDropColumn(t *testing.T) {
+	is := newModifyTestSchema()
+	s := newViewTestSession(t, is)
+
+	// A failing drop leaves the table as it is.
+	if err := execDropColumn(t, s, is, "ALTER TABLE t DROP a, DROP COLUMN nope"); errCode(err) != mysql.ErrCantDropFieldOrKey {
+		t.Fatalf("expect error %d, got %v", mysql.ErrCantDropFieldOrKey, err)
+	}
+	if cols := is.tables["t"].Meta().Columns; len(cols) != 3 || len(is.rows["t"][0]) != 3 {
+		t.Fatalf("expect the table unchanged, got %v", cols)
+	}
+	// A column a foreign key needs can't be dropped.
+	is.tables["t"].Meta().ForeignKeys = []*model.FKInfo{{Name: model.NewCIStr("fk_a"), Cols: []model.CIStr{model.NewCIStr("a")}}}
+	if err := execDropColumn(t, s, is, "ALTER TABLE t DROP a"); errCode(err) != mysql.ErrFkColumnCannotDrop {
+		t.Fatalf("expect error %d, got %v", mysql.ErrFkColumnCannotDrop, err)
+	}
+	is.tables["t"].Meta().ForeignKeys = nil
+
+	// The values of the column go with it, the index on b moves.
+	if err := execDropColumn(t, s, is, "ALTER TABLE t DROP COLUMN a"); err != nil {
+		t.Fatal(err)
+	}
+	info := is.tables["t"].Meta()
+	if len(info.Columns) != 2 || info.Columns[1].Name.L != "b" || info.Columns[1].Offset != 1 {
+		t.Fatalf("expect the columns id and b, got %v", info.Columns)
+	}
+	if idx := info.Indices[0].Columns[0]; idx.Offset != 1 {
+		t.Fatalf("expect the index on b at offset 1, got %d", idx.Offset)
+	}
+	if row := is.rows["t"][0]; len(row) != 2 || row[1].GetString() != "7" {
+		t.Fatalf("expect the row without a, got %v", row)
+	}
+	_, p, err := compileView(s, "DESCRIBE t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, _, err := showRows(s, is, p.(*plan.Show))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0][0].GetString() != "id" || rows[1][0].GetString() != "b" {
+		t.Fatalf("unexpected columns %v", rows)
+	}
+
+	// The index on b alone goes with b, the last column stays.
+	if err = execDropColumn(t, s, is, "ALTER TABLE t DROP b"); err != nil {
+		t.Fatal(err)
+	}
+	if info = is.tables["t"].Meta(); len(info.Columns) != 1 || len(info.Indices) != 0 {
+		t.Fatalf("expect id without indices, got %v %v", info.Columns, info.Indices)
+	}
+	if err = execDropColumn(t, s, is, "ALTER TABLE t DROP id"); errCode(err) != mysql.ErrCantRemoveAllFields {
+		t.Fatalf("expect error %d, got %v", mysql.ErrCantRemoveAllFields, err)
+	}
+}
+
+func TestDropColumnOfCompositeIndex(t *testing.T) {
+	is := newModifyTestSchema()
+	s := newViewTestSession(t, is)
+	is.tables["t"].Meta().Indices = []*model.IndexInfo{{
+		Name: model.NewCIStr("u_ab"),
+		Columns: []*model.IndexColumn{
+			{Name: model.NewCIStr("a"), Offset: 1, Length: basic.UnspecifiedLength},
+			{Name: model.NewCIStr("b"), Offset: 2, Length: basic.UnspecifiedLength},
+		},
+		Unique: true,
+		State:  model.StatePublic,
+	}}
+	is.rows["t"] = append(is.rows["t"], basic.MakeDatums(int64(3), int64(10), "8"))
+
+	// Without b, the rows 1 and 3 have the same key.
+	if err := execDropColumn(t, s, is, "ALTER TABLE t DROP b"); errCode(err) != mysql.ErrDupEntry {
+		t.Fatalf("expect error %d, got %v", mysql.ErrDupEntry, err)
+	}
+	if idx := is.tables["t"].Meta().Indices[0]; len(idx.Columns) != 2 {
+		t.Fatalf("expect the index unchanged, got %v", idx.Columns)
+	}
+
+	// Without a, the index is on b alone.
+	if err := execDropColumn(t, s, is, "ALTER TABLE t DROP a"); err != nil {
+		t.Fatal(err)
+	}
+	idx := is.tables["t"].Meta().Indices
+	if len(idx) != 1 || len(idx[0].Columns) != 1 || idx[0].Columns[0].Name.L != "b" || idx[0].Columns[0].Offset != 1 {
+		t.Fatalf("expect the index on b at offset 1, got %v", idx)
+	}
+}
@@ -0,0 +1,183 @@
+package engine
+
+import (
+	"sort"
+
+	"github.com/juju/errors"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/expression"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/memory"
+)
+
+// dualRows returns the rows of a SELECT reading no table compiled to p: the
+// select expressions of a SELECT without FROM evaluated once, and the derived
+// tables, unions, WHERE, GROUP BY, ORDER BY and LIMIT over them. It reads
+// the tables through their clustered index, or an index covering the columns
+// read. ok is false when p reads a table otherwise.
+func dualRows(ctx context.Context, p plan.Plan) (rows [][]basic.Datum, ok bool, err error) {
+	switch x := p.(type) {
+	case *plan.TableDual:
+		return make([][]basic.Datum, x.RowCount), true, nil
+	case *plan.PhysicalTableScan:
+		return tableScanRows(ctx, x)
+	case *plan.PhysicalIndexScan:
+		return indexScanRows(ctx, x)
+	case *plan.Union:
+		for _, child := range x.Children() {
+			childRows, ok, err := dualRows(ctx, child)
+			if err != nil || !ok {
+				return nil, ok, errors.Trace(err)
+			}
+			rows = append(rows, childRows...)
+		}
+		return rows, true, nil
+	case *plan.Projection, *plan.Selection, *plan.Sort, *plan.Limit, *plan.PhysicalAggregation:
+		if len(p.Children()) != 1 {
+			return nil, false, nil
+		}
+		rows, ok, err = dualRows(ctx, p.Children()[0])
+		if err != nil || !ok {
+			return nil, ok, errors.Trace(err)
+		}
+	default:
+		return nil, false, nil
+	}
+	switch x := p.(type) {
+	case *plan.Projection:
+		rows, err = projectRows(ctx, x.Exprs, rows)
+	case *plan.Selection:
+		rows, err = selectRows(ctx, x.Conditions, rows)
+	case *plan.Sort:
+		if rows, err = sortRows(ctx, x.ByItems, rows); err == nil && x.ExecLimit != nil {
+			rows = limitRows(x.ExecLimit, rows)
+		}
+	case *plan.Limit:
+		rows = limitRows(x, rows)
+	case *plan.PhysicalAggregation:
+		rows, err = aggregateRows(ctx, x, rows)
+	}
+	return rows, true, errors.Trace(err)
+}
+
+// projectRows evaluates exprs on each row.
+func projectRows(ctx context.Context, exprs []expression.Expression, rows [][]basic.Datum) ([][]basic.Datum, error) {
+	projected := make([][]basic.Datum, 0, len(rows))
+	for _, row := range rows {
+		newRow := make([]basic.Datum, 0, len(exprs))
+		for _, expr := range exprs {
+			d, err := expr.Eval(row)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			newRow = append(newRow, d)
+		}
+		if err := consumeRow(ctx, newRow); err != nil {
+			return nil, errors.Trace(err)
+		}
+		projected = append(projected, newRow)
+	}
+	return projected, nil
+}
+
+// selectRows returns the rows on which conditions hold.
+func selectRows(ctx context.Context, conditions []expression.Expression, rows [][]basic.Datum) ([][]basic.Datum, error) {
+	selected := rows[:0]
+	for _, row := range rows {
+		match, err := expression.EvalBool(conditions, row, ctx)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if match {
+			selected = append(selected, row)
+		}
+	}
+	return selected, nil
+}
+
+// sortRows sorts rows by byItems, NULL first in ascending order. Rows equal
+// on every item keep their order. The sort keys are tracked while it runs,
+// past the memory limit the sort fails with ErrOutOfSortMemory.
+func sortRows(ctx context.Context, byItems []*plan.ByItems, rows [][]basic.Datum) ([][]basic.Datum, error) {
+	sc := ctx.GetSessionVars().StmtCtx
+	var keysSize int64
+	defer func() { sc.MemTracker.Release(keysSize) }()
+	keys := make([][]basic.Datum, len(rows))
+	for i, row := range rows {
+		for _, by := range byItems {
+			d, err := by.Expr.Eval(row)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			keys[i] = append(keys[i], d)
+		}
+		size := rowSize(keys[i])
+		if err := sc.MemTracker.Consume(size); err != nil {
+			if memory.ErrMemoryExceeded.Equal(err) {
+				return nil, ErrOutOfSortMemory
+			}
+			return nil, errors.Trace(err)
+		}
+		keysSize += size
+	}
+	order := make([]int, len(rows))
+	for i := range order {
+		order[i] = i
+	}
+	var err error
+	sort.SliceStable(order, func(i, j int) bool {
+		for k, by := range byItems {
+			cmp, cmpErr := keys[order[i]][k].CompareDatum(sc, &keys[order[j]][k])
+			if cmpErr != nil {
+				err = cmpErr
+				return false
+			}
+			if cmp != 0 {
+				return (cmp < 0) != by.Desc
+			}
+		}
+		return false
+	})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	sorted := make([][]basic.Datum, len(rows))
+	for i, idx := range order {
+		sorted[i] = rows[idx]
+	}
+	return sorted, nil
+}
+
+// limitRows returns the rows the OFFSET and the row count of limit keep.
+func limitRows(limit *plan.Limit, rows [][]basic.Datum) [][]basic.Datum {
+	if limit.Offset >= uint64(len(rows)) {
+		return nil
+	}
+	rows = rows[limit.Offset:]
+	if limit.Count < uint64(len(rows)) {
+		rows = rows[:limit.Count]
+	}
+	return rows
+}
+
+func init() {
+	registerStmtHandler(&ast.SelectStmt{}, &stmtHandler{name: "select", handle: (*XMySQLEngine).execSelect})
+}
+
+// execSelect sends the rows of a SELECT.
+func (srv *XMySQLEngine) execSelect(session innodb.MySQLServerSession, stmt ast.StmtNode, p plan.Plan) {
+	x := stmt.(*ast.SelectStmt)
+	rows, ok, err := dualRows(session, p)
+	if err != nil {
+		session.SendError(toSQLError(err))
+		return
+	}
+	if ok {
+		if err = session.SendResultSet(ResultColumns(x, p), innodb.SliceRows(rows)); err != nil {
+			session.SendError(toSQLError(err))
+		}
+	}
+}
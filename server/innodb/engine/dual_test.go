@@ -0,0 +1,111 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+func TestSelectWithoutFrom(t *testing.T) {
+	s := newViewTestSession(t, newViewTestSchema())
+	tests := []struct {
+		sql    string
+		names  []string
+		values []string
+	}{
+		{"SELECT 1+1", []string{"1+1"}, []string{"2"}},
+		{"SELECT CONCAT('a','b') AS c, 3*4", []string{"c", "3*4"}, []string{"ab", "12"}},
+		{"SELECT 1 FROM DUAL", []string{"1"}, []string{"1"}},
+	}
+	for _, tt := range tests {
+		stmt, p, err := compileView(s, tt.sql)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows, ok, err := dualRows(s, p)
+		if err != nil || !ok || len(rows) != 1 {
+			t.Fatalf("%s: expect one row, got %v %v %v", tt.sql, rows, ok, err)
+		}
+		fields := ResultColumns(stmt, p)
+		for i, name := range tt.names {
+			if fields[i].Name != name {
+				t.Fatalf("%s: expect column %s, got %s", tt.sql, name, fields[i].Name)
+			}
+			if value, _ := rows[0][i].ToString(); value != tt.values[i] {
+				t.Fatalf("%s: expect %s, got %s", tt.sql, tt.values[i], value)
+			}
+		}
+	}
+
+	stmt, p, err := compileView(s, "SELECT NOW()")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, ok, err := dualRows(s, p)
+	if err != nil || !ok || len(rows) != 1 {
+		t.Fatalf("expect one row, got %v %v %v", rows, ok, err)
+	}
+	if fields := ResultColumns(stmt, p); fields[0].Name != "NOW()" || fields[0].Types != int(mysql.TypeDatetime) {
+		t.Fatalf("unexpected column %+v", fields[0])
+	}
+	value, _ := rows[0][0].ToString()
+	now, err := time.ParseInLocation("2006-01-02 15:04:05", value, time.Local)
+	if err != nil || time.Since(now) > time.Minute || time.Since(now) < -time.Minute {
+		t.Fatalf("expect the current time, got %s", value)
+	}
+
+	// A SELECT reading a table reads its clustered index, empty here.
+	is := newViewTestSchema(newFKTestTable("t", "a"))
+	s = newViewTestSession(t, is)
+	if _, p, err = compileView(s, "SELECT a FROM t"); err != nil {
+		t.Fatal(err)
+	}
+	if rows, ok, err = dualRows(s, p); err != nil || !ok || len(rows) != 0 {
+		t.Fatalf("expect no rows, got %v %v %v", rows, ok, err)
+	}
+}
+
+func TestControlFlowExpressions(t *testing.T) {
+	s := newViewTestSession(t, newViewTestSchema())
+	tests := []struct {
+		sql   string
+		value string
+		tp    byte
+	}{
+		{"SELECT CASE WHEN 1 > 2 THEN 'a' WHEN 2 > 3 THEN 'b' ELSE 'c' END", "c", mysql.TypeVarchar},
+		{"SELECT CASE 2 WHEN 1 THEN 'a' WHEN 2 THEN 'b' END", "b", mysql.TypeVarchar},
+		{"SELECT CASE 3 WHEN 1 THEN 'a' END", "<nil>", mysql.TypeVarString},
+		{"SELECT CASE NULL WHEN NULL THEN 1 ELSE 2 END", "2", mysql.TypeLonglong},
+		{"SELECT CASE WHEN 0 THEN 1 ELSE 2.5 END", "2.5", mysql.TypeNewDecimal},
+		{"SELECT IF(1 > 2, NULL, 7)", "7", mysql.TypeLonglong},
+		{"SELECT IF(1, 2, 'x')", "2", mysql.TypeVarchar},
+		{"SELECT IFNULL(NULL, 3)", "3", mysql.TypeLonglong},
+		{"SELECT COALESCE(NULL, NULL, 3, 4)", "3", mysql.TypeLonglong},
+		{"SELECT COALESCE(NULL, 'x', 1)", "x", mysql.TypeVarString},
+		{"SELECT COALESCE(NULL, NULL)", "<nil>", mysql.TypeVarString},
+		{"SELECT NULLIF(1, 1)", "<nil>", mysql.TypeLonglong},
+		{"SELECT NULLIF(1, 2)", "1", mysql.TypeLonglong},
+		{"SELECT NULLIF('a', 'b')", "a", mysql.TypeVarString},
+	}
+	for _, tt := range tests {
+		stmt, p, err := compileView(s, tt.sql)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.sql, err)
+		}
+		rows, ok, err := dualRows(s, p)
+		if err != nil || !ok || len(rows) != 1 {
+			t.Fatalf("%s: expect one row, got %v %v %v", tt.sql, rows, ok, err)
+		}
+		value := "<nil>"
+		if !rows[0][0].IsNull() {
+			value, _ = rows[0][0].ToString()
+		}
+		if value != tt.value {
+			t.Errorf("%s: got %s, want %s", tt.sql, value, tt.value)
+		}
+		if tp := ResultColumns(stmt, p)[0].Types; tp != int(tt.tp) {
+			t.Errorf("%s: got type %d, want %d", tt.sql, tp, tt.tp)
+		}
+	}
+}
@@ -3,14 +3,26 @@ package engine
 import (
 	"github.com/goioc/di"
 	log "github.com/sirupsen/logrus"
+	"github.com/zhukovaskychina/xmysql-server/server/common"
 	"github.com/zhukovaskychina/xmysql-server/server/conf"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/audit"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/buffer_pool"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/innodb_store/store"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/innodb_store/store/storebytes/mvcc"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/innodb_store/store/storebytes/pages"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/privilege/privileges"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/statistics"
 	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -21,22 +33,72 @@ type XMySQLEngine struct {
 	//定义查询线程
 	//	QueryExecutor *XMySQLExecutor
 	//定义purge线程
+	purgeSys *mvcc.PurgeSys
+	//活跃事务和读视图
+	mvcc *mvcc.Mvcc
+	//事务的行锁，见 row_locks.go
+	locks *mvcc.LockInfoManager
+	//行的旧版本，见 row_versions.go
+	versions *rowVersions
 	//定义SchemaManager
 	infoSchemaManager schemas.InfoSchema
+	//统计信息，ANALYZE TABLE 的结果
+	statsHandle *statistics.Handle
 
 	pool *buffer_pool.BufferPool
+	//审计日志
+	auditLog *audit.Logger
+	//read_only 和 super_read_only
+	readOnly readOnlyMode
+	//权限表的缓存，GRANT 和 REVOKE 修改它，没有读入权限表时为 nil
+	privHandle *privileges.Handle
+	//权限表 mysql.user 和 mysql.db，见 privilege_tables.go
+	privTables *privilegeTables
+	//Questions 和 Uptime
+	serverStatus serverStatistics
 }
 
 func NewXMySQLEngine(conf *conf.Cfg) *XMySQLEngine {
 	var mysqlEngine = new(XMySQLEngine)
 	mysqlEngine.conf = conf
+	mysqlEngine.initPageSize()
+	mysqlEngine.initLowerCaseTableNames()
+	mysqlEngine.initServerStatus()
+	variable.SysVars[variable.SecureFilePriv].Value = conf.SecureFilePriv
 	var fileSystem = basic.NewFileSystem(conf)
 	fileSystem.AddTableSpace(store.NewSysTableSpace(conf, false))
-	var bufferPool = buffer_pool.NewBufferPool(256*16384,
+	if err := store.CheckLowerCaseTableNames(conf); err != nil {
+		log.Fatal(err)
+	}
+	var bufferPool = buffer_pool.NewBufferPoolInstances(uint64(conf.InnodbBufferPoolSize), conf.InnodbBufferPoolInstances,
 		0.75, 0.25,
 		1000, fileSystem)
 	mysqlEngine.pool = bufferPool
+	mysqlEngine.initBufferPoolInstances()
+	mysqlEngine.initBufferPoolResize()
+	mysqlEngine.initAdaptiveHashIndex()
+	mysqlEngine.initChangeBuffer()
+	mysqlEngine.initPageChecksums()
+	mysqlEngine.initDefaultRowFormat()
+	mysqlEngine.initDefaultStorageEngine()
+	go mysqlEngine.mergeChangeBuffer()
 	mysqlEngine.infoSchemaManager = store.NewInfoSchemaManager(conf, bufferPool)
+	mysqlEngine.initPrivileges()
+	mysqlEngine.initBufferPoolDump()
+	mysqlEngine.initBufferPages()
+	mysqlEngine.initLogErrorVerbosity()
+	mysqlEngine.initAuditLog()
+	mysqlEngine.initReadOnly()
+	mysqlEngine.initMemoryLimit()
+	mysqlEngine.initPersistedVariables()
+	if conf.InnodbBufferPoolLoadAtStartup {
+		mysqlEngine.loadBufferPool()
+	}
+	mysqlEngine.statsHandle = statistics.NewHandle(nil, 0)
+	schemas.RegisterIndexStats(mysqlEngine.statsHandle)
+	mysqlEngine.initRowCounts()
+	go mysqlEngine.saveRowCounts()
+	mysqlEngine.initStatsAutoRecalc()
 	mysqlEngine.initPurgeThread()
 
 	di.RegisterBeanInstance("buffer_pool", bufferPool)
@@ -46,67 +108,390 @@ func NewXMySQLEngine(conf *conf.Cfg) *XMySQLEngine {
 
 func (srv *XMySQLEngine) initPurgeThread() {
 	go srv.flushToDisk()
+	srv.mvcc = mvcc.NewMvccWithTrxId(srv.maxTrxId())
+	srv.locks = mvcc.NewLockInfoManager()
+	srv.versions = newRowVersions()
+	srv.purgeSys = mvcc.NewPurgeSys(srv.mvcc, srv.versions)
+	variable.RegisterStatistics(srv.purgeSys)
+	srv.purgeSys.Start(time.Second, purgeBatchSize)
+}
+
+// maxTrxId returns the transaction id stored in the transaction system
+// page of ibdata1, from which the transaction ids go on after a restart.
+func (srv *XMySQLEngine) maxTrxId() mvcc.TrxId {
+	block := srv.pool.GetPageBlock(0, 5)
+	return mvcc.TrxId(pages.ParseSysTrxSysPage(*block.Frame).GetMaxTrxId())
+}
+
+// initAdaptiveHashIndex turns the adaptive hash index of the buffer pool on
+// or off as configured, lets SET GLOBAL innodb_adaptive_hash_index switch it
+// at runtime and adds its counters to SHOW ENGINE INNODB STATUS.
+func (srv *XMySQLEngine) initAdaptiveHashIndex() {
+	ahi := srv.pool.AHI
+	ahi.SetEnabled(srv.conf.InnodbAdaptiveHashIndex)
+	sv := variable.GetSysVar(variable.InnodbAdaptiveHashIndex)
+	variable.RegisterSysVar(sv, mysql.TypeVarString, func(*variable.SessionVars) (string, error) {
+		if ahi.Enabled() {
+			return "ON", nil
+		}
+		return "OFF", nil
+	})
+	variable.RegisterSysVarSetter(variable.InnodbAdaptiveHashIndex, func(_ *variable.SessionVars, value string) error {
+		switch strings.ToUpper(value) {
+		case "ON", "1", "TRUE":
+			ahi.SetEnabled(true)
+		case "OFF", "0", "FALSE":
+			ahi.SetEnabled(false)
+		default:
+			return variable.ErrWrongValueForVar.GenByArgs(variable.InnodbAdaptiveHashIndex, value)
+		}
+		return nil
+	})
+	registerInnodbStatus("insert buffer and adaptive hash index", srv.pool.InsertBufferStatus)
+}
+
+// initBufferPoolInstances reports the number of instances of the buffer
+// pool and adds their counters to SHOW ENGINE INNODB STATUS.
+func (srv *XMySQLEngine) initBufferPoolInstances() {
+	pool := srv.pool
+	sv := variable.GetSysVar(variable.InnodbBufferPoolInstances)
+	variable.RegisterSysVar(sv, mysql.TypeLonglong, func(*variable.SessionVars) (string, error) {
+		return strconv.Itoa(pool.Instances()), nil
+	})
+	registerInnodbStatus("buffer pool and memory", pool.BufferPoolStatus)
+}
+
+// initChangeBuffer sets what the change buffer buffers as configured, lets
+// SET GLOBAL innodb_change_buffering change it at runtime and
+// innodb_change_buffer_merge_now merge it all on demand.
+func (srv *XMySQLEngine) initChangeBuffer() {
+	cb := srv.pool.ChangeBuffer
+	if mode, err := buffer_pool.ParseChangeBuffering(srv.conf.InnodbChangeBuffering); err == nil {
+		cb.SetMode(mode)
+	}
+	sv := variable.GetSysVar(variable.InnodbChangeBuffering)
+	variable.RegisterSysVar(sv, mysql.TypeVarString, func(*variable.SessionVars) (string, error) {
+		return cb.Mode().String(), nil
+	})
+	variable.RegisterSysVarSetter(variable.InnodbChangeBuffering, func(_ *variable.SessionVars, value string) error {
+		mode, err := buffer_pool.ParseChangeBuffering(value)
+		if err != nil {
+			return variable.ErrWrongValueForVar.GenByArgs(variable.InnodbChangeBuffering, value)
+		}
+		cb.SetMode(mode)
+		return nil
+	})
+	registerSwitch(variable.InnodbChangeBufferMergeNow, func() bool {
+		return false
+	}, func(on bool) {
+		if on {
+			srv.mergeAllChangeBuffer()
+		}
+	})
+	registerInnodbStatus("insert buffer and adaptive hash index", srv.pool.InsertBufferStatus)
+}
+
+// initPageSize sets the size of the pages, innodb_page_size, before any
+// tablespace is opened or created.
+func (srv *XMySQLEngine) initPageSize() {
+	if err := common.SetPageSize(srv.conf.InnodbPageSize); err != nil {
+		log.Fatal(err)
+	}
+	variable.SysVars[variable.InnodbPageSize].Value = strconv.Itoa(common.PageSize())
+}
+
+// initLowerCaseTableNames sets how the names of databases and tables are
+// stored and compared, lower_case_table_names, before the dictionary loads.
+func (srv *XMySQLEngine) initLowerCaseTableNames() {
+	if err := model.SetLowerCaseTableNames(srv.conf.LowerCaseTableNames); err != nil {
+		log.Fatal(err)
+	}
+	variable.SysVars[variable.LowerCaseTableNames].Value = strconv.Itoa(model.LowerCaseTableNames())
+}
+
+// initPageChecksums sets how the pages are checksummed and what a page whose
+// checksum doesn't match does, innodb_checksum_algorithm and
+// innodb_corrupt_page_action.
+func (srv *XMySQLEngine) initPageChecksums() {
+	if algorithm, err := pages.ParseChecksumAlgorithm(srv.conf.InnodbChecksumAlgorithm); err == nil {
+		pages.SetChecksumAlgorithm(algorithm)
+	}
+	if action, err := store.ParseCorruptPageAction(srv.conf.InnodbCorruptPageAction); err == nil {
+		store.SetCorruptPageAction(action)
+	}
+	sv := variable.GetSysVar(variable.InnodbChecksumAlgorithm)
+	variable.RegisterSysVar(sv, mysql.TypeVarString, func(*variable.SessionVars) (string, error) {
+		return pages.GetChecksumAlgorithm().String(), nil
+	})
+	variable.RegisterSysVarSetter(variable.InnodbChecksumAlgorithm, func(_ *variable.SessionVars, value string) error {
+		algorithm, err := pages.ParseChecksumAlgorithm(value)
+		if err != nil {
+			return variable.ErrWrongValueForVar.GenByArgs(variable.InnodbChecksumAlgorithm, value)
+		}
+		pages.SetChecksumAlgorithm(algorithm)
+		return nil
+	})
+	sv = variable.GetSysVar(variable.InnodbCorruptPageAction)
+	variable.RegisterSysVar(sv, mysql.TypeVarString, func(*variable.SessionVars) (string, error) {
+		return store.GetCorruptPageAction().String(), nil
+	})
+	variable.RegisterSysVarSetter(variable.InnodbCorruptPageAction, func(_ *variable.SessionVars, value string) error {
+		action, err := store.ParseCorruptPageAction(value)
+		if err != nil {
+			return variable.ErrWrongValueForVar.GenByArgs(variable.InnodbCorruptPageAction, value)
+		}
+		store.SetCorruptPageAction(action)
+		return nil
+	})
+}
+
+// mergeAllChangeBuffer merges every insert of the change buffer into its
+// page and returns their number.
+func (srv *XMySQLEngine) mergeAllChangeBuffer() int {
+	n := srv.pool.MergeAllChangeBuffer()
+	log.Infof("合并写缓冲 %d 条插入", n)
+	return n
 }
 
+// initDefaultRowFormat lets SET GLOBAL innodb_default_row_format change the
+// row format of the tables created without ROW_FORMAT.
+func (srv *XMySQLEngine) initDefaultRowFormat() {
+	cfg := srv.conf
+	sv := variable.GetSysVar(variable.InnodbDefaultRowFormat)
+	variable.RegisterSysVar(sv, mysql.TypeVarString, func(*variable.SessionVars) (string, error) {
+		format, err := store.TableRowFormat("", cfg)
+		return strings.ToLower(format.String()), err
+	})
+	variable.RegisterSysVarSetter(variable.InnodbDefaultRowFormat, func(_ *variable.SessionVars, value string) error {
+		format, err := store.ParseRowFormat(value)
+		if err != nil {
+			return variable.ErrWrongValueForVar.GenByArgs(variable.InnodbDefaultRowFormat, value)
+		}
+		cfg.InnodbDefaultRowFormat = format.String()
+		return nil
+	})
+}
+
+// changeBufferMergeBatch is the number of pages merged at most per second
+// while nothing is buffered.
+const changeBufferMergeBatch = 20
+
+// mergeChangeBuffer merges the change buffer in the background, a batch of
+// pages each second nothing was buffered.
+func (srv *XMySQLEngine) mergeChangeBuffer() {
+	timeTicker := time.NewTicker(1 * time.Second)
+	for {
+		<-timeTicker.C
+		if srv.pool.ChangeBuffer.Idle() {
+			if n := srv.pool.MergeChangeBuffer(changeBufferMergeBatch); n > 0 {
+				log.Infof("合并写缓冲 %d 个页面", n)
+			}
+		}
+	}
+}
+
+// purgeBatchSize is the number of undo logs purged at most per second.
+const purgeBatchSize = 300
+
 func (srv *XMySQLEngine) flushToDisk() {
 	//count := 0
 	timeTicker := time.NewTicker(1 * time.Second)
 	for {
 		<-timeTicker.C
-		blockBuffer := srv.pool.GetFlushDiskList().GetLastBlock()
+		blockBuffer := srv.pool.GetLastDirtyBlock()
 		if blockBuffer == nil {
 			log.Info("没有页面可以刷新")
 		} else {
 			log.Info("刷新脏页面")
-			purgeThread(srv.pool.FileSystem, blockBuffer.GetSpaceId(), blockBuffer.GetPageNo(), blockBuffer)
+			srv.pool.FlushBlock(blockBuffer)
 		}
 
 	}
 }
 
-func purgeThread(system basic.FileSystem, spaceId uint32, pageNo uint32, block *buffer_pool.BufferBlock) {
-	ts := system.GetTableSpaceById(spaceId)
-	ts.FlushToDisk(pageNo, *(block.GetFrame()))
-}
-
 //ast->plan->storebytes->result->net
+//审计日志打开时，记录语句的类型、影响的行数、耗时和错误码
+//会话打开 profiling 时，记录语句各阶段的耗时
 func (srv *XMySQLEngine) ExecuteQuery(session innodb.MySQLServerSession, query string) {
+	atomic.AddUint64(&srv.serverStatus.questions, 1)
+	vars := session.GetSessionVars()
+	vars.SetProcessInfo(query)
+	defer vars.SetProcessInfo("")
+	var audited *auditedSession
+	if srv.auditLog.Enabled() {
+		audited = &auditedSession{MySQLServerSession: session}
+		session = audited
+	}
+	var profiled *profiledSession
+	if vars.Profiling {
+		profiled = newProfiledSession(session, query)
+		session = profiled
+	}
+	start := time.Now()
+	srv.executeQuery(session, query)
+	if profiled != nil {
+		profiled.finish()
+	}
+	if audited != nil {
+		srv.auditLog.Log(queryEvent(audited, query, srv.auditLog.Policy(), time.Since(start)))
+	}
+}
+
+// startsImplicitTxn reports whether stmt starts a transaction of a session
+// of vars: with autocommit off, outside a transaction, it reads or changes
+// a table.
+func startsImplicitTxn(vars *variable.SessionVars, stmt ast.StmtNode) bool {
+	if vars.IsAutocommit() || vars.InTxn() {
+		return false
+	}
+	return readsRows(stmt)
+}
+
+// readsRows reports whether stmt reads or writes the rows of a table.
+func readsRows(stmt ast.StmtNode) bool {
+	if changesRows(stmt) {
+		return true
+	}
+	if x, ok := stmt.(*ast.SelectStmt); ok {
+		return x.From != nil
+	}
+	return false
+}
 
+// changesRows reports whether stmt writes the rows of a table.
+func changesRows(stmt ast.StmtNode) bool {
+	switch stmt.(type) {
+	case *ast.InsertStmt, *ast.UpdateStmt, *ast.DeleteStmt, *ast.LoadDataStmt, *ast.ImportTableStmt:
+		return true
+	}
+	return false
+}
+
+// executeQuery parses and runs query.
+func (srv *XMySQLEngine) executeQuery(session innodb.MySQLServerSession, query string) {
+	profileStage(session, stageParsing)
 	stmt, err := session.ParseOneSQL(query, mysql.UTF8Charset, mysql.UTF8DefaultCollation)
 	if err != nil {
-		session.SendError(mysql.NewErr(mysql.ErrSyntax, err))
+		session.SendError(toSQLError(err))
 		return
 	}
-	Compile(session, stmt)
-	switch stmt.(type) {
-	case *ast.SelectStmt:
-		{
+	srv.executeStmt(session, stmt, false)
+}
 
+// executeStmt compiles and runs stmt and sends its result. A prepared stmt
+// has its placeholders set to the values it is executed with.
+func (srv *XMySQLEngine) executeStmt(session innodb.MySQLServerSession, stmt ast.StmtNode, prepared bool) {
+	ResetStmtCtx(session, stmt)
+	defer func() {
+		session.GetSessionVars().StmtCtx.MemTracker.Detach()
+	}()
+	if err := srv.checkReadOnly(session, stmt); err != nil {
+		session.SendError(toSQLError(err))
+		return
+	}
+	profileStage(session, stageOptimizing)
+	p, err := compile(session, stmt, prepared)
+	if err != nil {
+		session.SendError(toSQLError(err))
+		return
+	}
+	defer openTables.open(session.GetSessionVars(), srv.infoSchemaManager, stmt)()
+	// With autocommit off, the first statement reading or changing a table
+	// starts a transaction, which lasts until COMMIT or ROLLBACK.
+	if startsImplicitTxn(session.GetSessionVars(), stmt) {
+		if err = session.NewTxn(); err != nil {
+			session.SendError(toSQLError(err))
+			return
 		}
-	case *ast.CreateTableStmt:
-		{
-
+		srv.openReadView(session)
+	} else if !session.GetSessionVars().InTxn() && readsRows(stmt) {
+		// A statement outside a transaction is a transaction of its own,
+		// reading the rows committed when it starts and holding its row
+		// locks until it ends.
+		srv.openReadView(session)
+		defer srv.closeReadView(session)
+	}
+	// Outside a transaction each statement commits on its own.
+	defer func() {
+		if !session.GetSessionVars().InTxn() {
+			srv.commitRowDeltas(session)
 		}
-	case *ast.CreateDatabaseStmt:
-		{
+	}()
+	h := findStmtHandler(stmt)
+	if h == nil {
+		log.Debugf("no handler for %T", stmt)
+		return
+	}
+	log.Debugf("%T routed to %s", stmt, h.name)
+	profileStage(session, stageExecuting)
+	if retries := autocommitDeadlockRetries(session, stmt); retries > 0 {
+		srv.handleRetryingDeadlock(h, session, stmt, p, retries)
+	} else {
+		h.handle(srv, session, stmt, p)
+	}
+	srv.rollbackDeadlockVictim(session)
+}
 
-		}
-	case *ast.CreateIndexStmt:
-		{
+func init() {
+	registerStmtHandler(&ast.UseStmt{}, &stmtHandler{name: "use", handle: (*XMySQLEngine).execUse})
+	registerStmtHandler(&ast.BeginStmt{}, &stmtHandler{name: "begin", handle: (*XMySQLEngine).execBegin})
+	registerStmtHandler(&ast.CommitStmt{}, &stmtHandler{name: "commit", handle: (*XMySQLEngine).execCommit})
+	registerStmtHandler(&ast.RollbackStmt{}, &stmtHandler{name: "rollback", handle: (*XMySQLEngine).execRollback})
+}
 
-		}
-	case *ast.InsertStmt:
-		{
+// execUse runs a USE.
+func (srv *XMySQLEngine) execUse(session innodb.MySQLServerSession, stmt ast.StmtNode, p plan.Plan) {
+	srv.useDatabase(session, stmt.(*ast.UseStmt).DBName)
+}
 
-		}
-	case *ast.UpdateStmt:
-		{
+// InitDB runs a COM_INIT_DB, the command the clients send for USE dbName.
+func (srv *XMySQLEngine) InitDB(session innodb.MySQLServerSession, dbName string) {
+	if dbName == "" {
+		session.SendError(toSQLError(plan.ErrNoDB))
+		return
+	}
+	srv.useDatabase(session, dbName)
+}
 
-		}
-	case *ast.DeleteStmt:
-		{
+// useDatabase makes dbName the current database of session, or fails with
+// ER_BAD_DB_ERROR when it doesn't exist.
+func (srv *XMySQLEngine) useDatabase(session innodb.MySQLServerSession, dbName string) {
+	if _, ok := srv.infoSchemaManager.SchemaByName(model.NewCIStr(dbName)); !ok {
+		session.SendError(toSQLError(schemas.ErrDatabaseNotExists.GenByArgs(dbName)))
+		return
+	}
+	session.SetCurrentDatabase(dbName)
+	session.GetSessionVars().TrackSchema(dbName)
+	session.SendOK()
+}
 
-		}
+// execBegin runs a BEGIN.
+func (srv *XMySQLEngine) execBegin(session innodb.MySQLServerSession, stmt ast.StmtNode, p plan.Plan) {
+	srv.commitRowDeltas(session)
+	if err := session.NewTxn(); err != nil {
+		session.SendError(toSQLError(err))
+		return
+	}
+	srv.openReadView(session)
+	session.SendOK()
+}
 
+// execCommit runs a COMMIT.
+func (srv *XMySQLEngine) execCommit(session innodb.MySQLServerSession, stmt ast.StmtNode, p plan.Plan) {
+	srv.commitRowDeltas(session)
+	session.Commit()
+	srv.closeReadView(session)
+	session.SendOK()
+}
+
+// execRollback runs a ROLLBACK.
+func (srv *XMySQLEngine) execRollback(session innodb.MySQLServerSession, stmt ast.StmtNode, p plan.Plan) {
+	rollbackRowDeltas(session)
+	srv.rollbackRows(session)
+	srv.closeReadView(session)
+	if err := session.RollbackTxn(); err != nil {
+		session.SendError(toSQLError(err))
+		return
 	}
+	session.SendOK()
 }
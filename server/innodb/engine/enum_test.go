@@ -0,0 +1,201 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/expression"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/charset"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/codec"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+func TestEnumSetColumns(t *testing.T) {
+	// CREATE TABLE shirts (id INT, size ENUM('s','m','l'), tags SET('a','b'));
+	tbl := newFKTestTable("shirts", "id", "size", "tags")
+	tbl.Columns[1].FieldType = *basic.NewFieldType(mysql.TypeEnum)
+	tbl.Columns[1].Elems = []string{"s", "m", "l"}
+	tbl.Columns[2].FieldType = *basic.NewFieldType(mysql.TypeSet)
+	tbl.Columns[2].Elems = []string{"a", "b"}
+	s := newViewTestSession(t, newViewTestSchema(tbl))
+
+	for _, sql := range []string{
+		"CREATE TABLE t (c ENUM('a','b','A'))",
+		"CREATE TABLE t (c SET('x','y','x'))",
+	} {
+		if _, _, err := compileView(s, sql); errCode(err) != mysql.ErrDuplicatedValueInType {
+			t.Fatalf("%s: expect error %d, got %v", sql, mysql.ErrDuplicatedValueInType, err)
+		}
+	}
+
+	stmt, p, err := compileView(s, "SELECT size, tags FROM shirts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := ResultColumns(stmt, p)
+	if len(fields) != 2 {
+		t.Fatalf("unexpected columns %+v", fields)
+	}
+	for i, flag := range []uint{mysql.EnumFlag, mysql.SetFlag} {
+		if fields[i].Types != int(mysql.TypeString) || fields[i].Flags&int(flag) == 0 {
+			t.Fatalf("column %d: expect a string with flag %d, got %+v", i, flag, fields[i])
+		}
+	}
+}
+
+func TestEnumInsertAndOrder(t *testing.T) {
+	// CREATE TABLE shirts (id INT NOT NULL, size ENUM('small','medium','large'));
+	size := &model.ColumnInfo{Name: model.NewCIStr("size"), Offset: 1}
+	size.FieldType = *basic.NewFieldType(mysql.TypeEnum)
+	size.Elems = []string{"small", "medium", "large"}
+	cols := newInsertTestCols()[:1]
+	cols = append(cols, schemas.ToColumn(size))
+	insert := func(sqlMode string, values ...string) ([][]basic.Datum, *session, error) {
+		s := newInsertTestSession(t, sqlMode)
+		e := &InsertValues{ctx: s, tableCols: cols, Columns: columnNames("id", "size")}
+		for i, v := range values {
+			e.Lists = append(e.Lists, []expression.Expression{constant(int64(i)), constant(v)})
+		}
+		rows, err := e.getRows()
+		return rows, s, err
+	}
+
+	if _, _, err := insert("STRICT_TRANS_TABLES", "medium", "huge"); errCode(err) != mysql.WarnDataTruncated {
+		t.Fatalf("expect error %d in strict mode, got %v", mysql.WarnDataTruncated, err)
+	}
+	rows, s, err := insert("", "large", "huge", "SMALL", "medium")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.sessionVars.StmtCtx.WarningCount() != 1 {
+		t.Fatalf("expect a warning for huge, got %d", s.sessionVars.StmtCtx.WarningCount())
+	}
+
+	// Rows keep the ordinal of the member, 0 for the invalid value, and read
+	// back as the member.
+	for i, want := range []basic.Enum{{Name: "large", Value: 3}, {}, {Name: "small", Value: 1}, {Name: "medium", Value: 2}} {
+		b, err := codec.EncodeValue(nil, rows[i][1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, d, err := codec.DecodeOne(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.Kind() != basic.KindUint64 || d.GetUint64() != want.Value {
+			t.Fatalf("row %d: expect the ordinal %d to be stored, got %v", i, want.Value, d.GetValue())
+		}
+		if d, err = schemas.Unflatten(d, size); err != nil {
+			t.Fatal(err)
+		}
+		if got := d.GetMysqlEnum(); got != want {
+			t.Fatalf("row %d: expect %v, got %v", i, want, got)
+		}
+		rows[i][1] = d
+	}
+
+	// ORDER BY size sorts by the ordinal, not by the name.
+	by := []*plan.ByItems{{Expr: &expression.Column{Index: 1, RetType: &size.FieldType}}}
+	sorted, err := sortRows(s, by, rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, row := range sorted {
+		names = append(names, row[1].GetMysqlEnum().Name)
+	}
+	if want := []string{"", "small", "medium", "large"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("expect %v, got %v", want, names)
+	}
+}
+
+func TestBitColumns(t *testing.T) {
+	// CREATE TABLE flags (id INT, b BIT(8));
+	tbl := newFKTestTable("flags", "id", "b")
+	tbl.Columns[1].FieldType = *basic.NewFieldType(mysql.TypeBit)
+	tbl.Columns[1].Flen = 8
+	tbl.Columns[1].Flag |= mysql.UnsignedFlag
+	s := newViewTestSession(t, newViewTestSchema(tbl))
+
+	if _, _, err := compileView(s, "CREATE TABLE t (b BIT(65))"); errCode(err) != mysql.ErrTooBigDisplaywidth {
+		t.Fatalf("expect error %d, got %v", mysql.ErrTooBigDisplaywidth, err)
+	}
+
+	stmt, p, err := compileView(s, "SELECT b, b + 0 FROM flags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := ResultColumns(stmt, p)
+	if len(fields) != 2 || fields[0].Types != int(mysql.TypeBit) || fields[0].Flags&int(mysql.UnsignedFlag) == 0 {
+		t.Fatalf("expect an unsigned BIT column, got %+v", fields)
+	}
+	if !mysql.IsIntegerType(byte(fields[1].Types)) {
+		t.Fatalf("expect b + 0 to be an integer, got %+v", fields[1])
+	}
+}
+
+func TestBinaryColumns(t *testing.T) {
+	// CREATE TABLE digests (id INT, k VARBINARY(8), c BINARY(4), UNIQUE KEY uk (k));
+	tbl := newFKTestTable("digests", "id", "k", "c")
+	for i, tp := range []byte{mysql.TypeVarchar, mysql.TypeString} {
+		col := tbl.Columns[i+1]
+		col.FieldType = *basic.NewFieldType(tp)
+		col.Flen, col.Charset, col.Collate = 8>>uint(i), charset.CharsetBin, charset.CollationBin
+		col.Flag |= mysql.BinaryFlag
+	}
+	tbl.Indices = []*model.IndexInfo{{
+		Name:    model.NewCIStr("uk"),
+		Unique:  true,
+		Columns: []*model.IndexColumn{{Name: model.NewCIStr("k"), Offset: 1}},
+		State:   model.StatePublic,
+	}}
+	s := newViewTestSession(t, newViewTestSchema(tbl))
+
+	stmt, p, err := compileView(s, "SELECT k, c, X'41', HEX(c), 'a' FROM digests")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := ResultColumns(stmt, p)
+	for i, want := range []int{mysql.BinaryCollationID, mysql.BinaryCollationID, mysql.BinaryCollationID, 0, 0} {
+		if fields[i].Charset != want {
+			t.Fatalf("column %d: expect charset %d, got %+v", i, want, fields[i])
+		}
+	}
+
+	// Keys of VARBINARY compare byte by byte, 'a' and 'A' are different.
+	store := newFKTestStore(tbl)
+	insert := func(sql string) error {
+		_, p, err := compileView(s, sql)
+		if err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+		e := NewInsertValues(s, p.(*plan.Insert))
+		rows, err := e.getRows()
+		if err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+		_, err = e.upsertRows(store, tbl, rows)
+		return err
+	}
+	if err := insert("INSERT INTO digests VALUES (1, 'a', X'00FF'), (2, 'A', _binary 'xy')"); err != nil {
+		t.Fatal(err)
+	}
+	if err := insert("INSERT INTO digests VALUES (3, 0x61, NULL)"); errCode(err) != mysql.ErrDupEntry {
+		t.Fatalf("expect error %d, got %v", mysql.ErrDupEntry, err)
+	}
+
+	// BINARY values are padded with zero bytes to the length of the column.
+	_, rows, _ := store.Rows(tbl)
+	got := make(map[int64][]byte)
+	for _, row := range rows {
+		got[row[0].GetInt64()] = row[2].GetBytes()
+	}
+	want := map[int64][]byte{1: {0x00, 0xff, 0x00, 0x00}, 2: []byte("xy\x00\x00")}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expect %v, got %v", want, got)
+	}
+}
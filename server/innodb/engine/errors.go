@@ -0,0 +1,106 @@
+package engine
+
+import (
+	"github.com/juju/errors"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/terror"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// Error instances.
+var (
+	ErrWrongValueCountOnRow     = terror.ClassExecutor.New(codeWrongValueCountOnRow, mysql.MySQLErrName[mysql.ErrWrongValueCountOnRow])
+	ErrRowIsReferenced2         = terror.ClassExecutor.New(codeRowIsReferenced2, mysql.MySQLErrName[mysql.ErrRowIsReferenced2])
+	ErrNoReferencedRow2         = terror.ClassExecutor.New(codeNoReferencedRow2, mysql.MySQLErrName[mysql.ErrNoReferencedRow2])
+	ErrForeignCascadeDepth      = terror.ClassExecutor.New(codeFkDepthExceeded, mysql.MySQLErrName[mysql.ErrFkDepthExceeded])
+	ErrFileExists               = terror.ClassExecutor.New(codeFileExists, mysql.MySQLErrName[mysql.ErrFileExists])
+	ErrOptionPreventsStatement  = terror.ClassExecutor.New(codeOptionPreventsStatement, mysql.MySQLErrName[mysql.ErrOptionPreventsStatement])
+	ErrDupEntry                 = terror.ClassExecutor.New(codeDupEntry, "Duplicate entry '%s' for key '%s'")
+	ErrUnknownStorageEngine     = terror.ClassExecutor.New(codeUnknownStorageEngine, mysql.MySQLErrName[mysql.ErrUnknownStorageEngine])
+	ErrIllegalHaCreateOption    = terror.ClassExecutor.New(codeIllegalHaCreateOption, mysql.MySQLErrName[mysql.ErrIllegalHaCreateOption])
+	ErrConfigNotReloaded        = terror.ClassExecutor.New(codeConfigNotReloaded, "Settings not reloaded, they need a restart or are persisted: %s")
+	ErrLockWaitTimeout          = terror.ClassExecutor.New(codeLockWaitTimeout, mysql.MySQLErrName[mysql.ErrLockWaitTimeout])
+	ErrLockDeadlock             = terror.ClassExecutor.New(codeLockDeadlock, mysql.MySQLErrName[mysql.ErrLockDeadlock])
+	ErrReadOnlyTransaction      = terror.ClassExecutor.New(codeReadOnlyTransaction, mysql.MySQLErrName[mysql.ErrCantExecuteInReadOnlyTransaction])
+	ErrUnknownCharacterSet      = terror.ClassExecutor.New(codeUnknownCharacterSet, mysql.MySQLErrName[mysql.ErrUnknownCharacterSet])
+	ErrUnknownCollation         = terror.ClassExecutor.New(codeUnknownCollation, mysql.MySQLErrName[mysql.ErrUnknownCollation])
+	ErrCollationCharsetMismatch = terror.ClassExecutor.New(codeCollationCharsetMismatch, mysql.MySQLErrName[mysql.ErrCollationCharsetMismatch])
+	ErrWarnUsingOtherHandler    = terror.ClassExecutor.New(codeWarnUsingOtherHandler, mysql.MySQLErrName[mysql.ErrWarnUsingOtherHandler])
+	ErrOutOfSortMemory          = terror.ClassExecutor.New(codeOutOfSortMemory, mysql.MySQLErrName[mysql.ErrOutOfSortMemory])
+	ErrCrashedOnUsage           = terror.ClassExecutor.New(codeCrashedOnUsage, mysql.MySQLErrName[mysql.ErrCrashedOnUsage])
+	ErrCutValueGroupConcat      = terror.ClassExecutor.New(codeCutValueGroupConcat, mysql.MySQLErrName[mysql.ErrCutValueGroupConcat])
+	ErrWarnTooFewRecords        = terror.ClassExecutor.New(codeWarnTooFewRecords, mysql.MySQLErrName[mysql.ErrWarnTooFewRecords])
+	ErrWarnTooManyRecords       = terror.ClassExecutor.New(codeWarnTooManyRecords, mysql.MySQLErrName[mysql.ErrWarnTooManyRecords])
+	ErrImportMalformedLine      = terror.ClassExecutor.New(codeImportMalformedLine, "Malformed CSV at line %d: %v")
+	ErrImportTooManyErrors      = terror.ClassExecutor.New(codeImportTooManyErrors, "IMPORT TABLE stopped after more than %d bad lines")
+)
+
+// Error codes.
+const (
+	codeWrongValueCountOnRow     terror.ErrCode = terror.ErrCode(mysql.ErrWrongValueCountOnRow)
+	codeRowIsReferenced2         terror.ErrCode = terror.ErrCode(mysql.ErrRowIsReferenced2)
+	codeNoReferencedRow2         terror.ErrCode = terror.ErrCode(mysql.ErrNoReferencedRow2)
+	codeFkDepthExceeded          terror.ErrCode = terror.ErrCode(mysql.ErrFkDepthExceeded)
+	codeFileExists               terror.ErrCode = terror.ErrCode(mysql.ErrFileExists)
+	codeOptionPreventsStatement  terror.ErrCode = terror.ErrCode(mysql.ErrOptionPreventsStatement)
+	codeDupEntry                 terror.ErrCode = terror.ErrCode(mysql.ErrDupEntry)
+	codeUnknownStorageEngine     terror.ErrCode = terror.ErrCode(mysql.ErrUnknownStorageEngine)
+	codeIllegalHaCreateOption    terror.ErrCode = terror.ErrCode(mysql.ErrIllegalHaCreateOption)
+	codeConfigNotReloaded        terror.ErrCode = terror.ErrCode(mysql.ErrVariableIsReadonly)
+	codeLockWaitTimeout          terror.ErrCode = terror.ErrCode(mysql.ErrLockWaitTimeout)
+	codeLockDeadlock             terror.ErrCode = terror.ErrCode(mysql.ErrLockDeadlock)
+	codeReadOnlyTransaction      terror.ErrCode = terror.ErrCode(mysql.ErrCantExecuteInReadOnlyTransaction)
+	codeUnknownCharacterSet      terror.ErrCode = terror.ErrCode(mysql.ErrUnknownCharacterSet)
+	codeUnknownCollation         terror.ErrCode = terror.ErrCode(mysql.ErrUnknownCollation)
+	codeCollationCharsetMismatch terror.ErrCode = terror.ErrCode(mysql.ErrCollationCharsetMismatch)
+	codeWarnUsingOtherHandler    terror.ErrCode = terror.ErrCode(mysql.ErrWarnUsingOtherHandler)
+	codeOutOfSortMemory          terror.ErrCode = terror.ErrCode(mysql.ErrOutOfSortMemory)
+	codeCrashedOnUsage           terror.ErrCode = terror.ErrCode(mysql.ErrCrashedOnUsage)
+	codeCutValueGroupConcat      terror.ErrCode = terror.ErrCode(mysql.ErrCutValueGroupConcat)
+	codeWarnTooFewRecords        terror.ErrCode = terror.ErrCode(mysql.ErrWarnTooFewRecords)
+	codeWarnTooManyRecords       terror.ErrCode = terror.ErrCode(mysql.ErrWarnTooManyRecords)
+	codeImportMalformedLine      terror.ErrCode = terror.ErrCode(mysql.ErrWrongFieldTerminators)
+	codeImportTooManyErrors      terror.ErrCode = terror.ErrCode(mysql.ErrUnknown)
+)
+
+func init() {
+	executorMySQLErrCodes := map[terror.ErrCode]uint16{
+		codeWrongValueCountOnRow:     mysql.ErrWrongValueCountOnRow,
+		codeRowIsReferenced2:         mysql.ErrRowIsReferenced2,
+		codeNoReferencedRow2:         mysql.ErrNoReferencedRow2,
+		codeFkDepthExceeded:          mysql.ErrFkDepthExceeded,
+		codeFileExists:               mysql.ErrFileExists,
+		codeOptionPreventsStatement:  mysql.ErrOptionPreventsStatement,
+		codeDupEntry:                 mysql.ErrDupEntry,
+		codeUnknownStorageEngine:     mysql.ErrUnknownStorageEngine,
+		codeIllegalHaCreateOption:    mysql.ErrIllegalHaCreateOption,
+		codeConfigNotReloaded:        mysql.ErrVariableIsReadonly,
+		codeLockWaitTimeout:          mysql.ErrLockWaitTimeout,
+		codeLockDeadlock:             mysql.ErrLockDeadlock,
+		codeReadOnlyTransaction:      mysql.ErrCantExecuteInReadOnlyTransaction,
+		codeUnknownCharacterSet:      mysql.ErrUnknownCharacterSet,
+		codeUnknownCollation:         mysql.ErrUnknownCollation,
+		codeCollationCharsetMismatch: mysql.ErrCollationCharsetMismatch,
+		codeWarnUsingOtherHandler:    mysql.ErrWarnUsingOtherHandler,
+		codeOutOfSortMemory:          mysql.ErrOutOfSortMemory,
+		codeCrashedOnUsage:           mysql.ErrCrashedOnUsage,
+		codeCutValueGroupConcat:      mysql.ErrCutValueGroupConcat,
+		codeWarnTooFewRecords:        mysql.ErrWarnTooFewRecords,
+		codeWarnTooManyRecords:       mysql.ErrWarnTooManyRecords,
+		codeImportMalformedLine:      mysql.ErrWrongFieldTerminators,
+		codeImportTooManyErrors:      mysql.ErrUnknown,
+	}
+	terror.ErrClassToMySQLCodes[terror.ClassExecutor] = executorMySQLErrCodes
+}
+
+// toSQLError converts err to the error packet sent back to the client: the
+// code, SQLSTATE and message of a typed error, ER_UNKNOWN_ERROR with the
+// message of any other.
+func toSQLError(err error) *mysql.SQLError {
+	switch x := errors.Cause(err).(type) {
+	case *terror.Error:
+		return x.ToSQLError()
+	case *mysql.SQLError:
+		return x
+	}
+	return mysql.NewErrf(mysql.ErrUnknown, "%s", err.Error())
+}
@@ -0,0 +1,74 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/juju/errors"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/parser"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/privilege"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/auth"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// denyingPrivilegeManager grants nothing, on the databases in visible some
+// privilege on another table.
+type denyingPrivilegeManager struct {
+	visible map[string]bool
+}
+
+func (m *denyingPrivilegeManager) RequestVerification(db, table, column string, priv mysql.PrivilegeType) bool {
+	return false
+}
+
+func (m *denyingPrivilegeManager) DBIsVisible(db string) bool {
+	return m.visible[db]
+}
+
+func TestErrorCodes(t *testing.T) {
+	s := newViewTestSession(t, newViewTestSchema(newFKTestTable("t", "id")))
+	compileErr := func(sql string) error {
+		_, _, err := compileView(s, sql)
+		return err
+	}
+
+	_, parseErr := parser.New().ParseOneStmt("SELEC 1", mysql.UTF8Charset, mysql.UTF8DefaultCollation)
+	unknownTable := compileErr("SELECT * FROM nope")
+
+	s.sessionVars.User = &auth.UserIdentity{Username: "u", Hostname: "localhost"}
+	privilege.BindPrivilegeManager(s, &denyingPrivilegeManager{visible: map[string]bool{"test": true}})
+	tableDenied := compileErr("SELECT * FROM t")
+	privilege.BindPrivilegeManager(s, &denyingPrivilegeManager{})
+	dbDenied := compileErr("SELECT * FROM t")
+
+	tests := []struct {
+		name  string
+		err   error
+		code  uint16
+		state string
+	}{
+		{"syntax", parseErr, mysql.ErrParse, "42000"},
+		{"unknown table", unknownTable, mysql.ErrNoSuchTable, "42S02"},
+		{"duplicate key", ErrDupEntry.GenByArgs("1", "PRIMARY"), mysql.ErrDupEntry, "23000"},
+		{"table access denied", tableDenied, mysql.ErrTableaccessDenied, "42000"},
+		{"db access denied", dbDenied, mysql.ErrDBaccessDenied, "42000"},
+		{"lock wait timeout", ErrLockWaitTimeout.GenByArgs(), mysql.ErrLockWaitTimeout, "HY000"},
+		{"deadlock", ErrLockDeadlock.GenByArgs(), mysql.ErrLockDeadlock, "40001"},
+		{"untyped", errors.Trace(errors.New("boom")), mysql.ErrUnknown, "HY000"},
+	}
+	for _, tt := range tests {
+		if tt.err == nil {
+			t.Errorf("%s: expect an error", tt.name)
+			continue
+		}
+		sqlErr := toSQLError(tt.err)
+		if sqlErr.Code != tt.code || sqlErr.State != tt.state {
+			t.Errorf("%s: expect %d (%s), got %d (%s) %s", tt.name, tt.code, tt.state, sqlErr.Code, sqlErr.State, sqlErr.Message)
+		}
+	}
+	if msg := toSQLError(errors.New("boom")).Message; msg != "boom" {
+		t.Errorf("expect the message of an untyped error kept, got %q", msg)
+	}
+	if msg := toSQLError(tableDenied).Message; msg != "SELECT command denied to user 'u'@'localhost' for table 't'" {
+		t.Errorf("unexpected message %q", msg)
+	}
+}
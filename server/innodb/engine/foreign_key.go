@@ -0,0 +1,395 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+)
+
+// maxFKCascadeDepth is the nesting limit of cascading foreign key actions,
+// the same as InnoDB's FK_MAX_CASCADE_DEL.
+const maxFKCascadeDepth = 15
+
+// fkRowStore is the row access foreign key checks need. Rows are addressed
+// by their handle.
+type fkRowStore interface {
+	// Tables returns the tables of all the schemas.
+	Tables() []*model.TableInfo
+	// SchemaOf returns the schema of tbl, one of Tables.
+	SchemaOf(tbl *model.TableInfo) model.CIStr
+	rowReader
+	// DeleteRow removes the row with handle h from tbl.
+	DeleteRow(tbl *model.TableInfo, h int64) error
+	// UpdateRow replaces the row with handle h of tbl.
+	UpdateRow(tbl *model.TableInfo, h int64, row []basic.Datum) error
+}
+
+// fkKeyReader is implemented by the stores that can tell whether a table
+// has a row with given values of some of its columns without reading all of
+// its rows.
+type fkKeyReader interface {
+	// HasKey reports whether a row of tbl has vals in cols. ok is false
+	// when the store can't tell without reading the rows.
+	HasKey(tbl *model.TableInfo, cols []model.CIStr, vals []basic.Datum) (found bool, ok bool, err error)
+}
+
+// fkChecker enforces the FOREIGN KEY constraints of the tables in store:
+// child rows must reference an existing parent row, and changes to a
+// referenced parent row apply the ON DELETE / ON UPDATE action of each
+// referencing constraint. Nothing is checked while foreign_key_checks is
+// off.
+type fkChecker struct {
+	ctx   context.Context
+	store fkRowStore
+}
+
+// checkChildRow checks that every foreign key of tbl finds its parent row.
+// Like InnoDB, a key with a NULL column is not checked.
+func (c *fkChecker) checkChildRow(tbl *model.TableInfo, row []basic.Datum) error {
+	if !c.ctx.GetSessionVars().ForeignKeyChecks {
+		return nil
+	}
+	for _, fk := range tbl.ForeignKeys {
+		vals, err := fkValues(tbl, fk.Cols, row)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if vals == nil {
+			continue
+		}
+		parent := c.findTable(fkRefSchema(c.store.SchemaOf(tbl), fk), fk.RefTable)
+		if parent == nil {
+			return ErrNoReferencedRow2.GenByArgs(fkDescription(tbl, fk))
+		}
+		found, err := c.hasRows(parent, fk.RefCols, vals)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if !found {
+			return ErrNoReferencedRow2.GenByArgs(fkDescription(tbl, fk))
+		}
+	}
+	return nil
+}
+
+// onDeleteRow applies the ON DELETE action of the foreign keys referencing
+// row of tbl before the row is deleted.
+func (c *fkChecker) onDeleteRow(tbl *model.TableInfo, row []basic.Datum) error {
+	if !c.ctx.GetSessionVars().ForeignKeyChecks {
+		return nil
+	}
+	return c.onDelete(tbl, row, 0)
+}
+
+func (c *fkChecker) onDelete(tbl *model.TableInfo, row []basic.Datum, depth int) error {
+	if depth > maxFKCascadeDepth {
+		return ErrForeignCascadeDepth.GenByArgs(maxFKCascadeDepth)
+	}
+	for _, child := range c.store.Tables() {
+		for _, fk := range child.ForeignKeys {
+			if !c.references(child, fk, tbl) {
+				continue
+			}
+			vals, err := fkValues(tbl, fk.RefCols, row)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			if vals == nil {
+				continue
+			}
+			found, err := c.hasRows(child, fk.Cols, vals)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			if !found {
+				continue
+			}
+			action := ast.ReferOptionType(fk.OnDelete)
+			var handles []int64
+			var rows [][]basic.Datum
+			if action == ast.ReferOptionCascade || action == ast.ReferOptionSetNull {
+				// Only the actions changing the rows read them.
+				if handles, rows, err = c.findRows(child, fk.Cols, vals); err != nil {
+					return errors.Trace(err)
+				}
+			}
+			switch action {
+			case ast.ReferOptionCascade:
+				for i, h := range handles {
+					if err = c.onDelete(child, rows[i], depth+1); err != nil {
+						return errors.Trace(err)
+					}
+					if err = c.store.DeleteRow(child, h); err != nil {
+						return errors.Trace(err)
+					}
+				}
+			case ast.ReferOptionSetNull:
+				if err = c.setNull(child, fk, handles, rows, depth); err != nil {
+					return errors.Trace(err)
+				}
+			default:
+				// RESTRICT, NO ACTION and no option all reject the delete.
+				return ErrRowIsReferenced2.GenByArgs(fkDescription(child, fk))
+			}
+		}
+	}
+	return nil
+}
+
+// onUpdateRow applies the ON UPDATE action of the foreign keys referencing
+// oldRow of tbl before it is replaced by newRow.
+func (c *fkChecker) onUpdateRow(tbl *model.TableInfo, oldRow, newRow []basic.Datum) error {
+	if !c.ctx.GetSessionVars().ForeignKeyChecks {
+		return nil
+	}
+	return c.onUpdate(tbl, oldRow, newRow, 0)
+}
+
+func (c *fkChecker) onUpdate(tbl *model.TableInfo, oldRow, newRow []basic.Datum, depth int) error {
+	if depth > maxFKCascadeDepth {
+		return ErrForeignCascadeDepth.GenByArgs(maxFKCascadeDepth)
+	}
+	sc := c.ctx.GetSessionVars().StmtCtx
+	for _, child := range c.store.Tables() {
+		for _, fk := range child.ForeignKeys {
+			if !c.references(child, fk, tbl) {
+				continue
+			}
+			oldVals, err := fkValues(tbl, fk.RefCols, oldRow)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			if oldVals == nil {
+				continue
+			}
+			newVals, err := fkValues(tbl, fk.RefCols, newRow)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			if newVals != nil {
+				same, err := datumsEqual(sc, oldVals, newVals)
+				if err != nil {
+					return errors.Trace(err)
+				}
+				if same {
+					continue
+				}
+			}
+			found, err := c.hasRows(child, fk.Cols, oldVals)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			if !found {
+				continue
+			}
+			action := ast.ReferOptionType(fk.OnUpdate)
+			var handles []int64
+			var rows [][]basic.Datum
+			if action == ast.ReferOptionCascade || action == ast.ReferOptionSetNull {
+				// Only the actions changing the rows read them.
+				if handles, rows, err = c.findRows(child, fk.Cols, oldVals); err != nil {
+					return errors.Trace(err)
+				}
+			}
+			switch action {
+			case ast.ReferOptionCascade:
+				offsets, err := fkOffsets(child, fk.Cols)
+				if err != nil {
+					return errors.Trace(err)
+				}
+				for i, h := range handles {
+					updated := make([]basic.Datum, len(rows[i]))
+					copy(updated, rows[i])
+					for j, offset := range offsets {
+						if newVals == nil {
+							updated[offset].SetNull()
+						} else {
+							updated[offset] = newVals[j]
+						}
+					}
+					if err = c.onUpdate(child, rows[i], updated, depth+1); err != nil {
+						return errors.Trace(err)
+					}
+					if err = c.store.UpdateRow(child, h, updated); err != nil {
+						return errors.Trace(err)
+					}
+				}
+			case ast.ReferOptionSetNull:
+				if err = c.setNull(child, fk, handles, rows, depth); err != nil {
+					return errors.Trace(err)
+				}
+			default:
+				return ErrRowIsReferenced2.GenByArgs(fkDescription(child, fk))
+			}
+		}
+	}
+	return nil
+}
+
+// setNull sets the referencing columns of fk in the given child rows to NULL.
+func (c *fkChecker) setNull(child *model.TableInfo, fk *model.FKInfo, handles []int64, rows [][]basic.Datum, depth int) error {
+	offsets, err := fkOffsets(child, fk.Cols)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for i, h := range handles {
+		updated := make([]basic.Datum, len(rows[i]))
+		copy(updated, rows[i])
+		for _, offset := range offsets {
+			updated[offset].SetNull()
+		}
+		if err = c.onUpdate(child, rows[i], updated, depth+1); err != nil {
+			return errors.Trace(err)
+		}
+		if err = c.store.UpdateRow(child, h, updated); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// findTable returns the table schema.name of the store, or nil.
+func (c *fkChecker) findTable(schema, name model.CIStr) *model.TableInfo {
+	for _, tbl := range c.store.Tables() {
+		if model.TableNamesEqual(tbl.Name, name) && model.TableNamesEqual(c.store.SchemaOf(tbl), schema) {
+			return tbl
+		}
+	}
+	return nil
+}
+
+// references reports whether fk of child references tbl, the same name in
+// the same schema.
+func (c *fkChecker) references(child *model.TableInfo, fk *model.FKInfo, tbl *model.TableInfo) bool {
+	return model.TableNamesEqual(fk.RefTable, tbl.Name) &&
+		model.TableNamesEqual(fkRefSchema(c.store.SchemaOf(child), fk), c.store.SchemaOf(tbl))
+}
+
+// fkRefSchema returns the schema of the table fk of a table of schema
+// references.
+func fkRefSchema(schema model.CIStr, fk *model.FKInfo) model.CIStr {
+	if fk.RefSchema.L != "" {
+		return fk.RefSchema
+	}
+	return schema
+}
+
+// hasRows reports whether tbl has a row whose cols equal vals, asking the
+// store first when it can tell without reading all the rows.
+func (c *fkChecker) hasRows(tbl *model.TableInfo, cols []model.CIStr, vals []basic.Datum) (bool, error) {
+	if r, ok := c.store.(fkKeyReader); ok {
+		found, ok, err := r.HasKey(tbl, cols, vals)
+		if ok || err != nil {
+			return found, errors.Trace(err)
+		}
+	}
+	_, rows, err := c.findRows(tbl, cols, vals)
+	return len(rows) > 0, errors.Trace(err)
+}
+
+// findRows returns the rows of tbl whose cols equal vals.
+func (c *fkChecker) findRows(tbl *model.TableInfo, cols []model.CIStr, vals []basic.Datum) ([]int64, [][]basic.Datum, error) {
+	offsets, err := fkOffsets(tbl, cols)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	handles, rows, err := c.store.Rows(tbl)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	sc := c.ctx.GetSessionVars().StmtCtx
+	var matchedHandles []int64
+	var matchedRows [][]basic.Datum
+	for i, row := range rows {
+		key := make([]basic.Datum, len(offsets))
+		for j, offset := range offsets {
+			key[j] = row[offset]
+		}
+		same, err := datumsEqual(sc, key, vals)
+		if err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+		if same {
+			matchedHandles = append(matchedHandles, handles[i])
+			matchedRows = append(matchedRows, row)
+		}
+	}
+	return matchedHandles, matchedRows, nil
+}
+
+// fkValues returns the values of cols in row, or nil if any of them is NULL.
+func fkValues(tbl *model.TableInfo, cols []model.CIStr, row []basic.Datum) ([]basic.Datum, error) {
+	offsets, err := fkOffsets(tbl, cols)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	vals := make([]basic.Datum, len(offsets))
+	for i, offset := range offsets {
+		if row[offset].IsNull() {
+			return nil, nil
+		}
+		vals[i] = row[offset]
+	}
+	return vals, nil
+}
+
+func fkOffsets(tbl *model.TableInfo, cols []model.CIStr) ([]int, error) {
+	offsets := make([]int, len(cols))
+	for i, name := range cols {
+		col := findColumnInfo(tbl.Columns, name)
+		if col == nil {
+			return nil, errors.Errorf("foreign key column %s doesn't exist in table %s", name.O, tbl.Name.O)
+		}
+		offsets[i] = col.Offset
+	}
+	return offsets, nil
+}
+
+func findColumnInfo(cols []*model.ColumnInfo, name model.CIStr) *model.ColumnInfo {
+	for _, col := range cols {
+		if col.Name.L == name.L {
+			return col
+		}
+	}
+	return nil
+}
+
+func datumsEqual(sc *variable.StatementContext, a, b []basic.Datum) (bool, error) {
+	for i := range a {
+		cmp, err := a[i].CompareDatum(sc, &b[i])
+		if err != nil {
+			return false, errors.Trace(err)
+		}
+		if cmp != 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// fkDescription formats a constraint the way InnoDB reports it in
+// ER_NO_REFERENCED_ROW_2 and ER_ROW_IS_REFERENCED_2.
+func fkDescription(tbl *model.TableInfo, fk *model.FKInfo) string {
+	quote := func(names []model.CIStr) string {
+		quoted := make([]string, len(names))
+		for i, name := range names {
+			quoted[i] = "`" + name.O + "`"
+		}
+		return strings.Join(quoted, ", ")
+	}
+	desc := fmt.Sprintf("`%s`, CONSTRAINT `%s` FOREIGN KEY (%s) REFERENCES `%s` (%s)",
+		tbl.Name.O, fk.Name.O, quote(fk.Cols), fk.RefTable.O, quote(fk.RefCols))
+	if opt := ast.ReferOptionType(fk.OnDelete); opt != ast.ReferOptionNoOption {
+		desc += " ON DELETE " + opt.String()
+	}
+	if opt := ast.ReferOptionType(fk.OnUpdate); opt != ast.ReferOptionNoOption {
+		desc += " ON UPDATE " + opt.String()
+	}
+	return desc
+}
@@ -0,0 +1,476 @@
+package engine
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ddl"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/terror"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// fkTestStore keeps the rows of each table in memory, by the name of the
+// table, qualified by its schema outside test.
+type fkTestStore struct {
+	tables  []*model.TableInfo
+	schemas map[*model.TableInfo]model.CIStr
+	rows    map[string]map[int64][]basic.Datum
+	next    int64
+}
+
+func newFKTestStore(tables ...*model.TableInfo) *fkTestStore {
+	s := &fkTestStore{schemas: make(map[*model.TableInfo]model.CIStr), rows: make(map[string]map[int64][]basic.Datum)}
+	for _, tbl := range tables {
+		s.add("test", tbl)
+	}
+	return s
+}
+
+// add adds tbl to schema.
+func (s *fkTestStore) add(schema string, tbl *model.TableInfo) {
+	s.tables = append(s.tables, tbl)
+	s.schemas[tbl] = model.NewCIStr(schema)
+	s.rows[s.key(tbl)] = make(map[int64][]basic.Datum)
+}
+
+func (s *fkTestStore) key(tbl *model.TableInfo) string {
+	if schema := s.SchemaOf(tbl); schema.L != "test" {
+		return schema.L + "." + tbl.Name.L
+	}
+	return tbl.Name.L
+}
+
+func (s *fkTestStore) Tables() []*model.TableInfo {
+	return s.tables
+}
+
+func (s *fkTestStore) SchemaOf(tbl *model.TableInfo) model.CIStr {
+	if schema, ok := s.schemas[tbl]; ok {
+		return schema
+	}
+	return model.NewCIStr("test")
+}
+
+func (s *fkTestStore) Rows(tbl *model.TableInfo) ([]int64, [][]basic.Datum, error) {
+	var handles []int64
+	var rows [][]basic.Datum
+	for h, row := range s.rows[s.key(tbl)] {
+		handles = append(handles, h)
+		rows = append(rows, row)
+	}
+	return handles, rows, nil
+}
+
+func (s *fkTestStore) DeleteRow(tbl *model.TableInfo, h int64) error {
+	delete(s.rows[s.key(tbl)], h)
+	return nil
+}
+
+func (s *fkTestStore) UpdateRow(tbl *model.TableInfo, h int64, row []basic.Datum) error {
+	s.rows[s.key(tbl)][h] = row
+	return nil
+}
+
+func (s *fkTestStore) AddRow(tbl *model.TableInfo, row []basic.Datum) (int64, error) {
+	s.next++
+	s.rows[s.key(tbl)][s.next] = row
+	return s.next, nil
+}
+
+func (s *fkTestStore) insert(tbl *model.TableInfo, vals ...interface{}) []basic.Datum {
+	s.next++
+	row := basic.MakeDatums(vals...)
+	s.rows[s.key(tbl)][s.next] = row
+	return row
+}
+
+func newFKTestTable(name string, cols ...string) *model.TableInfo {
+	tbl := &model.TableInfo{Name: model.NewCIStr(name)}
+	for i, col := range cols {
+		info := &model.ColumnInfo{Name: model.NewCIStr(col), Offset: i, State: model.StatePublic}
+		info.FieldType = *basic.NewFieldType(mysql.TypeLong)
+		tbl.Columns = append(tbl.Columns, info)
+	}
+	return tbl
+}
+
+// newFKTestTables returns
+//
+//	CREATE TABLE parent (id INT PRIMARY KEY);
+//	CREATE TABLE child (id INT PRIMARY KEY, pid INT,
+//		CONSTRAINT fk_parent FOREIGN KEY (pid) REFERENCES parent (id) ON DELETE <onDelete>);
+//	CREATE TABLE grandchild (id INT PRIMARY KEY, cid INT,
+//		CONSTRAINT fk_child FOREIGN KEY (cid) REFERENCES child (id) ON DELETE CASCADE);
+func newFKTestTables(onDelete ast.ReferOptionType) (parent, child, grandchild *model.TableInfo) {
+	parent = newFKTestTable("parent", "id")
+	child = newFKTestTable("child", "id", "pid")
+	child.ForeignKeys = []*model.FKInfo{{
+		Name:     model.NewCIStr("fk_parent"),
+		RefTable: parent.Name,
+		RefCols:  []model.CIStr{model.NewCIStr("id")},
+		Cols:     []model.CIStr{model.NewCIStr("pid")},
+		OnDelete: int(onDelete),
+		State:    model.StatePublic,
+	}}
+	grandchild = newFKTestTable("grandchild", "id", "cid")
+	grandchild.ForeignKeys = []*model.FKInfo{{
+		Name:     model.NewCIStr("fk_child"),
+		RefTable: child.Name,
+		RefCols:  []model.CIStr{model.NewCIStr("id")},
+		Cols:     []model.CIStr{model.NewCIStr("cid")},
+		OnDelete: int(ast.ReferOptionCascade),
+		State:    model.StatePublic,
+	}}
+	return
+}
+
+func TestForeignKeyCheckChildRow(t *testing.T) {
+	parent, child, grandchild := newFKTestTables(ast.ReferOptionRestrict)
+	store := newFKTestStore(parent, child, grandchild)
+	store.insert(parent, 1)
+	c := &fkChecker{ctx: newInsertTestSession(t, "STRICT_TRANS_TABLES"), store: store}
+
+	// A child row without a parent is rejected.
+	err := c.checkChildRow(child, basic.MakeDatums(1, 2))
+	if errCode(err) != mysql.ErrNoReferencedRow2 {
+		t.Fatalf("expect error %d, got %v", mysql.ErrNoReferencedRow2, err)
+	}
+	// A child row with a parent is accepted.
+	if err = c.checkChildRow(child, basic.MakeDatums(1, 1)); err != nil {
+		t.Fatal(err)
+	}
+	// A NULL foreign key is not checked.
+	if err = c.checkChildRow(child, basic.MakeDatums(2, nil)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestForeignKeyOnDeleteRestrict(t *testing.T) {
+	parent, child, grandchild := newFKTestTables(ast.ReferOptionRestrict)
+	store := newFKTestStore(parent, child, grandchild)
+	referenced := store.insert(parent, 1)
+	unreferenced := store.insert(parent, 2)
+	store.insert(child, 10, 1)
+	c := &fkChecker{ctx: newInsertTestSession(t, "STRICT_TRANS_TABLES"), store: store}
+
+	err := c.onDeleteRow(parent, referenced)
+	if errCode(err) != mysql.ErrRowIsReferenced2 {
+		t.Fatalf("expect error %d, got %v", mysql.ErrRowIsReferenced2, err)
+	}
+	if len(store.rows["child"]) != 1 {
+		t.Fatalf("expect the child row to be kept, got %d rows", len(store.rows["child"]))
+	}
+	if err = c.onDeleteRow(parent, unreferenced); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestForeignKeyOnDeleteCascade(t *testing.T) {
+	parent, child, grandchild := newFKTestTables(ast.ReferOptionCascade)
+	store := newFKTestStore(parent, child, grandchild)
+	deleted := store.insert(parent, 1)
+	store.insert(parent, 2)
+	store.insert(child, 10, 1)
+	store.insert(child, 11, 1)
+	store.insert(child, 20, 2)
+	store.insert(grandchild, 100, 10)
+	store.insert(grandchild, 200, 20)
+	c := &fkChecker{ctx: newInsertTestSession(t, "STRICT_TRANS_TABLES"), store: store}
+
+	if err := c.onDeleteRow(parent, deleted); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.rows["child"]) != 1 {
+		t.Fatalf("expect 1 child row left, got %d", len(store.rows["child"]))
+	}
+	for _, row := range store.rows["child"] {
+		if row[1].GetInt64() != 2 {
+			t.Fatalf("expect the child of parent 2 to be kept, got pid %d", row[1].GetInt64())
+		}
+	}
+	if len(store.rows["grandchild"]) != 1 {
+		t.Fatalf("expect the cascade to reach grandchild, got %d rows", len(store.rows["grandchild"]))
+	}
+}
+
+func TestForeignKeyOnDeleteSetNull(t *testing.T) {
+	parent, child, grandchild := newFKTestTables(ast.ReferOptionSetNull)
+	store := newFKTestStore(parent, child, grandchild)
+	deleted := store.insert(parent, 1)
+	store.insert(child, 10, 1)
+	c := &fkChecker{ctx: newInsertTestSession(t, "STRICT_TRANS_TABLES"), store: store}
+
+	if err := c.onDeleteRow(parent, deleted); err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range store.rows["child"] {
+		if !row[1].IsNull() {
+			t.Fatalf("expect pid to be set to NULL, got %v", row[1].GetValue())
+		}
+	}
+}
+
+func TestForeignKeyOnUpdateCascade(t *testing.T) {
+	parent, child, grandchild := newFKTestTables(ast.ReferOptionRestrict)
+	child.ForeignKeys[0].OnUpdate = int(ast.ReferOptionCascade)
+	store := newFKTestStore(parent, child, grandchild)
+	old := store.insert(parent, 1)
+	store.insert(child, 10, 1)
+	c := &fkChecker{ctx: newInsertTestSession(t, "STRICT_TRANS_TABLES"), store: store}
+
+	if err := c.onUpdateRow(parent, old, basic.MakeDatums(5)); err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range store.rows["child"] {
+		if row[1].GetInt64() != 5 {
+			t.Fatalf("expect pid to follow the parent key, got %d", row[1].GetInt64())
+		}
+	}
+}
+
+func TestForeignKeyChecksOff(t *testing.T) {
+	parent, child, grandchild := newFKTestTables(ast.ReferOptionCascade)
+	store := newFKTestStore(parent, child, grandchild)
+	deleted := store.insert(parent, 1)
+	store.insert(child, 10, 1)
+	c := &fkChecker{ctx: newInsertTestSession(t, "STRICT_TRANS_TABLES"), store: store}
+	c.ctx.GetSessionVars().ForeignKeyChecks = false
+
+	// An orphan child row is accepted.
+	if err := c.checkChildRow(child, basic.MakeDatums(2, 5)); err != nil {
+		t.Fatal(err)
+	}
+	// Deleting a referenced parent neither fails nor cascades.
+	if err := c.onDeleteRow(parent, deleted); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.rows["child"]) != 1 {
+		t.Fatalf("expect the child row to be kept, got %d rows", len(store.rows["child"]))
+	}
+}
+
+func TestForeignKeyOtherSchema(t *testing.T) {
+	// other.parent has the same name as test.parent; test.child references
+	// other.parent.
+	parent, child, _ := newFKTestTables(ast.ReferOptionCascade)
+	child.ForeignKeys[0].RefSchema = model.NewCIStr("other")
+	otherParent := newFKTestTable("parent", "id")
+	store := newFKTestStore(parent, child)
+	store.add("other", otherParent)
+	store.insert(parent, 1)
+	referenced := store.insert(otherParent, 2)
+	store.insert(child, 10, 2)
+	c := &fkChecker{ctx: newInsertTestSession(t, "STRICT_TRANS_TABLES"), store: store}
+
+	// The parent row is looked for in other.parent.
+	if err := c.checkChildRow(child, basic.MakeDatums(11, 1)); errCode(err) != mysql.ErrNoReferencedRow2 {
+		t.Fatalf("expect error %d, got %v", mysql.ErrNoReferencedRow2, err)
+	}
+	if err := c.checkChildRow(child, basic.MakeDatums(11, 2)); err != nil {
+		t.Fatal(err)
+	}
+	// Deleting from test.parent doesn't cascade to the children of
+	// other.parent.
+	if err := c.onDeleteRow(parent, basic.MakeDatums(2)); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.rows["child"]) != 1 {
+		t.Fatalf("expect the child row to be kept, got %d rows", len(store.rows["child"]))
+	}
+	if err := c.onDeleteRow(otherParent, referenced); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.rows["child"]) != 0 {
+		t.Fatalf("expect the child row to be deleted, got %d rows", len(store.rows["child"]))
+	}
+}
+
+// fkTestSchema lists the tables of crossDBTestSchema by database.
+type fkTestSchema struct {
+	crossDBTestSchema
+}
+
+func (is *fkTestSchema) AllSchemas() []*model.DBInfo {
+	var dbs []*model.DBInfo
+	seen := make(map[string]bool)
+	for name := range is.tables {
+		db := name[:strings.Index(name, ".")]
+		if !seen[db] {
+			seen[db] = true
+			dbs = append(dbs, &model.DBInfo{Name: model.NewCIStr(db)})
+		}
+	}
+	sort.Slice(dbs, func(i, j int) bool { return dbs[i].Name.L < dbs[j].Name.L })
+	return dbs
+}
+
+func (is *fkTestSchema) SchemaTables(schema model.CIStr) []schemas.Table {
+	var names []string
+	for name := range is.tables {
+		if strings.HasPrefix(name, schema.L+".") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	tables := make([]schemas.Table, 0, len(names))
+	for _, name := range names {
+		tables = append(tables, is.tables[name])
+	}
+	return tables
+}
+
+// newFKTestSchema returns the tables of newFKTestTables in the database
+// test, with the primary keys on id.
+func newFKTestSchema() (*fkTestSchema, *model.TableInfo) {
+	parent, child, grandchild := newFKTestTables(ast.ReferOptionCascade)
+	for _, tbl := range []*model.TableInfo{parent, child, grandchild} {
+		tbl.PKIsHandle = true
+		tbl.Columns[0].Flag |= mysql.PriKeyFlag
+	}
+	is := &fkTestSchema{crossDBTestSchema{tables: map[string]schemas.Table{
+		"test.parent":     &viewTestTable{meta: parent},
+		"test.child":      &viewTestTable{meta: child},
+		"test.grandchild": &viewTestTable{meta: grandchild},
+	}}}
+	return is, child
+}
+
+func TestCheckForeignKeys(t *testing.T) {
+	is, child := newFKTestSchema()
+	db := model.NewCIStr("test")
+	if err := ddl.CheckForeignKeys(is, db, child); err != nil {
+		t.Fatal(err)
+	}
+
+	newTable := func(refTable, refCol string) *model.TableInfo {
+		tbl := newFKTestTable("t", "id", "ref")
+		tbl.ForeignKeys = []*model.FKInfo{{
+			Name:     model.NewCIStr("fk_t"),
+			RefTable: model.NewCIStr(refTable),
+			RefCols:  []model.CIStr{model.NewCIStr(refCol)},
+			Cols:     []model.CIStr{model.NewCIStr("ref")},
+		}}
+		return tbl
+	}
+	// A table referencing its own primary key needs no other table.
+	self := newTable("t", "id")
+	self.PKIsHandle = true
+	self.Columns[0].Flag |= mysql.PriKeyFlag
+	if err := ddl.CheckForeignKeys(is, db, self); err != nil {
+		t.Fatal(err)
+	}
+	for _, tbl := range []*model.TableInfo{
+		newTable("nope", "id"),
+		// child.pid has no index.
+		newTable("child", "pid"),
+		newTable("parent", "nope"),
+	} {
+		err := ddl.CheckForeignKeys(is, db, tbl)
+		if !terror.ErrorEqual(err, schemas.ErrCannotAddForeign) {
+			t.Fatalf("%s(%s): expect error %d, got %v", tbl.ForeignKeys[0].RefTable,
+				tbl.ForeignKeys[0].RefCols[0], mysql.ErrCannotAddForeign, err)
+		}
+	}
+}
+
+func TestForeignKeyInformationSchema(t *testing.T) {
+	is, _ := newFKTestSchema()
+	usage := schemas.KeyColumnUsageRows(is)
+	var fks []string
+	for _, row := range usage {
+		if row[9].IsNull() {
+			continue
+		}
+		fks = append(fks, strings.Join([]string{row[2].GetString(), row[5].GetString(), row[6].GetString(),
+			row[9].GetString(), row[10].GetString(), row[11].GetString()}, " "))
+	}
+	expected := []string{"fk_child grandchild cid test child id", "fk_parent child pid test parent id"}
+	sort.Strings(fks)
+	if strings.Join(fks, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expect foreign key columns %q, got %q", expected, fks)
+	}
+	// One PRIMARY row for each table.
+	if len(usage) != 5 {
+		t.Fatalf("expect 5 key columns, got %d", len(usage))
+	}
+
+	constraints := schemas.ReferentialConstraintsRows(is)
+	if len(constraints) != 2 {
+		t.Fatalf("expect 2 constraints, got %d", len(constraints))
+	}
+	for _, row := range constraints {
+		if row[5].GetString() != mysql.PrimaryKeyName || row[7].GetString() != "RESTRICT" || row[8].GetString() != "CASCADE" {
+			t.Fatalf("unexpected constraint %v", row)
+		}
+	}
+
+	s := newViewTestSession(t, is)
+	for sql, rows := range map[string]int{
+		"SELECT * FROM information_schema.key_column_usage":        5,
+		"SELECT * FROM information_schema.referential_constraints": 2,
+	} {
+		_, p, err := compileView(s, sql)
+		if err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+		if str := plan.ToString(p); strings.Count(str, "Projection") != rows {
+			t.Fatalf("%s: expect %d rows, got plan %s", sql, rows, str)
+		}
+	}
+}
+
+func TestForeignKeyStored(t *testing.T) {
+	parent, child, grandchild := newFKTestTables(ast.ReferOptionCascade)
+	srv, s := newStoredTestEngine(t, parent, child, grandchild)
+	execStored(t, srv, s, "INSERT INTO parent VALUES (1), (2)", 0)
+
+	// A child row without a parent is rejected, a valid one is written.
+	execStored(t, srv, s, "INSERT INTO child VALUES (10, 3)", mysql.ErrNoReferencedRow2)
+	execStored(t, srv, s, "INSERT INTO child VALUES (10, 1), (11, 1), (20, 2)", 0)
+	execStored(t, srv, s, "INSERT INTO grandchild VALUES (100, 10), (200, 20)", 0)
+	execStored(t, srv, s, "UPDATE child SET pid = 3 WHERE id = 20", mysql.ErrNoReferencedRow2)
+
+	// Deleting a parent removes its children and theirs.
+	execStored(t, srv, s, "DELETE FROM parent WHERE id = 1", 0)
+	if got := execStored(t, srv, s, "SELECT * FROM child", 0); got != "20,2" {
+		t.Fatalf("expect the child of parent 2 to be kept, got %s", got)
+	}
+	if got := execStored(t, srv, s, "SELECT * FROM grandchild", 0); got != "200,20" {
+		t.Fatalf("expect the cascade to reach grandchild, got %s", got)
+	}
+}
+
+func TestForeignKeyRestrictStored(t *testing.T) {
+	parent, child, grandchild := newFKTestTables(ast.ReferOptionRestrict)
+	child.ForeignKeys[0].OnUpdate = int(ast.ReferOptionCascade)
+	srv, s := newStoredTestEngine(t, parent, child, grandchild)
+	execStored(t, srv, s, "INSERT INTO parent VALUES (1), (2)", 0)
+	execStored(t, srv, s, "INSERT INTO child VALUES (10, 1)", 0)
+
+	// A referenced parent is kept, an unreferenced one is deleted.
+	execStored(t, srv, s, "DELETE FROM parent", mysql.ErrRowIsReferenced2)
+	if got := execStored(t, srv, s, "SELECT * FROM parent", 0); got != "1;2" {
+		t.Fatalf("expect the failed delete to leave the parents, got %s", got)
+	}
+	execStored(t, srv, s, "DELETE FROM parent WHERE id = 2", 0)
+
+	// The children follow the parent key.
+	execStored(t, srv, s, "UPDATE parent SET id = 5 WHERE id = 1", 0)
+	if got := execStored(t, srv, s, "SELECT * FROM child", 0); got != "10,5" {
+		t.Fatalf("expect pid to follow the parent key, got %s", got)
+	}
+
+	// Nothing is checked while foreign_key_checks is off.
+	execStored(t, srv, s, "SET foreign_key_checks = 0", 0)
+	execStored(t, srv, s, "INSERT INTO child VALUES (11, 7)", 0)
+	execStored(t, srv, s, "DELETE FROM parent", 0)
+	if got := execStored(t, srv, s, "SELECT * FROM child", 0); got != "10,5;11,7" {
+		t.Fatalf("expect the children to be kept, got %s", got)
+	}
+}
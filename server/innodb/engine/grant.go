@@ -0,0 +1,60 @@
+package engine
+
+import (
+	"github.com/juju/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/privilege/privileges"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// PrivilegeHandle returns the cache of the privilege tables GRANT and
+// REVOKE change, nil when the tables aren't loaded.
+func (srv *XMySQLEngine) PrivilegeHandle() *privileges.Handle {
+	return srv.privHandle
+}
+
+// grant runs GRANT or REVOKE stmt, on the databases of the current
+// database of ctx when it names none, and writes the privileges changed to
+// the privilege tables.
+func (srv *XMySQLEngine) grant(ctx context.Context, stmt ast.StmtNode) error {
+	if srv.privHandle == nil {
+		return mysql.NewErrf(mysql.ErrNotSupportedYet, "GRANT and REVOKE without the privilege tables")
+	}
+	currentDB := ctx.GetSessionVars().CurrentDB
+	var err error
+	switch x := stmt.(type) {
+	case *ast.GrantStmt:
+		err = srv.privHandle.Grant(x, currentDB)
+	case *ast.RevokeStmt:
+		err = srv.privHandle.Revoke(x, currentDB)
+	}
+	if err != nil {
+		return err
+	}
+	if err = srv.privTables.save(srv.privHandle.Get()); err != nil {
+		// The cache goes back to what the tables hold.
+		if reload := srv.privHandle.Update(ctx, srv.privTables); reload != nil {
+			log.Errorf("重新读取权限表失败: %v", reload)
+		}
+		return errors.Trace(err)
+	}
+	return nil
+}
+
+func init() {
+	registerStmtHandler(&ast.GrantStmt{}, &stmtHandler{name: "grant", handle: (*XMySQLEngine).execGrant})
+	registerStmtHandler(&ast.RevokeStmt{}, &stmtHandler{name: "revoke", handle: (*XMySQLEngine).execGrant})
+}
+
+// execGrant runs a GRANT or a REVOKE.
+func (srv *XMySQLEngine) execGrant(session innodb.MySQLServerSession, stmt ast.StmtNode, p plan.Plan) {
+	if err := srv.grant(session, stmt); err != nil {
+		session.SendError(toSQLError(err))
+		return
+	}
+	session.SendOK()
+}
@@ -0,0 +1,180 @@
+package engine
+
+import (
+	"bufio"
+	"encoding/csv"
+	"io"
+	"os"
+	"unicode/utf8"
+
+	"github.com/juju/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/expression"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
+)
+
+/**
+IMPORT TABLE 从 CSV 文件导入
+
+IMPORT TABLE t FROM CSV '/path' [WITH HEADER] [DELIMITER ','] [MAX_ERRORS n] 把服务端
+的 CSV 文件导入表 t，给演示和测试用的表准备数据。文件和 LOAD DATA、INTO OUTFILE 一样
+受 secure_file_priv 限制，语句要有 FILE 权限和表的 INSERT 权限。
+
+字段按位置对应表的列；WITH HEADER 时文件的第一行是列名，字段按列名对应，没有列出的列
+取默认值。字段像 INSERT 的值一样按列的类型转换、填默认值、检查 NOT NULL。
+
+格式不对的行（引号不配对、字段个数不对）跳过，值放不进表的行算出错，都记一条警告。
+跳过和出错的行加起来超过 MAX_ERRORS（默认 100）时导入失败，一行也不导入。
+
+文件是一行一行读的，转换好的行攒到 tidb_dml_batch_size 行，和 INSERT 一样通过
+tableRowStore 插入一批，每批之后在日志里记下进度。整个导入是一条语句，在一个事务里，
+失败时前面的批次也一起丢弃。结果是一行：表名、导入的行数、跳过的行数和出错的行数。
+**/
+
+// importResult counts the rows of an IMPORT TABLE.
+type importResult struct {
+	// loaded is the number of rows inserted.
+	loaded uint64
+	// skipped is the number of malformed lines skipped.
+	skipped uint64
+	// errors is the number of rows the table rejected.
+	errors uint64
+}
+
+// importTableRows reads the CSV file of v into rows of tbl and adds them to
+// store, batchSize rows at a time. It fails once more than v.MaxErrors lines
+// are skipped or rejected.
+func importTableRows(ctx context.Context, v *plan.ImportTable, tbl schemas.Table, store upsertRowStore, batchSize int) (importResult, error) {
+	var res importResult
+	path, err := secureFilePath(ctx, v.Path)
+	if err != nil {
+		return res, errors.Trace(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return res, errors.Trace(err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(bufio.NewReader(f))
+	r.Comma, _ = utf8.DecodeRuneInString(v.Delimiter)
+	// The lines with another number of fields are skipped one by one.
+	r.FieldsPerRecord = -1
+	insert := &InsertValues{ctx: ctx, tableCols: tbl.Cols()}
+	if v.Header {
+		names, err := r.Read()
+		if pe, ok := err.(*csv.ParseError); ok {
+			return res, ErrImportMalformedLine.GenByArgs(pe.Line, pe.Err)
+		}
+		if err != nil && err != io.EOF {
+			return res, errors.Trace(err)
+		}
+		for _, name := range names {
+			insert.Columns = append(insert.Columns, &ast.ColumnName{Name: model.NewCIStr(name)})
+		}
+	}
+	cols, err := insert.getColumns()
+	if err != nil {
+		return res, errors.Trace(err)
+	}
+
+	sc := ctx.GetSessionVars().StmtCtx
+	// reject records why a line isn't imported, and fails past MAX_ERRORS.
+	reject := func(count *uint64, warn error) error {
+		sc.AppendWarning(warn)
+		*count++
+		if res.skipped+res.errors > v.MaxErrors {
+			return ErrImportTooManyErrors.GenByArgs(v.MaxErrors)
+		}
+		return nil
+	}
+	batch := make([][]basic.Datum, 0, batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		added, err := addRows(ctx, store, tbl.Meta(), batch)
+		res.loaded += added
+		batch = batch[:0]
+		if err != nil {
+			return errors.Trace(err)
+		}
+		log.Infof("导入 %s: 已导入 %d 行，跳过 %d 行，出错 %d 行", v.Table.Name.O, res.loaded, res.skipped, res.errors)
+		return nil
+	}
+	for row := 1; ; row++ {
+		fields, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		var malformed error
+		if pe, ok := err.(*csv.ParseError); ok {
+			malformed = ErrImportMalformedLine.GenByArgs(pe.Line, pe.Err)
+		} else if err != nil {
+			return res, errors.Trace(err)
+		} else if len(fields) < len(cols) {
+			malformed = ErrWarnTooFewRecords.GenByArgs(row)
+		} else if len(fields) > len(cols) {
+			malformed = ErrWarnTooManyRecords.GenByArgs(row)
+		}
+		if malformed != nil {
+			if err = reject(&res.skipped, malformed); err != nil {
+				return res, errors.Trace(err)
+			}
+			continue
+		}
+		list := make([]expression.Expression, len(fields))
+		for i, field := range fields {
+			d := basic.NewStringDatum(field)
+			tp := &basic.FieldType{}
+			basic.DefaultTypeForValue(d.GetValue(), tp)
+			list[i] = &expression.Constant{Value: d, RetType: tp}
+		}
+		values, err := insert.getRow(cols, list, false)
+		if err != nil {
+			if err = reject(&res.errors, err); err != nil {
+				return res, errors.Trace(err)
+			}
+			continue
+		}
+		if batch = append(batch, values); len(batch) >= batchSize {
+			if err = flush(); err != nil {
+				return res, errors.Trace(err)
+			}
+		}
+	}
+	return res, errors.Trace(flush())
+}
+
+func init() {
+	registerStmtHandler(&ast.ImportTableStmt{}, &stmtHandler{name: "import table", handle: (*XMySQLEngine).execImportTable})
+}
+
+// execImportTable runs an IMPORT TABLE and sends the counts of its rows.
+func (srv *XMySQLEngine) execImportTable(session innodb.MySQLServerSession, stmt ast.StmtNode, p plan.Plan) {
+	if v, ok := p.(*plan.ImportTable); ok {
+		tbl, err := schemas.TableByName(srv.infoSchemaManager, v.Table.Schema, v.Table.Name)
+		if err != nil {
+			session.SendError(toSQLError(err))
+			return
+		}
+		store := newTableRowStore(session, srv.infoSchemaManager, srv.pool, v.Table.Schema)
+		res, err := importTableRows(session, v, tbl, store, session.GetSessionVars().DMLBatchSize)
+		if err != nil {
+			store.rollback()
+			session.SendError(toSQLError(err))
+			return
+		}
+		session.GetSessionVars().StmtCtx.AddAffectedRows(res.loaded)
+		row := basic.MakeDatums(v.Table.Name.O, res.loaded, res.skipped, res.errors)
+		if err = session.SendResultSet(planColumns(p), innodb.SliceRows([][]basic.Datum{row})); err != nil {
+			session.SendError(toSQLError(err))
+		}
+	}
+}
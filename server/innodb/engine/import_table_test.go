@@ -0,0 +1,131 @@
+package engine
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/conf"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/varsutil"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+func TestImportTable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tbl := &model.TableInfo{ID: 1, Name: model.NewCIStr("t")}
+	for _, col := range newInsertTestCols() {
+		tbl.Columns = append(tbl.Columns, col.ToInfo())
+	}
+	is := newViewTestSchema(tbl)
+	srv := &XMySQLEngine{conf: conf.NewCfg(), infoSchemaManager: is}
+	s := &serverTestSession{session: newViewTestSession(t, is)}
+	set := func(name, value string) {
+		t.Helper()
+		if err := varsutil.SetSessionSystemVar(s.sessionVars, name, basic.NewStringDatum(value)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	set(variable.SQLModeVar, "STRICT_TRANS_TABLES")
+	s.sessionVars.Systems[variable.SecureFilePriv] = dir
+	write := func(name, content string) {
+		t.Helper()
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// expect imports the file of sql in memory and checks the rows loaded,
+	// skipped and rejected, and the warnings.
+	expect := func(sql string, loaded, skipped, rejected uint64, warnings uint16) {
+		t.Helper()
+		_, p, err := compileView(s.session, sql)
+		if err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+		store := newFKTestStore(tbl)
+		res, err := importTableRows(s, p.(*plan.ImportTable), is.tables["t"], store, s.sessionVars.DMLBatchSize)
+		if err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+		if _, rows, _ := store.Rows(tbl); res != (importResult{loaded, skipped, rejected}) || uint64(len(rows)) != loaded {
+			t.Fatalf("%s: expect %d loaded, %d skipped and %d rejected, got %+v and %d rows", sql, loaded, skipped, rejected, res, len(rows))
+		}
+		if got := s.sessionVars.StmtCtx.WarningCount(); got != warnings {
+			t.Fatalf("%s: expect %d warnings, got %v", sql, warnings, s.sessionVars.StmtCtx.GetWarnings())
+		}
+	}
+	expectErr := func(sql string, code uint16) {
+		t.Helper()
+		s.rows, s.errs = nil, nil
+		srv.ExecuteQuery(s, sql)
+		if len(s.errs) != 1 || s.errs[0].Code != code {
+			t.Fatalf("%s: expect error %d, got %v", sql, code, s.errs)
+		}
+	}
+
+	// The fields map to the columns in order, a quoted field may hold the
+	// delimiter.
+	write("all.csv", "1,a,x,2020-01-01 00:00:00\n2,\"b,c\",,2020-01-02 00:00:00\n")
+	expect("IMPORT TABLE t FROM CSV 'all.csv'", 2, 0, 0, 0)
+	// The table can't be written: nothing is reported as imported.
+	expectErr("IMPORT TABLE t FROM CSV 'all.csv'", mysql.ErrNotSupportedYet)
+	if len(s.rows) != 0 || s.sessionVars.StmtCtx.AffectedRows() != 0 {
+		t.Fatalf("expect no rows imported, got %v", s.rows)
+	}
+	// With a header the fields map to the columns it names, the others get
+	// their defaults, in batches of tidb_dml_batch_size rows.
+	set(variable.TiDBDMLBatchSize, "2")
+	write("header.csv", "NAME;id\nx;1\ny;2\nz;3\n")
+	expect("IMPORT TABLE t FROM CSV 'header.csv' WITH HEADER DELIMITER ';'", 3, 0, 0, 0)
+	write("unknown.csv", "id,nope\n1,2\n")
+	expectErr("IMPORT TABLE t FROM CSV 'unknown.csv' WITH HEADER", mysql.ErrBadField)
+
+	// Malformed lines are skipped and rows the table rejects counted, each
+	// with a warning.
+	write("bad.csv", "id,name\n1,a\n2\n3,c,extra\n4,\"d\"x\nfive,e\n6,f\n")
+	expect("IMPORT TABLE t FROM CSV 'bad.csv' WITH HEADER", 2, 3, 1, 4)
+	// Past MAX_ERRORS the import fails.
+	expect("IMPORT TABLE t FROM CSV 'bad.csv' WITH HEADER MAX_ERRORS 4", 2, 3, 1, 4)
+	expectErr("IMPORT TABLE t FROM CSV 'bad.csv' WITH HEADER MAX_ERRORS 3", mysql.ErrUnknown)
+
+	// The file must be under secure_file_priv.
+	expectErr("IMPORT TABLE t FROM CSV '../outside.csv'", mysql.ErrOptionPreventsStatement)
+	s.sessionVars.Systems[variable.SecureFilePriv] = "NULL"
+	expectErr("IMPORT TABLE t FROM CSV 'all.csv'", mysql.ErrOptionPreventsStatement)
+	expectErr("IMPORT TABLE t FROM CSV 'all.csv' DELIMITER ';;'", mysql.ErrParse)
+}
+
+func TestImportTableStored(t *testing.T) {
+	tbl := newFKTestTable("t", "id", "name")
+	tbl.Columns[1].FieldType = *basic.NewFieldType(mysql.TypeVarchar)
+	tbl.Columns[1].Flen, tbl.Columns[1].Charset = 10, mysql.DefaultCharset
+	srv, s := newStoredTestEngine(t, tbl)
+	s.sessionVars.Systems[variable.SecureFilePriv] = srv.conf.DataDir
+	if err := ioutil.WriteFile(filepath.Join(srv.conf.DataDir, "t.csv"), []byte("2,b\n1,a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	execStored(t, srv, s, "IMPORT TABLE t FROM CSV 't.csv'", 0)
+	if n := s.sessionVars.StmtCtx.AffectedRows(); n != 2 {
+		t.Fatalf("expect 2 rows imported, got %d", n)
+	}
+	if got := execStored(t, srv, s, "SELECT id, name FROM t", 0); got != "1,a;2,b" {
+		t.Fatalf("expect the rows imported, got %s", got)
+	}
+	// A failed import leaves the table as it was.
+	if err := ioutil.WriteFile(filepath.Join(srv.conf.DataDir, "dup.csv"), []byte("3,c\n1,x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	execStored(t, srv, s, "IMPORT TABLE t FROM CSV 'dup.csv'", mysql.ErrDupEntry)
+	if got := execStored(t, srv, s, "SELECT id, name FROM t", 0); got != "1,a;2,b" {
+		t.Fatalf("expect the rows of the first import, got %s", got)
+	}
+}
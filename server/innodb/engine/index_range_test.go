@@ -0,0 +1,271 @@
+package engine
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// newCompositeIndexTestTable returns
+//
+//	CREATE TABLE t (id INT, age INT, city VARCHAR(20) NOT NULL, name INT,
+//		INDEX idx_age_city (age, city)) DEFAULT CHARSET = utf8mb4
+func newCompositeIndexTestTable() *model.TableInfo {
+	tbl := newFKTestTable("t", "id", "age", "city", "name")
+	tbl.ID = 1
+	for i, col := range tbl.Columns {
+		col.ID = int64(i + 1)
+	}
+	city := tbl.Columns[2]
+	city.FieldType = *basic.NewFieldType(mysql.TypeVarchar)
+	city.Flen, city.Charset, city.Collate = 20, "utf8mb4", "utf8mb4_bin"
+	city.Flag = mysql.NotNullFlag
+	tbl.Indices = []*model.IndexInfo{{
+		ID:    1,
+		Name:  model.NewCIStr("idx_age_city"),
+		Table: tbl.Name,
+		Columns: []*model.IndexColumn{
+			{Name: model.NewCIStr("age"), Offset: 1, Length: basic.UnspecifiedLength},
+			{Name: model.NewCIStr("city"), Offset: 2, Length: basic.UnspecifiedLength},
+		},
+		State: model.StatePublic,
+	}}
+	return tbl
+}
+
+func TestCompositeIndexRange(t *testing.T) {
+	s := newViewTestSession(t, newViewTestSchema(newCompositeIndexTestTable()))
+	setTraceVar(t, s, "optimizer_trace", "enabled=on")
+	for _, tt := range []struct {
+		sql         string
+		scan        string
+		filter      string
+		selectivity float64
+	}{
+		// Both columns are consumed.
+		{"SELECT * FROM t WHERE age = 30 AND city = 'Beijing'",
+			"table:t, index:age, city, range:[30 Beijing,30 Beijing], key_len:87, out of order:true", "", 1.0 / 1000 / 100},
+		{"SELECT * FROM t WHERE age = 30 AND city > 'B'",
+			"table:t, index:age, city, range:(30 B,30 +inf], key_len:87, out of order:true", "", 1.0 / 1000 / 3},
+		// The range on age is the last condition consumed, city is left as a
+		// filter.
+		{"SELECT * FROM t WHERE age > 25 AND city = 'Beijing'",
+			"table:t, index:age, city, range:(25 +inf,+inf +inf], key_len:5, out of order:true", "eq(test.t.city, Beijing)", 1.0 / 3},
+		{"SELECT * FROM t WHERE age BETWEEN 20 AND 30 ORDER BY age",
+			"table:t, index:age, city, range:[20,30], key_len:5, out of order:false", "", 1.0 / 40},
+		// IS NULL is a point: the NULLs are together in the index.
+		{"SELECT * FROM t WHERE age IS NULL AND city = 'Beijing'",
+			"table:t, index:age, city, range:[<nil> Beijing,<nil> Beijing], key_len:87, out of order:true", "", 1.0 / 1000 / 100},
+		// city alone can't use the index.
+		{"SELECT * FROM t WHERE city = 'Beijing'", "", "eq(test.t.city, Beijing)", 0},
+	} {
+		_, p, err := compileView(s, tt.sql)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.sql, err)
+		}
+		var scan, filter string
+		for ; p != nil; p = firstChild(p) {
+			switch x := p.(type) {
+			case *plan.PhysicalIndexScan:
+				scan = x.ExplainInfo()
+			case *plan.Selection:
+				filter = x.ExplainInfo()
+			}
+		}
+		if scan != tt.scan || filter != tt.filter {
+			t.Errorf("%s: expect scan %q and filter %q, got %q and %q", tt.sql, tt.scan, tt.filter, scan, filter)
+		}
+		if tt.selectivity == 0 {
+			continue
+		}
+		var trace traceTestTrace
+		if err := json.Unmarshal([]byte(s.sessionVars.LastOptimizerTrace.Trace), &trace); err != nil {
+			t.Fatal(err)
+		}
+		for _, path := range trace.AccessPaths {
+			if path.Index == "idx_age_city" && math.Abs(path.Selectivity-tt.selectivity) > 1e-9 {
+				t.Errorf("%s: expect selectivity %g, got %g", tt.sql, tt.selectivity, path.Selectivity)
+			}
+		}
+	}
+}
+
+func firstChild(p plan.Plan) plan.Plan {
+	if len(p.Children()) == 0 {
+		return nil
+	}
+	return p.Children()[0]
+}
+
+func TestLooseIndexScan(t *testing.T) {
+	s := newViewTestSession(t, newViewTestSchema(newCompositeIndexTestTable()))
+	for sql, loose := range map[string]bool{
+		"SELECT DISTINCT age FROM t":                   true,
+		"SELECT DISTINCT city, age FROM t":             true,
+		"SELECT DISTINCT age FROM t WHERE age > 3":     true,
+		"SELECT DISTINCT age FROM t ORDER BY age DESC": true,
+		// city isn't a prefix of the index.
+		"SELECT DISTINCT city FROM t": false,
+		// The index doesn't cover name.
+		"SELECT DISTINCT age, name FROM t": false,
+		// Every row of a group is counted.
+		"SELECT COUNT(DISTINCT age) FROM t": false,
+	} {
+		_, p, err := compileView(s, sql)
+		if err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+		var scan string
+		for ; p != nil; p = firstChild(p) {
+			if is, ok := p.(*plan.PhysicalIndexScan); ok {
+				scan = is.ExplainInfo()
+			}
+		}
+		if got := strings.Contains(scan, "using index for group-by"); got != loose {
+			t.Errorf("%s: expect loose scan %v, got %s", sql, loose, plan.ToString(p))
+		}
+	}
+}
+
+// indexTestTree holds the entries of an index in key order and counts the
+// ones read.
+type indexTestTree struct {
+	basic.Tree
+	entries [][]basic.Datum
+	read    int
+}
+
+func (tree *indexTestTree) Range(from, to basic.Value) (basic.Iterator, error) {
+	var next func(i int) basic.Iterator
+	next = func(i int) basic.Iterator {
+		return func() (uint32, basic.Value, basic.Row, error, basic.Iterator) {
+			if i >= len(tree.entries) {
+				return 0, nil, nil, nil, nil
+			}
+			tree.read++
+			return 3, nil, datumRow{datums: tree.entries[i]}, nil, next(i + 1)
+		}
+	}
+	return next(0), nil
+}
+
+func TestIsNullIndexScan(t *testing.T) {
+	is := newViewTestSchema(newCompositeIndexTestTable())
+	s := newViewTestSession(t, is)
+	// The entries of idx_age_city, the id after the key, NULLs first.
+	tree := &indexTestTree{entries: [][]basic.Datum{
+		basic.MakeDatums(nil, "Beijing", int64(2)),
+		basic.MakeDatums(nil, "Shanghai", int64(5)),
+		basic.MakeDatums(int64(20), "Beijing", int64(1)),
+		basic.MakeDatums(int64(30), "Beijing", int64(3)),
+		basic.MakeDatums(int64(30), "Shanghai", int64(4)),
+	}}
+	tbl := &scanTestTable{spaceTestTable: &spaceTestTable{viewTestTable: is.tables["t"].(*viewTestTable), spaceId: 5}, tree: tree}
+	for _, tt := range []struct {
+		sql  string
+		ids  []int64
+		read int
+	}{
+		// The scan stops at the first key that isn't NULL.
+		{"SELECT * FROM t WHERE age IS NULL", []int64{2, 5}, 3},
+		{"SELECT * FROM t WHERE age IS NULL AND city = 'Shanghai'", []int64{5}, 3},
+		// The scan skips the NULLs.
+		{"SELECT * FROM t FORCE INDEX (idx_age_city) WHERE age IS NOT NULL", []int64{1, 3, 4}, 5},
+		{"SELECT * FROM t FORCE INDEX (idx_age_city) WHERE NOT (age IS NULL)", []int64{1, 3, 4}, 5},
+	} {
+		_, p, err := compileView(s, tt.sql)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.sql, err)
+		}
+		var scan *plan.PhysicalIndexScan
+		for ; p != nil; p = firstChild(p) {
+			if x, ok := p.(*plan.PhysicalIndexScan); ok {
+				scan = x
+			}
+		}
+		if scan == nil {
+			t.Fatalf("%s: expect an index scan", tt.sql)
+		}
+		tree.read = 0
+		exec := NewIndexRangeScanExec(s, tbl, scan.Index, scan.Ranges, nil)
+		if err = exec.Open(); err != nil {
+			t.Fatal(err)
+		}
+		var ids []int64
+		for exec.Next() {
+			ids = append(ids, exec.GetRow().ToDatum()[2].GetInt64())
+		}
+		if exec.Err() != nil || !reflect.DeepEqual(ids, tt.ids) || tree.read != tt.read {
+			t.Errorf("%s: expect ids %v reading %d entries, got %v reading %d, %v", tt.sql, tt.ids, tt.read, ids, tree.read, exec.Err())
+		}
+	}
+}
+
+func TestGroupByIndex(t *testing.T) {
+	is := newViewTestSchema(newCompositeIndexTestTable())
+	s := newViewTestSession(t, is)
+	// The entries of idx_age_city, the id after the key.
+	tree := &indexTestTree{entries: [][]basic.Datum{
+		basic.MakeDatums(nil, "Beijing", int64(2)),
+		basic.MakeDatums(int64(20), "Beijing", int64(1)),
+		basic.MakeDatums(int64(20), "Beijing", int64(6)),
+		basic.MakeDatums(int64(20), "Shanghai", int64(7)),
+		basic.MakeDatums(int64(30), "Beijing", int64(3)),
+		basic.MakeDatums(int64(30), "Shanghai", int64(4)),
+	}}
+	is.tables["t"] = &scanTestTable{spaceTestTable: &spaceTestTable{viewTestTable: is.tables["t"].(*viewTestTable), spaceId: 5}, tree: tree}
+	for _, tt := range []struct {
+		sql  string
+		rows []string
+	}{
+		{"SELECT age, COUNT(*) FROM t GROUP BY age", []string{"NULL,1", "20,3", "30,2"}},
+		{"SELECT age, city, COUNT(*) FROM t GROUP BY age, city", []string{
+			"NULL,Beijing,1", "20,Beijing,2", "20,Shanghai,1", "30,Beijing,1", "30,Shanghai,1"}},
+		{"SELECT age, MIN(city), MAX(city) FROM t GROUP BY age", []string{
+			"NULL,Beijing,Beijing", "20,Beijing,Shanghai", "30,Beijing,Shanghai"}},
+		{"SELECT age, COUNT(*) FROM t WHERE age > 20 GROUP BY age", []string{"30,2"}},
+		{"SELECT age, COUNT(*) FROM t GROUP BY age HAVING COUNT(*) > 1", []string{"20,3", "30,2"}},
+		// A loose scan returns the first entry of each age only.
+		{"SELECT DISTINCT age FROM t", []string{"NULL", "20", "30"}},
+		{"SELECT age FROM t GROUP BY age", []string{"NULL", "20", "30"}},
+	} {
+		_, p, err := compileView(s, tt.sql)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.sql, err)
+		}
+		// The groups come in the order of the index: nothing is sorted.
+		var stream bool
+		for q := p; q != nil; q = firstChild(q) {
+			switch x := q.(type) {
+			case *plan.Sort:
+				t.Fatalf("%s: expect no sort, got %s", tt.sql, plan.ToString(p))
+			case *plan.PhysicalAggregation:
+				stream = x.AggType == plan.StreamedAgg
+			}
+		}
+		if !stream {
+			t.Fatalf("%s: expect a stream aggregation, got %s", tt.sql, plan.ToString(p))
+		}
+		if got := aggregateTestRows(t, s, tt.sql); strings.Join(got, "\n") != strings.Join(tt.rows, "\n") {
+			t.Fatalf("%s: expect rows\n%s\ngot\n%s", tt.sql, strings.Join(tt.rows, "\n"), strings.Join(got, "\n"))
+		}
+	}
+
+	// city isn't a prefix of the index, its groups are sorted.
+	_, p, err := compileView(s, "SELECT city, COUNT(*) FROM t GROUP BY city")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for q := p; q != nil; q = firstChild(q) {
+		if agg, ok := q.(*plan.PhysicalAggregation); ok && agg.AggType == plan.StreamedAgg {
+			t.Fatalf("expect a sorting aggregation, got %s", plan.ToString(p))
+		}
+	}
+}
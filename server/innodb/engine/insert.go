@@ -0,0 +1,434 @@
+package engine
+
+import (
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/expression"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// InsertValues turns the VALUES lists or SET assignments of an INSERT into
+// complete table rows: given values are cast to their column types, omitted
+// columns get their defaults and NOT NULL constraints are checked.
+type InsertValues struct {
+	ctx context.Context
+
+	tableCols   []*schemas.Column
+	Columns     []*ast.ColumnName
+	Lists       [][]expression.Expression
+	Setlist     []*expression.Assignment
+	OnDuplicate []*expression.Assignment
+	IgnoreErr   bool
+}
+
+// NewInsertValues creates an InsertValues for an insert plan.
+func NewInsertValues(ctx context.Context, v *plan.Insert) *InsertValues {
+	return &InsertValues{
+		ctx:         ctx,
+		tableCols:   v.Table.Cols(),
+		Columns:     v.Columns,
+		Lists:       v.Lists,
+		Setlist:     v.Setlist,
+		OnDuplicate: v.OnDuplicate,
+		IgnoreErr:   v.IgnoreErr,
+	}
+}
+
+// getColumns returns the columns the values are written to, in value order.
+func (e *InsertValues) getColumns() ([]*schemas.Column, error) {
+	var cols []*schemas.Column
+	var err error
+	if len(e.Setlist) > 0 {
+		// INSERT INTO t SET a = 1, b = 2
+		columns := make([]string, 0, len(e.Setlist))
+		for _, v := range e.Setlist {
+			columns = append(columns, v.Col.ColName.O)
+		}
+		cols, err = schemas.FindCols(e.tableCols, columns)
+	} else if len(e.Columns) > 0 {
+		// INSERT INTO t (a, b) VALUES (1, 2)
+		columns := make([]string, 0, len(e.Columns))
+		for _, v := range e.Columns {
+			columns = append(columns, v.Name.O)
+		}
+		cols, err = schemas.FindCols(e.tableCols, columns)
+	} else {
+		// INSERT INTO t VALUES (1, 2)
+		cols = e.tableCols
+	}
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err = schemas.CheckOnce(cols); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return cols, nil
+}
+
+// getRows evaluates every value list into a full row of the table.
+func (e *InsertValues) getRows() ([][]basic.Datum, error) {
+	cols, err := e.getColumns()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	lists := e.Lists
+	if len(e.Setlist) > 0 {
+		list := make([]expression.Expression, 0, len(e.Setlist))
+		for _, v := range e.Setlist {
+			list = append(list, v.Expr)
+		}
+		lists = [][]expression.Expression{list}
+	}
+
+	rows := make([][]basic.Datum, 0, len(lists))
+	for i, list := range lists {
+		if len(list) != len(cols) {
+			return nil, ErrWrongValueCountOnRow.GenByArgs(i + 1)
+		}
+		row, err := e.getRow(cols, list, len(lists) == 1)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func (e *InsertValues) getRow(cols []*schemas.Column, list []expression.Expression, singleRow bool) ([]basic.Datum, error) {
+	sc := e.ctx.GetSessionVars().StmtCtx
+	row := make([]basic.Datum, len(e.tableCols))
+	hasValue := make([]bool, len(e.tableCols))
+	for i, expr := range list {
+		val, err := expr.Eval(row)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		col := cols[i]
+		if val.IsNull() {
+			row[col.Offset] = val
+		} else if row[col.Offset], err = schemas.CastValue(e.ctx, val, col.ToInfo()); err != nil {
+			if !e.IgnoreErr {
+				return nil, errors.Trace(err)
+			}
+			sc.AppendWarning(err)
+		}
+		hasValue[col.Offset] = true
+	}
+	if err := e.fillDefaultValues(row, hasValue); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err := e.checkNotNull(row, singleRow); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return row, nil
+}
+
+// fillDefaultValues fills the columns that got no value with their defaults.
+// A NOT NULL column without a default fails with ER_NO_DEFAULT_FOR_FIELD in
+// strict mode.
+func (e *InsertValues) fillDefaultValues(row []basic.Datum, hasValue []bool) error {
+	for i, col := range e.tableCols {
+		if hasValue[i] || mysql.HasAutoIncrementFlag(col.Flag) {
+			continue
+		}
+		d, err := schemas.GetColDefaultValue(e.ctx, col.ToInfo())
+		if err != nil {
+			return errors.Trace(err)
+		}
+		row[i] = d
+	}
+	return nil
+}
+
+// checkNotNull rejects NULL values in NOT NULL columns. Like MySQL, a
+// single-row insert always fails, while a multi-row insert outside strict
+// mode (or with IGNORE) stores the zero value and warns.
+func (e *InsertValues) checkNotNull(row []basic.Datum, singleRow bool) error {
+	sc := e.ctx.GetSessionVars().StmtCtx
+	strict := e.ctx.GetSessionVars().StrictSQLMode
+	for i, col := range e.tableCols {
+		if mysql.HasAutoIncrementFlag(col.Flag) {
+			continue
+		}
+		err := col.CheckNotNull(row[i])
+		if err == nil {
+			continue
+		}
+		if !e.IgnoreErr && (strict || singleRow) {
+			return errors.Trace(err)
+		}
+		sc.AppendWarning(err)
+		row[i] = schemas.GetZeroValue(col.ToInfo())
+	}
+	return nil
+}
+
+// upsertRowStore is the row access INSERT ... ON DUPLICATE KEY UPDATE needs.
+type upsertRowStore interface {
+	fkRowStore
+	// AddRow adds row to tbl and returns its handle.
+	AddRow(tbl *model.TableInfo, row []basic.Datum) (int64, error)
+}
+
+// upsertRows adds rows to tbl of store. A row with the same value as an
+// existing one on a unique key instead updates that one with the ON
+// DUPLICATE KEY UPDATE assignments, or fails with ER_DUP_ENTRY when there
+// are none. The affected rows are counted like MySQL: 1 per inserted row,
+// 2 per updated one and 0 when the update changes nothing. With IGNORE, a
+// row breaking a unique key or a foreign key is skipped with a warning.
+func (e *InsertValues) upsertRows(store upsertRowStore, tbl *model.TableInfo, rows [][]basic.Datum) (uint64, error) {
+	sc := e.ctx.GetSessionVars().StmtCtx
+	fk := &fkChecker{ctx: e.ctx, store: store}
+	var affected uint64
+	var inserted, updated int64
+	defer func() {
+		if inserted > 0 {
+			addRowDelta(e.ctx, tbl.ID, inserted)
+		}
+		if updated > 0 {
+			addRowsChanged(e.ctx, tbl.ID, updated)
+		}
+	}()
+	for _, row := range rows {
+		h, oldRow, dup, err := findDuplicateRow(sc, store, tbl, row, nil)
+		if err != nil {
+			return affected, errors.Trace(err)
+		}
+		if oldRow == nil {
+			if err = fk.checkChildRow(tbl, row); err != nil {
+				if skipRowOnError(e.ctx, e.IgnoreErr, err) {
+					continue
+				}
+				return affected, errors.Trace(err)
+			}
+			if _, err = store.AddRow(tbl, row); err != nil {
+				return affected, errors.Trace(err)
+			}
+			affected++
+			inserted++
+			continue
+		}
+		if len(e.OnDuplicate) == 0 {
+			if skipRowOnError(e.ctx, e.IgnoreErr, dup) {
+				continue
+			}
+			return affected, dup
+		}
+		newRow, err := e.onDuplicateUpdate(oldRow, row)
+		if err != nil {
+			return affected, errors.Trace(err)
+		}
+		same, err := datumsEqual(sc, oldRow, newRow)
+		if err != nil {
+			return affected, errors.Trace(err)
+		}
+		if same {
+			continue
+		}
+		if err = checkUpdatedRow(sc, fk, tbl, h, oldRow, newRow); err != nil {
+			if skipRowOnError(e.ctx, e.IgnoreErr, err) {
+				continue
+			}
+			return affected, errors.Trace(err)
+		}
+		if err = store.UpdateRow(tbl, h, newRow); err != nil {
+			return affected, errors.Trace(err)
+		}
+		affected += 2
+		updated++
+	}
+	return affected, nil
+}
+
+// checkUpdatedRow checks that oldRow of tbl with handle h can be replaced
+// by newRow: no other row has its value on a unique key, its foreign keys
+// find their parent rows, and the rows referencing it allow the change,
+// which is applied to them by the ON UPDATE actions.
+func checkUpdatedRow(sc *variable.StatementContext, fk *fkChecker, tbl *model.TableInfo, h int64, oldRow, newRow []basic.Datum) error {
+	_, other, dup, err := findDuplicateRow(sc, fk.store, tbl, newRow, &h)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if other != nil {
+		return dup
+	}
+	if err = fk.checkChildRow(tbl, newRow); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(fk.onUpdateRow(tbl, oldRow, newRow))
+}
+
+// skipRowOnError reports whether the row that failed with err is skipped:
+// with IGNORE, a duplicate key or a foreign key violation is only a warning.
+func skipRowOnError(ctx context.Context, ignore bool, err error) bool {
+	if !ignore || !(ErrDupEntry.Equal(err) || ErrNoReferencedRow2.Equal(err) || ErrRowIsReferenced2.Equal(err)) {
+		return false
+	}
+	ctx.GetSessionVars().StmtCtx.AppendWarning(err)
+	return true
+}
+
+// onDuplicateUpdate returns oldRow updated by the ON DUPLICATE KEY UPDATE
+// assignments, in which VALUES(col) reads the value of col in newRow.
+func (e *InsertValues) onDuplicateUpdate(oldRow, newRow []basic.Datum) ([]basic.Datum, error) {
+	vars := e.ctx.GetSessionVars()
+	vars.CurrInsertValues = newRow
+	defer func() { vars.CurrInsertValues = nil }()
+
+	row := make([]basic.Datum, len(oldRow))
+	copy(row, oldRow)
+	for _, assign := range e.OnDuplicate {
+		val, err := assign.Expr.Eval(row)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if row[assign.Col.Index], err = assignValue(e.ctx, e.tableCols[assign.Col.Index], val, e.IgnoreErr); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	return row, nil
+}
+
+// assignValue returns val cast to the type of col for an assignment of
+// UPDATE or ON DUPLICATE KEY UPDATE. With IGNORE, or outside strict mode,
+// a value that doesn't fit is clamped and NULL in a NOT NULL column becomes
+// the zero value, each with a warning.
+func assignValue(ctx context.Context, col *schemas.Column, val basic.Datum, ignore bool) (basic.Datum, error) {
+	sc := ctx.GetSessionVars().StmtCtx
+	warn := ignore || !ctx.GetSessionVars().StrictSQLMode
+	if !val.IsNull() {
+		casted, err := schemas.CastValue(ctx, val, col.ToInfo())
+		if err != nil {
+			if !ignore {
+				return val, errors.Trace(err)
+			}
+			sc.AppendWarning(err)
+		}
+		val = casted
+	}
+	if err := col.CheckNotNull(val); err != nil {
+		if !warn {
+			return val, errors.Trace(err)
+		}
+		sc.AppendWarning(err)
+		val = schemas.GetZeroValue(col.ToInfo())
+	}
+	return val, nil
+}
+
+// uniqueKeys returns the column offsets of the unique keys of tbl: the
+// integer primary key stored as the handle, and the unique indices.
+func uniqueKeys(tbl *model.TableInfo) (names []string, keys [][]int) {
+	if tbl.PKIsHandle {
+		for _, col := range tbl.Columns {
+			if mysql.HasPriKeyFlag(col.Flag) {
+				names, keys = append(names, "PRIMARY"), append(keys, []int{col.Offset})
+			}
+		}
+	}
+	for _, idx := range tbl.Indices {
+		if !idx.Unique && !idx.Primary {
+			continue
+		}
+		offsets := make([]int, 0, len(idx.Columns))
+		for _, col := range idx.Columns {
+			offsets = append(offsets, col.Offset)
+		}
+		names, keys = append(names, idx.Name.O), append(keys, offsets)
+	}
+	return names, keys
+}
+
+// findDuplicateRow returns the handle and row of tbl with the same value as
+// row on a unique key, with the ER_DUP_ENTRY error describing the conflict,
+// or a nil row if there is none. The row with handle self, if not nil, is
+// the one row is to replace and is not a duplicate. Like InnoDB, a key with
+// a NULL column matches no row.
+func findDuplicateRow(sc *variable.StatementContext, store fkRowStore, tbl *model.TableInfo, row []basic.Datum, self *int64) (int64, []basic.Datum, error, error) {
+	names, keys := uniqueKeys(tbl)
+	if len(keys) == 0 {
+		return 0, nil, nil, nil
+	}
+	handles, rows, err := store.Rows(tbl)
+	if err != nil {
+		return 0, nil, nil, errors.Trace(err)
+	}
+	for k, key := range keys {
+		vals, ok := keyValues(row, key)
+		if !ok {
+			continue
+		}
+		for i, old := range rows {
+			if self != nil && handles[i] == *self {
+				continue
+			}
+			oldVals, ok := keyValues(old, key)
+			if !ok {
+				continue
+			}
+			same, err := datumsEqual(sc, vals, oldVals)
+			if err != nil {
+				return 0, nil, nil, errors.Trace(err)
+			}
+			if same {
+				return handles[i], old, ErrDupEntry.GenByArgs(keyString(vals), names[k]), nil
+			}
+		}
+	}
+	return 0, nil, nil, nil
+}
+
+func keyValues(row []basic.Datum, offsets []int) ([]basic.Datum, bool) {
+	vals := make([]basic.Datum, 0, len(offsets))
+	for _, offset := range offsets {
+		if row[offset].IsNull() {
+			return nil, false
+		}
+		vals = append(vals, row[offset])
+	}
+	return vals, true
+}
+
+// keyString formats the value of a key as MySQL does in ER_DUP_ENTRY.
+func keyString(vals []basic.Datum) string {
+	strs := make([]string, 0, len(vals))
+	for _, val := range vals {
+		str, _ := val.ToString()
+		strs = append(strs, str)
+	}
+	return strings.Join(strs, "-")
+}
+
+func init() {
+	registerStmtHandler(&ast.InsertStmt{}, &stmtHandler{name: "insert", handle: (*XMySQLEngine).execInsert})
+}
+
+// execInsert runs an INSERT.
+func (srv *XMySQLEngine) execInsert(session innodb.MySQLServerSession, stmt ast.StmtNode, p plan.Plan) {
+	if v, ok := p.(*plan.Insert); ok {
+		e := NewInsertValues(session, v)
+		rows, err := e.getRows()
+		if err != nil {
+			session.SendError(toSQLError(err))
+			return
+		}
+		store := newTableRowStore(session, srv.infoSchemaManager, srv.pool, v.DBName)
+		affected, err := e.upsertRows(store, v.Table.Meta(), rows)
+		if err != nil {
+			store.rollback()
+			session.SendError(toSQLError(err))
+			return
+		}
+		session.GetSessionVars().StmtCtx.AddAffectedRows(affected)
+		session.SendOK()
+	}
+}
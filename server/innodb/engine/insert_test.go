@@ -0,0 +1,425 @@
+package engine
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/zhukovaskychina/xmysql-server/server/conf"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/buffer_pool"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/expression"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/varsutil"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/terror"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// newInsertTestCols returns the columns of
+//
+//	CREATE TABLE t (
+//		id INT NOT NULL,
+//		name VARCHAR(10) NOT NULL DEFAULT 'none',
+//		note VARCHAR(10),
+//		created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP)
+func newInsertTestCols() []*schemas.Column {
+	newCol := func(offset int, name string, tp byte, flag uint, dflt interface{}) *schemas.Column {
+		col := &model.ColumnInfo{Name: model.NewCIStr(name), Offset: offset, DefaultValue: dflt}
+		col.FieldType = *basic.NewFieldType(tp)
+		col.Flag = flag
+		if tp == mysql.TypeVarchar {
+			col.Flen, col.Charset = 10, mysql.DefaultCharset
+		}
+		return schemas.ToColumn(col)
+	}
+	return []*schemas.Column{
+		newCol(0, "id", mysql.TypeLong, mysql.NotNullFlag, nil),
+		newCol(1, "name", mysql.TypeVarchar, mysql.NotNullFlag, "none"),
+		newCol(2, "note", mysql.TypeVarchar, 0, nil),
+		newCol(3, "created", mysql.TypeTimestamp, mysql.NotNullFlag, ast.CurrentTimestamp),
+	}
+}
+
+func newInsertTestSession(t *testing.T, sqlMode string) *session {
+	s, err := createSession(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = varsutil.SetSessionSystemVar(s.sessionVars, variable.SQLModeVar, basic.NewStringDatum(sqlMode)); err != nil {
+		t.Fatal(err)
+	}
+	ResetStmtCtx(s, &ast.InsertStmt{})
+	return s
+}
+
+func constant(v interface{}) expression.Expression {
+	d := basic.NewDatum(v)
+	tp := &basic.FieldType{}
+	basic.DefaultTypeForValue(v, tp)
+	return &expression.Constant{Value: d, RetType: tp}
+}
+
+func columnNames(names ...string) []*ast.ColumnName {
+	cols := make([]*ast.ColumnName, 0, len(names))
+	for _, name := range names {
+		cols = append(cols, &ast.ColumnName{Name: model.NewCIStr(name)})
+	}
+	return cols
+}
+
+func errCode(err error) uint16 {
+	if e, ok := errors.Cause(err).(*terror.Error); ok {
+		return e.ToSQLError().Code
+	}
+	return 0
+}
+
+func TestInsertFillsDefaults(t *testing.T) {
+	s := newInsertTestSession(t, "STRICT_TRANS_TABLES")
+	e := &InsertValues{
+		ctx:       s,
+		tableCols: newInsertTestCols(),
+		Columns:   columnNames("id"),
+		Lists:     [][]expression.Expression{{constant(int64(1))}},
+	}
+	rows, err := e.getRows()
+	if err != nil {
+		t.Fatal(err)
+	}
+	row := rows[0]
+	if row[0].GetInt64() != 1 {
+		t.Errorf("id: got %v", row[0].GetValue())
+	}
+	if row[1].GetString() != "none" {
+		t.Errorf("name should get its default, got %v", row[1].GetValue())
+	}
+	if !row[2].IsNull() {
+		t.Errorf("nullable column without default should be NULL, got %v", row[2].GetValue())
+	}
+	now := s.sessionVars.StmtCtx.NowTs
+	created, err := row[3].GetMysqlTime().Time.GoTime(time.Local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Unix() != now.Unix() {
+		t.Errorf("created should default to the statement time %v, got %v", now, created)
+	}
+}
+
+func TestInsertNoDefaultForNotNullColumn(t *testing.T) {
+	e := &InsertValues{
+		ctx:       newInsertTestSession(t, "STRICT_TRANS_TABLES"),
+		tableCols: newInsertTestCols(),
+		Columns:   columnNames("note"),
+		Lists:     [][]expression.Expression{{constant("x")}},
+	}
+	if _, err := e.getRows(); errCode(err) != mysql.ErrNoDefaultForField {
+		t.Fatalf("expected error 1364, got %v", err)
+	}
+
+	// Outside strict mode the implicit zero value is used and a warning raised.
+	s := newInsertTestSession(t, "")
+	e.ctx = s
+	rows, err := e.getRows()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows[0][0].GetInt64() != 0 || s.sessionVars.StmtCtx.WarningCount() != 1 {
+		t.Fatalf("got id %v with %d warnings", rows[0][0].GetValue(), s.sessionVars.StmtCtx.WarningCount())
+	}
+}
+
+func TestInsertNullIntoNotNullColumn(t *testing.T) {
+	for _, sqlMode := range []string{"STRICT_TRANS_TABLES", ""} {
+		e := &InsertValues{
+			ctx:       newInsertTestSession(t, sqlMode),
+			tableCols: newInsertTestCols(),
+			Columns:   columnNames("id", "name"),
+			Lists:     [][]expression.Expression{{constant(int64(1)), constant(nil)}},
+		}
+		if _, err := e.getRows(); errCode(err) != mysql.ErrBadNull {
+			t.Fatalf("sql_mode %q: expected error 1048, got %v", sqlMode, err)
+		}
+	}
+
+	// A multi-row insert outside strict mode stores the zero value instead.
+	s := newInsertTestSession(t, "")
+	e := &InsertValues{
+		ctx:       s,
+		tableCols: newInsertTestCols(),
+		Lists: [][]expression.Expression{
+			{constant(int64(1)), constant("a"), constant(nil), constant("2020-01-01 00:00:00")},
+			{constant(int64(2)), constant(nil), constant(nil), constant("2020-01-01 00:00:00")},
+		},
+	}
+	rows, err := e.getRows()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows[1][1].GetString() != "" || s.sessionVars.StmtCtx.WarningCount() != 1 {
+		t.Fatalf("got name %v with %d warnings", rows[1][1].GetValue(), s.sessionVars.StmtCtx.WarningCount())
+	}
+}
+
+func TestInsertValueCount(t *testing.T) {
+	e := &InsertValues{
+		ctx:       newInsertTestSession(t, "STRICT_TRANS_TABLES"),
+		tableCols: newInsertTestCols(),
+		Columns:   columnNames("id", "name"),
+		Lists:     [][]expression.Expression{{constant(int64(1))}},
+	}
+	if _, err := e.getRows(); errCode(err) != mysql.ErrWrongValueCountOnRow {
+		t.Fatalf("expected error 1136, got %v", err)
+	}
+}
+
+func TestInsertOnDuplicateKeyUpdate(t *testing.T) {
+	// CREATE TABLE counters (id INT PRIMARY KEY, cnt INT);
+	tbl := newFKTestTable("counters", "id", "cnt")
+	tbl.PKIsHandle = true
+	tbl.Columns[0].Flag |= mysql.PriKeyFlag | mysql.NotNullFlag
+	s := newViewTestSession(t, newViewTestSchema(tbl))
+	store := newFKTestStore(tbl)
+	upsert := func(sql string) (uint64, error) {
+		_, p, err := compileView(s, sql)
+		if err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+		e := NewInsertValues(s, p.(*plan.Insert))
+		rows, err := e.getRows()
+		if err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+		return e.upsertRows(store, tbl, rows)
+	}
+	counts := func() map[int64]int64 {
+		m := make(map[int64]int64)
+		_, rows, _ := store.Rows(tbl)
+		for _, row := range rows {
+			m[row[0].GetInt64()] = row[1].GetInt64()
+		}
+		return m
+	}
+
+	const sql = "INSERT INTO counters (id, cnt) VALUES %s ON DUPLICATE KEY UPDATE cnt = cnt + VALUES(cnt)"
+	tests := []struct {
+		values   string
+		affected uint64
+		counts   map[int64]int64
+	}{
+		{"(1, 1), (2, 5)", 2, map[int64]int64{1: 1, 2: 5}},
+		{"(1, 2), (1, 3), (3, 1)", 5, map[int64]int64{1: 6, 2: 5, 3: 1}},
+		{"(2, 0)", 0, map[int64]int64{1: 6, 2: 5, 3: 1}},
+	}
+	for _, tt := range tests {
+		affected, err := upsert(fmt.Sprintf(sql, tt.values))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.values, err)
+		}
+		if affected != tt.affected {
+			t.Fatalf("%s: expect %d affected rows, got %d", tt.values, tt.affected, affected)
+		}
+		if got := counts(); !reflect.DeepEqual(got, tt.counts) {
+			t.Fatalf("%s: expect %v, got %v", tt.values, tt.counts, got)
+		}
+	}
+
+	if _, err := upsert("INSERT INTO counters VALUES (3, 1)"); errCode(err) != mysql.ErrDupEntry {
+		t.Fatalf("expect error %d, got %v", mysql.ErrDupEntry, err)
+	}
+
+	// Outside of ON DUPLICATE KEY UPDATE, VALUES() is NULL.
+	_, p, err := compileView(s, "SELECT VALUES(cnt) FROM counters")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := p.(*plan.Projection).Exprs[0].Eval([]basic.Datum{basic.NewIntDatum(1), basic.NewIntDatum(2)})
+	if err != nil || !d.IsNull() {
+		t.Fatalf("expect NULL, got %v, %v", d.GetValue(), err)
+	}
+}
+
+// newIgnoreTestTables returns
+//
+//	CREATE TABLE parent (id INT PRIMARY KEY);
+//	CREATE TABLE items (id INT PRIMARY KEY, code INT NOT NULL, pid INT,
+//		UNIQUE KEY uk_code (code),
+//		CONSTRAINT fk_parent FOREIGN KEY (pid) REFERENCES parent (id));
+func newIgnoreTestTables() (parent, items *model.TableInfo) {
+	parent = newFKTestTable("parent", "id")
+	parent.PKIsHandle = true
+	parent.Columns[0].Flag |= mysql.PriKeyFlag | mysql.NotNullFlag
+	items = newFKTestTable("items", "id", "code", "pid")
+	items.PKIsHandle = true
+	items.Columns[0].Flag |= mysql.PriKeyFlag | mysql.NotNullFlag
+	items.Columns[1].Flag |= mysql.NotNullFlag
+	items.Indices = []*model.IndexInfo{{
+		Name:    model.NewCIStr("uk_code"),
+		Unique:  true,
+		Columns: []*model.IndexColumn{{Name: model.NewCIStr("code"), Offset: 1, Length: basic.UnspecifiedLength}},
+		State:   model.StatePublic,
+	}}
+	items.ForeignKeys = []*model.FKInfo{{
+		Name:     model.NewCIStr("fk_parent"),
+		RefTable: parent.Name,
+		RefCols:  []model.CIStr{model.NewCIStr("id")},
+		Cols:     []model.CIStr{model.NewCIStr("pid")},
+		State:    model.StatePublic,
+	}}
+	return parent, items
+}
+
+// warningCodes returns the codes of the warnings of the last statement of s.
+func warningCodes(s *session) []uint16 {
+	var codes []uint16
+	for _, warn := range s.sessionVars.StmtCtx.GetWarnings() {
+		codes = append(codes, errCode(warn))
+	}
+	return codes
+}
+
+func TestInsertIgnore(t *testing.T) {
+	parent, items := newIgnoreTestTables()
+	s := newViewTestSession(t, newViewTestSchema(parent, items))
+	if err := varsutil.SetSessionSystemVar(s.sessionVars, variable.SQLModeVar, basic.NewStringDatum("STRICT_TRANS_TABLES")); err != nil {
+		t.Fatal(err)
+	}
+	store := newFKTestStore(parent, items)
+	store.insert(parent, 1)
+	insert := func(sql string) (uint64, error) {
+		_, p, err := compileView(s, sql)
+		if err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+		e := NewInsertValues(s, p.(*plan.Insert))
+		rows, err := e.getRows()
+		if err != nil {
+			return 0, err
+		}
+		return e.upsertRows(store, items, rows)
+	}
+	codes := func() map[int64]int64 {
+		m := make(map[int64]int64)
+		_, rows, _ := store.Rows(items)
+		for _, row := range rows {
+			m[row[0].GetInt64()] = row[1].GetInt64()
+		}
+		return m
+	}
+
+	// Without IGNORE the first bad row fails the statement.
+	if _, err := insert("INSERT INTO items VALUES (1, 10, 1), (1, 11, 1)"); errCode(err) != mysql.ErrDupEntry {
+		t.Fatalf("expect error %d, got %v", mysql.ErrDupEntry, err)
+	}
+	if _, err := insert("INSERT INTO items VALUES (2, 12, 9)"); errCode(err) != mysql.ErrNoReferencedRow2 {
+		t.Fatalf("expect error %d, got %v", mysql.ErrNoReferencedRow2, err)
+	}
+
+	// With IGNORE the rows duplicating the primary key or the unique key,
+	// or without a parent row, are skipped with a warning each, and a NULL
+	// in the NOT NULL column becomes 0.
+	affected, err := insert("INSERT IGNORE INTO items VALUES (1, 11, 1), (2, 10, NULL), (3, 13, 9), (4, 14, 1), (5, NULL, 1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if affected != 2 {
+		t.Fatalf("expect 2 rows inserted, got %d", affected)
+	}
+	if got, want := codes(), map[int64]int64{1: 10, 4: 14, 5: 0}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expect %v, got %v", want, got)
+	}
+	want := []uint16{mysql.ErrBadNull, mysql.ErrDupEntry, mysql.ErrDupEntry, mysql.ErrNoReferencedRow2}
+	if got := warningCodes(s); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expect warnings %v, got %v", want, got)
+	}
+	if n := s.sessionVars.StmtCtx.WarningCount(); n != 4 {
+		t.Fatalf("expect 4 warnings, got %d", n)
+	}
+}
+
+func TestExecInsertChecksStoredRows(t *testing.T) {
+	is := newViewTestSchema(newTraceTestTable())
+	tree := &indexTestTree{entries: [][]basic.Datum{basic.MakeDatums(int64(1), int64(10), int64(100))}}
+	is.tables["t"] = &scanTestTable{spaceTestTable: &spaceTestTable{viewTestTable: is.tables["t"].(*viewTestTable), spaceId: 5}, tree: tree}
+	fs := basic.NewFileSystem(conf.NewCfg())
+	fs.AddTableSpace(dumpTestSpace(5))
+	srv := &XMySQLEngine{conf: conf.NewCfg(), infoSchemaManager: is, pool: buffer_pool.NewBufferPool(16*16384, 0.75, 0.25, 1000, fs)}
+	s := &serverTestSession{session: newViewTestSession(t, is)}
+	run := func(sql string) {
+		t.Helper()
+		s.errs = nil
+		srv.ExecuteQuery(s, sql)
+	}
+
+	// The row with the id of the stored one is a duplicate.
+	run("INSERT INTO t VALUES (1, 2, 3)")
+	if len(s.errs) != 1 || s.errs[0].Code != mysql.ErrDupEntry {
+		t.Fatalf("expect error %d, got %v", mysql.ErrDupEntry, s.errs)
+	}
+	// With IGNORE it is skipped with a warning, nothing is left to write.
+	run("INSERT IGNORE INTO t VALUES (1, 2, 3)")
+	if len(s.errs) != 0 || s.sessionVars.StmtCtx.AffectedRows() != 0 || s.sessionVars.StmtCtx.WarningCount() != 1 {
+		t.Fatalf("expect the row skipped with a warning, got %v", s.errs)
+	}
+	// The other rows go to a table that can't be written.
+	run("INSERT INTO t VALUES (2, 2, 3)")
+	if len(s.errs) != 1 || s.errs[0].Code != mysql.ErrNotSupportedYet {
+		t.Fatalf("expect error %d, got %v", mysql.ErrNotSupportedYet, s.errs)
+	}
+}
+
+func TestInsertIgnoreStored(t *testing.T) {
+	parent, items := newIgnoreTestTables()
+	// The stored tables have no secondary indexes.
+	items.Indices = nil
+	srv, s := newStoredTestEngine(t, parent, items)
+	execStored(t, srv, s, "SET sql_mode = 'STRICT_TRANS_TABLES'", 0)
+	execStored(t, srv, s, "INSERT INTO parent VALUES (1)", 0)
+	execStored(t, srv, s, "INSERT INTO items VALUES (1, 10, 1)", 0)
+
+	execStored(t, srv, s, "INSERT INTO items VALUES (2, 12, 1), (1, 11, 1)", mysql.ErrDupEntry)
+	execStored(t, srv, s, "INSERT IGNORE INTO items VALUES (1, 11, 1), (3, 13, 9), (4, 14, 1), (5, NULL, 1)", 0)
+	if n := s.sessionVars.StmtCtx.AffectedRows(); n != 2 {
+		t.Fatalf("expect 2 rows inserted, got %d", n)
+	}
+	want := []uint16{mysql.ErrBadNull, mysql.ErrDupEntry, mysql.ErrNoReferencedRow2}
+	if got := warningCodes(s.session); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expect warnings %v, got %v", want, got)
+	}
+	if got := execStored(t, srv, s, "SELECT * FROM items", 0); got != "1,10,1;4,14,1;5,0,1" {
+		t.Fatalf("expect the rows kept and inserted, got %s", got)
+	}
+}
+
+func TestInsertOnDuplicateKeyUpdateStored(t *testing.T) {
+	srv, s := newStoredTestEngine(t, newFKTestTable("counters", "id", "cnt"))
+	const sql = "INSERT INTO counters (id, cnt) VALUES %s ON DUPLICATE KEY UPDATE cnt = cnt + VALUES(cnt)"
+	tests := []struct {
+		values   string
+		affected uint64
+		rows     string
+	}{
+		{"(1, 1), (2, 5)", 2, "1,1;2,5"},
+		{"(1, 2), (1, 3), (3, 1)", 5, "1,6;2,5;3,1"},
+		{"(2, 0)", 0, "1,6;2,5;3,1"},
+	}
+	for _, tt := range tests {
+		execStored(t, srv, s, fmt.Sprintf(sql, tt.values), 0)
+		if n := s.sessionVars.StmtCtx.AffectedRows(); n != tt.affected {
+			t.Fatalf("%s: expect %d affected rows, got %d", tt.values, tt.affected, n)
+		}
+		if got := execStored(t, srv, s, "SELECT * FROM counters", 0); got != tt.rows {
+			t.Fatalf("%s: expect rows %s, got %s", tt.values, tt.rows, got)
+		}
+	}
+	execStored(t, srv, s, "INSERT INTO counters VALUES (3, 1)", mysql.ErrDupEntry)
+	if got := execStored(t, srv, s, "SELECT VALUES(cnt) FROM counters WHERE id = 1", 0); got != "NULL" {
+		t.Fatalf("expect NULL outside of ON DUPLICATE KEY UPDATE, got %s", got)
+	}
+}
@@ -0,0 +1,127 @@
+package engine
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/statistics"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// newJoinOrderTestTable returns
+//
+//	CREATE TABLE <name> (id INT PRIMARY KEY, x INT)
+func newJoinOrderTestTable(name string, id int64) *model.TableInfo {
+	tbl := newFKTestTable(name, "id", "x")
+	tbl.ID, tbl.PKIsHandle = id, true
+	for i, col := range tbl.Columns {
+		col.ID = int64(i + 1)
+	}
+	tbl.Columns[0].Flag = mysql.PriKeyFlag | mysql.NotNullFlag
+	return tbl
+}
+
+// newJoinOrderTestStats returns the statistics of a table of count rows
+// whose column x has ndv distinct values between 1 and count.
+func newJoinOrderTestStats(tbl *model.TableInfo, count, ndv int64) *statistics.Table {
+	x := tbl.Columns[1]
+	return &statistics.Table{
+		TableID: tbl.ID,
+		Count:   count,
+		Columns: map[int64]*statistics.Column{x.ID: {
+			Histogram: statistics.Histogram{ID: x.ID, NDV: ndv, Buckets: []statistics.Bucket{{
+				Count:      count,
+				LowerBound: basic.NewIntDatum(1),
+				UpperBound: basic.NewIntDatum(count),
+				Repeats:    1,
+			}}},
+			Info: x,
+		}},
+		Indices: map[int64]*statistics.Index{},
+	}
+}
+
+func TestJoinOrder(t *testing.T) {
+	a, b, c := newJoinOrderTestTable("a", 21), newJoinOrderTestTable("b", 22), newJoinOrderTestTable("c", 23)
+	// INDEX ix (x) of c.
+	c.Indices = []*model.IndexInfo{{
+		ID:      1,
+		Name:    model.NewCIStr("ix"),
+		Table:   c.Name,
+		Columns: []*model.IndexColumn{{Name: model.NewCIStr("x"), Offset: 1, Length: basic.UnspecifiedLength}},
+		State:   model.StatePublic,
+	}}
+	h := statistics.NewHandle(nil, 0)
+	h.UpdateTableStats([]*statistics.Table{
+		newJoinOrderTestStats(a, 100000, 10000),
+		newJoinOrderTestStats(b, 10000, 10000),
+		newJoinOrderTestStats(c, 100000, 100000),
+	}, nil)
+	plan.RegisterStatsHandle(h)
+	defer plan.RegisterStatsHandle(nil)
+	s := newViewTestSession(t, newViewTestSchema(a, b, c))
+	setTraceVar(t, s, "optimizer_trace", "enabled=on")
+
+	type joinOrderTrace struct {
+		Tables []string `json:"tables"`
+		Cost   float64  `json:"cost"`
+		Chosen bool     `json:"chosen"`
+	}
+	// order returns the order the tables of sql are read in, and the orders
+	// the optimizer considered.
+	order := func(sql string) ([]string, []joinOrderTrace) {
+		t.Helper()
+		if _, _, err := compileView(s, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+		var trace struct {
+			JoinOrders []joinOrderTrace `json:"considered_join_orders"`
+			JoinOrder  []string         `json:"join_order"`
+		}
+		if err := json.Unmarshal([]byte(s.sessionVars.LastOptimizerTrace.Trace), &trace); err != nil {
+			t.Fatal(err)
+		}
+		return trace.JoinOrder, trace.JoinOrders
+	}
+
+	// The only row of c matching the filter is read first by ix, though c
+	// is listed last, and b then a are matched to it.
+	for _, sql := range []string{
+		"SELECT a.id FROM a JOIN b ON a.x = b.id JOIN c ON b.x = c.id WHERE c.x = 5",
+		"SELECT a.id FROM a, b, c WHERE a.x = b.id AND b.x = c.id AND c.x = 5",
+	} {
+		got, considered := order(sql)
+		if want := []string{"c", "b", "a"}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: expect the order %v, got %v", sql, want, got)
+		}
+		var chosen []joinOrderTrace
+		for _, o := range considered {
+			if o.Chosen {
+				chosen = append(chosen, o)
+			}
+		}
+		if len(considered) < 3 || len(chosen) != 1 || !reflect.DeepEqual(chosen[0].Tables, got) {
+			t.Fatalf("%s: expect the orders considered with %v chosen, got %+v", sql, got, considered)
+		}
+		for _, o := range considered {
+			if o.Cost < chosen[0].Cost {
+				t.Fatalf("%s: %v costs less than the chosen order %+v", sql, o.Tables, chosen[0])
+			}
+		}
+	}
+
+	// STRAIGHT_JOIN reads the tables as they are listed.
+	for _, sql := range []string{
+		"SELECT STRAIGHT_JOIN a.id FROM a JOIN b ON a.x = b.id JOIN c ON b.x = c.id WHERE c.x = 5",
+		"SELECT a.id FROM a STRAIGHT_JOIN b ON a.x = b.id STRAIGHT_JOIN c ON b.x = c.id WHERE c.x = 5",
+	} {
+		got, considered := order(sql)
+		if want := []string{"a", "b", "c"}; !reflect.DeepEqual(got, want) || len(considered) != 0 {
+			t.Fatalf("%s: expect the order %v without reordering, got %v and %+v", sql, want, got, considered)
+		}
+	}
+}
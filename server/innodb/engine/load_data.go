@@ -0,0 +1,229 @@
+package engine
+
+import (
+	"bufio"
+	"io"
+	"os"
+
+	"github.com/juju/errors"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/expression"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
+)
+
+// loadDataRows reads the server side file of a LOAD DATA INFILE into rows
+// of tbl, converting the fields like the values of an INSERT.
+func loadDataRows(ctx context.Context, v *plan.LoadData, tbl schemas.Table) ([][]basic.Datum, error) {
+	path, err := secureFilePath(ctx, v.Path)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer f.Close()
+
+	insert := &InsertValues{ctx: ctx, tableCols: tbl.Cols(), Columns: v.Columns}
+	r := newInfileReader(f, v.FieldsInfo, v.LinesInfo)
+	for {
+		fields, err := r.readRow()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		list := make([]expression.Expression, len(fields))
+		for i, d := range fields {
+			tp := &basic.FieldType{}
+			basic.DefaultTypeForValue(d.GetValue(), tp)
+			list[i] = &expression.Constant{Value: d, RetType: tp}
+		}
+		insert.Lists = append(insert.Lists, list)
+	}
+	return insert.getRows()
+}
+
+// infileReader splits a file written by SELECT ... INTO OUTFILE back into
+// fields, undoing the enclosing and escaping.
+type infileReader struct {
+	r      *bufio.Reader
+	fields *ast.FieldsClause
+	lines  *ast.LinesClause
+}
+
+func newInfileReader(r io.Reader, fields *ast.FieldsClause, lines *ast.LinesClause) *infileReader {
+	return &infileReader{r: bufio.NewReader(r), fields: fields, lines: lines}
+}
+
+// readRow returns the fields of the next line, or io.EOF at the end of the
+// file. Lines without the LINES STARTING BY prefix are skipped.
+func (r *infileReader) readRow() ([]basic.Datum, error) {
+	if err := r.skipToStarting(); err != nil {
+		return nil, err
+	}
+	if _, err := r.r.Peek(1); err != nil {
+		return nil, err
+	}
+	var row []basic.Datum
+	for {
+		d, lineEnd, err := r.readField()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		row = append(row, d)
+		if lineEnd {
+			return row, nil
+		}
+	}
+}
+
+func (r *infileReader) skipToStarting() error {
+	starting := r.lines.Starting
+	if starting == "" {
+		return nil
+	}
+	for !r.consume(starting) {
+		if _, err := r.r.ReadByte(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readField reads one field and reports whether it ends the line.
+func (r *infileReader) readField() (basic.Datum, bool, error) {
+	escape, enclosed := r.fields.Escaped, r.fields.Enclosed
+	var (
+		buf    []byte
+		quoted bool
+		isNull bool
+	)
+	if enclosed != 0 && r.consume(string(enclosed)) {
+		quoted = true
+	}
+	wasQuoted := quoted
+	for {
+		c, err := r.r.ReadByte()
+		if err == io.EOF {
+			return r.makeField(buf, wasQuoted, isNull), true, nil
+		}
+		if err != nil {
+			return basic.Datum{}, false, errors.Trace(err)
+		}
+		if escape != 0 && c == escape {
+			next, err := r.r.ReadByte()
+			if err == io.EOF {
+				buf = append(buf, c)
+				continue
+			}
+			if err != nil {
+				return basic.Datum{}, false, errors.Trace(err)
+			}
+			if next == 'N' && !wasQuoted && len(buf) == 0 {
+				buf = append(buf, next)
+				isNull = true
+				continue
+			}
+			buf = append(buf, unescapeChar(next))
+			continue
+		}
+		if quoted {
+			if c != enclosed {
+				buf = append(buf, c)
+			} else if r.consume(string(enclosed)) {
+				// A doubled enclose character stands for itself.
+				buf = append(buf, c)
+			} else {
+				quoted = false
+			}
+			continue
+		}
+		r.r.UnreadByte()
+		if r.consume(r.lines.Terminated) {
+			return r.makeField(buf, wasQuoted, isNull), true, nil
+		}
+		if r.consume(r.fields.Terminated) {
+			return r.makeField(buf, wasQuoted, isNull), false, nil
+		}
+		r.r.ReadByte()
+		buf = append(buf, c)
+	}
+}
+
+// makeField returns the value of a field: \N is NULL, and so is an
+// unenclosed NULL when there is no escape character.
+func (r *infileReader) makeField(buf []byte, quoted, isNull bool) basic.Datum {
+	if isNull && len(buf) == 1 {
+		return basic.Datum{}
+	}
+	if r.fields.Escaped == 0 && !quoted && string(buf) == "NULL" {
+		return basic.Datum{}
+	}
+	return basic.NewStringDatum(string(buf))
+}
+
+// consume skips s if the input continues with it.
+func (r *infileReader) consume(s string) bool {
+	if s == "" {
+		return false
+	}
+	b, err := r.r.Peek(len(s))
+	if err != nil || string(b) != s {
+		return false
+	}
+	r.r.Discard(len(s))
+	return true
+}
+
+func unescapeChar(c byte) byte {
+	switch c {
+	case '0':
+		return 0
+	case 'b':
+		return '\b'
+	case 'n':
+		return '\n'
+	case 'r':
+		return '\r'
+	case 't':
+		return '\t'
+	case 'Z':
+		return 26
+	}
+	return c
+}
+
+func init() {
+	registerStmtHandler(&ast.LoadDataStmt{}, &stmtHandler{name: "load data", handle: (*XMySQLEngine).execLoadData})
+}
+
+// execLoadData runs a LOAD DATA.
+func (srv *XMySQLEngine) execLoadData(session innodb.MySQLServerSession, stmt ast.StmtNode, p plan.Plan) {
+	if v, ok := p.(*plan.LoadData); ok {
+		tbl, err := schemas.TableByName(srv.infoSchemaManager, v.Table.Schema, v.Table.Name)
+		if err != nil {
+			session.SendError(toSQLError(err))
+			return
+		}
+		rows, err := loadDataRows(session, v, tbl)
+		if err != nil {
+			session.SendError(toSQLError(err))
+			return
+		}
+		store := newTableRowStore(session, srv.infoSchemaManager, srv.pool, v.Table.Schema)
+		affected, err := addRows(session, store, tbl.Meta(), rows)
+		if err != nil {
+			store.rollback()
+			session.SendError(toSQLError(err))
+			return
+		}
+		session.GetSessionVars().StmtCtx.AddAffectedRows(affected)
+		session.SendOK()
+	}
+}
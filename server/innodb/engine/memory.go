@@ -0,0 +1,75 @@
+package engine
+
+import (
+	"strconv"
+	"unsafe"
+
+	"github.com/juju/errors"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/varsutil"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/memory"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+/**
+内存统计
+
+每个会话有一个内存跟踪器，最多使用 max_session_memory 字节；执行语句时，语句的跟踪器挂在会话的跟踪器下，
+语句结束时释放它使用的内存。所有会话的跟踪器挂在 memory.GlobalTracker 下，最多使用 max_server_memory 字节。
+
+排序、分组、投影保存的行计入语句的跟踪器。超过限制时，DISTINCT 把已见过的键写到磁盘后继续，
+排序中止语句并返回 ER_OUT_OF_SORTMEMORY，其他操作中止语句并返回 ErrMemoryExceeded。
+SHOW PROCESSLIST 和 performance_schema 的内存汇总表显示会话当前和最多使用的内存。
+**/
+
+// datumSize is the memory a Datum takes besides its bytes.
+const datumSize = int64(unsafe.Sizeof(basic.Datum{}))
+
+// rowOverhead is the memory the slice of a row takes besides its datums.
+const rowOverhead = int64(unsafe.Sizeof([]basic.Datum(nil)))
+
+// rowSize estimates the memory row takes.
+func rowSize(row []basic.Datum) int64 {
+	size := rowOverhead + int64(len(row))*datumSize
+	for i := range row {
+		size += int64(len(row[i].GetBytes()))
+	}
+	return size
+}
+
+// consumeRow consumes the memory row takes by the statement of ctx.
+func consumeRow(ctx context.Context, row []basic.Datum) error {
+	return errors.Trace(ctx.GetSessionVars().StmtCtx.MemTracker.Consume(rowSize(row)))
+}
+
+// newStmtMemTracker creates the memory tracker of a statement of the session
+// of vars, applying max_session_memory to the tracker of the session.
+func newStmtMemTracker(vars *variable.SessionVars) *memory.Tracker {
+	if value, err := varsutil.GetSessionSystemVar(vars, variable.MaxSessionMemory); err == nil {
+		limit, _ := strconv.ParseInt(value, 10, 64)
+		vars.MemTracker.SetBytesLimit(limit)
+	}
+	t := memory.NewTracker(memory.LabelStatement, 0)
+	t.AttachTo(vars.MemTracker)
+	return t
+}
+
+// initMemoryLimit limits the memory of the statements of all the sessions to
+// max_server_memory bytes, which SET GLOBAL changes.
+func (srv *XMySQLEngine) initMemoryLimit() {
+	memory.GlobalTracker.SetBytesLimit(srv.conf.MaxServerMemory)
+	sv := variable.GetSysVar(variable.MaxServerMemory)
+	variable.RegisterSysVar(sv, mysql.TypeLonglong, func(*variable.SessionVars) (string, error) {
+		return strconv.FormatInt(memory.GlobalTracker.BytesLimit(), 10), nil
+	})
+	variable.RegisterSysVarSetter(variable.MaxServerMemory, func(_ *variable.SessionVars, value string) error {
+		limit, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || limit < 0 {
+			return variable.ErrWrongValueForVar.GenByArgs(variable.MaxServerMemory, value)
+		}
+		memory.GlobalTracker.SetBytesLimit(limit)
+		return nil
+	})
+}
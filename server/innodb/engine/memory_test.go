@@ -0,0 +1,101 @@
+package engine
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/conf"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/memory"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+func TestMemoryLimit(t *testing.T) {
+	is := newViewTestSchema()
+	srv := &XMySQLEngine{conf: conf.NewCfg(), infoSchemaManager: is}
+	s := &serverTestSession{session: newViewTestSession(t, is)}
+	s.sessionVars.ConnectionID = NextConnectionID()
+	variable.AddProcess(s.sessionVars)
+	defer variable.RemoveProcess(s.sessionVars)
+	run := func(sql string) {
+		s.errs, s.rows = nil, nil
+		srv.ExecuteQuery(s, sql)
+		if len(s.errs) != 0 {
+			t.Fatalf("%s: %v", sql, s.errs[0])
+		}
+	}
+
+	// 40 small rows.
+	selects := make([]string, 40)
+	for i := range selects {
+		selects[i] = "SELECT " + strconv.Itoa(i) + " AS b"
+	}
+	rows := "(" + strings.Join(selects, " UNION ALL ") + ") t"
+	run("SELECT REPEAT('x', 1000) FROM " + rows + " ORDER BY b")
+	if len(s.rows) != 40 {
+		t.Fatalf("expect 40 rows, got %d", len(s.rows))
+	}
+	peak := s.sessionVars.MemTracker.MaxConsumed()
+	if peak < 40*1000 {
+		t.Errorf("expect the rows tracked, got peak %d", peak)
+	}
+	if got := s.sessionVars.MemTracker.BytesConsumed(); got != 0 {
+		t.Errorf("expect the memory released after the statement, got %d", got)
+	}
+
+	run("SET max_session_memory = 30000")
+	for sql, code := range map[string]uint16{
+		"SELECT REPEAT('x', 1000) FROM " + rows:                            mysql.ErrMemoryExceeded,
+		"SELECT b FROM " + rows + " ORDER BY REPEAT('x', 1000 + b)":        mysql.ErrOutOfSortMemory,
+		"SELECT COUNT(*) FROM " + rows + " GROUP BY REPEAT('x', 1000 + b)": mysql.ErrOutOfSortMemory,
+	} {
+		s.errs = nil
+		srv.ExecuteQuery(s, sql)
+		if len(s.errs) != 1 || s.errs[0].Code != code {
+			t.Errorf("%s: expect error %d, got %v", sql, code, s.errs)
+		}
+		if got := s.sessionVars.MemTracker.BytesConsumed(); got != 0 {
+			t.Errorf("%s: expect the memory released after the statement, got %d", sql, got)
+		}
+	}
+	run("SELECT b FROM " + rows + " ORDER BY b")
+
+	run("SHOW PROCESSLIST")
+	var found bool
+	for _, row := range s.rows {
+		if row[0].GetInt64() != int64(s.sessionVars.ConnectionID) {
+			continue
+		}
+		found = true
+		if command, _ := row[4].ToString(); command != "Query" || row[7].GetString() != "SHOW PROCESSLIST" {
+			t.Errorf("expect the statement shown, got %v", row)
+		}
+		if row[9].GetInt64() < peak {
+			t.Errorf("expect peak memory at least %d, got %v", peak, row[9].GetInt64())
+		}
+	}
+	if !found {
+		t.Errorf("expect the session listed, got %v", s.rows)
+	}
+
+	run("SELECT CURRENT_NUMBER_OF_BYTES_USED, HIGH_NUMBER_OF_BYTES_USED FROM performance_schema.memory_summary_by_thread_by_event_name WHERE THREAD_ID = " +
+		"CONNECTION_ID()")
+	if len(s.rows) != 1 || s.rows[0][1].GetInt64() < peak {
+		t.Errorf("expect the peak of the session, got %v", s.rows)
+	}
+	run("SELECT EVENT_NAME FROM performance_schema.memory_summary_global_by_event_name")
+	if len(s.rows) != 1 || s.rows[0][0].GetString() != "memory/sql/statement" {
+		t.Errorf("expect the global summary, got %v", s.rows)
+	}
+
+	// The server limit caps all the sessions.
+	run("SET max_session_memory = 0")
+	memory.GlobalTracker.SetBytesLimit(20000)
+	defer memory.GlobalTracker.SetBytesLimit(0)
+	s.errs = nil
+	srv.ExecuteQuery(s, "SELECT REPEAT('x', 1000) FROM "+rows)
+	if len(s.errs) != 1 || s.errs[0].Code != mysql.ErrMemoryExceeded || !strings.Contains(s.errs[0].Message, "server") {
+		t.Errorf("expect the server limit exceeded, got %v", s.errs)
+	}
+}
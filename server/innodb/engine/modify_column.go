@@ -0,0 +1,152 @@
+package engine
+
+import (
+	"github.com/juju/errors"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ddl"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+/**
+ALTER TABLE ... MODIFY COLUMN / CHANGE COLUMN
+
+按 MySQL 的规则把修改分成两类：
+	只改元数据：改名、默认值、注释、允许 NULL、整数的显示宽度、在 ENUM/SET 末尾追加成员、
+		长度前缀字节数不变的 VARCHAR 加长。表中的行不动
+	重建表：其他的类型、长度、符号、字符集的修改，以及列位置的移动
+重建时按新的定义建一张影子表：逐行读出旧表的行，被修改的列按 CAST 的规则转换成新的类型，
+每行都检查。严格模式下转换失败（1265/1366）或 NULL 放进 NOT NULL 列（1138）时整个语句失败，
+旧表不变；非严格模式下截断或取零值并产生警告。
+所有行转换完后，一次性用新的定义和新的行替换旧表，并从新的行重建二级索引，
+之后的 DESCRIBE 立即看到新的定义。把允许 NULL 的列改为 NOT NULL 时也检查每一行，
+没有 NULL 时仍然只改元数据。
+**/
+
+// modifyColumnSpecs returns the MODIFY COLUMN and CHANGE COLUMN specs of
+// stmt, in order.
+func modifyColumnSpecs(stmt *ast.AlterTableStmt) []*ast.AlterTableSpec {
+	var specs []*ast.AlterTableSpec
+	for _, spec := range stmt.Specs {
+		if spec.Tp == ast.AlterTableModifyColumn || spec.Tp == ast.AlterTableChangeColumn {
+			specs = append(specs, spec)
+		}
+	}
+	return specs
+}
+
+// modifyColumns applies specs, MODIFY COLUMN and CHANGE COLUMN specs, to
+// the table tn of a resolved ALTER TABLE, reading its rows with reader when
+// they have to be converted or checked.
+func modifyColumns(ctx context.Context, is schemas.InfoSchema, reader tableRowsReader, tn *ast.TableName, specs []*ast.AlterTableSpec) error {
+	tbl, err := is.TableByName(tn.Schema, tn.Name)
+	if err != nil || tbl == nil {
+		return schemas.ErrTableNotExists.GenByArgs(tn.Schema.O, tn.Name.O)
+	}
+	if tbl.Meta().IsView() {
+		return schemas.ErrWrongObject.GenByArgs(tn.Schema.O, tn.Name.O, "BASE TABLE")
+	}
+	info := tbl.Meta().Clone()
+	// origin is the offset in the rows of the table of each column, converted
+	// tells the columns whose values are converted to their new type.
+	origin := make(map[*model.ColumnInfo]int, len(info.Columns))
+	for i, col := range info.Columns {
+		origin[col] = i
+	}
+	converted := make(map[*model.ColumnInfo]bool)
+	var rebuild, checkNulls bool
+	for _, spec := range specs {
+		oldName := spec.NewColumn.Name.Name
+		if spec.Tp == ast.AlterTableChangeColumn {
+			oldName = spec.OldColumnName.Name
+		}
+		newCol, err := ddl.ModifiedColumn(ctx, info, oldName, spec)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		oldCol := info.Columns[newCol.Offset]
+		if err = ddl.ModifyTableColumn(info, newCol, oldName, spec.Position); err != nil {
+			return errors.Trace(err)
+		}
+		origin[newCol] = origin[oldCol]
+		delete(origin, oldCol)
+		converted[newCol] = converted[oldCol] || ddl.ModifyColumnRebuilds(oldCol, newCol)
+		rebuild = rebuild || converted[newCol]
+		checkNulls = checkNulls || !mysql.HasNotNullFlag(oldCol.Flag) && mysql.HasNotNullFlag(newCol.Flag)
+		if oldCol.Name.L != newCol.Name.L {
+			renameForeignKeyColumn(info, oldCol.Name, newCol.Name)
+		}
+	}
+	for i, col := range info.Columns {
+		// Moving a column moves its values in every row.
+		rebuild = rebuild || origin[col] != i
+	}
+	if !rebuild && !checkNulls {
+		return errors.Trace(is.AlterTableColumns(tn.Schema, tn.Name, info, nil))
+	}
+
+	rows, err := reader.TableRows(tbl)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	newRows, hasNulls, err := convertRows(ctx, info, rows, origin, converted)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !rebuild && !hasNulls {
+		// No row breaks the new NOT NULL, the rows stay as they are.
+		newRows = nil
+	}
+	return errors.Trace(is.AlterTableColumns(tn.Schema, tn.Name, info, newRows))
+}
+
+// convertRows returns rows, the rows of the table before the change, in the
+// columns of info: each column takes its value at origin, converted to its
+// new type when converted says so. It reports whether a NULL was replaced
+// in a NOT NULL column, which is an error in strict mode.
+func convertRows(ctx context.Context, info *model.TableInfo, rows [][]basic.Datum, origin map[*model.ColumnInfo]int, converted map[*model.ColumnInfo]bool) ([][]basic.Datum, bool, error) {
+	sc := ctx.GetSessionVars().StmtCtx
+	strict := ctx.GetSessionVars().StrictSQLMode
+	var hasNulls bool
+	newRows := make([][]basic.Datum, 0, len(rows))
+	for _, row := range rows {
+		newRow := make([]basic.Datum, len(info.Columns))
+		for i, col := range info.Columns {
+			v := row[origin[col]]
+			switch {
+			case v.IsNull() && mysql.HasNotNullFlag(col.Flag):
+				if strict {
+					return nil, false, ddl.ErrInvalidUseOfNull
+				}
+				sc.AppendWarning(schemas.ErrWarnDataTruncated.GenByArgs(col.Name.O, sc.AffectedRows()+1))
+				v = schemas.GetZeroValue(col)
+				hasNulls = true
+			case converted[col] && !v.IsNull():
+				casted, err := schemas.CastValue(ctx, v, col)
+				if err != nil {
+					return nil, false, errors.Trace(err)
+				}
+				v = casted
+			}
+			newRow[i] = v
+		}
+		newRows = append(newRows, newRow)
+		sc.AddAffectedRows(1)
+	}
+	return newRows, hasNulls, nil
+}
+
+// renameForeignKeyColumn renames the column oldName of the foreign keys of
+// info to newName.
+func renameForeignKeyColumn(info *model.TableInfo, oldName, newName model.CIStr) {
+	for _, fk := range info.ForeignKeys {
+		for i, col := range fk.Cols {
+			if col.L == oldName.L {
+				fk.Cols[i] = newName
+			}
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/varsutil"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// modifyTestSchema keeps the rows of its tables, replacing them on
+// AlterTableColumns.
+type modifyTestSchema struct {
+	*viewTestSchema
+	rows testTableRows
+	// rebuilt tells whether the last change replaced the rows.
+	rebuilt bool
+}
+
+func (is *modifyTestSchema) AlterTableColumns(schema, table model.CIStr, tbl *model.TableInfo, rows [][]basic.Datum) error {
+	is.tables[table.L] = &viewTestTable{meta: tbl}
+	is.rebuilt = rows != nil
+	if rows != nil {
+		is.rows[table.L] = rows
+	}
+	return nil
+}
+
+// newModifyTestSchema returns the schema of
+//
+//	CREATE TABLE t (id INT PRIMARY KEY, a INT, b VARCHAR(50), KEY idx_b (b));
+//
+// holding the rows (1, 10, '7'), (2, NULL, 'x').
+func newModifyTestSchema() *modifyTestSchema {
+	t := newFKTestTable("t", "id", "a", "b")
+	t.Charset, t.Collate = mysql.UTF8Charset, mysql.UTF8DefaultCollation
+	t.Columns[0].Flag |= mysql.PriKeyFlag | mysql.NotNullFlag
+	t.Columns[2].FieldType = *basic.NewFieldType(mysql.TypeVarchar)
+	t.Columns[2].Flen = 50
+	t.Columns[2].Charset, t.Columns[2].Collate = mysql.UTF8Charset, mysql.UTF8DefaultCollation
+	t.Columns[2].Flag |= mysql.MultipleKeyFlag
+	t.Indices = []*model.IndexInfo{{
+		Name:    model.NewCIStr("idx_b"),
+		Columns: []*model.IndexColumn{{Name: model.NewCIStr("b"), Offset: 2, Length: basic.UnspecifiedLength}},
+		State:   model.StatePublic,
+	}}
+	return &modifyTestSchema{
+		viewTestSchema: newViewTestSchema(t),
+		rows: testTableRows{"t": {
+			basic.MakeDatums(int64(1), int64(10), "7"),
+			basic.MakeDatums(int64(2), nil, "x"),
+		}},
+	}
+}
+
+func execModifyColumn(t *testing.T, s *session, is *modifyTestSchema, sql string) error {
+	stmt, _, err := compileView(s, sql)
+	if err != nil {
+		t.Fatalf("%s: %v", sql, err)
+	}
+	alter := stmt.(*ast.AlterTableStmt)
+	return modifyColumns(s, is, is.rows, alter.Table, modifyColumnSpecs(alter))
+}
+
+func TestModifyColumn(t *testing.T) {
+	is := newModifyTestSchema()
+	s := newViewTestSession(t, is)
+	if err := varsutil.SetSessionSystemVar(s.sessionVars, variable.SQLModeVar, basic.NewStringDatum("STRICT_TRANS_TABLES")); err != nil {
+		t.Fatal(err)
+	}
+
+	// A longer VARCHAR only changes the metadata.
+	if err := execModifyColumn(t, s, is, "ALTER TABLE t MODIFY b VARCHAR(60) COMMENT 'bee'"); err != nil {
+		t.Fatal(err)
+	}
+	if b := is.tables["t"].Meta().Columns[2]; is.rebuilt || b.Flen != 60 || b.Comment != "bee" {
+		t.Fatalf("expect VARCHAR(60) without a rebuild, got %d rebuilt %v", b.Flen, is.rebuilt)
+	}
+
+	// INT to BIGINT keeps the values, a move rebuilds the rows.
+	if err := execModifyColumn(t, s, is, "ALTER TABLE t MODIFY a BIGINT AFTER b"); err != nil {
+		t.Fatal(err)
+	}
+	cols := is.tables["t"].Meta().Columns
+	if !is.rebuilt || cols[2].Name.L != "a" || cols[2].Tp != mysql.TypeLonglong || cols[1].Name.L != "b" {
+		t.Fatalf("expect a moved after b as BIGINT, got %v", cols)
+	}
+	if row := is.rows["t"][0]; row[1].GetString() != "7" || row[2].GetInt64() != 10 {
+		t.Fatalf("expect the values moved, got %v", row)
+	}
+	if idx := is.tables["t"].Meta().Indices[0].Columns[0]; idx.Offset != 1 {
+		t.Fatalf("expect the index on b at offset 1, got %d", idx.Offset)
+	}
+
+	// Narrowing to TINYINT fails on a value past its range in strict mode.
+	is.rows["t"][0][2] = basic.NewIntDatum(300)
+	err := execModifyColumn(t, s, is, "ALTER TABLE t MODIFY a TINYINT")
+	if code := errCode(err); code != mysql.ErrWarnDataOutOfRange {
+		t.Fatalf("expect error %d, got %v", mysql.ErrWarnDataOutOfRange, err)
+	}
+	if a := is.tables["t"].Meta().Columns[2]; a.Tp != mysql.TypeLonglong || is.rows["t"][0][2].GetInt64() != 300 {
+		t.Fatalf("expect the table unchanged, got %v", a)
+	}
+
+	// A value that isn't an integer fails the whole change in strict mode.
+	err = execModifyColumn(t, s, is, "ALTER TABLE t MODIFY b INT")
+	if code := errCode(err); code != mysql.ErrTruncatedWrongValueForField && code != mysql.WarnDataTruncated {
+		t.Fatalf("expect error 1265 or 1366, got %v", err)
+	}
+	if b := is.tables["t"].Meta().Columns[1]; b.Tp != mysql.TypeVarchar || is.rows["t"][1][1].GetString() != "x" {
+		t.Fatalf("expect the table unchanged, got %v", b)
+	}
+	// NULL can't go in a NOT NULL column in strict mode.
+	if err = execModifyColumn(t, s, is, "ALTER TABLE t MODIFY a BIGINT NOT NULL"); errCode(err) != mysql.ErrInvalidUseOfNull {
+		t.Fatalf("expect error %d, got %v", mysql.ErrInvalidUseOfNull, err)
+	}
+	// Keys can't be added by a modification, nor BLOB columns indexed
+	// without a length.
+	if err = execModifyColumn(t, s, is, "ALTER TABLE t MODIFY a BIGINT UNIQUE"); err == nil {
+		t.Fatal("expect UNIQUE rejected")
+	}
+	if err = execModifyColumn(t, s, is, "ALTER TABLE t MODIFY b TEXT"); errCode(err) != mysql.ErrBlobKeyWithoutLength {
+		t.Fatalf("expect error %d, got %v", mysql.ErrBlobKeyWithoutLength, err)
+	}
+
+	// Without a strict sql_mode the values are converted with warnings.
+	if err = varsutil.SetSessionSystemVar(s.sessionVars, variable.SQLModeVar, basic.NewStringDatum("")); err != nil {
+		t.Fatal(err)
+	}
+	if err = execModifyColumn(t, s, is, "ALTER TABLE t CHANGE b c INT, MODIFY a BIGINT NOT NULL"); err != nil {
+		t.Fatal(err)
+	}
+	if warns := s.sessionVars.StmtCtx.GetWarnings(); len(warns) != 2 {
+		t.Fatalf("expect 2 warnings, got %v", warns)
+	}
+	if row := is.rows["t"][1]; row[1].GetInt64() != 0 || row[2].GetInt64() != 0 {
+		t.Fatalf("expect 'x' and NULL converted to 0, got %v", row)
+	}
+	if row := is.rows["t"][0]; row[1].GetInt64() != 7 {
+		t.Fatalf("expect '7' converted to 7, got %v", row)
+	}
+	if idx := is.tables["t"].Meta().Indices[0].Columns[0]; idx.Name.L != "c" {
+		t.Fatalf("expect the index on c, got %s", idx.Name)
+	}
+
+	// DESCRIBE shows the new definition.
+	_, p, err := compileView(s, "DESCRIBE t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, _, err := showRows(s, is, p.(*plan.Show))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || rows[1][0].GetString() != "c" || rows[1][1].GetString() != "int(11)" ||
+		rows[2][1].GetString() != "bigint(20)" || rows[2][2].GetString() != "NO" {
+		t.Fatalf("unexpected columns %v", rows)
+	}
+	if cols := schemas.ShowColumnsRows(is.tables["t"], true, "c"); len(cols) != 1 || len(cols[0]) != 9 {
+		t.Fatalf("expect the full description of c, got %v", cols)
+	}
+}
@@ -0,0 +1,176 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// newNameTestSession returns a session on
+//
+//	CREATE TABLE users (id INT, name INT);
+//	CREATE TABLE orders (id INT, user_id INT);
+func newNameTestSession(t *testing.T) *session {
+	return newViewTestSession(t, newViewTestSchema(
+		newFKTestTable("users", "id", "name"),
+		newFKTestTable("orders", "id", "user_id")))
+}
+
+func TestQualifiedColumnNames(t *testing.T) {
+	s := newNameTestSession(t)
+	tests := []struct {
+		sql    string
+		tables []string
+	}{
+		{"SELECT id FROM users u", []string{"u"}},
+		{"SELECT u.id FROM users u", []string{"u"}},
+		{"SELECT users.id FROM users", []string{"users"}},
+		{"SELECT test.users.id FROM test.users", []string{"users"}},
+		{"SELECT u.id, o.id FROM users u JOIN orders o ON u.id = o.user_id", []string{"u", "o"}},
+		{"SELECT orders.id, users.id FROM test.users, orders WHERE users.id = orders.user_id", []string{"orders", "users"}},
+		{"SELECT name, user_id FROM users u JOIN orders o ON u.id = o.user_id ORDER BY o.id", []string{"u", "o"}},
+	}
+	for _, tt := range tests {
+		_, p, err := compileView(s, tt.sql)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.sql, err)
+		}
+		cols := p.Schema().Columns
+		if len(cols) != len(tt.tables) {
+			t.Fatalf("%s: unexpected columns %v", tt.sql, cols)
+		}
+		for i, col := range cols {
+			if col.TblName.L != tt.tables[i] {
+				t.Fatalf("%s: expect column %d from %s, got %s", tt.sql, i, tt.tables[i], col)
+			}
+		}
+	}
+}
+
+func TestAmbiguousColumnNames(t *testing.T) {
+	s := newNameTestSession(t)
+	tests := []struct {
+		sql    string
+		code   uint16
+		clause string
+	}{
+		{"SELECT id FROM users u, orders o", mysql.ErrNonUniq, "field list"},
+		{"SELECT name FROM users u JOIN orders o ON u.id = o.user_id WHERE id = 1", mysql.ErrNonUniq, "where clause"},
+		{"SELECT u.name FROM users u JOIN orders o ON id = o.user_id", mysql.ErrNonUniq, "on clause"},
+		{"SELECT u.name FROM users u JOIN orders o ON u.id = o.user_id ORDER BY id", mysql.ErrNonUniq, "order clause"},
+		{"SELECT u.name FROM users u JOIN orders o ON u.id = o.user_id GROUP BY id", mysql.ErrNonUniq, "group statement"},
+		// An alias hides the name of its table.
+		{"SELECT users.id FROM users u", mysql.ErrBadField, "field list"},
+		{"SELECT u.name FROM users u WHERE x.id = 1", mysql.ErrBadField, "where clause"},
+		{"SELECT * FROM users u JOIN orders u", mysql.ErrNonuniqTable, "'u'"},
+		{"SELECT * FROM users JOIN test.users", mysql.ErrNonuniqTable, "'users'"},
+	}
+	for _, tt := range tests {
+		_, _, err := compileView(s, tt.sql)
+		if errCode(err) != tt.code || !strings.Contains(err.Error(), tt.clause) {
+			t.Fatalf("%s: expect error %d in %s, got %v", tt.sql, tt.code, tt.clause, err)
+		}
+	}
+}
+
+func TestTableNameCase(t *testing.T) {
+	defer model.SetLowerCaseTableNames(model.LowerCaseTableNamesSensitive)
+	s := newNameTestSession(t)
+	sqls := []string{
+		"SELECT USERS.id FROM users",
+		"SELECT U.id FROM users u",
+		"SELECT TEST.users.id FROM test.users",
+	}
+	for _, mode := range []int{model.LowerCaseTableNamesSensitive, model.LowerCaseTableNamesLower, model.LowerCaseTableNamesCompare} {
+		if err := model.SetLowerCaseTableNames(mode); err != nil {
+			t.Fatal(err)
+		}
+		for _, sql := range sqls {
+			_, _, err := compileView(s, sql)
+			// With lower_case_table_names=0 the qualifiers compare in case.
+			if mode == model.LowerCaseTableNamesSensitive {
+				if errCode(err) != mysql.ErrBadField {
+					t.Fatalf("%s with mode 0: expect error %d, got %v", sql, mysql.ErrBadField, err)
+				}
+			} else if err != nil {
+				t.Fatalf("%s with mode %d: %v", sql, mode, err)
+			}
+		}
+	}
+	if err := model.SetLowerCaseTableNames(3); err == nil {
+		t.Fatal("expect lower_case_table_names 3 to be refused")
+	}
+}
+
+func TestWildcardAndAliases(t *testing.T) {
+	s := newNameTestSession(t)
+	tests := []struct {
+		sql     string
+		columns string
+	}{
+		// t.* is the columns of t only, in their order.
+		{"SELECT o.*, u.name FROM users u JOIN orders o ON u.id = o.user_id", "o.id,o.user_id,u.name"},
+		{"SELECT u.*, o.* FROM users u JOIN orders o ON u.id = o.user_id", "u.id,u.name,o.id,o.user_id"},
+		{"SELECT test.users.* FROM users", "users.id,users.name"},
+		// The result columns are named by the aliases, and may repeat.
+		{"SELECT id AS user_id FROM users ORDER BY user_id", "users.user_id"},
+		{"SELECT id, id FROM users", "users.id,users.id"},
+		{"SELECT id AS a, name AS a FROM users", "users.a,users.a"},
+	}
+	for _, tt := range tests {
+		stmt, p, err := compileView(s, tt.sql)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.sql, err)
+		}
+		var names []string
+		for i, field := range ResultColumns(stmt, p) {
+			names = append(names, p.Schema().Columns[i].TblName.L+"."+field.Name)
+		}
+		if got := strings.Join(names, ","); got != tt.columns {
+			t.Fatalf("%s: expect columns %s, got %s", tt.sql, tt.columns, got)
+		}
+	}
+
+	errTests := []struct {
+		sql    string
+		code   uint16
+		clause string
+	}{
+		{"SELECT x.* FROM users u", mysql.ErrBadTable, "'x'"},
+		{"SELECT id AS a, name AS a FROM users ORDER BY a", mysql.ErrNonUniq, "'a' in order clause"},
+		{"SELECT id AS k FROM users WHERE k = 1", mysql.ErrBadField, "where clause"},
+	}
+	for _, tt := range errTests {
+		_, _, err := compileView(s, tt.sql)
+		if errCode(err) != tt.code || !strings.Contains(err.Error(), tt.clause) {
+			t.Fatalf("%s: expect error %d with %s, got %v", tt.sql, tt.code, tt.clause, err)
+		}
+	}
+}
+
+func TestAliasPrecedence(t *testing.T) {
+	s := newViewTestSession(t, newViewTestSchema())
+	const u = "(SELECT 1 id, 'b' name UNION ALL SELECT 2, 'a' UNION ALL SELECT 3, 'c') u"
+	for _, tt := range []struct {
+		sql  string
+		rows []string
+	}{
+		{"SELECT id AS user_id FROM " + u + " ORDER BY user_id DESC", []string{"3", "2", "1"}},
+		{"SELECT id AS k FROM " + u + " ORDER BY -k", []string{"3", "2", "1"}},
+		// In ORDER BY an alias shadows the column of a table...
+		{"SELECT name AS id FROM " + u + " ORDER BY id", []string{"a", "b", "c"}},
+		// ...which stays reachable qualified.
+		{"SELECT name AS id FROM " + u + " ORDER BY u.id DESC", []string{"c", "a", "b"}},
+		// In GROUP BY and HAVING the column of a table comes first.
+		{"SELECT city AS country, SUM(amount) FROM " + salesSQL + " GROUP BY country HAVING country = 'CN'",
+			[]string{"Shanghai,15"}},
+		{"SELECT country AS c, SUM(amount) AS total FROM " + salesSQL + " GROUP BY c HAVING total > 14",
+			[]string{"CN,15"}},
+	} {
+		if got := aggregateTestRows(t, s, tt.sql); strings.Join(got, "\n") != strings.Join(tt.rows, "\n") {
+			t.Fatalf("%s: expect rows\n%s\ngot\n%s", tt.sql, strings.Join(tt.rows, "\n"), strings.Join(got, "\n"))
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package engine
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/varsutil"
+)
+
+/**
+打开的表
+
+执行语句时，语句用到的表先被打开：放入表缓存，使用计数 In_use 加一，语句结束时减一。
+表留在缓存中，之后的语句直接使用；缓存中的表超过 table_open_cache 时，关闭不在使用的表。
+DROP、RENAME、ALTER、TRUNCATE 和增删索引的语句执行期间持有表名的锁（Name_locked），
+结束时关闭这些表，它们的定义已经改变。
+
+SHOW OPEN TABLES [FROM db] 列出缓存中的表。
+**/
+
+// openTable is a table of the table cache.
+type openTable struct {
+	db, name string
+	// inUse is the number of statements using the table, nameLocked the
+	// number of those changing its definition.
+	inUse      int
+	nameLocked int
+}
+
+// tableCache is the cache of the open tables, by lower case db.table.
+type tableCache struct {
+	sync.Mutex
+	tables map[string]*openTable
+}
+
+var openTables = &tableCache{tables: make(map[string]*openTable)}
+
+// changesTableDefinition reports whether stmt changes the definition of the
+// tables it names, so that they are closed once it is done.
+func changesTableDefinition(stmt ast.StmtNode) bool {
+	switch stmt.(type) {
+	case *ast.DropTableStmt, *ast.RenameTableStmt, *ast.AlterTableStmt, *ast.TruncateTableStmt,
+		*ast.CreateIndexStmt, *ast.DropIndexStmt:
+		return true
+	}
+	return false
+}
+
+// tableNameCollector collects the tables of is a statement names.
+type tableNameCollector struct {
+	is     schemas.InfoSchema
+	tables []*ast.TableName
+}
+
+func (c *tableNameCollector) Enter(in ast.Node) (ast.Node, bool) {
+	if tn, ok := in.(*ast.TableName); ok {
+		if tbl, err := c.is.TableByName(tn.Schema, tn.Name); err == nil && tbl != nil {
+			c.tables = append(c.tables, tn)
+		}
+	}
+	return in, false
+}
+
+func (c *tableNameCollector) Leave(in ast.Node) (ast.Node, bool) {
+	return in, true
+}
+
+// open opens the tables of is stmt uses until the returned function is
+// called at the end of the statement.
+func (c *tableCache) open(vars *variable.SessionVars, is schemas.InfoSchema, stmt ast.StmtNode) func() {
+	collector := &tableNameCollector{is: is}
+	stmt.Accept(collector)
+	if len(collector.tables) == 0 {
+		return func() {}
+	}
+	locks := changesTableDefinition(stmt)
+	c.Lock()
+	keys := make([]string, 0, len(collector.tables))
+	seen := make(map[string]bool, len(collector.tables))
+	for _, tn := range collector.tables {
+		key := tn.Schema.L + "." + tn.Name.L
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		t, ok := c.tables[key]
+		if !ok {
+			t = &openTable{db: tn.Schema.O, name: tn.Name.O}
+			c.tables[key] = t
+		}
+		t.inUse++
+		if locks {
+			t.nameLocked++
+		}
+		keys = append(keys, key)
+	}
+	c.Unlock()
+	return func() {
+		c.Lock()
+		defer c.Unlock()
+		for _, key := range keys {
+			t := c.tables[key]
+			t.inUse--
+			if locks {
+				t.nameLocked--
+				if t.inUse == 0 {
+					delete(c.tables, key)
+				}
+			}
+		}
+		c.evict(tableOpenCache(vars))
+	}
+}
+
+// nameLocked reports whether a statement changing the definition of
+// db.table holds its name lock.
+func (c *tableCache) nameLocked(db, table string) bool {
+	c.Lock()
+	defer c.Unlock()
+	t, ok := c.tables[strings.ToLower(db)+"."+strings.ToLower(table)]
+	return ok && t.nameLocked > 0
+}
+
+// evict closes the tables not in use while there are more than size.
+func (c *tableCache) evict(size int) {
+	for key, t := range c.tables {
+		if len(c.tables) <= size {
+			return
+		}
+		if t.inUse == 0 {
+			delete(c.tables, key)
+		}
+	}
+}
+
+// tableOpenCache returns the value of table_open_cache.
+func tableOpenCache(vars *variable.SessionVars) int {
+	value, err := varsutil.GetGlobalSystemVar(vars, variable.TableOpenCache)
+	if err != nil {
+		return 0
+	}
+	size, _ := strconv.Atoi(value)
+	return size
+}
+
+// rows returns the rows of SHOW OPEN TABLES FROM db, of every database
+// when db is empty.
+func (c *tableCache) rows(db model.CIStr) [][]basic.Datum {
+	c.Lock()
+	defer c.Unlock()
+	var rows [][]basic.Datum
+	for _, t := range c.tables {
+		if db.L != "" && strings.ToLower(t.db) != db.L {
+			continue
+		}
+		nameLocked := 0
+		if t.nameLocked > 0 {
+			nameLocked = 1
+		}
+		rows = append(rows, basic.MakeDatums(t.db, t.name, t.inUse, nameLocked))
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i][0].GetString() != rows[j][0].GetString() {
+			return rows[i][0].GetString() < rows[j][0].GetString()
+		}
+		return rows[i][1].GetString() < rows[j][1].GetString()
+	})
+	return rows
+}
@@ -0,0 +1,82 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+func TestOptimizerIndexHints(t *testing.T) {
+	s := newViewTestSession(t, newViewTestSchema(newTraceTestTable()))
+	tests := []struct {
+		sql  string
+		scan string
+	}{
+		// The primary key is cheaper, unless the index is forced.
+		{"SELECT * FROM t WHERE id = 1 AND a = 2", "Table(t)"},
+		{"SELECT /*+ INDEX(t ia) */ * FROM t WHERE id = 1 AND a = 2", "Index(t.ia)"},
+		{"SELECT /*+ INDEX(t) */ * FROM t WHERE id = 1 AND a = 2", "Index(t.ia)"},
+		{"SELECT /*+ INDEX(x ia) */ * FROM t x WHERE x.id = 1 AND x.a = 2", "Index(t.ia)"},
+		{"SELECT /*+ MAX_EXECUTION_TIME(1000) index(`t` `ia`) */ * FROM t WHERE id = 1 AND a = 2", "Index(t.ia)"},
+		// A hint names the table by its alias, and ordinary comments are ignored.
+		{"SELECT /*+ INDEX(t ia) */ * FROM t x WHERE x.id = 1 AND x.a = 2", "Table(t)"},
+		{"/* mysql-connector-java-8.0.28 */ SELECT /* INDEX(t ia) */ * FROM t WHERE id = 1 AND a = 2", "Table(t)"},
+		{"SELECT * FROM t /*+ INDEX(t ia) */ WHERE id = 1 AND a = 2", "Table(t)"},
+		// The index is cheaper, unless it is avoided.
+		{"SELECT * FROM t WHERE a = 2", "Index(t.ia)"},
+		{"SELECT /*+ NO_INDEX(t ia) */ * FROM t WHERE a = 2", "Table(t)"},
+		{"SELECT /*+ NO_INDEX(t) */ * FROM t WHERE a = 2", "Table(t)"},
+	}
+	for _, tt := range tests {
+		_, p, err := compileView(s, tt.sql)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.sql, err)
+		}
+		if str := plan.ToString(p); !strings.Contains(str, tt.scan) {
+			t.Fatalf("%s: expect %s, got %s", tt.sql, tt.scan, str)
+		}
+	}
+}
+
+func TestIndexHintClauses(t *testing.T) {
+	s := newViewTestSession(t, newViewTestSchema(newTraceTestTable()))
+	tests := []struct {
+		sql  string
+		scan string
+	}{
+		// FORCE reads by the index even if the primary key is cheaper.
+		{"SELECT * FROM t FORCE INDEX (ia) WHERE id = 1 AND a = 2", "Index(t.ia)"},
+		{"SELECT * FROM t x FORCE INDEX FOR JOIN (ia) WHERE x.id = 1", "Table(t)"},
+		{"SELECT * FROM t x FORCE INDEX (ia) WHERE x.id = 1", "Index(t.ia)"},
+		{"SELECT * FROM t FORCE INDEX (PRIMARY) WHERE a = 2", "Table(t)"},
+		// USE only suggests the index.
+		{"SELECT * FROM t USE INDEX (ia) WHERE id = 1 AND a = 2", "Table(t)"},
+		{"SELECT * FROM t USE INDEX (ia) WHERE a = 2", "Index(t.ia)"},
+		{"SELECT * FROM t USE INDEX () WHERE a = 2", "Table(t)"},
+		// IGNORE removes the index.
+		{"SELECT * FROM t IGNORE INDEX (ia) WHERE a = 2", "Table(t)"},
+		{"SELECT * FROM t IGNORE KEY (ia) FORCE INDEX (ia) WHERE id = 1", "Table(t)"},
+	}
+	for _, tt := range tests {
+		_, p, err := compileView(s, tt.sql)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.sql, err)
+		}
+		if str := plan.ToString(p); !strings.Contains(str, tt.scan) {
+			t.Fatalf("%s: expect %s, got %s", tt.sql, tt.scan, str)
+		}
+	}
+
+	for _, sql := range []string{
+		"SELECT * FROM t FORCE INDEX (nope) WHERE a = 2",
+		"SELECT * FROM t USE INDEX (ia, nope) WHERE a = 2",
+		"SELECT * FROM t IGNORE INDEX (nope)",
+		"SELECT * FROM t JOIN t s USE INDEX (nope) ON t.a = s.a",
+	} {
+		if _, _, err := compileView(s, sql); errCode(err) != mysql.ErrKeyDoesNotExits {
+			t.Fatalf("%s: expect error %d, got %v", sql, mysql.ErrKeyDoesNotExits, err)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package engine
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/expression"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/varsutil"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// newTraceTestTable returns
+//
+//	CREATE TABLE t (id INT PRIMARY KEY, a INT, b INT, INDEX ia (a))
+func newTraceTestTable() *model.TableInfo {
+	tbl := newFKTestTable("t", "id", "a", "b")
+	tbl.ID, tbl.PKIsHandle = 1, true
+	for i, col := range tbl.Columns {
+		col.ID = int64(i + 1)
+	}
+	tbl.Columns[0].Flag = mysql.PriKeyFlag | mysql.NotNullFlag
+	tbl.Indices = []*model.IndexInfo{{
+		ID:      1,
+		Name:    model.NewCIStr("ia"),
+		Table:   tbl.Name,
+		Columns: []*model.IndexColumn{{Name: model.NewCIStr("a"), Offset: 1, Length: basic.UnspecifiedLength}},
+		State:   model.StatePublic,
+	}}
+	return tbl
+}
+
+func setTraceVar(t *testing.T, s *session, name, value string) {
+	if err := varsutil.SetSessionSystemVar(s.sessionVars, name, basic.NewStringDatum(value)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type traceTestPath struct {
+	AccessType       string   `json:"access_type"`
+	Index            string   `json:"index"`
+	AccessConditions []string `json:"access_conditions"`
+	Selectivity      float64  `json:"selectivity"`
+	Chosen           bool     `json:"chosen"`
+}
+
+type traceTestTrace struct {
+	Rewrites []struct {
+		Rule string `json:"rule"`
+	} `json:"logical_rewrites"`
+	AccessPaths []traceTestPath `json:"considered_access_paths"`
+	JoinOrder   []string        `json:"join_order"`
+	ChosenPlan  string          `json:"chosen_plan"`
+}
+
+func TestOptimizerTrace(t *testing.T) {
+	s := newViewTestSession(t, newViewTestSchema(newTraceTestTable()))
+	const sql = "SELECT id FROM t WHERE a = 1"
+	if _, _, err := compileView(s, sql); err != nil {
+		t.Fatal(err)
+	}
+	if s.sessionVars.LastOptimizerTrace != nil {
+		t.Fatal("expect no trace when optimizer_trace is disabled")
+	}
+
+	setTraceVar(t, s, "optimizer_trace", "enabled=on")
+	if v := s.sessionVars.Systems["optimizer_trace"]; v != "enabled=on,one_line=off" {
+		t.Fatalf("unexpected optimizer_trace %s", v)
+	}
+	if _, _, err := compileView(s, sql); err != nil {
+		t.Fatal(err)
+	}
+	row := s.sessionVars.LastOptimizerTrace
+	if row == nil || row.Query != sql || row.MissingBytesBeyondMaxMemSize != 0 {
+		t.Fatalf("unexpected trace %+v", row)
+	}
+	var trace traceTestTrace
+	if err := json.Unmarshal([]byte(row.Trace), &trace); err != nil {
+		t.Fatal(err)
+	}
+	var rules []string
+	for _, r := range trace.Rewrites {
+		rules = append(rules, r.Rule)
+	}
+	if len(rules) < 2 || rules[0] != "column_pruning" || rules[len(rules)-1] != "predicate_push_down" {
+		t.Fatalf("unexpected rewrites %v", rules)
+	}
+	var chosen []traceTestPath
+	var index *traceTestPath
+	for i, path := range trace.AccessPaths {
+		if path.Chosen {
+			chosen = append(chosen, path)
+		}
+		if path.AccessType == "index_scan" {
+			index = &trace.AccessPaths[i]
+		}
+	}
+	if index == nil || index.Index != "ia" || len(index.AccessConditions) != 1 {
+		t.Fatalf("expect index ia to be considered with an access condition, got %+v", trace.AccessPaths)
+	}
+	if len(chosen) != 1 {
+		t.Fatalf("expect one chosen access path, got %+v", trace.AccessPaths)
+	}
+	if len(trace.JoinOrder) != 1 || trace.JoinOrder[0] != "t" || trace.ChosenPlan == "" {
+		t.Fatalf("unexpected join order %v and plan %q", trace.JoinOrder, trace.ChosenPlan)
+	}
+
+	// The same statement is traced the same way.
+	first := row.Trace
+	if _, _, err := compileView(s, sql); err != nil {
+		t.Fatal(err)
+	}
+	if s.sessionVars.LastOptimizerTrace.Trace != first {
+		t.Fatalf("expect a deterministic trace, got\n%s\nthen\n%s", first, s.sessionVars.LastOptimizerTrace.Trace)
+	}
+
+	setTraceVar(t, s, "optimizer_trace_max_mem_size", "10")
+	if _, _, err := compileView(s, sql); err != nil {
+		t.Fatal(err)
+	}
+	row = s.sessionVars.LastOptimizerTrace
+	if len(row.Trace) != 10 || row.MissingBytesBeyondMaxMemSize != len(first)-10 {
+		t.Fatalf("expect the trace to be cut to 10 bytes, got %d missing %d", len(row.Trace), row.MissingBytesBeyondMaxMemSize)
+	}
+}
+
+func TestSelectOptimizerTrace(t *testing.T) {
+	s := newViewTestSession(t, newViewTestSchema(newTraceTestTable()))
+	const sql = "SELECT * FROM information_schema.optimizer_trace"
+	_, p, err := compileView(s, sql)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cols := p.Schema().Columns
+	if len(cols) != 4 || cols[0].ColName.O != "QUERY" || cols[3].ColName.O != "INSUFFICIENT_PRIVILEGES" {
+		t.Fatalf("unexpected columns %v", cols)
+	}
+	if _, ok := p.(*plan.TableDual); !ok {
+		t.Fatalf("expect no row before a statement is traced, got %s", plan.ToString(p))
+	}
+
+	setTraceVar(t, s, "optimizer_trace", "enabled=on,one_line=on")
+	if _, _, err = compileView(s, "SELECT b FROM t"); err != nil {
+		t.Fatal(err)
+	}
+	if _, p, err = compileView(s, "SELECT query, trace FROM information_schema.optimizer_trace"); err != nil {
+		t.Fatal(err)
+	}
+	proj, ok := p.(*plan.Projection)
+	if !ok {
+		t.Fatalf("expect the trace row, got %s", plan.ToString(p))
+	}
+	query, _ := proj.Exprs[0].(*expression.Constant).Value.ToString()
+	trace, _ := proj.Exprs[1].(*expression.Constant).Value.ToString()
+	if query != "SELECT b FROM t" || trace != s.sessionVars.LastOptimizerTrace.Trace {
+		t.Fatalf("unexpected trace row %q %q", query, trace)
+	}
+	// Reading the trace doesn't replace it.
+	if s.sessionVars.LastOptimizerTrace.Query != "SELECT b FROM t" {
+		t.Fatalf("expect the trace to be kept, got %q", s.sessionVars.LastOptimizerTrace.Query)
+	}
+}
+
+func TestSetOptimizerTrace(t *testing.T) {
+	s := newViewTestSession(t, newViewTestSchema())
+	setTraceVar(t, s, "optimizer_trace", "one_line=on")
+	setTraceVar(t, s, "optimizer_trace", "enabled=on")
+	if !s.sessionVars.EnableOptimizerTrace || !s.sessionVars.OptimizerTraceOneLine {
+		t.Fatal("expect the flags left out to keep their value")
+	}
+	setTraceVar(t, s, "optimizer_trace", "default")
+	if v := s.sessionVars.Systems["optimizer_trace"]; v != "enabled=off,one_line=off" {
+		t.Fatalf("unexpected optimizer_trace %s", v)
+	}
+	for _, value := range []string{"enabled", "enabled=yes", "verbose=on"} {
+		err := varsutil.SetSessionSystemVar(s.sessionVars, "optimizer_trace", basic.NewStringDatum(value))
+		if errCode(err) != mysql.ErrWrongValueForVar {
+			t.Fatalf("%s: expect error %d, got %v", value, mysql.ErrWrongValueForVar, err)
+		}
+	}
+}
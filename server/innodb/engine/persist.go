@@ -0,0 +1,199 @@
+package engine
+
+import (
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/juju/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/varsutil"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+/**
+SET PERSIST 和配置文件重新加载
+
+SET PERSIST var = value 和 SET GLOBAL 一样设置全局变量，同时把当前的全局值写入数据目录下
+的 mysqld-auto.cnf（JSON 格式，和 MySQL 8.0 相同）；启动时在配置文件之上再设置这些变量。
+RESET PERSIST [var] 只删除文件中的记录，不改变当前的值。
+
+收到 SIGHUP 或执行 FLUSH CONFIG 时重新读取配置文件，值有变化的配置项中，可以在运行时
+设置的全局变量用 SET GLOBAL 的方式生效，其余的（端口、目录等）需要重启，跳过并给出警告。
+被 SET PERSIST 持久化的变量以 mysqld-auto.cnf 为准，也跳过。
+**/
+
+// initPersistedVariables reads the variables persisted in the data
+// directory and sets them, on top of the config file.
+func (srv *XMySQLEngine) initPersistedVariables() {
+	path := filepath.Join(srv.conf.DataDir, variable.PersistFilename)
+	p, err := variable.LoadPersistedVariables(path)
+	if err != nil {
+		log.Errorf("读取 %s 失败，忽略其中持久化的变量: %v", path, err)
+		p = variable.NewPersistedVariables(path)
+	}
+	variable.RegisterPersistedVariables(p)
+	vars := variable.NewSessionVars()
+	for _, v := range p.Variables() {
+		if err = varsutil.SetGlobalSystemVar(vars, v.Name, basic.NewStringDatum(v.Value)); err != nil {
+			log.Warnf("持久化的变量 %s = %s 无法设置: %v", v.Name, v.Value, err)
+		}
+	}
+}
+
+// persistVariable writes the global value of the variable name to the
+// persisted config file.
+func persistVariable(vars *variable.SessionVars, name string) error {
+	p := variable.GetPersistedVariables()
+	if p == nil {
+		return errors.Errorf("no persisted config file to write %s to", name)
+	}
+	value, err := varsutil.GetGlobalSystemVar(vars, name)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	var user, host string
+	if vars.User != nil {
+		user, host = vars.User.Username, vars.User.Hostname
+	}
+	return errors.Trace(p.Set(name, value, user, host))
+}
+
+// resetPersist executes RESET PERSIST [[IF EXISTS] name].
+func resetPersist(ctx context.Context, stmt *ast.ResetPersistStmt) error {
+	p := variable.GetPersistedVariables()
+	if p == nil {
+		if stmt.Name == "" {
+			return nil
+		}
+		return variable.ErrVarDoesNotExist.GenByArgs(stmt.Name)
+	}
+	found, err := p.Reset(stmt.Name)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !found {
+		err = variable.ErrVarDoesNotExist.GenByArgs(stmt.Name)
+		if !stmt.IfExists {
+			return err
+		}
+		ctx.GetSessionVars().StmtCtx.AppendWarning(err)
+	}
+	return nil
+}
+
+// ReloadConfig reads the config file again and sets the global variables
+// whose setting changed. It returns the changed settings it skipped, those
+// that need a restart and those persisted with SET PERSIST.
+func (srv *XMySQLEngine) ReloadConfig() ([]string, error) {
+	changed, err := srv.conf.ReloadSettings()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	keys := make([]string, 0, len(changed))
+	for key := range changed {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	p := variable.GetPersistedVariables()
+	vars := variable.NewSessionVars()
+	var skipped []string
+	for _, key := range keys {
+		name := strings.Replace(strings.ToLower(key), "-", "_", -1)
+		if p != nil && p.Has(name) {
+			skipped = append(skipped, key)
+			continue
+		}
+		sv := variable.GetSysVar(name)
+		if sv == nil || sv.Scope&variable.ScopeGlobal == 0 {
+			skipped = append(skipped, key)
+			continue
+		}
+		if err = varsutil.SetGlobalSystemVar(vars, name, basic.NewStringDatum(changed[key])); err != nil {
+			log.Warnf("配置项 %s = %s 无法生效: %v", key, changed[key], err)
+			skipped = append(skipped, key)
+			continue
+		}
+		log.Infof("配置项 %s 改为 %s", key, changed[key])
+	}
+	if len(skipped) > 0 {
+		log.Warnf("配置项 %s 需要重启才能生效", strings.Join(skipped, ", "))
+	}
+	return skipped, nil
+}
+
+// flushConfig executes FLUSH CONFIG, warning about the settings that
+// didn't take effect.
+func (srv *XMySQLEngine) flushConfig(ctx context.Context) error {
+	skipped, err := srv.ReloadConfig()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if len(skipped) > 0 {
+		ctx.GetSessionVars().StmtCtx.AppendWarning(ErrConfigNotReloaded.GenByArgs(strings.Join(skipped, ", ")))
+	}
+	return nil
+}
+
+// logLevels are the levels of the log for log_error_verbosity 1, 2 and 3.
+var logLevels = []log.Level{log.ErrorLevel, log.WarnLevel, log.InfoLevel}
+
+// initLogErrorVerbosity lets SET GLOBAL log_error_verbosity change the
+// level of the log: errors only, warnings too, or notes too.
+func (srv *XMySQLEngine) initLogErrorVerbosity() {
+	sv := variable.GetSysVar(variable.LogErrorVerbosity)
+	variable.RegisterSysVar(sv, mysql.TypeLonglong, func(*variable.SessionVars) (string, error) {
+		level := log.GetLevel()
+		for i, l := range logLevels {
+			if level <= l {
+				return strconv.Itoa(i + 1), nil
+			}
+		}
+		return strconv.Itoa(len(logLevels)), nil
+	})
+	variable.RegisterSysVarSetter(variable.LogErrorVerbosity, func(_ *variable.SessionVars, value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > len(logLevels) {
+			return variable.ErrWrongValueForVar.GenByArgs(variable.LogErrorVerbosity, value)
+		}
+		log.SetLevel(logLevels[n-1])
+		return nil
+	})
+}
+
+func init() {
+	registerStmtHandler(&ast.ResetPersistStmt{}, &stmtHandler{name: "reset persist", handle: (*XMySQLEngine).execResetPersist})
+	registerStmtHandler(&ast.FlushStmt{}, &stmtHandler{
+		name: "flush config",
+		match: func(stmt ast.StmtNode) bool {
+			return stmt.(*ast.FlushStmt).Tp == ast.FlushConfig
+		},
+		handle: (*XMySQLEngine).execFlushConfig,
+	})
+}
+
+// execResetPersist runs a RESET PERSIST.
+func (srv *XMySQLEngine) execResetPersist(session innodb.MySQLServerSession, stmt ast.StmtNode, p plan.Plan) {
+	x := stmt.(*ast.ResetPersistStmt)
+	if err := resetPersist(session, x); err != nil {
+		session.SendError(toSQLError(err))
+		return
+	}
+	session.SendOK()
+}
+
+// execFlushConfig runs a FLUSH CONFIG.
+func (srv *XMySQLEngine) execFlushConfig(session innodb.MySQLServerSession, stmt ast.StmtNode, p plan.Plan) {
+	if err := srv.flushConfig(session); err != nil {
+		session.SendError(toSQLError(err))
+		return
+	}
+	session.SendOK()
+}
@@ -0,0 +1,113 @@
+package engine
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/conf"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/varsutil"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+	"gopkg.in/ini.v1"
+)
+
+func execSet(t *testing.T, s *session, sql string) error {
+	stmt, p, err := compileView(s, sql)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reset, ok := stmt.(*ast.ResetPersistStmt); ok {
+		return resetPersist(s, reset)
+	}
+	return setVariables(s, p.(*plan.Set))
+}
+
+func TestSetPersist(t *testing.T) {
+	dir, err := ioutil.TempDir("", "datadir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer variable.RegisterPersistedVariables(nil)
+	defer func(value string) { variable.SysVars["max_connections"].Value = value }(variable.SysVars["max_connections"].Value)
+
+	cfg := conf.NewCfg()
+	cfg.DataDir = dir
+	srv := &XMySQLEngine{conf: cfg}
+	srv.initPersistedVariables()
+	s := newViewTestSession(t, newViewTestSchema())
+
+	if err = execSet(t, s, "SET PERSIST max_connections = 200"); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := varsutil.GetGlobalSystemVar(s.sessionVars, "max_connections"); value != "200" {
+		t.Fatalf("expect the global value set, got %s", value)
+	}
+	if err = execSet(t, s, "SET @@persist.max_connections = 300"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A restart reads the persisted value over the default.
+	variable.SysVars["max_connections"].Value = "151"
+	srv.initPersistedVariables()
+	if value, _ := varsutil.GetGlobalSystemVar(s.sessionVars, "max_connections"); value != "300" {
+		t.Fatalf("expect the persisted value set at startup, got %s", value)
+	}
+	rows := schemas.PersistedVariablesRows(variable.GetPersistedVariables())
+	if len(rows) != 1 || rows[0][0].GetString() != "max_connections" || rows[0][1].GetString() != "300" {
+		t.Fatalf("expect max_connections in persisted_variables, got %v", rows)
+	}
+
+	// The config file no longer overrides a persisted variable.
+	path := filepath.Join(dir, "my.ini")
+	if err = ioutil.WriteFile(path, []byte("[mysqld]\nport = 3306\nmax_connections = 100\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Raw, err = ini.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	cfg.ConfigFile = path
+	if err = ioutil.WriteFile(path, []byte("[mysqld]\nport = 3307\nmax_connections = 120\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	skipped, err := srv.ReloadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(skipped, []string{"max_connections", "port"}) {
+		t.Fatalf("expect max_connections and port skipped, got %v", skipped)
+	}
+
+	if err = execSet(t, s, "RESET PERSIST max_connections"); err != nil {
+		t.Fatal(err)
+	}
+	if variable.GetPersistedVariables().Has("max_connections") {
+		t.Fatal("expect max_connections no longer persisted")
+	}
+	if err = execSet(t, s, "RESET PERSIST max_connections"); errCode(err) != mysql.ErrVarDoesNotExist {
+		t.Fatalf("expect error %d, got %v", mysql.ErrVarDoesNotExist, err)
+	}
+	if err = execSet(t, s, "RESET PERSIST IF EXISTS max_connections"); err != nil {
+		t.Fatal(err)
+	}
+	if s.sessionVars.StmtCtx.WarningCount() != 1 {
+		t.Fatal("expect a warning for RESET PERSIST IF EXISTS")
+	}
+
+	// Once reset, a changed setting in the config file takes effect.
+	if err = ioutil.WriteFile(path, []byte("[mysqld]\nport = 3307\nmax_connections = 140\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if skipped, err = srv.ReloadConfig(); err != nil || len(skipped) != 0 {
+		t.Fatalf("expect nothing skipped, got %v %v", skipped, err)
+	}
+	if value, _ := varsutil.GetGlobalSystemVar(s.sessionVars, "max_connections"); value != "140" {
+		t.Fatalf("expect max_connections reloaded, got %s", value)
+	}
+}
@@ -0,0 +1,151 @@
+package engine
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+/**
+SQL 语句的预处理
+
+PREPARE stmt FROM '...' 解析语句，语句保存在会话中，语句中的 ? 是占位符。
+EXECUTE stmt USING @a, @b 依次用用户变量的值替换占位符后执行语句，
+DEALLOCATE PREPARE stmt 释放语句。
+**/
+
+// preparedStmt is a statement prepared by PREPARE.
+type preparedStmt struct {
+	sql  string
+	stmt ast.StmtNode
+	// params are the placeholders of stmt in the order of the statement.
+	params []*ast.ParamMarkerExpr
+}
+
+// paramMarkerCollector collects the placeholders of a statement.
+type paramMarkerCollector struct {
+	params []*ast.ParamMarkerExpr
+}
+
+func (c *paramMarkerCollector) Enter(in ast.Node) (ast.Node, bool) {
+	if x, ok := in.(*ast.ParamMarkerExpr); ok {
+		c.params = append(c.params, x)
+	}
+	return in, false
+}
+
+func (c *paramMarkerCollector) Leave(in ast.Node) (ast.Node, bool) {
+	return in, true
+}
+
+// prepareStmt prepares the statement of p under its name, replacing the
+// statement prepared under the name before.
+func prepareStmt(session innodb.MySQLServerSession, p *plan.Prepare, x *ast.PrepareStmt) error {
+	vars := session.GetSessionVars()
+	sql := p.SQLText
+	if x.SQLVar != nil {
+		vars.UsersLock.RLock()
+		sql = vars.Users[strings.ToLower(x.SQLVar.Name)]
+		vars.UsersLock.RUnlock()
+	}
+	stmt, err := session.ParseOneSQL(sql, mysql.UTF8Charset, mysql.UTF8DefaultCollation)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	switch stmt.(type) {
+	case *ast.PrepareStmt, *ast.ExecuteStmt, *ast.DeallocateStmt:
+		return mysql.NewErr(mysql.ErrUnsupportedPs)
+	}
+	if err = plan.Validate(stmt, true); err != nil {
+		return errors.Trace(err)
+	}
+	var collector paramMarkerCollector
+	stmt.Accept(&collector)
+	sort.Slice(collector.params, func(i, j int) bool {
+		return collector.params[i].Offset < collector.params[j].Offset
+	})
+	if len(collector.params) > 0xffff {
+		return mysql.NewErr(mysql.ErrPsManyParam)
+	}
+
+	deallocateStmt(vars, p.Name)
+	id := vars.GetNextPreparedStmtID()
+	vars.PreparedStmts[id] = &preparedStmt{sql: sql, stmt: stmt, params: collector.params}
+	vars.PreparedStmtNameToID[strings.ToLower(p.Name)] = id
+	return nil
+}
+
+// deallocateStmt drops the statement prepared under name, reporting whether
+// there is one.
+func deallocateStmt(vars *variable.SessionVars, name string) bool {
+	name = strings.ToLower(name)
+	id, ok := vars.PreparedStmtNameToID[name]
+	if !ok {
+		return false
+	}
+	delete(vars.PreparedStmtNameToID, name)
+	delete(vars.PreparedStmts, id)
+	return true
+}
+
+// boundStmt returns the statement prepared under the name of p, its
+// placeholders set to the values of the user variables p uses.
+func boundStmt(vars *variable.SessionVars, p *plan.Execute) (ast.StmtNode, error) {
+	id, ok := vars.PreparedStmtNameToID[strings.ToLower(p.Name)]
+	if !ok {
+		return nil, mysql.NewErr(mysql.ErrUnknownStmtHandler, len(p.Name), p.Name, "EXECUTE")
+	}
+	prepared := vars.PreparedStmts[id].(*preparedStmt)
+	if len(p.UsingVars) != len(prepared.params) {
+		return nil, mysql.NewErr(mysql.ErrWrongArguments, "EXECUTE")
+	}
+	for i, param := range prepared.params {
+		value, err := p.UsingVars[i].Eval(nil)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		param.SetDatum(value)
+	}
+	return prepared.stmt, nil
+}
+
+func init() {
+	registerStmtHandler(&ast.PrepareStmt{}, &stmtHandler{name: "prepare", handle: (*XMySQLEngine).execPrepare})
+	registerStmtHandler(&ast.ExecuteStmt{}, &stmtHandler{name: "execute", handle: (*XMySQLEngine).execExecute})
+	registerStmtHandler(&ast.DeallocateStmt{}, &stmtHandler{name: "deallocate prepare", handle: (*XMySQLEngine).execDeallocate})
+}
+
+// execPrepare runs a PREPARE.
+func (srv *XMySQLEngine) execPrepare(session innodb.MySQLServerSession, stmt ast.StmtNode, p plan.Plan) {
+	if err := prepareStmt(session, p.(*plan.Prepare), stmt.(*ast.PrepareStmt)); err != nil {
+		session.SendError(toSQLError(err))
+		return
+	}
+	session.SendOK()
+}
+
+// execExecute runs the prepared statement an EXECUTE names.
+func (srv *XMySQLEngine) execExecute(session innodb.MySQLServerSession, stmt ast.StmtNode, p plan.Plan) {
+	prepared, err := boundStmt(session.GetSessionVars(), p.(*plan.Execute))
+	if err != nil {
+		session.SendError(toSQLError(err))
+		return
+	}
+	srv.executeStmt(session, prepared, true)
+}
+
+// execDeallocate runs a DEALLOCATE PREPARE.
+func (srv *XMySQLEngine) execDeallocate(session innodb.MySQLServerSession, stmt ast.StmtNode, p plan.Plan) {
+	name := p.(*plan.Deallocate).Name
+	if !deallocateStmt(session.GetSessionVars(), name) {
+		session.SendError(mysql.NewErr(mysql.ErrUnknownStmtHandler, len(name), name, "DEALLOCATE PREPARE"))
+		return
+	}
+	session.SendOK()
+}
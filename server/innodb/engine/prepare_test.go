@@ -0,0 +1,77 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/conf"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+func TestPrepareExecute(t *testing.T) {
+	is := newViewTestSchema()
+	srv := &XMySQLEngine{conf: conf.NewCfg(), infoSchemaManager: is}
+	s := &serverTestSession{session: newViewTestSession(t, is)}
+	run := func(sql string) {
+		s.errs = nil
+		srv.executeQuery(s, sql)
+		if len(s.errs) != 0 {
+			t.Fatalf("%s: %v", sql, s.errs[0])
+		}
+	}
+	query := func(sql string) []string {
+		s.rows = nil
+		run(sql)
+		if len(s.rows) != 1 {
+			t.Fatalf("%s: expect 1 row, got %v", sql, s.rows)
+		}
+		var row []string
+		for _, d := range s.rows[0] {
+			str, _ := d.ToString()
+			row = append(row, str)
+		}
+		return row
+	}
+
+	run("PREPARE stmt FROM 'SELECT CONCAT(?, ''-'', ?), ? * 2'")
+	run("SET @a = 'x', @b = 'y', @c = 21")
+	got := query("EXECUTE stmt USING @a, @b, @c")
+	if expect := query("SELECT CONCAT('x', '-', 'y'), '21' * 2"); !reflect.DeepEqual(got, expect) {
+		t.Errorf("expect %v, got %v", expect, got)
+	}
+	// Each EXECUTE takes the values the variables have then.
+	run("SET @b = 'z'")
+	if got = query("EXECUTE stmt USING @a, @b, @c"); got[0] != "x-z" {
+		t.Errorf("expect x-z, got %v", got)
+	}
+
+	// The statement may be prepared from a user variable.
+	run("SET @q = 'SELECT ? + 1'")
+	run("PREPARE stmt2 FROM @q")
+	if got = query("EXECUTE stmt2 USING @c"); got[0] != "22" {
+		t.Errorf("expect 22, got %v", got)
+	}
+
+	for sql, code := range map[string]uint16{
+		"EXECUTE stmt USING @a":          mysql.ErrWrongArguments,
+		"EXECUTE missing":                mysql.ErrUnknownStmtHandler,
+		"PREPARE s3 FROM 'EXECUTE stmt'": mysql.ErrUnsupportedPs,
+		"SELECT ?":                       mysql.ErrSyntax,
+	} {
+		s.errs = nil
+		srv.executeQuery(s, sql)
+		if len(s.errs) != 1 || s.errs[0].Code != code {
+			t.Errorf("%s: expect error %d, got %v", sql, code, s.errs)
+		}
+	}
+
+	run("DEALLOCATE PREPARE stmt")
+	if _, ok := s.sessionVars.PreparedStmtNameToID["stmt"]; ok {
+		t.Error("expect stmt deallocated")
+	}
+	s.errs = nil
+	srv.executeQuery(s, "EXECUTE stmt USING @a, @b, @c")
+	if len(s.errs) != 1 || s.errs[0].Code != mysql.ErrUnknownStmtHandler {
+		t.Errorf("expect error %d executing a deallocated statement, got %v", mysql.ErrUnknownStmtHandler, s.errs)
+	}
+}
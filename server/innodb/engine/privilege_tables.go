@@ -0,0 +1,206 @@
+package engine
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/juju/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/innodb_store/store"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/parser"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/privilege/privileges"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/sqlexec"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+/**
+权限表
+
+--initialize 创建 mysql.user 和 mysql.db（见 initdb），root@localhost 有全部全局权限。
+引擎启动时打开这两张表，读入权限的缓存 privHandle：登录按它验证密码，语句按它检查权限。
+读不出权限表时 privHandle 为 nil，GRANT 和 REVOKE 返回 ER_NOT_SUPPORTED_YET，所有的
+登录都被拒绝。
+
+GRANT 和 REVOKE 先修改缓存，再把缓存和表不同的行写回表：去掉表中有、缓存中没有的行，
+再加入缓存中有、表中没有的行。写入出错时从表重新读入缓存，缓存和表保持一致。
+FLUSH PRIVILEGES 从表重新读入缓存。
+**/
+
+// privilegeTables are the privilege tables of the mysql database, which
+// answer the SELECT * FROM mysql.<table> of MySQLPrivilege.LoadAll.
+type privilegeTables struct {
+	srv    *XMySQLEngine
+	tables map[string]schemas.Table
+	// mu serializes the writes of the tables.
+	mu sync.Mutex
+}
+
+var _ sqlexec.RestrictedSQLExecutor = (*privilegeTables)(nil)
+
+// initPrivileges opens the privilege tables and loads privHandle from
+// them, leaving it nil when they can't be read.
+func (srv *XMySQLEngine) initPrivileges() {
+	tables, err := openPrivilegeTables(srv)
+	if err == nil {
+		h := privileges.NewHandle()
+		if err = h.Update(nil, tables); err == nil {
+			srv.privTables, srv.privHandle = tables, h
+			return
+		}
+	}
+	log.Errorf("读取权限表失败，所有的登录都会被拒绝: %v", err)
+}
+
+func openPrivilegeTables(srv *XMySQLEngine) (*privilegeTables, error) {
+	tables := &privilegeTables{srv: srv, tables: make(map[string]schemas.Table)}
+	for _, t := range privileges.Tables {
+		info, err := t.TableInfo()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		tbl, err := store.OpenOrdinaryTable(srv.conf, srv.pool, mysql.SystemDB, info, t.SpaceID)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		tables.tables[t.Name] = tbl
+	}
+	return tables, nil
+}
+
+// ExecRestrictedSQL runs sql, a SELECT * of a privilege table.
+func (t *privilegeTables) ExecRestrictedSQL(ctx context.Context, sql string) (ast.RecordSet, error) {
+	stmt, err := parser.New().ParseOneStmt(sql, mysql.UTF8Charset, mysql.UTF8DefaultCollation)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var name *ast.TableName
+	if sel, ok := stmt.(*ast.SelectStmt); ok && sel.From != nil && sel.From.TableRefs.Right == nil {
+		if source, ok := sel.From.TableRefs.Left.(*ast.TableSource); ok {
+			name, _ = source.Source.(*ast.TableName)
+		}
+	}
+	if name == nil || name.Schema.L != mysql.SystemDB || t.tables[name.Name.L] == nil {
+		return nil, errors.Errorf("不能在权限表上执行 %s", sql)
+	}
+	tbl := t.tables[name.Name.L]
+	rows, err := t.rows(ctx, tbl)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	rs := &privilegeRecordSet{rows: rows}
+	for _, col := range tbl.Meta().Columns {
+		rs.fields = append(rs.fields, &ast.ResultField{Column: col, ColumnAsName: col.Name, Table: tbl.Meta(), DBName: name.Schema})
+	}
+	return rs, nil
+}
+
+// rows reads the rows of tbl, in a session of its own without ctx.
+func (t *privilegeTables) rows(ctx context.Context, tbl schemas.Table) ([][]basic.Datum, error) {
+	if ctx == nil {
+		s, err := createSession(t.srv.infoSchemaManager)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		ctx = s
+	}
+	reader := &scanRowsReader{ctx: ctx, pool: t.srv.pool}
+	return reader.TableRows(tbl)
+}
+
+// save writes priv to the privilege tables, the rows which differ.
+func (t *privilegeTables) save(priv *privileges.MySQLPrivilege) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, tbl := range t.tables {
+		stored, err := t.rows(nil, tbl)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		cached := priv.Rows(tbl.Meta())
+		keep := make(map[string]bool, len(cached))
+		for _, row := range cached {
+			keep[privilegeRowKey(row)] = true
+		}
+		writer := tbl.(schemas.RowWriter)
+		for _, row := range stored {
+			key := privilegeRowKey(row)
+			if !keep[key] {
+				if err = writer.RemoveRow(row); err != nil {
+					return errors.Trace(err)
+				}
+			}
+			delete(keep, key)
+		}
+		for _, row := range cached {
+			if keep[privilegeRowKey(row)] {
+				if err = writer.AddRow(row); err != nil {
+					return errors.Trace(err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// privilegeRowKey returns the values of row joined, to compare the rows
+// of the privilege tables.
+func privilegeRowKey(row []basic.Datum) string {
+	vals := make([]string, 0, len(row))
+	for _, d := range row {
+		val, _ := d.ToString()
+		vals = append(vals, val)
+	}
+	return strings.Join(vals, "\x00")
+}
+
+// privilegeRecordSet is the result of a SELECT * of a privilege table.
+type privilegeRecordSet struct {
+	fields []*ast.ResultField
+	rows   [][]basic.Datum
+}
+
+func (rs *privilegeRecordSet) Fields() ([]*ast.ResultField, error) {
+	return rs.fields, nil
+}
+
+func (rs *privilegeRecordSet) Next() (*ast.Row, error) {
+	if len(rs.rows) == 0 {
+		return nil, nil
+	}
+	row := &ast.Row{Data: rs.rows[0]}
+	rs.rows = rs.rows[1:]
+	return row, nil
+}
+
+func (rs *privilegeRecordSet) Close() error {
+	return nil
+}
+
+func init() {
+	registerStmtHandler(&ast.FlushStmt{}, &stmtHandler{
+		name: "flush privileges",
+		match: func(stmt ast.StmtNode) bool {
+			return stmt.(*ast.FlushStmt).Tp == ast.FlushPrivileges
+		},
+		handle: (*XMySQLEngine).execFlushPrivileges,
+	})
+}
+
+// execFlushPrivileges runs a FLUSH PRIVILEGES.
+func (srv *XMySQLEngine) execFlushPrivileges(session innodb.MySQLServerSession, stmt ast.StmtNode, p plan.Plan) {
+	if srv.privHandle == nil {
+		session.SendError(toSQLError(mysql.NewErrf(mysql.ErrNotSupportedYet, "FLUSH PRIVILEGES without the privilege tables")))
+		return
+	}
+	if err := srv.privHandle.Update(session, srv.privTables); err != nil {
+		session.SendError(toSQLError(err))
+		return
+	}
+	session.SendOK()
+}
@@ -0,0 +1,111 @@
+package engine
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/initdb"
+	"github.com/zhukovaskychina/xmysql-server/server/conf"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/innodb_store/store"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/privilege"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/privilege/privileges"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/auth"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// newPrivilegeTestDataDir returns the configuration of a data directory
+// initialized by --initialize-insecure, where mysql.user has the accounts
+// of users too.
+func newPrivilegeTestDataDir(t *testing.T, users ...privileges.UserRecord) *conf.Cfg {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "privileges")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	cfg := conf.NewCfg()
+	cfg.DataDir, cfg.BaseDir = dir, dir
+	if _, err = initdb.Initialize(cfg, true); err != nil {
+		t.Fatal(err)
+	}
+	info, err := privileges.Tables[0].TableInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tbl, err := store.OpenOrdinaryTable(cfg, nil, mysql.SystemDB, info, privileges.Tables[0].SpaceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range (&privileges.MySQLPrivilege{User: users}).Rows(info) {
+		if err = tbl.AddRow(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return cfg
+}
+
+func TestPrivilegeTables(t *testing.T) {
+	cfg := newPrivilegeTestDataDir(t, privileges.UserRecord{Host: "%", User: "app", Password: auth.EncodePassword("secret")})
+	srv := NewXMySQLEngine(cfg)
+	h := srv.PrivilegeHandle()
+	if h == nil {
+		t.Fatal("expect the privilege tables to be loaded")
+	}
+	root := &privileges.UserPrivileges{Handle: h}
+	if !root.ConnectionVerification("root", "localhost", nil, []byte("salt")) || !root.RequestVerification("", "", "", mysql.SuperPriv) {
+		t.Fatal("expect root@localhost without password and with every privilege")
+	}
+
+	storeTestTables(t, srv, newFKTestTable("t", "id"))
+	admin := &serverTestSession{session: newViewTestSession(t, srv.infoSchemaManager)}
+	execStored(t, srv, admin, "INSERT INTO t VALUES (1)", 0)
+	// app logs in like the handler does it.
+	app := &serverTestSession{session: newViewTestSession(t, srv.infoSchemaManager)}
+	salt := []byte("0123456789abcdefghij")
+	p := &privileges.UserPrivileges{Handle: h}
+	if !p.ConnectionVerification("app", "10.0.0.1", auth.ScrambleNativePassword("secret", salt), salt) {
+		t.Fatal("expect app to log in")
+	}
+	app.sessionVars.User = &auth.UserIdentity{Username: "app", Hostname: "10.0.0.1"}
+	privilege.BindPrivilegeManager(app, p)
+
+	execStored(t, srv, app, "SELECT * FROM t", mysql.ErrDBaccessDenied)
+	execStored(t, srv, admin, "GRANT SELECT ON test.* TO 'app'@'%'", 0)
+	if got := execStored(t, srv, app, "SELECT * FROM t", 0); got != "1" {
+		t.Fatalf("expect app to read t, got %s", got)
+	}
+	execStored(t, srv, app, "INSERT INTO t VALUES (2)", mysql.ErrTableaccessDenied)
+	if p.RequestVerification("other", "", "", mysql.SelectPriv) {
+		t.Fatal("expect no SELECT on another database")
+	}
+
+	// The grant is in mysql.db: FLUSH PRIVILEGES and a restart keep it.
+	execStored(t, srv, admin, "FLUSH PRIVILEGES", 0)
+	if got := execStored(t, srv, app, "SELECT * FROM t", 0); got != "1" {
+		t.Fatalf("expect app to read t after FLUSH PRIVILEGES, got %s", got)
+	}
+	if err := srv.Close(); err != nil {
+		t.Fatal(err)
+	}
+	srv = NewXMySQLEngine(cfg)
+	defer srv.Close()
+	p = &privileges.UserPrivileges{User: "app", Host: "10.0.0.1", Handle: srv.PrivilegeHandle()}
+	if !p.RequestVerification("test", "", "", mysql.SelectPriv) || p.RequestVerification("test", "", "", mysql.InsertPriv) {
+		t.Fatal("expect the grant to be kept after a restart")
+	}
+}
+
+func TestPrivilegeTablesMissing(t *testing.T) {
+	cfg := newPrivilegeTestDataDir(t)
+	if err := os.Remove(cfg.DataDir + "/mysql/db.ibd"); err != nil {
+		t.Fatal(err)
+	}
+	srv := NewXMySQLEngine(cfg)
+	defer srv.Close()
+	if srv.PrivilegeHandle() != nil {
+		t.Fatal("expect no privilege cache without mysql.db")
+	}
+	s := &serverTestSession{session: newViewTestSession(t, srv.infoSchemaManager)}
+	execStored(t, srv, s, "GRANT SELECT ON test.* TO 'root'@'localhost'", mysql.ErrNotSupportedYet)
+}
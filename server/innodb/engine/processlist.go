@@ -0,0 +1,46 @@
+package engine
+
+import (
+	"time"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/privilege"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// processListInfoLength is the length the statements are cut to by SHOW
+// PROCESSLIST without FULL.
+const processListInfoLength = 100
+
+// processListRows returns the rows of SHOW [FULL] PROCESSLIST: the sessions
+// of the clients connected, only the sessions of the same user without the
+// PROCESS privilege, with the memory their statements take and took at most.
+func processListRows(ctx context.Context, full bool) [][]basic.Datum {
+	vars := ctx.GetSessionVars()
+	pm := privilege.GetPrivilegeManager(ctx)
+	allUsers := pm == nil || pm.RequestVerification("", "", "", mysql.ProcessPriv)
+	var rows [][]basic.Datum
+	for _, s := range variable.Processes() {
+		info := s.ProcessInfo()
+		if !allUsers && (vars.User == nil || info.User != vars.User.Username) {
+			continue
+		}
+		var db, state, query interface{}
+		if info.DB != "" {
+			db = info.DB
+		}
+		if info.Info != "" {
+			state = "executing"
+			if !full && len(info.Info) > processListInfoLength {
+				info.Info = info.Info[:processListInfoLength]
+			}
+			query = info.Info
+		}
+		rows = append(rows, basic.MakeDatums(int64(info.ID), info.User, info.Host, db, info.Command,
+			int64(time.Since(info.Time)/time.Second), state, query,
+			s.MemTracker.BytesConsumed(), s.MemTracker.MaxConsumed()))
+	}
+	return rows
+}
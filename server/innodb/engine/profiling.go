@@ -0,0 +1,125 @@
+package engine
+
+import (
+	"time"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+	"github.com/zhukovaskychina/xmysql-server/server/protocol"
+)
+
+/**
+语句的 profile
+
+会话打开 profiling 后，每条语句记下它在各个阶段花的时间：解析、优化（编译成计划）、
+执行和把结果发给客户端。结果集是边执行边发送的，所以读表的时间大多算在 sending 里。
+
+SHOW PROFILES 列出会话最近 profiling_history_size 条语句的编号、耗时和 SQL，
+SHOW PROFILE [FOR QUERY n] 列出一条语句各阶段的耗时，不指定 n 时是最后一条。
+profile 只留在会话里，关掉 profiling 后还能查看，会话结束时丢弃。
+**/
+
+// The stages of a profiled statement.
+const (
+	stageParsing    = "parsing"
+	stageOptimizing = "optimizing"
+	stageExecuting  = "executing"
+	stageSending    = "sending"
+)
+
+// profiledSession is a session whose statement is profiled: it times the
+// stages the statement goes through.
+type profiledSession struct {
+	innodb.MySQLServerSession
+	profile    *variable.QueryProfile
+	start      time.Time
+	stageStart time.Time
+}
+
+func newProfiledSession(session innodb.MySQLServerSession, query string) *profiledSession {
+	now := time.Now()
+	return &profiledSession{
+		MySQLServerSession: session,
+		profile:            &variable.QueryProfile{Query: query},
+		start:              now,
+		stageStart:         now,
+	}
+}
+
+// enter ends the current stage and starts status.
+func (s *profiledSession) enter(status string) {
+	stages := s.profile.Stages
+	if len(stages) > 0 && stages[len(stages)-1].Status == status {
+		return
+	}
+	now := s.endStage()
+	s.profile.Stages = append(stages, variable.ProfileStage{Status: status})
+	s.stageStart = now
+}
+
+func (s *profiledSession) endStage() time.Time {
+	now := time.Now()
+	if n := len(s.profile.Stages); n > 0 {
+		s.profile.Stages[n-1].Duration = now.Sub(s.stageStart)
+	}
+	return now
+}
+
+// finish ends the statement and keeps its profile in the session.
+func (s *profiledSession) finish() {
+	s.profile.Duration = s.endStage().Sub(s.start)
+	s.GetSessionVars().AddProfile(s.profile)
+}
+
+func (s *profiledSession) SendOK() {
+	s.enter(stageSending)
+	s.MySQLServerSession.SendOK()
+}
+
+func (s *profiledSession) SendHandleOk() {
+	s.enter(stageSending)
+	s.MySQLServerSession.SendHandleOk()
+}
+
+func (s *profiledSession) SendError(err *mysql.SQLError) {
+	s.enter(stageSending)
+	s.MySQLServerSession.SendError(err)
+}
+
+func (s *profiledSession) SendResultSet(fields []protocol.Field, rows innodb.RowIterator) error {
+	s.enter(stageSending)
+	return s.MySQLServerSession.SendResultSet(fields, rows)
+}
+
+// profileStage starts the stage status of the statement of session when it
+// is profiled.
+func profileStage(session innodb.MySQLServerSession, status string) {
+	if s, ok := session.(*profiledSession); ok {
+		s.enter(status)
+	}
+}
+
+// profilesRows returns the rows of SHOW PROFILES.
+func profilesRows(vars *variable.SessionVars) [][]basic.Datum {
+	rows := make([][]basic.Datum, 0, len(vars.Profiles))
+	for _, p := range vars.Profiles {
+		rows = append(rows, basic.MakeDatums(p.QueryID, p.Duration.Seconds(), p.Query))
+	}
+	return rows
+}
+
+// profileRows returns the rows of SHOW PROFILE FOR QUERY queryID, of the
+// last statement profiled when queryID is 0.
+func profileRows(vars *variable.SessionVars, queryID uint64) [][]basic.Datum {
+	p := vars.Profile(queryID)
+	if p == nil {
+		return nil
+	}
+	rows := make([][]basic.Datum, 0, len(p.Stages))
+	for _, stage := range p.Stages {
+		rows = append(rows, basic.MakeDatums(stage.Status, stage.Duration.Seconds()))
+	}
+	return rows
+}
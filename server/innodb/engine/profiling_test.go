@@ -0,0 +1,90 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zhukovaskychina/xmysql-server/server/conf"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/varsutil"
+)
+
+func TestProfiling(t *testing.T) {
+	is := newViewTestSchema(newFKTestTable("t", "id"))
+	srv := &XMySQLEngine{conf: conf.NewCfg(), infoSchemaManager: is}
+	s := &serverTestSession{session: newViewTestSession(t, is)}
+	set := func(name, value string) {
+		t.Helper()
+		if err := varsutil.SetSessionSystemVar(s.sessionVars, name, basic.NewStringDatum(value)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	query := func(sql string) [][]basic.Datum {
+		t.Helper()
+		s.rows, s.errs = nil, nil
+		srv.ExecuteQuery(s, sql)
+		if len(s.errs) != 0 {
+			t.Fatalf("%s: %v", sql, s.errs)
+		}
+		return s.rows
+	}
+
+	// Statements are profiled only with profiling on.
+	query("SELECT 1")
+	if rows := query("SHOW PROFILES"); len(rows) != 0 {
+		t.Fatalf("expect no profiles, got %v", rows)
+	}
+	set(variable.Profiling, "1")
+	query("SELECT 1")
+	srv.ExecuteQuery(s, "SELEC 1")
+	rows := query("SHOW PROFILES")
+	if len(rows) != 2 {
+		t.Fatalf("expect 2 profiles, got %v", rows)
+	}
+	for i, want := range []string{"SELECT 1", "SELEC 1"} {
+		row := rows[i]
+		if row[0].GetUint64() != uint64(i+1) || row[2].GetString() != want {
+			t.Fatalf("expect query %d %q, got %v", i+1, want, row)
+		}
+		if d := row[1].GetFloat64(); d <= 0 || d > time.Minute.Seconds() {
+			t.Fatalf("expect the duration of %q in seconds, got %v", want, d)
+		}
+	}
+
+	// The stages of a statement add up to its duration.
+	stages := func(sql string, want ...string) {
+		t.Helper()
+		rows := query(sql)
+		if len(rows) != len(want) {
+			t.Fatalf("%s: expect stages %v, got %v", sql, want, rows)
+		}
+		for i, row := range rows {
+			if row[0].GetString() != want[i] || row[1].GetFloat64() < 0 {
+				t.Fatalf("%s: expect stages %v, got %v", sql, want, rows)
+			}
+		}
+	}
+	stages("SHOW PROFILE FOR QUERY 1", stageParsing, stageOptimizing, stageExecuting, stageSending)
+	stages("SHOW PROFILE FOR QUERY 2", stageParsing, stageSending)
+	// SHOW PROFILE is of the last statement, the SHOW PROFILE before.
+	stages("SHOW PROFILE", stageParsing, stageOptimizing, stageExecuting, stageSending)
+	stages("SHOW PROFILE FOR QUERY 99")
+	p := s.sessionVars.Profile(1)
+	var total time.Duration
+	for _, stage := range p.Stages {
+		total += stage.Duration
+	}
+	if total > p.Duration {
+		t.Fatalf("expect the stages to take at most %v, got %v", p.Duration, total)
+	}
+
+	// Only the last profiling_history_size profiles are kept, and they stay
+	// with profiling off.
+	set(variable.ProfilingHistorySize, "2")
+	set(variable.Profiling, "0")
+	rows = query("SHOW PROFILES")
+	if len(rows) != 2 || rows[0][0].GetUint64() != 6 || rows[1][0].GetUint64() != 7 {
+		t.Fatalf("expect profiles 6 and 7, got %v", rows)
+	}
+}
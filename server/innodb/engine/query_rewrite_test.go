@@ -0,0 +1,86 @@
+package engine
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+)
+
+func TestQueryRewrite(t *testing.T) {
+	s := newViewTestSession(t, newViewTestSchema(newTraceTestTable()))
+	setTraceVar(t, s, "optimizer_trace", "enabled=on")
+	for _, tt := range []struct {
+		sql   string
+		plan  string
+		rules []string
+	}{
+		{"SELECT id FROM t WHERE id = 3+4", "Table(t)", []string{"constant_folding"}},
+		{"SELECT id FROM t WHERE 1 = 0", "Dual->Projection", []string{"constant_folding", "impossible_where"}},
+		{"SELECT id FROM t WHERE a = 1 OR a = 2 OR a = 3", "Index(t.ia)[[1,1] [2,2] [3,3]]->Projection", []string{"or_to_in"}},
+		{"SELECT id FROM t WHERE a = 1 OR b = 2", "Table(t)->Selection->Projection", nil},
+		{"SELECT DISTINCT a, COUNT(*) FROM t GROUP BY a", "Index(t.ia)[[<nil>,+inf]]->StreamAgg->Projection", []string{"distinct_elimination"}},
+		{"SELECT DISTINCT b FROM t GROUP BY a", "Index(t.ia)[[<nil>,+inf]]->StreamAgg->HashAgg", nil},
+		{"SELECT * FROM (SELECT a FROM t ORDER BY a) d GROUP BY a", "Index(t.ia)[[<nil>,+inf]]->StreamAgg", []string{"order_by_elimination"}},
+		{"SELECT a FROM t WHERE a IN (SELECT a FROM t ORDER BY b)", "SemiJoin{Table(t)->Table(t)}", []string{"order_by_elimination"}},
+		// The order of a derived table read as it is, or cut by a LIMIT,
+		// matters.
+		{"SELECT * FROM (SELECT a FROM t ORDER BY a) d", "Index(t.ia)[[<nil>,+inf]]", nil},
+		{"SELECT * FROM (SELECT a FROM t ORDER BY b LIMIT 2) d ORDER BY a",
+			"Table(t)->Sort + Limit(2) + Offset(0)->Sort->Projection", nil},
+	} {
+		before := plan.QueriesRewritten()
+		_, p, err := compileView(s, tt.sql)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.sql, err)
+		}
+		if got := plan.ToString(p); got != tt.plan {
+			t.Fatalf("%s: expect plan %s, got %s", tt.sql, tt.plan, got)
+		}
+		var trace traceTestTrace
+		if err := json.Unmarshal([]byte(s.sessionVars.LastOptimizerTrace.Trace), &trace); err != nil {
+			t.Fatal(err)
+		}
+		var rules []string
+		for _, r := range trace.Rewrites {
+			switch r.Rule {
+			case "constant_folding", "impossible_where", "or_to_in", "distinct_elimination", "order_by_elimination":
+				rules = append(rules, r.Rule)
+			}
+		}
+		if !reflect.DeepEqual(rules, tt.rules) {
+			t.Fatalf("%s: expect rewrites %v, got %v", tt.sql, tt.rules, rules)
+		}
+		var counted int64
+		if len(tt.rules) > 0 {
+			counted = 1
+		}
+		if got := plan.QueriesRewritten() - before; got != counted {
+			t.Fatalf("%s: expect %d query rewritten, got %d", tt.sql, counted, got)
+		}
+	}
+}
+
+func TestExplainImpossibleWhere(t *testing.T) {
+	s := newViewTestSession(t, newViewTestSchema(newTraceTestTable()))
+	for _, tt := range []struct {
+		sql  string
+		info string
+	}{
+		{"SELECT id FROM t WHERE a = 1 AND 1 = 0", "Impossible WHERE"},
+		{"SELECT id FROM t HAVING 1 = 0", "rows:0"},
+	} {
+		_, p, err := compileView(s, tt.sql)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.sql, err)
+		}
+		for len(p.Children()) > 0 {
+			p = p.Children()[0]
+		}
+		dual, ok := p.(*plan.TableDual)
+		if !ok || dual.ExplainInfo() != tt.info {
+			t.Fatalf("%s: expect a dual of %q, got %s", tt.sql, tt.info, plan.ToString(p))
+		}
+	}
+}
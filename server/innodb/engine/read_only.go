@@ -0,0 +1,111 @@
+package engine
+
+import (
+	"strings"
+	"sync/atomic"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/privilege"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/varsutil"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+/**
+只读模式
+
+read_only 打开时，没有 SUPER 权限的用户不能执行修改数据或表结构的语句：INSERT、UPDATE、
+DELETE、LOAD DATA、DDL、GRANT、REVOKE 和用户管理的语句，返回 1290；super_read_only
+打开时 SUPER 用户也不能执行。打开 super_read_only 同时打开 read_only，关闭 read_only
+同时关闭 super_read_only。SELECT 和 SET 不受影响。
+
+检查在分发语句时、编译执行之前进行；变量打开前已经写入数据的事务仍然可以提交。
+
+会话的 transaction_read_only（tx_read_only）打开时，这个会话的修改语句返回 1792。
+**/
+
+// readOnlyMode is the state of read_only and super_read_only.
+type readOnlyMode struct {
+	readOnly      int32
+	superReadOnly int32
+}
+
+func (m *readOnlyMode) on() bool {
+	return atomic.LoadInt32(&m.readOnly) == 1
+}
+
+func (m *readOnlyMode) superOn() bool {
+	return atomic.LoadInt32(&m.superReadOnly) == 1
+}
+
+// set turns read_only on or off, super_read_only with it when off.
+func (m *readOnlyMode) set(on bool) {
+	if on {
+		atomic.StoreInt32(&m.readOnly, 1)
+		return
+	}
+	atomic.StoreInt32(&m.readOnly, 0)
+	atomic.StoreInt32(&m.superReadOnly, 0)
+}
+
+// setSuper turns super_read_only on or off, read_only with it when on.
+func (m *readOnlyMode) setSuper(on bool) {
+	if on {
+		atomic.StoreInt32(&m.superReadOnly, 1)
+		atomic.StoreInt32(&m.readOnly, 1)
+		return
+	}
+	atomic.StoreInt32(&m.superReadOnly, 0)
+}
+
+// initReadOnly sets read_only and super_read_only as configured and lets
+// SET GLOBAL change them at runtime.
+func (srv *XMySQLEngine) initReadOnly() {
+	m := &srv.readOnly
+	m.set(srv.conf.ReadOnly)
+	m.setSuper(srv.conf.SuperReadOnly)
+	registerSwitch(variable.ReadOnly, m.on, m.set)
+	registerSwitch(variable.SuperReadOnly, m.superOn, m.setSuper)
+}
+
+// checkReadOnly returns the error of stmt when it changes data or schema
+// while the session's transactions or the server are read only.
+func (srv *XMySQLEngine) checkReadOnly(ctx context.Context, stmt ast.StmtNode) error {
+	if !changesData(stmt) {
+		return nil
+	}
+	if txReadOnly, err := varsutil.GetSessionSystemVar(ctx.GetSessionVars(), variable.TxReadOnly); err == nil && isOn(txReadOnly) {
+		return ErrReadOnlyTransaction
+	}
+	if srv.readOnly.superOn() {
+		return ErrOptionPreventsStatement.GenByArgs("--super-read-only")
+	}
+	if srv.readOnly.on() && !hasSuperPriv(ctx) {
+		return ErrOptionPreventsStatement.GenByArgs("--read-only")
+	}
+	return nil
+}
+
+// changesData reports whether stmt changes the data, the schema or the
+// accounts.
+func changesData(stmt ast.StmtNode) bool {
+	switch stmt.(type) {
+	case ast.DDLNode, *ast.InsertStmt, *ast.UpdateStmt, *ast.DeleteStmt, *ast.LoadDataStmt, *ast.ImportTableStmt,
+		*ast.GrantStmt, *ast.RevokeStmt, *ast.CreateUserStmt, *ast.AlterUserStmt, *ast.DropUserStmt, *ast.SetPwdStmt:
+		return true
+	}
+	return false
+}
+
+// hasSuperPriv reports whether the user of ctx has SUPER, which every user
+// has without a privilege manager.
+func hasSuperPriv(ctx context.Context) bool {
+	pm := privilege.GetPrivilegeManager(ctx)
+	return pm == nil || pm.RequestVerification("", "", "", mysql.SuperPriv)
+}
+
+// isOn reports whether the value of a boolean variable is ON or 1.
+func isOn(value string) bool {
+	return value == "1" || strings.EqualFold(value, "ON")
+}
@@ -0,0 +1,121 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/conf"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/parser"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/privilege"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/varsutil"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+func TestReadOnly(t *testing.T) {
+	srv := &XMySQLEngine{conf: conf.NewCfg()}
+	srv.initReadOnly()
+	s := newViewTestSession(t, newViewTestSchema(newFKTestTable("t", "id")))
+	super := newViewTestSession(t, newViewTestSchema(newFKTestTable("t", "id")))
+	privilege.BindPrivilegeManager(s, &denyingPrivilegeManager{})
+	setGlobal := func(name, value string) {
+		if err := varsutil.SetGlobalSystemVar(s.sessionVars, name, basic.NewStringDatum(value)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer setGlobal(variable.ReadOnly, "OFF")
+	check := func(s *session, sql string) uint16 {
+		stmt, err := parser.New().ParseOneStmt(sql, mysql.UTF8Charset, mysql.UTF8DefaultCollation)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return errCode(srv.checkReadOnly(s, stmt))
+	}
+
+	writes := []string{
+		"INSERT INTO t VALUES (1)",
+		"UPDATE t SET id = 2",
+		"DELETE FROM t",
+		"CREATE TABLE u (id INT)",
+		"DROP TABLE t",
+		"ALTER TABLE t ADD COLUMN c INT",
+		"TRUNCATE TABLE t",
+		"CREATE DATABASE d",
+		"GRANT SELECT ON test.* TO 'u'@'%'",
+		"CREATE USER 'u'@'%'",
+	}
+	reads := []string{
+		"SELECT * FROM t",
+		"SET @@session.sql_mode = ''",
+		"SHOW TABLES",
+		"COMMIT",
+	}
+	for _, sql := range writes {
+		if code := check(s, sql); code != 0 {
+			t.Errorf("%s: expect no error with read_only off, got %d", sql, code)
+		}
+	}
+
+	setGlobal(variable.ReadOnly, "ON")
+	for _, sql := range writes {
+		if code := check(s, sql); code != mysql.ErrOptionPreventsStatement {
+			t.Errorf("%s: expect error %d, got %d", sql, mysql.ErrOptionPreventsStatement, code)
+		}
+		if code := check(super, sql); code != 0 {
+			t.Errorf("%s: expect SUPER to write with read_only on, got %d", sql, code)
+		}
+	}
+	for _, sql := range reads {
+		if code := check(s, sql); code != 0 {
+			t.Errorf("%s: expect no error with read_only on, got %d", sql, code)
+		}
+	}
+
+	// super_read_only turns read_only on and binds SUPER too.
+	setGlobal(variable.ReadOnly, "OFF")
+	setGlobal(variable.SuperReadOnly, "ON")
+	if value, _ := varsutil.GetGlobalSystemVar(s.sessionVars, variable.ReadOnly); value != "ON" {
+		t.Errorf("expect read_only ON with super_read_only, got %s", value)
+	}
+	if code := check(super, "INSERT INTO t VALUES (1)"); code != mysql.ErrOptionPreventsStatement {
+		t.Errorf("expect error %d for SUPER, got %d", mysql.ErrOptionPreventsStatement, code)
+	}
+	if code := check(super, "SELECT * FROM t"); code != 0 {
+		t.Errorf("expect SELECT allowed, got %d", code)
+	}
+	setGlobal(variable.ReadOnly, "OFF")
+	if value, _ := varsutil.GetGlobalSystemVar(s.sessionVars, variable.SuperReadOnly); value != "OFF" {
+		t.Errorf("expect super_read_only OFF with read_only, got %s", value)
+	}
+
+	// A read only transaction rejects the writes of its session only.
+	if err := execSet(t, super, "SET SESSION TRANSACTION READ ONLY"); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := varsutil.GetSessionSystemVar(super.sessionVars, "transaction_read_only"); value != "1" {
+		t.Errorf("expect transaction_read_only 1, got %s", value)
+	}
+	if code := check(super, "INSERT INTO t VALUES (1)"); code != mysql.ErrCantExecuteInReadOnlyTransaction {
+		t.Errorf("expect error %d, got %d", mysql.ErrCantExecuteInReadOnlyTransaction, code)
+	}
+	if code := check(s, "INSERT INTO t VALUES (1)"); code != 0 {
+		t.Errorf("expect the other session to write, got %d", code)
+	}
+	if err := execSet(t, super, "SET transaction_read_only = OFF"); err != nil {
+		t.Fatal(err)
+	}
+	if code := check(super, "INSERT INTO t VALUES (1)"); code != 0 {
+		t.Errorf("expect writes after transaction_read_only is off, got %d", code)
+	}
+}
+
+func TestReadOnlyDispatch(t *testing.T) {
+	is := newViewTestSchema(newFKTestTable("t", "id"))
+	srv := &XMySQLEngine{conf: conf.NewCfg(), infoSchemaManager: is}
+	srv.readOnly.setSuper(true)
+	s := &serverTestSession{session: newViewTestSession(t, is)}
+	srv.ExecuteQuery(s, "DROP TABLE t")
+	if len(s.errs) != 1 || s.errs[0].Code != mysql.ErrOptionPreventsStatement || s.errs[0].State != "HY000" {
+		t.Fatalf("expect error %d, got %v", mysql.ErrOptionPreventsStatement, s.errs)
+	}
+}
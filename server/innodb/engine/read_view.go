@@ -0,0 +1,85 @@
+package engine
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/innodb_store/store/storebytes/mvcc"
+)
+
+/**
+事务的读视图
+
+事务开始时（BEGIN，或 autocommit 关闭时第一条读写表的语句）分配事务ID并创建读视图，
+COMMIT、ROLLBACK、下一个 BEGIN 或连接断开结束事务时关闭。
+purge 线程只清理所有打开的读视图都已看到的事务的 undo 日志，长事务打开期间，之后提交的事务的 undo 日志都留在历史链表里。
+
+事务写行时用它的事务ID加行锁，结束事务时一起释放（见 row_locks.go）。
+SELECT 按读视图读行的版本，看不到别的事务没有提交的写入（见 row_versions.go）；不在事务中的语句
+也打开自己的读视图，语句结束时关闭。ROLLBACK 和连接断开时把事务写过的行恢复成它写之前的版本。
+**/
+
+type readViewKeyType int
+
+func (k readViewKeyType) String() string {
+	return "read-view-key"
+}
+
+const readViewKey readViewKeyType = 0
+
+// txnReadView is the transaction id and the read view of the transaction
+// of a session.
+type txnReadView struct {
+	trxId mvcc.TrxId
+	view  *mvcc.ReadView
+	// locks are the row locks the transaction takes.
+	locks *mvcc.LockInfoManager
+	// versions are the versions of the rows before the transactions wrote
+	// them.
+	versions *rowVersions
+	// deadlock is set when a row lock of the transaction failed with a
+	// deadlock, the transaction is to be rolled back.
+	deadlock bool
+}
+
+// openReadView starts a transaction for ctx and opens its read view,
+// closing the one of the transaction before.
+func (srv *XMySQLEngine) openReadView(ctx context.Context) {
+	if srv.mvcc == nil {
+		return
+	}
+	srv.closeReadView(ctx)
+	id := srv.mvcc.BeginTrx()
+	ctx.SetValue(readViewKey, &txnReadView{trxId: id, view: srv.mvcc.CreateView(id), locks: srv.locks, versions: srv.versions})
+}
+
+// closeReadView closes the read view of the transaction of ctx, releases its
+// row locks and ends the transaction, once it committed or rolled back.
+func (srv *XMySQLEngine) closeReadView(ctx context.Context) {
+	v, ok := ctx.Value(readViewKey).(*txnReadView)
+	if !ok {
+		return
+	}
+	ctx.ClearValue(readViewKey)
+	srv.mvcc.CloseView(v.view, false)
+	// The versions are in the history list before the rows are unlocked,
+	// the versions of the next transaction writing them come after.
+	if v.versions != nil {
+		records := v.versions.committed(v.trxId)
+		if srv.purgeSys != nil && len(records) > 0 {
+			if err := srv.purgeSys.AddUndoLog(v.trxId, records); err != nil {
+				log.Errorf("提交的事务加入历史链表失败: %v", err)
+			}
+		}
+	}
+	if v.locks != nil {
+		v.locks.ReleaseLocks(v.trxId)
+	}
+	srv.mvcc.EndTrx(v.trxId)
+}
+
+// CloseSession rolls back the transaction session left open when its
+// connection closes, so that the purge thread doesn't wait for it.
+func (srv *XMySQLEngine) CloseSession(session context.Context) {
+	srv.rollbackRows(session)
+	srv.closeReadView(session)
+}
@@ -0,0 +1,70 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/conf"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/innodb_store/store/storebytes/mvcc"
+)
+
+func TestReadViewHoldsPurge(t *testing.T) {
+	srv := &XMySQLEngine{conf: conf.NewCfg(), infoSchemaManager: newViewTestSchema(), mvcc: mvcc.NewMvcc()}
+	srv.purgeSys = mvcc.NewPurgeSys(srv.mvcc, nil)
+	reader := &txnTestSession{&serverTestSession{session: newViewTestSession(t, newViewTestSchema())}}
+
+	// deleteRow commits a transaction which delete-marked a row.
+	deleteRow := func() {
+		t.Helper()
+		id := srv.mvcc.BeginTrx()
+		srv.mvcc.EndTrx(id)
+		if err := srv.purgeSys.AddUndoLog(id, []*mvcc.UndoRecord{{Type: mvcc.UndoDelMark}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	purge := func(expect int) {
+		t.Helper()
+		n, err := srv.purgeSys.Run(purgeBatchSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != expect {
+			t.Fatalf("expect %d undo logs purged, got %d", expect, n)
+		}
+	}
+
+	// The reader's transaction holds back the purge of the rows deleted
+	// after it started, until it commits.
+	srv.ExecuteQuery(reader, "BEGIN")
+	deleteRow()
+	purge(0)
+	if n := srv.purgeSys.HistoryLength(); n != 1 {
+		t.Fatalf("expect a history of 1, got %d", n)
+	}
+	srv.ExecuteQuery(reader, "COMMIT")
+	purge(1)
+
+	// So does it until it rolls back.
+	srv.ExecuteQuery(reader, "BEGIN")
+	deleteRow()
+	purge(0)
+	srv.ExecuteQuery(reader, "ROLLBACK")
+	purge(1)
+
+	// A new BEGIN ends the transaction before.
+	srv.ExecuteQuery(reader, "BEGIN")
+	deleteRow()
+	srv.ExecuteQuery(reader, "BEGIN")
+	purge(1)
+
+	// So does closing the connection.
+	deleteRow()
+	purge(0)
+	srv.CloseSession(reader)
+	purge(1)
+	if len(reader.errs) != 0 {
+		t.Fatalf("expect no errors, got %v", reader.errs)
+	}
+	if n := srv.mvcc.GetActiveReadViewSize(); n != 0 {
+		t.Errorf("expect no read views left, got %d", n)
+	}
+}
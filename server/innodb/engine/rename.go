@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"github.com/juju/errors"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
+)
+
+// renameTables renames the tables of a resolved RENAME TABLE statement, or
+// of ALTER TABLE ... RENAME, in order. Every pair is checked against the
+// names the pairs before it leave, so that t TO t_old, t_new TO t swaps two
+// tables, and nothing is renamed unless all of them can be.
+func renameTables(is schemas.InfoSchema, pairs []*ast.TableToTable) error {
+	// renamed overrides the InfoSchema with the names taken (true) and freed
+	// (false) by the pairs checked so far.
+	renamed := make(map[string]bool)
+	key := func(schema, table model.CIStr) string {
+		return model.TableNameKey(schema) + "." + model.TableNameKey(table)
+	}
+	exists := func(schema, table model.CIStr) bool {
+		if ok, found := renamed[key(schema, table)]; found {
+			return ok
+		}
+		return is.TableExists(schema, table)
+	}
+	renames := make([]schemas.TableRename, 0, len(pairs))
+	for _, pair := range pairs {
+		old, tn := pair.OldTable, pair.NewTable
+		if !exists(old.Schema, old.Name) {
+			return schemas.ErrTableNotExists.GenByArgs(old.Schema.O, old.Name.O)
+		}
+		if _, ok := is.SchemaByName(tn.Schema); !ok {
+			return schemas.ErrDatabaseNotExists.GenByArgs(tn.Schema.O)
+		}
+		if exists(tn.Schema, tn.Name) {
+			return schemas.ErrTableExists.GenByArgs(tn.Name.O)
+		}
+		renamed[key(old.Schema, old.Name)] = false
+		renamed[key(tn.Schema, tn.Name)] = true
+		renames = append(renames, schemas.TableRename{
+			OldSchema: old.Schema,
+			OldName:   old.Name,
+			NewSchema: tn.Schema,
+			NewName:   tn.Name,
+		})
+	}
+	return errors.Trace(is.RenameTables(renames))
+}
+
+// alterTableRenames returns the rename of ALTER TABLE ... RENAME, nil when
+// stmt doesn't rename the table. Of several RENAME clauses the last one
+// wins.
+func alterTableRenames(stmt *ast.AlterTableStmt) []*ast.TableToTable {
+	var pair *ast.TableToTable
+	for _, spec := range stmt.Specs {
+		if spec.Tp == ast.AlterTableRenameTable {
+			pair = &ast.TableToTable{OldTable: stmt.Table, NewTable: spec.NewTable}
+		}
+	}
+	if pair == nil {
+		return nil
+	}
+	return []*ast.TableToTable{pair}
+}
+
+func init() {
+	registerStmtHandler(&ast.RenameTableStmt{}, &stmtHandler{name: "rename table", handle: (*XMySQLEngine).execRenameTable})
+}
+
+// execRenameTable runs a RENAME TABLE.
+func (srv *XMySQLEngine) execRenameTable(session innodb.MySQLServerSession, stmt ast.StmtNode, p plan.Plan) {
+	x := stmt.(*ast.RenameTableStmt)
+	if err := renameTables(srv.infoSchemaManager, x.TableToTables); err != nil {
+		session.SendError(toSQLError(err))
+		return
+	}
+	session.SendOK()
+}
@@ -0,0 +1,98 @@
+package engine
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// renameTestSchema is a crossDBTestSchema whose tables can be renamed.
+type renameTestSchema struct {
+	*crossDBTestSchema
+}
+
+func (is *renameTestSchema) TableExists(schema, table model.CIStr) bool {
+	_, ok := is.tables[schema.L+"."+table.L]
+	return ok
+}
+
+func (is *renameTestSchema) RenameTables(renames []schemas.TableRename) error {
+	for _, r := range renames {
+		is.tables[r.NewSchema.L+"."+r.NewName.L] = is.tables[r.OldSchema.L+"."+r.OldName.L]
+		delete(is.tables, r.OldSchema.L+"."+r.OldName.L)
+	}
+	return nil
+}
+
+func (is *renameTestSchema) names() []string {
+	names := make([]string, 0, len(is.tables))
+	for name := range is.tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestRenameTable(t *testing.T) {
+	is := &renameTestSchema{&crossDBTestSchema{tables: map[string]schemas.Table{
+		"test.t":      &viewTestTable{meta: newFKTestTable("t", "id")},
+		"test.t_new":  &viewTestTable{meta: newFKTestTable("t_new", "id")},
+		"shop.orders": &viewTestTable{meta: newFKTestTable("orders", "id")},
+	}}}
+	s := newViewTestSession(t, is)
+	rename := func(sql string) error {
+		stmt, _, err := compileView(s, sql)
+		if err != nil {
+			return err
+		}
+		switch x := stmt.(type) {
+		case *ast.RenameTableStmt:
+			return renameTables(is, x.TableToTables)
+		case *ast.AlterTableStmt:
+			return renameTables(is, alterTableRenames(x))
+		}
+		t.Fatalf("%s: unexpected statement", sql)
+		return nil
+	}
+
+	tests := []struct {
+		sql    string
+		code   uint16
+		tables []string
+	}{
+		// The classic swap.
+		{"RENAME TABLE t TO t_old, t_new TO t", 0, []string{"shop.orders", "test.t", "test.t_old"}},
+		{"RENAME TABLE t TO shop.orders", mysql.ErrTableExists, nil},
+		{"RENAME TABLE t TO t2, t_old TO t2", mysql.ErrTableExists, nil},
+		{"RENAME TABLE t TO t2, nope TO t3", mysql.ErrNoSuchTable, nil},
+		{"RENAME TABLE t TO t2, t TO t3", mysql.ErrNoSuchTable, nil},
+		{"RENAME TABLE t TO nodb.t", mysql.ErrBadDB, nil},
+		{"RENAME TABLE t_old TO shop.t_old, shop.t_old TO shop.archive", 0, []string{"shop.archive", "shop.orders", "test.t"}},
+		{"ALTER TABLE t RENAME TO t1", 0, []string{"shop.archive", "shop.orders", "test.t1"}},
+		{"ALTER TABLE shop.orders RENAME AS t1", mysql.ErrTableExists, nil},
+	}
+	for _, tt := range tests {
+		before := is.names()
+		err := rename(tt.sql)
+		if tt.code != 0 {
+			if errCode(err) != tt.code {
+				t.Fatalf("%s: expect error %d, got %v", tt.sql, tt.code, err)
+			}
+			if !reflect.DeepEqual(is.names(), before) {
+				t.Fatalf("%s: expect no table renamed, got %v", tt.sql, is.names())
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tt.sql, err)
+		}
+		if !reflect.DeepEqual(is.names(), tt.tables) {
+			t.Fatalf("%s: expect tables %v, got %v", tt.sql, tt.tables, is.names())
+		}
+	}
+}
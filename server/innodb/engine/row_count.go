@@ -0,0 +1,78 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
+)
+
+// rowCountsFile is the file of the data directory the live row counts of
+// the tables are saved in.
+const rowCountsFile = "row_counts.json"
+
+// rowCountsSaveInterval is how often the live row counts are saved.
+const rowCountsSaveInterval = 10 * time.Second
+
+// initRowCounts reads the live row counts saved before the restart, and
+// lets the optimizer and SHOW TABLE STATUS read them.
+func (srv *XMySQLEngine) initRowCounts() {
+	path := srv.rowCountsFile()
+	if err := srv.statsHandle.LoadRowCounts(path); err != nil && !os.IsNotExist(err) {
+		log.Warnf("从 %s 读入表的行数失败: %v", path, err)
+	}
+	plan.RegisterStatsHandle(srv.statsHandle)
+	schemas.RegisterTableRowCounts(srv.statsHandle)
+}
+
+func (srv *XMySQLEngine) rowCountsFile() string {
+	return filepath.Join(srv.conf.DataDir, rowCountsFile)
+}
+
+// saveRowCounts saves the live row counts in the background, whenever they
+// changed since the last time.
+func (srv *XMySQLEngine) saveRowCounts() {
+	timeTicker := time.NewTicker(rowCountsSaveInterval)
+	for {
+		<-timeTicker.C
+		if _, err := srv.statsHandle.SaveRowCounts(srv.rowCountsFile()); err != nil {
+			log.Warnf("表的行数写入 %s 失败: %v", srv.rowCountsFile(), err)
+		}
+	}
+}
+
+// addRowDelta records in the transaction of ctx that its statement
+// inserted rows of the table tableID, or deleted them when rows < 0.
+func addRowDelta(ctx context.Context, tableID int64, rows int64) {
+	count := rows
+	if count < 0 {
+		count = -count
+	}
+	ctx.GetSessionVars().TxnCtx.UpdateDeltaForTable(tableID, rows, count)
+}
+
+// addRowsChanged records in the transaction of ctx that its statement
+// changed rows of the table tableID in place.
+func addRowsChanged(ctx context.Context, tableID int64, rows int64) {
+	ctx.GetSessionVars().TxnCtx.UpdateDeltaForTable(tableID, 0, rows)
+}
+
+// commitRowDeltas adds the rows the transaction of ctx inserted and
+// deleted to the live row counts.
+func (srv *XMySQLEngine) commitRowDeltas(ctx context.Context) {
+	txnCtx := ctx.GetSessionVars().TxnCtx
+	if srv.statsHandle != nil {
+		srv.statsHandle.ApplyDelta(txnCtx.TableDeltaMap)
+	}
+	txnCtx.ClearDelta()
+}
+
+// rollbackRowDeltas forgets the rows the transaction of ctx inserted and
+// deleted.
+func rollbackRowDeltas(ctx context.Context) {
+	ctx.GetSessionVars().TxnCtx.ClearDelta()
+}
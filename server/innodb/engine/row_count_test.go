@@ -0,0 +1,189 @@
+package engine
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/conf"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/statistics"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// txnTestSession is a session of ExecuteQuery which opens transactions.
+type txnTestSession struct {
+	*serverTestSession
+}
+
+func (s *txnTestSession) NewTxn() error {
+	s.sessionVars.SetStatusFlag(mysql.ServerStatusInTrans, true)
+	return nil
+}
+
+func (s *txnTestSession) Commit() {
+	s.sessionVars.SetStatusFlag(mysql.ServerStatusInTrans, false)
+}
+
+func (s *txnTestSession) RollbackTxn() error {
+	s.sessionVars.SetStatusFlag(mysql.ServerStatusInTrans, false)
+	return nil
+}
+
+func TestRowCount(t *testing.T) {
+	dir, err := ioutil.TempDir("", "datadir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cfg := conf.NewCfg()
+	cfg.DataDir = dir
+	tbl := newTraceTestTable()
+	// The pages of the clustered index estimate 200 rows.
+	is := &fkTestSchema{crossDBTestSchema{tables: map[string]schemas.Table{
+		"test.t": &statusTestTable{viewTestTable{meta: tbl}, 200, 5, 2},
+	}}}
+	srv := &XMySQLEngine{conf: cfg, infoSchemaManager: is, statsHandle: statistics.NewHandle(nil, 0)}
+	srv.initAuditLog()
+	defer srv.auditLog.Close()
+	srv.initRowCounts()
+	defer plan.RegisterStatsHandle(nil)
+	defer schemas.RegisterTableRowCounts(nil)
+	s := &txnTestSession{&serverTestSession{session: newViewTestSession(t, is)}}
+
+	expect := func(want int64) {
+		t.Helper()
+		if got, ok := srv.statsHandle.RowCount(tbl.ID); !ok || got != want {
+			t.Fatalf("expect %d rows, got %d %v", want, got, ok)
+		}
+	}
+	// The table can't be written: a failed INSERT counts nothing.
+	srv.ExecuteQuery(s, "INSERT INTO t VALUES (1, 2, 3), (4, 5, 6)")
+	if len(s.errs) != 1 || s.errs[0].Code != mysql.ErrNotSupportedYet {
+		t.Fatalf("expect error %d, got %v", mysql.ErrNotSupportedYet, s.errs)
+	}
+	if _, ok := srv.statsHandle.RowCount(tbl.ID); ok {
+		t.Fatal("expect no rows counted")
+	}
+	// The rows a statement writes count once it commits.
+	addRowDelta(s, tbl.ID, 2)
+	srv.commitRowDeltas(s)
+	expect(2)
+	srv.ExecuteQuery(s, "BEGIN")
+	addRowDelta(s, tbl.ID, 3)
+	expect(2)
+	srv.ExecuteQuery(s, "COMMIT")
+	expect(5)
+	srv.ExecuteQuery(s, "BEGIN")
+	addRowDelta(s, tbl.ID, 1)
+	srv.ExecuteQuery(s, "ROLLBACK")
+	expect(5)
+	s.errs = nil
+	srv.ExecuteQuery(s, "INSERT INTO t VALUES (19, 20)")
+	if len(s.errs) != 1 || s.errs[0].Code == mysql.ErrNotSupportedYet {
+		t.Fatalf("expect the insert of a short row to fail, got %v", s.errs)
+	}
+	expect(5)
+
+	// SHOW TABLE STATUS and the optimizer read the live count, not the
+	// estimate.
+	rows := schemas.ShowTableStatusRows(is, model.NewCIStr("test"), "dynamic")
+	if len(rows) != 1 || rows[0][4].GetInt64() != 5 {
+		t.Fatalf("expect 5 rows in the table status, got %v", rows)
+	}
+	setTraceVar(t, s.session, "optimizer_trace", "enabled=on")
+	if _, _, err = compileView(s.session, "SELECT id FROM t"); err != nil {
+		t.Fatal(err)
+	}
+	var trace struct {
+		AccessPaths []struct {
+			AccessType string  `json:"access_type"`
+			Rows       float64 `json:"rows"`
+		} `json:"considered_access_paths"`
+	}
+	if err = json.Unmarshal([]byte(s.sessionVars.LastOptimizerTrace.Trace), &trace); err != nil {
+		t.Fatal(err)
+	}
+	if len(trace.AccessPaths) == 0 || trace.AccessPaths[0].AccessType != "table_scan" || trace.AccessPaths[0].Rows != 5 {
+		t.Fatalf("expect a table scan of 5 rows, got %+v", trace.AccessPaths)
+	}
+
+	// The counts start again from the saved ones.
+	if saved, err := srv.statsHandle.SaveRowCounts(srv.rowCountsFile()); !saved || err != nil {
+		t.Fatalf("expect the counts to be saved, got %v %v", saved, err)
+	}
+	if saved, _ := srv.statsHandle.SaveRowCounts(srv.rowCountsFile()); saved {
+		t.Fatal("expect no save without changes")
+	}
+	if _, err = os.Stat(filepath.Join(dir, rowCountsFile)); err != nil {
+		t.Fatal(err)
+	}
+	restarted := &XMySQLEngine{conf: cfg, statsHandle: statistics.NewHandle(nil, 0)}
+	restarted.initRowCounts()
+	if got, ok := restarted.statsHandle.RowCount(tbl.ID); !ok || got != 5 {
+		t.Fatalf("expect 5 rows after the restart, got %d %v", got, ok)
+	}
+
+	// ANALYZE TABLE counts the rows, the live count starts from them.
+	h := srv.statsHandle
+	if err = h.AnalyzeTable(s.sessionVars.StmtCtx, tbl, [][]basic.Datum{basic.MakeDatums(1, 2, 3)}); err != nil {
+		t.Fatal(err)
+	}
+	if got := h.GetTableStats(tbl.ID).Count; got != 1 {
+		t.Fatalf("expect the analyzed count 1, got %d", got)
+	}
+	addRowDelta(s, tbl.ID, 1)
+	srv.commitRowDeltas(s)
+	if got := h.GetTableStats(tbl.ID); got.Count != 2 || got.Pseudo {
+		t.Fatalf("expect the live count 2 in the analyzed statistics, got %+v", got)
+	}
+}
+
+func TestRowCountStored(t *testing.T) {
+	srv, s := newStoredTestEngine(t, newFKTestTable("t", "id", "a"))
+	srv.statsHandle = statistics.NewHandle(nil, 0)
+	srv.initRowCounts()
+	defer plan.RegisterStatsHandle(nil)
+	defer schemas.RegisterTableRowCounts(nil)
+	ts := &txnTestSession{s}
+	tbl := srv.infoSchemaManager.(*viewTestSchema).tables["t"].Meta()
+
+	for _, tt := range []struct {
+		sql  string
+		err  uint16
+		rows int64
+	}{
+		{"INSERT INTO t VALUES (1, 10), (2, 20)", 0, 2},
+		// A failed statement writes and counts nothing.
+		{"INSERT INTO t VALUES (3, 30), (1, 0)", mysql.ErrDupEntry, 2},
+		{"DELETE FROM t WHERE id = 1", 0, 1},
+		{"UPDATE t SET a = 21 WHERE id = 2", 0, 1},
+		// The rows of a transaction count once it commits.
+		{"BEGIN", 0, 1},
+		{"INSERT INTO t VALUES (4, 40), (5, 50)", 0, 1},
+		{"DELETE FROM t WHERE id = 2", 0, 1},
+		{"COMMIT", 0, 2},
+	} {
+		s.errs = nil
+		srv.ExecuteQuery(ts, tt.sql)
+		if tt.err == 0 && len(s.errs) > 0 || tt.err != 0 && (len(s.errs) != 1 || s.errs[0].Code != tt.err) {
+			t.Fatalf("%s: expect error %d, got %v", tt.sql, tt.err, s.errs)
+		}
+		if got, ok := srv.statsHandle.RowCount(tbl.ID); !ok || got != tt.rows {
+			t.Fatalf("%s: expect %d rows, got %d %v", tt.sql, tt.rows, got, ok)
+		}
+	}
+	if got := execStored(t, srv, s, "SELECT * FROM t", 0); got != "4,40;5,50" {
+		t.Fatalf("expect the rows written, got %s", got)
+	}
+	// SHOW TABLE STATUS reads the live count.
+	rows := schemas.ShowTableStatusRows(srv.infoSchemaManager, model.NewCIStr("test"), "dynamic")
+	if len(rows) != 1 || rows[0][4].GetInt64() != 2 {
+		t.Fatalf("expect 2 rows in the table status, got %v", rows)
+	}
+}
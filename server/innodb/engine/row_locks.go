@@ -0,0 +1,103 @@
+package engine
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/juju/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/innodb_store/store/storebytes/mvcc"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/varsutil"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/codec"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+/**
+写行的行锁
+
+INSERT、UPDATE、DELETE、LOAD DATA 和 IMPORT TABLE 通过 tableRowStore 写一行之前，用事务ID给这一行加排他锁（X）：
+UPDATE 和 DELETE 锁旧行，INSERT 和 UPDATE 锁新行，行按表ID和主键的值区分。
+显式事务的行锁保持到 COMMIT 或 ROLLBACK；autocommit 下不在事务中的语句自成一个事务，行锁保持到语句结束。
+等待超过 innodb_lock_wait_timeout 秒返回 ER_LOCK_WAIT_TIMEOUT。
+
+等待形成环时锁管理器返回 ER_LOCK_DEADLOCK，申请锁的事务是牺牲者：语句出错撤销它写过的行，
+语句结束后回滚整个事务，恢复它写过的行，释放它的全部行锁，与它死锁的事务继续。autocommit 的语句
+按 xmysql_autocommit_deadlock_retries 重试（见 deadlock_retry.go）。
+
+只有写行加锁，读行不加锁；语句读出行之后才等到的锁不会让它重新读行。
+**/
+
+// lockRow locks row of tbl in X mode for the transaction of ctx, before it
+// is written. It does nothing outside a transaction or without row locks.
+func lockRow(ctx context.Context, tbl *model.TableInfo, row []basic.Datum) error {
+	v, ok := ctx.Value(readViewKey).(*txnReadView)
+	if !ok || v.locks == nil {
+		return nil
+	}
+	key, err := rowLockKey(tbl, row)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	err = v.locks.LockRow(v.trxId, uint64(tbl.ID), key, mvcc.LockModeX, lockWaitTimeout(ctx.GetSessionVars()))
+	if sqlErr, ok := err.(*mysql.SQLError); ok && sqlErr.Code == mysql.ErrLockDeadlock {
+		v.deadlock = true
+	}
+	return errors.Trace(err)
+}
+
+// rowLockKey returns the key of row of tbl in the row locks, its primary
+// key, or the whole row when tbl has none.
+func rowLockKey(tbl *model.TableInfo, row []basic.Datum) ([]byte, error) {
+	var key []basic.Datum
+	if tbl.PKIsHandle {
+		for _, col := range tbl.Columns {
+			if mysql.HasPriKeyFlag(col.Flag) {
+				key = append(key, row[col.Offset])
+			}
+		}
+	}
+	for _, idx := range tbl.Indices {
+		if idx.Primary && key == nil {
+			for _, col := range idx.Columns {
+				key = append(key, row[col.Offset])
+			}
+		}
+	}
+	if key == nil {
+		key = row
+	}
+	return codec.EncodeKey(nil, key...)
+}
+
+// lockWaitTimeout returns innodb_lock_wait_timeout of vars.
+func lockWaitTimeout(vars *variable.SessionVars) time.Duration {
+	value, err := varsutil.GetSessionSystemVar(vars, "innodb_lock_wait_timeout")
+	if err != nil {
+		return 50 * time.Second
+	}
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 50 * time.Second
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// rollbackDeadlockVictim rolls back the transaction of session once it lost
+// a deadlock, releasing its row locks.
+func (srv *XMySQLEngine) rollbackDeadlockVictim(session innodb.MySQLServerSession) {
+	v, ok := session.Value(readViewKey).(*txnReadView)
+	if !ok || !v.deadlock {
+		return
+	}
+	rollbackRowDeltas(session)
+	srv.rollbackRows(session)
+	srv.closeReadView(session)
+	if err := session.RollbackTxn(); err != nil {
+		log.Errorf("回滚死锁的事务失败: %v", err)
+	}
+}
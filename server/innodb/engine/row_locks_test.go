@@ -0,0 +1,76 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/innodb_store/store/storebytes/mvcc"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+func TestRowLockDeadlock(t *testing.T) {
+	srv, s := newStoredTestEngine(t, newFKTestTable("t", "id", "v"))
+	srv.mvcc, srv.locks = mvcc.NewMvcc(), mvcc.NewLockInfoManager()
+	s.sessionVars.SetStatusFlag(mysql.ServerStatusAutocommit, true)
+	execStored(t, srv, s, "INSERT INTO t VALUES (1, 0), (2, 0)", 0)
+	// The autocommit INSERT released its locks.
+	if n := srv.mvcc.GetActiveReadViewSize(); n != 0 {
+		t.Fatalf("expect the statement transaction to end, got %d read views", n)
+	}
+	newSession := func() *txnTestSession {
+		s := &txnTestSession{&serverTestSession{session: newViewTestSession(t, srv.infoSchemaManager)}}
+		s.sessionVars.SetStatusFlag(mysql.ServerStatusAutocommit, true)
+		return s
+	}
+	exec := func(s *txnTestSession, sql string) []*mysql.SQLError {
+		s.errs = nil
+		srv.ExecuteQuery(s, sql)
+		return s.errs
+	}
+	a, b := newSession(), newSession()
+
+	exec(a, "BEGIN")
+	exec(a, "UPDATE t SET v = 1 WHERE id = 1")
+	exec(b, "BEGIN")
+	exec(b, "UPDATE t SET v = 2 WHERE id = 2")
+	// a waits for the lock b holds on 2.
+	done := make(chan []*mysql.SQLError, 1)
+	go func() { done <- exec(a, "UPDATE t SET v = 1 WHERE id = 2") }()
+	select {
+	case errs := <-done:
+		t.Fatalf("expect a to wait for b, got %v", errs)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// b waiting for a closes the cycle: b fails and is rolled back.
+	if errs := exec(b, "UPDATE t SET v = 2 WHERE id = 1"); len(errs) != 1 || errs[0].Code != mysql.ErrLockDeadlock {
+		t.Fatalf("expect error %d, got %v", mysql.ErrLockDeadlock, errs)
+	}
+	if b.sessionVars.InTxn() {
+		t.Fatal("expect the transaction of b to be rolled back")
+	}
+	select {
+	case errs := <-done:
+		if len(errs) != 0 {
+			t.Fatal(errs)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expect a to get the lock of b")
+	}
+	exec(a, "COMMIT")
+	if got := execStored(t, srv, s, "SELECT * FROM t", 0); got != "1,1;2,1" {
+		t.Fatalf("expect 1,1;2,1, got %s", got)
+	}
+
+	// Waiting longer than innodb_lock_wait_timeout fails the statement.
+	exec(a, "BEGIN")
+	exec(a, "UPDATE t SET v = 4 WHERE id = 1")
+	exec(b, "SET innodb_lock_wait_timeout = 0")
+	if errs := exec(b, "UPDATE t SET v = 3 WHERE id = 1"); len(errs) != 1 || errs[0].Code != mysql.ErrLockWaitTimeout {
+		t.Fatalf("expect error %d, got %v", mysql.ErrLockWaitTimeout, errs)
+	}
+	exec(a, "ROLLBACK")
+	if n := srv.mvcc.GetActiveReadViewSize(); n != 0 {
+		t.Fatalf("expect no transaction left, got %d read views", n)
+	}
+}
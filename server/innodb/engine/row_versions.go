@@ -0,0 +1,303 @@
+package engine
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/juju/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/innodb_store/store/storebytes/mvcc"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
+)
+
+/**
+行的旧版本
+
+聚簇索引中只有行的最新版本。tableRowStore 写一行之前，在 rowVersions 中记下这一行被事务写之前的样子
+（还没有这一行时是 nil），行按表ID和主键的值区分，与行锁相同；一个事务对一行只记第一次写之前的版本。
+同一行的版本从旧到新排列，行锁保证一行最新的版本属于正在写它的事务。
+
+SELECT 和 CHECKSUM TABLE 按事务的读视图读行（一致性读）：从聚簇索引中的行开始，读视图看不到写它的事务时
+退到那个事务写之前的版本，别的事务没有提交的写入、读视图创建之后才提交的写入都看不到。
+不在事务中的语句也有自己的读视图，只看到语句开始时已经提交的行。
+INSERT、UPDATE、DELETE 读最新的行（当前读），加行锁之后再写。
+
+语句出错撤销一次写入时去掉它记下的版本；ROLLBACK 把事务写过的行恢复成它第一次写之前的版本。
+事务提交时把写过的行作为 undo 记录交给 purge 线程（mvcc.PurgeSys），rowVersions 是它的 Purger：
+打开的读视图都看到这个事务的写入之后，purge 才去掉它记下的版本，长事务打开期间之后提交的版本都留着。
+删除直接从聚簇索引中去掉记录，不做删除标记，purge 不用再从索引中去掉记录，只去掉行的旧版本；
+版本只在内存中，没有 undo 页面要释放。
+
+有二级索引的表还不能写，索引扫描直接读索引项，不用考虑旧版本。
+**/
+
+// rowVersion is the version of a row before the transaction trxId wrote it.
+type rowVersion struct {
+	trxId mvcc.TrxId
+	// row is nil when the row didn't exist.
+	row []basic.Datum
+}
+
+// versionKey is a row of a table in the row versions.
+type versionKey struct {
+	tableId int64
+	key     string
+}
+
+// rowVersions are the old versions of the rows the transactions wrote.
+type rowVersions struct {
+	mu sync.Mutex
+	// tables are the versions of the rows of the tables by id, the rows by
+	// key, the oldest version first.
+	tables map[int64]map[string][]rowVersion
+	// written are the rows of the versions each transaction kept, in the
+	// order it first wrote them.
+	written map[mvcc.TrxId][]versionKey
+}
+
+func newRowVersions() *rowVersions {
+	return &rowVersions{tables: make(map[int64]map[string][]rowVersion), written: make(map[mvcc.TrxId][]versionKey)}
+}
+
+// keep keeps row, the version of the row key of the table tableId before the
+// transaction trxId writes it, unless the transaction wrote it before. The
+// function returned drops the version again, when the write is undone.
+func (vs *rowVersions) keep(trxId mvcc.TrxId, tableId int64, key string, row []basic.Datum) func() {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+	chains := vs.tables[tableId]
+	if chains == nil {
+		chains = make(map[string][]rowVersion)
+		vs.tables[tableId] = chains
+	}
+	chain := chains[key]
+	if n := len(chain); n > 0 && chain[n-1].trxId == trxId {
+		return func() {}
+	}
+	chains[key] = append(chain, rowVersion{trxId: trxId, row: row})
+	vs.written[trxId] = append(vs.written[trxId], versionKey{tableId: tableId, key: key})
+	return func() {
+		vs.mu.Lock()
+		defer vs.mu.Unlock()
+		if chain := vs.tables[tableId][key]; len(chain) > 0 && chain[len(chain)-1].trxId == trxId {
+			vs.drop(tableId, key, len(chain)-1)
+		}
+		if written := vs.written[trxId]; len(written) > 0 {
+			vs.written[trxId] = written[:len(written)-1]
+		}
+	}
+}
+
+// drop removes the version i of the row key of the table tableId.
+func (vs *rowVersions) drop(tableId int64, key string, i int) {
+	chains := vs.tables[tableId]
+	chain := chains[key]
+	if i >= len(chain) {
+		return
+	}
+	if chain = append(chain[:i:i], chain[i+1:]...); len(chain) > 0 {
+		chains[key] = chain
+		return
+	}
+	delete(chains, key)
+	if len(chains) == 0 {
+		delete(vs.tables, tableId)
+	}
+}
+
+// visible returns the rows of tbl view sees, given rows, its rows in the
+// clustered index, in its order.
+func (vs *rowVersions) visible(tbl *model.TableInfo, view *mvcc.ReadView, rows [][]basic.Datum) ([][]basic.Datum, error) {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+	chains := vs.tables[tbl.ID]
+	if len(chains) == 0 {
+		return rows, nil
+	}
+	seen := make(map[string]bool, len(chains))
+	visible := make([][]basic.Datum, 0, len(rows))
+	keys := make([]string, 0, len(rows))
+	for _, row := range rows {
+		key, err := rowLockKey(tbl, row)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if chain, ok := chains[string(key)]; ok {
+			seen[string(key)] = true
+			row = visibleVersion(chain, view, row)
+		}
+		if row != nil {
+			visible = append(visible, row)
+			keys = append(keys, string(key))
+		}
+	}
+	// The rows deleted since view was created are back in the order of
+	// their keys.
+	for key, chain := range chains {
+		if seen[key] {
+			continue
+		}
+		if row := visibleVersion(chain, view, nil); row != nil {
+			i := sort.SearchStrings(keys, key)
+			visible = append(visible[:i], append([][]basic.Datum{row}, visible[i:]...)...)
+			keys = append(keys[:i], append([]string{key}, keys[i:]...)...)
+		}
+	}
+	return visible, nil
+}
+
+// visibleVersion returns the version of chain view sees, row being the
+// latest one.
+func visibleVersion(chain []rowVersion, view *mvcc.ReadView, row []basic.Datum) []basic.Datum {
+	for i := len(chain) - 1; i >= 0 && !view.ChangesVisible(chain[i].trxId, ""); i-- {
+		row = chain[i].row
+	}
+	return row
+}
+
+// writtenBy returns the rows the transaction trxId wrote, the last one
+// first, with their versions before it.
+func (vs *rowVersions) writtenBy(trxId mvcc.TrxId) ([]versionKey, [][]basic.Datum) {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+	written := vs.written[trxId]
+	keys := make([]versionKey, 0, len(written))
+	rows := make([][]basic.Datum, 0, len(written))
+	for i := len(written) - 1; i >= 0; i-- {
+		k := written[i]
+		chain := vs.tables[k.tableId][k.key]
+		if n := len(chain); n > 0 && chain[n-1].trxId == trxId {
+			keys = append(keys, k)
+			rows = append(rows, chain[n-1].row)
+		}
+	}
+	return keys, rows
+}
+
+// rolledBack drops the versions the transaction trxId kept, once its rows
+// are restored.
+func (vs *rowVersions) rolledBack(trxId mvcc.TrxId) {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+	for _, k := range vs.written[trxId] {
+		if chain := vs.tables[k.tableId][k.key]; len(chain) > 0 && chain[len(chain)-1].trxId == trxId {
+			vs.drop(k.tableId, k.key, len(chain)-1)
+		}
+	}
+	delete(vs.written, trxId)
+}
+
+// committed returns the undo records of the rows the transaction trxId
+// wrote, at its commit, for purge to drop the versions it kept.
+func (vs *rowVersions) committed(trxId mvcc.TrxId) []*mvcc.UndoRecord {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+	written := vs.written[trxId]
+	delete(vs.written, trxId)
+	// An insert keeps a version too, the row absent, so none of them is an
+	// UndoInsert record freed at the commit.
+	records := make([]*mvcc.UndoRecord, len(written))
+	for i, k := range written {
+		records[i] = &mvcc.UndoRecord{Type: mvcc.UndoUpdate, TableId: uint64(k.tableId), Key: []byte(k.key)}
+	}
+	return records
+}
+
+// PurgeRecord implements mvcc.Purger PurgeRecord interface. It drops the
+// oldest version of the row of rec: purge runs in the order of the commits,
+// the versions of the transactions committed before are already dropped.
+func (vs *rowVersions) PurgeRecord(rec *mvcc.UndoRecord) error {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+	tableId, key := int64(rec.TableId), string(rec.Key)
+	if chain := vs.tables[tableId][key]; len(chain) > 0 && vs.written[chain[0].trxId] == nil {
+		vs.drop(tableId, key, 0)
+	}
+	return nil
+}
+
+// FreeUndoLog implements mvcc.Purger FreeUndoLog interface. The versions
+// are in memory, there are no undo pages to free.
+func (vs *rowVersions) FreeUndoLog(id mvcc.TrxId) error {
+	return nil
+}
+
+// keepVersion keeps the version before of the row key of tbl, before the
+// transaction of ctx writes it. It returns the function dropping it when
+// the write is undone.
+func keepVersion(ctx context.Context, tbl *model.TableInfo, key, before []basic.Datum) (func(), error) {
+	v, ok := ctx.Value(readViewKey).(*txnReadView)
+	if !ok || v.versions == nil {
+		return func() {}, nil
+	}
+	k, err := rowLockKey(tbl, key)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return v.versions.keep(v.trxId, tbl.ID, string(k), before), nil
+}
+
+// rollbackRows restores the rows the transaction of ctx wrote to their
+// versions before it, at its ROLLBACK.
+func (srv *XMySQLEngine) rollbackRows(ctx context.Context) {
+	v, ok := ctx.Value(readViewKey).(*txnReadView)
+	if !ok || v.versions == nil {
+		return
+	}
+	written, rows := v.versions.writtenBy(v.trxId)
+	// The latest rows of the tables written, by key.
+	latest := make(map[int64]map[string][]basic.Datum)
+	for i, k := range written {
+		if err := srv.restoreRow(ctx, latest, k, rows[i]); err != nil {
+			log.Errorf("回滚事务写过的行失败: %v", err)
+		}
+	}
+	// The other transactions read the versions until the rows are back.
+	v.versions.rolledBack(v.trxId)
+}
+
+// restoreRow writes row, or removes the row when it is nil, as the row k,
+// latest being the latest rows of the tables read so far.
+func (srv *XMySQLEngine) restoreRow(ctx context.Context, latest map[int64]map[string][]basic.Datum, k versionKey, row []basic.Datum) error {
+	t, ok := srv.infoSchemaManager.TableByID(k.tableId)
+	if !ok {
+		return errors.Errorf("table %d doesn't exist", k.tableId)
+	}
+	w, ok := t.(schemas.RowWriter)
+	if !ok {
+		return errWriteRows()
+	}
+	rows, ok := latest[k.tableId]
+	if !ok {
+		reader := &scanRowsReader{ctx: ctx, pool: srv.pool}
+		all, err := reader.TableRows(t)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		rows = make(map[string][]basic.Datum, len(all))
+		for _, r := range all {
+			key, err := rowLockKey(t.Meta(), r)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			rows[string(key)] = r
+		}
+		latest[k.tableId] = rows
+	}
+	if cur := rows[k.key]; cur != nil {
+		if err := w.RemoveRow(cur); err != nil {
+			return errors.Trace(err)
+		}
+		delete(rows, k.key)
+	}
+	if row != nil {
+		if err := w.AddRow(row); err != nil {
+			return errors.Trace(err)
+		}
+		rows[k.key] = row
+	}
+	return nil
+}
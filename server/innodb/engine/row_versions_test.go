@@ -0,0 +1,123 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/innodb_store/store/storebytes/mvcc"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// newVersionsTestEngine returns an engine keeping the versions of the rows
+// of the stored table t (id, v), and two sessions with autocommit on.
+func newVersionsTestEngine(t *testing.T) (*XMySQLEngine, *txnTestSession, *txnTestSession) {
+	srv, _ := newStoredTestEngine(t, newFKTestTable("t", "id", "v"))
+	srv.mvcc, srv.locks, srv.versions = mvcc.NewMvcc(), mvcc.NewLockInfoManager(), newRowVersions()
+	srv.purgeSys = mvcc.NewPurgeSys(srv.mvcc, srv.versions)
+	newSession := func() *txnTestSession {
+		s := &txnTestSession{&serverTestSession{session: newViewTestSession(t, srv.infoSchemaManager)}}
+		s.sessionVars.SetStatusFlag(mysql.ServerStatusAutocommit, true)
+		return s
+	}
+	return srv, newSession(), newSession()
+}
+
+// execTxn runs sql in the transactions of s as execStored does.
+func execTxn(t *testing.T, srv *XMySQLEngine, s *txnTestSession, sql string) string {
+	t.Helper()
+	s.rows, s.errs = nil, nil
+	srv.ExecuteQuery(s, sql)
+	if len(s.errs) > 0 {
+		t.Fatalf("%s: %v", sql, s.errs)
+	}
+	return storedRows(s.serverTestSession)
+}
+
+func TestConsistentRead(t *testing.T) {
+	srv, a, b := newVersionsTestEngine(t)
+	expect := func(s *txnTestSession, want string) {
+		t.Helper()
+		if got := execTxn(t, srv, s, "SELECT * FROM t"); got != want {
+			t.Fatalf("expect %s, got %s", want, got)
+		}
+	}
+	execTxn(t, srv, a, "INSERT INTO t VALUES (1, 0), (2, 0)")
+
+	execTxn(t, srv, a, "BEGIN")
+	execTxn(t, srv, a, "INSERT INTO t VALUES (3, 0)")
+	execTxn(t, srv, a, "UPDATE t SET v = 1 WHERE id = 1")
+	execTxn(t, srv, a, "DELETE FROM t WHERE id = 2")
+	expect(a, "1,1;3,0")
+	// b doesn't see the writes a didn't commit, in a transaction or not.
+	expect(b, "1,0;2,0")
+	execTxn(t, srv, b, "BEGIN")
+	expect(b, "1,0;2,0")
+	execTxn(t, srv, a, "COMMIT")
+	// The transaction of b keeps reading the rows of its read view.
+	expect(b, "1,0;2,0")
+	execTxn(t, srv, b, "COMMIT")
+	expect(b, "1,1;3,0")
+
+	// ROLLBACK restores the rows a wrote.
+	execTxn(t, srv, a, "BEGIN")
+	execTxn(t, srv, a, "UPDATE t SET v = 5 WHERE id = 3")
+	execTxn(t, srv, a, "INSERT INTO t VALUES (4, 0)")
+	execTxn(t, srv, a, "DELETE FROM t WHERE id = 1")
+	expect(a, "3,5;4,0")
+	expect(b, "1,1;3,0")
+	execTxn(t, srv, a, "ROLLBACK")
+	expect(a, "1,1;3,0")
+
+	// No read view needs the versions any more.
+	if _, err := srv.purgeSys.Run(purgeBatchSize); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(srv.versions.tables); n != 0 {
+		t.Fatalf("expect the versions to be dropped, got those of %d tables", n)
+	}
+}
+
+func TestPurgeRowVersions(t *testing.T) {
+	srv, a, b := newVersionsTestEngine(t)
+	purge := func(expect int) {
+		t.Helper()
+		n, err := srv.purgeSys.Run(purgeBatchSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != expect {
+			t.Fatalf("expect %d undo logs purged, got %d", expect, n)
+		}
+	}
+	execTxn(t, srv, a, "INSERT INTO t VALUES (1, 0), (2, 0)")
+	purge(1)
+
+	// The rows deleted under a long running reader keep their versions.
+	execTxn(t, srv, b, "BEGIN")
+	execTxn(t, srv, b, "SELECT * FROM t")
+	execTxn(t, srv, a, "DELETE FROM t WHERE id = 1")
+	execTxn(t, srv, a, "UPDATE t SET v = 1 WHERE id = 2")
+	purge(0)
+	if n := srv.purgeSys.HistoryLength(); n != 2 {
+		t.Fatalf("expect a history of 2, got %d", n)
+	}
+	if got := execTxn(t, srv, b, "SELECT * FROM t"); got != "1,0;2,0" {
+		t.Fatalf("expect the reader to see 1,0;2,0, got %s", got)
+	}
+
+	// Once the reader commits they are purged.
+	execTxn(t, srv, b, "COMMIT")
+	purge(2)
+	if n := len(srv.versions.tables); n != 0 {
+		t.Fatalf("expect the versions to be purged, got those of %d tables", n)
+	}
+	if got := execTxn(t, srv, b, "SELECT * FROM t"); got != "2,1" {
+		t.Fatalf("expect 2,1, got %s", got)
+	}
+	stats, err := srv.purgeSys.Stats(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats[mvcc.StatusHistoryListLength] != int64(0) || stats[mvcc.StatusPurgedRecords] != uint64(4) {
+		t.Fatalf("unexpected purge status %v", stats)
+	}
+}
@@ -0,0 +1,347 @@
+package engine
+
+import (
+	"github.com/juju/errors"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/buffer_pool"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/innodb_store/store"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+)
+
+/**
+表和索引的扫描
+
+扫描按键的顺序读取 B+ 树中 [from, to] 之间的行，当前行所在的页面在缓冲池中保持
+pin 住，移到下一个页面时 unpin 前一个。扫描以任何方式结束时都释放它 pin 住的所有
+页面：读到最后一行、Next 中出错时立即释放，执行器出错提前结束时由 Close 释放，
+所以执行器只要保证调用 Close，就不会有页面泄漏在缓冲池中。
+
+读入的页面校验和不对、表空间被标记为损坏时，扫描以 ER_CRASHED_ON_USAGE 结束。
+
+按索引范围扫描时，从索引的开头按键的顺序读，跳过在第一个范围之前的记录，读到超过
+最后一个范围的记录就结束。NULL 比所有的值都小，都在索引的开头：col IS NULL 的范围
+[NULL, NULL] 读到第一个不为 NULL 的键就停下，col IS NOT NULL 的范围 (NULL, +inf]
+跳过开头的 NULL。二级索引的记录是索引的列在前、主键在后，键就是记录的前几列。
+
+松散扫描（loose index scan）只读每组的第一条记录：键的前几列和上一条返回的记录相同的
+记录都跳过，直接读到下一组。GROUP BY 和 DISTINCT 的列是索引的前缀时用它。
+**/
+
+// pagePinner pins the pages of the buffer pool a scan reads.
+type pagePinner interface {
+	PinPage(space uint32, pageNumber uint32) *buffer_pool.BufferBlock
+	UnpinPage(block *buffer_pool.BufferBlock)
+}
+
+// IndexScanExec reads the rows of a B+tree in key order.
+type IndexScanExec struct {
+	baseCursor
+	tree     basic.Tree
+	table    string
+	spaceID  uint32
+	from, to basic.Value
+	pool     pagePinner
+	// ranges are the ranges of the keys of the index the scan returns, in
+	// order, all of them when nil. keyLen is the number of columns of the
+	// keys.
+	ranges []*basic.IndexRange
+	keyLen int
+	// looseColumns is the number of the first columns of the keys a loose
+	// scan returns the first row of each of the values of, 0 for a scan
+	// returning every row. groupKey is the value of the last row returned.
+	looseColumns int
+	groupKey     []basic.Datum
+
+	it  basic.Iterator
+	row basic.Row
+	err error
+	// pinned are the pages the scan holds pinned.
+	pinned map[uint32]*buffer_pool.BufferBlock
+}
+
+// NewIndexScanExec returns a scan of the rows of index of tbl between from
+// and to, all of them when both are nil.
+func NewIndexScanExec(ctx context.Context, tbl schemas.Table, index string, from, to basic.Value, pool pagePinner) *IndexScanExec {
+	return &IndexScanExec{
+		baseCursor: NewBaseCursor(ctx),
+		tree:       tbl.GetBtree(index),
+		table:      tbl.Meta().Name.O,
+		spaceID:    tbl.SpaceId(),
+		from:       from,
+		to:         to,
+		pool:       pool,
+		pinned:     make(map[uint32]*buffer_pool.BufferBlock),
+	}
+}
+
+// NewTableScanExec returns a scan of all the rows of tbl in the order of
+// its primary key.
+func NewTableScanExec(ctx context.Context, tbl schemas.Table, pool pagePinner) *IndexScanExec {
+	return NewIndexScanExec(ctx, tbl, "PRIMARY", nil, nil, pool)
+}
+
+// NewIndexRangeScanExec returns a scan of the rows of index of tbl whose
+// keys are in ranges, as the planner builds them.
+func NewIndexRangeScanExec(ctx context.Context, tbl schemas.Table, index *model.IndexInfo, ranges []*basic.IndexRange, pool pagePinner) *IndexScanExec {
+	e := NewIndexScanExec(ctx, tbl, index.Name.O, nil, nil, pool)
+	e.ranges, e.keyLen = ranges, len(index.Columns)
+	return e
+}
+
+// NewLooseIndexScanExec returns a scan of index of tbl like
+// NewIndexRangeScanExec, which only returns the first row of the keys having
+// the same first columns and skips to the next value of them.
+func NewLooseIndexScanExec(ctx context.Context, tbl schemas.Table, index *model.IndexInfo, ranges []*basic.IndexRange, columns int, pool pagePinner) *IndexScanExec {
+	e := NewIndexRangeScanExec(ctx, tbl, index, ranges, pool)
+	e.looseColumns = columns
+	return e
+}
+
+func (e *IndexScanExec) Open() error {
+	if e.tree == nil {
+		return errors.New("no such index")
+	}
+	if store.IsSpaceCorrupted(e.spaceID) {
+		return ErrCrashedOnUsage.GenByArgs(e.table)
+	}
+	it, err := e.tree.Range(e.from, e.to)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	e.it, e.row, e.err, e.groupKey = it, nil, nil, nil
+	return nil
+}
+
+// Next moves to the next row and pins its page. At the end of the scan or
+// on error it unpins every page, and Err returns the error.
+func (e *IndexScanExec) Next() bool {
+	for e.it != nil {
+		pageNo, _, row, err, it := e.it()
+		if err != nil || it == nil {
+			e.end(errors.Trace(err))
+			return false
+		}
+		e.it, e.row = it, row
+		if e.ranges != nil {
+			in, past, err := e.inRanges(row)
+			if err != nil || past {
+				// The keys after are past the last range too.
+				e.end(errors.Trace(err))
+				return false
+			}
+			if !in {
+				continue
+			}
+		}
+		if e.looseColumns > 0 {
+			next, err := e.nextGroup(row)
+			if err != nil {
+				e.end(errors.Trace(err))
+				return false
+			}
+			if !next {
+				continue
+			}
+		}
+		if _, ok := e.pinned[pageNo]; !ok && e.pool != nil {
+			e.unpinAll()
+			e.pinned[pageNo] = e.pool.PinPage(e.spaceID, pageNo)
+		}
+		// The pages read may have failed their checksum.
+		if store.IsSpaceCorrupted(e.spaceID) {
+			e.end(ErrCrashedOnUsage.GenByArgs(e.table))
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+// end ends the scan with err, unpinning every page.
+func (e *IndexScanExec) end(err error) {
+	e.err = err
+	e.it, e.row = nil, nil
+	e.unpinAll()
+}
+
+// inRanges reports whether the key of row is in one of the ranges of the
+// scan, and whether it is past the last one.
+func (e *IndexScanExec) inRanges(row basic.Row) (in bool, past bool, err error) {
+	key := row.ToDatum()
+	if len(key) > e.keyLen {
+		key = key[:e.keyLen]
+	}
+	sc := e.ctx.GetSessionVars().StmtCtx
+	for _, ran := range e.ranges {
+		cmp, err := compareKey(sc, key, ran.LowVal)
+		if err != nil {
+			return false, false, errors.Trace(err)
+		}
+		if cmp < 0 || cmp == 0 && ran.LowExclude {
+			// The ranges are in order, the key is before all the next ones.
+			return false, false, nil
+		}
+		if cmp, err = compareKey(sc, key, ran.HighVal); err != nil {
+			return false, false, errors.Trace(err)
+		}
+		if cmp < 0 || cmp == 0 && !ran.HighExclude {
+			return true, false, nil
+		}
+	}
+	return false, true, nil
+}
+
+// nextGroup reports whether row starts the next group of a loose scan, the
+// first columns of its key differing from the ones of the last row returned.
+func (e *IndexScanExec) nextGroup(row basic.Row) (bool, error) {
+	key := row.ToDatum()
+	if len(key) > e.looseColumns {
+		key = key[:e.looseColumns]
+	}
+	if e.groupKey != nil {
+		cmp, err := compareKey(e.ctx.GetSessionVars().StmtCtx, key, e.groupKey)
+		if err != nil || cmp == 0 {
+			return false, errors.Trace(err)
+		}
+	}
+	e.groupKey = append(e.groupKey[:0], key...)
+	return true, nil
+}
+
+// indexScanRows returns the rows of the index scan is, the entries of its
+// index in the columns of its schema. ok is false for the scans it can't
+// run: the ones reading the rows of the table too, or the index backwards.
+func indexScanRows(ctx context.Context, is *plan.PhysicalIndexScan) (rows [][]basic.Datum, ok bool, err error) {
+	if is.DoubleRead || is.Desc {
+		return nil, false, nil
+	}
+	info, ok := ctx.GetSessionVars().TxnCtx.InfoSchema.(schemas.InfoSchema)
+	if !ok {
+		return nil, false, nil
+	}
+	tbl, ok := info.TableByID(is.Table.ID)
+	if !ok {
+		return nil, false, nil
+	}
+	// The entries of a secondary index are its columns, then the primary
+	// key.
+	offsets := make([]int, len(is.Columns))
+	for i, col := range is.Columns {
+		offsets[i] = len(is.Index.Columns)
+		for j, idxCol := range is.Index.Columns {
+			if idxCol.Name.L == col.Name.L {
+				offsets[i] = j
+				break
+			}
+		}
+	}
+	e := NewLooseIndexScanExec(ctx, tbl, is.Index, is.Ranges, is.LooseScanColumns, nil)
+	if err = e.Open(); err != nil {
+		return nil, true, errors.Trace(err)
+	}
+	defer e.Close()
+	for e.Next() {
+		entry := e.GetRow().ToDatum()
+		row := make([]basic.Datum, len(offsets))
+		for i, offset := range offsets {
+			if offset < len(entry) {
+				row[i] = entry[offset]
+			}
+		}
+		if err = consumeRow(ctx, row); err != nil {
+			return nil, true, errors.Trace(err)
+		}
+		rows = append(rows, row)
+	}
+	return rows, true, errors.Trace(e.Err())
+}
+
+// tableScanRows returns the columns ts reads of the rows of its table its
+// access conditions keep, in the order of the clustered index.
+func tableScanRows(ctx context.Context, ts *plan.PhysicalTableScan) (rows [][]basic.Datum, ok bool, err error) {
+	if ts.Aggregated {
+		return nil, false, nil
+	}
+	info, ok := ctx.GetSessionVars().TxnCtx.InfoSchema.(schemas.InfoSchema)
+	if !ok {
+		return nil, false, nil
+	}
+	tbl, ok := info.TableByID(ts.Table.ID)
+	if !ok || tbl.GetBtree("PRIMARY") == nil {
+		return nil, false, nil
+	}
+	reader := &scanRowsReader{ctx: ctx, consistent: true}
+	all, err := reader.TableRows(tbl)
+	if err != nil {
+		return nil, true, errors.Trace(err)
+	}
+	for _, row := range all {
+		cols := make([]basic.Datum, len(ts.Columns))
+		for i, col := range ts.Columns {
+			if col.Offset < len(row) {
+				cols[i] = row[col.Offset]
+			}
+		}
+		if err = consumeRow(ctx, cols); err != nil {
+			return nil, true, errors.Trace(err)
+		}
+		rows = append(rows, cols)
+	}
+	if rows, err = selectRows(ctx, ts.AccessCondition, rows); err != nil {
+		return nil, true, errors.Trace(err)
+	}
+	if ts.Desc {
+		reverseRows(rows)
+	}
+	if rows, err = sortRows(ctx, ts.SortItems(), rows); err != nil {
+		return nil, true, errors.Trace(err)
+	}
+	return scanLimitRows(ts.LimitCount, rows), true, nil
+}
+
+// compareKey compares key with the values of a bound of a range, on the
+// columns the bound has.
+func compareKey(sc *variable.StatementContext, key, bound []basic.Datum) (int, error) {
+	for i := 0; i < len(key) && i < len(bound); i++ {
+		cmp, err := key[i].CompareDatum(sc, &bound[i])
+		if err != nil || cmp != 0 {
+			return cmp, errors.Trace(err)
+		}
+	}
+	return 0, nil
+}
+
+func (e *IndexScanExec) GetRow() basic.Row {
+	return e.row
+}
+
+// Err returns the error which ended the scan.
+func (e *IndexScanExec) Err() error {
+	return e.err
+}
+
+// Close ends the scan and unpins every page it holds.
+func (e *IndexScanExec) Close() error {
+	e.it, e.row = nil, nil
+	e.unpinAll()
+	return nil
+}
+
+func (e *IndexScanExec) Type() string {
+	return "IndexScan"
+}
+
+func (e *IndexScanExec) CursorName() string {
+	return "IndexScan"
+}
+
+func (e *IndexScanExec) unpinAll() {
+	for pageNo, block := range e.pinned {
+		e.pool.UnpinPage(block)
+		delete(e.pinned, pageNo)
+	}
+}
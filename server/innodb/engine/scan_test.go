@@ -0,0 +1,97 @@
+package engine
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/conf"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/buffer_pool"
+)
+
+// scanTestTree holds rows rows, two per page, and fails reading row failAt
+// when it is set.
+type scanTestTree struct {
+	basic.Tree
+	rows   int
+	failAt int
+}
+
+func (tree *scanTestTree) Range(from, to basic.Value) (basic.Iterator, error) {
+	var next func(i int) basic.Iterator
+	next = func(i int) basic.Iterator {
+		return func() (uint32, basic.Value, basic.Row, error, basic.Iterator) {
+			if tree.failAt > 0 && i == tree.failAt {
+				return 0, nil, nil, errors.New("corrupted page"), nil
+			}
+			if i >= tree.rows {
+				return 0, nil, nil, nil, nil
+			}
+			return uint32(3 + i/2), nil, nil, nil, next(i + 1)
+		}
+	}
+	return next(0), nil
+}
+
+// scanTestTable is a table whose primary key is tree.
+type scanTestTable struct {
+	*spaceTestTable
+	tree basic.Tree
+}
+
+func (t *scanTestTable) GetBtree(indexName string) basic.Tree {
+	return t.tree
+}
+
+func TestIndexScanReleasesPins(t *testing.T) {
+	fs := basic.NewFileSystem(conf.NewCfg())
+	fs.AddTableSpace(dumpTestSpace(5))
+	pool := buffer_pool.NewBufferPool(16*16384, 0.75, 0.25, 1000, fs)
+	is := newViewTestSchema(newFKTestTable("t", "id"))
+	s := newViewTestSession(t, is)
+	tree := &scanTestTree{rows: 6}
+	tbl := &scanTestTable{spaceTestTable: &spaceTestTable{viewTestTable: is.tables["t"].(*viewTestTable), spaceId: 5}, tree: tree}
+
+	// A scan read to the end holds the page of its current row only.
+	scan := NewTableScanExec(s, tbl, pool)
+	if err := scan.Open(); err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for scan.Next() {
+		n++
+		if pool.PinnedPages() != 1 {
+			t.Fatalf("expect the current page pinned, got %d pins", pool.PinnedPages())
+		}
+	}
+	if n != 6 || scan.Err() != nil || pool.PinnedPages() != 0 {
+		t.Fatalf("expect 6 rows and no pins left, got %d rows, %v, %d pins", n, scan.Err(), pool.PinnedPages())
+	}
+
+	// An error in the middle of the scan releases its pages.
+	tree.failAt = 3
+	if err := scan.Open(); err != nil {
+		t.Fatal(err)
+	}
+	for scan.Next() {
+	}
+	if scan.Err() == nil || pool.PinnedPages() != 0 {
+		t.Fatalf("expect the error and no pins left, got %v, %d pins", scan.Err(), pool.PinnedPages())
+	}
+
+	// A scan under an executor ending early is released by Close.
+	tree.failAt = 0
+	sel := &ProjectionExec{baseCursor: NewBaseCursor(s, NewTableScanExec(s, tbl, pool))}
+	if err := sel.Open(); err != nil {
+		t.Fatal(err)
+	}
+	if !sel.Next() || pool.PinnedPages() != 1 {
+		t.Fatalf("expect a row read with its page pinned, got %d pins", pool.PinnedPages())
+	}
+	if err := sel.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if pool.PinnedPages() != 0 {
+		t.Fatalf("expect no pins left after Close, got %d", pool.PinnedPages())
+	}
+}
@@ -0,0 +1,192 @@
+package engine
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/varsutil"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// selectIntoOutfile streams the rows of cursor into the file named by opt
+// and returns the number of rows written. The file must not exist yet.
+func selectIntoOutfile(ctx context.Context, opt *ast.SelectIntoOption, cursor basic.Cursor) (uint64, error) {
+	path, err := secureFilePath(ctx, opt.FileName)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		if os.IsExist(err) {
+			return 0, ErrFileExists.GenByArgs(opt.FileName)
+		}
+		return 0, errors.Trace(err)
+	}
+	defer f.Close()
+
+	if err = cursor.Open(); err != nil {
+		return 0, errors.Trace(err)
+	}
+	defer cursor.Close()
+	w := newOutfileWriter(f, opt.FieldsInfo, opt.LinesInfo)
+	var count uint64
+	for cursor.Next() {
+		if err = w.writeRow(cursor.GetRow().ToDatum()); err != nil {
+			return count, errors.Trace(err)
+		}
+		count++
+	}
+	if err = w.flush(); err != nil {
+		return count, errors.Trace(err)
+	}
+	return count, errors.Trace(f.Close())
+}
+
+// secureFilePath resolves name against secure_file_priv: "NULL" disables
+// file import and export, an empty value allows any path, and a directory
+// only allows the files under it. A relative name is taken relative to
+// that directory.
+func secureFilePath(ctx context.Context, name string) (string, error) {
+	priv, err := varsutil.GetSessionSystemVar(ctx.GetSessionVars(), variable.SecureFilePriv)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	if strings.EqualFold(priv, "NULL") {
+		return "", ErrOptionPreventsStatement.GenByArgs("--secure-file-priv")
+	}
+	if priv == "" {
+		return filepath.Abs(name)
+	}
+	dir, err := filepath.Abs(priv)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	path := name
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	path = filepath.Clean(path)
+	if path != dir && !strings.HasPrefix(path, dir+string(filepath.Separator)) {
+		return "", ErrOptionPreventsStatement.GenByArgs("--secure-file-priv")
+	}
+	return path, nil
+}
+
+// outfileWriter writes rows the way SELECT ... INTO OUTFILE formats them.
+type outfileWriter struct {
+	w      *bufio.Writer
+	fields *ast.FieldsClause
+	lines  *ast.LinesClause
+}
+
+func newOutfileWriter(w io.Writer, fields *ast.FieldsClause, lines *ast.LinesClause) *outfileWriter {
+	return &outfileWriter{w: bufio.NewWriter(w), fields: fields, lines: lines}
+}
+
+func (w *outfileWriter) writeRow(row []basic.Datum) error {
+	for i := range row {
+		if i > 0 {
+			w.w.WriteString(w.fields.Terminated)
+		}
+		if err := w.writeField(&row[i]); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	_, err := w.w.WriteString(w.lines.Terminated)
+	return errors.Trace(err)
+}
+
+// writeField writes a value enclosed and escaped as configured. NULL is
+// written as \N, or as NULL when there is no escape character.
+func (w *outfileWriter) writeField(d *basic.Datum) error {
+	escape := w.fields.Escaped
+	if d.IsNull() {
+		if escape == 0 {
+			w.w.WriteString("NULL")
+		} else {
+			w.w.WriteByte(escape)
+			w.w.WriteByte('N')
+		}
+		return nil
+	}
+	s, err := d.ToString()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if w.fields.Enclosed != 0 {
+		w.w.WriteByte(w.fields.Enclosed)
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if escape != 0 && w.needEscape(c) {
+			w.w.WriteByte(escape)
+			if c == 0 {
+				c = '0'
+			}
+		}
+		w.w.WriteByte(c)
+	}
+	if w.fields.Enclosed != 0 {
+		w.w.WriteByte(w.fields.Enclosed)
+	}
+	return nil
+}
+
+// needEscape reports whether c must be prefixed with the escape character:
+// the escape and enclose characters and ASCII NUL always are, and the first
+// characters of the terminators are when fields are not enclosed.
+func (w *outfileWriter) needEscape(c byte) bool {
+	switch {
+	case c == w.fields.Escaped, c == 0:
+		return true
+	case w.fields.Enclosed != 0:
+		return c == w.fields.Enclosed
+	}
+	return startsWith(w.fields.Terminated, c) || startsWith(w.lines.Terminated, c)
+}
+
+func (w *outfileWriter) flush() error {
+	return errors.Trace(w.w.Flush())
+}
+
+func startsWith(s string, c byte) bool {
+	return len(s) > 0 && s[0] == c
+}
+
+func init() {
+	registerStmtHandler(&ast.SelectStmt{}, &stmtHandler{
+		name:     "select into outfile",
+		priority: 1,
+		match: func(stmt ast.StmtNode) bool {
+			return stmt.(*ast.SelectStmt).SelectIntoOpt != nil
+		},
+		handle: (*XMySQLEngine).execSelectInto,
+	})
+}
+
+// execSelectInto writes the rows of a SELECT ... INTO OUTFILE.
+func (srv *XMySQLEngine) execSelectInto(session innodb.MySQLServerSession, stmt ast.StmtNode, p plan.Plan) {
+	x := stmt.(*ast.SelectStmt)
+	cursor := NewCursorBuilder(session, srv.infoSchemaManager).build(p)
+	if cursor == nil {
+		session.SendError(mysql.NewErrf(mysql.ErrNotSupportedYet, "this query"))
+		return
+	}
+	count, err := selectIntoOutfile(session, x.SelectIntoOpt, cursor)
+	if err != nil {
+		session.SendError(toSQLError(err))
+		return
+	}
+	session.GetSessionVars().StmtCtx.AddAffectedRows(count)
+	session.SendOK()
+}
@@ -0,0 +1,213 @@
+package engine
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// datumRow is a basic.Row that only carries its values.
+type datumRow struct {
+	basic.Row
+	datums []basic.Datum
+}
+
+func (r datumRow) ToDatum() []basic.Datum {
+	return r.datums
+}
+
+// datumCursor returns rows from memory.
+type datumCursor struct {
+	basic.Cursor
+	rows [][]basic.Datum
+	pos  int
+}
+
+func (c *datumCursor) Open() error {
+	c.pos = -1
+	return nil
+}
+
+func (c *datumCursor) Next() bool {
+	c.pos++
+	return c.pos < len(c.rows)
+}
+
+func (c *datumCursor) GetRow() basic.Row {
+	return datumRow{datums: c.rows[c.pos]}
+}
+
+func (c *datumCursor) Close() error {
+	return nil
+}
+
+func newOutfileTestSession(t *testing.T, secureFilePriv string) *session {
+	s := newInsertTestSession(t, "STRICT_TRANS_TABLES")
+	s.sessionVars.Systems[variable.SecureFilePriv] = secureFilePriv
+	return s
+}
+
+func newOutfileTestDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "outfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func outfileTestRows() [][]basic.Datum {
+	return [][]basic.Datum{
+		basic.MakeDatums(int64(1), "a,b", nil),
+		basic.MakeDatums(int64(2), "say \"hi\"\n", "c:\\tmp\tx"),
+		basic.MakeDatums(nil, "", "NULL"),
+	}
+}
+
+func TestSelectIntoOutfileCSV(t *testing.T) {
+	dir := newOutfileTestDir(t)
+	defer os.RemoveAll(dir)
+	s := newOutfileTestSession(t, dir)
+	opt := &ast.SelectIntoOption{
+		Tp:         ast.SelectIntoOutfile,
+		FileName:   "t.csv",
+		FieldsInfo: &ast.FieldsClause{Terminated: ",", Enclosed: '"', Escaped: '\\'},
+		LinesInfo:  &ast.LinesClause{Terminated: "\r\n"},
+	}
+	count, err := selectIntoOutfile(s, opt, &datumCursor{rows: outfileTestRows()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Fatalf("expect 3 rows written, got %d", count)
+	}
+	content, err := ioutil.ReadFile(filepath.Join(dir, "t.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := "\"1\",\"a,b\",\\N\r\n" +
+		"\"2\",\"say \\\"hi\\\"\n\",\"c:\\\\tmp\tx\"\r\n" +
+		"\\N,\"\",\"NULL\"\r\n"
+	if string(content) != expect {
+		t.Fatalf("expect %q, got %q", expect, content)
+	}
+}
+
+func TestSelectIntoOutfileRoundTrip(t *testing.T) {
+	for _, fields := range []*ast.FieldsClause{
+		{Terminated: "\t", Escaped: '\\'},
+		{Terminated: ",", Enclosed: '"', Escaped: '\\'},
+		{Terminated: "||", Enclosed: '\''},
+	} {
+		dir := newOutfileTestDir(t)
+		s := newOutfileTestSession(t, dir)
+		opt := &ast.SelectIntoOption{
+			Tp:         ast.SelectIntoOutfile,
+			FileName:   filepath.Join(dir, "t.txt"),
+			FieldsInfo: fields,
+			LinesInfo:  &ast.LinesClause{Terminated: "\n"},
+		}
+		rows := outfileTestRows()
+		if fields.Escaped == 0 {
+			// Without an escape character a NULL string can't be told from NULL.
+			rows = rows[:2]
+		}
+		if _, err := selectIntoOutfile(s, opt, &datumCursor{rows: rows}); err != nil {
+			t.Fatal(err)
+		}
+
+		f, err := os.Open(opt.FileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := newInfileReader(f, opt.FieldsInfo, opt.LinesInfo)
+		for i, row := range rows {
+			got, err := r.readRow()
+			if err != nil {
+				t.Fatalf("%+v: row %d: %v", fields, i, err)
+			}
+			if len(got) != len(row) {
+				t.Fatalf("%+v: row %d: expect %d fields, got %d", fields, i, len(row), len(got))
+			}
+			for j := range row {
+				if row[j].IsNull() != got[j].IsNull() {
+					t.Fatalf("%+v: row %d field %d: expect NULL %v", fields, i, j, row[j].IsNull())
+				}
+				expect, _ := row[j].ToString()
+				if actual, _ := got[j].ToString(); actual != expect {
+					t.Fatalf("%+v: row %d field %d: expect %q, got %q", fields, i, j, expect, actual)
+				}
+			}
+		}
+		if _, err = r.readRow(); err != io.EOF {
+			t.Fatalf("%+v: expect EOF, got %v", fields, err)
+		}
+		f.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestSelectIntoOutfileExists(t *testing.T) {
+	dir := newOutfileTestDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "t.txt")
+	if err := ioutil.WriteFile(path, []byte("keep"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := newOutfileTestSession(t, dir)
+	opt := &ast.SelectIntoOption{
+		Tp:         ast.SelectIntoOutfile,
+		FileName:   path,
+		FieldsInfo: &ast.FieldsClause{Terminated: "\t", Escaped: '\\'},
+		LinesInfo:  &ast.LinesClause{Terminated: "\n"},
+	}
+	_, err := selectIntoOutfile(s, opt, &datumCursor{rows: outfileTestRows()})
+	if errCode(err) != mysql.ErrFileExists {
+		t.Fatalf("expect error %d, got %v", mysql.ErrFileExists, err)
+	}
+	if content, _ := ioutil.ReadFile(path); string(content) != "keep" {
+		t.Fatalf("expect the file to be kept, got %q", content)
+	}
+}
+
+func TestSecureFilePriv(t *testing.T) {
+	dir := newOutfileTestDir(t)
+	defer os.RemoveAll(dir)
+	tests := []struct {
+		priv string
+		name string
+		ok   bool
+	}{
+		{"NULL", filepath.Join(dir, "t.txt"), false},
+		{"null", "t.txt", false},
+		{"", filepath.Join(dir, "t.txt"), true},
+		{dir, filepath.Join(dir, "t.txt"), true},
+		{dir, "t.txt", true},
+		{dir, filepath.Join(dir, "..", "t.txt"), false},
+		{dir, dir + "x" + string(filepath.Separator) + "t.txt", false},
+		{filepath.Join(dir, "sub"), filepath.Join(dir, "t.txt"), false},
+	}
+	for _, tt := range tests {
+		s := newOutfileTestSession(t, tt.priv)
+		path, err := secureFilePath(s, tt.name)
+		if !tt.ok {
+			if errCode(err) != mysql.ErrOptionPreventsStatement {
+				t.Fatalf("secure_file_priv %q, file %q: expect error %d, got %v", tt.priv, tt.name, mysql.ErrOptionPreventsStatement, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("secure_file_priv %q, file %q: %v", tt.priv, tt.name, err)
+		}
+		if !strings.HasPrefix(path, dir) {
+			t.Fatalf("secure_file_priv %q, file %q: expect a path under %s, got %s", tt.priv, tt.name, dir, path)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package engine
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+)
+
+// Server status variables.
+const (
+	StatusQuestions       = "Questions"
+	StatusUptime          = "Uptime"
+	StatusDeadlockRetries = "Xmysql_autocommit_deadlock_retries"
+)
+
+// serverStatistics reports the statements the clients sent, the time the
+// server has been up and the autocommit statements retried after a
+// deadlock as status variables.
+type serverStatistics struct {
+	startTime       time.Time
+	questions       uint64
+	deadlockRetries uint64
+}
+
+// GetScope implements variable.Statistics GetScope interface.
+func (s *serverStatistics) GetScope(status string) variable.ScopeFlag {
+	return variable.ScopeGlobal
+}
+
+// Stats implements variable.Statistics Stats interface.
+func (s *serverStatistics) Stats(vars *variable.SessionVars) (map[string]interface{}, error) {
+	return map[string]interface{}{
+		StatusQuestions:       atomic.LoadUint64(&s.questions),
+		StatusUptime:          int64(time.Since(s.startTime) / time.Second),
+		StatusDeadlockRetries: atomic.LoadUint64(&s.deadlockRetries),
+	}, nil
+}
+
+func (srv *XMySQLEngine) initServerStatus() {
+	srv.serverStatus.startTime = time.Now()
+	variable.RegisterStatistics(&srv.serverStatus)
+}
@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/juju/errors"
@@ -165,6 +166,26 @@ func (s *session) GetSessionVars() *variable.SessionVars {
 	return s.sessionVars
 }
 
+// Txn implements the context.Context interface. The session doesn't start
+// transactions of its own, the planner sees it as read only.
+func (s *session) Txn() basic.XMySQLTransaction {
+	return nil
+}
+
+// lastConnectionID is the connection id of the last session created.
+var lastConnectionID uint64
+
+// NextConnectionID returns the id of a new connection, network or embedded.
+func NextConnectionID() uint64 {
+	return atomic.AddUint64(&lastConnectionID, 1)
+}
+
+// InfoSchema returns the schemas of the server, which the sessions resolve
+// names with.
+func (srv *XMySQLEngine) InfoSchema() schemas.InfoSchema {
+	return srv.infoSchemaManager
+}
+
 // Some vars name for debug.
 const (
 	retryEmptyHistoryList = "RetryEmptyHistoryList"
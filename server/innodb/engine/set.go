@@ -0,0 +1,181 @@
+package engine
+
+import (
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/expression"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/varsutil"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/charset"
+)
+
+// setVariables executes SET: user variables, session and global system
+// variables, and SET PERSIST, which sets the global value and writes it to
+// the persisted config file.
+func setVariables(ctx context.Context, p *plan.Set) error {
+	vars := ctx.GetSessionVars()
+	for _, v := range p.VarAssigns {
+		name := strings.ToLower(v.Name)
+		if v.Name == ast.SetNames || v.Name == ast.SetCharset {
+			if err := setNames(vars, v); err != nil {
+				return errors.Trace(err)
+			}
+			continue
+		}
+		if !v.IsSystem {
+			value, err := v.Expr.Eval(nil)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			vars.UsersLock.Lock()
+			if value.IsNull() {
+				delete(vars.Users, name)
+			} else {
+				vars.Users[name], err = value.ToString()
+			}
+			vars.UsersLock.Unlock()
+			if err != nil {
+				return errors.Trace(err)
+			}
+			vars.TrackStateChange()
+			continue
+		}
+		value, err := sysVarValue(name, v)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if name == variable.DefaultStorageEngine && !value.IsNull() {
+			if value, err = storageEngineValue(value); err != nil {
+				return errors.Trace(err)
+			}
+		}
+		if !v.IsGlobal {
+			if err = varsutil.SetSessionSystemVar(vars, name, value); err != nil {
+				return errors.Trace(err)
+			}
+			continue
+		}
+		if err = varsutil.SetGlobalSystemVar(vars, name, value); err != nil {
+			return errors.Trace(err)
+		}
+		if v.IsPersist {
+			if err = persistVariable(vars, name); err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+	return nil
+}
+
+// sysVarValue returns the value v assigns to the system variable name, its
+// compiled-in default for DEFAULT.
+func sysVarValue(name string, v *expression.VarAssignment) (basic.Datum, error) {
+	if v.IsDefault {
+		sv := variable.GetSysVar(name)
+		if sv == nil {
+			return basic.Datum{}, variable.UnknownSystemVar.GenByArgs(name)
+		}
+		return basic.NewStringDatum(sv.Value), nil
+	}
+	return v.Expr.Eval(nil)
+}
+
+// storageEngineValue returns the name of the storage engine value names,
+// which default_storage_engine is set to.
+func storageEngineValue(value basic.Datum) (basic.Datum, error) {
+	name, err := value.ToString()
+	if err != nil {
+		return basic.Datum{}, errors.Trace(err)
+	}
+	if name, err = storageEngineName(name); err != nil {
+		return basic.Datum{}, err
+	}
+	return basic.NewStringDatum(name), nil
+}
+
+// setNames executes SET NAMES charset [COLLATE collation], which sets the
+// client, connection and results charsets to charset, and SET CHARACTER SET
+// charset, which sets the connection charset to the one of the database
+// instead. The charset and the collation are checked before any variable
+// is set, so that either all of them change or none.
+func setNames(vars *variable.SessionVars, v *expression.VarAssignment) error {
+	value, err := v.Expr.Eval(nil)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	name, err := value.ToString()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	cs, collation, err := charset.GetCharsetInfo(name)
+	if err != nil {
+		return ErrUnknownCharacterSet.GenByArgs(name)
+	}
+	if v.ExtendValue != nil {
+		if collation, err = v.ExtendValue.Value.ToString(); err != nil {
+			return errors.Trace(err)
+		}
+		collation = strings.ToLower(collation)
+		if err = checkCollation(cs, collation); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	connection := cs
+	if v.Name == ast.SetCharset {
+		if connection, err = varsutil.GetSessionSystemVar(vars, variable.CharsetDatabase); err != nil {
+			return errors.Trace(err)
+		}
+		if collation, err = varsutil.GetSessionSystemVar(vars, variable.CollationDatabase); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	for _, set := range []struct{ name, value string }{
+		{"character_set_client", cs},
+		{variable.CharacterSetConnection, connection},
+		{variable.CharacterSetResults, cs},
+		{variable.CollationConnection, collation},
+	} {
+		if err = varsutil.SetSessionSystemVar(vars, set.name, basic.NewStringDatum(set.value)); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// checkCollation returns the error of collation when it isn't one of cs.
+func checkCollation(cs, collation string) error {
+	if charset.ValidCharsetAndCollation(cs, collation) {
+		return nil
+	}
+	for _, c := range charset.GetCollations() {
+		if c.Name == collation {
+			return ErrCollationCharsetMismatch.GenByArgs(collation, cs)
+		}
+	}
+	return ErrUnknownCollation.GenByArgs(collation)
+}
+
+func init() {
+	registerStmtHandler(&ast.SetStmt{}, &stmtHandler{name: "set", handle: (*XMySQLEngine).execSet})
+}
+
+// execSet runs a SET.
+func (srv *XMySQLEngine) execSet(session innodb.MySQLServerSession, stmt ast.StmtNode, p plan.Plan) {
+	inTxn := session.GetSessionVars().InTxn()
+	if err := setVariables(session, p.(*plan.Set)); err != nil {
+		session.SendError(toSQLError(err))
+		return
+	}
+	// Turning autocommit on commits the open transaction.
+	if inTxn && !session.GetSessionVars().InTxn() {
+		session.Commit()
+		srv.closeReadView(session)
+	}
+	session.SendOK()
+}
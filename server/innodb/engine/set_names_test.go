@@ -0,0 +1,72 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/juju/errors"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/varsutil"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/terror"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+func sessionVar(t *testing.T, s *session, name string) string {
+	value, err := varsutil.GetSessionSystemVar(s.sessionVars, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return value
+}
+
+func TestSetNames(t *testing.T) {
+	s := newViewTestSession(t, newViewTestSchema())
+	if err := execSet(t, s, "SET NAMES utf8mb4"); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"character_set_client":     "utf8mb4",
+		"character_set_connection": "utf8mb4",
+		"character_set_results":    "utf8mb4",
+		"collation_connection":     "utf8mb4_general_ci",
+	} {
+		if got := sessionVar(t, s, name); got != want {
+			t.Errorf("expect %s to be %s, got %s", name, want, got)
+		}
+	}
+	if _, ok := s.sessionVars.Users["setnames"]; ok {
+		t.Error("expect no user variable set")
+	}
+	if s.sessionVars.ResultsCharset != "utf8mb4" {
+		t.Errorf("expect the results sent in utf8mb4, got %q", s.sessionVars.ResultsCharset)
+	}
+
+	if err := execSet(t, s, "SET NAMES latin1 COLLATE latin1_bin"); err != nil {
+		t.Fatal(err)
+	}
+	if got := sessionVar(t, s, "collation_connection"); got != "latin1_bin" {
+		t.Errorf("expect latin1_bin, got %s", got)
+	}
+
+	// A failed SET NAMES changes nothing.
+	for sql, code := range map[string]uint16{
+		"SET NAMES klingon":                    mysql.ErrUnknownCharacterSet,
+		"SET NAMES utf8mb4 COLLATE latin1_bin": mysql.ErrCollationCharsetMismatch,
+		"SET NAMES utf8mb4 COLLATE nope":       mysql.ErrUnknownCollation,
+	} {
+		err := execSet(t, s, sql)
+		if terr, ok := errors.Cause(err).(*terror.Error); !ok || terr.ToSQLError().Code != code {
+			t.Errorf("%s: expect error %d, got %v", sql, code, err)
+		}
+		if got := sessionVar(t, s, "character_set_client"); got != "latin1" {
+			t.Errorf("%s: expect character_set_client unchanged, got %s", sql, got)
+		}
+	}
+
+	// SET CHARACTER SET takes the connection charset from the database.
+	if err := execSet(t, s, "SET CHARACTER SET utf8"); err != nil {
+		t.Fatal(err)
+	}
+	if sessionVar(t, s, "character_set_client") != "utf8" || sessionVar(t, s, "character_set_results") != "utf8" ||
+		sessionVar(t, s, "character_set_connection") != sessionVar(t, s, "character_set_database") {
+		t.Error("unexpected charsets after SET CHARACTER SET")
+	}
+}
@@ -0,0 +1,152 @@
+package engine
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/expression"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/varsutil"
+)
+
+// showRows returns the rows of the SHOW statement compiled to p. ok is
+// false for the kinds of SHOW the engine doesn't answer yet.
+func showRows(ctx context.Context, is schemas.InfoSchema, p *plan.Show) (rows [][]basic.Datum, ok bool, err error) {
+	switch p.Tp {
+	case ast.ShowIndex:
+		tbl, err := schemas.TableByName(is, p.Table.Schema, p.Table.Name)
+		if err != nil {
+			return nil, true, errors.Trace(err)
+		}
+		return schemas.ShowIndexRows(tbl), true, nil
+	case ast.ShowColumns:
+		tbl, err := schemas.TableByName(is, p.Table.Schema, p.Table.Name)
+		if err != nil {
+			return nil, true, errors.Trace(err)
+		}
+		var column string
+		if p.Column != nil {
+			column = p.Column.Name.O
+		}
+		return schemas.ShowColumnsRows(tbl, p.Full, column), true, nil
+	case ast.ShowDatabases:
+		return schemas.ShowDatabasesRows(is), true, nil
+	case ast.ShowTables:
+		db := model.NewCIStr(p.DBName)
+		if _, ok := is.SchemaByName(db); !ok {
+			return nil, true, schemas.ErrDatabaseNotExists.GenByArgs(p.DBName)
+		}
+		return schemas.ShowTablesRows(is, db, p.Full), true, nil
+	case ast.ShowTableStatus:
+		db := model.NewCIStr(p.DBName)
+		if _, ok := is.SchemaByName(db); !ok {
+			return nil, true, schemas.ErrDatabaseNotExists.GenByArgs(p.DBName)
+		}
+		defaultRowFormat, err := varsutil.GetGlobalSystemVar(ctx.GetSessionVars(), variable.InnodbDefaultRowFormat)
+		if err != nil {
+			return nil, true, errors.Trace(err)
+		}
+		return schemas.ShowTableStatusRows(is, db, defaultRowFormat), true, nil
+	case ast.ShowOpenTables:
+		return openTables.rows(model.NewCIStr(p.DBName)), true, nil
+	case ast.ShowEngineStatus:
+		if !strings.EqualFold(p.Engine, "InnoDB") {
+			return nil, true, ErrUnknownStorageEngine.GenByArgs(p.Engine)
+		}
+		return [][]basic.Datum{basic.MakeDatums("InnoDB", "", innodbStatus())}, true, nil
+	case ast.ShowProcessList:
+		return processListRows(ctx, p.Full), true, nil
+	case ast.ShowProfiles:
+		return profilesRows(ctx.GetSessionVars()), true, nil
+	case ast.ShowProfile:
+		return profileRows(ctx.GetSessionVars(), p.QueryID), true, nil
+	}
+	return nil, false, nil
+}
+
+// showResultRows returns the rows of the SHOW statement compiled to p that
+// its LIKE or WHERE clause selects. ok is false for the kinds of SHOW the
+// engine doesn't answer yet.
+func showResultRows(ctx context.Context, is schemas.InfoSchema, p plan.Plan) (rows [][]basic.Datum, ok bool, err error) {
+	var conditions []expression.Expression
+	if sel, isSel := p.(*plan.Selection); isSel && len(sel.Children()) == 1 {
+		conditions, p = sel.Conditions, sel.Children()[0]
+	}
+	show, isShow := p.(*plan.Show)
+	if !isShow {
+		return nil, false, nil
+	}
+	rows, ok, err = showRows(ctx, is, show)
+	if err != nil || !ok || len(conditions) == 0 {
+		return rows, ok, errors.Trace(err)
+	}
+	rows, err = selectRows(ctx, conditions, rows)
+	return rows, true, errors.Trace(err)
+}
+
+// innodbStatusSections are the sections of SHOW ENGINE INNODB STATUS,
+// registered by the parts of the engine they describe.
+var innodbStatusSections struct {
+	sync.RWMutex
+	sections map[string]func() string
+	names    []string
+}
+
+// registerInnodbStatus makes section write the part called name of SHOW
+// ENGINE INNODB STATUS. Registering a name again replaces its section.
+func registerInnodbStatus(name string, section func() string) {
+	innodbStatusSections.Lock()
+	defer innodbStatusSections.Unlock()
+	if innodbStatusSections.sections == nil {
+		innodbStatusSections.sections = make(map[string]func() string)
+	}
+	if _, ok := innodbStatusSections.sections[name]; !ok {
+		innodbStatusSections.names = append(innodbStatusSections.names, name)
+	}
+	innodbStatusSections.sections[name] = section
+}
+
+// innodbStatus returns the text of SHOW ENGINE INNODB STATUS.
+func innodbStatus() string {
+	innodbStatusSections.RLock()
+	defer innodbStatusSections.RUnlock()
+	var buf strings.Builder
+	buf.WriteString("\n=====================================\n")
+	buf.WriteString(time.Now().Format("2006-01-02 15:04:05") + " INNODB MONITOR OUTPUT\n")
+	buf.WriteString("=====================================\n")
+	for _, name := range innodbStatusSections.names {
+		buf.WriteString(innodbStatusSections.sections[name]())
+	}
+	buf.WriteString("----------------------------\n")
+	buf.WriteString("END OF INNODB MONITOR OUTPUT\n")
+	buf.WriteString("============================\n")
+	return buf.String()
+}
+
+func init() {
+	registerStmtHandler(&ast.ShowStmt{}, &stmtHandler{name: "show", handle: (*XMySQLEngine).execShow})
+}
+
+// execShow sends the rows of a SHOW statement.
+func (srv *XMySQLEngine) execShow(session innodb.MySQLServerSession, stmt ast.StmtNode, p plan.Plan) {
+	x := stmt.(*ast.ShowStmt)
+	rows, ok, err := showResultRows(session, srv.infoSchemaManager, p)
+	if err != nil {
+		session.SendError(toSQLError(err))
+		return
+	}
+	if ok {
+		if err = session.SendResultSet(ResultColumns(x, p), innodb.SliceRows(rows)); err != nil {
+			session.SendError(toSQLError(err))
+		}
+	}
+}
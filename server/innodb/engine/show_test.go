@@ -0,0 +1,431 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/conf"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/buffer_pool"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/varsutil"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/statistics"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// showIndexString formats the Key_name, Seq_in_index, Column_name,
+// Non_unique, Cardinality and Null of the rows of SHOW INDEX.
+func showIndexString(rows [][]basic.Datum) string {
+	var lines []string
+	for _, row := range rows {
+		card := "NULL"
+		if !row[6].IsNull() {
+			card = fmt.Sprint(row[6].GetInt64())
+		}
+		lines = append(lines, fmt.Sprintf("%s %d %s %d %s %q", row[2].GetString(), row[3].GetInt64(),
+			row[4].GetString(), row[1].GetInt64(), card, row[9].GetString()))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestShowIndex(t *testing.T) {
+	// CREATE TABLE t (id INT PRIMARY KEY, a INT NOT NULL, b INT,
+	//	KEY idx_b (b), UNIQUE KEY uk_ab (a, b));
+	tbl := newFKTestTable("t", "id", "a", "b")
+	tbl.ID = 1
+	tbl.PKIsHandle = true
+	tbl.Columns[0].Flag |= mysql.PriKeyFlag | mysql.NotNullFlag
+	tbl.Columns[1].Flag |= mysql.NotNullFlag
+	newIndex := func(id int64, name string, unique bool, cols ...int) *model.IndexInfo {
+		idx := &model.IndexInfo{ID: id, Name: model.NewCIStr(name), Unique: unique, State: model.StatePublic}
+		for _, offset := range cols {
+			idx.Columns = append(idx.Columns, &model.IndexColumn{Name: tbl.Columns[offset].Name,
+				Offset: offset, Length: basic.UnspecifiedLength})
+		}
+		return idx
+	}
+	tbl.Indices = []*model.IndexInfo{newIndex(1, "idx_b", false, 2), newIndex(2, "uk_ab", true, 1, 2)}
+	is := &fkTestSchema{crossDBTestSchema{tables: map[string]schemas.Table{"test.t": &viewTestTable{meta: tbl}}}}
+	s := newViewTestSession(t, is)
+
+	show := func() [][]basic.Datum {
+		_, p, err := compileView(s, "SHOW INDEX FROM t")
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows, _, err := showRows(s, is, p.(*plan.Show))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// information_schema.STATISTICS has the same rows.
+		stats := schemas.StatisticsRows(is)
+		if len(stats) != len(rows) {
+			t.Fatalf("expect %d rows in STATISTICS, got %d", len(rows), len(stats))
+		}
+		for i, row := range rows {
+			if stats[i][5].GetString() != row[2].GetString() || stats[i][9].GetValue() != row[6].GetValue() {
+				t.Fatalf("STATISTICS row %d differs from SHOW INDEX: %v", i, stats[i])
+			}
+		}
+		return rows
+	}
+	schemas.RegisterIndexStats(nil)
+	expected := `PRIMARY 1 id 0 NULL ""
+idx_b 1 b 1 NULL "YES"
+uk_ab 1 a 0 NULL ""
+uk_ab 2 b 0 NULL "YES"`
+	if str := showIndexString(show()); str != expected {
+		t.Fatalf("expect\n%s\ngot\n%s", expected, str)
+	}
+
+	// ANALYZE TABLE measures the cardinality.
+	h := statistics.NewHandle(nil, 0)
+	schemas.RegisterIndexStats(h)
+	defer schemas.RegisterIndexStats(nil)
+	store := newFKTestStore(tbl)
+	for _, row := range [][]interface{}{{1, 1, 1}, {2, 1, 2}, {3, 2, 2}, {4, 3, nil}} {
+		store.insert(tbl, row...)
+	}
+	_, p, err := compileView(s, "ANALYZE TABLE t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = analyze(s.sessionVars.StmtCtx, h, store, p.(*plan.Analyze)); err != nil {
+		t.Fatal(err)
+	}
+	expected = `PRIMARY 1 id 0 4 ""
+idx_b 1 b 1 3 "YES"
+uk_ab 1 a 0 3 ""
+uk_ab 2 b 0 4 "YES"`
+	if str := showIndexString(show()); str != expected {
+		t.Fatalf("expect\n%s\ngot\n%s", expected, str)
+	}
+
+	// CREATE INDEX and DROP INDEX change the list, the new index has no
+	// statistics until the next ANALYZE TABLE.
+	tbl.Indices = []*model.IndexInfo{tbl.Indices[1], newIndex(3, "idx_a", false, 1)}
+	expected = `PRIMARY 1 id 0 4 ""
+uk_ab 1 a 0 3 ""
+uk_ab 2 b 0 4 "YES"
+idx_a 1 a 1 NULL ""`
+	if str := showIndexString(show()); str != expected {
+		t.Fatalf("expect\n%s\ngot\n%s", expected, str)
+	}
+
+	if _, _, err = compileView(s, "SELECT * FROM information_schema.statistics"); err != nil {
+		t.Fatal(err)
+	}
+	_, p, err = compileView(s, "SHOW INDEX FROM nope")
+	if err == nil {
+		_, _, err = showRows(s, is, p.(*plan.Show))
+	}
+	if errCode(err) != mysql.ErrNoSuchTable {
+		t.Fatalf("expect error %d, got %v", mysql.ErrNoSuchTable, err)
+	}
+}
+
+func TestStatisticsTable(t *testing.T) {
+	// CREATE TABLE t (id INT PRIMARY KEY, a INT, b INT, KEY idx_ab (a, b));
+	tbl := newFKTestTable("t", "id", "a", "b")
+	tbl.PKIsHandle = true
+	tbl.Columns[0].Flag |= mysql.PriKeyFlag | mysql.NotNullFlag
+	idx := &model.IndexInfo{ID: 1, Name: model.NewCIStr("idx_ab"), State: model.StatePublic}
+	for _, offset := range []int{2, 1} {
+		idx.Columns = append(idx.Columns, &model.IndexColumn{Name: tbl.Columns[offset].Name,
+			Offset: offset, Length: basic.UnspecifiedLength})
+	}
+	tbl.Indices = []*model.IndexInfo{idx}
+	srv, s := newStoredTestEngine(t, tbl)
+	const sql = "SELECT TABLE_SCHEMA, TABLE_NAME, NON_UNIQUE, INDEX_NAME, SEQ_IN_INDEX, COLUMN_NAME, CARDINALITY, INDEX_TYPE " +
+		"FROM information_schema.STATISTICS WHERE TABLE_NAME = 't'"
+
+	schemas.RegisterIndexStats(nil)
+	expected := "test,t,0,PRIMARY,1,id,NULL,BTREE;test,t,1,idx_ab,1,b,NULL,BTREE;test,t,1,idx_ab,2,a,NULL,BTREE"
+	if got := execStored(t, srv, s, sql, 0); got != expected {
+		t.Fatalf("expect %s, got %s", expected, got)
+	}
+
+	// The cardinality is the one ANALYZE TABLE measured.
+	h := statistics.NewHandle(nil, 0)
+	schemas.RegisterIndexStats(h)
+	defer schemas.RegisterIndexStats(nil)
+	store := newFKTestStore(tbl)
+	for _, row := range [][]interface{}{{1, 1, 1}, {2, 1, 2}, {3, 2, 2}, {4, 3, 2}} {
+		store.insert(tbl, row...)
+	}
+	_, p, err := compileView(s.session, "ANALYZE TABLE t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = analyze(s.sessionVars.StmtCtx, h, store, p.(*plan.Analyze)); err != nil {
+		t.Fatal(err)
+	}
+	expected = "test,t,0,PRIMARY,1,id,4,BTREE;test,t,1,idx_ab,1,b,2,BTREE;test,t,1,idx_ab,2,a,4,BTREE"
+	if got := execStored(t, srv, s, sql, 0); got != expected {
+		t.Fatalf("expect %s, got %s", expected, got)
+	}
+}
+
+func TestShowEngineInnodbStatus(t *testing.T) {
+	srv := &XMySQLEngine{conf: conf.NewCfg(), pool: buffer_pool.NewBufferPool(16*16384, 0.75, 0.25, 1000, nil)}
+	srv.initAdaptiveHashIndex()
+	is := newViewTestSchema()
+	s := newViewTestSession(t, is)
+	show := func(sql string) ([][]basic.Datum, error) {
+		_, p, err := compileView(s, sql)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows, _, err := showRows(s, is, p.(*plan.Show))
+		return rows, err
+	}
+
+	rows, err := show("SHOW ENGINE INNODB STATUS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0][0].GetString() != "InnoDB" ||
+		!strings.Contains(rows[0][2].GetString(), "Adaptive hash index ON, 8 partitions, 0 cells") {
+		t.Fatalf("unexpected rows %v", rows)
+	}
+	if _, err = show("SHOW ENGINE MYISAM STATUS"); errCode(err) != mysql.ErrUnknownStorageEngine {
+		t.Fatalf("expect error %d, got %v", mysql.ErrUnknownStorageEngine, err)
+	}
+
+	// SET GLOBAL innodb_adaptive_hash_index switches it at runtime.
+	err = varsutil.SetGlobalSystemVar(s.sessionVars, variable.InnodbAdaptiveHashIndex, basic.NewStringDatum("off"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if srv.pool.AHI.Enabled() {
+		t.Fatal("expect the adaptive hash index to be off")
+	}
+	if val, _ := varsutil.GetGlobalSystemVar(s.sessionVars, variable.InnodbAdaptiveHashIndex); val != "OFF" {
+		t.Fatalf("expect OFF, got %s", val)
+	}
+	rows, _ = show("SHOW ENGINE InnoDB STATUS")
+	if !strings.Contains(rows[0][2].GetString(), "Adaptive hash index OFF") {
+		t.Fatalf("unexpected status %s", rows[0][2].GetString())
+	}
+	err = varsutil.SetGlobalSystemVar(s.sessionVars, variable.InnodbAdaptiveHashIndex, basic.NewStringDatum("maybe"))
+	if errCode(err) != mysql.ErrWrongValueForVar || srv.pool.AHI.Enabled() {
+		t.Fatalf("expect error %d, got %v", mysql.ErrWrongValueForVar, err)
+	}
+	err = varsutil.SetGlobalSystemVar(s.sessionVars, variable.InnodbAdaptiveHashIndex, basic.NewIntDatum(1))
+	if err != nil || !srv.pool.AHI.Enabled() {
+		t.Fatalf("expect the adaptive hash index to be on, got %v", err)
+	}
+}
+
+// statusTestTable is a table of rows records whose clustered index has
+// dataPages pages and whose secondary indexes have indexPages.
+type statusTestTable struct {
+	viewTestTable
+	rows                  int64
+	dataPages, indexPages int64
+}
+
+func (t *statusTestTable) EstimateIndexRows(indexName string) (int64, bool) {
+	return t.rows, indexName == mysql.PrimaryKeyName
+}
+
+func (t *statusTestTable) TableStatus() schemas.TableStatus {
+	return schemas.TableStatus{DataLength: t.dataPages * 16384, IndexLength: t.indexPages * 16384}
+}
+
+func TestShowTableStatus(t *testing.T) {
+	orders := &statusTestTable{viewTestTable{meta: newFKTestTable("orders", "id", "user_id")}, 200, 5, 2}
+	orders.meta.Collate = "utf8_bin"
+	is := &fkTestSchema{crossDBTestSchema{tables: map[string]schemas.Table{
+		"test.orders":  orders,
+		"test.users":   &viewTestTable{meta: newFKTestTable("users", "id")},
+		"test.v":       schemas.NewView(&model.TableInfo{Name: model.NewCIStr("v"), View: &model.ViewInfo{}}),
+		"shop.carts":   &viewTestTable{meta: newFKTestTable("carts", "id")},
+		"shop.payment": &viewTestTable{meta: newFKTestTable("payment", "id")},
+	}}}
+	s := newViewTestSession(t, is)
+	show := func(sql string) ([][]basic.Datum, error) {
+		_, p, err := compileView(s, sql)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows, ok, err := showResultRows(s, is, p)
+		if !ok {
+			t.Fatalf("%s: expect the engine to answer", sql)
+		}
+		return rows, err
+	}
+
+	rows, err := show("SHOW TABLE STATUS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("expect 3 tables of test, got %v", rows)
+	}
+	// Name, Rows, Avg_row_length, Data_length, Index_length, Collation.
+	row := rows[0]
+	if row[0].GetString() != "orders" || row[1].GetString() != "InnoDB" || row[4].GetInt64() != 200 ||
+		row[5].GetInt64() != 5*16384/200 || row[6].GetInt64() != 5*16384 || row[8].GetInt64() != 2*16384 ||
+		row[14].GetString() != "utf8_bin" {
+		t.Fatalf("unexpected status of orders %v", row)
+	}
+	if rows[1][0].GetString() != "users" || rows[1][6].GetInt64() != 0 || rows[1][5].GetInt64() != 0 {
+		t.Fatalf("expect no size known for users, got %v", rows[1])
+	}
+	if rows[2][0].GetString() != "v" || !rows[2][1].IsNull() || rows[2][17].GetString() != "VIEW" {
+		t.Fatalf("unexpected status of the view %v", rows[2])
+	}
+
+	// information_schema.TABLES reports the same sizes.
+	for _, r := range schemas.TablesRows(is, "dynamic") {
+		if r[1].GetString() == "test" && r[2].GetString() == "orders" {
+			if r[7].GetInt64() != 200 || r[9].GetInt64() != 5*16384 || r[11].GetInt64() != 2*16384 {
+				t.Fatalf("TABLES differs from SHOW TABLE STATUS: %v", r)
+			}
+		}
+	}
+
+	if rows, err = show("SHOW TABLE STATUS FROM shop LIKE 'p%'"); err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0][0].GetString() != "payment" {
+		t.Fatalf("expect payment only, got %v", rows)
+	}
+	if rows, err = show("SHOW TABLE STATUS WHERE Rows > 100"); err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0][0].GetString() != "orders" {
+		t.Fatalf("expect orders only, got %v", rows)
+	}
+	if _, err = show("SHOW TABLE STATUS FROM nope"); errCode(err) != mysql.ErrBadDB {
+		t.Fatalf("expect error %d, got %v", mysql.ErrBadDB, err)
+	}
+}
+
+func TestShowTables(t *testing.T) {
+	is := &fkTestSchema{crossDBTestSchema{tables: map[string]schemas.Table{
+		"test.users":  &viewTestTable{meta: newFKTestTable("users", "id")},
+		"test.Orders": &viewTestTable{meta: newFKTestTable("Orders", "id")},
+		"test.v":      schemas.NewView(&model.TableInfo{Name: model.NewCIStr("v"), View: &model.ViewInfo{}}),
+		"shop.carts":  &viewTestTable{meta: newFKTestTable("carts", "id")},
+		"mysql.user":  &viewTestTable{meta: newFKTestTable("user", "id")},
+	}}}
+	s := newViewTestSession(t, is)
+	show := func(sql string) (string, error) {
+		_, p, err := compileView(s, sql)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows, ok, err := showResultRows(s, is, p)
+		if !ok {
+			t.Fatalf("%s: expect the engine to answer", sql)
+		}
+		var lines []string
+		for _, row := range rows {
+			var fields []string
+			for _, d := range row {
+				fields = append(fields, d.GetString())
+			}
+			lines = append(lines, strings.Join(fields, " "))
+		}
+		return strings.Join(lines, ","), err
+	}
+
+	// The names are listed as they are stored.
+	tests := []struct {
+		sql      string
+		expected string
+	}{
+		{"SHOW TABLES", "Orders,users,v"},
+		{"SHOW FULL TABLES", "Orders BASE TABLE,users BASE TABLE,v VIEW"},
+		{"SHOW TABLES FROM shop", "carts"},
+		{"SHOW TABLES LIKE 'u%'", "users"},
+		{"SHOW TABLES FROM test LIKE '_'", "v"},
+		{"SHOW TABLES WHERE Tables_in_test LIKE '%s'", "Orders,users"},
+		{"SHOW FULL TABLES WHERE Table_type = 'VIEW'", "v VIEW"},
+		{"SHOW DATABASES", "information_schema,mysql,performance_schema,shop,test"},
+		{"SHOW DATABASES LIKE 'mysql'", "mysql"},
+		{"SHOW DATABASES LIKE 'performance\\_%'", "performance_schema"},
+		{"SHOW DATABASES LIKE 'nope%'", ""},
+		{"SHOW DATABASES WHERE `Database` LIKE 's%' OR `Database` = 'test'", "shop,test"},
+	}
+	for _, tt := range tests {
+		got, err := show(tt.sql)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.sql, err)
+		}
+		if got != tt.expected {
+			t.Fatalf("%s: expect %q, got %q", tt.sql, tt.expected, got)
+		}
+	}
+	if _, err := show("SHOW TABLES FROM nope"); errCode(err) != mysql.ErrBadDB {
+		t.Fatalf("expect error %d, got %v", mysql.ErrBadDB, err)
+	}
+}
+
+func TestShowOpenTables(t *testing.T) {
+	defer func(cache *tableCache) { openTables = cache }(openTables)
+	openTables = &tableCache{tables: make(map[string]*openTable)}
+	is := &fkTestSchema{crossDBTestSchema{tables: map[string]schemas.Table{
+		"test.t":      &viewTestTable{meta: newFKTestTable("t", "id")},
+		"test.t2":     &viewTestTable{meta: newFKTestTable("t2", "id")},
+		"shop.orders": &viewTestTable{meta: newFKTestTable("orders", "id")},
+	}}}
+	s := newViewTestSession(t, is)
+	open := func(sql string) func() {
+		stmt, _, err := compileView(s, sql)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return openTables.open(s.sessionVars, is, stmt)
+	}
+	show := func(sql string) string {
+		_, p, err := compileView(s, sql)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows, _, err := showResultRows(s, is, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var lines []string
+		for _, row := range rows {
+			lines = append(lines, fmt.Sprintf("%s.%s %d %d", row[0].GetString(), row[1].GetString(),
+				row[2].GetInt64(), row[3].GetInt64()))
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	closeJoin := open("SELECT * FROM t, shop.orders")
+	closeSelect := open("SELECT * FROM t")
+	closeRename := open("RENAME TABLE t2 TO t3")
+	if str := show("SHOW OPEN TABLES"); str != "shop.orders 1 0\ntest.t 2 0\ntest.t2 1 1" {
+		t.Fatalf("unexpected open tables\n%s", str)
+	}
+	if str := show("SHOW OPEN TABLES FROM test LIKE 't2'"); str != "test.t2 1 1" {
+		t.Fatalf("unexpected open tables\n%s", str)
+	}
+	closeRename()
+	closeSelect()
+	closeJoin()
+	// The tables stay open once the statements are done, the renamed one
+	// is closed.
+	if str := show("SHOW OPEN TABLES"); str != "shop.orders 0 0\ntest.t 0 0" {
+		t.Fatalf("unexpected open tables\n%s", str)
+	}
+
+	err := varsutil.SetGlobalSystemVar(s.sessionVars, variable.TableOpenCache, basic.NewStringDatum("1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer varsutil.SetGlobalSystemVar(s.sessionVars, variable.TableOpenCache, basic.NewStringDatum("2000"))
+	open("SELECT * FROM t")()
+	if str := show("SHOW OPEN TABLES"); strings.Count(str, "\n") != 0 {
+		t.Fatalf("expect one table kept open, got\n%s", str)
+	}
+}
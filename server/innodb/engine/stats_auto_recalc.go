@@ -0,0 +1,247 @@
+package engine
+
+import (
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/varsutil"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/statistics"
+)
+
+/**
+统计信息的自动重新计算
+
+提交的 INSERT、UPDATE、DELETE 把改动的行数记在表的实时行数上（ModifyCount），ANALYZE 后从 0 重新开始。
+后台每隔一段时间检查所有的表：自上次 ANALYZE 以来改动的行数超过当时行数的
+xmysql_stats_auto_recalc_threshold（默认 0.1，和 InnoDB 的 innodb_stats_auto_recalc 一样是 10%）时，
+表进入等待队列；从未 ANALYZE 过的表有改动就进入队列。innodb_stats_auto_recalc 为 OFF 时不检查。
+
+队列中的表一次只处理一个：读出表的行，随机抽样最多 statsRecalcSampleRows 行建立统计信息，按表的总行数放大后
+整个替换旧的统计信息，优化器之后的代价估算马上使用新的。正在被 DROP、ALTER 等修改定义的语句持有表名锁的表
+留在队列中，等下一轮再处理。
+
+information_schema.XMYSQL_STATS_AUTO_RECALC 列出在等待和处理过的表：状态、改动的行数、
+上次重新计算的时间、抽样的行数和失败的原因。
+**/
+
+// statsAutoRecalcInterval is how often the tables are checked for changes.
+var statsAutoRecalcInterval = 10 * time.Second
+
+// statsRecalcSampleRows is the most rows the statistics are rebuilt from.
+var statsRecalcSampleRows = 10000
+
+// The states of a table in information_schema.XMYSQL_STATS_AUTO_RECALC.
+const (
+	statsRecalcPending = "PENDING"
+	statsRecalcRunning = "RUNNING"
+	statsRecalcIdle    = "IDLE"
+)
+
+// statsAutoRecalc rebuilds in the background the statistics of the tables
+// changed the most since they were analyzed.
+type statsAutoRecalc struct {
+	handle *statistics.Handle
+	reader tableRowsReader
+	// vars reads the global variables.
+	vars *variable.SessionVars
+	// nameLocked reports whether a statement changing the definition of
+	// db.table holds it.
+	nameLocked func(db, table string) bool
+
+	mu sync.Mutex
+	// pending are the ids of the tables waiting, in order.
+	pending []int64
+	tables  map[int64]*schemas.StatsRecalcState
+}
+
+func newStatsAutoRecalc(handle *statistics.Handle, reader tableRowsReader, vars *variable.SessionVars) *statsAutoRecalc {
+	return &statsAutoRecalc{
+		handle:     handle,
+		reader:     reader,
+		vars:       vars,
+		nameLocked: openTables.nameLocked,
+		tables:     make(map[int64]*schemas.StatsRecalcState),
+	}
+}
+
+// initStatsAutoRecalc starts recalculating the statistics of the changed
+// tables in the background.
+func (srv *XMySQLEngine) initStatsAutoRecalc() {
+	s, err := createSession(srv.infoSchemaManager)
+	if err != nil {
+		log.Warnf("统计信息的自动重新计算没有启动: %v", err)
+		return
+	}
+	r := newStatsAutoRecalc(srv.statsHandle, &scanRowsReader{ctx: s, pool: srv.pool}, s.sessionVars)
+	schemas.RegisterStatsRecalcStates(r)
+	go func() {
+		timeTicker := time.NewTicker(statsAutoRecalcInterval)
+		for {
+			<-timeTicker.C
+			r.run(srv.infoSchemaManager)
+		}
+	}()
+}
+
+// run queues the tables of is changed more than the threshold and
+// recalculates the statistics of those waiting, one after the other.
+func (r *statsAutoRecalc) run(is schemas.InfoSchema) {
+	if value, err := varsutil.GetGlobalSystemVar(r.vars, variable.InnodbStatsAutoRecalc); err != nil || !isOn(value) {
+		return
+	}
+	r.enqueue(is)
+	for _, id := range r.pendingTables() {
+		r.recalc(is, id)
+	}
+}
+
+// threshold returns the part of its rows a table must have changed to be
+// recalculated.
+func (r *statsAutoRecalc) threshold() float64 {
+	value, err := varsutil.GetGlobalSystemVar(r.vars, variable.StatsAutoRecalcThreshold)
+	if err != nil {
+		return 0.1
+	}
+	threshold, err := strconv.ParseFloat(value, 64)
+	if err != nil || threshold < 0 {
+		return 0.1
+	}
+	return threshold
+}
+
+// enqueue queues the tables of is changed more than the threshold.
+func (r *statsAutoRecalc) enqueue(is schemas.InfoSchema) {
+	threshold := r.threshold()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, db := range is.AllSchemas() {
+		for _, tbl := range is.SchemaTables(db.Name) {
+			meta := tbl.Meta()
+			if meta == nil || meta.IsView() {
+				continue
+			}
+			modified, analyzed := r.handle.ModifiedRows(meta.ID)
+			if modified == 0 || float64(modified) <= threshold*float64(analyzed) {
+				continue
+			}
+			state, ok := r.tables[meta.ID]
+			if !ok {
+				state = &schemas.StatsRecalcState{}
+				r.tables[meta.ID] = state
+			}
+			state.DB, state.Table = db.Name.O, meta.Name.O
+			if state.State != statsRecalcPending {
+				state.State = statsRecalcPending
+				r.pending = append(r.pending, meta.ID)
+			}
+		}
+	}
+}
+
+func (r *statsAutoRecalc) pendingTables() []int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]int64(nil), r.pending...)
+}
+
+// recalc rebuilds the statistics of the pending table id from a sample of
+// its rows, unless its name is locked.
+func (r *statsAutoRecalc) recalc(is schemas.InfoSchema, id int64) {
+	r.mu.Lock()
+	state := r.tables[id]
+	db, name := state.DB, state.Table
+	r.mu.Unlock()
+	tbl, err := is.TableByName(model.NewCIStr(db), model.NewCIStr(name))
+	if err != nil || tbl == nil || tbl.Meta().ID != id {
+		// Dropped or renamed.
+		r.mu.Lock()
+		r.dequeue(id)
+		delete(r.tables, id)
+		r.mu.Unlock()
+		return
+	}
+	if r.nameLocked(db, name) {
+		return
+	}
+	r.mu.Lock()
+	r.dequeue(id)
+	state.State = statsRecalcRunning
+	r.mu.Unlock()
+
+	sampled, total, err := r.analyze(tbl)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	state.State = statsRecalcIdle
+	state.LastRecalc, state.LastError = time.Now(), ""
+	if err != nil {
+		log.Warnf("重新计算表 %s.%s 的统计信息失败: %v", db, name, err)
+		state.LastError = err.Error()
+		return
+	}
+	state.SampledRows, state.TableRows = sampled, total
+}
+
+// analyze rebuilds the statistics of tbl from a sample of its rows and
+// returns the number of rows sampled and of the table.
+func (r *statsAutoRecalc) analyze(tbl schemas.Table) (int64, int64, error) {
+	rows, err := r.reader.TableRows(tbl)
+	if err != nil {
+		return 0, 0, errors.Trace(err)
+	}
+	sample := sampleRows(rows, statsRecalcSampleRows)
+	sc := &variable.StatementContext{TimeZone: time.Local}
+	if err = r.handle.AnalyzeTableSample(sc, tbl.Meta(), sample, int64(len(rows))); err != nil {
+		return 0, 0, errors.Trace(err)
+	}
+	return int64(len(sample)), int64(len(rows)), nil
+}
+
+func (r *statsAutoRecalc) dequeue(id int64) {
+	for i, pending := range r.pending {
+		if pending == id {
+			r.pending = append(r.pending[:i], r.pending[i+1:]...)
+			return
+		}
+	}
+}
+
+// sampleRows returns n of rows picked at random, all of them when there
+// are no more than n.
+func sampleRows(rows [][]basic.Datum, n int) [][]basic.Datum {
+	if len(rows) <= n {
+		return rows
+	}
+	sample := make([][]basic.Datum, n)
+	for i, j := range rand.Perm(len(rows))[:n] {
+		sample[i] = rows[j]
+	}
+	return sample
+}
+
+// StatsRecalcStates implements schemas.StatsRecalcStates.
+func (r *statsAutoRecalc) StatsRecalcStates() []schemas.StatsRecalcState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	states := make([]schemas.StatsRecalcState, 0, len(r.tables))
+	for id, state := range r.tables {
+		s := *state
+		s.ModifiedRows, _ = r.handle.ModifiedRows(id)
+		states = append(states, s)
+	}
+	sort.Slice(states, func(i, j int) bool {
+		if states[i].DB != states[j].DB {
+			return states[i].DB < states[j].DB
+		}
+		return states[i].Table < states[j].Table
+	})
+	return states
+}
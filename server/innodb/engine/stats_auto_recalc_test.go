@@ -0,0 +1,127 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/varsutil"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/statistics"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+func TestStatsAutoRecalc(t *testing.T) {
+	defer func(n int) { statsRecalcSampleRows = n }(statsRecalcSampleRows)
+	statsRecalcSampleRows = 50
+	newTable := func(name string, id int64) *model.TableInfo {
+		tbl := newFKTestTable(name, "id", "x")
+		tbl.ID, tbl.PKIsHandle = id, true
+		tbl.Columns[0].ID, tbl.Columns[1].ID = 1, 2
+		tbl.Columns[0].Flag |= mysql.PriKeyFlag | mysql.NotNullFlag
+		return tbl
+	}
+	t1, t2 := newTable("t1", 31), newTable("t2", 32)
+	is := &fkTestSchema{crossDBTestSchema{tables: map[string]schemas.Table{
+		"test.t1": &viewTestTable{meta: t1},
+		"test.t2": &viewTestTable{meta: t2},
+	}}}
+	rows := func(n int) [][]basic.Datum {
+		rows := make([][]basic.Datum, 0, n)
+		for i := 1; i <= n; i++ {
+			rows = append(rows, basic.MakeDatums(int64(i), int64(i%7)))
+		}
+		return rows
+	}
+	reader := testTableRows{"t1": rows(100), "t2": rows(3)}
+	h := statistics.NewHandle(nil, 0)
+	s := newViewTestSession(t, is)
+	if err := h.AnalyzeTable(s.sessionVars.StmtCtx, t1, reader["t1"]); err != nil {
+		t.Fatal(err)
+	}
+	r := newStatsAutoRecalc(h, reader, s.sessionVars)
+	locked := map[string]bool{}
+	r.nameLocked = func(db, table string) bool { return locked[table] }
+	schemas.RegisterStatsRecalcStates(r)
+	defer schemas.RegisterStatsRecalcStates(nil)
+	changed := func(tbl *model.TableInfo, n int64) {
+		h.ApplyDelta(map[int64]variable.TableDelta{tbl.ID: {Delta: n, Count: n}})
+	}
+
+	// 10 rows changed out of the 100 analyzed are not enough.
+	changed(t1, 10)
+	r.run(is)
+	if states := r.StatsRecalcStates(); len(states) != 0 {
+		t.Fatalf("expect no table queued, got %+v", states)
+	}
+
+	// 11 are. t2 was never analyzed and is queued by its first change, but
+	// an ALTER TABLE holds it.
+	changed(t1, 1)
+	changed(t2, 3)
+	locked["t2"] = true
+	reader["t1"] = rows(111)
+	r.run(is)
+	stats := h.GetTableStats(t1.ID)
+	if stats.Count != 111 || stats.Columns[1].NDV != 111 {
+		t.Fatalf("expect the statistics of 111 rows, got %d rows and %d ids", stats.Count, stats.Columns[1].NDV)
+	}
+	if modified, analyzed := h.ModifiedRows(t1.ID); modified != 0 || analyzed != 111 {
+		t.Fatalf("expect no change since 111 rows were analyzed, got %d and %d", modified, analyzed)
+	}
+	expected := [][]interface{}{
+		{"test", "t1", "IDLE", int64(0), int64(50), int64(111)},
+		{"test", "t2", "PENDING", int64(3), nil, nil},
+	}
+	check := func() {
+		t.Helper()
+		got := schemas.StatsAutoRecalcRows()
+		if len(got) != len(expected) {
+			t.Fatalf("expect %d rows, got %v", len(expected), got)
+		}
+		for i, row := range got {
+			values := []interface{}{row[0].GetValue(), row[1].GetValue(), row[2].GetValue(),
+				row[3].GetValue(), row[5].GetValue(), row[6].GetValue()}
+			for j, v := range values {
+				if v != expected[i][j] {
+					t.Fatalf("row %d: expect %v, got %v", i, expected[i], values)
+				}
+			}
+			if row[4].IsNull() != (row[2].GetString() == "PENDING") || !row[7].IsNull() {
+				t.Fatalf("row %d: unexpected LAST_RECALC or LAST_ERROR in %v", i, row)
+			}
+		}
+	}
+	check()
+	if _, _, err := compileView(s, "SELECT * FROM information_schema.xmysql_stats_auto_recalc"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing is checked with innodb_stats_auto_recalc off.
+	sv := variable.SysVars[variable.InnodbStatsAutoRecalc]
+	defer func(value string) { sv.Value = value }(sv.Value)
+	if err := varsutil.SetGlobalSystemVar(s.sessionVars, variable.InnodbStatsAutoRecalc, basic.NewStringDatum("OFF")); err != nil {
+		t.Fatal(err)
+	}
+	changed(t1, 50)
+	locked["t2"] = false
+	r.run(is)
+	expected[0][3] = int64(50)
+	check()
+
+	// Once released, t2 is analyzed from all its rows.
+	if err := varsutil.SetGlobalSystemVar(s.sessionVars, variable.InnodbStatsAutoRecalc, basic.NewStringDatum("ON")); err != nil {
+		t.Fatal(err)
+	}
+	reader["t1"] = rows(161)
+	r.run(is)
+	expected = [][]interface{}{
+		{"test", "t1", "IDLE", int64(0), int64(50), int64(161)},
+		{"test", "t2", "IDLE", int64(0), int64(3), int64(3)},
+	}
+	check()
+	if stats = h.GetTableStats(t2.ID); stats.Pseudo || stats.Count != 3 {
+		t.Fatalf("expect the statistics of 3 rows, got %+v", stats)
+	}
+}
@@ -0,0 +1,48 @@
+package engine
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+)
+
+// stmtMatcher reports whether a handler takes stmt.
+type stmtMatcher func(stmt ast.StmtNode) bool
+
+// stmtHandler runs the statements of an AST node type that its matcher
+// takes, p being the plan compiled for stmt, and sends their result.
+type stmtHandler struct {
+	name string
+	// priority orders the handlers of a node type, the highest is tried
+	// first.
+	priority int
+	// match takes every statement of the type when it is nil.
+	match  stmtMatcher
+	handle func(srv *XMySQLEngine, session innodb.MySQLServerSession, stmt ast.StmtNode, p plan.Plan)
+}
+
+// stmtHandlers are the handlers of each AST node type, by priority.
+var stmtHandlers = make(map[reflect.Type][]*stmtHandler)
+
+// registerStmtHandler registers h for the statements of the type of stmt.
+func registerStmtHandler(stmt ast.StmtNode, h *stmtHandler) {
+	tp := reflect.TypeOf(stmt)
+	handlers := append(stmtHandlers[tp], h)
+	sort.SliceStable(handlers, func(i, j int) bool {
+		return handlers[i].priority > handlers[j].priority
+	})
+	stmtHandlers[tp] = handlers
+}
+
+// findStmtHandler returns the handler of stmt, nil when no handler takes it.
+func findStmtHandler(stmt ast.StmtNode) *stmtHandler {
+	for _, h := range stmtHandlers[reflect.TypeOf(stmt)] {
+		if h.match == nil || h.match(stmt) {
+			return h
+		}
+	}
+	return nil
+}
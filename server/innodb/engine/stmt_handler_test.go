@@ -0,0 +1,54 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/conf"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/parser"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+func TestStmtHandlerRouting(t *testing.T) {
+	tests := []struct {
+		sql     string
+		handler string
+	}{
+		// Keywords inside literals don't change the route.
+		{"SELECT 'show tables' AS s", "select"},
+		{"SELECT 'select @@version' AS s", "select"},
+		{"SELECT 1 INTO OUTFILE '/tmp/t.csv'", "select into outfile"},
+		{"SHOW TABLES", "show"},
+		{"SET @a = 'use test'", "set"},
+		{"DROP VIEW v", "drop view"},
+		{"FLUSH CONFIG", "flush config"},
+		{"REVOKE SELECT ON test.* FROM 'u'@'%'", "revoke"},
+		{"COMMIT", "commit"},
+		{"DROP TABLE t", ""},
+		{"UPDATE t SET id = 2", "update"},
+		{"DELETE FROM t", "delete"},
+	}
+	for _, tt := range tests {
+		stmt, err := parser.New().ParseOneStmt(tt.sql, mysql.UTF8Charset, mysql.UTF8DefaultCollation)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var name string
+		if h := findStmtHandler(stmt); h != nil {
+			name = h.name
+		}
+		if name != tt.handler {
+			t.Errorf("%s: expect handler %q, got %q", tt.sql, tt.handler, name)
+		}
+	}
+
+	is := newViewTestSchema()
+	srv := &XMySQLEngine{conf: conf.NewCfg(), infoSchemaManager: is}
+	s := &serverTestSession{session: newViewTestSession(t, is)}
+	srv.executeQuery(s, "SELECT 'show tables' AS s")
+	if len(s.errs) != 0 {
+		t.Fatal(s.errs[0])
+	}
+	if len(s.rows) != 1 || s.rows[0][0].GetString() != "show tables" {
+		t.Errorf("expect the literal back, got %v", s.rows)
+	}
+}
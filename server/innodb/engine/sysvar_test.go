@@ -0,0 +1,55 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/expression"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+func TestUnknownSystemVariables(t *testing.T) {
+	s := newViewTestSession(t, newViewTestSchema())
+	stmt, p, err := compileView(s, "SELECT @@identity, @@innodb_flush_log_at_trx_commit AS flush, @@system_time_zone, @@nope, @@global.innodb_no_such_option, @@tidb_current_ts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{"@@identity", "flush", "@@system_time_zone", "@@nope", "@@global.innodb_no_such_option", "@@tidb_current_ts"}
+	fields := ResultColumns(stmt, p)
+	if len(fields) != len(names) {
+		t.Fatalf("unexpected columns %+v", fields)
+	}
+	for i, field := range fields {
+		if field.Name != names[i] {
+			t.Fatalf("column %d: expect %s, got %s", i, names[i], field.Name)
+		}
+	}
+
+	// Each unknown variable reads as NULL, with a warning of its own.
+	proj := p.(*plan.Projection)
+	zone, _ := time.Now().Zone()
+	for i, expected := range []interface{}{"", "1", zone, nil, nil, int64(0)} {
+		d := proj.Exprs[i].(*expression.Constant).Value
+		if d.GetValue() != expected {
+			t.Fatalf("column %d: expect %v, got %v", i, expected, d.GetValue())
+		}
+	}
+	warns := s.sessionVars.StmtCtx.GetWarnings()
+	if len(warns) != 2 || errCode(warns[0]) != mysql.ErrUnknownSystemVariable {
+		t.Fatalf("unexpected warnings %v", warns)
+	}
+
+	// A variable registered with a getter is computed when read.
+	variable.RegisterSysVar(&variable.SysVar{Scope: variable.ScopeNone, Name: "test_answer"}, mysql.TypeLonglong,
+		func(*variable.SessionVars) (string, error) { return "42", nil })
+	_, p, err = compileView(s, "SELECT @@test_answer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := p.(*plan.Projection).Exprs[0].(*expression.Constant)
+	if c.Value.GetInt64() != 42 || c.RetType.Tp != mysql.TypeLonglong || s.sessionVars.StmtCtx.WarningCount() != 0 {
+		t.Fatalf("unexpected value %v of type %d", c.Value.GetValue(), c.RetType.Tp)
+	}
+}
@@ -0,0 +1,333 @@
+package engine
+
+import (
+	"github.com/juju/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+/**
+语句读写的表中的行
+
+INSERT、UPDATE、DELETE、LOAD DATA 和 IMPORT TABLE 通过 tableRowStore 读写表中的行。
+读一张表时按主键的顺序扫描它的聚簇索引，行的句柄是它在扫描中的序号，只在这条语句中
+有效；读过的表在语句中缓存，一条语句检查多行时不会每行都扫描一遍。
+
+外键检查按列的值找行时，如果表有以这些列开头的二级索引，只在索引上读 [值, 值] 这一段
+（HasKey）：子表插入时找父行、父表修改时找子行，都只要知道有没有，找不到时不用读整张表。
+只有 CASCADE 和 SET NULL 要修改找到的行时才读出整行。
+
+AddRow、UpdateRow、DeleteRow 通过表的 schemas.RowWriter 写它的聚簇索引，同时修改缓存：
+新行加在缓存的最后，删掉的行在缓存中留下一个空位，其他行的句柄不变。UPDATE 先去掉旧行
+再加入新行，加入失败时放回旧行。表不能写时（没有实现 RowWriter）它们返回
+ER_NOT_SUPPORTED_YET，语句写第一行之前的检查照常做。
+
+写一行之前先给它加行锁（见 row_locks.go），再记下它写之前的版本（见 row_versions.go）。
+读表是当前读，读最新的行，不按事务的读视图。每次写入都记下撤销它的办法。语句出错时调用 rollback 从后往前撤销这条语句写过的行，
+表的行数变化也一起扣回，语句要么全部生效，要么什么都不改。
+**/
+
+// tableRowStore reads and writes the rows of the tables of a schema for a
+// statement.
+type tableRowStore struct {
+	ctx    context.Context
+	is     schemas.InfoSchema
+	pool   pagePinner
+	schema model.CIStr
+	// schemas are the schemas of the tables listed by Tables, by id.
+	schemas map[int64]model.CIStr
+	// rows are the tables read, by id. The rows deleted are nil.
+	rows map[int64][][]basic.Datum
+	// undo reverts the writes of the statement, in the order of the writes.
+	undo []func() error
+}
+
+func newTableRowStore(ctx context.Context, is schemas.InfoSchema, pool pagePinner, schema model.CIStr) *tableRowStore {
+	return &tableRowStore{ctx: ctx, is: is, pool: pool, schema: schema, rows: make(map[int64][][]basic.Datum)}
+}
+
+// Tables returns the tables of all the schemas.
+func (s *tableRowStore) Tables() []*model.TableInfo {
+	s.schemas = make(map[int64]model.CIStr)
+	var tables []*model.TableInfo
+	for _, db := range s.is.AllSchemas() {
+		for _, tbl := range s.is.SchemaTables(db.Name) {
+			if !tbl.Meta().IsView() {
+				tables = append(tables, tbl.Meta())
+				s.schemas[tbl.Meta().ID] = db.Name
+			}
+		}
+	}
+	return tables
+}
+
+// SchemaOf returns the schema of tbl: the one Tables found it in, or else
+// the schema of the statement.
+func (s *tableRowStore) SchemaOf(tbl *model.TableInfo) model.CIStr {
+	if schema, ok := s.schemas[tbl.ID]; ok {
+		return schema
+	}
+	return s.schema
+}
+
+// table returns the table of tbl in the info schema.
+func (s *tableRowStore) table(tbl *model.TableInfo) (schemas.Table, error) {
+	schema := s.SchemaOf(tbl)
+	t, err := s.is.TableByName(schema, tbl.Name)
+	if err != nil || t == nil {
+		return nil, schemas.ErrTableNotExists.GenByArgs(schema.O, tbl.Name.O)
+	}
+	return t, nil
+}
+
+// Rows returns the rows of tbl in the order of its primary key, their
+// handles numbering them in that order.
+func (s *tableRowStore) Rows(tbl *model.TableInfo) ([]int64, [][]basic.Datum, error) {
+	rows, ok := s.rows[tbl.ID]
+	if !ok {
+		t, err := s.table(tbl)
+		if err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+		reader := &scanRowsReader{ctx: s.ctx, pool: s.pool}
+		if rows, err = reader.TableRows(t); err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+		s.rows[tbl.ID] = rows
+	}
+	handles := make([]int64, 0, len(rows))
+	live := make([][]basic.Datum, 0, len(rows))
+	for i, row := range rows {
+		if row != nil {
+			handles = append(handles, int64(i))
+			live = append(live, row)
+		}
+	}
+	return handles, live, nil
+}
+
+// HasKey reports whether a row of tbl has vals in cols, reading the entries
+// of a secondary index starting with cols. ok is false when tbl has none.
+func (s *tableRowStore) HasKey(tbl *model.TableInfo, cols []model.CIStr, vals []basic.Datum) (bool, bool, error) {
+	idx := keyIndex(tbl, cols)
+	if idx == nil {
+		return false, false, nil
+	}
+	t, err := s.table(tbl)
+	if err != nil {
+		return false, true, errors.Trace(err)
+	}
+	ran := &basic.IndexRange{LowVal: vals, HighVal: vals}
+	scan := NewIndexRangeScanExec(s.ctx, t, idx, []*basic.IndexRange{ran}, s.pool)
+	if err := scan.Open(); err != nil {
+		return false, true, errors.Trace(err)
+	}
+	defer scan.Close()
+	found := scan.Next()
+	return found, true, errors.Trace(scan.Err())
+}
+
+// keyIndex returns a secondary index of tbl whose first columns are cols,
+// whole, or nil if there is none.
+func keyIndex(tbl *model.TableInfo, cols []model.CIStr) *model.IndexInfo {
+	for _, idx := range tbl.Indices {
+		if idx.Primary || idx.State != model.StatePublic || len(idx.Columns) < len(cols) {
+			continue
+		}
+		match := true
+		for i, col := range cols {
+			if idx.Columns[i].Name.L != col.L || idx.Columns[i].Length != basic.UnspecifiedLength {
+				match = false
+				break
+			}
+		}
+		if match {
+			return idx
+		}
+	}
+	return nil
+}
+
+// AddRow adds row to tbl.
+func (s *tableRowStore) AddRow(tbl *model.TableInfo, row []basic.Datum) (int64, error) {
+	w, err := s.writer(tbl)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	// The handle of the row is its place in the rows read.
+	if _, _, err = s.Rows(tbl); err != nil {
+		return 0, errors.Trace(err)
+	}
+	if err = lockRow(s.ctx, tbl, row); err != nil {
+		return 0, errors.Trace(err)
+	}
+	dropVersion, err := keepVersion(s.ctx, tbl, row, nil)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	if err = w.AddRow(row); err != nil {
+		dropVersion()
+		return 0, errors.Trace(err)
+	}
+	s.rows[tbl.ID] = append(s.rows[tbl.ID], row)
+	h := int64(len(s.rows[tbl.ID]) - 1)
+	s.undo = append(s.undo, func() error {
+		if err := w.RemoveRow(row); err != nil {
+			return err
+		}
+		dropVersion()
+		s.rows[tbl.ID][h] = nil
+		addRowDelta(s.ctx, tbl.ID, -1)
+		return nil
+	})
+	return h, nil
+}
+
+// UpdateRow replaces the row with handle h of tbl.
+func (s *tableRowStore) UpdateRow(tbl *model.TableInfo, h int64, row []basic.Datum) error {
+	w, old, err := s.rowOf(tbl, h)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err = lockRow(s.ctx, tbl, old); err != nil {
+		return errors.Trace(err)
+	}
+	if err = lockRow(s.ctx, tbl, row); err != nil {
+		return errors.Trace(err)
+	}
+	dropOld, err := keepVersion(s.ctx, tbl, old, old)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	// The row may move to another key.
+	dropNew, err := keepVersion(s.ctx, tbl, row, nil)
+	if err != nil {
+		dropOld()
+		return errors.Trace(err)
+	}
+	dropVersions := func() {
+		dropNew()
+		dropOld()
+	}
+	if err = w.RemoveRow(old); err != nil {
+		dropVersions()
+		return errors.Trace(err)
+	}
+	if err = w.AddRow(row); err != nil {
+		if restoreErr := w.AddRow(old); restoreErr != nil {
+			return errors.Trace(restoreErr)
+		}
+		dropVersions()
+		return errors.Trace(err)
+	}
+	s.rows[tbl.ID][h] = row
+	s.undo = append(s.undo, func() error {
+		if err := w.RemoveRow(row); err != nil {
+			return err
+		}
+		if err := w.AddRow(old); err != nil {
+			return err
+		}
+		dropVersions()
+		s.rows[tbl.ID][h] = old
+		return nil
+	})
+	return nil
+}
+
+// DeleteRow removes the row with handle h from tbl.
+func (s *tableRowStore) DeleteRow(tbl *model.TableInfo, h int64) error {
+	w, old, err := s.rowOf(tbl, h)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err = lockRow(s.ctx, tbl, old); err != nil {
+		return errors.Trace(err)
+	}
+	dropVersion, err := keepVersion(s.ctx, tbl, old, old)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err = w.RemoveRow(old); err != nil {
+		dropVersion()
+		return errors.Trace(err)
+	}
+	s.rows[tbl.ID][h] = nil
+	s.undo = append(s.undo, func() error {
+		if err := w.AddRow(old); err != nil {
+			return err
+		}
+		dropVersion()
+		s.rows[tbl.ID][h] = old
+		addRowDelta(s.ctx, tbl.ID, 1)
+		return nil
+	})
+	return nil
+}
+
+// rollback reverts the writes of the statement, the last one first, after
+// it failed.
+func (s *tableRowStore) rollback() {
+	for i := len(s.undo) - 1; i >= 0; i-- {
+		if err := s.undo[i](); err != nil {
+			log.Errorf("语句出错后撤销写入失败: %v", err)
+			break
+		}
+	}
+	s.undo = nil
+}
+
+// writer returns the writer of the rows of tbl.
+func (s *tableRowStore) writer(tbl *model.TableInfo) (schemas.RowWriter, error) {
+	t, err := s.table(tbl)
+	if err != nil {
+		return nil, err
+	}
+	w, ok := t.(schemas.RowWriter)
+	if !ok {
+		return nil, errWriteRows()
+	}
+	return w, nil
+}
+
+// rowOf returns the writer of tbl and its row with handle h.
+func (s *tableRowStore) rowOf(tbl *model.TableInfo, h int64) (schemas.RowWriter, []basic.Datum, error) {
+	w, err := s.writer(tbl)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, _, err = s.Rows(tbl); err != nil {
+		return nil, nil, err
+	}
+	rows := s.rows[tbl.ID]
+	if h < 0 || h >= int64(len(rows)) || rows[h] == nil {
+		return nil, nil, errors.Errorf("table %s has no row with handle %d", tbl.Name.O, h)
+	}
+	return w, rows[h], nil
+}
+
+// errWriteRows is the error of the writes of a tableRowStore to a table
+// whose rows can't be written.
+func errWriteRows() error {
+	return mysql.NewErrf(mysql.ErrNotSupportedYet, "writing the rows of a table")
+}
+
+// addRows adds rows to tbl of store, and returns the number of rows added.
+func addRows(ctx context.Context, store upsertRowStore, tbl *model.TableInfo, rows [][]basic.Datum) (uint64, error) {
+	var affected uint64
+	var err error
+	for _, row := range rows {
+		if _, err = store.AddRow(tbl, row); err != nil {
+			break
+		}
+		affected++
+	}
+	if affected > 0 {
+		addRowDelta(ctx, tbl.ID, int64(affected))
+	}
+	return affected, errors.Trace(err)
+}
@@ -0,0 +1,229 @@
+package engine
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/conf"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/buffer_pool"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/innodb_store/store"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// newStoredTestEngine returns an engine whose schema test holds tables,
+// stored in a temporary data directory.
+func newStoredTestEngine(t *testing.T, tables ...*model.TableInfo) (*XMySQLEngine, *serverTestSession) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "rows")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	cfg := conf.NewCfg()
+	cfg.DataDir = dir
+	pool := buffer_pool.NewBufferPool(16*16384, 0.75, 0.25, 1000, basic.NewFileSystem(cfg))
+	srv := &XMySQLEngine{conf: cfg, pool: pool}
+	storeTestTables(t, srv, tables...)
+	return srv, &serverTestSession{session: newViewTestSession(t, srv.infoSchemaManager)}
+}
+
+// storeTestTables makes tables, stored in the data directory of srv, the
+// schema test of srv. The first column of a table without a primary key
+// becomes it.
+func storeTestTables(t *testing.T, srv *XMySQLEngine, tables ...*model.TableInfo) {
+	t.Helper()
+	is := newViewTestSchema()
+	for i, info := range tables {
+		if info.ID == 0 {
+			info.ID = int64(i + 1)
+		}
+		if !info.PKIsHandle && len(info.Indices) == 0 {
+			info.PKIsHandle = true
+			info.Columns[0].Flag |= mysql.PriKeyFlag | mysql.NotNullFlag
+		}
+		tbl, err := store.CreateOrdinaryTable(srv.conf, srv.pool, "test", info, uint32(100+i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		is.tables[info.Name.L] = tbl
+	}
+	srv.infoSchemaManager = is
+}
+
+// execStored runs sql on srv, expecting error code or none when it is 0,
+// and returns the rows it sends as "1,a;2,b".
+func execStored(t *testing.T, srv *XMySQLEngine, s *serverTestSession, sql string, code uint16) string {
+	t.Helper()
+	s.rows, s.errs = nil, nil
+	srv.ExecuteQuery(s, sql)
+	if code == 0 && len(s.errs) > 0 || code != 0 && (len(s.errs) != 1 || s.errs[0].Code != code) {
+		t.Fatalf("%s: expect error %d, got %v", sql, code, s.errs)
+	}
+	return storedRows(s)
+}
+
+// storedRows returns the rows s received, the fields separated by commas
+// and the rows by semicolons.
+func storedRows(s *serverTestSession) string {
+	var rows []string
+	for _, row := range s.rows {
+		var fields []string
+		for _, d := range row {
+			v, _ := d.ToString()
+			if d.IsNull() {
+				v = "NULL"
+			}
+			fields = append(fields, v)
+		}
+		rows = append(rows, strings.Join(fields, ","))
+	}
+	return strings.Join(rows, ";")
+}
+
+func TestTableRowStore(t *testing.T) {
+	is := newViewTestSchema(newTraceTestTable())
+	s := newViewTestSession(t, is)
+	tree := &indexTestTree{entries: [][]basic.Datum{
+		basic.MakeDatums(int64(1), int64(10), int64(100)),
+		basic.MakeDatums(int64(2), int64(20), int64(200)),
+	}}
+	tbl := &scanTestTable{spaceTestTable: &spaceTestTable{viewTestTable: is.tables["t"].(*viewTestTable), spaceId: 5}, tree: tree}
+	is.tables["t"] = tbl
+	store := newTableRowStore(s, is, nil, model.NewCIStr("test"))
+
+	// The rows are numbered in the order of the clustered index, and read
+	// once a statement.
+	for i := 0; i < 2; i++ {
+		handles, rows, err := store.Rows(tbl.Meta())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != 2 || handles[0] != 0 || handles[1] != 1 || rows[1][1].GetInt64() != 20 {
+			t.Fatalf("expect the 2 rows of the tree, got %v %v", handles, rows)
+		}
+	}
+	if tree.read != 2 {
+		t.Fatalf("expect the tree read once, got %d entries read", tree.read)
+	}
+
+	// The tree can't be written: nothing is written, nor counted.
+	affected, err := addRows(s, store, tbl.Meta(), [][]basic.Datum{basic.MakeDatums(int64(3), nil, nil)})
+	if affected != 0 || toSQLError(err).Code != mysql.ErrNotSupportedYet {
+		t.Fatalf("expect error %d and no rows, got %d %v", mysql.ErrNotSupportedYet, affected, err)
+	}
+	if len(s.sessionVars.TxnCtx.TableDeltaMap) != 0 {
+		t.Fatalf("expect no change of the row count, got %v", s.sessionVars.TxnCtx.TableDeltaMap)
+	}
+}
+
+func TestTableRowStoreHasKey(t *testing.T) {
+	is := newViewTestSchema(newCompositeIndexTestTable())
+	s := newViewTestSession(t, is)
+	// The entries of idx_age_city, the id after the key.
+	tree := &indexTestTree{entries: [][]basic.Datum{
+		basic.MakeDatums(int64(20), "Beijing", int64(1)),
+		basic.MakeDatums(int64(30), "Beijing", int64(3)),
+		basic.MakeDatums(int64(40), "Shanghai", int64(4)),
+	}}
+	tbl := &scanTestTable{spaceTestTable: &spaceTestTable{viewTestTable: is.tables["t"].(*viewTestTable), spaceId: 5}, tree: tree}
+	is.tables["t"] = tbl
+	store := newTableRowStore(s, is, nil, model.NewCIStr("test"))
+
+	age := []model.CIStr{model.NewCIStr("age")}
+	for _, tt := range []struct {
+		cols  []model.CIStr
+		vals  []basic.Datum
+		found bool
+		ok    bool
+		read  int
+	}{
+		// The scan stops at the first key past the value.
+		{age, basic.MakeDatums(int64(30)), true, true, 2},
+		{age, basic.MakeDatums(int64(25)), false, true, 2},
+		{[]model.CIStr{model.NewCIStr("age"), model.NewCIStr("city")}, basic.MakeDatums(int64(40), "Shanghai"), true, true, 3},
+		// city isn't a prefix of the index.
+		{[]model.CIStr{model.NewCIStr("city")}, basic.MakeDatums("Beijing"), false, false, 0},
+	} {
+		tree.read = 0
+		found, ok, err := store.HasKey(tbl.Meta(), tt.cols, tt.vals)
+		if err != nil || found != tt.found || ok != tt.ok || tree.read != tt.read {
+			t.Fatalf("%v %v: expect %v %v reading %d entries, got %v %v reading %d, %v",
+				tt.cols, tt.vals, tt.found, tt.ok, tt.read, found, ok, tree.read, err)
+		}
+	}
+}
+
+// keyTestStore is an fkTestStore telling from its keys alone that no row
+// has them, counting the tables it reads.
+type keyTestStore struct {
+	*fkTestStore
+	read int
+}
+
+func (s *keyTestStore) HasKey(tbl *model.TableInfo, cols []model.CIStr, vals []basic.Datum) (bool, bool, error) {
+	return false, true, nil
+}
+
+func (s *keyTestStore) Rows(tbl *model.TableInfo) ([]int64, [][]basic.Datum, error) {
+	s.read++
+	return s.fkTestStore.Rows(tbl)
+}
+
+func TestFKCheckReadsKeys(t *testing.T) {
+	parent, items := newIgnoreTestTables()
+	s := newViewTestSession(t, newViewTestSchema(parent, items))
+	store := &keyTestStore{fkTestStore: newFKTestStore(parent, items)}
+	store.insert(parent, 1)
+	fk := &fkChecker{ctx: s, store: store}
+
+	// The parent row isn't looked for in the rows.
+	if err := fk.checkChildRow(items, basic.MakeDatums(int64(1), int64(10), int64(1))); errCode(err) != mysql.ErrNoReferencedRow2 {
+		t.Fatalf("expect error %d, got %v", mysql.ErrNoReferencedRow2, err)
+	}
+	// Nor the child rows.
+	if err := fk.onDeleteRow(parent, basic.MakeDatums(int64(1))); err != nil {
+		t.Fatal(err)
+	}
+	if store.read != 0 {
+		t.Fatalf("expect no table read, got %d", store.read)
+	}
+}
+
+func TestTableRowStoreWrites(t *testing.T) {
+	srv, s := newStoredTestEngine(t, newFKTestTable("t", "id", "a"))
+	tbl := srv.infoSchemaManager.(*viewTestSchema).tables["t"].Meta()
+	rows := newTableRowStore(s, srv.infoSchemaManager, srv.pool, model.NewCIStr("test"))
+	for _, row := range [][]basic.Datum{basic.MakeDatums(int64(2), int64(20)), basic.MakeDatums(int64(1), int64(10))} {
+		if _, err := rows.AddRow(tbl, row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rows.UpdateRow(tbl, 1, basic.MakeDatums(int64(3), int64(30))); err != nil {
+		t.Fatal(err)
+	}
+	if err := rows.DeleteRow(tbl, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := rows.DeleteRow(tbl, 0); err == nil {
+		t.Fatalf("expect the row deleted")
+	}
+	// A failed update leaves the row.
+	if _, err := rows.AddRow(tbl, basic.MakeDatums(int64(4), int64(40))); err != nil {
+		t.Fatal(err)
+	}
+	if err := rows.UpdateRow(tbl, 2, basic.MakeDatums(int64(3), int64(0))); toSQLError(err).Code != mysql.ErrDupEntry {
+		t.Fatalf("expect error %d, got %v", mysql.ErrDupEntry, err)
+	}
+	if handles, got, _ := rows.Rows(tbl); len(handles) != 2 || handles[0] != 1 || handles[1] != 2 {
+		t.Fatalf("expect the rows 1 and 2 left, got %v %v", handles, got)
+	}
+
+	// The statements read the rows written, in the order of the key.
+	if got := execStored(t, srv, s, "SELECT * FROM t", 0); got != "3,30;4,40" {
+		t.Fatalf("expect the rows written, got %s", got)
+	}
+}
@@ -0,0 +1,69 @@
+package engine
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/conf"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+func TestTransactionStatus(t *testing.T) {
+	dir, err := ioutil.TempDir("", "datadir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cfg := conf.NewCfg()
+	cfg.DataDir = dir
+	is := &fkTestSchema{crossDBTestSchema{tables: map[string]schemas.Table{
+		"test.t": &viewTestTable{meta: newTraceTestTable()},
+	}}}
+	srv := &XMySQLEngine{conf: cfg, infoSchemaManager: is}
+	srv.initAuditLog()
+	defer srv.auditLog.Close()
+	s := &txnTestSession{&serverTestSession{session: newViewTestSession(t, is)}}
+
+	const (
+		inTrans    = mysql.ServerStatusInTrans
+		autocommit = mysql.ServerStatusAutocommit
+	)
+	// The table can't be written: the changes fail, still starting the
+	// transactions.
+	const notSupported = mysql.ErrNotSupportedYet
+	for _, tt := range []struct {
+		sql    string
+		status uint16
+		err    uint16
+	}{
+		{"SELECT 1", autocommit, 0},
+		{"BEGIN", autocommit | inTrans, 0},
+		{"INSERT INTO t VALUES (1, 2, 3)", autocommit | inTrans, notSupported},
+		{"COMMIT", autocommit, 0},
+		// Outside a transaction each statement commits on its own.
+		{"INSERT INTO t VALUES (2, 2, 3)", autocommit, notSupported},
+		{"SET autocommit = 0", 0, 0},
+		// Without a table, no transaction is started.
+		{"SELECT 1", 0, 0},
+		{"INSERT INTO t VALUES (3, 2, 3)", inTrans, notSupported},
+		{"COMMIT", 0, 0},
+		{"DELETE FROM t WHERE id = 3", inTrans, 0},
+		{"ROLLBACK", 0, 0},
+		{"INSERT INTO t VALUES (4, 2, 3)", inTrans, notSupported},
+		// Turning autocommit on commits the transaction.
+		{"SET autocommit = 1", autocommit, 0},
+		{"SET sql_mode = 'NO_BACKSLASH_ESCAPES'", autocommit | mysql.ServerStatusNoBackslashEscaped, 0},
+		{"SET sql_mode = ''", autocommit, 0},
+	} {
+		s.errs = nil
+		srv.ExecuteQuery(s, tt.sql)
+		if tt.err == 0 && len(s.errs) > 0 || tt.err != 0 && (len(s.errs) != 1 || s.errs[0].Code != tt.err) {
+			t.Fatalf("%s: expect error %d, got %v", tt.sql, tt.err, s.errs)
+		}
+		if got := s.Status(); got != tt.status {
+			t.Fatalf("%s: expect status %#x, got %#x", tt.sql, tt.status, got)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package engine
+
+import (
+	"github.com/juju/errors"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/expression"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// UpdateValues applies the SET assignments of a single-table UPDATE to the
+// rows its WHERE clause matched.
+type UpdateValues struct {
+	ctx context.Context
+
+	tableCols   []*schemas.Column
+	OrderedList []*expression.Assignment
+	IgnoreErr   bool
+}
+
+// NewUpdateValues creates an UpdateValues for an update plan of tbl.
+func NewUpdateValues(ctx context.Context, v *plan.Update, tbl *model.TableInfo) *UpdateValues {
+	cols := make([]*schemas.Column, 0, len(tbl.Columns))
+	for _, col := range tbl.Columns {
+		cols = append(cols, schemas.ToColumn(col))
+	}
+	return &UpdateValues{
+		ctx:         ctx,
+		tableCols:   cols,
+		OrderedList: v.OrderedList,
+		IgnoreErr:   v.IgnoreErr,
+	}
+}
+
+// update runs the single-table UPDATE compiled to p on tbl of store: the
+// rows its WHERE clause matches, in the order of its ORDER BY and up to its
+// LIMIT, get the assignments.
+func (e *UpdateValues) update(store fkRowStore, tbl *model.TableInfo, p *plan.Update) (uint64, error) {
+	handles, rows, err := matchedRows(e.ctx, store, tbl, p)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	return e.updateRows(store, tbl, handles, rows)
+}
+
+// updateRows replaces the rows of tbl of store with the given handles by
+// their values after the assignments, and returns the number of rows
+// changed. A value that doesn't fit its column fails the statement in strict
+// mode; with IGNORE it is clamped with a warning, and a row that would break
+// a unique key or a foreign key keeps its old values with a warning.
+func (e *UpdateValues) updateRows(store fkRowStore, tbl *model.TableInfo, handles []int64, rows [][]basic.Datum) (uint64, error) {
+	sc := e.ctx.GetSessionVars().StmtCtx
+	fk := &fkChecker{ctx: e.ctx, store: store}
+	var affected uint64
+	for i, oldRow := range rows {
+		newRow, err := e.assign(oldRow)
+		if err != nil {
+			return affected, errors.Trace(err)
+		}
+		same, err := datumsEqual(sc, oldRow, newRow)
+		if err != nil {
+			return affected, errors.Trace(err)
+		}
+		if same {
+			continue
+		}
+		if err = checkUpdatedRow(sc, fk, tbl, handles[i], oldRow, newRow); err != nil {
+			if skipRowOnError(e.ctx, e.IgnoreErr, err) {
+				continue
+			}
+			return affected, errors.Trace(err)
+		}
+		if err = store.UpdateRow(tbl, handles[i], newRow); err != nil {
+			return affected, errors.Trace(err)
+		}
+		affected++
+	}
+	addRowsChanged(e.ctx, tbl.ID, int64(affected))
+	return affected, nil
+}
+
+// assign returns oldRow with the assignments applied in order, each seeing
+// the values assigned before it like MySQL.
+func (e *UpdateValues) assign(oldRow []basic.Datum) ([]basic.Datum, error) {
+	row := make([]basic.Datum, len(oldRow))
+	copy(row, oldRow)
+	for _, assign := range e.OrderedList {
+		val, err := assign.Expr.Eval(row)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if row[assign.Col.Index], err = assignValue(e.ctx, e.tableCols[assign.Col.Index], val, e.IgnoreErr); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	return row, nil
+}
+
+func init() {
+	registerStmtHandler(&ast.UpdateStmt{}, &stmtHandler{name: "update", handle: (*XMySQLEngine).execUpdate})
+}
+
+// execUpdate runs a single-table UPDATE.
+func (srv *XMySQLEngine) execUpdate(session innodb.MySQLServerSession, stmt ast.StmtNode, p plan.Plan) {
+	if v, ok := p.(*plan.Update); ok {
+		tbl, db := dmlTable(v)
+		if tbl == nil {
+			session.SendError(mysql.NewErrf(mysql.ErrNotSupportedYet, "UPDATE of several tables"))
+			return
+		}
+		store := newTableRowStore(session, srv.infoSchemaManager, srv.pool, db)
+		affected, err := NewUpdateValues(session, v, tbl).update(store, tbl, v)
+		if err != nil {
+			store.rollback()
+			session.SendError(toSQLError(err))
+			return
+		}
+		session.GetSessionVars().StmtCtx.AddAffectedRows(affected)
+		session.SendOK()
+	}
+}
@@ -0,0 +1,125 @@
+package engine
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/varsutil"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+func TestUpdateIgnore(t *testing.T) {
+	parent, items := newIgnoreTestTables()
+	s := newViewTestSession(t, newViewTestSchema(parent, items))
+	if err := varsutil.SetSessionSystemVar(s.sessionVars, variable.SQLModeVar, basic.NewStringDatum("STRICT_TRANS_TABLES")); err != nil {
+		t.Fatal(err)
+	}
+	store := newFKTestStore(parent, items)
+	store.insert(parent, 1)
+	store.insert(parent, 2)
+	reset := func() {
+		store.rows["items"] = make(map[int64][]basic.Datum)
+		store.insert(items, 1, 10, 1)
+		store.insert(items, 2, 20, 1)
+		store.insert(items, 3, 30, 2)
+	}
+	// update runs sql on the rows of items, in the order they were added.
+	update := func(sql string) (uint64, error) {
+		_, p, err := compileView(s, sql)
+		if err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+		return NewUpdateValues(s, p.(*plan.Update), items).update(store, items, p.(*plan.Update))
+	}
+	rows := func() [][]int64 {
+		var got [][]int64
+		_, all, _ := store.Rows(items)
+		for _, row := range all {
+			vals := make([]int64, 0, len(row))
+			for _, d := range row {
+				vals = append(vals, d.GetInt64())
+			}
+			got = append(got, vals)
+		}
+		sort.Slice(got, func(i, j int) bool { return got[i][0] < got[j][0] })
+		return got
+	}
+
+	tests := []struct {
+		sql      string
+		err      uint16
+		affected uint64
+		rows     [][]int64
+		warnings []uint16
+	}{
+		// A row taking the unique key of another one fails the statement,
+		// with IGNORE it keeps its old values.
+		{"UPDATE items SET code = 20", mysql.ErrDupEntry, 0, nil, nil},
+		{"UPDATE IGNORE items SET code = 20", 0, 0, [][]int64{{1, 10, 1}, {2, 20, 1}, {3, 30, 2}},
+			[]uint16{mysql.ErrDupEntry, mysql.ErrDupEntry}},
+		// The same for the primary key, and for a foreign key without parent.
+		{"UPDATE IGNORE items SET id = 3", 0, 0, [][]int64{{1, 10, 1}, {2, 20, 1}, {3, 30, 2}},
+			[]uint16{mysql.ErrDupEntry, mysql.ErrDupEntry}},
+		{"UPDATE items SET pid = pid + 1", mysql.ErrNoReferencedRow2, 0, nil, nil},
+		{"UPDATE IGNORE items SET pid = pid + 1", 0, 2, [][]int64{{1, 10, 2}, {2, 20, 2}, {3, 30, 2}},
+			[]uint16{mysql.ErrNoReferencedRow2}},
+		// NULL in a NOT NULL column fails in strict mode, with IGNORE the
+		// column gets its zero value, which only the first row can take.
+		{"UPDATE items SET code = NULL", mysql.ErrBadNull, 0, nil, nil},
+		{"UPDATE IGNORE items SET code = NULL, id = id * 10", 0, 1, [][]int64{{2, 20, 1}, {3, 30, 2}, {10, 0, 1}},
+			[]uint16{mysql.ErrBadNull, mysql.ErrBadNull, mysql.ErrDupEntry, mysql.ErrBadNull, mysql.ErrDupEntry}},
+	}
+	for _, tt := range tests {
+		reset()
+		affected, err := update(tt.sql)
+		if tt.err != 0 {
+			if errCode(err) != tt.err {
+				t.Fatalf("%s: expect error %d, got %v", tt.sql, tt.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tt.sql, err)
+		}
+		if affected != tt.affected {
+			t.Fatalf("%s: expect %d rows changed, got %d", tt.sql, tt.affected, affected)
+		}
+		if got := rows(); !reflect.DeepEqual(got, tt.rows) {
+			t.Fatalf("%s: expect %v, got %v", tt.sql, tt.rows, got)
+		}
+		if got := warningCodes(s); !reflect.DeepEqual(got, tt.warnings) {
+			t.Fatalf("%s: expect warnings %v, got %v", tt.sql, tt.warnings, got)
+		}
+	}
+}
+
+func TestUpdateIgnoreStored(t *testing.T) {
+	parent, items := newIgnoreTestTables()
+	// The stored tables have no secondary indexes.
+	items.Indices = nil
+	srv, s := newStoredTestEngine(t, parent, items)
+	execStored(t, srv, s, "SET sql_mode = 'STRICT_TRANS_TABLES'", 0)
+	execStored(t, srv, s, "INSERT INTO parent VALUES (1), (2)", 0)
+	execStored(t, srv, s, "INSERT INTO items VALUES (1, 10, 1), (2, 20, 1), (3, 30, 2)", 0)
+
+	execStored(t, srv, s, "UPDATE items SET id = 3 WHERE id = 2", mysql.ErrDupEntry)
+	execStored(t, srv, s, "UPDATE items SET pid = pid + 1", mysql.ErrNoReferencedRow2)
+	if got := execStored(t, srv, s, "SELECT * FROM items", 0); got != "1,10,1;2,20,1;3,30,2" {
+		t.Fatalf("expect the failed updates to leave the rows, got %s", got)
+	}
+	execStored(t, srv, s, "UPDATE IGNORE items SET pid = pid + 1, code = NULL", 0)
+	if n := s.sessionVars.StmtCtx.AffectedRows(); n != 2 {
+		t.Fatalf("expect 2 rows changed, got %d", n)
+	}
+	want := []uint16{mysql.ErrBadNull, mysql.ErrBadNull, mysql.ErrBadNull, mysql.ErrNoReferencedRow2}
+	if got := warningCodes(s.session); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expect warnings %v, got %v", want, got)
+	}
+	if got := execStored(t, srv, s, "SELECT * FROM items", 0); got != "1,0,2;2,0,2;3,30,2" {
+		t.Fatalf("expect the rows with a parent to change, got %s", got)
+	}
+}
@@ -0,0 +1,39 @@
+package engine
+
+import (
+	"github.com/zhukovaskychina/xmysql-server/server/innodb"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// ValidateQuery checks query as ExecuteQuery would before running it, in
+// database when it isn't empty: it is parsed, its names are resolved, its
+// expressions typed and the privileges of the user of session checked. It
+// returns the first error as a *mysql.SQLError, nil when query would run.
+// Nothing is executed and the session is left as it was.
+func (srv *XMySQLEngine) ValidateQuery(session innodb.MySQLServerSession, query, database string) error {
+	sessVars := session.GetSessionVars()
+	currentDB, sc, trace := sessVars.CurrentDB, sessVars.StmtCtx, sessVars.LastOptimizerTrace
+	defer func() {
+		sessVars.CurrentDB, sessVars.StmtCtx, sessVars.LastOptimizerTrace = currentDB, sc, trace
+	}()
+	if database != "" {
+		if _, ok := srv.infoSchemaManager.SchemaByName(model.NewCIStr(database)); !ok {
+			return toSQLError(schemas.ErrDatabaseNotExists.GenByArgs(database))
+		}
+		sessVars.CurrentDB = database
+	}
+	stmt, err := session.ParseOneSQL(query, mysql.UTF8Charset, mysql.UTF8DefaultCollation)
+	if err != nil {
+		return toSQLError(err)
+	}
+	ResetStmtCtx(session, stmt)
+	if err = srv.checkReadOnly(session, stmt); err != nil {
+		return toSQLError(err)
+	}
+	if _, err = Compile(session, stmt); err != nil {
+		return toSQLError(err)
+	}
+	return nil
+}
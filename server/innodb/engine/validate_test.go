@@ -0,0 +1,46 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+func TestValidateQuery(t *testing.T) {
+	is := newViewTestSchema(newTraceTestTable())
+	srv := &XMySQLEngine{infoSchemaManager: is}
+	s := &serverTestSession{session: newViewTestSession(t, is)}
+	s.sessionVars.CurrentDB = ""
+
+	for sql, code := range map[string]uint16{
+		"SELECT id, a FROM t WHERE b > 1 ORDER BY a": 0,
+		"SELECT c FROM t":       mysql.ErrBadField,
+		"SELECT * FROM missing": mysql.ErrNoSuchTable,
+		"SELECT FROM t":         mysql.ErrParse,
+	} {
+		err := srv.ValidateQuery(s, sql, "test")
+		if code == 0 {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", sql, err)
+			}
+			continue
+		}
+		if got := sqlErrCode(err); got != code {
+			t.Errorf("%s: expect error %d, got %v", sql, code, err)
+		}
+	}
+	if got := sqlErrCode(srv.ValidateQuery(s, "SELECT 1", "nope")); got != mysql.ErrBadDB {
+		t.Errorf("expect error %d for an unknown database, got %d", mysql.ErrBadDB, got)
+	}
+	if s.sessionVars.CurrentDB != "" || len(s.errs) != 0 || s.rows != nil {
+		t.Errorf("expect the session untouched, got db %q, errors %v, rows %v", s.sessionVars.CurrentDB, s.errs, s.rows)
+	}
+}
+
+// sqlErrCode returns the code of the *mysql.SQLError err, 0 for nil.
+func sqlErrCode(err error) uint16 {
+	if e, ok := err.(*mysql.SQLError); ok {
+		return e.Code
+	}
+	return 0
+}
@@ -0,0 +1,145 @@
+package engine
+
+import (
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// createView stores the definition of a resolved CREATE VIEW statement.
+// The columns of the view are named after the column list, or after the
+// fields of the SELECT when there is none, and take the types of the fields.
+func createView(is schemas.InfoSchema, stmt *ast.CreateViewStmt) error {
+	schema, name := stmt.ViewName.Schema, stmt.ViewName.Name
+	if _, ok := is.SchemaByName(schema); !ok {
+		return schemas.ErrDatabaseNotExists.GenByArgs(schema.O)
+	}
+	fields := stmt.Select.(ast.ResultSetNode).GetResultFields()
+	if len(stmt.Cols) > 0 && len(stmt.Cols) != len(fields) {
+		return schemas.ErrViewWrongList.GenByArgs()
+	}
+	if old, err := is.TableByName(schema, name); err == nil && old != nil {
+		if !old.Meta().IsView() {
+			if stmt.OrReplace {
+				return schemas.ErrWrongObject.GenByArgs(schema.O, name.O, "VIEW")
+			}
+			return schemas.ErrTableExists.GenByArgs(name.O)
+		}
+		if !stmt.OrReplace {
+			return schemas.ErrTableExists.GenByArgs(name.O)
+		}
+	}
+
+	info := &model.TableInfo{
+		Name:  name,
+		State: model.StatePublic,
+		View:  &model.ViewInfo{SelectStmt: stmt.Select.Text(), Cols: stmt.Cols},
+	}
+	names := make(map[string]struct{}, len(fields))
+	for i, rf := range fields {
+		col := &model.ColumnInfo{
+			ID:     int64(i + 1),
+			Offset: i,
+			Name:   viewColumnName(stmt.Cols, i, rf),
+			State:  model.StatePublic,
+		}
+		if _, ok := names[col.Name.L]; ok {
+			return schemas.ErrColumnExists.GenByArgs(col.Name.O)
+		}
+		names[col.Name.L] = struct{}{}
+		if rf.Column != nil && rf.Column.Name.L != "" {
+			col.FieldType = rf.Column.FieldType
+		} else if tp := rf.Expr.GetType(); tp.Tp != mysql.TypeUnspecified || tp.Flen > 0 {
+			col.FieldType = *tp
+		} else {
+			col.FieldType = *basic.NewFieldType(mysql.TypeVarString)
+		}
+		info.Columns = append(info.Columns, col)
+	}
+	info.MaxColumnID = int64(len(info.Columns))
+	return errors.Trace(is.CreateView(schema, info, stmt.OrReplace))
+}
+
+func viewColumnName(cols []model.CIStr, i int, rf *ast.ResultField) model.CIStr {
+	if len(cols) > 0 {
+		return cols[i]
+	}
+	if rf.ColumnAsName.L != "" {
+		return rf.ColumnAsName
+	}
+	return rf.Column.Name
+}
+
+// dropView removes the views of a DROP VIEW statement. Nothing is dropped
+// when one of the names is a table. Missing views are reported together,
+// as warnings under IF EXISTS.
+func dropView(ctx context.Context, is schemas.InfoSchema, stmt *ast.DropTableStmt) error {
+	var views, missing []*ast.TableName
+	for _, tn := range stmt.Tables {
+		tbl, err := is.TableByName(tn.Schema, tn.Name)
+		if err != nil || tbl == nil {
+			missing = append(missing, tn)
+			continue
+		}
+		if !tbl.Meta().IsView() {
+			return schemas.ErrWrongObject.GenByArgs(tn.Schema.O, tn.Name.O, "VIEW")
+		}
+		views = append(views, tn)
+	}
+	for _, tn := range views {
+		if err := is.DropView(tn.Schema, tn.Name); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(missing))
+	for _, tn := range missing {
+		names = append(names, tn.Schema.O+"."+tn.Name.O)
+	}
+	if stmt.IfExists {
+		ctx.GetSessionVars().StmtCtx.AppendWarning(schemas.ErrTableDropExists.GenByArgs(strings.Join(names, ",")))
+		return nil
+	}
+	return schemas.ErrTableDropExists.GenByArgs(strings.Join(names, ","))
+}
+
+func init() {
+	registerStmtHandler(&ast.CreateViewStmt{}, &stmtHandler{name: "create view", handle: (*XMySQLEngine).execCreateView})
+	registerStmtHandler(&ast.DropTableStmt{}, &stmtHandler{
+		name: "drop view",
+		match: func(stmt ast.StmtNode) bool {
+			return stmt.(*ast.DropTableStmt).IsView
+		},
+		handle: (*XMySQLEngine).execDropView,
+	})
+}
+
+// execCreateView runs a CREATE VIEW.
+func (srv *XMySQLEngine) execCreateView(session innodb.MySQLServerSession, stmt ast.StmtNode, p plan.Plan) {
+	x := stmt.(*ast.CreateViewStmt)
+	if err := createView(srv.infoSchemaManager, x); err != nil {
+		session.SendError(toSQLError(err))
+		return
+	}
+	session.SendOK()
+}
+
+// execDropView runs a DROP VIEW.
+func (srv *XMySQLEngine) execDropView(session innodb.MySQLServerSession, stmt ast.StmtNode, p plan.Plan) {
+	x := stmt.(*ast.DropTableStmt)
+	if err := dropView(session, srv.infoSchemaManager, x); err != nil {
+		session.SendError(toSQLError(err))
+		return
+	}
+	session.SendOK()
+}
@@ -0,0 +1,269 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/parser"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// viewTestTable is a base table known by its meta only.
+type viewTestTable struct {
+	schemas.Table
+	meta *model.TableInfo
+}
+
+func (t *viewTestTable) Meta() *model.TableInfo {
+	return t.meta
+}
+
+func (t *viewTestTable) Cols() []*schemas.Column {
+	cols := make([]*schemas.Column, 0, len(t.meta.Columns))
+	for _, col := range t.meta.Columns {
+		cols = append(cols, schemas.ToColumn(col))
+	}
+	return cols
+}
+
+// GetBtree returns an empty tree: the table has no rows.
+func (t *viewTestTable) GetBtree(indexName string) basic.Tree {
+	return &indexTestTree{}
+}
+
+func (t *viewTestTable) SpaceId() uint32 {
+	return 0
+}
+
+// viewTestSchema keeps the tables and views of the "test" database.
+type viewTestSchema struct {
+	schemas.InfoSchema
+	tables map[string]schemas.Table
+}
+
+func newViewTestSchema(tables ...*model.TableInfo) *viewTestSchema {
+	is := &viewTestSchema{tables: make(map[string]schemas.Table)}
+	for _, tbl := range tables {
+		is.tables[tbl.Name.L] = &viewTestTable{meta: tbl}
+	}
+	return is
+}
+
+func (is *viewTestSchema) SchemaByName(schema model.CIStr) (*model.DBInfo, bool) {
+	if schema.L != "test" {
+		return nil, false
+	}
+	return &model.DBInfo{Name: schema}, true
+}
+
+func (is *viewTestSchema) AllSchemas() []*model.DBInfo {
+	return []*model.DBInfo{{Name: model.NewCIStr("test")}}
+}
+
+func (is *viewTestSchema) TableByName(schema, table model.CIStr) (schemas.Table, error) {
+	if tbl, ok := is.tables[table.L]; ok && schema.L == "test" {
+		return tbl, nil
+	}
+	return nil, schemas.ErrTableNotExists.GenByArgs(schema.O, table.O)
+}
+
+func (is *viewTestSchema) SchemaTables(schema model.CIStr) []schemas.Table {
+	if schema.L != "test" {
+		return nil
+	}
+	tables := make([]schemas.Table, 0, len(is.tables))
+	for _, tbl := range is.tables {
+		tables = append(tables, tbl)
+	}
+	return tables
+}
+
+func (is *viewTestSchema) TableByID(id int64) (schemas.Table, bool) {
+	for _, tbl := range is.tables {
+		if tbl.Meta().ID == id {
+			return tbl, true
+		}
+	}
+	return nil, false
+}
+
+func (is *viewTestSchema) CreateView(schema model.CIStr, view *model.TableInfo, orReplace bool) error {
+	is.tables[view.Name.L] = schemas.NewView(view)
+	return nil
+}
+
+func (is *viewTestSchema) DropView(schema, view model.CIStr) error {
+	delete(is.tables, view.L)
+	return nil
+}
+
+func newViewTestSession(t *testing.T, is schemas.InfoSchema) *session {
+	s, err := createSession(is)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.sessionVars.CurrentDB = "test"
+	return s
+}
+
+// compileView parses and compiles sql like ExecuteQuery does.
+func compileView(s *session, sql string) (ast.StmtNode, plan.Plan, error) {
+	stmt, err := parser.New().ParseOneStmt(sql, mysql.UTF8Charset, mysql.UTF8DefaultCollation)
+	if err != nil {
+		return nil, nil, err
+	}
+	ResetStmtCtx(s, stmt)
+	p, err := Compile(s, stmt)
+	return stmt, p, err
+}
+
+func execView(t *testing.T, s *session, sql string) error {
+	stmt, _, err := compileView(s, sql)
+	if err != nil {
+		return err
+	}
+	is := s.sessionVars.TxnCtx.InfoSchema.(schemas.InfoSchema)
+	switch x := stmt.(type) {
+	case *ast.CreateViewStmt:
+		return createView(is, x)
+	case *ast.DropTableStmt:
+		return dropView(s, is, x)
+	}
+	t.Fatalf("unexpected statement %s", sql)
+	return nil
+}
+
+func TestCreateView(t *testing.T) {
+	is := newViewTestSchema()
+	s := newViewTestSession(t, is)
+	if err := execView(t, s, "CREATE VIEW v AS SELECT 1 AS a, 'x' AS b"); err != nil {
+		t.Fatal(err)
+	}
+	tbl, err := is.TableByName(model.NewCIStr("test"), model.NewCIStr("v"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta := tbl.Meta()
+	if !meta.IsView() || meta.View.SelectStmt != "SELECT 1 AS a, 'x' AS b" {
+		t.Fatalf("unexpected view definition %+v", meta.View)
+	}
+	if len(meta.Columns) != 2 || meta.Columns[0].Name.O != "a" || meta.Columns[1].Name.O != "b" {
+		t.Fatalf("unexpected view columns %v", tbl.Cols())
+	}
+
+	err = execView(t, s, "CREATE VIEW v AS SELECT 2")
+	if errCode(err) != mysql.ErrTableExists {
+		t.Fatalf("expect error %d, got %v", mysql.ErrTableExists, err)
+	}
+	if err = execView(t, s, "CREATE OR REPLACE VIEW v (x, y, z) AS SELECT 1, 2, 3"); err != nil {
+		t.Fatal(err)
+	}
+	if tbl, _ = is.TableByName(model.NewCIStr("test"), model.NewCIStr("v")); tbl.Meta().Columns[2].Name.O != "z" {
+		t.Fatalf("expect the view to be replaced, got %v", tbl.Meta().Columns)
+	}
+	err = execView(t, s, "CREATE VIEW w (x) AS SELECT 1, 2")
+	if errCode(err) != mysql.ErrViewWrongList {
+		t.Fatalf("expect error %d, got %v", mysql.ErrViewWrongList, err)
+	}
+	err = execView(t, s, "CREATE VIEW w AS SELECT 1 AS a, 2 AS a")
+	if errCode(err) != mysql.ErrDupFieldName {
+		t.Fatalf("expect error %d, got %v", mysql.ErrDupFieldName, err)
+	}
+	err = execView(t, s, "CREATE VIEW w AS SELECT * FROM missing")
+	if errCode(err) != mysql.ErrNoSuchTable {
+		t.Fatalf("expect error %d, got %v", mysql.ErrNoSuchTable, err)
+	}
+}
+
+func TestCreateViewOverTable(t *testing.T) {
+	is := newViewTestSchema(newFKTestTable("t", "id"))
+	s := newViewTestSession(t, is)
+	err := execView(t, s, "CREATE VIEW t AS SELECT 1")
+	if errCode(err) != mysql.ErrTableExists {
+		t.Fatalf("expect error %d, got %v", mysql.ErrTableExists, err)
+	}
+	err = execView(t, s, "CREATE OR REPLACE VIEW t AS SELECT 1")
+	if errCode(err) != mysql.ErrWrongObject {
+		t.Fatalf("expect error %d, got %v", mysql.ErrWrongObject, err)
+	}
+}
+
+func TestDropView(t *testing.T) {
+	is := newViewTestSchema(newFKTestTable("t", "id"))
+	s := newViewTestSession(t, is)
+	if err := execView(t, s, "CREATE VIEW v AS SELECT 1"); err != nil {
+		t.Fatal(err)
+	}
+
+	err := execView(t, s, "DROP VIEW t")
+	if errCode(err) != mysql.ErrWrongObject {
+		t.Fatalf("expect error %d, got %v", mysql.ErrWrongObject, err)
+	}
+	err = execView(t, s, "DROP VIEW v, missing")
+	if errCode(err) != mysql.ErrBadTable {
+		t.Fatalf("expect error %d, got %v", mysql.ErrBadTable, err)
+	}
+	if _, ok := is.tables["v"]; ok {
+		t.Fatal("expect the existing view to be dropped")
+	}
+	if err = execView(t, s, "DROP VIEW IF EXISTS v"); err != nil {
+		t.Fatal(err)
+	}
+	if s.sessionVars.StmtCtx.WarningCount() != 1 {
+		t.Fatalf("expect 1 warning, got %d", s.sessionVars.StmtCtx.WarningCount())
+	}
+}
+
+func TestSelectFromView(t *testing.T) {
+	is := newViewTestSchema()
+	s := newViewTestSession(t, is)
+	if err := execView(t, s, "CREATE VIEW v (a, b) AS SELECT 1, 'x'"); err != nil {
+		t.Fatal(err)
+	}
+	_, p, err := compileView(s, "SELECT b, a FROM v WHERE a = 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cols := p.Schema().Columns
+	if len(cols) != 2 || cols[0].ColName.L != "b" || cols[1].ColName.L != "a" {
+		t.Fatalf("unexpected columns %v", cols)
+	}
+}
+
+func TestSelectFromInvalidView(t *testing.T) {
+	is := newViewTestSchema(newFKTestTable("t", "id"))
+	s := newViewTestSession(t, is)
+	if err := execView(t, s, "CREATE VIEW v AS SELECT id FROM t"); err != nil {
+		t.Fatal(err)
+	}
+	delete(is.tables, "t")
+	_, _, err := compileView(s, "SELECT * FROM v")
+	if errCode(err) != mysql.ErrViewInvalid {
+		t.Fatalf("expect error %d, got %v", mysql.ErrViewInvalid, err)
+	}
+}
+
+func TestWriteThroughView(t *testing.T) {
+	is := newViewTestSchema()
+	s := newViewTestSession(t, is)
+	if err := execView(t, s, "CREATE VIEW v (a) AS SELECT 1"); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		sql  string
+		code uint16
+	}{
+		{"INSERT INTO v VALUES (2)", mysql.ErrNonInsertableTable},
+		{"UPDATE v SET a = 2", mysql.ErrNonUpdatableTable},
+		{"DELETE FROM v WHERE a = 1", mysql.ErrNonUpdatableTable},
+	} {
+		_, _, err := compileView(s, tt.sql)
+		if errCode(err) != tt.code {
+			t.Fatalf("%s: expect error %d, got %v", tt.sql, tt.code, err)
+		}
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/expression"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/distinct"
 	"strings"
 )
 
@@ -100,11 +101,20 @@ func NewAggFunction(funcType string, funcArgs []expression.Expression, distinct
 
 // AggEvaluateContext is used to storebytes intermediate result when calculating aggregate functions.
 type AggEvaluateContext struct {
-	DistinctChecker *distinctChecker
+	DistinctChecker *distinct.Checker
 	Count           int64
 	Value           types.Datum
 	Buffer          *bytes.Buffer // Buffer is used for group_concat.
 	GotFirstRow     bool          // It will check if the agg has met the first row key.
+	// Truncated tells the result of group_concat was cut at
+	// group_concat_max_len.
+	Truncated bool
+
+	// sc is the statement the rows are aggregated for.
+	sc *variable.StatementContext
+	// concatItems are the values of a group_concat with an ORDER BY, sorted
+	// once the group is complete.
+	concatItems []concatItem
 }
 
 // AggFunctionMode stands for the aggregation function's mode.
@@ -209,7 +219,7 @@ func (af *aggFunction) SetArgs(args []expression.Expression) {
 func (af *aggFunction) CreateContext() *AggEvaluateContext {
 	ctx := &AggEvaluateContext{}
 	if af.Distinct {
-		ctx.DistinctChecker = createDistinctChecker()
+		ctx.DistinctChecker = distinct.CreateDistinctChecker()
 	}
 	return ctx
 }
@@ -224,7 +234,7 @@ func (af *aggFunction) updateSum(ctx *AggEvaluateContext, sc *variable.Statement
 		return nil
 	}
 	if af.Distinct {
-		d, err1 := ctx.DistinctChecker.Check([]types.Datum{value})
+		d, err1 := ctx.DistinctChecker.CheckDatums([]types.Datum{value})
 		if err1 != nil {
 			return errors.Trace(err1)
 		}
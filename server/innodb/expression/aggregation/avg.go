@@ -52,7 +52,7 @@ func (af *avgFunction) updateAvg(ctx *AggEvaluateContext, sc *variable.Statement
 		return nil
 	}
 	if af.Distinct {
-		d, err1 := ctx.DistinctChecker.Check([]types.Datum{value})
+		d, err1 := ctx.DistinctChecker.CheckDatums([]types.Datum{value})
 		if err1 != nil {
 			return errors.Trace(err1)
 		}
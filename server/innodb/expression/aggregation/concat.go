@@ -15,83 +15,163 @@ package aggregation
 
 import (
 	"bytes"
-	"fmt"
+	"sort"
+	"unicode/utf8"
+
 	types "github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
 
 	"github.com/juju/errors"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/expression"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/terror"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/charset"
 	"github.com/zhukovaskychina/xmysql-server/server/mysql"
 )
 
+// concatFunction is GROUP_CONCAT. Its arguments are the values joined, then
+// the items of its ORDER BY, then the separator.
 type concatFunction struct {
 	aggFunction
-	separator string
-	sepInited bool
+	// desc tells the direction of each item of the ORDER BY.
+	desc []bool
+}
+
+// concatItem is a value of a GROUP_CONCAT with an ORDER BY, and the values
+// of the items it is sorted by.
+type concatItem struct {
+	value []byte
+	keys  []types.Datum
+}
+
+// NewGroupConcat creates a GROUP_CONCAT of args, the last of which is the
+// separator, sorting the values of a group by byItems, in descending order
+// where desc tells so, before joining them.
+func NewGroupConcat(args []expression.Expression, distinct bool, byItems []expression.Expression, desc []bool) Aggregation {
+	values, sep := args[:len(args)-1], args[len(args)-1]
+	funcArgs := make([]expression.Expression, 0, len(args)+len(byItems))
+	funcArgs = append(append(append(funcArgs, values...), byItems...), sep)
+	return &concatFunction{aggFunction: newAggFunc(ast.AggFuncGroupConcat, funcArgs, distinct), desc: desc}
+}
+
+// values returns the arguments whose values are joined.
+func (cf *concatFunction) values() []expression.Expression {
+	return cf.Args[:len(cf.Args)-len(cf.desc)-1]
+}
+
+// byItems returns the items of the ORDER BY.
+func (cf *concatFunction) byItems() []expression.Expression {
+	return cf.Args[len(cf.Args)-len(cf.desc)-1 : len(cf.Args)-1]
 }
 
 // Clone implements Aggregation interface.
 func (cf *concatFunction) Clone() Aggregation {
 	nf := *cf
+	nf.Args = make([]expression.Expression, len(cf.Args))
 	for i, arg := range cf.Args {
 		nf.Args[i] = arg.Clone()
 	}
 	return &nf
 }
 
-// GetType implements Aggregation interface.
+// Equal implements Aggregation interface.
+func (cf *concatFunction) Equal(b Aggregation, ctx context.Context) bool {
+	other, ok := b.(*concatFunction)
+	if !ok || len(cf.desc) != len(other.desc) {
+		return false
+	}
+	for i := range cf.desc {
+		if cf.desc[i] != other.desc[i] {
+			return false
+		}
+	}
+	return cf.aggFunction.Equal(b, ctx)
+}
+
+// String implements fmt.Stringer interface.
+func (cf *concatFunction) String() string {
+	buffer := bytes.NewBufferString(cf.name + "(")
+	for i, arg := range cf.values() {
+		if i > 0 {
+			buffer.WriteString(", ")
+		}
+		buffer.WriteString(arg.String())
+	}
+	for i, item := range cf.byItems() {
+		if i == 0 {
+			buffer.WriteString(" order by ")
+		} else {
+			buffer.WriteString(", ")
+		}
+		buffer.WriteString(item.String())
+		if cf.desc[i] {
+			buffer.WriteString(" desc")
+		}
+	}
+	buffer.WriteString(" separator " + cf.Args[len(cf.Args)-1].String() + ")")
+	return buffer.String()
+}
+
+// GetType implements Aggregation interface. The result is a BLOB when one of
+// the values is a binary string, a TEXT otherwise.
 func (cf *concatFunction) GetType() *types.FieldType {
-	retType := types.NewFieldType(mysql.TypeVarString)
+	retType := types.NewFieldType(mysql.TypeBlob)
 	retType.Charset = charset.CharsetUTF8
 	retType.Collate = charset.CollationUTF8
+	for _, arg := range cf.values() {
+		if types.IsBinaryStr(arg.GetType()) {
+			retType.Charset, retType.Collate = charset.CharsetBin, charset.CollationBin
+			retType.Flag |= mysql.BinaryFlag
+			break
+		}
+	}
 	retType.Flen, retType.Decimal = mysql.MaxBlobWidth, 0
 	return retType
 }
 
-func (cf *concatFunction) writeValue(ctx *AggEvaluateContext, val types.Datum) {
+func (cf *concatFunction) writeValue(buffer *bytes.Buffer, val types.Datum) error {
 	if val.Kind() == types.KindBytes {
-		ctx.Buffer.Write(val.GetBytes())
-	} else {
-		ctx.Buffer.WriteString(fmt.Sprintf("%v", val.GetValue()))
+		buffer.Write(val.GetBytes())
+		return nil
+	}
+	str, err := val.ToString()
+	if err != nil {
+		return errors.Trace(err)
 	}
+	buffer.WriteString(str)
+	return nil
 }
 
-func (cf *concatFunction) initSeparator(sc *variable.StatementContext, row []types.Datum) error {
-	sepArg := cf.Args[len(cf.Args)-1]
-	sep, isNull, err := sepArg.EvalString(row, sc)
+func (cf *concatFunction) separator(sc *variable.StatementContext) (string, error) {
+	sep, isNull, err := cf.Args[len(cf.Args)-1].EvalString(nil, sc)
 	if err != nil {
-		return errors.Trace(err)
+		return "", errors.Trace(err)
 	}
 	if isNull {
-		return errors.Errorf("Invalid separator argument.")
+		return "", errors.Errorf("Invalid separator argument.")
 	}
-	cf.separator = sep
-	cf.Args = cf.Args[:len(cf.Args)-1]
-	return nil
+	return sep, nil
 }
 
-// Update implements Aggregation interface.
+// Update implements Aggregation interface. The values of a group without an
+// ORDER BY are joined as they come, the others kept with their keys until
+// the group is complete.
 func (cf *concatFunction) Update(ctx *AggEvaluateContext, sc *variable.StatementContext, row []types.Datum) error {
-	datumBuf := make([]types.Datum, 0, len(cf.Args))
-	if !cf.sepInited {
-		err := cf.initSeparator(sc, row)
-		if err != nil {
-			return errors.Trace(err)
-		}
-		cf.sepInited = true
-	}
-	for _, a := range cf.Args {
+	values := cf.values()
+	datumBuf := make([]types.Datum, 0, len(values))
+	for _, a := range values {
 		value, err := a.Eval(row)
 		if err != nil {
 			return errors.Trace(err)
 		}
-		if value.GetValue() == nil {
+		if value.IsNull() {
 			return nil
 		}
 		datumBuf = append(datumBuf, value)
 	}
 	if cf.Distinct {
-		d, err := ctx.DistinctChecker.Check(datumBuf)
+		d, err := ctx.DistinctChecker.CheckDatums(datumBuf)
 		if err != nil {
 			return errors.Trace(err)
 		}
@@ -99,24 +179,121 @@ func (cf *concatFunction) Update(ctx *AggEvaluateContext, sc *variable.Statement
 			return nil
 		}
 	}
-	if ctx.Buffer == nil {
-		ctx.Buffer = &bytes.Buffer{}
-	} else {
-		ctx.Buffer.WriteString(cf.separator)
+	ctx.sc = sc
+	// The values after the cut of a result without ORDER BY are dropped.
+	if len(cf.desc) == 0 && ctx.Truncated {
+		return nil
+	}
+	value := &bytes.Buffer{}
+	if len(cf.desc) == 0 {
+		if ctx.Buffer == nil {
+			ctx.Buffer = value
+		} else {
+			sep, err := cf.separator(sc)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			value = ctx.Buffer
+			value.WriteString(sep)
+		}
 	}
 	for _, val := range datumBuf {
-		cf.writeValue(ctx, val)
+		if err := cf.writeValue(value, val); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	if len(cf.desc) == 0 {
+		cf.truncate(ctx)
+		return nil
+	}
+	keys := make([]types.Datum, 0, len(cf.desc))
+	for _, item := range cf.byItems() {
+		key, err := item.Eval(row)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		keys = append(keys, key)
 	}
-	// TODO: if total length is greater than global var group_concat_max_len, truncate it.
+	ctx.concatItems = append(ctx.concatItems, concatItem{value: value.Bytes(), keys: keys})
+	return nil
+}
+
+// truncate cuts the result of the group of ctx at group_concat_max_len
+// bytes, never in the middle of a character of a non-binary result.
+func (cf *concatFunction) truncate(ctx *AggEvaluateContext) {
+	maxLen := int(ctx.sc.GroupConcatMaxLen)
+	if maxLen <= 0 || ctx.Buffer.Len() <= maxLen {
+		return
+	}
+	b := ctx.Buffer.Bytes()[:maxLen]
+	if !types.IsBinaryStr(cf.GetType()) {
+		for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+			if utf8.RuneStart(b[i]) {
+				if !utf8.FullRune(b[i:]) {
+					b = b[:i]
+				}
+				break
+			}
+		}
+	}
+	ctx.Buffer.Truncate(len(b))
+	ctx.Truncated = true
+}
+
+// join joins the values kept for the ORDER BY in its order.
+func (cf *concatFunction) join(ctx *AggEvaluateContext) error {
+	var err error
+	sort.SliceStable(ctx.concatItems, func(i, j int) bool {
+		a, b := ctx.concatItems[i].keys, ctx.concatItems[j].keys
+		for k := range a {
+			cmp, err1 := a[k].CompareDatum(ctx.sc, &b[k])
+			if err1 != nil {
+				err = err1
+				return false
+			}
+			if cmp != 0 {
+				return cmp < 0 != cf.desc[k]
+			}
+		}
+		return false
+	})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	sep, err := cf.separator(ctx.sc)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	ctx.Buffer = &bytes.Buffer{}
+	for i, item := range ctx.concatItems {
+		if i > 0 {
+			ctx.Buffer.WriteString(sep)
+		}
+		ctx.Buffer.Write(item.value)
+		if ctx.sc.GroupConcatMaxLen > 0 && uint64(ctx.Buffer.Len()) > ctx.sc.GroupConcatMaxLen {
+			break
+		}
+	}
+	ctx.concatItems = nil
+	cf.truncate(ctx)
 	return nil
 }
 
 // GetResult implements Aggregation interface.
 func (cf *concatFunction) GetResult(ctx *AggEvaluateContext) (d types.Datum) {
-	if ctx.Buffer != nil {
-		d.SetString(ctx.Buffer.String())
-	} else {
+	if len(ctx.concatItems) > 0 {
+		if err := cf.join(ctx); err != nil {
+			terror.Log(errors.Trace(err))
+		}
+	}
+	if ctx.Buffer == nil {
 		d.SetNull()
+		return d
+	}
+	if types.IsBinaryStr(cf.GetType()) {
+		d.SetBytes(ctx.Buffer.Bytes())
+	} else {
+		d.SetString(ctx.Buffer.String())
 	}
 	return d
 }
@@ -85,7 +85,7 @@ func (cf *countFunction) Update(ctx *AggEvaluateContext, sc *variable.StatementC
 		}
 	}
 	if cf.Distinct {
-		d, err := ctx.DistinctChecker.Check(datumBuf)
+		d, err := ctx.DistinctChecker.CheckDatums(datumBuf)
 		if err != nil {
 			return errors.Trace(err)
 		}
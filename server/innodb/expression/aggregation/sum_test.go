@@ -0,0 +1,67 @@
+package aggregation
+
+import (
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	types "github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/expression"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/mock"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+func TestSumCaseWhenPivot(t *testing.T) {
+	// SELECT SUM(CASE WHEN status = 'paid' THEN 1 ELSE 0 END),
+	//        SUM(CASE status WHEN 'open' THEN amount END) FROM orders
+	ctx := mock.NewContext()
+	sc := ctx.GetSessionVars().StmtCtx
+	status := &expression.Column{Index: 0, RetType: types.NewFieldType(mysql.TypeVarchar)}
+	amount := &expression.Column{Index: 1, RetType: types.NewFieldType(mysql.TypeLonglong)}
+	str := func(s string) expression.Expression {
+		return &expression.Constant{Value: types.NewStringDatum(s), RetType: types.NewFieldType(mysql.TypeVarString)}
+	}
+	num := func(i int64) expression.Expression {
+		return &expression.Constant{Value: types.NewIntDatum(i), RetType: types.NewFieldType(mysql.TypeLonglong)}
+	}
+	newCase := func(cond string, then, els expression.Expression) expression.Expression {
+		eq, err := expression.NewFunction(ctx, ast.EQ, types.NewFieldType(mysql.TypeLonglong), status, str(cond))
+		if err != nil {
+			t.Fatal(err)
+		}
+		args := []expression.Expression{eq, then}
+		if els != nil {
+			args = append(args, els)
+		}
+		f, err := expression.NewFunction(ctx, ast.Case, types.NewFieldType(mysql.TypeUnspecified), args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+
+	paid := NewAggFunction(ast.AggFuncSum, []expression.Expression{newCase("paid", num(1), num(0))}, false)
+	open := NewAggFunction(ast.AggFuncSum, []expression.Expression{newCase("open", amount, nil)}, false)
+	paidCtx, openCtx := paid.CreateContext(), open.CreateContext()
+	rows := [][]types.Datum{
+		types.MakeDatums("paid", 10),
+		types.MakeDatums("open", 20),
+		types.MakeDatums("paid", 30),
+		types.MakeDatums(nil, 40),
+		types.MakeDatums("open", 5),
+	}
+	for _, row := range rows {
+		if err := paid.Update(paidCtx, sc, row); err != nil {
+			t.Fatal(err)
+		}
+		if err := open.Update(openCtx, sc, row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	paidSum, openSum := paid.GetResult(paidCtx), open.GetResult(openCtx)
+	if got, _ := paidSum.ToString(); got != "2" {
+		t.Errorf("paid count: got %s, want 2", got)
+	}
+	if got, _ := openSum.ToString(); got != "25" {
+		t.Errorf("open amount: got %s, want 25", got)
+	}
+}
@@ -3,40 +3,10 @@ package aggregation
 import (
 	"github.com/juju/errors"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
-	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/codec"
 
 	types "github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
 )
 
-// distinctChecker stores existing keys and checks if given data is distinct.
-type distinctChecker struct {
-	//existingKeys *mvmap.MVMap
-	buf []byte
-}
-
-// createDistinctChecker creates a new distinct checker.
-func createDistinctChecker() *distinctChecker {
-	return &distinctChecker{
-		//	existingKeys: mvmap.NewMVMap(),
-	}
-}
-
-// Check checks if values is distinct.
-func (d *distinctChecker) Check(values []types.Datum) (bool, error) {
-	d.buf = d.buf[:0]
-	var err error
-	d.buf, err = codec.EncodeValue(d.buf, values...)
-	if err != nil {
-		return false, errors.Trace(err)
-	}
-	//v := d.existingKeys.Get(d.buf)
-	//if v != nil {
-	//	return false, nil
-	//}
-	//d.existingKeys.Put(d.buf, []byte{})
-	return true, nil
-}
-
 // calculateSum adds v to sum.
 func calculateSum(sc *variable.StatementContext, sum, v types.Datum) (data types.Datum, err error) {
 	// for avg and sum calculation
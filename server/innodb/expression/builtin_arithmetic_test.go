@@ -0,0 +1,148 @@
+package expression
+
+import (
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	types "github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/terror"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/mock"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+func TestArithmeticPromotion(t *testing.T) {
+	tests := []struct {
+		funcName string
+		args     []Expression
+		tp       types.EvalType
+		expect   string
+	}{
+		// int op int stays integer, decimal wins over int, double wins over all.
+		{ast.Plus, []Expression{intCon(1), intCon(2)}, types.ETInt, "3"},
+		{ast.Plus, []Expression{intCon(1), decCon("1.5")}, types.ETDecimal, "2.5"},
+		{ast.Mul, []Expression{decCon("1.5"), realCon(2)}, types.ETReal, "3"},
+		{ast.Minus, []Expression{intCon(0), intCon(1)}, types.ETInt, "-1"},
+
+		// An int added to a decimal keeps the decimal's scale, and a BIGINT
+		// UNSIGNED past its maximum is carried by the decimal.
+		{ast.Plus, []Expression{intCon(1), decCon("1.50")}, types.ETDecimal, "2.50"},
+		{ast.Minus, []Expression{decCon("1.50"), intCon(1)}, types.ETDecimal, "0.50"},
+		{ast.Mul, []Expression{intCon(3), decCon("1.50")}, types.ETDecimal, "4.50"},
+		{ast.Plus, []Expression{uintCon(18446744073709551615), decCon("1")}, types.ETDecimal, "18446744073709551616"},
+
+		// Division always yields a decimal with div_precision_increment (4)
+		// extra digits of scale, unless an operand is a double.
+		{ast.Div, []Expression{intCon(1), intCon(3)}, types.ETDecimal, "0.3333"},
+		{ast.Div, []Expression{decCon("1.00"), intCon(3)}, types.ETDecimal, "0.333333"},
+		{ast.Div, []Expression{intCon(6), intCon(3)}, types.ETDecimal, "2.0000"},
+		{ast.Div, []Expression{realCon(1), intCon(4)}, types.ETReal, "0.25"},
+
+		// DIV truncates towards zero.
+		{ast.IntDiv, []Expression{intCon(7), intCon(2)}, types.ETInt, "3"},
+		{ast.IntDiv, []Expression{intCon(-7), intCon(2)}, types.ETInt, "-3"},
+		{ast.IntDiv, []Expression{decCon("7.5"), decCon("2.5")}, types.ETInt, "3"},
+	}
+	for i, tt := range tests {
+		f, err := NewFunction(mock.NewContext(), tt.funcName, types.NewFieldType(mysql.TypeUnspecified), tt.args...)
+		if err != nil {
+			t.Fatalf("#%d: %v", i, err)
+		}
+		if got := f.GetType().EvalType(); got != tt.tp {
+			t.Errorf("#%d %s: got eval type %v, want %v", i, tt.funcName, got, tt.tp)
+		}
+		if got := evalFunc(t, mock.NewContext(), tt.funcName, tt.args...); got != tt.expect {
+			t.Errorf("#%d %s: got %s, want %s", i, tt.funcName, got, tt.expect)
+		}
+	}
+}
+
+func TestNumericComparePromotion(t *testing.T) {
+	tests := []struct {
+		funcName string
+		args     []Expression
+		expect   string
+	}{
+		// int and decimal compare as decimals.
+		{ast.EQ, []Expression{intCon(2), decCon("2.00")}, "1"},
+		{ast.EQ, []Expression{intCon(1), decCon("1.000000001")}, "0"},
+		{ast.LT, []Expression{intCon(1), decCon("1.5")}, "1"},
+
+		// int and double compare as doubles, so integers past 2^53 equal the
+		// nearest double like in MySQL.
+		{ast.EQ, []Expression{intCon(1), realCon(1)}, "1"},
+		{ast.GT, []Expression{intCon(2), realCon(1.5)}, "1"},
+		{ast.EQ, []Expression{intCon(9007199254740993), realCon(9007199254740992)}, "1"},
+		{ast.EQ, []Expression{intCon(-1), realCon(-1)}, "1"},
+		{ast.NullEQ, []Expression{intCon(0), realCon(0)}, "1"},
+
+		// Signed and unsigned ints compare by value.
+		{ast.EQ, []Expression{uintCon(18446744073709551615), intCon(-1)}, "0"},
+		{ast.LT, []Expression{intCon(-1), uintCon(1)}, "1"},
+	}
+	for i, tt := range tests {
+		if got := evalFunc(t, mock.NewContext(), tt.funcName, tt.args...); got != tt.expect {
+			t.Errorf("#%d %s%v: got %s, want %s", i, tt.funcName, tt.args, got, tt.expect)
+		}
+	}
+}
+
+func TestArithmeticOverflow(t *testing.T) {
+	tests := []struct {
+		funcName string
+		args     []Expression
+	}{
+		{ast.Plus, []Expression{intCon(9223372036854775807), intCon(1)}},
+		{ast.Mul, []Expression{intCon(9223372036854775807), intCon(2)}},
+		{ast.Minus, []Expression{uintCon(0), intCon(1)}},
+	}
+	for i, tt := range tests {
+		f, err := NewFunction(mock.NewContext(), tt.funcName, types.NewFieldType(mysql.TypeUnspecified), tt.args...)
+		if err != nil {
+			t.Fatalf("#%d: %v", i, err)
+		}
+		_, err = f.Eval(nil)
+		if !terror.ErrorEqual(err, types.ErrOverflow) {
+			t.Errorf("#%d %s: expected out of range error, got %v", i, tt.funcName, err)
+		}
+	}
+
+	// NO_UNSIGNED_SUBTRACTION makes the result signed instead.
+	ctx := mock.NewContext()
+	ctx.GetSessionVars().SQLMode |= mysql.ModeNoUnsignedSubtraction
+	if got := evalFunc(t, ctx, ast.Minus, uintCon(0), intCon(1)); got != "-1" {
+		t.Errorf("got %s, want -1", got)
+	}
+}
+
+func TestDivisionByZero(t *testing.T) {
+	for _, funcName := range []string{ast.Div, ast.IntDiv, ast.Mod} {
+		// SELECT: NULL and a warning.
+		ctx := mock.NewContext()
+		ctx.GetSessionVars().StmtCtx.InSelectStmt = true
+		if got := evalFunc(t, ctx, funcName, intCon(1), intCon(0)); got != "<nil>" {
+			t.Errorf("%s: got %s, want NULL", funcName, got)
+		}
+		if ctx.GetSessionVars().StmtCtx.WarningCount() != 1 {
+			t.Errorf("%s: expected a division by zero warning", funcName)
+		}
+
+		// INSERT under STRICT_TRANS_TABLES,ERROR_FOR_DIVISION_BY_ZERO: an error.
+		ctx = mock.NewContext()
+		ctx.GetSessionVars().SQLMode |= mysql.ModeErrorForDivisionByZero
+		ctx.GetSessionVars().StmtCtx.InInsertStmt = true
+		f, err := NewFunction(ctx, funcName, types.NewFieldType(mysql.TypeUnspecified), intCon(1), intCon(0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = f.Eval(nil); !terror.ErrorEqual(err, ErrDivisionByZero) {
+			t.Errorf("%s: expected division by zero error in strict mode, got %v", funcName, err)
+		}
+
+		// The same INSERT without a strict mode only warns.
+		ctx.GetSessionVars().StrictSQLMode = false
+		ctx.GetSessionVars().StmtCtx.DividedByZeroAsWarning = true
+		if got := evalFunc(t, ctx, funcName, intCon(1), intCon(0)); got != "<nil>" {
+			t.Errorf("%s: got %s, want NULL", funcName, got)
+		}
+	}
+}
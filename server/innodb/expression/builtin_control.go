@@ -16,6 +16,7 @@ package expression
 import (
 	"github.com/cznic/mathutil"
 	"github.com/juju/errors"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
 	types "github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic/json"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
@@ -56,6 +57,17 @@ type caseWhenFunctionClass struct {
 	baseFunctionClass
 }
 
+// wrapWithIsTrue wraps a non-integer condition with IS TRUE, so that it is
+// tested the way MySQL does: any non-zero number is true. Casting it to an
+// integer instead would round 0.4 down to false.
+func wrapWithIsTrue(ctx context.Context, arg Expression) (Expression, error) {
+	if arg.GetType().EvalType() == types.ETInt {
+		return arg, nil
+	}
+	expr, err := NewFunction(ctx, ast.IsTruth, types.NewFieldType(mysql.TypeLonglong), arg)
+	return expr, errors.Trace(err)
+}
+
 // Infer result type for builtin IF, IFNULL && NULLIF.
 func inferType4ControlFuncs(lhs, rhs *types.FieldType) *types.FieldType {
 	resultFieldType := &types.FieldType{}
@@ -136,6 +148,11 @@ func (c *caseWhenFunctionClass) getFunction(ctx context.Context, args []Expressi
 		return nil, errors.Trace(err)
 	}
 	l := len(args)
+	for i := 0; i < l-1; i += 2 {
+		if args[i], err = wrapWithIsTrue(ctx, args[i]); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
 	// Fill in each 'THEN' clause parameter type.
 	fieldTps := make([]*types.FieldType, 0, (l+1)/2)
 	decimal, flen, isBinaryStr := args[1].GetType().Decimal, 0, false
@@ -397,6 +414,9 @@ func (c *ifFunctionClass) getFunction(ctx context.Context, args []Expression) (s
 	if err = c.verifyArgs(args); err != nil {
 		return nil, errors.Trace(err)
 	}
+	if args[0], err = wrapWithIsTrue(ctx, args[0]); err != nil {
+		return nil, errors.Trace(err)
+	}
 	retTp := inferType4ControlFuncs(args[1].GetType(), args[2].GetType())
 	evalTps := retTp.EvalType()
 	bf := newBaseBuiltinFuncWithTp(ctx, args, evalTps, types.ETInt, evalTps, evalTps)
@@ -0,0 +1,113 @@
+package expression
+
+import (
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	types "github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/mock"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+func TestControlFunctions(t *testing.T) {
+	tests := []struct {
+		funcName string
+		args     []Expression
+		expect   string
+	}{
+		// IF treats any non-zero number as true.
+		{ast.If, []Expression{intCon(1), strCon("a"), strCon("b")}, "a"},
+		{ast.If, []Expression{intCon(0), strCon("a"), strCon("b")}, "b"},
+		{ast.If, []Expression{realCon(0.3), intCon(1), intCon(2)}, "1"},
+		{ast.If, []Expression{strCon("0.5"), intCon(1), intCon(2)}, "1"},
+		{ast.If, []Expression{strCon("0"), intCon(1), intCon(2)}, "2"},
+		{ast.If, []Expression{nullCon(), intCon(1), intCon(2)}, "2"},
+		{ast.If, []Expression{intCon(1), intCon(1), realCon(2.5)}, "1"},
+
+		// Searched CASE: condition, result pairs and an optional ELSE.
+		{ast.Case, []Expression{intCon(0), strCon("a"), intCon(1), strCon("b"), strCon("c")}, "b"},
+		{ast.Case, []Expression{intCon(0), strCon("a"), nullCon(), strCon("b"), strCon("c")}, "c"},
+		{ast.Case, []Expression{intCon(0), strCon("a"), nullCon(), strCon("b")}, "<nil>"},
+		{ast.Case, []Expression{realCon(0.2), intCon(1), intCon(0)}, "1"},
+		{ast.Case, []Expression{intCon(0), intCon(1), realCon(2.5)}, "2.5"},
+		{ast.Case, []Expression{intCon(1), intCon(1), strCon("x")}, "1"},
+
+		{ast.Ifnull, []Expression{nullCon(), intCon(2)}, "2"},
+		{ast.Ifnull, []Expression{intCon(1), strCon("x")}, "1"},
+		{ast.Ifnull, []Expression{nullCon(), nullCon()}, "<nil>"},
+
+		{ast.Coalesce, []Expression{nullCon(), nullCon(), intCon(3)}, "3"},
+		{ast.Coalesce, []Expression{nullCon(), strCon("a"), intCon(3)}, "a"},
+		{ast.Coalesce, []Expression{nullCon(), nullCon()}, "<nil>"},
+
+		// GREATEST and LEAST are NULL as soon as any argument is.
+		{ast.Greatest, []Expression{intCon(1), intCon(5), intCon(3)}, "5"},
+		{ast.Greatest, []Expression{intCon(1), nullCon(), intCon(3)}, "<nil>"},
+		{ast.Greatest, []Expression{strCon("10"), intCon(9)}, "10"},
+		{ast.Least, []Expression{strCon("b"), strCon("a")}, "a"},
+		{ast.Least, []Expression{intCon(1), realCon(0.5)}, "0.5"},
+		{ast.Least, []Expression{intCon(1), nullCon()}, "<nil>"},
+	}
+	for i, tt := range tests {
+		if got := evalFunc(t, mock.NewContext(), tt.funcName, tt.args...); got != tt.expect {
+			t.Errorf("#%d %s: got %s, want %s", i, tt.funcName, got, tt.expect)
+		}
+	}
+}
+
+func TestCaseWhenResultType(t *testing.T) {
+	tests := []struct {
+		args []Expression
+		tp   types.EvalType
+	}{
+		{[]Expression{intCon(1), intCon(1), intCon(0)}, types.ETInt},
+		{[]Expression{intCon(1), intCon(1), realCon(0.5)}, types.ETReal},
+		{[]Expression{intCon(1), intCon(1), strCon("x")}, types.ETString},
+		{[]Expression{intCon(1), nullCon(), intCon(0)}, types.ETInt},
+	}
+	for i, tt := range tests {
+		f, err := NewFunction(mock.NewContext(), ast.Case, types.NewFieldType(mysql.TypeUnspecified), tt.args...)
+		if err != nil {
+			t.Fatalf("#%d: %v", i, err)
+		}
+		if got := f.GetType().EvalType(); got != tt.tp {
+			t.Errorf("#%d: got eval type %v, want %v", i, got, tt.tp)
+		}
+	}
+}
+
+func TestCaseWhenOverColumns(t *testing.T) {
+	// CASE WHEN a > 1 THEN b ELSE 0 END over a few rows, the shape used by
+	// SUM(CASE WHEN ... THEN ... ELSE 0 END) pivots.
+	ctx := mock.NewContext()
+	a := &Column{Index: 0, RetType: types.NewFieldType(mysql.TypeLonglong)}
+	b := &Column{Index: 1, RetType: types.NewFieldType(mysql.TypeLonglong)}
+	cond, err := NewFunction(ctx, ast.GT, types.NewFieldType(mysql.TypeLonglong), a, intCon(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := NewFunction(ctx, ast.Case, types.NewFieldType(mysql.TypeUnspecified), cond, b, intCon(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := [][]types.Datum{
+		types.MakeDatums(1, 10),
+		types.MakeDatums(2, 20),
+		types.MakeDatums(nil, 30),
+		types.MakeDatums(3, nil),
+	}
+	expect := []string{"0", "20", "0", "<nil>"}
+	for i, row := range rows {
+		d, err := f.Eval(row)
+		if err != nil {
+			t.Fatalf("row %d: %v", i, err)
+		}
+		got := "<nil>"
+		if !d.IsNull() {
+			got, _ = d.ToString()
+		}
+		if got != expect[i] {
+			t.Errorf("row %d: got %s, want %s", i, got, expect[i])
+		}
+	}
+}
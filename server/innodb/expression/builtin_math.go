@@ -268,6 +268,25 @@ func (c *roundFunctionClass) getFunction(ctx context.Context, args []Expression)
 	return sig, nil
 }
 
+// roundReal rounds f to dec decimal places the way MySQL rounds approximate
+// values: halfway cases go to the nearest even digit, so ROUND(2.5E0) is 2
+// while ROUND(2.5) on the exact DECIMAL value is 3.
+func roundReal(f float64, dec int64) float64 {
+	if dec < 0 {
+		shift := math.Pow10(int(-dec))
+		if math.IsInf(shift, 0) {
+			return 0
+		}
+		return math.RoundToEven(f/shift) * shift
+	}
+	shift := math.Pow10(int(dec))
+	tmp := f * shift
+	if math.IsInf(tmp, 0) {
+		return f
+	}
+	return math.RoundToEven(tmp) / shift
+}
+
 type builtinRoundRealSig struct {
 	baseBuiltinFunc
 }
@@ -279,7 +298,7 @@ func (b *builtinRoundRealSig) evalReal(row []types.Datum) (float64, bool, error)
 	if isNull || err != nil {
 		return 0, isNull, errors.Trace(err)
 	}
-	return types.Round(val, 0), false, nil
+	return roundReal(val, 0), false, nil
 }
 
 type builtinRoundIntSig struct {
@@ -325,7 +344,7 @@ func (b *builtinRoundWithFracRealSig) evalReal(row []types.Datum) (float64, bool
 	if isNull || err != nil {
 		return 0, isNull, errors.Trace(err)
 	}
-	return types.Round(val, int(frac)), false, nil
+	return roundReal(val, frac), false, nil
 }
 
 type builtinRoundWithFracIntSig struct {
@@ -0,0 +1,61 @@
+package expression
+
+import (
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/mock"
+)
+
+// The expected values below are what MySQL 5.7 returns for the same calls.
+func TestNumericFunctions(t *testing.T) {
+	tests := []struct {
+		funcName string
+		args     []Expression
+		expect   string
+	}{
+		// Exact values round half away from zero, approximate values to even.
+		{ast.Round, []Expression{decCon("2.5")}, "3"},
+		{ast.Round, []Expression{decCon("-2.5")}, "-3"},
+		{ast.Round, []Expression{realCon(2.5)}, "2"},
+		{ast.Round, []Expression{realCon(-2.5)}, "-2"},
+		{ast.Round, []Expression{realCon(3.5)}, "4"},
+		{ast.Round, []Expression{decCon("1.235"), intCon(2)}, "1.24"},
+		{ast.Round, []Expression{decCon("1.2345"), intCon(2)}, "1.23"},
+		{ast.Round, []Expression{intCon(1250), intCon(-2)}, "1300"},
+		{ast.Round, []Expression{realCon(1250), intCon(-2)}, "1200"},
+		{ast.Round, []Expression{nullCon()}, "<nil>"},
+
+		{ast.Floor, []Expression{decCon("-1.5")}, "-2"},
+		{ast.Floor, []Expression{realCon(1.5)}, "1"},
+		{ast.Ceil, []Expression{decCon("1.2")}, "2"},
+		{ast.Ceiling, []Expression{realCon(-1.2)}, "-1"},
+
+		{ast.Abs, []Expression{intCon(-5)}, "5"},
+		{ast.Abs, []Expression{decCon("-5.25")}, "5.25"},
+
+		// MOD takes the sign of the dividend.
+		{ast.Mod, []Expression{intCon(10), intCon(3)}, "1"},
+		{ast.Mod, []Expression{intCon(-10), intCon(3)}, "-1"},
+		{ast.Mod, []Expression{decCon("10.5"), intCon(3)}, "1.5"},
+		{ast.Mod, []Expression{intCon(10), intCon(0)}, "<nil>"},
+
+		{ast.Pow, []Expression{intCon(2), intCon(10)}, "1024"},
+		{ast.Power, []Expression{intCon(2), intCon(-1)}, "0.5"},
+	}
+	for i, tt := range tests {
+		if got := evalFunc(t, mock.NewContext(), tt.funcName, tt.args...); got != tt.expect {
+			t.Errorf("#%d %s: got %s, want %s", i, tt.funcName, got, tt.expect)
+		}
+	}
+}
+
+func TestPowOutOfRange(t *testing.T) {
+	f, err := NewFunction(mock.NewContext(), ast.Pow, realCon(0).GetType(), intCon(10), intCon(400))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = f.Eval(nil); err == nil {
+		t.Fatal("POW(10, 400) should report DOUBLE value is out of range")
+	}
+}
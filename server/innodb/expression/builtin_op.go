@@ -342,7 +342,10 @@ func (c *isTrueOrFalseFunctionClass) getFunction(ctx context.Context, args []Exp
 	}
 
 	argTp := args[0].GetType().EvalType()
-	if argTp != types.ETReal && argTp != types.ETDecimal {
+	if argTp.IsStringKind() {
+		// Strings and temporal values are tested as numbers, so '0.5' IS TRUE holds.
+		argTp = types.ETReal
+	} else if argTp != types.ETReal && argTp != types.ETDecimal {
 		argTp = types.ETInt
 	}
 
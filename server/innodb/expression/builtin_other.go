@@ -411,6 +411,8 @@ func (b *builtinGetVarSig) evalString(row []types.Datum) (string, bool, error) {
 	return "", true, nil
 }
 
+// valuesFunctionClass is VALUES(col) in ON DUPLICATE KEY UPDATE, the value
+// the insert would have given col. Anywhere else it is NULL, as in MySQL 5.7.
 type valuesFunctionClass struct {
 	baseFunctionClass
 
@@ -454,7 +456,7 @@ type builtinValuesIntSig struct {
 func (b *builtinValuesIntSig) evalInt(_ []types.Datum) (int64, bool, error) {
 	values := b.ctx.GetSessionVars().CurrInsertValues
 	if values == nil {
-		return 0, true, errors.New("Session current insert values is nil")
+		return 0, true, nil
 	}
 	row := values.([]types.Datum)
 	if b.offset < len(row) {
@@ -474,7 +476,7 @@ type builtinValuesRealSig struct {
 func (b *builtinValuesRealSig) evalReal(_ []types.Datum) (float64, bool, error) {
 	values := b.ctx.GetSessionVars().CurrInsertValues
 	if values == nil {
-		return 0, true, errors.New("Session current insert values is nil")
+		return 0, true, nil
 	}
 	row := values.([]types.Datum)
 	if b.offset < len(row) {
@@ -494,7 +496,7 @@ type builtinValuesDecimalSig struct {
 func (b *builtinValuesDecimalSig) evalDecimal(_ []types.Datum) (*types.MyDecimal, bool, error) {
 	values := b.ctx.GetSessionVars().CurrInsertValues
 	if values == nil {
-		return nil, true, errors.New("Session current insert values is nil")
+		return nil, true, nil
 	}
 	row := values.([]types.Datum)
 	if b.offset < len(row) {
@@ -517,7 +519,7 @@ type builtinValuesStringSig struct {
 func (b *builtinValuesStringSig) evalString(_ []types.Datum) (string, bool, error) {
 	values := b.ctx.GetSessionVars().CurrInsertValues
 	if values == nil {
-		return "", true, errors.New("Session current insert values is nil")
+		return "", true, nil
 	}
 	row := values.([]types.Datum)
 	if b.offset < len(row) {
@@ -537,7 +539,7 @@ type builtinValuesTimeSig struct {
 func (b *builtinValuesTimeSig) evalTime(_ []types.Datum) (types.Time, bool, error) {
 	values := b.ctx.GetSessionVars().CurrInsertValues
 	if values == nil {
-		return types.Time{}, true, errors.New("Session current insert values is nil")
+		return types.Time{}, true, nil
 	}
 	row := values.([]types.Datum)
 	if b.offset < len(row) {
@@ -560,7 +562,7 @@ type builtinValuesDurationSig struct {
 func (b *builtinValuesDurationSig) evalDuration(_ []types.Datum) (types.Duration, bool, error) {
 	values := b.ctx.GetSessionVars().CurrInsertValues
 	if values == nil {
-		return types.Duration{}, true, errors.New("Session current insert values is nil")
+		return types.Duration{}, true, nil
 	}
 	row := values.([]types.Datum)
 	if b.offset < len(row) {
@@ -580,7 +582,7 @@ type builtinValuesJSONSig struct {
 func (b *builtinValuesJSONSig) evalJSON(_ []types.Datum) (json.JSON, bool, error) {
 	values := b.ctx.GetSessionVars().CurrInsertValues
 	if values == nil {
-		return json.JSON{}, true, errors.New("Session current insert values is nil")
+		return json.JSON{}, true, nil
 	}
 	row := values.([]types.Datum)
 	if b.offset < len(row) {
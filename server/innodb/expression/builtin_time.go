@@ -1401,7 +1401,7 @@ func evalFromUnixTime(ctx context.Context, fsp int, row []types.Datum, arg Expre
 		fsp = types.MaxFsp
 	}
 
-	tmp := time.Unix(integralPart, fractionalPart).In(sc.TimeZone)
+	tmp := time.Unix(integralPart, fractionalPart).In(getTimeZone(ctx))
 	t, err := convertTimeToMysqlTime(tmp, fsp)
 	if err != nil {
 		return res, true, errors.Trace(err)
@@ -1730,7 +1730,7 @@ type builtinCurrentDateSig struct {
 // See https://dev.mysql.com/doc/refman/5.7/en/date-and-time-functions.html#function_curdate
 func (b *builtinCurrentDateSig) evalTime(row []types.Datum) (d types.Time, isNull bool, err error) {
 	tz := b.ctx.GetSessionVars().GetTimeZone()
-	year, month, day := time.Now().In(tz).Date()
+	year, month, day := b.ctx.GetSessionVars().StmtCtx.GetNowTs().In(tz).Date()
 	result := types.Time{
 		Time: types.FromDate(year, int(month), day, 0, 0, 0, 0),
 		Type: mysql.TypeDate,
@@ -1779,7 +1779,7 @@ type builtinCurrentTime0ArgSig struct {
 
 func (b *builtinCurrentTime0ArgSig) evalDuration(row []types.Datum) (types.Duration, bool, error) {
 	tz := b.ctx.GetSessionVars().GetTimeZone()
-	dur := time.Now().In(tz).Format(types.TimeFormat)
+	dur := b.ctx.GetSessionVars().StmtCtx.GetNowTs().In(tz).Format(types.TimeFormat)
 	res, err := types.ParseDuration(dur, types.MinFsp)
 	if err != nil {
 		return types.Duration{}, true, errors.Trace(err)
@@ -1798,7 +1798,7 @@ func (b *builtinCurrentTime1ArgSig) evalDuration(row []types.Datum) (types.Durat
 		return types.Duration{}, true, errors.Trace(err)
 	}
 	tz := b.ctx.GetSessionVars().GetTimeZone()
-	dur := time.Now().In(tz).Format(types.TimeFSPFormat)
+	dur := b.ctx.GetSessionVars().StmtCtx.GetNowTs().In(tz).Format(types.TimeFSPFormat)
 	res, err := types.ParseDuration(dur, int(fsp))
 	if err != nil {
 		return types.Duration{}, true, errors.Trace(err)
@@ -2273,7 +2273,7 @@ func (du *baseDateArithmitical) add(ctx context.Context, date types.Time, interv
 	}
 
 	goTime = goTime.Add(dur)
-	goTime = goTime.AddDate(int(year), int(month), int(day))
+	goTime = addDate(goTime, year, month, day)
 
 	if goTime.Nanosecond() == 0 {
 		date.Fsp = 0
@@ -2296,7 +2296,7 @@ func (du *baseDateArithmitical) sub(ctx context.Context, date types.Time, interv
 	}
 
 	goTime = goTime.Add(dur)
-	goTime = goTime.AddDate(int(year), int(month), int(day))
+	goTime = addDate(goTime, year, month, day)
 
 	if goTime.Nanosecond() == 0 {
 		date.Fsp = 0
@@ -2306,6 +2306,26 @@ func (du *baseDateArithmitical) sub(ctx context.Context, date types.Time, interv
 	return date, false, nil
 }
 
+// addDate adds the year, month and day parts of an interval to t.
+// Unlike time.AddDate, a day that does not exist in the target month is
+// clamped to the last day of that month instead of spilling into the next
+// one, so '2025-01-31' + INTERVAL 1 MONTH is '2025-02-28' as in MySQL.
+func addDate(t time.Time, year, month, day int64) time.Time {
+	if year != 0 || month != 0 {
+		months := int64(t.Year())*12 + int64(t.Month()-1) + year*12 + month
+		y, m := int(months/12), time.Month(months%12+1)
+		if months < 0 && months%12 != 0 {
+			y, m = y-1, time.Month(months%12+13)
+		}
+		d := t.Day()
+		if last := time.Date(y, m+1, 0, 0, 0, 0, 0, time.UTC).Day(); d > last {
+			d = last
+		}
+		t = time.Date(y, m, d, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	}
+	return t.AddDate(0, 0, int(day))
+}
+
 type addDateFunctionClass struct {
 	baseFunctionClass
 }
@@ -2899,7 +2919,11 @@ type builtinUnixTimestampCurrentSig struct {
 // evalInt evals a UNIX_TIMESTAMP().
 // See https://dev.mysql.com/doc/refman/5.7/en/date-and-time-functions.html#function_unix-timestamp
 func (b *builtinUnixTimestampCurrentSig) evalInt(row []types.Datum) (int64, bool, error) {
-	dec, err := goTimeToMysqlUnixTimestamp(time.Now(), 1)
+	now, err := getSystemTimestamp(b.ctx)
+	if err != nil {
+		return 0, true, errors.Trace(err)
+	}
+	dec, err := goTimeToMysqlUnixTimestamp(now, 1)
 	if err != nil {
 		return 0, true, errors.Trace(err)
 	}
@@ -0,0 +1,101 @@
+package expression
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/mock"
+)
+
+func TestDateArithmetic(t *testing.T) {
+	ctx := mock.NewContext()
+	tbl := []struct {
+		fn       string
+		date     string
+		interval int64
+		unit     string
+		expect   string
+	}{
+		{ast.DateAdd, "2025-01-31", 1, "MONTH", "2025-02-28"},
+		{ast.DateAdd, "2024-01-31", 1, "MONTH", "2024-02-29"},
+		{ast.DateAdd, "2024-02-29", 1, "YEAR", "2025-02-28"},
+		{ast.DateAdd, "2024-02-28", 1, "DAY", "2024-02-29"},
+		{ast.DateAdd, "2023-12-31 23:59:59", 1, "SECOND", "2024-01-01 00:00:00"},
+		{ast.DateAdd, "2024-03-31 10:00:00", 90, "MINUTE", "2024-03-31 11:30:00"},
+		{ast.DateAdd, "2024-03-31 10:00:00", 15, "HOUR", "2024-04-01 01:00:00"},
+		{ast.DateSub, "2025-03-31", 1, "MONTH", "2025-02-28"},
+		{ast.DateSub, "2024-03-01", 1, "DAY", "2024-02-29"},
+		{ast.DateSub, "2025-01-31", 2, "MONTH", "2024-11-30"},
+		{ast.DateSub, "2000-02-29", 100, "YEAR", "1900-02-28"},
+	}
+	for _, test := range tbl {
+		got := evalFunc(t, ctx, test.fn, strCon(test.date), intCon(test.interval), strCon(test.unit))
+		if got != test.expect {
+			t.Errorf("%s('%s', INTERVAL %d %s) = %s, want %s",
+				test.fn, test.date, test.interval, test.unit, got, test.expect)
+		}
+	}
+}
+
+func TestDateDiffAndFormat(t *testing.T) {
+	ctx := mock.NewContext()
+	if got := evalFunc(t, ctx, ast.DateDiff, strCon("2024-03-01 23:59:59"), strCon("2024-02-28 00:00:01")); got != "2" {
+		t.Errorf("DATEDIFF across leap day = %s, want 2", got)
+	}
+	if got := evalFunc(t, ctx, ast.DateDiff, strCon("2023-01-01"), strCon("2024-01-01")); got != "-365" {
+		t.Errorf("DATEDIFF negative = %s, want -365", got)
+	}
+	if got := evalFunc(t, ctx, ast.DateDiff, strCon("2023-01-01"), nullCon()); got != "<nil>" {
+		t.Errorf("DATEDIFF with NULL = %s, want NULL", got)
+	}
+	got := evalFunc(t, ctx, ast.DateFormat, strCon("2024-02-29 13:05:09"), strCon("%Y/%m/%d %H:%i:%s"))
+	if got != "2024/02/29 13:05:09" {
+		t.Errorf("DATE_FORMAT = %s", got)
+	}
+}
+
+func TestUnixTimestamp(t *testing.T) {
+	ctx := mock.NewContext()
+	ctx.GetSessionVars().TimeZone = time.UTC
+	ctx.GetSessionVars().StmtCtx.TimeZone = time.UTC
+	if got := evalFunc(t, ctx, ast.UnixTimestamp, strCon("1970-01-02 00:00:00")); got != "86400.000000" {
+		t.Errorf("UNIX_TIMESTAMP = %s", got)
+	}
+	if got := evalFunc(t, ctx, ast.FromUnixTime, intCon(86400)); got != "1970-01-02 00:00:00" {
+		t.Errorf("FROM_UNIXTIME = %s", got)
+	}
+	if got := evalFunc(t, ctx, ast.FromUnixTime, intCon(1709210709), strCon("%Y-%m-%d %H:%i:%s")); got != "2024-02-29 12:45:09" {
+		t.Errorf("FROM_UNIXTIME with format = %s", got)
+	}
+
+	// FROM_UNIXTIME honors the session time_zone.
+	ctx.GetSessionVars().TimeZone = time.FixedZone("UTC+8", 8*3600)
+	if got := evalFunc(t, ctx, ast.FromUnixTime, intCon(0)); got != "1970-01-01 08:00:00" {
+		t.Errorf("FROM_UNIXTIME in +08:00 = %s", got)
+	}
+}
+
+func TestNowIsStableWithinStatement(t *testing.T) {
+	ctx := mock.NewContext()
+	ctx.GetSessionVars().TimeZone = time.UTC
+	start := time.Date(2024, 2, 29, 23, 59, 59, 0, time.UTC)
+	ctx.GetSessionVars().StmtCtx.NowTs = start
+
+	for _, fn := range []string{ast.Now, ast.CurrentTimestamp} {
+		if got := evalFunc(t, ctx, fn); got != "2024-02-29 23:59:59" {
+			t.Errorf("%s() = %s, want statement start time", fn, got)
+		}
+	}
+	if got := evalFunc(t, ctx, ast.Curdate); got != "2024-02-29" {
+		t.Errorf("CURDATE() = %s", got)
+	}
+	if got := evalFunc(t, ctx, ast.UnixTimestamp); got != "1709251199" {
+		t.Errorf("UNIX_TIMESTAMP() = %s", got)
+	}
+
+	ctx.GetSessionVars().TimeZone = time.FixedZone("UTC+8", 8*3600)
+	if got := evalFunc(t, ctx, ast.Now); got != "2024-03-01 07:59:59" {
+		t.Errorf("NOW() in +08:00 = %s", got)
+	}
+}
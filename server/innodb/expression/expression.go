@@ -160,6 +160,7 @@ type VarAssignment struct {
 	IsDefault   bool
 	IsGlobal    bool
 	IsSystem    bool
+	IsPersist   bool
 	ExtendValue *Constant
 }
 
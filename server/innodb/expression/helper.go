@@ -123,13 +123,12 @@ func GetTimeValue(ctx context.Context, v interface{}, tp byte, fsp int) (d types
 }
 
 func getSystemTimestamp(ctx context.Context) (time.Time, error) {
-	now := time.Now()
-
 	if ctx == nil {
-		return now, nil
+		return time.Now(), nil
 	}
 
 	sessionVars := ctx.GetSessionVars()
+	now := sessionVars.StmtCtx.GetNowTs()
 	timestampStr, err := varsutil.GetSessionSystemVar(sessionVars, "timestamp")
 	if err != nil {
 		return now, errors.Trace(err)
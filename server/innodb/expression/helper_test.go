@@ -0,0 +1,69 @@
+package expression
+
+import (
+	"testing"
+
+	types "github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/mock"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// newFunction builds funcName over args returning a BIGINT.
+func newFunction(funcName string, args ...Expression) Expression {
+	return NewFunctionInternal(mock.NewContext(), funcName, types.NewFieldType(mysql.TypeLonglong), args...)
+}
+
+func strCon(s string) Expression {
+	return &Constant{Value: types.NewStringDatum(s), RetType: types.NewFieldType(mysql.TypeVarString)}
+}
+
+func intCon(i int64) Expression {
+	return &Constant{Value: types.NewIntDatum(i), RetType: types.NewFieldType(mysql.TypeLonglong)}
+}
+
+func uintCon(u uint64) Expression {
+	tp := types.NewFieldType(mysql.TypeLonglong)
+	tp.Flag |= mysql.UnsignedFlag
+	return &Constant{Value: types.NewUintDatum(u), RetType: tp}
+}
+
+func realCon(f float64) Expression {
+	return &Constant{Value: types.NewFloat64Datum(f), RetType: types.NewFieldType(mysql.TypeDouble)}
+}
+
+// decCon returns an exact DECIMAL literal such as 2.50.
+func decCon(s string) Expression {
+	d := new(types.MyDecimal)
+	if err := d.FromString([]byte(s)); err != nil {
+		panic(err)
+	}
+	tp := types.NewFieldType(mysql.TypeNewDecimal)
+	tp.Flen, tp.Decimal = d.PrecisionAndFrac()
+	return &Constant{Value: types.NewDecimalDatum(d), RetType: tp}
+}
+
+func nullCon() Expression {
+	return &Constant{Value: types.Datum{}, RetType: types.NewFieldType(mysql.TypeNull)}
+}
+
+// evalFunc builds funcName over args and evaluates it to a string,
+// reporting NULL as "<nil>".
+func evalFunc(t *testing.T, ctx context.Context, funcName string, args ...Expression) string {
+	f, err := NewFunction(ctx, funcName, types.NewFieldType(mysql.TypeUnspecified), args...)
+	if err != nil {
+		t.Fatalf("%s: %v", funcName, err)
+	}
+	d, err := f.Eval(nil)
+	if err != nil {
+		t.Fatalf("%s: %v", funcName, err)
+	}
+	if d.IsNull() {
+		return "<nil>"
+	}
+	s, err := d.ToString()
+	if err != nil {
+		t.Fatalf("%s: %v", funcName, err)
+	}
+	return s
+}
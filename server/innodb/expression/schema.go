@@ -18,6 +18,7 @@ import (
 
 	"github.com/juju/errors"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
 )
 
 // KeyInfo stores the columns of one unique key or primary key.
@@ -109,8 +110,8 @@ func (s *Schema) FindColumnAndIndex(astCol *ast.ColumnName) (*Column, int, error
 	dbName, tblName, colName := astCol.Schema, astCol.Table, astCol.Name
 	idx := -1
 	for i, col := range s.Columns {
-		if (dbName.L == "" || dbName.L == col.DBName.L) &&
-			(tblName.L == "" || tblName.L == col.TblName.L) &&
+		if (dbName.L == "" || model.TableNamesEqual(dbName, col.DBName)) &&
+			(tblName.L == "" || model.TableNamesEqual(tblName, col.TblName)) &&
 			(colName.L == col.ColName.L) {
 			if idx == -1 {
 				idx = i
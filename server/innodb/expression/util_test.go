@@ -14,21 +14,15 @@
 package expression
 
 import (
-	"github.com/zhukovaskychina/xmysql-server/server/innodb/_vendor/src/github.com/pingcap/check"
+	"testing"
+
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
 	types "github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/mock"
-	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/testleak"
 	"github.com/zhukovaskychina/xmysql-server/server/mysql"
 )
 
-var _ = check.Suite(&testUtilSuite{})
-
-type testUtilSuite struct {
-}
-
-func (s *testUtilSuite) TestSubstituteCorCol2Constant(c *check.C) {
-	defer testleak.AfterTest(c)()
+func TestSubstituteCorCol2Constant(t *testing.T) {
 	ctx := mock.NewContext()
 	corCol1 := &CorrelatedColumn{Data: &One.Value}
 	corCol1.RetType = types.NewFieldType(mysql.TypeLonglong)
@@ -39,22 +33,33 @@ func (s *testUtilSuite) TestSubstituteCorCol2Constant(c *check.C) {
 	plus2 := newFunction(ast.Plus, plus, One)
 	ans1 := &Constant{Value: types.NewIntDatum(3), RetType: types.NewFieldType(mysql.TypeLonglong)}
 	ret, err := SubstituteCorCol2Constant(plus2)
-	c.Assert(err, check.IsNil)
-	c.Assert(ret.Equal(ans1, ctx), check.IsTrue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ret.Equal(ans1, ctx) {
+		t.Errorf("expect %s, got %s", ans1, ret)
+	}
 	col1 := &Column{Index: 1, RetType: types.NewFieldType(mysql.TypeLonglong)}
 	ret, err = SubstituteCorCol2Constant(col1)
-	c.Assert(err, check.IsNil)
+	if err != nil {
+		t.Fatal(err)
+	}
 	ans2 := col1
-	c.Assert(ret.Equal(ans2, ctx), check.IsTrue)
+	if !ret.Equal(ans2, ctx) {
+		t.Errorf("expect %s, got %s", ans2, ret)
+	}
 	plus3 := newFunction(ast.Plus, plus2, col1)
 	ret, err = SubstituteCorCol2Constant(plus3)
-	c.Assert(err, check.IsNil)
+	if err != nil {
+		t.Fatal(err)
+	}
 	ans3 := newFunction(ast.Plus, ans1, col1)
-	c.Assert(ret.Equal(ans3, ctx), check.IsTrue)
+	if !ret.Equal(ans3, ctx) {
+		t.Errorf("expect %s, got %s", ans3, ret)
+	}
 }
 
-func (s *testUtilSuite) TestPushDownNot(c *check.C) {
-	defer testleak.AfterTest(c)()
+func TestPushDownNot(t *testing.T) {
 	ctx := mock.NewContext()
 	col := &Column{Index: 1, RetType: types.NewFieldType(mysql.TypeLonglong)}
 	// !((a=1||a=1)&&a=1)
@@ -67,5 +72,7 @@ func (s *testUtilSuite) TestPushDownNot(c *check.C) {
 	andFunc2 := newFunction(ast.LogicAnd, neFunc, neFunc)
 	orFunc2 := newFunction(ast.LogicOr, andFunc2, neFunc)
 	ret := PushDownNot(notFunc, false, ctx)
-	c.Assert(ret.Equal(orFunc2, ctx), check.IsTrue)
+	if !ret.Equal(orFunc2, ctx) {
+		t.Errorf("expect %s, got %s", orFunc2, ret)
+	}
 }
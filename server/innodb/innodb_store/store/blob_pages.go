@@ -0,0 +1,186 @@
+package store
+
+import (
+	"github.com/pkg/errors"
+	"github.com/zhukovaskychina/xmysql-server/server/common"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/innodb_store/store/storebytes/pages"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/tuple"
+)
+
+/**
+溢出页链表
+
+超过页内阈值的列值按 pages.BlobPageDataSize() 个字节切分，依次存放到一串 BLOB 页面中，
+每个页面的页头记录本页存放的字节数和下一个页面的页号，最后一个页面为 FIL_NULL。
+记录中的 20 字节 FieldRef 指向第一个页面，并记录整个值的长度。
+
+读取时沿链表拼接，直到读满 FieldRef 中的长度；更新或删除记录时，
+旧值所在的整条链表归还给表空间。
+**/
+
+// BlobPageStore keeps the pages of the space the overflow chains are in.
+type BlobPageStore interface {
+	// AllocatePage returns a free page of the space.
+	AllocatePage() (uint32, error)
+	// FreePage gives page pageNo back to the space.
+	FreePage(pageNo uint32) error
+	// ReadPage returns the content of page pageNo.
+	ReadPage(pageNo uint32) ([]byte, error)
+	// WritePage stores content as page pageNo.
+	WritePage(pageNo uint32, content []byte) error
+}
+
+// BlobPages stores the off-page values of a space in chains of BLOB pages.
+type BlobPages struct {
+	spaceId uint32
+	store   BlobPageStore
+}
+
+// NewBlobPages returns the overflow pages of space spaceId, kept in store.
+func NewBlobPages(spaceId uint32, store BlobPageStore) *BlobPages {
+	return &BlobPages{spaceId: spaceId, store: store}
+}
+
+// WriteOverflow writes value to a new chain of BLOB pages.
+func (b *BlobPages) WriteOverflow(value []byte) (FieldRef, error) {
+	n := (len(value) + pages.BlobPageDataSize() - 1) / pages.BlobPageDataSize()
+	if n == 0 {
+		n = 1
+	}
+	pageNos := make([]uint32, 0, n)
+	for i := 0; i < n; i++ {
+		pageNo, err := b.store.AllocatePage()
+		if err != nil {
+			b.freePages(pageNos)
+			return FieldRef{}, err
+		}
+		pageNos = append(pageNos, pageNo)
+	}
+	for i, pageNo := range pageNos {
+		next := uint32(pages.BlobPageNull)
+		if i+1 < n {
+			next = pageNos[i+1]
+		}
+		end := (i + 1) * pages.BlobPageDataSize()
+		if end > len(value) {
+			end = len(value)
+		}
+		page := pages.NewBlobPage(b.spaceId, pageNo, value[i*pages.BlobPageDataSize():end], next)
+		if err := b.store.WritePage(pageNo, page.GetSerializeBytes()); err != nil {
+			b.freePages(pageNos)
+			return FieldRef{}, err
+		}
+	}
+	return FieldRef{
+		SpaceId: b.spaceId,
+		PageNo:  pageNos[0],
+		Offset:  common.PAGE_FILE_HEADER_SIZE,
+		Length:  uint64(len(value)),
+	}, nil
+}
+
+// ReadOverflow reassembles the value at ref from its chain.
+func (b *BlobPages) ReadOverflow(ref FieldRef) ([]byte, error) {
+	value := make([]byte, 0, ref.Length)
+	err := b.walk(ref, func(pageNo uint32, page *pages.BlobPage) error {
+		value = append(value, page.Data...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(value)) != ref.Length {
+		return nil, errors.Errorf("overflow chain at page %d has %d bytes, expect %d", ref.PageNo, len(value), ref.Length)
+	}
+	return value, nil
+}
+
+// FreeOverflow gives the pages of the chain at ref back to the space.
+func (b *BlobPages) FreeOverflow(ref FieldRef) error {
+	var pageNos []uint32
+	err := b.walk(ref, func(pageNo uint32, page *pages.BlobPage) error {
+		pageNos = append(pageNos, pageNo)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return b.freePages(pageNos)
+}
+
+// walk calls do with each page of the chain at ref, in order.
+func (b *BlobPages) walk(ref FieldRef, do func(pageNo uint32, page *pages.BlobPage) error) error {
+	if ref.SpaceId != b.spaceId {
+		return errors.Errorf("overflow chain in space %d, expect space %d", ref.SpaceId, b.spaceId)
+	}
+	// A chain never has more pages than its length needs, which also stops
+	// on a cycle.
+	maxPages := int(ref.Length/uint64(pages.BlobPageDataSize())) + 1
+	pageNo := ref.PageNo
+	for i := 0; pageNo != pages.BlobPageNull; i++ {
+		if i == maxPages {
+			return errors.Errorf("overflow chain at page %d is longer than %d bytes", ref.PageNo, ref.Length)
+		}
+		content, err := b.store.ReadPage(pageNo)
+		if err != nil {
+			return err
+		}
+		if len(content) != common.PageSize() {
+			return errors.Errorf("page %d of an overflow chain has %d bytes", pageNo, len(content))
+		}
+		page := pages.ParseBlobPage(content)
+		if page.GetPageType() != common.FILE_PAGE_TYPE_BLOB {
+			return errors.Errorf("page %d of an overflow chain is not a BLOB page", pageNo)
+		}
+		if err = do(pageNo, &page); err != nil {
+			return err
+		}
+		pageNo = page.GetNextPageNo()
+	}
+	return nil
+}
+
+func (b *BlobPages) freePages(pageNos []uint32) error {
+	for _, pageNo := range pageNos {
+		if err := b.store.FreePage(pageNo); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FreeExternValues gives back the overflow chains of the off-page values
+// of content, a record of tableTuple, when the record is deleted or its
+// values are replaced.
+func FreeExternValues(content []byte, tableTuple tuple.TableRowTuple) error {
+	_, overflow := rowFormatOf(tableTuple)
+	header := NewClusterLeafRowHeaderWithContents(tableTuple, content).(*ClusterLeafRowHeader)
+	offset := int(header.GetRowHeaderLength())
+	for i := 0; i < tableTuple.GetColumnLength(); i++ {
+		if header.IsValueNullByIdx(byte(i)) {
+			continue
+		}
+		col := tableTuple.GetColumnInfos(byte(i))
+		switch col.FieldType {
+		case "VARCHAR", "TEXT", "BLOB", "VARBINARY", "BINARY":
+			length := header.GetVarValueLengthByIndex(byte(i))
+			if header.IsValueExternByIdx(byte(i)) {
+				if overflow == nil || length < fieldRefSize {
+					return errors.Errorf("no overflow pages for the value of column %d", i)
+				}
+				ref := NewFieldRefWithBytes(content[offset+length-fieldRefSize : offset+length])
+				if err := overflow.FreeOverflow(ref); err != nil {
+					return err
+				}
+			}
+			offset += length
+		case "BIGINT":
+			offset += 8
+		case "INT":
+			offset += 4
+		case "ENUM", "CHAR":
+			offset += col.GetMaxByteLength()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package store
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/innodb_store/store/storebytes/pages"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/tuple"
+	"github.com/zhukovaskychina/xmysql-server/util"
+)
+
+// testBlobPageStore keeps the pages in memory and reuses the freed ones.
+type testBlobPageStore struct {
+	pages map[uint32][]byte
+	free  []uint32
+	next  uint32
+}
+
+func newTestBlobPageStore() *testBlobPageStore {
+	return &testBlobPageStore{pages: make(map[uint32][]byte), next: 3}
+}
+
+func (s *testBlobPageStore) AllocatePage() (uint32, error) {
+	var pageNo uint32
+	if n := len(s.free); n > 0 {
+		pageNo, s.free = s.free[n-1], s.free[:n-1]
+	} else {
+		pageNo, s.next = s.next, s.next+1
+	}
+	s.pages[pageNo] = nil
+	return pageNo, nil
+}
+
+func (s *testBlobPageStore) FreePage(pageNo uint32) error {
+	if _, ok := s.pages[pageNo]; !ok {
+		return fmt.Errorf("page %d is not allocated", pageNo)
+	}
+	delete(s.pages, pageNo)
+	s.free = append(s.free, pageNo)
+	return nil
+}
+
+func (s *testBlobPageStore) ReadPage(pageNo uint32) ([]byte, error) {
+	content, ok := s.pages[pageNo]
+	if !ok {
+		return nil, fmt.Errorf("page %d is not allocated", pageNo)
+	}
+	return content, nil
+}
+
+func (s *testBlobPageStore) WritePage(pageNo uint32, content []byte) error {
+	s.pages[pageNo] = content
+	return nil
+}
+
+func TestBlobPages(t *testing.T) {
+	store := newTestBlobPageStore()
+	blobs := NewBlobPages(5, store)
+	meta := &TableTupleMeta{TableName: "t", RowFormat: RowFormatDynamic, Overflow: blobs, Columns: []*tuple.FormColumnsWrapper{
+		{FieldName: "ID", FieldType: "INT", FieldLength: 4, NotNull: true},
+		{FieldName: "BODY", FieldType: "BLOB"},
+	}}
+	writeRow := func(body []byte) []byte {
+		row := NewClusterLeafRowWithFrm(meta).(*ClusterLeafRow)
+		for i, content := range [][]byte{util.ConvertUInt4Bytes(1), body} {
+			if err := row.WriteColumn(content, byte(i)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return row.ToByte()
+	}
+	readBody := func(record []byte) []byte {
+		return NewClusterLeafRowWithContent(record, meta.GetPrimaryClusterLeafTuple()).ReadValueByIndex(1).ToByte()
+	}
+
+	// INSERT a value over three and a half pages.
+	long := make([]byte, pages.BlobPageDataSize()*7/2)
+	for i := range long {
+		long[i] = byte(i * 7)
+	}
+	record := writeRow(long)
+	if len(store.pages) != 4 {
+		t.Fatalf("expect 4 BLOB pages, got %d", len(store.pages))
+	}
+	if body := readBody(record); !bytes.Equal(body, long) {
+		t.Fatalf("read back %d bytes, not the %d written", len(body), len(long))
+	}
+
+	// UPDATE to a value of two pages frees the old chain.
+	if err := FreeExternValues(record, meta.GetPrimaryClusterLeafTuple()); err != nil {
+		t.Fatal(err)
+	}
+	shorter := bytes.Repeat([]byte("xmysql"), pages.BlobPageDataSize()/4)
+	record = writeRow(shorter)
+	if len(store.pages) != 2 || len(store.free) != 2 {
+		t.Fatalf("expect 2 BLOB pages and 2 free ones, got %d and %d", len(store.pages), len(store.free))
+	}
+	if body := readBody(record); !bytes.Equal(body, shorter) {
+		t.Fatalf("read back %d bytes, not the %d written", len(body), len(shorter))
+	}
+
+	// DELETE frees it all.
+	if err := FreeExternValues(record, meta.GetPrimaryClusterLeafTuple()); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.pages) != 0 {
+		t.Fatalf("expect no BLOB page left, got %d", len(store.pages))
+	}
+
+	// A broken chain is an error, not a short value.
+	ref, err := blobs.WriteOverflow(long)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := pages.ParseBlobPage(store.pages[ref.PageNo])
+	second := store.pages[first.GetNextPageNo()]
+	store.FreePage(first.GetNextPageNo())
+	if _, err = blobs.ReadOverflow(ref); err == nil {
+		t.Fatal("expect an error reading a broken chain")
+	}
+	store.pages[first.GetNextPageNo()] = second
+	if _, err = blobs.ReadOverflow(ref); err != nil {
+		t.Fatal(err)
+	}
+	ref.Length--
+	if _, err = blobs.ReadOverflow(ref); err == nil {
+		t.Fatal("expect an error reading a chain of the wrong length")
+	}
+}
@@ -16,14 +16,15 @@ import (
 // BTree stores Item instances in an ordered structure, allowing easy insertion,
 // removal, and iteration.x
 //
-// Write operations are not safe for concurrent mutation by multiple
-// goroutines, but Read operations are.
+// Add and the lookups latch the pages they go through, so that several
+// goroutines can modify the same tree.
 type BTree struct {
 	basic.Tree
 	//	Tree
 	spaceId      uint32
 	rootPageNo   uint32
 	indexName    string
+	indexId      uint64 //索引在数据字典中的ID，读入的页面在缓冲池中记下它
 	rootPage     *Index
 	indexSegment Segment
 	dataSegment  Segment
@@ -36,6 +37,8 @@ type BTree struct {
 	leafTuple     tuple.TableRowTuple
 
 	IsInit bool //判断是否用来初始化
+
+	latches pageLatches
 }
 
 func (self *BTree) Keys() (basic.RowItemsIterator, error) {
@@ -99,6 +102,20 @@ func NewBtreeWithBufferPool(spaceId uint32, rootPageNo uint32, indexName string,
 	}
 }
 
+// SetIndexId sets the id of the index of the tree in the dictionary, which
+// the pages of the tree record in the buffer pool.
+func (self *BTree) SetIndexId(indexId uint64) {
+	self.indexId = indexId
+}
+
+// ownPage records in block, a page of the tree in the buffer pool, the
+// index of the tree and the size of the records of index.
+func (self *BTree) ownPage(block *buffer_pool.BufferBlock, index *Index) {
+	if self.indexId != 0 {
+		block.SetIndex(self.indexId, len(index.IndexPage.UserRecords))
+	}
+}
+
 func (self *BTree) do(pageNumber uint32, internalDo func(page *Index) error, leafDo func(page *Index) error) error {
 	var leafOrInternal string
 	var index *Index
@@ -128,9 +145,11 @@ func (self *BTree) do(pageNumber uint32, internalDo func(page *Index) error, lea
 		leafOrInternal = self.getCurrentPageType(bytes, leafOrInternal)
 		if leafOrInternal == common.PAGE_INTERNAL {
 			index = NewPageIndexByLoadBytesWithTuple(bytes, self.internalTuple).(*Index)
+			self.ownPage(bufferBlock, index)
 			return internalDo(index)
 		} else {
 			index = NewPageIndexByLoadBytesWithTuple(bytes, self.leafTuple).(*Index)
+			self.ownPage(bufferBlock, index)
 			return leafDo(index)
 		}
 	}
@@ -218,8 +237,7 @@ func (self *BTree) rangeIterator(from basic.Value, to basic.Value) (bi bpt_itera
 		bi, err = self.forward(from, to)
 	}
 	if from == nil && to == nil {
-		bi, err = self.backward(from, to)
-		return bi, err
+		return self.forward(from, to)
 	}
 	compareValue, _ := from.LessThan(to)
 	if !compareValue.Raw().(bool) {
@@ -398,9 +416,9 @@ func (self *BTree) nextLoc(pageNo uint32, i int) (uint32, int, bool, error) {
 	nextBlk := func(pageNo uint32, j int) (uint32, int, bool, error) {
 		changed := false
 		err := self.doLeaf(pageNo, func(n *Index) error {
-			if j >= n.GetRecordSize()-2 && n.GetNextPageNo() != 0 {
+			if j > n.GetRecordSize() && n.GetNextPageNo() != 0 {
 				pageNo = n.GetNextPageNo()
-				j = 0
+				j = 1
 				changed = true
 			}
 			return nil
@@ -420,7 +438,7 @@ func (self *BTree) nextLoc(pageNo uint32, i int) (uint32, int, bool, error) {
 	}
 	var end bool = false
 	err = self.doLeaf(pageNo, func(n *Index) error {
-		if j >= n.GetRecordSize()-2 {
+		if j > n.GetRecordSize() {
 			end = true
 		}
 		return nil
@@ -524,56 +542,62 @@ func (self *BTree) _getEnd(root uint32, key basic.Value) (pageNo uint32, i int,
 * the block which has a key greater or equal to the search key.
  */
 func (self *BTree) getStart(key basic.Value) (pageNo uint32, i int, err error) {
-	return self._getStart(self.rootPageNo, key)
-}
-
-func (self *BTree) _getStart(n uint32, key basic.Value) (pageNo uint32, i int, err error) {
-	var leafOrInternal string
-
-	bufferBlock := self.BufferPool.GetPageBlock(self.spaceId, n)
-	bytes := *bufferBlock.Frame
-	filePageTypeBytes := bytes[24:26]
-	filePageType := util.ReadUB2Byte2Int(filePageTypeBytes)
-	if filePageType == common.FILE_PAGE_INDEX {
-		infimumSupremum := bytes[38+56 : 38+56+26]
-		flags := util.ConvertByte2BitsString(infimumSupremum[0])[3]
-		if flags == common.PAGE_LEAF {
-			leafOrInternal = common.PAGE_LEAF
-		} else {
-			leafOrInternal = common.PAGE_INTERNAL
-		}
+	if key == nil || self.BufferPool == nil || self.BufferPool.AHI == nil {
+		return self._getStart(self.rootPageNo, key)
 	}
-
-	if leafOrInternal != common.PAGE_LEAF {
-		return self.internalGetStart(n, key)
-	} else {
-		return self.leafGetStart(n, key, false, 0)
+	//先查自适应哈希索引，命中且槽位上仍是该key时不再下降
+	ahi := self.BufferPool.AHI
+	if ref, ok := ahi.Lookup(self.spaceId, self.indexName, key.ToByte()); ok && self.keyAt(ref.PageNo, ref.Slot, key) {
+		return ref.PageNo, ref.Slot, nil
 	}
-
+	pageNo, i, err = self._getStart(self.rootPageNo, key)
+	if err == nil && self.keyAt(pageNo, i, key) {
+		ahi.Observe(self.spaceId, self.indexName, 1, key.ToByte(), buffer_pool.AHIRecordRef{SpaceId: self.spaceId, PageNo: pageNo, Slot: i})
+	}
+	return pageNo, i, err
 }
 
-//非叶子节点的查找，返回当前key的下标，查找到key所在的页面号
-//@param n pageNo
-//@param key 查找key
-
-func (self *BTree) internalGetStart(n uint32, key basic.Value) (pageNo uint32, i int, err error) {
-	var kid uint32
-	err = self.doInternal(n, func(nIndex *Index) error {
-		currentRow, _ := nIndex.FindByKey(key)
-		kid = currentRow.GetPageNumber()
+// keyAt reports whether the key at slot i of page a is key.
+func (self *BTree) keyAt(a uint32, i int, key basic.Value) bool {
+	var equal bool
+	err := self.doKey(a, i, func(akey basic.Value) error {
+		v, err := key.Equal(akey)
+		if err != nil {
+			return err
+		}
+		equal, _ = v.Raw().(bool)
 		return nil
 	})
-	if err != nil {
-		return 0, 0, err
+	return err == nil && equal
+}
+
+// _getStart goes down from page n to the leaf of key, latching each page
+// shared and releasing its parent once it holds it.
+func (self *BTree) _getStart(n uint32, key basic.Value) (pageNo uint32, i int, err error) {
+	path := self.newLatchPath()
+	defer path.release()
+	path.push(n, false)
+	for {
+		leaf, err := self.isLeaf(n)
+		if err != nil {
+			return 0, 0, err
+		}
+		if leaf {
+			return self.leafGetStart(n, key, false, 0)
+		}
+		if n, err = self.childOf(n, key); err != nil {
+			return 0, 0, err
+		}
+		path.push(n, false)
+		path.releaseAncestors()
 	}
-	return self._getStart(kid, key)
 }
 
 //叶子页面的查找
 //
 func (self *BTree) leafGetStart(n uint32, key basic.Value, stop bool, end uint32) (pageNo uint32, i int, err error) {
 	if key == nil {
-		return n, 0, nil
+		return n, 1, nil
 	}
 	if stop && n == end {
 		return 0, 0, errors.Errorf("hit end %v %v %v", n, end, key)
@@ -612,6 +636,9 @@ func (self *BTree) leafGetStart(n uint32, key basic.Value, stop bool, end uint32
 		return 0, 0, err
 	}
 	if next != 0 {
+		latch := self.latches.get(next)
+		latch.RLock()
+		defer latch.RUnlock()
 		return self.leafGetStart(next, key, stop, end)
 	}
 	return n, i + 1, nil
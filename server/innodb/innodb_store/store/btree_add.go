@@ -15,7 +15,12 @@ func (self *BTree) Add(key basic.Value, value basic.Row) (err error) {
 		return errors.New("key 为null")
 	}
 
-	_, _, err = self.add(self.rootPageNo, key, value)
+	//先乐观下降，叶子页面放不下时再悲观地重来
+	done, err := self.optimisticAdd(key, value)
+	if err != nil || done {
+		return err
+	}
+	_, _, err = self.pessimisticAdd(key, value)
 	if err != nil {
 		return err
 	}
@@ -24,16 +29,6 @@ func (self *BTree) Add(key basic.Value, value basic.Row) (err error) {
 	return nil
 }
 
-func (self *BTree) add(root uint32, key basic.Value, value basic.Row) (cntDelta, newRoot uint32, err error) {
-	a, b, err := self.insert(root, key, value)
-	if err != nil {
-		return 0, 0, err
-	} else if b == 0 {
-		return 1, a, nil
-	}
-	return 1, newRoot, nil
-}
-
 /* right is only set on split left is always set.
  * - When split is false left is the pointer to block
  * - When split is true left is the pointer to the new left block
@@ -189,6 +184,7 @@ func (self *BTree) leafInsert(n uint32, key basic.Value, value basic.Row) (a, b
 			}
 			var bytesBuff = nIndex.IndexPage.GetSerializeBytes()
 			bufferBlock.Frame = &bytesBuff
+			self.ownPage(bufferBlock, nIndex)
 			self.BufferPool.UpdateBlock(self.spaceId, n, bufferBlock)
 			return nil
 		}
@@ -212,6 +208,7 @@ func (self *BTree) leafInsert(n uint32, key basic.Value, value basic.Row) (a, b
 		}
 		var bytesBuff = nIndex.IndexPage.GetSerializeBytes()
 		bufferBlock.Frame = &bytesBuff
+		self.ownPage(bufferBlock, nIndex)
 		self.BufferPool.UpdateBlock(self.spaceId, n, bufferBlock)
 		return nil
 	})
@@ -248,6 +245,11 @@ func (self *BTree) leafSplit(n uint32, key basic.Value, value basic.Row) (a, b u
 	if err != nil {
 		return 0, 0, err
 	}
+	//分裂后两个页面上的记录都移动了
+	if self.BufferPool != nil && self.BufferPool.AHI != nil {
+		self.BufferPool.AHI.InvalidatePage(self.spaceId, a)
+		self.BufferPool.AHI.InvalidatePage(self.spaceId, b)
+	}
 	return a, b, nil
 }
 
@@ -0,0 +1,99 @@
+package store
+
+import (
+	"github.com/pkg/errors"
+	"github.com/zhukovaskychina/xmysql-server/server/common"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/buffer_pool"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/tuple"
+	"github.com/zhukovaskychina/xmysql-server/util"
+)
+
+/**
+非唯一二级索引的写缓冲
+
+插入二级索引记录时，从根页面向下查找，到达第一层（PageLevel 为 1）的非叶子页面后，
+不再读取目标叶子页面：叶子页面不在缓冲池中时，记录交给写缓冲，直接返回。
+唯一索引需要读取叶子页面检查重复，不使用写缓冲。
+
+写缓冲中的记录在叶子页面读入缓冲池时由 IndexPageMerger 合并到页面中。
+**/
+
+// IndexPageMerger returns the merger adding the records buffered for a leaf
+// page of an index of leafTuple; newRow reads a record.
+func IndexPageMerger(leafTuple tuple.TableRowTuple, newRow func(content []byte, tableTuple tuple.TableRowTuple) basic.Row) buffer_pool.ChangeBufferMerger {
+	return func(frame []byte, records [][]byte) ([]byte, error) {
+		if len(frame) != common.PageSize() || util.ReadUB2Byte2Int(frame[24:26]) != common.FILE_PAGE_INDEX {
+			return nil, errors.New("change buffer merge into a page that is not an index page")
+		}
+		index := NewPageIndexByLoadBytesWithTuple(frame, leafTuple).(*Index)
+		if index.PageLeafOrInternal() != common.PAGE_LEAF {
+			return nil, errors.Errorf("change buffer merge into page %d, not a leaf page", index.GetPageNumber())
+		}
+		for _, record := range records {
+			row := newRow(record, leafTuple)
+			if index.IsFull(row) {
+				return nil, errors.Errorf("change buffer merge overflows page %d", index.GetPageNumber())
+			}
+			index.AddRow(row)
+		}
+		return index.ToByte(), nil
+	}
+}
+
+// EnableChangeBuffer lets the inserts into the leaf pages of the index that
+// aren't in the buffer pool be buffered.
+func (self *BTree) EnableChangeBuffer() {
+	if self.IsInit || self.BufferPool == nil {
+		return
+	}
+	self.BufferPool.ChangeBuffer.RegisterMerger(self.spaceId, self.indexName, IndexPageMerger(self.leafTuple, NewSecondaryLeafRow))
+}
+
+// AddNonUnique inserts value into the index, which must be a non-unique
+// secondary index. The insert is buffered when its leaf page isn't in the
+// buffer pool.
+func (self *BTree) AddNonUnique(key basic.Value, value basic.Row) error {
+	if key == nil {
+		return errors.New("key 为null")
+	}
+	if !self.IsInit && self.BufferPool != nil {
+		pageNo, ok, err := self.leafPageOf(key)
+		if err != nil {
+			return err
+		}
+		if ok && self.BufferPool.BufferInsert(self.spaceId, pageNo, self.indexName, value.ToByte()) {
+			return nil
+		}
+	}
+	return self.Add(key, value)
+}
+
+// leafPageOf returns the leaf page key goes to, found without reading the
+// leaf page. It returns false when the leaf page had to be read, which is
+// when the root is a leaf.
+func (self *BTree) leafPageOf(key basic.Value) (pageNo uint32, ok bool, err error) {
+	pageNo = self.rootPageNo
+	for {
+		var next uint32
+		var level int
+		err = self.do(pageNo, func(index *Index) error {
+			row, _ := index.FindByKey(key)
+			if row == nil {
+				return errors.Errorf("no child of page %d for the key", pageNo)
+			}
+			next = row.GetPageNumber()
+			level = int(util.ReadUB2Byte2Int(index.IndexPage.PageHeader.PageLevel[:2]))
+			return nil
+		}, func(index *Index) error {
+			return nil
+		})
+		if err != nil || next == 0 {
+			return pageNo, false, err
+		}
+		if level == 1 {
+			return next, true, nil
+		}
+		pageNo = next
+	}
+}
@@ -0,0 +1,52 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/common"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/buffer_pool"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/tuple"
+)
+
+func TestIndexPageMerger(t *testing.T) {
+	sysTuple := NewSysTableTuple()
+	index := NewPageIndexWithTuple(10, 7, sysTuple).(*Index)
+	row := NewClusterSysIndexLeafRow(sysTuple, false)
+	initSysTableRow("test", sysTuple, row)
+	index.AddRow(row)
+	if index.PageLeafOrInternal() != common.PAGE_LEAF {
+		t.Fatal("expect a leaf page")
+	}
+
+	cb := buffer_pool.NewChangeBuffer(buffer_pool.ChangeBufferingAll)
+	cb.RegisterMerger(10, "PRIMARY", IndexPageMerger(sysTuple, func(content []byte, tableTuple tuple.TableRowTuple) basic.Row {
+		return NewClusterSysIndexLeafRowWithContent(content, tableTuple)
+	}))
+	for i := 0; i < 2; i++ {
+		if !cb.Buffer(10, 7, "PRIMARY", row.ToByte()) {
+			t.Fatal("expect the insert to be buffered")
+		}
+	}
+	frame := index.ToByte()
+	block := buffer_pool.NewBufferBlock(&frame, 10, 7)
+	if ok, err := cb.Merge(block); err != nil || !ok {
+		t.Fatalf("expect a merge, got %v", err)
+	}
+	merged := NewPageIndexByLoadBytesWithTuple(*block.Frame, sysTuple).(*Index)
+	if merged.GetRecordSize() != 3 {
+		t.Fatalf("expect 3 records after the merge, got %d", merged.GetRecordSize())
+	}
+
+	// A page that isn't an index leaf keeps the inserts buffered.
+	cb.Buffer(10, 8, "PRIMARY", row.ToByte())
+	blob := NewBlobPages(10, newTestBlobPageStore())
+	ref, _ := blob.WriteOverflow([]byte("xmysql"))
+	content, _ := blob.store.ReadPage(ref.PageNo)
+	if _, err := cb.Merge(buffer_pool.NewBufferBlock(&content, 10, 8)); err == nil {
+		t.Fatal("expect an error merging into a BLOB page")
+	}
+	if !cb.Has(10, 8) {
+		t.Fatal("expect the inserts to stay buffered")
+	}
+}
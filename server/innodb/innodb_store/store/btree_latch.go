@@ -0,0 +1,269 @@
+package store
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+)
+
+// pageLatches are the latches of the pages of a tree. They are always taken
+// from the root down, and along the leaves from left to right, so that two
+// operations never wait for each other in a cycle.
+type pageLatches struct {
+	mu      sync.Mutex
+	latches map[uint32]*sync.RWMutex
+}
+
+func (l *pageLatches) get(pageNo uint32) *sync.RWMutex {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.latches == nil {
+		l.latches = make(map[uint32]*sync.RWMutex)
+	}
+	latch, ok := l.latches[pageNo]
+	if !ok {
+		latch = new(sync.RWMutex)
+		l.latches[pageNo] = latch
+	}
+	return latch
+}
+
+// latchPath is the pages an operation holds latched, from the top down.
+type latchPath struct {
+	latches   *pageLatches
+	pages     []uint32
+	exclusive []bool
+}
+
+func (self *BTree) newLatchPath() *latchPath {
+	return &latchPath{latches: &self.latches}
+}
+
+// push latches pageNo below the pages already held.
+func (p *latchPath) push(pageNo uint32, exclusive bool) {
+	latch := p.latches.get(pageNo)
+	if exclusive {
+		latch.Lock()
+	} else {
+		latch.RLock()
+	}
+	p.pages = append(p.pages, pageNo)
+	p.exclusive = append(p.exclusive, exclusive)
+}
+
+// relatchExclusive latches the lowest page exclusively. Unless it is the
+// root, the page above it is still held, so nothing can split it in between.
+func (p *latchPath) relatchExclusive() {
+	last := len(p.pages) - 1
+	if p.exclusive[last] {
+		return
+	}
+	latch := p.latches.get(p.pages[last])
+	latch.RUnlock()
+	latch.Lock()
+	p.exclusive[last] = true
+}
+
+// releaseAncestors releases every page but the lowest one.
+func (p *latchPath) releaseAncestors() {
+	last := len(p.pages) - 1
+	for i := 0; i < last; i++ {
+		p.unlock(i)
+	}
+	p.pages = p.pages[last:]
+	p.exclusive = p.exclusive[last:]
+}
+
+func (p *latchPath) release() {
+	for i := range p.pages {
+		p.unlock(i)
+	}
+	p.pages, p.exclusive = nil, nil
+}
+
+func (p *latchPath) unlock(i int) {
+	latch := p.latches.get(p.pages[i])
+	if p.exclusive[i] {
+		latch.Unlock()
+	} else {
+		latch.RUnlock()
+	}
+}
+
+// isLeaf reports whether page n is a leaf of the tree.
+func (self *BTree) isLeaf(n uint32) (leaf bool, err error) {
+	var index bool
+	err = self.do(n,
+		func(*Index) error {
+			index = true
+			return nil
+		},
+		func(*Index) error {
+			index, leaf = true, true
+			return nil
+		})
+	if err == nil && !index {
+		err = errors.Errorf("page %d isn't an index page", n)
+	}
+	return leaf, err
+}
+
+// childOf returns the child of the internal page n key is under.
+func (self *BTree) childOf(n uint32, key basic.Value) (child uint32, err error) {
+	err = self.doInternal(n, func(nIndex *Index) error {
+		row, _ := nIndex.FindByKey(key)
+		child = row.GetPageNumber()
+		return nil
+	})
+	return child, err
+}
+
+// isSafe reports whether inserting value under page n can't split n. The
+// node pointer an internal page gets on a split below is taken to be no
+// larger than value.
+func (self *BTree) isSafe(n uint32, value basic.Row) (safe bool, err error) {
+	isSafe := func(nIndex *Index) error {
+		safe = !nIndex.IsFull(value)
+		return nil
+	}
+	err = self.do(n, isSafe, isSafe)
+	return safe, err
+}
+
+// optimisticAdd adds value when its leaf has room for it. The internal pages
+// are latched shared and released as soon as their child is, the leaf
+// exclusively. It returns false, having changed nothing, when the leaf
+// would split.
+func (self *BTree) optimisticAdd(key basic.Value, value basic.Row) (bool, error) {
+	path := self.newLatchPath()
+	defer path.release()
+	n := self.rootPageNo
+	path.push(n, false)
+	for {
+		leaf, err := self.isLeaf(n)
+		if err != nil {
+			return false, err
+		}
+		if leaf {
+			break
+		}
+		if n, err = self.childOf(n, key); err != nil {
+			return false, err
+		}
+		path.push(n, false)
+		path.releaseAncestors()
+	}
+	path.relatchExclusive()
+	// A root leaf may have been split while it wasn't latched.
+	if leaf, err := self.isLeaf(n); err != nil || !leaf {
+		return false, err
+	}
+	safe, err := self.isSafe(n, value)
+	if err != nil || !safe {
+		return false, err
+	}
+	_, _, err = self.leafInsert(n, key, value)
+	return err == nil, err
+}
+
+// pessimisticAdd adds value latching the pages on its way exclusively. The
+// pages above one that can't split are released, the insert then starts
+// from the highest page still held.
+func (self *BTree) pessimisticAdd(key basic.Value, value basic.Row) (a, b uint32, err error) {
+	path := self.newLatchPath()
+	defer path.release()
+	n := self.rootPageNo
+	path.push(n, true)
+	for {
+		leaf, err := self.isLeaf(n)
+		if err != nil {
+			return 0, 0, err
+		}
+		if leaf {
+			break
+		}
+		if n, err = self.childOf(n, key); err != nil {
+			return 0, 0, err
+		}
+		path.push(n, true)
+		safe, err := self.isSafe(n, value)
+		if err != nil {
+			return 0, 0, err
+		}
+		if safe {
+			path.releaseAncestors()
+		}
+	}
+	return self.insert(path.pages[0], key, value)
+}
+
+// CheckConsistency walks the tree and returns an error describing the first
+// problem found: records out of order in a page, leaves at different depths,
+// or a leaf list that doesn't link the leaves in key order.
+func (self *BTree) CheckConsistency() error {
+	var leaves []uint32
+	var nextPages []uint32
+	leafDepth := -1
+	var walk func(n uint32, depth int) error
+	walk = func(n uint32, depth int) error {
+		latch := self.latches.get(n)
+		latch.RLock()
+		var children []uint32
+		err := self.do(n,
+			func(nIndex *Index) error {
+				if err := checkPageOrder(n, nIndex); err != nil {
+					return err
+				}
+				for i := 1; i <= nIndex.GetRecordSize(); i++ {
+					row, _ := nIndex.GetRowByIndex(i)
+					children = append(children, row.GetPageNumber())
+				}
+				return nil
+			},
+			func(nIndex *Index) error {
+				if leafDepth >= 0 && depth != leafDepth {
+					return errors.Errorf("leaf %d is at depth %d, expect %d", n, depth, leafDepth)
+				}
+				leafDepth = depth
+				leaves = append(leaves, n)
+				nextPages = append(nextPages, nIndex.GetNextPageNo())
+				return checkPageOrder(n, nIndex)
+			})
+		latch.RUnlock()
+		if err != nil {
+			return err
+		}
+		for _, child := range children {
+			if err = walk(child, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(self.rootPageNo, 0); err != nil {
+		return err
+	}
+	for i, next := range nextPages {
+		var expect uint32
+		if i+1 < len(leaves) {
+			expect = leaves[i+1]
+		}
+		if next != expect {
+			return errors.Errorf("leaf %d links to page %d, expect %d", leaves[i], next, expect)
+		}
+	}
+	return nil
+}
+
+// checkPageOrder checks the records of page n are in key order.
+func checkPageOrder(n uint32, nIndex *Index) error {
+	for i := 2; i <= nIndex.GetRecordSize(); i++ {
+		prev, _ := nIndex.GetRowByIndex(i - 1)
+		row, _ := nIndex.GetRowByIndex(i)
+		if row.Less(prev) {
+			return errors.Errorf("record %d of page %d is out of order", i, n)
+		}
+	}
+	return nil
+}
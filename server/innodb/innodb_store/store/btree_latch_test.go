@@ -0,0 +1,69 @@
+package store
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/innodb_store/store/storebytes/blocks"
+)
+
+func TestBTreeConcurrentAdd(t *testing.T) {
+	blockFile := blocks.NewBlockFile(t.TempDir(), "latch.ibd", 4*16384)
+	blockFile.CreateFile()
+	defer blockFile.Close()
+	sysTuple := NewSysTableTuple()
+	root := NewPageIndexWithTuple(0, 1, sysTuple).(*Index)
+	if err := blockFile.WriteContentByPage(1, root.ToByte()); err != nil {
+		t.Fatal(err)
+	}
+	tree := NewBtreeAtInit(1, "PRIMARY", nil, nil, root, blockFile, sysTuple, sysTuple)
+
+	// Each goroutine adds keys overlapping those of the next one.
+	const workers, keys = 8, 8
+	errs := make(chan error, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for k := w * keys / 2; k < w*keys/2+keys; k++ {
+				row := NewClusterSysIndexLeafRow(sysTuple, false)
+				initSysTableRowForRange("test", fmt.Sprintf("t%03d", k), sysTuple, row)
+				if err := tree.Add(row.GetPrimaryKey(), row); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(w)
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(30 * time.Second):
+		t.Fatal("concurrent adds deadlocked")
+	}
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	if err := tree.CheckConsistency(); err != nil {
+		t.Fatal(err)
+	}
+	err := blockFile.Do(1, func(content []byte) error {
+		leaf := NewPageIndexByLoadBytesWithTuple(content, sysTuple).(*Index)
+		if leaf.GetRecordSize() != workers*keys {
+			return fmt.Errorf("expect %d records, got %d", workers*keys, leaf.GetRecordSize())
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,78 @@
+package store
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+)
+
+/**
+缓冲池页面的归属
+
+打开表时从 SYS_INDEXES 中读出每个索引的 ID，交给索引的 B+ 树，B+ 树读入或写入的
+页面在缓冲池中记下这个 ID。这里记录索引 ID 和表空间分别属于哪个表、哪个索引，
+information_schema.INNODB_BUFFER_PAGE 按它给缓冲池中的页面标上表名和索引名。
+表空间中不属于 B+ 树的页面（文件头、inode 等）只有表名。
+
+记下的是表本身，表改名以后报告中就是新的名字。
+**/
+
+type indexOwner struct {
+	table *OrdinaryTable
+	index string
+}
+
+// pageOwners are the tables and indexes of the opened tables, by index id
+// and space id.
+type pageOwners struct {
+	mu      sync.RWMutex
+	indexes map[uint64]indexOwner
+	spaces  map[uint32]*OrdinaryTable
+}
+
+// add records that the B+tree of index of table has the id indexId.
+func (o *pageOwners) add(table *OrdinaryTable, index string, indexId uint64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.indexes == nil {
+		o.indexes = make(map[uint64]indexOwner)
+		o.spaces = make(map[uint32]*OrdinaryTable)
+	}
+	o.indexes[indexId] = indexOwner{table: table, index: index}
+	o.spaces[table.spaceId] = table
+}
+
+// PageOwner implements schemas.BufferPageOwners.
+func (i *InfoSchemaManager) PageOwner(space uint32, indexId uint64) (table string, index string) {
+	i.owners.mu.RLock()
+	defer i.owners.mu.RUnlock()
+	if owner, ok := i.owners.indexes[indexId]; ok && indexId != 0 && owner.table.spaceId == space {
+		return owner.table.qualifiedName(), owner.index
+	}
+	if tbl, ok := i.owners.spaces[space]; ok {
+		return tbl.qualifiedName(), ""
+	}
+	return "", ""
+}
+
+// qualifiedName returns the name of the table as INNODB_BUFFER_PAGE shows
+// it, `db`.`table`.
+func (o *OrdinaryTable) qualifiedName() string {
+	return fmt.Sprintf("`%s`.`%s`", o.databaseName, o.tableName)
+}
+
+// dictIndexId returns the id of an index read from the INDEX_ID of its row
+// of SYS_INDEXES, 0 when it has none.
+func dictIndexId(value basic.Value) uint64 {
+	if value == nil {
+		return 0
+	}
+	switch id := value.Raw().(type) {
+	case uint64:
+		return id
+	case int64:
+		return uint64(id)
+	}
+	return 0
+}
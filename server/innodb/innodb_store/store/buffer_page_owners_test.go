@@ -0,0 +1,37 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/conf"
+)
+
+func TestPageOwner(t *testing.T) {
+	i := new(InfoSchemaManager)
+	tbl := &OrdinaryTable{conf: conf.NewCfg(), spaceId: 5, databaseName: "test", tableName: "t1"}
+	i.owners.add(tbl, "PRIMARY", 7)
+	i.owners.add(tbl, "idx_a", 8)
+
+	for _, c := range []struct {
+		space        uint32
+		indexId      uint64
+		table, index string
+	}{
+		{5, 7, "`test`.`t1`", "PRIMARY"},
+		{5, 8, "`test`.`t1`", "idx_a"},
+		// A page of the space outside the trees.
+		{5, 0, "`test`.`t1`", ""},
+		// An index id recorded by a page of another space.
+		{6, 7, "", ""},
+	} {
+		if table, index := i.PageOwner(c.space, c.indexId); table != c.table || index != c.index {
+			t.Fatalf("page of index %d of space %d: expect %s %s, got %s %s", c.indexId, c.space, c.table, c.index, table, index)
+		}
+	}
+
+	// The pages are named after the table renamed.
+	tbl.rename("test2", "t2", nil)
+	if table, _ := i.PageOwner(5, 7); table != "`test2`.`t2`" {
+		t.Fatalf("expect the new name of the table, got %s", table)
+	}
+}
@@ -8,7 +8,7 @@ import (
 
 /////////////////////////////////////////////////////////////
 ///
-/// 每个Extent的大小均为1MB 256*16384 Byte
+/// 每个Extent的大小：16K 及以下的页面为1MB，见 common.ExtentPages
 ///
 //////////////////////////////////////////////////////////////
 
@@ -211,7 +211,7 @@ func (o *OrdinaryExtent) ExtentType() string {
 }
 
 func (o *OrdinaryExtent) FreePage(pageNumber uint32) {
-	offset := pageNumber - 64*o.ExtentNumber
+	offset := pageNumber - uint32(common.ExtentPages())*o.ExtentNumber
 	o.XDESEntryWrapper.XdesDescPageMap[byte(offset)] = true
 	buff := NewAllocatedPage(pageNumber).ToByte()
 	if !o.isInit {
@@ -228,7 +228,7 @@ func (o *OrdinaryExtent) AllocateNewPage(pageType int, tuple tuple.TableRowTuple
 
 	//计算pageNo
 	//
-	pageNo := uint32(offset) + o.ExtentNumber*256
+	pageNo := uint32(offset) + o.ExtentNumber*uint32(common.ExtentPages())
 
 	switch pageType {
 	case common.FILE_PAGE_INDEX:
@@ -302,6 +302,6 @@ func NewSecondaryPrimaryExtent(initPageNumber uint32) Extent {
 	otherIBDExtent := new(SecondaryPrimaryExtent)
 	otherIBDExtent.XDesPage = NewXDesWrapper(initPageNumber)
 	otherIBDExtent.IBufPages = NewIBuf(initPageNumber + 1)
-	otherIBDExtent.ExtentId = int64(initPageNumber >> 6)
+	otherIBDExtent.ExtentId = int64(initPageNumber / uint32(common.ExtentPages()))
 	return otherIBDExtent
 }
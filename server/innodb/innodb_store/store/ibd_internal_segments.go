@@ -35,9 +35,9 @@ func (i *InternalSegment) GetStatsCost(startPageNo, endPageNo uint32) map[string
 	//
 	var extentFullList = i.inode.SegFullExtentMap[i.segmentId]
 
-	extentStartNumber := startPageNo >> 6
-	extentEndNumber := endPageNo >> 6
-	resultMap["BLOCKS"] = int64((extentEndNumber - extentStartNumber) * 64)
+	extentStartNumber := startPageNo / uint32(common.ExtentPages())
+	extentEndNumber := endPageNo / uint32(common.ExtentPages())
+	resultMap["BLOCKS"] = int64(extentEndNumber-extentStartNumber) * int64(common.ExtentPages())
 	if extentNotFullList != nil {
 		nBlocks := extentNotFullList.RangeCost(extentStartNumber, extentEndNumber)
 		resultMap["BLOCKS"] = int64(nBlocks)
@@ -40,10 +40,10 @@ func (d *DataSegment) GetStatsCost(startPageNo, endPageNo uint32) map[string]int
 	//
 	var extentFullList = d.inode.SegFullExtentMap[d.segmentId]
 
-	extentStartNumber := startPageNo >> 6
+	extentStartNumber := startPageNo / uint32(common.ExtentPages())
 
-	extentEndNumber := endPageNo >> 6
-	resultMap["BLOCKS"] = int64((extentEndNumber - extentStartNumber) * 64)
+	extentEndNumber := endPageNo / uint32(common.ExtentPages())
+	resultMap["BLOCKS"] = int64(extentEndNumber-extentStartNumber) * int64(common.ExtentPages())
 	resultMap["nROWS"] = int64(0)
 	if extentNotFullList != nil {
 		nBlocks := extentNotFullList.RangeCost(extentStartNumber, extentEndNumber)
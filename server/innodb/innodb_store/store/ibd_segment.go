@@ -44,3 +44,19 @@ type Segment interface {
 
 	GetSegmentHeader() *segs.SegmentHeader
 }
+
+// segmentPages returns the number of pages allocated to seg, 0 when it
+// isn't the segment of an index.
+func segmentPages(seg Segment) int64 {
+	switch s := seg.(type) {
+	case *DataSegment:
+		if s.inode != nil {
+			return s.inode.segmentPages(s.segmentId)
+		}
+	case *InternalSegment:
+		if s.inode != nil {
+			return s.inode.segmentPages(s.segmentId)
+		}
+	}
+	return 0
+}
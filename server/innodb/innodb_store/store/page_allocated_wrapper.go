@@ -9,7 +9,7 @@ import (
 type Allocated struct {
 	FileHeader pages.FileHeader
 
-	body []byte //16384-38-8
+	body []byte //页面大小-38-8
 
 	FileTrailer pages.FileTrailer
 }
@@ -33,7 +33,7 @@ func (a *Allocated) GetFileTrailer() *pages.FileTrailer {
 //用于实现
 func NewAllocatedPage(pageNumber uint32) IPageWrapper {
 	var allocated = new(Allocated)
-	allocated.body = make([]byte, 16384-38-8)
+	allocated.body = make([]byte, common.PageSize()-38-8)
 	allocated.FileHeader = pages.NewFileHeader()
 	allocated.FileHeader.WritePageFileType(common.FILE_PAGE_TYPE_ALLOCATED)
 	allocated.FileHeader.WritePageOffset(pageNumber)
@@ -42,7 +42,7 @@ func NewAllocatedPage(pageNumber uint32) IPageWrapper {
 }
 func NewAllocatedPageByBytes(spaceId uint32, pageNumber uint32) IPageWrapper {
 	var allocated = new(Allocated)
-	allocated.body = make([]byte, 16384-38-8)
+	allocated.body = make([]byte, common.PageSize()-38-8)
 	allocated.FileHeader = pages.NewFileHeader()
 	allocated.FileHeader.WritePageFileType(common.FILE_PAGE_TYPE_ALLOCATED)
 	allocated.FileHeader.WritePageOffset(pageNumber)
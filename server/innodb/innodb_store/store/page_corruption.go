@@ -0,0 +1,91 @@
+package store
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/innodb_store/store/storebytes/pages"
+)
+
+/**
+页面损坏
+
+从磁盘读入页面时按 innodb_checksum_algorithm 校验页面，校验失败时记录错误日志，之后：
+	innodb_corrupt_page_action=error 把表空间标记为损坏，读这个表的语句返回 ER_CRASHED_ON_USAGE，直到重启
+	innodb_corrupt_page_action=log 只记录日志，继续使用读到的页面
+**/
+
+// CorruptPageAction is the value of innodb_corrupt_page_action.
+type CorruptPageAction int32
+
+// Values of innodb_corrupt_page_action.
+const (
+	CorruptPageError CorruptPageAction = iota
+	CorruptPageLog
+)
+
+var corruptPageActionNames = []string{"error", "log"}
+
+func (a CorruptPageAction) String() string {
+	return corruptPageActionNames[a]
+}
+
+// ParseCorruptPageAction returns the value of innodb_corrupt_page_action
+// called name, in any case.
+func ParseCorruptPageAction(name string) (CorruptPageAction, error) {
+	for i, aName := range corruptPageActionNames {
+		if strings.EqualFold(name, aName) {
+			return CorruptPageAction(i), nil
+		}
+	}
+	return 0, errors.Errorf("unknown innodb_corrupt_page_action %s", name)
+}
+
+var corruptPageAction int32
+
+// GetCorruptPageAction returns what is done when a page read fails its
+// checksum.
+func GetCorruptPageAction() CorruptPageAction {
+	return CorruptPageAction(atomic.LoadInt32(&corruptPageAction))
+}
+
+// SetCorruptPageAction changes what is done when a page read fails its
+// checksum.
+func SetCorruptPageAction(a CorruptPageAction) {
+	atomic.StoreInt32(&corruptPageAction, int32(a))
+}
+
+// corruptSpaces are the ids of the spaces with a corrupted page.
+var corruptSpaces sync.Map
+
+// MarkSpaceCorrupted marks the space spaceId corrupted, so that the
+// statements reading its table fail.
+func MarkSpaceCorrupted(spaceId uint32) {
+	corruptSpaces.Store(spaceId, struct{}{})
+}
+
+// IsSpaceCorrupted reports whether a page of the space spaceId failed its
+// checksum with innodb_corrupt_page_action=error.
+func IsSpaceCorrupted(spaceId uint32) bool {
+	_, ok := corruptSpaces.Load(spaceId)
+	return ok
+}
+
+// verifyPage checks the checksum of page pageNo of the space spaceId read
+// from file. It returns an error when the page is corrupted and
+// innodb_corrupt_page_action is error.
+func verifyPage(spaceId uint32, pageNo uint32, file string, content []byte) error {
+	if pages.VerifyChecksum(content) {
+		return nil
+	}
+	err := errors.Errorf("page %d of %s is corrupted, wrong checksum", pageNo, file)
+	log.Error(err)
+	if GetCorruptPageAction() == CorruptPageLog {
+		return nil
+	}
+	MarkSpaceCorrupted(spaceId)
+	return err
+}
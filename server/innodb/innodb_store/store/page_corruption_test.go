@@ -0,0 +1,53 @@
+package store
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/common"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/innodb_store/store/storebytes/blocks"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/innodb_store/store/storebytes/pages"
+)
+
+func TestLoadCorruptedPage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "page_corruption")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := blocks.NewBlockFile(dir, "t1.ibd", 4*common.PAGE_SIZE)
+	file.CreateFile()
+	defer file.Close()
+	ts := &UnSysTableSpace{spaceId: 901, blockFile: file}
+
+	data := bytes.Repeat([]byte("xmysql"), common.PAGE_SIZE/6+1)[:common.PAGE_SIZE]
+	file.WriteContentByPage(1, data)
+	file.WriteContentByPage(2, data)
+	if _, err = ts.LoadPageByPageNumber(1); err != nil || IsSpaceCorrupted(901) {
+		t.Fatalf("expect the page written to be read, got %v", err)
+	}
+	// A page never written is not corrupted.
+	if _, err = ts.LoadPageByPageNumber(3); err != nil {
+		t.Fatal(err)
+	}
+
+	// A byte of page 2 changes on disk.
+	file.WriteFileBySeekStart(2*common.PAGE_SIZE+100, []byte{0})
+	SetCorruptPageAction(CorruptPageLog)
+	if _, err = ts.LoadPageByPageNumber(2); err != nil || IsSpaceCorrupted(901) {
+		t.Fatalf("expect the corruption only logged, got %v", err)
+	}
+	SetCorruptPageAction(CorruptPageError)
+	if _, err = ts.LoadPageByPageNumber(2); err == nil || !IsSpaceCorrupted(901) {
+		t.Fatal("expect the space marked corrupted")
+	}
+
+	// With none the pages aren't checked.
+	pages.SetChecksumAlgorithm(pages.ChecksumNone)
+	defer pages.SetChecksumAlgorithm(pages.ChecksumCRC32)
+	if _, err = ts.LoadPageByPageNumber(2); err != nil {
+		t.Fatal(err)
+	}
+}
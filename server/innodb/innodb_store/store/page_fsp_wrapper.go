@@ -1,7 +1,6 @@
 package store
 
 import (
-	"bytes"
 	"github.com/zhukovaskychina/xmysql-server/server/common"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/innodb_store/store/storebytes/pages"
 )
@@ -29,23 +28,13 @@ func (fsp *Fsp) GetXDesEntryWrapper(id int32) *XDESEntryWrapper {
 	return fsp.xdesEntryMap[id]
 }
 
-//TODO 需要将FSPwrapper 和Fsp之间做更好的衔接
+// NewFsp wraps an FSP_HDR page, with the XDES entries it has.
 func NewFsp(fspHrdBinaryPage *pages.FspHrdBinaryPage) IPageWrapper {
 	var fsp = new(Fsp)
 	fsp.fspHrdBinaryPage = fspHrdBinaryPage
-	fsp.xdesEntryMap = make([]*XDESEntryWrapper, 256)
-	for i := 0; i < 256; i++ {
-
-		xdesId := util.ReadUB8Byte2Long(fspHrdBinaryPage.XDESEntrys[i].XDesId)
-		currentXDesEntry := NewXDesEntryWrapper(xdesId, 0, 0, 0, 0, fsp)
-		currentXDesEntry.DescPage(0, false)
-		for m := 0; m < 16; m++ {
-			currentXDesEntry.DescPage(uint8(m), false)
-		}
-		for j := 16; j < 64; j++ {
-			currentXDesEntry.DescPage(uint8(j), true)
-		}
-		fsp.xdesEntryMap[i] = NewXDesEntryWrapper(xdesId, 0, 0, 0, 0, fsp)
+	fsp.xdesEntryMap = make([]*XDESEntryWrapper, len(fspHrdBinaryPage.XDESEntrys))
+	for i := range fspHrdBinaryPage.XDESEntrys {
+		fsp.xdesEntryMap[i] = NewXDesEntryWrapperByEntry(fspHrdBinaryPage.XDESEntrys[i], fsp)
 	}
 	return fsp
 }
@@ -54,8 +43,8 @@ func NewFspInitialize(spaceId uint32) IPageWrapper {
 	var fsp = new(Fsp)
 	fspHrdBinaryPage := pages.NewFspHrdPage(spaceId)
 	fsp.fspHrdBinaryPage = fspHrdBinaryPage
-	fsp.xdesEntryMap = make([]*XDESEntryWrapper, 256)
-	for i := 0; i < 256; i++ {
+	fsp.xdesEntryMap = make([]*XDESEntryWrapper, len(fspHrdBinaryPage.XDESEntrys))
+	for i := range fspHrdBinaryPage.XDESEntrys {
 
 		xdesId := util.ReadUB8Byte2Long(fspHrdBinaryPage.XDESEntrys[i].XDesId)
 		currentXDesEntry := NewXDesEntryWrapper(xdesId, 0, 0, 0, 0, fsp)
@@ -65,7 +54,7 @@ func NewFspInitialize(spaceId uint32) IPageWrapper {
 			for m := 0; m < 18; m++ {
 				currentXDesEntry.DescPage(uint8(m), false)
 			}
-			for j := 18; j < 64; j++ {
+			for j := 18; j < common.ExtentPages(); j++ {
 				currentXDesEntry.DescPage(uint8(j), true)
 			}
 			currentXDesEntry.SetDesState(common.XDES_FREE_FRAG)
@@ -89,8 +78,8 @@ func NewFspByLoadBytes(content []byte) IPageWrapper {
 	fspBinary.FileTrailer = pages.NewFileTrailer()
 
 	fspBinary.LoadFileHeader(content[0:38])
-	fspBinary.LoadFileTrailer(content[16384-8 : 16384])
-	fspBinary.EmptySpace = content[16384-8-5986 : 16384-8]
+	fspBinary.LoadFileTrailer(content[len(content)-8:])
+	fspBinary.EmptySpace = content[len(content)-8-pages.FspHrdEmptySpaceSize() : len(content)-8]
 	//初始化
 	fspBinary.FileSpaceHeader = &pages.FileSpaceHeader{
 		SpaceId:                 content[38:42],
@@ -106,15 +95,11 @@ func NewFspByLoadBytes(content []byte) IPageWrapper {
 		SegFullINodesList:       content[118:134],
 		SegFreeINodesList:       content[134:150],
 	}
-	fspBinary.XDESEntrys = make([]pages.XDESEntry, 256)
-	//复盘XDESEntry，一共256个
-	for k, _ := range fspBinary.XDESEntrys {
-		fspBinary.XDESEntrys[k] = pages.XDESEntry{
-			XDesId:       content[150+k*20 : 158+k*20],
-			XDesFlstNode: content[158+k*20 : 170+k*20],
-			XDesState:    content[170+k*20 : 174+k*20],
-			XDesBitMap:   content[174+k*20 : 190+k*20],
-		}
+	fspBinary.XDESEntrys = make([]pages.XDESEntry, common.ExtentsPerXDesPage())
+	//复盘XDESEntry，16K 页面一共256个
+	for k := range fspBinary.XDESEntrys {
+		offset := pages.FspHrdXDesArrayOffset + k*common.XDesEntrySize()
+		fspBinary.XDESEntrys[k] = pages.ParseXDesEntry(content[offset : offset+common.XDesEntrySize()])
 	}
 
 	return NewFsp(fspBinary)
@@ -126,10 +111,10 @@ func (fsp *Fsp) SetFreeLimit(freePageNo uint32) {
 }
 
 func (fsp *Fsp) SetXDesEntryInfo(extentNumber uint32, wrapper *XDESEntryWrapper) {
-	fsp.xdesEntryMap[extentNumber] = wrapper
-	if extentNumber > 256 {
+	if extentNumber >= uint32(len(fsp.xdesEntryMap)) {
 		panic("区间号错误")
 	}
+	fsp.xdesEntryMap[extentNumber] = wrapper
 	fsp.fspHrdBinaryPage.XDESEntrys[extentNumber] = wrapper.ToXDesEntry()
 
 }
@@ -156,6 +141,16 @@ func (fsp *Fsp) SetFspSize(size uint32) {
 	fsp.fspHrdBinaryPage.FileSpaceHeader.Size = util.ConvertUInt4Bytes(size)
 }
 
+// GetSpaceFlags returns FSP_SPACE_FLAGS.
+func (fsp *Fsp) GetSpaceFlags() pages.SpaceFlags {
+	return pages.ParseSpaceFlags(util.ReadUB4Byte2UInt32(fsp.fspHrdBinaryPage.FileSpaceHeader.SpaceFlags))
+}
+
+// SetSpaceFlags changes FSP_SPACE_FLAGS.
+func (fsp *Fsp) SetSpaceFlags(flags pages.SpaceFlags) {
+	fsp.fspHrdBinaryPage.FileSpaceHeader.SpaceFlags = util.ConvertUInt4Bytes(flags.ToUint32())
+}
+
 //在空闲的Extent上最小的尚未被初始化的Page的PageNumber
 func (fsp *Fsp) GetFspFreeLimit() uint32 {
 	return util.ReadUB4Byte2UInt32(fsp.fspHrdBinaryPage.FileSpaceHeader.FreeLimit)
@@ -242,7 +237,7 @@ func (fsp *Fsp) GetFreeSegINodeBaseInfo() CommonNodeInfo {
 }
 
 func (fsp *Fsp) GetSerializeBytes() []byte {
-	for i := 0; i < 256; i++ {
+	for i := range fsp.xdesEntryMap {
 		fsp.fspHrdBinaryPage.XDESEntrys[i] = fsp.xdesEntryMap[i].ToXDesEntry()
 	}
 	return fsp.fspHrdBinaryPage.GetSerializeBytes()
@@ -272,7 +267,7 @@ type XDESEntryWrapper struct {
 	PreNodeOffset      uint16         //偏移量
 	NextNodePageNumber uint32         //下一个Extent
 	NextNodeoffset     uint16         //偏移量
-	XdesDescPageMap    map[uint8]bool //2个bit 表示一个Page，2个表示一个page，1个表示是否空闲，1个空 一共64个页面
+	XdesDescPageMap    map[uint8]bool //2个bit 表示一个Page，2个表示一个page，1个表示是否空闲，1个空 16K 页面一共64个页面
 
 	XDesState common.XDES_STATE
 }
@@ -286,13 +281,25 @@ func NewXDesEntryWrapper(XdesId uint64, PreNodePageNo uint32, PreNodeOffset uint
 	xdesEntryWrapper.NextNodeoffset = NextNodeOffset
 	xdesEntryWrapper.XdesDescPageMap = make(map[uint8]bool)
 	xdesEntryWrapper.XDesState = common.XDES_FREE
-	for i := 0; i < 64; i++ {
+	for i := 0; i < common.ExtentPages(); i++ {
 		xdesEntryWrapper.XdesDescPageMap[byte(i)] = true
 	}
 	xdesEntryWrapper.wrapper = wrapper
 	return xdesEntryWrapper
 }
 
+// NewXDesEntryWrapperByEntry wraps an XDES entry read from a page.
+func NewXDesEntryWrapperByEntry(entry pages.XDESEntry, wrapper IPageWrapper) *XDESEntryWrapper {
+	xdesEntryWrapper := NewXDesEntryWrapper(util.ReadUB8Byte2Long(entry.XDesId),
+		util.ReadUB4Byte2UInt32(entry.XDesFlstNode[0:4]), util.ReadUB2Byte2Int(entry.XDesFlstNode[4:6]),
+		util.ReadUB4Byte2UInt32(entry.XDesFlstNode[6:10]), util.ReadUB2Byte2Int(entry.XDesFlstNode[10:12]), wrapper)
+	xdesEntryWrapper.XDesState = common.XDES_STATE(util.ReadUB4Byte2UInt32(entry.XDesState))
+	for i := 0; i < common.ExtentPages(); i++ {
+		xdesEntryWrapper.XdesDescPageMap[uint8(i)] = entry.XDesBitMap[i/4]&(1<<(uint(i)%4*2)) != 0
+	}
+	return xdesEntryWrapper
+}
+
 func (xdes *XDESEntryWrapper) SetDesState(state common.XDES_STATE) {
 	xdes.XDesState = state
 }
@@ -302,7 +309,7 @@ func (xdes *XDESEntryWrapper) DescPage(pageOffset uint8, isFree bool) {
 }
 
 func (xdes *XDESEntryWrapper) GetNearsFreePage() uint8 {
-	for i := 0; i < 64; i++ {
+	for i := 0; i < common.ExtentPages(); i++ {
 		v := xdes.XdesDescPageMap[uint8(i)]
 		if v {
 			return uint8(i)
@@ -321,18 +328,14 @@ func (xdes *XDESEntryWrapper) ToBytes() []byte {
 	buff = append(buff, util.ConvertUInt4Bytes(xdes.NextNodePageNumber)...)
 	buff = append(buff, util.ConvertUInt2Bytes(xdes.NextNodeoffset)...)
 	buff = append(buff, util.ConvertUInt4Bytes(uint32(xdes.XDesState))...)
-	var buffer bytes.Buffer
-	for k, v := range xdes.XdesDescPageMap {
-		bitKey := util.ConvertByte2Bits(k)
-		buffer.WriteString(util.Substr(bitKey, 4, 8))
-		if v {
-			buffer.WriteString("1")
-		} else {
-			buffer.WriteString("0")
+	// 每个页面2个bit，低位的 XDES_FREE_BIT 表示页面空闲
+	bitmap := make([]byte, common.XDesBitmapSize())
+	for page, free := range xdes.XdesDescPageMap {
+		if free && int(page) < common.ExtentPages() {
+			bitmap[int(page)/4] |= 1 << (uint(page) % 4 * 2)
 		}
-		buffer.WriteString("0")
 	}
-	buff = append(buff, util.ConvertBits2Bytes(buffer.String())...)
+	buff = append(buff, bitmap...)
 
 	return buff
 }
@@ -343,6 +346,6 @@ func (xdes *XDESEntryWrapper) ToXDesEntry() pages.XDESEntry {
 		XDesId:       content[0:8],
 		XDesFlstNode: content[8:20],
 		XDesState:    content[20:24],
-		XDesBitMap:   content[24:40],
+		XDesBitMap:   content[common.XDesBitmapOffset:common.XDesEntrySize()],
 	}
 }
@@ -26,9 +26,9 @@ func NewIBufByLoadBytes(content []byte) *IBuf {
 	iBufBitMapPage.FileTrailer = pages.NewFileTrailer()
 
 	iBufBitMapPage.LoadFileHeader(content[0:38])
-	iBufBitMapPage.ChangeBufferBitMap = content[38 : 38+9192]
-	iBufBitMapPage.EmptySpace = content[16384-8-8146 : 16384-8]
-	iBufBitMapPage.LoadFileTrailer(content[16384-8 : 16384])
+	iBufBitMapPage.ChangeBufferBitMap = content[38 : 38+pages.IBufBitmapSize()]
+	iBufBitMapPage.EmptySpace = content[38+pages.IBufBitmapSize() : len(content)-8]
+	iBufBitMapPage.LoadFileTrailer(content[len(content)-8:])
 
 	return &IBuf{iBufPage: *iBufBitMapPage}
 }
@@ -159,7 +159,8 @@ func AddRow(sr **SlotRows, row basic.Row) {
 			heapNo = v.GetRowLength() + heapNo
 		}
 		slotRow.MaxRow.SetHeapNo(heapNo)
-		slotRow.RowOffSet = slotRow.MaxRow.GetHeapNo()
+		//记录头的heap_no只有13个bit，8K以上的偏移量放不下，槽位用2个字节的偏移量
+		slotRow.RowOffSet = heapNo
 		heapNo = slotRow.MaxRow.GetRowLength() + heapNo
 		if i == (*sr).GetNDirs()-1 {
 			slotRow.MaxRow.SetNextRowOffset(0)
@@ -424,7 +425,7 @@ func NewPageIndexByLoadBytes(content []byte) IPageWrapper {
 	indexPage.FileTrailer = pages.NewFileTrailer()
 
 	indexPage.LoadFileHeader(content[0:38])
-	indexPage.LoadFileTrailer(content[16384-8 : 16384])
+	indexPage.LoadFileTrailer(content[len(content)-8:])
 
 	indexPage.ParsePageHeader(content[38 : 38+56])
 	indexPage.ParseInfimumSupermum(content[38+56 : 38+56+26])
@@ -452,7 +453,7 @@ func NewPageIndexByLoadBytesWithTuple(content []byte, tuple tuple.TableRowTuple)
 	indexPage.FileTrailer = pages.NewFileTrailer()
 
 	indexPage.LoadFileHeader(content[0:38])
-	indexPage.LoadFileTrailer(content[16384-8 : 16384])
+	indexPage.LoadFileTrailer(content[len(content)-8:])
 
 	indexPage.ParsePageHeader(content[38 : 38+56])
 	indexPage.ParseInfimumSupermum(content[38+56 : 38+56+26])
@@ -549,7 +550,7 @@ func (i *Index) AddRow(row basic.Row) {
 	i.IndexPage.UserRecords = rowData
 	i.IndexPage.PageDirectory = slotData
 
-	i.IndexPage.FreeSpace = util.AppendByte(common.PAGE_SIZE -
+	i.IndexPage.FreeSpace = util.AppendByte(common.PageSize() -
 		common.PAGE_FILE_HEADER_SIZE -
 		common.PAGE_PAGE_HEADER_SIZE -
 		common.PAGE_INFIMUMSUPERUM_SIZE -
@@ -572,7 +573,7 @@ func (i *Index) IsFullParams(row basic.Row) bool {
 
 func (i *Index) IsFull(row basic.Row) bool {
 
-	var rest = common.PAGE_SIZE - common.PAGE_FILE_HEADER_SIZE -
+	var rest = common.PageSize() - common.PAGE_FILE_HEADER_SIZE -
 		common.PAGE_FILE_TRAILER_SIZE - common.PAGE_PAGE_HEADER_SIZE - common.PAGE_INFIMUMSUPERUM_SIZE - len(i.IndexPage.UserRecords) - len(i.IndexPage.PageDirectory)
 	return rest < int(row.GetRowLength())
 }
@@ -615,6 +616,10 @@ func (i *Index) parseIndexBytes2SlotRows(content []byte, pageType string) {
 	var startOffset uint16 = infimumRow.GetHeapNo()
 
 	var endOffset uint16 = supremumRow.GetHeapNo()
+	//supremum的heap_no在8K以上时溢出，以最后一个槽位为准
+	if pageDir := i.IndexPage.PageDirectory; len(pageDir) >= 2 {
+		endOffset = util.ReadUB2Byte2Int(pageDir[len(pageDir)-2:])
+	}
 	for nextOffset != 0 {
 
 		startOffset = startOffset + nextOffset
@@ -622,7 +627,7 @@ func (i *Index) parseIndexBytes2SlotRows(content []byte, pageType string) {
 		if startOffset == endOffset {
 			break
 		}
-		if startOffset == uint16(16384) {
+		if int(startOffset) == common.PageSize() {
 			break
 		}
 
@@ -739,7 +744,7 @@ func (i *Index) Truncate() {
 	i.IndexPage.UserRecords = make([]byte, 0)
 	i.IndexPage.PageHeader.PageNRecs = util.ConvertUInt2Bytes(0)
 	i.IndexPage.PageDirectory = make([]byte, 0)
-	i.IndexPage.FreeSpace = util.AppendByte(common.PAGE_SIZE - common.PAGE_FILE_HEADER_SIZE - common.PAGE_INFIMUMSUPERUM_SIZE - common.PAGE_FILE_TRAILER_SIZE)
+	i.IndexPage.FreeSpace = util.AppendByte(common.PageSize() - common.PAGE_FILE_HEADER_SIZE - common.PAGE_INFIMUMSUPERUM_SIZE - common.PAGE_FILE_TRAILER_SIZE)
 }
 
 func (i *Index) TruncateByIndex(index int) {
@@ -750,8 +755,25 @@ func (i *Index) TruncateByIndex(index int) {
 	i.IndexPage.PageHeader.PageNRecs = util.ConvertUInt2Bytes(recordSize)
 	i.IndexPage.UserRecords = rowData
 	i.IndexPage.PageDirectory = slotData
-	i.IndexPage.FreeSpace = util.AppendByte(common.PAGE_SIZE - common.PAGE_FILE_HEADER_SIZE - common.PAGE_INFIMUMSUPERUM_SIZE - common.PAGE_FILE_TRAILER_SIZE - len(rowData) - len(slotData))
+	i.IndexPage.FreeSpace = util.AppendByte(common.PageSize() - common.PAGE_FILE_HEADER_SIZE - common.PAGE_INFIMUMSUPERUM_SIZE - common.PAGE_FILE_TRAILER_SIZE - len(rowData) - len(slotData))
+
+}
 
+// ReplaceRows makes rows, in key order, the records of the page in place
+// of the ones it has. It fails when they don't fit in the page.
+func (i *Index) ReplaceRows(rows []basic.Row) error {
+	var slotRowData = NewSlotRows()
+	i.SlotRowData = &slotRowData
+	i.IndexPage.PageHeader.PageNRecs = util.ConvertUInt2Bytes(0)
+	i.IndexPage.UserRecords = make([]byte, 0)
+	i.IndexPage.PageDirectory = make([]byte, 0)
+	for _, row := range rows {
+		if i.IsFull(row) {
+			return fmt.Errorf("records overflow page %d", i.GetPageNumber())
+		}
+		i.AddRow(row)
+	}
+	return nil
 }
 
 //根据Key值查找
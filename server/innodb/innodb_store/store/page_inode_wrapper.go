@@ -10,7 +10,7 @@ import "github.com/zhukovaskychina/xmysql-server/util"
 //TODO 这里需要加强对重启的后复盘操作
 //用于管理数据文件中的segment,用于存储各种INodeEntry
 //第三个page的类型FIL_PAGE_INODE
-//每个Inode页面可以存储85个记录，
+//每个Inode页面可以存储85个记录（16K 页面），见 common.INodesPerPage
 //PreNodePageNumber  []byte //4个字节	表示指向前一个INode页面号
 //PreNodeOffset      []byte //2个字节 65536-1
 //NextNodePageNumber []byte //4个字节  表示指向后一个INode页面号
@@ -119,7 +119,7 @@ func (iNode *INode) AllocateINodeEntry(segmentId uint64) *pages.INodeEntry {
 func (iNode *INode) getCloseZeroSeg() int {
 
 	var result = 0
-	for i := 0; i < 85; i++ {
+	for i := range iNode.INodePage.INodeEntries {
 		if (util.ReadUB4Byte2UInt32(iNode.INodePage.INodeEntries[i].MagicNumber)) != 0x5D669D2 {
 			result = i
 			break
@@ -171,7 +171,7 @@ func (iNode *INode) GetFreeExtentList() {
 				//递归完成
 
 				nextXDesEntryPage, _ := iNode.ts.LoadPageByPageNumber(nextNodePgNo)
-				nextXDESEntry := nextXDesEntryPage[nextOffset : nextOffset+40]
+				nextXDESEntry := nextXDesEntryPage[nextOffset : int(nextOffset)+common.XDesEntrySize()]
 				//
 				filePageTypeBytes := nextXDesEntryPage[24:26]
 				filePageType := util.ReadUB2Byte2Int(filePageTypeBytes)
@@ -182,7 +182,7 @@ func (iNode *INode) GetFreeExtentList() {
 						//计算区号
 						//计算逻辑，根据偏移号，计算区号
 						//获得当前区的相对位置
-						currentPageNodeOffsetNo := (nextOffset - 150) / 40
+						currentPageNodeOffsetNo := (nextOffset - pages.FspHrdXDesArrayOffset) / uint16(common.XDesEntrySize())
 
 						//	u := uint32(currentPageNodeOffsetNo) + nextNodePageNo
 						var xdesEntryWrapper *XDESEntryWrapper
@@ -202,8 +202,8 @@ func (iNode *INode) GetFreeExtentList() {
 						}
 
 						//
-						startNo := uint32(currentPageNodeOffsetNo * 64)
-						endNo := uint32((currentPageNodeOffsetNo + 1) * 64)
+						startNo := uint32(currentPageNodeOffsetNo) * uint32(common.ExtentPages())
+						endNo := uint32(currentPageNodeOffsetNo+1) * uint32(common.ExtentPages())
 						//加载extent 到bufferPool
 						var pageArrayWrapper = make([]uint32, 0)
 
@@ -238,7 +238,7 @@ func (iNode *INode) GetFreeExtentList() {
 
 				bufferblock := iNode.bufferPool.GetPageBlock(iNode.spaceId, nextNodePgNo)
 				nextXDesEntryPage := *bufferblock.Frame
-				nextXDESEntry := nextXDesEntryPage[nextOffset : nextOffset+40]
+				nextXDESEntry := nextXDesEntryPage[nextOffset : int(nextOffset)+common.XDesEntrySize()]
 				//
 				filePageTypeBytes := nextXDesEntryPage[24:26]
 				filePageType := util.ReadUB2Byte2Int(filePageTypeBytes)
@@ -249,7 +249,7 @@ func (iNode *INode) GetFreeExtentList() {
 						//计算区号
 						//计算逻辑，根据偏移号，计算区号
 						//获得当前区的相对位置
-						currentPageNodeOffsetNo := (nextOffset - 150) / 40
+						currentPageNodeOffsetNo := (nextOffset - pages.FspHrdXDesArrayOffset) / uint16(common.XDesEntrySize())
 
 						//	u := uint32(currentPageNodeOffsetNo) + nextNodePageNo
 						var xdesEntryWrapper *XDESEntryWrapper
@@ -262,8 +262,8 @@ func (iNode *INode) GetFreeExtentList() {
 						//加载普通区
 						extent := NewOrdinaryExtent(0, uint32(currentPageNodeOffsetNo), xdesEntryWrapper, iNode.bufferPool)
 
-						startNo := uint32(currentPageNodeOffsetNo * 64)
-						endNo := uint32((currentPageNodeOffsetNo + 1) * 64)
+						startNo := uint32(currentPageNodeOffsetNo) * uint32(common.ExtentPages())
+						endNo := uint32(currentPageNodeOffsetNo+1) * uint32(common.ExtentPages())
 						iNode.bufferPool.RangePageLoad(0, startNo, endNo)
 						//加载extent 到bufferPool
 						var pageArrayWrapper = make([]uint32, 0)
@@ -320,7 +320,7 @@ func (iNode *INode) GetFullExtentList() {
 				//递归完成
 
 				nextXDesEntryPage, _ := iNode.ts.LoadPageByPageNumber(nextNodePgNo)
-				nextXDESEntry := nextXDesEntryPage[nextOffset : nextOffset+40]
+				nextXDESEntry := nextXDesEntryPage[nextOffset : int(nextOffset)+common.XDesEntrySize()]
 				//
 				filePageTypeBytes := nextXDesEntryPage[24:26]
 				filePageType := util.ReadUB2Byte2Int(filePageTypeBytes)
@@ -331,7 +331,7 @@ func (iNode *INode) GetFullExtentList() {
 						//计算区号
 						//计算逻辑，根据偏移号，计算区号
 						//获得当前区的相对位置
-						currentPageNodeOffsetNo := (nextOffset - 150) / 40
+						currentPageNodeOffsetNo := (nextOffset - pages.FspHrdXDesArrayOffset) / uint16(common.XDesEntrySize())
 
 						//	u := uint32(currentPageNodeOffsetNo) + nextNodePageNo
 						var xdesEntryWrapper *XDESEntryWrapper
@@ -351,8 +351,8 @@ func (iNode *INode) GetFullExtentList() {
 						}
 
 						//
-						startNo := uint32(currentPageNodeOffsetNo * 64)
-						endNo := uint32((currentPageNodeOffsetNo + 1) * 64)
+						startNo := uint32(currentPageNodeOffsetNo) * uint32(common.ExtentPages())
+						endNo := uint32(currentPageNodeOffsetNo+1) * uint32(common.ExtentPages())
 						//加载extent 到bufferPool
 						var pageArrayWrapper = make([]uint32, 0)
 
@@ -387,7 +387,7 @@ func (iNode *INode) GetFullExtentList() {
 
 				bufferblock := iNode.bufferPool.GetPageBlock(iNode.spaceId, nextNodePgNo)
 				nextXDesEntryPage := *bufferblock.Frame
-				nextXDESEntry := nextXDesEntryPage[nextOffset : nextOffset+40]
+				nextXDESEntry := nextXDesEntryPage[nextOffset : int(nextOffset)+common.XDesEntrySize()]
 				//
 				filePageTypeBytes := nextXDesEntryPage[24:26]
 				filePageType := util.ReadUB2Byte2Int(filePageTypeBytes)
@@ -398,7 +398,7 @@ func (iNode *INode) GetFullExtentList() {
 						//计算区号
 						//计算逻辑，根据偏移号，计算区号
 						//获得当前区的相对位置
-						currentPageNodeOffsetNo := (nextOffset - 150) / 40
+						currentPageNodeOffsetNo := (nextOffset - pages.FspHrdXDesArrayOffset) / uint16(common.XDesEntrySize())
 
 						//	u := uint32(currentPageNodeOffsetNo) + nextNodePageNo
 						var xdesEntryWrapper *XDESEntryWrapper
@@ -411,8 +411,8 @@ func (iNode *INode) GetFullExtentList() {
 						//加载普通区
 						extent := NewOrdinaryExtent(0, uint32(currentPageNodeOffsetNo), xdesEntryWrapper, iNode.bufferPool)
 
-						startNo := uint32(currentPageNodeOffsetNo * 64)
-						endNo := uint32((currentPageNodeOffsetNo + 1) * 64)
+						startNo := uint32(currentPageNodeOffsetNo) * uint32(common.ExtentPages())
+						endNo := uint32(currentPageNodeOffsetNo+1) * uint32(common.ExtentPages())
 						iNode.bufferPool.RangePageLoad(0, startNo, endNo)
 						//加载extent 到bufferPool
 						var pageArrayWrapper = make([]uint32, 0)
@@ -467,7 +467,7 @@ func (iNode *INode) GetNotFullExtentList() {
 				//递归完成
 
 				nextXDesEntryPage, _ := iNode.ts.LoadPageByPageNumber(nextNodePgNo)
-				nextXDESEntry := nextXDesEntryPage[nextOffset : nextOffset+40]
+				nextXDESEntry := nextXDesEntryPage[nextOffset : int(nextOffset)+common.XDesEntrySize()]
 				//
 				filePageTypeBytes := nextXDesEntryPage[24:26]
 				filePageType := util.ReadUB2Byte2Int(filePageTypeBytes)
@@ -478,7 +478,7 @@ func (iNode *INode) GetNotFullExtentList() {
 						//计算区号
 						//计算逻辑，根据偏移号，计算区号
 						//获得当前区的相对位置
-						currentPageNodeOffsetNo := (nextOffset - 150) / 40
+						currentPageNodeOffsetNo := (nextOffset - pages.FspHrdXDesArrayOffset) / uint16(common.XDesEntrySize())
 
 						//	u := uint32(currentPageNodeOffsetNo) + nextNodePageNo
 						var xdesEntryWrapper *XDESEntryWrapper
@@ -498,8 +498,8 @@ func (iNode *INode) GetNotFullExtentList() {
 						}
 
 						//
-						startNo := uint32(currentPageNodeOffsetNo * 64)
-						endNo := uint32((currentPageNodeOffsetNo + 1) * 64)
+						startNo := uint32(currentPageNodeOffsetNo) * uint32(common.ExtentPages())
+						endNo := uint32(currentPageNodeOffsetNo+1) * uint32(common.ExtentPages())
 						//加载extent 到bufferPool
 						var pageArrayWrapper = make([]uint32, 0)
 
@@ -534,7 +534,7 @@ func (iNode *INode) GetNotFullExtentList() {
 
 				bufferblock := iNode.bufferPool.GetPageBlock(iNode.spaceId, nextNodePgNo)
 				nextXDesEntryPage := *bufferblock.Frame
-				nextXDESEntry := nextXDesEntryPage[nextOffset : nextOffset+40]
+				nextXDESEntry := nextXDesEntryPage[nextOffset : int(nextOffset)+common.XDesEntrySize()]
 				//
 				filePageTypeBytes := nextXDesEntryPage[24:26]
 				filePageType := util.ReadUB2Byte2Int(filePageTypeBytes)
@@ -545,7 +545,7 @@ func (iNode *INode) GetNotFullExtentList() {
 						//计算区号
 						//计算逻辑，根据偏移号，计算区号
 						//获得当前区的相对位置
-						currentPageNodeOffsetNo := (nextOffset - 150) / 40
+						currentPageNodeOffsetNo := (nextOffset - pages.FspHrdXDesArrayOffset) / uint16(common.XDesEntrySize())
 
 						//	u := uint32(currentPageNodeOffsetNo) + nextNodePageNo
 						var xdesEntryWrapper *XDESEntryWrapper
@@ -558,8 +558,8 @@ func (iNode *INode) GetNotFullExtentList() {
 						//加载普通区
 						extent := NewOrdinaryExtent(0, uint32(currentPageNodeOffsetNo), xdesEntryWrapper, iNode.bufferPool)
 
-						startNo := uint32(currentPageNodeOffsetNo * 64)
-						endNo := uint32((currentPageNodeOffsetNo + 1) * 64)
+						startNo := uint32(currentPageNodeOffsetNo) * uint32(common.ExtentPages())
+						endNo := uint32(currentPageNodeOffsetNo+1) * uint32(common.ExtentPages())
 						iNode.bufferPool.RangePageLoad(0, startNo, endNo)
 						//加载extent 到bufferPool
 						var pageArrayWrapper = make([]uint32, 0)
@@ -658,8 +658,8 @@ func NewINodeEntryWrapper(segmentId uint64, wrapper IPageWrapper) *INodeEntryWra
 	var inodeEntry = new(INodeEntryWrapper)
 	inodeEntry.wrapper = wrapper
 	inodeEntry.SegmentId = segmentId
-	inodeEntry.fragmentArray = make([]pages.FragmentArrayEntry, 32)
-	for i := 0; i < 32; i++ {
+	inodeEntry.fragmentArray = make([]pages.FragmentArrayEntry, common.INodeFragSlots())
+	for i := range inodeEntry.fragmentArray {
 		inodeEntry.fragmentArray[i] = pages.FragmentArrayEntry{PageNo: util.AppendByte(4)}
 	}
 
@@ -727,3 +727,31 @@ func (inodeEntry *INodeEntryWrapper) ToINodeEntry() *pages.INodeEntry {
 
 	return &inodeCurrentEntry
 }
+
+// segmentPages returns the number of pages allocated to the segment
+// segmentId: the pages of its fragment array and the 64 pages of each
+// extent on its FREE, NOT_FULL and FULL lists.
+func (iNode *INode) segmentPages(segmentId uint64) int64 {
+	var pages int64
+	var extents int
+	if entry := iNode.SegMap[segmentId]; entry != nil {
+		for _, v := range entry.fragmentArray {
+			if util.ReadUB4Byte2UInt32(v.PageNo) != 0 {
+				pages++
+			}
+		}
+		extents = int(entry.FreeListLength + entry.NotFullListLength + entry.FullListLength)
+	}
+	// The extents allocated since the entry was read are only on the lists
+	// in memory.
+	listed := 0
+	for _, lists := range []map[uint64]*ExtentList{iNode.SegFreeExtentMap, iNode.SegNotExtentMap, iNode.SegFullExtentMap} {
+		if list := lists[segmentId]; list != nil {
+			listed += list.Size()
+		}
+	}
+	if listed > extents {
+		extents = listed
+	}
+	return pages + int64(extents)*int64(common.ExtentPages())
+}
@@ -0,0 +1,151 @@
+package store
+
+import (
+	"fmt"
+	"github.com/zhukovaskychina/xmysql-server/server/common"
+	"github.com/zhukovaskychina/xmysql-server/server/conf"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/innodb_store/store/storebytes/blocks"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/innodb_store/store/storebytes/pages"
+	"strings"
+	"testing"
+)
+
+// The page sizes are set for the whole package, so these tests don't run in
+// parallel.
+
+func TestPageSizeGeometry(t *testing.T) {
+	defer common.SetPageSize(common.PAGE_SIZE)
+	for _, size := range []int{8192, 32768} {
+		if err := common.SetPageSize(size); err != nil {
+			t.Fatal(err)
+		}
+		fsp := NewFspInitialize(5).(*Fsp)
+		blob := pages.NewBlobPage(5, 7, make([]byte, pages.BlobPageDataSize()), pages.FilNull)
+		for name, content := range map[string][]byte{
+			"FSP_HDR":     fsp.GetSerializeBytes(),
+			"INODE":       NewINode(5, 2).(*INode).GetSerializeBytes(),
+			"IBUF_BITMAP": NewIBuf(5).GetSerializeBytes(),
+			"INDEX":       NewPageIndexWithTuple(5, 3, NewSysTableTuple()).(*Index).ToByte(),
+			"TRX_SYS":     pages.NewSysTrxSysPage().GetSerializeBytes(),
+			"RSEG":        pages.NewRollBackPage(6).GetSerializeBytes(),
+			"BLOB":        blob.GetSerializeBytes(),
+		} {
+			if len(content) != size {
+				t.Errorf("%d: %s page has %d bytes", size, name, len(content))
+			}
+		}
+
+		// The FSP_HDR page reads back with all of its XDES entries.
+		loaded := NewFspByLoadBytes(fsp.GetSerializeBytes()).(*Fsp)
+		if len(loaded.xdesEntryMap) != common.ExtentsPerXDesPage() {
+			t.Errorf("%d: expect %d XDES entries, got %d", size, common.ExtentsPerXDesPage(), len(loaded.xdesEntryMap))
+		}
+		xdes := ParseXDesPage(fsp.GetSerializeBytes())
+		for i, entry := range xdes.xdes.XDESEntries {
+			if len(entry.GetSerializeByte()) != common.XDesEntrySize() {
+				t.Fatalf("%d: XDES entry %d has %d bytes", size, i, len(entry.GetSerializeByte()))
+			}
+		}
+		if string(loaded.GetSerializeBytes()) != string(fsp.GetSerializeBytes()) {
+			t.Errorf("%d: FSP_HDR page changed reading it back", size)
+		}
+		if got := common.PageSizeOfSSize(loaded.GetSpaceFlags().PageSSize); got != size {
+			t.Errorf("%d: FSP_SPACE_FLAGS has page size %d", size, got)
+		}
+
+		// The last extent of an XDES page has its entry inside the page, the
+		// next one is on the next XDES page.
+		last := uint32(common.ExtentsPerXDesPage() - 1)
+		if pageNo, offset := xdesAddr(last); pageNo != 0 || int(offset)+common.XDesEntrySize() > size-8 {
+			t.Errorf("%d: extent %d at page %d offset %d", size, last, pageNo, offset)
+		}
+		if pageNo, offset := xdesAddr(last + 1); pageNo != uint32(size) || offset != xdesArrayOffset {
+			t.Errorf("%d: extent %d at page %d offset %d", size, last+1, pageNo, offset)
+		}
+		if spaceInitialPages() != uint32(size) {
+			t.Errorf("%d: a new tablespace has %d pages", size, spaceInitialPages())
+		}
+		if threshold := fieldExternThreshold(); threshold != size/2-200 {
+			t.Errorf("%d: fields are stored off page from %d bytes", size, threshold)
+		}
+	}
+}
+
+func TestPageSizeRows(t *testing.T) {
+	defer common.SetPageSize(common.PAGE_SIZE)
+	for _, size := range []int{8192, 32768} {
+		if err := common.SetPageSize(size); err != nil {
+			t.Fatal(err)
+		}
+		blockFile := blocks.NewBlockFile(t.TempDir(), "t.ibd", 64*int64(size))
+		blockFile.CreateFile()
+		sysTuple := NewSysTableTuple()
+		root := NewPageIndexWithTuple(0, 1, sysTuple).(*Index)
+		if err := blockFile.WriteContentByPage(1, root.ToByte()); err != nil {
+			t.Fatal(err)
+		}
+		tree := NewBtreeAtInit(1, "PRIMARY", nil, nil, root, blockFile, sysTuple, sysTuple)
+
+		// Rows filling most of an 8K root page, which stays a leaf: the tree
+		// has no segments to split it. With 32K pages the records go past
+		// the 8K the 13 bits of heap_no can point to.
+		probe := NewClusterSysIndexLeafRow(sysTuple, false)
+		initSysTableRowForRange("test", "t0000", sysTuple, probe)
+		rows := (8192 - 512) / (int(probe.GetRowLength()) + 1)
+		if size > 8192 {
+			rows = 8192/int(probe.GetRowLength()) + 8
+		}
+		for k := 0; k < rows; k++ {
+			row := NewClusterSysIndexLeafRow(sysTuple, false)
+			initSysTableRowForRange("test", fmt.Sprintf("t%04d", k), sysTuple, row)
+			if err := tree.Add(row.GetPrimaryKey(), row); err != nil {
+				t.Fatalf("%d: %v", size, err)
+			}
+		}
+		if err := tree.CheckConsistency(); err != nil {
+			t.Fatalf("%d: %v", size, err)
+		}
+		err := blockFile.Do(1, func(content []byte) error {
+			if len(content) != size || !pages.IsChecksumValid(content) {
+				return fmt.Errorf("root page of %d bytes with a wrong checksum", len(content))
+			}
+			leaf := NewPageIndexByLoadBytesWithTuple(content, sysTuple).(*Index)
+			list := leaf.SlotRowData.GetRowListWithoutInfiuAndSupremum()
+			if len(list) != rows {
+				return fmt.Errorf("expect %d records, got %d", rows, len(list))
+			}
+			for k, row := range list {
+				want := NewClusterSysIndexLeafRow(sysTuple, false)
+				initSysTableRowForRange("test", fmt.Sprintf("t%04d", k), sysTuple, want)
+				if equal, _ := want.GetPrimaryKey().Equal(row.GetPrimaryKey()); !equal.Raw().(bool) {
+					return fmt.Errorf("record %d has key %v", k, row.GetPrimaryKey().ToString())
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("%d: %v", size, err)
+		}
+		blockFile.Close()
+	}
+}
+
+func TestPageSizeSysTableSpace(t *testing.T) {
+	defer common.SetPageSize(common.PAGE_SIZE)
+	if err := common.SetPageSize(8192); err != nil {
+		t.Fatal(err)
+	}
+	cfg := conf.NewCfg()
+	cfg.DataDir = t.TempDir()
+	cfg.BaseDir = cfg.DataDir
+	NewSysTableSpace(cfg, true)
+	if err := CheckSysTableSpace(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	// The page size can't change once the tablespace is created.
+	common.SetPageSize(32768)
+	if err := CheckSysTableSpace(cfg); err == nil || !strings.Contains(err.Error(), "innodb_page_size 8192") {
+		t.Fatalf("expect the page size to be checked, got %v", err)
+	}
+}
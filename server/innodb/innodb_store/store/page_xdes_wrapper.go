@@ -1,6 +1,7 @@
 package store
 
 import (
+	"github.com/zhukovaskychina/xmysql-server/server/common"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/innodb_store/store/storebytes/pages"
 	"github.com/zhukovaskychina/xmysql-server/util"
 )
@@ -26,17 +27,15 @@ func ParseXDesPage(content []byte) *XDesPageWrapper {
 	xDesPage.FileTrailer = pages.NewFileTrailer()
 
 	xDesPage.LoadFileHeader(content[0:38])
-	xDesPage.LoadFileTrailer(content[16384-8 : 16384])
-	xDesPage.FirstEmptySpace = content[38:150]
-	xDesPage.XDESEntries = make([]pages.XDESEntry, 256)
-	for i := 0; i < 256; i++ {
-		xDesPage.XDESEntries[i].XDesId = content[150+40*i : 158+40*i]
-		xDesPage.XDESEntries[i].XDesFlstNode = content[158+40*i : 170+40*i]
-		xDesPage.XDESEntries[i].XDesState = content[170+40*i : 174+40*i]
-		xDesPage.XDESEntries[i].XDesBitMap = content[174+40*i : 190+40*i]
+	xDesPage.LoadFileTrailer(content[len(content)-8:])
+	xDesPage.FirstEmptySpace = content[38:pages.FspHrdXDesArrayOffset]
+	//复盘XDESEntry，16K 页面一共256个
+	xDesPage.XDESEntries = make([]pages.XDESEntry, common.ExtentsPerXDesPage())
+	for i := range xDesPage.XDESEntries {
+		offset := pages.FspHrdXDesArrayOffset + i*common.XDesEntrySize()
+		xDesPage.XDESEntries[i] = pages.ParseXDesEntry(content[offset : offset+common.XDesEntrySize()])
 	}
-	xDesPage.SecondEmptySpace = content[10390:16376]
-	//复盘XDESEntry，一共255个
+	xDesPage.SecondEmptySpace = content[len(content)-8-pages.FspHrdEmptySpaceSize() : len(content)-8]
 
 	return &XDesPageWrapper{xdes: *xDesPage}
 }
@@ -0,0 +1,53 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/tuple"
+	"github.com/zhukovaskychina/xmysql-server/util"
+)
+
+func TestCharColumnRoundTrip(t *testing.T) {
+	// The charset of a column is kept in its definition in the .frm.
+	var cols []*tuple.FormColumnsWrapper
+	for _, col := range []*tuple.FormColumnsWrapper{
+		{FieldName: "id", FieldType: "INT", FieldLength: 4, NotNull: true},
+		{FieldName: "code", FieldType: "CHAR", FieldLength: 4, FieldCharset: "latin1", FieldCommentValue: "code"},
+		{FieldName: "name", FieldType: "CHAR", FieldLength: 4, FieldCharset: "utf8mb4"},
+		{FieldName: "note", FieldType: "VARCHAR", FieldLength: 16, FieldCharset: "utf8"},
+	} {
+		read := tuple.NewFormColumnWrapper()
+		read.ParseContent(col.ToBytes())
+		if read.FieldName != col.FieldName || read.FieldType != col.FieldType || read.FieldLength != col.FieldLength ||
+			read.NotNull != col.NotNull || read.FieldCommentValue != col.FieldCommentValue || read.FieldCharset != col.FieldCharset {
+			t.Fatalf("expect %+v back, got %+v", col, read)
+		}
+		cols = append(cols, read)
+	}
+	meta := &TableTupleMeta{TableName: "t", Columns: cols}
+	if code := cols[1]; code.GetMaxByteLength() != 4 {
+		t.Fatalf("expect 4 bytes for latin1 CHAR(4), got %d", code.GetMaxByteLength())
+	}
+
+	// A CHAR takes all the bytes of its charset, whatever its value.
+	row := NewClusterLeafRowWithFrm(meta).(*ClusterLeafRow)
+	for i, value := range [][]byte{util.ConvertUInt4Bytes(1), []byte("ab"), []byte("日本"), []byte("after")} {
+		if err := row.WriteColumn(value, byte(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	header := NewClusterLeafRowHeaderWithContents(meta.GetPrimaryClusterLeafTuple(), row.ToByte()).(*ClusterLeafRowHeader)
+	if length := header.GetRecordBytesRealLength(); length != 4+4+16+5 || length != len(row.value.ToByte()) {
+		t.Fatalf("expect %d bytes of values, got %d counted of %d written", 4+4+16+5, length, len(row.value.ToByte()))
+	}
+	read := NewClusterLeafRowWithContent(row.ToByte(), meta.GetPrimaryClusterLeafTuple())
+	for i, expect := range []string{"ab", "日本", "after"} {
+		if got := string(read.ReadValueByIndex(i + 1).ToByte()); got != expect {
+			t.Fatalf("column %d: expect %q, got %q", i+1, expect, got)
+		}
+	}
+
+	if err := row.WriteColumn([]byte("abcde"), 1); err == nil {
+		t.Fatal("expect an error for 5 bytes in CHAR(4)")
+	}
+}
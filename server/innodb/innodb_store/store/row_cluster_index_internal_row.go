@@ -3,7 +3,6 @@ package store
 import (
 	"bytes"
 	"encoding/binary"
-	"fmt"
 	"github.com/zhukovaskychina/xmysql-server/server/common"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/tuple"
@@ -62,17 +61,8 @@ func NewClusterInternalRowHeaderWithContents(frmMeta tuple.TableRowTuple, conten
 	var clr = new(ClusterLeafRowHeader)
 	clr.FrmMeta = frmMeta
 	clr.VarLengthContentMap = make(map[byte]uint16)
-	//计算列长度
-	cl := frmMeta.GetColumnLength()
-	//根据列长度，计算出NULL表长度计算
-	//除以8,得到字节表示长度
-	var size int
-
-	if cl&7 == 0 {
-		size = cl >> 3
-	} else {
-		size = cl>>3 + 1
-	}
+	//根据可为NULL的列数，计算出NULL表长度
+	size := nullBitmapSize(frmMeta)
 
 	//获得可变长度
 	varColumns := frmMeta.GetVarColumns()
@@ -275,7 +265,7 @@ func NewClusterInternalRowWithContent(content []byte, tableTuple tuple.TableRowT
 
 	for i := 0; i < tableTuple.GetColumnLength(); i++ {
 
-		if currentRow.header.IsValueNullByIdx(byte(int(i))) {
+		if !currentRow.header.IsValueNullByIdx(byte(int(i))) {
 			fieldType := tableTuple.GetColumnInfos(byte(i)).FieldType
 			switch fieldType {
 			case "VARCHAR":
@@ -298,10 +288,18 @@ func NewClusterInternalRowWithContent(content []byte, tableTuple tuple.TableRowT
 					startOffset = startOffset + 4
 					break
 				}
+			case "ENUM":
+				{
+					col := tableTuple.GetColumnInfos(byte(i))
+					size := uint16(col.GetMaxByteLength())
+					currentRow.RowValues = append(currentRow.RowValues, basic.NewEnumValue(content[startOffset:startOffset+size], col.FieldElems))
+					startOffset = startOffset + size
+					break
+				}
 			}
 
 		} else {
-			fmt.Println("------------------")
+			currentRow.RowValues = append(currentRow.RowValues, nil)
 		}
 
 	}
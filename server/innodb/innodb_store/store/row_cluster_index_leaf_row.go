@@ -3,12 +3,10 @@ package store
 import (
 	"bytes"
 	"encoding/binary"
-	"fmt"
 	"github.com/zhukovaskychina/xmysql-server/server/common"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/tuple"
 	"github.com/zhukovaskychina/xmysql-server/util"
-	"strconv"
 	"strings"
 )
 
@@ -52,6 +50,9 @@ func NewClusterLeafRowHeader(frmMeta tuple.TableRowTuple) basic.FieldDataHeader
 
 	//空值处理
 	clr.NullContent = make([]byte, 0)
+	if frmMeta != nil {
+		clr.NullContent = make([]byte, nullBitmapSize(frmMeta))
+	}
 	//可变长度
 	clr.VarLengthContent = make([]byte, 0)
 
@@ -63,17 +64,8 @@ func NewClusterLeafRowHeaderWithContents(frmMeta tuple.TableRowTuple, content []
 	var clr = new(ClusterLeafRowHeader)
 	clr.FrmMeta = frmMeta
 	clr.VarLengthContentMap = make(map[byte]uint16)
-	//计算列长度
-	cl := frmMeta.GetColumnLength()
-	//根据列长度，计算出NULL表长度计算
-	//除以8,得到字节表示长度
-	var size int
-
-	if cl&7 == 0 {
-		size = cl >> 3
-	} else {
-		size = cl>>3 + 1
-	}
+	//根据可为NULL的列数，计算出NULL表长度
+	size := nullBitmapSize(frmMeta)
 
 	//if cl%8 > 0 {
 	//	size++
@@ -93,7 +85,7 @@ func NewClusterLeafRowHeaderWithContents(frmMeta tuple.TableRowTuple, content []
 	var afterCur = 2
 	for i := 0; i < varLength; i++ {
 		currentCols := frmMeta.GetColumnInfos(byte(i))
-		if currentCols.FieldType == "VARCHAR" {
+		if isVarColumn(currentCols.FieldType) {
 			//此处和mysql定义的不一致，为了便于实现row的反序列化，特将变长部分二位字节处理，这样一来，字节header部分的长度为
 			// 可变变量数量*2+NullSize+5
 
@@ -221,31 +213,26 @@ func (cldr *ClusterLeafRowHeader) ToByte() []byte {
 }
 
 func (cldr *ClusterLeafRowHeader) SetValueNull(nullValue byte, index byte) {
-	//不为空
-	nullStrArrays := util.ConvertBytes2BitStrings(cldr.NullContent)
-
-	if index < byte(len(nullStrArrays)) {
-		if len(nullStrArrays) == 0 {
-			nullStrArrays = append(nullStrArrays, "0", "0", "0", "0", "0", "0", "0", "0")
-		}
-		nullStrArrays[byte(len(nullStrArrays))-1-index] = strconv.Itoa(int(nullValue))
-	} else {
-		var newNullStrArrays = make([]string, 0)
-		newNullStrArrays = append(newNullStrArrays, "0", "0", "0", "0", "0", "0", "0")
-		newNullStrArrays = append(newNullStrArrays, strconv.Itoa(int(nullValue)))
-		newNullStrArrays = append(newNullStrArrays, nullStrArrays...)
-		nullStrArrays = newNullStrArrays
+	bit := nullBit(cldr.FrmMeta, index)
+	if bit < 0 {
+		return
 	}
+	if size := nullBitmapSize(cldr.FrmMeta); len(cldr.NullContent) != size {
+		cldr.NullContent = make([]byte, size)
+	}
+	setNullBit(cldr.NullContent, bit, nullValue == 1)
+}
 
-	cldr.NullContent = util.ConvertStringArrays2BytesArrays(nullStrArrays)
+// IsValueExternByIdx reports whether the value of column index is stored
+// off-page, the record keeping only its prefix and reference.
+func (cldr *ClusterLeafRowHeader) IsValueExternByIdx(index byte) bool {
+	return cldr.VarLengthContentMap[index]&varLengthExtern != 0
 }
 
+// IsValueNullByIdx reports whether the value of column index is NULL.
 func (cldr *ClusterLeafRowHeader) IsValueNullByIdx(index byte) bool {
-
-	nullStrArrays := util.ConvertBytes2BitStrings(cldr.NullContent)
-
-	return nullStrArrays[len(nullStrArrays)-int(index)-1] == "0"
-
+	bit := nullBit(cldr.FrmMeta, index)
+	return bit >= 0 && isNullBit(cldr.NullContent, bit)
 }
 
 //暂时不考虑溢出页
@@ -254,7 +241,7 @@ func (cldr *ClusterLeafRowHeader) SetValueLengthByIndex(realLength int, index by
 	fieldType := cldr.FrmMeta.GetColumnInfos(index).FieldType
 	//fieldLength := cldr.FrmMeta.GetColumnInfos(index).FieldLength
 	switch fieldType {
-	case "VARCHAR":
+	case "VARCHAR", "TEXT", "BLOB", "VARBINARY", "BINARY":
 		{
 			//if fieldLength*3 > 255 {
 			//	if realLength > 127 {
@@ -276,9 +263,9 @@ func (cldr *ClusterLeafRowHeader) SetValueLengthByIndex(realLength int, index by
 
 }
 
-//获取可变变量长度
+//获取可变变量长度，溢出页中的值为记录中存放部分的长度
 func (cldr *ClusterLeafRowHeader) GetVarValueLengthByIndex(index byte) int {
-	return int(cldr.VarLengthContentMap[index])
+	return int(cldr.VarLengthContentMap[index] &^ varLengthExtern)
 }
 
 /****
@@ -288,17 +275,15 @@ func (cldr *ClusterLeafRowHeader) GetVarValueLengthByIndex(index byte) int {
 */
 func (cldr *ClusterLeafRowHeader) GetRecordBytesRealLength() int {
 	var result = 0
-	nullSize := len(cldr.NullContent)
 	for i := 0; i < cldr.FrmMeta.GetColumnLength(); i++ {
 		formCols := cldr.FrmMeta.GetColumnInfos(byte(i))
 		fieldType := formCols.FieldType
-		isNull := cldr.NullContent[nullSize-i]
 
-		if isNull == 0 {
-			if fieldType == "VARCHAR" {
-				result = result + int(cldr.VarLengthContentMap[byte(i)])
+		if !cldr.IsValueNullByIdx(byte(i)) {
+			if isVarColumn(fieldType) {
+				result = result + cldr.GetVarValueLengthByIndex(byte(i))
 			} else {
-				result = result + int(formCols.FieldLength)
+				result = result + formCols.GetMaxByteLength()
 			}
 		}
 
@@ -332,8 +317,11 @@ func NewClusterLeafRowDataWithContents(content []byte, meta tuple.TableRowTuple)
 	return clusterLeafRowData
 }
 
+// WriteBytesWithNull appends the value of the next column. Values are
+// stored back to back, their lengths known from the column types and the
+// variable length list, and NULL values take no space.
 func (cld *ClusterLeafRowData) WriteBytesWithNull(content []byte) {
-	cld.Content = util.WriteWithNull(cld.Content, content)
+	cld.Content = append(cld.Content, content...)
 }
 
 func (cld *ClusterLeafRowData) GetPrimaryKey() []byte {
@@ -396,15 +384,42 @@ func (row *ClusterLeafRow) WriteWithNull(content []byte) {
 }
 
 func (row *ClusterLeafRow) WriteBytesWithNullWithsPos(content []byte, index byte) {
+	if err := row.WriteColumn(content, index); err != nil {
+		panic(err)
+	}
+}
+
+// WriteColumn appends content, the value of column index or nil for NULL.
+// A long value of a variable length column goes to the overflow pages of
+// the table as its row format says.
+func (row *ClusterLeafRow) WriteColumn(content []byte, index byte) error {
 	if content == nil {
 		row.header.SetValueNull(1, index)
 		row.header.SetValueLengthByIndex(0, index)
-	} else {
-		row.header.SetValueNull(0, index)
-		row.header.SetValueLengthByIndex(len(content), index)
+		return nil
 	}
-
-	row.value.WriteBytesWithNull(content)
+	row.header.SetValueNull(0, index)
+	if col := row.FrmMeta.GetColumnInfos(index); !isVarColumn(col.FieldType) {
+		value, err := fixedColumnValue(col, content)
+		if err != nil {
+			return err
+		}
+		row.header.SetValueLengthByIndex(len(value), index)
+		row.value.WriteBytesWithNull(value)
+		return nil
+	}
+	format, pages := rowFormatOf(row.FrmMeta)
+	local, extern, err := format.localValue(content, pages)
+	if err != nil {
+		return err
+	}
+	length := len(local)
+	if extern {
+		length |= varLengthExtern
+	}
+	row.header.SetValueLengthByIndex(length, index)
+	row.value.WriteBytesWithNull(local)
+	return nil
 }
 
 func (row *ClusterLeafRow) GetRowLength() uint16 {
@@ -501,8 +516,10 @@ func NewClusterLeafRowWithContent(content []byte, tableTuple tuple.TableRowTuple
 
 	currentRow.FrmMeta = tableTuple
 
-	currentRow.header = NewClusterLeafRowHeaderWithContents(tableTuple, content)
+	header := NewClusterLeafRowHeaderWithContents(tableTuple, content).(*ClusterLeafRowHeader)
+	currentRow.header = header
 	currentRow.RowValues = make([]basic.Value, 0)
+	_, pages := rowFormatOf(tableTuple)
 
 	rowHeaderLength := currentRow.header.GetRowHeaderLength()
 
@@ -510,13 +527,20 @@ func NewClusterLeafRowWithContent(content []byte, tableTuple tuple.TableRowTuple
 
 	for i := 0; i < tableTuple.GetColumnLength(); i++ {
 
-		if currentRow.header.IsValueNullByIdx(byte(int(i))) {
+		if !currentRow.header.IsValueNullByIdx(byte(int(i))) {
 			fieldType := tableTuple.GetColumnInfos(byte(i)).FieldType
 			switch fieldType {
-			case "VARCHAR":
+			case "VARCHAR", "TEXT", "BLOB", "VARBINARY", "BINARY":
 				{
 					realLength := currentRow.header.GetVarValueLengthByIndex(byte(i))
-					currentRow.RowValues = append(currentRow.RowValues, basic.NewVarcharVal(content[startOffset:int(startOffset)+realLength]))
+					value := content[startOffset : int(startOffset)+realLength]
+					if header.IsValueExternByIdx(byte(i)) {
+						var err error
+						if value, err = externValue(value, pages); err != nil {
+							panic(err)
+						}
+					}
+					currentRow.RowValues = append(currentRow.RowValues, basic.NewVarcharVal(value))
 					startOffset = startOffset + uint16(realLength)
 					break
 				}
@@ -533,10 +557,27 @@ func NewClusterLeafRowWithContent(content []byte, tableTuple tuple.TableRowTuple
 					startOffset = startOffset + 4
 					break
 				}
+			case "ENUM":
+				{
+					col := tableTuple.GetColumnInfos(byte(i))
+					size := uint16(col.GetMaxByteLength())
+					currentRow.RowValues = append(currentRow.RowValues, basic.NewEnumValue(content[startOffset:startOffset+size], col.FieldElems))
+					startOffset = startOffset + size
+					break
+				}
+			case "CHAR":
+				{
+					// The spaces padding the value aren't part of it.
+					size := uint16(tableTuple.GetColumnInfos(byte(i)).GetMaxByteLength())
+					value := bytes.TrimRight(content[startOffset:startOffset+size], " ")
+					currentRow.RowValues = append(currentRow.RowValues, basic.NewVarcharVal(value))
+					startOffset = startOffset + size
+					break
+				}
 			}
 
 		} else {
-			fmt.Println("------------------")
+			currentRow.RowValues = append(currentRow.RowValues, nil)
 		}
 
 	}
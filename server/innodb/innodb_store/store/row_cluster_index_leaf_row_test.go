@@ -72,7 +72,7 @@ func TestSetValueLengthByIndex(t *testing.T) {
 	clr := row.header.(*ClusterLeafRowHeader)
 	assert.Equal(t, currentRow.GetRowLength(), currentSysTableRow.GetRowLength())
 
-	assert.Equal(t, len(clr.NullContent), 2)
+	assert.Equal(t, len(clr.NullContent), nullBitmapSize(tuple))
 
 	//assert.Equal(t, binary.BigEndian.Uint16(clr.NullContent),uint16(0))
 
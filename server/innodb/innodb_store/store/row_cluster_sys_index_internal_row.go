@@ -2,7 +2,6 @@ package store
 
 import (
 	"bytes"
-	"fmt"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/tuple"
 	"github.com/zhukovaskychina/xmysql-server/util"
@@ -15,8 +14,16 @@ type ClusterSysIndexInternalRow struct {
 	RowValues []basic.Value
 }
 
+// ToDatum returns the values of the row in the order of the columns of its
+// tuple, as decodeValue reads them.
 func (c *ClusterSysIndexInternalRow) ToDatum() []basic.Datum {
-	panic("implement me")
+	datums := make([]basic.Datum, len(c.RowValues))
+	for i, value := range c.RowValues {
+		if value != nil {
+			datums[i] = decodeValue(c.FrmMeta.GetColumnInfos(byte(i)), value)
+		}
+	}
+	return datums
 }
 
 func (c *ClusterSysIndexInternalRow) GetHeaderLength() uint16 {
@@ -87,6 +94,10 @@ func (c *ClusterSysIndexInternalRow) WriteBytesWithNullWithsPos(content []byte,
 		{
 			c.RowValues[index] = basic.NewIntValue(content)
 		}
+	case "ENUM":
+		{
+			c.RowValues[index] = basic.NewEnumValue(content, c.FrmMeta.GetColumnInfos(index).FieldElems)
+		}
 	}
 
 	c.value.WriteBytesWithNull(content)
@@ -149,7 +160,7 @@ func NewClusterSysIndexInternalRowWithContent(content []byte, tableTuple tuple.T
 
 	for i := 0; i < tableTuple.GetColumnLength(); i++ {
 
-		if currentRow.header.IsValueNullByIdx(byte(int(i))) {
+		if !currentRow.header.IsValueNullByIdx(byte(int(i))) {
 			fieldType := tableTuple.GetColumnInfos(byte(i)).FieldType
 			switch fieldType {
 			case "VARCHAR":
@@ -172,10 +183,18 @@ func NewClusterSysIndexInternalRowWithContent(content []byte, tableTuple tuple.T
 					startOffset = startOffset + 4
 					break
 				}
+			case "ENUM":
+				{
+					col := tableTuple.GetColumnInfos(byte(i))
+					size := uint16(col.GetMaxByteLength())
+					currentRow.RowValues = append(currentRow.RowValues, basic.NewEnumValue(content[startOffset:startOffset+size], col.FieldElems))
+					startOffset = startOffset + size
+					break
+				}
 			}
 
 		} else {
-			fmt.Println("------------------")
+			currentRow.RowValues = append(currentRow.RowValues, nil)
 		}
 
 	}
@@ -196,8 +215,12 @@ func (c *ClusterSysIndexInternalRow) Less(than basic.Row) bool {
 	if than.IsInfimumRow() {
 		return false
 	}
-	thanPk := than.GetPrimaryKey()
-	resultBool, err := c.GetPrimaryKey().LessThan(thanPk)
+	thanPk, thisPk := than.GetPrimaryKey(), c.GetPrimaryKey()
+	if thisPk == nil || thanPk == nil {
+		//NULL排在最前
+		return thisPk == nil && thanPk != nil
+	}
+	resultBool, err := thisPk.LessThan(thanPk)
 	if err != nil {
 		panic(err)
 	}
@@ -2,7 +2,6 @@ package store
 
 import (
 	"bytes"
-	"fmt"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/tuple"
 	"github.com/zhukovaskychina/xmysql-server/util"
@@ -85,6 +84,10 @@ func (c *ClusterSysIndexLeafRow) WriteBytesWithNullWithsPos(content []byte, inde
 		{
 			c.RowValues[index] = basic.NewIntValue(content)
 		}
+	case "ENUM":
+		{
+			c.RowValues[index] = basic.NewEnumValue(content, c.FrmMeta.GetColumnInfos(index).FieldElems)
+		}
 	}
 
 	c.value.WriteBytesWithNull(content)
@@ -148,7 +151,7 @@ func NewClusterSysIndexLeafRowWithContent(content []byte, tableTuple tuple.Table
 
 	for i := 0; i < tableTuple.GetColumnLength(); i++ {
 
-		if currentRow.header.IsValueNullByIdx(byte(int(i))) {
+		if !currentRow.header.IsValueNullByIdx(byte(int(i))) {
 			fieldType := tableTuple.GetColumnInfos(byte(i)).FieldType
 			switch fieldType {
 			case "VARCHAR":
@@ -171,10 +174,18 @@ func NewClusterSysIndexLeafRowWithContent(content []byte, tableTuple tuple.Table
 					startOffset = startOffset + 4
 					break
 				}
+			case "ENUM":
+				{
+					col := tableTuple.GetColumnInfos(byte(i))
+					size := uint16(col.GetMaxByteLength())
+					currentRow.RowValues = append(currentRow.RowValues, basic.NewEnumValue(content[startOffset:startOffset+size], col.FieldElems))
+					startOffset = startOffset + size
+					break
+				}
 			}
 
 		} else {
-			fmt.Println("------------------")
+			currentRow.RowValues = append(currentRow.RowValues, nil)
 		}
 
 	}
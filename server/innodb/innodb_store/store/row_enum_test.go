@@ -0,0 +1,48 @@
+package store
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/tuple"
+	"github.com/zhukovaskychina/xmysql-server/util"
+)
+
+func TestEnumColumnRoundTrip(t *testing.T) {
+	elems := []string{"small", "medium", "large"}
+	cols := []*tuple.FormColumnsWrapper{
+		{FieldName: "id", FieldType: "INT", FieldLength: 4, NotNull: true},
+		{FieldName: "size", FieldType: "ENUM", FieldLength: int16(len(elems)), FieldElems: elems},
+		{FieldName: "note", FieldType: "VARCHAR", FieldLength: 16},
+	}
+	meta := &TableTupleMeta{TableName: "t", Columns: cols}
+	leafTuple := meta.GetPrimaryClusterLeafTuple()
+
+	read := func(ordinal uint64) basic.Value {
+		row := NewClusterLeafRowWithFrm(meta)
+		row.WriteBytesWithNullWithsPos(util.ConvertUInt4Bytes(1), 0)
+		row.WriteBytesWithNullWithsPos(basic.EnumOrdinalBytes(ordinal, 1), 1)
+		row.WriteBytesWithNullWithsPos([]byte("after"), 2)
+		content := NewClusterLeafRowWithContent(row.ToByte(), leafTuple)
+		// The ENUM takes one byte, the column after it reads right.
+		if note := content.ReadValueByIndex(2); note == nil || !bytes.Equal(note.ToByte(), []byte("after")) {
+			t.Fatalf("expect note to be after, got %v", note)
+		}
+		return content.ReadValueByIndex(1)
+	}
+	large, small, invalid := read(3), read(1), read(0)
+	if large.ToString() != "large" || small.ToString() != "small" || invalid.ToString() != "" {
+		t.Fatalf("expect large, small and '', got %q, %q and %q", large.ToString(), small.ToString(), invalid.ToString())
+	}
+	if d := large.ToDatum(); d.Kind() != basic.KindMysqlEnum || d.GetMysqlEnum().Value != 3 {
+		t.Fatalf("expect the enum large with ordinal 3, got %v", d)
+	}
+	// ENUM values compare by their ordinals, not their names.
+	if less, _ := large.LessThan(small); less.Raw().(bool) {
+		t.Errorf("expect large not to be less than small")
+	}
+	if less, _ := invalid.LessThan(small); !less.Raw().(bool) {
+		t.Errorf("expect '' to be less than small")
+	}
+}
@@ -0,0 +1,228 @@
+package store
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/zhukovaskychina/xmysql-server/server/common"
+	"github.com/zhukovaskychina/xmysql-server/server/conf"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/innodb_store/store/storebytes/pages"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/innodb_store/store/storebytes/table"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/tuple"
+	"github.com/zhukovaskychina/xmysql-server/util"
+)
+
+/**
+行格式决定了长的可变长度列（VARCHAR、TEXT、BLOB、VARBINARY）怎样存放：
+
+值不超过 fieldExternThreshold() 时，三种行格式都把它完整地放在记录中。
+
+超过时，值存放到溢出页中，记录中只留下：
+	REDUNDANT、COMPACT：前 768 字节的前缀，加上 20 字节的溢出页引用
+	DYNAMIC：只有 20 字节的溢出页引用
+
+可变长度列表中该列的长度是记录中实际存放的长度，并置上 varLengthExtern 位。
+**/
+
+// RowFormat is the format of the records of a table, kept in the RowType
+// of its .frm.
+type RowFormat byte
+
+// Row formats. REDUNDANT records share the header of COMPACT records here,
+// they differ only in how they store long values.
+const (
+	RowFormatCompact   RowFormat = table.CompactRowType
+	RowFormatDynamic   RowFormat = table.DynamicRowType
+	RowFormatRedundant RowFormat = table.RedundantRowType
+)
+
+const (
+	// fieldLocalPrefixLen is the prefix of an off-page value REDUNDANT and
+	// COMPACT keep in the record.
+	fieldLocalPrefixLen = 768
+	// fieldRefSize is the size of a FieldRef in the record.
+	fieldRefSize = 20
+	// varLengthExtern flags the length of an off-page value in the variable
+	// length list.
+	varLengthExtern = 0x4000
+)
+
+// fieldExternThreshold returns the longest value kept whole in the record,
+// about half a page so that a page holds at least two records.
+func fieldExternThreshold() int {
+	return common.PageSize()/2 - 200
+}
+
+var rowFormatNames = map[RowFormat]string{
+	RowFormatCompact:   "COMPACT",
+	RowFormatDynamic:   "DYNAMIC",
+	RowFormatRedundant: "REDUNDANT",
+}
+
+func (f RowFormat) String() string {
+	return rowFormatNames[f]
+}
+
+// ParseRowFormat returns the row format called name, in any case.
+func ParseRowFormat(name string) (RowFormat, error) {
+	for f, fName := range rowFormatNames {
+		if strings.EqualFold(name, fName) {
+			return f, nil
+		}
+	}
+	return 0, errors.Errorf("unsupported row format %s", name)
+}
+
+// TableRowFormat returns the row format of a table created with
+// ROW_FORMAT=name: innodb_default_row_format when name is empty or DEFAULT.
+func TableRowFormat(name string, cfg *conf.Cfg) (RowFormat, error) {
+	if name == "" || strings.EqualFold(name, "DEFAULT") {
+		if cfg == nil || cfg.InnodbDefaultRowFormat == "" {
+			return RowFormatDynamic, nil
+		}
+		name = cfg.InnodbDefaultRowFormat
+	}
+	return ParseRowFormat(name)
+}
+
+// SpaceFlags returns the FSP_SPACE_FLAGS of a tablespace holding a table of
+// format, as InnoDB derives them from the table flags: COMPACT and DYNAMIC
+// are post-Antelope, DYNAMIC also has atomic BLOBs. The page size is the
+// one of the server.
+func (f RowFormat) SpaceFlags() pages.SpaceFlags {
+	return pages.SpaceFlags{
+		IsPostAntelope: f != RowFormatRedundant,
+		AtomicBlobs:    f == RowFormatDynamic,
+		PageSSize:      common.PageSSize(common.PageSize()),
+	}
+}
+
+// RowFormatOfSpaceFlags returns the row format recorded in the
+// FSP_SPACE_FLAGS of a tablespace.
+func RowFormatOfSpaceFlags(flags pages.SpaceFlags) RowFormat {
+	switch {
+	case flags.AtomicBlobs:
+		return RowFormatDynamic
+	case flags.IsPostAntelope:
+		return RowFormatCompact
+	}
+	return RowFormatRedundant
+}
+
+// FieldRef locates a value stored in overflow pages, what an off-page
+// column keeps in the record like InnoDB's BTR_EXTERN_FIELD_REF: the space,
+// the first page and the offset in it, then the length of the value.
+type FieldRef struct {
+	SpaceId uint32
+	PageNo  uint32
+	Offset  uint32
+	Length  uint64
+}
+
+func NewFieldRefWithBytes(content []byte) FieldRef {
+	return FieldRef{
+		SpaceId: util.ReadUB4Byte2UInt32(content[0:4]),
+		PageNo:  util.ReadUB4Byte2UInt32(content[4:8]),
+		Offset:  util.ReadUB4Byte2UInt32(content[8:12]),
+		Length:  util.ReadUB8Byte2Long(content[12:20]),
+	}
+}
+
+func (r FieldRef) ToBytes() []byte {
+	var buff = make([]byte, 0, fieldRefSize)
+	buff = append(buff, util.ConvertUInt4Bytes(r.SpaceId)...)
+	buff = append(buff, util.ConvertUInt4Bytes(r.PageNo)...)
+	buff = append(buff, util.ConvertUInt4Bytes(r.Offset)...)
+	buff = append(buff, util.ConvertULong8Bytes(r.Length)...)
+	return buff
+}
+
+// OverflowPages keeps the values of a table stored off-page.
+type OverflowPages interface {
+	// WriteOverflow stores value and returns where it went.
+	WriteOverflow(value []byte) (FieldRef, error)
+	// ReadOverflow returns the value stored at ref.
+	ReadOverflow(ref FieldRef) ([]byte, error)
+	// FreeOverflow frees the value stored at ref.
+	FreeOverflow(ref FieldRef) error
+}
+
+// isVarColumn reports whether the values of a column of fieldType are
+// stored with their length in the variable length list. BINARY values are
+// kept with their length like VARBINARY ones, so both read back byte for
+// byte.
+func isVarColumn(fieldType string) bool {
+	switch fieldType {
+	case "VARCHAR", "TEXT", "BLOB", "VARBINARY", "BINARY":
+		return true
+	}
+	return false
+}
+
+// fixedColumnValue returns content as it is stored in col, a column not
+// in the variable length list. CHAR values take all the bytes reserved for
+// the column, GetMaxByteLength of them, padded with spaces the way MySQL
+// pads CHAR, so that the length of a record can be told from its columns.
+func fixedColumnValue(col *tuple.FormColumnsWrapper, content []byte) ([]byte, error) {
+	if col.FieldType != "CHAR" {
+		return content, nil
+	}
+	size := col.GetMaxByteLength()
+	if len(content) > size {
+		return nil, errors.Errorf("value of %d bytes is too long for %s CHAR(%d)", len(content), col.FieldName, col.FieldLength)
+	}
+	value := make([]byte, size)
+	copy(value, content)
+	for i := len(content); i < size; i++ {
+		value[i] = ' '
+	}
+	return value, nil
+}
+
+// rowFormatOf returns the row format of the records of t and the overflow
+// pages of their long values. Tuples without a table, like the ones of the
+// dictionary, are COMPACT without overflow pages.
+func rowFormatOf(t tuple.TableRowTuple) (RowFormat, OverflowPages) {
+	if c, ok := t.(*ClusterLeafTuple); ok {
+		return c.RowFormat, c.Overflow
+	}
+	return RowFormatCompact, nil
+}
+
+// localValue returns what the record keeps of value, a value of a variable
+// length column, and whether the rest went to pages.
+func (f RowFormat) localValue(value []byte, pages OverflowPages) ([]byte, bool, error) {
+	if len(value) <= fieldExternThreshold() {
+		return value, false, nil
+	}
+	if pages == nil {
+		return nil, false, errors.Errorf("value of %d bytes needs overflow pages", len(value))
+	}
+	prefix := fieldLocalPrefixLen
+	if f == RowFormatDynamic {
+		prefix = 0
+	}
+	ref, err := pages.WriteOverflow(value[prefix:])
+	if err != nil {
+		return nil, false, err
+	}
+	local := make([]byte, 0, prefix+fieldRefSize)
+	local = append(local, value[:prefix]...)
+	return append(local, ref.ToBytes()...), true, nil
+}
+
+// externValue returns the value of an off-page column from local, what its
+// record keeps of it.
+func externValue(local []byte, pages OverflowPages) ([]byte, error) {
+	if len(local) < fieldRefSize || pages == nil {
+		return nil, errors.Errorf("no overflow pages for the value of %d bytes", len(local))
+	}
+	prefix := len(local) - fieldRefSize
+	rest, err := pages.ReadOverflow(NewFieldRefWithBytes(local[prefix:]))
+	if err != nil {
+		return nil, err
+	}
+	value := make([]byte, 0, prefix+len(rest))
+	value = append(value, local[:prefix]...)
+	return append(value, rest...), nil
+}
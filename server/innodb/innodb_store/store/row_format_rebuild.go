@@ -0,0 +1,153 @@
+package store
+
+import (
+	"github.com/pkg/errors"
+	"github.com/zhukovaskychina/xmysql-server/server/common"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+)
+
+/**
+ALTER TABLE ... ROW_FORMAT 重建表
+
+聚簇索引叶子页面上的每一条记录按新的行格式重新编码：长的可变长度列按旧格式读出
+完整的值，再按新格式写入（DYNAMIC 只留 20 字节的引用，REDUNDANT、COMPACT 留 768 字节
+的前缀和引用），旧的溢出页随后释放。非叶子页面只有主键和页号，不需要改写。
+
+记录改写完之后，.frm 的 RowType 和表空间的 FSP_SPACE_FLAGS 才改为新的行格式。
+**/
+
+// convertRecord re-encodes record, a record of a clustered index leaf page
+// of from, in the row format of to, and frees the overflow pages record
+// used.
+func convertRecord(record []byte, from, to *ClusterLeafTuple) ([]byte, error) {
+	old := NewClusterLeafRowWithContent(record, from)
+	row := &ClusterLeafRow{
+		header:  NewClusterLeafRowHeader(to),
+		value:   &ClusterLeafRowData{Content: make([]byte, 0), meta: to},
+		FrmMeta: to,
+	}
+	for i := 0; i < from.GetColumnLength(); i++ {
+		var content []byte
+		if value := old.ReadValueByIndex(i); value != nil {
+			content = value.ToByte()
+		}
+		if err := row.WriteColumn(content, byte(i)); err != nil {
+			return nil, err
+		}
+	}
+	if err := FreeExternValues(record, from); err != nil {
+		return nil, err
+	}
+	return row.ToByte(), nil
+}
+
+// rebuildLeafPage returns frame, a page of a clustered index, with the
+// records of from converted to the row format of to. Non-leaf pages are
+// returned as they are.
+func rebuildLeafPage(frame []byte, from, to *ClusterLeafTuple) ([]byte, error) {
+	index := NewPageIndexByLoadBytesWithTuple(frame, from).(*Index)
+	if index.PageLeafOrInternal() != common.PAGE_LEAF {
+		return frame, nil
+	}
+	var rows []basic.Row
+	for _, row := range index.SlotRowData.GetRowListWithoutInfiuAndSupremum() {
+		record, err := convertRecord(row.ToByte(), from, to)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, NewClusterLeafRowWithContent(record, to))
+	}
+	if err := index.ReplaceRows(rows); err != nil {
+		return nil, err
+	}
+	return index.ToByte(), nil
+}
+
+// rebuildRowFormat converts the records of the clustered index tree to
+// the row format of to, leaf page by leaf page from the leftmost one.
+func (self *BTree) rebuildRowFormat(to *ClusterLeafTuple) error {
+	from, ok := self.leafTuple.(*ClusterLeafTuple)
+	if !ok {
+		return errors.Errorf("index %s is not a clustered index", self.indexName)
+	}
+	pageNo := self.rootPageNo
+	for {
+		var child uint32
+		err := self.do(pageNo, func(index *Index) error {
+			row, found := index.GetRowByIndex(1)
+			if !found {
+				return errors.Errorf("internal page %d has no record", pageNo)
+			}
+			child = row.GetPageNumber()
+			return nil
+		}, func(index *Index) error {
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if child == 0 {
+			break
+		}
+		pageNo = child
+	}
+	for pageNo != 0 && pageNo != pageNull {
+		frame, err := self.readPage(pageNo)
+		if err != nil {
+			return err
+		}
+		next := NewPageIndexByLoadBytesWithTuple(frame, from).(*Index).GetNextPageNo()
+		if frame, err = rebuildLeafPage(frame, from, to); err != nil {
+			return errors.Wrapf(err, "rebuild page %d", pageNo)
+		}
+		if err = self.writePage(pageNo, frame); err != nil {
+			return err
+		}
+		pageNo = next
+	}
+	self.leafTuple = to
+	return nil
+}
+
+func (self *BTree) readPage(pageNo uint32) ([]byte, error) {
+	if self.IsInit {
+		return self.blockFile.ReadPageByNumber(pageNo)
+	}
+	return *self.BufferPool.GetPageBlock(self.spaceId, pageNo).Frame, nil
+}
+
+func (self *BTree) writePage(pageNo uint32, frame []byte) error {
+	if self.IsInit {
+		return self.blockFile.WriteContentByPage(int64(pageNo), frame)
+	}
+	block := self.BufferPool.GetPageBlock(self.spaceId, pageNo)
+	block.Frame = &frame
+	self.BufferPool.UpdateBlock(self.spaceId, pageNo, block)
+	if self.BufferPool.AHI != nil {
+		self.BufferPool.AHI.InvalidatePage(self.spaceId, pageNo)
+	}
+	return nil
+}
+
+// rebuildRowFormat rebuilds the table in format and records format in its
+// .frm and in the flags of space.
+func (o *OrdinaryTable) rebuildRowFormat(format RowFormat, space *UnSysTableSpace) error {
+	meta := o.tableTupleMeta
+	if tree, ok := o.btreeMap["PRIMARY"].(*BTree); ok && tree != nil {
+		if from, ok := tree.leafTuple.(*ClusterLeafTuple); ok {
+			to := *from
+			to.RowFormat = format
+			if err := tree.rebuildRowFormat(&to); err != nil {
+				return err
+			}
+		}
+	}
+	meta.RowFormat = format
+	if meta.blockFile != nil {
+		meta.FlushToDisk()
+	}
+	if space != nil {
+		return space.SetRowFormat(format)
+	}
+	return nil
+}
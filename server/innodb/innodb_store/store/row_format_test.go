@@ -0,0 +1,230 @@
+package store
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/conf"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/innodb_store/store/storebytes/pages"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/tuple"
+	"github.com/zhukovaskychina/xmysql-server/util"
+)
+
+// testOverflowPages keeps the off-page values in memory, one per page.
+type testOverflowPages map[uint32][]byte
+
+func (pages testOverflowPages) WriteOverflow(value []byte) (FieldRef, error) {
+	pageNo := uint32(len(pages) + 1)
+	pages[pageNo] = append([]byte(nil), value...)
+	return FieldRef{SpaceId: 1, PageNo: pageNo, Length: uint64(len(value))}, nil
+}
+
+func (pages testOverflowPages) ReadOverflow(ref FieldRef) ([]byte, error) {
+	value, ok := pages[ref.PageNo]
+	if !ok || uint64(len(value)) != ref.Length {
+		return nil, fmt.Errorf("bad reference %+v", ref)
+	}
+	return value, nil
+}
+
+func (pages testOverflowPages) FreeOverflow(ref FieldRef) error {
+	delete(pages, ref.PageNo)
+	return nil
+}
+
+func TestTableRowFormat(t *testing.T) {
+	cfg := conf.NewCfg()
+	tests := []struct {
+		name, defaultFormat string
+		expected            RowFormat
+	}{
+		{"", "", RowFormatDynamic},
+		{"DEFAULT", "COMPACT", RowFormatCompact},
+		{"", "REDUNDANT", RowFormatRedundant},
+		{"compact", "DYNAMIC", RowFormatCompact},
+		{"Dynamic", "COMPACT", RowFormatDynamic},
+	}
+	for _, tt := range tests {
+		cfg.InnodbDefaultRowFormat = tt.defaultFormat
+		format, err := TableRowFormat(tt.name, cfg)
+		if err != nil || format != tt.expected {
+			t.Fatalf("%q with default %q: expect %s, got %s, %v", tt.name, tt.defaultFormat, tt.expected, format, err)
+		}
+	}
+	for _, name := range []string{"FIXED", "COMPRESSED"} {
+		if _, err := TableRowFormat(name, cfg); err == nil {
+			t.Fatalf("expect %s to be unsupported", name)
+		}
+	}
+}
+
+func TestRowFormatOffPage(t *testing.T) {
+	long := bytes.Repeat([]byte("0123456789abcdef"), 1500)
+	short := []byte("short text")
+	tests := []struct {
+		format RowFormat
+		value  []byte
+		// local is what the record keeps of value, off-page the part stored
+		// in the overflow pages.
+		local, offPage int
+	}{
+		{RowFormatDynamic, long, fieldRefSize, len(long)},
+		{RowFormatCompact, long, fieldLocalPrefixLen + fieldRefSize, len(long) - fieldLocalPrefixLen},
+		{RowFormatRedundant, long, fieldLocalPrefixLen + fieldRefSize, len(long) - fieldLocalPrefixLen},
+		{RowFormatDynamic, short, len(short), 0},
+		{RowFormatCompact, short, len(short), 0},
+	}
+	for _, tt := range tests {
+		pages := testOverflowPages{}
+		meta := &TableTupleMeta{TableName: "t", RowFormat: tt.format, Overflow: pages, Columns: []*tuple.FormColumnsWrapper{
+			{FieldName: "ID", FieldType: "INT", FieldLength: 4, NotNull: true},
+			{FieldName: "BODY", FieldType: "TEXT"},
+			{FieldName: "NAME", FieldType: "VARCHAR", FieldLength: 16},
+		}}
+		row := NewClusterLeafRowWithFrm(meta).(*ClusterLeafRow)
+		for i, content := range [][]byte{util.ConvertUInt4Bytes(7), tt.value, []byte("name")} {
+			if err := row.WriteColumn(content, byte(i)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if size := len(row.value.ToByte()); size != 4+tt.local+4 {
+			t.Fatalf("%s, %d bytes: expect %d bytes in the record, got %d", tt.format, len(tt.value), 4+tt.local+4, size)
+		}
+		offPage := 0
+		for _, value := range pages {
+			offPage += len(value)
+		}
+		if offPage != tt.offPage {
+			t.Fatalf("%s, %d bytes: expect %d bytes off-page, got %d", tt.format, len(tt.value), tt.offPage, offPage)
+		}
+
+		read := NewClusterLeafRowWithContent(row.ToByte(), meta.GetPrimaryClusterLeafTuple())
+		if body := read.ReadValueByIndex(1).ToByte(); !bytes.Equal(body, tt.value) {
+			t.Fatalf("%s, %d bytes: read back %d bytes", tt.format, len(tt.value), len(body))
+		}
+		if name := read.ReadValueByIndex(2).ToByte(); string(name) != "name" {
+			t.Fatalf("%s: expect name, got %q", tt.format, name)
+		}
+	}
+
+	// Without overflow pages a long value has nowhere to go.
+	meta := &TableTupleMeta{TableName: "t", RowFormat: RowFormatDynamic, Columns: []*tuple.FormColumnsWrapper{
+		{FieldName: "BODY", FieldType: "BLOB"},
+	}}
+	if err := NewClusterLeafRowWithFrm(meta).(*ClusterLeafRow).WriteColumn(long, 0); err == nil {
+		t.Fatal("expect an error without overflow pages")
+	}
+}
+
+func TestRowFormatSpaceFlags(t *testing.T) {
+	for _, format := range []RowFormat{RowFormatRedundant, RowFormatCompact, RowFormatDynamic} {
+		flags := pages.ParseSpaceFlags(format.SpaceFlags().ToUint32())
+		if got := RowFormatOfSpaceFlags(flags); got != format {
+			t.Fatalf("expect %s back from the flags, got %s", format, got)
+		}
+	}
+	// The page size and the other flags are kept.
+	flags := pages.SpaceFlags{IsPostAntelope: true, AtomicBlobs: true, PageSSize: 5, Temporary: true}
+	if flags.ToUint32() != 0x1|0x20|5<<6|1<<12 {
+		t.Fatalf("unexpected FSP_SPACE_FLAGS %#x", flags.ToUint32())
+	}
+	if pages.ParseSpaceFlags(flags.ToUint32()) != flags {
+		t.Fatalf("expect %+v, got %+v", flags, pages.ParseSpaceFlags(flags.ToUint32()))
+	}
+
+	dir, err := ioutil.TempDir("", "row_format")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cfg := conf.NewCfg()
+	cfg.DataDir = dir
+	space := NewTableSpaceFileWithRowFormat(cfg, "test", "t", 5, false, nil, RowFormatCompact).(*UnSysTableSpace)
+	if format, err := space.RowFormat(); err != nil || format != RowFormatCompact {
+		t.Fatalf("expect COMPACT in the flags of a new tablespace, got %s, %v", format, err)
+	}
+	if err = space.SetRowFormat(RowFormatDynamic); err != nil {
+		t.Fatal(err)
+	}
+	if format, err := space.RowFormat(); err != nil || format != RowFormatDynamic {
+		t.Fatalf("expect DYNAMIC after the rebuild, got %s, %v", format, err)
+	}
+}
+
+func TestRebuildRowFormat(t *testing.T) {
+	overflow := testOverflowPages{}
+	meta := &TableTupleMeta{TableName: "t", RowFormat: RowFormatDynamic, Overflow: overflow, Columns: []*tuple.FormColumnsWrapper{
+		{FieldName: "ID", FieldType: "INT", FieldLength: 4, NotNull: true},
+		{FieldName: "BODY", FieldType: "TEXT"},
+	}}
+	bodies := [][]byte{bytes.Repeat([]byte("dynamic"), 1500), nil, []byte("short")}
+	from := meta.GetPrimaryClusterLeafTuple().(*ClusterLeafTuple)
+	index := NewPageIndexWithTuple(5, 3, from).(*Index)
+	for i, body := range bodies {
+		row := NewClusterLeafRowWithFrm(meta).(*ClusterLeafRow)
+		for k, content := range [][]byte{util.ConvertUInt4Bytes(uint32(i + 1)), body} {
+			if err := row.WriteColumn(content, byte(k)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		index.AddRow(NewClusterLeafRowWithContent(row.ToByte(), from))
+	}
+
+	to := *from
+	to.RowFormat = RowFormatCompact
+	frame, err := rebuildLeafPage(index.ToByte(), from, &to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rebuilt := NewPageIndexByLoadBytesWithTuple(frame, &to).(*Index)
+	if rebuilt.GetRecordSize() != len(bodies) {
+		t.Fatalf("expect %d records, got %d", len(bodies), rebuilt.GetRecordSize())
+	}
+	for i, body := range bodies {
+		row, _ := rebuilt.GetRowByIndex(i + 1)
+		value := row.ReadValueByIndex(1)
+		if body == nil {
+			if value != nil {
+				t.Fatalf("row %d: expect NULL, got %q", i, value.ToByte())
+			}
+			continue
+		}
+		if !bytes.Equal(value.ToByte(), body) {
+			t.Fatalf("row %d: read back %d bytes, not the %d written", i, len(value.ToByte()), len(body))
+		}
+	}
+	// COMPACT keeps a prefix in the record, the old chain was freed.
+	if len(overflow) != 1 {
+		t.Fatalf("expect one value off-page, got %d", len(overflow))
+	}
+	for _, value := range overflow {
+		if len(value) != len(bodies[0])-fieldLocalPrefixLen {
+			t.Fatalf("expect %d bytes off-page, got %d", len(bodies[0])-fieldLocalPrefixLen, len(value))
+		}
+	}
+}
+
+func TestBinaryColumnValues(t *testing.T) {
+	meta := &TableTupleMeta{TableName: "t", RowFormat: RowFormatDynamic, Overflow: testOverflowPages{}, Columns: []*tuple.FormColumnsWrapper{
+		{FieldName: "ID", FieldType: "INT", FieldLength: 4, NotNull: true},
+		{FieldName: "K", FieldType: "VARBINARY", FieldLength: 8},
+		{FieldName: "C", FieldType: "BINARY", FieldLength: 4},
+		{FieldName: "B", FieldType: "BLOB"},
+	}}
+	values := [][]byte{util.ConvertUInt4Bytes(1), {0x00, 0xff, 0x00}, {'a', 0x00, 0x00, 0x00}, {0xde, 0xad, 0xbe, 0xef}}
+	row := NewClusterLeafRowWithFrm(meta).(*ClusterLeafRow)
+	for i, content := range values {
+		if err := row.WriteColumn(content, byte(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := NewClusterLeafRowWithContent(row.ToByte(), meta.GetPrimaryClusterLeafTuple())
+	for i := 1; i < len(values); i++ {
+		if value := read.ReadValueByIndex(i).ToByte(); !bytes.Equal(value, values[i]) {
+			t.Fatalf("column %d: expect %v, got %v", i, values[i], value)
+		}
+	}
+}
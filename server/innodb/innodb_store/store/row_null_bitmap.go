@@ -0,0 +1,53 @@
+package store
+
+import (
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/tuple"
+)
+
+/**
+记录头的NULL值列表：每个允许为NULL的列占一位，不满8位补齐为一个字节，NOT NULL的列不占位。
+和变长字段长度列表一样倒序存放：第一个可为NULL的列对应最后一个字节（离记录头最近）的最低位，
+第9个可为NULL的列对应倒数第二个字节的最低位，以此类推。
+**/
+
+// nullBitmapSize returns the bytes of the NULL bitmap of the records of t.
+func nullBitmapSize(t tuple.TableRowTuple) int {
+	n := 0
+	for i := 0; i < t.GetColumnLength(); i++ {
+		if !t.GetColumnInfos(byte(i)).NotNull {
+			n++
+		}
+	}
+	return (n + 7) >> 3
+}
+
+// nullBit returns the bit of column index of t in the NULL bitmap, -1 when
+// the column is NOT NULL and has none.
+func nullBit(t tuple.TableRowTuple, index byte) int {
+	if t.GetColumnInfos(index).NotNull {
+		return -1
+	}
+	bit := 0
+	for i := byte(0); i < index; i++ {
+		if !t.GetColumnInfos(i).NotNull {
+			bit++
+		}
+	}
+	return bit
+}
+
+// setNullBit sets or clears bit in the NULL bitmap.
+func setNullBit(bitmap []byte, bit int, null bool) {
+	i := len(bitmap) - 1 - bit>>3
+	mask := byte(1) << uint(bit&7)
+	if null {
+		bitmap[i] |= mask
+	} else {
+		bitmap[i] &^= mask
+	}
+}
+
+// isNullBit reports whether bit is set in the NULL bitmap.
+func isNullBit(bitmap []byte, bit int) bool {
+	return bitmap[len(bitmap)-1-bit>>3]&(1<<uint(bit&7)) != 0
+}
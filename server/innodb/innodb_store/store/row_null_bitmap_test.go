@@ -0,0 +1,87 @@
+package store
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/tuple"
+	"github.com/zhukovaskychina/xmysql-server/util"
+)
+
+func TestNullBitmapRoundTrip(t *testing.T) {
+	// 12 columns, 10 of them nullable, so the bitmap takes two bytes.
+	var cols []*tuple.FormColumnsWrapper
+	for i := 0; i < 12; i++ {
+		col := &tuple.FormColumnsWrapper{FieldName: fmt.Sprintf("c%d", i), FieldType: "INT", FieldLength: 4}
+		switch {
+		case i == 0 || i == 6:
+			col.NotNull = true
+		case i%3 == 1:
+			col.FieldType, col.FieldLength = "VARCHAR", 16
+		case i%3 == 2:
+			col.FieldType, col.FieldLength = "BIGINT", 8
+		}
+		cols = append(cols, col)
+	}
+	meta := &TableTupleMeta{TableName: "t", Columns: cols}
+	leafTuple := meta.GetPrimaryClusterLeafTuple()
+	if size := nullBitmapSize(leafTuple); size != 2 {
+		t.Fatalf("expect a 2 byte NULL bitmap, got %d", size)
+	}
+
+	encode := func(i int) []byte {
+		switch cols[i].FieldType {
+		case "VARCHAR":
+			return []byte(fmt.Sprintf("value%d", i))
+		case "BIGINT":
+			return util.ConvertULong8Bytes(uint64(i))
+		}
+		return util.ConvertUInt4Bytes(uint32(i))
+	}
+	for _, nulls := range []map[int]bool{
+		{},
+		{1: true, 2: true, 3: true, 4: true, 5: true, 7: true, 8: true, 9: true, 10: true, 11: true},
+		{1: true, 9: true},
+		{11: true},
+		{2: true, 3: true, 10: true},
+	} {
+		row := NewClusterLeafRowWithFrm(meta)
+		for i := range cols {
+			var content []byte
+			if !nulls[i] {
+				content = encode(i)
+			}
+			row.WriteBytesWithNullWithsPos(content, byte(i))
+		}
+		read := NewClusterLeafRowWithContent(row.ToByte(), leafTuple)
+		if read.GetRowLength() != row.GetRowLength() {
+			t.Fatalf("%v: expect row length %d, got %d", nulls, row.GetRowLength(), read.GetRowLength())
+		}
+		for i := range cols {
+			val := read.ReadValueByIndex(i)
+			if nulls[i] {
+				if val != nil {
+					t.Fatalf("%v: expect c%d to be NULL, got %v", nulls, i, val.ToByte())
+				}
+				continue
+			}
+			if val == nil || !bytes.Equal(val.ToByte(), encode(i)) {
+				t.Fatalf("%v: expect c%d to be %v, got %v", nulls, i, encode(i), val)
+			}
+		}
+	}
+
+	// The first nullable column is the lowest bit of the last byte, the
+	// ninth the lowest bit of the first.
+	header := NewClusterLeafRowHeader(leafTuple).(*ClusterLeafRowHeader)
+	header.SetValueNull(1, 1)
+	header.SetValueNull(1, 10)
+	if !bytes.Equal(header.NullContent, []byte{0x01, 0x01}) {
+		t.Fatalf("unexpected NULL bitmap %08b", header.NullContent)
+	}
+	header.SetValueNull(1, 6)
+	if !bytes.Equal(header.NullContent, []byte{0x01, 0x01}) || header.IsValueNullByIdx(6) {
+		t.Fatalf("expect a NOT NULL column to have no bit, got %08b", header.NullContent)
+	}
+}
@@ -7,7 +7,6 @@ import (
 	"github.com/zhukovaskychina/xmysql-server/server/common"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/tuple"
-	"strconv"
 	"strings"
 
 	"github.com/zhukovaskychina/xmysql-server/util"
@@ -53,6 +52,9 @@ func NewSecondaryLeafRowHeader(frmMeta tuple.TableRowTuple) basic.FieldDataHeade
 
 	//空值处理
 	clr.NullContent = make([]byte, 0)
+	if frmMeta != nil {
+		clr.NullContent = make([]byte, nullBitmapSize(frmMeta))
+	}
 	//可变长度
 	clr.VarLengthContent = make([]byte, 0)
 
@@ -64,11 +66,8 @@ func NewSecondaryLeafRowHeaderWithContents(frmMeta tuple.TableRowTuple, content
 	var clr = new(SecondaryLeafRowHeader)
 	clr.FrmMeta = frmMeta
 	clr.VarLengthContentMap = make(map[byte]uint16)
-	//计算列长度
-	cl := frmMeta.GetColumnLength()
-	//根据列长度，计算出NULL表长度计算
-	//除以8,得到字节表示长度
-	size := cl&7 + 1
+	//根据可为NULL的列数，计算出NULL表长度
+	size := nullBitmapSize(frmMeta)
 
 	//获得可变长度
 	varColumns := frmMeta.GetVarColumns()
@@ -211,31 +210,20 @@ func (cldr *SecondaryLeafRowHeader) ToByte() []byte {
 }
 
 func (cldr *SecondaryLeafRowHeader) SetValueNull(nullValue byte, index byte) {
-	//不为空
-	nullStrArrays := util.ConvertBytes2BitStrings(cldr.NullContent)
-
-	if index < byte(len(nullStrArrays)) {
-		if len(nullStrArrays) == 0 {
-			nullStrArrays = append(nullStrArrays, "0", "0", "0", "0", "0", "0", "0", "0")
-		}
-		nullStrArrays[byte(len(nullStrArrays))-1-index] = strconv.Itoa(int(nullValue))
-	} else {
-		var newNullStrArrays = make([]string, 0)
-		newNullStrArrays = append(newNullStrArrays, "0", "0", "0", "0", "0", "0", "0")
-		newNullStrArrays = append(newNullStrArrays, strconv.Itoa(int(nullValue)))
-		newNullStrArrays = append(newNullStrArrays, nullStrArrays...)
-		nullStrArrays = newNullStrArrays
+	bit := nullBit(cldr.FrmMeta, index)
+	if bit < 0 {
+		return
 	}
-
-	cldr.NullContent = util.ConvertStringArrays2BytesArrays(nullStrArrays)
+	if size := nullBitmapSize(cldr.FrmMeta); len(cldr.NullContent) != size {
+		cldr.NullContent = make([]byte, size)
+	}
+	setNullBit(cldr.NullContent, bit, nullValue == 1)
 }
 
+// IsValueNullByIdx reports whether the value of column index is NULL.
 func (cldr *SecondaryLeafRowHeader) IsValueNullByIdx(index byte) bool {
-
-	nullStrArrays := util.ConvertBytes2BitStrings(cldr.NullContent)
-
-	return nullStrArrays[len(nullStrArrays)-int(index)-1] == "0"
-
+	bit := nullBit(cldr.FrmMeta, index)
+	return bit >= 0 && isNullBit(cldr.NullContent, bit)
 }
 
 //暂时不考虑溢出页
@@ -278,17 +266,15 @@ func (cldr *SecondaryLeafRowHeader) GetVarValueLengthByIndex(index byte) int {
 */
 func (cldr *SecondaryLeafRowHeader) GetRecordBytesRealLength() int {
 	var result = 0
-	nullSize := len(cldr.NullContent)
 	for i := 0; i < cldr.FrmMeta.GetColumnLength(); i++ {
 		formCols := cldr.FrmMeta.GetColumnInfos(byte(i))
 		fieldType := formCols.FieldType
-		isNull := cldr.NullContent[nullSize-i]
 
-		if isNull == 0 {
+		if !cldr.IsValueNullByIdx(byte(i)) {
 			if fieldType == "VARCHAR" {
 				result = result + int(cldr.VarLengthContentMap[byte(i)])
 			} else {
-				result = result + int(formCols.FieldLength)
+				result = result + formCols.GetMaxByteLength()
 			}
 		}
 
@@ -394,6 +380,10 @@ func (row *SecondaryLeafRow) WriteBytesWithNullWithsPos(content []byte, index by
 		row.header.SetValueNull(1, index)
 		row.header.SetValueLengthByIndex(0, index)
 	} else {
+		var err error
+		if content, err = fixedColumnValue(row.FrmMeta.GetColumnInfos(index), content); err != nil {
+			panic(err)
+		}
 		row.header.SetValueNull(0, index)
 		row.header.SetValueLengthByIndex(len(content), index)
 	}
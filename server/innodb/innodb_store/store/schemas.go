@@ -9,10 +9,14 @@ import (
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/tuple"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
 	"github.com/zhukovaskychina/xmysql-server/util"
 	"io/ioutil"
+	"log"
+	"os"
 	"path"
 	"strings"
+	"sync"
 )
 
 type InfoSchemaManager struct {
@@ -24,6 +28,11 @@ type InfoSchemaManager struct {
 	schemaMap       map[string]schemas.Database
 	pool            *buffer_pool.BufferPool
 	tuplelru        schemas.TupleLRUCache
+	//视图定义，schema名 -> 视图名 -> 视图
+	viewsMu sync.RWMutex
+	views   map[string]map[string]schemas.Table
+	//缓冲池中的页面属于哪个表和索引，见 buffer_page_owners.go
+	owners pageOwners
 }
 
 func (i *InfoSchemaManager) SchemaByID(id int64) (*model.DBInfo, bool) {
@@ -46,6 +55,7 @@ func NewInfoSchemaManager(conf *conf.Cfg, pool *buffer_pool.BufferPool) schemas.
 	infoSchemaManager.sysTableSpace = NewSysTableSpace(conf, false)
 	infoSchemaManager.pool = pool
 	infoSchemaManager.tuplelru = NewTupleLRUCache()
+	infoSchemaManager.views = make(map[string]map[string]schemas.Table)
 	infoSchemaManager.initSysSchemas()
 	return infoSchemaManager
 }
@@ -67,19 +77,19 @@ func (i *InfoSchemaManager) initSysSchemas() {
 	sysDb := NewInfoSchemasDB().(*InfoSchemasDB)
 	memorySystemTable := NewMemoryInnodbSysTable(i.dictionarySys)
 	sysDb.addSystemTable(common.INNODB_SYS_TABLES, memorySystemTable)
-	i.tuplelru.Set(common.INFORMATION_SCHEMAS, common.INNODB_SYS_TABLES, memorySystemTable)
+	i.cacheSysTable(common.INNODB_SYS_TABLES, memorySystemTable)
 	memoryColumnTable := NewMemoryInnodbSysColumns(i.dictionarySys)
 	sysDb.addSystemTable(common.INNODB_SYS_COLUMNS, memoryColumnTable)
-	i.tuplelru.Set(common.INFORMATION_SCHEMAS, common.INNODB_SYS_COLUMNS, memoryColumnTable)
+	i.cacheSysTable(common.INNODB_SYS_COLUMNS, memoryColumnTable)
 	memoryIndexTable := NewMemorySysIndexTable(i.dictionarySys)
 	sysDb.addSystemTable(common.INNODB_SYS_INDEXES, memoryIndexTable)
-	i.tuplelru.Set(common.INFORMATION_SCHEMAS, common.INNODB_SYS_INDEXES, memoryIndexTable)
+	i.cacheSysTable(common.INNODB_SYS_INDEXES, memoryIndexTable)
 	currentDataFilesTable := NewMemoryInnodbDataFiles(16, "SYS_DATAFILES_SPACE", i.pool)
 	sysDb.addSystemTable(common.INNODB_SYS_DATAFILES, currentDataFilesTable)
-	i.tuplelru.Set(common.INFORMATION_SCHEMAS, common.INNODB_SYS_DATAFILES, currentDataFilesTable)
+	i.cacheSysTable(common.INNODB_SYS_DATAFILES, currentDataFilesTable)
 	currentTableSpaceTable := NewMemoryInnodbDataFiles(13, "SYS_TABLESPACES_SPACE", i.pool)
 	sysDb.addSystemTable(common.INNODB_SYS_TABLESPACES, currentTableSpaceTable)
-	i.tuplelru.Set(common.INFORMATION_SCHEMAS, common.INNODB_SYS_TABLESPACES, currentTableSpaceTable)
+	i.cacheSysTable(common.INNODB_SYS_TABLESPACES, currentTableSpaceTable)
 
 	//d := &model.DBInfo{Tables: nil, Name: nil}
 	//TODO 初始化DATAFILES
@@ -87,12 +97,33 @@ func (i *InfoSchemaManager) initSysSchemas() {
 	//sysDb.addSystemTable(common.INNODB_SYS_DATAFILES, nil)
 	i.schemaMap[common.INFORMATION_SCHEMAS] = sysDb
 
-	dbInfo := model.NewDBInfo(model.CIStr{L: strings.ToLower(common.INFORMATION_SCHEMAS), O: common.INFORMATION_SCHEMAS})
+	dbInfo := model.NewDBInfo(model.NewCIStr(common.INFORMATION_SCHEMAS))
 
-	i.schemaDBInfoMap[common.INFORMATION_SCHEMAS] = dbInfo
+	i.schemaDBInfoMap[model.TableNameKey(dbInfo.Name)] = dbInfo
 
 }
 
+// cacheSysTable adds the system table name of INFORMATION_SCHEMAS to the
+// tables of the dictionary.
+func (i *InfoSchemaManager) cacheSysTable(name string, tbl schemas.Table) {
+	db, table := tableKey(model.NewCIStr(common.INFORMATION_SCHEMAS), model.NewCIStr(name))
+	i.tuplelru.Set(db, table, tbl)
+}
+
+// tableKey returns the keys of the database schema and of its table in the
+// maps of the dictionary: the names as given with lower_case_table_names=0,
+// in lower case otherwise.
+func tableKey(schema, table model.CIStr) (string, string) {
+	return model.TableNameKey(schema), model.TableNameKey(table)
+}
+
+// dictTableName returns the NAME of schema.table in SYS_TABLES, its keys
+// joined by a slash.
+func dictTableName(schema, table model.CIStr) string {
+	db, name := tableKey(schema, table)
+	return db + "/" + name
+}
+
 //mysql 不管是啥，在硬盘上创建文件夹，mysql会认为是数据库
 func (i *InfoSchemaManager) loadDatabase() {
 	dataDir := i.conf.DataDir
@@ -111,13 +142,10 @@ func (i *InfoSchemaManager) loadDatabase() {
 			}
 			if isExist {
 				currentDB := &model.DBInfo{
-					Name: model.CIStr{
-						O: dirName,
-						L: strings.ToLower(dirName),
-					},
+					Name:   model.NewCIStr(dirName),
 					Tables: nil,
 				}
-				i.schemaDBInfoMap[dirName] = currentDB
+				i.schemaDBInfoMap[model.TableNameKey(currentDB.Name)] = currentDB
 			}
 		}
 	}
@@ -125,8 +153,8 @@ func (i *InfoSchemaManager) loadDatabase() {
 
 func (i *InfoSchemaManager) SchemaByName(schema model.CIStr) (*model.DBInfo, bool) {
 
-	if i.schemaDBInfoMap[schema.L] != nil {
-		return i.schemaDBInfoMap[schema.L], true
+	if db := i.schemaDBInfoMap[model.TableNameKey(schema)]; db != nil {
+		return db, true
 	}
 	return nil, false
 }
@@ -136,13 +164,231 @@ func (i *InfoSchemaManager) SchemaExists(schema model.CIStr) bool {
 }
 
 func (i *InfoSchemaManager) TableByName(schema, table model.CIStr) (schemas.Table, error) {
-
-	return i.tuplelru.Get(schema.O, table.O)
+	db, name := tableKey(schema, table)
+	i.viewsMu.RLock()
+	view, ok := i.views[db][name]
+	i.viewsMu.RUnlock()
+	if ok {
+		return view, nil
+	}
+	tbl, err := i.tuplelru.Get(db, name)
+	if err != nil {
+		return nil, schemas.ErrTableNotExists.GenByArgs(schema.O, table.O)
+	}
+	return tbl, nil
 
 }
 
 func (i *InfoSchemaManager) TableExists(schema, table model.CIStr) bool {
-	return i.tuplelru.Has(schema.O, table.O)
+	db, name := tableKey(schema, table)
+	i.viewsMu.RLock()
+	_, ok := i.views[db][name]
+	i.viewsMu.RUnlock()
+	if ok {
+		return true
+	}
+	return i.tuplelru.Has(db, name)
+}
+
+func (i *InfoSchemaManager) CreateView(schema model.CIStr, view *model.TableInfo, orReplace bool) error {
+	view.Name = model.StoredTableName(view.Name)
+	db, name := tableKey(schema, view.Name)
+	i.viewsMu.Lock()
+	defer i.viewsMu.Unlock()
+	if old, ok := i.views[db][name]; ok && !orReplace {
+		return schemas.ErrTableExists.GenByArgs(old.TableName())
+	}
+	if i.tuplelru.Has(db, name) {
+		return schemas.ErrTableExists.GenByArgs(view.Name.O)
+	}
+	if i.views[db] == nil {
+		i.views[db] = make(map[string]schemas.Table)
+	}
+	i.views[db][name] = schemas.NewView(view)
+	return nil
+}
+
+func (i *InfoSchemaManager) DropView(schema, view model.CIStr) error {
+	db, name := tableKey(schema, view)
+	i.viewsMu.Lock()
+	defer i.viewsMu.Unlock()
+	if _, ok := i.views[db][name]; !ok {
+		return schemas.ErrTableDropExists.GenByArgs(schema.O + "." + view.O)
+	}
+	delete(i.views[db], name)
+	return nil
+}
+
+// RenameTables renames the tables and views of renames in order. When one
+// of them fails, the ones already renamed are renamed back, so that either
+// all of them apply or none.
+func (i *InfoSchemaManager) RenameTables(renames []schemas.TableRename) error {
+	i.viewsMu.Lock()
+	defer i.viewsMu.Unlock()
+	for k, r := range renames {
+		if err := i.renameTable(r); err != nil {
+			for k--; k >= 0; k-- {
+				undo := renames[k]
+				undo.OldSchema, undo.OldName, undo.NewSchema, undo.NewName = undo.NewSchema, undo.NewName, undo.OldSchema, undo.OldName
+				if undoErr := i.renameTable(undo); undoErr != nil {
+					log.Printf("undo rename of %s.%s: %v", undo.NewSchema.O, undo.NewName.O, undoErr)
+				}
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// renameTable renames one table or view, moving the .frm and .ibd files
+// into the directory of the new database.
+func (i *InfoSchemaManager) renameTable(r schemas.TableRename) error {
+	newDBInfo, ok := i.SchemaByName(r.NewSchema)
+	if !ok {
+		return schemas.ErrDatabaseNotExists.GenByArgs(r.NewSchema.O)
+	}
+	newName := model.StoredTableName(r.NewName)
+	oldDB, oldKey := tableKey(r.OldSchema, r.OldName)
+	newDB, newKey := tableKey(r.NewSchema, newName)
+	if _, ok := i.views[newDB][newKey]; ok || i.tuplelru.Has(newDB, newKey) {
+		return schemas.ErrTableExists.GenByArgs(r.NewName.O)
+	}
+	if view, ok := i.views[oldDB][oldKey]; ok {
+		delete(i.views[oldDB], oldKey)
+		view.Meta().Name = newName
+		if i.views[newDB] == nil {
+			i.views[newDB] = make(map[string]schemas.Table)
+		}
+		i.views[newDB][newKey] = view
+		return nil
+	}
+	tbl, err := i.tuplelru.Get(oldDB, oldKey)
+	if err != nil {
+		return schemas.ErrTableNotExists.GenByArgs(r.OldSchema.O, r.OldName.O)
+	}
+	oldSchemaFile, oldTableFile := tableFileNames(i.conf.DataDir, r.OldSchema.O, r.OldName.O)
+	newSchemaFile := newDBInfo.Name.O
+	if err := renameTableFiles(i.conf.DataDir, oldSchemaFile, oldTableFile, newSchemaFile, newName.O); err != nil {
+		return err
+	}
+	if ordinaryTable, ok := tbl.(*OrdinaryTable); ok {
+		var space *UnSysTableSpace
+		if i.pool != nil {
+			space, _ = i.pool.FileSystem.GetTableSpaceById(ordinaryTable.spaceId).(*UnSysTableSpace)
+		}
+		ordinaryTable.rename(newSchemaFile, newName.O, space)
+	}
+	i.tuplelru.Remove(oldDB, oldKey)
+	return i.tuplelru.Set(newDB, newKey, tbl)
+}
+
+// AlterTableRowFormat rebuilds the table in rowFormat, in
+// innodb_default_row_format when it is empty, and records the new format in
+// its .frm and tablespace flags.
+func (i *InfoSchemaManager) AlterTableRowFormat(schema, table model.CIStr, rowFormat string) error {
+	format, err := TableRowFormat(rowFormat, i.conf)
+	if err != nil {
+		return err
+	}
+	db, name := tableKey(schema, table)
+	i.viewsMu.RLock()
+	_, isView := i.views[db][name]
+	i.viewsMu.RUnlock()
+	if isView {
+		return schemas.ErrWrongObject.GenByArgs(schema.O, table.O, "BASE TABLE")
+	}
+	tbl, err := i.tuplelru.Get(db, name)
+	if err != nil {
+		return schemas.ErrTableNotExists.GenByArgs(schema.O, table.O)
+	}
+	ordinaryTable, ok := tbl.(*OrdinaryTable)
+	if !ok {
+		return fmt.Errorf("table %s.%s can't be rebuilt", schema.O, table.O)
+	}
+	var space *UnSysTableSpace
+	if i.pool != nil {
+		space, _ = i.pool.FileSystem.GetTableSpaceById(ordinaryTable.spaceId).(*UnSysTableSpace)
+	}
+	return ordinaryTable.rebuildRowFormat(format, space)
+}
+
+// AlterTableColumns replaces the definition of the table with tbl, and its
+// rows with rows unless they are nil. The tables of the dictionary keep no
+// column definition to replace yet.
+func (i *InfoSchemaManager) AlterTableColumns(schema, table model.CIStr, tbl *model.TableInfo, rows [][]basic.Datum) error {
+	db, name := tableKey(schema, table)
+	i.viewsMu.RLock()
+	_, isView := i.views[db][name]
+	i.viewsMu.RUnlock()
+	if isView {
+		return schemas.ErrWrongObject.GenByArgs(schema.O, table.O, "BASE TABLE")
+	}
+	if _, err := i.tuplelru.Get(db, name); err != nil {
+		return schemas.ErrTableNotExists.GenByArgs(schema.O, table.O)
+	}
+	return mysql.NewErrf(mysql.ErrNotSupportedYet, "changing the columns of a table")
+}
+
+// tableFileExts are the files a table keeps in its database directory.
+var tableFileExts = []string{".frm", ".ibd"}
+
+// tableFileNames returns the names of the directory of the database schema
+// under dataDir and of the files of its table, by lower_case_table_names: in
+// lower case with 1; with 2 the names on disk equal to them but for the
+// case, which keep the case they were created in.
+func tableFileNames(dataDir, schema, table string) (string, string) {
+	schema, table = model.StoredTableNameString(schema), model.StoredTableNameString(table)
+	if model.LowerCaseTableNames() != model.LowerCaseTableNamesCompare {
+		return schema, table
+	}
+	schema = fileNameOnDisk(dataDir, schema, "")
+	if table != "" {
+		for _, ext := range tableFileExts {
+			if name := fileNameOnDisk(path.Join(dataDir, schema), table, ext); name != table {
+				return schema, name
+			}
+		}
+	}
+	return schema, table
+}
+
+// fileNameOnDisk returns the name, less ext, of the file name+ext of dir in
+// any case, name when there is none.
+func fileNameOnDisk(dir, name, ext string) string {
+	if ok, _ := util.PathExists(path.Join(dir, name+ext)); ok {
+		return name
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return name
+	}
+	for _, f := range files {
+		if strings.EqualFold(f.Name(), name+ext) {
+			return f.Name()[:len(f.Name())-len(ext)]
+		}
+	}
+	return name
+}
+
+// renameTableFiles moves the files of the table oldDB/oldTable under dataDir
+// to newDB/newTable, moving back the ones already moved on failure.
+func renameTableFiles(dataDir, oldDB, oldTable, newDB, newTable string) error {
+	var moved []string
+	for _, ext := range tableFileExts {
+		from := path.Join(dataDir, oldDB, oldTable+ext)
+		to := path.Join(dataDir, newDB, newTable+ext)
+		if ok, _ := util.PathExists(from); !ok {
+			continue
+		}
+		if err := os.Rename(from, to); err != nil {
+			for _, ext := range moved {
+				os.Rename(path.Join(dataDir, newDB, newTable+ext), path.Join(dataDir, oldDB, oldTable+ext))
+			}
+			return err
+		}
+		moved = append(moved, ext)
+	}
+	return nil
 }
 
 func (i *InfoSchemaManager) AllSchemaNames() []string {
@@ -150,15 +396,28 @@ func (i *InfoSchemaManager) AllSchemaNames() []string {
 }
 
 func (i *InfoSchemaManager) AllSchemas() []*model.DBInfo {
-	panic("implement me")
+	dbs := make([]*model.DBInfo, 0, len(i.schemaDBInfoMap))
+	for _, db := range i.schemaDBInfoMap {
+		dbs = append(dbs, db)
+	}
+	return dbs
 }
 
 func (i *InfoSchemaManager) Clone() (result []*model.DBInfo) {
 	panic("implement me")
 }
 
+// SchemaTables returns the views of schema. The tables kept in pages have
+// no TableInfo to describe them yet and are left out.
 func (i *InfoSchemaManager) SchemaTables(schema model.CIStr) []schemas.Table {
-	panic("implement me")
+	i.viewsMu.RLock()
+	defer i.viewsMu.RUnlock()
+	db := model.TableNameKey(schema)
+	tables := make([]schemas.Table, 0, len(i.views[db]))
+	for _, view := range i.views[db] {
+		tables = append(tables, view)
+	}
+	return tables
 }
 
 func (i *InfoSchemaManager) SchemaMetaVersion() int64 {
@@ -185,14 +444,15 @@ func (i *InfoSchemaManager) GetTableByName(schema string, tableName string) (sch
 	if strings.ToUpper(schema) == common.INFORMATION_SCHEMAS {
 		tableName = strings.ToUpper(tableName)
 	}
-	table, err := i.tuplelru.Get(schema, tableName)
+	db, name := tableKey(model.NewCIStr(schema), model.NewCIStr(tableName))
+	table, err := i.tuplelru.Get(db, name)
 	//没有查找到
 	if err != nil {
 		err = nil
 		//查找表的元祖信息
 		memorySystemTable, _ := i.schemaMap[common.INFORMATION_SCHEMAS].GetTable(common.INNODB_SYS_TABLES)
 		memoryIndexTable, _ := i.schemaMap[common.INFORMATION_SCHEMAS].GetTable(common.INNODB_SYS_INDEXES)
-		searchKey := basic.NewVarcharVal([]byte(schema + "/" + tableName))
+		searchKey := basic.NewVarcharVal([]byte(dictTableName(model.NewCIStr(schema), model.NewCIStr(tableName))))
 		var ordinaryTable schemas.Table
 		iterator, _ := memorySystemTable.GetBtree("PRIMARY").Find(searchKey)
 		var found bool
@@ -213,7 +473,8 @@ func (i *InfoSchemaManager) GetTableByName(schema string, tableName string) (sch
 				ordinaryTable.(*OrdinaryTable).ReadFrmTuples()
 
 				//构建TableSpace
-				currentTableSpace := NewTableSpaceFile(i.conf, schema, tableName, spaceId.Raw().(uint32), false, i.pool)
+				schemaFile, tableFile := tableFileNames(i.conf.DataDir, schema, tableName)
+				currentTableSpace := NewTableSpaceFile(i.conf, schemaFile, tableFile, spaceId.Raw().(uint32), false, i.pool)
 				i.pool.FileSystem.AddTableSpace(currentTableSpace)
 
 				//构建表的主键索引，secondary 索引
@@ -271,12 +532,17 @@ func (i *InfoSchemaManager) GetTableByName(schema string, tableName string) (sch
 						)
 						btree := NewBtreeWithBufferPool(spaceIdValue.Raw().(uint32), rootPageValue.Raw().(uint32),
 							currentIndexNameValue.ToString(), internalSegments, dataSegments, rootIndex, i.pool, internalTuple, leafTuple)
+						if indexId := dictIndexId(row.GetValueByColName("INDEX_ID")); indexId != 0 {
+							btree.SetIndexId(indexId)
+							btree.ownPage(bufferBlock, rootIndex)
+							i.owners.add(ordinaryTable.(*OrdinaryTable), currentIndexNameValue.ToString(), indexId)
+						}
 						ordinaryTable.(*OrdinaryTable).AddBTree(currentIndexNameValue.ToString(), btree)
 
 						return nil
 					})
 				}
-				i.tuplelru.Set(schema, tableName, ordinaryTable)
+				i.tuplelru.Set(db, name, ordinaryTable)
 			}
 		}
 
@@ -5,6 +5,13 @@ import (
 	"github.com/zhukovaskychina/xmysql-server/server/conf"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/buffer_pool"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/terror"
+	"github.com/zhukovaskychina/xmysql-server/util"
+	"io/ioutil"
+	"os"
+	"path"
 	"testing"
 )
 
@@ -25,3 +32,144 @@ func TestSchemaInit(t *testing.T) {
 		fmt.Println(schemaManager)
 	})
 }
+
+func TestRenameTables(t *testing.T) {
+	dataDir, err := ioutil.TempDir("", "rename")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dataDir)
+	for _, name := range []string{"test/t.frm", "test/t.ibd", "test/t_new.frm", "test/t_new.ibd"} {
+		os.MkdirAll(path.Join(dataDir, path.Dir(name)), os.ModePerm)
+		ioutil.WriteFile(path.Join(dataDir, name), []byte(name), os.ModePerm)
+	}
+	os.MkdirAll(path.Join(dataDir, "shop"), os.ModePerm)
+
+	cfg := conf.NewCfg()
+	cfg.DataDir = dataDir
+	i := &InfoSchemaManager{
+		conf: cfg,
+		schemaDBInfoMap: map[string]*model.DBInfo{
+			"test": {Name: model.NewCIStr("test")},
+			"shop": {Name: model.NewCIStr("shop")},
+		},
+		tuplelru: NewTupleLRUCache(),
+		views:    make(map[string]map[string]schemas.Table),
+	}
+	tbl, tblNew := NewOrdinaryTable(cfg, 1, 1, "test/t"), NewOrdinaryTable(cfg, 2, 2, "test/t_new")
+	i.tuplelru.Set("test", "t", tbl)
+	i.tuplelru.Set("test", "t_new", tblNew)
+	rename := func(oldSchema, oldName, newSchema, newName string) schemas.TableRename {
+		return schemas.TableRename{
+			OldSchema: model.NewCIStr(oldSchema), OldName: model.NewCIStr(oldName),
+			NewSchema: model.NewCIStr(newSchema), NewName: model.NewCIStr(newName),
+		}
+	}
+	checkFile := func(name, content string) {
+		data, err := ioutil.ReadFile(path.Join(dataDir, name))
+		if err != nil || string(data) != content {
+			t.Fatalf("expect %s holding %s, got %q, %v", name, content, data, err)
+		}
+	}
+
+	// A failing rename undoes the ones before it.
+	err = i.RenameTables([]schemas.TableRename{rename("test", "t", "shop", "t_old"), rename("test", "t_new", "nodb", "t")})
+	if !terror.ErrorEqual(err, schemas.ErrDatabaseNotExists) {
+		t.Fatalf("expect unknown database, got %v", err)
+	}
+	checkFile("test/t.ibd", "test/t.ibd")
+	if !i.TableExists(model.NewCIStr("test"), model.NewCIStr("t")) || i.TableExists(model.NewCIStr("shop"), model.NewCIStr("t_old")) {
+		t.Fatal("expect test.t not renamed")
+	}
+
+	if err := i.RenameTables([]schemas.TableRename{rename("test", "t", "shop", "t_old"), rename("test", "t_new", "test", "t")}); err != nil {
+		t.Fatal(err)
+	}
+	checkFile("shop/t_old.frm", "test/t.frm")
+	checkFile("shop/t_old.ibd", "test/t.ibd")
+	checkFile("test/t.ibd", "test/t_new.ibd")
+	if got, _ := i.TableByName(model.NewCIStr("shop"), model.NewCIStr("t_old")); got != tbl {
+		t.Fatal("expect shop.t_old to be the old test.t")
+	}
+	if got, _ := i.TableByName(model.NewCIStr("test"), model.NewCIStr("t")); got != tblNew {
+		t.Fatal("expect test.t to be the old test.t_new")
+	}
+	if tbl.(*OrdinaryTable).fullName != "shop/t_old" {
+		t.Fatalf("unexpected table name %s", tbl.(*OrdinaryTable).fullName)
+	}
+	if err := i.RenameTables([]schemas.TableRename{rename("shop", "t_old", "test", "t")}); !terror.ErrorEqual(err, schemas.ErrTableExists) {
+		t.Fatalf("expect table exists, got %v", err)
+	}
+}
+
+func TestTableNameCase(t *testing.T) {
+	defer model.SetLowerCaseTableNames(model.LowerCaseTableNamesSensitive)
+	dataDir, err := ioutil.TempDir("", "case")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dataDir)
+	for _, name := range []string{"test/Orders.frm", "test/Orders.ibd"} {
+		os.MkdirAll(path.Join(dataDir, path.Dir(name)), os.ModePerm)
+		ioutil.WriteFile(path.Join(dataDir, name), []byte(name), os.ModePerm)
+	}
+	os.MkdirAll(path.Join(dataDir, "shop"), os.ModePerm)
+	cfg := conf.NewCfg()
+	cfg.DataDir = dataDir
+	newManager := func() *InfoSchemaManager {
+		i := &InfoSchemaManager{
+			conf:            cfg,
+			schemaDBInfoMap: make(map[string]*model.DBInfo),
+			tuplelru:        NewTupleLRUCache(),
+			views:           make(map[string]map[string]schemas.Table),
+		}
+		for _, db := range []string{"test", "shop"} {
+			i.schemaDBInfoMap[model.TableNameKey(model.NewCIStr(db))] = &model.DBInfo{Name: model.NewCIStr(db)}
+		}
+		db, name := tableKey(model.NewCIStr("test"), model.NewCIStr("Orders"))
+		i.tuplelru.Set(db, name, NewOrdinaryTable(cfg, 1, 1, "test/Orders"))
+		return i
+	}
+	exists := func(i *InfoSchemaManager, schema, table string) bool {
+		return i.TableExists(model.NewCIStr(schema), model.NewCIStr(table))
+	}
+
+	// 0: names compare in case.
+	i := newManager()
+	if !exists(i, "test", "Orders") || exists(i, "test", "orders") || exists(i, "TEST", "Orders") {
+		t.Fatal("expect only test.Orders to name the table with lower_case_table_names=0")
+	}
+
+	// 2: names compare in lower case, the files keep the case they were
+	// created in, and renamed ones take the case of the new name.
+	model.SetLowerCaseTableNames(model.LowerCaseTableNamesCompare)
+	i = newManager()
+	if !exists(i, "TEST", "ORDERS") || !exists(i, "test", "orders") {
+		t.Fatal("expect TEST.ORDERS to name test.Orders with lower_case_table_names=2")
+	}
+	if schema, table := tableFileNames(dataDir, "TEST", "orders"); schema != "test" || table != "Orders" {
+		t.Fatalf("expect the files test/Orders, got %s/%s", schema, table)
+	}
+	err = i.RenameTables([]schemas.TableRename{{
+		OldSchema: model.NewCIStr("test"), OldName: model.NewCIStr("ORDERS"),
+		NewSchema: model.NewCIStr("Shop"), NewName: model.NewCIStr("Items"),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := util.PathExists(path.Join(dataDir, "shop", "Items.ibd")); !ok {
+		t.Fatal("expect shop/Items.ibd")
+	}
+	if !exists(i, "shop", "items") || exists(i, "test", "orders") {
+		t.Fatal("expect test.Orders renamed to shop.Items")
+	}
+
+	// 1: names are stored in lower case.
+	model.SetLowerCaseTableNames(model.LowerCaseTableNamesLower)
+	if schema, table := tableFileNames(dataDir, "Shop", "Items"); schema != "shop" || table != "items" {
+		t.Fatalf("expect the files shop/items, got %s/%s", schema, table)
+	}
+	if name := model.StoredTableName(model.NewCIStr("Items")); name.O != "items" {
+		t.Fatalf("expect the name stored as items, got %s", name.O)
+	}
+}
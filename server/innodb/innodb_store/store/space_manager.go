@@ -0,0 +1,250 @@
+package store
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/zhukovaskychina/xmysql-server/server/common"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/buffer_pool"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/innodb_store/store/storebytes/blocks"
+	"github.com/zhukovaskychina/xmysql-server/util"
+)
+
+/**
+表空间文件的收缩
+
+ibdata1 和 .ibd 文件按 autoextend 增长，但从不缩小。TruncateUnusedExtents 从文件末尾
+向前检查区，连续的空闲区（XDES 状态为 XDES_FREE，不属于任何段，缓冲池中也没有它们的
+页面或写缓冲）从 FSP_FREE 链表中摘除，FSP_SIZE 改为剩下的页面数，然后截断文件。
+
+每组区的第一个区存放描述这组区的 XDES 页面（第一组为 FSP_HDR 页面），这样的区从不截断，
+收缩到此为止。一组区的个数和区的页面数由页面大小决定，见 common/page_size.go。
+**/
+
+// FSP_HDR 和 XDES 页面中的偏移量
+const (
+	fspSizeOffset      = 46
+	fspFreeLimitOffset = 50
+	fspFreeListOffset  = 62
+	xdesArrayOffset    = 150
+	xdesStateOffset    = 20
+)
+
+// pagesPerExtent, extentsPerXDesPage and xdesEntrySize are the geometry
+// of the XDES pages for the page size.
+func pagesPerExtent() uint32 { return uint32(common.ExtentPages()) }
+
+func extentsPerXDesPage() uint32 { return uint32(common.ExtentsPerXDesPage()) }
+
+func xdesEntrySize() uint32 { return uint32(common.XDesEntrySize()) }
+
+// SpaceManager keeps the data files of the tablespaces, ibdata1 and the
+// .ibd files, by space id.
+type SpaceManager struct {
+	mu    sync.Mutex
+	files map[uint32]*blocks.BlockFile
+	// pool holds the pages being used, which are never released.
+	pool *buffer_pool.BufferPool
+}
+
+// NewSpaceManager returns a space manager with no space, whose pages are
+// cached in pool.
+func NewSpaceManager(pool *buffer_pool.BufferPool) *SpaceManager {
+	return &SpaceManager{files: make(map[uint32]*blocks.BlockFile), pool: pool}
+}
+
+// AddSpace makes file the data file of space spaceId.
+func (sm *SpaceManager) AddSpace(spaceId uint32, file *blocks.BlockFile) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.files[spaceId] = file
+}
+
+// TruncateUnusedExtents gives the free extents at the end of the data file
+// of space spaceId back to the OS and returns their number.
+func (sm *SpaceManager) TruncateUnusedExtents(spaceId uint32) (uint32, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	file, ok := sm.files[spaceId]
+	if !ok {
+		return 0, errors.Errorf("tablespace %d is not open", spaceId)
+	}
+	descs := &xdesPages{file: file, pages: make(map[uint32][]byte)}
+	fsp, err := descs.page(0)
+	if err != nil {
+		return 0, err
+	}
+	size := util.ReadUB4Byte2UInt32(fsp[fspSizeOffset : fspSizeOffset+4])
+	extents := size / pagesPerExtent()
+	keep := extents
+	for keep > 1 {
+		unused, err := sm.extentUnused(spaceId, descs, keep-1)
+		if err != nil {
+			return 0, err
+		}
+		if !unused {
+			break
+		}
+		keep--
+	}
+	if keep == extents {
+		return 0, nil
+	}
+
+	for extent := keep; extent < extents; extent++ {
+		if err = descs.removeFromFreeList(extent); err != nil {
+			return 0, err
+		}
+	}
+	newSize := keep * pagesPerExtent()
+	copy(fsp[fspSizeOffset:], util.ConvertUInt4Bytes(newSize))
+	if util.ReadUB4Byte2UInt32(fsp[fspFreeLimitOffset:fspFreeLimitOffset+4]) > newSize {
+		copy(fsp[fspFreeLimitOffset:], util.ConvertUInt4Bytes(newSize))
+	}
+	// 先写表空间头，再截断文件
+	for pageNo, content := range descs.pages {
+		if pageNo >= newSize {
+			continue
+		}
+		if err = file.WriteContentByPage(int64(pageNo), content); err != nil {
+			return 0, err
+		}
+		if sm.pool != nil {
+			sm.pool.DiscardPage(spaceId, pageNo)
+		}
+	}
+	if err = file.Truncate(int64(newSize) * int64(common.PageSize())); err != nil {
+		return 0, err
+	}
+	return extents - keep, nil
+}
+
+// extentUnused reports whether no page of extent is used: the extent is
+// free, in no segment, holds no descriptor page and none of its pages is
+// in the buffer pool.
+func (sm *SpaceManager) extentUnused(spaceId uint32, descs *xdesPages, extent uint32) (bool, error) {
+	if extent%extentsPerXDesPage() == 0 {
+		return false, nil
+	}
+	entry, err := descs.entry(extent)
+	if err != nil {
+		return false, err
+	}
+	if util.ReadUB4Byte2UInt32(entry[xdesStateOffset:xdesStateOffset+4]) != uint32(common.XDES_FREE) ||
+		util.ReadUB8Byte2Long(entry[0:8]) != 0 {
+		return false, nil
+	}
+	if sm.pool != nil {
+		for pageNo := extent * pagesPerExtent(); pageNo < (extent+1)*pagesPerExtent(); pageNo++ {
+			if sm.pool.HoldsPage(spaceId, pageNo) {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+// xdesPages are the descriptor pages of a space read from its file; the
+// changes to them are written back together.
+type xdesPages struct {
+	file  *blocks.BlockFile
+	pages map[uint32][]byte
+}
+
+func (d *xdesPages) page(pageNo uint32) ([]byte, error) {
+	if content, ok := d.pages[pageNo]; ok {
+		return content, nil
+	}
+	content, err := d.file.ReadPageByNumber(pageNo)
+	if err != nil {
+		return nil, err
+	}
+	d.pages[pageNo] = content
+	return content, nil
+}
+
+// entry returns the XDES entry of extent in its descriptor page.
+func (d *xdesPages) entry(extent uint32) ([]byte, error) {
+	pageNo, offset := xdesAddr(extent)
+	content, err := d.page(pageNo)
+	if err != nil {
+		return nil, err
+	}
+	return content[offset : uint32(offset)+xdesEntrySize()], nil
+}
+
+// node returns the 12 bytes of the list node at (pageNo, offset), nil for
+// the end of a list.
+func (d *xdesPages) node(pageNo uint32, offset uint16) ([]byte, error) {
+	if pageNo == pageNull || offset == 0 {
+		return nil, nil
+	}
+	if offset < xdesArrayOffset || uint32(offset-xdesArrayOffset)%xdesEntrySize() != 8 ||
+		uint32(offset)+12 > xdesArrayOffset+extentsPerXDesPage()*xdesEntrySize() {
+		return nil, errors.Errorf("bad extent list node at page %d offset %d", pageNo, offset)
+	}
+	content, err := d.page(pageNo)
+	if err != nil {
+		return nil, err
+	}
+	return content[offset : offset+12], nil
+}
+
+// removeFromFreeList takes extent out of FSP_FREE and clears its entry.
+func (d *xdesPages) removeFromFreeList(extent uint32) error {
+	entry, err := d.entry(extent)
+	if err != nil {
+		return err
+	}
+	pageNo, offset := xdesAddr(extent)
+	offset += 8
+	fsp, _ := d.page(0)
+	base := fsp[fspFreeListOffset : fspFreeListOffset+16]
+	prev, next := entry[8:14], entry[14:20]
+	first, last := base[4:10], base[10:16]
+	linked := flstAddrIs(first, pageNo, offset) || flstAddrIs(last, pageNo, offset)
+	if node, err := d.node(flstAddr(prev)); err != nil {
+		return err
+	} else if node != nil && flstAddrIs(node[6:12], pageNo, offset) {
+		copy(node[6:12], next)
+		linked = true
+	}
+	if node, err := d.node(flstAddr(next)); err != nil {
+		return err
+	} else if node != nil && flstAddrIs(node[0:6], pageNo, offset) {
+		copy(node[0:6], prev)
+		linked = true
+	}
+	if flstAddrIs(first, pageNo, offset) {
+		copy(first, next)
+	}
+	if flstAddrIs(last, pageNo, offset) {
+		copy(last, prev)
+	}
+	if length := util.ReadUB4Byte2UInt32(base[0:4]); linked && length > 0 {
+		copy(base[0:4], util.ConvertUInt4Bytes(length-1))
+	}
+	for i := range entry {
+		entry[i] = 0
+	}
+	return nil
+}
+
+// pageNull is FIL_NULL, the page of the end of a list.
+const pageNull = 0xFFFFFFFF
+
+// xdesAddr returns the descriptor page and the offset of the XDES entry of
+// extent.
+func xdesAddr(extent uint32) (uint32, uint16) {
+	pageNo := extent / extentsPerXDesPage() * uint32(common.PageSize())
+	return pageNo, uint16(xdesArrayOffset + extent%extentsPerXDesPage()*xdesEntrySize())
+}
+
+func flstAddr(addr []byte) (uint32, uint16) {
+	return util.ReadUB4Byte2UInt32(addr[0:4]), util.ReadUB2Byte2Int(addr[4:6])
+}
+
+func flstAddrIs(addr []byte, pageNo uint32, offset uint16) bool {
+	p, o := flstAddr(addr)
+	return p == pageNo && o == offset
+}
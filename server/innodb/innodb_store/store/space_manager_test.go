@@ -0,0 +1,152 @@
+package store
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/common"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/buffer_pool"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/innodb_store/store/storebytes/blocks"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/innodb_store/store/storebytes/pages"
+	"github.com/zhukovaskychina/xmysql-server/util"
+)
+
+// setExtent writes the segment and the state of extent to its XDES entry.
+func setExtent(fsp []byte, extent uint32, segId uint64, state common.XDES_STATE) {
+	_, offset := xdesAddr(extent)
+	copy(fsp[offset:], util.ConvertULong8Bytes(segId))
+	copy(fsp[offset+xdesStateOffset:], util.ConvertUInt4Bytes(uint32(state)))
+}
+
+// linkFreeList makes extents, in order, the FSP_FREE list.
+func linkFreeList(fsp []byte, extents ...uint32) {
+	addr := func(extent uint32) []byte {
+		if extent == pageNull {
+			return append(util.ConvertUInt4Bytes(pageNull), 0, 0)
+		}
+		pageNo, offset := xdesAddr(extent)
+		return append(util.ConvertUInt4Bytes(pageNo), util.ConvertUInt2Bytes(offset+8)...)
+	}
+	base := fsp[fspFreeListOffset:]
+	copy(base, util.ConvertUInt4Bytes(uint32(len(extents))))
+	copy(base[4:], addr(extents[0]))
+	copy(base[10:], addr(extents[len(extents)-1]))
+	for i, extent := range extents {
+		prev, next := uint32(pageNull), uint32(pageNull)
+		if i > 0 {
+			prev = extents[i-1]
+		}
+		if i+1 < len(extents) {
+			next = extents[i+1]
+		}
+		_, offset := xdesAddr(extent)
+		copy(fsp[offset+8:], addr(prev))
+		copy(fsp[offset+14:], addr(next))
+	}
+}
+
+// freeList returns the extents of the FSP_FREE list and its length.
+func freeList(t *testing.T, fsp []byte) ([]uint32, uint32) {
+	base := fsp[fspFreeListOffset:]
+	var extents []uint32
+	pageNo, offset := flstAddr(base[4:10])
+	for pageNo != pageNull && offset != 0 {
+		if len(extents) > int(extentsPerXDesPage()) {
+			t.Fatal("FSP_FREE has a cycle")
+		}
+		extent := pageNo/uint32(common.PageSize())*extentsPerXDesPage() + uint32(offset-8-xdesArrayOffset)/xdesEntrySize()
+		extents = append(extents, extent)
+		pageNo, offset = flstAddr(fsp[offset+6 : offset+12])
+	}
+	return extents, util.ReadUB4Byte2UInt32(base[0:4])
+}
+
+func TestTruncateUnusedExtents(t *testing.T) {
+	dir, err := ioutil.TempDir("", "space_manager")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	const extents = 16
+	file := blocks.NewBlockFile(dir, "t1.ibd", int64(extents*pagesPerExtent())*common.PAGE_SIZE)
+	file.CreateFile()
+	defer file.Close()
+
+	// Extents 1 to 3 and 10 are in segments, the others are free.
+	fspWrapper := NewFspInitialize(5).(*Fsp)
+	fspWrapper.SetFspSize(extents * pagesPerExtent())
+	fspWrapper.SetFreeLimit(extents * pagesPerExtent())
+	fsp := fspWrapper.GetSerializeBytes()
+	for extent := uint32(1); extent < extents; extent++ {
+		setExtent(fsp, extent, 0, common.XDES_FREE)
+	}
+	for _, extent := range []uint32{1, 2, 3, 10} {
+		setExtent(fsp, extent, uint64(extent), common.XDES_FSEG)
+	}
+	linkFreeList(fsp, 4, 5, 6, 7, 8, 9, 11, 12, 13, 14, 15)
+	file.WriteContentByPage(0, fsp)
+	data := bytes.Repeat([]byte("xmysql"), common.PAGE_SIZE/6+1)[:common.PAGE_SIZE]
+	file.WriteContentByPage(int64(3*pagesPerExtent()+5), data)
+
+	pool := buffer_pool.NewBufferPool(16*16384, 0.75, 0.25, 1000, nil)
+	sm := NewSpaceManager(pool)
+	if _, err = sm.TruncateUnusedExtents(5); err == nil {
+		t.Fatal("expect an error for a space not open")
+	}
+	sm.AddSpace(5, file)
+	fileSize := func() int64 {
+		info, err := file.StorageFile.Stat()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return info.Size()
+	}
+
+	// A page of the last extent in the pool keeps it.
+	pool.ChangeBuffer.RegisterMerger(5, "idx", func(frame []byte, records [][]byte) ([]byte, error) { return frame, nil })
+	pool.BufferInsert(5, 15*pagesPerExtent()+1, "idx", []byte("a"))
+	if n, err := sm.TruncateUnusedExtents(5); err != nil || n != 0 || fileSize() != int64(extents*pagesPerExtent())*common.PAGE_SIZE {
+		t.Fatalf("expect nothing truncated, got %d extents, %v", n, err)
+	}
+	pool.ChangeBuffer = buffer_pool.NewChangeBuffer(buffer_pool.ChangeBufferingAll)
+
+	n, err := sm.TruncateUnusedExtents(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 5 || fileSize() != int64(11*pagesPerExtent())*common.PAGE_SIZE {
+		t.Fatalf("expect extents 11 to 15 truncated, got %d extents and %d bytes", n, fileSize())
+	}
+	fsp, _ = file.ReadPageByNumber(0)
+	if size := util.ReadUB4Byte2UInt32(fsp[fspSizeOffset:]); size != 11*pagesPerExtent() {
+		t.Fatalf("expect FSP_SIZE %d, got %d", 11*pagesPerExtent(), size)
+	}
+	if list, length := freeList(t, fsp); length != 6 || len(list) != 6 || list[5] != 9 {
+		t.Fatalf("expect FSP_FREE 4 to 9, got %v of length %d", list, length)
+	}
+
+	// Freeing extent 10 lets everything after extent 3 go.
+	setExtent(fsp, 10, 0, common.XDES_FREE)
+	linkFreeList(fsp, 4, 5, 6, 7, 8, 9, 10)
+	file.WriteContentByPage(0, fsp)
+	if n, err = sm.TruncateUnusedExtents(5); err != nil || n != 7 {
+		t.Fatalf("expect 7 extents truncated, got %d, %v", n, err)
+	}
+	fsp, _ = file.ReadPageByNumber(0)
+	if list, length := freeList(t, fsp); length != 0 || len(list) != 0 {
+		t.Fatalf("expect FSP_FREE empty, got %v of length %d", list, length)
+	}
+	if size := util.ReadUB4Byte2UInt32(fsp[fspFreeLimitOffset:]); size != 4*pagesPerExtent() || fileSize() != int64(4*pagesPerExtent())*common.PAGE_SIZE {
+		t.Fatalf("expect FSP_FREE_LIMIT %d, got %d", 4*pagesPerExtent(), size)
+	}
+	// The page was written with its checksum.
+	pages.StampChecksum(data)
+	if content, _ := file.ReadPageByNumber(3*pagesPerExtent() + 5); !bytes.Equal(content, data) {
+		t.Fatal("expect the pages in use untouched")
+	}
+	if n, err = sm.TruncateUnusedExtents(5); err != nil || n != 0 {
+		t.Fatalf("expect nothing left to truncate, got %d, %v", n, err)
+	}
+}
@@ -3,6 +3,7 @@ package blocks
 import (
 	"fmt"
 	"github.com/zhukovaskychina/xmysql-server/server/common"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/innodb_store/store/storebytes/pages"
 	"github.com/zhukovaskychina/xmysql-server/util"
 	"io"
 	"log"
@@ -130,13 +131,13 @@ func (blockFile *BlockFile) ReadPageByNumber(pageNumber uint32) ([]byte, error)
 
 	// 都是含前不含后的概念
 	// offset是从0开始的, 可以比当前的文件内容长度大，多出的部分会用空(0)来代替
-	_, err := blockFile.StorageFile.Seek(int64(pageNumber)*common.PAGE_SIZE, io.SeekStart)
+	_, err := blockFile.StorageFile.Seek(int64(pageNumber)*int64(common.PageSize()), io.SeekStart)
 	if err != nil {
 		log.Fatal(err)
 		return nil, err
 	}
-	b := make([]byte, common.PAGE_SIZE)
-	_, err = blockFile.StorageFile.ReadAt(b, int64(pageNumber*(common.PAGE_SIZE)))
+	b := make([]byte, common.PageSize())
+	_, err = blockFile.StorageFile.ReadAt(b, int64(pageNumber)*int64(common.PageSize()))
 	if err != nil {
 		log.Fatal(err)
 		return nil, err
@@ -186,11 +187,12 @@ func (blockFile *BlockFile) WriteFileBySeekStart(offset uint64, data []byte) {
 }
 
 //****
-//根据
+//根据页面号写入页面，整个页面写入时写入校验和
 //***//
 func (blockFile *BlockFile) WriteContentByPage(pageNum int64, data []byte) error {
 	blockFile.OpenFile()
-	_, err := blockFile.StorageFile.Seek(int64(pageNum)*common.PAGE_SIZE, io.SeekStart)
+	data = withChecksum(data)
+	_, err := blockFile.StorageFile.Seek(int64(pageNum)*int64(common.PageSize()), io.SeekStart)
 	blockFile.AddRead()
 	if err != nil {
 		log.Fatal(err)
@@ -199,7 +201,7 @@ func (blockFile *BlockFile) WriteContentByPage(pageNum int64, data []byte) error
 	}
 	blockFile.RealeaseRead()
 	blockFile.AddWrite()
-	_, err = blockFile.StorageFile.WriteAt(data, int64(pageNum)*common.PAGE_SIZE)
+	_, err = blockFile.StorageFile.WriteAt(data, int64(pageNum)*int64(common.PageSize()))
 
 	if err != nil {
 		blockFile.RealeaseWrite()
@@ -210,6 +212,17 @@ func (blockFile *BlockFile) WriteContentByPage(pageNum int64, data []byte) error
 	return nil
 }
 
+// withChecksum returns a copy of data with its checksum when it is a whole
+// page, data itself otherwise, leaving the buffer of the caller as it is.
+func withChecksum(data []byte) []byte {
+	if len(data) != common.PageSize() {
+		return data
+	}
+	page := append([]byte(nil), data...)
+	pages.StampChecksum(page)
+	return page
+}
+
 func (blockFile *BlockFile) Size() int64 {
 	if blockFile.OpenState == 2 {
 		blockFile.OpenFile()
@@ -219,20 +232,27 @@ func (blockFile *BlockFile) Size() int64 {
 	return fd.Size()
 }
 
+// Truncate cuts the file to size bytes and gives the rest back to the OS.
+func (blockFile *BlockFile) Truncate(size int64) error {
+	blockFile.OpenFile()
+	return blockFile.StorageFile.Truncate(size)
+}
+
 /**
 根据页面号写入页面
 **/
 func (blockFile *BlockFile) WritePageContentFileBySeekStart(pageOffset uint64, data []byte) error {
 	blockFile.OpenFile()
+	data = withChecksum(data)
 	blockFile.AddRead()
-	_, err := blockFile.StorageFile.Seek(int64(pageOffset)*16384, io.SeekStart)
+	_, err := blockFile.StorageFile.Seek(int64(pageOffset)*int64(common.PageSize()), io.SeekStart)
 	if err != nil {
 		log.Fatal(err)
 		blockFile.RealeaseRead()
 		return err
 	}
 	blockFile.AddWrite()
-	_, err = blockFile.StorageFile.WriteAt(data, int64(pageOffset)*16384)
+	_, err = blockFile.StorageFile.WriteAt(data, int64(pageOffset)*int64(common.PageSize()))
 
 	if err != nil {
 		blockFile.RealeaseWrite()
@@ -255,7 +275,7 @@ func (blockFile *BlockFile) Do(offset uint32, do func([]byte) error) error {
 
 //通常加载64个页面
 func (blockFile BlockFile) DoRange(startOffset uint32, endOffset uint32, do func([]byte, uint32, uint32) error) error {
-	bytes, err := blockFile.ReadFileBySeekStartWithSize(uint64(startOffset*common.PAGE_SIZE), int64((endOffset-startOffset)*(common.PAGE_SIZE)))
+	bytes, err := blockFile.ReadFileBySeekStartWithSize(uint64(startOffset)*uint64(common.PageSize()), int64(endOffset-startOffset)*int64(common.PageSize()))
 	if err != nil {
 		return err
 	}
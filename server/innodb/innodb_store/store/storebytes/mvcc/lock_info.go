@@ -0,0 +1,267 @@
+package mvcc
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+/**
+行锁
+
+SELECT ... LOCK IN SHARE MODE 加共享锁（S），SELECT ... FOR UPDATE 和修改行的语句加排他锁（X）。
+S 与 S 兼容，多个事务可以同时持有一行的共享锁；S 与 X、X 与 X 不兼容。
+
+每行的锁按申请的顺序排成队列，一个锁只有与排在它前面的其他事务的锁（已授予的和等待中的）都兼容时才授予，
+这样等待中的 X 锁不会被之后不断到来的 S 锁饿死。同一事务已持有的锁不阻塞自己，持有 S 锁的事务可以升级为 X 锁。
+等待超过 innodb_lock_wait_timeout 返回 ER_LOCK_WAIT_TIMEOUT；事务提交或回滚时释放它的全部行锁。
+
+死锁检测：一个锁要等待时，沿着等待关系（等待的事务 -> 排在它前面、与它不兼容的锁的事务）找下去，
+如果又回到申请锁的事务，就是死锁。这时不再等待，撤掉这个锁并返回 ER_LOCK_DEADLOCK，
+由申请锁的事务（牺牲者）回滚并释放它持有的锁，其他事务继续。
+**/
+
+// LockMode is the mode of a row lock.
+type LockMode int
+
+const (
+	// LockModeS is the shared lock of LOCK IN SHARE MODE.
+	LockModeS LockMode = iota
+	// LockModeX is the exclusive lock of FOR UPDATE and of the changes.
+	LockModeX
+)
+
+func (m LockMode) String() string {
+	switch m {
+	case LockModeS:
+		return "S"
+	case LockModeX:
+		return "X"
+	}
+	return "LockMode(" + strconv.Itoa(int(m)) + ")"
+}
+
+// lockCompatible tells, by the mode of a lock of a transaction and the mode
+// of a lock of another one, whether both can be held on the same row.
+var lockCompatible = [2][2]bool{
+	LockModeS: {LockModeS: true, LockModeX: false},
+	LockModeX: {LockModeS: false, LockModeX: false},
+}
+
+// SelectLockMode returns the mode of the row locks a SELECT with lock
+// takes, false when it takes none.
+func SelectLockMode(lock ast.SelectLockType) (LockMode, bool) {
+	switch lock {
+	case ast.SelectLockInShareMode:
+		return LockModeS, true
+	case ast.SelectLockForUpdate:
+		return LockModeX, true
+	}
+	return 0, false
+}
+
+// rowLock is a lock of a transaction on a row, granted or waiting.
+type rowLock struct {
+	trxId   TrxId
+	mode    LockMode
+	granted bool
+}
+
+// rowLocks is the queue of the locks of a row, in the order they were asked
+// for.
+type rowLocks struct {
+	queue []*rowLock
+	// changed is closed when locks of the row are granted.
+	changed chan struct{}
+}
+
+// grantable reports whether the lock at i of the queue can be granted: it
+// is compatible with the locks of the other transactions ahead of it.
+func (r *rowLocks) grantable(i int) bool {
+	lock := r.queue[i]
+	for _, ahead := range r.queue[:i] {
+		if ahead.trxId != lock.trxId && !lockCompatible[ahead.mode][lock.mode] {
+			return false
+		}
+	}
+	return true
+}
+
+// grant grants the waiting locks that can be, waking their transactions.
+func (r *rowLocks) grant() {
+	var granted bool
+	for i, lock := range r.queue {
+		if !lock.granted && r.grantable(i) {
+			lock.granted = true
+			granted = true
+		}
+	}
+	if granted {
+		close(r.changed)
+		r.changed = make(chan struct{})
+	}
+}
+
+// LockInfoManager keeps the row locks of the transactions.
+type LockInfoManager struct {
+	mu sync.Mutex
+	// rows are the locks of the rows locked, by table id and row key.
+	rows map[string]*rowLocks
+	// trxRows are the rows each transaction locks or waits for.
+	trxRows map[TrxId][]string
+}
+
+// NewLockInfoManager creates a LockInfoManager holding no lock.
+func NewLockInfoManager() *LockInfoManager {
+	return &LockInfoManager{
+		rows:    make(map[string]*rowLocks),
+		trxRows: make(map[TrxId][]string),
+	}
+}
+
+// rowName is the name of the row of key of the table tableId in the locks.
+func rowName(tableId uint64, key []byte) string {
+	return strconv.FormatUint(tableId, 10) + ":" + string(key)
+}
+
+// LockRow locks the row of key of the table tableId in mode for the
+// transaction trxId, waiting for the locks of the other transactions it
+// conflicts with to be released. It waits timeout at most, then gives up
+// with ER_LOCK_WAIT_TIMEOUT.
+func (m *LockInfoManager) LockRow(trxId TrxId, tableId uint64, key []byte, mode LockMode, timeout time.Duration) error {
+	name := rowName(tableId, key)
+	m.mu.Lock()
+	row := m.rows[name]
+	if row == nil {
+		row = &rowLocks{changed: make(chan struct{})}
+		m.rows[name] = row
+	}
+	for _, held := range row.queue {
+		// A lock as strong held already covers the new one.
+		if held.trxId == trxId && held.granted && held.mode >= mode {
+			m.mu.Unlock()
+			return nil
+		}
+	}
+	lock := &rowLock{trxId: trxId, mode: mode}
+	row.queue = append(row.queue, lock)
+	m.trxRows[trxId] = append(m.trxRows[trxId], name)
+	lock.granted = row.grantable(len(row.queue) - 1)
+	if !lock.granted && m.waitsFor(trxId, trxId, make(map[TrxId]bool)) {
+		m.removeLock(name, lock)
+		m.mu.Unlock()
+		return mysql.NewErr(mysql.ErrLockDeadlock)
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for !lock.granted {
+		changed := row.changed
+		m.mu.Unlock()
+		select {
+		case <-changed:
+			m.mu.Lock()
+		case <-timer.C:
+			m.mu.Lock()
+			if lock.granted {
+				continue
+			}
+			m.removeLock(name, lock)
+			m.mu.Unlock()
+			return mysql.NewErr(mysql.ErrLockWaitTimeout)
+		}
+	}
+	m.mu.Unlock()
+	return nil
+}
+
+// waitsFor reports whether the transaction trxId waits, directly or through
+// other transactions waiting, for target. visited are the transactions
+// already followed.
+func (m *LockInfoManager) waitsFor(trxId, target TrxId, visited map[TrxId]bool) bool {
+	visited[trxId] = true
+	for _, name := range m.trxRows[trxId] {
+		row := m.rows[name]
+		if row == nil {
+			continue
+		}
+		for i, lock := range row.queue {
+			if lock.trxId != trxId || lock.granted {
+				continue
+			}
+			for _, ahead := range row.queue[:i] {
+				if ahead.trxId == trxId || lockCompatible[ahead.mode][lock.mode] {
+					continue
+				}
+				if ahead.trxId == target {
+					return true
+				}
+				if !visited[ahead.trxId] && m.waitsFor(ahead.trxId, target, visited) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// removeLock removes lock from the queue of the row name, granting the
+// locks it held back.
+func (m *LockInfoManager) removeLock(name string, lock *rowLock) {
+	row := m.rows[name]
+	for i, l := range row.queue {
+		if l == lock {
+			row.queue = append(row.queue[:i], row.queue[i+1:]...)
+			break
+		}
+	}
+	if len(row.queue) == 0 {
+		close(row.changed)
+		delete(m.rows, name)
+		return
+	}
+	row.grant()
+}
+
+// ReleaseLocks releases the row locks of the transaction trxId, on commit
+// or rollback, granting the locks waiting for them.
+func (m *LockInfoManager) ReleaseLocks(trxId TrxId) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, name := range m.trxRows[trxId] {
+		row := m.rows[name]
+		if row == nil {
+			continue
+		}
+		for i := 0; i < len(row.queue); {
+			if row.queue[i].trxId == trxId {
+				m.removeLock(name, row.queue[i])
+				if m.rows[name] == nil {
+					break
+				}
+				continue
+			}
+			i++
+		}
+	}
+	delete(m.trxRows, trxId)
+}
+
+// RowLocks returns the modes of the locks granted on the row of key of the
+// table tableId, by transaction.
+func (m *LockInfoManager) RowLocks(tableId uint64, key []byte) map[TrxId][]LockMode {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	locks := make(map[TrxId][]LockMode)
+	if row := m.rows[rowName(tableId, key)]; row != nil {
+		for _, lock := range row.queue {
+			if lock.granted {
+				locks[lock.trxId] = append(locks[lock.trxId], lock.mode)
+			}
+		}
+	}
+	return locks
+}
@@ -0,0 +1,180 @@
+package mvcc
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+func TestLockInfoManagerSharedAndExclusive(t *testing.T) {
+	m := NewLockInfoManager()
+	key := []byte("row1")
+	mode, ok := SelectLockMode(ast.SelectLockInShareMode)
+	if !ok || mode != LockModeS {
+		t.Fatalf("expect LOCK IN SHARE MODE to take S locks, got %v", mode)
+	}
+
+	// Two readers lock the row in share mode at the same time.
+	var readers sync.WaitGroup
+	locked := make(chan TrxId, 2)
+	release := make(chan struct{})
+	for _, trx := range []TrxId{1, 2} {
+		readers.Add(1)
+		go func(trx TrxId) {
+			defer readers.Done()
+			if err := m.LockRow(trx, 10, key, LockModeS, time.Second); err != nil {
+				t.Error(err)
+				return
+			}
+			locked <- trx
+			<-release
+			m.ReleaseLocks(trx)
+		}(trx)
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-locked:
+		case <-time.After(time.Second):
+			t.Fatal("expect both readers to hold the shared lock")
+		}
+	}
+	if got := m.RowLocks(10, key); len(got) != 2 {
+		t.Fatalf("expect 2 shared locks, got %v", got)
+	}
+
+	// The writer waits for both readers.
+	writerLocked := make(chan error, 1)
+	go func() {
+		writerLocked <- m.LockRow(3, 10, key, LockModeX, 5*time.Second)
+	}()
+	select {
+	case err := <-writerLocked:
+		t.Fatalf("expect the writer blocked by the readers, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	// A reader coming after the waiting writer waits behind it.
+	if err := m.LockRow(4, 10, key, LockModeS, 20*time.Millisecond); !isLockWaitTimeout(err) {
+		t.Fatalf("expect the reader to wait behind the writer, got %v", err)
+	}
+	close(release)
+	readers.Wait()
+	select {
+	case err := <-writerLocked:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expect the writer to lock the row once the readers released it")
+	}
+	if got := m.RowLocks(10, key); len(got) != 1 || got[3][0] != LockModeX {
+		t.Fatalf("expect the exclusive lock of the writer, got %v", got)
+	}
+
+	// The exclusive lock blocks readers and other writers.
+	for _, mode := range []LockMode{LockModeS, LockModeX} {
+		if err := m.LockRow(5, 10, key, mode, 20*time.Millisecond); !isLockWaitTimeout(err) {
+			t.Errorf("%s: expect lock wait timeout, got %v", mode, err)
+		}
+	}
+	// Other rows are not locked.
+	if err := m.LockRow(5, 10, []byte("row2"), LockModeX, 0); err != nil {
+		t.Fatal(err)
+	}
+	m.ReleaseLocks(3)
+	m.ReleaseLocks(5)
+	if got := m.RowLocks(10, key); len(got) != 0 {
+		t.Errorf("expect no lock left, got %v", got)
+	}
+}
+
+func TestLockInfoManagerUpgrade(t *testing.T) {
+	m := NewLockInfoManager()
+	key := []byte("row1")
+	if err := m.LockRow(1, 10, key, LockModeS, 0); err != nil {
+		t.Fatal(err)
+	}
+	// The only reader upgrades its lock, a lock it holds covers a weaker one.
+	for _, mode := range []LockMode{LockModeX, LockModeS, LockModeX} {
+		if err := m.LockRow(1, 10, key, mode, 0); err != nil {
+			t.Fatalf("%s: %v", mode, err)
+		}
+	}
+	if got := m.RowLocks(10, key)[1]; len(got) != 2 {
+		t.Fatalf("expect the S and X locks of the transaction, got %v", got)
+	}
+	// Another reader can't upgrade while it shares the row.
+	m.ReleaseLocks(1)
+	for _, trx := range []TrxId{1, 2} {
+		if err := m.LockRow(trx, 10, key, LockModeS, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.LockRow(1, 10, key, LockModeX, 20*time.Millisecond); !isLockWaitTimeout(err) {
+		t.Fatalf("expect lock wait timeout, got %v", err)
+	}
+}
+
+func TestLockInfoManagerDeadlock(t *testing.T) {
+	m := NewLockInfoManager()
+	a, b, c := []byte("a"), []byte("b"), []byte("c")
+	for trx, key := range map[TrxId][]byte{1: a, 2: b, 3: c} {
+		if err := m.LockRow(trx, 10, key, LockModeX, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 1 waits for 2, which waits for 3.
+	waits := make(chan error, 2)
+	go func() { waits <- m.LockRow(1, 10, b, LockModeX, 5*time.Second) }()
+	go func() { waits <- m.LockRow(2, 10, c, LockModeX, 5*time.Second) }()
+	time.Sleep(50 * time.Millisecond)
+
+	// 3 closes the cycle: it fails at once and keeps its locks.
+	start := time.Now()
+	if err := m.LockRow(3, 10, a, LockModeX, 5*time.Second); !isDeadlock(err) {
+		t.Fatalf("expect a deadlock, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatal("expect the deadlock found without waiting")
+	}
+	if got := m.RowLocks(10, c)[3]; len(got) != 1 {
+		t.Fatalf("expect 3 to keep its lock, got %v", got)
+	}
+	// Its rollback lets the others go on.
+	m.ReleaseLocks(3)
+	for i := 0; i < 2; i++ {
+		if err := <-waits; err != nil {
+			t.Fatal(err)
+		}
+		m.ReleaseLocks(2)
+	}
+
+	// Two readers upgrading the row they share deadlock too.
+	m = NewLockInfoManager()
+	for _, trx := range []TrxId{1, 2} {
+		if err := m.LockRow(trx, 10, a, LockModeS, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	go func() { waits <- m.LockRow(1, 10, a, LockModeX, 5*time.Second) }()
+	time.Sleep(50 * time.Millisecond)
+	if err := m.LockRow(2, 10, a, LockModeX, 5*time.Second); !isDeadlock(err) {
+		t.Fatalf("expect a deadlock, got %v", err)
+	}
+	m.ReleaseLocks(2)
+	if err := <-waits; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func isDeadlock(err error) bool {
+	sqlErr, ok := err.(*mysql.SQLError)
+	return ok && sqlErr.Code == mysql.ErrLockDeadlock
+}
+
+func isLockWaitTimeout(err error) bool {
+	sqlErr, ok := err.(*mysql.SQLError)
+	return ok && sqlErr.Code == mysql.ErrLockWaitTimeout
+}
@@ -1,34 +1,149 @@
 package mvcc
 
+import "sync"
+
 type Mvcc struct {
-	ActiveViews []ReadView
-	FreeViews   []ReadView
+	mu          sync.Mutex
+	nextTrxId   TrxId   //下一个分配的事务ID
+	activeTrxs  []TrxId //活跃事务ID，按分配顺序
+	ActiveViews []*ReadView
+	FreeViews   []*ReadView
 }
 
-//创建一个readview
-func (m Mvcc) CreateView() (*ReadView, *TrxT) {
+func NewMvcc() *Mvcc {
+	return &Mvcc{nextTrxId: 1}
+}
 
-	return nil, nil
+// NewMvccWithTrxId returns an Mvcc assigning transaction ids from
+// nextTrxId on, the one the transaction system page stores, 1 at least.
+func NewMvccWithTrxId(nextTrxId TrxId) *Mvcc {
+	if nextTrxId < 1 {
+		nextTrxId = 1
+	}
+	return &Mvcc{nextTrxId: nextTrxId}
 }
 
-//关闭一个readview
-func (m Mvcc) CloseView(view *ReadView, ownMutex bool) {
+// BeginTrx assigns the next transaction id and makes it active.
+func (m *Mvcc) BeginTrx() TrxId {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id := m.nextTrxId
+	m.nextTrxId++
+	m.activeTrxs = append(m.activeTrxs, id)
+	return id
+}
 
+// EndTrx removes id from the active transactions, on commit or rollback.
+func (m *Mvcc) EndTrx(id TrxId) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, active := range m.activeTrxs {
+		if active == id {
+			m.activeTrxs = append(m.activeTrxs[:i], m.activeTrxs[i+1:]...)
+			return
+		}
+	}
 }
 
-//是否关闭一个View
-func (m Mvcc) IsViewRelease(view *ReadView) bool {
-	return false
+//创建一个readview
+func (m *Mvcc) CreateView(creator TrxId) *ReadView {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var view *ReadView
+	if n := len(m.FreeViews); n > 0 {
+		view, m.FreeViews = m.FreeViews[n-1], m.FreeViews[:n-1]
+	} else {
+		view = new(ReadView)
+	}
+	view.creatorTrxId = creator
+	view.maxTrxId = m.nextTrxId
+	view.minTrxId = m.nextTrxId
+	view.mIds = view.mIds[:0]
+	for _, id := range m.activeTrxs {
+		if id == creator {
+			continue
+		}
+		view.mIds = append(view.mIds, id)
+		if id < view.minTrxId {
+			view.minTrxId = id
+		}
+	}
+	m.ActiveViews = append(m.ActiveViews, view)
+	return view
 }
 
-func (m Mvcc) CloneOldestView() {
+//关闭一个readview
+func (m *Mvcc) CloseView(view *ReadView, ownMutex bool) {
+	if !ownMutex {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+	}
+	for i, active := range m.ActiveViews {
+		if active == view {
+			m.ActiveViews = append(m.ActiveViews[:i], m.ActiveViews[i+1:]...)
+			m.FreeViews = append(m.FreeViews, view)
+			return
+		}
+	}
+}
 
+//是否关闭一个View
+func (m *Mvcc) IsViewRelease(view *ReadView) bool {
+	return !m.IsReadViewActive(view)
 }
 
-func (m Mvcc) GetActiveReadViewSize() int {
-	return 0
+// CloneOldestView returns a copy of the oldest active view, the one that
+// sees the fewest changes, nil when there is no active view.
+func (m *Mvcc) CloneOldestView() *ReadView {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var oldest *ReadView
+	for _, view := range m.ActiveViews {
+		if oldest == nil || view.minTrxId < oldest.minTrxId {
+			oldest = view
+		}
+	}
+	if oldest == nil {
+		return nil
+	}
+	clone := *oldest
+	clone.mIds = append([]TrxId(nil), oldest.mIds...)
+	return &clone
 }
 
-func (m Mvcc) IsReadViewActive(view ReadView) bool {
+func (m *Mvcc) GetActiveReadViewSize() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.ActiveViews)
+}
+
+func (m *Mvcc) IsReadViewActive(view *ReadView) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, active := range m.ActiveViews {
+		if active == view {
+			return true
+		}
+	}
 	return false
 }
+
+// ChangesVisibleToAll checks whether every active view sees the changes of
+// the committed transaction id, so that no view needs the versions it
+// replaced any more. The changes of a transaction still active are not,
+// the views created before it ends don't see them.
+func (m *Mvcc) ChangesVisibleToAll(id TrxId) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, active := range m.activeTrxs {
+		if active == id {
+			return false
+		}
+	}
+	for _, view := range m.ActiveViews {
+		if !view.ChangesVisible(id, "") {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,202 @@
+package mvcc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+)
+
+// UndoType is the kind of change an undo record reverts.
+type UndoType int
+
+const (
+	// UndoInsert reverts an insert; it is only needed for rollback.
+	UndoInsert UndoType = iota
+	// UndoDelMark reverts a delete mark: purging it removes the marked
+	// entries from the clustered and secondary indexes.
+	UndoDelMark
+	// UndoUpdate reverts an update in place: purging it removes the
+	// secondary index entries of the old values.
+	UndoUpdate
+)
+
+// UndoRecord is a record of the undo log of a transaction.
+type UndoRecord struct {
+	Type    UndoType
+	TableId uint64
+	// Key is the clustered index key of the row.
+	Key []byte
+	// OldValues are the secondary index entries the change replaced, by
+	// index name.
+	OldValues map[string][]byte
+}
+
+// Purger removes what purge decides is no longer needed.
+type Purger interface {
+	// PurgeRecord physically removes the index entries rec delete-marked
+	// or replaced.
+	PurgeRecord(rec *UndoRecord) error
+	// FreeUndoLog frees the undo pages of the transaction id, once all of
+	// its records are purged.
+	FreeUndoLog(id TrxId) error
+}
+
+// undoLog is the undo log of a committed transaction, waiting for purge.
+type undoLog struct {
+	trxId   TrxId
+	records []*UndoRecord
+}
+
+// PurgeSys keeps the undo logs of committed transactions, the history list,
+// in commit order and purges them once no active read view needs the
+// versions they replaced. Purge stops at the first log some view still
+// needs, so the purge point only moves forward.
+type PurgeSys struct {
+	mvcc   *Mvcc
+	purger Purger
+
+	mu          sync.Mutex
+	history     []*undoLog
+	purgedTrxId TrxId //最近一个被purge的事务ID
+	purged      uint64
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewPurgeSys returns a purge system checking the read views of m. A nil
+// purger only drops the undo logs from the history.
+func NewPurgeSys(m *Mvcc, purger Purger) *PurgeSys {
+	return &PurgeSys{mvcc: m, purger: purger}
+}
+
+// AddUndoLog adds the undo log of the transaction id to the history list at
+// its commit. Logs without records to purge, like those of inserts only,
+// are freed right away.
+func (p *PurgeSys) AddUndoLog(id TrxId, records []*UndoRecord) error {
+	needPurge := false
+	for _, rec := range records {
+		if rec.Type != UndoInsert {
+			needPurge = true
+			break
+		}
+	}
+	if !needPurge {
+		if p.purger != nil {
+			return p.purger.FreeUndoLog(id)
+		}
+		return nil
+	}
+	p.mu.Lock()
+	p.history = append(p.history, &undoLog{trxId: id, records: records})
+	p.mu.Unlock()
+	return nil
+}
+
+// Run purges at most batch undo logs from the head of the history list and
+// returns how many it purged.
+func (p *PurgeSys) Run(batch int) (int, error) {
+	n := 0
+	for ; n < batch; n++ {
+		p.mu.Lock()
+		if len(p.history) == 0 || !p.mvcc.ChangesVisibleToAll(p.history[0].trxId) {
+			p.mu.Unlock()
+			break
+		}
+		undo := p.history[0]
+		p.mu.Unlock()
+
+		if err := p.purgeLog(undo); err != nil {
+			return n, err
+		}
+		p.mu.Lock()
+		p.history = p.history[1:]
+		p.purgedTrxId = undo.trxId
+		p.purged += uint64(len(undo.records))
+		p.mu.Unlock()
+	}
+	return n, nil
+}
+
+func (p *PurgeSys) purgeLog(undo *undoLog) error {
+	if p.purger == nil {
+		return nil
+	}
+	for _, rec := range undo.records {
+		if rec.Type == UndoInsert {
+			continue
+		}
+		if err := p.purger.PurgeRecord(rec); err != nil {
+			return err
+		}
+	}
+	return p.purger.FreeUndoLog(undo.trxId)
+}
+
+// Start runs purge every interval in the background until Stop.
+func (p *PurgeSys) Start(interval time.Duration, batch int) {
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+	go func() {
+		defer close(p.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-ticker.C:
+				p.Run(batch)
+			}
+		}
+	}()
+}
+
+// Stop stops the background purge and waits for it.
+func (p *PurgeSys) Stop() {
+	if p.stop == nil {
+		return
+	}
+	close(p.stop)
+	<-p.done
+	p.stop = nil
+}
+
+// HistoryLength returns the number of undo logs waiting for purge, the
+// purge lag.
+func (p *PurgeSys) HistoryLength() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.history)
+}
+
+// PurgedTrxId returns the purge point, the last transaction purged.
+func (p *PurgeSys) PurgedTrxId() TrxId {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.purgedTrxId
+}
+
+// Purge status variables.
+const (
+	StatusHistoryListLength = "Innodb_history_list_length"
+	StatusPurgeTrxId        = "Innodb_purge_trx_id"
+	StatusPurgedRecords     = "Innodb_purged_records"
+)
+
+// GetScope implements variable.Statistics GetScope interface.
+func (p *PurgeSys) GetScope(status string) variable.ScopeFlag {
+	return variable.ScopeGlobal
+}
+
+// Stats implements variable.Statistics Stats interface.
+func (p *PurgeSys) Stats(vars *variable.SessionVars) (map[string]interface{}, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return map[string]interface{}{
+		StatusHistoryListLength: int64(len(p.history)),
+		StatusPurgeTrxId:        uint64(p.purgedTrxId),
+		StatusPurgedRecords:     p.purged,
+	}, nil
+}
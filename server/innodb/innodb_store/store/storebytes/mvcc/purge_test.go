@@ -0,0 +1,97 @@
+package mvcc
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testPurger records what purge removes.
+type testPurger struct {
+	keys  []string
+	freed []TrxId
+}
+
+func (p *testPurger) PurgeRecord(rec *UndoRecord) error {
+	p.keys = append(p.keys, string(rec.Key))
+	return nil
+}
+
+func (p *testPurger) FreeUndoLog(id TrxId) error {
+	p.freed = append(p.freed, id)
+	return nil
+}
+
+func TestReadViewVisibility(t *testing.T) {
+	m := NewMvcc()
+	committed, active := m.BeginTrx(), m.BeginTrx()
+	m.EndTrx(committed)
+	reader := m.BeginTrx()
+	view := m.CreateView(reader)
+	later := m.BeginTrx()
+	for id, visible := range map[TrxId]bool{committed: true, active: false, reader: true, later: false} {
+		if view.ChangesVisible(id, "t") != visible {
+			t.Fatalf("trx %d: expect visible %v", id, visible)
+		}
+	}
+	m.CloseView(view, false)
+	if m.GetActiveReadViewSize() != 0 || !m.IsViewRelease(view) {
+		t.Fatal("expect the view closed")
+	}
+	// The views created before active ends won't see its changes.
+	if !m.ChangesVisibleToAll(committed) || m.ChangesVisibleToAll(active) {
+		t.Fatal("expect only the changes of the committed transaction visible to all")
+	}
+}
+
+func TestPurgeHeldBackByReader(t *testing.T) {
+	m := NewMvcc()
+	purger := &testPurger{}
+	p := NewPurgeSys(m, purger)
+
+	// A long running reader opens its view before the deletes.
+	reader := m.BeginTrx()
+	view := m.CreateView(reader)
+
+	for _, key := range []string{"1", "2"} {
+		id := m.BeginTrx()
+		p.AddUndoLog(id, []*UndoRecord{{Type: UndoDelMark, Key: []byte(key)}})
+		m.EndTrx(id)
+	}
+	// Inserts have nothing to purge.
+	insert := m.BeginTrx()
+	p.AddUndoLog(insert, []*UndoRecord{{Type: UndoInsert, Key: []byte("3")}})
+	m.EndTrx(insert)
+
+	if n, err := p.Run(10); err != nil || n != 0 {
+		t.Fatalf("expect purge held back by the reader, purged %d, %v", n, err)
+	}
+	if p.HistoryLength() != 2 || len(purger.keys) != 0 {
+		t.Fatalf("unexpected history length %d, purged %v", p.HistoryLength(), purger.keys)
+	}
+	stats, _ := p.Stats(nil)
+	if stats[StatusHistoryListLength] != int64(2) {
+		t.Fatalf("unexpected status %v", stats)
+	}
+
+	// A view opened after the deletes doesn't hold purge back.
+	m.CloseView(view, false)
+	m.EndTrx(reader)
+	m.CreateView(m.BeginTrx())
+
+	p.Start(time.Millisecond, 10)
+	deadline := time.Now().Add(time.Second)
+	for p.HistoryLength() > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	p.Stop()
+	if !reflect.DeepEqual(purger.keys, []string{"1", "2"}) {
+		t.Fatalf("expect the deleted rows purged, got %v", purger.keys)
+	}
+	if !reflect.DeepEqual(purger.freed, []TrxId{insert, 2, 3}) {
+		t.Fatalf("unexpected freed undo logs %v", purger.freed)
+	}
+	if p.PurgedTrxId() != 3 {
+		t.Fatalf("unexpected purge point %d", p.PurgedTrxId())
+	}
+}
@@ -13,9 +13,8 @@ package mvcc
 
 //**//
 
-type TrxId struct {
-	id [6]byte
-}
+// TrxId 事务ID，磁盘上占6个字节，按分配顺序递增
+type TrxId uint64
 
 /****
 所有回滚段都记录在trx_sys->rseg_array，数组大小为128，分别对应不同的回滚段；
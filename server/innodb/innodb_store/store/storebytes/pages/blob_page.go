@@ -0,0 +1,110 @@
+package pages
+
+import (
+	"github.com/zhukovaskychina/xmysql-server/server/common"
+	"github.com/zhukovaskychina/xmysql-server/util"
+)
+
+// BLOB 页面的页头，紧跟在文件头之后
+const (
+	BlobPageHeaderSize = 8
+	// BlobPageNull is the next page of the last page of a chain, FIL_NULL.
+	BlobPageNull = 0xFFFFFFFF
+)
+
+// BlobPageDataSize returns the most bytes of a value a BLOB page holds.
+func BlobPageDataSize() int {
+	return common.PageSize() - common.PAGE_FILE_HEADER_SIZE - BlobPageHeaderSize - common.PAGE_FILE_TRAILER_SIZE
+}
+
+/**
+存放溢出列的页面，FIL_PAGE_TYPE_BLOB
+
+	//////////////////////////
+	//      FileHeader      //  38
+	//////////////////////////
+	//      PartLen         //  4 本页存放的字节数
+	//      NextPage        //  4 下一个页面，最后一个页面为 FIL_NULL
+	//////////////////////////
+	//      Data            //  PartLen
+	//      EmptySpace      //
+	//////////////////////////
+	//      FileTrailer     //  8
+	//////////////////////////
+**/
+
+// BlobPage is a page of a chain holding an off-page value.
+type BlobPage struct {
+	AbstractPage
+	PartLen    []byte
+	NextPage   []byte
+	Data       []byte
+	EmptySpace []byte
+}
+
+// NewBlobPage returns page pageNo of space spaceId holding data, a part of
+// a value, followed by page nextPage of the chain.
+func NewBlobPage(spaceId uint32, pageNo uint32, data []byte, nextPage uint32) BlobPage {
+	var fileHeader = NewFileHeader()
+	fileHeader.WritePageSpaceCheckSum(nil)
+	fileHeader.WritePageOffset(pageNo)
+	fileHeader.WritePagePrev(0)
+	fileHeader.WritePageNext(0)
+	fileHeader.WritePageLSN(0)
+	fileHeader.WritePageFileType(common.FILE_PAGE_TYPE_BLOB)
+	fileHeader.WritePageFileFlushLSN(0)
+	fileHeader.WritePageArch(spaceId)
+	return BlobPage{
+		AbstractPage: AbstractPage{
+			FileHeader:  fileHeader,
+			FileTrailer: NewFileTrailer(),
+		},
+		PartLen:    util.ConvertUInt4Bytes(uint32(len(data))),
+		NextPage:   util.ConvertUInt4Bytes(nextPage),
+		Data:       data,
+		EmptySpace: make([]byte, BlobPageDataSize()-len(data)),
+	}
+}
+
+// ParseBlobPage reads a BLOB page from its content.
+func ParseBlobPage(content []byte) BlobPage {
+	var blobPage BlobPage
+	blobPage.LoadFileHeader(content[0:common.PAGE_FILE_HEADER_SIZE])
+	body := content[common.PAGE_FILE_HEADER_SIZE:]
+	blobPage.PartLen = body[0:4]
+	blobPage.NextPage = body[4:8]
+	partLen := int(util.ReadUB4Byte2UInt32(blobPage.PartLen))
+	if partLen > BlobPageDataSize() {
+		partLen = BlobPageDataSize()
+	}
+	blobPage.Data = body[BlobPageHeaderSize : BlobPageHeaderSize+partLen]
+	blobPage.EmptySpace = body[BlobPageHeaderSize+partLen : BlobPageHeaderSize+BlobPageDataSize()]
+	blobPage.LoadFileTrailer(content[len(content)-common.PAGE_FILE_TRAILER_SIZE:])
+	return blobPage
+}
+
+// GetPageType returns the type of the page in its file header.
+func (b *BlobPage) GetPageType() int {
+	return int(util.ReadUB2Byte2Int(b.FileHeader.FilePageType))
+}
+
+// GetNextPageNo returns the next page of the chain, BlobPageNull on the
+// last page.
+func (b *BlobPage) GetNextPageNo() uint32 {
+	return util.ReadUB4Byte2UInt32(b.NextPage)
+}
+
+func (b *BlobPage) SerializeBytes() []byte {
+	var buff = make([]byte, 0, common.PageSize())
+	buff = append(buff, b.FileHeader.GetSerialBytes()...)
+	buff = append(buff, b.PartLen...)
+	buff = append(buff, b.NextPage...)
+	buff = append(buff, b.Data...)
+	buff = append(buff, b.EmptySpace...)
+	buff = append(buff, b.FileTrailer.FileTrailer...)
+	return buff
+}
+
+func (b *BlobPage) GetSerializeBytes() []byte {
+	return b.SerializeBytes()
+}
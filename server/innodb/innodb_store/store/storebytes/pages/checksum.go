@@ -0,0 +1,132 @@
+package pages
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"strings"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+	"github.com/zhukovaskychina/xmysql-server/server/common"
+)
+
+/****
+页面校验和，与 innodb_checksum_algorithm=crc32 相同：
+
+FIL_PAGE_SPACE_OR_CHKSUM（0-4）和 FIL_PAGE_END_LSN_OLD_CHKSUM（页面最后8个字节的前4个）都存放
+crc32c(4-26) ^ crc32c(38-页面大小减8)，大端序；页面最后4个字节是 FIL_PAGE_LSN 的低4个字节。
+innochecksum 按这个规则校验页面，全部是0的页面视为没有使用过的页面。
+
+innodb_checksum_algorithm=none 时两处都写入 BUF_NO_CHECKSUM_MAGIC（0xDEADBEEF），读入时不校验。
+crc32 读入页面时校验，也接受 none 写入的页面，所以两种设置之间可以随时切换。
+****/
+
+// noChecksumMagic is the checksum of the pages written with
+// innodb_checksum_algorithm=none, BUF_NO_CHECKSUM_MAGIC.
+const noChecksumMagic = 0xDEADBEEF
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// ChecksumAlgorithm is the value of innodb_checksum_algorithm.
+type ChecksumAlgorithm int32
+
+// Values of innodb_checksum_algorithm.
+const (
+	ChecksumCRC32 ChecksumAlgorithm = iota
+	ChecksumNone
+)
+
+var checksumAlgorithmNames = []string{"crc32", "none"}
+
+func (a ChecksumAlgorithm) String() string {
+	return checksumAlgorithmNames[a]
+}
+
+// ParseChecksumAlgorithm returns the value of innodb_checksum_algorithm
+// called name, in any case.
+func ParseChecksumAlgorithm(name string) (ChecksumAlgorithm, error) {
+	for i, aName := range checksumAlgorithmNames {
+		if strings.EqualFold(name, aName) {
+			return ChecksumAlgorithm(i), nil
+		}
+	}
+	return 0, errors.Errorf("unknown innodb_checksum_algorithm %s", name)
+}
+
+var checksumAlgorithm int32
+
+// GetChecksumAlgorithm returns how the pages are checksummed.
+func GetChecksumAlgorithm() ChecksumAlgorithm {
+	return ChecksumAlgorithm(atomic.LoadInt32(&checksumAlgorithm))
+}
+
+// SetChecksumAlgorithm changes how the pages written from now on are
+// checksummed and whether the pages read are checked.
+func SetChecksumAlgorithm(a ChecksumAlgorithm) {
+	atomic.StoreInt32(&checksumAlgorithm, int32(a))
+}
+
+// PageChecksum returns the crc32 checksum of page.
+func PageChecksum(page []byte) uint32 {
+	return crc32.Checksum(page[4:26], crc32cTable) ^ crc32.Checksum(page[38:checksumTrailerOffset(page)], crc32cTable)
+}
+
+// StampChecksum writes the checksum of innodb_checksum_algorithm and the
+// low 4 bytes of the LSN of page in its header and trailer, in place.
+// Slices of other sizes are left alone.
+func StampChecksum(page []byte) {
+	if len(page) != common.PageSize() {
+		return
+	}
+	copy(page[lsnTrailerOffset(page):], page[20:24])
+	checksum := uint32(noChecksumMagic)
+	if GetChecksumAlgorithm() == ChecksumCRC32 {
+		checksum = PageChecksum(page)
+	}
+	binary.BigEndian.PutUint32(page[0:4], checksum)
+	binary.BigEndian.PutUint32(page[checksumTrailerOffset(page):], checksum)
+}
+
+// IsChecksumValid reports whether page is a page never written or has the
+// checksums and LSN trailer StampChecksum writes.
+func IsChecksumValid(page []byte) bool {
+	if len(page) != common.PageSize() {
+		return false
+	}
+	if isZeroPage(page) {
+		return true
+	}
+	if binary.BigEndian.Uint32(page[20:24]) != binary.BigEndian.Uint32(page[lsnTrailerOffset(page):]) {
+		return false
+	}
+	checksum := PageChecksum(page)
+	return binary.BigEndian.Uint32(page[0:4]) == checksum && binary.BigEndian.Uint32(page[checksumTrailerOffset(page):]) == checksum
+}
+
+// VerifyChecksum reports whether page, read from disk, passes the check of
+// innodb_checksum_algorithm: with crc32 it has a valid checksum or was
+// written with none, with none any page does.
+func VerifyChecksum(page []byte) bool {
+	if GetChecksumAlgorithm() == ChecksumNone || IsChecksumValid(page) {
+		return true
+	}
+	return len(page) == common.PageSize() &&
+		binary.BigEndian.Uint32(page[0:4]) == noChecksumMagic &&
+		binary.BigEndian.Uint32(page[checksumTrailerOffset(page):]) == noChecksumMagic &&
+		binary.BigEndian.Uint32(page[20:24]) == binary.BigEndian.Uint32(page[lsnTrailerOffset(page):])
+}
+
+// checksumTrailerOffset returns the offset of the checksum in the trailer
+// of page, lsnTrailerOffset that of the low 4 bytes of its LSN.
+func checksumTrailerOffset(page []byte) int { return len(page) - 8 }
+
+func lsnTrailerOffset(page []byte) int { return len(page) - 4 }
+
+func isZeroPage(page []byte) bool {
+	for _, b := range page {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
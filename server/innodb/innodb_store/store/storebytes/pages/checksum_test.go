@@ -0,0 +1,63 @@
+package pages
+
+import (
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/common"
+)
+
+func TestStampChecksum(t *testing.T) {
+	if !IsChecksumValid(make([]byte, common.PAGE_SIZE)) {
+		t.Fatal("expect a page never written to be valid")
+	}
+	page := NewSysTrxSysPage().GetSerializeBytes()
+	copy(page[16:24], []byte{0, 0, 0, 0, 0x12, 0x34, 0x56, 0x78})
+	if IsChecksumValid(page) {
+		t.Fatal("expect a page without checksum to be invalid")
+	}
+	StampChecksum(page)
+	if !IsChecksumValid(page) {
+		t.Fatal("expect a stamped page to be valid")
+	}
+	if got := page[common.PAGE_SIZE-4:]; string(got) != "\x12\x34\x56\x78" {
+		t.Fatalf("expect the low bytes of the LSN in the trailer, got %v", got)
+	}
+	page[100] ^= 1
+	if IsChecksumValid(page) {
+		t.Fatal("expect a changed page to be invalid")
+	}
+}
+
+func TestChecksumAlgorithm(t *testing.T) {
+	defer SetChecksumAlgorithm(ChecksumCRC32)
+	none, err := ParseChecksumAlgorithm("NONE")
+	if err != nil || none != ChecksumNone {
+		t.Fatalf("expect none, got %v %v", none, err)
+	}
+	if _, err = ParseChecksumAlgorithm("innodb"); err == nil {
+		t.Fatal("expect an error for an unsupported algorithm")
+	}
+
+	// A page written with none has the magic and passes the check of crc32.
+	SetChecksumAlgorithm(ChecksumNone)
+	page := NewSysTrxSysPage().GetSerializeBytes()
+	StampChecksum(page)
+	if got := page[0:4]; string(got) != "\xde\xad\xbe\xef" {
+		t.Fatalf("expect the magic checksum, got %v", got)
+	}
+	SetChecksumAlgorithm(ChecksumCRC32)
+	if IsChecksumValid(page) || !VerifyChecksum(page) {
+		t.Fatal("expect crc32 to accept a page written with none")
+	}
+
+	// crc32 finds the changed pages, none reads them as they are.
+	StampChecksum(page)
+	page[100] ^= 1
+	if VerifyChecksum(page) {
+		t.Fatal("expect a changed page to fail the check")
+	}
+	SetChecksumAlgorithm(ChecksumNone)
+	if !VerifyChecksum(page) {
+		t.Fatal("expect no check with none")
+	}
+}
@@ -190,7 +190,7 @@ func NewIndexPage(pageNo uint32, spaceId uint32) *IndexPage {
 		PageHeader:      *pageHeader,
 		InfimumSupermum: INFIMUM_SURERMUM_COMPACT,
 		UserRecords:     util.AppendByte(0),
-		FreeSpace:       util.AppendByte(16384 - 38 - 8 - 26 - 56),
+		FreeSpace:       util.AppendByte(common.PageSize() - 38 - 8 - 26 - 56),
 		PageDirectory:   util.AppendByte(0),
 	}
 }
@@ -205,7 +205,7 @@ func NewIndexPageWithParams(pageNo uint32, spaceId uint32) IndexPage {
 		PageHeader:      PageHeader{},
 		InfimumSupermum: INFIMUM_SURERMUM_COMPACT,
 		UserRecords:     util.AppendByte(0),
-		FreeSpace:       util.AppendByte(16384 - 38 - 8 - 26 - 56),
+		FreeSpace:       util.AppendByte(common.PageSize() - 38 - 8 - 26 - 56),
 		PageDirectory:   util.AppendByte(0),
 	}
 }
@@ -234,7 +234,7 @@ func (ip *IndexPage) ParseInfimumSupermum(content []byte) {
 func (ip *IndexPage) ParsePageSlots(content []byte) {
 	pnd := ip.PageHeader.PageNDirSlots
 	_, pncnt := util.ReadUB2(pnd, 0)
-	ip.PageDirectory = content[16384-8-pncnt*2 : 16384-8]
+	ip.PageDirectory = content[len(content)-8-int(pncnt)*2 : len(content)-8]
 
 }
 
@@ -249,7 +249,7 @@ func (ip *IndexPage) ParseUserRecordsAndFreeSpace(content []byte) {
 
 	if pageDirLength == 0 {
 		ip.UserRecords = make([]byte, 0)
-		ip.FreeSpace = content[38+56+26 : 16384-8-len(ip.PageDirectory)]
+		ip.FreeSpace = content[38+56+26 : len(content)-8-len(ip.PageDirectory)]
 		return
 	}
 	//计算最大值偏移量
@@ -260,7 +260,7 @@ func (ip *IndexPage) ParseUserRecordsAndFreeSpace(content []byte) {
 
 	ip.UserRecords = content[38+56+26 : supermumOffsetValue+5+8]
 
-	ip.FreeSpace = content[38+56+26+supermumOffsetValue+5+8 : 16384-8-len(ip.PageDirectory)]
+	ip.FreeSpace = content[supermumOffsetValue+5+8 : len(content)-8-len(ip.PageDirectory)]
 }
 
 func (ip *IndexPage) SerializeBytes() []byte {
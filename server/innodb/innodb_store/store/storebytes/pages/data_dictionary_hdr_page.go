@@ -28,11 +28,11 @@ type DataDictionaryHeaderSysPage struct {
 
 	DataDictHeader DataDictHeader //52   记录存储基本系统表的根页面未知以及InnoDB存储引擎的一些全局信息
 
-	UnusedSpace []byte //90-94
+	LowerCaseTableNames []byte //90-94 初始化时的lower_case_table_names加1，0表示没有记录
 
 	SegmentHeader []byte //94-104
 
-	SecondEmptySpace []byte //104-16376
+	SecondEmptySpace []byte //104 到页面大小-8
 
 	FileTrailer FileTrailer
 }
@@ -83,9 +83,9 @@ func NewDataDictHeaderPage() *DataDictionaryHeaderSysPage {
 	}
 	ddp.SegmentHeader = segs.NewSegmentHeader(0, 7, 0).GetBytes()
 
-	ddp.UnusedSpace = util.AppendByte(4)
+	ddp.LowerCaseTableNames = util.AppendByte(4)
 
-	ddp.SecondEmptySpace = util.AppendByte(16376 - 104)
+	ddp.SecondEmptySpace = util.AppendByte(common.PageSize() - 8 - 104)
 
 	ddp.FileTrailer = NewFileTrailer()
 
@@ -99,7 +99,7 @@ func ParseDataDictHrdPage(content []byte) *DataDictionaryHeaderSysPage {
 	fspBinary.FileTrailer = NewFileTrailer()
 
 	fspBinary.LoadFileHeader(content[0:38])
-	fspBinary.LoadFileTrailer(content[16384-8 : 16384])
+	fspBinary.LoadFileTrailer(content[len(content)-8:])
 
 	fspBinary.DataDictHeader = DataDictHeader{
 		MaxRowId:             content[38:46],
@@ -114,9 +114,9 @@ func ParseDataDictHrdPage(content []byte) *DataDictionaryHeaderSysPage {
 		SysFieldsRootPage:    content[86:90],
 	}
 
-	fspBinary.UnusedSpace = content[90:94]
+	fspBinary.LowerCaseTableNames = content[90:94]
 	fspBinary.SegmentHeader = content[94:104]
-	fspBinary.SecondEmptySpace = content[104:16376]
+	fspBinary.SecondEmptySpace = content[104 : len(content)-8]
 
 	return fspBinary
 }
@@ -149,6 +149,22 @@ func (d *DataDictionaryHeaderSysPage) GetMaxSpaceId() uint64 {
 	return util.ReadUB8Byte2Long(d.DataDictHeader.MaxSpaceId)
 }
 
+// SetLowerCaseTableNames records the lower_case_table_names the data
+// directory is initialized with.
+func (d *DataDictionaryHeaderSysPage) SetLowerCaseTableNames(mode int) {
+	d.LowerCaseTableNames = util.ConvertUInt4Bytes(uint32(mode + 1))
+}
+
+// GetLowerCaseTableNames returns the recorded lower_case_table_names, false
+// when the page was written before it was recorded.
+func (d *DataDictionaryHeaderSysPage) GetLowerCaseTableNames() (int, bool) {
+	v := util.ReadUB4Byte2UInt32(d.LowerCaseTableNames)
+	if v == 0 {
+		return 0, false
+	}
+	return int(v) - 1, true
+}
+
 func (d *DataDictionaryHeaderSysPage) GetSerializeBytes() []byte {
 	var buff = make([]byte, 0)
 	buff = append(buff, d.FileHeader.GetSerialBytes()...)
@@ -162,7 +178,7 @@ func (d *DataDictionaryHeaderSysPage) GetSerializeBytes() []byte {
 	buff = append(buff, d.DataDictHeader.SysColumnsRootPage...)
 	buff = append(buff, d.DataDictHeader.SysIndexesRootPage...)
 	buff = append(buff, d.DataDictHeader.SysFieldsRootPage...)
-	buff = append(buff, d.UnusedSpace...)
+	buff = append(buff, d.LowerCaseTableNames...)
 	buff = append(buff, d.SegmentHeader...)
 	buff = append(buff, d.SecondEmptySpace...)
 	buff = append(buff, d.FileTrailer.FileTrailer...)
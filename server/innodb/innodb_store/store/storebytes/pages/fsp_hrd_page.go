@@ -29,7 +29,7 @@ func NewFileSpaceHeader(spaceId uint32) *FileSpaceHeader {
 	fileSpaceHeader.NotUsed = []byte{0, 0, 0, 0}
 	fileSpaceHeader.Size = util.ConvertInt4Bytes(0)
 	fileSpaceHeader.FreeLimit = util.ConvertInt4Bytes(0)
-	fileSpaceHeader.SpaceFlags = util.ConvertInt4Bytes(0)
+	fileSpaceHeader.SpaceFlags = util.ConvertUInt4Bytes(SpaceFlags{PageSSize: common.PageSSize(common.PageSize())}.ToUint32())
 	fileSpaceHeader.FragNUsed = util.ConvertUInt4Bytes(0)
 	fileSpaceHeader.BaseNodeForFreeList = []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
 	fileSpaceHeader.BaseNodeForFragFreeList = []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
@@ -82,6 +82,53 @@ type SpaceFlags struct {
 	Encryption     bool //表空间是否加密
 }
 
+// SpaceFlags 各属性在 FSP_SPACE_FLAGS 中的位置
+const (
+	spaceFlagPostAntelope   = 1 << 0
+	spaceFlagZipSSizeShift  = 1
+	spaceFlagAtomicBlobs    = 1 << 5
+	spaceFlagPageSSizeShift = 6
+	spaceFlagDataDir        = 1 << 10
+	spaceFlagShared         = 1 << 11
+	spaceFlagTemporary      = 1 << 12
+	spaceFlagEncryption     = 1 << 13
+)
+
+// ToUint32 returns the flags as FSP_SPACE_FLAGS stores them.
+func (f SpaceFlags) ToUint32() uint32 {
+	flags := uint32(f.ZipSSzie&0xF)<<spaceFlagZipSSizeShift | uint32(f.PageSSize&0xF)<<spaceFlagPageSSizeShift
+	for _, bit := range []struct {
+		set  bool
+		flag uint32
+	}{
+		{f.IsPostAntelope, spaceFlagPostAntelope},
+		{f.AtomicBlobs, spaceFlagAtomicBlobs},
+		{f.DataDir, spaceFlagDataDir},
+		{f.Shared, spaceFlagShared},
+		{f.Temporary, spaceFlagTemporary},
+		{f.Encryption, spaceFlagEncryption},
+	} {
+		if bit.set {
+			flags |= bit.flag
+		}
+	}
+	return flags
+}
+
+// ParseSpaceFlags reads the flags stored in FSP_SPACE_FLAGS.
+func ParseSpaceFlags(flags uint32) SpaceFlags {
+	return SpaceFlags{
+		IsPostAntelope: flags&spaceFlagPostAntelope != 0,
+		ZipSSzie:       byte(flags >> spaceFlagZipSSizeShift & 0xF),
+		AtomicBlobs:    flags&spaceFlagAtomicBlobs != 0,
+		PageSSize:      byte(flags >> spaceFlagPageSSizeShift & 0xF),
+		DataDir:        flags&spaceFlagDataDir != 0,
+		Shared:         flags&spaceFlagShared != 0,
+		Temporary:      flags&spaceFlagTemporary != 0,
+		Encryption:     flags&spaceFlagEncryption != 0,
+	}
+}
+
 /****
 
 SpaceFlags
@@ -101,8 +148,8 @@ Unused 18 没有使用到的bit
 type FspHrdBinaryPage struct {
 	AbstractPage
 	FileSpaceHeader *FileSpaceHeader //112字节
-	XDESEntrys      []XDESEntry      //10240 byte 存储本组256组区对应的属性信息，每组40byte
-	EmptySpace      []byte           //5986 byte
+	XDESEntrys      []XDESEntry      //16K 页面为 10240 byte，存储本组256个区对应的属性信息，每个40byte
+	EmptySpace      []byte           //16K 页面为 5986 byte
 
 }
 
@@ -135,7 +182,7 @@ func NewFspHrdPage(spaceId uint32) *FspHrdBinaryPage {
 	var fspHrdBinaryPage = new(FspHrdBinaryPage)
 	fspHrdBinaryPage.FileHeader = *fileHeader
 	fspHrdBinaryPage.FileSpaceHeader = fileSpaceHeader
-	fspHrdBinaryPage.EmptySpace = util.AppendByte(5986)
+	fspHrdBinaryPage.EmptySpace = util.AppendByte(FspHrdEmptySpaceSize())
 	fspHrdBinaryPage.FileTrailer = NewFileTrailer()
 
 	fspHrdBinaryPage.XDESEntrys = appendXDesEntry()
@@ -143,9 +190,19 @@ func NewFspHrdPage(spaceId uint32) *FspHrdBinaryPage {
 	return fspHrdBinaryPage
 }
 
+// FspHrdEmptySpaceSize returns the size of the unused space of an FSP_HDR
+// or XDES page, between the XDES entries and the file trailer.
+func FspHrdEmptySpaceSize() int {
+	return common.PageSize() - FspHrdXDesArrayOffset - common.ExtentsPerXDesPage()*common.XDesEntrySize() - common.PAGE_FILE_TRAILER_SIZE
+}
+
+// FspHrdXDesArrayOffset is the offset of the first XDES entry of an FSP_HDR
+// or XDES page, XDES_ARR_OFFSET.
+const FspHrdXDesArrayOffset = 150
+
 func appendXDesEntry() []XDESEntry {
 	var xdesEntries = make([]XDESEntry, 0)
-	for i := 0; i < 256; i++ {
+	for i := 0; i < common.ExtentsPerXDesPage(); i++ {
 		xdesEntry := NewXdesEntry()
 
 		xdesEntries = append(xdesEntries, xdesEntry)
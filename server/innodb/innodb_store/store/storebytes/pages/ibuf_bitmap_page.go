@@ -17,8 +17,14 @@ const BYTES_2048 = 3
 //BitMap
 type IBufBitMapPage struct {
 	AbstractPage
-	ChangeBufferBitMap []byte //8192 byte，每4个bit表示一个页面，即管理一个页面，也就是该bitmap下面的16384个页面
-	EmptySpace         []byte // 8146 byte
+	ChangeBufferBitMap []byte //页面大小/2 byte，每4个bit表示一个页面，即管理一个页面，也就是该bitmap下面的 页面大小 个页面
+	EmptySpace         []byte //16K 页面为 8146 byte
+}
+
+// IBufBitmapSize returns the size of the bitmap of an IBUF_BITMAP page,
+// 4 bits for each of the pages it describes.
+func IBufBitmapSize() int {
+	return common.PageSize() / 2
 }
 
 //其中4个bit描述每个page的change_buffer 信息
@@ -62,8 +68,8 @@ func NewIBufBitMapPage(pageNumber uint32) IBufBitMapPage {
 	fileHeader.WritePageSpaceCheckSum(nil)
 
 	ibufBitMapPage.FileHeader = *fileHeader
-	ibufBitMapPage.ChangeBufferBitMap = make([]byte, 8192)
-	ibufBitMapPage.EmptySpace = make([]byte, 8146)
+	ibufBitMapPage.ChangeBufferBitMap = make([]byte, IBufBitmapSize())
+	ibufBitMapPage.EmptySpace = make([]byte, common.PageSize()-38-IBufBitmapSize()-8)
 	ibufBitMapPage.FileTrailer = NewFileTrailer()
 	return *ibufBitMapPage
 }
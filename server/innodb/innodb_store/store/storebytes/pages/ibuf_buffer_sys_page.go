@@ -1,5 +1,44 @@
 package pages
 
+import (
+	"github.com/zhukovaskychina/xmysql-server/server/common"
+	"github.com/zhukovaskychina/xmysql-server/util"
+)
+
 //存储changeBuffer的头部信息 3号
+//IBUF_HEADER 只有一个字段：ChangeBuffer B+树的段头，其余部分为空
 type FilePageTypeSysPage struct {
+	AbstractPage
+
+	IBufSegHeader []byte //10 byte ChangeBuffer B+树的段头
+
+	EmptySpace []byte //页面大小-38-10-8
+
+}
+
+func NewFilePageTypeSysPage(pageNumber uint32) *FilePageTypeSysPage {
+	var sysPage = new(FilePageTypeSysPage)
+	sysPage.FileHeader = NewSysFileHeader(pageNumber, common.FILE_PAGE_TYPE_SYS)
+	sysPage.IBufSegHeader = util.AppendByte(10)
+	sysPage.EmptySpace = util.AppendByte(common.PageSize() - 38 - 10 - 8)
+	sysPage.FileTrailer = NewFileTrailer()
+	return sysPage
+}
+
+func ParseFilePageTypeSysPage(content []byte) *FilePageTypeSysPage {
+	var sysPage = new(FilePageTypeSysPage)
+	sysPage.LoadFileHeader(content[0:38])
+	sysPage.IBufSegHeader = content[38:48]
+	sysPage.EmptySpace = content[48 : len(content)-8]
+	sysPage.LoadFileTrailer(content[len(content)-8:])
+	return sysPage
+}
+
+func (s *FilePageTypeSysPage) GetSerializeBytes() []byte {
+	var buff = make([]byte, 0)
+	buff = append(buff, s.FileHeader.GetSerialBytes()...)
+	buff = append(buff, s.IBufSegHeader...)
+	buff = append(buff, s.EmptySpace...)
+	buff = append(buff, s.FileTrailer.FileTrailer...)
+	return buff
 }
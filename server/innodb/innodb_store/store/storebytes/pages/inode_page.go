@@ -18,8 +18,8 @@ import (
 type INodePage struct {
 	AbstractPage
 	INodePageList DESListNode   //12 byte 存储上一个和下一个INode的页面指针 38-50
-	INodeEntries  []*INodeEntry //16320 byte 用于存储具体的段信息，每个INode 192 byte，一共85 个
-	EmptySpace    []byte        //6 byte
+	INodeEntries  []*INodeEntry //16K 页面为16320 byte 用于存储具体的段信息，每个INode 192 byte，一共85 个，见 common.INodesPerPage
+	EmptySpace    []byte        //16K 页面为6 byte
 
 }
 
@@ -31,8 +31,8 @@ func (fragmentArray FragmentArrayEntry) Byte() []byte {
 	return fragmentArray.PageNo
 }
 
-//192个字节
-//管理了85个段
+//16K 页面为192个字节
+//一个页面管理了85个段
 type INodeEntry struct {
 	SegmentId           []byte               //8个字节，该结构体对应的段的编号（ID） 若值为0，则表示该SLot未被使用
 	NotFullNUsed        []byte               //4个字节，在Notfull链表中已经使用了多少个页面
@@ -40,14 +40,14 @@ type INodeEntry struct {
 	NotFullListBaseNode []byte               //16个字节，NotFull链表
 	FullListBaseNode    []byte               //16个字节，Full链表
 	MagicNumber         []byte               //4个字节 0x5D669D2
-	FragmentArrayEntry  []FragmentArrayEntry //一共32个array，每个ArrayEntry为零散的页面号
+	FragmentArrayEntry  []FragmentArrayEntry //16K 页面一共32个array，每个ArrayEntry为零散的页面号，见 common.INodeFragSlots
 }
 
 //每当创建一个新的索引，构建一个新的Btree，先为非叶子节点的额segment段分配一个inodeentry，再创建一个rootpage，
 //并将该色门头的位置记录到rootpage中，然后再分配leafsegment的inode entry，并记录到rootpage中
 func NewINodeEntry(SegmentId uint64) *INodeEntry {
-	framentArray := make([]FragmentArrayEntry, 32)
-	for i := 0; i < 32; i++ {
+	framentArray := make([]FragmentArrayEntry, common.INodeFragSlots())
+	for i := range framentArray {
 		framentArray[i] = FragmentArrayEntry{PageNo: util.AppendByte(4)}
 	}
 
@@ -75,7 +75,7 @@ func (ientry *INodeEntry) FragmentArrayIsFull() bool {
 func (ientry *INodeEntry) GetCloseZeroFrag() int {
 
 	var result = -1
-	for i := 0; i < 32; i++ {
+	for i := range ientry.FragmentArrayEntry {
 		if (util.ReadUB4Byte2UInt32(ientry.FragmentArrayEntry[i].PageNo)) == 0 {
 			result = i
 			break
@@ -108,13 +108,13 @@ func NewINodePage(spaceNo uint32, pageNumber uint32) *INodePage {
 		NextNodePageNumber: util.AppendByte(4),
 		NextNodeOffSet:     util.AppendByte(2),
 	}
-	iPage.INodeEntries = make([]*INodeEntry, 85)
+	iPage.INodeEntries = make([]*INodeEntry, common.INodesPerPage())
 	for k, v := range iPage.INodeEntries {
 		v = NewINodeEntry(0)
 		iPage.INodeEntries[k] = v
 	}
 	iPage.FileTrailer = NewFileTrailer()
-	iPage.EmptySpace = make([]byte, 6)
+	iPage.EmptySpace = make([]byte, inodeEmptySpaceSize())
 	return iPage
 }
 
@@ -137,7 +137,7 @@ func (ibuf *INodePage) SerializeBytes() []byte {
 		buff = append(buff, v.NotFullListBaseNode...)
 		buff = append(buff, v.FullListBaseNode...)
 		buff = append(buff, v.MagicNumber...)
-		buff = append(buff, util.AppendByte(32*4)...)
+		buff = append(buff, util.AppendByte(common.INodeFragSlots()*4)...)
 	}
 	buff = append(buff, ibuf.EmptySpace...)
 	buff = append(buff, ibuf.FileTrailer.FileTrailer...)
@@ -151,7 +151,7 @@ func NewINodeByParseBytes(content []byte) *INodePage {
 	inodePage.FileTrailer = NewFileTrailer()
 
 	inodePage.LoadFileHeader(content[0:38])
-	inodePage.LoadFileTrailer(content[16384-8 : 16384])
+	inodePage.LoadFileTrailer(content[len(content)-8:])
 
 	//PreNodePageNumber  []byte //4个字节	表示指向前一个INode页面号
 	//PreNodeOffset      []byte //2个字节 65536-1
@@ -162,9 +162,9 @@ func NewINodeByParseBytes(content []byte) *INodePage {
 	inodePage.INodePageList.NextNodePageNumber = content[44:48]
 	inodePage.INodePageList.NextNodeOffSet = content[48:50]
 
-	inodePage.EmptySpace = content[16384-8-6 : 16384-8]
+	inodePage.EmptySpace = content[len(content)-8-inodeEmptySpaceSize() : len(content)-8]
 
-	inodePage.INodeEntries = make([]*INodeEntry, 85)
+	inodePage.INodeEntries = make([]*INodeEntry, common.INodesPerPage())
 
 	//NotFullNUsed        []byte               //4个字节，在Notfull链表中已经使用了多少个页面
 	//FreeListBaseNode    []byte               //16个字节，Free链表 segment上所有page均空闲的extent链表
@@ -184,16 +184,17 @@ func NewINodeByParseBytes(content []byte) *INodePage {
 	//		FragmentArrayEntry:  parseFragmentArray(content[114+192*k : 242+192*k]),
 	//	}
 	//}
-	for k := 0; k < 85; k++ {
+	for k := range inodePage.INodeEntries {
 		//	FragmentArrayEntry := make([]pages.FragmentArrayEntry, 32)
+		entry := content[common.INodeArrOffset+common.INodeEntrySize()*k : common.INodeArrOffset+common.INodeEntrySize()*(k+1)]
 		inodePage.INodeEntries[k] = &INodeEntry{
-			SegmentId:           content[50+192*k : 58+192*k],
-			NotFullNUsed:        content[58+192*k : 62+192*k],
-			FreeListBaseNode:    content[62+192*k : 78+192*k],
-			NotFullListBaseNode: content[78+192*k : 94+192*k],
-			FullListBaseNode:    content[94+192*k : 110+192*k],
-			MagicNumber:         content[110+192*k : 114+192*k],
-			FragmentArrayEntry:  parseFragmentArray(content[114+192*k : 242+192*k]),
+			SegmentId:           entry[0:8],
+			NotFullNUsed:        entry[8:12],
+			FreeListBaseNode:    entry[12:28],
+			NotFullListBaseNode: entry[28:44],
+			FullListBaseNode:    entry[44:60],
+			MagicNumber:         entry[60:64],
+			FragmentArrayEntry:  parseFragmentArray(entry[common.INodeFragArrOffset:]),
 		}
 	}
 	return inodePage
@@ -205,8 +206,14 @@ func (ibuf *INodePage) GetSerializeBytes() []byte {
 
 func parseFragmentArray(content []byte) []FragmentArrayEntry {
 	var buff = make([]FragmentArrayEntry, 0)
-	for i := 0; i < 32; i++ {
+	for i := 0; i < common.INodeFragSlots(); i++ {
 		buff = append(buff, FragmentArrayEntry{PageNo: content[i*4 : i*4+4]})
 	}
 	return buff
 }
+
+// inodeEmptySpaceSize returns the size of the unused space of an INODE
+// page, between the INODE entries and the file trailer.
+func inodeEmptySpaceSize() int {
+	return common.PageSize() - common.INodeArrOffset - common.INodesPerPage()*common.INodeEntrySize() - common.PAGE_FILE_TRAILER_SIZE
+}
@@ -28,6 +28,21 @@ func NewFileHeader() FileHeader {
 	}
 }
 
+// NewSysFileHeader returns the header of the page pageNumber of the system
+// tablespace, of type pageType, not linked to other pages.
+func NewSysFileHeader(pageNumber uint32, pageType int) FileHeader {
+	fh := NewFileHeader()
+	fh.WritePageSpaceCheckSum(nil)
+	fh.WritePageOffset(pageNumber)
+	fh.WritePagePrev(0)
+	fh.WritePageNext(0)
+	fh.WritePageLSN(0)
+	fh.WritePageFileType(pageType)
+	fh.WritePageFileFlushLSN(0)
+	fh.WritePageArch(0)
+	return fh
+}
+
 //暂时写死
 func (fh *FileHeader) WritePageSpaceCheckSum(checkSum []byte) {
 	fh.FilePageSpaceOrCheckSum = []byte{0x01, 0x02, 0x03, 0x04}
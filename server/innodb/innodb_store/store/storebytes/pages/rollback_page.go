@@ -1,19 +1,88 @@
 package pages
 
+import (
+	"github.com/zhukovaskychina/xmysql-server/server/common"
+	"github.com/zhukovaskychina/xmysql-server/util"
+)
+
+const (
+	// TrxRsegMaxSize is TRX_RSEG_MAX_SIZE of a rollback segment without
+	// limit.
+	TrxRsegMaxSize = 0xFFFFFFFE
+)
+
+// TrxRsegNSlots returns the number of undo slots of a rollback segment,
+// TRX_RSEG_N_SLOTS: 1024 with 16K pages.
+func TrxRsegNSlots() int {
+	return common.PageSize() / 16
+}
+
 //回滚页面
 type RollBackPage struct {
 	AbstractPage
 	TrxRsegMaxSize     []byte //4 byte   管理所有的Undo页面链表中的Undo页面数之和的最大值，最大值0xFFFFFFFF
 	TrxRsegHistorySize []byte //4 byte History链表占用的页面数量
-	TrxRsegHistory     []byte //20 byte	History链表的基节点
+	TrxRsegHistory     []byte //16 byte	History链表的基节点
 	TrxRsegFsegHeader  []byte //10byte	对应的段空间header
-	TrxRsegUndoSlots   []byte //4096byte 各个undo页面链表的first undo page 的页面号码的集合，也就是undo slot 集合
-	EmptySpace         []byte //16384-4096-38-4-4-20-10
+	TrxRsegUndoSlots   []byte //页面大小/4 byte 各个undo页面链表的first undo page 的页面号码的集合，也就是undo slot 集合
+	EmptySpace         []byte //页面大小-38-4-4-16-10-页面大小/4-8
 
 }
 
-//构造Rollback回滚页面，理论上是系统表的第5号空间
-func NewRollBackPage() *RollBackPage {
+//构造Rollback回滚页面，第一个回滚段在系统表的6号页面
+//History链表为空，所有的undo slot 都为 FIL_NULL
+func NewRollBackPage(pageNumber uint32) *RollBackPage {
+	var rollbackPage = new(RollBackPage)
+	rollbackPage.FileHeader = NewSysFileHeader(pageNumber, common.FILE_PAGE_TYPE_SYS)
+	rollbackPage.TrxRsegMaxSize = util.ConvertUInt4Bytes(TrxRsegMaxSize)
+	rollbackPage.TrxRsegHistorySize = util.ConvertUInt4Bytes(0)
+	rollbackPage.TrxRsegHistory = util.AppendByte(4)
+	for i := 0; i < 2; i++ {
+		rollbackPage.TrxRsegHistory = append(rollbackPage.TrxRsegHistory, util.ConvertUInt4Bytes(FilNull)...)
+		rollbackPage.TrxRsegHistory = append(rollbackPage.TrxRsegHistory, 0, 0)
+	}
+	rollbackPage.TrxRsegFsegHeader = util.AppendByte(10)
+	rollbackPage.TrxRsegUndoSlots = make([]byte, 0, TrxRsegNSlots()*4)
+	for i := 0; i < TrxRsegNSlots(); i++ {
+		rollbackPage.TrxRsegUndoSlots = append(rollbackPage.TrxRsegUndoSlots, util.ConvertUInt4Bytes(FilNull)...)
+	}
+	rollbackPage.EmptySpace = util.AppendByte(common.PageSize() - 38 - 4 - 4 - 16 - 10 - TrxRsegNSlots()*4 - 8)
+	rollbackPage.FileTrailer = NewFileTrailer()
+	return rollbackPage
+}
+
+func ParseRollBackPage(content []byte) *RollBackPage {
 	var rollbackPage = new(RollBackPage)
+	rollbackPage.LoadFileHeader(content[0:38])
+	rollbackPage.TrxRsegMaxSize = content[38:42]
+	rollbackPage.TrxRsegHistorySize = content[42:46]
+	rollbackPage.TrxRsegHistory = content[46:62]
+	rollbackPage.TrxRsegFsegHeader = content[62:72]
+	rollbackPage.TrxRsegUndoSlots = content[72 : 72+TrxRsegNSlots()*4]
+	rollbackPage.EmptySpace = content[72+TrxRsegNSlots()*4 : len(content)-8]
+	rollbackPage.LoadFileTrailer(content[len(content)-8:])
 	return rollbackPage
 }
+
+func (r *RollBackPage) GetHistorySize() uint32 {
+	return util.ReadUB4Byte2UInt32(r.TrxRsegHistorySize)
+}
+
+// GetUndoSlot returns the first undo page of the undo log in slot i,
+// FIL_NULL when the slot is free.
+func (r *RollBackPage) GetUndoSlot(i int) uint32 {
+	return util.ReadUB4Byte2UInt32(r.TrxRsegUndoSlots[i*4 : i*4+4])
+}
+
+func (r *RollBackPage) GetSerializeBytes() []byte {
+	var buff = make([]byte, 0)
+	buff = append(buff, r.FileHeader.GetSerialBytes()...)
+	buff = append(buff, r.TrxRsegMaxSize...)
+	buff = append(buff, r.TrxRsegHistorySize...)
+	buff = append(buff, r.TrxRsegHistory...)
+	buff = append(buff, r.TrxRsegFsegHeader...)
+	buff = append(buff, r.TrxRsegUndoSlots...)
+	buff = append(buff, r.EmptySpace...)
+	buff = append(buff, r.FileTrailer.FileTrailer...)
+	return buff
+}
@@ -1,5 +1,99 @@
 package pages
 
-//事务系统的相关信息
+import (
+	"github.com/zhukovaskychina/xmysql-server/server/common"
+	"github.com/zhukovaskychina/xmysql-server/util"
+)
+
+const (
+	// FilNull is FIL_NULL, the page number of no page.
+	FilNull = 0xFFFFFFFF
+	// TrxSysRsegSlots is the number of rollback segment slots of the
+	// transaction system page.
+	TrxSysRsegSlots = 128
+)
+
+//事务系统的相关信息，系统表空间的5号页面
+/****
+*  //////////////////////////
+*  //      FileHeader      //  38
+*  //////////////////////////
+*  //   TRX_SYS_TRX_ID_STORE  //  8 最大事务ID，启动时从这里恢复事务ID
+*  //////////////////////////
+*  //   TRX_SYS_FSEG_HEADER   //  10 事务系统段头
+*  //////////////////////////
+*  //   TRX_SYS_RSEGS         //  128*8 回滚段的 space id 和页号，没有使用的为 FIL_NULL
+*  //////////////////////////
+*  //      EmptySpace      //
+*  //////////////////////////
+*  //      FileTrailer     //  8
+*  //////////////////////////
+****/
 type SysTrxSysPage struct {
+	AbstractPage
+
+	TrxIdStore []byte //8 byte
+
+	FsegHeader []byte //10 byte
+
+	RsegSlots []byte //128*8 byte
+
+	EmptySpace []byte //页面大小-38-8-10-1024-8
+}
+
+func NewSysTrxSysPage() *SysTrxSysPage {
+	var trxSysPage = new(SysTrxSysPage)
+	trxSysPage.FileHeader = NewSysFileHeader(5, common.FILE_PAGE_TYPE_TRX_SYS)
+	trxSysPage.TrxIdStore = util.ConvertULong8Bytes(1)
+	trxSysPage.FsegHeader = util.AppendByte(10)
+	trxSysPage.RsegSlots = make([]byte, 0, TrxSysRsegSlots*8)
+	for i := 0; i < TrxSysRsegSlots; i++ {
+		trxSysPage.RsegSlots = append(trxSysPage.RsegSlots, util.ConvertUInt4Bytes(FilNull)...)
+		trxSysPage.RsegSlots = append(trxSysPage.RsegSlots, util.ConvertUInt4Bytes(FilNull)...)
+	}
+	trxSysPage.EmptySpace = util.AppendByte(common.PageSize() - 38 - 8 - 10 - TrxSysRsegSlots*8 - 8)
+	trxSysPage.FileTrailer = NewFileTrailer()
+	return trxSysPage
+}
+
+func ParseSysTrxSysPage(content []byte) *SysTrxSysPage {
+	var trxSysPage = new(SysTrxSysPage)
+	trxSysPage.LoadFileHeader(content[0:38])
+	trxSysPage.TrxIdStore = content[38:46]
+	trxSysPage.FsegHeader = content[46:56]
+	trxSysPage.RsegSlots = content[56 : 56+TrxSysRsegSlots*8]
+	trxSysPage.EmptySpace = content[56+TrxSysRsegSlots*8 : len(content)-8]
+	trxSysPage.LoadFileTrailer(content[len(content)-8:])
+	return trxSysPage
+}
+
+func (t *SysTrxSysPage) GetMaxTrxId() uint64 {
+	return util.ReadUB8Byte2Long(t.TrxIdStore)
+}
+
+func (t *SysTrxSysPage) SetMaxTrxId(trxId uint64) {
+	t.TrxIdStore = util.ConvertULong8Bytes(trxId)
+}
+
+// GetRsegSlot returns the space id and the page number of the header of the
+// rollback segment in slot i, FIL_NULL when the slot isn't used.
+func (t *SysTrxSysPage) GetRsegSlot(i int) (spaceId uint32, pageNo uint32) {
+	slot := t.RsegSlots[i*8 : i*8+8]
+	return util.ReadUB4Byte2UInt32(slot[0:4]), util.ReadUB4Byte2UInt32(slot[4:8])
+}
+
+func (t *SysTrxSysPage) SetRsegSlot(i int, spaceId uint32, pageNo uint32) {
+	copy(t.RsegSlots[i*8:], util.ConvertUInt4Bytes(spaceId))
+	copy(t.RsegSlots[i*8+4:], util.ConvertUInt4Bytes(pageNo))
+}
+
+func (t *SysTrxSysPage) GetSerializeBytes() []byte {
+	var buff = make([]byte, 0)
+	buff = append(buff, t.FileHeader.GetSerialBytes()...)
+	buff = append(buff, t.TrxIdStore...)
+	buff = append(buff, t.FsegHeader...)
+	buff = append(buff, t.RsegSlots...)
+	buff = append(buff, t.EmptySpace...)
+	buff = append(buff, t.FileTrailer.FileTrailer...)
+	return buff
 }
@@ -12,7 +12,7 @@ type DESListNode struct {
 	NextNodeOffSet     []byte //2个字节	65536-1
 }
 
-//XDES entry,每个Entry 占用40个字节
+//XDES entry,16K 页面每个Entry 占用40个字节，大小见 common.XDesEntrySize
 //一个XDES-ENtry 对应一个extent
 // XdesId 与xdesstate之间的关系 如果xdesid有值，则xdesstate为fseg
 // xdesflstNode 则是将相同状态的extent做了链接
@@ -21,7 +21,7 @@ type XDESEntry struct {
 	XDesId       []byte //8 个 byte 每个段都有唯一的编号，分配段的号码
 	XDesFlstNode []byte //12 个长度 XDesEntry链表 维持Extent链表的双向指针节点
 	XDesState    []byte //4个字节长度，根据该Extent状态信息，包括：XDES_FREE,FREE_FRAG,FULL_FRAG,FSEG
-	XDesBitMap   []byte //16个字节，一共128个bit，用两个bit表示Extent中的一个page，一个bit表示该page是否空闲的（XDES_FREE_BIT）,另一个保留位
+	XDesBitMap   []byte //16K 页面为16个字节，一共128个bit，用两个bit表示Extent中的一个page，一个bit表示该page是否空闲的（XDES_FREE_BIT）,另一个保留位
 }
 
 //extentoffset 区的偏移量
@@ -30,7 +30,7 @@ func NewXdesEntry() XDESEntry {
 	xdesEntry.XDesId = util.AppendByte(8)
 	xdesEntry.XDesFlstNode = util.AppendByte(12)
 	xdesEntry.XDesState = util.ConvertUInt4Bytes(uint32(common.XDES_FREE))
-	xdesEntry.XDesBitMap = util.AppendByte(16)
+	xdesEntry.XDesBitMap = util.AppendByte(common.XDesBitmapSize())
 	return *xdesEntry
 }
 
@@ -39,7 +39,7 @@ func ParseXDesEntry(content []byte) XDESEntry {
 	xdesEntry.XDesId = content[0:8]
 	xdesEntry.XDesFlstNode = content[8:20]
 	xdesEntry.XDesState = content[20:24]
-	xdesEntry.XDesBitMap = content[24:40]
+	xdesEntry.XDesBitMap = content[common.XDesBitmapOffset:common.XDesEntrySize()]
 	return *xdesEntry
 }
 
@@ -89,8 +89,8 @@ func (x *XDESEntry) GetSerializeByte() []byte {
 type XDesPage struct {
 	AbstractPage
 	FirstEmptySpace  []byte      //38-150
-	XDESEntries      []XDESEntry //150-10390
-	SecondEmptySpace []byte      //10390-16376
+	XDESEntries      []XDESEntry //16K 页面为 150-10390
+	SecondEmptySpace []byte      //16K 页面为 10390-16376
 }
 
 func NewXDesPage(pageNumber uint32) XDesPage {
@@ -17,6 +17,12 @@ const (
 	colKey
 )
 
+// Row types other than CompactRowType kept in Form.RowType.
+const (
+	DynamicRowType   = 1
+	RedundantRowType = 2
+)
+
 /**
 *  0x0100
 auto_increment_offset表示自增长字段从那个数开始，他的取值范围是1 .. 65535
@@ -3,8 +3,16 @@ package store
 import (
 	"github.com/zhukovaskychina/xmysql-server/server/conf"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/buffer_pool"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"math"
+	"os"
+	"path"
 	"strings"
 
+	"github.com/zhukovaskychina/xmysql-server/server/common"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
 	tuple2 "github.com/zhukovaskychina/xmysql-server/server/innodb/tuple"
 )
@@ -37,6 +45,11 @@ type OrdinaryTable struct {
 	fullName string
 
 	tableTupleMeta *TableTupleMeta
+
+	//表的定义，表空间和读取页面的缓冲池，由 CreateOrdinaryTable 和 OpenOrdinaryTable 设置
+	info  *model.TableInfo
+	space *UnSysTableSpace
+	pool  *buffer_pool.BufferPool
 }
 
 func (o OrdinaryTable) TableName() string {
@@ -67,6 +80,45 @@ func (o OrdinaryTable) GetBtree(indexName string) basic.Tree {
 	return o.btreeMap[indexName]
 }
 
+// EstimateIndexRows implements schemas.IndexRowsEstimator, from the pages of
+// the leaf segment of the index.
+func (o OrdinaryTable) EstimateIndexRows(indexName string) (int64, bool) {
+	tree, ok := o.btreeMap[indexName].(*BTree)
+	if !ok || tree == nil || tree.dataSegment == nil {
+		return 0, false
+	}
+	rows := tree.dataSegment.GetStatsCost(0, math.MaxUint32)["nROWS"]
+	return rows, rows > 0
+}
+
+// TableStatus implements schemas.TableStatusReader: the pages of the
+// segments of the clustered index are its data, those of the secondary
+// indexes its index; the .frm and .ibd files tell when it was created and
+// last written.
+func (o OrdinaryTable) TableStatus() schemas.TableStatus {
+	var status schemas.TableStatus
+	for name, tree := range o.btreeMap {
+		btree, ok := tree.(*BTree)
+		if !ok || btree == nil {
+			continue
+		}
+		length := (segmentPages(btree.indexSegment) + segmentPages(btree.dataSegment)) * int64(common.PageSize())
+		if name == mysql.PrimaryKeyName {
+			status.DataLength += length
+		} else {
+			status.IndexLength += length
+		}
+	}
+	dir := path.Join(o.conf.DataDir, o.databaseName)
+	if info, err := os.Stat(path.Join(dir, o.tableName+".frm")); err == nil {
+		status.CreateTime = info.ModTime()
+	}
+	if info, err := os.Stat(path.Join(dir, o.tableName+".ibd")); err == nil {
+		status.UpdateTime = info.ModTime()
+	}
+	return status
+}
+
 func (o OrdinaryTable) GetTableTupleMeta() tuple2.TableTuple {
 	return o.tableTupleMeta
 }
@@ -88,12 +140,34 @@ func (o *OrdinaryTable) AddFilePath(filePath string) {
 
 func (o *OrdinaryTable) ReadFrmTuples() {
 	nameCopy := strings.Split(o.fullName, "/")
-	o.databaseName = nameCopy[0]
-	o.tableName = nameCopy[1]
+	o.databaseName, o.tableName = tableFileNames(o.conf.DataDir, nameCopy[0], nameCopy[1])
 	o.tableTupleMeta = NewTupleMeta(o.databaseName, o.tableName, o.conf)
 	o.tableTupleMeta.ReadFrmFromDisk()
 }
 
+// rename renames the table to databaseName/tableName, along with the open
+// .frm file and the tablespace space, once their files have been moved.
+func (o *OrdinaryTable) rename(databaseName string, tableName string, space *UnSysTableSpace) {
+	o.databaseName = databaseName
+	o.tableName = tableName
+	o.fullName = databaseName + "/" + tableName
+	dir := path.Join(o.conf.DataDir, databaseName)
+	if o.tableTupleMeta != nil {
+		o.tableTupleMeta.DatabaseName = databaseName
+		o.tableTupleMeta.TableName = tableName
+		if frm := o.tableTupleMeta.blockFile; frm != nil {
+			frm.FilePath = path.Join(dir, tableName+".frm")
+			frm.FileName = tableName + ".frm"
+		}
+	}
+	if space != nil {
+		space.dataBaseName = databaseName
+		space.tableName = tableName + ".ibd"
+		space.blockFile.FilePath = dir
+		space.blockFile.FileName = tableName + ".ibd"
+	}
+}
+
 func (o *OrdinaryTable) GetInfoWrappers() []*tuple2.IndexInfoWrapper {
 	var indexInfoWrappers = make([]*tuple2.IndexInfoWrapper, 0)
 	indexInfoWrappers = append(indexInfoWrappers, o.tableTupleMeta.PrimaryIndexInfos)
@@ -0,0 +1,53 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/common"
+	"github.com/zhukovaskychina/xmysql-server/server/conf"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+func TestOrdinaryTableStatus(t *testing.T) {
+	inode := &INode{
+		SegMap:           make(map[uint64]*INodeEntryWrapper),
+		SegFreeExtentMap: make(map[uint64]*ExtentList),
+		SegFullExtentMap: make(map[uint64]*ExtentList),
+		SegNotExtentMap:  make(map[uint64]*ExtentList),
+	}
+	// Segments 1 and 2 are the leaf and non-leaf segments of the clustered
+	// index, 3 the leaf segment of a secondary index.
+	for id := uint64(1); id <= 3; id++ {
+		inode.SegMap[id] = NewINodeEntryWrapper(id, inode)
+	}
+	for _, page := range []uint32{4, 5, 6} {
+		inode.SegMap[1].ApplyDiscretePage(page)
+	}
+	inode.SegMap[1].FullListLength = 2
+	inode.SegMap[2].ApplyDiscretePage(3)
+	inode.SegMap[3].ApplyDiscretePage(7)
+	// An extent allocated since the entry was read.
+	inode.SegFreeExtentMap[3] = NewExtentList("FREE")
+	inode.SegFreeExtentMap[3].AddExtent(NewSecondaryPrimaryExtent(128))
+
+	if pages := segmentPages(&DataSegment{inode: inode, segmentId: 1}); pages != 3+2*64 {
+		t.Fatalf("expect %d pages, got %d", 3+2*64, pages)
+	}
+	if pages := segmentPages(&DataSegment{}); pages != 0 {
+		t.Fatalf("expect no pages without an inode, got %d", pages)
+	}
+
+	tbl := NewOrdinaryTable(conf.NewCfg(), 5, 1, "test/t").(*OrdinaryTable)
+	tbl.AddBTree(mysql.PrimaryKeyName, &BTree{
+		dataSegment:  &DataSegment{inode: inode, segmentId: 1},
+		indexSegment: &InternalSegment{inode: inode, segmentId: 2},
+	})
+	tbl.AddBTree("idx_a", &BTree{dataSegment: &DataSegment{inode: inode, segmentId: 3}})
+	status := tbl.TableStatus()
+	if status.DataLength != (3+2*64+1)*common.PAGE_SIZE || status.IndexLength != (1+64)*common.PAGE_SIZE {
+		t.Fatalf("unexpected data length %d and index length %d", status.DataLength, status.IndexLength)
+	}
+	if !status.CreateTime.IsZero() || !status.UpdateTime.IsZero() {
+		t.Fatalf("expect no times without the files, got %v", status)
+	}
+}
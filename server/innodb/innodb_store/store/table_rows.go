@@ -0,0 +1,460 @@
+package store
+
+import (
+	"math"
+	"os"
+	"path"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/zhukovaskychina/xmysql-server/server/conf"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/buffer_pool"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/tuple"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/charset"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+	"github.com/zhukovaskychina/xmysql-server/util"
+)
+
+/**
+业务表的行
+
+CreateOrdinaryTable 为一张表创建 .frm 和 .ibd：.frm 记下各列在记录中的类型，.ibd 在
+FSP、IBUF 和 INODE 三个页面之后的 3 号页面是聚簇索引的根页面，一开始是一个空的叶子页面。
+OpenOrdinaryTable 打开已经创建的表。表的定义（model.TableInfo）由调用者给出，.frm 不保存
+ENUM 列的成员，也不保存列是否 UNSIGNED。
+
+AddRow 和 RemoveRow（schemas.RowWriter）按主键的顺序改写根页面上的记录：读出页面上的
+全部记录，加入或去掉一行后整页重写，绕过缓冲池直接写入 .ibd，再丢弃缓冲池中的旧页面，
+之后的读取从文件读入新的页面。没有主键的表按所有列的顺序排列。
+
+聚簇索引还只有根页面这一层：根页面放不下新的记录时 AddRow 返回 ER_RECORD_FILE_FULL，
+不分裂页面。有二级索引的表不能写，二级索引还没有办法跟着聚簇索引一起修改。
+
+列的类型：TINYINT 到 INT 在记录中占 4 个字节，像 InnoDB 一样把符号位取反保存，BIGINT 占
+8 个字节，都是有符号数；CHAR、VARCHAR、TEXT 和它们的二进制版本保存值的字节；ENUM 保存
+成员的序号。其他类型的表不能创建。
+**/
+
+// ordinaryRootPage is the page of the tablespace of a table holding the root
+// of its clustered index, the one after the FSP header, the insert buffer
+// bitmap and the first inode page.
+const ordinaryRootPage = 3
+
+// CreateOrdinaryTable creates the .frm and the .ibd of info, a table of
+// database, in the tablespace spaceId, and opens it. pool reads its pages,
+// or nil to read them from the file, at initialization.
+func CreateOrdinaryTable(cfg *conf.Cfg, pool *buffer_pool.BufferPool, database string, info *model.TableInfo, spaceId uint32) (*OrdinaryTable, error) {
+	if _, err := os.Stat(tableFilePath(cfg, database, info, ".ibd")); err == nil {
+		return nil, schemas.ErrTableExists.GenByArgs(info.Name.O)
+	}
+	if err := os.MkdirAll(path.Dir(tableFilePath(cfg, database, info, ".ibd")), 0750); err != nil {
+		return nil, err
+	}
+	meta, err := tableTupleMetaOf(cfg, database, info)
+	if err != nil {
+		return nil, err
+	}
+	space := NewTableSpaceFile(cfg, database, info.Name.O, spaceId, false, pool).(*UnSysTableSpace)
+	meta.FlushToDisk()
+	root := NewPageIndexWithTuple(spaceId, ordinaryRootPage, meta.GetPrimaryClusterLeafTuple()).(*Index)
+	if err = space.blockFile.WriteContentByPage(ordinaryRootPage, root.ToByte()); err != nil {
+		return nil, err
+	}
+	return openOrdinaryTable(cfg, pool, database, info, spaceId, meta, space), nil
+}
+
+// OpenOrdinaryTable opens info, a table of database created by
+// CreateOrdinaryTable in the tablespace spaceId.
+func OpenOrdinaryTable(cfg *conf.Cfg, pool *buffer_pool.BufferPool, database string, info *model.TableInfo, spaceId uint32) (*OrdinaryTable, error) {
+	if _, err := os.Stat(tableFilePath(cfg, database, info, ".ibd")); err != nil {
+		return nil, schemas.ErrTableNotExists.GenByArgs(database, info.Name.O)
+	}
+	meta, err := tableTupleMetaOf(cfg, database, info)
+	if err != nil {
+		return nil, err
+	}
+	space := NewTableSpaceFile(cfg, database, info.Name.O, spaceId, false, pool).(*UnSysTableSpace)
+	return openOrdinaryTable(cfg, pool, database, info, spaceId, meta, space), nil
+}
+
+func openOrdinaryTable(cfg *conf.Cfg, pool *buffer_pool.BufferPool, database string, info *model.TableInfo, spaceId uint32, meta *TableTupleMeta, space *UnSysTableSpace) *OrdinaryTable {
+	table := NewOrdinaryTable(cfg, spaceId, uint64(info.ID), database+"/"+info.Name.O).(*OrdinaryTable)
+	table.databaseName, table.tableName = tableFileNames(cfg.DataDir, database, info.Name.O)
+	table.tableTupleMeta = meta
+	table.tuple = meta.GetPrimaryClusterLeafTuple()
+	table.info, table.space, table.pool = info, space, pool
+	internalTuple := NewClusterInternalTuple(meta)
+	var tree *BTree
+	if pool != nil {
+		pool.FileSystem.AddTableSpace(space)
+		tree = NewBtreeWithBufferPool(spaceId, ordinaryRootPage, mysql.PrimaryKeyName, nil, nil, nil, pool, internalTuple, table.tuple)
+	} else {
+		tree = NewBtreeAtInit(ordinaryRootPage, mysql.PrimaryKeyName, nil, nil, nil, space.blockFile, internalTuple, table.tuple)
+	}
+	table.AddBTree(mysql.PrimaryKeyName, tree)
+	return table
+}
+
+// tableFilePath returns the path of the file of info, a table of database,
+// with extension ext.
+func tableFilePath(cfg *conf.Cfg, database string, info *model.TableInfo, ext string) string {
+	database, name := tableFileNames(cfg.DataDir, database, info.Name.O)
+	return path.Join(cfg.DataDir, database, name+ext)
+}
+
+// tableTupleMetaOf returns the tuple of the records of info, a table of
+// database.
+func tableTupleMetaOf(cfg *conf.Cfg, database string, info *model.TableInfo) (*TableTupleMeta, error) {
+	dbName, tableName := tableFileNames(cfg.DataDir, database, info.Name.O)
+	meta := NewTupleMeta(dbName, tableName, cfg)
+	meta.DatabaseName, meta.TableName = dbName, tableName
+	for _, col := range info.Columns {
+		frmCol, err := frmColumn(col)
+		if err != nil {
+			return nil, err
+		}
+		meta.Columns = append(meta.Columns, frmCol)
+		meta.ColumnsMap[frmCol.FieldName] = frmCol
+	}
+	for _, idx := range info.Indices {
+		if !idx.Primary {
+			meta.SecondaryIndexInfos = append(meta.SecondaryIndexInfos, indexInfoWrapper(meta, idx))
+			meta.IndexesMap[idx.Name.O] = meta.SecondaryIndexInfos[len(meta.SecondaryIndexInfos)-1]
+		}
+	}
+	primary := &tuple.IndexInfoWrapper{IndexName: mysql.PrimaryKeyName, Primary: true, Unique: true}
+	for _, offset := range primaryKeyOffsets(info) {
+		primary.IndexColumns = append(primary.IndexColumns, meta.Columns[offset])
+	}
+	meta.PrimaryKeyMeta, meta.PrimaryIndexInfos = primary, primary
+	return meta, nil
+}
+
+func indexInfoWrapper(meta *TableTupleMeta, idx *model.IndexInfo) *tuple.IndexInfoWrapper {
+	wrapper := &tuple.IndexInfoWrapper{IndexName: idx.Name.O, Primary: idx.Primary, Unique: idx.Unique}
+	for _, col := range idx.Columns {
+		wrapper.IndexColumns = append(wrapper.IndexColumns, meta.Columns[col.Offset])
+	}
+	return wrapper
+}
+
+// frmColumn returns the column of the .frm of col.
+func frmColumn(col *model.ColumnInfo) (*tuple.FormColumnsWrapper, error) {
+	frmCol := &tuple.FormColumnsWrapper{
+		FieldName:     col.Name.O,
+		NotNull:       mysql.HasNotNullFlag(col.Flag),
+		AutoIncrement: mysql.HasAutoIncrementFlag(col.Flag),
+		FieldCharset:  col.Charset,
+	}
+	binary := col.Charset == charset.CharsetBin
+	length := col.Flen
+	if length > math.MaxInt16 {
+		length = math.MaxInt16
+	}
+	switch col.Tp {
+	case mysql.TypeTiny, mysql.TypeShort, mysql.TypeInt24, mysql.TypeLong:
+		frmCol.FieldType, length = "INT", 4
+	case mysql.TypeLonglong:
+		frmCol.FieldType, length = "BIGINT", 8
+	case mysql.TypeVarchar, mysql.TypeVarString:
+		frmCol.FieldType = typeName(binary, "VARCHAR", "VARBINARY")
+	case mysql.TypeString:
+		frmCol.FieldType = typeName(binary, "CHAR", "BINARY")
+	case mysql.TypeTinyBlob, mysql.TypeBlob, mysql.TypeMediumBlob, mysql.TypeLongBlob:
+		frmCol.FieldType = typeName(binary, "TEXT", "BLOB")
+	case mysql.TypeEnum:
+		frmCol.FieldType, frmCol.FieldElems, length = "ENUM", col.Elems, len(col.Elems)
+	default:
+		return nil, mysql.NewErrf(mysql.ErrNotSupportedYet, "the type of column %s", col.Name.O)
+	}
+	if mysql.HasUnsignedFlag(col.Flag) {
+		return nil, mysql.NewErrf(mysql.ErrNotSupportedYet, "the UNSIGNED column %s", col.Name.O)
+	}
+	frmCol.FieldLength = int16(length)
+	frmCol.FieldTypeIntValue = int(tuple.RefTypeValue[frmCol.FieldType])
+	return frmCol, nil
+}
+
+func typeName(binary bool, text string, bin string) string {
+	if binary {
+		return bin
+	}
+	return text
+}
+
+// primaryKeyOffsets returns the offsets of the columns of the primary key of
+// info, all of them when it has none.
+func primaryKeyOffsets(info *model.TableInfo) []int {
+	var offsets []int
+	if info.PKIsHandle {
+		for _, col := range info.Columns {
+			if mysql.HasPriKeyFlag(col.Flag) {
+				return []int{col.Offset}
+			}
+		}
+	}
+	for _, idx := range info.Indices {
+		if idx.Primary {
+			for _, col := range idx.Columns {
+				offsets = append(offsets, col.Offset)
+			}
+			return offsets
+		}
+	}
+	for _, col := range info.Columns {
+		offsets = append(offsets, col.Offset)
+	}
+	return offsets
+}
+
+// hasPrimaryKey reports whether info has a primary key.
+func hasPrimaryKey(info *model.TableInfo) bool {
+	if info.PKIsHandle {
+		return true
+	}
+	for _, idx := range info.Indices {
+		if idx.Primary {
+			return true
+		}
+	}
+	return false
+}
+
+// signBit32 is flipped in the INT values of the records, as InnoDB does,
+// so that the records, which compare them unsigned, keep them in order.
+const signBit32 = uint32(1) << 31
+
+// encodeDatum returns d as a record of col keeps it, nil for NULL.
+func encodeDatum(col *tuple.FormColumnsWrapper, d basic.Datum) ([]byte, error) {
+	if d.IsNull() {
+		return nil, nil
+	}
+	sc := new(variable.StatementContext)
+	switch col.FieldType {
+	case "INT":
+		v, err := d.ToInt64(sc)
+		if err != nil {
+			return nil, err
+		}
+		if v < math.MinInt32 || v > math.MaxInt32 {
+			return nil, errors.Errorf("value %d is out of the range of %s", v, col.FieldName)
+		}
+		return util.ConvertUInt4Bytes(uint32(int32(v)) ^ signBit32), nil
+	case "BIGINT":
+		v, err := d.ToInt64(sc)
+		if err != nil {
+			return nil, err
+		}
+		return util.ConvertULong8Bytes(uint64(v)), nil
+	case "ENUM":
+		var ordinal uint64
+		switch d.Kind() {
+		case basic.KindMysqlEnum:
+			ordinal = d.GetMysqlEnum().Value
+		case basic.KindString, basic.KindBytes:
+			e, err := basic.ParseEnumName(col.FieldElems, d.GetString())
+			if err != nil {
+				return nil, err
+			}
+			ordinal = e.Value
+		default:
+			v, err := d.ToInt64(sc)
+			if err != nil {
+				return nil, err
+			}
+			ordinal = uint64(v)
+		}
+		return basic.EnumOrdinalBytes(ordinal, col.GetMaxByteLength()), nil
+	}
+	s, err := d.ToString()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(s), nil
+}
+
+// decodeValue returns the datum of v, a value of col in a record.
+func decodeValue(col *tuple.FormColumnsWrapper, v basic.Value) basic.Datum {
+	switch col.FieldType {
+	case "INT":
+		return basic.NewIntDatum(int64(int32(util.ReadUB4Byte2UInt32(v.ToByte()) ^ signBit32)))
+	case "BIGINT":
+		return basic.NewIntDatum(int64(util.ReadUB8Byte2Long(v.ToByte())))
+	case "ENUM":
+		return v.ToDatum()
+	case "BLOB", "VARBINARY", "BINARY":
+		return basic.NewBytesDatum(v.ToByte())
+	}
+	return basic.NewStringDatum(string(v.ToByte()))
+}
+
+// tableRecord is a record of the clustered index of a table with the
+// values of its key, which order the records of a page.
+type tableRecord struct {
+	basic.Row
+	key []basic.Datum
+}
+
+func (r *tableRecord) Less(than basic.Row) bool {
+	if than.IsSupremumRow() {
+		return true
+	}
+	if than.IsInfimumRow() {
+		return false
+	}
+	other, ok := than.(*tableRecord)
+	return ok && compareDatums(r.key, other.key) < 0
+}
+
+// compareDatums compares a and b, two rows or keys of the same columns.
+func compareDatums(a, b []basic.Datum) int {
+	sc := new(variable.StatementContext)
+	for i := range a {
+		if cmp, err := a[i].CompareDatum(sc, &b[i]); err != nil || cmp != 0 {
+			return cmp
+		}
+	}
+	return 0
+}
+
+// Meta returns the definition of the table, nil for the tables of the
+// dictionary.
+func (o OrdinaryTable) Meta() *model.TableInfo {
+	return o.info
+}
+
+func (o OrdinaryTable) Cols() []*schemas.Column {
+	cols := make([]*schemas.Column, 0, len(o.info.Columns))
+	for _, col := range o.info.Columns {
+		cols = append(cols, schemas.ToColumn(col))
+	}
+	return cols
+}
+
+func (o OrdinaryTable) WritableCols() []*schemas.Column {
+	return o.Cols()
+}
+
+var _ schemas.RowWriter = (*OrdinaryTable)(nil)
+
+// AddRow implements schemas.RowWriter. It fails with ER_RECORD_FILE_FULL
+// when the root page of the table can't take the row.
+func (o *OrdinaryTable) AddRow(row []basic.Datum) error {
+	meta := o.tableTupleMeta
+	record := NewClusterLeafRowWithFrm(meta).(*ClusterLeafRow)
+	for i, col := range meta.Columns {
+		content, err := encodeDatum(col, row[i])
+		if err != nil {
+			return err
+		}
+		if err = record.WriteColumn(content, byte(i)); err != nil {
+			return err
+		}
+	}
+	added := o.newRecord(NewClusterLeafRowWithContent(record.ToByte(), o.tuple))
+	return o.writeRecords(func(records []*tableRecord) ([]*tableRecord, error) {
+		i := 0
+		for ; i < len(records); i++ {
+			cmp := compareDatums(records[i].key, added.key)
+			if cmp == 0 && hasPrimaryKey(o.info) {
+				return nil, mysql.NewErr(mysql.ErrDupEntry, keyString(added.key), mysql.PrimaryKeyName)
+			}
+			if cmp > 0 {
+				break
+			}
+		}
+		records = append(records, nil)
+		copy(records[i+1:], records[i:])
+		records[i] = added
+		return records, nil
+	})
+}
+
+// RemoveRow implements schemas.RowWriter.
+func (o *OrdinaryTable) RemoveRow(row []basic.Datum) error {
+	return o.writeRecords(func(records []*tableRecord) ([]*tableRecord, error) {
+		for i, record := range records {
+			if compareDatums(record.ToDatum(), row) == 0 {
+				return append(records[:i], records[i+1:]...), nil
+			}
+		}
+		return nil, errors.Errorf("table %s has no row %s", o.info.Name.O, keyString(row))
+	})
+}
+
+func (o *OrdinaryTable) newRecord(row basic.Row) *tableRecord {
+	datums := row.ToDatum()
+	offsets := primaryKeyOffsets(o.info)
+	key := make([]basic.Datum, len(offsets))
+	for i, offset := range offsets {
+		key[i] = datums[offset]
+	}
+	return &tableRecord{Row: row, key: key}
+}
+
+// writeRecords replaces the records of the root page of the clustered index
+// with the ones change returns from them, in key order.
+func (o *OrdinaryTable) writeRecords(change func(records []*tableRecord) ([]*tableRecord, error)) error {
+	for _, idx := range o.info.Indices {
+		if !idx.Primary {
+			return mysql.NewErrf(mysql.ErrNotSupportedYet, "writing the rows of a table with secondary indexes")
+		}
+	}
+	tree := o.btreeMap[mysql.PrimaryKeyName].(*BTree)
+	latch := tree.latches.get(ordinaryRootPage)
+	latch.Lock()
+	defer latch.Unlock()
+	frame, err := tree.readPage(ordinaryRootPage)
+	if err != nil {
+		return err
+	}
+	root := NewPageIndexByLoadBytesWithTuple(frame, o.tuple).(*Index)
+	var records []*tableRecord
+	if root.GetRecordSize() > 0 {
+		for _, row := range root.SlotRowData.GetRowListWithoutInfiuAndSupremum() {
+			records = append(records, o.newRecord(row))
+		}
+		//页面按第一列排列记录，表按主键
+		sort.SliceStable(records, func(i, j int) bool {
+			return compareDatums(records[i].key, records[j].key) < 0
+		})
+	}
+	if records, err = change(records); err != nil {
+		return err
+	}
+	rows := make([]basic.Row, len(records))
+	for i, record := range records {
+		rows[i] = record
+	}
+	if err = root.ReplaceRows(rows); err != nil {
+		return mysql.NewErr(mysql.ErrRecordFileFull, o.info.Name.O)
+	}
+	if len(rows) == 0 {
+		//没有记录时 ReplaceRows 不重算页面的空闲空间，重新写一个空的根页面
+		root = NewPageIndexWithTuple(o.spaceId, ordinaryRootPage, o.tuple).(*Index)
+	}
+	if err = o.space.blockFile.WriteContentByPage(ordinaryRootPage, root.ToByte()); err != nil {
+		return err
+	}
+	if o.pool != nil {
+		o.pool.DiscardPage(o.spaceId, ordinaryRootPage)
+	}
+	return nil
+}
+
+// keyString returns the values of key as ER_DUP_ENTRY shows them.
+func keyString(key []basic.Datum) string {
+	s := ""
+	for i, d := range key {
+		if i > 0 {
+			s += "-"
+		}
+		v, _ := d.ToString()
+		s += v
+	}
+	return s
+}
@@ -0,0 +1,176 @@
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/conf"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/innodb_store/store/storebytes/pages"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+func newRowsTestTable() *model.TableInfo {
+	col := func(offset int, name string, tp byte, flen int, flag uint, elems ...string) *model.ColumnInfo {
+		c := &model.ColumnInfo{ID: int64(offset + 1), Name: model.NewCIStr(name), Offset: offset, State: model.StatePublic}
+		c.Tp, c.Flen, c.Flag, c.Elems, c.Charset = tp, flen, flag, elems, "utf8mb4"
+		return c
+	}
+	return &model.TableInfo{
+		ID:   1,
+		Name: model.NewCIStr("t"),
+		Columns: []*model.ColumnInfo{
+			col(0, "id", mysql.TypeLong, 11, mysql.PriKeyFlag|mysql.NotNullFlag),
+			col(1, "name", mysql.TypeVarchar, 1000, 0),
+			col(2, "size", mysql.TypeEnum, 1, 0, "small", "large"),
+			col(3, "code", mysql.TypeString, 4, 0),
+		},
+		PKIsHandle: true,
+		State:      model.StatePublic,
+	}
+}
+
+// tableRows reads the rows of tbl through its clustered index.
+func tableRows(t *testing.T, tbl *OrdinaryTable) [][]basic.Datum {
+	t.Helper()
+	it, err := tbl.GetBtree(mysql.PrimaryKeyName).Range(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rows [][]basic.Datum
+	for {
+		var row basic.Row
+		if _, _, row, err, it = it(); err != nil {
+			t.Fatal(err)
+		}
+		if it == nil {
+			return rows
+		}
+		rows = append(rows, row.ToDatum())
+	}
+}
+
+func TestOrdinaryTableRows(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rows")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cfg := conf.NewCfg()
+	cfg.DataDir = dir
+	info := newRowsTestTable()
+	tbl, err := CreateOrdinaryTable(cfg, nil, "test", info, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = CreateOrdinaryTable(cfg, nil, "test", info, 5); err == nil {
+		t.Fatalf("expect the table to exist")
+	}
+	if tbl.Meta() != info || len(tbl.Cols()) != 4 {
+		t.Fatalf("expect the definition of the table, got %v", tbl.Meta())
+	}
+
+	for _, row := range [][]basic.Datum{
+		basic.MakeDatums(int64(3), "c", "large", "ccc"),
+		basic.MakeDatums(int64(-1), nil, nil, nil),
+		basic.MakeDatums(int64(2), "b", basic.Enum{Name: "small", Value: 1}, "bb"),
+	} {
+		if err = tbl.AddRow(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The rows come in the order of the key, negative ones first, and the
+	// CHAR values without their padding.
+	expect := func(want string) {
+		t.Helper()
+		var got []string
+		for _, row := range tableRows(t, tbl) {
+			var fields []string
+			for _, d := range row {
+				s, _ := d.ToString()
+				if d.IsNull() {
+					s = "NULL"
+				}
+				fields = append(fields, s)
+			}
+			got = append(got, strings.Join(fields, ","))
+		}
+		if strings.Join(got, ";") != want {
+			t.Fatalf("expect %s, got %s", want, strings.Join(got, ";"))
+		}
+	}
+	expect("-1,NULL,NULL,NULL;2,b,small,bb;3,c,large,ccc")
+
+	if err = tbl.AddRow(basic.MakeDatums(int64(2), "x", nil, nil)); err == nil || err.(*mysql.SQLError).Code != mysql.ErrDupEntry {
+		t.Fatalf("expect error %d, got %v", mysql.ErrDupEntry, err)
+	}
+	if err = tbl.RemoveRow(basic.MakeDatums(int64(2), "b", basic.Enum{Name: "small", Value: 1}, "bb")); err != nil {
+		t.Fatal(err)
+	}
+	if err = tbl.RemoveRow(basic.MakeDatums(int64(2), "b", nil, nil)); err == nil {
+		t.Fatalf("expect no such row")
+	}
+	expect("-1,NULL,NULL,NULL;3,c,large,ccc")
+
+	// The rows are in the file.
+	if tbl, err = OpenOrdinaryTable(cfg, nil, "test", info, 5); err != nil {
+		t.Fatal(err)
+	}
+	expect("-1,NULL,NULL,NULL;3,c,large,ccc")
+	// Removing the last row leaves a whole empty page.
+	for _, row := range tableRows(t, tbl) {
+		if err = tbl.RemoveRow(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expect("")
+	if frame, err := tbl.GetBtree(mysql.PrimaryKeyName).(*BTree).readPage(ordinaryRootPage); err != nil || !pages.VerifyChecksum(frame) {
+		t.Fatalf("expect a valid root page, got %v", err)
+	}
+	if err = tbl.AddRow(basic.MakeDatums(int64(4), "d", nil, nil)); err != nil {
+		t.Fatal(err)
+	}
+	expect("4,d,NULL,NULL")
+	if _, err = OpenOrdinaryTable(cfg, nil, "test", &model.TableInfo{Name: model.NewCIStr("nope")}, 6); err == nil {
+		t.Fatalf("expect no table nope")
+	}
+
+	// The root page is the only page of the table.
+	long := strings.Repeat("x", 1000)
+	for id := int64(10); ; id++ {
+		if err = tbl.AddRow(basic.MakeDatums(id, long, nil, nil)); err != nil {
+			break
+		}
+	}
+	if err.(*mysql.SQLError).Code != mysql.ErrRecordFileFull {
+		t.Fatalf("expect error %d, got %v", mysql.ErrRecordFileFull, err)
+	}
+}
+
+func TestOrdinaryTableUnsupported(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rows")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cfg := conf.NewCfg()
+	cfg.DataDir = dir
+	info := newRowsTestTable()
+	info.Columns[0].Tp = mysql.TypeDatetime
+	if _, err = CreateOrdinaryTable(cfg, nil, "test", info, 5); err == nil || err.(*mysql.SQLError).Code != mysql.ErrNotSupportedYet {
+		t.Fatalf("expect error %d, got %v", mysql.ErrNotSupportedYet, err)
+	}
+
+	info = newRowsTestTable()
+	info.Indices = []*model.IndexInfo{{Name: model.NewCIStr("idx_name"), Columns: []*model.IndexColumn{{Name: model.NewCIStr("name"), Offset: 1}}}}
+	tbl, err := CreateOrdinaryTable(cfg, nil, "test", info, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = tbl.AddRow(basic.MakeDatums(int64(1), "a", nil, nil)); err == nil || err.(*mysql.SQLError).Code != mysql.ErrNotSupportedYet {
+		t.Fatalf("expect error %d, got %v", mysql.ErrNotSupportedYet, err)
+	}
+}
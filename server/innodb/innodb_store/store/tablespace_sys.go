@@ -2,10 +2,15 @@ package store
 
 import (
 	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
 	"github.com/zhukovaskychina/xmysql-server/server/common"
 	"github.com/zhukovaskychina/xmysql-server/server/conf"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/buffer_pool"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/innodb_store/store/storebytes/blocks"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/innodb_store/store/storebytes/pages"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/innodb_store/store/storebytes/segs"
 	"path"
 	"sync"
 
@@ -43,13 +48,13 @@ type SysTableSpace struct {
 	IBuf       *IBuf  //1 号页面
 	FirstInode *INode //2 号页面
 
-	insertBufferHeader *Allocated //3
+	insertBufferHeader *pages.FilePageTypeSysPage //3
 
 	insertBufferRootIndex *Index //4
 
-	transactionSystem *Allocated //5
+	transactionSystem *pages.SysTrxSysPage //5
 
-	rollBackSegment *Allocated //6
+	rollBackSegment *pages.RollBackPage //6
 
 	DataDict *DataDictWrapper //7号页面
 
@@ -94,6 +99,12 @@ func (sysTable *SysTableSpace) LoadExtentFromDisk(extentNumber int) Extent {
 	panic("implement me")
 }
 
+// spaceInitialPages returns the size in pages of a new tablespace, the
+// extents its FSP_HDR page describes: 256 extents with 16K pages.
+func spaceInitialPages() uint32 {
+	return uint32(common.ExtentsPerXDesPage() * common.ExtentPages())
+}
+
 //初始化数据库
 func NewSysTableSpace(cfg *conf.Cfg, IsInit bool) TableSpace {
 	tableSpace := new(SysTableSpace)
@@ -101,7 +112,7 @@ func NewSysTableSpace(cfg *conf.Cfg, IsInit bool) TableSpace {
 	tableSpace.IsInit = IsInit
 	filePath := path.Join(cfg.BaseDir, "/", "ibdata1")
 	isFlag, _ := util.PathExists(filePath)
-	blockfile := blocks.NewBlockFile(cfg.BaseDir, "ibdata1", 256*64*16384)
+	blockfile := blocks.NewBlockFile(cfg.BaseDir, "ibdata1", int64(spaceInitialPages())*int64(common.PageSize()))
 	tableSpace.blockFile = blockfile
 	if !isFlag {
 		tableSpace.blockFile.CreateFile()
@@ -110,10 +121,13 @@ func NewSysTableSpace(cfg *conf.Cfg, IsInit bool) TableSpace {
 		tableSpace.initFreeLimit()
 		tableSpace.flushToDisk()
 		tableSpace.initSysTableDataDict()
+		tableSpace.initSysSegments()
 		tableSpace.initDatabaseDictionary()
 		tableSpace.flushToDisk()
 		tableSpace.initAllSysTables()
 		//	tableSpace.flushToDisk()
+	} else {
+		tableSpace.loadHeadPage()
 	}
 	return tableSpace
 }
@@ -123,7 +137,7 @@ func NewSysTableSpaceByBufferPool(cfg *conf.Cfg, pool *buffer_pool.BufferPool) T
 	tableSpace.conf = cfg
 	filePath := path.Join(cfg.BaseDir, "/", "ibdata1")
 	isFlag, _ := util.PathExists(filePath)
-	blockfile := blocks.NewBlockFile(cfg.BaseDir, "ibdata1", 256*64*16384)
+	blockfile := blocks.NewBlockFile(cfg.BaseDir, "ibdata1", int64(spaceInitialPages())*int64(common.PageSize()))
 	tableSpace.blockFile = blockfile
 	tableSpace.pool = pool
 	tableSpace.pool.FileSystem.AddTableSpace(tableSpace)
@@ -132,29 +146,115 @@ func NewSysTableSpaceByBufferPool(cfg *conf.Cfg, pool *buffer_pool.BufferPool) T
 		tableSpace.initFreeLimit()
 		tableSpace.flushToDisk()
 		tableSpace.initSysTableDataDict()
+		tableSpace.initSysSegments()
 		tableSpace.initDatabaseDictionary()
 		tableSpace.flushToDisk()
 		tableSpace.initAllSysTables()
+	} else {
+		tableSpace.loadHeadPage()
 	}
 
 	return tableSpace
 }
 
+// sysPageTypes are the types of pages 0-7 of ibdata1.
+var sysPageTypes = []uint16{
+	common.FILE_PAGE_TYPE_FSP_HDR,
+	common.FILE_PAGE_IBUF_BITMAP,
+	common.FILE_PAGE_INODE,
+	common.FILE_PAGE_TYPE_SYS,
+	common.FILE_PAGE_INDEX,
+	common.FILE_PAGE_TYPE_TRX_SYS,
+	common.FILE_PAGE_TYPE_SYS,
+	common.FILE_PAGE_TYPE_SYS,
+}
+
+// CheckSysTableSpace checks the ibdata1 of cfg before the server starts
+// on it: its size and page size, then the checksum, the page number and
+// the type of pages 0-7, and that the first rollback segment is in page 6.
+func CheckSysTableSpace(cfg *conf.Cfg) error {
+	filePath := path.Join(cfg.BaseDir, "ibdata1")
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return err
+	}
+	if info.Size() < int64(len(sysPageTypes)*common.PageSize()) || info.Size()%int64(common.PageSize()) != 0 {
+		return errors.Errorf("%s has %d bytes, not a system tablespace", filePath, info.Size())
+	}
+	blockFile := blocks.NewBlockFile(cfg.BaseDir, "ibdata1", info.Size())
+	defer blockFile.Close()
+	head, err := blockFile.ReadFileBySeekStartWithSize(0, fspSpaceFlagsOffset+4)
+	if err != nil {
+		return err
+	}
+	flags := pages.ParseSpaceFlags(util.ReadUB4Byte2UInt32(head[fspSpaceFlagsOffset:]))
+	if size := common.PageSizeOfSSize(flags.PageSSize); size != common.PageSize() {
+		return errors.Errorf("%s was created with innodb_page_size %d, not %d", filePath, size, common.PageSize())
+	}
+	for pageNo, pageType := range sysPageTypes {
+		content, err := blockFile.ReadPageByNumber(uint32(pageNo))
+		if err != nil {
+			return err
+		}
+		if !pages.VerifyChecksum(content) {
+			return errors.Errorf("page %d of %s is corrupted, wrong checksum", pageNo, filePath)
+		}
+		if n := util.ReadUB4Byte2UInt32(content[4:8]); n != uint32(pageNo) {
+			return errors.Errorf("page %d of %s has page number %d", pageNo, filePath, n)
+		}
+		if t := util.ReadUB2Byte2Int(content[24:26]); t != pageType {
+			return errors.Errorf("page %d of %s has type %d, expect %d", pageNo, filePath, t, pageType)
+		}
+		if pageNo == 5 {
+			if space, page := pages.ParseSysTrxSysPage(content).GetRsegSlot(0); space != 0 || page != 6 {
+				return errors.Errorf("first rollback segment of %s is at page %d of space %d, expect page 6", filePath, page, space)
+			}
+		}
+	}
+	return nil
+}
+
+// CheckLowerCaseTableNames checks that the data directory of cfg was
+// initialized with the lower_case_table_names of cfg, which can't change
+// after initialization.
+func CheckLowerCaseTableNames(cfg *conf.Cfg) error {
+	filePath := path.Join(cfg.BaseDir, "ibdata1")
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return err
+	}
+	blockFile := blocks.NewBlockFile(cfg.BaseDir, "ibdata1", info.Size())
+	defer blockFile.Close()
+	content, err := blockFile.ReadPageByNumber(7)
+	if err != nil {
+		return err
+	}
+	mode, ok := pages.ParseDataDictHrdPage(content).GetLowerCaseTableNames()
+	if ok && mode != cfg.LowerCaseTableNames {
+		return errors.Errorf("%s was initialized with lower_case_table_names %d, not %d", filePath, mode, cfg.LowerCaseTableNames)
+	}
+	return nil
+}
+
 func (sysTable *SysTableSpace) initHeadPage() {
 	//初始化FspHrdPage
 	sysTable.Fsp = NewFspInitialize(0).(*Fsp)
-	sysTable.IBuf = NewIBuf(0)
+	sysTable.Fsp.SetFspSize(spaceInitialPages())
+	sysTable.IBuf = NewIBuf(1)
 	sysTable.FirstInode = NewINode(0, 2).(*INode)
 
-	sysTable.insertBufferHeader = NewAllocatedPage(3).(*Allocated)
+	sysTable.insertBufferHeader = pages.NewFilePageTypeSysPage(3)
 
 	sysTable.insertBufferRootIndex = NewPageIndex(4).(*Index)
 
-	sysTable.transactionSystem = NewAllocatedPage(5).(*Allocated)
+	//第一个回滚段在6号页面，其余127个slot没有使用
+	sysTable.transactionSystem = pages.NewSysTrxSysPage()
+	sysTable.transactionSystem.SetRsegSlot(0, 0, 6)
 
-	sysTable.rollBackSegment = NewAllocatedPage(6).(*Allocated)
+	sysTable.rollBackSegment = pages.NewRollBackPage(6)
 
 	sysTable.DataDict = NewDataDictWrapper().(*DataDictWrapper)
+	sysTable.DataDict.DataHrdPage.SetLowerCaseTableNames(sysTable.conf.LowerCaseTableNames)
 	// TODO	完成这里的数据字典的加载优化
 	sysTable.SysTables = NewPageIndexWithTuple(0, 8, NewSysTableTupleWithFlags(common.PAGE_LEAF)).(*Index)
 	sysTable.SysTablesIds = NewPageIndexWithTuple(0, 9, NewSysTableTupleWithFlags(common.PAGE_LEAF)).(*Index)
@@ -169,6 +269,47 @@ func (sysTable *SysTableSpace) initHeadPage() {
 
 }
 
+//从文件中加载3-6号页面
+func (sysTable *SysTableSpace) loadHeadPage() {
+	if content, err := sysTable.LoadPageByPageNumber(3); err == nil {
+		sysTable.insertBufferHeader = pages.ParseFilePageTypeSysPage(content)
+	}
+	if content, err := sysTable.LoadPageByPageNumber(5); err == nil {
+		sysTable.transactionSystem = pages.ParseSysTrxSysPage(content)
+	}
+	if content, err := sysTable.LoadPageByPageNumber(6); err == nil {
+		sysTable.rollBackSegment = pages.ParseRollBackPage(content)
+	}
+}
+
+// initSysSegments creates, after the segments of the dictionary, the
+// segments of the change buffer tree, of the transaction system and of the
+// first rollback segment and writes their headers in pages 3, 5 and 6.
+func (sysTable *SysTableSpace) initSysSegments() {
+	sysTable.insertBufferHeader.IBufSegHeader = sysTable.allocateSysSegment()
+	sysTable.transactionSystem.FsegHeader = sysTable.allocateSysSegment()
+	sysTable.rollBackSegment.TrxRsegFsegHeader = sysTable.allocateSysSegment()
+}
+
+// allocateSysSegment adds an inode entry for a new segment to page 2 and
+// returns the segment header pointing to it.
+func (sysTable *SysTableSpace) allocateSysSegment() []byte {
+	inode := sysTable.GetFirstINode()
+	offset := inode.getCloseZeroSeg()
+	inode.AllocateINodeEntry(util.ReadUB8Byte2Long(sysTable.GetFirstFsp().GetNextSegmentId()))
+	sysTable.FlushToDisk(2, inode.ToByte())
+	return segs.NewSegmentHeader(0, 2, uint16(offset)).GetBytes()
+}
+
+// GetMaxTrxId returns the transaction id stored in the transaction system
+// page, 0 when the page wasn't loaded.
+func (sysTable *SysTableSpace) GetMaxTrxId() uint64 {
+	if sysTable.transactionSystem == nil {
+		return 0
+	}
+	return sysTable.transactionSystem.GetMaxTrxId()
+}
+
 //初始化数据字典
 func (sysTable *SysTableSpace) initDatabaseDictionary() {
 	sysTable.DictionarySys = NewDictionarySysByWrapper(sysTable.DataDict)
@@ -193,9 +334,9 @@ func (sysTable *SysTableSpace) initFreeLimit() {
 
 //初始化FspExtent信息
 func (sysTable *SysTableSpace) initFspExtents() {
-	sysTable.Fsp.SetFspFreeExtentListInfo(&CommonNodeInfo{NodeInfoLength: 1, PreNodePageNumber: 0, PreNodeOffset: 0, NextNodePageNumber: 0, NextNodeOffset: 190})
-	sysTable.Fsp.SetFreeLimit(128)
-	sysTable.Fsp.SetFspFreeFragExtentListInfo(&CommonNodeInfo{NodeInfoLength: 1, PreNodePageNumber: 0, PreNodeOffset: 0, NextNodePageNumber: 0, NextNodeOffset: 150})
+	sysTable.Fsp.SetFspFreeExtentListInfo(&CommonNodeInfo{NodeInfoLength: 1, PreNodePageNumber: 0, PreNodeOffset: 0, NextNodePageNumber: 0, NextNodeOffset: uint16(pages.FspHrdXDesArrayOffset + common.XDesEntrySize())})
+	sysTable.Fsp.SetFreeLimit(2 * uint32(common.ExtentPages()))
+	sysTable.Fsp.SetFspFreeFragExtentListInfo(&CommonNodeInfo{NodeInfoLength: 1, PreNodePageNumber: 0, PreNodeOffset: 0, NextNodePageNumber: 0, NextNodeOffset: pages.FspHrdXDesArrayOffset})
 }
 
 //初始化字典段
@@ -267,7 +408,10 @@ func (sysTable *SysTableSpace) flushToDisk() {
 	sysTable.lockmu.Unlock()
 	sysTable.lockmu.Lock()
 	sysTable.blockFile.WriteContentByPage(2, sysTable.FirstInode.GetSerializeBytes())
-	//sysTable.blockFile.WriteContentByPage(3,nil)
+	sysTable.blockFile.WriteContentByPage(3, sysTable.insertBufferHeader.GetSerializeBytes())
+	sysTable.blockFile.WriteContentByPage(4, sysTable.insertBufferRootIndex.IndexPage.GetSerializeBytes())
+	sysTable.blockFile.WriteContentByPage(5, sysTable.transactionSystem.GetSerializeBytes())
+	sysTable.blockFile.WriteContentByPage(6, sysTable.rollBackSegment.GetSerializeBytes())
 	sysTable.lockmu.Unlock()
 	sysTable.lockmu.Lock()
 	sysTable.blockFile.WriteContentByPage(7, sysTable.DataDict.DataHrdPage.GetSerializeBytes())
@@ -299,8 +443,14 @@ func (sysTable *SysTableSpace) flushToDisk() {
 func (sysTable *SysTableSpace) initSysTableTable() {
 }
 
+// LoadPageByPageNumber reads the page pageNo from ibdata1 and checks its
+// checksum.
 func (sysTable *SysTableSpace) LoadPageByPageNumber(pageNo uint32) ([]byte, error) {
-	return sysTable.blockFile.ReadPageByNumber(pageNo)
+	content, err := sysTable.blockFile.ReadPageByNumber(pageNo)
+	if err != nil {
+		return content, err
+	}
+	return content, verifyPage(0, pageNo, path.Join(sysTable.blockFile.FilePath, sysTable.blockFile.FileName), content)
 }
 
 //获取所有的完全用满的InodePage链表
@@ -376,7 +526,7 @@ func (sysTable *SysTableSpace) GetFspFreeExtentList() *ExtentList {
 			for {
 				//递归完成
 				nextXDesEntryPage, _ := sysTable.LoadPageByPageNumber(nextNodePageNo)
-				nextXDESEntry := nextXDesEntryPage[nextOffset : nextOffset+40]
+				nextXDESEntry := nextXDesEntryPage[nextOffset : int(nextOffset)+common.XDesEntrySize()]
 				//
 				filePageTypeBytes := nextXDesEntryPage[24:26]
 				filePageType := util.ReadUB2Byte2Int(filePageTypeBytes)
@@ -387,7 +537,7 @@ func (sysTable *SysTableSpace) GetFspFreeExtentList() *ExtentList {
 						//计算区号
 						//计算逻辑，根据偏移号，计算区号
 						//获得当前区的相对位置
-						currentPageNodeOffsetNo := (nextOffset - 150) / 40
+						currentPageNodeOffsetNo := (nextOffset - pages.FspHrdXDesArrayOffset) / uint16(common.XDesEntrySize())
 
 						//	u := uint32(currentPageNodeOffsetNo) + nextNodePageNo
 						var xdesEntryWrapper *XDESEntryWrapper
@@ -400,8 +550,8 @@ func (sysTable *SysTableSpace) GetFspFreeExtentList() *ExtentList {
 						//加载普通区
 						extent := NewOrdinaryExtentAtInit(0, uint32(currentPageNodeOffsetNo), xdesEntryWrapper, sysTable, true)
 
-						startNo := uint32(currentPageNodeOffsetNo * 64)
-						endNo := uint32((currentPageNodeOffsetNo + 1) * 64)
+						startNo := uint32(currentPageNodeOffsetNo) * uint32(common.ExtentPages())
+						endNo := uint32(currentPageNodeOffsetNo+1) * uint32(common.ExtentPages())
 
 						//加载extent 到bufferPool
 						var pageArrayWrapper = make([]uint32, 0)
@@ -452,7 +602,7 @@ func (sysTable *SysTableSpace) GetFspFreeExtentList() *ExtentList {
 			//递归完成
 			bufferBlockNextFsp := sysTable.pool.GetPageBlock(0, nextNodePageNo)
 			nextXDesEntryPage := *bufferBlockNextFsp.Frame
-			nextXDESEntry := nextXDesEntryPage[nextOffset : nextOffset+40]
+			nextXDESEntry := nextXDesEntryPage[nextOffset : int(nextOffset)+common.XDesEntrySize()]
 
 			//
 			filePageTypeBytes := nextXDesEntryPage[24:26]
@@ -464,7 +614,7 @@ func (sysTable *SysTableSpace) GetFspFreeExtentList() *ExtentList {
 					//计算区号
 					//计算逻辑，根据偏移号，计算区号
 					//获得当前区的相对位置
-					currentPageNodeOffsetNo := (nextOffset - 150) / 40
+					currentPageNodeOffsetNo := (nextOffset - pages.FspHrdXDesArrayOffset) / uint16(common.XDesEntrySize())
 
 					//	u := uint32(currentPageNodeOffsetNo) + nextNodePageNo
 					var xdesEntryWrapper *XDESEntryWrapper
@@ -477,8 +627,8 @@ func (sysTable *SysTableSpace) GetFspFreeExtentList() *ExtentList {
 					//加载普通区
 					extent := NewOrdinaryExtent(0, uint32(currentPageNodeOffsetNo), xdesEntryWrapper, sysTable.pool)
 
-					startNo := uint32(currentPageNodeOffsetNo * 64)
-					endNo := uint32((currentPageNodeOffsetNo + 1) * 64)
+					startNo := uint32(currentPageNodeOffsetNo) * uint32(common.ExtentPages())
+					endNo := uint32(currentPageNodeOffsetNo+1) * uint32(common.ExtentPages())
 					sysTable.pool.RangePageLoad(0, startNo, endNo)
 					//加载extent 到bufferPool
 					var pageArrayWrapper = make([]uint32, 0)
@@ -537,7 +687,7 @@ func (sysTable *SysTableSpace) GetFspFreeFragExtentList() *ExtentList {
 			//递归完成
 			bufferBlockNextFsp := sysTable.pool.GetPageBlock(0, nextNodePageNo)
 			nextXDesEntryPage := *bufferBlockNextFsp.Frame
-			nextXDESEntry := nextXDesEntryPage[nextOffset : nextOffset+40]
+			nextXDESEntry := nextXDesEntryPage[nextOffset : int(nextOffset)+common.XDesEntrySize()]
 
 			//
 			filePageTypeBytes := nextXDesEntryPage[24:26]
@@ -549,7 +699,7 @@ func (sysTable *SysTableSpace) GetFspFreeFragExtentList() *ExtentList {
 					//计算区号
 					//计算逻辑，根据偏移号，计算区号
 					//获得当前区的相对位置
-					currentPageNodeOffsetNo := (nextOffset - 150) / 40
+					currentPageNodeOffsetNo := (nextOffset - pages.FspHrdXDesArrayOffset) / uint16(common.XDesEntrySize())
 
 					//	u := uint32(currentPageNodeOffsetNo) + nextNodePageNo
 					var xdesEntryWrapper *XDESEntryWrapper
@@ -562,8 +712,8 @@ func (sysTable *SysTableSpace) GetFspFreeFragExtentList() *ExtentList {
 					//加载普通区
 					extent := NewOrdinaryExtent(0, uint32(currentPageNodeOffsetNo), xdesEntryWrapper, sysTable.pool)
 
-					startNo := uint32(currentPageNodeOffsetNo * 64)
-					endNo := uint32((currentPageNodeOffsetNo + 1) * 64)
+					startNo := uint32(currentPageNodeOffsetNo) * uint32(common.ExtentPages())
+					endNo := uint32(currentPageNodeOffsetNo+1) * uint32(common.ExtentPages())
 					sysTable.pool.RangePageLoad(0, startNo, endNo)
 					//加载extent 到bufferPool
 					var pageArrayWrapper = make([]uint32, 0)
@@ -613,7 +763,7 @@ func (sysTable *SysTableSpace) GetFspFreeFragExtentList() *ExtentList {
 				if err != nil {
 					fmt.Println(err)
 				}
-				nextXDESEntry := nextXDesEntryPage[nextOffset : nextOffset+40]
+				nextXDESEntry := nextXDesEntryPage[nextOffset : int(nextOffset)+common.XDesEntrySize()]
 				//
 				filePageTypeBytes := nextXDesEntryPage[24:26]
 				filePageType := util.ReadUB2Byte2Int(filePageTypeBytes)
@@ -624,7 +774,7 @@ func (sysTable *SysTableSpace) GetFspFreeFragExtentList() *ExtentList {
 						//计算区号
 						//计算逻辑，根据偏移号，计算区号
 						//获得当前区的相对位置
-						currentPageNodeOffsetNo := (nextOffset - 150) / 40
+						currentPageNodeOffsetNo := (nextOffset - pages.FspHrdXDesArrayOffset) / uint16(common.XDesEntrySize())
 
 						//	u := uint32(currentPageNodeOffsetNo) + nextNodePageNo
 						var xdesEntryWrapper *XDESEntryWrapper
@@ -641,8 +791,8 @@ func (sysTable *SysTableSpace) GetFspFreeFragExtentList() *ExtentList {
 							extent = NewOrdinaryExtentAtInit(0, uint32(currentPageNodeOffsetNo), xdesEntryWrapper, sysTable, true)
 						}
 
-						startNo := uint32(currentPageNodeOffsetNo * 64)
-						endNo := uint32((currentPageNodeOffsetNo + 1) * 64)
+						startNo := uint32(currentPageNodeOffsetNo) * uint32(common.ExtentPages())
+						endNo := uint32(currentPageNodeOffsetNo+1) * uint32(common.ExtentPages())
 
 						//加载extent 到bufferPool
 						var pageArrayWrapper = make([]uint32, 0)
@@ -701,7 +851,7 @@ func (sysTable *SysTableSpace) GetFspFullFragExtentList() *ExtentList {
 		//递归完成
 		bufferBlockNextFsp := sysTable.pool.GetPageBlock(0, nextNodePageNo)
 		nextXDesEntryPage := *bufferBlockNextFsp.Frame
-		nextXDESEntry := nextXDesEntryPage[nextOffset : nextOffset+40]
+		nextXDESEntry := nextXDesEntryPage[nextOffset : int(nextOffset)+common.XDesEntrySize()]
 
 		//
 		filePageTypeBytes := nextXDesEntryPage[24:26]
@@ -713,7 +863,7 @@ func (sysTable *SysTableSpace) GetFspFullFragExtentList() *ExtentList {
 				//计算区号
 				//计算逻辑，根据偏移号，计算区号
 				//获得当前区的相对位置
-				currentPageNodeOffsetNo := (nextOffset - 150) / 40
+				currentPageNodeOffsetNo := (nextOffset - pages.FspHrdXDesArrayOffset) / uint16(common.XDesEntrySize())
 
 				//	u := uint32(currentPageNodeOffsetNo) + nextNodePageNo
 				var xdesEntryWrapper *XDESEntryWrapper
@@ -726,8 +876,8 @@ func (sysTable *SysTableSpace) GetFspFullFragExtentList() *ExtentList {
 				//加载普通区
 				extent := NewOrdinaryExtent(0, uint32(currentPageNodeOffsetNo), xdesEntryWrapper, sysTable.pool)
 
-				startNo := uint32(currentPageNodeOffsetNo * 64)
-				endNo := uint32((currentPageNodeOffsetNo + 1) * 64)
+				startNo := uint32(currentPageNodeOffsetNo) * uint32(common.ExtentPages())
+				endNo := uint32(currentPageNodeOffsetNo+1) * uint32(common.ExtentPages())
 				sysTable.pool.RangePageLoad(0, startNo, endNo)
 				//加载extent 到bufferPool
 				var pageArrayWrapper = make([]uint32, 0)
@@ -8,8 +8,12 @@ import (
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/buffer_pool"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/innodb_store/store/storebytes/blocks"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/innodb_store/store/storebytes/pages"
 	"github.com/zhukovaskychina/xmysql-server/util"
+	"io/ioutil"
+	"os"
 	"path"
+	"strings"
 	"testing"
 )
 
@@ -84,3 +88,65 @@ func TestFileIBData1(t *testing.T) {
 		fmt.Println(content)
 	}
 }
+
+func TestCheckLowerCaseTableNames(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ibdata1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cfg := conf.NewCfg()
+	cfg.DataDir, cfg.BaseDir = dir, dir
+	cfg.LowerCaseTableNames = 1
+	NewSysTableSpace(cfg, true)
+	if err = CheckLowerCaseTableNames(cfg); err != nil {
+		t.Fatal(err)
+	}
+	cfg.LowerCaseTableNames = 0
+	if err = CheckLowerCaseTableNames(cfg); err == nil || !strings.Contains(err.Error(), "lower_case_table_names 1, not 0") {
+		t.Fatalf("expect the server to refuse another lower_case_table_names, got %v", err)
+	}
+}
+
+func TestCheckSysTableSpace(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ibdata1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cfg := conf.NewCfg()
+	cfg.DataDir, cfg.BaseDir = dir, dir
+	NewSysTableSpace(cfg, true)
+	if err = CheckSysTableSpace(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	// Restarted on the file, the tablespace reads its pages 3-6 back.
+	sysTs := NewSysTableSpace(cfg, false).(*SysTableSpace)
+	assert.Equal(t, uint64(1), sysTs.GetMaxTrxId())
+	spaceId, pageNo := sysTs.transactionSystem.GetRsegSlot(0)
+	assert.Equal(t, []uint32{0, 6}, []uint32{spaceId, pageNo})
+	if _, pageNo = sysTs.transactionSystem.GetRsegSlot(1); pageNo != pages.FilNull {
+		t.Errorf("expect rollback segment slot 1 unused, got page %d", pageNo)
+	}
+	assert.Equal(t, uint32(pages.FilNull), sysTs.rollBackSegment.GetUndoSlot(0))
+	assert.Equal(t, uint32(0), sysTs.rollBackSegment.GetHistorySize())
+	for _, header := range [][]byte{sysTs.insertBufferHeader.IBufSegHeader, sysTs.transactionSystem.FsegHeader, sysTs.rollBackSegment.TrxRsegFsegHeader} {
+		if inodePage := util.ReadUB4Byte2UInt32(header[4:8]); inodePage != 2 {
+			t.Errorf("expect a segment header pointing to page 2, got %v", header)
+		}
+	}
+
+	// A byte changed behind the checksum is found.
+	file, err := os.OpenFile(path.Join(dir, "ibdata1"), os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = file.WriteAt([]byte{0xFF}, 5*common.PAGE_SIZE+40); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	if err = CheckSysTableSpace(cfg); err == nil || !strings.Contains(err.Error(), "page 5") {
+		t.Fatalf("expect page 5 to be corrupted, got %v", err)
+	}
+}
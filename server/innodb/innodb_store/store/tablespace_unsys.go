@@ -52,6 +52,9 @@ type UnSysTableSpace struct {
 	tableMeta *TableTupleMeta //表元祖信息
 
 	pool *buffer_pool.BufferPool
+
+	// rowFormat is the row format recorded in the flags of a new tablespace.
+	rowFormat RowFormat
 }
 
 /***
@@ -61,14 +64,23 @@ FSP header page是表空间的root page，存储表空间关键元数据信息
 
 **/
 func NewTableSpaceFile(cfg *conf.Cfg, databaseName string, tableName string, spaceId uint32, isSys bool, pool *buffer_pool.BufferPool) TableSpace {
+	rowFormat, _ := TableRowFormat("", cfg)
+	return NewTableSpaceFileWithRowFormat(cfg, databaseName, tableName, spaceId, isSys, pool, rowFormat)
+}
+
+// NewTableSpaceFileWithRowFormat opens the tablespace of a table, creating
+// it with the flags of rowFormat when its file doesn't exist.
+func NewTableSpaceFileWithRowFormat(cfg *conf.Cfg, databaseName string, tableName string, spaceId uint32, isSys bool, pool *buffer_pool.BufferPool, rowFormat RowFormat) TableSpace {
 	tableSpace := new(UnSysTableSpace)
+	tableSpace.rowFormat = rowFormat
+	databaseName, tableName = tableFileNames(cfg.DataDir, databaseName, tableName)
 	filePath := path.Join(cfg.DataDir, "/", databaseName)
 	isFlag, _ := util.PathExists(filePath)
 	if !isFlag {
 		util.CreateDataBaseDir(cfg.DataDir, databaseName)
 	}
 	tableName = tableName + ".ibd"
-	blockfile := blocks.NewBlockFile(filePath, tableName, 256*64*16384)
+	blockfile := blocks.NewBlockFile(filePath, tableName, int64(spaceInitialPages())*int64(common.PageSize()))
 	tableSpace.blockFile = blockfile
 	tableSpace.pool = pool
 	//pool.FileSystem.AddTableSpace(tableSpace)
@@ -114,13 +126,14 @@ func (tableSpace *UnSysTableSpace) initHeadPage() {
 		//初始化INodePage
 		//	iNodePage := pages.NewINodePage(tableSpace.spaceId)
 		tableSpace.Fsp = NewFspInitialize(tableSpace.spaceId).(*Fsp)
-		tableSpace.Fsp.SetFspSize(64 * 256)
+		tableSpace.Fsp.SetFspSize(spaceInitialPages())
+		tableSpace.Fsp.SetSpaceFlags(tableSpace.rowFormat.SpaceFlags())
 		tableSpace.Fsp.SetFspFreeExtentListInfo(&CommonNodeInfo{
-			NodeInfoLength:     256,
+			NodeInfoLength:     uint32(common.ExtentsPerXDesPage()),
 			PreNodePageNumber:  0,
 			PreNodeOffset:      0,
 			NextNodePageNumber: 0, //下一个区，空闲区
-			NextNodeOffset:     190,
+			NextNodeOffset:     uint16(pages.FspHrdXDesArrayOffset + common.XDesEntrySize()),
 		})
 		tableSpace.Fsp.SetFspFreeFragExtentListInfo(&CommonNodeInfo{
 			NodeInfoLength:     1,
@@ -160,8 +173,51 @@ func (tableSpace *UnSysTableSpace) initHeadPage() {
 	}
 }
 
+// fspSpaceFlagsOffset is the offset of FSP_SPACE_FLAGS in page 0.
+const fspSpaceFlagsOffset = 54
+
+// RowFormat returns the row format recorded in the flags of the tablespace.
+func (tableSpace *UnSysTableSpace) RowFormat() (RowFormat, error) {
+	content, err := tableSpace.blockFile.ReadPageByNumber(0)
+	if err != nil {
+		return 0, err
+	}
+	flags := util.ReadUB4Byte2UInt32(content[fspSpaceFlagsOffset : fspSpaceFlagsOffset+4])
+	return RowFormatOfSpaceFlags(pages.ParseSpaceFlags(flags)), nil
+}
+
+// SetRowFormat records rowFormat in the flags of the tablespace, keeping
+// its other flags.
+func (tableSpace *UnSysTableSpace) SetRowFormat(rowFormat RowFormat) error {
+	content, err := tableSpace.blockFile.ReadPageByNumber(0)
+	if err != nil {
+		return err
+	}
+	flags := pages.ParseSpaceFlags(util.ReadUB4Byte2UInt32(content[fspSpaceFlagsOffset : fspSpaceFlagsOffset+4]))
+	formatFlags := rowFormat.SpaceFlags()
+	flags.IsPostAntelope, flags.AtomicBlobs = formatFlags.IsPostAntelope, formatFlags.AtomicBlobs
+	copy(content[fspSpaceFlagsOffset:], util.ConvertUInt4Bytes(flags.ToUint32()))
+	if err = tableSpace.blockFile.WriteContentByPage(0, content); err != nil {
+		return err
+	}
+	if tableSpace.Fsp != nil {
+		tableSpace.Fsp.SetSpaceFlags(flags)
+	}
+	if tableSpace.pool != nil {
+		tableSpace.pool.DiscardPage(tableSpace.spaceId, 0)
+	}
+	tableSpace.rowFormat = rowFormat
+	return nil
+}
+
+// LoadPageByPageNumber reads the page pageNumber from the file and checks
+// its checksum.
 func (tableSpace *UnSysTableSpace) LoadPageByPageNumber(pageNumber uint32) ([]byte, error) {
-	return tableSpace.blockFile.ReadPageByNumber(pageNumber)
+	content, err := tableSpace.blockFile.ReadPageByNumber(pageNumber)
+	if err != nil {
+		return content, err
+	}
+	return content, verifyPage(tableSpace.spaceId, pageNumber, path.Join(tableSpace.blockFile.FilePath, tableSpace.blockFile.FileName), content)
 }
 
 //获取INodeList
@@ -239,7 +295,7 @@ func (tableSpace *UnSysTableSpace) GetFspFreeExtentList() *ExtentList {
 			//递归完成
 			bufferBlockNextFsp := tableSpace.pool.GetPageBlock(tableSpace.spaceId, nextNodePageNo)
 			nextXDesEntryPage := *bufferBlockNextFsp.Frame
-			nextXDESEntry := nextXDesEntryPage[nextOffset : nextOffset+40]
+			nextXDESEntry := nextXDesEntryPage[nextOffset : int(nextOffset)+common.XDesEntrySize()]
 
 			//
 			filePageTypeBytes := nextXDesEntryPage[24:26]
@@ -251,9 +307,9 @@ func (tableSpace *UnSysTableSpace) GetFspFreeExtentList() *ExtentList {
 					//计算区号
 					//计算逻辑，根据偏移号，计算区号
 					//获得当前区的相对位置
-					currentPageNodeOffsetNo := (nextOffset - 150) / 40
-					startNo := uint32(currentPageNodeOffsetNo * 64)
-					endNo := uint32((currentPageNodeOffsetNo + 1) * 64)
+					currentPageNodeOffsetNo := (nextOffset - pages.FspHrdXDesArrayOffset) / uint16(common.XDesEntrySize())
+					startNo := uint32(currentPageNodeOffsetNo) * uint32(common.ExtentPages())
+					endNo := uint32(currentPageNodeOffsetNo+1) * uint32(common.ExtentPages())
 					tableSpace.pool.RangePageLoad(tableSpace.spaceId, startNo, endNo)
 					//加载extent 到bufferPool
 					var pageArrayWrapper = make([]uint32, 0)
@@ -338,7 +394,7 @@ func (tableSpace *UnSysTableSpace) GetFspFreeFragExtentList() *ExtentList {
 			//递归完成
 			bufferBlockNextFsp := tableSpace.pool.GetPageBlock(tableSpace.spaceId, nextNodePageNo)
 			nextXDesEntryPage := *bufferBlockNextFsp.Frame
-			nextXDESEntry := nextXDesEntryPage[nextOffset : nextOffset+40]
+			nextXDESEntry := nextXDesEntryPage[nextOffset : int(nextOffset)+common.XDesEntrySize()]
 
 			//
 			filePageTypeBytes := nextXDesEntryPage[24:26]
@@ -350,9 +406,9 @@ func (tableSpace *UnSysTableSpace) GetFspFreeFragExtentList() *ExtentList {
 					//计算区号
 					//计算逻辑，根据偏移号，计算区号
 					//获得当前区的相对位置
-					currentPageNodeOffsetNo := (nextOffset - 150) / 40
-					startNo := uint32(currentPageNodeOffsetNo * 64)
-					endNo := uint32((currentPageNodeOffsetNo + 1) * 64)
+					currentPageNodeOffsetNo := (nextOffset - pages.FspHrdXDesArrayOffset) / uint16(common.XDesEntrySize())
+					startNo := uint32(currentPageNodeOffsetNo) * uint32(common.ExtentPages())
+					endNo := uint32(currentPageNodeOffsetNo+1) * uint32(common.ExtentPages())
 					tableSpace.pool.RangePageLoad(tableSpace.spaceId, startNo, endNo)
 					//加载extent 到bufferPool
 					var pageArrayWrapper = make([]uint32, 0)
@@ -437,7 +493,7 @@ func (tableSpace *UnSysTableSpace) GetFspFullFragExtentList() *ExtentList {
 			//递归完成
 			bufferBlockNextFsp := tableSpace.pool.GetPageBlock(tableSpace.spaceId, nextNodePageNo)
 			nextXDesEntryPage := *bufferBlockNextFsp.Frame
-			nextXDESEntry := nextXDesEntryPage[nextOffset : nextOffset+40]
+			nextXDESEntry := nextXDesEntryPage[nextOffset : int(nextOffset)+common.XDesEntrySize()]
 
 			//
 			filePageTypeBytes := nextXDesEntryPage[24:26]
@@ -449,9 +505,9 @@ func (tableSpace *UnSysTableSpace) GetFspFullFragExtentList() *ExtentList {
 					//计算区号
 					//计算逻辑，根据偏移号，计算区号
 					//获得当前区的相对位置
-					currentPageNodeOffsetNo := (nextOffset - 150) / 40
-					startNo := uint32(currentPageNodeOffsetNo * 64)
-					endNo := uint32((currentPageNodeOffsetNo + 1) * 64)
+					currentPageNodeOffsetNo := (nextOffset - pages.FspHrdXDesArrayOffset) / uint16(common.XDesEntrySize())
+					startNo := uint32(currentPageNodeOffsetNo) * uint32(common.ExtentPages())
+					endNo := uint32(currentPageNodeOffsetNo+1) * uint32(common.ExtentPages())
 					tableSpace.pool.RangePageLoad(tableSpace.spaceId, startNo, endNo)
 					//加载extent 到bufferPool
 					var pageArrayWrapper = make([]uint32, 0)
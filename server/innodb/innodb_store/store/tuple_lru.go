@@ -73,13 +73,17 @@ func (t *TupleLRUCacheImpl) get(key uint64) (schemas.Table, error) {
 func (t TupleLRUCacheImpl) Remove(databaseName string, tableName string) bool {
 	t.mu.Lock()
 	defer t.mu.Unlock()
-	var buff = []byte(databaseName + "/" + tableName)
+	var buff = []byte(databaseName + tableName)
 	hashCode := util.HashCode(buff)
 	return t.remove(hashCode)
 }
 
 func (t TupleLRUCacheImpl) Has(databaseName string, tableName string) bool {
-	panic("implement me")
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	var buff = []byte(databaseName + tableName)
+	_, ok := t.items[util.HashCode(buff)]
+	return ok
 }
 func (t *TupleLRUCacheImpl) remove(key uint64) bool {
 	if ent, ok := t.items[key]; ok {
@@ -19,6 +19,9 @@ type TableTupleMeta struct {
 	Columns        []*tuple.FormColumnsWrapper
 	Cfg            *conf.Cfg
 	blockFile      *blocks.BlockFile
+	RowFormat      RowFormat
+	// Overflow keeps the values stored off-page.
+	Overflow OverflowPages
 
 	PrimaryIndexInfos   *tuple.IndexInfoWrapper
 	SecondaryIndexInfos []*tuple.IndexInfoWrapper
@@ -35,6 +38,7 @@ func NewTupleMeta(DatabaseName string, tableName string, Cfg *conf.Cfg) *TableTu
 	tableTupleMeta.IndexesMap = indexMap
 	tableTupleMeta.ColumnsMap = columnsMap
 	tableTupleMeta.Columns = Columns
+	tableTupleMeta.RowFormat, _ = TableRowFormat("", Cfg)
 	return tableTupleMeta
 }
 
@@ -91,6 +95,7 @@ func (m *TableTupleMeta) GetDatabaseName() string {
 //}
 
 func (m *TableTupleMeta) ReadFrmBytes(form *table.Form) {
+	m.RowFormat = RowFormat(form.RowType)
 	//读取form
 	for _, v := range form.FieldBytes {
 		currentFormCols := tuple.NewFormColumnWrapper()
@@ -107,7 +112,7 @@ func (m *TableTupleMeta) ReadFrmBytes(form *table.Form) {
 	}
 }
 
-func (m TableTupleMeta) ReadFrmFromDisk() {
+func (m *TableTupleMeta) ReadFrmFromDisk() {
 
 	frmContent, _ := m.blockFile.ReadFileBySeekStartWithSize(0, m.blockFile.FileSize)
 	frm := table.NewFormWithBytes(frmContent)
@@ -116,6 +121,7 @@ func (m TableTupleMeta) ReadFrmFromDisk() {
 
 func (m *TableTupleMeta) FlushToDisk() {
 	form := table.NewForm(m.DatabaseName, m.TableName)
+	form.RowType = byte(m.RowFormat)
 	form.ColumnsLength = util.ConvertUInt4Bytes(uint32(len(m.Columns)))
 	for _, v := range m.Columns {
 		currentFields := table.FieldBytes{
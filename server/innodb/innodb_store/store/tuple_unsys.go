@@ -12,6 +12,8 @@ type ClusterLeafTuple struct {
 	dataBaseName      string
 	Columns           []*tuple.FormColumnsWrapper
 	PrimaryIndexInfos *tuple.IndexInfoWrapper
+	RowFormat         RowFormat
+	Overflow          OverflowPages
 }
 
 func NewClusterLeafTuple(meta *TableTupleMeta) tuple.TableRowTuple {
@@ -19,6 +21,8 @@ func NewClusterLeafTuple(meta *TableTupleMeta) tuple.TableRowTuple {
 	clusterLeafTuple.TableName = meta.TableName
 	clusterLeafTuple.Columns = meta.Columns
 	clusterLeafTuple.PrimaryIndexInfos = meta.PrimaryKeyMeta
+	clusterLeafTuple.RowFormat = meta.RowFormat
+	clusterLeafTuple.Overflow = meta.Overflow
 	return clusterLeafTuple
 }
 
@@ -53,7 +57,7 @@ func (c ClusterLeafTuple) GetColumnInfos(index byte) *tuple.FormColumnsWrapper {
 func (c ClusterLeafTuple) GetVarColumns() []*tuple.FormColumnsWrapper {
 	var formColumnsWrapperCols = make([]*tuple.FormColumnsWrapper, 0)
 	for i := 0; i < len(c.Columns); i++ {
-		if c.Columns[i].FieldType == "VARCHAR" {
+		if isVarColumn(c.Columns[i].FieldType) {
 			formColumnsWrapperCols = append(formColumnsWrapperCols, c.Columns[i])
 		}
 	}
@@ -19,12 +19,16 @@ func (t Txn) IsReadOnly() bool {
 	return false
 }
 
+// Commit implements the XMySQLTransaction interface. The transaction
+// doesn't keep any change yet, there is nothing to write.
 func (t Txn) Commit() error {
-	panic("implement me")
+	return nil
 }
 
+// Rollback implements the XMySQLTransaction interface. The transaction
+// doesn't keep any change yet, there is nothing to undo.
 func (t Txn) Rollback() error {
-	panic("implement me")
+	return nil
 }
 
 func (t Txn) String() string {
@@ -0,0 +1,75 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+/**
+lower_case_table_names
+
+决定数据库名、表名和视图名（包括表的别名）怎么存储、怎么比较，和 MySQL 一样有三种取值：
+	0：按声明的大小写存储，比较时区分大小写
+	1：存储为小写，比较时不区分大小写（Windows 和 macOS 上 MySQL 的默认值）
+	2：按声明的大小写存储，比较时转换为小写
+
+它在初始化数据目录时确定，之后不能再修改，启动时由 lower_case_table_names 设置。
+列名、索引名和 information_schema 的表名总是不区分大小写，不受它影响。
+**/
+
+// The values of lower_case_table_names.
+const (
+	LowerCaseTableNamesSensitive = 0
+	LowerCaseTableNamesLower     = 1
+	LowerCaseTableNamesCompare   = 2
+)
+
+var lowerCaseTableNames = LowerCaseTableNamesSensitive
+
+// SetLowerCaseTableNames sets how the names of databases and tables are
+// stored and compared. It is set once at startup.
+func SetLowerCaseTableNames(mode int) error {
+	if mode < LowerCaseTableNamesSensitive || mode > LowerCaseTableNamesCompare {
+		return fmt.Errorf("lower_case_table_names %d is not 0, 1 or 2", mode)
+	}
+	lowerCaseTableNames = mode
+	return nil
+}
+
+// LowerCaseTableNames returns the value of lower_case_table_names.
+func LowerCaseTableNames() int {
+	return lowerCaseTableNames
+}
+
+// StoredTableName returns name, of a database or a table, as DDL stores
+// it: in lower case with lower_case_table_names=1, as declared otherwise.
+func StoredTableName(name CIStr) CIStr {
+	if lowerCaseTableNames == LowerCaseTableNamesLower {
+		return CIStr{O: name.L, L: name.L}
+	}
+	return name
+}
+
+// StoredTableNameString is StoredTableName of a plain name, the name of its
+// directory or file.
+func StoredTableNameString(name string) string {
+	if lowerCaseTableNames == LowerCaseTableNamesLower {
+		return strings.ToLower(name)
+	}
+	return name
+}
+
+// TableNameKey returns what name, of a database or a table, compares by:
+// the name as given with lower_case_table_names=0, in lower case otherwise.
+func TableNameKey(name CIStr) string {
+	if lowerCaseTableNames == LowerCaseTableNamesSensitive && name.O != "" {
+		return name.O
+	}
+	return name.L
+}
+
+// TableNamesEqual reports whether the names of databases or tables a and b
+// name the same one.
+func TableNamesEqual(a, b CIStr) bool {
+	return TableNameKey(a) == TableNameKey(b)
+}
@@ -89,8 +89,9 @@ type TableInfo struct {
 	Charset string `json:"charset"`
 	Collate string `json:"collate"`
 	// Columns are listed in the order in which they appear in the schema.
-	Columns []*ColumnInfo `json:"cols"`
-	Indices []*IndexInfo  `json:"index_info"`
+	Columns     []*ColumnInfo `json:"cols"`
+	Indices     []*IndexInfo  `json:"index_info"`
+	ForeignKeys []*FKInfo     `json:"fk_info"`
 
 	State       SchemaState `json:"state"`
 	PKIsHandle  bool        `json:"pk_is_handle"`
@@ -108,6 +109,17 @@ type TableInfo struct {
 
 	// ShardRowIDBits specify if the implicit row ID is sharded.
 	ShardRowIDBits uint64
+
+	// Engine is the storage engine the table is stored in, empty for
+	// InnoDB.
+	Engine string `json:"engine,omitempty"`
+
+	// RowFormat is the ROW_FORMAT the table was created with, empty for
+	// innodb_default_row_format.
+	RowFormat string `json:"row_format,omitempty"`
+
+	// View is not nil if the table is a view.
+	View *ViewInfo `json:"view"`
 }
 
 func (t *TableInfo) GetDBID(dbID int64) int64 {
@@ -122,6 +134,7 @@ func (t *TableInfo) Clone() *TableInfo {
 	nt := *t
 	nt.Columns = make([]*ColumnInfo, len(t.Columns))
 	nt.Indices = make([]*IndexInfo, len(t.Indices))
+	nt.ForeignKeys = make([]*FKInfo, len(t.ForeignKeys))
 
 	for i := range t.Columns {
 		nt.Columns[i] = t.Columns[i].Clone()
@@ -131,9 +144,22 @@ func (t *TableInfo) Clone() *TableInfo {
 		nt.Indices[i] = t.Indices[i].Clone()
 	}
 
+	for i := range t.ForeignKeys {
+		nt.ForeignKeys[i] = t.ForeignKeys[i].Clone()
+	}
+
+	if t.View != nil {
+		nt.View = t.View.Clone()
+	}
+
 	return &nt
 }
 
+// IsView checks if the table is a view.
+func (t *TableInfo) IsView() bool {
+	return t.View != nil
+}
+
 // GetPkName will return the pk name if pk exists.
 func (t *TableInfo) GetPkName() CIStr {
 	if t.PKIsHandle {
@@ -224,6 +250,49 @@ const (
 	IndexTypeHash
 )
 
+// FKInfo provides meta data describing a foreign key constraint.
+type FKInfo struct {
+	ID       int64       `json:"id"`
+	Name     CIStr       `json:"fk_name"`
+	RefTable CIStr       `json:"ref_table"`
+	RefCols  []CIStr     `json:"ref_cols"`
+	Cols     []CIStr     `json:"cols"`
+	OnDelete int         `json:"on_delete"`
+	OnUpdate int         `json:"on_update"`
+	State    SchemaState `json:"state"`
+	// RefSchema is the database of RefTable, empty for the database of the
+	// table itself.
+	RefSchema CIStr `json:"ref_schema"`
+}
+
+// Clone clones FKInfo.
+func (fk *FKInfo) Clone() *FKInfo {
+	nfk := *fk
+
+	nfk.RefCols = make([]CIStr, len(fk.RefCols))
+	nfk.Cols = make([]CIStr, len(fk.Cols))
+	copy(nfk.RefCols, fk.RefCols)
+	copy(nfk.Cols, fk.Cols)
+
+	return &nfk
+}
+
+// ViewInfo provides meta data describing a view. The SELECT statement is
+// kept as text and planned again whenever the view is used.
+// See https://dev.mysql.com/doc/refman/5.7/en/create-view.html
+type ViewInfo struct {
+	SelectStmt string  `json:"view_select"`
+	Cols       []CIStr `json:"view_cols"`
+}
+
+// Clone clones ViewInfo.
+func (v *ViewInfo) Clone() *ViewInfo {
+	nv := *v
+	nv.Cols = make([]CIStr, len(v.Cols))
+	copy(nv.Cols, v.Cols)
+	return &nv
+}
+
 // IndexInfo provides meta data describing a DB index.
 // It corresponds to the statement `CREATE INDEX Name ON Table (Column);`
 // See https://dev.mysql.com/doc/refman/5.7/en/create-index.html
@@ -19,7 +19,6 @@ package net
 
 import (
 	"compress/flate"
-	"crypto/tls"
 	"fmt"
 	"io"
 	"net"
@@ -101,7 +100,7 @@ func (c *mysqlConn) send(interface{}) (int, error) {
 
 func (c *mysqlConn) close(int) {}
 
-func (c mysqlConn) readTimeout() time.Duration {
+func (c *mysqlConn) readTimeout() time.Duration {
 	return c.rTimeout
 }
 
@@ -124,7 +123,7 @@ func (c *mysqlConn) SetReadTimeout(rTimeout time.Duration) {
 	}
 }
 
-func (c mysqlConn) writeTimeout() time.Duration {
+func (c *mysqlConn) writeTimeout() time.Duration {
 	return c.wTimeout
 }
 
@@ -323,11 +322,8 @@ func (t *MysqlTCPConn) close(waitSec int) {
 		}
 		if conn, ok := t.conn.(*net.TCPConn); ok {
 			_ = conn.SetLinger(waitSec)
-			_ = conn.Close()
-		} else {
-			_ = t.conn.(*tls.Conn).Close()
-
 		}
+		_ = t.conn.Close()
 		t.conn = nil
 	}
 }
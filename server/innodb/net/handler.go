@@ -20,11 +20,18 @@ package net
 import (
 	"errors"
 	"fmt"
+	"github.com/zhukovaskychina/xmysql-server/server/common"
 	"github.com/zhukovaskychina/xmysql-server/server/conf"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/audit"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/engine"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/privilege"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/privilege/privileges"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/auth"
 	"github.com/zhukovaskychina/xmysql-server/server/mysql"
 	"github.com/zhukovaskychina/xmysql-server/server/protocol"
+	"net"
 	"sync"
 	//"github.com/zhukovaskychina/xmysql-serverimpl/serverimpl/net/service"
 )
@@ -71,9 +78,26 @@ func NewMySQLMessageHandler(cfg *conf.Cfg) *MySQLMessageHandler {
 	mySQLMessageHandler.sessionMap = make(map[Session]innodb.MySQLServerSession)
 	mySQLMessageHandler.cfg = cfg
 	mySQLMessageHandler.XMySQLEngine = engine.NewXMySQLEngine(cfg)
+	variable.RegisterStatistics(mySQLMessageHandler)
 	return mySQLMessageHandler
 }
 
+// StatusThreadsConnected is the status variable of the number of open
+// connections.
+const StatusThreadsConnected = "Threads_connected"
+
+// GetScope implements variable.Statistics GetScope interface.
+func (m *MySQLMessageHandler) GetScope(status string) variable.ScopeFlag {
+	return variable.ScopeGlobal
+}
+
+// Stats implements variable.Statistics Stats interface.
+func (m *MySQLMessageHandler) Stats(vars *variable.SessionVars) (map[string]interface{}, error) {
+	m.rwlock.RLock()
+	defer m.rwlock.RUnlock()
+	return map[string]interface{}{StatusThreadsConnected: int64(len(m.sessionMap))}, nil
+}
+
 func (m *MySQLMessageHandler) OnOpen(session Session) error {
 	var (
 		err error
@@ -99,13 +123,150 @@ func (m *MySQLMessageHandler) OnOpen(session Session) error {
 }
 
 func (m *MySQLMessageHandler) OnClose(session Session) {
-	session.Close()
-	delete(m.sessionMap, session)
+	m.closeSession(session)
 }
 
 func (m *MySQLMessageHandler) OnError(session Session, err error) {
-	session.Close()
+	m.closeSession(session)
+}
+
+// closeSession rolls back the open transaction of session, forgets the
+// session and closes its connection.
+func (m *MySQLMessageHandler) closeSession(session Session) {
+	m.rwlock.Lock()
+	mysqlSession, ok := m.sessionMap[session]
 	delete(m.sessionMap, session)
+	m.rwlock.Unlock()
+	if ok {
+		if m.XMySQLEngine != nil {
+			m.XMySQLEngine.CloseSession(mysqlSession)
+		}
+		if err := mysqlSession.RollbackTxn(); err != nil {
+			log.Warnf("rollback the transaction of session %s error %v", session.Stat(), err)
+		}
+		if session.GetAttribute("auth_status") != nil {
+			variable.RemoveProcess(mysqlSession.GetSessionVars())
+			m.auditConnection(mysqlSession, audit.EventDisconnect, "success")
+		}
+	}
+	session.Close()
+}
+
+// auditLog returns the audit log of the server, nil without an engine.
+func (m *MySQLMessageHandler) auditLog() *audit.Logger {
+	if m.XMySQLEngine == nil {
+		return nil
+	}
+	return m.XMySQLEngine.AuditLog()
+}
+
+// auditConnection logs the event class of the connection of mysqlSession
+// to the audit log.
+func (m *MySQLMessageHandler) auditConnection(mysqlSession innodb.MySQLServerSession, class, status string) {
+	l := m.auditLog()
+	if !l.Enabled() {
+		return
+	}
+	vars := mysqlSession.GetSessionVars()
+	e := &audit.Event{
+		Class:        class,
+		ConnectionID: vars.ConnectionID,
+		DB:           vars.CurrentDB,
+		Status:       status,
+	}
+	if vars.User != nil {
+		e.User, e.Host = vars.User.Username, vars.User.Hostname
+	}
+	l.Log(e)
+}
+
+// authenticate verifies the auth response of a, the client at host,
+// against the salt of the handshake of mysqlSession, and checks the
+// privileges of the account from then on. Every login fails when the engine
+// couldn't load the privilege tables.
+func (m *MySQLMessageHandler) authenticate(mysqlSession innodb.MySQLServerSession, a *protocol.AuthPacket, host string) bool {
+	h := m.XMySQLEngine.PrivilegeHandle()
+	if h == nil {
+		return false
+	}
+	salted, ok := mysqlSession.(interface{ Salt() []byte })
+	if !ok {
+		return false
+	}
+	p := &privileges.UserPrivileges{Handle: h}
+	if !p.ConnectionVerification(a.User, host, a.Password, salted.Salt()) {
+		return false
+	}
+	privilege.BindPrivilegeManager(mysqlSession, p)
+	return true
+}
+
+// handleAuthentication logs in the client of session with body, its
+// handshake response. A client offering another auth plugin than
+// mysql_native_password, the one of every account, is asked to switch to
+// it with an AuthSwitchRequest, and body is then its answer to it.
+func (m *MySQLMessageHandler) handleAuthentication(session Session, mysqlSession innodb.MySQLServerSession, body []byte) {
+	a, switched := session.GetAttribute("auth_switch").(*protocol.AuthPacket)
+	if switched {
+		// The answer is the bare auth response, empty for an account
+		// without password.
+		session.RemoveAttribute("auth_switch")
+		a.Password = append([]byte(nil), body...)
+		a.AuthPlugin = mysql.AuthName
+	} else {
+		a = new(protocol.AuthPacket)
+		if err := a.DecodeAuth(body); err != nil {
+			m.auditConnection(mysqlSession, audit.EventAuthFailure, "failure")
+			mysqlSession.SendError(mysql.NewErr(mysql.ErrHandshake))
+			m.closeSession(session)
+			return
+		}
+		vars := mysqlSession.GetSessionVars()
+		vars.User = &auth.UserIdentity{Username: a.User, Hostname: remoteHost(session)}
+		vars.ClientCapability = a.ClientFlag()
+		if a.ClientFlag()&common.CLIENT_PLUGIN_AUTH != 0 && a.AuthPlugin != "" && a.AuthPlugin != mysql.AuthName {
+			switcher, ok := mysqlSession.(interface{ SendAuthSwitchRequest(plugin string) error })
+			if ok {
+				if err := switcher.SendAuthSwitchRequest(mysql.AuthName); err != nil {
+					log.Warnf("send the auth switch request of session %s error %v", session.Stat(), err)
+					m.closeSession(session)
+					return
+				}
+				session.SetAttribute("auth_switch", a)
+				return
+			}
+		}
+	}
+	host := mysqlSession.GetSessionVars().User.Hostname
+	if !m.authenticate(mysqlSession, a, host) {
+		m.auditConnection(mysqlSession, audit.EventAuthFailure, "failure")
+		usingPassword := "NO"
+		if len(a.Password) > 0 {
+			usingPassword = "YES"
+		}
+		mysqlSession.SendError(mysql.NewErr(mysql.ErrAccessDenied, a.User, host, usingPassword))
+		m.closeSession(session)
+		return
+	}
+	session.SetAttribute("auth_status", "success")
+	mysqlSession.SetCurrentDatabase(a.Database)
+	variable.AddProcess(mysqlSession.GetSessionVars())
+	m.auditConnection(mysqlSession, audit.EventConnect, "success")
+	mysqlSession.SendOK()
+}
+
+// remoteHost returns the host of the client of session, localhost for a
+// client on the loopback interface like MySQL names it.
+func remoteHost(session Session) string {
+	addr := session.RemoteAddr()
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return "localhost"
+	}
+	return host
 }
 
 func (m *MySQLMessageHandler) OnCron(session Session) {
@@ -115,24 +276,20 @@ func (m *MySQLMessageHandler) OnCron(session Session) {
 func (m *MySQLMessageHandler) OnMessage(session Session, pkg interface{}) {
 	currentMysqlSession := m.sessionMap[session]
 	recMySQLPkg := pkg.(*MySQLPackage)
+	// A command starts with sequence id 0, the response follows it.
+	currentMysqlSession.SetPacketSequence(recMySQLPkg.Header.PacketId + 1)
 
 	authStatus := session.GetAttribute("auth_status")
 	if authStatus == nil {
-		a := new(protocol.AuthPacket)
-		var authData = make([]byte, 0)
-		authData = append(authData, recMySQLPkg.Header.PacketLength...)
-		authData = append(authData, recMySQLPkg.Header.PacketId)
-		authData = append(authData, recMySQLPkg.Body...)
-		a.DecodeAuth(authData)
-		session.SetAttribute("auth_status", "success")
-		currentMysqlSession.SetCurrentDatabase(a.Database)
-		m.sessionMap[session] = currentMysqlSession
-		buff := make([]byte, 0)
-
-		session.WriteBytes(protocol.EncodeOK(buff, 0, 0, nil))
+		m.handleAuthentication(session, currentMysqlSession, recMySQLPkg.Body)
+		return
+	}
+	packetType, arg, err := protocol.DecodeCommand(recMySQLPkg.Body)
+	if err != nil {
+		currentMysqlSession.SendError(mysql.NewErr(mysql.ErrMalformedPacket))
+		m.closeSession(session)
 		return
 	}
-	packetType := recMySQLPkg.Body[0]
 	switch packetType {
 	case mysql.ComSleep:
 		{
@@ -142,16 +299,19 @@ func (m *MySQLMessageHandler) OnMessage(session Session, pkg interface{}) {
 	case mysql.ComQuery:
 		{
 
-			sql := string(recMySQLPkg.Body[1:])
+			sql := string(arg)
 
 			m.XMySQLEngine.ExecuteQuery(currentMysqlSession, sql)
 		}
+	case mysql.ComInitDB:
+		m.XMySQLEngine.InitDB(currentMysqlSession, string(arg))
 	case mysql.ComQuit:
 		{
-			fmt.Println("")
-
+			// The client doesn't wait for any response.
+			m.closeSession(session)
 		}
-
+	default:
+		currentMysqlSession.SendError(mysql.NewErr(mysql.ErrUnknownCom))
 	}
 
 }
@@ -0,0 +1,730 @@
+package net
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zhukovaskychina/xmysql-server/initdb"
+	"github.com/zhukovaskychina/xmysql-server/server/common"
+	"github.com/zhukovaskychina/xmysql-server/server/conf"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/engine"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/innodb_store/store"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/privilege"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/privilege/privileges"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/varsutil"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/auth"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+	"github.com/zhukovaskychina/xmysql-server/server/protocol"
+	"github.com/zhukovaskychina/xmysql-server/util"
+)
+
+// handlerTestSession is an authenticated connection recording what is
+// written to it.
+type handlerTestSession struct {
+	Session
+	written [][]byte
+	closed  bool
+}
+
+func (s *handlerTestSession) GetAttribute(key interface{}) interface{} {
+	if key == "auth_status" {
+		return "success"
+	}
+	return nil
+}
+
+func (s *handlerTestSession) WriteBytes(pkg []byte) error {
+	s.written = append(s.written, pkg)
+	return nil
+}
+
+func (s *handlerTestSession) Stat() string {
+	return "handler test session"
+}
+
+func (s *handlerTestSession) Close() {
+	s.closed = true
+}
+
+func TestComQuit(t *testing.T) {
+	conn := &handlerTestSession{}
+	mysqlSession := &MySQLServerSessionImpl{session: conn, sessionVars: variable.NewSessionVars()}
+	if err := mysqlSession.NewTxn(); err != nil {
+		t.Fatal(err)
+	}
+	if !mysqlSession.sessionVars.InTxn() {
+		t.Fatal("expect an open transaction")
+	}
+	h := &MySQLMessageHandler{sessionMap: map[Session]innodb.MySQLServerSession{conn: mysqlSession}}
+
+	h.OnMessage(conn, &MySQLPackage{Body: []byte{mysql.ComQuit}})
+	if _, ok := h.sessionMap[conn]; ok {
+		t.Fatal("expect the session to be removed")
+	}
+	if mysqlSession.sessionVars.InTxn() || mysqlSession.txn != nil {
+		t.Fatal("expect the transaction to be rolled back")
+	}
+	if !conn.closed {
+		t.Fatal("expect the connection to be closed")
+	}
+	if len(conn.written) != 0 {
+		t.Fatalf("expect no response, got %v", conn.written)
+	}
+
+	// The connection closing afterwards finds nothing left to do.
+	h.OnClose(conn)
+	if len(h.sessionMap) != 0 || len(conn.written) != 0 {
+		t.Fatal("unexpected session left")
+	}
+}
+
+func TestSendResultSet(t *testing.T) {
+	conn := &handlerTestSession{}
+	mysqlSession := &MySQLServerSessionImpl{session: conn, sessionVars: variable.NewSessionVars(), sequence: 1}
+	fields := []protocol.Field{{Name: "1+1", Types: int(mysql.TypeLonglong)}, {Name: "c", Types: int(mysql.TypeVarString)}}
+	rows := [][]basic.Datum{basic.MakeDatums(int64(2), nil), basic.MakeDatums(int64(3), "")}
+	if err := mysqlSession.SendResultSet(fields, innodb.SliceRows(rows)); err != nil {
+		t.Fatal(err)
+	}
+	// The column definitions go out before the rows are produced.
+	if len(conn.written) != 2 {
+		t.Fatalf("expect the columns and the rows written apart, got %d writes", len(conn.written))
+	}
+	buff := bytes.Join(conn.written, nil)
+	var payloads [][]byte
+	for seq := byte(1); len(buff) > 0; seq++ {
+		payload, id, n, err := protocol.ReadPacket(buff, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id != seq {
+			t.Fatalf("expect packet %d, got %d", seq, id)
+		}
+		payloads = append(payloads, payload)
+		buff = buff[n:]
+	}
+	// column count, 2 columns, EOF, 2 rows, EOF
+	if len(payloads) != 7 || !bytes.Equal(payloads[0], []byte{2}) || payloads[3][0] != 0xfe || payloads[6][0] != 0xfe {
+		t.Fatalf("unexpected packets %v", payloads)
+	}
+	if !bytes.Equal(payloads[4], []byte{1, '2', 0xfb}) || !bytes.Equal(payloads[5], []byte{1, '3', 0}) {
+		t.Fatalf("expect NULL and the empty string told apart, got %v %v", payloads[4], payloads[5])
+	}
+}
+
+func TestResultSetTerminator(t *testing.T) {
+	// packets returns the payloads of a result set of one row, sent with a
+	// warning to a client of capabilities.
+	packets := func(capabilities uint32) [][]byte {
+		conn := &handlerTestSession{}
+		vars := variable.NewSessionVars()
+		vars.ClientCapability = capabilities
+		vars.StmtCtx = new(variable.StatementContext)
+		vars.StmtCtx.AppendWarning(mysql.NewErr(mysql.ErrTruncatedWrongValue, "DOUBLE", "x"))
+		mysqlSession := &MySQLServerSessionImpl{session: conn, sessionVars: vars, sequence: 1}
+		fields := []protocol.Field{{Name: "1", Types: int(mysql.TypeLonglong)}}
+		if err := mysqlSession.SendResultSet(fields, innodb.SliceRows([][]basic.Datum{basic.MakeDatums(int64(1))})); err != nil {
+			t.Fatal(err)
+		}
+		buff := bytes.Join(conn.written, nil)
+		var payloads [][]byte
+		for seq := byte(1); len(buff) > 0; seq++ {
+			payload, id, n, err := protocol.ReadPacket(buff, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if id != seq {
+				t.Fatalf("expect packet %d, got %d", seq, id)
+			}
+			payloads = append(payloads, payload)
+			buff = buff[n:]
+		}
+		return payloads
+	}
+	status := byte(mysql.ServerStatusAutocommit)
+
+	// column count, column, EOF, row, EOF with the warning count first.
+	payloads := packets(common.CLIENT_PROTOCOL_41)
+	if len(payloads) != 5 {
+		t.Fatalf("expect 5 packets, got %v", payloads)
+	}
+	if !bytes.Equal(payloads[2], []byte{0xfe, 0, 0, status, 0}) {
+		t.Fatalf("unexpected EOF after the columns %v", payloads[2])
+	}
+	if !bytes.Equal(payloads[4], []byte{0xfe, 1, 0, status, 0}) {
+		t.Fatalf("unexpected EOF after the rows %v", payloads[4])
+	}
+
+	// column count, column, row, OK with the EOF header and the status first.
+	payloads = packets(common.CLIENT_PROTOCOL_41 | common.CLIENT_DEPRECATE_EOF)
+	if len(payloads) != 4 {
+		t.Fatalf("expect no EOF after the columns, got %v", payloads)
+	}
+	if !bytes.Equal(payloads[2], []byte{1, '1'}) {
+		t.Fatalf("expect the row after the columns, got %v", payloads[2])
+	}
+	if !bytes.Equal(payloads[3], []byte{0xfe, 0, 0, status, 0, 1, 0}) {
+		t.Fatalf("unexpected OK after the rows %v", payloads[3])
+	}
+	ok := protocol.DecodeOk(payloads[3])
+	if ok.ServerStatus != mysql.ServerStatusAutocommit || ok.WarningNum != 1 {
+		t.Fatalf("unexpected OK %+v", ok)
+	}
+}
+
+func TestServerStatus(t *testing.T) {
+	conn := &handlerTestSession{}
+	mysqlSession := &MySQLServerSessionImpl{session: conn, sessionVars: variable.NewSessionVars(), sequence: 1}
+	// okStatus returns the status of the OK packet sent, eofStatus the one
+	// of the last EOF packet of a result set.
+	okStatus := func() uint16 {
+		conn.written = nil
+		mysqlSession.SendOK()
+		payload, _, _, err := protocol.ReadPacket(conn.written[0], 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return protocol.DecodeOk(payload).ServerStatus
+	}
+	eofStatus := func() uint16 {
+		conn.written = nil
+		if err := mysqlSession.SendResultSet([]protocol.Field{{Name: "1", Types: int(mysql.TypeLonglong)}}, innodb.SliceRows(nil)); err != nil {
+			t.Fatal(err)
+		}
+		buff := conn.written[len(conn.written)-1]
+		eof := buff[len(buff)-2:]
+		return uint16(eof[0]) | uint16(eof[1])<<8
+	}
+	expect := func(step string, want uint16) {
+		t.Helper()
+		if ok, eof := okStatus(), eofStatus(); ok != want || eof != want {
+			t.Fatalf("%s: expect status %#x, got %#x in OK and %#x in EOF", step, want, ok, eof)
+		}
+	}
+
+	expect("new session", mysql.ServerStatusAutocommit)
+	if err := mysqlSession.NewTxn(); err != nil {
+		t.Fatal(err)
+	}
+	expect("BEGIN", mysql.ServerStatusAutocommit|mysql.ServerStatusInTrans)
+	mysqlSession.Commit()
+	expect("COMMIT", mysql.ServerStatusAutocommit)
+	if err := varsutil.SetSessionSystemVar(mysqlSession.sessionVars, variable.AutocommitVar, basic.NewStringDatum("0")); err != nil {
+		t.Fatal(err)
+	}
+	expect("SET autocommit = 0", 0)
+	if err := varsutil.SetSessionSystemVar(mysqlSession.sessionVars, variable.SQLModeVar, basic.NewStringDatum("NO_BACKSLASH_ESCAPES")); err != nil {
+		t.Fatal(err)
+	}
+	expect("SET sql_mode", mysql.ServerStatusNoBackslashEscaped)
+}
+
+func TestSessionStateTracking(t *testing.T) {
+	conn := &handlerTestSession{}
+	vars := variable.NewSessionVars()
+	vars.ClientCapability = common.CLIENT_PROTOCOL_41 | common.CLIENT_SESSION_TRACK
+	mysqlSession := &MySQLServerSessionImpl{session: conn, sessionVars: vars, sequence: 1}
+	set := func(name, value string) {
+		t.Helper()
+		if err := varsutil.SetSessionSystemVar(vars, name, basic.NewStringDatum(value)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// expect checks the OK packet of a statement making the changes of do
+	// lists the changes of want.
+	expect := func(step string, do func(), want ...[]string) {
+		t.Helper()
+		vars.StmtCtx = new(variable.StatementContext)
+		do()
+		conn.written = nil
+		mysqlSession.SendOK()
+		payload, _, _, err := protocol.ReadPacket(conn.written[0], 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var state []byte
+		for _, change := range want {
+			state = protocol.AppendSessionStateChange(state, change[0][0], change[1:]...)
+		}
+		status := protocol.DecodeOk(payload).ServerStatus
+		if len(state) == 0 {
+			if status&mysql.ServerSessionStateChanged != 0 || len(payload) != 7 {
+				t.Fatalf("%s: expect no session state, got %v", step, payload)
+			}
+			return
+		}
+		// The empty message follows the 7 bytes up to the warnings.
+		if status&mysql.ServerSessionStateChanged == 0 || !bytes.Equal(payload[9:], state) || int(payload[8]) != len(state) {
+			t.Fatalf("%s: expect the session state %v, got %v", step, state, payload)
+		}
+	}
+	tracker := func(tracker byte) string { return string([]byte{tracker}) }
+
+	expect("SET time_zone", func() { set(variable.TimeZone, "+08:00") },
+		[]string{tracker(mysql.SessionTrackSystemVariables), "time_zone", "+08:00"})
+	expect("SET autocommit twice", func() { set(variable.AutocommitVar, "0"); set(variable.AutocommitVar, "1") },
+		[]string{tracker(mysql.SessionTrackSystemVariables), "autocommit", "1"})
+	expect("SET sql_mode", func() { set(variable.SQLModeVar, "ANSI_QUOTES") })
+	expect("USE", func() { vars.TrackSchema("test") },
+		[]string{tracker(mysql.SessionTrackSchema), "test"})
+
+	set(variable.SessionTrackSystemVariables, "sql_mode,transaction_isolation")
+	set(variable.SessionTrackStateChange, "ON")
+	set(variable.SessionTrackTransactionInfo, "CHARACTERISTICS")
+	expect("SET SESSION TRANSACTION", func() { set(variable.TxnIsolation, "READ-COMMITTED"); set(variable.TxReadOnly, "1") },
+		[]string{tracker(mysql.SessionTrackSystemVariables), "transaction_isolation", "READ-COMMITTED"},
+		[]string{tracker(mysql.SessionTrackTransactionCharacteristics), "SET TRANSACTION ISOLATION LEVEL READ COMMITTED; SET TRANSACTION READ ONLY;"},
+		[]string{tracker(mysql.SessionTrackStateChange), "1"})
+	expect("SET time_zone untracked", func() { set(variable.TimeZone, "+00:00") },
+		[]string{tracker(mysql.SessionTrackStateChange), "1"})
+
+	// A client not tracking the session state gets none.
+	vars.ClientCapability = common.CLIENT_PROTOCOL_41
+	expect("SET sql_mode without CLIENT_SESSION_TRACK", func() { set(variable.SQLModeVar, "") })
+}
+
+// countingRows yields rows of a number and a padding, counting them.
+type countingRows struct {
+	n, total int
+	// yielded is called after each row.
+	yielded func(n int)
+}
+
+func (r *countingRows) Next() ([]basic.Datum, error) {
+	if r.n == r.total {
+		return nil, nil
+	}
+	r.n++
+	if r.yielded != nil {
+		r.yielded(r.n)
+	}
+	return basic.MakeDatums(int64(r.n), strings.Repeat("x", 90)), nil
+}
+
+func TestSendResultSetStreaming(t *testing.T) {
+	fields := []protocol.Field{{Name: "n", Types: int(mysql.TypeLonglong)}, {Name: "pad", Types: int(mysql.TypeVarString)}}
+	newSession := func() (*handlerTestSession, *MySQLServerSessionImpl) {
+		conn := &handlerTestSession{}
+		vars := variable.NewSessionVars()
+		vars.StmtCtx.NowTs = time.Now()
+		if err := varsutil.SetSessionSystemVar(vars, variable.NetBufferLength, basic.NewStringDatum("1024")); err != nil {
+			t.Fatal(err)
+		}
+		return conn, &MySQLServerSessionImpl{session: conn, sessionVars: vars, sequence: 1}
+	}
+
+	conn, mysqlSession := newSession()
+	rows := &countingRows{total: 1000}
+	var producedAtWrite []int
+	rows.yielded = func(int) {
+		if len(conn.written) > len(producedAtWrite) {
+			producedAtWrite = append(producedAtWrite, rows.n)
+		}
+	}
+	if err := mysqlSession.SendResultSet(fields, rows); err != nil {
+		t.Fatal(err)
+	}
+	// About ten rows fill a batch, each sent before the next rows are made.
+	if len(conn.written) < 90 || producedAtWrite[1] > 20 {
+		t.Fatalf("expect the rows sent in small batches, got %d writes, rows made by the second %v", len(conn.written), producedAtWrite[:2])
+	}
+	seq := byte(1)
+	count := 0
+	for i, buff := range conn.written {
+		if i > 0 && i < len(conn.written)-1 && len(buff) > 1024+100 {
+			t.Fatalf("expect batches of net_buffer_length, got %d bytes", len(buff))
+		}
+		for len(buff) > 0 {
+			_, id, n, err := protocol.ReadPacket(buff, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if id != seq {
+				t.Fatalf("expect packet %d, got %d", seq, id)
+			}
+			seq++
+			count++
+			buff = buff[n:]
+		}
+	}
+	// column count, 2 columns, EOF, the rows, EOF
+	if count != 1000+5 {
+		t.Fatalf("expect %d packets, got %d", 1000+5, count)
+	}
+
+	// A killed statement stops at the end of the batch.
+	conn, mysqlSession = newSession()
+	rows = &countingRows{total: 1000, yielded: func(n int) {
+		if n == 100 {
+			atomic.StoreUint32(&mysqlSession.sessionVars.Killed, 1)
+		}
+	}}
+	err := mysqlSession.SendResultSet(fields, rows)
+	if e, ok := err.(*mysql.SQLError); !ok || e.Code != mysql.ErrQueryInterrupted {
+		t.Fatalf("expect error %d, got %v", mysql.ErrQueryInterrupted, err)
+	}
+	if rows.n > 120 {
+		t.Fatalf("expect the rows stopped soon after the kill, got %d", rows.n)
+	}
+
+	// So does one running longer than max_execution_time.
+	conn, mysqlSession = newSession()
+	if err = varsutil.SetSessionSystemVar(mysqlSession.sessionVars, variable.MaxExecutionTime, basic.NewStringDatum("10")); err != nil {
+		t.Fatal(err)
+	}
+	mysqlSession.sessionVars.StmtCtx.NowTs = time.Now().Add(-time.Second)
+	rows = &countingRows{total: 1000}
+	err = mysqlSession.SendResultSet(fields, rows)
+	if e, ok := err.(*mysql.SQLError); !ok || e.Code != mysql.ErrQueryTimeout {
+		t.Fatalf("expect error %d, got %v", mysql.ErrQueryTimeout, err)
+	}
+	if rows.n > 20 {
+		t.Fatalf("expect the rows stopped at the first batch, got %d", rows.n)
+	}
+}
+
+func TestSendResultSetCharset(t *testing.T) {
+	fields := []protocol.Field{{Name: "c", Types: int(mysql.TypeVarString)}}
+	rows := [][]basic.Datum{basic.MakeDatums("café €")}
+	for _, c := range []struct {
+		charset string
+		want    []byte
+	}{
+		{"", []byte("café €")},
+		{"utf8mb4", []byte("café €")},
+		{"latin1", []byte{'c', 'a', 'f', 0xe9, ' ', 0x80}},
+		{"ascii", []byte("caf? ?")},
+	} {
+		conn := &handlerTestSession{}
+		vars := variable.NewSessionVars()
+		vars.ResultsCharset = c.charset
+		mysqlSession := &MySQLServerSessionImpl{session: conn, sessionVars: vars, sequence: 1}
+		if err := mysqlSession.SendResultSet(fields, innodb.SliceRows(rows)); err != nil {
+			t.Fatal(err)
+		}
+		buff := bytes.Join(conn.written, nil)
+		var payloads [][]byte
+		for len(buff) > 0 {
+			payload, _, n, err := protocol.ReadPacket(buff, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			payloads = append(payloads, payload)
+			buff = buff[n:]
+		}
+		// column count, column, EOF, row, EOF
+		if row := payloads[3]; !bytes.Equal(row[1:], c.want) {
+			t.Errorf("%s: expect %v, got %v", c.charset, c.want, row[1:])
+		}
+	}
+
+	// The values of binary columns go out as they are.
+	conn := &handlerTestSession{}
+	vars := variable.NewSessionVars()
+	vars.ResultsCharset = "latin1"
+	mysqlSession := &MySQLServerSessionImpl{session: conn, sessionVars: vars, sequence: 1}
+	fields = []protocol.Field{{Name: "b", Types: int(mysql.TypeVarString), Charset: mysql.BinaryCollationID}}
+	raw := []byte{0x00, 0xc3, 0xa9, 0xff}
+	rows = [][]basic.Datum{{basic.NewStringDatum(string(raw))}}
+	if err := mysqlSession.SendResultSet(fields, innodb.SliceRows(rows)); err != nil {
+		t.Fatal(err)
+	}
+	buff := bytes.Join(conn.written, nil)
+	for i := 0; i < 4; i++ {
+		payload, _, n, err := protocol.ReadPacket(buff, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 3 && !bytes.Equal(payload[1:], raw) {
+			t.Errorf("expect %v sent raw, got %v", raw, payload[1:])
+		}
+		buff = buff[n:]
+	}
+}
+
+func TestUnknownCommand(t *testing.T) {
+	conn := &handlerTestSession{}
+	mysqlSession := &MySQLServerSessionImpl{session: conn, sessionVars: variable.NewSessionVars()}
+	h := &MySQLMessageHandler{sessionMap: map[Session]innodb.MySQLServerSession{conn: mysqlSession}}
+
+	h.OnMessage(conn, &MySQLPackage{Body: []byte{0xfa}})
+	if len(conn.written) != 1 {
+		t.Fatalf("expect an error packet, got %v", conn.written)
+	}
+	payload, id, _, err := protocol.ReadPacket(conn.written[0], 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 1 || payload[0] != 0xff {
+		t.Fatalf("expect an error packet with sequence 1, got %d %v", id, payload)
+	}
+	if code := uint16(payload[1]) | uint16(payload[2])<<8; code != mysql.ErrUnknownCom || string(payload[3:9]) != "#08S01" {
+		t.Fatalf("expect ER_UNKNOWN_COM_ERROR 08S01, got %d %s", code, payload[3:9])
+	}
+}
+
+func TestComInitDB(t *testing.T) {
+	conn := &handlerTestSession{}
+	mysqlSession := &MySQLServerSessionImpl{session: conn, sessionVars: variable.NewSessionVars()}
+	h := &MySQLMessageHandler{
+		sessionMap:   map[Session]innodb.MySQLServerSession{conn: mysqlSession},
+		XMySQLEngine: &engine.XMySQLEngine{},
+	}
+
+	// The command reaches the engine, which needs a database name.
+	h.OnMessage(conn, &MySQLPackage{Body: []byte{mysql.ComInitDB}})
+	if len(conn.written) != 1 {
+		t.Fatalf("expect an error packet, got %v", conn.written)
+	}
+	payload, _, _, err := protocol.ReadPacket(conn.written[0], 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := uint16(payload[1]) | uint16(payload[2])<<8; payload[0] != 0xff || code != mysql.ErrNoDB {
+		t.Fatalf("expect error %d, got %v", mysql.ErrNoDB, payload)
+	}
+}
+
+func TestMalformedPacket(t *testing.T) {
+	conn := &handlerTestSession{}
+	mysqlSession := &MySQLServerSessionImpl{session: conn, sessionVars: variable.NewSessionVars()}
+	h := &MySQLMessageHandler{sessionMap: map[Session]innodb.MySQLServerSession{conn: mysqlSession}}
+	h.OnMessage(conn, &MySQLPackage{})
+	if !conn.closed || len(h.sessionMap) != 0 || len(conn.written) != 1 {
+		t.Fatalf("expect an error packet and the connection to be closed, got %v", conn.written)
+	}
+	payload, _, _, err := protocol.ReadPacket(conn.written[0], 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := uint16(payload[1]) | uint16(payload[2])<<8; payload[0] != 0xff || code != mysql.ErrMalformedPacket {
+		t.Fatalf("expect error %d, got %v", mysql.ErrMalformedPacket, payload)
+	}
+
+	// A handshake response cut in the middle of the user name.
+	login := &loginTestSession{attributes: map[interface{}]interface{}{}}
+	mysqlSession = &MySQLServerSessionImpl{session: login, sessionVars: variable.NewSessionVars()}
+	h = &MySQLMessageHandler{sessionMap: map[Session]innodb.MySQLServerSession{login: mysqlSession}}
+	hs := protocol.DecodeHandshake(protocol.EncodeHandshake(nil, []byte("01234567890123456789"))[4:])
+	body := protocol.EncodeLogin(hs, "app", "secret", "test")[:34]
+	h.OnMessage(login, &MySQLPackage{Header: MySQLPkgHeader{PacketLength: []byte{34, 0, 0}, PacketId: 1}, Body: body})
+	if login.attributes["auth_status"] != nil || !login.closed || len(h.sessionMap) != 0 {
+		t.Fatal("expect the connection to be closed")
+	}
+	payload, _, _, err = protocol.ReadPacket(login.written[0], 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := uint16(payload[1]) | uint16(payload[2])<<8; payload[0] != 0xff || code != mysql.ErrHandshake {
+		t.Fatalf("expect error %d, got %v", mysql.ErrHandshake, payload)
+	}
+}
+
+// loginTestSession is a connection before its login.
+type loginTestSession struct {
+	handlerTestSession
+	attributes map[interface{}]interface{}
+	// addr is the address of the client, 10.0.0.1:40000 when empty.
+	addr string
+}
+
+func (s *loginTestSession) GetAttribute(key interface{}) interface{} {
+	return s.attributes[key]
+}
+
+func (s *loginTestSession) SetAttribute(key, value interface{}) {
+	s.attributes[key] = value
+}
+
+func (s *loginTestSession) RemoveAttribute(key interface{}) {
+	delete(s.attributes, key)
+}
+
+func (s *loginTestSession) RemoteAddr() string {
+	if s.addr != "" {
+		return s.addr
+	}
+	return "10.0.0.1:40000"
+}
+
+// newTestEngine starts an engine on a data directory initialized by
+// --initialize-insecure, where mysql.user has the accounts app@'%'
+// identified by secret and guest@'%' without password too.
+func newTestEngine(t *testing.T) *engine.XMySQLEngine {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "net")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	cfg := conf.NewCfg()
+	cfg.DataDir, cfg.BaseDir = dir, dir
+	if _, err = initdb.Initialize(cfg, true); err != nil {
+		t.Fatal(err)
+	}
+	info, err := privileges.Tables[0].TableInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tbl, err := store.OpenOrdinaryTable(cfg, nil, mysql.SystemDB, info, privileges.Tables[0].SpaceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := &privileges.MySQLPrivilege{User: []privileges.UserRecord{
+		{Host: "%", User: "app", Password: auth.EncodePassword("secret")},
+		{Host: "%", User: "guest"},
+	}}
+	for _, row := range users.Rows(info) {
+		if err = tbl.AddRow(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	srv := engine.NewXMySQLEngine(cfg)
+	t.Cleanup(func() { srv.Close() })
+	return srv
+}
+
+func TestLogin(t *testing.T) {
+	srv := newTestEngine(t)
+	login := func(srv *engine.XMySQLEngine, password string) (*loginTestSession, *MySQLServerSessionImpl, *MySQLMessageHandler) {
+		conn := &loginTestSession{attributes: map[interface{}]interface{}{}}
+		salt, err := scrambles.NewSalt()
+		if err != nil {
+			t.Fatal(err)
+		}
+		mysqlSession := &MySQLServerSessionImpl{session: conn, sessionVars: variable.NewSessionVars(), salt: salt}
+		h := &MySQLMessageHandler{sessionMap: map[Session]innodb.MySQLServerSession{conn: mysqlSession}, XMySQLEngine: srv}
+
+		// The client answers the salt of the handshake.
+		hs := protocol.DecodeHandshake(protocol.EncodeHandshake(nil, salt)[4:])
+		body := protocol.EncodeLogin(hs, "app", password, "test")
+		length := []byte{byte(len(body)), byte(len(body) >> 8), byte(len(body) >> 16)}
+		h.OnMessage(conn, &MySQLPackage{Header: MySQLPkgHeader{PacketLength: length, PacketId: 1}, Body: body})
+		return conn, mysqlSession, h
+	}
+
+	conn, mysqlSession, h := login(srv, "secret")
+	if conn.attributes["auth_status"] != "success" || conn.closed {
+		t.Fatal("expect the login to succeed")
+	}
+	if payload, _, _, err := protocol.ReadPacket(conn.written[0], 0); err != nil || payload[0] != 0 {
+		t.Fatalf("expect an OK packet, got %v %v", payload, err)
+	}
+	if privilege.GetPrivilegeManager(mysqlSession) == nil || mysqlSession.GetCurrentDataBase() != "test" {
+		t.Fatal("expect the session to check the privileges of app in test")
+	}
+
+	expectDenied := func(conn *loginTestSession, h *MySQLMessageHandler) {
+		t.Helper()
+		if conn.attributes["auth_status"] != nil || !conn.closed || len(h.sessionMap) != 0 {
+			t.Fatal("expect the connection to be closed")
+		}
+		payload, _, _, err := protocol.ReadPacket(conn.written[0], 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code := uint16(payload[1]) | uint16(payload[2])<<8; payload[0] != 0xff || code != mysql.ErrAccessDenied {
+			t.Fatalf("expect error %d, got %v", mysql.ErrAccessDenied, payload)
+		}
+	}
+	conn, _, h = login(srv, "wrong")
+	expectDenied(conn, h)
+	// Without the privilege tables nobody logs in.
+	conn, _, h = login(&engine.XMySQLEngine{}, "secret")
+	expectDenied(conn, h)
+
+	// root@localhost logs in on the loopback interface.
+	for _, addr := range []string{"127.0.0.1:40000", "[::1]:40000"} {
+		if host := remoteHost(&loginTestSession{addr: addr}); host != "localhost" {
+			t.Fatalf("expect %s from localhost, got %s", addr, host)
+		}
+	}
+}
+
+func TestAuthSwitch(t *testing.T) {
+	srv := newTestEngine(t)
+	// login sends the handshake response of a client offering
+	// caching_sha2_password for user, then answers the AuthSwitchRequest
+	// with answer, the mysql_native_password response for password unless
+	// it is given.
+	login := func(user, password string, answer []byte) *loginTestSession {
+		t.Helper()
+		conn := &loginTestSession{attributes: map[interface{}]interface{}{}}
+		salt, err := scrambles.NewSalt()
+		if err != nil {
+			t.Fatal(err)
+		}
+		mysqlSession := &MySQLServerSessionImpl{session: conn, sessionVars: variable.NewSessionVars(), salt: salt}
+		h := &MySQLMessageHandler{sessionMap: map[Session]innodb.MySQLServerSession{conn: mysqlSession}, XMySQLEngine: srv}
+
+		body := util.WriteUB4(nil, common.CLIENT_PROTOCOL_41|common.CLIENT_SECURE_CONNECTION|
+			common.CLIENT_CONNECT_WITH_DB|common.CLIENT_PLUGIN_AUTH)
+		body = append(body, make([]byte, 4+1+23)...)
+		body = append(body, user+"\x00"...)
+		// A caching_sha2_password response, 32 bytes.
+		body = append(body, 32)
+		body = append(body, bytes.Repeat([]byte{'x'}, 32)...)
+		body = append(body, "test\x00caching_sha2_password\x00"...)
+		h.OnMessage(conn, &MySQLPackage{Header: MySQLPkgHeader{PacketId: 1}, Body: body})
+		if conn.attributes["auth_status"] != nil || conn.closed || len(conn.written) != 1 {
+			t.Fatalf("expect an auth switch request, got %v", conn.written)
+		}
+		payload, seq, _, err := protocol.ReadPacket(conn.written[0], 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := "\xfe" + mysql.AuthName + "\x00"
+		if seq != 2 || !bytes.HasPrefix(payload, []byte(want)) || len(payload) != len(want)+auth.ScrambleLength+1 {
+			t.Fatalf("expect an auth switch request to %s, got %q seq %d", mysql.AuthName, payload, seq)
+		}
+		switchSalt := payload[len(want) : len(want)+auth.ScrambleLength]
+		if bytes.Equal(switchSalt, salt) {
+			t.Fatal("expect a new salt")
+		}
+		if answer == nil {
+			answer = auth.ScrambleNativePassword(password, switchSalt)
+		}
+		conn.written = nil
+		h.OnMessage(conn, &MySQLPackage{Header: MySQLPkgHeader{PacketId: 3}, Body: answer})
+		return conn
+	}
+	expectOK := func(conn *loginTestSession) {
+		t.Helper()
+		payload, seq, _, err := protocol.ReadPacket(conn.written[0], 0)
+		if err != nil || payload[0] != 0 || seq != 4 {
+			t.Fatalf("expect an OK packet, got %v seq %d %v", payload, seq, err)
+		}
+		if conn.attributes["auth_status"] != "success" || conn.closed {
+			t.Fatal("expect the login to succeed")
+		}
+	}
+	expectDenied := func(conn *loginTestSession) {
+		t.Helper()
+		payload, _, _, err := protocol.ReadPacket(conn.written[0], 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code := uint16(payload[1]) | uint16(payload[2])<<8; payload[0] != 0xff || code != mysql.ErrAccessDenied {
+			t.Fatalf("expect error %d, got %v", mysql.ErrAccessDenied, payload)
+		}
+		if conn.attributes["auth_status"] != nil || !conn.closed {
+			t.Fatal("expect the connection to be closed")
+		}
+	}
+
+	expectOK(login("app", "secret", nil))
+	// An account without password answers with an empty response.
+	expectOK(login("guest", "", []byte{}))
+	expectDenied(login("app", "wrong", nil))
+	expectDenied(login("guest", "secret", nil))
+	// A client refusing the switch answers with its own response.
+	expectDenied(login("app", "", bytes.Repeat([]byte{'x'}, 32)))
+}
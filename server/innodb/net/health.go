@@ -0,0 +1,123 @@
+package net
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/zhukovaskychina/xmysql-server/server/conf"
+)
+
+const (
+	healthzPath = "/healthz"
+	readyzPath  = "/readyz"
+)
+
+// The subsystems the readiness probe waits for.
+const (
+	// subsystemStorage is ready once the storage is bootstrapped and the
+	// system tablespaces are open.
+	subsystemStorage = "storage"
+	// subsystemListener is ready while the MySQL listener accepts
+	// connections.
+	subsystemListener = "listener"
+	// subsystemDispatcher is ready while the statements of the sessions are
+	// dispatched to the engine.
+	subsystemDispatcher = "dispatcher"
+)
+
+var subsystems = []string{subsystemStorage, subsystemListener, subsystemDispatcher}
+
+// health is the state of the server the health probes report. The liveness
+// probe passes while the listener accepts connections, the readiness probe
+// once every subsystem is ready and until the server starts shutting down.
+type health struct {
+	mu           sync.RWMutex
+	ready        map[string]bool
+	shuttingDown bool
+}
+
+func newHealth() *health {
+	return &health{ready: make(map[string]bool)}
+}
+
+// setReady records whether subsystem is ready.
+func (h *health) setReady(subsystem string, ready bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.ready[subsystem] = ready
+}
+
+// shutdown makes the server not ready for good, before its connections are
+// closed, so that load balancers stop sending it new ones.
+func (h *health) shutdown() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.shuttingDown = true
+}
+
+// readiness is the body of the answers of the readiness probe.
+type readiness struct {
+	Ready        bool            `json:"ready"`
+	ShuttingDown bool            `json:"shutting_down,omitempty"`
+	Subsystems   map[string]bool `json:"subsystems"`
+}
+
+func (h *health) readiness() readiness {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	r := readiness{Ready: !h.shuttingDown, ShuttingDown: h.shuttingDown, Subsystems: make(map[string]bool, len(subsystems))}
+	for _, name := range subsystems {
+		r.Subsystems[name] = h.ready[name]
+		r.Ready = r.Ready && h.ready[name]
+	}
+	return r
+}
+
+// healthzHandler is the liveness probe: 200 while the listener accepts
+// connections, 503 otherwise.
+func (h *health) healthzHandler(w http.ResponseWriter, r *http.Request) {
+	h.mu.RLock()
+	live := h.ready[subsystemListener]
+	h.mu.RUnlock()
+	if !live {
+		http.Error(w, "not listening", http.StatusServiceUnavailable)
+		return
+	}
+	w.Write([]byte("ok\n"))
+}
+
+// readyzHandler is the readiness probe: 200 when the server is ready to
+// serve queries, 503 otherwise, with a JSON body telling which subsystems
+// are ready.
+func (h *health) readyzHandler(w http.ResponseWriter, r *http.Request) {
+	body := h.readiness()
+	w.Header().Set("Content-Type", "application/json")
+	if !body.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(body)
+}
+
+// serveHealth serves the health probes of h on the health port in the
+// background. It returns the listener, nil when the port is 0.
+func serveHealth(conf *conf.Cfg, h *health) (net.Listener, error) {
+	if conf.HealthPort == 0 {
+		return nil, nil
+	}
+	listener, err := net.Listen("tcp", net.JoinHostPort(conf.BindAddress, strconv.Itoa(conf.HealthPort)))
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(healthzPath, h.healthzHandler)
+	mux.HandleFunc(readyzPath, h.readyzHandler)
+	log.Infof("health probes served on %s%s and %s", listener.Addr(), healthzPath, readyzPath)
+	go func() {
+		log.Info(http.Serve(listener, mux))
+	}()
+	return listener, nil
+}
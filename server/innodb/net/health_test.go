@@ -0,0 +1,98 @@
+package net
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/conf"
+)
+
+func TestHealthProbes(t *testing.T) {
+	h := newHealth()
+	probe := func(handler http.HandlerFunc) (int, *readiness) {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest("GET", "/", nil))
+		if w.Header().Get("Content-Type") != "application/json" {
+			return w.Code, nil
+		}
+		var body readiness
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		return w.Code, &body
+	}
+	expect := func(live, ready int, subsystems map[string]bool, shuttingDown bool) {
+		t.Helper()
+		if code, _ := probe(h.healthzHandler); code != live {
+			t.Fatalf("expect liveness %d, got %d", live, code)
+		}
+		code, body := probe(h.readyzHandler)
+		if code != ready {
+			t.Fatalf("expect readiness %d, got %d", ready, code)
+		}
+		want := &readiness{Ready: ready == http.StatusOK, ShuttingDown: shuttingDown, Subsystems: subsystems}
+		if !reflect.DeepEqual(body, want) {
+			t.Fatalf("expect %+v, got %+v", want, body)
+		}
+	}
+
+	// Starting up: the storage first, then the listener, then the
+	// dispatcher.
+	expect(503, 503, map[string]bool{"storage": false, "listener": false, "dispatcher": false}, false)
+	h.setReady(subsystemStorage, true)
+	expect(503, 503, map[string]bool{"storage": true, "listener": false, "dispatcher": false}, false)
+	h.setReady(subsystemListener, true)
+	expect(200, 503, map[string]bool{"storage": true, "listener": true, "dispatcher": false}, false)
+	h.setReady(subsystemDispatcher, true)
+	expect(200, 200, map[string]bool{"storage": true, "listener": true, "dispatcher": true}, false)
+
+	// Shutting down, not ready before the connections are closed.
+	h.shutdown()
+	expect(200, 503, map[string]bool{"storage": true, "listener": true, "dispatcher": true}, true)
+	h.setReady(subsystemListener, false)
+	expect(503, 503, map[string]bool{"storage": true, "listener": false, "dispatcher": true}, true)
+}
+
+func TestHealthEndpoint(t *testing.T) {
+	cfg := conf.NewCfg()
+	h := newHealth()
+	if listener, err := serveHealth(cfg, h); listener != nil || err != nil {
+		t.Fatalf("expect no endpoint on port 0, got %v, %v", listener, err)
+	}
+
+	free, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.HealthPort = free.Addr().(*net.TCPAddr).Port
+	free.Close()
+	listener, err := serveHealth(cfg, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	for _, ready := range []bool{false, true} {
+		for _, name := range subsystems {
+			h.setReady(name, ready)
+		}
+		want := http.StatusServiceUnavailable
+		if ready {
+			want = http.StatusOK
+		}
+		for _, path := range []string{healthzPath, readyzPath} {
+			resp, err := http.Get("http://" + listener.Addr().String() + path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != want {
+				t.Fatalf("%s: expect %d, got %d", path, want, resp.StatusCode)
+			}
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package net
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+
+	gxsync "github.com/dubbogo/gost/sync"
+	"github.com/zhukovaskychina/xmysql-server/server/conf"
+)
+
+/**
+进程内的服务端
+
+InProcessServer 不监听端口，每次 Dial 用 net.Pipe 建一对内存里的连接：服务端一头和
+TCP 连接一样配置成 session，由 MySQLMessageHandler 处理，握手、认证、命令都走同一套
+代码；客户端一头交给调用方，直接读写报文字节。
+
+测试不用再连固定的端口，不会端口冲突，也可以并行跑。net.Pipe 没有缓冲，
+服务端写的报文要等客户端读走才返回，所以 session 在自己的 goroutine 里打开。
+**/
+
+// InProcessServer serves the MySQL protocol on in-memory connections,
+// without a listener.
+type InProcessServer struct {
+	cfg        *conf.Cfg
+	msgHandler *MySQLMessageHandler
+	endPointID EndPointID
+
+	mu       sync.Mutex
+	sessions map[*session]struct{}
+	closed   bool
+}
+
+// NewInProcessServer returns a server of the engine cfg configures, served
+// in process.
+func NewInProcessServer(cfg *conf.Cfg) *InProcessServer {
+	return newInProcessServer(cfg, NewMySQLMessageHandler(cfg))
+}
+
+func newInProcessServer(cfg *conf.Cfg, handler *MySQLMessageHandler) *InProcessServer {
+	return &InProcessServer{
+		cfg:        cfg,
+		msgHandler: handler,
+		endPointID: atomic.AddInt32(&serverID, 1),
+		sessions:   make(map[*session]struct{}),
+	}
+}
+
+// Dial opens a connection to the server and returns the client end of it.
+// The server sends the handshake as soon as the client reads.
+func (s *InProcessServer) Dial() (net.Conn, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, ErrSessionClosed
+	}
+	for ss := range s.sessions {
+		if ss.IsClosed() {
+			delete(s.sessions, ss)
+		}
+	}
+	client, server := net.Pipe()
+	ss := newTCPSession(server, s).(*session)
+	configureSession(s.cfg, ss, s.msgHandler)
+	s.sessions[ss] = struct{}{}
+	go ss.run()
+	return client, nil
+}
+
+// ID implements EndPoint.
+func (s *InProcessServer) ID() EndPointID {
+	return s.endPointID
+}
+
+// EndPointType implements EndPoint, the connections being streams like
+// the ones of a TCP server.
+func (s *InProcessServer) EndPointType() EndPointType {
+	return TCP_SERVER
+}
+
+// RunEventLoop implements EndPoint. The connections are opened by Dial.
+func (s *InProcessServer) RunEventLoop(newSession NewSessionCallback) {}
+
+// IsClosed implements EndPoint.
+func (s *InProcessServer) IsClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// Close implements EndPoint, closing the open connections.
+func (s *InProcessServer) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for ss := range s.sessions {
+		ss.Close()
+	}
+	s.sessions = nil
+}
+
+// GetTaskPool implements EndPoint, the messages being handled in the
+// goroutine of their connection.
+func (s *InProcessServer) GetTaskPool() gxsync.GenericTaskPool {
+	return nil
+}
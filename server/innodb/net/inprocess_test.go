@@ -0,0 +1,109 @@
+package net
+
+import (
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/goioc/di"
+	"github.com/zhukovaskychina/xmysql-server/server/conf"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+	"github.com/zhukovaskychina/xmysql-server/server/protocol"
+)
+
+// initializeContainer initializes the container of the process once, with
+// the dictionary of the first engine the sessions use.
+var initializeContainer sync.Once
+
+// readPacket reads a packet from conn, returning its payload and sequence
+// id.
+func readPacket(t *testing.T, conn net.Conn) ([]byte, byte) {
+	t.Helper()
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		t.Fatal(err)
+	}
+	payload := make([]byte, int(header[0])|int(header[1])<<8|int(header[2])<<16)
+	if _, err := io.ReadFull(conn, payload); err != nil {
+		t.Fatal(err)
+	}
+	return payload, header[3]
+}
+
+// writePacket writes payload to conn as the packet of sequence id seq.
+func writePacket(t *testing.T, conn net.Conn, payload []byte, seq byte) {
+	t.Helper()
+	n := len(payload)
+	if _, err := conn.Write(append([]byte{byte(n), byte(n >> 8), byte(n >> 16), seq}, payload...)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInProcessServer(t *testing.T) {
+	xmysqlEngine := newTestEngine(t)
+	initializeContainer.Do(func() {
+		if err := di.InitializeContainer(); err != nil {
+			t.Fatal(err)
+		}
+	})
+	cfg := conf.NewCfg()
+	cfg.SessionNumber = 10
+	cfg.SessionTimeoutDuration = time.Minute
+	cfg.MySQLSessionParam.PkgWQSize = 16
+	cfg.MySQLSessionParam.TcpReadTimeoutDuration = time.Second
+	cfg.MySQLSessionParam.TcpWriteTimeoutDuration = time.Second
+	cfg.MySQLSessionParam.WaitTimeoutDuration = time.Second
+	h := &MySQLMessageHandler{
+		cfg:          cfg,
+		sessionMap:   make(map[Session]innodb.MySQLServerSession),
+		XMySQLEngine: xmysqlEngine,
+	}
+	srv := newInProcessServer(cfg, h)
+	defer srv.Close()
+
+	conn, err := srv.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	// Handshake and login.
+	payload, seq := readPacket(t, conn)
+	if seq != 0 {
+		t.Fatalf("expect the handshake with sequence 0, got %d", seq)
+	}
+	writePacket(t, conn, protocol.EncodeLogin(protocol.DecodeHandshake(payload), "app", "secret", ""), 1)
+	if payload, seq = readPacket(t, conn); payload[0] != 0 || seq != 2 {
+		t.Fatalf("expect an OK packet with sequence 2, got %v seq %d", payload, seq)
+	}
+
+	// SELECT 1 answers a column, its definition, an EOF, the row and an
+	// EOF.
+	writePacket(t, conn, append([]byte{mysql.ComQuery}, "SELECT 1"...), 0)
+	if payload, _ = readPacket(t, conn); len(payload) != 1 || payload[0] != 1 {
+		t.Fatalf("expect 1 column, got %v", payload)
+	}
+	readPacket(t, conn)
+	if payload, _ = readPacket(t, conn); payload[0] != 0xfe {
+		t.Fatalf("expect an EOF packet, got %v", payload)
+	}
+	if payload, _ = readPacket(t, conn); string(payload) != "\x011" {
+		t.Fatalf("expect the row 1, got %q", payload)
+	}
+	if payload, _ = readPacket(t, conn); payload[0] != 0xfe {
+		t.Fatalf("expect an EOF packet, got %v", payload)
+	}
+
+	// Closing the server closes the connection.
+	srv.Close()
+	if _, err = conn.Read(make([]byte, 1)); err == nil {
+		t.Fatal("expect the connection to be closed")
+	}
+	if _, err = srv.Dial(); err == nil {
+		t.Fatal("expect a closed server to refuse connections")
+	}
+}
@@ -0,0 +1,126 @@
+package net
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/zhukovaskychina/xmysql-server/server/conf"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/engine"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+)
+
+const metricsPath = "/metrics"
+
+// metric is a metric of the server, computed from the status variables. It
+// is left out when they don't have what it needs.
+type metric struct {
+	name string
+	help string
+	// tp is the Prometheus type of the metric, gauge or counter.
+	tp    string
+	value func(status map[string]*variable.StatusVal) (float64, bool)
+}
+
+var metrics = []metric{
+	{"xmysql_buffer_pool_hit_rate", "Share of the pages asked for found in the buffer pool.", "gauge", bufferPoolHitRate},
+	{"xmysql_buffer_pool_read_requests_total", "Pages asked for from the buffer pool.", "counter",
+		statusValue(engine.StatusBufferPoolReadRequests)},
+	{"xmysql_buffer_pool_reads_total", "Pages read into the buffer pool.", "counter", statusValue(engine.StatusBufferPoolReads)},
+	{"xmysql_buffer_pool_pages_total", "Pages the buffer pool holds at most.", "gauge", statusValue(engine.StatusBufferPoolPagesTotal)},
+	{"xmysql_buffer_pool_pages_dirty", "Dirty pages of the buffer pool waiting to be flushed.", "gauge",
+		statusValue(engine.StatusBufferPoolPagesDirty)},
+	{"xmysql_buffer_pool_pages_free", "Pages the buffer pool can still take.", "gauge", statusValue(engine.StatusBufferPoolPagesFree)},
+	{"xmysql_questions_total", "Statements sent by the clients.", "counter", statusValue(engine.StatusQuestions)},
+	{"xmysql_queries_per_second", "Statements sent by the clients per second, averaged since startup.", "gauge", queriesPerSecond},
+	{"xmysql_connections", "Open client connections.", "gauge", statusValue(StatusThreadsConnected)},
+	{"xmysql_uptime_seconds", "Seconds since the server started.", "counter", statusValue(engine.StatusUptime)},
+}
+
+// statusValue returns the value of the numeric status variable name.
+func statusValue(name string) func(map[string]*variable.StatusVal) (float64, bool) {
+	return func(status map[string]*variable.StatusVal) (float64, bool) {
+		v, ok := status[name]
+		if !ok {
+			return 0, false
+		}
+		switch x := v.Value.(type) {
+		case int:
+			return float64(x), true
+		case int64:
+			return float64(x), true
+		case uint64:
+			return float64(x), true
+		}
+		return 0, false
+	}
+}
+
+func bufferPoolHitRate(status map[string]*variable.StatusVal) (float64, bool) {
+	requests, ok := statusValue(engine.StatusBufferPoolReadRequests)(status)
+	if !ok {
+		return 0, false
+	}
+	reads, ok := statusValue(engine.StatusBufferPoolReads)(status)
+	if !ok {
+		return 0, false
+	}
+	if requests == 0 {
+		return 1, true
+	}
+	return 1 - reads/requests, true
+}
+
+func queriesPerSecond(status map[string]*variable.StatusVal) (float64, bool) {
+	questions, ok := statusValue(engine.StatusQuestions)(status)
+	if !ok {
+		return 0, false
+	}
+	uptime, ok := statusValue(engine.StatusUptime)(status)
+	if !ok {
+		return 0, false
+	}
+	if uptime == 0 {
+		return questions, true
+	}
+	return questions / uptime, true
+}
+
+// metricsHandler writes the metrics in the Prometheus text format.
+func metricsHandler(w http.ResponseWriter, r *http.Request) {
+	status, err := variable.GetStatusVars(nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	for _, m := range metrics {
+		value, ok := m.value(status)
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %s\n",
+			m.name, m.help, m.name, m.tp, m.name, strconv.FormatFloat(value, 'g', -1, 64))
+	}
+}
+
+// serveMetrics serves the metrics on the metrics port in the background.
+// It returns the listener, nil when the port is 0.
+func serveMetrics(conf *conf.Cfg) (net.Listener, error) {
+	if conf.MetricsPort == 0 {
+		return nil, nil
+	}
+	listener, err := net.Listen("tcp", net.JoinHostPort(conf.BindAddress, strconv.Itoa(conf.MetricsPort)))
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(metricsPath, metricsHandler)
+	log.Infof("metrics served on %s%s", listener.Addr(), metricsPath)
+	go func() {
+		log.Info(http.Serve(listener, mux))
+	}()
+	return listener, nil
+}
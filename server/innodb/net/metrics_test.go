@@ -0,0 +1,102 @@
+package net
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/conf"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/engine"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+)
+
+// metricsTestStatistics stands for the status variables of the engine.
+type metricsTestStatistics struct{}
+
+func (metricsTestStatistics) GetScope(status string) variable.ScopeFlag {
+	return variable.ScopeGlobal
+}
+
+func (metricsTestStatistics) Stats(vars *variable.SessionVars) (map[string]interface{}, error) {
+	return map[string]interface{}{
+		engine.StatusBufferPoolPagesTotal:   int64(256),
+		engine.StatusBufferPoolPagesDirty:   int64(6),
+		engine.StatusBufferPoolPagesFree:    int64(200),
+		engine.StatusBufferPoolReadRequests: uint64(100),
+		engine.StatusBufferPoolReads:        uint64(25),
+		engine.StatusQuestions:              uint64(120),
+		engine.StatusUptime:                 int64(60),
+	}, nil
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	cfg := conf.NewCfg()
+	if listener, err := serveMetrics(cfg); listener != nil || err != nil {
+		t.Fatalf("expect no endpoint on port 0, got %v, %v", listener, err)
+	}
+
+	conn := &handlerTestSession{}
+	h := &MySQLMessageHandler{sessionMap: map[Session]innodb.MySQLServerSession{
+		conn: &MySQLServerSessionImpl{session: conn, sessionVars: variable.NewSessionVars()},
+	}}
+	variable.RegisterStatistics(h)
+	variable.RegisterStatistics(metricsTestStatistics{})
+
+	free, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.MetricsPort = free.Addr().(*net.TCPAddr).Port
+	free.Close()
+	listener, err := serveMetrics(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	resp, err := http.Get("http://" + listener.Addr().String() + metricsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := make(map[string]float64)
+	scanner := bufio.NewScanner(strings.NewReader(string(body)))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			t.Fatalf("malformed line %q", line)
+		}
+		if values[fields[0]], err = strconv.ParseFloat(fields[1], 64); err != nil {
+			t.Fatalf("line %q: %v", line, err)
+		}
+	}
+	for name, expect := range map[string]float64{
+		"xmysql_buffer_pool_hit_rate":            0.75,
+		"xmysql_buffer_pool_read_requests_total": 100,
+		"xmysql_buffer_pool_reads_total":         25,
+		"xmysql_buffer_pool_pages_total":         256,
+		"xmysql_buffer_pool_pages_dirty":         6,
+		"xmysql_buffer_pool_pages_free":          200,
+		"xmysql_questions_total":                 120,
+		"xmysql_queries_per_second":              2,
+		"xmysql_connections":                     1,
+		"xmysql_uptime_seconds":                  60,
+	} {
+		if got, ok := values[name]; !ok || got != expect {
+			t.Errorf("expect %s %v, got %v in\n%s", name, expect, got, body)
+		}
+	}
+}
@@ -3,7 +3,7 @@ package net
 import (
 	"bytes"
 	"encoding/binary"
-	"github.com/zhukovaskychina/xmysql-server/util"
+	"github.com/zhukovaskychina/xmysql-server/server/protocol"
 )
 
 type MySQLPkgHeader struct {
@@ -32,15 +32,19 @@ func (p MySQLPackage) Marshal() (*bytes.Buffer, error) {
 	return buf, nil
 }
 
-func (p *MySQLPackage) Unmarshal(buf *bytes.Buffer) (int, error) {
-
-	if buf.Len() < 4 {
+// Unmarshal reads the payload at the head of buf, joining the packets of a
+// payload longer than protocol.MaxPayloadLen. A payload larger than
+// maxAllowedPacket bytes is rejected, 0 means no limit.
+func (p *MySQLPackage) Unmarshal(buf *bytes.Buffer, maxAllowedPacket int) (int, error) {
+	body, seq, n, err := protocol.ReadPacket(buf.Bytes(), maxAllowedPacket)
+	if err == protocol.ErrIncompletePacket {
 		return 0, ErrNotEnoughStream
 	}
-	var cursor = 0
-	cursor, packetLength := util.ReadUB3(buf.Bytes(), cursor)
+	p.Header.PacketId = seq
+	if err != nil {
+		return 0, err
+	}
 	p.Header.PacketLength = buf.Bytes()[0:3]
-	p.Header.PacketId = buf.Bytes()[3]
-	p.Body = buf.Bytes()[4 : packetLength+4]
-	return int(packetLength + 4), nil
+	p.Body = body
+	return n, nil
 }
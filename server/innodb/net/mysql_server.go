@@ -40,6 +40,8 @@ type MySQLServer struct {
 	conf       *conf.Cfg
 	serverList []Server
 	taskPool   gxsync.GenericTaskPool
+	msgHandler *MySQLMessageHandler
+	health     *health
 }
 
 func NewMySQLServer(conf *conf.Cfg) *MySQLServer {
@@ -48,11 +50,18 @@ func NewMySQLServer(conf *conf.Cfg) *MySQLServer {
 		conf:       conf,
 		serverList: nil,
 		taskPool:   gxsync.NewTaskPoolSimple(0),
+		health:     newHealth(),
 	}
 }
 
 func (srv *MySQLServer) Start() {
 	initProfiling(srv.conf)
+	if _, err := serveMetrics(srv.conf); err != nil {
+		log.Errorf("metrics endpoint error %v", err)
+	}
+	if _, err := serveHealth(srv.conf, srv.health); err != nil {
+		log.Errorf("health endpoint error %v", err)
+	}
 
 	di.RegisterBeanInstance("globalConfig", srv.conf)
 	srv.taskPool = gxsync.NewTaskPoolSimple(0)
@@ -88,6 +97,9 @@ func (srv *MySQLServer) initServer(conf *conf.Cfg) {
 		server   Server
 	)
 	mysqlMsgHandler := NewMySQLMessageHandler(conf)
+	srv.msgHandler = mysqlMsgHandler
+	srv.health.setReady(subsystemStorage, true)
+	mysqlPkgHandler.SetMaxAllowedPacket(conf.MaxAllowedPacket)
 	portList = append(portList, strconv.Itoa(conf.Port))
 	if len(portList) == 0 {
 		panic("portList is nil")
@@ -95,7 +107,7 @@ func (srv *MySQLServer) initServer(conf *conf.Cfg) {
 	for _, port := range portList {
 		addr = net.JoinHostPort(conf.BindAddress, port)
 
-		serverOpts := []ServerOption{WithLocalAddress(addr)}
+		serverOpts := serverOptions(conf, addr)
 		//serverOpts = append(serverOpts, getty.WithServerTaskPool(srv.taskPool))
 		server = NewTCPServer(serverOpts...)
 		// run serverimpl
@@ -104,9 +116,6 @@ func (srv *MySQLServer) initServer(conf *conf.Cfg) {
 				ok      bool
 				tcpConn *net.TCPConn
 			)
-			if conf.MySQLSessionParam.CompressEncoding {
-				session.SetCompressType(CompressZip)
-			}
 			if tcpConn, ok = session.Conn().(*net.TCPConn); !ok {
 				panic(fmt.Sprintf("%s, session.conn{%#v} is not tcp connection\n", session.Stat(), session.Conn()))
 			}
@@ -118,15 +127,7 @@ func (srv *MySQLServer) initServer(conf *conf.Cfg) {
 			tcpConn.SetReadBuffer(conf.MySQLSessionParam.TcpRBufSize)
 			tcpConn.SetWriteBuffer(conf.MySQLSessionParam.TcpWBufSize)
 
-			session.SetName(conf.MySQLSessionParam.SessionName)
-			session.SetMaxMsgLen(conf.MySQLSessionParam.MaxMsgLen)
-			session.SetPkgHandler(mysqlPkgHandler)
-			session.SetEventListener(mysqlMsgHandler)
-			session.SetWQLen(conf.MySQLSessionParam.PkgWQSize)
-			session.SetReadTimeout(conf.MySQLSessionParam.TcpReadTimeoutDuration)
-			session.SetWriteTimeout(conf.MySQLSessionParam.TcpWriteTimeoutDuration)
-			session.SetCronPeriod((int)(conf.SessionTimeoutDuration / 1e6))
-			session.SetWaitTime(conf.MySQLSessionParam.WaitTimeoutDuration)
+			configureSession(conf, session, mysqlMsgHandler)
 			//session.SetTaskPool(taskPool)
 			log.Debug("app accepts new session:%s\n", session.Stat())
 			return nil
@@ -134,15 +135,49 @@ func (srv *MySQLServer) initServer(conf *conf.Cfg) {
 		log.Debug("serverimpl bind addr{%s} ok!", addr)
 		srv.serverList = append(srv.serverList, server)
 	}
+	srv.health.setReady(subsystemListener, true)
+	srv.health.setReady(subsystemDispatcher, true)
+}
+
+// configureSession sets up session, a new connection of any kind, by cfg
+// to be served by handler.
+func configureSession(cfg *conf.Cfg, session Session, handler *MySQLMessageHandler) {
+	if cfg.MySQLSessionParam.CompressEncoding {
+		session.SetCompressType(CompressZip)
+	}
+	session.SetName(cfg.MySQLSessionParam.SessionName)
+	session.SetMaxMsgLen(cfg.MySQLSessionParam.MaxMsgLen)
+	session.SetPkgHandler(mysqlPkgHandler)
+	session.SetEventListener(handler)
+	session.SetWQLen(cfg.MySQLSessionParam.PkgWQSize)
+	session.SetReadTimeout(cfg.MySQLSessionParam.TcpReadTimeoutDuration)
+	session.SetWriteTimeout(cfg.MySQLSessionParam.TcpWriteTimeoutDuration)
+	session.SetCronPeriod((int)(cfg.SessionTimeoutDuration / 1e6))
+	session.SetWaitTime(cfg.MySQLSessionParam.WaitTimeoutDuration)
+}
+
+// serverOptions returns the options of the server listening on addr.
+func serverOptions(cfg *conf.Cfg, addr string) []ServerOption {
+	opts := []ServerOption{WithLocalAddress(addr)}
+	if cfg.ThreadHandling == conf.ThreadHandlingPoolOfThreads {
+		opts = append(opts, WithServerThreadPool(cfg.ThreadPoolSize))
+	}
+	return opts
 }
 
 func (srv *MySQLServer) uninitServer() {
+	srv.health.setReady(subsystemDispatcher, false)
 	for _, server := range srv.serverList {
 		server.Close()
 	}
+	srv.health.setReady(subsystemListener, false)
 	if srv.taskPool != nil {
 		srv.taskPool.Close()
 	}
+	if srv.msgHandler != nil {
+		srv.msgHandler.XMySQLEngine.Close()
+	}
+	srv.health.setReady(subsystemStorage, false)
 }
 
 func (srv *MySQLServer) initSignal() {
@@ -155,7 +190,9 @@ func (srv *MySQLServer) initSignal() {
 		log.Info("get signal %s", sig.String())
 		switch sig {
 		case syscall.SIGHUP:
-		// reload()
+			if srv.msgHandler != nil {
+				srv.msgHandler.XMySQLEngine.ReloadConfig()
+			}
 		default:
 			go time.AfterFunc(srv.conf.FailFastTimeoutDuration, func() {
 				// log.Warn("app exit now by force...")
@@ -164,6 +201,8 @@ func (srv *MySQLServer) initSignal() {
 
 			})
 
+			// 先报告不再就绪，负载均衡不再发来新的连接，再关闭已有的连接
+			srv.health.shutdown()
 			// 要么fastFailTimeout时间内执行完毕下面的逻辑然后程序退出，要么执行上面的超时函数程序强行退出
 			srv.uninitServer()
 			di.Close()
@@ -53,6 +53,9 @@ type serverimpl struct {
 
 	endPointType EndPointType
 
+	// threadPool serves the connections when the server has a thread pool.
+	threadPool *threadPool
+
 	sync.Once
 	done chan struct{}
 	wg   sync.WaitGroup
@@ -100,6 +103,10 @@ func (s *serverimpl) stop() {
 				s.pktListener.Close()
 				s.pktListener = nil
 			}
+			// Unblock the accept loop.
+			if s.streamListener != nil {
+				s.streamListener.Close()
+			}
 		})
 	}
 }
@@ -212,7 +219,11 @@ func (s *serverimpl) runTcpEventLoop(newSession NewSessionCallback) {
 				continue
 			}
 			delay = 0
-			client.(*session).run()
+			if s.threadPool != nil {
+				s.threadPool.run(client.(*session))
+			} else {
+				client.(*session).run()
+			}
 		}
 	}()
 }
@@ -223,6 +234,14 @@ func (s *serverimpl) RunEventLoop(newSession NewSessionCallback) {
 	if err := s.listen(); err != nil {
 		panic(fmt.Errorf("serverimpl.listen() = error:%+v", jerrors.ErrorStack(err)))
 	}
+	if s.threadPoolSize > 0 {
+		p, err := newThreadPool(s.threadPoolSize)
+		if err != nil {
+			log.Warnf("serverimpl{%s} serves a goroutine per connection: %v", s.addr, err)
+		} else {
+			s.threadPool = p
+		}
+	}
 
 	s.runTcpEventLoop(newSession)
 
@@ -235,4 +254,7 @@ func (s *serverimpl) PacketConn() net.PacketConn {
 func (s *serverimpl) Close() {
 	s.stop()
 	s.wg.Wait()
+	if s.threadPool != nil {
+		s.threadPool.close()
+	}
 }
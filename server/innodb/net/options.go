@@ -37,6 +37,9 @@ type ServerOptions struct {
 	caCert     string
 	// task queue
 	tPool gxsync.GenericTaskPool
+	// threadPoolSize is the number of workers serving the connections of
+	// the pool-of-threads thread handling, 0 for a goroutine per connection.
+	threadPoolSize int
 }
 
 // @addr serverimpl listen address.
@@ -81,6 +84,14 @@ func WithServerTaskPool(pool gxsync.GenericTaskPool) ServerOption {
 	}
 }
 
+// @size: number of workers serving the idle connections parked in a poller,
+// instead of a read and a write goroutine per connection.
+func WithServerThreadPool(size int) ServerOption {
+	return func(o *ServerOptions) {
+		o.threadPoolSize = size
+	}
+}
+
 // @WithSslEnabled enable use tls
 func WithServerSslEnabled(sslEnabled bool) ServerOption {
 	return func(o *ServerOptions) {
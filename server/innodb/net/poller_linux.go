@@ -0,0 +1,105 @@
+package net
+
+import (
+	"io"
+	"net"
+	"syscall"
+	"time"
+
+	jerrors "github.com/juju/errors"
+)
+
+// epoller is the poller of Linux. The connections are watched with
+// EPOLLONESHOT, so an event disarms them until they are rearmed.
+type epoller struct {
+	fd     int
+	events []syscall.EpollEvent
+}
+
+func newPoller() (poller, error) {
+	fd, err := syscall.EpollCreate1(syscall.EPOLL_CLOEXEC)
+	if err != nil {
+		return nil, jerrors.Trace(err)
+	}
+	return &epoller{fd: fd, events: make([]syscall.EpollEvent, 128)}, nil
+}
+
+func (e *epoller) ctl(op, fd int) error {
+	ev := &syscall.EpollEvent{Events: syscall.EPOLLIN | syscall.EPOLLRDHUP | syscall.EPOLLONESHOT, Fd: int32(fd)}
+	return jerrors.Trace(syscall.EpollCtl(e.fd, op, fd, ev))
+}
+
+func (e *epoller) add(fd int) error {
+	return e.ctl(syscall.EPOLL_CTL_ADD, fd)
+}
+
+func (e *epoller) rearm(fd int) error {
+	return e.ctl(syscall.EPOLL_CTL_MOD, fd)
+}
+
+func (e *epoller) remove(fd int) error {
+	return jerrors.Trace(syscall.EpollCtl(e.fd, syscall.EPOLL_CTL_DEL, fd, nil))
+}
+
+func (e *epoller) wait(fds []int, timeout time.Duration) ([]int, error) {
+	n, err := syscall.EpollWait(e.fd, e.events, int(timeout/time.Millisecond))
+	if err == syscall.EINTR {
+		return fds, nil
+	}
+	if err != nil {
+		return fds, jerrors.Trace(err)
+	}
+	for _, ev := range e.events[:n] {
+		fds = append(fds, int(ev.Fd))
+	}
+	return fds, nil
+}
+
+func (e *epoller) close() error {
+	return jerrors.Trace(syscall.Close(e.fd))
+}
+
+// connFD returns the file descriptor of a plain TCP connection.
+func connFD(conn net.Conn) (int, bool) {
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok {
+		return 0, false
+	}
+	raw, err := tcpConn.SyscallConn()
+	if err != nil {
+		return 0, false
+	}
+	var fd int
+	if err = raw.Control(func(s uintptr) { fd = int(s) }); err != nil {
+		return 0, false
+	}
+	return fd, true
+}
+
+// readAvailable reads into p what conn received without waiting for more.
+// It returns 0 and no error when there is nothing, io.EOF once the peer
+// closed the connection.
+func readAvailable(conn net.Conn, p []byte) (int, error) {
+	raw, err := conn.(*net.TCPConn).SyscallConn()
+	if err != nil {
+		return 0, jerrors.Trace(err)
+	}
+	var n int
+	var readErr error
+	err = raw.Read(func(fd uintptr) bool {
+		n, readErr = syscall.Read(int(fd), p)
+		return true
+	})
+	if err != nil {
+		return 0, jerrors.Trace(err)
+	}
+	switch {
+	case readErr == syscall.EAGAIN || readErr == syscall.EINTR:
+		return 0, nil
+	case readErr != nil:
+		return 0, jerrors.Trace(readErr)
+	case n == 0:
+		return 0, io.EOF
+	}
+	return n, nil
+}
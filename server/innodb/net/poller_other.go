@@ -0,0 +1,23 @@
+//go:build !linux
+// +build !linux
+
+package net
+
+import (
+	"errors"
+	"net"
+)
+
+var errNoPoller = errors.New("pool-of-threads is only supported on Linux")
+
+func newPoller() (poller, error) {
+	return nil, errNoPoller
+}
+
+func connFD(conn net.Conn) (int, bool) {
+	return 0, false
+}
+
+func readAvailable(conn net.Conn, p []byte) (int, error) {
+	return 0, errNoPoller
+}
@@ -24,15 +24,25 @@ import (
 
 import (
 	log "github.com/sirupsen/logrus"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+	"github.com/zhukovaskychina/xmysql-server/server/protocol"
 )
 
 type MySQLEchoPkgHandler struct {
+	// maxAllowedPacket is the largest payload accepted from a client, 0
+	// means no limit.
+	maxAllowedPacket int
 }
 
 func NewMySQLEchoPkgHandler() *MySQLEchoPkgHandler {
 	return &MySQLEchoPkgHandler{}
 }
 
+// SetMaxAllowedPacket sets the largest payload accepted from a client.
+func (h *MySQLEchoPkgHandler) SetMaxAllowedPacket(length int) {
+	h.maxAllowedPacket = length
+}
+
 func (h *MySQLEchoPkgHandler) Read(ss Session, data []byte) (interface{}, int, error) {
 	var (
 		err error
@@ -42,11 +52,16 @@ func (h *MySQLEchoPkgHandler) Read(ss Session, data []byte) (interface{}, int, e
 	)
 
 	buf = bytes.NewBuffer(data)
-	len, err = pkg.Unmarshal(buf)
+	len, err = pkg.Unmarshal(buf, h.maxAllowedPacket)
 	if err != nil {
 		if err == ErrNotEnoughStream {
 			return nil, 0, nil
 		}
+		if sqlErr, ok := err.(*mysql.SQLError); ok && sqlErr.Code == mysql.ErrNetPacketTooLarge {
+			// Tell the client why before the session is closed.
+			packet := protocol.NewErrorPacket(sqlErr)
+			ss.WriteBytes(packet.EncodeErrorPackets())
+		}
 
 		return nil, 0, err
 	}
@@ -24,19 +24,25 @@ import (
 	"fmt"
 	"github.com/goioc/di"
 	"github.com/pkg/errors"
+	"github.com/zhukovaskychina/xmysql-server/server/common"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/engine"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/innodb_store/txn"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/parser"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/varsutil"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/auth"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/charset"
 	"github.com/zhukovaskychina/xmysql-server/server/mysql"
 	"github.com/zhukovaskychina/xmysql-server/server/protocol"
 	goctx "golang.org/x/net/context"
 	"io"
 	"net"
 	"runtime"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -119,6 +125,10 @@ type session struct {
 	// read goroutines done signal
 	rDone chan struct{}
 	lock  sync.RWMutex
+
+	// pooled is set when a threadPool serves the session instead of its
+	// own goroutines.
+	pooled *pooledConn
 }
 
 func newSession(endPoint EndPoint, conn Connection) *session {
@@ -358,7 +368,8 @@ func (s *session) WritePkg(pkg interface{}, timeout time.Duration) error {
 		}
 	}()
 
-	if timeout <= 0 {
+	// No goroutine of a pooled session drains the write queue.
+	if timeout <= 0 || s.pooled != nil {
 		pkgBytes, err := s.writer.Write(s, pkg)
 		if err != nil {
 			log.Warn("%s, [session.WritePkg] session.writer.Write(@pkg:%#v) = error:%+v", s.Stat(), pkg, err)
@@ -457,6 +468,14 @@ func (s *session) WriteBytesArray(pkgs ...[]byte) error {
 
 // func (s *session) RunEventLoop() {
 func (s *session) run() {
+	if s.open() {
+		s.start()
+	}
+}
+
+// open calls the session opened, and returns false if it was refused and
+// closed.
+func (s *session) open() bool {
 	if s.Connection == nil || s.listener == nil || s.writer == nil {
 		errStr := fmt.Sprintf("session{name:%s, conn:%#v, listener:%#v, writer:%#v}",
 			s.name, s.Connection, s.listener, s.writer)
@@ -473,10 +492,13 @@ func (s *session) run() {
 	if err := s.listener.OnOpen(s); err != nil {
 		log.Error("[OnOpen] session %s, error: %#v", s.Stat(), err)
 		s.Close()
-		return
+		return false
 	}
+	return true
+}
 
-	// start read/write gr
+// start runs the read and write goroutines of the session.
+func (s *session) start() {
 	atomic.AddInt32(&(s.grNum), 2)
 	go s.handleLoop()
 	go s.handlePackage()
@@ -661,11 +683,9 @@ func (s *session) handleTCPPackage() error {
 		conn     *MysqlTCPConn
 		exit     bool
 		bufLen   int
-		pkgLen   int
 		bufp     *[]byte
 		buf      []byte
 		pktBuf   *bytes.Buffer
-		pkg      interface{}
 	)
 
 	// buf = make([]byte, maxReadBufLen)
@@ -716,33 +736,7 @@ func (s *session) handleTCPPackage() error {
 			continue // just continue if session can not read no more stream bytes.
 		}
 		pktBuf.Write(buf[:bufLen])
-		for {
-			if pktBuf.Len() <= 0 {
-				break
-			}
-			pkg, pkgLen, err = s.reader.Read(s, pktBuf.Bytes())
-			// for case 3/case 4
-			if err == nil && s.maxMsgLen > 0 && pkgLen > int(s.maxMsgLen) {
-				err = jerrors.Errorf("pkgLen %d > session max message len %d", pkgLen, s.maxMsgLen)
-			}
-			// handle case 1
-			if err != nil {
-				log.Warn("%s, [session.handleTCPPackage] = len{%d}, error:%+v",
-					s.sessionToken(), pkgLen, jerrors.ErrorStack(err))
-				exit = true
-				break
-			}
-			// handle case 2/case 3
-			if pkg == nil {
-				break
-			}
-			// handle case 4
-			s.UpdateActive()
-			s.addTask(pkg)
-			pktBuf.Next(pkgLen)
-			// continue to handle case 5
-		}
-		if exit {
+		if err = s.handlePkgBuf(pktBuf); err != nil {
 			break
 		}
 	}
@@ -750,6 +744,36 @@ func (s *session) handleTCPPackage() error {
 	return jerrors.Trace(err)
 }
 
+// handlePkgBuf handles the complete packages at the head of pktBuf and
+// drops them from it, leaving the start of the next one.
+func (s *session) handlePkgBuf(pktBuf *bytes.Buffer) error {
+	for {
+		if pktBuf.Len() <= 0 {
+			return nil
+		}
+		pkg, pkgLen, err := s.reader.Read(s, pktBuf.Bytes())
+		// for case 3/case 4
+		if err == nil && s.maxMsgLen > 0 && pkgLen > int(s.maxMsgLen) {
+			err = jerrors.Errorf("pkgLen %d > session max message len %d", pkgLen, s.maxMsgLen)
+		}
+		// handle case 1
+		if err != nil {
+			log.Warn("%s, [session.handleTCPPackage] = len{%d}, error:%+v",
+				s.sessionToken(), pkgLen, jerrors.ErrorStack(err))
+			return err
+		}
+		// handle case 2/case 3
+		if pkg == nil {
+			return nil
+		}
+		// handle case 4
+		s.UpdateActive()
+		s.addTask(pkg)
+		pktBuf.Next(pkgLen)
+		// continue to handle case 5
+	}
+}
+
 func (s *session) stop() {
 	select {
 	case <-s.done: // s.done is a blocked channel. if it has not been closed, the default branch will be invoked.
@@ -764,6 +788,9 @@ func (s *session) stop() {
 				conn.SetWriteDeadline(now.Add(s.writeTimeout()))
 			}
 			close(s.done)
+			if s.pooled != nil {
+				s.pooled.pool.wake(s)
+			}
 			c := s.GetAttribute(sessionClientKey)
 			if clt, ok := c.(*client); ok {
 				clt.reConnect()
@@ -816,8 +843,18 @@ type MySQLServerSessionImpl struct {
 	parser      *parser.Parser
 	sessionVars *variable.SessionVars
 	info        schemas.InfoSchema
+	// sequence is the sequence id of the next packet sent to the client.
+	sequence byte
+	// txn is the open transaction, nil when there is none.
+	txn basic.XMySQLTransaction
+	// salt is the salt sent in the handshake, or in the AuthSwitchRequest
+	// after it, the one the client's auth response is verified with.
+	salt []byte
 }
 
+// scrambles generates the salts of the handshakes.
+var scrambles = auth.NewScrambleManager(nil)
+
 func NewMySQLServerSession(session Session) innodb.MySQLServerSession {
 	var mysqlSession = new(MySQLServerSessionImpl)
 	mysqlSession.info = di.GetInstance("infoSchemanager").(schemas.InfoSchema)
@@ -827,6 +864,7 @@ func NewMySQLServerSession(session Session) innodb.MySQLServerSession {
 	mysqlSession.parser = parser.New()
 	mysqlSession.sessionVars = variable.NewSessionVars()
 	mysqlSession.sessionVars.TxnCtx.InfoSchema = mysqlSession.info
+	mysqlSession.sessionVars.ConnectionID = engine.NextConnectionID()
 	return mysqlSession
 }
 
@@ -835,23 +873,187 @@ func (m *MySQLServerSessionImpl) GetLastActiveTime() time.Time {
 }
 
 func (m *MySQLServerSessionImpl) SendOK() {
+	var affectedRows int64
+	if m.sessionVars != nil && m.sessionVars.StmtCtx != nil {
+		affectedRows = int64(m.sessionVars.StmtCtx.AffectedRows())
+	}
 	buff := make([]byte, 0)
-	buff = protocol.EncodeOK(buff, 0, 0, nil)
-	m.session.WriteBytes(buff)
+	buff = protocol.EncodeOKWithSessionState(buff, affectedRows, 0, m.Status(), nil, m.sessionState())
+	m.writePackets(buff)
+}
+
+// sessionState returns the session state information of the OK packet of
+// the current statement, nil when the client doesn't track it.
+func (m *MySQLServerSessionImpl) sessionState() []byte {
+	if m.sessionVars == nil || m.sessionVars.StmtCtx == nil ||
+		m.sessionVars.ClientCapability&common.CLIENT_SESSION_TRACK == 0 {
+		return nil
+	}
+	var state []byte
+	for _, change := range m.sessionVars.StmtCtx.StateChanges {
+		state = protocol.AppendSessionStateChange(state, change.Tracker, change.Values...)
+	}
+	return state
 }
 
 func (m *MySQLServerSessionImpl) SendHandleOk() {
+	salt, err := scrambles.NewSalt()
+	if err != nil {
+		log.Errorf("generate the salt of session %s error %v", m.session.Stat(), err)
+		m.session.Close()
+		return
+	}
+	m.salt = salt
 	buff := make([]byte, 0)
-	buff = protocol.EncodeHandshake(buff)
-	m.session.WriteBytes(buff)
+	buff = protocol.EncodeHandshake(buff, salt)
+	m.writePackets(buff)
 }
 
-func (m MySQLServerSessionImpl) SendError(error *mysql.SQLError) {
+// Salt returns the salt sent in the handshake, or in the AuthSwitchRequest
+// after it.
+func (m *MySQLServerSessionImpl) Salt() []byte {
+	return m.salt
+}
+
+// SendAuthSwitchRequest asks the client to authenticate again with plugin,
+// answering a new salt.
+func (m *MySQLServerSessionImpl) SendAuthSwitchRequest(plugin string) error {
+	salt, err := scrambles.NewSalt()
+	if err != nil {
+		return jerrors.Trace(err)
+	}
+	m.salt = salt
+	return m.writePackets(protocol.EncodeAuthSwitchRequest(nil, plugin, salt))
+}
+
+func (m *MySQLServerSessionImpl) SendError(error *mysql.SQLError) {
 	buff := make([]byte, 0)
 	packet := protocol.NewErrorPacket(error)
 	buff = packet.EncodeErrorPackets()
 
-	m.session.WriteBytes(buff)
+	m.writePackets(buff)
+}
+
+// SendResultSet sends the column count, the column definitions and an EOF
+// at once, then the rows in text as rows yields them and a closing EOF with
+// the status the session has then. A client with CLIENT_DEPRECATE_EOF gets
+// no EOF after the columns and an OK packet closing the rows instead. The rows go out in batches of
+// net_buffer_length bytes, so that a result set is never held whole in
+// memory and a slow client holds the statement back. Between two batches
+// the statement stops when it is killed or past max_execution_time.
+func (m *MySQLServerSessionImpl) SendResultSet(fields []protocol.Field, rows innodb.RowIterator) error {
+	rs := protocol.NewSelectResponse(len(fields))
+	rs.SetServerStatus(m.Status())
+	for _, field := range fields {
+		rs.AddColumn(field)
+	}
+	buff := rs.Header.EncodeBuff()
+	rs.PackId = rs.Header.PacketId
+	buff = append(buff, rs.EncodeFields()...)
+	buff = m.appendResultTerminator(buff, rs, false)
+	if err := m.writePackets(buff); err != nil {
+		return jerrors.Trace(err)
+	}
+	batchSize := m.intSystemVar(variable.NetBufferLength)
+	buff = make([]byte, 0, batchSize)
+	resultsCharset := m.sessionVars.ResultsCharset
+	for {
+		row, err := rows.Next()
+		if err != nil {
+			return jerrors.Trace(err)
+		}
+		if row == nil {
+			break
+		}
+		values := make([][]byte, len(row))
+		for i, d := range row {
+			if d.IsNull() {
+				continue
+			}
+			s, err := d.ToString()
+			if err != nil {
+				return jerrors.Trace(err)
+			}
+			// Binary values are bytes, not text in some charset.
+			if d.Kind() == basic.KindString && resultsCharset != "" && fields[i].Charset != mysql.BinaryCollationID {
+				values[i] = charset.Encode(resultsCharset, s)
+				continue
+			}
+			values[i] = []byte(s)
+		}
+		buff = append(buff, rs.WriteRow(values)...)
+		if int64(len(buff)) < batchSize {
+			continue
+		}
+		if err = m.writePackets(buff); err != nil {
+			return jerrors.Trace(err)
+		}
+		buff = make([]byte, 0, batchSize)
+		if err = m.checkInterrupted(); err != nil {
+			return err
+		}
+	}
+	rs.SetServerStatus(m.Status())
+	if sc := m.sessionVars.StmtCtx; sc != nil {
+		rs.SetWarningCount(sc.WarningCount())
+	}
+	buff = m.appendResultTerminator(buff, rs, true)
+	return jerrors.Trace(m.writePackets(buff))
+}
+
+// appendResultTerminator appends to buff the packet closing the column
+// definitions of rs, or its rows when last is set: an EOF packet, or for a
+// client with CLIENT_DEPRECATE_EOF nothing after the columns and an OK
+// packet after the rows.
+func (m *MySQLServerSessionImpl) appendResultTerminator(buff []byte, rs *protocol.SelectResponse, last bool) []byte {
+	deprecateEOF := m.sessionVars.ClientCapability&protocol.GetCapabilitiesWithoutParams()&common.CLIENT_DEPRECATE_EOF != 0
+	switch {
+	case !last && deprecateEOF:
+		return buff
+	case !last:
+		return append(buff, rs.EncodeEof()...)
+	case deprecateEOF:
+		return append(buff, rs.EncodeLastOK()...)
+	}
+	return append(buff, rs.EncodeLastEof()...)
+}
+
+// checkInterrupted returns an error when the running statement is killed
+// or has run longer than max_execution_time.
+func (m *MySQLServerSessionImpl) checkInterrupted() error {
+	if atomic.LoadUint32(&m.sessionVars.Killed) != 0 {
+		return mysql.NewErr(mysql.ErrQueryInterrupted)
+	}
+	limit := m.intSystemVar(variable.MaxExecutionTime)
+	sc := m.sessionVars.StmtCtx
+	if limit > 0 && sc != nil && time.Since(sc.NowTs) > time.Duration(limit)*time.Millisecond {
+		return mysql.NewErr(mysql.ErrQueryTimeout)
+	}
+	return nil
+}
+
+// intSystemVar returns the value of the numeric system variable name of
+// the session, 0 when it isn't a number.
+func (m *MySQLServerSessionImpl) intSystemVar(name string) int64 {
+	value, err := varsutil.GetSessionSystemVar(m.sessionVars, name)
+	if err != nil {
+		return 0
+	}
+	n, _ := strconv.ParseInt(value, 10, 64)
+	return n
+}
+
+func (m *MySQLServerSessionImpl) SetPacketSequence(seq byte) {
+	m.sequence = seq
+}
+
+// writePackets sends the packets encoded in buff, numbered from the
+// sequence id the client expects next. Every packet sent to the client
+// goes through it, so that the ids of a response stay contiguous however
+// many packets it is made of.
+func (m *MySQLServerSessionImpl) writePackets(buff []byte) error {
+	m.sequence = protocol.SetPacketSequence(buff, m.sequence)
+	return m.session.WriteBytes(buff)
 }
 
 func (m *MySQLServerSessionImpl) GetCurrentDataBase() string {
@@ -936,6 +1138,9 @@ func checkArgs(args ...interface{}) error {
 }
 
 func (s *MySQLServerSessionImpl) SetValue(key fmt.Stringer, value interface{}) {
+	if s.values == nil {
+		s.values = make(map[fmt.Stringer]interface{})
+	}
 	s.values[key] = value
 }
 
@@ -963,18 +1168,44 @@ func (m *MySQLServerSessionImpl) PrepareTxnCtx() {
 
 }
 
+// Commit implements the MySQLServerSession interface: it commits the open
+// transaction of the session, if any.
 func (m *MySQLServerSessionImpl) Commit() {
-
+	if m.txn == nil {
+		return
+	}
+	if err := m.txn.Commit(); err != nil {
+		log.Warnf("提交事务失败: %v", err)
+	}
+	m.txn = nil
+	m.sessionVars.SetStatusFlag(mysql.ServerStatusInTrans, false)
 }
 
 // NewTxn creates a new transaction for further execution.
 // If old transaction is valid, it is committed first.
 // It's used in BEGIN statement and DDL statements to commit old transaction.
 func (m *MySQLServerSessionImpl) NewTxn() error {
+	m.Commit()
+	m.txn = txn.NewTxn()
+	m.sessionVars.SetStatusFlag(mysql.ServerStatusInTrans, true)
 	return nil
 }
 
+// RollbackTxn implements the MySQLServerSession interface.
+func (m *MySQLServerSessionImpl) RollbackTxn() error {
+	if m.txn == nil {
+		return nil
+	}
+	err := m.txn.Rollback()
+	m.txn = nil
+	m.sessionVars.SetStatusFlag(mysql.ServerStatusInTrans, false)
+	return jerrors.Trace(err)
+}
+
 func (m *MySQLServerSessionImpl) Txn() basic.XMySQLTransaction {
+	if m.txn != nil {
+		return m.txn
+	}
 	return txn.NewTxn()
 }
 
@@ -0,0 +1,289 @@
+package net
+
+import (
+	"bytes"
+	"io"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	jerrors "github.com/juju/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// pollTimeout is how long the poller waits before checking whether the
+// pool is closed.
+const pollTimeout = 100 * time.Millisecond
+
+// The states of a pooled connection.
+const (
+	// pooledParked is the state of a connection waiting for data, which a
+	// read event or closing it wakes.
+	pooledParked int32 = iota
+	// pooledRunning is the state of a connection a worker is serving.
+	pooledRunning
+)
+
+// poller tells which of the connections it watches can be read. A
+// connection reported stops being watched until it is rearmed, so a single
+// worker handles it at a time.
+type poller interface {
+	// add watches fd until it can be read.
+	add(fd int) error
+	// rearm watches fd again after it was reported.
+	rearm(fd int) error
+	// remove stops watching fd, before it is closed.
+	remove(fd int) error
+	// wait appends to fds the connections that can be read, waiting up to
+	// timeout for one.
+	wait(fds []int, timeout time.Duration) ([]int, error)
+	close() error
+}
+
+// pooledConn is the state of a session served by a threadPool.
+type pooledConn struct {
+	pool  *threadPool
+	fd    int
+	state int32
+	// pktBuf is the start of the package still being received.
+	pktBuf *bytes.Buffer
+	// cronAt is when the session's OnCron was last called.
+	cronAt time.Time
+}
+
+// threadPool serves the sessions of the pool-of-threads thread handling.
+// Instead of each connection reading with a goroutine of its own, a poller
+// watches the idle ones and a fixed number of workers handle the packages
+// of those that received data. Sessions whose data may be buffered out of
+// the poller's sight, TLS and compressed ones, keep their own goroutines.
+type threadPool struct {
+	poller poller
+	ready  chan *session
+
+	mu sync.Mutex
+	// sessions are the pooled sessions by fd.
+	sessions map[int]*session
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newThreadPool starts a pool of size workers, or fails when the platform
+// has no poller.
+func newThreadPool(size int) (*threadPool, error) {
+	pl, err := newPoller()
+	if err != nil {
+		return nil, jerrors.Trace(err)
+	}
+	p := &threadPool{
+		poller:   pl,
+		ready:    make(chan *session, defaultQLen),
+		sessions: make(map[int]*session),
+		done:     make(chan struct{}),
+	}
+	p.wg.Add(size + 2)
+	go p.poll()
+	go p.cron()
+	for i := 0; i < size; i++ {
+		go p.work()
+	}
+	return p, nil
+}
+
+// run opens s and serves it from the pool, or from its own goroutines when
+// its connection can't be polled.
+func (p *threadPool) run(s *session) {
+	if !s.open() {
+		return
+	}
+	if !p.add(s) {
+		s.start()
+	}
+}
+
+// add parks s in the pool, and returns false if its connection can't be
+// polled.
+func (p *threadPool) add(s *session) bool {
+	conn, ok := s.Connection.(*MysqlTCPConn)
+	if !ok || conn.compress != CompressNone {
+		return false
+	}
+	fd, ok := connFD(conn.conn)
+	if !ok {
+		return false
+	}
+	s.pooled = &pooledConn{pool: p, fd: fd, state: pooledRunning, pktBuf: new(bytes.Buffer), cronAt: time.Now()}
+	p.mu.Lock()
+	p.sessions[fd] = s
+	p.mu.Unlock()
+	p.park(s, p.poller.add)
+	return true
+}
+
+// park waits for the next data of s with arm, or finishes it if it was
+// closed meanwhile.
+func (p *threadPool) park(s *session, arm func(int) error) {
+	c := s.pooled
+	atomic.StoreInt32(&c.state, pooledParked)
+	if err := arm(c.fd); err != nil {
+		log.Warnf("%s, [threadPool.park] error:%v", s.sessionToken(), err)
+		s.stop()
+	}
+	// Closed before it was parked, nothing else wakes it.
+	if s.IsClosed() {
+		p.wake(s)
+	}
+}
+
+// wake hands s to a worker if it is parked.
+func (p *threadPool) wake(s *session) {
+	if !atomic.CompareAndSwapInt32(&s.pooled.state, pooledParked, pooledRunning) {
+		return
+	}
+	select {
+	case p.ready <- s:
+	default:
+		// The workers are busy, possibly the caller among them.
+		go func() {
+			select {
+			case p.ready <- s:
+			case <-p.done:
+			}
+		}()
+	}
+}
+
+func (p *threadPool) poll() {
+	defer p.wg.Done()
+	var (
+		fds []int
+		err error
+	)
+	for {
+		select {
+		case <-p.done:
+			return
+		default:
+		}
+		if fds, err = p.poller.wait(fds[:0], pollTimeout); err != nil {
+			log.Warnf("[threadPool.poll] error:%v", err)
+			continue
+		}
+		for _, fd := range fds {
+			p.mu.Lock()
+			s := p.sessions[fd]
+			p.mu.Unlock()
+			if s != nil {
+				p.wake(s)
+			}
+		}
+	}
+}
+
+func (p *threadPool) work() {
+	defer p.wg.Done()
+	buf := make([]byte, maxReadBufLen)
+	for {
+		select {
+		case <-p.done:
+			return
+		case s := <-p.ready:
+			p.serve(s, buf)
+		}
+	}
+}
+
+// serve handles the packages of the data s received and parks it again,
+// or finishes it once it is closed.
+func (p *threadPool) serve(s *session, buf []byte) {
+	defer func() {
+		if r := recover(); r != nil {
+			const size = 64 << 10
+			rBuf := make([]byte, size)
+			rBuf = rBuf[:runtime.Stack(rBuf, false)]
+			log.Errorf("[threadPool.serve] panic session %s: err=%s\n%s", s.sessionToken(), r, rBuf)
+			s.stop()
+			p.finish(s)
+		}
+	}()
+	if !s.IsClosed() {
+		if err := p.read(s, buf); err != nil {
+			log.Errorf("%s, [threadPool.serve] error:%+v", s.sessionToken(), jerrors.ErrorStack(err))
+			s.stop()
+			s.listener.OnError(s, err)
+		}
+	}
+	if s.IsClosed() {
+		p.finish(s)
+		return
+	}
+	p.park(s, p.poller.rearm)
+}
+
+// read reads what the connection of s received and handles the complete
+// packages in it. The connection is stopped at its end.
+func (p *threadPool) read(s *session, buf []byte) error {
+	conn := s.Connection.(*MysqlTCPConn)
+	n, err := readAvailable(conn.conn, buf)
+	if err == io.EOF {
+		s.stop()
+		return nil
+	}
+	if err != nil {
+		return jerrors.Trace(err)
+	}
+	atomic.AddUint32(&conn.readBytes, uint32(n))
+	s.pooled.pktBuf.Write(buf[:n])
+	return jerrors.Trace(s.handlePkgBuf(s.pooled.pktBuf))
+}
+
+// finish stops watching the closed s and releases it.
+func (p *threadPool) finish(s *session) {
+	c := s.pooled
+	if err := p.poller.remove(c.fd); err != nil {
+		log.Debugf("%s, [threadPool.finish] error:%v", s.sessionToken(), err)
+	}
+	p.mu.Lock()
+	delete(p.sessions, c.fd)
+	p.mu.Unlock()
+	s.listener.OnClose(s)
+	s.gc()
+}
+
+// cron calls OnCron of the pooled sessions every period of theirs.
+func (p *threadPool) cron() {
+	defer p.wg.Done()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case now := <-ticker.C:
+			var due []*session
+			p.mu.Lock()
+			for _, s := range p.sessions {
+				if now.Sub(s.pooled.cronAt) >= s.period {
+					s.pooled.cronAt = now
+					due = append(due, s)
+				}
+			}
+			p.mu.Unlock()
+			for _, s := range due {
+				if !s.IsClosed() {
+					s.listener.OnCron(s)
+				}
+			}
+		}
+	}
+}
+
+// close stops the workers. The sessions left are not closed.
+func (p *threadPool) close() {
+	close(p.done)
+	p.wg.Wait()
+	if err := p.poller.close(); err != nil {
+		log.Warnf("[threadPool.close] error:%v", err)
+	}
+}
@@ -0,0 +1,144 @@
+package net
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// linePkgHandler reads and writes the packages of the thread pool tests,
+// lines of text.
+type linePkgHandler struct{}
+
+func (linePkgHandler) Read(ss Session, data []byte) (interface{}, int, error) {
+	i := bytes.IndexByte(data, '\n')
+	if i < 0 {
+		return nil, 0, nil
+	}
+	return string(data[:i]), i + 1, nil
+}
+
+func (linePkgHandler) Write(ss Session, pkg interface{}) ([]byte, error) {
+	return []byte(pkg.(string)), nil
+}
+
+// countingListener answers each line with the number of lines its session
+// received so far.
+type countingListener struct {
+	opened, closed int32
+}
+
+func (l *countingListener) OnOpen(ss Session) error {
+	atomic.AddInt32(&l.opened, 1)
+	return nil
+}
+
+func (l *countingListener) OnClose(ss Session) {
+	atomic.AddInt32(&l.closed, 1)
+}
+
+func (l *countingListener) OnError(ss Session, err error) {}
+
+func (l *countingListener) OnCron(ss Session) {}
+
+func (l *countingListener) OnMessage(ss Session, pkg interface{}) {
+	n, _ := ss.GetAttribute("n").(int)
+	n++
+	ss.SetAttribute("n", n)
+	ss.WriteBytes([]byte(fmt.Sprintf("%d:%s\n", n, pkg)))
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !cond(); {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestThreadPool(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("pool-of-threads needs epoll")
+	}
+	srv := newServer(TCP_SERVER, WithLocalAddress("127.0.0.1:0"), WithServerThreadPool(4))
+	listener := &countingListener{}
+	srv.RunEventLoop(func(ss Session) error {
+		ss.SetPkgHandler(linePkgHandler{})
+		ss.SetEventListener(listener)
+		return nil
+	})
+	defer srv.Close()
+	if srv.threadPool == nil {
+		t.Fatal("expect a thread pool")
+	}
+
+	// Idle connections take no goroutine of their own.
+	const idle = 500
+	before := runtime.NumGoroutine()
+	conns := make([]net.Conn, 0, idle)
+	defer func() {
+		for _, c := range conns {
+			c.Close()
+		}
+	}()
+	for i := 0; i < idle; i++ {
+		c, err := net.Dial("tcp", srv.addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conns = append(conns, c)
+	}
+	waitFor(t, "the connections to open", func() bool { return atomic.LoadInt32(&listener.opened) == idle })
+	if grown := runtime.NumGoroutine() - before; grown > 20 {
+		t.Fatalf("expect the goroutines to stay bounded, %d more for %d connections", grown, idle)
+	}
+
+	// Each session keeps its own state whichever worker handles it, and
+	// the lines of a session are handled in order.
+	readers := map[net.Conn]*bufio.Reader{}
+	expect := func(c net.Conn, lines ...string) {
+		t.Helper()
+		r, ok := readers[c]
+		if !ok {
+			r = bufio.NewReader(c)
+			readers[c] = r
+		}
+		c.SetReadDeadline(time.Now().Add(5 * time.Second))
+		for _, line := range lines {
+			got, err := r.ReadString('\n')
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != line+"\n" {
+				t.Fatalf("expect %q, got %q", line, got)
+			}
+		}
+	}
+	a, b := conns[0], conns[idle-1]
+	a.Write([]byte("x\n"))
+	expect(a, "1:x")
+	b.Write([]byte("y\nz\n"))
+	expect(b, "1:y", "2:z")
+	// A line split across two reads.
+	a.Write([]byte("hel"))
+	time.Sleep(20 * time.Millisecond)
+	a.Write([]byte("lo\n"))
+	expect(a, "2:hello")
+
+	// A connection closed by the client is released.
+	b.Close()
+	waitFor(t, "the connection to close", func() bool { return atomic.LoadInt32(&listener.closed) == 1 })
+	srv.threadPool.mu.Lock()
+	pooled := len(srv.threadPool.sessions)
+	srv.threadPool.mu.Unlock()
+	if pooled != idle-1 {
+		t.Fatalf("expect %d pooled sessions, got %d", idle-1, pooled)
+	}
+}
@@ -0,0 +1,94 @@
+package parser
+
+import (
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+)
+
+// parseOptimizerHints parses the optimizer hints in the text of a comment
+// /*+ ... */ following SELECT, like
+//
+//	INDEX(t idx_a, idx_b) NO_INDEX(s) TIDB_SMJ(t, s)
+//
+// As in MySQL, a hint that isn't known is ignored, and so are the ones
+// following a hint that can't be parsed.
+// See https://dev.mysql.com/doc/refman/8.0/en/optimizer-hints.html
+func parseOptimizerHints(text string) []*ast.TableOptimizerHint {
+	s := NewScanner(text)
+	var hints []*ast.TableOptimizerHint
+	for {
+		tok, _, name := s.scan()
+		if tok != identifier {
+			return hints
+		}
+		if tok, _, _ = s.scan(); tok != '(' {
+			return hints
+		}
+		args, ok := scanHintArgs(s)
+		if !ok {
+			return hints
+		}
+		if hint := newOptimizerHint(model.NewCIStr(name), args); hint != nil {
+			hints = append(hints, hint)
+		}
+	}
+}
+
+// scanHintArgs scans the arguments of a hint up to its ')'. They are
+// separated by commas, each one a list of words separated by spaces.
+func scanHintArgs(s *Scanner) (args [][]model.CIStr, ok bool) {
+	var arg []model.CIStr
+	for {
+		tok, _, lit := s.scan()
+		switch tok {
+		case identifier, quotedIdentifier, intLit, floatLit, decLit, stringLit:
+			// Literals only fit hints that aren't known, like
+			// MAX_EXECUTION_TIME(1000).
+			arg = append(arg, model.NewCIStr(lit))
+		case ',':
+			if len(arg) == 0 {
+				return nil, false
+			}
+			args, arg = append(args, arg), nil
+		case ')':
+			if len(arg) == 0 {
+				return args, len(args) == 0
+			}
+			return append(args, arg), true
+		default:
+			return nil, false
+		}
+	}
+}
+
+// newOptimizerHint returns the hint name(args), nil if it isn't known or
+// its arguments don't fit it.
+func newOptimizerHint(name model.CIStr, args [][]model.CIStr) *ast.TableOptimizerHint {
+	if len(args) == 0 {
+		return nil
+	}
+	hint := &ast.TableOptimizerHint{HintName: name}
+	switch name.L {
+	case "tidb_smj", "tidb_inlj":
+		// TIDB_SMJ(t1, t2)
+		for _, arg := range args {
+			if len(arg) != 1 {
+				return nil
+			}
+			hint.Tables = append(hint.Tables, arg[0])
+		}
+	case "index", "no_index":
+		// INDEX(t idx1, idx2)
+		hint.Tables = args[0][:1]
+		hint.Indexes = append([]model.CIStr(nil), args[0][1:]...)
+		for _, arg := range args[1:] {
+			if len(arg) != 1 {
+				return nil
+			}
+			hint.Indexes = append(hint.Indexes, arg[0])
+		}
+	default:
+		return nil
+	}
+	return hint
+}
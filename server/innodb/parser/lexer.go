@@ -20,6 +20,7 @@ import (
 	"unicode"
 	"unicode/utf8"
 
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
 	"github.com/zhukovaskychina/xmysql-server/server/mysql"
 )
 
@@ -40,8 +41,12 @@ type Scanner struct {
 	errs         []error
 	stmtStartPos int
 
-	// For scanning such kind of comment: /*! MySQL-specific code */ or /*+ optimizer hint */
+	// For scanning such kind of comment: /*! MySQL-specific code */
 	specialComment specialCommentScanner
+	// hints are the optimizer hints of the last /*+ optimizer hint */ comment.
+	hints []*ast.TableOptimizerHint
+	// lastTok is the last token returned to the parser.
+	lastTok int
 
 	sqlMode mysql.SQLMode
 }
@@ -63,26 +68,6 @@ func (s *mysqlSpecificCodeScanner) scan() (tok int, pos Pos, lit string) {
 	return
 }
 
-type optimizerHintScanner struct {
-	*Scanner
-	Pos
-	end bool
-}
-
-func (s *optimizerHintScanner) scan() (tok int, pos Pos, lit string) {
-	tok, pos, lit = s.Scanner.scan()
-	pos.Line += s.Pos.Line
-	pos.Col += s.Pos.Col
-	pos.Offset += s.Pos.Offset
-	if tok == 0 {
-		if !s.end {
-			tok = hintEnd
-			s.end = true
-		}
-	}
-	return
-}
-
 // Errors returns the errors during a scan.
 func (s *Scanner) Errors() []error {
 	return s.errs
@@ -94,6 +79,7 @@ func (s *Scanner) reset(sql string) {
 	s.buf.Reset()
 	s.errs = s.errs[:0]
 	s.stmtStartPos = 0
+	s.lastTok = 0
 }
 
 func (s *Scanner) stmtText() string {
@@ -111,16 +97,19 @@ func (s *Scanner) stmtText() string {
 	return text
 }
 
-// Errorf tells scanner something is wrong.
+// Errorf tells scanner something is wrong, as ER_PARSE_ERROR near the rest
+// of the query. An empty format is the syntax error of the grammar.
 // Scanner satisfies yyLexer interface which need this function.
 func (s *Scanner) Errorf(format string, a ...interface{}) {
 	str := fmt.Sprintf(format, a...)
+	if str == "" {
+		str = mysql.MySQLErrName[mysql.ErrSyntax]
+	}
 	val := s.r.s[s.r.pos().Offset:]
-	if len(val) > 2048 {
-		val = val[:2048]
+	if len(val) > mysql.ErrTextLength {
+		val = val[:mysql.ErrTextLength]
 	}
-	err := fmt.Errorf("line %d column %d near \"%s\"%s (total length %d)", s.r.p.Line, s.r.p.Col, val, str, len(s.r.s))
-	s.errs = append(s.errs, err)
+	s.errs = append(s.errs, ErrParse.GenByArgs(str, val, s.r.p.Line))
 }
 
 // Lex returns a token and store the token value in v.
@@ -129,6 +118,12 @@ func (s *Scanner) Errorf(format string, a ...interface{}) {
 // return 0 tells parser that scanner meets EOF,
 // return invalid tells parser that scanner meets illegal character.
 func (s *Scanner) Lex(v *yySymType) int {
+	tok := s.lex(v)
+	s.lastTok = tok
+	return tok
+}
+
+func (s *Scanner) lex(v *yySymType) int {
 	tok, pos, lit := s.scan()
 	v.offset = pos.Offset
 	v.ident = lit
@@ -162,6 +157,8 @@ func (s *Scanner) Lex(v *yySymType) int {
 		return tok
 	case null:
 		v.item = nil
+	case hintComment:
+		v.item = s.hints
 	case quotedIdentifier:
 		tok = identifier
 	}
@@ -336,20 +333,11 @@ func startWithSlash(s *Scanner) (tok int, pos Pos, lit string) {
 		comment := s.r.data(&pos)
 
 		// See https://dev.mysql.com/doc/refman/5.7/en/optimizer-hints.html
-		if strings.HasPrefix(comment, "/*+") {
-			begin := sqlOffsetInComment(comment)
-			end := len(comment) - 2
-			sql := comment[begin:end]
-			s.specialComment = &optimizerHintScanner{
-				Scanner: NewScanner(sql),
-				Pos: Pos{
-					pos.Line,
-					pos.Col,
-					pos.Offset + begin,
-				},
-			}
-
-			tok = hintBegin
+		// The hints are only read right after SELECT, anywhere else the
+		// comment is an ordinary one.
+		if strings.HasPrefix(comment, "/*+") && s.lastTok == selectKwd {
+			s.hints = parseOptimizerHints(comment[3 : len(comment)-2])
+			tok = hintComment
 			return
 		}
 
@@ -401,7 +389,7 @@ func startWithAt(s *Scanner) (tok int, pos Pos, lit string) {
 	} else if ch1 == '@' {
 		s.r.inc()
 		stream := s.r.s[pos.Offset+2:]
-		for _, v := range []string{"global.", "session.", "local."} {
+		for _, v := range []string{"global.", "persist.", "session.", "local."} {
 			if len(v) > len(stream) {
 				continue
 			}
@@ -189,6 +189,7 @@ var tokenMap = map[string]int{
 	"COMPRESSION":         compression,
 	"CONNECTION":          connection,
 	"CONSISTENT":          consistent,
+	"CSV":                 csv,
 	"CONSTRAINT":          constraint,
 	"CONVERT":             convert,
 	"COUNT":               count,
@@ -217,6 +218,7 @@ var tokenMap = map[string]int{
 	"DECIMAL":             decimalType,
 	"DEFAULT":             defaultKwd,
 	"DELAY_KEY_WRITE":     delayKeyWrite,
+	"DELIMITER":           delimiter,
 	"DELAYED":             delayed,
 	"DELETE":              deleteKwd,
 	"DESC":                desc,
@@ -243,6 +245,7 @@ var tokenMap = map[string]int{
 	"EVENTS":              events,
 	"EXCLUSIVE":           exclusive,
 	"EXECUTE":             execute,
+	"EXTENDED":            extended,
 	"EXISTS":              exists,
 	"EXPLAIN":             explain,
 	"EXTRACT":             extract,
@@ -252,6 +255,7 @@ var tokenMap = map[string]int{
 	"FIXED":               fixed,
 	"FLOAT":               floatType,
 	"FLUSH":               flush,
+	"CONFIG":              config,
 	"FOR":                 forKwd,
 	"FORCE":               force,
 	"FOREIGN":             foreign,
@@ -268,6 +272,7 @@ var tokenMap = map[string]int{
 	"GROUP":               group,
 	"GROUP_CONCAT":        groupConcat,
 	"HASH":                hash,
+	"HEADER":              header,
 	"HAVING":              having,
 	"HIGH_PRIORITY":       highPriority,
 	"HOUR":                hour,
@@ -275,6 +280,7 @@ var tokenMap = map[string]int{
 	"HOUR_MINUTE":         hourMinute,
 	"HOUR_SECOND":         hourSecond,
 	"IDENTIFIED":          identified,
+	"IMPORT":              importKwd,
 	"IF":                  ifKwd,
 	"IGNORE":              ignore,
 	"IN":                  in,
@@ -312,6 +318,7 @@ var tokenMap = map[string]int{
 	"LONGTEXT":            longtextType,
 	"LOW_PRIORITY":        lowPriority,
 	"MAX":                 max,
+	"MAX_ERRORS":          maxErrors,
 	"MAX_ROWS":            maxRows,
 	"MAXVALUE":            maxValue,
 	"MEDIUMBLOB":          mediumblobType,
@@ -341,10 +348,12 @@ var tokenMap = map[string]int{
 	"OFFSET":              offset,
 	"ON":                  on,
 	"ONLY":                only,
+	"OPEN":                open,
 	"OPTION":              option,
 	"OR":                  or,
 	"ORDER":               order,
 	"OUTER":               outer,
+	"OUTFILE":             outfile,
 	"PARTITION":           partition,
 	"PARTITIONS":          partitions,
 	"PASSWORD":            password,
@@ -354,9 +363,12 @@ var tokenMap = map[string]int{
 	"PREPARE":             prepare,
 	"PRIMARY":             primary,
 	"PRIVILEGES":          privileges,
+	"PERSIST":             persist,
 	"PROCEDURE":           procedure,
 	"PROCESS":             process,
 	"PROCESSLIST":         processlist,
+	"PROFILE":             profile,
+	"PROFILES":            profiles,
 	"QUARTER":             quarter,
 	"QUERY":               query,
 	"QUICK":               quick,
@@ -365,7 +377,9 @@ var tokenMap = map[string]int{
 	"PACK_KEYS":           packKeys,
 	"READ":                read,
 	"REAL":                realType,
+	"RECURSIVE":           recursive,
 	"REDUNDANT":           redundant,
+	"RESET":               reset,
 	"REFERENCES":          references,
 	"REGEXP":              regexpKwd,
 	"RENAME":              rename,
@@ -378,6 +392,7 @@ var tokenMap = map[string]int{
 	"RIGHT":               right,
 	"RLIKE":               rlike,
 	"ROLLBACK":            rollback,
+	"ROLLUP":              rollup,
 	"ROW":                 row,
 	"ROW_COUNT":           rowCount,
 	"ROW_FORMAT":          rowFormat,
@@ -402,6 +417,7 @@ var tokenMap = map[string]int{
 	"SQL_NO_CACHE":        sqlNoCache,
 	"START":               start,
 	"STARTING":            starting,
+	"STRAIGHT_JOIN":       straightJoin,
 	"STATS":               stats,
 	"STATS_BUCKETS":       statsBuckets,
 	"STATS_HISTOGRAMS":    statsHistograms,
@@ -0,0 +1,47 @@
+package parser
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Normalize returns sql with its literals replaced by ?, its keywords in
+// lower case and its tokens separated by single spaces, so that the
+// statements differing only in their constants normalize to the same text.
+func Normalize(sql string) string {
+	s := NewScanner(sql)
+	var buf strings.Builder
+	var v yySymType
+	for {
+		tok := s.Lex(&v)
+		if tok == 0 {
+			break
+		}
+		end := s.r.pos().Offset
+		if tok == unicode.ReplacementChar || tok == invalid {
+			if buf.Len() > 0 {
+				buf.WriteByte(' ')
+			}
+			buf.WriteString(strings.TrimSpace(sql[v.offset:]))
+			break
+		}
+		text := v.ident
+		if v.offset >= 0 && v.offset < end && end <= len(sql) {
+			text = sql[v.offset:end]
+		}
+		switch tok {
+		case intLit, floatLit, decLit, hexLit, bitLit, stringLit:
+			text = "?"
+		case identifier:
+		default:
+			if text != "" && unicode.IsLetter(rune(text[0])) {
+				text = strings.ToLower(text)
+			}
+		}
+		if buf.Len() > 0 {
+			buf.WriteByte(' ')
+		}
+		buf.WriteString(text)
+	}
+	return buf.String()
+}
@@ -27,11 +27,13 @@ package parser
 
 import (
 	"strings"
+	"unicode/utf8"
 
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
 	types "github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/parser/opcode"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/auth"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/charset"
 	"github.com/zhukovaskychina/xmysql-server/server/mysql"
 )
@@ -42,105 +44,129 @@ import (
 	offset int // offset
 	item interface{}
 	ident string
+	expr ast.ExprNode
+	statement ast.StmtNode
 }
 
+
+%token	<ident>
+	/*yy:token "%c"     */	identifier	"identifier"
+	underscoreCS	"UNDERSCORE_CHARSET"
+	/*yy:token "\"%c\"" */	stringLit	"string literal"
+	singleAtIdentifier
+	doubleAtIdentifier
+	invalid	"a special token never used by parser, used by lexer to indicate error"
+
+%token	<item>
+	hintComment
+
 %token	<ident>
-	/*yy:token "%c"     */	identifier      "identifier"
-	/*yy:token "\"%c\"" */	stringLit       "string literal"
-	invalid		"a special token never used by parser, used by lexer to indicate error"
-	andand		"&&"
-	oror		"||"
-
-	/* the following tokens belong to ReservedKeyword*/
-	add		"ADD"
-	all 		"ALL"
-	alter		"ALTER"
-	analyze		"ANALYZE"
-	and		"AND"
-	as		"AS"
-	asc		"ASC"
-	between		"BETWEEN"
+	andand	"&&"
+	oror	"||"
+	add	"ADD"
+	all	"ALL"
+	alter	"ALTER"
+	analyze	"ANALYZE"
+	and	"AND"
+	as	"AS"
+	asc	"ASC"
+	between	"BETWEEN"
 	bigIntType	"BIGINT"
 	binaryType	"BINARY"
 	blobType	"BLOB"
-	both		"BOTH"
-	by		"BY"
-	cascade		"CASCADE"
-	caseKwd		"CASE"
-	change          "CHANGE"
+	both	"BOTH"
+	by	"BY"
+	cascade	"CASCADE"
+	caseKwd	"CASE"
+	change	"CHANGE"
 	character	"CHARACTER"
 	charType	"CHAR"
-	check 		"CHECK"
-	collate 	"COLLATE"
-	column		"COLUMN"
+	check	"CHECK"
+	collate	"COLLATE"
+	column	"COLUMN"
 	constraint	"CONSTRAINT"
-	convert		"CONVERT"
-	create		"CREATE"
-	cross 		"CROSS"
-	currentDate 	"CURRENT_DATE"
-	currentTime 	"CURRENT_TIME"
+	convert	"CONVERT"
+	create	"CREATE"
+	cross	"CROSS"
+	currentDate	"CURRENT_DATE"
+	currentTime	"CURRENT_TIME"
 	currentTs	"CURRENT_TIMESTAMP"
 	currentUser	"CURRENT_USER"
 	database	"DATABASE"
 	databases	"DATABASES"
-	dayHour		"DAY_HOUR"
+	dayHour	"DAY_HOUR"
 	dayMicrosecond	"DAY_MICROSECOND"
 	dayMinute	"DAY_MINUTE"
-	daySecond 	"DAY_SECOND"
+	daySecond	"DAY_SECOND"
 	decimalType	"DECIMAL"
 	defaultKwd	"DEFAULT"
-	delayed		"DELAYED"
+	delayed	"DELAYED"
 	deleteKwd	"DELETE"
-	desc		"DESC"
+	desc	"DESC"
 	describe	"DESCRIBE"
 	distinct	"DISTINCT"
-	div 		"DIV"
+
+%token
+	distinctRow
+
+%token	<ident>
+	div	"DIV"
 	doubleType	"DOUBLE"
-	drop		"DROP"
-	dual 		"DUAL"
-	elseKwd		"ELSE"
+	drop	"DROP"
+	dual	"DUAL"
+	elseKwd	"ELSE"
 	enclosed	"ENCLOSED"
-	escaped 	"ESCAPED"
-	exists		"EXISTS"
-	explain		"EXPLAIN"
+	escaped	"ESCAPED"
+	exists	"EXISTS"
+	explain	"EXPLAIN"
 	falseKwd	"FALSE"
 	floatType	"FLOAT"
-	forKwd		"FOR"
-	force		"FORCE"
-	foreign		"FOREIGN"
-	from		"FROM"
+	forKwd	"FOR"
+	force	"FORCE"
+	foreign	"FOREIGN"
+	from	"FROM"
 	fulltext	"FULLTEXT"
-	grants		"GRANTS"
-	group		"GROUP"
-	having		"HAVING"
+
+%token
+	generated	"GENERATED"
+
+%token	<ident>
+	grant	"GRANT"
+	group	"GROUP"
+	having	"HAVING"
 	highPriority	"HIGH_PRIORITY"
 	hourMicrosecond	"HOUR_MICROSECOND"
 	hourMinute	"HOUR_MINUTE"
 	hourSecond	"HOUR_SECOND"
-	ifKwd		"IF"
-	ignore		"IGNORE"
-	in		"IN"
-	index		"INDEX"
-	infile		"INFILE"
-	inner 		"INNER"
+	ifKwd	"IF"
+	ignore	"IGNORE"
+	in	"IN"
+	index	"INDEX"
+	infile	"INFILE"
+	inner	"INNER"
 	integerType	"INTEGER"
 	interval	"INTERVAL"
-	into		"INTO"
-	is		"IS"
-	insert		"INSERT"
-	intType		"INT"
-	join		"JOIN"
-	key		"KEY"
-	keys		"KEYS"
-	leading		"LEADING"
-	left		"LEFT"
-	like		"LIKE"
-	limit		"LIMIT"
-	lines 		"LINES"
-	load		"LOAD"
+	into	"INTO"
+	is	"IS"
+	insert	"INSERT"
+	intType	"INT"
+	join	"JOIN"
+	key	"KEY"
+	keys	"KEYS"
+
+%token
+	kill	"KILL"
+
+%token	<ident>
+	leading	"LEADING"
+	left	"LEFT"
+	like	"LIKE"
+	limit	"LIMIT"
+	lines	"LINES"
+	load	"LOAD"
 	localTime	"LOCALTIME"
-	localTs		"LOCALTIMESTAMP"
-	lock		"LOCK"
+	localTs	"LOCALTIMESTAMP"
+	lock	"LOCK"
 	longblobType	"LONGBLOB"
 	longtextType	"LONGTEXT"
 	lowPriority	"LOW_PRIORITY"
@@ -149,610 +175,761 @@ import (
 	mediumIntType	"MEDIUMINT"
 	mediumtextType	"MEDIUMTEXT"
 	minuteMicrosecond	"MINUTE_MICROSECOND"
-	minuteSecond 		"MINUTE_SECOND"
-	mod 		"MOD"
-	not		"NOT"
-	noWriteToBinLog "NO_WRITE_TO_BINLOG"
-	null		"NULL"
+	minuteSecond	"MINUTE_SECOND"
+	mod	"MOD"
+	not	"NOT"
+	noWriteToBinLog	"NO_WRITE_TO_BINLOG"
+	null	"NULL"
 	numericType	"NUMERIC"
-	on		"ON"
-	option		"OPTION"
-	or		"OR"
-	order		"ORDER"
-	outer		"OUTER"
+
+%token
+	nvarcharType	"NVARCHAR"
+
+%token	<ident>
+	on	"ON"
+	option	"OPTION"
+	or	"OR"
+	order	"ORDER"
+	outer	"OUTER"
+
+%token
+	packKeys	"PACK_KEYS"
+
+%token	<ident>
 	partition	"PARTITION"
-	partitions	"PARTITIONS"
 	precisionType	"PRECISION"
-	primary		"PRIMARY"
+	primary	"PRIMARY"
 	procedure	"PROCEDURE"
+
+%token
+	shardRowIDBits	"SHARD_ROW_ID_BITS"
+
+%token	<ident>
 	rangeKwd	"RANGE"
-	read		"READ"
+	read	"READ"
 	realType	"REAL"
+	recursive	"RECURSIVE"
 	references	"REFERENCES"
 	regexpKwd	"REGEXP"
-	rename          "RENAME"
-	repeat		"REPEAT"
-	replace		"REPLACE"
+	rename	"RENAME"
+	repeat	"REPEAT"
+	replace	"REPLACE"
 	restrict	"RESTRICT"
-	right		"RIGHT"
-	rlike		"RLIKE"
-	schema		"SCHEMA"
-	schemas		"SCHEMAS"
+
+%token
+	revoke	"REVOKE"
+
+%token	<ident>
+	right	"RIGHT"
+	rlike	"RLIKE"
 	secondMicrosecond	"SECOND_MICROSECOND"
 	selectKwd	"SELECT"
-	set		"SET"
-	show		"SHOW"
+	set	"SET"
+	show	"SHOW"
 	smallIntType	"SMALLINT"
+
+%token
+	sqlCalcFoundRows	"SQL_CALC_FOUND_ROWS"
+
+%token	<ident>
 	starting	"STARTING"
+	straightJoin	"STRAIGHT_JOIN"
 	tableKwd	"TABLE"
+
+%token
+	stored	"STORED"
+
+%token	<ident>
 	terminated	"TERMINATED"
-	then		"THEN"
+	then	"THEN"
 	tinyblobType	"TINYBLOB"
 	tinyIntType	"TINYINT"
 	tinytextType	"TINYTEXT"
-	to		"TO"
+	to	"TO"
 	trailing	"TRAILING"
-	trueKwd		"TRUE"
-	unique		"UNIQUE"
-	union		"UNION"
-	unlock		"UNLOCK"
+
+%token
+	trigger	"TRIGGER"
+
+%token	<ident>
+	trueKwd	"TRUE"
+	unique	"UNIQUE"
+	union	"UNION"
+	unlock	"UNLOCK"
 	unsigned	"UNSIGNED"
-	update		"UPDATE"
-	use		"USE"
-	using		"USING"
-	utcDate 	"UTC_DATE"
-	values		"VALUES"
+	update	"UPDATE"
+	use	"USE"
+	using	"USING"
+	utcDate	"UTC_DATE"
+
+%token
+	utcTimestamp	"UTC_TIMESTAMP"
+	utcTime	"UTC_TIME"
+
+%token	<ident>
+	values	"VALUES"
 	varcharType	"VARCHAR"
 	varbinaryType	"VARBINARY"
-	when		"WHEN"
-	where		"WHERE"
-	write		"WRITE"
-	with		"WITH"
-	xor 		"XOR"
+
+%token
+	virtual	"VIRTUAL"
+
+%token	<ident>
+	when	"WHEN"
+	where	"WHERE"
+	write	"WRITE"
+	with	"WITH"
+	xor	"XOR"
 	yearMonth	"YEAR_MONTH"
 	zerofill	"ZEROFILL"
 
-	/* the following tokens belong to NotKeywordToken*/
-	abs		"ABS"
-	addDate		"ADDDATE"
-	admin		"ADMIN"
-	ceil		"CEIL"
-	ceiling		"CEILING"
-	coalesce	"COALESCE"
-	concat		"CONCAT"
-	concatWs	"CONCAT_WS"
-	connectionID 	"CONNECTION_ID"
-	curTime 	"CUR_TIME"
-	count		"COUNT"
-	day		"DAY"
-	datediff	"DATEDIFF"
-	dateAdd		"DATE_ADD"
-	dateFormat	"DATE_FORMAT"
-	dateSub		"DATE_SUB"
-	dayname		"DAYNAME"
-	dayofmonth	"DAYOFMONTH"
-	dayofweek	"DAYOFWEEK"
-	dayofyear	"DAYOFYEAR"
-	fromDays	"FROM_DAYS"
-	events		"EVENTS"
-	fieldKwd	"FIELD_KWD"
-	findInSet	"FIND_IN_SET"
-  	floor   	"FLOOR"
-	foundRows	"FOUND_ROWS"
-	fromUnixTime	"FROM_UNIXTIME"
-	grant		"GRANT"
-	groupConcat	"GROUP_CONCAT"
-	greatest	"GREATEST"
-	hour		"HOUR"
-	hex         	"HEX"
-	unhex         	"UNHEX"
-	ifNull		"IFNULL"
-	isNull		"ISNULL"
-	lastInsertID	"LAST_INSERT_ID"
-	lcase 		"LCASE"
-	length		"LENGTH"
-	least		"LEAST"
-	ln		"LN"
-	locate		"LOCATE"
-	log		"LOG"
-	log2		"LOG2"
-	log10		"LOG10"
-	lower 		"LOWER"
-	ltrim		"LTRIM"
-	max		"MAX"
-	microsecond	"MICROSECOND"
-	min		"MIN"
-	minute		"MINUTE"
-	nullIf		"NULLIF"
-	month		"MONTH"
-	monthname	"MONTHNAME"
-	now		"NOW"
-	pow 		"POW"
-	power 		"POWER"
-	rand		"RAND"
-	second		"SECOND"
-	sign		"SIGN"
-	sleep		"SLEEP"
-	sqrt 		"SQRT"
-	calcFoundRows	"SQL_CALC_FOUND_ROWS"
-	strcmp		"STRCMP"
-	strToDate	"STR_TO_DATE"
-	subDate		"SUBDATE"
-	substring	"SUBSTRING"
-	substringIndex	"SUBSTRING_INDEX"
-	sum		"SUM"
-	sysDate		"SYSDATE"
-	timediff	"TIMEDIFF"
-	trim		"TRIM"
-	rtrim 		"RTRIM"
-	ucase 		"UCASE"
-	unixTimestamp	"UNIX_TIMESTAMP"
-	upper 		"UPPER"
-	version		"VERSION"
-	weekday		"WEEKDAY"
-	weekofyear	"WEEKOFYEAR"
-	yearweek	"YEARWEEK"
-	round		"ROUND"
-	statsPersistent	"STATS_PERSISTENT"
-	getLock		"GET_LOCK"
-	releaseLock	"RELEASE_LOCK"
-	rpad		"RPAD"
-	bitLength	"BIT_LENGTH"
-	charFunc	"CHAR_FUNC"
-	charLength	"CHAR_LENGTH"
-	characterLength	"CHARACTER_LENGTH"
-	conv		"CONV"
-	bitXor		"BIT_XOR"
-	crc32		"CRC32"
-
-	/* the following tokens belong to UnReservedKeyword*/
-	action		"ACTION"
-	after		"AFTER"
-	any 		"ANY"
-	ascii		"ASCII"
-	at		"AT"
+%token
+	natural	"NATURAL"
+
+%token	<ident>
+	action	"ACTION"
+	after	"AFTER"
+
+%token
+	always	"ALWAYS"
+
+%token	<ident>
+	any	"ANY"
+	ascii	"ASCII"
 	autoIncrement	"AUTO_INCREMENT"
 	avgRowLength	"AVG_ROW_LENGTH"
-	avg		"AVG"
-	begin		"BEGIN"
-	binlog		"BINLOG"
-	bitType		"BIT"
+	avg	"AVG"
+	begin	"BEGIN"
+	binlog	"BINLOG"
+	bitType	"BIT"
 	booleanType	"BOOLEAN"
 	boolType	"BOOL"
-	btree		"BTREE"
+	btree	"BTREE"
 	byteType	"BYTE"
 	charsetKwd	"CHARSET"
 	checksum	"CHECKSUM"
+	coalesce	"COALESCE"
 	collation	"COLLATION"
-	columns		"COLUMNS"
-	comment 	"COMMENT"
-	commit		"COMMIT"
+	columns	"COLUMNS"
+	comment	"COMMENT"
+	commit	"COMMIT"
 	committed	"COMMITTED"
-	compact		"COMPACT"
+	compact	"COMPACT"
 	compressed	"COMPRESSED"
 	compression	"COMPRESSION"
-	connection 	"CONNECTION"
+	config	"CONFIG"
+	connection	"CONNECTION"
 	consistent	"CONSISTENT"
-	data 		"DATA"
+	csv	"CSV"
+	day	"DAY"
+	data	"DATA"
 	dateType	"DATE"
 	datetimeType	"DATETIME"
 	deallocate	"DEALLOCATE"
 	delayKeyWrite	"DELAY_KEY_WRITE"
-	disable		"DISABLE"
-	do		"DO"
+	delimiter	"DELIMITER"
+	disable	"DISABLE"
+	do	"DO"
 	duplicate	"DUPLICATE"
-	dynamic		"DYNAMIC"
-	enable		"ENABLE"
-	end		"END"
-	engine		"ENGINE"
-	engines		"ENGINES"
-	escape 		"ESCAPE"
-	execute		"EXECUTE"
-	fields		"FIELDS"
-	first		"FIRST"
-	fixed		"FIXED"
-	flush		"FLUSH"
-	full		"FULL"
+	dynamic	"DYNAMIC"
+	enable	"ENABLE"
+	end	"END"
+	engine	"ENGINE"
+	engines	"ENGINES"
+
+%token	<item>
+	enum	"ENUM"
+
+%token	<ident>
+	events	"EVENTS"
+	escape	"ESCAPE"
+
+%token
+	exclusive	"EXCLUSIVE"
+
+%token	<ident>
+	execute	"EXECUTE"
+	extended	"EXTENDED"
+	fields	"FIELDS"
+	first	"FIRST"
+	fixed	"FIXED"
+	flush	"FLUSH"
+
+%token
+	format	"FORMAT"
+
+%token	<ident>
+	full	"FULL"
 	function	"FUNCTION"
-	hash		"HASH"
+	grants	"GRANTS"
+	hash	"HASH"
+	header	"HEADER"
+	hour	"HOUR"
 	identified	"IDENTIFIED"
+	importKwd	"IMPORT"
 	isolation	"ISOLATION"
-	indexes		"INDEXES"
+	indexes	"INDEXES"
+
+%token
+	jsonType	"JSON"
+
+%token	<ident>
 	keyBlockSize	"KEY_BLOCK_SIZE"
-	local		"LOCAL"
-	less		"LESS"
-	level		"LEVEL"
-	mode		"MODE"
-	modify		"MODIFY"
-	maxRows		"MAX_ROWS"
-	minRows		"MIN_ROWS"
-	names		"NAMES"
+	local	"LOCAL"
+	less	"LESS"
+	level	"LEVEL"
+	microsecond	"MICROSECOND"
+	minute	"MINUTE"
+	mode	"MODE"
+	modify	"MODIFY"
+	month	"MONTH"
+	maxErrors	"MAX_ERRORS"
+	maxRows	"MAX_ROWS"
+	minRows	"MIN_ROWS"
+	names	"NAMES"
 	national	"NATIONAL"
-	no		"NO"
-	offset		"OFFSET"
-	only		"ONLY"
+	no	"NO"
+
+%token
+	none	"NONE"
+
+%token	<ident>
+	offset	"OFFSET"
+	only	"ONLY"
+	open	"OPEN"
 	password	"PASSWORD"
-	prepare		"PREPARE"
+	partitions	"PARTITIONS"
+	persist	"PERSIST"
+
+%token
+	plugins	"PLUGINS"
+
+%token	<ident>
+	prepare	"PREPARE"
 	privileges	"PRIVILEGES"
+
+%token
+	process	"PROCESS"
+
+%token	<ident>
 	processlist	"PROCESSLIST"
-	quarter		"QUARTER"
-	quick		"QUICK"
+	profile	"PROFILE"
+	profiles	"PROFILES"
+	quarter	"QUARTER"
+
+%token
+	query	"QUERY"
+
+%token	<ident>
+	quick	"QUICK"
 	redundant	"REDUNDANT"
 	repeatable	"REPEATABLE"
-	reverse		"REVERSE"
+	reset	"RESET"
+	reverse	"REVERSE"
 	rollback	"ROLLBACK"
-	row 		"ROW"
+	rollup	"ROLLUP"
+	row	"ROW"
+
+%token
+	rowCount	"ROW_COUNT"
+
+%token	<ident>
 	rowFormat	"ROW_FORMAT"
+	second	"SECOND"
+
+%token
+	separator	"SEPARATOR"
+
+%token	<ident>
 	serializable	"SERIALIZABLE"
-	session		"SESSION"
-	share		"SHARE"
-	signed		"SIGNED"
+	session	"SESSION"
+	share	"SHARE"
+
+%token
+	shared	"SHARED"
+
+%token	<ident>
+	signed	"SIGNED"
 	snapshot	"SNAPSHOT"
-	space 		"SPACE"
 	sqlCache	"SQL_CACHE"
 	sqlNoCache	"SQL_NO_CACHE"
-	start		"START"
-	status		"STATUS"
-	some 		"SOME"
-	global		"GLOBAL"
-	tables		"TABLES"
+	start	"START"
+	statsPersistent	"STATS_PERSISTENT"
+	status	"STATUS"
+
+%token
+	super	"SUPER"
+
+%token	<ident>
+	some	"SOME"
+	global	"GLOBAL"
+	tables	"TABLES"
 	textType	"TEXT"
-	than		"THAN"
+	than	"THAN"
 	timeType	"TIME"
 	timestampType	"TIMESTAMP"
-	timestampDiff	"TIMESTAMPDIFF"
 	transaction	"TRANSACTION"
 	triggers	"TRIGGERS"
 	truncate	"TRUNCATE"
 	uncommitted	"UNCOMMITTED"
-	unknown 	"UNKNOWN"
-	user		"USER"
-	value		"VALUE"
+	unknown	"UNKNOWN"
+	user	"USER"
+	value	"VALUE"
 	variables	"VARIABLES"
-	view		"VIEW"
+	view	"VIEW"
 	warnings	"WARNINGS"
-	week		"WEEK"
+	week	"WEEK"
 	yearType	"YEAR"
+	addDate	"ADDDATE"
+	bitXor	"BIT_XOR"
+
+%token	<item>
+	cast	"CAST"
+
+%token	<ident>
+	count	"COUNT"
+	curTime	"CUR_TIME"
+	dateAdd	"DATE_ADD"
+	dateSub	"DATE_SUB"
+	extract	"EXTRACT"
+	getFormat	"GET_FORMAT"
+	groupConcat	"GROUP_CONCAT"
+	min	"MIN"
+	max	"MAX"
+	now	"NOW"
+	position	"POSITION"
+	subDate	"SUBDATE"
+	sum	"SUM"
+	substring	"SUBSTRING"
+	timestampAdd	"TIMESTAMPADD"
+	timestampDiff	"TIMESTAMPDIFF"
+	trim	"TRIM"
+	admin	"ADMIN"
+
+%token
+	cancel	"CANCEL"
 
 %token	<item>
+	ddl	"DDL"
+
+%token
+	jobs	"JOBS"
+	stats	"STATS"
+	statsMeta	"STATS_META"
+	statsHistograms	"STATS_HISTOGRAMS"
+	statsBuckets	"STATS_BUCKETS"
+	tidb	"TIDB"
 
-	/*yy:token "1.%d"   */	floatLit        "floating-point literal"
-	/*yy:token "1.%d"   */	decLit          "decimal literal"
-	/*yy:token "%d"     */	intLit          "integer literal"
-	/*yy:token "%x"     */	hexLit          "hexadecimal literal"
-	/*yy:token "%b"     */	bitLit          "bit literal"
+%token	<ident>
+	tidbSMJ	"TIDB_SMJ"
+	tidbINLJ	"TIDB_INLJ"
 
-	andnot		"&^"
+%token	<item>
+	/*yy:token "1.%d"   */	floatLit	"floating-point literal"
+	/*yy:token "1.%d"   */	decLit	"decimal literal"
+	/*yy:token "%d"     */	intLit	"integer literal"
+	/*yy:token "%x"     */	hexLit	"hexadecimal literal"
+	/*yy:token "%b"     */	bitLit	"bit literal"
+	andnot	"&^"
 	assignmentEq	":="
-	cast		"CAST"
-	curDate 	"CURDATE"
-	ddl		"DDL"
-	enum 		"ENUM"
-	eq		"="
-	extract		"EXTRACT"
-
-	ge		">="
-	le		"<="
-	lsh		"<<"
-	neq		"!="
+	eq	"="
+	ge	">="
+	le	"<="
+
+%token
+	jss
+	juss
+
+%token	<item>
+	lsh	"<<"
+	neq	"!="
 	neqSynonym	"<>"
-	nulleq		"<=>"
-	placeholder	"PLACEHOLDER"
-	rsh		">>"
-	sysVar		"SYS_VAR"
-	underscoreCS	"UNDERSCORE_CHARSET"
-	userVar		"USER_VAR"
+	nulleq	"<=>"
+
+%token
+	paramMarker
+
+%token	<item>
+	rsh	">>"
+
+%token
+	empty
+	lowerThanIntervalKeyword
+	lowerThanStringLitToken
+	lowerThanSetKeyword
+	lowerThanInsertValues
+	insertValues
+	lowerThanKey
+	tableRefPriority
+	lowerThanOn
+	lowerThanEq
+	neg
+	lowerThanComma
+
+%token	<ident>
+	outfile	"OUTFILE"
 
-%type   <item>
-	AdminStmt		"Check table statement or show ddl statement"
-	AlterTableStmt		"Alter table statement"
-	AlterTableSpec		"Alter table specification"
+%type	<item>
+	AlterTableSpec	"Alter table specification"
 	AlterTableSpecList	"Alter table specification list"
-	AlterUserStmt		"Alter user statement"
-	AnalyzeTableStmt	"Analyze table statement"
-	AnyOrAll		"Any or All for subquery"
-	Assignment		"assignment"
-	AssignmentList		"assignment list"
+	AnyOrAll	"Any or All for subquery"
+	Assignment	"assignment"
+	AssignmentList	"assignment list"
 	AssignmentListOpt	"assignment list opt"
-	AuthOption		"User auth option"
-	AuthString		"Password string value"
-	BeginTransactionStmt	"BEGIN TRANSACTION statement"
-	BinlogStmt		"Binlog base64 statement"
-	CastType		"Cast function target type"
-	CharsetName		"Character set name"
-	ColumnDef		"table column definition"
-	ColumnName		"column name"
-	ColumnNameList		"column name list"
+	AuthOption	"User auth option"
+	AuthString	"Password string value"
+	BetweenOrNotOp
+	BitValueType	"bit value types"
+	BlobType	"Blob types"
+	BuggyDefaultFalseDistinctOpt
+	ByItem	"BY item"
+	ByList	"BY list"
+	CastType	"Cast function target type"
+	CharsetName	"Character set name"
+	ColumnDef	"table column definition"
+	ColumnName	"column name"
+	ColumnNameList	"column name list"
 	ColumnNameListOpt	"column name list opt"
-	ColumnSetValue		"insert statement set value by column name"
-	ColumnSetValueList	"insert statement set value by column name list"
-	CommitStmt		"COMMIT statement"
-	CompareOp		"Compare opcode"
-	ColumnOption		"column definition option"
+	ColumnNameListOptWithBrackets
+	ColumnOption	"column definition option"
 	ColumnOptionList	"column definition option list"
 	ColumnOptionListOpt	"optional column definition option list"
-	Constraint		"table constraint"
-	ConstraintElem		"table constraint element"
+	ColumnPosition	"Column position [First|After ColumnName]"
+	ColumnSetValue	"insert statement set value by column name"
+	ColumnSetValueList	"insert statement set value by column name list"
+	CommonTableExpr	"Common table expression"
+	CommonTableExprList	"Common table expression list"
+	CompareOp	"Compare opcode"
+	Constraint	"table constraint"
+	ConstraintElem	"table constraint element"
 	ConstraintKeywordOpt	"Constraint Keyword or empty"
-	CreateDatabaseStmt	"Create Database Statement"
-	CreateIndexStmt		"CREATE INDEX statement"
 	CreateIndexStmtUnique	"CREATE INDEX optional UNIQUE clause"
-	DatabaseOption		"CREATE Database specification"
+	DBName	"Database Name"
+	DatabaseOption	"CREATE Database specification"
 	DatabaseOptionList	"CREATE Database specification list"
 	DatabaseOptionListOpt	"CREATE Database specification list opt"
-	CreateTableStmt		"CREATE TABLE statement"
-	CreateUserStmt		"CREATE User statement"
-	DBName			"Database Name"
-	DeallocateStmt		"Deallocate prepared statement"
-	Default			"DEFAULT clause"
-	DefaultOpt		"optional DEFAULT clause"
-	DefaultValueExpr	"DefaultValueExpr(Now or Signed Literal)"
-	DeleteFromStmt		"DELETE FROM statement"
-	DistinctOpt		"Distinct option"
-	DoStmt			"Do statement"
-	DropDatabaseStmt	"DROP DATABASE statement"
-	DropIndexStmt		"DROP INDEX statement"
-	DropTableStmt		"DROP TABLE statement"
-	DropUserStmt		"DROP USER"
-	DropViewStmt		"DROP VIEW statement"
-	EmptyStmt		"empty statement"
-	Enclosed		"Enclosed by"
-	EqOpt			"= or empty"
-	EscapedTableRef 	"escaped table reference"
-	Escaped			"Escaped by"
-	ExecuteStmt		"Execute statement"
-	ExplainStmt		"EXPLAIN statement"
-	Expression		"expression"
-	ExpressionList		"expression list"
+	DateAndTimeType	"Date and Time types"
+	DefaultFalseDistinctOpt
+	DefaultTrueDistinctOpt
+	DistinctKwd
+	DistinctOpt	"Distinct option"
+	ElseOpt	"Optional else clause"
+	Enclosed	"Enclosed by"
+	EqOpt	"= or empty"
+	Escaped	"Escaped by"
+	EscapedTableRef	"escaped table reference"
+	ExpressionList	"expression list"
 	ExpressionListOpt	"expression list opt"
-	ExpressionListList	"expression list list"
-	ExpressionListListItem	"expression list list item"
-	Factor			"expression factor"
-	PredicateExpr		"Predicate expression factor"
-	Field			"field expression"
-	Fields			"Fields clause"
+	Field	"field expression"
+	FieldAsName	"Field alias name"
+	FieldAsNameOpt	"Field alias name opt"
+	FieldLen	"Field length"
+	FieldList	"field expression list"
+	FieldOpt	"Field type definition option"
+	FieldOpts	"Field type definition option list"
+	Fields	"Fields clause"
+	ImportOptionList	"IMPORT TABLE options"
 	FieldsTerminated	"Fields terminated by"
-	FieldAsName		"Field alias name"
-	FieldAsNameOpt		"Field alias name opt"
-	FieldList		"field expression list"
-	FlushStmt		"Flush statement"
-	TableRefsClause		"Table references clause"
-	Function		"function expr"
-	FunctionCallAgg		"Function call on aggregate data"
-	FunctionCallConflict	"Function call with reserved keyword as function name"
-	FunctionCallKeyword	"Function call with keyword as function name"
-	FunctionCallNonKeyword	"Function call with nonkeyword as function name"
+	FixedPointType	"Exact value types"
+	FloatOpt	"Floating-point type option"
+	FloatingPointType	"Approximate value types"
+	FlushOption
+	FromDual
 	FuncDatetimePrec	"Function datetime precision"
-	GlobalScope		"The scope of variable"
-	GrantStmt		"Grant statement"
-	GroupByClause		"GROUP BY clause"
-	HashString		"Hashed string"
-	HavingClause		"HAVING clause"
-	IfExists		"If Exists"
-	IfNotExists		"If Not Exists"
-	IgnoreOptional		"IGNORE or empty"
-	IndexColName		"Index column name"
+	FuncDatetimePrecList
+	FuncDatetimePrecListOpt
+	GeneratedAlways
+	GlobalScope	"The scope of variable"
+	GroupByClause	"GROUP BY clause"
+	HashString	"Hashed string"
+	HavingClause	"HAVING clause"
+	IfExists	"If Exists"
+	IfNotExists	"If Not Exists"
+	IgnoreOptional	"IGNORE or empty"
+	InOrNotOp
+	IndexColName	"Index column name"
 	IndexColNameList	"List of index column name"
-	IndexHint		"index hint"
-	IndexHintList		"index hint list"
+	IndexHint	"index hint"
+	IndexHintList	"index hint list"
 	IndexHintListOpt	"index hint list opt"
-	IndexHintScope		"index hint scope"
-	IndexHintType		"index hint type"
-	IndexName		"index name"
-	IndexNameList		"index name list"
-	IndexOption		"Index Option"
-	IndexOptionList		"Index Option List or empty"
-	IndexType		"index type"
-	IndexTypeOpt		"Optional index type"
-	InsertIntoStmt		"INSERT INTO statement"
-	InsertValues		"Rest part of INSERT/REPLACE INTO statement"
-	JoinTable 		"join table"
-	JoinType		"join type"
-	LikeEscapeOpt 		"like escape option"
-	LimitClause		"LIMIT clause"
-	LimitOption		"Limit option could be integer or parameter marker."
-	Lines			"Lines clause"
-	LinesTerminated		"Lines terminated by"
-	Literal			"literal value"
-	LoadDataStmt		"Load data statement"
-	LocalOpt		"Local opt"
-	LockTablesStmt		"Lock tables statement"
+	IndexHintScope	"index hint scope"
+	IndexHintType	"index hint type"
+	IndexName	"index name"
+	IndexNameList	"index name list"
+	IndexOption	"Index Option"
+	IndexOptionList	"Index Option List or empty"
+	IndexType	"index type"
+	IndexTypeOpt	"Optional index type"
+	InsertValues	"Rest part of INSERT/REPLACE INTO statement"
+	IntegerType	"Integer Types types"
+	IsOrNotOp
+	JoinTable	"join table"
+	JoinType	"join type"
+	KeyOrIndexOpt
+	KillOrKillTiDB
+	LengthNum	"Field length num(uint64)"
+	LikeEscapeOpt	"like escape option"
+	LikeOrNotOp
+	LimitClause	"LIMIT clause"
+	LimitOption	"Limit option could be integer or parameter marker."
+	Lines	"Lines clause"
+	LinesTerminated	"Lines terminated by"
+	LocalOpt	"Local opt"
+	LockClause
+	LockClauseOpt
+	LockTablesStmt	"Lock tables statement"
 	LowPriorityOptional	"LOW_PRIORITY or empty"
-	NotOpt			"optional NOT"
-	NumLiteral		"Num/Int/Float/Decimal Literal"
-	NoWriteToBinLogAliasOpt "NO_WRITE_TO_BINLOG alias LOCAL or empty"
-	ObjectType		"Grant statement object type"
+	NUM	"numbers"
+	NoWriteToBinLogAliasOpt	"NO_WRITE_TO_BINLOG alias LOCAL or empty"
+	NowSymFunc
+	NumList
+	NumLiteral	"Num/Int/Float/Decimal Literal"
+	NumericType	"Numeric types"
+	ObjectType	"Grant statement object type"
+	OnDeleteOpt	"optional ON DELETE clause"
 	OnDuplicateKeyUpdate	"ON DUPLICATE KEY UPDATE value list"
-	Operand			"operand"
-	OptFull			"Full or empty"
-	Order			"ORDER BY clause optional collation specification"
-	OrderBy			"ORDER BY clause"
-	ByItem			"BY item"
-	OrderByOptional		"Optional ORDER BY clause optional"
-	ByList			"BY list"
-	QuickOptional		"QUICK or empty"
+	OnUpdateOpt	"optional ON UPDATE clause"
+	OptBinary	"Optional BINARY"
+	OptCharset	"Optional Character setting"
+	OptCollate	"Optional Collate setting"
+	OptFieldLen	"Field length or empty"
+	OptFull	"Full or empty"
+	OrReplace	"Optional OR REPLACE"
+	OptGConcatSeparator
+	OptionalBraces
+	Order	"ORDER BY clause optional collation specification"
+	OrderBy	"ORDER BY clause"
+	OrderByOptional	"Optional ORDER BY clause optional"
+	PartDefStorageOpt
+	PartDefValuesOpt
 	PartitionDefinition	"Partition definition"
-	PartitionDefinitionList "Partition definition list"
+	PartitionDefinitionList	"Partition definition list"
 	PartitionDefinitionListOpt	"Partition definition list option"
-	PartitionOpt		"Partition option"
-	PartitionNumOpt		"PARTITION NUM option"
-	PasswordOpt		"Password option"
-	ColumnPosition		"Column position [First|After ColumnName]"
-	PreparedStmt		"PreparedStmt"
-	PrepareSQL		"Prepare statement sql string"
-	PrimaryExpression	"primary expression"
-	PrimaryFactor		"primary expression factor"
-	Priority		"insert statement priority"
-	PrivElem		"Privilege element"
-	PrivElemList		"Privilege element list"
-	PrivLevel		"Privilege scope"
-	PrivType		"Privilege type"
-	ReferDef		"Reference definition"
-	OnDeleteOpt		"optional ON DELETE clause"
-	OnUpdateOpt		"optional ON UPDATE clause"
-	ReferOpt		"reference option"
-	RenameTableStmt         "rename table statement"
-	ReplaceIntoStmt		"REPLACE INTO statement"
-	ReplacePriority		"replace statement priority"
-	RollbackStmt		"ROLLBACK statement"
-	RowFormat		"Row format option"
-	SelectLockOpt		"FOR UPDATE or LOCK IN SHARE MODE,"
-	SelectStmt		"SELECT statement"
+	PartitionNumOpt	"PARTITION NUM option"
+	PartitionOpt	"Partition option"
+	PasswordOpt	"Password option"
+	Precision	"Floating-point precision option"
+	PrepareSQL	"Prepare statement sql string"
+	Priority	"insert statement priority"
+	PrivElem	"Privilege element"
+	PrivElemList	"Privilege element list"
+	PrivLevel	"Privilege scope"
+	PrivType	"Privilege type"
+	QuickOptional	"QUICK or empty"
+	ChecksumTableOpt	"QUICK, EXTENDED or empty"
+	ReferDef	"Reference definition"
+	ReferOpt	"reference option"
+	RegexpOrNotOp
+	ReplacePriority	"replace statement priority"
+	RowFormat	"Row format option"
+	RowValue
+	SelectLockOpt	"FOR UPDATE or LOCK IN SHARE MODE,"
 	SelectStmtCalcFoundRows	"SELECT statement optional SQL_CALC_FOUND_ROWS"
-	SelectStmtSQLCache	"SELECT statement optional SQL_CAHCE/SQL_NO_CACHE"
-	SelectStmtDistinct	"SELECT statement optional DISTINCT clause"
 	SelectStmtFieldList	"SELECT statement field list"
-	SelectStmtLimit		"SELECT statement optional LIMIT clause"
-	SelectStmtOpts		"Select statement options"
-	SelectStmtGroup		"SELECT statement optional GROUP BY clause"
-	SetStmt			"Set variable statement"
-	ShowStmt		"Show engines/databases/tables/columns/warnings/status statement"
-	ShowTargetFilterable    "Show target that can be filtered by WHERE or LIKE"
+	SelectStmtGroup	"SELECT statement optional GROUP BY clause"
+	SelectStmtIntoOption	"SELECT statement optional INTO clause"
+	SelectStmtLimit	"SELECT statement optional LIMIT clause"
+	SelectStmtOpts	"Select statement options"
+	SelectStmtSQLCache	"SELECT statement optional SQL_CAHCE/SQL_NO_CACHE"
+	SelectStmtStraightJoin	"SELECT statement optional STRAIGHT_JOIN"
 	ShowDatabaseNameOpt	"Show tables/columns statement database name option"
-	ShowTableAliasOpt       "Show table alias option"
 	ShowLikeOrWhereOpt	"Show like or where clause option"
-	SignedLiteral		"Literal or NumLiteral with sign"
-	Starting		"Starting by"
-	Statement		"statement"
-	StatementList		"statement list"
+	ShowTableAliasOpt	"Show table alias option"
+	ShowTargetFilterable	"Show target that can be filtered by WHERE or LIKE"
+	Start
+	Starting	"Starting by"
+	StatementList	"statement list"
 	StatsPersistentVal	"stats_persistent value"
-	StringName		"string literal or identifier"
-	StringList 		"string list"
-	ExplainableStmt		"explainable statement"
-	SubSelect		"Sub Select"
-	Symbol			"Constraint Symbol"
-	SystemVariable		"System defined variable name"
-	TableAsName		"table alias name"
-	TableAsNameOpt 		"table alias name optional"
-	TableElement		"table definition element"
+	StringList	"string list"
+	StringName	"string literal or identifier"
+	StringType	"String types"
+	Symbol	"Constraint Symbol"
+	TableAsName	"table alias name"
+	TableAsNameOpt	"table alias name optional"
+	TableElement	"table definition element"
 	TableElementList	"table definition element list"
-	TableFactor 		"table factor"
-	TableLock		"Table name and lock type"
-	TableLockList		"Table lock list"
-	TableName		"Table name"
-	TableNameList		"Table name list"
+	TableFactor	"table factor"
+	TableLock	"Table name and lock type"
+	TableLockList	"Table lock list"
+	TableName	"Table name"
+	TableNameList	"Table name list"
 	TableNameListOpt	"Table name list opt"
-	TableOption		"create table option"
-	TableOptionList		"create table option list"
+	TableOptimizerHints
+	TableOption	"create table option"
+	TableOptionList	"create table option list"
 	TableOptionListOpt	"create table option list opt"
-	TableRef 		"table reference"
-	TableRefs 		"table references"
-	TrimDirection		"Trim string direction"
-	TruncateTableStmt	"TRANSACTION TABLE statement"
-	UnionOpt		"Union Option(empty/ALL/DISTINCT)"
-	UnionStmt		"Union select state ment"
-	UnionClauseList		"Union select clause list"
-	UnionSelect		"Union (select) item"
+	TableOrTables
+	TableRef	"table reference"
+	TableRefs	"table references"
+	TableRefsClause	"Table references clause"
+	TableToTable
+	TableToTableList
+	TablesTerminalSym
+	TextType	"Text types"
+	TiDBKeyword
+	TransactionChar	"Transaction characteristic"
+	TransactionChars	"Transaction characteristic list"
+	TrimDirection	"Trim string direction"
+	Type	"Types"
+	UnionClauseList	"Union select clause list"
+	UnionOpt	"Union Option(empty/ALL/DISTINCT)"
+	UnionSelect	"Union (select) item"
 	UnlockTablesStmt	"Unlock tables statement"
-	UpdateStmt		"UPDATE statement"
-	Username		"Username"
-	UsernameList		"UsernameList"
-	UserSpec		"Username and auth option"
-	UserSpecList		"Username and auth option list"
-	UserVariable		"User defined variable name"
+	UserSpec	"Username and auth option"
+	UserSpecList	"Username and auth option list"
 	UserVariableList	"User defined variable name list"
-	UseStmt			"USE statement"
+	Username	"Username"
+	UsernameList	"UsernameList"
+	Values
+	ValuesList
+	ValuesOpt
+	Varchar
+	Variable	"User or system variable"
 	VariableAssignment	"set variable value"
 	VariableAssignmentList	"set variable value list"
-	Variable		"User or system variable"
-	WhereClause		"WHERE clause"
+	ViewFieldList	"View column name list"
+	ViewFieldListOpt	"Optional view column name list"
+	VirtualOrStored
+	WhenClause	"When clause"
+	WhenClauseList	"When clause list"
+	WhereClause	"WHERE clause"
 	WhereClauseOptional	"Optinal WHERE clause"
-	WhenClause		"When clause"
-	WhenClauseList		"When clause list"
-	WithReadLockOpt		"With Read Lock opt"
-	ElseOpt			"Optional else clause"
-	ExpressionOpt		"Optional expression"
-	Type			"Types"
-
-	NumericType		"Numeric types"
-	IntegerType		"Integer Types types"
-	FixedPointType		"Exact value types"
-	FloatingPointType	"Approximate value types"
-	BitValueType		"bit value types"
-	StringType		"String types"
-	BlobType		"Blob types"
-	TextType		"Text types"
-	DateAndTimeType		"Date and Time types"
-
-	OptFieldLen		"Field length or empty"
-	FieldLen		"Field length"
-	FieldOpts		"Field type definition option list"
-	FieldOpt		"Field type definition option"
-	FloatOpt		"Floating-point type option"
-	Precision		"Floating-point precision option"
-	OptBinary		"Optional BINARY"
-	OptCharset		"Optional Character setting"
-	OptCollate		"Optional Collate setting"
-	NUM			"numbers"
-	LengthNum		"Field length num(uint64)"
+	WithClause	"WITH clause"
+	WithGrantOptionOpt
+	WithReadLockOpt	"With Read Lock opt"
 
 %type	<ident>
-	KeyOrIndex		"{KEY|INDEX}"
+	CharsetKw	"charset or charater set"
 	ColumnKeywordOpt	"Column keyword or empty"
-	PrimaryOpt		"Optional primary keyword"
-	NowSym			"CURRENT_TIMESTAMP/LOCALTIME/LOCALTIMESTAMP/NOW"
-	DefaultKwdOpt		"optional DEFAULT keyword"
-	DatabaseSym		"DATABASE or SCHEMA"
-	ExplainSym		"EXPLAIN or DESCRIBE or DESC"
-	RegexpSym		"REGEXP or RLIKE"
-	IntoOpt			"INTO or EmptyString"
-	ValueSym		"Value or Values"
-	TimeUnit		"Time unit"
-	DeallocateSym		"Deallocate or drop"
-	OuterOpt		"optional OUTER clause"
-	CrossOpt		"Cross join option"
-	TransactionChar		"Transaction characteristic"
-	TransactionChars	"Transaction characteristic list"
-	IsolationLevel		"Isolation level"
-	ShowIndexKwd		"Show index/indexs/key keyword"
-	FromOrIn		"From or In"
-	OptTable		"Optional table keyword"
-	OptInteger		"Optional Integer keyword"
-	NationalOpt		"National option"
-	CharsetKw		"charset or charater set"
-	CommaOpt		"optional comma"
-	LockType		"Table locks type"
-	logAnd			"logical and operator"
-	logOr			"logical or operator"
-	FieldsOrColumns 	"Fields or columns"
-
-%type	<ident>
-	Identifier			"identifier or unreserved keyword"
-	IdentifierOrReservedKeyword	"Identifier or ReservedKeyword"
-	NotKeywordToken			"Tokens not mysql keyword but treated specially"
-	UnReservedKeyword		"MySQL unreserved keywords"
-	ReservedKeyword			"MySQL reserved keywords"
-	FunctionNameConflict		"Built-in function call names which are conflict with keywords"
-	FunctionNameDateArith		"Date arith function call names (date_add or date_sub)"
+	CommaOpt	"optional comma"
+	CrossOpt	"Cross join option"
+	DatabaseSym	"DATABASE or SCHEMA"
+	DeallocateSym	"Deallocate or drop"
+	DefaultKwdOpt	"optional DEFAULT keyword"
+	ExplainSym	"EXPLAIN or DESCRIBE or DESC"
+	FieldsOrColumns	"Fields or columns"
+	FromOrIn	"From or In"
+	FunctionNameConflict	"Built-in function call names which are conflict with keywords"
+	FunctionNameDateArith	"Date arith function call names (date_add or date_sub)"
 	FunctionNameDateArithMultiForms	"Date arith function call names (adddate or subdate)"
+	FunctionNameDatetimePrecision
+	FunctionNameOptionalBraces
+	GetFormatSelector
+	Identifier	"identifier or unreserved keyword"
+	IndexHintName	"index name or PRIMARY"
+	IntoOpt	"INTO or EmptyString"
+	IsolationLevel	"Isolation level"
+	KeyOrIndex	"{KEY|INDEX}"
+	LockType	"Table locks type"
+	NationalOpt	"National option"
+	NotKeywordToken	"Tokens not mysql keyword but treated specially"
+	NowSym	"CURRENT_TIMESTAMP/LOCALTIME/LOCALTIMESTAMP/NOW"
+	OptInteger	"Optional Integer keyword"
+	OptTable	"Optional table keyword"
+	OuterOpt	"optional OUTER clause"
+	PrimaryOpt	"Optional primary keyword"
+	RegexpSym	"REGEXP or RLIKE"
+	ShowIndexKwd	"Show index/indexs/key keyword"
+	TimeUnit	"Time unit"
+	TimestampUnit
+	UnReservedKeyword	"MySQL unreserved keywords"
+	ValueSym	"Value or Values"
+	logAnd
+	logOr
+
+%type	<expr>
+	BitExpr
+	BoolPri
+	DefaultValueExpr	"DefaultValueExpr(Now or Signed Literal)"
+	ExprOrDefault
+	Expression	"expression"
+	ExpressionOpt	"Optional expression"
+	FunctionCallGeneric
+	FunctionCallKeyword	"Function call with keyword as function name"
+	FunctionCallNonKeyword	"Function call with nonkeyword as function name"
+	Literal	"literal value"
+	NowSymOptionFraction
+	PredicateExpr	"Predicate expression factor"
+	SetExpr
+	SignedLiteral	"Literal or NumLiteral with sign"
+	SimpleExpr
+	SimpleIdent
+	StringLiteral
+	SubSelect	"Sub Select"
+	SumExpr
+	SystemVariable	"System defined variable name"
+	UserVariable	"User defined variable name"
+
+%type	<statement>
+	AdminStmt	"Check table statement or show ddl statement"
+	AlterTableStmt	"Alter table statement"
+	AlterUserStmt	"Alter user statement"
+	AnalyzeTableStmt	"Analyze table statement"
+	BeginTransactionStmt	"BEGIN TRANSACTION statement"
+	BinlogStmt	"Binlog base64 statement"
+	ChecksumTableStmt	"CHECKSUM TABLE statement"
+	CommitStmt	"COMMIT statement"
+	CreateDatabaseStmt	"Create Database Statement"
+	CreateIndexStmt	"CREATE INDEX statement"
+	CreateTableStmt	"CREATE TABLE statement"
+	CreateViewStmt	"CREATE VIEW statement"
+	CreateUserStmt	"CREATE User statement"
+	DeallocateStmt	"Deallocate prepared statement"
+	DeleteFromStmt	"DELETE FROM statement"
+	DoStmt	"Do statement"
+	DropDatabaseStmt	"DROP DATABASE statement"
+	DropIndexStmt	"DROP INDEX statement"
+	DropStatsStmt
+	DropTableStmt	"DROP TABLE statement"
+	DropUserStmt	"DROP USER"
+	DropViewStmt	"DROP VIEW statement"
+	EmptyStmt	"empty statement"
+	ExecuteStmt	"Execute statement"
+	ExplainStmt	"EXPLAIN statement"
+	ExplainableStmt	"explainable statement"
+	FlushStmt	"Flush statement"
+	GrantStmt	"Grant statement"
+	InsertIntoStmt	"INSERT INTO statement"
+	ImportTableStmt	"IMPORT TABLE statement"
+	KillStmt
+	LoadDataStmt	"Load data statement"
+	PreparedStmt	"PreparedStmt"
+	RenameTableStmt	"rename table statement"
+	ResetPersistStmt	"RESET PERSIST statement"
+	ReplaceIntoStmt	"REPLACE INTO statement"
+	RevokeStmt
+	RollbackStmt	"ROLLBACK statement"
+	SelectStmt	"SELECT statement"
+	SetStmt	"Set variable statement"
+	ShowStmt	"Show engines/databases/tables/columns/warnings/status statement"
+	Statement	"statement"
+	TruncateTableStmt	"TRANSACTION TABLE statement"
+	UnionStmt	"Union select state ment"
+	UpdateStmt	"UPDATE statement"
+	UseStmt	"USE statement"
+	ViewSelectStmt	"SELECT or UNION statement of a view"
+	WithSelectStmt	"SELECT or UNION statement with a WITH clause"
 
-%precedence lowestOpt
-%token	tableRefPriority
-
-%precedence lowerThanCalcFoundRows
-%precedence calcFoundRows
+%precedence empty
 
-%precedence lowerThanSQLCache
 %precedence sqlCache sqlNoCache
-
 %precedence lowerThanIntervalKeyword
 %precedence interval
-
+%precedence lowerThanStringLitToken
+%precedence stringLit
 %precedence lowerThanSetKeyword
 %precedence set
-
 %precedence lowerThanInsertValues
 %precedence insertValues
-
 %precedence lowerThanKey
 %precedence key
 
-%left   join inner cross left right full
+%left   join straightJoin inner cross left right full natural
 /* A dummy token to force the priority of TableRef production in a join. */
 %left   tableRefPriority
 %precedence lowerThanOn
-%precedence on
-%right  assignmentEq
+%precedence on using
+%right   assignmentEq
 %left 	oror or
 %left 	xor
 %left 	andand and
@@ -769,23 +946,11 @@ import (
 %right 	not
 %right	collate
 
-%precedence lowerThanLeftParen
 %precedence '('
-%precedence lowerThanQuick
 %precedence quick
-%precedence lowerThanEscape
 %precedence escape
 %precedence lowerThanComma
 %precedence ','
-%precedence lowerThanWith
-%precedence with
-%precedence lowerThanInto
-%precedence into
-%precedence lowerThanIf
-%precedence ifKwd
-%precedence lowerThanIgnore
-%precedence ignore
-%precedence tableKwd
 
 %start	Start
 
@@ -810,30 +975,38 @@ AlterTableSpec:
 	TableOptionListOpt
 	{
 		$$ = &ast.AlterTableSpec{
-			Tp:	ast.AlterTableOption,
-			Options:$1.([]*ast.TableOption),
+			Tp:      ast.AlterTableOption,
+			Options: $1.([]*ast.TableOption),
 		}
 	}
 |	"ADD" ColumnKeywordOpt ColumnDef ColumnPosition
 	{
 		$$ = &ast.AlterTableSpec{
-			Tp: 		ast.AlterTableAddColumn,
-			NewColumn:	$3.(*ast.ColumnDef),
-			Position:	$4.(*ast.ColumnPosition),
+			Tp:        ast.AlterTableAddColumn,
+			NewColumn: $3.(*ast.ColumnDef),
+			Position:  $4.(*ast.ColumnPosition),
+		}
+	}
+|	"ADD" ColumnKeywordOpt '(' ColumnDef ColumnPosition ')'
+	{
+		$$ = &ast.AlterTableSpec{
+			Tp:        ast.AlterTableAddColumn,
+			NewColumn: $4.(*ast.ColumnDef),
+			Position:  $5.(*ast.ColumnPosition),
 		}
 	}
 |	"ADD" Constraint
 	{
 		constraint := $2.(*ast.Constraint)
 		$$ = &ast.AlterTableSpec{
-			Tp: ast.AlterTableAddConstraint,
+			Tp:         ast.AlterTableAddConstraint,
 			Constraint: constraint,
 		}
 	}
 |	"DROP" ColumnKeywordOpt ColumnName
 	{
 		$$ = &ast.AlterTableSpec{
-			Tp: ast.AlterTableDropColumn,
+			Tp:            ast.AlterTableDropColumn,
 			OldColumnName: $3.(*ast.ColumnName),
 		}
 	}
@@ -844,14 +1017,14 @@ AlterTableSpec:
 |	"DROP" KeyOrIndex IndexName
 	{
 		$$ = &ast.AlterTableSpec{
-			Tp: ast.AlterTableDropIndex,
+			Tp:   ast.AlterTableDropIndex,
 			Name: $3.(string),
 		}
 	}
 |	"DROP" "FOREIGN" "KEY" Symbol
 	{
 		$$ = &ast.AlterTableSpec{
-			Tp: ast.AlterTableDropForeignKey,
+			Tp:   ast.AlterTableDropForeignKey,
 			Name: $4.(string),
 		}
 	}
@@ -866,36 +1039,99 @@ AlterTableSpec:
 |	"MODIFY" ColumnKeywordOpt ColumnDef ColumnPosition
 	{
 		$$ = &ast.AlterTableSpec{
-			Tp:		ast.AlterTableModifyColumn,
-			NewColumn:	$3.(*ast.ColumnDef),
-			Position:	$4.(*ast.ColumnPosition),
+			Tp:        ast.AlterTableModifyColumn,
+			NewColumn: $3.(*ast.ColumnDef),
+			Position:  $4.(*ast.ColumnPosition),
+		}
+	}
+|	"CHANGE" ColumnKeywordOpt ColumnName ColumnDef ColumnPosition
+	{
+		$$ = &ast.AlterTableSpec{
+			Tp:            ast.AlterTableChangeColumn,
+			OldColumnName: $3.(*ast.ColumnName),
+			NewColumn:     $4.(*ast.ColumnDef),
+			Position:      $5.(*ast.ColumnPosition),
+		}
+	}
+|	"ALTER" ColumnKeywordOpt ColumnName "SET" "DEFAULT" SignedLiteral
+	{
+		option := &ast.ColumnOption{Expr: $6}
+		$$ = &ast.AlterTableSpec{
+			Tp: ast.AlterTableAlterColumn,
+			NewColumn: &ast.ColumnDef{
+				Name:    $3.(*ast.ColumnName),
+				Options: []*ast.ColumnOption{option},
+			},
 		}
 	}
-|	"CHANGE" ColumnKeywordOpt ColumnName ColumnDef
+|	"ALTER" ColumnKeywordOpt ColumnName "DROP" "DEFAULT"
 	{
 		$$ = &ast.AlterTableSpec{
-			Tp:    		ast.AlterTableChangeColumn,
-			OldColumnName:	$3.(*ast.ColumnName),
-			NewColumn: 	$4.(*ast.ColumnDef),
+			Tp: ast.AlterTableAlterColumn,
+			NewColumn: &ast.ColumnDef{
+				Name: $3.(*ast.ColumnName),
+			},
 		}
 	}
 |	"RENAME" "TO" TableName
 	{
 		$$ = &ast.AlterTableSpec{
-			Tp:    		ast.AlterTableRenameTable,
-			NewTable:      $3.(*ast.TableName),
+			Tp:       ast.AlterTableRenameTable,
+			NewTable: $3.(*ast.TableName),
+		}
+	}
+|	"RENAME" TableName
+	{
+		$$ = &ast.AlterTableSpec{
+			Tp:       ast.AlterTableRenameTable,
+			NewTable: $2.(*ast.TableName),
 		}
 	}
 |	"RENAME" "AS" TableName
 	{
 		$$ = &ast.AlterTableSpec{
-			Tp:    		ast.AlterTableRenameTable,
-			NewTable:      $3.(*ast.TableName),
+			Tp:       ast.AlterTableRenameTable,
+			NewTable: $3.(*ast.TableName),
+		}
+	}
+|	LockClause
+	{
+		$$ = &ast.AlterTableSpec{
+			Tp:       ast.AlterTableLock,
+			LockType: $1.(ast.LockType),
 		}
 	}
 
+LockClauseOpt:
+	{}
+|	LockClause
+
+LockClause:
+	"LOCK" "=" "NONE"
+	{
+		$$ = ast.LockTypeNone
+	}
+|	"LOCK" "=" "DEFAULT"
+	{
+		$$ = ast.LockTypeDefault
+	}
+|	"LOCK" "=" "SHARED"
+	{
+		$$ = ast.LockTypeShared
+	}
+|	"LOCK" "=" "EXCLUSIVE"
+	{
+		$$ = ast.LockTypeExclusive
+	}
+
+KeyOrIndex:
+	"KEY"
+|	"INDEX"
 
-KeyOrIndex: "KEY" | "INDEX"
+KeyOrIndexOpt:
+	{}
+|	KeyOrIndex
+	{}
 
 ColumnKeywordOpt:
 	{}
@@ -912,7 +1148,7 @@ ColumnPosition:
 |	"AFTER" ColumnName
 	{
 		$$ = &ast.ColumnPosition{
-			Tp: ast.ColumnPositionAfter,
+			Tp:             ast.ColumnPositionAfter,
 			RelativeColumn: $2.(*ast.ColumnName),
 		}
 	}
@@ -950,27 +1186,49 @@ Symbol:
  * See http://dev.mysql.com/doc/refman/5.7/en/rename-table.html
  *******************************************************************************************/
 RenameTableStmt:
-	 "RENAME" "TABLE" TableName "TO" TableName
-	 {
+	"RENAME" "TABLE" TableToTableList
+	{
 		$$ = &ast.RenameTableStmt{
-			OldTable: $3.(*ast.TableName), 
-			NewTable: $5.(*ast.TableName),
-		}	
-	 }
+			OldTable:      $3.([]*ast.TableToTable)[0].OldTable,
+			NewTable:      $3.([]*ast.TableToTable)[0].NewTable,
+			TableToTables: $3.([]*ast.TableToTable),
+		}
+	}
 
-/*******************************************************************************************/
+TableToTableList:
+	TableToTable
+	{
+		$$ = []*ast.TableToTable{$1.(*ast.TableToTable)}
+	}
+|	TableToTableList ',' TableToTable
+	{
+		$$ = append($1.([]*ast.TableToTable), $3.(*ast.TableToTable))
+	}
+
+TableToTable:
+	TableName "TO" TableName
+	{
+		$$ = &ast.TableToTable{
+			OldTable: $1.(*ast.TableName),
+			NewTable: $3.(*ast.TableName),
+		}
+	}
 
 AnalyzeTableStmt:
 	"ANALYZE" "TABLE" TableNameList
-	 {
+	{
 		$$ = &ast.AnalyzeTableStmt{TableNames: $3.([]*ast.TableName)}
-	 }
+	}
+|	"ANALYZE" "TABLE" TableName "INDEX" IndexNameList
+	{
+		$$ = &ast.AnalyzeTableStmt{TableNames: []*ast.TableName{$3.(*ast.TableName)}, IndexNames: $5.([]model.CIStr)}
+	}
 
 /*******************************************************************************************/
 Assignment:
-	ColumnName eq Expression
+	ColumnName "=" Expression
 	{
-		$$ = &ast.Assignment{Column: $1.(*ast.ColumnName), Expr:$3.(ast.ExprNode)}
+		$$ = &ast.Assignment{Column: $1.(*ast.ColumnName), Expr: $3}
 	}
 
 AssignmentList:
@@ -984,7 +1242,6 @@ AssignmentList:
 	}
 
 AssignmentListOpt:
-	/* EMPTY */
 	{
 		$$ = []*ast.Assignment{}
 	}
@@ -1005,7 +1262,7 @@ BeginTransactionStmt:
 	}
 
 BinlogStmt:
-	"BINLOG" stringLit
+	"BINLOG" "string literal"
 	{
 		$$ = &ast.BinlogStmt{Str: $2}
 	}
@@ -1021,11 +1278,11 @@ ColumnName:
 	{
 		$$ = &ast.ColumnName{Name: model.NewCIStr($1)}
 	}
-|	Identifier '.' IdentifierOrReservedKeyword
+|	Identifier '.' Identifier
 	{
 		$$ = &ast.ColumnName{Table: model.NewCIStr($1), Name: model.NewCIStr($3)}
 	}
-|	Identifier '.' Identifier '.' IdentifierOrReservedKeyword
+|	Identifier '.' Identifier '.' Identifier
 	{
 		$$ = &ast.ColumnName{Schema: model.NewCIStr($1), Table: model.NewCIStr($3), Name: model.NewCIStr($5)}
 	}
@@ -1041,7 +1298,6 @@ ColumnNameList:
 	}
 
 ColumnNameListOpt:
-	/* EMPTY */
 	{
 		$$ = []*ast.ColumnName{}
 	}
@@ -1050,6 +1306,35 @@ ColumnNameListOpt:
 		$$ = $1.([]*ast.ColumnName)
 	}
 
+ColumnNameListOptWithBrackets:
+	{
+		$$ = []*ast.ColumnName{}
+	}
+|	'(' ColumnNameListOpt ')'
+	{
+		$$ = $2.([]*ast.ColumnName)
+	}
+
+ChecksumTableStmt:
+	"CHECKSUM" "TABLE" TableNameList ChecksumTableOpt
+	{
+		$$ = &ast.ChecksumTableStmt{Tables: $3.([]*ast.TableName), Quick: $4.(bool)}
+	}
+
+ChecksumTableOpt:
+	%prec empty
+	{
+		$$ = false
+	}
+|	"QUICK"
+	{
+		$$ = true
+	}
+|	"EXTENDED"
+	{
+		$$ = false
+	}
+
 CommitStmt:
 	"COMMIT"
 	{
@@ -1058,7 +1343,7 @@ CommitStmt:
 
 PrimaryOpt:
 	{}
-| "PRIMARY"
+|	"PRIMARY"
 
 ColumnOption:
 	"NOT" "NULL"
@@ -1090,16 +1375,16 @@ ColumnOption:
 	}
 |	"DEFAULT" DefaultValueExpr
 	{
-		$$ = &ast.ColumnOption{Tp: ast.ColumnOptionDefaultValue, Expr: $2.(ast.ExprNode)}
+		$$ = &ast.ColumnOption{Tp: ast.ColumnOptionDefaultValue, Expr: $2}
 	}
-|	"ON" "UPDATE" NowSym
+|	"ON" "UPDATE" NowSymOptionFraction
 	{
 		nowFunc := &ast.FuncCallExpr{FnName: model.NewCIStr("CURRENT_TIMESTAMP")}
 		$$ = &ast.ColumnOption{Tp: ast.ColumnOptionOnUpdate, Expr: nowFunc}
 	}
-|	"COMMENT" stringLit
+|	"COMMENT" "string literal"
 	{
-		$$ =  &ast.ColumnOption{Tp: ast.ColumnOptionComment, Expr: ast.NewValueExpr($2)}
+		$$ = &ast.ColumnOption{Tp: ast.ColumnOptionComment, Expr: ast.NewValueExpr($2)}
 	}
 |	"CHECK" '(' Expression ')'
 	{
@@ -1107,6 +1392,37 @@ ColumnOption:
 		// The CHECK clause is parsed but ignored by all storage engines.
 		$$ = &ast.ColumnOption{}
 	}
+|	GeneratedAlways "AS" '(' Expression ')' VirtualOrStored
+	{
+		startOffset := parser.startOffset(&yyS[yypt-2])
+		endOffset := parser.endOffset(&yyS[yypt-1])
+		expr := $4
+		expr.SetText(parser.src[startOffset:endOffset])
+
+		$$ = &ast.ColumnOption{
+			Tp:     ast.ColumnOptionGenerated,
+			Expr:   expr,
+			Stored: $6.(bool),
+		}
+	}
+
+GeneratedAlways:
+	{}
+|	"GENERATED" "ALWAYS"
+	{}
+
+VirtualOrStored:
+	{
+		$$ = false
+	}
+|	"VIRTUAL"
+	{
+		$$ = false
+	}
+|	"STORED"
+	{
+		$$ = true
+	}
 
 ColumnOptionList:
 	ColumnOption
@@ -1128,41 +1444,41 @@ ColumnOptionListOpt:
 	}
 
 ConstraintElem:
-	"PRIMARY" "KEY" IndexTypeOpt '(' IndexColNameList ')' IndexOptionList
+	"PRIMARY" "KEY" IndexName IndexTypeOpt '(' IndexColNameList ')' IndexOptionList
 	{
 		c := &ast.Constraint{
-			Tp: ast.ConstraintPrimaryKey,
-			Keys: $5.([]*ast.IndexColName),
+			Tp:   ast.ConstraintPrimaryKey,
+			Keys: $6.([]*ast.IndexColName),
 		}
-		if $7 != nil {
-			c.Option = $7.(*ast.IndexOption)
+		if $8 != nil {
+			c.Option = $8.(*ast.IndexOption)
 		}
-		if $3 != nil {
+		if $4 != nil {
 			if c.Option == nil {
 				c.Option = &ast.IndexOption{}
 			}
-			c.Option.Tp = $3.(model.IndexType)
+			c.Option.Tp = $4.(model.IndexType)
 		}
 		$$ = c
 	}
-|	"FULLTEXT" "KEY" IndexName '(' IndexColNameList ')' IndexOptionList
+|	"FULLTEXT" KeyOrIndex IndexName '(' IndexColNameList ')' IndexOptionList
 	{
 		c := &ast.Constraint{
-			Tp:	ast.ConstraintFulltext,
-			Keys:	$5.([]*ast.IndexColName),
-			Name:	$3.(string),
+			Tp:   ast.ConstraintFulltext,
+			Keys: $5.([]*ast.IndexColName),
+			Name: $3.(string),
 		}
 		if $7 != nil {
 			c.Option = $7.(*ast.IndexOption)
 		}
 		$$ = c
 	}
-|	"INDEX" IndexName IndexTypeOpt '(' IndexColNameList ')' IndexOptionList
+|	KeyOrIndex IndexName IndexTypeOpt '(' IndexColNameList ')' IndexOptionList
 	{
 		c := &ast.Constraint{
-			Tp:	ast.ConstraintIndex,
-			Keys:	$5.([]*ast.IndexColName),
-			Name:	$2.(string),
+			Tp:   ast.ConstraintIndex,
+			Keys: $5.([]*ast.IndexColName),
+			Name: $2.(string),
 		}
 		if $7 != nil {
 			c.Option = $7.(*ast.IndexOption)
@@ -1175,69 +1491,15 @@ ConstraintElem:
 		}
 		$$ = c
 	}
-|	"KEY" IndexName IndexTypeOpt '(' IndexColNameList ')' IndexOptionList
+|	"UNIQUE" KeyOrIndexOpt IndexName IndexTypeOpt '(' IndexColNameList ')' IndexOptionList
 	{
 		c := &ast.Constraint{
-			Tp:	ast.ConstraintKey,
-			Keys:	$5.([]*ast.IndexColName),
-			Name:	$2.(string),
+			Tp:   ast.ConstraintUniq,
+			Keys: $6.([]*ast.IndexColName),
+			Name: $3.(string),
 		}
-		if $7 != nil {
-			c.Option = $7.(*ast.IndexOption)
-		}
-		if $3 != nil {
-			if c.Option == nil {
-				c.Option = &ast.IndexOption{}
-			}
-			c.Option.Tp = $3.(model.IndexType)
-		}
-		$$ = c
-	}
-|	"UNIQUE" IndexName IndexTypeOpt '(' IndexColNameList ')' IndexOptionList
-	{
-		c := &ast.Constraint{
-			Tp:	ast.ConstraintUniq,
-			Keys:	$5.([]*ast.IndexColName),
-			Name:	$2.(string),
-		}
-		if $7 != nil {
-			c.Option = $7.(*ast.IndexOption)
-		}
-		if $3 != nil {
-			if c.Option == nil {
-				c.Option = &ast.IndexOption{}
-			}
-			c.Option.Tp = $3.(model.IndexType)
-		}
-		$$ = c
-	}
-|	"UNIQUE" "INDEX" IndexName IndexTypeOpt '(' IndexColNameList ')' IndexOptionList
-	{
-		c := &ast.Constraint{
-			Tp:	ast.ConstraintUniqIndex,
-			Keys:	$6.([]*ast.IndexColName),
-			Name:	$3.(string),
-		}
-		if $8 != nil {
-			c.Option = $8.(*ast.IndexOption)
-		}
-		if $4 != nil {
-			if c.Option == nil {
-				c.Option = &ast.IndexOption{}
-			}
-			c.Option.Tp = $4.(model.IndexType)
-		}
-		$$ = c
-	}
-|	"UNIQUE" "KEY" IndexName IndexTypeOpt '(' IndexColNameList ')' IndexOptionList
-	{
-		c := &ast.Constraint{
-			Tp:	ast.ConstraintUniqKey,
-			Keys:	$6.([]*ast.IndexColName),
-			Name:	$3.(string),
-		}
-		if $8 != nil {
-			c.Option = $8.(*ast.IndexOption)
+		if $8 != nil {
+			c.Option = $8.(*ast.IndexOption)
 		}
 		if $4 != nil {
 			if c.Option == nil {
@@ -1250,10 +1512,10 @@ ConstraintElem:
 |	"FOREIGN" "KEY" IndexName '(' IndexColNameList ')' ReferDef
 	{
 		$$ = &ast.Constraint{
-			Tp:	ast.ConstraintForeignKey,
-			Keys:	$5.([]*ast.IndexColName),
-			Name:	$3.(string),
-			Refer:	$7.(*ast.ReferenceDef),
+			Tp:    ast.ConstraintForeignKey,
+			Keys:  $5.([]*ast.IndexColName),
+			Name:  $3.(string),
+			Refer: $7.(*ast.ReferenceDef),
 		}
 	}
 
@@ -1269,26 +1531,28 @@ ReferDef:
 			onUpdateOpt = $7.(*ast.OnUpdateOpt)
 		}
 		$$ = &ast.ReferenceDef{
-			Table: $2.(*ast.TableName),
+			Table:         $2.(*ast.TableName),
 			IndexColNames: $4.([]*ast.IndexColName),
-			OnDelete: onDeleteOpt,
-			OnUpdate: onUpdateOpt,
+			OnDelete:      onDeleteOpt,
+			OnUpdate:      onUpdateOpt,
 		}
 	}
 
 OnDeleteOpt:
+	%prec lowerThanOn
 	{
 		$$ = &ast.OnDeleteOpt{}
-	} %prec lowerThanOn
+	}
 |	"ON" "DELETE" ReferOpt
 	{
 		$$ = &ast.OnDeleteOpt{ReferOpt: $3.(ast.ReferOptionType)}
 	}
 
 OnUpdateOpt:
+	%prec lowerThanOn
 	{
 		$$ = &ast.OnUpdateOpt{}
-	} %prec lowerThanOn
+	}
 |	"ON" "UPDATE" ReferOpt
 	{
 		$$ = &ast.OnUpdateOpt{ReferOpt: $3.(ast.ReferOptionType)}
@@ -1324,23 +1588,38 @@ ReferOpt:
  *      https://github.com/mysql/mysql-server/blob/5.7/sql/sql_yacc.yy#L6832
  */
 DefaultValueExpr:
+	NowSymOptionFraction
+|	SignedLiteral
+
+NowSymOptionFraction:
 	NowSym
 	{
 		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr("CURRENT_TIMESTAMP")}
 	}
-|	NowSym '(' ')'
+|	NowSymFunc '(' ')'
 	{
 		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr("CURRENT_TIMESTAMP")}
 	}
-|	NowSym '(' NUM ')'
+|	NowSymFunc '(' NUM ')'
 	{
 		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr("CURRENT_TIMESTAMP")}
 	}
-|	SignedLiteral
+
+NowSymFunc:
+	"CURRENT_TIMESTAMP"
+	{}
+|	"LOCALTIME"
+	{}
+|	"LOCALTIMESTAMP"
+	{}
+|	"NOW"
+	{}
 
 // TODO: Process other three keywords
 NowSym:
-"CURRENT_TIMESTAMP" | "LOCALTIME" | "LOCALTIMESTAMP" | "NOW"
+	"CURRENT_TIMESTAMP"
+|	"LOCALTIME"
+|	"LOCALTIMESTAMP"
 
 SignedLiteral:
 	Literal
@@ -1358,19 +1637,33 @@ SignedLiteral:
 
 // TODO: support decimal literal
 NumLiteral:
-	intLit
-|	floatLit
-|	decLit
-
+	"integer literal"
+|	"floating-point literal"
+|	"decimal literal"
 
 CreateIndexStmt:
-	"CREATE" CreateIndexStmtUnique "INDEX" Identifier "ON" TableName '(' IndexColNameList ')'
-	{
+	"CREATE" CreateIndexStmtUnique "INDEX" Identifier IndexTypeOpt "ON" TableName '(' IndexColNameList ')' IndexOptionList LockClauseOpt
+	{
+		var indexOption *ast.IndexOption
+		if $11 != nil {
+			indexOption = $11.(*ast.IndexOption)
+			if indexOption.Tp == model.IndexTypeInvalid {
+				if $5 != nil {
+					indexOption.Tp = $5.(model.IndexType)
+				}
+			}
+		} else {
+			indexOption = &ast.IndexOption{}
+			if $5 != nil {
+				indexOption.Tp = $5.(model.IndexType)
+			}
+		}
 		$$ = &ast.CreateIndexStmt{
-			Unique: $2.(bool),
-			IndexName: $4,
-                	Table: $6.(*ast.TableName),
-			IndexColNames: $8.([]*ast.IndexColName),
+			Unique:        $2.(bool),
+			IndexName:     $4,
+			Table:         $7.(*ast.TableName),
+			IndexColNames: $9.([]*ast.IndexColName),
+			IndexOption:   indexOption,
 		}
 	}
 
@@ -1391,10 +1684,7 @@ IndexColName:
 	}
 
 IndexColNameList:
-	{
-		$$ = []*ast.IndexColName{}
-	}
-|	IndexColName
+	IndexColName
 	{
 		$$ = []*ast.IndexColName{$1.(*ast.IndexColName)}
 	}
@@ -1403,8 +1693,6 @@ IndexColNameList:
 		$$ = append($1.([]*ast.IndexColName), $3.(*ast.IndexColName))
 	}
 
-
-
 /*******************************************************************
  *
  *  Create Database Statement
@@ -1419,17 +1707,17 @@ CreateDatabaseStmt:
 	"CREATE" DatabaseSym IfNotExists DBName DatabaseOptionListOpt
 	{
 		$$ = &ast.CreateDatabaseStmt{
-			IfNotExists:	$3.(bool),
-			Name:		$4.(string),
-			Options:	$5.([]*ast.DatabaseOption),
+			IfNotExists: $3.(bool),
+			Name:        $4.(string),
+			Options:     $5.([]*ast.DatabaseOption),
 		}
 	}
 
 DBName:
 	Identifier
-  {
-    $$ = $1
-  }
+	{
+		$$ = $1
+	}
 
 DatabaseOption:
 	DefaultKwdOpt CharsetKw EqOpt CharsetName
@@ -1475,7 +1763,7 @@ DatabaseOptionList:
 CreateTableStmt:
 	"CREATE" "TABLE" IfNotExists TableName '(' TableElementList ')' TableOptionListOpt PartitionOpt
 	{
-		tes := $6.([]interface {})
+		tes := $6.([]interface{})
 		var columnDefs []*ast.ColumnDef
 		var constraints []*ast.Constraint
 		for _, te := range tes {
@@ -1491,25 +1779,74 @@ CreateTableStmt:
 			return 1
 		}
 		$$ = &ast.CreateTableStmt{
-			Table:          $4.(*ast.TableName),
-			IfNotExists:    $3.(bool),
-			Cols:           columnDefs,
-			Constraints:    constraints,
-			Options:        $8.([]*ast.TableOption),
+			Table:       $4.(*ast.TableName),
+			IfNotExists: $3.(bool),
+			Cols:        columnDefs,
+			Constraints: constraints,
+			Options:     $8.([]*ast.TableOption),
+		}
+	}
+|	"CREATE" "TABLE" IfNotExists TableName "LIKE" TableName
+	{
+		$$ = &ast.CreateTableStmt{
+			Table:       $4.(*ast.TableName),
+			ReferTable:  $6.(*ast.TableName),
+			IfNotExists: $3.(bool),
 		}
 	}
 
-Default:
-	"DEFAULT" Expression
+/*******************************************************************
+ *
+ *  Create View Statement
+ *
+ *  Example:
+ *      CREATE OR REPLACE VIEW v (a, b) AS SELECT c1, c2 FROM t
+ *******************************************************************/
+CreateViewStmt:
+	"CREATE" OrReplace "VIEW" TableName ViewFieldListOpt "AS" ViewSelectStmt
 	{
-		$$ = $2
+		sel := $7.(ast.StmtNode)
+		sel.SetText(strings.TrimSpace(parser.src[parser.startOffset(&yyS[yypt]):parser.endOffset(&parser.yylval)]))
+		$$ = &ast.CreateViewStmt{
+			OrReplace: $2.(bool),
+			ViewName:  $4.(*ast.TableName),
+			Cols:      $5.([]model.CIStr),
+			Select:    sel,
+		}
 	}
 
-DefaultOpt:
+OrReplace:
 	{
-		$$ = nil
+		$$ = false
+	}
+|	"OR" "REPLACE"
+	{
+		$$ = true
+	}
+
+ViewFieldListOpt:
+	{
+		$$ = []model.CIStr{}
+	}
+|	'(' ViewFieldList ')'
+	{
+		$$ = $2.([]model.CIStr)
+	}
+
+ViewFieldList:
+	Identifier
+	{
+		$$ = []model.CIStr{model.NewCIStr($1)}
+	}
+|	ViewFieldList ',' Identifier
+	{
+		$$ = append($1.([]model.CIStr), model.NewCIStr($3))
 	}
-|	Default
+
+ViewSelectStmt:
+	SelectStmt
+|	UnionStmt
+|	WithSelectStmt
 
 DefaultKwdOpt:
 	{}
@@ -1517,9 +1854,11 @@ DefaultKwdOpt:
 
 PartitionOpt:
 	{}
+|	"PARTITION" "BY" "KEY" '(' ColumnNameList ')' PartitionNumOpt PartitionDefinitionListOpt
+	{}
 |	"PARTITION" "BY" "HASH" '(' Expression ')' PartitionNumOpt PartitionDefinitionListOpt
 	{}
-|	"PARTITION" "BY" "RANGE" '(' Expression ')' PartitionNumOpt  PartitionDefinitionListOpt
+|	"PARTITION" "BY" "RANGE" '(' Expression ')' PartitionNumOpt PartitionDefinitionListOpt
 	{}
 
 PartitionNumOpt:
@@ -1534,14 +1873,22 @@ PartitionDefinitionListOpt:
 
 PartitionDefinitionList:
 	PartitionDefinition
+|	PartitionDefinitionList ',' PartitionDefinition
+
+PartitionDefinition:
+	"PARTITION" Identifier PartDefValuesOpt PartDefStorageOpt
 	{}
-|	PartitionDefinition ',' PartitionDefinitionList
+
+PartDefValuesOpt:
+	{}
+|	"VALUES" "LESS" "THAN" "MAXVALUE"
+	{}
+|	"VALUES" "LESS" "THAN" '(' ExpressionList ')'
 	{}
 
-PartitionDefinition:
-	"PARTITION" Identifier "VALUES" "LESS" "THAN" ExpressionList "ENGINE" eq Identifier
+PartDefStorageOpt:
 	{}
-|	"PARTITION" Identifier "VALUES" "LESS" "THAN" "MAXVALUE" "ENGINE" eq Identifier
+|	"ENGINE" "=" Identifier
 	{}
 
 /******************************************************************
@@ -1551,7 +1898,7 @@ PartitionDefinition:
 DoStmt:
 	"DO" ExpressionList
 	{
-		$$ = &ast.DoStmt {
+		$$ = &ast.DoStmt{
 			Exprs: $2.([]ast.ExprNode),
 		}
 	}
@@ -1567,10 +1914,10 @@ DeleteFromStmt:
 		// Single Table
 		join := &ast.Join{Left: &ast.TableSource{Source: $6.(ast.ResultSetNode)}, Right: nil}
 		x := &ast.DeleteStmt{
-			TableRefs:	&ast.TableRefsClause{TableRefs: join},
-			LowPriority:	$2.(bool),
-			Quick:		$3.(bool),
-			Ignore:		$4.(bool),
+			TableRefs:   &ast.TableRefsClause{TableRefs: join},
+			LowPriority: $2.(bool),
+			Quick:       $3.(bool),
+			IgnoreErr:   $4.(bool),
 		}
 		if $7 != nil {
 			x.Where = $7.(ast.ExprNode)
@@ -1588,13 +1935,13 @@ DeleteFromStmt:
 	{
 		// Multiple Table
 		x := &ast.DeleteStmt{
-			LowPriority:	$2.(bool),
-			Quick:		$3.(bool),
-			Ignore:		$4.(bool),
-			IsMultiTable:	true,
-			BeforeFrom:	true,
-			Tables:		&ast.DeleteTableList{Tables: $5.([]*ast.TableName)},
-			TableRefs:	&ast.TableRefsClause{TableRefs: $7.(*ast.Join)},
+			LowPriority:  $2.(bool),
+			Quick:        $3.(bool),
+			IgnoreErr:    $4.(bool),
+			IsMultiTable: true,
+			BeforeFrom:   true,
+			Tables:       &ast.DeleteTableList{Tables: $5.([]*ast.TableName)},
+			TableRefs:    &ast.TableRefsClause{TableRefs: $7.(*ast.Join)},
 		}
 		if $8 != nil {
 			x.Where = $8.(ast.ExprNode)
@@ -1605,12 +1952,12 @@ DeleteFromStmt:
 	{
 		// Multiple Table
 		x := &ast.DeleteStmt{
-			LowPriority:	$2.(bool),
-			Quick:		$3.(bool),
-			Ignore:		$4.(bool),
-			IsMultiTable:	true,
-			Tables:		&ast.DeleteTableList{Tables: $6.([]*ast.TableName)},
-			TableRefs:	&ast.TableRefsClause{TableRefs: $8.(*ast.Join)},
+			LowPriority:  $2.(bool),
+			Quick:        $3.(bool),
+			IgnoreErr:    $4.(bool),
+			IsMultiTable: true,
+			Tables:       &ast.DeleteTableList{Tables: $6.([]*ast.TableName)},
+			TableRefs:    &ast.TableRefsClause{TableRefs: $8.(*ast.Join)},
 		}
 		if $9 != nil {
 			x.Where = $9.(ast.ExprNode)
@@ -1619,7 +1966,7 @@ DeleteFromStmt:
 	}
 
 DatabaseSym:
-"DATABASE" | "SCHEMA"
+	"DATABASE"
 
 DropDatabaseStmt:
 	"DROP" DatabaseSym IfExists DBName
@@ -1644,45 +1991,58 @@ DropTableStmt:
 	}
 
 DropViewStmt:
-	"DROP" "VIEW" "IF" "EXISTS" TableNameList
+	"DROP" "VIEW" TableNameList
+	{
+		$$ = &ast.DropTableStmt{Tables: $3.([]*ast.TableName), IsView: true}
+	}
+|	"DROP" "VIEW" "IF" "EXISTS" TableNameList
 	{
-		$$ = &ast.DoStmt{}
+		$$ = &ast.DropTableStmt{IfExists: true, Tables: $5.([]*ast.TableName), IsView: true}
 	}
 
 DropUserStmt:
-    "DROP" "USER" UsernameList
-    {
-        $$ = &ast.DropUserStmt{IfExists: false, UserList: $3.([]string)}
-    }
-|   "DROP" "USER" "IF" "EXISTS" UsernameList
-    {
-        $$ = &ast.DropUserStmt{IfExists: true, UserList: $5.([]string)}
-    }
+	"DROP" "USER" UsernameList
+	{
+		$$ = &ast.DropUserStmt{IfExists: false, UserList: $3.([]*auth.UserIdentity)}
+	}
+|	"DROP" "USER" "IF" "EXISTS" UsernameList
+	{
+		$$ = &ast.DropUserStmt{IfExists: true, UserList: $5.([]*auth.UserIdentity)}
+	}
+
+DropStatsStmt:
+	"DROP" "STATS" TableName
+	{
+		$$ = &ast.DropStatsStmt{Table: $3.(*ast.TableName)}
+	}
 
 TableOrTables:
 	"TABLE"
+	{}
 |	"TABLES"
+	{}
 
 EqOpt:
 	{}
-|	eq
+|	"="
 
 EmptyStmt:
-	/* EMPTY */
 	{
 		$$ = nil
 	}
 
 ExplainSym:
-"EXPLAIN" | "DESCRIBE" | "DESC"
+	"EXPLAIN"
+|	"DESCRIBE"
+|	"DESC"
 
 ExplainStmt:
 	ExplainSym TableName
 	{
 		$$ = &ast.ExplainStmt{
 			Stmt: &ast.ShowStmt{
-				Tp:	ast.ShowColumns,
-				Table:	$2.(*ast.TableName),
+				Tp:    ast.ShowColumns,
+				Table: $2.(*ast.TableName),
 			},
 		}
 	}
@@ -1690,89 +2050,95 @@ ExplainStmt:
 	{
 		$$ = &ast.ExplainStmt{
 			Stmt: &ast.ShowStmt{
-				Tp:	ast.ShowColumns,
-				Table:	$2.(*ast.TableName),
-				Column:	$3.(*ast.ColumnName),
+				Tp:     ast.ShowColumns,
+				Table:  $2.(*ast.TableName),
+				Column: $3.(*ast.ColumnName),
 			},
 		}
 	}
 |	ExplainSym ExplainableStmt
 	{
-		$$ = &ast.ExplainStmt{Stmt: $2.(ast.StmtNode)}
+		$$ = &ast.ExplainStmt{
+			Stmt:   $2,
+			Format: "row",
+		}
+	}
+|	ExplainSym "FORMAT" "=" "string literal" ExplainableStmt
+	{
+		$$ = &ast.ExplainStmt{
+			Stmt:   $5,
+			Format: $4,
+		}
 	}
 
 LengthNum:
 	NUM
 	{
-		switch v := $1.(type) {
-		case int64:
-			$$ = uint64(v)
-		case uint64:
-			$$ = uint64(v)
-		}
+		$$ = getUint64FromNUM($1)
 	}
 
 NUM:
-	intLit
+	"integer literal"
 
 Expression:
-	"USER_VAR" assignmentEq Expression %prec assignmentEq
+	singleAtIdentifier ":=" Expression
 	{
-		v := $1.(string)
+		v := $1
 		v = strings.TrimPrefix(v, "@")
 		$$ = &ast.VariableExpr{
-				Name: 	  v,
-				IsGlobal: false,
-				IsSystem: false,
-				Value:	  $3.(ast.ExprNode),
+			Name:     v,
+			IsGlobal: false,
+			IsSystem: false,
+			Value:    $3,
 		}
 	}
 |	Expression logOr Expression %prec oror
 	{
-		$$ = &ast.BinaryOperationExpr{Op: opcode.OrOr, L: $1.(ast.ExprNode), R: $3.(ast.ExprNode)}
+		$$ = &ast.BinaryOperationExpr{Op: opcode.LogicOr, L: $1, R: $3}
 	}
 |	Expression "XOR" Expression %prec xor
 	{
-		$$ = &ast.BinaryOperationExpr{Op: opcode.LogicXor, L: $1.(ast.ExprNode), R: $3.(ast.ExprNode)}
+		$$ = &ast.BinaryOperationExpr{Op: opcode.LogicXor, L: $1, R: $3}
 	}
 |	Expression logAnd Expression %prec andand
 	{
-		$$ = &ast.BinaryOperationExpr{Op: opcode.AndAnd, L: $1.(ast.ExprNode), R: $3.(ast.ExprNode)}
+		$$ = &ast.BinaryOperationExpr{Op: opcode.LogicAnd, L: $1, R: $3}
 	}
 |	"NOT" Expression %prec not
 	{
-		$$ = &ast.UnaryOperationExpr{Op: opcode.Not, V: $2.(ast.ExprNode)}
+		$$ = &ast.UnaryOperationExpr{Op: opcode.Not, V: $2}
 	}
-|	Factor "IS" NotOpt trueKwd %prec is
+|	BoolPri IsOrNotOp "TRUE"
 	{
-		$$ = &ast.IsTruthExpr{Expr:$1.(ast.ExprNode), Not: $3.(bool), True: int64(1)}
+		$$ = &ast.IsTruthExpr{Expr: $1, Not: !$2.(bool), True: int64(1)}
 	}
-|	Factor "IS" NotOpt falseKwd %prec is
+|	BoolPri IsOrNotOp "FALSE"
 	{
-		$$ = &ast.IsTruthExpr{Expr:$1.(ast.ExprNode), Not: $3.(bool), True: int64(0)}
+		$$ = &ast.IsTruthExpr{Expr: $1, Not: !$2.(bool), True: int64(0)}
 	}
-|	Factor "IS" NotOpt "UNKNOWN" %prec is
+|	BoolPri IsOrNotOp "UNKNOWN"
 	{
 		/* https://dev.mysql.com/doc/refman/5.7/en/comparison-operators.html#operator_is */
-		$$ = &ast.IsNullExpr{Expr: $1.(ast.ExprNode), Not: $3.(bool)}
+		$$ = &ast.IsNullExpr{Expr: $1, Not: !$2.(bool)}
 	}
-|	Factor
-
+|	BoolPri
 
 logOr:
-"||" | "OR"
+	"||"
+|	"OR"
 
 logAnd:
-"&&" | "AND"
+	"&&"
+|	"AND"
 
 ExpressionList:
 	Expression
 	{
-		$$ = []ast.ExprNode{$1.(ast.ExprNode)}
+		$$ = []ast.ExprNode{$1}
 	}
 |	ExpressionList ',' Expression
 	{
-		$$ = append($1.([]ast.ExprNode), $3.(ast.ExprNode))
+		$$ = append($1.([]ast.ExprNode), $3)
 	}
 
 ExpressionListOpt:
@@ -1781,32 +2147,48 @@ ExpressionListOpt:
 	}
 |	ExpressionList
 
-Factor:
-	Factor "IS" NotOpt "NULL" %prec is
+FuncDatetimePrecListOpt:
 	{
-		$$ = &ast.IsNullExpr{Expr: $1.(ast.ExprNode), Not: $3.(bool)}
+		$$ = []ast.ExprNode{}
 	}
-|	Factor CompareOp PredicateExpr %prec eq
+|	FuncDatetimePrecList
 	{
-		$$ = &ast.BinaryOperationExpr{Op: $2.(opcode.Op), L: $1.(ast.ExprNode), R: $3.(ast.ExprNode)}
+		$$ = $1
 	}
-|	Factor CompareOp "USER_VAR" assignmentEq PredicateExpr %prec assignmentEq
+
+FuncDatetimePrecList:
+	"integer literal"
 	{
-		v := $3.(string)
-		v = strings.TrimPrefix(v, "@")
-		variable := &ast.VariableExpr{
-				Name: 	  v,
-				IsGlobal: false,
-				IsSystem: false,
-				Value:	  $5.(ast.ExprNode),
-		}
-		$$ = &ast.BinaryOperationExpr{Op: $2.(opcode.Op), L: $1.(ast.ExprNode), R: variable}
+		expr := ast.NewValueExpr($1)
+		$$ = []ast.ExprNode{expr}
+	}
+
+BoolPri:
+	BoolPri IsOrNotOp "NULL"
+	{
+		$$ = &ast.IsNullExpr{Expr: $1, Not: !$2.(bool)}
 	}
-|	Factor CompareOp AnyOrAll SubSelect %prec eq
+|	BoolPri CompareOp PredicateExpr
+	{
+		$$ = &ast.BinaryOperationExpr{Op: $2.(opcode.Op), L: $1, R: $3}
+	}
+|	BoolPri CompareOp AnyOrAll SubSelect
 	{
 		sq := $4.(*ast.SubqueryExpr)
 		sq.MultiRows = true
-		$$ = &ast.CompareSubqueryExpr{Op: $2.(opcode.Op), L: $1.(ast.ExprNode), R: sq, All: $3.(bool)}
+		$$ = &ast.CompareSubqueryExpr{Op: $2.(opcode.Op), L: $1, R: sq, All: $3.(bool)}
+	}
+|	BoolPri CompareOp singleAtIdentifier ":=" PredicateExpr
+	{
+		v := $3
+		v = strings.TrimPrefix(v, "@")
+		variable := &ast.VariableExpr{
+			Name:     v,
+			IsGlobal: false,
+			IsSystem: false,
+			Value:    $5,
+		}
+		$$ = &ast.BinaryOperationExpr{Op: $2.(opcode.Op), L: $1, R: variable}
 	}
 |	PredicateExpr
 
@@ -1844,6 +2226,56 @@ CompareOp:
 		$$ = opcode.NullEQ
 	}
 
+BetweenOrNotOp:
+	"BETWEEN"
+	{
+		$$ = true
+	}
+|	"NOT" "BETWEEN"
+	{
+		$$ = false
+	}
+
+IsOrNotOp:
+	"IS"
+	{
+		$$ = true
+	}
+|	"IS" "NOT"
+	{
+		$$ = false
+	}
+
+InOrNotOp:
+	"IN"
+	{
+		$$ = true
+	}
+|	"NOT" "IN"
+	{
+		$$ = false
+	}
+
+LikeOrNotOp:
+	"LIKE"
+	{
+		$$ = true
+	}
+|	"NOT" "LIKE"
+	{
+		$$ = false
+	}
+
+RegexpOrNotOp:
+	RegexpSym
+	{
+		$$ = true
+	}
+|	"NOT" RegexpSym
+	{
+		$$ = false
+	}
+
 AnyOrAll:
 	"ANY"
 	{
@@ -1859,28 +2291,28 @@ AnyOrAll:
 	}
 
 PredicateExpr:
-	PrimaryFactor NotOpt "IN" '(' ExpressionList ')'
+	BitExpr InOrNotOp '(' ExpressionList ')'
 	{
-		$$ = &ast.PatternInExpr{Expr: $1.(ast.ExprNode), Not: $2.(bool), List: $5.([]ast.ExprNode)}
+		$$ = &ast.PatternInExpr{Expr: $1, Not: !$2.(bool), List: $4.([]ast.ExprNode)}
 	}
-|	PrimaryFactor NotOpt "IN" SubSelect
+|	BitExpr InOrNotOp SubSelect
 	{
-		sq := $4.(*ast.SubqueryExpr)
+		sq := $3.(*ast.SubqueryExpr)
 		sq.MultiRows = true
-		$$ = &ast.PatternInExpr{Expr: $1.(ast.ExprNode), Not: $2.(bool), Sel: sq}
+		$$ = &ast.PatternInExpr{Expr: $1, Not: !$2.(bool), Sel: sq}
 	}
-|	PrimaryFactor NotOpt "BETWEEN" PrimaryFactor "AND" PredicateExpr
+|	BitExpr BetweenOrNotOp BitExpr "AND" PredicateExpr
 	{
 		$$ = &ast.BetweenExpr{
-			Expr:	$1.(ast.ExprNode),
-			Left:	$4.(ast.ExprNode),
-			Right:	$6.(ast.ExprNode),
-			Not:	$2.(bool),
+			Expr:  $1,
+			Left:  $3,
+			Right: $5,
+			Not:   !$2.(bool),
 		}
 	}
-|	PrimaryFactor NotOpt "LIKE" PrimaryExpression LikeEscapeOpt
+|	BitExpr LikeOrNotOp SimpleExpr LikeEscapeOpt
 	{
-		escape := $5.(string)
+		escape := $4.(string)
 		if len(escape) > 1 {
 			yylex.Errorf("Incorrect arguments %s to ESCAPE", escape)
 			return 1
@@ -1888,40 +2320,32 @@ PredicateExpr:
 			escape = "\\"
 		}
 		$$ = &ast.PatternLikeExpr{
-			Expr:		$1.(ast.ExprNode),
-			Pattern:	$4.(ast.ExprNode),
-			Not: 		$2.(bool),
-			Escape: 	escape[0],
+			Expr:    $1,
+			Pattern: $3,
+			Not:     !$2.(bool),
+			Escape:  escape[0],
 		}
 	}
-|	PrimaryFactor NotOpt RegexpSym PrimaryExpression
+|	BitExpr RegexpOrNotOp SimpleExpr
 	{
-		$$ = &ast.PatternRegexpExpr{Expr: $1.(ast.ExprNode), Pattern: $4.(ast.ExprNode), Not: $2.(bool)}
+		$$ = &ast.PatternRegexpExpr{Expr: $1, Pattern: $3, Not: !$2.(bool)}
 	}
-|	PrimaryFactor
+|	BitExpr
 
 RegexpSym:
-"REGEXP" | "RLIKE"
+	"REGEXP"
+|	"RLIKE"
 
 LikeEscapeOpt:
-	%prec lowerThanEscape
+	%prec empty
 	{
 		$$ = "\\"
 	}
-|	"ESCAPE" stringLit
+|	"ESCAPE" "string literal"
 	{
 		$$ = $2
 	}
 
-NotOpt:
-	{
-		$$ = false
-	}
-|	"NOT"
-	{
-		$$ = true
-	}
-
 Field:
 	'*'
 	{
@@ -1939,13 +2363,12 @@ Field:
 	}
 |	Expression FieldAsNameOpt
 	{
-		expr := $1.(ast.ExprNode)
+		expr := $1
 		asName := $2.(string)
 		$$ = &ast.SelectField{Expr: expr, AsName: model.NewCIStr(asName)}
 	}
 
 FieldAsNameOpt:
-	/* EMPTY */
 	{
 		$$ = ""
 	}
@@ -1963,11 +2386,11 @@ FieldAsName:
 	{
 		$$ = $2
 	}
-|	stringLit
+|	"string literal"
 	{
 		$$ = $1
 	}
-|	"AS" stringLit
+|	"AS" "string literal"
 	{
 		$$ = $2
 	}
@@ -1998,6 +2421,10 @@ GroupByClause:
 	{
 		$$ = &ast.GroupByClause{Items: $3.([]*ast.ByItem)}
 	}
+|	"GROUP" "BY" ByList "WITH" "ROLLUP"
+	{
+		$$ = &ast.GroupByClause{Items: $3.([]*ast.ByItem), Rollup: true}
+	}
 
 HavingClause:
 	{
@@ -2005,11 +2432,10 @@ HavingClause:
 	}
 |	"HAVING" Expression
 	{
-		$$ = &ast.HavingClause{Expr: $2.(ast.ExprNode)}
+		$$ = &ast.HavingClause{Expr: $2}
 	}
 
 IfExists:
-	%prec lowestOpt
 	{
 		$$ = false
 	}
@@ -2019,7 +2445,6 @@ IfExists:
 	}
 
 IfNotExists:
-	%prec lowestOpt
 	{
 		$$ = false
 	}
@@ -2028,9 +2453,7 @@ IfNotExists:
 		$$ = true
 	}
 
-
 IgnoreOptional:
-	%prec lowerThanIgnore
 	{
 		$$ = false
 	}
@@ -2070,7 +2493,6 @@ IndexOptionList:
 		}
 	}
 
-
 IndexOption:
 	"KEY_BLOCK_SIZE" EqOpt LengthNum
 	{
@@ -2081,13 +2503,13 @@ IndexOption:
 	}
 |	IndexType
 	{
-		$$ = &ast.IndexOption {
+		$$ = &ast.IndexOption{
 			Tp: $1.(model.IndexType),
 		}
 	}
-|	"COMMENT" stringLit
+|	"COMMENT" "string literal"
 	{
-		$$ = &ast.IndexOption {
+		$$ = &ast.IndexOption{
 			Comment: $2,
 		}
 	}
@@ -2113,57 +2535,213 @@ IndexTypeOpt:
 
 /**********************************Identifier********************************************/
 Identifier:
-identifier | UnReservedKeyword | NotKeywordToken
-
-IdentifierOrReservedKeyword:
-Identifier | ReservedKeyword
+	"identifier"
+|	UnReservedKeyword
+|	NotKeywordToken
+|	TiDBKeyword
+	{}
 
 UnReservedKeyword:
- "ACTION" | "ASCII" | "AUTO_INCREMENT" | "AFTER" | "AT" | "AVG" | "BEGIN" | "BIT" | "BOOL" | "BOOLEAN" | "BTREE" | "CHARSET"
-| "COLUMNS" | "COMMIT" | "COMPACT" | "COMPRESSED" | "CONSISTENT" | "DATA" | "DATE" | "DATETIME" | "DEALLOCATE" | "DO"
-| "DYNAMIC"| "END" | "ENGINE" | "ENGINES" | "ESCAPE" | "EXECUTE" | "FIELDS" | "FIRST" | "FIXED" | "FULL" |"GLOBAL"
-| "HASH" | "LESS" | "LOCAL" | "NAMES" | "OFFSET" | "PASSWORD" %prec lowerThanEq | "PREPARE" | "QUICK" | "REDUNDANT" 
-| "ROLLBACK" | "SESSION" | "SIGNED" | "SNAPSHOT" | "START" | "STATUS" | "TABLES" | "TEXT" | "THAN" | "TIME" | "TIMESTAMP" 
-| "TRANSACTION" | "TRUNCATE" | "UNKNOWN" | "VALUE" | "WARNINGS" | "YEAR" | "MODE"  | "WEEK"  | "ANY" | "SOME" | "USER" | "IDENTIFIED"
-| "COLLATION" | "COMMENT" | "AVG_ROW_LENGTH" | "CONNECTION" | "CHECKSUM" | "COMPRESSION" | "KEY_BLOCK_SIZE" | "MAX_ROWS"
-| "MIN_ROWS" | "NATIONAL" | "ROW" | "ROW_FORMAT" | "QUARTER" | "GRANTS" | "TRIGGERS" | "DELAY_KEY_WRITE" | "ISOLATION"
-| "REPEATABLE" | "COMMITTED" | "UNCOMMITTED" | "ONLY" | "SERIALIZABLE" | "LEVEL" | "VARIABLES" | "SQL_CACHE" | "INDEXES" | "PROCESSLIST"
-| "SQL_NO_CACHE" | "DISABLE"  | "ENABLE" | "REVERSE" | "SPACE" | "PRIVILEGES" | "NO" | "BINLOG" | "FUNCTION" | "VIEW" | "MODIFY" | "EVENTS" | "PARTITIONS"
-| "TIMESTAMPDIFF"
-
-ReservedKeyword:
-"ADD" | "ALL" | "ALTER" | "ANALYZE" | "AND" | "AS" | "ASC" | "BETWEEN" | "BIGINT"
-| "BINARY" | "BLOB" | "BOTH" | "BY" | "CASCADE" | "CASE" | "CHANGE" | "CHARACTER" | "CHECK" | "COLLATE"
-| "COLUMN" | "CONSTRAINT" | "CONVERT" | "CREATE" | "CROSS" | "CURRENT_DATE" | "CURRENT_TIME"
-| "CURRENT_TIMESTAMP" | "CURRENT_USER" | "DATABASE" | "DATABASES" | "DAY_HOUR" | "DAY_MICROSECOND"
-| "DAY_MINUTE" | "DAY_SECOND" | "DECIMAL" | "DEFAULT" | "DELETE" | "DESC" | "DESCRIBE"
-| "DISTINCT" | "DIV" | "DOUBLE" | "DROP" | "DUAL" | "ELSE" | "ENCLOSED" | "ESCAPED"
-| "EXISTS" | "EXPLAIN" | "FALSE" | "FLOAT" | "FOR" | "FORCE" | "FOREIGN" | "FROM"
-| "FULLTEXT" | "GRANT" | "GROUP" | "HAVING" | "HOUR_MICROSECOND" | "HOUR_MINUTE"
-| "HOUR_SECOND" | "IF" | "IGNORE" | "IN" | "INDEX" | "INFILE" | "INNER" | "INSERT" | "INT" | "INTO" | "INTEGER"
-| "INTERVAL" | "IS" | "JOIN" | "KEY" | "KEYS" | "LEADING" | "LEFT" | "LIKE" | "LIMIT" | "LINES" | "LOAD"
-| "LOCALTIME" | "LOCALTIMESTAMP" | "LOCK" | "LONGBLOB" | "LONGTEXT" | "MAXVALUE" | "MEDIUMBLOB" | "MEDIUMINT" | "MEDIUMTEXT"
-| "MINUTE_MICROSECOND" | "MINUTE_SECOND" | "MOD" | "NOT" | "NO_WRITE_TO_BINLOG" | "NULL" | "NUMERIC"
-| "ON" | "OPTION" | "OR" | "ORDER" | "OUTER" | "PARTITION" | "PRECISION" | "PRIMARY" | "PROCEDURE" | "RANGE" | "READ" 
-| "REAL" | "REFERENCES" | "REGEXP" | "RENAME" | "REPEAT" | "REPLACE" | "RESTRICT" | "RIGHT" | "RLIKE"
-| "SCHEMA" | "SCHEMAS" | "SECOND_MICROSECOND" | "SELECT" | "SET" | "SHOW" | "SMALLINT"
-| "STARTING" | "TABLE" | "TERMINATED" | "THEN" | "TINYBLOB" | "TINYINT" | "TINYTEXT" | "TO"
-| "TRAILING" | "TRUE" | "UNION" | "UNIQUE" | "UNLOCK" | "UNSIGNED"
-| "UPDATE" | "USE" | "USING" | "UTC_DATE" | "VALUES" | "VARBINARY" | "VARCHAR"
-| "WHEN" | "WHERE" | "WRITE" | "XOR" | "YEAR_MONTH" | "ZEROFILL"
- /*
-| "DELAYED" | "HIGH_PRIORITY" | "LOW_PRIORITY"| "WITH"
- */
+	"ACTION"
+|	"ASCII"
+|	"AUTO_INCREMENT"
+|	"AFTER"
+|	"ALWAYS"
+	{}
+|	"AVG"
+|	"BEGIN"
+|	"BIT"
+|	"BOOL"
+|	"BOOLEAN"
+|	"BTREE"
+|	"BYTE"
+|	"CHARSET"
+|	"COLUMNS"
+|	"COMMIT"
+|	"COMPACT"
+|	"CONFIG"
+|	"COMPRESSED"
+|	"CONSISTENT"
+|	"DATA"
+|	"DATE" %prec lowerThanStringLitToken
+|	"DATETIME"
+|	"DAY"
+|	"DEALLOCATE"
+|	"DO"
+|	"DUPLICATE"
+|	"DYNAMIC"
+|	"END"
+|	"ENGINE"
+|	"ENGINES"
+|	"ENUM"
+	{}
+|	"ESCAPE"
+|	"EXECUTE"
+|	"EXTENDED"
+|	"FIELDS"
+|	"FIRST"
+|	"FIXED"
+|	"FLUSH"
+|	"FORMAT"
+	{}
+|	"FULL"
+|	"GLOBAL"
+|	"HASH"
+|	"HOUR"
+|	"LESS"
+|	"LOCAL"
+|	"NAMES"
+|	"OFFSET"
+|	"OPEN"
+|	"PASSWORD" %prec lowerThanEq
+|	"PERSIST"
+|	"PREPARE"
+|	"QUICK"
+|	"REDUNDANT"
+|	"RESET"
+|	"ROLLBACK"
+|	"ROLLUP"
+|	"SESSION"
+|	"SIGNED"
+|	"SNAPSHOT"
+|	"START"
+|	"STATUS"
+|	"TABLES"
+|	"TEXT"
+|	"THAN"
+|	"TIME" %prec lowerThanStringLitToken
+|	"TIMESTAMP" %prec lowerThanStringLitToken
+|	"TRANSACTION"
+|	"TRUNCATE"
+|	"UNKNOWN"
+|	"VALUE"
+|	"WARNINGS"
+|	"YEAR"
+|	"MODE"
+|	"WEEK"
+|	"ANY"
+|	"SOME"
+|	"USER"
+|	"IDENTIFIED"
+|	"COLLATION"
+|	"COMMENT"
+|	"AVG_ROW_LENGTH"
+|	"CONNECTION"
+|	"CHECKSUM"
+|	"COMPRESSION"
+|	"KEY_BLOCK_SIZE"
+|	"MAX_ROWS"
+|	"MIN_ROWS"
+|	"NATIONAL"
+|	"ROW"
+|	"ROW_FORMAT"
+|	"QUARTER"
+|	"GRANTS"
+|	"TRIGGERS"
+|	"DELAY_KEY_WRITE"
+|	"ISOLATION"
+|	"JSON"
+	{}
+|	"REPEATABLE"
+|	"COMMITTED"
+|	"UNCOMMITTED"
+|	"ONLY"
+|	"SERIALIZABLE"
+|	"LEVEL"
+|	"VARIABLES"
+|	"SQL_CACHE"
+|	"INDEXES"
+|	"PROCESSLIST"
+|	"PROFILE"
+|	"PROFILES"
+|	"CSV"
+|	"DELIMITER"
+|	"HEADER"
+|	"IMPORT"
+|	"MAX_ERRORS"
+|	"SQL_NO_CACHE"
+|	"DISABLE"
+|	"ENABLE"
+|	"REVERSE"
+|	"PRIVILEGES"
+|	"NO"
+|	"BINLOG"
+|	"FUNCTION"
+|	"VIEW"
+|	"MODIFY"
+|	"EVENTS"
+|	"PARTITIONS"
+|	"NONE"
+	{}
+|	"SUPER"
+	{}
+|	"EXCLUSIVE"
+	{}
+|	"STATS_PERSISTENT"
+|	"ROW_COUNT"
+	{}
+|	"COALESCE"
+|	"MONTH"
+|	"PROCESS"
+	{}
+|	"MICROSECOND"
+|	"MINUTE"
+|	"PLUGINS"
+	{}
+|	"QUERY"
+	{}
+|	"SECOND"
+|	"SEPARATOR"
+	{}
+|	"SHARE"
+|	"SHARED"
+	{}
 
+TiDBKeyword:
+	"ADMIN"
+	{}
+|	"CANCEL"
+	{}
+|	"DDL"
+|	"JOBS"
+	{}
+|	"STATS"
+	{}
+|	"STATS_META"
+	{}
+|	"STATS_HISTOGRAMS"
+	{}
+|	"STATS_BUCKETS"
+	{}
+|	"TIDB"
+	{}
+|	"TIDB_SMJ"
+	{}
+|	"TIDB_INLJ"
+	{}
 
 NotKeywordToken:
-	"ABS" | "ADDDATE" | "ADMIN" | "COALESCE" | "CONCAT" | "CONCAT_WS" | "CONNECTION_ID" | "CUR_TIME"| "COUNT" | "DAY"
-|	"DATEDIFF" | "DATE_ADD" | "DATE_FORMAT" | "DATE_SUB" | "DAYNAME" | "DAYOFMONTH" | "DAYOFWEEK" | "DAYOFYEAR" | "FROM_DAYS" | "FIND_IN_SET" | "FOUND_ROWS"
-|	"GROUP_CONCAT"| "GREATEST" | "LEAST" | "HOUR" | "HEX" | "UNHEX" | "IFNULL" | "ISNULL" | "LAST_INSERT_ID" | "LCASE" | "LENGTH" | "LOCATE" | "LOWER" | "LTRIM"
-|	"MAX" | "MICROSECOND" | "MIN" |	"MINUTE" | "NULLIF" | "MONTH" | "MONTHNAME" | "NOW" | "POW" | "POWER" | "RAND"
-|	"SECOND" | "SIGN" | "SLEEP" | "SQRT" | "SQL_CALC_FOUND_ROWS" | "STR_TO_DATE" | "SUBDATE" | "SUBSTRING" %prec lowerThanLeftParen |
-"SUBSTRING_INDEX" | "SUM" | "TRIM" | "RTRIM" | "UCASE" | "UPPER" | "VERSION" | "WEEKDAY" | "WEEKOFYEAR" | "YEARWEEK" | "ROUND"
-|	"STATS_PERSISTENT" | "GET_LOCK" | "RELEASE_LOCK" | "CEIL" | "CEILING" | "FLOOR" | "FROM_UNIXTIME" | "TIMEDIFF" | "LN" | "LOG" | "LOG2" | "LOG10" | "FIELD_KWD"
+	"ADDDATE"
+|	"BIT_XOR"
+|	"CAST"
+	{}
+|	"COUNT"
+|	"CUR_TIME"
+|	"DATE_ADD"
+|	"DATE_SUB"
+|	"EXTRACT"
+|	"GET_FORMAT"
+|	"GROUP_CONCAT"
+|	"MIN"
+|	"MAX"
+|	"NOW"
+|	"POSITION"
+|	"SUBDATE"
+|	"SUBSTRING"
+|	"SUM"
+|	"TIMESTAMPADD"
+|	"TIMESTAMPDIFF"
+|	"TRIM"
 
 /************************************************************************************
  *
@@ -2175,8 +2753,8 @@ InsertIntoStmt:
 	"INSERT" Priority IgnoreOptional IntoOpt TableName InsertValues OnDuplicateKeyUpdate
 	{
 		x := $6.(*ast.InsertStmt)
-		x.Priority = $2.(int)
-		x.Ignore = $3.(bool)
+		x.Priority = $2.(mysql.PriorityEnum)
+		x.IgnoreErr = $3.(bool)
 		// Wraps many layers here so that it can be processed the same way as select statement.
 		ts := &ast.TableSource{Source: $5.(*ast.TableName)}
 		x.Table = &ast.TableRefsClause{TableRefs: &ast.Join{Left: ts}}
@@ -2187,16 +2765,15 @@ InsertIntoStmt:
 	}
 
 IntoOpt:
-	%prec lowerThanInto
 	{}
 |	"INTO"
 
 InsertValues:
-	'(' ColumnNameListOpt ')' ValueSym ExpressionListList
+	'(' ColumnNameListOpt ')' ValueSym ValuesList
 	{
 		$$ = &ast.InsertStmt{
-			Columns:   $2.([]*ast.ColumnName),
-			Lists:      $5.([][]ast.ExprNode),
+			Columns: $2.([]*ast.ColumnName),
+			Lists:   $5.([][]ast.ExprNode),
 		}
 	}
 |	'(' ColumnNameListOpt ')' SelectStmt
@@ -2207,9 +2784,9 @@ InsertValues:
 	{
 		$$ = &ast.InsertStmt{Columns: $2.([]*ast.ColumnName), Select: $4.(*ast.UnionStmt)}
 	}
-|	ValueSym ExpressionListList %prec insertValues
+|	ValueSym ValuesList
 	{
-		$$ = &ast.InsertStmt{Lists:  $2.([][]ast.ExprNode)}
+		$$ = &ast.InsertStmt{Lists: $2.([][]ast.ExprNode)}
 	}
 |	SelectStmt
 	{
@@ -2225,30 +2802,54 @@ InsertValues:
 	}
 
 ValueSym:
-"VALUE" | "VALUES"
+	"VALUE"
+|	"VALUES"
 
-ExpressionListList:
-	ExpressionListListItem
+ValuesList:
+	RowValue
 	{
 		$$ = [][]ast.ExprNode{$1.([]ast.ExprNode)}
 	}
-|	ExpressionListList ',' ExpressionListListItem
+|	ValuesList ',' RowValue
 	{
 		$$ = append($1.([][]ast.ExprNode), $3.([]ast.ExprNode))
 	}
 
-ExpressionListListItem:
-	'(' ExpressionListOpt ')'
+RowValue:
+	'(' ValuesOpt ')'
 	{
 		$$ = $2
 	}
 
+ValuesOpt:
+	{
+		$$ = []ast.ExprNode{}
+	}
+|	Values
+
+Values:
+	Values ',' ExprOrDefault
+	{
+		$$ = append($1.([]ast.ExprNode), $3)
+	}
+|	ExprOrDefault
+	{
+		$$ = []ast.ExprNode{$1}
+	}
+
+ExprOrDefault:
+	Expression
+|	"DEFAULT"
+	{
+		$$ = &ast.DefaultExpr{}
+	}
+
 ColumnSetValue:
-	ColumnName eq Expression
+	ColumnName "=" Expression
 	{
 		$$ = &ast.Assignment{
-			Column:	$1.(*ast.ColumnName),
-			Expr:	$3.(ast.ExprNode),
+			Column: $1.(*ast.ColumnName),
+			Expr:   $3,
 		}
 	}
 
@@ -2278,8 +2879,6 @@ OnDuplicateKeyUpdate:
 		$$ = $5
 	}
 
-/***********************************Insert Statements END************************************/
-
 /************************************************************************************
  *  Replace Statements
  *  See https://dev.mysql.com/doc/refman/5.7/en/replace.html
@@ -2291,7 +2890,7 @@ ReplaceIntoStmt:
 	{
 		x := $5.(*ast.InsertStmt)
 		x.IsReplace = true
-		x.Priority = $2.(int)
+		x.Priority = $2.(mysql.PriorityEnum)
 		ts := &ast.TableSource{Source: $4.(*ast.TableName)}
 		x.Table = &ast.TableRefsClause{TableRefs: &ast.Join{Left: ts}}
 		$$ = x
@@ -2299,111 +2898,90 @@ ReplaceIntoStmt:
 
 ReplacePriority:
 	{
-		$$ = ast.NoPriority
+		$$ = mysql.NoPriority
 	}
 |	"LOW_PRIORITY"
 	{
-		$$ = ast.LowPriority
+		$$ = mysql.LowPriority
 	}
 |	"DELAYED"
 	{
-		$$ = ast.DelayedPriority
+		$$ = mysql.DelayedPriority
 	}
 
-/***********************************Replace Statements END************************************/
-
 Literal:
 	"FALSE"
 	{
-		$$ = int64(0)
+		$$ = ast.NewValueExpr(false)
 	}
 |	"NULL"
 	{
-		$$ = nil
+		$$ = ast.NewValueExpr(nil)
 	}
 |	"TRUE"
 	{
-		$$ = int64(1)
+		$$ = ast.NewValueExpr(true)
 	}
-|	floatLit
-|	decLit
-|	intLit
-|	stringLit
+|	"floating-point literal"
 	{
-		tp := types.NewFieldType(mysql.TypeString)
-		tp.Charset, tp.Collate = parser.charset, parser.collation
-		expr := ast.NewValueExpr($1)
-		expr.SetType(tp)
-		$$ = expr
+		$$ = ast.NewValueExpr($1)
 	}
-|	"UNDERSCORE_CHARSET" stringLit
+|	"decimal literal"
 	{
-		// See https://dev.mysql.com/doc/refman/5.7/en/charset-literal.html
-		tp := types.NewFieldType(mysql.TypeString)
-		tp.Charset = $1.(string)
-		co, err := charset.GetDefaultCollation(tp.Charset)
-		if err != nil {
-			yylex.Errorf("Get collation error for charset: %s", tp.Charset)
-			return 1
-		}
-		tp.Collate = co
-		expr := ast.NewValueExpr($2)
-		expr.SetType(tp)
-		$$ = expr
+		$$ = ast.NewValueExpr($1)
 	}
-|	hexLit
-|	bitLit
-
-Operand:
-	Literal
+|	"integer literal"
 	{
 		$$ = ast.NewValueExpr($1)
 	}
-|	ColumnName
+|	StringLiteral %prec lowerThanStringLitToken
 	{
-		$$ = &ast.ColumnNameExpr{Name: $1.(*ast.ColumnName)}
+		$$ = $1
 	}
-|	'(' Expression ')'
+|	"UNDERSCORE_CHARSET" "string literal"
 	{
-		startOffset := parser.startOffset(&yyS[yypt-1])
-		endOffset := parser.endOffset(&yyS[yypt])
-		expr := $2.(ast.ExprNode)
-		expr.SetText(parser.src[startOffset:endOffset])
-		$$ = &ast.ParenthesesExpr{Expr: expr}
+		// See https://dev.mysql.com/doc/refman/5.7/en/charset-literal.html
+		co, err := charset.GetDefaultCollation($1)
+		if err != nil {
+			yylex.Errorf("Get collation error for charset: %s", $1)
+			return 1
+		}
+		expr := ast.NewValueExpr($2)
+		tp := expr.GetType()
+		tp.Charset = $1
+		tp.Collate = co
+		if tp.Collate == charset.CollationBin {
+			tp.Flag |= mysql.BinaryFlag
+		}
+		$$ = expr
 	}
-|	"DEFAULT" %prec lowerThanLeftParen
+|	"hexadecimal literal"
 	{
-		$$ = &ast.DefaultExpr{}
+		$$ = ast.NewValueExpr($1)
 	}
-|	"DEFAULT" '(' ColumnName ')'
+|	"bit literal"
 	{
-		$$ = &ast.DefaultExpr{Name: $3.(*ast.ColumnName)}
+		$$ = ast.NewValueExpr($1)
 	}
-|	Variable
+
+StringLiteral:
+	"string literal"
 	{
-		$$ = $1
+		expr := ast.NewValueExpr($1)
+		$$ = expr
 	}
-|	"PLACEHOLDER"
+|	StringLiteral "string literal"
 	{
-		$$ = &ast.ParamMarkerExpr{
-			Offset: yyS[yypt].offset,
+		valExpr := $1.(*ast.ValueExpr)
+		strLit := valExpr.GetString()
+		expr := ast.NewValueExpr(strLit + $2)
+		// Fix #4239, use first string literal as projection name.
+		if valExpr.GetProjectionOffset() >= 0 {
+			expr.SetProjectionOffset(valExpr.GetProjectionOffset())
+		} else {
+			expr.SetProjectionOffset(len(strLit))
 		}
-	}
-|	"ROW" '(' ExpressionList ',' Expression ')'
-	{
-		values := append($3.([]ast.ExprNode), $5.(ast.ExprNode))
-		$$ = &ast.RowExpr{Values: values}
-	}
-|	'(' ExpressionList ',' Expression ')'
-	{
-		values := append($2.([]ast.ExprNode), $4.(ast.ExprNode))
-		$$ = &ast.RowExpr{Values: values}
-	}
-|	"EXISTS" SubSelect
-	{
-		sq := $2.(*ast.SubqueryExpr)
-		sq.Exists = true
-		$$ = &ast.ExistsSubqueryExpr{Sel: sq}
+		$$ = expr
 	}
 
 OrderBy:
@@ -2433,11 +3011,10 @@ ByItem:
 				expr = &ast.PositionExpr{N: int(position)}
 			}
 		}
-		$$ = &ast.ByItem{Expr: expr.(ast.ExprNode), Desc: $2.(bool)}
+		$$ = &ast.ByItem{Expr: expr, Desc: $2.(bool)}
 	}
 
 Order:
-	/* EMPTY */
 	{
 		$$ = false // ASC by default
 	}
@@ -2459,109 +3036,196 @@ OrderByOptional:
 		$$ = $1
 	}
 
-PrimaryExpression:
-	Operand
-|	Function
-|	SubSelect
-|	'!' PrimaryExpression %prec neg
+BitExpr:
+	BitExpr '|' BitExpr
 	{
-		$$ = &ast.UnaryOperationExpr{Op: opcode.Not, V: $2.(ast.ExprNode)}
+		$$ = &ast.BinaryOperationExpr{Op: opcode.Or, L: $1, R: $3}
 	}
-|	'~'  PrimaryExpression %prec neg
+|	BitExpr '&' BitExpr
 	{
-		$$ = &ast.UnaryOperationExpr{Op: opcode.BitNeg, V: $2.(ast.ExprNode)}
+		$$ = &ast.BinaryOperationExpr{Op: opcode.And, L: $1, R: $3}
 	}
-|	'-' PrimaryExpression %prec neg
+|	BitExpr "<<" BitExpr
 	{
-		$$ = &ast.UnaryOperationExpr{Op: opcode.Minus, V: $2.(ast.ExprNode)}
+		$$ = &ast.BinaryOperationExpr{Op: opcode.LeftShift, L: $1, R: $3}
 	}
-|	'+' PrimaryExpression %prec neg
+|	BitExpr ">>" BitExpr
 	{
-		$$ = &ast.UnaryOperationExpr{Op: opcode.Plus, V: $2.(ast.ExprNode)}
+		$$ = &ast.BinaryOperationExpr{Op: opcode.RightShift, L: $1, R: $3}
 	}
-|	"BINARY" PrimaryExpression %prec neg
+|	BitExpr '+' BitExpr
 	{
-		// See https://dev.mysql.com/doc/refman/5.7/en/cast-functions.html#operator_binary
-		x := types.NewFieldType(mysql.TypeString)
-		x.Charset = charset.CharsetBin
-		x.Collate = charset.CharsetBin
-		$$ = &ast.FuncCastExpr{
-			Expr: $2.(ast.ExprNode),
-			Tp: x,
-			FunctionType: ast.CastBinaryOperator,
+		$$ = &ast.BinaryOperationExpr{Op: opcode.Plus, L: $1, R: $3}
+	}
+|	BitExpr '-' BitExpr
+	{
+		$$ = &ast.BinaryOperationExpr{Op: opcode.Minus, L: $1, R: $3}
+	}
+|	BitExpr '+' "INTERVAL" Expression TimeUnit
+	{
+		$$ = &ast.FuncCallExpr{
+			FnName: model.NewCIStr("DATE_ADD"),
+			Args: []ast.ExprNode{
+				$1,
+				$4,
+				ast.NewValueExpr($5),
+			},
 		}
 	}
-|	PrimaryExpression "COLLATE" StringName %prec neg
+|	BitExpr '-' "INTERVAL" Expression TimeUnit
 	{
-		// TODO: Create a builtin function hold expr and collation. When do evaluation, convert expr result using the collation.
-		$$ = $1
+		$$ = &ast.FuncCallExpr{
+			FnName: model.NewCIStr("DATE_SUB"),
+			Args: []ast.ExprNode{
+				$1,
+				$4,
+				ast.NewValueExpr($5),
+			},
+		}
 	}
-
-Function:
-	FunctionCallKeyword
-|	FunctionCallNonKeyword
-|	FunctionCallConflict
-|	FunctionCallAgg
-
-FunctionNameConflict:
-	"DATABASE"
-|	"SCHEMA"
-|	"IF"
-|	"LEFT"
-|	"REPEAT"
-|	"CURRENT_USER"
-|	"UTC_DATE"
-|	"CURRENT_DATE"
-|	"VERSION"
-|	"INTERVAL" %prec lowerThanIntervalKeyword
-
-FunctionCallConflict:
-	FunctionNameConflict '(' ExpressionListOpt ')'
+|	BitExpr '*' BitExpr
 	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: $3.([]ast.ExprNode)}
+		$$ = &ast.BinaryOperationExpr{Op: opcode.Mul, L: $1, R: $3}
 	}
-|	"CURRENT_USER"
+|	BitExpr '/' BitExpr
 	{
-		// See https://dev.mysql.com/doc/refman/5.7/en/information-functions.html#function_current-user
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1)}
+		$$ = &ast.BinaryOperationExpr{Op: opcode.Div, L: $1, R: $3}
 	}
-|	"CURRENT_DATE"
+|	BitExpr '%' BitExpr
 	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1)}
+		$$ = &ast.BinaryOperationExpr{Op: opcode.Mod, L: $1, R: $3}
 	}
-|	"UTC_DATE"
+|	BitExpr "DIV" BitExpr
 	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1)}
+		$$ = &ast.BinaryOperationExpr{Op: opcode.IntDiv, L: $1, R: $3}
+	}
+|	BitExpr "MOD" BitExpr
+	{
+		$$ = &ast.BinaryOperationExpr{Op: opcode.Mod, L: $1, R: $3}
 	}
-|	"MOD" '(' PrimaryFactor ',' PrimaryFactor ')'
+|	BitExpr '^' BitExpr
 	{
-		$$ = &ast.BinaryOperationExpr{Op: opcode.Mod, L: $3.(ast.ExprNode), R: $5.(ast.ExprNode)}
+		$$ = &ast.BinaryOperationExpr{Op: opcode.Xor, L: $1, R: $3}
 	}
+|	SimpleExpr
 
-DistinctOpt:
+SimpleIdent:
+	Identifier
 	{
-		$$ = false
+		$$ = &ast.ColumnNameExpr{Name: &ast.ColumnName{
+			Name: model.NewCIStr($1),
+		}}
 	}
-|	"ALL"
+|	Identifier '.' Identifier
 	{
-		$$ = false
+		$$ = &ast.ColumnNameExpr{Name: &ast.ColumnName{
+			Table: model.NewCIStr($1),
+			Name:  model.NewCIStr($3),
+		}}
 	}
-|	"DISTINCT"
+|	'.' Identifier '.' Identifier
 	{
-		$$ = true
+		$$ = &ast.ColumnNameExpr{Name: &ast.ColumnName{
+			Table: model.NewCIStr($2),
+			Name:  model.NewCIStr($4),
+		}}
 	}
-|	"DISTINCT" "ALL"
+|	Identifier '.' Identifier '.' Identifier
 	{
-		$$ = true
+		$$ = &ast.ColumnNameExpr{Name: &ast.ColumnName{
+			Schema: model.NewCIStr($1),
+			Table:  model.NewCIStr($3),
+			Name:   model.NewCIStr($5),
+		}}
 	}
 
-FunctionCallKeyword:
-	"CAST" '(' Expression "AS" CastType ')'
+SimpleExpr:
+	SimpleIdent
+|	FunctionCallKeyword
+|	FunctionCallNonKeyword
+|	FunctionCallGeneric
+|	SimpleExpr "COLLATE" StringName
+	{
+		// TODO: Create a builtin function hold expr and collation. When do evaluation, convert expr result using the collation.
+		$$ = $1
+	}
+|	Literal
+|	paramMarker
+	{
+		$$ = &ast.ParamMarkerExpr{
+			Offset: yyS[yypt].offset,
+		}
+	}
+|	Variable
+	{}
+|	SumExpr
+|	'!' SimpleExpr %prec neg
+	{
+		$$ = &ast.UnaryOperationExpr{Op: opcode.Not, V: $2}
+	}
+|	'~' SimpleExpr %prec neg
+	{
+		$$ = &ast.UnaryOperationExpr{Op: opcode.BitNeg, V: $2}
+	}
+|	'-' SimpleExpr %prec neg
+	{
+		$$ = &ast.UnaryOperationExpr{Op: opcode.Minus, V: $2}
+	}
+|	'+' SimpleExpr %prec neg
+	{
+		$$ = &ast.UnaryOperationExpr{Op: opcode.Plus, V: $2}
+	}
+|	SubSelect
+|	'(' Expression ')'
+	{
+		startOffset := parser.startOffset(&yyS[yypt-1])
+		endOffset := parser.endOffset(&yyS[yypt])
+		expr := $2
+		expr.SetText(parser.src[startOffset:endOffset])
+		$$ = &ast.ParenthesesExpr{Expr: expr}
+	}
+|	'(' ExpressionList ',' Expression ')'
+	{
+		values := append($2.([]ast.ExprNode), $4)
+		$$ = &ast.RowExpr{Values: values}
+	}
+|	"ROW" '(' ExpressionList ',' Expression ')'
+	{
+		values := append($3.([]ast.ExprNode), $5)
+		$$ = &ast.RowExpr{Values: values}
+	}
+|	"EXISTS" SubSelect
+	{
+		sq := $2.(*ast.SubqueryExpr)
+		sq.Exists = true
+		$$ = &ast.ExistsSubqueryExpr{Sel: sq}
+	}
+|	"BINARY" SimpleExpr %prec neg
+	{
+		// See https://dev.mysql.com/doc/refman/5.7/en/cast-functions.html#operator_binary
+		x := types.NewFieldType(mysql.TypeString)
+		x.Charset = charset.CharsetBin
+		x.Collate = charset.CharsetBin
+		$$ = &ast.FuncCastExpr{
+			Expr:         $2,
+			Tp:           x,
+			FunctionType: ast.CastBinaryOperator,
+		}
+	}
+|	"CAST" '(' Expression "AS" CastType ')'
 	{
 		/* See https://dev.mysql.com/doc/refman/5.7/en/cast-functions.html#function_cast */
+		tp := $5.(*types.FieldType)
+		defaultFlen, defaultDecimal := mysql.GetDefaultFieldLengthAndDecimalForCast(tp.Tp)
+		if tp.Flen == types.UnspecifiedLength {
+			tp.Flen = defaultFlen
+		}
+		if tp.Decimal == types.UnspecifiedLength {
+			tp.Decimal = defaultDecimal
+		}
 		$$ = &ast.FuncCastExpr{
-			Expr: $3.(ast.ExprNode),
-			Tp: $5.(*types.FieldType),
+			Expr:         $3,
+			Tp:           tp,
 			FunctionType: ast.CastFunction,
 		}
 	}
@@ -2569,83 +3233,157 @@ FunctionCallKeyword:
 	{
 		x := &ast.CaseExpr{WhenClauses: $3.([]*ast.WhenClause)}
 		if $2 != nil {
-			x.Value = $2.(ast.ExprNode)
+			x.Value = $2
 		}
 		if $4 != nil {
 			x.ElseClause = $4.(ast.ExprNode)
 		}
 		$$ = x
 	}
+|	"CONVERT" '(' Expression ',' CastType ')'
+	{
+		// See https://dev.mysql.com/doc/refman/5.7/en/cast-functions.html#function_convert
+		tp := $5.(*types.FieldType)
+		defaultFlen, defaultDecimal := mysql.GetDefaultFieldLengthAndDecimalForCast(tp.Tp)
+		if tp.Flen == types.UnspecifiedLength {
+			tp.Flen = defaultFlen
+		}
+		if tp.Decimal == types.UnspecifiedLength {
+			tp.Decimal = defaultDecimal
+		}
+		$$ = &ast.FuncCastExpr{
+			Expr:         $3,
+			Tp:           tp,
+			FunctionType: ast.CastConvertFunction,
+		}
+	}
 |	"CONVERT" '(' Expression "USING" StringName ')'
 	{
 		// See https://dev.mysql.com/doc/refman/5.7/en/cast-functions.html#function_convert
-		charset := ast.NewValueExpr($5)
+		charset1 := ast.NewValueExpr($5)
 		$$ = &ast.FuncCallExpr{
 			FnName: model.NewCIStr($1),
-			Args: []ast.ExprNode{$3.(ast.ExprNode), charset},
+			Args:   []ast.ExprNode{$3, charset1},
 		}
 	}
-|	"CONVERT" '(' Expression ',' CastType ')'
+|	"DEFAULT" '(' SimpleIdent ')'
 	{
-		// See https://dev.mysql.com/doc/refman/5.7/en/cast-functions.html#function_convert
-		$$ = &ast.FuncCastExpr{
-			Expr: $3.(ast.ExprNode),
-			Tp: $5.(*types.FieldType),
-			FunctionType: ast.CastConvertFunction,
-		}
+		$$ = &ast.DefaultExpr{Name: $3.(*ast.ColumnNameExpr).Name}
 	}
-|	"ASCII" '(' Expression ')'
+|	"VALUES" '(' SimpleIdent ')'
 	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+		$$ = &ast.ValuesExpr{Column: $3.(*ast.ColumnNameExpr)}
 	}
-|	"DATE" '(' Expression ')'
+|	SimpleIdent jss "string literal"
 	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+		expr := ast.NewValueExpr($3)
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr(ast.JSONExtract), Args: []ast.ExprNode{$1, expr}}
 	}
-|	"USER" '(' ')'
+|	SimpleIdent juss "string literal"
 	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1)}
+		expr := ast.NewValueExpr($3)
+		extract := &ast.FuncCallExpr{FnName: model.NewCIStr(ast.JSONExtract), Args: []ast.ExprNode{$1, expr}}
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr(ast.JSONUnquote), Args: []ast.ExprNode{extract}}
 	}
-|	"VALUES" '(' ColumnName ')' %prec lowerThanInsertValues
+
+DistinctKwd:
+	"DISTINCT"
+	{}
+|	distinctRow
+	{}
+
+DistinctOpt:
+	"ALL"
 	{
-		// TODO: support qualified identifier for column_name
-		$$ = &ast.ValuesExpr{Column: &ast.ColumnNameExpr{Name: $3.(*ast.ColumnName)}}
+		$$ = false
 	}
-|	"WEEK" '(' ExpressionList ')'
+|	DistinctKwd
 	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: $3.([]ast.ExprNode)}
+		$$ = true
 	}
-|	"YEAR" '(' Expression ')'
+
+DefaultFalseDistinctOpt:
 	{
-		$$ = &ast.FuncCallExpr{FnName:model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+		$$ = false
 	}
+|	DistinctOpt
 
-FunctionCallNonKeyword:
-	"COALESCE" '(' ExpressionList ')'
+DefaultTrueDistinctOpt:
 	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: $3.([]ast.ExprNode)}
+		$$ = true
 	}
-|	"CURDATE" '(' ')'
+|	DistinctOpt
+
+BuggyDefaultFalseDistinctOpt:
+	DefaultFalseDistinctOpt
+|	DistinctKwd "ALL"
 	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1.(string))}
+		$$ = true
 	}
-|	"CUR_TIME" '(' ExpressionOpt ')'
+
+FunctionNameConflict:
+	"ASCII"
+|	"CHARSET"
+|	"COALESCE"
+|	"COLLATION"
+|	"DATE"
+|	"DATABASE"
+|	"DAY"
+|	"HOUR"
+|	"IF"
+|	"INTERVAL" %prec lowerThanIntervalKeyword
+|	"FORMAT"
+	{}
+|	"LEFT"
+|	"MICROSECOND"
+|	"MINUTE"
+|	"MONTH"
+|	"NOW"
+|	"QUARTER"
+|	"REPEAT"
+|	"REPLACE"
+|	"REVERSE"
+|	"RIGHT"
+|	"ROW_COUNT"
+	{}
+|	"SECOND"
+|	"TIME"
+|	"TIMESTAMP"
+|	"TRUNCATE"
+|	"USER"
+|	"WEEK"
+|	"YEAR"
+
+OptionalBraces:
+	{}
+|	'(' ')'
+	{}
+
+FunctionNameOptionalBraces:
+	"CURRENT_USER"
+|	"CURRENT_DATE"
+|	"UTC_DATE"
+
+FunctionNameDatetimePrecision:
+	"CURRENT_TIME"
+|	"CURRENT_TIMESTAMP"
+|	"LOCALTIME"
+|	"LOCALTIMESTAMP"
+|	"UTC_TIME"
+	{}
+|	"UTC_TIMESTAMP"
+	{}
+
+FunctionCallKeyword:
+	FunctionNameConflict '(' ExpressionListOpt ')'
 	{
-		args := []ast.ExprNode{}
-		if $3 != nil {
-			args = append(args, $3.(ast.ExprNode))
-		}
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: args}
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: $3.([]ast.ExprNode)}
 	}
-|	"CURRENT_TIME" FuncDatetimePrec
+|	FunctionNameOptionalBraces OptionalBraces
 	{
-		args := []ast.ExprNode{}
-		if $2 != nil {
-			args = append(args, $2.(ast.ExprNode))
-		}
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: args}
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1)}
 	}
-|	"CURRENT_TIMESTAMP" FuncDatetimePrec
+|	FunctionNameDatetimePrecision FuncDatetimePrec
 	{
 		args := []ast.ExprNode{}
 		if $2 != nil {
@@ -2653,58 +3391,54 @@ FunctionCallNonKeyword:
 		}
 		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: args}
 	}
-|	"ABS" '(' Expression ')'
+|	"CHAR" '(' ExpressionList ')'
 	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+		nilVal := ast.NewValueExpr(nil)
+		args := $3.([]ast.ExprNode)
+		$$ = &ast.FuncCallExpr{
+			FnName: model.NewCIStr(ast.CharFunc),
+			Args:   append(args, nilVal),
+		}
 	}
-|	"CONCAT" '(' ExpressionList ')'
+|	"CHAR" '(' ExpressionList "USING" StringName ')'
 	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: $3.([]ast.ExprNode)}
-	}
-|	"CONCAT_WS" '(' ExpressionList ')'
-	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: $3.([]ast.ExprNode)}
-	}
-|	"CEIL" '(' Expression ')'
-	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+		charset1 := ast.NewValueExpr($5)
+		args := $3.([]ast.ExprNode)
+		$$ = &ast.FuncCallExpr{
+			FnName: model.NewCIStr(ast.CharFunc),
+			Args:   append(args, charset1),
+		}
 	}
-|	"CEILING" '(' Expression ')'
+|	"DATE" "string literal"
 	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+		expr := ast.NewValueExpr($2)
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr(ast.DateLiteral), Args: []ast.ExprNode{expr}}
 	}
-|	"DATEDIFF" '(' Expression ',' Expression ')'
-    {
-   		$$ = &ast.FuncCallExpr{
-   			FnName: model.NewCIStr($1),
-   			Args: []ast.ExprNode{$3.(ast.ExprNode), $5.(ast.ExprNode)},
-   		}
-   	}
-|	"DAY" '(' Expression ')'
+|	"TIME" "string literal"
 	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+		expr := ast.NewValueExpr($2)
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr(ast.TimeLiteral), Args: []ast.ExprNode{expr}}
 	}
-|	"DAYNAME" '(' Expression ')'
+|	"TIMESTAMP" "string literal"
 	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+		expr := ast.NewValueExpr($2)
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr(ast.TimestampLiteral), Args: []ast.ExprNode{expr}}
 	}
-|	"DAYOFWEEK" '(' Expression ')'
+|	"INSERT" '(' ExpressionListOpt ')'
 	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr(ast.InsertFunc), Args: $3.([]ast.ExprNode)}
 	}
-|	"DAYOFMONTH" '(' Expression ')'
+|	"MOD" '(' BitExpr ',' BitExpr ')'
 	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+		$$ = &ast.BinaryOperationExpr{Op: opcode.Mod, L: $3, R: $5}
 	}
-|	"DAYOFYEAR" '(' Expression ')'
+|	"PASSWORD" '(' ExpressionListOpt ')'
 	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr(ast.PasswordFunc), Args: $3.([]ast.ExprNode)}
 	}
-| 	"FLOOR" '(' Expression ')'
-  	{
-    	$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
-  	}
-|	"FIELD_KWD" '(' ExpressionList ')'
+
+FunctionCallNonKeyword:
+	"CUR_TIME" '(' FuncDatetimePrecListOpt ')'
 	{
 		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: $3.([]ast.ExprNode)}
 	}
@@ -2713,8 +3447,8 @@ FunctionCallNonKeyword:
 		$$ = &ast.FuncCallExpr{
 			FnName: model.NewCIStr($1),
 			Args: []ast.ExprNode{
-				$3.(ast.ExprNode),
-				$5.(ast.ExprNode),
+				$3,
+				$5,
 				ast.NewValueExpr("DAY"),
 			},
 		}
@@ -2724,8 +3458,8 @@ FunctionCallNonKeyword:
 		$$ = &ast.FuncCallExpr{
 			FnName: model.NewCIStr($1),
 			Args: []ast.ExprNode{
-				$3.(ast.ExprNode),
-				$6.(ast.ExprNode),
+				$3,
+				$6,
 				ast.NewValueExpr($7),
 			},
 		}
@@ -2735,540 +3469,342 @@ FunctionCallNonKeyword:
 		$$ = &ast.FuncCallExpr{
 			FnName: model.NewCIStr($1),
 			Args: []ast.ExprNode{
-				$3.(ast.ExprNode),
-				$6.(ast.ExprNode),
+				$3,
+				$6,
 				ast.NewValueExpr($7),
 			},
 		}
 	}
-|	"DATE_FORMAT" '(' Expression ',' Expression ')'
+|	"EXTRACT" '(' TimeUnit "FROM" Expression ')'
 	{
+		timeUnit := ast.NewValueExpr($3)
 		$$ = &ast.FuncCallExpr{
 			FnName: model.NewCIStr($1),
-			Args:	[]ast.ExprNode{
-				$3.(ast.ExprNode),
-				$5.(ast.ExprNode),
-			},
+			Args:   []ast.ExprNode{timeUnit, $5},
 		}
 	}
-|	"FROM_DAYS" '(' Expression ')'
+|	"GET_FORMAT" '(' GetFormatSelector ',' Expression ')'
 	{
 		$$ = &ast.FuncCallExpr{
 			FnName: model.NewCIStr($1),
-			Args: []ast.ExprNode{$3.(ast.ExprNode)},
+			Args:   []ast.ExprNode{ast.NewValueExpr($3), $5},
 		}
 	}
-|	"EXTRACT" '(' TimeUnit "FROM" Expression ')'
+|	"POSITION" '(' BitExpr "IN" Expression ')'
 	{
-		timeUnit := ast.NewValueExpr($3)
-		$$ = &ast.FuncCallExpr{
-			FnName: model.NewCIStr($1.(string)),
-			Args: []ast.ExprNode{timeUnit, $5.(ast.ExprNode)},
-		}
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3, $5}}
 	}
-|	"FIND_IN_SET" '(' Expression ',' Expression ')'
+|	"SUBSTRING" '(' Expression ',' Expression ')'
 	{
 		$$ = &ast.FuncCallExpr{
 			FnName: model.NewCIStr($1),
-			Args:	[]ast.ExprNode{
-				$3.(ast.ExprNode),
-				$5.(ast.ExprNode),
-			},
+			Args:   []ast.ExprNode{$3, $5},
 		}
 	}
-|	"FOUND_ROWS" '(' ')'
-	{
-		$$ =  &ast.FuncCallExpr{FnName: model.NewCIStr($1)}
-	}
-|	"FROM_UNIXTIME" '(' Expression ')'
+|	"SUBSTRING" '(' Expression "FROM" Expression ')'
 	{
 		$$ = &ast.FuncCallExpr{
 			FnName: model.NewCIStr($1),
-			Args: []ast.ExprNode{$3.(ast.ExprNode)},
+			Args:   []ast.ExprNode{$3, $5},
 		}
 	}
-|	"FROM_UNIXTIME" '(' Expression ',' Expression ')'
+|	"SUBSTRING" '(' Expression ',' Expression ',' Expression ')'
 	{
 		$$ = &ast.FuncCallExpr{
 			FnName: model.NewCIStr($1),
-			Args: []ast.ExprNode{$3.(ast.ExprNode), $5.(ast.ExprNode)},
+			Args:   []ast.ExprNode{$3, $5, $7},
 		}
 	}
-|	"GREATEST" '(' ExpressionList ')'
-	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: $3.([]ast.ExprNode)}
-	}
-|	"LEAST" '(' ExpressionList ')'
-	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: $3.([]ast.ExprNode)}
-	}
-|	"HOUR" '(' Expression ')'
-	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
-	}
-|	"HEX" '(' Expression ')'
-	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
-	}
-|	"UNHEX" '(' Expression ')'
-	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
-	}
-
-|	"IFNULL" '(' ExpressionList ')'
-	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: $3.([]ast.ExprNode)}
-	}
-|	"ISNULL" '(' Expression ')'
-	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
-	}
-|	"LAST_INSERT_ID" '(' ExpressionOpt ')'
-	{
-		args := []ast.ExprNode{}
-		if $3 != nil {
-			args = append(args, $3.(ast.ExprNode))
-		}
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: args}
-	}
-|	"LENGTH" '(' Expression ')'
-	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
-	}
-|	"LN" '(' Expression ')'
-	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
-	}
-|	"LOCATE" '(' Expression ',' Expression ')'
+|	"SUBSTRING" '(' Expression "FROM" Expression "FOR" Expression ')'
 	{
 		$$ = &ast.FuncCallExpr{
 			FnName: model.NewCIStr($1),
-			Args: []ast.ExprNode{$3.(ast.ExprNode), $5.(ast.ExprNode)},
+			Args:   []ast.ExprNode{$3, $5, $7},
 		}
 	}
-|	"LOCATE" '(' Expression ',' Expression ',' Expression ')'
+|	"TIMESTAMPADD" '(' TimestampUnit ',' Expression ',' Expression ')'
 	{
 		$$ = &ast.FuncCallExpr{
 			FnName: model.NewCIStr($1),
-			Args: []ast.ExprNode{$3.(ast.ExprNode), $5.(ast.ExprNode), $7.(ast.ExprNode)},
+			Args:   []ast.ExprNode{ast.NewValueExpr($3), $5, $7},
 		}
 	}
-|	"LOG" '(' Expression ')'
-	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
-	}
-|	"LOG" '(' Expression ',' Expression ')'
-	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode), $5.(ast.ExprNode)}}
-	}
-|	"LOG2" '(' Expression ')'
-	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
-	}
-|	"LOG10" '(' Expression ')'
-	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
-	}
-|	"LOWER" '(' Expression ')'
-	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
-	}
-|	"LCASE" '(' Expression ')'
-	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
-	}
-|	"LTRIM" '(' Expression ')'
-	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
-	}
-|	"MICROSECOND" '(' Expression ')'
-	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
-	}
-|	"MINUTE" '(' Expression ')'
-	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
-	}
-|	"MONTH" '(' Expression ')'
-	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
-	}
-|	"MONTHNAME" '(' Expression ')'
-	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
-	}
-|	"NOW" '(' ExpressionOpt ')'
-	{
-		args := []ast.ExprNode{}
-		if $3 != nil {
-			args = append(args, $3.(ast.ExprNode))
-		}
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: args}
-	}
-|	"NULLIF" '(' ExpressionList ')'
-	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: $3.([]ast.ExprNode)}
-	}
-|	"POW" '(' Expression ',' Expression ')'
-	{
-		args := []ast.ExprNode{$3.(ast.ExprNode), $5.(ast.ExprNode)}
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: args}
-	}
-|	"POWER" '(' Expression ',' Expression ')'
-	{
-		args := []ast.ExprNode{$3.(ast.ExprNode), $5.(ast.ExprNode)}
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: args}
-	}
-|	"RAND" '(' ExpressionOpt ')'
-	{
-
-		args := []ast.ExprNode{}
-		if $3 != nil {
-			args = append(args, $3.(ast.ExprNode))
-		}
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: args}
-	}
-|	"REPLACE" '(' Expression ',' Expression ',' Expression ')'
-	{
-		args := []ast.ExprNode{$3.(ast.ExprNode), $5.(ast.ExprNode), $7.(ast.ExprNode)}
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: args}
-	}
-|	"REVERSE" '(' Expression ')'
-	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
-	}
-|	"RTRIM" '(' Expression ')'
-	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
-	}
-|	"SECOND" '(' Expression ')'
-	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
-	}
-|	"SIGN" '(' Expression ')'
-	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
-	}
-|	"SQRT" '(' Expression ')'
-	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
-	}
-
-|	"SLEEP" '(' Expression ')'
-	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
-	}
-|	"SPACE" '(' Expression ')'
-	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
-	}
-|	"STRCMP" '(' Expression ',' Expression ')'
-	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode), $5.(ast.ExprNode)}}
-	}
-|	"STR_TO_DATE" '(' Expression ',' Expression ')'
-	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode), $5.(ast.ExprNode)}}
-	}
-|	"SUBSTRING" '(' Expression ',' Expression ')'
+|	"TIMESTAMPDIFF" '(' TimestampUnit ',' Expression ',' Expression ')'
 	{
 		$$ = &ast.FuncCallExpr{
 			FnName: model.NewCIStr($1),
-			Args: []ast.ExprNode{$3.(ast.ExprNode), $5.(ast.ExprNode)},
+			Args:   []ast.ExprNode{ast.NewValueExpr($3), $5, $7},
 		}
 	}
-|	"SUBSTRING" '(' Expression "FROM" Expression ')'
+|	"TRIM" '(' Expression ')'
 	{
 		$$ = &ast.FuncCallExpr{
 			FnName: model.NewCIStr($1),
-			Args: []ast.ExprNode{$3.(ast.ExprNode), $5.(ast.ExprNode)},
+			Args:   []ast.ExprNode{$3},
 		}
 	}
-|	"SUBSTRING" '(' Expression ',' Expression ',' Expression ')'
+|	"TRIM" '(' Expression "FROM" Expression ')'
 	{
 		$$ = &ast.FuncCallExpr{
 			FnName: model.NewCIStr($1),
-			Args: []ast.ExprNode{$3.(ast.ExprNode), $5.(ast.ExprNode), $7.(ast.ExprNode)},
+			Args:   []ast.ExprNode{$5, $3},
 		}
 	}
-|	"SUBSTRING" '(' Expression "FROM" Expression "FOR" Expression ')'
+|	"TRIM" '(' TrimDirection "FROM" Expression ')'
 	{
+		nilVal := ast.NewValueExpr(nil)
+		direction := ast.NewValueExpr(int($3.(ast.TrimDirectionType)))
 		$$ = &ast.FuncCallExpr{
 			FnName: model.NewCIStr($1),
-			Args: []ast.ExprNode{$3.(ast.ExprNode), $5.(ast.ExprNode), $7.(ast.ExprNode)},
+			Args:   []ast.ExprNode{$5, nilVal, direction},
 		}
 	}
-|	"SUBSTRING_INDEX" '(' Expression ',' Expression ',' Expression ')'
+|	"TRIM" '(' TrimDirection Expression "FROM" Expression ')'
 	{
+		direction := ast.NewValueExpr(int($3.(ast.TrimDirectionType)))
 		$$ = &ast.FuncCallExpr{
 			FnName: model.NewCIStr($1),
-			Args: []ast.ExprNode{$3.(ast.ExprNode), $5.(ast.ExprNode), $7.(ast.ExprNode)},
+			Args:   []ast.ExprNode{$6, $4, direction},
 		}
 	}
-|	"SYSDATE" '(' ExpressionOpt ')'
+
+GetFormatSelector:
+	"DATE"
 	{
-		args := []ast.ExprNode{}
-		if $3 != nil {
-			args = append(args, $3.(ast.ExprNode))
-		}
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: args}
+		$$ = strings.ToUpper($1)
 	}
-|	"TIME" '(' Expression ')'
+|	"DATETIME"
 	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+		$$ = strings.ToUpper($1)
 	}
-|	"TIMEDIFF" '(' Expression ',' Expression ')'
+|	"TIME"
 	{
-		$$ = &ast.FuncCallExpr{
-			FnName: model.NewCIStr($1),
-			Args: []ast.ExprNode{$3.(ast.ExprNode), $5.(ast.ExprNode)},
-		}
+		$$ = strings.ToUpper($1)
 	}
-|	"TIMESTAMPDIFF" '(' TimeUnit ',' Expression ',' Expression ')'
+|	"TIMESTAMP"
 	{
-		$$ = &ast.FuncCallExpr{
-			FnName: model.NewCIStr($1),
-			Args: []ast.ExprNode{ast.NewValueExpr($3), $5.(ast.ExprNode), $7.(ast.ExprNode)},
-		}
+		$$ = strings.ToUpper($1)
 	}
-|	"TRIM" '(' Expression ')'
+
+FunctionNameDateArith:
+	"DATE_ADD"
+|	"DATE_SUB"
+
+FunctionNameDateArithMultiForms:
+	"ADDDATE"
+|	"SUBDATE"
+
+TrimDirection:
+	"BOTH"
 	{
-		$$ = &ast.FuncCallExpr{
-			FnName: model.NewCIStr($1),
-			Args: []ast.ExprNode{$3.(ast.ExprNode)},
-		}
+		$$ = ast.TrimBoth
 	}
-|	"TRIM" '(' Expression "FROM" Expression ')'
+|	"LEADING"
 	{
-		$$ = &ast.FuncCallExpr{
-			FnName: model.NewCIStr($1),
-			Args: []ast.ExprNode{$5.(ast.ExprNode), $3.(ast.ExprNode)},
-		}
+		$$ = ast.TrimLeading
 	}
-|	"TRIM" '(' TrimDirection "FROM" Expression ')'
+|	"TRAILING"
 	{
-		nilVal := ast.NewValueExpr(nil)
-		direction := ast.NewValueExpr($3)
-		$$ = &ast.FuncCallExpr{
-			FnName: model.NewCIStr($1),
-			Args: []ast.ExprNode{$5.(ast.ExprNode), nilVal, direction},
-		}
+		$$ = ast.TrimTrailing
 	}
-|	"TRIM" '(' TrimDirection Expression "FROM" Expression ')'
+
+SumExpr:
+	"AVG" '(' BuggyDefaultFalseDistinctOpt Expression ')'
 	{
-		direction := ast.NewValueExpr($3)
-		$$ = &ast.FuncCallExpr{
-			FnName: model.NewCIStr($1),
-			Args: []ast.ExprNode{$6.(ast.ExprNode),$4.(ast.ExprNode), direction},
-		}
+		$$ = &ast.AggregateFuncExpr{F: $1, Args: []ast.ExprNode{$4}, Distinct: $3.(bool)}
 	}
-|	"UPPER" '(' Expression ')'
+|	"BIT_XOR" '(' Expression ')'
 	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+		$$ = &ast.AggregateFuncExpr{F: $1, Args: []ast.ExprNode{$3}}
 	}
-|	"UCASE" '(' Expression ')'
+|	"COUNT" '(' DistinctKwd ExpressionList ')'
 	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+		$$ = &ast.AggregateFuncExpr{F: $1, Args: $4.([]ast.ExprNode), Distinct: true}
 	}
-|	"UNIX_TIMESTAMP" '(' ')'
+|	"COUNT" '(' "ALL" Expression ')'
 	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1)}
+		$$ = &ast.AggregateFuncExpr{F: $1, Args: []ast.ExprNode{$4}}
 	}
-|	"UNIX_TIMESTAMP" '(' Expression ')'
+|	"COUNT" '(' Expression ')'
 	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+		$$ = &ast.AggregateFuncExpr{F: $1, Args: []ast.ExprNode{$3}}
 	}
-|	"WEEKDAY" '(' Expression ')'
+|	"COUNT" '(' '*' ')'
 	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+		args := []ast.ExprNode{ast.NewValueExpr(1)}
+		$$ = &ast.AggregateFuncExpr{F: $1, Args: args}
 	}
-|	"WEEKOFYEAR" '(' Expression ')'
+|	"GROUP_CONCAT" '(' BuggyDefaultFalseDistinctOpt ExpressionList OrderByOptional OptGConcatSeparator ')'
 	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+		args := $4.([]ast.ExprNode)
+		args = append(args, $6.(ast.ExprNode))
+		agg := &ast.AggregateFuncExpr{F: $1, Args: args, Distinct: $3.(bool)}
+		if $5 != nil {
+			agg.Order = $5.(*ast.OrderByClause)
+		}
+		$$ = agg
 	}
-|	"YEARWEEK" '(' ExpressionList ')'
+|	"MAX" '(' BuggyDefaultFalseDistinctOpt Expression ')'
 	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: $3.([]ast.ExprNode)}
+		$$ = &ast.AggregateFuncExpr{F: $1, Args: []ast.ExprNode{$4}, Distinct: $3.(bool)}
 	}
-|	"CONNECTION_ID" '(' ')'
+|	"MIN" '(' BuggyDefaultFalseDistinctOpt Expression ')'
 	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1)}
+		$$ = &ast.AggregateFuncExpr{F: $1, Args: []ast.ExprNode{$4}, Distinct: $3.(bool)}
+	}
+|	"SUM" '(' BuggyDefaultFalseDistinctOpt Expression ')'
+	{
+		$$ = &ast.AggregateFuncExpr{F: $1, Args: []ast.ExprNode{$4}, Distinct: $3.(bool)}
+	}
+
+OptGConcatSeparator:
+	{
+		$$ = ast.NewValueExpr(",")
+	}
+|	"SEPARATOR" "string literal"
+	{
+		$$ = ast.NewValueExpr($2)
 	}
-|	"ROUND" '(' ExpressionList ')'
+
+FunctionCallGeneric:
+	"identifier" '(' ExpressionListOpt ')'
 	{
 		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: $3.([]ast.ExprNode)}
 	}
-|	"GET_LOCK" '(' Expression ',' Expression ')'
+
+FuncDatetimePrec:
 	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode), $5.(ast.ExprNode)}}
+		$$ = nil
 	}
-|	"RELEASE_LOCK" '(' Expression ')'
+|	'(' ')'
 	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+		$$ = nil
 	}
-|	"RPAD" '(' Expression ',' Expression ',' Expression ')'
+|	'(' "integer literal" ')'
 	{
-		$$ = &ast.FuncCallExpr{
-			FnName: model.NewCIStr($1),
-			Args: []ast.ExprNode{$3.(ast.ExprNode), $5.(ast.ExprNode), $7.(ast.ExprNode)},
-		}
+		expr := ast.NewValueExpr($2)
+		$$ = expr
 	}
-|	"BIT_LENGTH" '(' Expression ')'
+
+TimeUnit:
+	"MICROSECOND"
 	{
-		$$ = &ast.FuncCallExpr{
-			FnName: model.NewCIStr($1),
-			Args: []ast.ExprNode{$3.(ast.ExprNode)},
-		}
+		$$ = strings.ToUpper($1)
 	}
-|   "CHAR" '(' ExpressionList ')'
-    {
-		nilVal := ast.NewValueExpr(nil)
-		args := $3.([]ast.ExprNode)
-		$$ = &ast.FuncCallExpr{
-			FnName: model.NewCIStr(ast.CharFunc),
-			Args: append(args, nilVal),
-		}
-    }
-|   "CHAR" '(' ExpressionList "USING" StringName ')'
-    {
-		charset := ast.NewValueExpr($5)
-		args := $3.([]ast.ExprNode)
-		$$ = &ast.FuncCallExpr{
-			FnName: model.NewCIStr(ast.CharFunc),
-			Args: append(args, charset),
-		}
-    }
-|	"CHAR_LENGTH" '(' Expression ')'
+|	"SECOND"
 	{
-		$$ = &ast.FuncCallExpr{
-			FnName: model.NewCIStr($1),
-			Args: []ast.ExprNode{$3.(ast.ExprNode)},
-		}
+		$$ = strings.ToUpper($1)
 	}
-|	"CHARACTER_LENGTH" '(' Expression ')'
+|	"MINUTE"
 	{
-		$$ = &ast.FuncCallExpr{
-			FnName: model.NewCIStr(ast.CharLength),
-			Args: []ast.ExprNode{$3.(ast.ExprNode)},
-		}
+		$$ = strings.ToUpper($1)
 	}
-|	"CONV" '(' Expression ',' Expression ',' Expression ')'
+|	"HOUR"
 	{
-		$$ = &ast.FuncCallExpr{
-			FnName: model.NewCIStr($1),
-			Args: []ast.ExprNode{$3.(ast.ExprNode), $5.(ast.ExprNode), $7.(ast.ExprNode)},
-		}
+		$$ = strings.ToUpper($1)
+	}
+|	"DAY"
+	{
+		$$ = strings.ToUpper($1)
 	}
-|	"CRC32" '(' Expression ')'
+|	"WEEK"
 	{
-		$$ = &ast.FuncCallExpr{
-			FnName: model.NewCIStr($1),
-			Args: []ast.ExprNode{$3.(ast.ExprNode)},
-		}
+		$$ = strings.ToUpper($1)
 	}
-
-
-FunctionNameDateArith:
-	"DATE_ADD"
-|	"DATE_SUB"
-
-
-FunctionNameDateArithMultiForms:
-	"ADDDATE"
-|	"SUBDATE"
-
-
-TrimDirection:
-	"BOTH"
+|	"MONTH"
 	{
-		$$ = ast.TrimBoth
+		$$ = strings.ToUpper($1)
 	}
-|	"LEADING"
+|	"QUARTER"
 	{
-		$$ = ast.TrimLeading
+		$$ = strings.ToUpper($1)
 	}
-|	"TRAILING"
+|	"YEAR"
 	{
-		$$ = ast.TrimTrailing
+		$$ = strings.ToUpper($1)
 	}
-
-FunctionCallAgg:
-	"AVG" '(' DistinctOpt Expression ')'
+|	"SECOND_MICROSECOND"
+	{
+		$$ = strings.ToUpper($1)
+	}
+|	"MINUTE_MICROSECOND"
 	{
-		$$ = &ast.AggregateFuncExpr{F: $1, Args: []ast.ExprNode{$4.(ast.ExprNode)}, Distinct: $3.(bool)}
+		$$ = strings.ToUpper($1)
 	}
-|	"BIT_XOR" '(' Expression ')'
+|	"MINUTE_SECOND"
 	{
-		$$ = &ast.AggregateFuncExpr{F: $1, Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+		$$ = strings.ToUpper($1)
 	}
-|	"COUNT" '(' "DISTINCT" ExpressionList ')'
+|	"HOUR_MICROSECOND"
 	{
-		$$ = &ast.AggregateFuncExpr{F: $1, Args: $4.([]ast.ExprNode), Distinct: true}
+		$$ = strings.ToUpper($1)
 	}
-|	"COUNT" '(' "ALL" Expression ')'
+|	"HOUR_SECOND"
 	{
-		$$ = &ast.AggregateFuncExpr{F: $1, Args: []ast.ExprNode{$4.(ast.ExprNode)}}
+		$$ = strings.ToUpper($1)
 	}
-|	"COUNT" '(' Expression ')'
+|	"HOUR_MINUTE"
 	{
-		$$ = &ast.AggregateFuncExpr{F: $1, Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+		$$ = strings.ToUpper($1)
 	}
-|	"COUNT" '(' '*' ')'
+|	"DAY_MICROSECOND"
 	{
-		args := []ast.ExprNode{ast.NewValueExpr(1)}
-		$$ = &ast.AggregateFuncExpr{F: $1, Args: args}
+		$$ = strings.ToUpper($1)
 	}
-|	"GROUP_CONCAT" '(' DistinctOpt ExpressionList ')'
+|	"DAY_SECOND"
 	{
-		$$ = &ast.AggregateFuncExpr{F: $1, Args: $4.([]ast.ExprNode), Distinct: $3.(bool)}
+		$$ = strings.ToUpper($1)
 	}
-|	"MAX" '(' DistinctOpt Expression ')'
+|	"DAY_MINUTE"
 	{
-		$$ = &ast.AggregateFuncExpr{F: $1, Args: []ast.ExprNode{$4.(ast.ExprNode)}, Distinct: $3.(bool)}
+		$$ = strings.ToUpper($1)
 	}
-|	"MIN" '(' DistinctOpt Expression ')'
+|	"DAY_HOUR"
 	{
-		$$ = &ast.AggregateFuncExpr{F: $1, Args: []ast.ExprNode{$4.(ast.ExprNode)}, Distinct: $3.(bool)}
+		$$ = strings.ToUpper($1)
 	}
-|	"SUM" '(' DistinctOpt Expression ')'
+|	"YEAR_MONTH"
 	{
-		$$ = &ast.AggregateFuncExpr{F: $1, Args: []ast.ExprNode{$4.(ast.ExprNode)}, Distinct: $3.(bool)}
+		$$ = strings.ToUpper($1)
 	}
 
-FuncDatetimePrec:
+TimestampUnit:
+	"MICROSECOND"
 	{
-		$$ = nil
+		$$ = strings.ToUpper($1)
 	}
-|	'(' ')'
+|	"SECOND"
 	{
-		$$ = nil
+		$$ = strings.ToUpper($1)
 	}
-|	'(' Expression ')'
+|	"MINUTE"
 	{
-		$$ = $2
+		$$ = strings.ToUpper($1)
 	}
-
-TimeUnit:
-	"MICROSECOND"
-|	"SECOND"
-|	"MINUTE"
 |	"HOUR"
+	{
+		$$ = strings.ToUpper($1)
+	}
 |	"DAY"
+	{
+		$$ = strings.ToUpper($1)
+	}
 |	"WEEK"
+	{
+		$$ = strings.ToUpper($1)
+	}
 |	"MONTH"
+	{
+		$$ = strings.ToUpper($1)
+	}
 |	"QUARTER"
+	{
+		$$ = strings.ToUpper($1)
+	}
 |	"YEAR"
-|	"SECOND_MICROSECOND"
-|	"MINUTE_MICROSECOND"
-|	"MINUTE_SECOND"
-|	"HOUR_MICROSECOND"
-|	"HOUR_SECOND"
-|	"HOUR_MINUTE"
-|	"DAY_MICROSECOND"
-|	"DAY_SECOND"
-|	"DAY_MINUTE"
-|	"DAY_HOUR"
-|	"YEAR_MONTH"
+	{
+		$$ = strings.ToUpper($1)
+	}
 
 ExpressionOpt:
 	{
@@ -3293,13 +3829,12 @@ WhenClause:
 	"WHEN" Expression "THEN" Expression
 	{
 		$$ = &ast.WhenClause{
-			Expr: $2.(ast.ExprNode),
-			Result: $4.(ast.ExprNode),
+			Expr:   $2,
+			Result: $4,
 		}
 	}
 
 ElseOpt:
-	/* empty */
 	{
 		$$ = nil
 	}
@@ -3311,31 +3846,46 @@ ElseOpt:
 CastType:
 	"BINARY" OptFieldLen
 	{
-		x := types.NewFieldType(mysql.TypeString)
-		x.Flen = $2.(int)
+		x := types.NewFieldType(mysql.TypeVarString)
+		x.Flen = $2.(int) // TODO: Flen should be the flen of expression
 		x.Charset = charset.CharsetBin
-		x.Collate = charset.CharsetBin
+		x.Collate = charset.CollationBin
+		x.Flag |= mysql.BinaryFlag
 		$$ = x
 	}
 |	"CHAR" OptFieldLen OptBinary OptCharset
 	{
-		x := types.NewFieldType(mysql.TypeString)
-		x.Flen = $2.(int)
+		x := types.NewFieldType(mysql.TypeVarString)
+		x.Flen = $2.(int) // TODO: Flen should be the flen of expression
+		x.Charset = $4.(string)
 		if $3.(bool) {
 			x.Flag |= mysql.BinaryFlag
 		}
-		x.Charset = $4.(string)
+		if x.Charset == "" {
+			x.Charset = charset.CharsetUTF8
+			x.Collate = charset.CollationUTF8
+		}
 		$$ = x
 	}
 |	"DATE"
 	{
 		x := types.NewFieldType(mysql.TypeDate)
+		x.Charset = charset.CharsetBin
+		x.Collate = charset.CollationBin
+		x.Flag |= mysql.BinaryFlag
 		$$ = x
 	}
 |	"DATETIME" OptFieldLen
 	{
 		x := types.NewFieldType(mysql.TypeDatetime)
+		x.Flen, _ = mysql.GetDefaultFieldLengthAndDecimalForCast(mysql.TypeDatetime)
 		x.Decimal = $2.(int)
+		if x.Decimal > 0 {
+			x.Flen = x.Flen + 1 + x.Decimal
+		}
+		x.Charset = charset.CharsetBin
+		x.Collate = charset.CollationBin
+		x.Flag |= mysql.BinaryFlag
 		$$ = x
 	}
 |	"DECIMAL" FloatOpt
@@ -3344,98 +3894,64 @@ CastType:
 		x := types.NewFieldType(mysql.TypeNewDecimal)
 		x.Flen = fopt.Flen
 		x.Decimal = fopt.Decimal
-		if fopt.Flen == types.UnspecifiedLength {
-			x.Flen = mysql.GetDefaultFieldLength(mysql.TypeNewDecimal)
-			x.Decimal = mysql.GetDefaultDecimal(mysql.TypeNewDecimal)
-		}
+		x.Charset = charset.CharsetBin
+		x.Collate = charset.CollationBin
+		x.Flag |= mysql.BinaryFlag
 		$$ = x
 	}
 |	"TIME" OptFieldLen
 	{
 		x := types.NewFieldType(mysql.TypeDuration)
+		x.Flen, _ = mysql.GetDefaultFieldLengthAndDecimalForCast(mysql.TypeDuration)
 		x.Decimal = $2.(int)
+		if x.Decimal > 0 {
+			x.Flen = x.Flen + 1 + x.Decimal
+		}
+		x.Charset = charset.CharsetBin
+		x.Collate = charset.CollationBin
+		x.Flag |= mysql.BinaryFlag
 		$$ = x
 	}
 |	"SIGNED" OptInteger
 	{
 		x := types.NewFieldType(mysql.TypeLonglong)
+		x.Charset = charset.CharsetBin
+		x.Collate = charset.CollationBin
+		x.Flag |= mysql.BinaryFlag
 		$$ = x
 	}
 |	"UNSIGNED" OptInteger
 	{
 		x := types.NewFieldType(mysql.TypeLonglong)
-		x.Flag |= mysql.UnsignedFlag
+		x.Flag |= mysql.UnsignedFlag | mysql.BinaryFlag
+		x.Charset = charset.CharsetBin
+		x.Collate = charset.CollationBin
 		$$ = x
 	}
-
-
-PrimaryFactor:
-	PrimaryFactor '|' PrimaryFactor %prec '|'
-	{
-		$$ = &ast.BinaryOperationExpr{Op: opcode.Or, L: $1.(ast.ExprNode), R: $3.(ast.ExprNode)}
-	}
-|	PrimaryFactor '&' PrimaryFactor %prec '&'
-	{
-		$$ = &ast.BinaryOperationExpr{Op: opcode.And, L: $1.(ast.ExprNode), R: $3.(ast.ExprNode)}
-	}
-|	PrimaryFactor "<<" PrimaryFactor %prec lsh
-	{
-		$$ = &ast.BinaryOperationExpr{Op: opcode.LeftShift, L: $1.(ast.ExprNode), R: $3.(ast.ExprNode)}
-	}
-|	PrimaryFactor ">>" PrimaryFactor %prec rsh
-	{
-		$$ = &ast.BinaryOperationExpr{Op: opcode.RightShift, L: $1.(ast.ExprNode), R: $3.(ast.ExprNode)}
-	}
-|	PrimaryFactor '+' PrimaryFactor %prec '+'
+|	"JSON"
 	{
-		$$ = &ast.BinaryOperationExpr{Op: opcode.Plus, L: $1.(ast.ExprNode), R: $3.(ast.ExprNode)}
-	}
-|	PrimaryFactor '-' PrimaryFactor %prec '-'
-	{
-		$$ = &ast.BinaryOperationExpr{Op: opcode.Minus, L: $1.(ast.ExprNode), R: $3.(ast.ExprNode)}
-	}
-|	PrimaryFactor '*' PrimaryFactor %prec '*'
-	{
-		$$ = &ast.BinaryOperationExpr{Op: opcode.Mul, L: $1.(ast.ExprNode), R: $3.(ast.ExprNode)}
-	}
-|	PrimaryFactor '/' PrimaryFactor %prec '/'
-	{
-		$$ = &ast.BinaryOperationExpr{Op: opcode.Div, L: $1.(ast.ExprNode), R: $3.(ast.ExprNode)}
-	}
-|	PrimaryFactor '%' PrimaryFactor %prec '%'
-	{
-		$$ = &ast.BinaryOperationExpr{Op: opcode.Mod, L: $1.(ast.ExprNode), R: $3.(ast.ExprNode)}
-	}
-|	PrimaryFactor "DIV" PrimaryFactor %prec div
-	{
-		$$ = &ast.BinaryOperationExpr{Op: opcode.IntDiv, L: $1.(ast.ExprNode), R: $3.(ast.ExprNode)}
-	}
-|	PrimaryFactor "MOD" PrimaryFactor %prec mod
-	{
-		$$ = &ast.BinaryOperationExpr{Op: opcode.Mod, L: $1.(ast.ExprNode), R: $3.(ast.ExprNode)}
-	}
-|	PrimaryFactor '^' PrimaryFactor
-	{
-		$$ = &ast.BinaryOperationExpr{Op: opcode.Xor, L: $1.(ast.ExprNode), R: $3.(ast.ExprNode)}
+		x := types.NewFieldType(mysql.TypeJSON)
+		x.Flag |= mysql.BinaryFlag | (mysql.ParseToJSONFlag)
+		x.Charset = charset.CharsetUTF8
+		x.Collate = charset.CollationUTF8
+		$$ = x
 	}
-|	PrimaryExpression
-
 
 Priority:
 	{
-		$$ = ast.NoPriority
+		$$ = mysql.NoPriority
 	}
 |	"LOW_PRIORITY"
 	{
-		$$ = ast.LowPriority
+		$$ = mysql.LowPriority
 	}
 |	"HIGH_PRIORITY"
 	{
-		$$ = ast.HighPriority
+		$$ = mysql.HighPriority
 	}
 |	"DELAYED"
 	{
-		$$ = ast.DelayedPriority
+		$$ = mysql.DelayedPriority
 	}
 
 LowPriorityOptional:
@@ -3450,11 +3966,11 @@ LowPriorityOptional:
 TableName:
 	Identifier
 	{
-		$$ = &ast.TableName{Name:model.NewCIStr($1)}
+		$$ = &ast.TableName{Name: model.NewCIStr($1)}
 	}
-|	IdentifierOrReservedKeyword '.' IdentifierOrReservedKeyword
+|	Identifier '.' Identifier
 	{
-		$$ = &ast.TableName{Schema:model.NewCIStr($1),	Name:model.NewCIStr($3)}
+		$$ = &ast.TableName{Schema: model.NewCIStr($1), Name: model.NewCIStr($3)}
 	}
 
 TableNameList:
@@ -3469,7 +3985,7 @@ TableNameList:
 	}
 
 QuickOptional:
-	%prec lowerThanQuick
+	%prec empty
 	{
 		$$ = false
 	}
@@ -3478,15 +3994,6 @@ QuickOptional:
 		$$ = true
 	}
 
-/***************************Prepared Statement Start******************************
- * See https://dev.mysql.com/doc/refman/5.7/en/prepare.html
- * Example:
- * PREPARE stmt_name FROM 'SELECT SQRT(POW(?,2) + POW(?,2)) AS hypotenuse';
- * OR
- * SET @s = 'SELECT SQRT(POW(?,2) + POW(?,2)) AS hypotenuse';
- * PREPARE stmt_name FROM @s;
- */
-
 PreparedStmt:
 	"PREPARE" Identifier "FROM" PrepareSQL
 	{
@@ -3499,19 +4006,21 @@ PreparedStmt:
 			sqlVar = $4.(*ast.VariableExpr)
 		}
 		$$ = &ast.PrepareStmt{
-			Name:		$2,
-			SQLText:	sqlText,
-			SQLVar: 	sqlVar,
+			Name:    $2,
+			SQLText: sqlText,
+			SQLVar:  sqlVar,
 		}
 	}
 
 PrepareSQL:
-	stringLit
+	"string literal"
 	{
 		$$ = $1
 	}
 |	UserVariable
-
+	{
+		$$ = $1.(interface{})
+	}
 
 /*
  * See https://dev.mysql.com/doc/refman/5.7/en/execute.html
@@ -3528,7 +4037,7 @@ ExecuteStmt:
 |	"EXECUTE" Identifier "USING" UserVariableList
 	{
 		$$ = &ast.ExecuteStmt{
-			Name: $2,
+			Name:      $2,
 			UsingVars: $4.([]ast.ExprNode),
 		}
 	}
@@ -3536,17 +4045,13 @@ ExecuteStmt:
 UserVariableList:
 	UserVariable
 	{
-		$$ = []ast.ExprNode{$1.(ast.ExprNode)}
+		$$ = []ast.ExprNode{$1}
 	}
 |	UserVariableList ',' UserVariable
 	{
-		$$ = append($1.([]ast.ExprNode), $3.(ast.ExprNode))
+		$$ = append($1.([]ast.ExprNode), $3)
 	}
 
-/*
- * See https://dev.mysql.com/doc/refman/5.0/en/deallocate-prepare.html
- */
-
 DeallocateStmt:
 	DeallocateSym "PREPARE" Identifier
 	{
@@ -3554,10 +4059,8 @@ DeallocateStmt:
 	}
 
 DeallocateSym:
-"DEALLOCATE" | "DROP"
-
-/****************************Prepared Statement End*******************************/
-
+	"DEALLOCATE"
+|	"DROP"
 
 RollbackStmt:
 	"ROLLBACK"
@@ -3566,21 +4069,26 @@ RollbackStmt:
 	}
 
 SelectStmt:
-	"SELECT" SelectStmtOpts SelectStmtFieldList SelectStmtLimit SelectLockOpt
+	"SELECT" SelectStmtOpts SelectStmtFieldList OrderByOptional SelectStmtLimit SelectLockOpt SelectStmtIntoOption
 	{
-		st := &ast.SelectStmt {
-			Distinct:      $2.(bool),
-			Fields:        $3.(*ast.FieldList),
-			LockTp:	       $5.(ast.SelectLockType),
+		st := &ast.SelectStmt{
+			SelectStmtOpts: $2.(*ast.SelectStmtOpts),
+			Distinct:       $2.(*ast.SelectStmtOpts).Distinct,
+			Fields:         $3.(*ast.FieldList),
+			LockTp:         $6.(ast.SelectLockType),
 		}
 		lastField := st.Fields.Fields[len(st.Fields.Fields)-1]
 		if lastField.Expr != nil && lastField.AsName.O == "" {
 			src := parser.src
 			var lastEnd int
 			if $4 != nil {
-				lastEnd = yyS[yypt-1].offset-1
-			} else if $5 != ast.SelectLockNone {
-				lastEnd = yyS[yypt].offset-1
+				lastEnd = yyS[yypt-3].offset - 1
+			} else if $5 != nil {
+				lastEnd = yyS[yypt-2].offset - 1
+			} else if $6 != ast.SelectLockNone {
+				lastEnd = yyS[yypt-1].offset - 1
+			} else if $7 != nil {
+				lastEnd = yyS[yypt].offset - 1
 			} else {
 				lastEnd = len(src)
 				if src[lastEnd-1] == ';' {
@@ -3590,44 +4098,60 @@ SelectStmt:
 			lastField.SetText(src[lastField.Offset:lastEnd])
 		}
 		if $4 != nil {
-			st.Limit = $4.(*ast.Limit)
+			st.OrderBy = $4.(*ast.OrderByClause)
+		}
+		if $5 != nil {
+			st.Limit = $5.(*ast.Limit)
+		}
+		if $7 != nil {
+			st.SelectIntoOpt = $7.(*ast.SelectIntoOption)
 		}
 		$$ = st
 	}
-|	"SELECT" SelectStmtOpts SelectStmtFieldList FromDual WhereClauseOptional SelectStmtLimit SelectLockOpt
+|	"SELECT" SelectStmtOpts SelectStmtFieldList FromDual WhereClauseOptional OrderByOptional SelectStmtLimit SelectLockOpt SelectStmtIntoOption
 	{
-		st := &ast.SelectStmt {
-			Distinct:      $2.(bool),
-			Fields:        $3.(*ast.FieldList),
-			LockTp:	       $7.(ast.SelectLockType),
+		st := &ast.SelectStmt{
+			SelectStmtOpts: $2.(*ast.SelectStmtOpts),
+			Distinct:       $2.(*ast.SelectStmtOpts).Distinct,
+			Fields:         $3.(*ast.FieldList),
+			LockTp:         $8.(ast.SelectLockType),
 		}
 		lastField := st.Fields.Fields[len(st.Fields.Fields)-1]
 		if lastField.Expr != nil && lastField.AsName.O == "" {
-			lastEnd := yyS[yypt-3].offset-1
+			lastEnd := yyS[yypt-5].offset - 1
 			lastField.SetText(parser.src[lastField.Offset:lastEnd])
 		}
 		if $5 != nil {
 			st.Where = $5.(ast.ExprNode)
 		}
 		if $6 != nil {
-			st.Limit = $6.(*ast.Limit)
+			st.OrderBy = $6.(*ast.OrderByClause)
+		}
+		if $7 != nil {
+			st.Limit = $7.(*ast.Limit)
+		}
+		if $9 != nil {
+			st.SelectIntoOpt = $9.(*ast.SelectIntoOption)
 		}
 		$$ = st
 	}
-|	"SELECT" SelectStmtOpts SelectStmtFieldList "FROM"
-	TableRefsClause WhereClauseOptional SelectStmtGroup HavingClause OrderByOptional
-	SelectStmtLimit SelectLockOpt
+|	"SELECT" SelectStmtOpts SelectStmtFieldList "FROM" TableRefsClause WhereClauseOptional SelectStmtGroup HavingClause OrderByOptional SelectStmtLimit SelectLockOpt SelectStmtIntoOption
 	{
+		opts := $2.(*ast.SelectStmtOpts)
 		st := &ast.SelectStmt{
-			Distinct:	$2.(bool),
-			Fields:		$3.(*ast.FieldList),
-			From:		$5.(*ast.TableRefsClause),
-			LockTp:		$11.(ast.SelectLockType),
+			SelectStmtOpts: $2.(*ast.SelectStmtOpts),
+			Distinct:       opts.Distinct,
+			Fields:         $3.(*ast.FieldList),
+			From:           $5.(*ast.TableRefsClause),
+			LockTp:         $11.(ast.SelectLockType),
+		}
+		if opts.TableHints != nil {
+			st.TableHints = opts.TableHints
 		}
 
 		lastField := st.Fields.Fields[len(st.Fields.Fields)-1]
 		if lastField.Expr != nil && lastField.AsName.O == "" {
-			lastEnd := parser.endOffset(&yyS[yypt-7])
+			lastEnd := parser.endOffset(&yyS[yypt-8])
 			lastField.SetText(parser.src[lastField.Offset:lastEnd])
 		}
 
@@ -3651,12 +4175,16 @@ SelectStmt:
 			st.Limit = $10.(*ast.Limit)
 		}
 
+		if $12 != nil {
+			st.SelectIntoOpt = $12.(*ast.SelectIntoOption)
+		}
+
 		$$ = st
 	}
 
 FromDual:
 	"FROM" "DUAL"
-
+	{}
 
 TableRefsClause:
 	TableRefs
@@ -3690,7 +4218,7 @@ EscapedTableRef:
 		/*
 		* ODBC escape syntax for outer join is { OJ join_table }
 		* Use an Identifier for OJ
-		*/
+		 */
 		$$ = $3
 	}
 
@@ -3722,6 +4250,18 @@ TableFactor:
 	{
 		$$ = &ast.TableSource{Source: $2.(*ast.UnionStmt), AsName: $4.(model.CIStr)}
 	}
+|	'(' WithSelectStmt ')' TableAsName
+	{
+		$$ = &ast.TableSource{Source: $2.(ast.ResultSetNode), AsName: $4.(model.CIStr)}
+	}
+|	'(' SelectStmt ')'
+	{
+		// The planner rejects the derived table for its missing alias.
+		st := $2.(*ast.SelectStmt)
+		endOffset := parser.endOffset(&yyS[yypt])
+		parser.setLastSelectFieldText(st, endOffset)
+		$$ = &ast.TableSource{Source: st}
+	}
 |	'(' TableRefs ')'
 	{
 		$$ = $2
@@ -3777,14 +4317,13 @@ IndexHintScope:
 		$$ = ast.HintForGroupBy
 	}
 
-
 IndexHint:
 	IndexHintType IndexHintScope '(' IndexNameList ')'
 	{
 		$$ = &ast.IndexHint{
-			IndexNames:	$4.([]model.CIStr),
-			HintType:	$1.(ast.IndexHintType),
-			HintScope:	$2.(ast.IndexHintScope),
+			IndexNames: $4.([]model.CIStr),
+			HintType:   $1.(ast.IndexHintType),
+			HintScope:  $2.(ast.IndexHintScope),
 		}
 	}
 
@@ -3793,15 +4332,18 @@ IndexNameList:
 		var nameList []model.CIStr
 		$$ = nameList
 	}
-|	Identifier
+|	IndexHintName
 	{
 		$$ = []model.CIStr{model.NewCIStr($1)}
 	}
-|	IndexNameList ',' Identifier
+|	IndexNameList ',' IndexHintName
 	{
 		$$ = append($1.([]model.CIStr), model.NewCIStr($3))
 	}
 
+IndexHintName:
+	Identifier
+|	"PRIMARY"
 
 IndexHintList:
 	IndexHint
@@ -3809,9 +4351,9 @@ IndexHintList:
 		$$ = []*ast.IndexHint{$1.(*ast.IndexHint)}
 	}
 |	IndexHintList IndexHint
- 	{
- 		$$ = append($1.([]*ast.IndexHint), $2.(*ast.IndexHint))
- 	}
+	{
+		$$ = append($1.([]*ast.IndexHint), $2.(*ast.IndexHint))
+	}
 
 IndexHintListOpt:
 	{
@@ -3824,22 +4366,45 @@ IndexHintListOpt:
 	}
 
 JoinTable:
-	/* Use %prec to evaluate production TableRef before cross join */
 	TableRef CrossOpt TableRef %prec tableRefPriority
 	{
 		$$ = &ast.Join{Left: $1.(ast.ResultSetNode), Right: $3.(ast.ResultSetNode), Tp: ast.CrossJoin}
 	}
 |	TableRef CrossOpt TableRef "ON" Expression
 	{
-		on := &ast.OnCondition{Expr: $5.(ast.ExprNode)}
+		on := &ast.OnCondition{Expr: $5}
 		$$ = &ast.Join{Left: $1.(ast.ResultSetNode), Right: $3.(ast.ResultSetNode), Tp: ast.CrossJoin, On: on}
 	}
+|	TableRef CrossOpt TableRef "USING" '(' ColumnNameList ')'
+	{
+		$$ = &ast.Join{Left: $1.(ast.ResultSetNode), Right: $3.(ast.ResultSetNode), Tp: ast.CrossJoin, Using: $6.([]*ast.ColumnName)}
+	}
+|	TableRef "STRAIGHT_JOIN" TableRef %prec tableRefPriority
+	{
+		$$ = &ast.Join{Left: $1.(ast.ResultSetNode), Right: $3.(ast.ResultSetNode), Tp: ast.CrossJoin, StraightJoin: true}
+	}
+|	TableRef "STRAIGHT_JOIN" TableRef "ON" Expression
+	{
+		on := &ast.OnCondition{Expr: $5}
+		$$ = &ast.Join{Left: $1.(ast.ResultSetNode), Right: $3.(ast.ResultSetNode), Tp: ast.CrossJoin, On: on, StraightJoin: true}
+	}
 |	TableRef JoinType OuterOpt "JOIN" TableRef "ON" Expression
 	{
-		on := &ast.OnCondition{Expr: $7.(ast.ExprNode)}
+		on := &ast.OnCondition{Expr: $7}
 		$$ = &ast.Join{Left: $1.(ast.ResultSetNode), Right: $5.(ast.ResultSetNode), Tp: $2.(ast.JoinType), On: on}
 	}
-	/* Support Using */
+|	TableRef JoinType OuterOpt "JOIN" TableRef "USING" '(' ColumnNameList ')'
+	{
+		$$ = &ast.Join{Left: $1.(ast.ResultSetNode), Right: $5.(ast.ResultSetNode), Tp: $2.(ast.JoinType), Using: $8.([]*ast.ColumnName)}
+	}
+|	TableRef "NATURAL" "JOIN" TableRef
+	{
+		$$ = &ast.Join{Left: $1.(ast.ResultSetNode), Right: $4.(ast.ResultSetNode), NaturalJoin: true}
+	}
+|	TableRef "NATURAL" JoinType OuterOpt "JOIN" TableRef
+	{
+		$$ = &ast.Join{Left: $1.(ast.ResultSetNode), Right: $6.(ast.ResultSetNode), Tp: $3.(ast.JoinType), NaturalJoin: true}
+	}
 
 JoinType:
 	"LEFT"
@@ -3872,9 +4437,9 @@ LimitClause:
 LimitOption:
 	LengthNum
 	{
-		$$ = ast.NewValueExpr($1)	
+		$$ = ast.NewValueExpr($1)
 	}
-|	"PLACEHOLDER"
+|	paramMarker
 	{
 		$$ = &ast.ParamMarkerExpr{
 			Offset: yyS[yypt].offset,
@@ -3898,29 +4463,49 @@ SelectStmtLimit:
 		$$ = &ast.Limit{Offset: $4.(ast.ExprNode), Count: $2.(ast.ExprNode)}
 	}
 
-SelectStmtDistinct:
-	/* EMPTY */
+SelectStmtOpts:
+	TableOptimizerHints DefaultFalseDistinctOpt Priority SelectStmtStraightJoin SelectStmtSQLCache SelectStmtCalcFoundRows
 	{
-		$$ = false
+		opt := &ast.SelectStmtOpts{}
+		if $1 != nil {
+			opt.TableHints = $1.([]*ast.TableOptimizerHint)
+		}
+		if $2 != nil {
+			opt.Distinct = $2.(bool)
+		}
+		if $3 != nil {
+			opt.Priority = $3.(mysql.PriorityEnum)
+		}
+		opt.StraightJoin = $4.(bool)
+		if $5 != nil {
+			opt.SQLCache = $5.(bool)
+		}
+		if $6 != nil {
+			opt.CalcFoundRows = $6.(bool)
+		}
+
+		$$ = opt
 	}
-|	"ALL"
+
+TableOptimizerHints:
 	{
-		$$ = false
+		$$ = nil
 	}
-|	"DISTINCT"
+|	hintComment
 	{
-		$$ = true
+		$$ = $1
 	}
 
-SelectStmtOpts:
-	SelectStmtDistinct SelectStmtSQLCache SelectStmtCalcFoundRows
+SelectStmtStraightJoin:
 	{
-		// TODO: return calc_found_rows opt and support more other options
-		$$ = $1
+		$$ = false
+	}
+|	"STRAIGHT_JOIN"
+	{
+		$$ = true
 	}
 
 SelectStmtCalcFoundRows:
-	%prec lowerThanCalcFoundRows
 	{
 		$$ = false
 	}
@@ -3928,10 +4513,11 @@ SelectStmtCalcFoundRows:
 	{
 		$$ = true
 	}
+
 SelectStmtSQLCache:
-	%prec lowerThanSQLCache
+	%prec empty
 	{
-		$$ = false
+		$$ = true
 	}
 |	"SQL_CACHE"
 	{
@@ -3949,7 +4535,6 @@ SelectStmtFieldList:
 	}
 
 SelectStmtGroup:
-	/* EMPTY */
 	{
 		$$ = nil
 	}
@@ -3964,7 +4549,7 @@ SubSelect:
 		parser.setLastSelectFieldText(s, endOffset)
 		src := parser.src
 		// See the implementation of yyParse function
-		s.SetText(src[yyS[yypt-1].offset-1:yyS[yypt].offset-1])
+		s.SetText(src[yyS[yypt-1].offset-1 : yyS[yypt].offset-1])
 		$$ = &ast.SubqueryExpr{Query: s}
 	}
 |	'(' UnionStmt ')'
@@ -3972,13 +4557,69 @@ SubSelect:
 		s := $2.(*ast.UnionStmt)
 		src := parser.src
 		// See the implementation of yyParse function
-		s.SetText(src[yyS[yypt-1].offset-1:yyS[yypt].offset-1])
+		s.SetText(src[yyS[yypt-1].offset-1 : yyS[yypt].offset-1])
+		$$ = &ast.SubqueryExpr{Query: s}
+	}
+|	'(' WithSelectStmt ')'
+	{
+		s := $2.(ast.ResultSetNode)
+		src := parser.src
+		s.SetText(src[yyS[yypt-1].offset-1 : yyS[yypt].offset-1])
 		$$ = &ast.SubqueryExpr{Query: s}
 	}
 
+// See https://dev.mysql.com/doc/refman/8.0/en/with.html
+WithSelectStmt:
+	WithClause SelectStmt
+	{
+		st := $2.(*ast.SelectStmt)
+		st.With = $1.(*ast.WithClause)
+		$$ = st
+	}
+|	WithClause UnionStmt
+	{
+		st := $2.(*ast.UnionStmt)
+		st.With = $1.(*ast.WithClause)
+		$$ = st
+	}
+
+WithClause:
+	"WITH" CommonTableExprList
+	{
+		$$ = &ast.WithClause{CTEs: $2.([]*ast.CommonTableExpression)}
+	}
+|	"WITH" "RECURSIVE" CommonTableExprList
+	{
+		$$ = &ast.WithClause{IsRecursive: true, CTEs: $3.([]*ast.CommonTableExpression)}
+	}
+
+CommonTableExprList:
+	CommonTableExpr
+	{
+		$$ = []*ast.CommonTableExpression{$1.(*ast.CommonTableExpression)}
+	}
+|	CommonTableExprList ',' CommonTableExpr
+	{
+		$$ = append($1.([]*ast.CommonTableExpression), $3.(*ast.CommonTableExpression))
+	}
+
+CommonTableExpr:
+	Identifier ViewFieldListOpt "AS" SubSelect
+	{
+		// Keep the text between the parentheses, it's parsed again for
+		// every reference to the expression.
+		query := $4.(*ast.SubqueryExpr).Query
+		src := parser.src[parser.startOffset(&yyS[yypt]):parser.endOffset(&parser.yylval)]
+		query.SetText(strings.TrimSpace(src[1 : len(src)-1]))
+		$$ = &ast.CommonTableExpression{
+			Name:        model.NewCIStr($1),
+			ColNameList: $2.([]model.CIStr),
+			Query:       query,
+		}
+	}
+
 // See https://dev.mysql.com/doc/refman/5.7/en/innodb-locking-reads.html
 SelectLockOpt:
-	/* empty */
 	{
 		$$ = ast.SelectLockNone
 	}
@@ -3991,6 +4632,21 @@ SelectLockOpt:
 		$$ = ast.SelectLockInShareMode
 	}
 
+// See https://dev.mysql.com/doc/refman/5.7/en/select-into.html
+SelectStmtIntoOption:
+	{
+		$$ = nil
+	}
+|	"INTO" "OUTFILE" "string literal" Fields Lines
+	{
+		$$ = &ast.SelectIntoOption{
+			Tp:         ast.SelectIntoOutfile,
+			FileName:   $3,
+			FieldsInfo: $4.(*ast.FieldsClause),
+			LinesInfo:  $5.(*ast.LinesClause),
+		}
+	}
+
 // See https://dev.mysql.com/doc/refman/5.7/en/union.html
 UnionStmt:
 	UnionClauseList "UNION" UnionOpt SelectStmt
@@ -4000,7 +4656,11 @@ UnionStmt:
 		lastSelect := union.SelectList.Selects[len(union.SelectList.Selects)-1]
 		endOffset := parser.endOffset(&yyS[yypt-2])
 		parser.setLastSelectFieldText(lastSelect, endOffset)
-		union.SelectList.Selects = append(union.SelectList.Selects, $4.(*ast.SelectStmt))
+		st := $4.(*ast.SelectStmt)
+		// The ORDER BY and LIMIT after the last SELECT are those of the union.
+		union.OrderBy, st.OrderBy = st.OrderBy, nil
+		union.Limit, st.Limit = st.Limit, nil
+		union.SelectList.Selects = append(union.SelectList.Selects, st)
 		$$ = union
 	}
 |	UnionClauseList "UNION" UnionOpt '(' SelectStmt ')' OrderByOptional SelectStmtLimit
@@ -4044,27 +4704,19 @@ UnionClauseList:
 
 UnionSelect:
 	SelectStmt
+	{
+		$$ = $1.(interface{})
+	}
 |	'(' SelectStmt ')'
 	{
 		st := $2.(*ast.SelectStmt)
 		endOffset := parser.endOffset(&yyS[yypt])
 		parser.setLastSelectFieldText(st, endOffset)
-		$$ = st
+		$$ = $2
 	}
 
 UnionOpt:
-	{
-		$$ = true
-	}
-|	"ALL"
-	{
-		$$ = false
-	}
-|	"DISTINCT"
-	{
-		$$ = true
-	}
-
+	DefaultTrueDistinctOpt
 
 /********************Set Statement*******************************/
 SetStmt:
@@ -4072,62 +4724,125 @@ SetStmt:
 	{
 		$$ = &ast.SetStmt{Variables: $2.([]*ast.VariableAssignment)}
 	}
-|	"SET" "PASSWORD" eq PasswordOpt
+|	"SET" "PASSWORD" "=" PasswordOpt
 	{
 		$$ = &ast.SetPwdStmt{Password: $4.(string)}
 	}
-|	"SET" "PASSWORD" "FOR" Username eq PasswordOpt
+|	"SET" "PASSWORD" "FOR" Username "=" PasswordOpt
 	{
-		$$ = &ast.SetPwdStmt{User: $4.(string), Password: $6.(string)}
+		$$ = &ast.SetPwdStmt{User: $4.(*auth.UserIdentity), Password: $6.(string)}
 	}
 |	"SET" "GLOBAL" "TRANSACTION" TransactionChars
 	{
-		// Parsed but ignored
+		vars := $4.([]*ast.VariableAssignment)
+		for _, v := range vars {
+			v.IsGlobal = true
+		}
+		$$ = &ast.SetStmt{Variables: vars}
 	}
 |	"SET" "SESSION" "TRANSACTION" TransactionChars
 	{
-		// Parsed but ignored
+		$$ = &ast.SetStmt{Variables: $4.([]*ast.VariableAssignment)}
 	}
 
 TransactionChars:
 	TransactionChar
+	{
+		if $1 != nil {
+			$$ = $1
+		} else {
+			$$ = []*ast.VariableAssignment{}
+		}
+	}
 |	TransactionChars ',' TransactionChar
+	{
+		if $3 != nil {
+			varAssigns := $3.([]*ast.VariableAssignment)
+			$$ = append($1.([]*ast.VariableAssignment), varAssigns...)
+		} else {
+			$$ = $1
+		}
+	}
 
 TransactionChar:
 	"ISOLATION" "LEVEL" IsolationLevel
+	{
+		varAssigns := []*ast.VariableAssignment{}
+		expr := ast.NewValueExpr($3)
+		varAssigns = append(varAssigns, &ast.VariableAssignment{Name: "tx_isolation", Value: expr, IsSystem: true})
+		$$ = varAssigns
+	}
 |	"READ" "WRITE"
+	{
+		varAssigns := []*ast.VariableAssignment{}
+		expr := ast.NewValueExpr("0")
+		varAssigns = append(varAssigns, &ast.VariableAssignment{Name: "tx_read_only", Value: expr, IsSystem: true})
+		$$ = varAssigns
+	}
 |	"READ" "ONLY"
+	{
+		varAssigns := []*ast.VariableAssignment{}
+		expr := ast.NewValueExpr("1")
+		varAssigns = append(varAssigns, &ast.VariableAssignment{Name: "tx_read_only", Value: expr, IsSystem: true})
+		$$ = varAssigns
+	}
 
 IsolationLevel:
 	"REPEATABLE" "READ"
-|	"READ"	"COMMITTED"
-|	"READ"	"UNCOMMITTED"
+	{
+		$$ = ast.RepeatableRead
+	}
+|	"READ" "COMMITTED"
+	{
+		$$ = ast.ReadCommitted
+	}
+|	"READ" "UNCOMMITTED"
+	{
+		$$ = ast.ReadUncommitted
+	}
 |	"SERIALIZABLE"
+	{
+		$$ = ast.Serializable
+	}
+
+SetExpr:
+	"ON"
+	{
+		$$ = ast.NewValueExpr("ON")
+	}
+|	ExprOrDefault
 
 VariableAssignment:
-	Identifier eq Expression
+	Identifier "=" SetExpr
 	{
-		$$ = &ast.VariableAssignment{Name: $1, Value: $3.(ast.ExprNode), IsSystem: true}
+		$$ = &ast.VariableAssignment{Name: $1, Value: $3, IsSystem: true}
 	}
-|	"GLOBAL" Identifier eq Expression
+|	"GLOBAL" Identifier "=" SetExpr
 	{
-		$$ = &ast.VariableAssignment{Name: $2, Value: $4.(ast.ExprNode), IsGlobal: true, IsSystem: true}
+		$$ = &ast.VariableAssignment{Name: $2, Value: $4, IsGlobal: true, IsSystem: true}
 	}
-|	"SESSION" Identifier eq Expression
+|	"SESSION" Identifier "=" SetExpr
 	{
-		$$ = &ast.VariableAssignment{Name: $2, Value: $4.(ast.ExprNode), IsSystem: true}
+		$$ = &ast.VariableAssignment{Name: $2, Value: $4, IsSystem: true}
 	}
-|	"LOCAL" Identifier eq Expression
+|	"PERSIST" Identifier "=" SetExpr
 	{
-		$$ = &ast.VariableAssignment{Name: $2, Value: $4.(ast.ExprNode), IsSystem: true}
+		$$ = &ast.VariableAssignment{Name: $2, Value: $4, IsGlobal: true, IsSystem: true, IsPersist: true}
 	}
-|	"SYS_VAR" eq Expression
+|	"LOCAL" Identifier "=" Expression
 	{
-		v := strings.ToLower($1.(string))
-		var isGlobal bool
+		$$ = &ast.VariableAssignment{Name: $2, Value: $4, IsSystem: true}
+	}
+|	doubleAtIdentifier "=" SetExpr
+	{
+		v := strings.ToLower($1)
+		var isGlobal, isPersist bool
 		if strings.HasPrefix(v, "@@global.") {
 			isGlobal = true
 			v = strings.TrimPrefix(v, "@@global.")
+		} else if strings.HasPrefix(v, "@@persist.") {
+			isGlobal, isPersist = true, true
+			v = strings.TrimPrefix(v, "@@persist.")
 		} else if strings.HasPrefix(v, "@@session.") {
 			v = strings.TrimPrefix(v, "@@session.")
 		} else if strings.HasPrefix(v, "@@local.") {
@@ -4135,39 +4850,39 @@ VariableAssignment:
 		} else if strings.HasPrefix(v, "@@") {
 			v = strings.TrimPrefix(v, "@@")
 		}
-		$$ = &ast.VariableAssignment{Name: v, Value: $3.(ast.ExprNode), IsGlobal: isGlobal, IsSystem: true}
+		$$ = &ast.VariableAssignment{Name: v, Value: $3, IsGlobal: isGlobal, IsSystem: true, IsPersist: isPersist}
 	}
-|	"USER_VAR" eq Expression
+|	singleAtIdentifier "=" Expression
 	{
-		v := $1.(string)
+		v := $1
 		v = strings.TrimPrefix(v, "@")
-		$$ = &ast.VariableAssignment{Name: v, Value: $3.(ast.ExprNode)}
+		$$ = &ast.VariableAssignment{Name: v, Value: $3}
 	}
-|	"USER_VAR" assignmentEq Expression
+|	singleAtIdentifier ":=" Expression
 	{
-		v := $1.(string)
+		v := $1
 		v = strings.TrimPrefix(v, "@")
-		$$ = &ast.VariableAssignment{Name: v, Value: $3.(ast.ExprNode)}
+		$$ = &ast.VariableAssignment{Name: v, Value: $3}
 	}
 |	"NAMES" CharsetName
 	{
 		$$ = &ast.VariableAssignment{
-			Name: ast.SetNames,
+			Name:  ast.SetNames,
 			Value: ast.NewValueExpr($2.(string)),
 		}
 	}
 |	"NAMES" CharsetName "COLLATE" StringName
 	{
 		$$ = &ast.VariableAssignment{
-			Name: ast.SetNames,
-			Value: ast.NewValueExpr($2.(string)),
+			Name:        ast.SetNames,
+			Value:       ast.NewValueExpr($2.(string)),
 			ExtendValue: ast.NewValueExpr($4.(string)),
 		}
 	}
 |	CharsetKw CharsetName
 	{
 		$$ = &ast.VariableAssignment{
-			Name: ast.SetNames,
+			Name:  ast.SetCharset,
 			Value: ast.NewValueExpr($2.(string)),
 		}
 	}
@@ -4177,7 +4892,7 @@ CharsetName:
 	{
 		$$ = $1
 	}
-|	binaryType
+|	"BINARY"
 	{
 		$$ = charset.CharsetBin
 	}
@@ -4196,12 +4911,15 @@ VariableAssignmentList:
 	}
 
 Variable:
-	SystemVariable | UserVariable
+	SystemVariable
+	{}
+|	UserVariable
+	{}
 
 SystemVariable:
-	"SYS_VAR"
+	doubleAtIdentifier
 	{
-		v := strings.ToLower($1.(string))
+		v := strings.ToLower($1)
 		var isGlobal bool
 		if strings.HasPrefix(v, "@@global.") {
 			isGlobal = true
@@ -4217,31 +4935,39 @@ SystemVariable:
 	}
 
 UserVariable:
-	"USER_VAR"
+	singleAtIdentifier
 	{
-		v := $1.(string)
+		v := $1
 		v = strings.TrimPrefix(v, "@")
 		$$ = &ast.VariableExpr{Name: v, IsGlobal: false, IsSystem: false}
 	}
 
 Username:
-	stringLit "AT" stringLit
+	StringName
+	{
+		$$ = &auth.UserIdentity{Username: $1.(string), Hostname: "%"}
+	}
+|	StringName '@' StringName
+	{
+		$$ = &auth.UserIdentity{Username: $1.(string), Hostname: $3.(string)}
+	}
+|	StringName singleAtIdentifier
 	{
-		$$ = $1 + "@" + $3
+		$$ = &auth.UserIdentity{Username: $1.(string), Hostname: strings.TrimPrefix($2, "@")}
 	}
 
 UsernameList:
-    Username
-    {
-        $$ = []string{$1.(string)}
-    }
-|   UsernameList ',' Username
-    {
-        $$ = append($1.([]string), $3.(string))
-    }
+	Username
+	{
+		$$ = []*auth.UserIdentity{$1.(*auth.UserIdentity)}
+	}
+|	UsernameList ',' Username
+	{
+		$$ = append($1.([]*auth.UserIdentity), $3.(*auth.UserIdentity))
+	}
 
 PasswordOpt:
-	stringLit
+	"string literal"
 	{
 		$$ = $1
 	}
@@ -4251,7 +4977,7 @@ PasswordOpt:
 	}
 
 AuthString:
-	stringLit
+	"string literal"
 	{
 		$$ = $1
 	}
@@ -4262,13 +4988,41 @@ AdminStmt:
 	{
 		$$ = &ast.AdminStmt{Tp: ast.AdminShowDDL}
 	}
+|	"ADMIN" "SHOW" "DDL" "JOBS"
+	{
+		$$ = &ast.AdminStmt{Tp: ast.AdminShowDDLJobs}
+	}
 |	"ADMIN" "CHECK" "TABLE" TableNameList
 	{
 		$$ = &ast.AdminStmt{
-			Tp:	ast.AdminCheckTable,
+			Tp:     ast.AdminCheckTable,
 			Tables: $4.([]*ast.TableName),
 		}
 	}
+|	"CHECK" "TABLE" TableNameList
+	{
+		$$ = &ast.AdminStmt{
+			Tp:     ast.AdminCheckTable,
+			Tables: $3.([]*ast.TableName),
+		}
+	}
+|	"ADMIN" "CANCEL" "DDL" "JOBS" NumList
+	{
+		$$ = &ast.AdminStmt{
+			Tp:     ast.AdminCancelDDLJobs,
+			JobIDs: $5.([]int64),
+		}
+	}
+
+NumList:
+	NUM
+	{
+		$$ = []int64{$1.(int64)}
+	}
+|	NumList ',' NUM
+	{
+		$$ = append($1.([]int64), $3.(int64))
+	}
 
 /****************************Show Statement*******************************/
 ShowStmt:
@@ -4287,15 +5041,15 @@ ShowStmt:
 |	"SHOW" "CREATE" "TABLE" TableName
 	{
 		$$ = &ast.ShowStmt{
-			Tp:	ast.ShowCreateTable,
-			Table:	$4.(*ast.TableName),
+			Tp:    ast.ShowCreateTable,
+			Table: $4.(*ast.TableName),
 		}
 	}
-|	"SHOW" "CREATE" "DATABASE" DBName 
+|	"SHOW" "CREATE" "DATABASE" DBName
 	{
 		$$ = &ast.ShowStmt{
-			Tp:	ast.ShowCreateDatabase,
-			DBName:	$4.(string),
+			Tp:     ast.ShowCreateDatabase,
+			DBName: $4.(string),
 		}
 	}
 |	"SHOW" "GRANTS"
@@ -4307,8 +5061,16 @@ ShowStmt:
 	{
 		// See https://dev.mysql.com/doc/refman/5.7/en/show-grants.html
 		$$ = &ast.ShowStmt{
-			Tp:	ast.ShowGrants,
-			User:	$4.(string),
+			Tp:   ast.ShowGrants,
+			User: $4.(*auth.UserIdentity),
+		}
+	}
+|	"SHOW" "ENGINE" Identifier "STATUS"
+	{
+		// See https://dev.mysql.com/doc/refman/5.7/en/show-engine.html
+		$$ = &ast.ShowStmt{
+			Tp:     ast.ShowEngineStatus,
+			Engine: $3,
 		}
 	}
 |	"SHOW" "PROCESSLIST"
@@ -4317,6 +5079,69 @@ ShowStmt:
 			Tp: ast.ShowProcessList,
 		}
 	}
+|	"SHOW" "PROFILES"
+	{
+		// See https://dev.mysql.com/doc/refman/5.7/en/show-profiles.html
+		$$ = &ast.ShowStmt{
+			Tp: ast.ShowProfiles,
+		}
+	}
+|	"SHOW" "PROFILE"
+	{
+		// See https://dev.mysql.com/doc/refman/5.7/en/show-profile.html
+		$$ = &ast.ShowStmt{
+			Tp: ast.ShowProfile,
+		}
+	}
+|	"SHOW" "PROFILE" "FOR" "QUERY" NUM
+	{
+		$$ = &ast.ShowStmt{
+			Tp:      ast.ShowProfile,
+			QueryID: getUint64FromNUM($5),
+		}
+	}
+|	"SHOW" "STATS_META" ShowLikeOrWhereOpt
+	{
+		stmt := &ast.ShowStmt{
+			Tp: ast.ShowStatsMeta,
+		}
+		if $3 != nil {
+			if x, ok := $3.(*ast.PatternLikeExpr); ok {
+				stmt.Pattern = x
+			} else {
+				stmt.Where = $3.(ast.ExprNode)
+			}
+		}
+		$$ = stmt
+	}
+|	"SHOW" "STATS_HISTOGRAMS" ShowLikeOrWhereOpt
+	{
+		stmt := &ast.ShowStmt{
+			Tp: ast.ShowStatsHistograms,
+		}
+		if $3 != nil {
+			if x, ok := $3.(*ast.PatternLikeExpr); ok {
+				stmt.Pattern = x
+			} else {
+				stmt.Where = $3.(ast.ExprNode)
+			}
+		}
+		$$ = stmt
+	}
+|	"SHOW" "STATS_BUCKETS" ShowLikeOrWhereOpt
+	{
+		stmt := &ast.ShowStmt{
+			Tp: ast.ShowStatsBuckets,
+		}
+		if $3 != nil {
+			if x, ok := $3.(*ast.PatternLikeExpr); ok {
+				stmt.Pattern = x
+			} else {
+				stmt.Where = $3.(ast.ExprNode)
+			}
+		}
+		$$ = stmt
+	}
 
 ShowIndexKwd:
 	"INDEX"
@@ -4324,7 +5149,8 @@ ShowIndexKwd:
 |	"KEYS"
 
 FromOrIn:
-"FROM" | "IN"
+	"FROM"
+|	"IN"
 
 ShowTargetFilterable:
 	"ENGINES"
@@ -4335,51 +5161,54 @@ ShowTargetFilterable:
 	{
 		$$ = &ast.ShowStmt{Tp: ast.ShowDatabases}
 	}
-|	"SCHEMAS"
-	{
-		$$ = &ast.ShowStmt{Tp: ast.ShowDatabases}
-	}
-|	"CHARACTER" "SET"
+|	CharsetKw
 	{
 		$$ = &ast.ShowStmt{Tp: ast.ShowCharset}
 	}
 |	OptFull "TABLES" ShowDatabaseNameOpt
 	{
 		$$ = &ast.ShowStmt{
-			Tp:	ast.ShowTables,
-			DBName:	$3.(string),
-			Full:	$1.(bool),
+			Tp:     ast.ShowTables,
+			DBName: $3.(string),
+			Full:   $1.(bool),
 		}
 	}
 |	"TABLE" "STATUS" ShowDatabaseNameOpt
 	{
 		$$ = &ast.ShowStmt{
-			Tp:	ast.ShowTableStatus,
-			DBName:	$3.(string),
+			Tp:     ast.ShowTableStatus,
+			DBName: $3.(string),
+		}
+	}
+|	"OPEN" "TABLES" ShowDatabaseNameOpt
+	{
+		$$ = &ast.ShowStmt{
+			Tp:     ast.ShowOpenTables,
+			DBName: $3.(string),
 		}
 	}
 |	ShowIndexKwd FromOrIn TableName
-    {
-        $$ = &ast.ShowStmt{
-            Tp: ast.ShowIndex,
-            Table: $3.(*ast.TableName),
-        }
-    }
+	{
+		$$ = &ast.ShowStmt{
+			Tp:    ast.ShowIndex,
+			Table: $3.(*ast.TableName),
+		}
+	}
 |	ShowIndexKwd FromOrIn Identifier FromOrIn Identifier
-    {
-        show := &ast.ShowStmt{
-            Tp: ast.ShowIndex,
-            Table: &ast.TableName{Name:model.NewCIStr($3), Schema: model.NewCIStr($5)},
-        }
-        $$ = show
-    }
+	{
+		show := &ast.ShowStmt{
+			Tp:    ast.ShowIndex,
+			Table: &ast.TableName{Name: model.NewCIStr($3), Schema: model.NewCIStr($5)},
+		}
+		$$ = show
+	}
 |	OptFull "COLUMNS" ShowTableAliasOpt ShowDatabaseNameOpt
 	{
 		$$ = &ast.ShowStmt{
 			Tp:     ast.ShowColumns,
-			Table:	$3.(*ast.TableName),
-			DBName:	$4.(string),
-			Full:	$1.(bool),
+			Table:  $3.(*ast.TableName),
+			DBName: $4.(string),
+			Full:   $1.(bool),
 		}
 	}
 |	OptFull "FIELDS" ShowTableAliasOpt ShowDatabaseNameOpt
@@ -4387,9 +5216,9 @@ ShowTargetFilterable:
 		// SHOW FIELDS is a synonym for SHOW COLUMNS.
 		$$ = &ast.ShowStmt{
 			Tp:     ast.ShowColumns,
-			Table:	$3.(*ast.TableName),
-			DBName:	$4.(string),
-			Full:	$1.(bool),
+			Table:  $3.(*ast.TableName),
+			DBName: $4.(string),
+			Full:   $1.(bool),
 		}
 	}
 |	"WARNINGS"
@@ -4399,33 +5228,33 @@ ShowTargetFilterable:
 |	GlobalScope "VARIABLES"
 	{
 		$$ = &ast.ShowStmt{
-			Tp: ast.ShowVariables,
+			Tp:          ast.ShowVariables,
 			GlobalScope: $1.(bool),
 		}
 	}
 |	GlobalScope "STATUS"
 	{
 		$$ = &ast.ShowStmt{
-			Tp: ast.ShowStatus,
+			Tp:          ast.ShowStatus,
 			GlobalScope: $1.(bool),
 		}
 	}
 |	"COLLATION"
 	{
 		$$ = &ast.ShowStmt{
-			Tp: 	ast.ShowCollation,
+			Tp: ast.ShowCollation,
 		}
 	}
 |	"TRIGGERS" ShowDatabaseNameOpt
 	{
 		$$ = &ast.ShowStmt{
-			Tp:	ast.ShowTriggers,
-			DBName:	$2.(string),
+			Tp:     ast.ShowTriggers,
+			DBName: $2.(string),
 		}
 	}
 |	"PROCEDURE" "STATUS"
 	{
-		$$ = &ast.ShowStmt {
+		$$ = &ast.ShowStmt{
 			Tp: ast.ShowProcedureStatus,
 		}
 	}
@@ -4435,31 +5264,38 @@ ShowTargetFilterable:
 		// See http://dev.mysql.com/doc/refman/5.7/en/show-function-status.html
 		// We do not support neither stored functions nor stored procedures.
 		// So we reuse show procedure status process logic.
-		$$ = &ast.ShowStmt {
+		$$ = &ast.ShowStmt{
 			Tp: ast.ShowProcedureStatus,
 		}
 	}
-|   "EVENTS" ShowDatabaseNameOpt
-    {
-        $$ = &ast.ShowStmt{
-        	Tp:	ast.ShowEvents,
-        	DBName:	$2.(string),
-       	}
-    }
+|	"EVENTS" ShowDatabaseNameOpt
+	{
+		$$ = &ast.ShowStmt{
+			Tp:     ast.ShowEvents,
+			DBName: $2.(string),
+		}
+	}
+|	"PLUGINS"
+	{
+		$$ = &ast.ShowStmt{
+			Tp: ast.ShowPlugins,
+		}
+	}
+
 ShowLikeOrWhereOpt:
 	{
 		$$ = nil
 	}
-|	"LIKE" PrimaryExpression
+|	"LIKE" SimpleExpr
 	{
 		$$ = &ast.PatternLikeExpr{
-			Pattern: $2.(ast.ExprNode),
-			Escape: '\\',
+			Pattern: $2,
+			Escape:  '\\',
 		}
 	}
 |	"WHERE" Expression
 	{
-		$$ = $2.(ast.ExprNode)
+		$$ = $2
 	}
 
 GlobalScope:
@@ -4507,13 +5343,43 @@ ShowTableAliasOpt:
 		$$ = $2.(*ast.TableName)
 	}
 
+ResetPersistStmt:
+	"RESET" "PERSIST"
+	{
+		$$ = &ast.ResetPersistStmt{}
+	}
+|	"RESET" "PERSIST" IfExists Identifier
+	{
+		$$ = &ast.ResetPersistStmt{IfExists: $3.(bool), Name: strings.ToLower($4)}
+	}
+
 FlushStmt:
-	"FLUSH" NoWriteToBinLogAliasOpt TableOrTables TableNameListOpt WithReadLockOpt
+	"FLUSH" NoWriteToBinLogAliasOpt FlushOption
 	{
-		$$ = &ast.FlushTableStmt{
-			Tables: $4.([]*ast.TableName),
-			NoWriteToBinLog: $2.(bool),
-			ReadLock: $5.(bool),
+		tmp := $3.(*ast.FlushStmt)
+		tmp.NoWriteToBinLog = $2.(bool)
+		$$ = tmp
+	}
+
+FlushOption:
+	"PRIVILEGES"
+	{
+		$$ = &ast.FlushStmt{
+			Tp: ast.FlushPrivileges,
+		}
+	}
+|	"CONFIG"
+	{
+		$$ = &ast.FlushStmt{
+			Tp: ast.FlushConfig,
+		}
+	}
+|	TableOrTables TableNameListOpt WithReadLockOpt
+	{
+		$$ = &ast.FlushStmt{
+			Tp:       ast.FlushTables,
+			Tables:   $2.([]*ast.TableName),
+			ReadLock: $3.(bool),
 		}
 	}
 
@@ -4556,6 +5422,7 @@ Statement:
 |	AnalyzeTableStmt
 |	BeginTransactionStmt
 |	BinlogStmt
+|	ChecksumTableStmt
 |	CommitStmt
 |	DeallocateStmt
 |	DeleteFromStmt
@@ -4564,6 +5431,7 @@ Statement:
 |	CreateDatabaseStmt
 |	CreateIndexStmt
 |	CreateTableStmt
+|	CreateViewStmt
 |	CreateUserStmt
 |	DoStmt
 |	DropDatabaseStmt
@@ -4571,29 +5439,37 @@ Statement:
 |	DropTableStmt
 |	DropViewStmt
 |	DropUserStmt
+|	DropStatsStmt
 |	FlushStmt
 |	GrantStmt
 |	InsertIntoStmt
+|	ImportTableStmt
+|	KillStmt
 |	LoadDataStmt
 |	PreparedStmt
 |	RollbackStmt
 |	RenameTableStmt
 |	ReplaceIntoStmt
+|	ResetPersistStmt
+|	RevokeStmt
 |	SelectStmt
 |	UnionStmt
+|	WithSelectStmt
 |	SetStmt
 |	ShowStmt
-|	TruncateTableStmt
-|	UpdateStmt
-|	UseStmt
 |	SubSelect
 	{
 		// `(select 1)`; is a valid select statement
 		// TODO: This is used to fix issue #320. There may be a better solution.
-		$$ = $1.(*ast.SubqueryExpr).Query
+		$$ = $1.(*ast.SubqueryExpr).Query.(ast.StmtNode)
 	}
+|	TruncateTableStmt
+|	UpdateStmt
+|	UseStmt
 |	UnlockTablesStmt
+	{}
 |	LockTablesStmt
+	{}
 
 ExplainableStmt:
 	SelectStmt
@@ -4602,12 +5478,13 @@ ExplainableStmt:
 |	InsertIntoStmt
 |	ReplaceIntoStmt
 |	UnionStmt
+|	WithSelectStmt
 
 StatementList:
 	Statement
 	{
 		if $1 != nil {
-			s := $1.(ast.StmtNode)
+			s := $1
 			if lexer, ok := yylex.(stmtTexter); ok {
 				s.SetText(lexer.stmtText())
 			}
@@ -4617,7 +5494,7 @@ StatementList:
 |	StatementList ';' Statement
 	{
 		if $3 != nil {
-			s := $3.(ast.StmtNode)
+			s := $3
 			if lexer, ok := yylex.(stmtTexter); ok {
 				s.SetText(lexer.stmtText())
 			}
@@ -4673,7 +5550,7 @@ TableOption:
 	{
 		$$ = &ast.TableOption{Tp: ast.TableOptionEngine, StrValue: $2}
 	}
-|	"ENGINE" eq Identifier
+|	"ENGINE" "=" Identifier
 	{
 		$$ = &ast.TableOption{Tp: ast.TableOptionEngine, StrValue: $3}
 	}
@@ -4685,11 +5562,11 @@ TableOption:
 	{
 		$$ = &ast.TableOption{Tp: ast.TableOptionCollate, StrValue: $4.(string)}
 	}
-|	"AUTO_INCREMENT" eq LengthNum
+|	"AUTO_INCREMENT" "=" LengthNum
 	{
 		$$ = &ast.TableOption{Tp: ast.TableOptionAutoIncrement, UintValue: $3.(uint64)}
 	}
-|	"COMMENT" EqOpt stringLit
+|	"COMMENT" EqOpt "string literal"
 	{
 		$$ = &ast.TableOption{Tp: ast.TableOptionComment, StrValue: $3}
 	}
@@ -4697,7 +5574,7 @@ TableOption:
 	{
 		$$ = &ast.TableOption{Tp: ast.TableOptionAvgRowLength, UintValue: $3.(uint64)}
 	}
-|	"CONNECTION" EqOpt stringLit
+|	"CONNECTION" EqOpt "string literal"
 	{
 		$$ = &ast.TableOption{Tp: ast.TableOptionConnection, StrValue: $3}
 	}
@@ -4705,11 +5582,11 @@ TableOption:
 	{
 		$$ = &ast.TableOption{Tp: ast.TableOptionCheckSum, UintValue: $3.(uint64)}
 	}
-|	"PASSWORD" EqOpt stringLit
+|	"PASSWORD" EqOpt "string literal"
 	{
 		$$ = &ast.TableOption{Tp: ast.TableOptionPassword, StrValue: $3}
 	}
-|	"COMPRESSION" EqOpt Identifier
+|	"COMPRESSION" EqOpt "string literal"
 	{
 		$$ = &ast.TableOption{Tp: ast.TableOptionCompression, StrValue: $3}
 	}
@@ -4737,12 +5614,20 @@ TableOption:
 	{
 		$$ = &ast.TableOption{Tp: ast.TableOptionStatsPersistent}
 	}
+|	"SHARD_ROW_ID_BITS" EqOpt LengthNum
+	{
+		$$ = &ast.TableOption{Tp: ast.TableOptionShardRowID, UintValue: $3.(uint64)}
+	}
+|	"PACK_KEYS" EqOpt StatsPersistentVal
+	{
+		// Parse it but will ignore it.
+		$$ = &ast.TableOption{Tp: ast.TableOptionPackKeys}
+	}
 
 StatsPersistentVal:
 	"DEFAULT"
 	{}
 |	LengthNum
-	{}
 
 TableOptionListOpt:
 	{
@@ -4759,13 +5644,12 @@ TableOptionList:
 	{
 		$$ = append($1.([]*ast.TableOption), $2.(*ast.TableOption))
 	}
-|	TableOptionList ','  TableOption
+|	TableOptionList ',' TableOption
 	{
 		$$ = append($1.([]*ast.TableOption), $3.(*ast.TableOption))
 	}
 
 OptTable:
-	%prec lowestOpt
 	{}
 |	"TABLE"
 
@@ -4776,7 +5660,7 @@ TruncateTableStmt:
 	}
 
 RowFormat:
-	 "ROW_FORMAT" EqOpt "DEFAULT"
+	"ROW_FORMAT" EqOpt "DEFAULT"
 	{
 		$$ = ast.RowFormatDefault
 	}
@@ -4854,16 +5738,11 @@ NumericType:
 		x := types.NewFieldType($1.(byte))
 		x.Flen = fopt.Flen
 		if x.Tp == mysql.TypeFloat {
-			// Fix issue #312
-			if x.Flen > 53 {
-				yylex.Errorf("Float len(%d) should not be greater than 53", x.Flen)
-				return 1
-			}
-			if x.Flen > 24 {
+			if x.Flen > mysql.PrecisionForFloat {
 				x.Tp = mysql.TypeDouble
 			}
 		}
-		x.Decimal =fopt.Decimal
+		x.Decimal = fopt.Decimal
 		for _, o := range $3.([]*ast.TypeOpt) {
 			if o.IsUnsigned {
 				x.Flag |= mysql.UnsignedFlag
@@ -4878,10 +5757,9 @@ NumericType:
 	{
 		x := types.NewFieldType($1.(byte))
 		x.Flen = $2.(int)
-		if x.Flen == -1 || x.Flen == 0 {
+		// A width over 64 is reported against the column by the validator.
+		if x.Flen == types.UnspecifiedLength || x.Flen == 0 {
 			x.Flen = 1
-		} else if x.Flen > 64 {
-			yylex.Errorf("invalid field length %d for bit type, must in [1, 64]", x.Flen)
 		}
 		$$ = x
 	}
@@ -4923,6 +5801,7 @@ IntegerType:
 OptInteger:
 	{}
 |	"INTEGER"
+|	"INT"
 
 FixedPointType:
 	"DECIMAL"
@@ -4963,6 +5842,8 @@ StringType:
 	{
 		x := types.NewFieldType(mysql.TypeString)
 		x.Flen = $3.(int)
+		x.Charset = $5.(string)
+		x.Collate = $6.(string)
 		if $4.(bool) {
 			x.Flag |= mysql.BinaryFlag
 		}
@@ -4971,20 +5852,22 @@ StringType:
 |	NationalOpt "CHAR" OptBinary OptCharset OptCollate
 	{
 		x := types.NewFieldType(mysql.TypeString)
+		x.Charset = $4.(string)
+		x.Collate = $5.(string)
 		if $3.(bool) {
 			x.Flag |= mysql.BinaryFlag
 		}
 		$$ = x
 	}
-|	NationalOpt "VARCHAR" FieldLen OptBinary OptCharset OptCollate
+|	Varchar FieldLen OptBinary OptCharset OptCollate
 	{
 		x := types.NewFieldType(mysql.TypeVarchar)
-		x.Flen = $3.(int)
-		if $4.(bool) {
+		x.Flen = $2.(int)
+		x.Charset = $4.(string)
+		x.Collate = $5.(string)
+		if $3.(bool) {
 			x.Flag |= mysql.BinaryFlag
 		}
-		x.Charset = $5.(string)
-		x.Collate = $6.(string)
 		$$ = x
 	}
 |	"BINARY" OptFieldLen
@@ -4993,6 +5876,7 @@ StringType:
 		x.Flen = $2.(int)
 		x.Charset = charset.CharsetBin
 		x.Collate = charset.CharsetBin
+		x.Flag |= mysql.BinaryFlag
 		$$ = x
 	}
 |	"VARBINARY" FieldLen
@@ -5001,20 +5885,25 @@ StringType:
 		x.Flen = $2.(int)
 		x.Charset = charset.CharsetBin
 		x.Collate = charset.CharsetBin
+		x.Flag |= mysql.BinaryFlag
 		$$ = x
 	}
 |	BlobType
 	{
+		x := $1.(*types.FieldType)
+		x.Charset = charset.CharsetBin
+		x.Collate = charset.CharsetBin
+		x.Flag |= mysql.BinaryFlag
 		$$ = $1.(*types.FieldType)
 	}
 |	TextType OptBinary OptCharset OptCollate
 	{
 		x := $1.(*types.FieldType)
+		x.Charset = $3.(string)
+		x.Collate = $4.(string)
 		if $2.(bool) {
 			x.Flag |= mysql.BinaryFlag
 		}
-		x.Charset = $3.(string)
-		x.Collate = $4.(string)
 		$$ = x
 	}
 |	"ENUM" '(' StringList ')' OptCharset OptCollate
@@ -5033,39 +5922,47 @@ StringType:
 		x.Collate = $6.(string)
 		$$ = x
 	}
+|	"JSON"
+	{
+		x := types.NewFieldType(mysql.TypeJSON)
+		x.Decimal = 0
+		x.Charset = charset.CharsetBin
+		x.Collate = charset.CollationBin
+		$$ = x
+	}
 
 NationalOpt:
 	{}
 |	"NATIONAL"
 
+Varchar:
+	"NATIONAL" "VARCHAR"
+	{}
+|	"VARCHAR"
+	{}
+|	"NVARCHAR"
+	{}
+
 BlobType:
 	"TINYBLOB"
 	{
 		x := types.NewFieldType(mysql.TypeTinyBlob)
-		x.Charset = charset.CharsetBin
-		x.Collate = charset.CharsetBin
 		$$ = x
 	}
 |	"BLOB" OptFieldLen
 	{
 		x := types.NewFieldType(mysql.TypeBlob)
 		x.Flen = $2.(int)
-		x.Charset = charset.CharsetBin
-		x.Collate = charset.CharsetBin
 		$$ = x
 	}
 |	"MEDIUMBLOB"
 	{
 		x := types.NewFieldType(mysql.TypeMediumBlob)
-		x.Charset = charset.CharsetBin
-		x.Collate = charset.CharsetBin
 		$$ = x
 	}
 |	"LONGBLOB"
 	{
 		x := types.NewFieldType(mysql.TypeLongBlob)
-		x.Charset = charset.CharsetBin
-		x.Collate = charset.CharsetBin
 		$$ = x
 	}
 
@@ -5093,7 +5990,6 @@ TextType:
 		$$ = x
 	}
 
-
 DateAndTimeType:
 	"DATE"
 	{
@@ -5103,25 +5999,41 @@ DateAndTimeType:
 |	"DATETIME" OptFieldLen
 	{
 		x := types.NewFieldType(mysql.TypeDatetime)
+		x.Flen = mysql.MaxDatetimeWidthNoFsp
 		x.Decimal = $2.(int)
+		if x.Decimal > 0 {
+			x.Flen = x.Flen + 1 + x.Decimal
+		}
 		$$ = x
 	}
 |	"TIMESTAMP" OptFieldLen
 	{
 		x := types.NewFieldType(mysql.TypeTimestamp)
+		x.Flen = mysql.MaxDatetimeWidthNoFsp
 		x.Decimal = $2.(int)
+		if x.Decimal > 0 {
+			x.Flen = x.Flen + 1 + x.Decimal
+		}
 		$$ = x
 	}
 |	"TIME" OptFieldLen
 	{
 		x := types.NewFieldType(mysql.TypeDuration)
+		x.Flen = mysql.MaxDurationWidthNoFsp
 		x.Decimal = $2.(int)
+		if x.Decimal > 0 {
+			x.Flen = x.Flen + 1 + x.Decimal
+		}
 		$$ = x
 	}
 |	"YEAR" OptFieldLen
 	{
 		x := types.NewFieldType(mysql.TypeYear)
 		x.Flen = $2.(int)
+		if x.Flen != types.UnspecifiedLength && x.Flen != 4 {
+			yylex.Errorf("Supports only YEAR or YEAR(4) column.")
+			return -1
+		}
 		$$ = x
 	}
 
@@ -5133,7 +6045,6 @@ FieldLen:
 
 OptFieldLen:
 	{
-		/* -1 means unspecified field length*/
 		$$ = types.UnspecifiedLength
 	}
 |	FieldLen
@@ -5211,17 +6122,17 @@ OptCollate:
 	}
 
 StringList:
-	stringLit
+	"string literal"
 	{
 		$$ = []string{$1}
 	}
-|	StringList ',' stringLit
+|	StringList ',' "string literal"
 	{
 		$$ = append($1.([]string), $3)
 	}
 
 StringName:
-	stringLit
+	"string literal"
 	{
 		$$ = $1
 	}
@@ -5244,9 +6155,10 @@ UpdateStmt:
 			refs = &ast.Join{Left: $4.(ast.ResultSetNode)}
 		}
 		st := &ast.UpdateStmt{
-			LowPriority:	$2.(bool),
-			TableRefs:	&ast.TableRefsClause{TableRefs: refs},
-			List:		$6.([]*ast.Assignment),
+			LowPriority: $2.(bool),
+			TableRefs:   &ast.TableRefsClause{TableRefs: refs},
+			List:        $6.([]*ast.Assignment),
+			IgnoreErr:   $3.(bool),
 		}
 		if $7 != nil {
 			st.Where = $7.(ast.ExprNode)
@@ -5262,9 +6174,10 @@ UpdateStmt:
 |	"UPDATE" LowPriorityOptional IgnoreOptional TableRefs "SET" AssignmentList WhereClauseOptional
 	{
 		st := &ast.UpdateStmt{
-			LowPriority:	$2.(bool),
-			TableRefs:	&ast.TableRefsClause{TableRefs: $4.(*ast.Join)},
-			List:		$6.([]*ast.Assignment),
+			LowPriority: $2.(bool),
+			TableRefs:   &ast.TableRefsClause{TableRefs: $4.(*ast.Join)},
+			List:        $6.([]*ast.Assignment),
+			IgnoreErr:   $3.(bool),
 		}
 		if $7 != nil {
 			st.Where = $7.(ast.ExprNode)
@@ -5281,7 +6194,7 @@ UseStmt:
 WhereClause:
 	"WHERE" Expression
 	{
-		$$ = $2.(ast.ExprNode)
+		$$ = $2
 	}
 
 WhereClauseOptional:
@@ -5305,10 +6218,10 @@ CommaOpt:
 CreateUserStmt:
 	"CREATE" "USER" IfNotExists UserSpecList
 	{
- 		// See https://dev.mysql.com/doc/refman/5.7/en/create-user.html
+		// See https://dev.mysql.com/doc/refman/5.7/en/create-user.html
 		$$ = &ast.CreateUserStmt{
 			IfNotExists: $3.(bool),
-			Specs: $4.([]*ast.UserSpec),
+			Specs:       $4.([]*ast.UserSpec),
 		}
 	}
 
@@ -5318,17 +6231,17 @@ AlterUserStmt:
 	{
 		$$ = &ast.AlterUserStmt{
 			IfExists: $3.(bool),
-			Specs: $4.([]*ast.UserSpec),
+			Specs:    $4.([]*ast.UserSpec),
 		}
 	}
-| 	"ALTER" "USER" IfExists "USER" '(' ')' "IDENTIFIED" "BY" AuthString
+|	"ALTER" "USER" IfExists "USER" '(' ')' "IDENTIFIED" "BY" AuthString
 	{
-		auth := &ast.AuthOption {
-			AuthString: $9.(string),
+		auth := &ast.AuthOption{
+			AuthString:   $9.(string),
 			ByAuthString: true,
 		}
 		$$ = &ast.AlterUserStmt{
-			IfExists: $3.(bool),
+			IfExists:    $3.(bool),
 			CurrentAuth: auth,
 		}
 	}
@@ -5337,7 +6250,7 @@ UserSpec:
 	Username AuthOption
 	{
 		userSpec := &ast.UserSpec{
-			User: $1.(string),
+			User: $1.(*auth.UserIdentity),
 		}
 		if $2 != nil {
 			userSpec.AuthOpt = $2.(*ast.AuthOption)
@@ -5361,8 +6274,8 @@ AuthOption:
 	}
 |	"IDENTIFIED" "BY" AuthString
 	{
-		$$ = &ast.AuthOption {
-			AuthString: $3.(string),
+		$$ = &ast.AuthOption{
+			AuthString:   $3.(string),
 			ByAuthString: true,
 		}
 	}
@@ -5374,7 +6287,7 @@ AuthOption:
 	}
 
 HashString:
-	stringLit
+	"string literal"
 	{
 		$$ = $1
 	}
@@ -5384,15 +6297,25 @@ HashString:
  * See https://dev.mysql.com/doc/refman/5.7/en/grant.html
  *************************************************************************************/
 GrantStmt:
-	 "GRANT" PrivElemList "ON" ObjectType PrivLevel "TO" UserSpecList
-	 {
+	"GRANT" PrivElemList "ON" ObjectType PrivLevel "TO" UserSpecList WithGrantOptionOpt
+	{
 		$$ = &ast.GrantStmt{
-			Privs: $2.([]*ast.PrivElem),
+			Privs:      $2.([]*ast.PrivElem),
 			ObjectType: $4.(ast.ObjectTypeType),
-			Level: $5.(*ast.GrantLevel),
-			Users: $7.([]*ast.UserSpec),
+			Level:      $5.(*ast.GrantLevel),
+			Users:      $7.([]*ast.UserSpec),
+			WithGrant:  $8.(bool),
 		}
-	 }
+	}
+
+WithGrantOptionOpt:
+	{
+		$$ = false
+	}
+|	"WITH" "GRANT" "OPTION"
+	{
+		$$ = true
+	}
 
 PrivElem:
 	PrivType
@@ -5440,6 +6363,10 @@ PrivType:
 	{
 		$$ = mysql.CreateUserPriv
 	}
+|	"TRIGGER"
+	{
+		$$ = mysql.TriggerPriv
+	}
 |	"DELETE"
 	{
 		$$ = mysql.DeletePriv
@@ -5448,6 +6375,10 @@ PrivType:
 	{
 		$$ = mysql.DropPriv
 	}
+|	"PROCESS"
+	{
+		$$ = mysql.ProcessPriv
+	}
 |	"EXECUTE"
 	{
 		$$ = mysql.ExecutePriv
@@ -5464,6 +6395,10 @@ PrivType:
 	{
 		$$ = mysql.SelectPriv
 	}
+|	"SUPER"
+	{
+		$$ = mysql.SuperPriv
+	}
 |	"SHOW" "DATABASES"
 	{
 		$$ = mysql.ShowDBPriv
@@ -5476,6 +6411,10 @@ PrivType:
 	{
 		$$ = mysql.GrantPriv
 	}
+|	"REFERENCES"
+	{
+		$$ = mysql.ReferencesPriv
+	}
 
 ObjectType:
 	{
@@ -5489,48 +6428,60 @@ ObjectType:
 PrivLevel:
 	'*'
 	{
-		$$ = &ast.GrantLevel {
+		$$ = &ast.GrantLevel{
 			Level: ast.GrantLevelDB,
 		}
 	}
 |	'*' '.' '*'
 	{
-		$$ = &ast.GrantLevel {
+		$$ = &ast.GrantLevel{
 			Level: ast.GrantLevelGlobal,
 		}
 	}
-| 	Identifier '.' '*'
+|	Identifier '.' '*'
 	{
-		$$ = &ast.GrantLevel {
-			Level: ast.GrantLevelDB,
+		$$ = &ast.GrantLevel{
+			Level:  ast.GrantLevelDB,
 			DBName: $1,
 		}
 	}
 |	Identifier '.' Identifier
 	{
-		$$ = &ast.GrantLevel {
-			Level: ast.GrantLevelTable,
-			DBName: $1,
+		$$ = &ast.GrantLevel{
+			Level:     ast.GrantLevelTable,
+			DBName:    $1,
 			TableName: $3,
 		}
 	}
 |	Identifier
 	{
-		$$ = &ast.GrantLevel {
-			Level: ast.GrantLevelTable,
+		$$ = &ast.GrantLevel{
+			Level:     ast.GrantLevelTable,
 			TableName: $1,
 		}
 	}
 
+RevokeStmt:
+	"REVOKE" PrivElemList "ON" ObjectType PrivLevel "FROM" UserSpecList
+	{
+		$$ = &ast.RevokeStmt{
+			Privs:      $2.([]*ast.PrivElem),
+			ObjectType: $4.(ast.ObjectTypeType),
+			Level:      $5.(*ast.GrantLevel),
+			Users:      $7.([]*ast.UserSpec),
+		}
+	}
+
 /**************************************LoadDataStmt*****************************************
  * See https://dev.mysql.com/doc/refman/5.7/en/load-data.html
  *******************************************************************************************/
 LoadDataStmt:
-	"LOAD" "DATA" LocalOpt "INFILE" stringLit "INTO" "TABLE" TableName Fields Lines
+	"LOAD" "DATA" LocalOpt "INFILE" "string literal" "INTO" "TABLE" TableName Fields Lines ColumnNameListOptWithBrackets
 	{
 		x := &ast.LoadDataStmt{
-			Path:       $5,
-			Table:      $8.(*ast.TableName),
+			Path:    $5,
+			Table:   $8.(*ast.TableName),
+			Columns: $11.([]*ast.ColumnName),
 		}
 		if $3 != nil {
 			x.IsLocal = true
@@ -5544,9 +6495,48 @@ LoadDataStmt:
 		$$ = x
 	}
 
+/*********************************************************************
+ * IMPORT TABLE t FROM CSV '/path' [WITH HEADER] [DELIMITER ','] [MAX_ERRORS n]
+ *********************************************************************/
+ImportTableStmt:
+	"IMPORT" "TABLE" TableName "FROM" "CSV" "string literal" ImportOptionList
+	{
+		x := $7.(*ast.ImportTableStmt)
+		x.Table = $3.(*ast.TableName)
+		x.Path = $6
+		$$ = x
+	}
+
+ImportOptionList:
+	{
+		$$ = &ast.ImportTableStmt{Delimiter: ",", MaxErrors: ast.DefaultImportMaxErrors}
+	}
+|	ImportOptionList "WITH" "HEADER"
+	{
+		x := $1.(*ast.ImportTableStmt)
+		x.Header = true
+		$$ = x
+	}
+|	ImportOptionList "DELIMITER" "string literal"
+	{
+		if utf8.RuneCountInString($3) != 1 {
+			yylex.Errorf("Incorrect arguments %s to DELIMITER", $3)
+			return 1
+		}
+		x := $1.(*ast.ImportTableStmt)
+		x.Delimiter = $3
+		$$ = x
+	}
+|	ImportOptionList "MAX_ERRORS" NUM
+	{
+		x := $1.(*ast.ImportTableStmt)
+		x.MaxErrors = getUint64FromNUM($3)
+		$$ = x
+	}
+
 LocalOpt:
 	{
-		$$ = nil 
+		$$ = nil
 	}
 |	"LOCAL"
 	{
@@ -5554,7 +6544,7 @@ LocalOpt:
 	}
 
 Fields:
-     	{
+	{
 		escape := "\\"
 		$$ = &ast.FieldsClause{
 			Terminated: "\t",
@@ -5573,7 +6563,7 @@ Fields:
 		if len(str) > 1 {
 			yylex.Errorf("Incorrect arguments %s to ENCLOSED", escape)
 			return 1
-		}else if len(str) != 0 {
+		} else if len(str) != 0 {
 			enclosed = str[0]
 		}
 		$$ = &ast.FieldsClause{
@@ -5584,13 +6574,14 @@ Fields:
 	}
 
 FieldsOrColumns:
-"FIELDS" | "COLUMNS"
+	"FIELDS"
+|	"COLUMNS"
 
 FieldsTerminated:
 	{
 		$$ = "\t"
 	}
-|	"TERMINATED" "BY" stringLit
+|	"TERMINATED" "BY" "string literal"
 	{
 		$$ = $3
 	}
@@ -5599,7 +6590,7 @@ Enclosed:
 	{
 		$$ = ""
 	}
-|	"ENCLOSED" "BY" stringLit
+|	"ENCLOSED" "BY" "string literal"
 	{
 		$$ = $3
 	}
@@ -5608,7 +6599,7 @@ Escaped:
 	{
 		$$ = "\\"
 	}
-|	"ESCAPED" "BY" stringLit
+|	"ESCAPED" "BY" "string literal"
 	{
 		$$ = $3
 	}
@@ -5626,7 +6617,7 @@ Starting:
 	{
 		$$ = ""
 	}
-|	"STARTING" "BY" stringLit
+|	"STARTING" "BY" "string literal"
 	{
 		$$ = $3
 	}
@@ -5635,24 +6626,23 @@ LinesTerminated:
 	{
 		$$ = "\n"
 	}
-|	"TERMINATED" "BY" stringLit
+|	"TERMINATED" "BY" "string literal"
 	{
 		$$ = $3
 	}
 
-
-/*********************************************************************
- * Lock/Unlock Tables
- * See http://dev.mysql.com/doc/refman/5.7/en/lock-tables.html
- * All the statement leaves empty. This is used to prevent mysqldump error.
- *********************************************************************/
-
 UnlockTablesStmt:
-	"UNLOCK" "TABLES"
+	"UNLOCK" TablesTerminalSym
 	{}
 
 LockTablesStmt:
-	"LOCK" "TABLES" TableLockList
+	"LOCK" TablesTerminalSym TableLockList
+	{}
+
+TablesTerminalSym:
+	"TABLES"
+	{}
+|	"TABLE"
 	{}
 
 TableLock:
@@ -5667,4 +6657,38 @@ TableLockList:
 	TableLock
 |	TableLockList ',' TableLock
 
+KillStmt:
+	KillOrKillTiDB NUM
+	{
+		$$ = &ast.KillStmt{
+			ConnectionID:  getUint64FromNUM($2),
+			TiDBExtension: $1.(bool),
+		}
+	}
+|	KillOrKillTiDB "CONNECTION" NUM
+	{
+		$$ = &ast.KillStmt{
+			ConnectionID:  getUint64FromNUM($3),
+			TiDBExtension: $1.(bool),
+		}
+	}
+|	KillOrKillTiDB "QUERY" NUM
+	{
+		$$ = &ast.KillStmt{
+			ConnectionID:  getUint64FromNUM($3),
+			Query:         true,
+			TiDBExtension: $1.(bool),
+		}
+	}
+
+KillOrKillTiDB:
+	"KILL"
+	{
+		$$ = false
+	}
+|	"KILL" "TIDB"
+	{
+		$$ = true
+	}
+
 %%
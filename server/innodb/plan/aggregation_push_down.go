@@ -326,7 +326,7 @@ func (a *aggregationOptimizer) optimize(p LogicalPlan, ctx context.Context, allo
 
 // aggPushDown tries to push down aggregate functions to join paths.
 func (a *aggregationOptimizer) aggPushDown(p LogicalPlan) LogicalPlan {
-	if agg, ok := p.(*LogicalAggregation); ok {
+	if agg, ok := p.(*LogicalAggregation); ok && !agg.Rollup {
 		proj := a.tryToEliminateAggregation(agg)
 		if proj != nil {
 			p = proj
@@ -0,0 +1,108 @@
+package plan
+
+import (
+	"github.com/juju/errors"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/parser"
+)
+
+// ExpandWith replaces every reference to a common table expression of a
+// WITH clause in node by a derived table holding its query, so that the
+// resolver and the planner only ever see derived tables. The query is
+// parsed again for every reference, like the SELECT of a view.
+func ExpandWith(ctx context.Context, node ast.Node) error {
+	e := &cteExpander{ctx: ctx}
+	node.Accept(e)
+	return errors.Trace(e.err)
+}
+
+// cteExpander is the visitor of ExpandWith. scopes holds the expressions
+// visible from the node being visited, the innermost WITH clause last.
+type cteExpander struct {
+	ctx    context.Context
+	scopes [][]*ast.CommonTableExpression
+	err    error
+}
+
+func withClause(node ast.Node) *ast.WithClause {
+	switch x := node.(type) {
+	case *ast.SelectStmt:
+		return x.With
+	case *ast.UnionStmt:
+		return x.With
+	}
+	return nil
+}
+
+// Enter implements Visitor interface.
+func (e *cteExpander) Enter(in ast.Node) (ast.Node, bool) {
+	if with := withClause(in); with != nil {
+		if with.IsRecursive {
+			e.err = ErrNotSupportedYet.GenByArgs("WITH RECURSIVE")
+			return in, true
+		}
+		names := make(map[string]struct{}, len(with.CTEs))
+		for _, cte := range with.CTEs {
+			if _, ok := names[cte.Name.L]; ok {
+				e.err = ErrNonuniqTable.GenByArgs(cte.Name.O)
+				return in, true
+			}
+			names[cte.Name.L] = struct{}{}
+		}
+		e.scopes = append(e.scopes, with.CTEs)
+		return in, false
+	}
+	ts, ok := in.(*ast.TableSource)
+	if !ok {
+		return in, false
+	}
+	tn, ok := ts.Source.(*ast.TableName)
+	if !ok || tn.Schema.L != "" {
+		return in, false
+	}
+	for i := len(e.scopes) - 1; i >= 0; i-- {
+		for j, cte := range e.scopes[i] {
+			if cte.Name.L == tn.Name.L {
+				// An expression only sees the ones defined before it.
+				visible := make([][]*ast.CommonTableExpression, 0, i+1)
+				visible = append(visible, e.scopes[:i]...)
+				visible = append(visible, e.scopes[i][:j])
+				e.expand(ts, cte, visible)
+				// The query is expanded already, and must not be expanded
+				// again with the expressions it can't see.
+				return in, true
+			}
+		}
+	}
+	return in, false
+}
+
+// expand makes ts the derived table of cte.
+func (e *cteExpander) expand(ts *ast.TableSource, cte *ast.CommonTableExpression, scopes [][]*ast.CommonTableExpression) {
+	charset, collation := e.ctx.GetSessionVars().GetCharsetInfo()
+	stmt, err := parser.New().ParseOneStmt(cte.Query.Text(), charset, collation)
+	if err != nil {
+		e.err = errors.Trace(err)
+		return
+	}
+	inner := &cteExpander{ctx: e.ctx, scopes: scopes}
+	stmt.Accept(inner)
+	if inner.err != nil {
+		e.err = inner.err
+		return
+	}
+	ts.Source = stmt.(ast.ResultSetNode)
+	if ts.AsName.L == "" {
+		ts.AsName = cte.Name
+	}
+	ts.ColumnNames = cte.ColNameList
+}
+
+// Leave implements Visitor interface.
+func (e *cteExpander) Leave(in ast.Node) (ast.Node, bool) {
+	if withClause(in) != nil && e.err == nil {
+		e.scopes = e.scopes[:len(e.scopes)-1]
+	}
+	return in, e.err == nil
+}
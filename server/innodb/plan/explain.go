@@ -17,8 +17,12 @@ import (
 	"bytes"
 	"fmt"
 
+	types "github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/expression"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/expression/aggregation"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/charset"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
 )
 
 func setParents4FinalPlan(plan PhysicalPlan) {
@@ -88,6 +92,12 @@ func (p *PhysicalIndexScan) ExplainInfo() string {
 			}
 		}
 	}
+	if keyLen := p.usedKeyLen(); keyLen > 0 {
+		buffer.WriteString(fmt.Sprintf(", key_len:%d", keyLen))
+	}
+	if p.LooseScanColumns > 0 {
+		buffer.WriteString(", using index for group-by")
+	}
 	buffer.WriteString(fmt.Sprintf(", out of order:%v", p.OutOfOrder))
 	if p.Desc {
 		buffer.WriteString(", desc")
@@ -95,6 +105,84 @@ func (p *PhysicalIndexScan) ExplainInfo() string {
 	return buffer.String()
 }
 
+// usedKeyLen returns the number of bytes of the index key the ranges of p
+// use, as the key_len of MySQL: the equal and in conditions use their
+// columns, the other access conditions the column after them.
+func (p *PhysicalIndexScan) usedKeyLen() int {
+	used := p.accessInAndEqCount
+	if len(p.AccessCondition) > used {
+		used++
+	}
+	if used > len(p.Index.Columns) {
+		used = len(p.Index.Columns)
+	}
+	keyLen := 0
+	for _, idxCol := range p.Index.Columns[:used] {
+		keyLen += indexColumnKeyLen(p.Table.Columns[idxCol.Offset], idxCol)
+	}
+	return keyLen
+}
+
+// indexColumnKeyLen returns the number of bytes col takes in the keys of an
+// index on idxCol, the byte telling NULL included.
+func indexColumnKeyLen(col *model.ColumnInfo, idxCol *model.IndexColumn) int {
+	var keyLen int
+	switch col.Tp {
+	case mysql.TypeTiny, mysql.TypeYear:
+		keyLen = 1
+	case mysql.TypeShort:
+		keyLen = 2
+	case mysql.TypeInt24, mysql.TypeDate:
+		keyLen = 3
+	case mysql.TypeLong, mysql.TypeFloat:
+		keyLen = 4
+	case mysql.TypeLonglong, mysql.TypeDouble:
+		keyLen = 8
+	case mysql.TypeDuration:
+		keyLen = 3 + fspKeyLen(col.Decimal)
+	case mysql.TypeTimestamp:
+		keyLen = 4 + fspKeyLen(col.Decimal)
+	case mysql.TypeDatetime:
+		keyLen = 5 + fspKeyLen(col.Decimal)
+	case mysql.TypeNewDecimal:
+		flen, frac := col.Flen, col.Decimal
+		if flen == types.UnspecifiedLength {
+			// DECIMAL is DECIMAL(10, 0).
+			flen, frac = 10, 0
+		}
+		if frac == types.UnspecifiedLength {
+			frac = 0
+		}
+		keyLen = types.DecimalBinSize(flen, frac)
+	case mysql.TypeEnum, mysql.TypeSet:
+		keyLen = mysql.GetEnumSetStorageSize(col.Tp, len(col.Elems))
+	case mysql.TypeBit:
+		keyLen = (col.Flen + 7) / 8
+	default:
+		chars := col.Flen
+		if idxCol.Length != types.UnspecifiedLength {
+			chars = idxCol.Length
+		}
+		keyLen = chars * charset.GetMaxBytesPerChar(col.Charset)
+		if col.Tp != mysql.TypeString {
+			// The length of variable-length values.
+			keyLen += 2
+		}
+	}
+	if !mysql.HasNotNullFlag(col.Flag) {
+		keyLen++
+	}
+	return keyLen
+}
+
+// fspKeyLen returns the number of bytes of the fractional seconds of fsp.
+func fspKeyLen(fsp int) int {
+	if fsp <= 0 {
+		return 0
+	}
+	return (fsp + 1) / 2
+}
+
 // ExplainInfo implements PhysicalPlan interface.
 func (p *PhysicalTableScan) ExplainInfo() string {
 	buffer := bytes.NewBufferString("")
@@ -154,6 +242,9 @@ func (p *Projection) ExplainInfo() string {
 
 // ExplainInfo implements PhysicalPlan interface.
 func (p *TableDual) ExplainInfo() string {
+	if p.impossibleWhere {
+		return impossibleWhereLabel
+	}
 	return fmt.Sprintf("rows:%v", p.RowCount)
 }
 
@@ -185,6 +276,9 @@ func (p *PhysicalAggregation) ExplainInfo() string {
 		buffer.WriteString(fmt.Sprintf(", group by:%s",
 			expression.ExplainExpressionList(p.GroupByItems)))
 	}
+	if p.Rollup {
+		buffer.WriteString(" with rollup")
+	}
 	buffer.WriteString(", funcs:")
 	for i, agg := range p.AggFuncs {
 		buffer.WriteString(aggregation.ExplainAggFunc(agg))
@@ -28,10 +28,12 @@ import (
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/parser/opcode"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/varsutil"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/terror"
 	"github.com/zhukovaskychina/xmysql-server/server/mysql"
 )
 
-// EvalSubquery evaluates incorrelated subqueries once.
+// EvalSubquery evaluates incorrelated subqueries once. Without it they are
+// planned like the correlated ones, as an apply.
 var EvalSubquery func(p PhysicalPlan, is schemas.InfoSchema, ctx context.Context) ([][]types.Datum, error)
 
 // evalAstExpr evaluates ast expression directly.
@@ -503,14 +505,14 @@ func (er *expressionRewriter) handleExistSubquery(v *ast.ExistsSubqueryExpr) (as
 		return v, true
 	}
 	np = er.b.buildExists(np)
-	if len(np.extractCorrelatedCols()) > 0 {
+	if len(np.extractCorrelatedCols()) > 0 || EvalSubquery == nil {
 		er.p = er.b.buildSemiApply(er.p, np.Children()[0].(LogicalPlan), nil, er.asScalar, false)
 		if !er.asScalar {
 			return v, true
 		}
 		er.ctxStack = append(er.ctxStack, er.p.Schema().Columns[er.p.Schema().Len()-1])
 	} else {
-		physicalPlan, err := doOptimize(er.b.optFlag, np, er.b.ctx, er.b.allocator)
+		physicalPlan, err := doOptimize(er.b, np)
 		if err != nil {
 			er.err = errors.Trace(err)
 			return v, true
@@ -552,8 +554,8 @@ func (er *expressionRewriter) handleInSubquery(v *ast.PatternInExpr) (ast.Node,
 	// Sometimes we can unfold the in subquery. For example, a in (select * from t) can rewrite to `a in (1,2,3,4)`.
 	// TODO: Now we cannot add it to CBO framework. Instead, user can set a session variable to open this optimization.
 	// We will improve our CBO framework in future.
-	if lLen == 1 && er.ctx.GetSessionVars().AllowInSubqueryUnFolding && len(np.extractCorrelatedCols()) == 0 {
-		physicalPlan, err := doOptimize(er.b.optFlag, np, er.b.ctx, er.b.allocator)
+	if lLen == 1 && er.ctx.GetSessionVars().AllowInSubqueryUnFolding && len(np.extractCorrelatedCols()) == 0 && EvalSubquery != nil {
+		physicalPlan, err := doOptimize(er.b, np)
 		if err != nil {
 			er.err = errors.Trace(err)
 			return v, true
@@ -618,7 +620,7 @@ func (er *expressionRewriter) handleScalarSubquery(v *ast.SubqueryExpr) (ast.Nod
 		return v, true
 	}
 	np = er.b.buildMaxOneRow(np)
-	if len(np.extractCorrelatedCols()) > 0 {
+	if len(np.extractCorrelatedCols()) > 0 || EvalSubquery == nil {
 		er.p = er.b.buildApplyWithJoinType(er.p, np, LeftOuterJoin)
 		if np.Schema().Len() > 1 {
 			newCols := make([]expression.Expression, 0, np.Schema().Len())
@@ -636,7 +638,7 @@ func (er *expressionRewriter) handleScalarSubquery(v *ast.SubqueryExpr) (ast.Nod
 		}
 		return v, true
 	}
-	physicalPlan, err := doOptimize(er.b.optFlag, np, er.b.ctx, er.b.allocator)
+	physicalPlan, err := doOptimize(er.b, np)
 	if err != nil {
 		er.err = errors.Trace(err)
 		return v, true
@@ -733,9 +735,30 @@ func (er *expressionRewriter) Leave(originInNode ast.Node) (retNode ast.Node, ok
 	if er.err != nil {
 		return retNode, false
 	}
+	if isOperation(inNode) && len(er.ctxStack) > 0 {
+		if _, ok := er.ctxStack[len(er.ctxStack)-1].(*expression.Constant); ok {
+			er.b.addRewrite(ruleConstantFolding)
+		}
+	}
 	return originInNode, true
 }
 
+// isOperation reports whether node computes a value from its arguments,
+// which is folded to a constant when all of them are constants. A negative
+// number is a literal, not the negation of one.
+func isOperation(node ast.Node) bool {
+	switch x := node.(type) {
+	case *ast.UnaryOperationExpr:
+		_, literal := x.V.(*ast.ValueExpr)
+		return !(literal && x.Op == opcode.Minus)
+	case *ast.FuncCallExpr, *ast.BinaryOperationExpr, *ast.BetweenExpr,
+		*ast.CaseExpr, *ast.FuncCastExpr, *ast.PatternLikeExpr, *ast.PatternRegexpExpr, *ast.PatternInExpr,
+		*ast.IsNullExpr, *ast.IsTruthExpr:
+		return true
+	}
+	return false
+}
+
 func datumToConstant(d types.Datum, tp byte) *expression.Constant {
 	return &expression.Constant{Value: d, RetType: types.NewFieldType(tp)}
 }
@@ -772,10 +795,28 @@ func (er *expressionRewriter) rewriteVariable(v *ast.VariableExpr) {
 	} else {
 		val, err = varsutil.GetSessionSystemVar(sessionVars, name)
 	}
+	if terror.ErrorEqual(err, variable.UnknownSystemVar) {
+		// Connectors ask for many variables in one query, some of which the
+		// server may not know. Each of those reads as NULL with a warning,
+		// rather than failing the whole query.
+		sessionVars.StmtCtx.AppendWarning(err)
+		er.ctxStack = append(er.ctxStack, datumToConstant(types.Datum{}, mysql.TypeNull))
+		return
+	}
 	if err != nil {
 		er.err = errors.Trace(err)
 		return
 	}
+	if tp := variable.GetSysVarType(name); tp != mysql.TypeVarString {
+		d := types.NewStringDatum(val)
+		d, err = d.ConvertTo(sessionVars.StmtCtx, types.NewFieldType(tp))
+		if err != nil {
+			er.err = errors.Trace(err)
+			return
+		}
+		er.ctxStack = append(er.ctxStack, datumToConstant(d, tp))
+		return
+	}
 	e := datumToConstant(types.NewStringDatum(val), mysql.TypeVarString)
 	e.RetType.Charset = er.ctx.GetSessionVars().Systems[variable.CharacterSetConnection]
 	e.RetType.Collate = er.ctx.GetSessionVars().Systems[variable.CollationConnection]
@@ -1136,7 +1177,7 @@ func (er *expressionRewriter) funcCallToExpression(v *ast.FuncCallExpr) {
 func (er *expressionRewriter) toColumn(v *ast.ColumnName) {
 	column, err := er.schema.FindColumn(v)
 	if err != nil {
-		er.err = ErrAmbiguous.GenByArgs(v.Name)
+		er.err = ErrAmbiguous.GenByArgs(v.Name, er.b.clause())
 		return
 	}
 	if column != nil {
@@ -1151,7 +1192,7 @@ func (er *expressionRewriter) toColumn(v *ast.ColumnName) {
 			return
 		}
 		if err != nil {
-			er.err = ErrAmbiguous.GenByArgs(v.Name)
+			er.err = ErrAmbiguous.GenByArgs(v.Name, er.b.clause())
 			return
 		}
 	}
@@ -14,31 +14,39 @@
 package plan
 
 import (
+	"math"
 	"sort"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/expression"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
 )
 
-// tryToGetJoinGroup tries to fetch a whole join group, which all joins is cartesian join.
-func tryToGetJoinGroup(j *LogicalJoin) ([]LogicalPlan, bool) {
+// tryToGetJoinGroup tries to fetch a whole join group of inner joins, with
+// the conditions of their ON clauses. A join that can't be reordered is a
+// table of the group.
+func tryToGetJoinGroup(j *LogicalJoin) ([]LogicalPlan, []expression.Expression, bool) {
 	// Ignore reorder if:
 	// 1. already reordered
 	// 2. not inner join
 	// 3. forced merge join
 	// 4. forced index nested loop join
-	if j.reordered || !j.cartesianJoin || j.preferMergeJoin || j.preferINLJ > 0 {
-		return nil, false
+	// 5. STRAIGHT_JOIN
+	if j.reordered || !(j.cartesianJoin || j.onInnerJoin) || j.preferMergeJoin || j.preferINLJ > 0 || j.straightJoin {
+		return nil, nil, false
 	}
+	conds := j.onConditions()
 	lChild := j.children[0].(LogicalPlan)
 	rChild := j.children[1].(LogicalPlan)
 	if nj, ok := lChild.(*LogicalJoin); ok {
-		plans, valid := tryToGetJoinGroup(nj)
-		return append(plans, rChild), valid
+		if plans, childConds, valid := tryToGetJoinGroup(nj); valid {
+			return append(plans, rChild), append(childConds, conds...), true
+		}
 	}
-	return []LogicalPlan{lChild, rChild}, true
+	return []LogicalPlan{lChild, rChild}, conds, true
 }
 
 func findColumnIndexByGroup(groups []LogicalPlan, col *expression.Column) int {
@@ -51,6 +59,10 @@ func findColumnIndexByGroup(groups []LogicalPlan, col *expression.Column) int {
 	return -1
 }
 
+// joinReorderDPLimit is the most tables of a join group ordered by dynamic
+// programming, the larger groups are ordered greedily.
+const joinReorderDPLimit = 6
+
 type joinReOrderSolver struct {
 	graph      []edgeList
 	group      []LogicalPlan
@@ -59,6 +71,30 @@ type joinReOrderSolver struct {
 	groupRank  []*rankInfo
 	allocator  *idAllocator
 	ctx        context.Context
+
+	// edges are the equal conditions joining two tables of the group.
+	edges []joinEdge
+	// total is the row count of each table of the group, rows the one left
+	// after the conditions on the table alone, read the cost of reading
+	// these rows.
+	total []float64
+	rows  []float64
+	read  []float64
+}
+
+// joinEdge is an equal condition between the column lCol of the table left
+// of the group and rCol of the table right.
+type joinEdge struct {
+	left, right int
+	lCol, rCol  *expression.Column
+}
+
+// joinOrder is a left-deep order of tables of the group, with the rows it
+// gives and the cost of joining them.
+type joinOrder struct {
+	tables []int
+	rows   float64
+	cost   float64
 }
 
 type edgeList []*rankInfo
@@ -92,81 +128,276 @@ func (e *joinReOrderSolver) Len() int {
 	return len(e.groupRank)
 }
 
-// reorderJoin implements a simple join reorder algorithm. It will extract all the equal conditions and compose them to a graph.
-// Then walk through the graph and pick the nodes connected by some edges to compose a join tree.
-// We will pick the node with least result set as early as possible.
+// reorderJoin orders the tables of group joined by conds. The statistics of
+// the tables give the rows each one has after its own conditions, and the
+// distinct values of the join keys how many rows the equal conditions
+// between them keep. Up to joinReorderDPLimit tables, every left-deep order
+// is costed by dynamic programming and the cheapest one kept, reading a
+// table by an index of its join key when it has one. Larger groups are
+// joined greedily, starting from the table with the fewest rows and
+// following the equal conditions.
 func (e *joinReOrderSolver) reorderJoin(group []LogicalPlan, conds []expression.Expression) {
 	e.graph = make([]edgeList, len(group))
 	e.group = group
 	e.visited = make([]bool, len(group))
 	e.resultJoin = nil
-	e.groupRank = make([]*rankInfo, len(group))
-	for i := 0; i < len(e.groupRank); i++ {
-		e.groupRank[i] = &rankInfo{
-			nodeID: i,
-			rate:   1.0,
-		}
-	}
+	e.edges = nil
+	tableConds := make([][]expression.Expression, len(group))
 	for _, cond := range conds {
-		if f, ok := cond.(*expression.ScalarFunction); ok {
-			if f.FuncName.L == ast.EQ {
-				lCol, lok := f.GetArgs()[0].(*expression.Column)
-				rCol, rok := f.GetArgs()[1].(*expression.Column)
-				if lok && rok {
-					lID := findColumnIndexByGroup(group, lCol)
-					rID := findColumnIndexByGroup(group, rCol)
-					if lID != rID {
-						e.graph[lID] = append(e.graph[lID], &rankInfo{nodeID: rID})
-						e.graph[rID] = append(e.graph[rID], &rankInfo{nodeID: lID})
-						continue
-					}
+		if f, ok := cond.(*expression.ScalarFunction); ok && f.FuncName.L == ast.EQ {
+			lCol, lok := f.GetArgs()[0].(*expression.Column)
+			rCol, rok := f.GetArgs()[1].(*expression.Column)
+			if lok && rok {
+				lID := findColumnIndexByGroup(group, lCol)
+				rID := findColumnIndexByGroup(group, rCol)
+				if lID >= 0 && rID >= 0 && lID != rID {
+					e.graph[lID] = append(e.graph[lID], &rankInfo{nodeID: rID})
+					e.graph[rID] = append(e.graph[rID], &rankInfo{nodeID: lID})
+					e.edges = append(e.edges, joinEdge{left: lID, right: rID, lCol: lCol, rCol: rCol})
+					continue
 				}
 			}
-			id := -1
-			rate := 1.0
-			cols := expression.ExtractColumns(f)
-			for _, col := range cols {
-				idx := findColumnIndexByGroup(group, col)
-				if id == -1 {
-					switch f.FuncName.L {
-					case ast.EQ:
-						rate *= 0.1
-					case ast.LT, ast.LE, ast.GE, ast.GT:
-						rate *= 0.3
-					// TODO: Estimate it more precisely in future.
-					default:
-						rate *= 0.9
-					}
-					id = idx
-				} else {
-					id = -1
-					break
-				}
-			}
-			if id != -1 {
-				e.groupRank[id].rate *= rate
+		}
+		id := -1
+		for _, col := range expression.ExtractColumns(cond) {
+			idx := findColumnIndexByGroup(group, col)
+			if id != -1 && idx != id {
+				id = -1
+				break
 			}
+			id = idx
+		}
+		if id != -1 {
+			tableConds[id] = append(tableConds[id], cond)
 		}
 	}
+	e.total = make([]float64, len(group))
+	e.rows = make([]float64, len(group))
+	e.read = make([]float64, len(group))
+	for i, p := range group {
+		e.total[i], e.rows[i] = e.estimateRows(p, tableConds[i])
+		e.read[i] = e.total[i] * scanFactor
+		if e.hasIndexedCondition(i, tableConds[i]) {
+			e.read[i] = seekFactor + e.rows[i]*scanFactor
+		}
+	}
+	if len(group) <= joinReorderDPLimit {
+		e.reorderJoinByDP()
+		return
+	}
+
+	e.groupRank = make([]*rankInfo, len(group))
+	for i := range e.groupRank {
+		e.groupRank[i] = &rankInfo{nodeID: i, rate: e.rows[i]}
+	}
 	for _, node := range e.graph {
 		for _, edge := range node {
 			edge.rate = e.groupRank[edge.nodeID].rate
 		}
 	}
-	sort.Sort(e)
+	sort.Stable(e)
 	for _, edge := range e.graph {
-		sort.Sort(edge)
+		sort.Stable(edge)
 	}
 	var cartesianJoinGroup []LogicalPlan
+	var tables []int
 	for j := 0; j < len(e.groupRank); j++ {
 		i := e.groupRank[j].nodeID
 		if !e.visited[i] {
 			e.resultJoin = e.group[i]
-			e.walkGraphAndComposeJoin(i)
+			tables = append(tables, e.walkGraphAndComposeJoin(i)...)
 			cartesianJoinGroup = append(cartesianJoinGroup, e.resultJoin)
 		}
 	}
 	e.makeBushyJoin(cartesianJoinGroup)
+	order := e.costOrder(tables)
+	optimizerTraceOf(e.ctx).addJoinOrder(e.tableNames(order.tables), order.rows, order.cost, true)
+}
+
+// reorderJoinByDP joins the group in the cheapest of its left-deep orders.
+// best[set] is the cheapest order of the tables of set, found from the ones
+// of set less one table.
+func (e *joinReOrderSolver) reorderJoinByDP() {
+	n := len(e.group)
+	all := 1<<uint(n) - 1
+	best := make([]*joinOrder, all+1)
+	for i := 0; i < n; i++ {
+		best[1<<uint(i)] = e.firstTable(i)
+	}
+	var considered []*joinOrder
+	for set := 1; set < all; set++ {
+		if best[set] == nil {
+			continue
+		}
+		for j := 0; j < n; j++ {
+			if set&(1<<uint(j)) != 0 {
+				continue
+			}
+			next := e.joinTable(best[set], j)
+			if set|1<<uint(j) == all {
+				considered = append(considered, next)
+			}
+			if cur := best[set|1<<uint(j)]; cur == nil || next.cost < cur.cost {
+				best[set|1<<uint(j)] = next
+			}
+		}
+	}
+	trace := optimizerTraceOf(e.ctx)
+	for _, order := range considered {
+		trace.addJoinOrder(e.tableNames(order.tables), order.rows, order.cost, order == best[all])
+	}
+	e.resultJoin = e.group[best[all].tables[0]]
+	for _, i := range best[all].tables[1:] {
+		e.resultJoin = e.newJoin(e.resultJoin, e.group[i])
+	}
+}
+
+// costOrder returns the cost of joining the tables of the group in order.
+func (e *joinReOrderSolver) costOrder(tables []int) *joinOrder {
+	order := e.firstTable(tables[0])
+	for _, i := range tables[1:] {
+		order = e.joinTable(order, i)
+	}
+	return order
+}
+
+// firstTable returns the order starting with table i.
+func (e *joinReOrderSolver) firstTable(i int) *joinOrder {
+	return &joinOrder{tables: []int{i}, rows: e.rows[i], cost: e.read[i]}
+}
+
+// joinTable returns order followed by table j. When an equal condition
+// joins j to a table of order by a column of j leading an index, j is read
+// by that index once for every row of order. Otherwise it is read once and
+// its rows matched to the ones of order.
+func (e *joinReOrderSolver) joinTable(order *joinOrder, j int) *joinOrder {
+	joined := make(map[int]bool, len(order.tables))
+	for _, i := range order.tables {
+		joined[i] = true
+	}
+	selectivity := 1.0
+	var key *expression.Column
+	for _, edge := range e.edges {
+		var col, other *expression.Column
+		switch {
+		case edge.left == j && joined[edge.right]:
+			col, other = edge.lCol, edge.rCol
+		case edge.right == j && joined[edge.left]:
+			col, other = edge.rCol, edge.lCol
+		default:
+			continue
+		}
+		selectivity /= math.Max(e.distinctValues(j, col), e.distinctValues(findColumnIndexByGroup(e.group, other), other))
+		if key == nil && e.hasIndexOn(j, col) {
+			key = col
+		}
+	}
+	var cost float64
+	if key != nil {
+		cost = order.rows * (seekFactor + e.total[j]/e.distinctValues(j, key)*scanFactor)
+	} else {
+		cost = e.read[j] + order.rows*cpuFactor
+	}
+	rows := order.rows * e.rows[j] * selectivity
+	tables := make([]int, 0, len(order.tables)+1)
+	tables = append(append(tables, order.tables...), j)
+	return &joinOrder{tables: tables, rows: rows, cost: order.cost + cost + rows*cpuFactor}
+}
+
+// estimateRows returns the rows of p, and the ones left after conds.
+func (e *joinReOrderSolver) estimateRows(p LogicalPlan, conds []expression.Expression) (total, rows float64) {
+	if ds, ok := p.(*DataSource); ok {
+		total = float64(ds.statisticTable.Count)
+		selectivity, err := ds.statisticTable.Selectivity(e.ctx, conds)
+		if err != nil {
+			log.Warnf("[join reorder] estimate the selectivity of %s error %v", ds.tableInfo.Name, err)
+			selectivity = selectionFactor
+		}
+		return total, total * selectivity
+	}
+	total = p.prepareStatsProfile().count
+	if len(conds) > 0 {
+		return total, total * selectionFactor
+	}
+	return total, total
+}
+
+// distinctValues returns the number of distinct values of col of table i.
+func (e *joinReOrderSolver) distinctValues(i int, col *expression.Column) float64 {
+	ndv := e.total[i] * distinctFactor
+	if ds, ok := e.group[i].(*DataSource); ok {
+		if info := ds.columnInfo(col); info != nil {
+			if hist, ok := ds.statisticTable.Columns[info.ID]; ok && hist.NDV > 0 {
+				ndv = float64(hist.NDV)
+			} else if ds.tableInfo.PKIsHandle && mysql.HasPriKeyFlag(info.Flag) {
+				ndv = e.total[i]
+			}
+		}
+	}
+	return math.Max(ndv, 1)
+}
+
+// hasIndexOn reports whether col of table i leads an index of the table.
+func (e *joinReOrderSolver) hasIndexOn(i int, col *expression.Column) bool {
+	ds, ok := e.group[i].(*DataSource)
+	if !ok {
+		return false
+	}
+	info := ds.columnInfo(col)
+	if info == nil {
+		return false
+	}
+	if ds.tableInfo.PKIsHandle && mysql.HasPriKeyFlag(info.Flag) {
+		return true
+	}
+	for _, index := range ds.tableInfo.Indices {
+		if index.State == model.StatePublic && index.Columns[0].Name.L == info.Name.L {
+			return true
+		}
+	}
+	return false
+}
+
+// hasIndexedCondition reports whether one of conds compares a column of
+// table i leading an index to a constant, so that the rows of the table
+// are read from that index.
+func (e *joinReOrderSolver) hasIndexedCondition(i int, conds []expression.Expression) bool {
+	for _, cond := range conds {
+		f, ok := cond.(*expression.ScalarFunction)
+		if !ok {
+			continue
+		}
+		switch f.FuncName.L {
+		case ast.EQ, ast.LT, ast.LE, ast.GT, ast.GE:
+		default:
+			continue
+		}
+		args := f.GetArgs()
+		col, ok := args[0].(*expression.Column)
+		if _, isConst := args[1].(*expression.Constant); !ok || !isConst {
+			col, ok = args[1].(*expression.Column)
+			if _, isConst = args[0].(*expression.Constant); !ok || !isConst {
+				continue
+			}
+		}
+		if e.hasIndexOn(i, col) {
+			return true
+		}
+	}
+	return false
+}
+
+// tableNames returns the names of tables of the group.
+func (e *joinReOrderSolver) tableNames(tables []int) []string {
+	names := make([]string, 0, len(tables))
+	for _, i := range tables {
+		if alias := extractTableAlias(e.group[i]); alias != nil {
+			names = append(names, alias.O)
+		} else {
+			names = append(names, ToString(e.group[i]))
+		}
+	}
+	return names
 }
 
 // Make cartesian join as bushy tree.
@@ -196,13 +427,16 @@ func (e *joinReOrderSolver) newJoin(lChild, rChild LogicalPlan) *LogicalJoin {
 }
 
 // walkGraph implements a dfs algorithm. Each time it picks a edge with lowest rate, which has been sorted before.
-func (e *joinReOrderSolver) walkGraphAndComposeJoin(u int) {
+// It returns the tables joined, in order.
+func (e *joinReOrderSolver) walkGraphAndComposeJoin(u int) []int {
 	e.visited[u] = true
+	tables := []int{u}
 	for _, edge := range e.graph[u] {
 		v := edge.nodeID
 		if !e.visited[v] {
 			e.resultJoin = e.newJoin(e.resultJoin, e.group[v])
-			e.walkGraphAndComposeJoin(v)
+			tables = append(tables, e.walkGraphAndComposeJoin(v)...)
 		}
 	}
+	return tables
 }
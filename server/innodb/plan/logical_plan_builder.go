@@ -23,11 +23,13 @@ import (
 	"github.com/cznic/mathutil"
 	"github.com/juju/errors"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/expression"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/expression/aggregation"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/parser"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/varsutil"
 	"github.com/zhukovaskychina/xmysql-server/server/mysql"
 )
 
@@ -36,6 +38,10 @@ const (
 	TiDBMergeJoin = "tidb_smj"
 	// TiDBIndexNestedLoopJoin is hint enforce index nested loop join.
 	TiDBIndexNestedLoopJoin = "tidb_inlj"
+	// HintIndex is hint enforce reading a table by one of the indices.
+	HintIndex = "index"
+	// HintNoIndex is hint enforce not reading a table by the indices.
+	HintNoIndex = "no_index"
 )
 
 type idAllocator struct {
@@ -56,7 +62,7 @@ func (p *LogicalAggregation) collectGroupByColumns() {
 	}
 }
 
-func (b *planBuilder) buildAggregation(p LogicalPlan, aggFuncList []*ast.AggregateFuncExpr, gbyItems []expression.Expression) (LogicalPlan, map[int]int) {
+func (b *planBuilder) buildAggregation(p LogicalPlan, aggFuncList []*ast.AggregateFuncExpr, gbyItems []expression.Expression, rollup bool) (LogicalPlan, map[int]int) {
 	b.optFlag = b.optFlag | flagBuildKeyInfo
 	b.optFlag = b.optFlag | flagAggregationOptimize
 
@@ -76,6 +82,20 @@ func (b *planBuilder) buildAggregation(p LogicalPlan, aggFuncList []*ast.Aggrega
 			newArgList = append(newArgList, newArg)
 		}
 		newFunc := aggregation.NewAggFunction(aggFunc.F, newArgList, aggFunc.Distinct)
+		if aggFunc.Order != nil {
+			byItems := make([]expression.Expression, 0, len(aggFunc.Order.Items))
+			desc := make([]bool, 0, len(aggFunc.Order.Items))
+			for _, item := range aggFunc.Order.Items {
+				newItem, np, err := b.rewrite(item.Expr, p, nil, true)
+				if err != nil {
+					b.err = errors.Trace(err)
+					return nil, nil
+				}
+				p = np
+				byItems, desc = append(byItems, newItem), append(desc, item.Desc)
+			}
+			newFunc = aggregation.NewGroupConcat(newArgList, aggFunc.Distinct, byItems, desc)
+		}
 		combined := false
 		for j, oldFunc := range agg.AggFuncs {
 			if oldFunc.Equal(newFunc, b.ctx) {
@@ -103,6 +123,7 @@ func (b *planBuilder) buildAggregation(p LogicalPlan, aggFuncList []*ast.Aggrega
 	}
 	setParentAndChildren(agg, p)
 	agg.GroupByItems = gbyItems
+	agg.Rollup = rollup
 	agg.SetSchema(schema)
 	agg.collectGroupByColumns()
 	return agg, aggIndexMap
@@ -130,13 +151,27 @@ func (b *planBuilder) buildResultSetNode(node ast.ResultSetNode) LogicalPlan {
 		}
 		if v, ok := p.(*DataSource); ok {
 			v.TableAsName = &x.AsName
+			if hints := b.TableHints(); hints != nil {
+				tblName := x.AsName
+				if tblName.L == "" {
+					tblName = v.tableInfo.Name
+				}
+				v.indexHints = hints.indexHintsOf(v.tableInfo, tblName, v.indexHints)
+			}
 		}
-		for _, col := range p.Schema().Columns {
+		if len(x.ColumnNames) > 0 && len(x.ColumnNames) != p.Schema().Len() {
+			b.err = schemas.ErrViewWrongList.GenByArgs()
+			return nil
+		}
+		for i, col := range p.Schema().Columns {
 			col.OrigTblName = col.TblName
 			if x.AsName.L != "" {
 				col.TblName = x.AsName
 				col.DBName = model.NewCIStr("")
 			}
+			if len(x.ColumnNames) > 0 {
+				col.ColName = x.ColumnNames[i]
+			}
 		}
 		return p
 	case *ast.SelectStmt:
@@ -235,7 +270,7 @@ func (b *planBuilder) buildJoin(join *ast.Join) LogicalPlan {
 	rightAlias := extractTableAlias(rightPlan)
 
 	newSchema := expression.MergeSchema(leftPlan.Schema(), rightPlan.Schema())
-	joinPlan := LogicalJoin{}.init(b.allocator, b.ctx)
+	joinPlan := LogicalJoin{straightJoin: join.StraightJoin || b.straightJoin}.init(b.allocator, b.ctx)
 	setParentAndChildren(joinPlan, leftPlan, rightPlan)
 	joinPlan.SetSchema(newSchema)
 
@@ -276,6 +311,7 @@ func (b *planBuilder) buildJoin(join *ast.Join) LogicalPlan {
 			return nil
 		}
 	} else if join.On != nil {
+		b.curClause = onClause
 		onExpr, _, err := b.rewrite(join.On.Expr, joinPlan, nil, false)
 		if err != nil {
 			b.err = err
@@ -296,6 +332,7 @@ func (b *planBuilder) buildJoin(join *ast.Join) LogicalPlan {
 		joinPlan.JoinType = RightOuterJoin
 	} else {
 		joinPlan.JoinType = InnerJoin
+		joinPlan.onInnerJoin = join.On != nil
 	}
 	return joinPlan
 }
@@ -415,6 +452,10 @@ func (b *planBuilder) buildSelection(p LogicalPlan, where ast.ExprNode, AggMappe
 					// If there is condition which is always false, return dual plan directly.
 					dual := TableDual{}.init(b.allocator, b.ctx)
 					dual.SetSchema(p.Schema().Clone())
+					if b.curClause == whereClause {
+						dual.impossibleWhere = true
+						b.addRewrite(ruleImpossibleWhere)
+					}
 					return dual
 				}
 			}
@@ -545,6 +586,7 @@ func (b *planBuilder) buildDistinct(child LogicalPlan, length int) LogicalPlan {
 	agg := LogicalAggregation{
 		AggFuncs:     make([]aggregation.Aggregation, 0, child.Schema().Len()),
 		GroupByItems: expression.Column2Exprs(child.Schema().Clone().Columns[:length]),
+		Distinct:     true,
 	}.init(b.allocator, b.ctx)
 	agg.collectGroupByColumns()
 	for _, col := range child.Schema().Columns {
@@ -561,12 +603,43 @@ func joinFieldType(a, b *types.FieldType) *types.FieldType {
 	resultTp.Decimal = mathutil.Max(a.Decimal, b.Decimal)
 	// `Flen - Decimal` is the fraction before '.'
 	resultTp.Flen = mathutil.Max(a.Flen-a.Decimal, b.Flen-b.Decimal) + resultTp.Decimal
+	if resultTp.EvalType() == types.ETInt && a.EvalType() == types.ETInt && b.EvalType() == types.ETInt {
+		aUnsigned, bUnsigned := mysql.HasUnsignedFlag(a.Flag), mysql.HasUnsignedFlag(b.Flag)
+		switch {
+		case aUnsigned && bUnsigned:
+			resultTp.Flag |= mysql.UnsignedFlag
+		case aUnsigned != bUnsigned && resultTp.Tp == mysql.TypeLonglong:
+			// No integer type holds both a negative BIGINT and a BIGINT
+			// UNSIGNED past its maximum, MySQL merges them to DECIMAL(20).
+			resultTp.Tp, resultTp.Flen, resultTp.Decimal = mysql.TypeNewDecimal, mysql.MaxIntWidth, 0
+		case aUnsigned != bUnsigned:
+			resultTp.Tp, resultTp.Flen = mysql.TypeLonglong, mysql.MaxIntWidth
+		}
+	}
 	resultTp.Charset = a.Charset
 	resultTp.Collate = a.Collate
 	expression.SetBinFlagOrBinStr(b, resultTp)
 	return resultTp
 }
 
+// castUnionColumn casts the i-th column of proj, a select of a union, to the
+// numeric type tp of the union's column when it has another one, so that an
+// INT selected along with a DECIMAL is returned with the DECIMAL's scale and
+// a DECIMAL along with a DOUBLE as a DOUBLE.
+func castUnionColumn(ctx context.Context, proj *Projection, i int, tp *types.FieldType) {
+	col := proj.Schema().Columns[i]
+	switch tp.EvalType() {
+	case types.ETInt, types.ETDecimal, types.ETReal:
+	default:
+		return
+	}
+	if col.RetType.EvalType() == tp.EvalType() && (tp.EvalType() != types.ETDecimal || col.RetType.Decimal == tp.Decimal) {
+		return
+	}
+	proj.Exprs[i] = expression.BuildCastFunction(ctx, proj.Exprs[i], tp)
+	col.RetType = tp
+}
+
 func (b *planBuilder) buildUnion(union *ast.UnionStmt) LogicalPlan {
 	u := Union{}.init(b.allocator, b.ctx)
 	u.children = make([]Plan, len(union.SelectList.Selects))
@@ -609,6 +682,9 @@ func (b *planBuilder) buildUnion(union *ast.UnionStmt) LogicalPlan {
 			}
 		}
 		col.RetType = resultTp
+		for _, child := range u.children {
+			castUnionColumn(b.ctx, child.(*Projection), i, resultTp)
+		}
 	}
 
 	for _, v := range firstSchema.Columns {
@@ -721,8 +797,8 @@ func (b *planBuilder) buildLimit(src LogicalPlan, limit *ast.Limit) LogicalPlan
 // colMatch(a,b) means that if a match b, e.g. t.a can match test.t.a but test.t.a can't match t.a.
 // Because column a want column from database test exactly.
 func colMatch(a *ast.ColumnName, b *ast.ColumnName) bool {
-	if a.Schema.L == "" || a.Schema.L == b.Schema.L {
-		if a.Table.L == "" || a.Table.L == b.Table.L {
+	if a.Schema.L == "" || model.TableNamesEqual(a.Schema, b.Schema) {
+		if a.Table.L == "" || model.TableNamesEqual(a.Table, b.Table) {
 			return a.Name.L == b.Name.L
 		}
 	}
@@ -744,7 +820,7 @@ func matchField(f *ast.SelectField, col *ast.ColumnNameExpr, ignoreAsName bool)
 	return false
 }
 
-func resolveFromSelectFields(v *ast.ColumnNameExpr, fields []*ast.SelectField, ignoreAsName bool) (index int, err error) {
+func resolveFromSelectFields(v *ast.ColumnNameExpr, fields []*ast.SelectField, ignoreAsName bool, clause string) (index int, err error) {
 	var matchedExpr ast.ExprNode
 	index = -1
 	for i, field := range fields {
@@ -761,7 +837,7 @@ func resolveFromSelectFields(v *ast.ColumnNameExpr, fields []*ast.SelectField, i
 				index = i
 			} else if !colMatch(matchedExpr.(*ast.ColumnNameExpr).Name, curCol.Name) &&
 				!colMatch(curCol.Name, matchedExpr.(*ast.ColumnNameExpr).Name) {
-				return -1, ErrAmbiguous.GenByArgs(curCol.Name.Name.L)
+				return -1, ErrAmbiguous.GenByArgs(v.Name.Name.O, clause)
 			}
 		}
 	}
@@ -799,10 +875,18 @@ func (a *havingAndOrderbyExprResolver) Enter(n ast.Node) (node ast.Node, skipChi
 	return n, false
 }
 
+// clause returns the clause being resolved.
+func (a *havingAndOrderbyExprResolver) clause() string {
+	if a.orderBy {
+		return orderByClause
+	}
+	return havingClause
+}
+
 func (a *havingAndOrderbyExprResolver) resolveFromSchema(v *ast.ColumnNameExpr, schema *expression.Schema) (int, error) {
 	col, err := schema.FindColumn(v.Name)
 	if err != nil {
-		return -1, errors.Trace(err)
+		return -1, ErrAmbiguous.GenByArgs(v.Name.Name.L, a.clause())
 	}
 	if col == nil {
 		return -1, nil
@@ -853,7 +937,7 @@ func (a *havingAndOrderbyExprResolver) Leave(n ast.Node) (node ast.Node, ok bool
 		}
 		index := -1
 		if resolveFieldsFirst {
-			index, a.err = resolveFromSelectFields(v, a.selectFields, false)
+			index, a.err = resolveFromSelectFields(v, a.selectFields, false, a.clause())
 			if a.err != nil {
 				return node, false
 			}
@@ -861,7 +945,7 @@ func (a *havingAndOrderbyExprResolver) Leave(n ast.Node) (node ast.Node, ok bool
 				if a.orderBy {
 					index, a.err = a.resolveFromSchema(v, a.p.Schema())
 				} else {
-					index, a.err = resolveFromSelectFields(v, a.selectFields, true)
+					index, a.err = resolveFromSelectFields(v, a.selectFields, true, a.clause())
 				}
 			}
 		} else {
@@ -871,7 +955,7 @@ func (a *havingAndOrderbyExprResolver) Leave(n ast.Node) (node ast.Node, ok bool
 			index, err = a.resolveFromSchema(v, a.p.Schema())
 			_ = err
 			if index == -1 {
-				index, a.err = resolveFromSelectFields(v, a.selectFields, false)
+				index, a.err = resolveFromSelectFields(v, a.selectFields, false, a.clause())
 			}
 		}
 		if a.err != nil {
@@ -882,7 +966,7 @@ func (a *havingAndOrderbyExprResolver) Leave(n ast.Node) (node ast.Node, ok bool
 			for _, schema := range a.outerSchemas {
 				col, err1 := schema.FindColumn(v.Name)
 				if err1 != nil {
-					a.err = errors.Trace(err1)
+					a.err = ErrAmbiguous.GenByArgs(v.Name.Name.L, a.clause())
 					return node, false
 				}
 				if col != nil {
@@ -983,7 +1067,7 @@ func (g *gbyResolver) Leave(inNode ast.Node) (ast.Node, bool) {
 		col, err := g.schema.FindColumn(v.Name)
 		if col == nil || !g.inExpr {
 			var index = -1
-			index, g.err = resolveFromSelectFields(v, g.fields, false)
+			index, g.err = resolveFromSelectFields(v, g.fields, false, groupByStatement)
 			if g.err != nil {
 				return inNode, false
 			}
@@ -993,7 +1077,9 @@ func (g *gbyResolver) Leave(inNode ast.Node) (ast.Node, bool) {
 			if index != -1 {
 				return g.fields[index].Expr, true
 			}
-			g.err = errors.Trace(err)
+			if err != nil {
+				g.err = ErrAmbiguous.GenByArgs(v.Name.Name.L, groupByStatement)
+			}
 			return inNode, false
 		}
 	case *ast.PositionExpr:
@@ -1041,8 +1127,8 @@ func (b *planBuilder) unfoldWildStar(p LogicalPlan, selectFields []*ast.SelectFi
 		dbName := field.WildCard.Schema
 		tblName := field.WildCard.Table
 		for _, col := range p.Schema().Columns {
-			if (dbName.L == "" || dbName.L == col.DBName.L) &&
-				(tblName.L == "" || tblName.L == col.TblName.L) &&
+			if (dbName.L == "" || model.TableNamesEqual(dbName, col.DBName)) &&
+				(tblName.L == "" || model.TableNamesEqual(tblName, col.TblName)) &&
 				col.ID != model.ExtraHandleID {
 				colName := &ast.ColumnNameExpr{
 					Name: &ast.ColumnName{
@@ -1062,20 +1148,33 @@ func (b *planBuilder) unfoldWildStar(p LogicalPlan, selectFields []*ast.SelectFi
 
 func (b *planBuilder) pushTableHints(hints []*ast.TableOptimizerHint) bool {
 	var sortMergeTables, INLJTables []model.CIStr
+	var indexHints []indexHintInfo
 	for _, hint := range hints {
 		switch hint.HintName.L {
 		case TiDBMergeJoin:
 			sortMergeTables = append(sortMergeTables, hint.Tables...)
 		case TiDBIndexNestedLoopJoin:
 			INLJTables = append(INLJTables, hint.Tables...)
+		case HintIndex:
+			indexHints = append(indexHints, indexHintInfo{tblName: hint.Tables[0],
+				hint: &ast.IndexHint{IndexNames: hint.Indexes, HintType: ast.HintForce, HintScope: ast.HintForScan}})
+		case HintNoIndex:
+			hintType := ast.HintIgnore
+			if len(hint.Indexes) == 0 {
+				// An empty USE INDEX reads the table without any index.
+				hintType = ast.HintUse
+			}
+			indexHints = append(indexHints, indexHintInfo{tblName: hint.Tables[0],
+				hint: &ast.IndexHint{IndexNames: hint.Indexes, HintType: hintType, HintScope: ast.HintForScan}})
 		default:
 			// ignore hints that not implemented
 		}
 	}
-	if len(sortMergeTables) != 0 || len(INLJTables) != 0 {
+	if len(sortMergeTables) != 0 || len(INLJTables) != 0 || len(indexHints) != 0 {
 		b.tableHintInfo = append(b.tableHintInfo, tableHintInfo{
 			sortMergeJoinTables:       sortMergeTables,
 			indexNestedLoopJoinTables: INLJTables,
+			indexHints:                indexHints,
 		})
 		return true
 	}
@@ -1106,6 +1205,12 @@ func (b *planBuilder) buildSelect(sel *ast.SelectStmt) LogicalPlan {
 		b.needColHandle++
 	}
 
+	if sel.SelectIntoOpt != nil {
+		b.visitInfo = appendVisitInfo(b.visitInfo, mysql.FilePriv, "", "", "")
+	}
+	// A subquery is built in the middle of a clause of the outer query.
+	defer func(clause string) { b.curClause = clause }(b.curClause)
+
 	hasAgg := b.detectSelectAgg(sel)
 	var (
 		p                             LogicalPlan
@@ -1114,6 +1219,8 @@ func (b *planBuilder) buildSelect(sel *ast.SelectStmt) LogicalPlan {
 		gbyCols                       []expression.Expression
 	)
 	if sel.From != nil {
+		defer func(straightJoin bool) { b.straightJoin = straightJoin }(b.straightJoin)
+		b.straightJoin = sel.SelectStmtOpts != nil && sel.StraightJoin
 		p = b.buildResultSetNode(sel.From.TableRefs)
 	} else {
 		p = b.buildTableDual()
@@ -1121,12 +1228,19 @@ func (b *planBuilder) buildSelect(sel *ast.SelectStmt) LogicalPlan {
 	if b.err != nil {
 		return nil
 	}
+	b.curClause = fieldList
 	originalFields := sel.Fields.Fields
 	sel.Fields.Fields = b.unfoldWildStar(p, sel.Fields.Fields)
 	if b.err != nil {
 		return nil
 	}
 	if sel.GroupBy != nil {
+		// As in MySQL 5.7, the rows of a rollup can't be sorted.
+		if sel.GroupBy.Rollup && sel.OrderBy != nil {
+			b.err = ErrWrongUsage.GenByArgs("CUBE/ROLLUP", "ORDER BY")
+			return nil
+		}
+		b.curClause = groupByStatement
 		p, gbyCols = b.resolveGbyExprs(p, sel.GroupBy, sel.Fields.Fields)
 		if b.err != nil {
 			return nil
@@ -1137,11 +1251,13 @@ func (b *planBuilder) buildSelect(sel *ast.SelectStmt) LogicalPlan {
 	// which only can be done before building projection and extracting Agg functions.
 	havingMap, orderMap = b.resolveHavingAndOrderBy(sel, p)
 	if sel.Where != nil {
+		b.curClause = whereClause
 		p = b.buildSelection(p, sel.Where, nil)
 		if b.err != nil {
 			return nil
 		}
 	}
+	b.curClause = fieldList
 	if sel.LockTp != ast.SelectLockNone {
 		p = b.buildSelectLock(p, sel.LockTp)
 	}
@@ -1151,7 +1267,7 @@ func (b *planBuilder) buildSelect(sel *ast.SelectStmt) LogicalPlan {
 			return nil
 		}
 		var aggIndexMap map[int]int
-		p, aggIndexMap = b.buildAggregation(p, aggFuncs, gbyCols)
+		p, aggIndexMap = b.buildAggregation(p, aggFuncs, gbyCols, sel.GroupBy != nil && sel.GroupBy.Rollup)
 		for k, v := range totalMap {
 			totalMap[k] = aggIndexMap[v]
 		}
@@ -1165,6 +1281,7 @@ func (b *planBuilder) buildSelect(sel *ast.SelectStmt) LogicalPlan {
 		return nil
 	}
 	if sel.Having != nil {
+		b.curClause = havingClause
 		p = b.buildSelection(p, sel.Having.Expr, havingMap)
 		if b.err != nil {
 			return nil
@@ -1177,6 +1294,7 @@ func (b *planBuilder) buildSelect(sel *ast.SelectStmt) LogicalPlan {
 		}
 	}
 	if sel.OrderBy != nil {
+		b.curClause = orderByClause
 		p = b.buildSort(p, sel.OrderBy.Items, orderMap)
 		if b.err != nil {
 			return nil
@@ -1225,41 +1343,51 @@ func (ds *DataSource) newExtraHandleSchemaCol() *expression.Column {
 }
 
 func (b *planBuilder) buildDataSource(tn *ast.TableName) LogicalPlan {
-	//handle := sessionctx.GetDomain(b.ctx).StatsHandle()
-	//var statisticTable *statistics.Table
-	//if handle == nil {
-	//	// When the first session is created, the handle hasn't been initialized.
-	//	statisticTable = statistics.PseudoTable(tn.TableInfo.ID)
-	//} else {
-	//	statisticTable = handle.GetTableStats(tn.TableInfo.ID)
-	//}
-
 	schemaName := tn.Schema
 	if schemaName.L == "" {
 		schemaName = model.NewCIStr(b.ctx.GetSessionVars().CurrentDB)
 	}
-	tbl, err := b.is.TableByName(schemaName, tn.Name)
+	tbl, err := schemas.TableByName(b.is, schemaName, tn.Name)
 	if err != nil {
 		b.err = errors.Trace(err)
 		return nil
 	}
 	tableInfo := tbl.Meta()
+	if tableInfo.IsView() {
+		return b.buildDataSourceFromView(schemaName, tableInfo)
+	}
+	if schemas.IsMemTable(schemaName, tableInfo.Name) {
+		return b.buildMemTable(schemaName, tableInfo)
+	}
+	if err = checkIndexHints(tn.IndexHints, tableInfo); err != nil {
+		b.err = errors.Trace(err)
+		return nil
+	}
 
 	p := DataSource{
 		indexHints:     tn.IndexHints,
 		tableInfo:      tableInfo,
-		statisticTable: nil,
+		statisticTable: tableStats(tableInfo.ID),
 		DBName:         schemaName,
 		Columns:        make([]*model.ColumnInfo, 0, len(tableInfo.Columns)),
 		NeedColHandle:  b.needColHandle > 0,
 	}.init(b.allocator, b.ctx)
 	b.visitInfo = appendVisitInfo(b.visitInfo, mysql.SelectPriv, schemaName.L, tableInfo.Name.L, "")
 
+	// The columns are taken from the meta, as not every Table implements
+	// Cols and WritableCols.
 	var columns []*schemas.Column
-	if b.inUpdateStmt {
-		//columns = tbl.WritableCols()
-	} else {
-		//	columns = tbl.Cols()
+	for _, col := range tableInfo.Columns {
+		switch col.State {
+		case model.StatePublic:
+		case model.StateWriteOnly, model.StateWriteReorganization:
+			if !b.inUpdateStmt {
+				continue
+			}
+		default:
+			continue
+		}
+		columns = append(columns, schemas.ToColumn(col))
 	}
 	var pkCol *expression.Column
 	p.Columns = make([]*model.ColumnInfo, 0, len(columns))
@@ -1320,17 +1448,145 @@ func (b *planBuilder) buildDataSource(tn *ast.TableName) LogicalPlan {
 	return b.projectVirtualColumns(p, columns)
 }
 
+// buildDataSourceFromView plans the SELECT of a view in place of the view,
+// like a derived table named after it. Filters on the view are merged into
+// the SELECT by the usual predicate push down, which stops on its own at
+// aggregations and limits that must be computed first.
+func (b *planBuilder) buildDataSourceFromView(dbName model.CIStr, tableInfo *model.TableInfo) LogicalPlan {
+	charset, collation := b.ctx.GetSessionVars().GetCharsetInfo()
+	stmt, err := parser.New().ParseOneStmt(tableInfo.View.SelectStmt, charset, collation)
+	if err != nil {
+		b.err = errors.Trace(err)
+		return nil
+	}
+	if err = ExpandWith(b.ctx, stmt); err != nil {
+		b.err = errors.Trace(err)
+		return nil
+	}
+	// Unqualified tables in the SELECT belong to the schema of the view.
+	if err = resolveNameInSchema(stmt, b.is, dbName.O, b.ctx); err != nil {
+		b.err = schemas.ErrViewInvalid.GenByArgs(dbName.O, tableInfo.Name.O)
+		return nil
+	}
+	selectPlan := b.buildResultSetNode(stmt.(ast.ResultSetNode))
+	if b.err != nil {
+		return nil
+	}
+	if selectPlan.Schema().Len() != len(tableInfo.Columns) {
+		b.err = schemas.ErrViewInvalid.GenByArgs(dbName.O, tableInfo.Name.O)
+		return nil
+	}
+
+	b.optFlag |= flagEliminateProjection
+	proj := Projection{Exprs: expression.Column2Exprs(selectPlan.Schema().Columns)}.init(b.allocator, b.ctx)
+	schema := expression.NewSchema(make([]*expression.Column, 0, len(tableInfo.Columns))...)
+	for i, col := range selectPlan.Schema().Columns {
+		schema.Append(&expression.Column{
+			FromID:   proj.id,
+			Position: i + 1,
+			ColName:  tableInfo.Columns[i].Name,
+			TblName:  tableInfo.Name,
+			DBName:   dbName,
+			RetType:  col.RetType,
+		})
+	}
+	proj.SetSchema(schema)
+	setParentAndChildren(proj, selectPlan)
+	return proj
+}
+
+// buildMemTable builds the plan reading an information_schema table made up
+// by the server: a projection of the values of each of its rows over a dual
+// table, under a union when there are several.
+func (b *planBuilder) buildMemTable(dbName model.CIStr, tableInfo *model.TableInfo) LogicalPlan {
+	var rows [][]types.Datum
+	switch tableInfo.Name.L {
+	case "optimizer_trace":
+		// Reading the trace must not replace it.
+		b.readOptimizerTrace = true
+		if row := optimizerTraceRow(b.ctx.GetSessionVars().LastOptimizerTrace); row != nil {
+			rows = append(rows, row)
+		}
+	case "key_column_usage":
+		rows = schemas.KeyColumnUsageRows(b.is)
+	case "referential_constraints":
+		rows = schemas.ReferentialConstraintsRows(b.is)
+	case "statistics":
+		rows = schemas.StatisticsRows(b.is)
+	case "xmysql_stats_auto_recalc":
+		rows = schemas.StatsAutoRecalcRows()
+	case "innodb_buffer_page":
+		rows = schemas.BufferPageRows()
+	case "innodb_buffer_pool_stats_by_table":
+		rows = schemas.BufferPoolStatsByTableRows()
+	case "innodb_buffer_pool_stats_by_index":
+		rows = schemas.BufferPoolStatsByIndexRows()
+	case "tables":
+		defaultRowFormat, _ := varsutil.GetGlobalSystemVar(b.ctx.GetSessionVars(), variable.InnodbDefaultRowFormat)
+		rows = schemas.TablesRows(b.is, defaultRowFormat)
+	case "persisted_variables":
+		rows = schemas.PersistedVariablesRows(variable.GetPersistedVariables())
+	case "memory_summary_by_thread_by_event_name":
+		rows = schemas.MemorySummaryByThreadRows(variable.Processes())
+	case "memory_summary_global_by_event_name":
+		rows = schemas.MemorySummaryGlobalRows()
+	}
+	newSchema := func() *expression.Schema {
+		schema := expression.NewSchema(make([]*expression.Column, 0, len(tableInfo.Columns))...)
+		for i, col := range tableInfo.Columns {
+			schema.Append(&expression.Column{
+				Position: i + 1,
+				ColName:  col.Name,
+				TblName:  tableInfo.Name,
+				DBName:   dbName,
+				RetType:  &col.FieldType,
+			})
+		}
+		return schema
+	}
+	if len(rows) == 0 {
+		dual := TableDual{}.init(b.allocator, b.ctx)
+		dual.SetSchema(newSchema())
+		return dual
+	}
+	projs := make([]Plan, 0, len(rows))
+	for _, row := range rows {
+		exprs := make([]expression.Expression, 0, len(row))
+		for i, d := range row {
+			exprs = append(exprs, &expression.Constant{Value: d, RetType: &tableInfo.Columns[i].FieldType})
+		}
+		proj := Projection{Exprs: exprs}.init(b.allocator, b.ctx)
+		schema := newSchema()
+		for _, col := range schema.Columns {
+			col.FromID = proj.id
+		}
+		proj.SetSchema(schema)
+		setParentAndChildren(proj, b.buildTableDual())
+		projs = append(projs, proj)
+	}
+	if len(projs) == 1 {
+		return projs[0].(LogicalPlan)
+	}
+	u := Union{}.init(b.allocator, b.ctx)
+	schema := newSchema()
+	for _, col := range schema.Columns {
+		col.FromID = u.id
+	}
+	u.SetSchema(schema)
+	setParentAndChildren(u, projs...)
+	return u
+}
+
 // projectVirtualColumns is only for DataSource. If some table has virtual generated columns,
 // we add a projection on the original DataSource, and calculate those columns in the projection
 // so that plans above it can reference generated columns by their name.
 func (b *planBuilder) projectVirtualColumns(ds *DataSource, columns []*schemas.Column) LogicalPlan {
 	var hasVirtualGeneratedColumn = false
 	for _, column := range columns {
-		//if column.IsGenerated() && !column.GeneratedStored {
-		//	hasVirtualGeneratedColumn = true
-		//	break
-		//}
-		fmt.Println(column)
+		if column.IsGenerated() && !column.GeneratedStored {
+			hasVirtualGeneratedColumn = true
+			break
+		}
 	}
 	if !hasVirtualGeneratedColumn {
 		return ds
@@ -1463,13 +1719,28 @@ func (b *planBuilder) buildUpdate(update *ast.UpdateStmt) LogicalPlan {
 	b.inUpdateStmt = true
 	b.needColHandle++
 	sel := &ast.SelectStmt{Fields: &ast.FieldList{}, From: update.TableRefs, Where: update.Where, OrderBy: update.Order, Limit: update.Limit}
+	var tableList []*ast.TableName
+	tableList = extractTableList(sel.From.TableRefs, tableList)
+	b.checkNotView(tableList, "UPDATE")
+	if b.err != nil {
+		return nil
+	}
+	// ORDER BY and LIMIT only apply to a single-table UPDATE.
+	if len(tableList) > 1 {
+		if update.Order != nil {
+			b.err = ErrWrongUsage.GenByArgs("UPDATE", "ORDER BY")
+			return nil
+		}
+		if update.Limit != nil {
+			b.err = ErrWrongUsage.GenByArgs("UPDATE", "LIMIT")
+			return nil
+		}
+	}
 	p := b.buildResultSetNode(sel.From.TableRefs)
 	if b.err != nil {
 		return nil
 	}
 
-	var tableList []*ast.TableName
-	tableList = extractTableList(sel.From.TableRefs, tableList)
 	for _, t := range tableList {
 		dbName := t.Schema.L
 		if dbName == "" {
@@ -1479,12 +1750,14 @@ func (b *planBuilder) buildUpdate(update *ast.UpdateStmt) LogicalPlan {
 	}
 
 	if sel.Where != nil {
+		b.curClause = whereClause
 		p = b.buildSelection(p, sel.Where, nil)
 		if b.err != nil {
 			return nil
 		}
 	}
 	if sel.OrderBy != nil {
+		b.curClause = orderByClause
 		p = b.buildSort(p, sel.OrderBy.Items, nil)
 		if b.err != nil {
 			return nil
@@ -1496,6 +1769,7 @@ func (b *planBuilder) buildUpdate(update *ast.UpdateStmt) LogicalPlan {
 			return nil
 		}
 	}
+	b.curClause = fieldList
 	orderedList, np := b.buildUpdateLists(tableList, update.List, p)
 	if b.err != nil {
 		return nil
@@ -1531,9 +1805,9 @@ func (b *planBuilder) buildUpdateLists(tableList []*ast.TableName, list []*ast.A
 
 	for _, tn := range tableList {
 		tableInfo := tn.TableInfo
-		table, found := b.is.TableByID(tableInfo.ID)
-		if !found {
-			b.err = schemas.ErrTableNotExists.GenByArgs(tn.DBInfo.Name.O, tableInfo.Name.O)
+		table, err := schemas.TableByName(b.is, tn.Schema, tn.Name)
+		if err != nil {
+			b.err = errors.Trace(err)
 			return nil, nil
 		}
 		for i, colInfo := range tableInfo.Columns {
@@ -1624,18 +1898,28 @@ func extractTableAsNameForUpdate(p Plan, asNames map[*model.TableInfo][]*model.C
 func (b *planBuilder) buildDelete(delete *ast.DeleteStmt) LogicalPlan {
 	b.needColHandle++
 	sel := &ast.SelectStmt{Fields: &ast.FieldList{}, From: delete.TableRefs, Where: delete.Where, OrderBy: delete.Order, Limit: delete.Limit}
+	if delete.Tables != nil {
+		b.checkNotView(delete.Tables.Tables, "DELETE")
+	} else {
+		b.checkNotView(extractTableList(delete.TableRefs.TableRefs, nil), "DELETE")
+	}
+	if b.err != nil {
+		return nil
+	}
 	p := b.buildResultSetNode(sel.From.TableRefs)
 	if b.err != nil {
 		return nil
 	}
 
 	if sel.Where != nil {
+		b.curClause = whereClause
 		p = b.buildSelection(p, sel.Where, nil)
 		if b.err != nil {
 			return nil
 		}
 	}
 	if sel.OrderBy != nil {
+		b.curClause = orderByClause
 		p = b.buildSort(p, sel.OrderBy.Items, nil)
 		if b.err != nil {
 			return nil
@@ -1682,6 +1966,17 @@ func (b *planBuilder) buildDelete(delete *ast.DeleteStmt) LogicalPlan {
 	return del
 }
 
+// checkNotView rejects UPDATE and DELETE through a view, the rows of a view
+// have no storage of their own.
+func (b *planBuilder) checkNotView(tables []*ast.TableName, stmt string) {
+	for _, tn := range tables {
+		if tn.TableInfo != nil && tn.TableInfo.IsView() {
+			b.err = ErrNonUpdatableTable.GenByArgs(tn.Name.O, stmt)
+			return
+		}
+	}
+}
+
 func extractTableList(node ast.ResultSetNode, input []*ast.TableName) []*ast.TableName {
 	switch x := node.(type) {
 	case *ast.Join:
@@ -93,6 +93,11 @@ type LogicalJoin struct {
 	cartesianJoin   bool
 	preferINLJ      int
 	preferMergeJoin bool
+	// onInnerJoin is an inner join with an ON clause, whose tables can be
+	// reordered like the ones of a cartesian join.
+	onInnerJoin bool
+	// straightJoin keeps the left child read before the right one.
+	straightJoin bool
 
 	EqualConditions []*expression.ScalarFunction
 	LeftConditions  expression.CNFExprs
@@ -136,6 +141,14 @@ func (p *LogicalJoin) attachOnConds(onConds []expression.Expression) {
 	p.OtherConditions = append(other, p.OtherConditions...)
 }
 
+// onConditions returns the conditions of the ON clause of p.
+func (p *LogicalJoin) onConditions() []expression.Expression {
+	conds := expression.ScalarFuncs2Exprs(p.EqualConditions)
+	conds = append(conds, p.LeftConditions...)
+	conds = append(conds, p.RightConditions...)
+	return append(conds, p.OtherConditions...)
+}
+
 func (p *LogicalJoin) extractCorrelatedCols() []*expression.CorrelatedColumn {
 	corCols := p.basePlan.extractCorrelatedCols()
 	for _, fun := range p.EqualConditions {
@@ -183,6 +196,13 @@ type LogicalAggregation struct {
 	GroupByItems []expression.Expression
 	// groupByCols stores the columns that are group-by items.
 	groupByCols []*expression.Column
+	// Rollup means GROUP BY ... WITH ROLLUP: the rows of the groups are
+	// followed by the ones of the groups of each prefix of the group-by
+	// items, and at last by the one of all the rows.
+	Rollup bool
+	// Distinct means the aggregation removes the duplicate rows of SELECT
+	// DISTINCT, whose rows come in no particular order.
+	Distinct bool
 
 	possibleProperties [][]*expression.Column
 	inputCount         float64 // inputCount is the input count of this plan.
@@ -270,6 +290,9 @@ type TableDual struct {
 	basePhysicalPlan
 
 	RowCount int
+	// impossibleWhere means the dual replaces a query whose WHERE is always
+	// false.
+	impossibleWhere bool
 }
 
 // DataSource represents a tablescan without condition push down.
@@ -298,6 +321,15 @@ type DataSource struct {
 	unionScanSchema *expression.Schema
 }
 
+// columnInfo returns the column of the table col is read from, nil when p
+// doesn't read col.
+func (p *DataSource) columnInfo(col *expression.Column) *model.ColumnInfo {
+	if i := p.Schema().ColumnIndex(col); i >= 0 && i < len(p.Columns) {
+		return p.Columns[i]
+	}
+	return nil
+}
+
 func (p *DataSource) getPKIsHandleCol() *expression.Column {
 	if !p.tableInfo.PKIsHandle {
 		return nil
@@ -16,7 +16,9 @@ package plan
 import (
 	"math"
 
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
 	types "github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/expression"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
 )
 
@@ -37,7 +39,9 @@ func (ts *PhysicalTableScan) matchProperty(prop *requiredProperty, infos ...*phy
 			count:    infos[0].count,
 			reliable: infos[0].reliable})
 	}
-	if len(prop.props) == 1 && ts.pkCol != nil && ts.pkCol.Equal(prop.props[0].col, ts.ctx) {
+	// The handle is unique, so once the rows are ordered by it the sort keys
+	// after it are ordered as well.
+	if ts.pkCol != nil && ts.pkCol.Equal(prop.props[0].col, ts.ctx) && (len(prop.props) == 1 || prop.sortKeyLen > 0) {
 		sortedTS := ts.Copy().(*PhysicalTableScan)
 		sortedTS.Desc = prop.props[0].desc
 		sortedTS.KeepOrder = true
@@ -81,6 +85,63 @@ func allMatch(matchedList []bool) bool {
 	return true
 }
 
+// pruneConstProps removes the sort columns fixed to a constant by an equal
+// condition in conds. Every row read has the same value for such a column,
+// so its position and direction don't matter when matching an index order.
+func pruneConstProps(prop *requiredProperty, conds []expression.Expression) *requiredProperty {
+	if len(prop.props) == 0 {
+		return prop
+	}
+	var constCols []*expression.Column
+	for _, cond := range conds {
+		if col := constEqColumn(cond); col != nil {
+			constCols = append(constCols, col)
+		}
+	}
+	if len(constCols) == 0 {
+		return prop
+	}
+	ret := &requiredProperty{
+		props:      make([]*columnProp, 0, len(prop.props)),
+		sortKeyLen: prop.sortKeyLen,
+		limit:      prop.limit,
+	}
+	for i, p := range prop.props {
+		isConst := false
+		for _, col := range constCols {
+			if col.Equal(p.col, nil) {
+				isConst = true
+				break
+			}
+		}
+		if !isConst {
+			ret.props = append(ret.props, p)
+		} else if i < prop.sortKeyLen {
+			ret.sortKeyLen--
+		}
+	}
+	return ret
+}
+
+// constEqColumn returns the column of a `column = constant` condition, or nil.
+func constEqColumn(cond expression.Expression) *expression.Column {
+	sf, ok := cond.(*expression.ScalarFunction)
+	if !ok || sf.FuncName.L != ast.EQ {
+		return nil
+	}
+	args := sf.GetArgs()
+	for i := range args {
+		col, ok := args[i].(*expression.Column)
+		if !ok {
+			continue
+		}
+		if _, ok = args[1-i].(*expression.Constant); ok {
+			return col
+		}
+	}
+	return nil
+}
+
 // matchPropColumn checks if the idxCol match one of columns in required property and return the matched index.
 // If no column is matched, return -1.
 func matchPropColumn(prop *requiredProperty, matchedIdx int, idxCol *model.IndexColumn) int {
@@ -123,7 +184,13 @@ func (is *PhysicalIndexScan) matchProperty(prop *requiredProperty, infos ...*phy
 	}
 	matchedIdx := 0
 	matchedList := make([]bool, len(prop.props))
-	for i, idxCol := range is.Index.Columns {
+	idxCols := is.Index.Columns
+	if pkName := is.Table.GetPkName(); pkName.L != "" && !is.Index.Primary {
+		// A secondary index entry ends with the handle, so the entries of
+		// equal keys are read in handle order.
+		idxCols = append(idxCols[:len(idxCols):len(idxCols)], &model.IndexColumn{Name: pkName, Length: types.UnspecifiedLength})
+	}
+	for i, idxCol := range idxCols {
 		if idxCol.Length != types.UnspecifiedLength {
 			break
 		}
@@ -0,0 +1,137 @@
+package plan
+
+import (
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	types "github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/expression"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// newMatchTestTable returns the meta and the schema columns of
+//
+//	CREATE TABLE t (id INT PRIMARY KEY, a INT, b INT, c INT, INDEX abc (a, b, c))
+func newMatchTestTable() (*model.TableInfo, []*expression.Column) {
+	tbl := &model.TableInfo{Name: model.NewCIStr("t"), PKIsHandle: true}
+	var cols []*expression.Column
+	for i, name := range []string{"id", "a", "b", "c"} {
+		info := &model.ColumnInfo{ID: int64(i + 1), Name: model.NewCIStr(name), Offset: i, State: model.StatePublic}
+		info.FieldType = *types.NewFieldType(mysql.TypeLong)
+		if i == 0 {
+			info.Flag = mysql.PriKeyFlag | mysql.NotNullFlag
+		}
+		tbl.Columns = append(tbl.Columns, info)
+		cols = append(cols, &expression.Column{FromID: 1, Position: i, ID: info.ID, ColName: info.Name, TblName: tbl.Name, RetType: &info.FieldType})
+	}
+	tbl.Indices = []*model.IndexInfo{{
+		Name:  model.NewCIStr("abc"),
+		Table: tbl.Name,
+		Columns: []*model.IndexColumn{
+			{Name: model.NewCIStr("a"), Offset: 1, Length: types.UnspecifiedLength},
+			{Name: model.NewCIStr("b"), Offset: 2, Length: types.UnspecifiedLength},
+			{Name: model.NewCIStr("c"), Offset: 3, Length: types.UnspecifiedLength},
+		},
+		State: model.StatePublic,
+	}}
+	return tbl, cols
+}
+
+func sortProp(cols []*expression.Column, desc []bool, idx ...int) *requiredProperty {
+	prop := &requiredProperty{sortKeyLen: len(idx)}
+	for i, j := range idx {
+		prop.props = append(prop.props, &columnProp{col: cols[j], desc: desc[i]})
+	}
+	return prop
+}
+
+func asc(n int) []bool {
+	return make([]bool, n)
+}
+
+func isSorted(p PhysicalPlan) bool {
+	_, ok := p.(*Sort)
+	return ok
+}
+
+func TestTableScanMatchOrderByPK(t *testing.T) {
+	tbl, cols := newMatchTestTable()
+	ts := PhysicalTableScan{Table: tbl, pkCol: cols[0]}.init(new(idAllocator), nil)
+	ts.readOnly = true
+	info := &physicalPlanInfo{count: 100}
+
+	// ORDER BY id DESC, a is satisfied by a reverse table scan.
+	res := ts.matchProperty(sortProp(cols, []bool{true, false}, 0, 1), info)
+	if isSorted(res.p) {
+		t.Fatal("expect the sort on the handle to be removed")
+	}
+	if scan := res.p.(*PhysicalTableScan); !scan.KeepOrder || !scan.Desc {
+		t.Fatalf("expect an ordered reverse scan, got keep order %v desc %v", scan.KeepOrder, scan.Desc)
+	}
+	// ORDER BY a can't be read from the table in order.
+	if res = ts.matchProperty(sortProp(cols, asc(1), 1), info); res.p != nil && !isSorted(res.p) {
+		t.Fatal("expect the sort on a non handle column to be kept")
+	}
+}
+
+func TestIndexScanMatchOrderBy(t *testing.T) {
+	tbl, cols := newMatchTestTable()
+	newScan := func(accessEqualCount int) *PhysicalIndexScan {
+		is := PhysicalIndexScan{Table: tbl, Index: tbl.Indices[0], OutOfOrder: true}.init(new(idAllocator), nil)
+		is.readOnly = true
+		is.accessEqualCount = accessEqualCount
+		return is
+	}
+	info := &physicalPlanInfo{count: 100}
+
+	tests := []struct {
+		equal  int
+		idx    []int
+		desc   []bool
+		sorted bool
+	}{
+		// ORDER BY a, b
+		{0, []int{1, 2}, asc(2), false},
+		// ORDER BY a DESC, b DESC, c DESC
+		{0, []int{1, 2, 3}, []bool{true, true, true}, false},
+		// ORDER BY a, b, c, id: the handle follows the index columns.
+		{0, []int{1, 2, 3, 0}, asc(4), false},
+		// WHERE a = ? ORDER BY b, c
+		{1, []int{2, 3}, asc(2), false},
+		// ORDER BY a, b DESC
+		{0, []int{1, 2}, []bool{false, true}, true},
+		// ORDER BY b, c without an equal condition on a.
+		{0, []int{2, 3}, asc(2), true},
+		// ORDER BY a, c
+		{0, []int{1, 3}, asc(2), true},
+	}
+	for i, tt := range tests {
+		res := newScan(tt.equal).matchProperty(sortProp(cols, tt.desc, tt.idx...), info)
+		sorted := res.p == nil || isSorted(res.p)
+		if sorted != tt.sorted {
+			t.Fatalf("case %d: expect sort kept %v, got %v", i, tt.sorted, sorted)
+		}
+		if !sorted && res.p.(*PhysicalIndexScan).OutOfOrder {
+			t.Fatalf("case %d: expect an ordered index scan", i)
+		}
+	}
+}
+
+func TestPruneConstProps(t *testing.T) {
+	_, cols := newMatchTestTable()
+	eq, err := expression.NewFunction(nil, ast.EQ, types.NewFieldType(mysql.TypeTiny), cols[1], &expression.Constant{Value: types.NewIntDatum(1), RetType: types.NewFieldType(mysql.TypeLonglong)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// WHERE a = 1 ORDER BY a DESC, b: the direction on a doesn't matter.
+	prop := pruneConstProps(sortProp(cols, []bool{true, false}, 1, 2), []expression.Expression{eq})
+	if len(prop.props) != 1 || prop.sortKeyLen != 1 || prop.props[0].col != cols[2] {
+		t.Fatalf("expect only b to be left, got %d columns", len(prop.props))
+	}
+	// An unrelated condition keeps the property.
+	prop = pruneConstProps(sortProp(cols, asc(1), 2), []expression.Expression{eq})
+	if len(prop.props) != 1 {
+		t.Fatalf("expect b to be kept, got %d columns", len(prop.props))
+	}
+}
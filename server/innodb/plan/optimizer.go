@@ -16,11 +16,14 @@ package plan
 import (
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
 	"math"
+	"strings"
+	"sync/atomic"
 
 	"github.com/juju/errors"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/expression"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/privilege"
 
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/terror"
 	"github.com/zhukovaskychina/xmysql-server/server/mysql"
@@ -49,6 +52,17 @@ var optRuleList = []logicalOptRule{
 	&pushDownTopNOptimizer{},
 }
 
+// optRuleNames are the names of the rules of optRuleList in the optimizer trace.
+var optRuleNames = []string{
+	"column_pruning",
+	"projection_elimination",
+	"build_keys",
+	"decorrelation",
+	"predicate_push_down",
+	"aggregation_push_down",
+	"topn_push_down",
+}
+
 // logicalOptRule means a logical optimizing rule, which contains decorrelate, ppd, column pruning, etc.
 type logicalOptRule interface {
 	optimize(LogicalPlan, context.Context, *idAllocator) (LogicalPlan, error)
@@ -68,9 +82,34 @@ func Optimize(ctx context.Context, node ast.Node, is schemas.InfoSchema) (Plan,
 	if builder.err != nil {
 		return nil, errors.Trace(builder.err)
 	}
+	var trace *optimizerTrace
+	if ctx.GetSessionVars().EnableOptimizerTrace && !builder.readOptimizerTrace {
+		trace = startOptimizerTrace(ctx)
+	}
+
+	// Maybe it's better to move this to Preprocess, but check privilege need table
+	// information, which is collected into visitInfo during logical plan builder.
+	if pm := privilege.GetPrivilegeManager(ctx); pm != nil {
+		if err := checkPrivilege(ctx, pm, builder.visitInfo); err != nil {
+			trace.save(ctx, node, true)
+			return nil, errors.Trace(err)
+		}
+	}
 
 	if logic, ok := p.(LogicalPlan); ok {
-		return doOptimize(builder.optFlag, logic, ctx, allocator)
+		// The rules applied while building the plan, the trace didn't see.
+		for _, rule := range builder.rewrites {
+			trace.addRewrite(rule, logic)
+		}
+		physical, err := doOptimize(builder, logic)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if len(builder.rewrites) > 0 {
+			atomic.AddInt64(&queriesRewritten, 1)
+		}
+		trace.save(ctx, node, false)
+		return physical, nil
 	}
 	return p, nil
 }
@@ -90,8 +129,10 @@ func BuildLogicalPlan(ctx context.Context, node ast.Node, is schemas.InfoSchema)
 	return p, nil
 }
 
-func doOptimize(flag uint64, logic LogicalPlan, ctx context.Context, allocator *idAllocator) (PhysicalPlan, error) {
-	logic, err := logicalOptimize(flag, logic, ctx, allocator)
+func doOptimize(b *planBuilder, logic LogicalPlan) (PhysicalPlan, error) {
+	flag, ctx := b.optFlag, b.ctx
+	logic = b.rewriteQuery(logic)
+	logic, err := logicalOptimize(flag, logic, ctx, b.allocator)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -102,17 +143,19 @@ func doOptimize(flag uint64, logic LogicalPlan, ctx context.Context, allocator *
 	if UseDAGPlanBuilder(ctx) {
 		physical, err = dagPhysicalOptimize(logic)
 	} else {
-		physical, err = physicalOptimize(flag, logic, allocator)
+		physical, err = physicalOptimize(flag, logic, b.allocator)
 	}
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
 	finalPlan := eliminatePhysicalProjection(physical)
+	optimizerTraceOf(ctx).setChosenPlan(finalPlan)
 	return finalPlan, nil
 }
 
 func logicalOptimize(flag uint64, logic LogicalPlan, ctx context.Context, alloc *idAllocator) (LogicalPlan, error) {
 	var err error
+	trace := optimizerTraceOf(ctx)
 	for i, rule := range optRuleList {
 		// The order of flags is same as the order of optRule in the list.
 		// We use a bitmask to record which opt rules should be used. If the i-th bit is 1, it means we should
@@ -124,6 +167,7 @@ func logicalOptimize(flag uint64, logic LogicalPlan, ctx context.Context, alloc
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
+		trace.addRewrite(optRuleNames[i], logic)
 	}
 	return logic, errors.Trace(err)
 }
@@ -154,6 +198,26 @@ func physicalOptimize(flag uint64, logic LogicalPlan, allocator *idAllocator) (P
 	return p, nil
 }
 
+// checkPrivilege checks the privileges the plan needs. A database on which
+// the user has no privilege at all is denied as a whole, a table by the
+// command it is denied.
+func checkPrivilege(ctx context.Context, pm privilege.Manager, vs []visitInfo) error {
+	for _, v := range vs {
+		if pm.RequestVerification(v.db, v.table, v.column, v.privilege) {
+			continue
+		}
+		user := ctx.GetSessionVars().User
+		if user != nil && v.db != "" && !pm.DBIsVisible(v.db) {
+			return ErrDBaccessDenied.GenByArgs(user.Username, user.Hostname, v.db)
+		}
+		if user != nil && v.table != "" {
+			return ErrTableaccessDenied.GenByArgs(strings.ToUpper(mysql.Priv2Str[v.privilege]), user.Username, user.Hostname, v.table)
+		}
+		return ErrSpecificAccessDenied.GenByArgs(mysql.Priv2Str[v.privilege])
+	}
+	return nil
+}
+
 func existsCartesianProduct(p LogicalPlan) bool {
 	if join, ok := p.(*LogicalJoin); ok && len(join.EqualConditions) == 0 {
 		return join.JoinType == InnerJoin || join.JoinType == LeftOuterJoin || join.JoinType == RightOuterJoin
@@ -190,6 +254,16 @@ const (
 	// MySQL error code.
 	CodeNoDB                 terror.ErrCode = mysql.ErrNoDB
 	CodeUnknownExplainFormat terror.ErrCode = mysql.ErrUnknownExplainFormat
+	CodeSpecificAccessDenied terror.ErrCode = mysql.ErrSpecificAccessDenied
+	CodeNonUpdatableTable    terror.ErrCode = mysql.ErrNonUpdatableTable
+	CodeNonInsertableTable   terror.ErrCode = mysql.ErrNonInsertableTable
+	CodeNonuniqTable         terror.ErrCode = mysql.ErrNonuniqTable
+	CodeDerivedMustHaveAlias terror.ErrCode = mysql.ErrDerivedMustHaveAlias
+	CodeNotSupportedYet      terror.ErrCode = mysql.ErrNotSupportedYet
+	CodeKeyDoesNotExist      terror.ErrCode = mysql.ErrKeyDoesNotExits
+	CodeDBaccessDenied       terror.ErrCode = mysql.ErrDBaccessDenied
+	CodeTableaccessDenied    terror.ErrCode = mysql.ErrTableaccessDenied
+	CodeWrongUsage           terror.ErrCode = mysql.ErrWrongUsage
 )
 
 // Optimizer base errors.
@@ -201,6 +275,16 @@ var (
 	ErrIllegalReference            = terror.ClassOptimizer.New(CodeIllegalReference, "Illegal reference")
 	ErrNoDB                        = terror.ClassOptimizer.New(CodeNoDB, "No database selected")
 	ErrUnknownExplainFormat        = terror.ClassOptimizer.New(CodeUnknownExplainFormat, mysql.MySQLErrName[mysql.ErrUnknownExplainFormat])
+	ErrSpecificAccessDenied        = terror.ClassOptimizer.New(CodeSpecificAccessDenied, mysql.MySQLErrName[mysql.ErrSpecificAccessDenied])
+	ErrNonUpdatableTable           = terror.ClassOptimizer.New(CodeNonUpdatableTable, mysql.MySQLErrName[mysql.ErrNonUpdatableTable])
+	ErrNonInsertableTable          = terror.ClassOptimizer.New(CodeNonInsertableTable, mysql.MySQLErrName[mysql.ErrNonInsertableTable])
+	ErrNonuniqTable                = terror.ClassOptimizer.New(CodeNonuniqTable, mysql.MySQLErrName[mysql.ErrNonuniqTable])
+	ErrDerivedMustHaveAlias        = terror.ClassOptimizer.New(CodeDerivedMustHaveAlias, mysql.MySQLErrName[mysql.ErrDerivedMustHaveAlias])
+	ErrNotSupportedYet             = terror.ClassOptimizer.New(CodeNotSupportedYet, mysql.MySQLErrName[mysql.ErrNotSupportedYet])
+	ErrKeyDoesNotExist             = terror.ClassOptimizer.New(CodeKeyDoesNotExist, mysql.MySQLErrName[mysql.ErrKeyDoesNotExits])
+	ErrDBaccessDenied              = terror.ClassOptimizer.New(CodeDBaccessDenied, "Access denied for user '%s'@'%s' to database '%s'")
+	ErrTableaccessDenied           = terror.ClassOptimizer.New(CodeTableaccessDenied, mysql.MySQLErrName[mysql.ErrTableaccessDenied])
+	ErrWrongUsage                  = terror.ClassOptimizer.New(CodeWrongUsage, mysql.MySQLErrName[mysql.ErrWrongUsage])
 )
 
 func init() {
@@ -211,6 +295,16 @@ func init() {
 		CodeIllegalReference:     mysql.ErrIllegalReference,
 		CodeNoDB:                 mysql.ErrNoDB,
 		CodeUnknownExplainFormat: mysql.ErrUnknownExplainFormat,
+		CodeSpecificAccessDenied: mysql.ErrSpecificAccessDenied,
+		CodeNonUpdatableTable:    mysql.ErrNonUpdatableTable,
+		CodeNonInsertableTable:   mysql.ErrNonInsertableTable,
+		CodeNonuniqTable:         mysql.ErrNonuniqTable,
+		CodeDerivedMustHaveAlias: mysql.ErrDerivedMustHaveAlias,
+		CodeNotSupportedYet:      mysql.ErrNotSupportedYet,
+		CodeKeyDoesNotExist:      mysql.ErrKeyDoesNotExits,
+		CodeDBaccessDenied:       mysql.ErrDBaccessDenied,
+		CodeTableaccessDenied:    mysql.ErrTableaccessDenied,
+		CodeWrongUsage:           mysql.ErrWrongUsage,
 	}
 	terror.ErrClassToMySQLCodes[terror.ClassOptimizer] = mySQLErrCodes
 	expression.EvalAstExpr = evalAstExpr
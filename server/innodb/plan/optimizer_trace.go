@@ -0,0 +1,210 @@
+package plan
+
+import (
+	"bytes"
+	"encoding/json"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	types "github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/expression"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+)
+
+// optimizerTrace is the trace of how a statement is planned, recorded when
+// optimizer_trace is enabled. The fields are encoded in the order they are
+// declared and every list in the order the optimizer went through it, so
+// that planning a statement twice gives the same trace.
+type optimizerTrace struct {
+	Rewrites    []*rewriteTrace    `json:"logical_rewrites"`
+	AccessPaths []*accessPathTrace `json:"considered_access_paths"`
+	JoinOrders  []*joinOrderTrace  `json:"considered_join_orders,omitempty"`
+	JoinOrder   []string           `json:"join_order"`
+	ChosenPlan  string             `json:"chosen_plan"`
+}
+
+// rewriteTrace is a logical rule applied to the plan, and the plan after it.
+type rewriteTrace struct {
+	Rule string `json:"rule"`
+	Plan string `json:"plan"`
+}
+
+// accessPathTrace is a way of reading a table the optimizer considered.
+type accessPathTrace struct {
+	Table            string   `json:"table"`
+	AccessType       string   `json:"access_type"`
+	Index            string   `json:"index,omitempty"`
+	RequiredOrder    []string `json:"required_order,omitempty"`
+	PushedConditions []string `json:"pushed_conditions,omitempty"`
+	AccessConditions []string `json:"access_conditions,omitempty"`
+	FilterConditions []string `json:"filter_conditions,omitempty"`
+	Rows             float64  `json:"rows"`
+	Selectivity      float64  `json:"selectivity"`
+	Cost             float64  `json:"cost"`
+	Chosen           bool     `json:"chosen"`
+
+	info *physicalPlanInfo
+}
+
+// joinOrderTrace is an order of the tables of a join the optimizer costed.
+type joinOrderTrace struct {
+	Tables []string `json:"tables"`
+	Rows   float64  `json:"rows"`
+	Cost   float64  `json:"cost"`
+	Chosen bool     `json:"chosen"`
+}
+
+// startOptimizerTrace starts the trace of the statement of ctx.
+func startOptimizerTrace(ctx context.Context) *optimizerTrace {
+	t := &optimizerTrace{}
+	ctx.GetSessionVars().StmtCtx.OptimizerTrace = t
+	return t
+}
+
+// optimizerTraceOf returns the trace of the statement of ctx, nil when it
+// isn't traced. The methods of a nil trace do nothing.
+func optimizerTraceOf(ctx context.Context) *optimizerTrace {
+	if ctx == nil {
+		return nil
+	}
+	t, _ := ctx.GetSessionVars().StmtCtx.OptimizerTrace.(*optimizerTrace)
+	return t
+}
+
+func (t *optimizerTrace) addRewrite(rule string, p LogicalPlan) {
+	if t == nil {
+		return
+	}
+	t.Rewrites = append(t.Rewrites, &rewriteTrace{Rule: rule, Plan: ToString(p)})
+}
+
+// addAccessPath records reading p by index, or by its rows when index is
+// nil, to satisfy prop. pushed are the conditions pushed down to p, split
+// into the ones bounding the ranges read and the ones filtering the rows.
+func (t *optimizerTrace) addAccessPath(p *DataSource, prop *requiredProperty, index *model.IndexInfo,
+	pushed, access, filters []expression.Expression, rowCount float64, info *physicalPlanInfo) {
+	if t == nil {
+		return
+	}
+	path := &accessPathTrace{
+		Table:            p.tableInfo.Name.O,
+		AccessType:       "table_scan",
+		PushedConditions: exprsToStrings(pushed),
+		AccessConditions: exprsToStrings(access),
+		FilterConditions: exprsToStrings(filters),
+		Rows:             rowCount,
+		Cost:             info.cost,
+		info:             info,
+	}
+	if index != nil {
+		path.AccessType, path.Index = "index_scan", index.Name.O
+	}
+	for _, col := range prop.props {
+		item := col.col.String()
+		if col.desc {
+			item += " desc"
+		}
+		path.RequiredOrder = append(path.RequiredOrder, item)
+	}
+	if total := p.statisticTable.Count; total > 0 {
+		path.Selectivity = rowCount / float64(total)
+	}
+	t.AccessPaths = append(t.AccessPaths, path)
+}
+
+// addJoinOrder records joining tables in order, giving rows at cost.
+func (t *optimizerTrace) addJoinOrder(tables []string, rows, cost float64, chosen bool) {
+	if t == nil {
+		return
+	}
+	t.JoinOrders = append(t.JoinOrders, &joinOrderTrace{Tables: tables, Rows: rows, Cost: cost, Chosen: chosen})
+}
+
+// chooseAccessPath marks the access path the optimizer kept.
+func (t *optimizerTrace) chooseAccessPath(info *physicalPlanInfo) {
+	if t == nil {
+		return
+	}
+	for _, path := range t.AccessPaths {
+		if path.info == info {
+			path.Chosen = true
+		}
+	}
+}
+
+// setChosenPlan records the final plan, and the order its tables are read.
+func (t *optimizerTrace) setChosenPlan(p PhysicalPlan) {
+	if t == nil {
+		return
+	}
+	t.ChosenPlan = ToString(p)
+	t.JoinOrder = appendJoinOrder(nil, p)
+}
+
+func appendJoinOrder(order []string, p Plan) []string {
+	switch x := p.(type) {
+	case *PhysicalTableScan:
+		return append(order, x.Table.Name.O)
+	case *PhysicalIndexScan:
+		return append(order, x.Table.Name.O)
+	}
+	for _, child := range p.Children() {
+		order = appendJoinOrder(order, child)
+	}
+	return order
+}
+
+// save keeps the trace as the last one of the session, cut to
+// optimizer_trace_max_mem_size.
+func (t *optimizerTrace) save(ctx context.Context, node ast.Node, insufficientPrivileges bool) {
+	if t == nil {
+		return
+	}
+	vars := ctx.GetSessionVars()
+	row := &variable.OptimizerTrace{Query: node.Text(), InsufficientPrivileges: insufficientPrivileges}
+	if !insufficientPrivileges {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		// The plans are written with "->", keep it readable.
+		enc.SetEscapeHTML(false)
+		if !vars.OptimizerTraceOneLine {
+			enc.SetIndent("", "  ")
+		}
+		if err := enc.Encode(t); err != nil {
+			log.Warnf("[optimizer trace] encode trace error %v", err)
+		}
+		b := bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+		if max := vars.OptimizerTraceMaxMemSize; len(b) > max {
+			row.MissingBytesBeyondMaxMemSize = len(b) - max
+			b = b[:max]
+		}
+		row.Trace = string(b)
+	}
+	vars.LastOptimizerTrace = row
+}
+
+// optimizerTraceRow returns the row of information_schema.OPTIMIZER_TRACE
+// holding trace, nil when there is no trace.
+func optimizerTraceRow(trace *variable.OptimizerTrace) []types.Datum {
+	if trace == nil {
+		return nil
+	}
+	var insufficientPrivileges int64
+	if trace.InsufficientPrivileges {
+		insufficientPrivileges = 1
+	}
+	return types.MakeDatums(trace.Query, trace.Trace, trace.MissingBytesBeyondMaxMemSize, insufficientPrivileges)
+}
+
+func exprsToStrings(exprs []expression.Expression) []string {
+	if len(exprs) == 0 {
+		return nil
+	}
+	strs := make([]string, 0, len(exprs))
+	for _, expr := range exprs {
+		strs = append(strs, expr.String())
+	}
+	return strs
+}
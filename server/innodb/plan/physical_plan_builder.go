@@ -21,6 +21,7 @@ import (
 
 	"github.com/juju/errors"
 	log "github.com/sirupsen/logrus"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/expression"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/expression/aggregation"
@@ -34,6 +35,7 @@ const (
 	netWorkStartFactor = 20.0
 	scanFactor         = 2.0
 	descScanFactor     = 5 * scanFactor
+	seekFactor         = 5 * scanFactor
 	memoryFactor       = 5.0
 	hashAggMemFactor   = 2.0
 	selectionFactor    = 0.8
@@ -69,8 +71,10 @@ func (p *DataSource) convert2TableScan(prop *requiredProperty) (*physicalPlanInf
 	table := p.tableInfo
 	sc := p.ctx.GetSessionVars().StmtCtx
 	ts.Ranges = []types.IntColumnRange{{LowVal: math.MinInt64, HighVal: math.MaxInt64}}
+	var pushed, filters []expression.Expression
 	if len(p.parents) > 0 {
 		if sel, ok := p.parents[0].(*Selection); ok {
+			pushed = sel.Conditions
 			newSel := sel.Copy().(*Selection)
 			conds := make([]expression.Expression, 0, len(sel.Conditions))
 			for _, cond := range sel.Conditions {
@@ -83,6 +87,8 @@ func (p *DataSource) convert2TableScan(prop *requiredProperty) (*physicalPlanInf
 				return nil, errors.Trace(err)
 			}
 			ts.Ranges = ranges
+			prop = pruneConstProps(prop, sel.Conditions)
+			filters = newSel.Conditions
 			if len(newSel.Conditions) > 0 {
 				newSel.SetChildren(ts)
 				newSel.onTable = true
@@ -108,7 +114,9 @@ func (p *DataSource) convert2TableScan(prop *requiredProperty) (*physicalPlanInf
 	//if ts.TableConditionPBExpr != nil {
 	//	rowCount = rowCount * selectionFactor
 	//}
-	return resultPlan.matchProperty(prop, &physicalPlanInfo{count: rowCount, reliable: !statsTbl.Pseudo}), nil
+	info := resultPlan.matchProperty(prop, &physicalPlanInfo{count: rowCount, reliable: !statsTbl.Pseudo})
+	optimizerTraceOf(p.ctx).addAccessPath(p, prop, nil, pushed, ts.AccessCondition, filters, rowCount, info)
+	return info, nil
 }
 
 func (p *DataSource) convert2IndexScan(prop *requiredProperty, index *model.IndexInfo) (*physicalPlanInfo, error) {
@@ -137,8 +145,10 @@ func (p *DataSource) convert2IndexScan(prop *requiredProperty, index *model.Inde
 	rowCount := float64(statsTbl.Count)
 	sc := p.ctx.GetSessionVars().StmtCtx
 	is.Ranges = ranger.FullIndexRange()
+	var pushed, filters []expression.Expression
 	if len(p.parents) > 0 {
 		if sel, ok := p.parents[0].(*Selection); ok {
+			pushed = sel.Conditions
 			newSel := sel.Copy().(*Selection)
 			conds := make([]expression.Expression, 0, len(sel.Conditions))
 			for _, cond := range sel.Conditions {
@@ -165,6 +175,8 @@ func (p *DataSource) convert2IndexScan(prop *requiredProperty, index *model.Inde
 			if err != nil {
 				return nil, errors.Trace(err)
 			}
+			prop = pruneConstProps(prop, sel.Conditions)
+			filters = newSel.Conditions
 			if len(newSel.Conditions) > 0 {
 				newSel.SetChildren(is)
 				newSel.onTable = true
@@ -173,7 +185,9 @@ func (p *DataSource) convert2IndexScan(prop *requiredProperty, index *model.Inde
 		}
 	}
 	is.DoubleRead = !isCoveringIndex(is.Columns, is.Index.Columns, is.Table.PKIsHandle)
-	return resultPlan.matchProperty(prop, &physicalPlanInfo{count: rowCount, reliable: !statsTbl.Pseudo}), nil
+	info := resultPlan.matchProperty(prop, &physicalPlanInfo{count: rowCount, reliable: !statsTbl.Pseudo})
+	optimizerTraceOf(p.ctx).addAccessPath(p, prop, index, pushed, is.AccessCondition, filters, rowCount, info)
+	return info, nil
 }
 
 func isCoveringIndex(columns []*model.ColumnInfo, indexColumns []*model.IndexColumn, pkIsHandle bool) bool {
@@ -259,6 +273,7 @@ func (p *DataSource) convert2PhysicalPlan(prop *requiredProperty) (*physicalPlan
 			}
 		}
 	}
+	optimizerTraceOf(p.ctx).chooseAccessPath(info)
 	return info, errors.Trace(p.storePlanInfo(prop, info))
 }
 
@@ -612,6 +627,10 @@ func (p *LogicalJoin) buildSelectionWithConds(leftAsOuter bool) (*Selection, []*
 		conds = append(conds, newCond)
 	}
 	selection.Conditions = conds
+	selection.controllerStatus = selection.checkScanController()
+	if selection.controllerStatus == notController {
+		return nil, nil
+	}
 	return selection, corCols
 }
 
@@ -1058,6 +1077,7 @@ func (p *LogicalAggregation) convert2PhysicalPlanStream(prop *requiredProperty)
 		AggType:      StreamedAgg,
 		AggFuncs:     p.AggFuncs,
 		GroupByItems: p.GroupByItems,
+		Distinct:     p.Distinct,
 	}.init(p.allocator, p.ctx)
 	agg.HasGby = len(p.GroupByItems) > 0
 	agg.SetSchema(p.schema)
@@ -1091,18 +1111,72 @@ func (p *LogicalAggregation) convert2PhysicalPlanStream(prop *requiredProperty)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
+	if looseInfo := p.convert2LooseScan(childInfo); looseInfo != nil {
+		// The scan only reads the rows of the groups.
+		info = addPlanToResponse(agg, looseInfo)
+		info.cost += info.count * cpuFactor
+		return info, nil
+	}
 	info = addPlanToResponse(agg, childInfo)
 	info.cost += info.count * cpuFactor
 	info.count = info.count * aggFactor
 	return info, nil
 }
 
+// isLooseScan reports whether the aggregation of info reads a loose scan.
+func isLooseScan(info *physicalPlanInfo) bool {
+	if info.p == nil || len(info.p.Children()) == 0 {
+		return false
+	}
+	is, ok := info.p.Children()[0].(*PhysicalIndexScan)
+	return ok && is.LooseScanColumns > 0
+}
+
+// convert2LooseScan returns the loose scan reading the first row of each
+// group of p from the index scan of childInfo, nil when the scan can't be
+// loose. A loose scan needs the group-by items to be the first columns of a
+// covering index and p to keep only the first row of each group, like the
+// aggregation of DISTINCT does.
+func (p *LogicalAggregation) convert2LooseScan(childInfo *physicalPlanInfo) *physicalPlanInfo {
+	is, ok := childInfo.p.(*PhysicalIndexScan)
+	if !ok || is.DoubleRead || len(p.groupByCols) == 0 || len(p.groupByCols) > len(is.Index.Columns) {
+		return nil
+	}
+	for _, fun := range p.AggFuncs {
+		if fun.GetName() != ast.AggFuncFirstRow {
+			return nil
+		}
+	}
+	gbySchema := expression.NewSchema(p.groupByCols...)
+	for _, idxCol := range is.Index.Columns[:len(p.groupByCols)] {
+		colInfo := is.Table.Columns[idxCol.Offset]
+		if idxCol.Length != types.UnspecifiedLength && idxCol.Length != colInfo.Flen {
+			return nil
+		}
+		grouped := false
+		for i, colInfo := range is.Columns {
+			if colInfo.Name.L == idxCol.Name.L {
+				grouped = gbySchema.ColumnIndex(is.Schema().Columns[i]) >= 0
+				break
+			}
+		}
+		if !grouped {
+			return nil
+		}
+	}
+	loose := is.Copy().(*PhysicalIndexScan)
+	loose.LooseScanColumns = len(p.groupByCols)
+	groups := childInfo.count * aggFactor
+	return &physicalPlanInfo{p: loose, count: groups, cost: groups * seekFactor, reliable: childInfo.reliable}
+}
+
 // convert2PhysicalPlanFinalHash converts the logical aggregation to the final hash aggregation *physicalPlanInfo.
 func (p *LogicalAggregation) convert2PhysicalPlanFinalHash(x physicalDistSQLPlan, childInfo *physicalPlanInfo) *physicalPlanInfo {
 	agg := PhysicalAggregation{
 		AggType:      FinalAgg,
 		AggFuncs:     p.AggFuncs,
 		GroupByItems: p.GroupByItems,
+		Distinct:     p.Distinct,
 	}.init(p.allocator, p.ctx)
 	agg.SetSchema(p.schema)
 	agg.HasGby = len(p.GroupByItems) > 0
@@ -1112,23 +1186,31 @@ func (p *LogicalAggregation) convert2PhysicalPlanFinalHash(x physicalDistSQLPlan
 	}
 	x.(PhysicalPlan).SetSchema(schema)
 	info := addPlanToResponse(agg, childInfo)
+	info.cost += groupSortCost(info.count)
 	info.count = info.count * aggFactor
-	// if we build the final aggregation, it must be the best plan.
-	info.cost = 0
 	return info
 }
 
+// groupSortCost is the cost of grouping cnt rows by sorting them, which is
+// how the hash aggregations group. A stream aggregation reading its rows in
+// the order of an index saves it.
+func groupSortCost(cnt float64) float64 {
+	return sortCost(cnt)
+}
+
 // convert2PhysicalPlanCompleteHash converts the logical aggregation to the complete hash aggregation *physicalPlanInfo.
 func (p *LogicalAggregation) convert2PhysicalPlanCompleteHash(childInfo *physicalPlanInfo) *physicalPlanInfo {
 	agg := PhysicalAggregation{
 		AggType:      CompleteAgg,
 		AggFuncs:     p.AggFuncs,
 		GroupByItems: p.GroupByItems,
+		Rollup:       p.Rollup,
+		Distinct:     p.Distinct,
 	}.init(p.allocator, p.ctx)
 	agg.HasGby = len(p.GroupByItems) > 0
 	agg.SetSchema(p.schema)
 	info := addPlanToResponse(agg, childInfo)
-	info.cost += info.count * memoryFactor
+	info.cost += groupSortCost(info.count)
 	info.count = info.count * aggFactor
 	return info
 }
@@ -1146,7 +1228,9 @@ func (p *LogicalAggregation) convert2PhysicalPlanHash() (*physicalPlanInfo, erro
 			break
 		}
 	}
-	if !distinct {
+	// The super-aggregate rows of a rollup can't be merged from partial
+	// results.
+	if !distinct && !p.Rollup {
 		if x, ok := childInfo.p.(physicalDistSQLPlan); ok {
 			info := p.convert2PhysicalPlanFinalHash(x, childInfo)
 			if info != nil {
@@ -1167,6 +1251,19 @@ func (p *LogicalAggregation) convert2PhysicalPlan(prop *requiredProperty) (*phys
 		return planInfo, nil
 	}
 	limit := prop.limit
+	if p.Rollup {
+		// A rollup is only done by hash, its rows come in no order a parent
+		// can ask for.
+		planInfo = &physicalPlanInfo{cost: math.MaxFloat64}
+		if len(prop.props) == 0 {
+			planInfo, err = p.convert2PhysicalPlanHash()
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+		planInfo = enforceProperty(limitProperty(limit), planInfo)
+		return planInfo, errors.Trace(p.storePlanInfo(prop, planInfo))
+	}
 	if len(prop.props) == 0 {
 		planInfo, err = p.convert2PhysicalPlanHash()
 		if err != nil {
@@ -1177,13 +1274,31 @@ func (p *LogicalAggregation) convert2PhysicalPlan(prop *requiredProperty) (*phys
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-	if planInfo == nil || streamInfo.cost < planInfo.cost {
+	// Sorting the rows to group them is weighed against reading them in the
+	// order of an index, and a loose scan reading the first row of each group
+	// only is cheaper still.
+	if planInfo == nil || streamInfo.cost < planInfo.cost || isLooseScan(streamInfo) {
 		planInfo = streamInfo
 	}
 	planInfo = enforceProperty(limitProperty(limit), planInfo)
 	return planInfo, errors.Trace(p.storePlanInfo(prop, planInfo))
 }
 
+// convert2PhysicalPlan implements the LogicalPlan convert2PhysicalPlan interface.
+// The rows of a dual table are sorted and limited like those of a table, for
+// the ORDER BY and LIMIT of a SELECT without FROM and of its derived tables.
+func (p *TableDual) convert2PhysicalPlan(prop *requiredProperty) (*physicalPlanInfo, error) {
+	info, err := p.getPlanInfo(prop)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if info != nil {
+		return info, nil
+	}
+	info = enforceProperty(prop, &physicalPlanInfo{p: p, count: float64(p.RowCount), reliable: true})
+	return info, errors.Trace(p.storePlanInfo(prop, info))
+}
+
 // convert2PhysicalPlan implements the LogicalPlan convert2PhysicalPlan interface.
 func (p *Union) convert2PhysicalPlan(prop *requiredProperty) (*physicalPlanInfo, error) {
 	info, err := p.getPlanInfo(prop)
@@ -1216,6 +1331,51 @@ func (p *Union) convert2PhysicalPlan(prop *requiredProperty) (*physicalPlanInfo,
 	return info, errors.Trace(p.storePlanInfo(prop, info))
 }
 
+// checkScanController returns how this selection can control the scan below
+// it: by an equal condition of a correlated column on the handle, or on an
+// index. It returns notController if the conditions can't drive either.
+func (p *Selection) checkScanController() int {
+	ds, ok := p.children[0].(*DataSource)
+	if !ok {
+		return notController
+	}
+	var corColConds []expression.Expression
+	for _, expr := range p.Conditions {
+		if !expr.IsCorrelated() {
+			continue
+		}
+		cond := expression.PushDownNot(expr, false, nil)
+		for _, col := range extractCorColumns(cond) {
+			*col.Data = expression.One.Value
+		}
+		newCond, err := expression.SubstituteCorCol2Constant(cond)
+		terror.Log(errors.Trace(err))
+		corColConds = append(corColConds, newCond)
+	}
+	if len(corColConds) == 0 {
+		return notController
+	}
+	if pkCol := ds.getPKIsHandleCol(); pkCol != nil {
+		access, _ := ranger.DetachColumnConditions(corColConds, pkCol.ColName)
+		for _, cond := range access {
+			if f, ok := cond.(*expression.ScalarFunction); ok && f.FuncName.L == ast.EQ {
+				return controlTableScan
+			}
+		}
+	}
+	indices, _ := availableIndices(ds.indexHints, ds.tableInfo)
+	for _, idx := range indices {
+		condsBackUp := make([]expression.Expression, 0, len(corColConds))
+		for _, cond := range corColConds {
+			condsBackUp = append(condsBackUp, cond.Clone())
+		}
+		if _, _, accessEqualCount, _ := ranger.DetachIndexScanConditions(condsBackUp, idx); accessEqualCount > 0 {
+			return controlIndexScan
+		}
+	}
+	return notController
+}
+
 // makeScanController will try to build a selection that controls the below scan's filter condition,
 // and return a physicalPlanInfo. If the onlyCheck is true, it will only check whether this selection
 // can become a scan controller without building the physical plan.
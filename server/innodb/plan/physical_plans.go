@@ -155,6 +155,11 @@ type PhysicalIndexScan struct {
 	// DoubleRead means if the index executor will read kv two times.
 	// If the query requires the columns that don't belong to index, DoubleRead will be true.
 	DoubleRead bool
+	// LooseScanColumns is the number of the first columns of the index a
+	// loose scan reads the distinct values of: it reads the first entry of
+	// each of them and jumps to the next one. It is 0 for a scan reading
+	// every entry.
+	LooseScanColumns int
 
 	// accessInAndEqCount is counter of all conditions in AccessCondition[accessEqualCount:accessInAndEqCount].
 	accessInAndEqCount int
@@ -322,6 +327,12 @@ func (p *physicalTableSource) clearForAggPushDown() {
 	p.gbyItems = nil
 }
 
+// SortItems returns the ORDER BY items pushed down to the scan along with
+// LimitCount: the rows are sorted by them before the limit applies.
+func (p *physicalTableSource) SortItems() []*ByItems {
+	return p.sortItems
+}
+
 func (p *physicalTableSource) clearForTopnPushDown() {
 	p.sortItems = nil
 	//p.SortItemsPB = nil
@@ -578,6 +589,10 @@ type PhysicalAggregation struct {
 	AggType      AggregationType
 	AggFuncs     []aggregation.Aggregation
 	GroupByItems []expression.Expression
+	// Rollup is the Rollup of the LogicalAggregation.
+	Rollup bool
+	// Distinct is the Distinct of the LogicalAggregation.
+	Distinct bool
 
 	propKeys   []*expression.Column
 	inputCount float64 // inputCount is the input count of this plan.
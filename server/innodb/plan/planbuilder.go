@@ -35,9 +35,9 @@ var (
 	ErrUnsupportedType      = terror.ClassOptimizerPlan.New(CodeUnsupportedType, "Unsupported type")
 	SystemInternalErrorType = terror.ClassOptimizerPlan.New(SystemInternalError, "System internal error")
 	ErrUnknownColumn        = terror.ClassOptimizerPlan.New(CodeUnknownColumn, mysql.MySQLErrName[mysql.ErrBadField])
-	ErrUnknownTable         = terror.ClassOptimizerPlan.New(CodeUnknownColumn, mysql.MySQLErrName[mysql.ErrBadTable])
+	ErrUnknownTable         = terror.ClassOptimizerPlan.New(CodeUnknownTable, mysql.MySQLErrName[mysql.ErrBadTable])
 	ErrWrongArguments       = terror.ClassOptimizerPlan.New(CodeWrongArguments, "Incorrect arguments to EXECUTE")
-	ErrAmbiguous            = terror.ClassOptimizerPlan.New(CodeAmbiguous, "Column '%s' in field list is ambiguous")
+	ErrAmbiguous            = terror.ClassOptimizerPlan.New(CodeAmbiguous, mysql.MySQLErrName[mysql.ErrNonUniq])
 	ErrAnalyzeMissIndex     = terror.ClassOptimizerPlan.New(CodeAnalyzeMissIndex, "Index '%s' in field list does not exist in table '%s'")
 	ErrAlterAutoID          = terror.ClassAutoid.New(CodeAlterAutoID, "No support for setting auto_increment using alter_table")
 	ErrBadGeneratedColumn   = terror.ClassOptimizerPlan.New(CodeBadGeneratedColumn, mysql.MySQLErrName[mysql.ErrBadGeneratedColumn])
@@ -77,6 +77,14 @@ type visitInfo struct {
 type tableHintInfo struct {
 	indexNestedLoopJoinTables []model.CIStr
 	sortMergeJoinTables       []model.CIStr
+	indexHints                []indexHintInfo
+}
+
+// indexHintInfo is an INDEX or NO_INDEX optimizer hint, as the index hint
+// it stands for, on the table named or aliased tblName.
+type indexHintInfo struct {
+	tblName model.CIStr
+	hint    *ast.IndexHint
 }
 
 func (info *tableHintInfo) ifPreferMergeJoin(tableNames ...*model.CIStr) bool {
@@ -100,6 +108,27 @@ func (info *tableHintInfo) ifPreferMergeJoin(tableNames ...*model.CIStr) bool {
 	return false
 }
 
+// indexHintsOf returns hints, the index hints written after the table named
+// or aliased tblName, with the ones of the optimizer hints on it. INDEX(t)
+// naming no index forces reading t by any of its indices.
+func (info *tableHintInfo) indexHintsOf(tableInfo *model.TableInfo, tblName model.CIStr, hints []*ast.IndexHint) []*ast.IndexHint {
+	result := hints
+	for _, entry := range info.indexHints {
+		if entry.tblName.L != tblName.L {
+			continue
+		}
+		hint := entry.hint
+		if hint.HintType == ast.HintForce && len(hint.IndexNames) == 0 {
+			hint = &ast.IndexHint{HintType: ast.HintForce, HintScope: ast.HintForScan}
+			for _, index := range tableInfo.Indices {
+				hint.IndexNames = append(hint.IndexNames, index.Name)
+			}
+		}
+		result = append(result[:len(result):len(result)], hint)
+	}
+	return result
+}
+
 func (info *tableHintInfo) ifPreferINLJ(tableNames ...*model.CIStr) bool {
 	for _, tableName := range tableNames {
 		if tableName == nil {
@@ -130,6 +159,23 @@ type planBuilder struct {
 	visitInfo     []visitInfo
 	tableHintInfo []tableHintInfo
 	optFlag       uint64
+	// readOptimizerTrace is true when the statement reads the optimizer trace.
+	readOptimizerTrace bool
+	// curClause is the clause being built, named in the errors of its columns.
+	curClause string
+	// rewrites are the rewrite rules applied to the statement.
+	rewrites []string
+	// straightJoin is true while building the FROM clause of a SELECT
+	// STRAIGHT_JOIN.
+	straightJoin bool
+}
+
+// clause returns the clause being built, the field list when it isn't known.
+func (b *planBuilder) clause() string {
+	if b.curClause == unknownClause {
+		return fieldList
+	}
+	return b.curClause
 }
 
 func (b *planBuilder) build(node ast.Node) Plan {
@@ -149,6 +195,8 @@ func (b *planBuilder) build(node ast.Node) Plan {
 		return b.buildInsert(x)
 	case *ast.LoadDataStmt:
 		return b.buildLoadData(x)
+	case *ast.ImportTableStmt:
+		return b.buildImportTable(x)
 	case *ast.PrepareStmt:
 		return b.buildPrepare(x)
 	case *ast.SelectStmt:
@@ -165,9 +213,12 @@ func (b *planBuilder) build(node ast.Node) Plan {
 		return b.buildSet(x)
 	case *ast.AnalyzeTableStmt:
 		return b.buildAnalyze(x)
+	case *ast.ChecksumTableStmt:
+		return b.buildChecksumTable(x)
 	case *ast.BinlogStmt, *ast.FlushStmt, *ast.UseStmt,
 		*ast.BeginStmt, *ast.CommitStmt, *ast.RollbackStmt, *ast.CreateUserStmt, *ast.SetPwdStmt,
-		*ast.GrantStmt, *ast.DropUserStmt, *ast.AlterUserStmt, *ast.RevokeStmt, *ast.KillStmt, *ast.DropStatsStmt:
+		*ast.GrantStmt, *ast.DropUserStmt, *ast.AlterUserStmt, *ast.RevokeStmt, *ast.KillStmt, *ast.DropStatsStmt,
+		*ast.ResetPersistStmt:
 		return b.buildSimple(node.(ast.StmtNode))
 	case ast.DDLNode:
 		return b.buildDDL(x)
@@ -213,9 +264,10 @@ func (b *planBuilder) buildSet(v *ast.SetStmt) Plan {
 	p := &Set{}
 	for _, vars := range v.Variables {
 		assign := &expression.VarAssignment{
-			Name:     vars.Name,
-			IsGlobal: vars.IsGlobal,
-			IsSystem: vars.IsSystem,
+			Name:      vars.Name,
+			IsGlobal:  vars.IsGlobal,
+			IsSystem:  vars.IsSystem,
+			IsPersist: vars.IsPersist,
 		}
 		if _, ok := vars.Value.(*ast.DefaultExpr); !ok {
 			mockTablePlan := TableDual{}.init(b.allocator, b.ctx)
@@ -280,14 +332,21 @@ func availableIndices(hints []*ast.IndexHint, tableInfo *model.TableInfo) (indic
 	if len(usableHints) == 0 {
 		return publicIndices, true
 	}
-	var hasUse bool
+	var hasUse, hasForce, useHandle bool
 	var ignores []*model.IndexInfo
 	for _, hint := range usableHints {
 		switch hint.HintType {
 		case ast.HintUse, ast.HintForce:
-			// Currently we don't distinguish between Force and Use because our cost estimation is not reliable.
+			// Both restrict the choice to the named indices. USE still leaves
+			// a table scan to the cost, FORCE only reads the table if none of
+			// them can be used.
 			hasUse = true
+			hasForce = hasForce || hint.HintType == ast.HintForce
 			for _, idxName := range hint.IndexNames {
+				if idxName.L == "primary" && tableInfo.PKIsHandle {
+					useHandle = true
+					continue
+				}
 				idx := findIndexByName(publicIndices, idxName)
 				if idx != nil {
 					indices = append(indices, idx)
@@ -304,9 +363,9 @@ func availableIndices(hints []*ast.IndexHint, tableInfo *model.TableInfo) (indic
 		}
 	}
 	indices = removeIgnores(indices, ignores)
-	// If we have got FORCE or USE index hint, table scan is excluded.
+	// If we have got a FORCE index hint, table scan is excluded.
 	if len(indices) != 0 {
-		return indices, false
+		return indices, !hasForce || useHandle
 	}
 	if hasUse {
 		// Empty use hint means don't use any index.
@@ -315,6 +374,22 @@ func availableIndices(hints []*ast.IndexHint, tableInfo *model.TableInfo) (indic
 	return removeIgnores(publicIndices, ignores), true
 }
 
+// checkIndexHints checks that the indices named by hints, written after a
+// table reference, exist in the table.
+func checkIndexHints(hints []*ast.IndexHint, tableInfo *model.TableInfo) error {
+	for _, hint := range hints {
+		for _, idxName := range hint.IndexNames {
+			if idxName.L == "primary" && tableInfo.PKIsHandle {
+				continue
+			}
+			if findIndexByName(tableInfo.Indices, idxName) == nil {
+				return ErrKeyDoesNotExist.GenByArgs(idxName.O, tableInfo.Name.O)
+			}
+		}
+	}
+	return nil
+}
+
 func removeIgnores(indices, ignores []*model.IndexInfo) []*model.IndexInfo {
 	if len(ignores) == 0 {
 		return indices
@@ -460,6 +535,20 @@ func (b *planBuilder) buildAnalyze(as *ast.AnalyzeTableStmt) Plan {
 	return b.buildAnalyzeIndex(as)
 }
 
+// buildChecksumTable builds CHECKSUM TABLE, which needs SELECT on the
+// tables.
+func (b *planBuilder) buildChecksumTable(cs *ast.ChecksumTableStmt) Plan {
+	p := &ChecksumTable{Tables: cs.Tables, Quick: cs.Quick}
+	for _, tn := range cs.Tables {
+		b.visitInfo = appendVisitInfo(b.visitInfo, mysql.SelectPriv, tn.Schema.L, tn.Name.L, "")
+	}
+	schema := expression.NewSchema(make([]*expression.Column, 0, 2)...)
+	schema.Append(buildColumn("", "Table", mysql.TypeVarchar, 2*mysql.MaxTableNameLength))
+	schema.Append(buildColumn("", "Checksum", mysql.TypeLonglong, 21))
+	p.SetSchema(schema)
+	return p
+}
+
 func buildShowDDLFields() *expression.Schema {
 	schema := expression.NewSchema(make([]*expression.Column, 0, 4)...)
 	schema.Append(buildColumn("", "SCHEMA_VER", mysql.TypeLonglong, 4))
@@ -540,6 +629,8 @@ func (b *planBuilder) buildShow(show *ast.ShowStmt) Plan {
 		Flag:        show.Flag,
 		Full:        show.Full,
 		User:        show.User,
+		Engine:      show.Engine,
+		QueryID:     show.QueryID,
 		GlobalScope: show.GlobalScope,
 	}.init(b.allocator, b.ctx)
 	resultPlan = p
@@ -596,8 +687,12 @@ func (b *planBuilder) buildSimple(node ast.StmtNode) Plan {
 		b.visitInfo = appendVisitInfo(b.visitInfo, mysql.CreateUserPriv, "", "", "")
 	case *ast.GrantStmt:
 		b.visitInfo = collectVisitInfoFromGrantStmt(b.visitInfo, raw)
-	case *ast.SetPwdStmt, *ast.RevokeStmt, *ast.KillStmt:
+	case *ast.SetPwdStmt, *ast.RevokeStmt, *ast.KillStmt, *ast.ResetPersistStmt:
 		b.visitInfo = appendVisitInfo(b.visitInfo, mysql.SuperPriv, "", "", "")
+	case *ast.FlushStmt:
+		if raw.Tp == ast.FlushConfig {
+			b.visitInfo = appendVisitInfo(b.visitInfo, mysql.SuperPriv, "", "", "")
+		}
 	}
 	return p
 }
@@ -700,16 +795,21 @@ func (b *planBuilder) buildInsert(insert *ast.InsertStmt) Plan {
 		return nil
 	}
 	tableInfo := tn.TableInfo
+	if tableInfo.IsView() {
+		b.err = ErrNonInsertableTable.GenByArgs(tn.Name.O, "INSERT")
+		return nil
+	}
 	// Build Schema with DBName otherwise ColumnRef with DBName cannot match any Column in Schema.
 	schema := expression.TableInfo2SchemaWithDBName(tn.Schema, tableInfo)
-	tableInPlan, ok := b.is.TableByID(tableInfo.ID)
-	if !ok {
-		b.err = errors.Errorf("Can't get table %s.", tableInfo.Name.O)
+	tableInPlan, err := schemas.TableByName(b.is, tn.Schema, tn.Name)
+	if err != nil {
+		b.err = errors.Trace(err)
 		return nil
 	}
 
 	insertPlan := Insert{
 		Table:       tableInPlan,
+		DBName:      tn.Schema,
 		Columns:     insert.Columns,
 		tableSchema: schema,
 		IsReplace:   insert.IsReplace,
@@ -719,7 +819,7 @@ func (b *planBuilder) buildInsert(insert *ast.InsertStmt) Plan {
 
 	b.visitInfo = append(b.visitInfo, visitInfo{
 		privilege: mysql.InsertPriv,
-		db:        tn.DBInfo.Name.L,
+		db:        tn.Schema.L,
 		table:     tableInfo.Name.L,
 	})
 
@@ -897,11 +997,17 @@ func (b *planBuilder) buildLoadData(ld *ast.LoadDataStmt) Plan {
 		FieldsInfo: ld.FieldsInfo,
 		LinesInfo:  ld.LinesInfo,
 	}
+	if !ld.IsLocal {
+		b.visitInfo = appendVisitInfo(b.visitInfo, mysql.FilePriv, "", "", "")
+	}
 	tableInfo := p.Table.TableInfo
-	tableInPlan, ok := b.is.TableByID(tableInfo.ID)
-	if !ok {
-		db := b.ctx.GetSessionVars().CurrentDB
-		b.err = schemas.ErrTableNotExists.GenByArgs(db, tableInfo.Name.O)
+	if tableInfo.IsView() {
+		b.err = ErrNonInsertableTable.GenByArgs(tableInfo.Name.O, "LOAD")
+		return nil
+	}
+	tableInPlan, err := schemas.TableByName(b.is, p.Table.Schema, p.Table.Name)
+	if err != nil {
+		b.err = errors.Trace(err)
 		return nil
 	}
 	schema := expression.TableInfo2Schema(tableInfo)
@@ -912,6 +1018,32 @@ func (b *planBuilder) buildLoadData(ld *ast.LoadDataStmt) Plan {
 	return p
 }
 
+// buildImportTable builds IMPORT TABLE, which needs FILE and INSERT on the
+// table. It returns the rows loaded, the lines skipped as malformed and
+// the rows rejected by the table.
+func (b *planBuilder) buildImportTable(it *ast.ImportTableStmt) Plan {
+	p := &ImportTable{
+		Table:     it.Table,
+		Path:      it.Path,
+		Header:    it.Header,
+		Delimiter: it.Delimiter,
+		MaxErrors: it.MaxErrors,
+	}
+	b.visitInfo = appendVisitInfo(b.visitInfo, mysql.FilePriv, "", "", "")
+	b.visitInfo = appendVisitInfo(b.visitInfo, mysql.InsertPriv, it.Table.Schema.L, it.Table.Name.L, "")
+	if it.Table.TableInfo.IsView() {
+		b.err = ErrNonInsertableTable.GenByArgs(it.Table.TableInfo.Name.O, "IMPORT")
+		return nil
+	}
+	schema := expression.NewSchema(make([]*expression.Column, 0, 4)...)
+	schema.Append(buildColumn("", "Table", mysql.TypeVarchar, 2*mysql.MaxTableNameLength))
+	schema.Append(buildColumn("", "Loaded", mysql.TypeLonglong, 21))
+	schema.Append(buildColumn("", "Skipped", mysql.TypeLonglong, 21))
+	schema.Append(buildColumn("", "Errors", mysql.TypeLonglong, 21))
+	p.SetSchema(schema)
+	return p
+}
+
 func (b *planBuilder) buildDDL(node ast.DDLNode) Plan {
 	switch v := node.(type) {
 	case *ast.AlterTableStmt:
@@ -944,6 +1076,12 @@ func (b *planBuilder) buildDDL(node ast.DDLNode) Plan {
 				table:     v.ReferTable.Name.L,
 			})
 		}
+	case *ast.CreateViewStmt:
+		b.visitInfo = append(b.visitInfo, visitInfo{
+			privilege: mysql.CreateViewPriv,
+			db:        v.ViewName.Schema.L,
+			table:     v.ViewName.Name.L,
+		})
 	case *ast.DropDatabaseStmt:
 		b.visitInfo = append(b.visitInfo, visitInfo{
 			privilege: mysql.DropPriv,
@@ -970,16 +1108,18 @@ func (b *planBuilder) buildDDL(node ast.DDLNode) Plan {
 			table:     v.Table.Name.L,
 		})
 	case *ast.RenameTableStmt:
-		b.visitInfo = append(b.visitInfo, visitInfo{
-			privilege: mysql.AlterPriv,
-			db:        v.OldTable.Schema.L,
-			table:     v.OldTable.Name.L,
-		})
-		b.visitInfo = append(b.visitInfo, visitInfo{
-			privilege: mysql.AlterPriv,
-			db:        v.NewTable.Schema.L,
-			table:     v.NewTable.Name.L,
-		})
+		for _, t := range v.TableToTables {
+			b.visitInfo = append(b.visitInfo, visitInfo{
+				privilege: mysql.AlterPriv,
+				db:        t.OldTable.Schema.L,
+				table:     t.OldTable.Name.L,
+			})
+			b.visitInfo = append(b.visitInfo, visitInfo{
+				privilege: mysql.AlterPriv,
+				db:        t.NewTable.Schema.L,
+				table:     t.NewTable.Name.L,
+			})
+		}
 	}
 
 	p := &DDL{Statement: node}
@@ -1150,15 +1290,27 @@ func buildShowSchema(s *ast.ShowStmt) (schema *expression.Schema) {
 		ftypes = []byte{mysql.TypeVarchar, mysql.TypeLonglong, mysql.TypeVarchar, mysql.TypeLonglong,
 			mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeLonglong, mysql.TypeLonglong,
 			mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeVarchar}
+	case ast.ShowEngineStatus:
+		names = []string{"Type", "Name", "Status"}
+	case ast.ShowOpenTables:
+		names = []string{"Database", "Table", "In_use", "Name_locked"}
+		ftypes = []byte{mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeLonglong, mysql.TypeLonglong}
 	case ast.ShowPlugins:
 		names = []string{"Name", "Status", "Type", "Library", "License"}
 		ftypes = []byte{
 			mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeVarchar,
 		}
 	case ast.ShowProcessList:
-		names = []string{"Id", "User", "Host", "db", "Command", "Time", "State", "Info"}
+		names = []string{"Id", "User", "Host", "db", "Command", "Time", "State", "Info", "Memory_used", "Max_memory_used"}
 		ftypes = []byte{mysql.TypeLonglong, mysql.TypeVarchar, mysql.TypeVarchar,
-			mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeLong, mysql.TypeVarchar, mysql.TypeString}
+			mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeLong, mysql.TypeVarchar, mysql.TypeString,
+			mysql.TypeLonglong, mysql.TypeLonglong}
+	case ast.ShowProfiles:
+		names = []string{"Query_ID", "Duration", "Query"}
+		ftypes = []byte{mysql.TypeLonglong, mysql.TypeDouble, mysql.TypeVarchar}
+	case ast.ShowProfile:
+		names = []string{"Status", "Duration"}
+		ftypes = []byte{mysql.TypeVarchar, mysql.TypeDouble}
 	case ast.ShowStatsMeta:
 		names = []string{"Db_name", "Table_name", "Update_time", "Modify_count", "Row_count"}
 		ftypes = []byte{mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeDatetime, mysql.TypeLonglong, mysql.TypeLonglong}
@@ -48,6 +48,15 @@ type CheckTable struct {
 	Tables []*ast.TableName
 }
 
+// ChecksumTable represents a CHECKSUM TABLE plan.
+type ChecksumTable struct {
+	basePlan
+
+	Tables []*ast.TableName
+	// Quick asks for the live checksums, which InnoDB doesn't keep.
+	Quick bool
+}
+
 // CancelDDLJobs represents a cancel DDL jobs plan.
 type CancelDDLJobs struct {
 	basePlan
@@ -94,13 +103,15 @@ type Show struct {
 	baseLogicalPlan
 	basePhysicalPlan
 
-	Tp     ast.ShowStmtType // Databases/Tables/Columns/....
-	DBName string
-	Table  *ast.TableName  // Used for showing columns.
-	Column *ast.ColumnName // Used for `desc table column`.
-	Flag   int             // Some flag parsed from sql, such as FULL.
-	Full   bool
-	User   *auth.UserIdentity // Used for show grants.
+	Tp      ast.ShowStmtType // Databases/Tables/Columns/....
+	DBName  string
+	Table   *ast.TableName  // Used for showing columns.
+	Column  *ast.ColumnName // Used for `desc table column`.
+	Flag    int             // Some flag parsed from sql, such as FULL.
+	Full    bool
+	User    *auth.UserIdentity // Used for show grants.
+	Engine  string             // Used for show engine status.
+	QueryID uint64             // Used for show profile.
 
 	// Used by show variables
 	GlobalScope bool
@@ -135,6 +146,7 @@ type Insert struct {
 	basePhysicalPlan
 
 	Table       schemas.Table
+	DBName      model.CIStr
 	tableSchema *expression.Schema
 	Columns     []*ast.ColumnName
 	Lists       [][]expression.Expression
@@ -188,6 +200,17 @@ type LoadData struct {
 	GenCols InsertGeneratedColumns
 }
 
+// ImportTable represents an IMPORT TABLE plan.
+type ImportTable struct {
+	basePlan
+
+	Table     *ast.TableName
+	Path      string
+	Header    bool
+	Delimiter string
+	MaxErrors uint64
+}
+
 // DDL represents a DDL statement plan.
 type DDL struct {
 	basePlan
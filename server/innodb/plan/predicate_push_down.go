@@ -73,8 +73,10 @@ func (p *LogicalJoin) PredicatePushDown(predicates []expression.Expression) (ret
 	if err != nil {
 		return nil, nil, errors.Trace(err)
 	}
-	groups, valid := tryToGetJoinGroup(p)
+	groups, onConds, valid := tryToGetJoinGroup(p)
 	if valid {
+		// The ON conditions of inner joins filter like the WHERE ones.
+		predicates = append(predicates, onConds...)
 		e := joinReOrderSolver{allocator: p.allocator, ctx: p.ctx}
 		e.reorderJoin(groups, predicates)
 		newJoin := e.resultJoin
@@ -388,6 +390,12 @@ func (p *LogicalAggregation) getGbyColIndex(col *expression.Column) int {
 // PredicatePushDown implements LogicalPlan PredicatePushDown interface.
 func (p *LogicalAggregation) PredicatePushDown(predicates []expression.Expression) (ret []expression.Expression, retPlan LogicalPlan, err error) {
 	retPlan = p
+	if p.Rollup {
+		// A condition on a group-by column also holds on the NULL of the
+		// super-aggregate rows, it can't be checked on the rows grouped.
+		_, _, err = p.baseLogicalPlan.PredicatePushDown(nil)
+		return predicates, retPlan, errors.Trace(err)
+	}
 	var exprsOriginal []expression.Expression
 	var condsToPush []expression.Expression
 	for _, fun := range p.AggFuncs {
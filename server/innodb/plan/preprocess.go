@@ -22,6 +22,9 @@ import (
 
 // Preprocess does preprocess work for optimizer.
 func Preprocess(node ast.Node, info schemas.InfoSchema, ctx context.Context) error {
+	if err := ExpandWith(ctx, node); err != nil {
+		return errors.Trace(err)
+	}
 	if err := ResolveName(node, info, ctx); err != nil {
 		return errors.Trace(err)
 	}
@@ -0,0 +1,281 @@
+package plan
+
+import (
+	"sync/atomic"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/expression"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+)
+
+// The rules rewriting a query, by their names in the optimizer trace. The
+// first two are applied while the plan is built, the others by
+// rewriteQuery.
+const (
+	ruleConstantFolding  = "constant_folding"
+	ruleImpossibleWhere  = "impossible_where"
+	ruleOrToIn           = "or_to_in"
+	ruleDistinctElim     = "distinct_elimination"
+	ruleOrderByElim      = "order_by_elimination"
+	impossibleWhereLabel = "Impossible WHERE"
+)
+
+// StatusQueriesRewritten is the status variable counting the queries the
+// optimizer rewrote.
+const StatusQueriesRewritten = "Queries_rewritten"
+
+var queriesRewritten int64
+
+// QueriesRewritten returns the number of queries at least one rewrite rule
+// applied to since the server started.
+func QueriesRewritten() int64 {
+	return atomic.LoadInt64(&queriesRewritten)
+}
+
+// queryRewriteStatistics reports the queries rewritten as a status variable.
+type queryRewriteStatistics struct{}
+
+// GetScope implements variable.Statistics GetScope interface.
+func (s queryRewriteStatistics) GetScope(status string) variable.ScopeFlag {
+	return variable.ScopeGlobal
+}
+
+// Stats implements variable.Statistics Stats interface.
+func (s queryRewriteStatistics) Stats(vars *variable.SessionVars) (map[string]interface{}, error) {
+	return map[string]interface{}{
+		StatusQueriesRewritten: QueriesRewritten(),
+	}, nil
+}
+
+func init() {
+	variable.RegisterStatistics(queryRewriteStatistics{})
+}
+
+// addRewrite records that rule applied to the statement being built.
+func (b *planBuilder) addRewrite(rule string) {
+	for _, r := range b.rewrites {
+		if r == rule {
+			return
+		}
+	}
+	b.rewrites = append(b.rewrites, rule)
+}
+
+// rewriteQuery applies to p the rewrites that need the whole plan: OR
+// chains of equalities on a column become IN lists the ranger can use,
+// aggregations over rows already unique on their group-by columns and
+// sorts whose order is lost above them are removed. It returns the new root
+// of the plan.
+func (b *planBuilder) rewriteQuery(p LogicalPlan) LogicalPlan {
+	trace := optimizerTraceOf(b.ctx)
+	if b.convertOrToIn(p) {
+		b.addRewrite(ruleOrToIn)
+		trace.addRewrite(ruleOrToIn, p)
+	}
+	if np, ok := b.eliminateDistinct(p); ok {
+		p = np
+		b.addRewrite(ruleDistinctElim)
+		trace.addRewrite(ruleDistinctElim, p)
+	}
+	if np, ok := eliminateOrderBy(p, true); ok {
+		p = np
+		b.addRewrite(ruleOrderByElim)
+		trace.addRewrite(ruleOrderByElim, p)
+	}
+	return p
+}
+
+// convertOrToIn replaces in the selections of p every condition
+// `c = x OR c = y ...` on a single column by `c IN (x, y, ...)`.
+func (b *planBuilder) convertOrToIn(p LogicalPlan) bool {
+	converted := false
+	if sel, ok := p.(*Selection); ok {
+		for i, cond := range sel.Conditions {
+			if in := b.orToIn(cond); in != nil {
+				sel.Conditions[i] = in
+				converted = true
+			}
+		}
+	}
+	for _, child := range p.Children() {
+		if b.convertOrToIn(child.(LogicalPlan)) {
+			converted = true
+		}
+	}
+	return converted
+}
+
+// orToIn returns the IN function cond is the same as, nil when it isn't an
+// OR of equalities of the same column to constants. As when IN is built
+// from the query, the constants must compare as the type of the column.
+func (b *planBuilder) orToIn(cond expression.Expression) expression.Expression {
+	f, ok := cond.(*expression.ScalarFunction)
+	if !ok || f.FuncName.L != ast.LogicOr {
+		return nil
+	}
+	items := expression.SplitDNFItems(cond)
+	var col *expression.Column
+	args := make([]expression.Expression, 1, len(items)+1)
+	for _, item := range items {
+		eq, ok := item.(*expression.ScalarFunction)
+		if !ok || eq.FuncName.L != ast.EQ {
+			return nil
+		}
+		c, con := eqColumnConstant(eq)
+		if c == nil || col != nil && !col.Equal(c, b.ctx) {
+			return nil
+		}
+		if expression.GetAccurateCmpType(c, con) != c.GetType().EvalType() {
+			return nil
+		}
+		col = c
+		args = append(args, con)
+	}
+	args[0] = col
+	in, err := expression.NewFunction(b.ctx, ast.In, cond.GetType(), args...)
+	if err != nil {
+		return nil
+	}
+	return in
+}
+
+// eqColumnConstant returns the column and the constant eq compares, nils
+// when it compares something else.
+func eqColumnConstant(eq *expression.ScalarFunction) (*expression.Column, *expression.Constant) {
+	args := eq.GetArgs()
+	if col, ok := args[0].(*expression.Column); ok {
+		if con, ok := args[1].(*expression.Constant); ok {
+			return col, con
+		}
+	}
+	if col, ok := args[1].(*expression.Column); ok {
+		if con, ok := args[0].(*expression.Constant); ok {
+			return col, con
+		}
+	}
+	return nil, nil
+}
+
+// eliminateDistinct removes from p the aggregations that only keep the
+// first row of each group, like the one of DISTINCT, over rows with no two
+// in the same group, like the ones of a GROUP BY on the same columns.
+func (b *planBuilder) eliminateDistinct(p LogicalPlan) (LogicalPlan, bool) {
+	eliminated := false
+	for _, child := range p.Children() {
+		if _, ok := b.eliminateDistinct(child.(LogicalPlan)); ok {
+			eliminated = true
+		}
+	}
+	agg, ok := p.(*LogicalAggregation)
+	if !ok || !b.isRedundantAggregation(agg) {
+		return p, eliminated
+	}
+	child := agg.children[0].(LogicalPlan)
+	if err := RemovePlan(agg); err != nil {
+		return p, eliminated
+	}
+	return child, true
+}
+
+// isRedundantAggregation reports whether agg outputs the rows of its child
+// as they are.
+func (b *planBuilder) isRedundantAggregation(agg *LogicalAggregation) bool {
+	child := agg.children[0].(LogicalPlan)
+	cols := child.Schema().Columns
+	if agg.Rollup || len(agg.GroupByItems) == 0 || len(agg.AggFuncs) != len(cols) || agg.Schema().Len() != len(cols) {
+		return false
+	}
+	for i, fun := range agg.AggFuncs {
+		if fun.GetName() != ast.AggFuncFirstRow || fun.IsDistinct() || !fun.GetArgs()[0].Equal(cols[i], b.ctx) ||
+			!agg.Schema().Columns[i].Equal(cols[i], b.ctx) {
+			return false
+		}
+	}
+	groupCols := make([]*expression.Column, 0, len(agg.GroupByItems))
+	for _, item := range agg.GroupByItems {
+		col, ok := item.(*expression.Column)
+		if !ok {
+			return false
+		}
+		groupCols = append(groupCols, col)
+	}
+	return b.isUniqueOn(child, groupCols)
+}
+
+// isUniqueOn reports whether no two rows of p have the same values of cols.
+// It only knows the rows of an aggregation are unique on its group-by
+// columns.
+func (b *planBuilder) isUniqueOn(p LogicalPlan, cols []*expression.Column) bool {
+	switch x := p.(type) {
+	case *Selection:
+		return b.isUniqueOn(x.children[0].(LogicalPlan), cols)
+	case *Projection:
+		// Rows unique on some of the columns are unique on all of them, so
+		// the columns computed by the projection can be left out.
+		inner := make([]*expression.Column, 0, len(cols))
+		for _, col := range cols {
+			if i := x.Schema().ColumnIndex(col); i >= 0 {
+				if c, ok := x.Exprs[i].(*expression.Column); ok {
+					inner = append(inner, c)
+				}
+			}
+		}
+		return b.isUniqueOn(x.children[0].(LogicalPlan), inner)
+	case *LogicalAggregation:
+		if x.Rollup {
+			return false
+		}
+		for _, item := range x.GroupByItems {
+			if !b.isGroupedBy(x, item, cols) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// isGroupedBy reports whether one of cols is the first row of the group-by
+// item of agg.
+func (b *planBuilder) isGroupedBy(agg *LogicalAggregation, item expression.Expression, cols []*expression.Column) bool {
+	for _, col := range cols {
+		i := agg.Schema().ColumnIndex(col)
+		if i < 0 || i >= len(agg.AggFuncs) {
+			continue
+		}
+		fun := agg.AggFuncs[i]
+		if fun.GetName() == ast.AggFuncFirstRow && fun.GetArgs()[0].Equal(item, b.ctx) {
+			return true
+		}
+	}
+	return false
+}
+
+// eliminateOrderBy removes from p the sorts without a limit above them
+// whose order the plans above lose, such as the ORDER BY of a subquery
+// joined, aggregated or sorted again. ordered tells whether the order of
+// the rows of p is kept. It returns the new root of p.
+func eliminateOrderBy(p LogicalPlan, ordered bool) (LogicalPlan, bool) {
+	childOrdered := ordered
+	switch p.(type) {
+	case *Limit:
+		childOrdered = true
+	case *Sort, *TopN, *LogicalAggregation, *LogicalJoin, *LogicalApply, *Union, *MaxOneRow, *Exists:
+		childOrdered = false
+	}
+	eliminated := false
+	for _, child := range p.Children() {
+		if _, ok := eliminateOrderBy(child.(LogicalPlan), childOrdered); ok {
+			eliminated = true
+		}
+	}
+	sort, ok := p.(*Sort)
+	if !ok || ordered {
+		return p, eliminated
+	}
+	child := sort.children[0].(LogicalPlan)
+	if err := RemovePlan(sort); err != nil {
+		return p, eliminated
+	}
+	return child, true
+}
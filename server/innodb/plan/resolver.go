@@ -40,7 +40,12 @@ const (
 // ResolveName resolves table name and column name.
 // It generates ResultFields for ResultSetNode and resolves ColumnNameExpr to a ResultField.
 func ResolveName(node ast.Node, info schemas.InfoSchema, ctx context.Context) error {
-	defaultSchema := ctx.GetSessionVars().CurrentDB
+	return resolveNameInSchema(node, info, ctx.GetSessionVars().CurrentDB, ctx)
+}
+
+// resolveNameInSchema resolves node with unqualified tables taken from
+// defaultSchema instead of the current database.
+func resolveNameInSchema(node ast.Node, info schemas.InfoSchema, defaultSchema string, ctx context.Context) error {
 	resolver := nameResolver{Info: info, Ctx: ctx, DefaultSchema: model.NewCIStr(defaultSchema)}
 	node.Accept(&resolver)
 	return errors.Trace(resolver.Err)
@@ -195,6 +200,9 @@ func (nr *nameResolver) Enter(inNode ast.Node) (outNode ast.Node, skipChildren b
 	case *ast.CreateTableStmt:
 		nr.pushContext()
 		nr.currentContext().inCreateOrDropTable = true
+	case *ast.CreateViewStmt:
+		nr.pushContext()
+		nr.currentContext().inCreateOrDropTable = true
 	case *ast.ColumnOption:
 		nr.currentContext().inColumnOption = true
 	case *ast.DeleteStmt:
@@ -216,6 +224,8 @@ func (nr *nameResolver) Enter(inNode ast.Node) (outNode ast.Node, skipChildren b
 		nr.currentContext().inHaving = true
 	case *ast.InsertStmt:
 		nr.pushContext()
+	case *ast.ImportTableStmt:
+		nr.pushContext()
 	case *ast.LoadDataStmt:
 		nr.pushContext()
 	case *ast.Join:
@@ -277,6 +287,8 @@ func (nr *nameResolver) Leave(inNode ast.Node) (node ast.Node, ok bool) {
 		nr.popContext()
 	case *ast.CreateTableStmt:
 		nr.popContext()
+	case *ast.CreateViewStmt:
+		nr.popContext()
 	case *ast.ColumnOption:
 		nr.currentContext().inColumnOption = false
 	case *ast.DeleteTableList:
@@ -349,6 +361,8 @@ func (nr *nameResolver) Leave(inNode ast.Node) (node ast.Node, ok bool) {
 		nr.handleUnionSelectList(v)
 	case *ast.InsertStmt:
 		nr.popContext()
+	case *ast.ImportTableStmt:
+		nr.popContext()
 	case *ast.LoadDataStmt:
 		nr.popContext()
 	case *ast.DeleteStmt:
@@ -362,8 +376,7 @@ func (nr *nameResolver) Leave(inNode ast.Node) (node ast.Node, ok bool) {
 // handleTableName looks up and sets the schema information and result fields for table name.
 func (nr *nameResolver) handleTableName(tn *ast.TableName) {
 	if tn.Schema.L == "" {
-		sessionVars := nr.Ctx.GetSessionVars()
-		if sessionVars.CurrentDB == "" {
+		if nr.DefaultSchema.L == "" {
 			nr.Err = errors.Trace(ErrNoDB)
 			return
 		}
@@ -388,7 +401,7 @@ func (nr *nameResolver) handleTableName(tn *ast.TableName) {
 		tn.SetResultFields(tableName.GetResultFields())
 		return
 	}
-	table, err := nr.Info.TableByName(tn.Schema, tn.Name)
+	table, err := schemas.TableByName(nr.Info, tn.Schema, tn.Name)
 	if err != nil {
 		nr.Err = errors.Trace(err)
 		return
@@ -444,24 +457,41 @@ func (nr *nameResolver) handleTableSource(ts *ast.TableSource) {
 	switch ts.Source.(type) {
 	case *ast.TableName:
 		var name string
+		// alias is the name the table is known by in the statement.
+		alias := ts.AsName
 		if ts.AsName.L != "" {
 			name = ts.AsName.L
 		} else {
 			tableName := ts.Source.(*ast.TableName)
 			name = nr.tableUniqueName(tableName.Schema, tableName.Name)
+			alias = tableName.Name
 		}
 		if _, ok := ctx.tableMap[name]; ok {
-			nr.Err = errors.Errorf("duplicated table/alias name %s", name)
+			nr.Err = ErrNonuniqTable.GenByArgs(alias.O)
 			return
 		}
 		ctx.tableMap[name] = len(ctx.tables)
-	case *ast.SelectStmt:
+	case *ast.SelectStmt, *ast.UnionStmt:
 		name := ts.AsName.L
+		if name == "" {
+			nr.Err = ErrDerivedMustHaveAlias.GenByArgs()
+			return
+		}
 		if _, ok := ctx.derivedTableMap[name]; ok {
-			nr.Err = errors.Errorf("duplicated table/alias name %s", name)
+			nr.Err = ErrNonuniqTable.GenByArgs(ts.AsName.O)
 			return
 		}
 		ctx.derivedTableMap[name] = len(ctx.tables)
+		if len(ts.ColumnNames) > 0 {
+			// The column list of a common table expression renames its columns.
+			if len(ts.ColumnNames) != len(ts.GetResultFields()) {
+				nr.Err = schemas.ErrViewWrongList.GenByArgs()
+				return
+			}
+			for i, rf := range ts.GetResultFields() {
+				rf.ColumnAsName = ts.ColumnNames[i]
+			}
+		}
 	}
 	dupNames := make(map[string]struct{}, len(ts.GetResultFields()))
 	for _, f := range ts.GetResultFields() {
@@ -901,7 +931,8 @@ func (nr *nameResolver) handleUnionSelectList(u *ast.UnionSelectList) {
 }
 
 func (nr *nameResolver) fillShowFields(s *ast.ShowStmt) {
-	if s.DBName == "" {
+	// SHOW OPEN TABLES without FROM lists the tables of every database.
+	if s.DBName == "" && s.Tp != ast.ShowOpenTables {
 		if s.Table != nil && s.Table.Schema.L != "" {
 			s.DBName = s.Table.Schema.O
 		} else {
@@ -979,10 +1010,21 @@ func (nr *nameResolver) fillShowFields(s *ast.ShowStmt) {
 		ftypes = []byte{mysql.TypeVarchar, mysql.TypeLonglong, mysql.TypeVarchar, mysql.TypeLonglong,
 			mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeLonglong, mysql.TypeLonglong,
 			mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeVarchar}
+	case ast.ShowEngineStatus:
+		names = []string{"Type", "Name", "Status"}
+	case ast.ShowOpenTables:
+		names = []string{"Database", "Table", "In_use", "Name_locked"}
+		ftypes = []byte{mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeLonglong, mysql.TypeLonglong}
 	case ast.ShowProcessList:
 		names = []string{"Id", "User", "Host", "db", "Command", "Time", "State", "Info"}
 		ftypes = []byte{mysql.TypeLonglong, mysql.TypeVarchar, mysql.TypeVarchar,
 			mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeLong, mysql.TypeVarchar, mysql.TypeString}
+	case ast.ShowProfiles:
+		names = []string{"Query_ID", "Duration", "Query"}
+		ftypes = []byte{mysql.TypeLonglong, mysql.TypeDouble, mysql.TypeVarchar}
+	case ast.ShowProfile:
+		names = []string{"Status", "Duration"}
+		ftypes = []byte{mysql.TypeVarchar, mysql.TypeDouble}
 	case ast.ShowStatsMeta:
 		names = []string{"Db_name", "Table_name", "Update_time", "Modify_count", "Row_count"}
 		ftypes = []byte{mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeDatetime, mysql.TypeLonglong, mysql.TypeLonglong}
@@ -1016,6 +1058,10 @@ func (nr *nameResolver) fillShowFields(s *ast.ShowStmt) {
 
 	if s.Pattern != nil && s.Pattern.Expr == nil {
 		rf := fields[0]
+		if s.Tp == ast.ShowOpenTables {
+			// The pattern matches the names of the tables.
+			rf = fields[1]
+		}
 		s.Pattern.Expr = &ast.ColumnNameExpr{
 			Name: &ast.ColumnName{Name: rf.ColumnAsName},
 		}
@@ -15,11 +15,29 @@ package plan
 
 import (
 	"math"
+	"sync/atomic"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/expression"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/statistics"
 )
 
+var statsHandle atomic.Value
+
+// RegisterStatsHandle sets the statistics the tables are costed with, the
+// pseudo statistics when h is nil.
+func RegisterStatsHandle(h *statistics.Handle) {
+	statsHandle.Store(&h)
+}
+
+// tableStats returns the statistics of the table id.
+func tableStats(id int64) *statistics.Table {
+	if h, ok := statsHandle.Load().(**statistics.Handle); ok && *h != nil {
+		return (*h).GetTableStats(id)
+	}
+	return statistics.PseudoTable(id)
+}
+
 // statsProfile stores the basic information of statistics for the a plan's output. It is used for cost estimation.
 type statsProfile struct {
 	count       float64
@@ -149,13 +149,20 @@ func (v *typeInferrer) aggregateFunc(x *ast.AggregateFuncExpr) {
 		ft.Decimal = x.Args[0].GetType().Decimal
 		x.SetType(ft)
 	case ast.AggFuncGroupConcat:
-		ft := basic.NewFieldType(mysql.TypeVarString)
+		ft := basic.NewFieldType(mysql.TypeBlob)
 		ft.Charset = v.defaultCharset
 		cln, err := charset.GetDefaultCollation(v.defaultCharset)
 		if err != nil {
 			v.err = err
 		}
 		ft.Collate = cln
+		for _, arg := range x.Args[:len(x.Args)-1] {
+			if basic.IsBinaryStr(arg.GetType()) {
+				ft.Charset, ft.Collate = charset.CharsetBin, charset.CollationBin
+				ft.Flag |= mysql.BinaryFlag
+				break
+			}
+		}
 		x.SetType(ft)
 	}
 }
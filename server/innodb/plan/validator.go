@@ -433,6 +433,12 @@ func checkColumn(colDef *ast.ColumnDef) error {
 		if tp.Flen != types.UnspecifiedLength && tp.Flen > mysql.PrecisionForDouble {
 			return types.ErrWrongFieldSpec.Gen("Incorrect column specifier for column '%s'", colDef.Name.Name.O)
 		}
+	case mysql.TypeBit:
+		if tp.Flen > mysql.MaxBitDisplayWidth {
+			return types.ErrTooBigDisplayWidth.Gen("Display width out of range for column '%s' (max = %d)", colDef.Name.Name.O, mysql.MaxBitDisplayWidth)
+		}
+	case mysql.TypeEnum:
+		return errors.Trace(checkDuplicatedElems(colDef))
 	case mysql.TypeSet:
 		if len(tp.Elems) > mysql.MaxTypeSetMembers {
 			return types.ErrTooBigSet.Gen("Too many strings for column %s and SET", colDef.Name.Name.O)
@@ -443,12 +449,27 @@ func checkColumn(colDef *ast.ColumnDef) error {
 				return types.ErrIllegalValueForType.GenByArgs(types.TypeStr(tp.Tp), str)
 			}
 		}
+		return errors.Trace(checkDuplicatedElems(colDef))
 	default:
 		// TODO: Add more types.
 	}
 	return nil
 }
 
+// checkDuplicatedElems checks the members of an ENUM or SET are unique. They
+// are matched ignoring case, the way values are.
+func checkDuplicatedElems(colDef *ast.ColumnDef) error {
+	seen := make(map[string]struct{}, len(colDef.Tp.Elems))
+	for _, elem := range colDef.Tp.Elems {
+		key := strings.ToLower(elem)
+		if _, ok := seen[key]; ok {
+			return types.ErrDuplicatedValueInType.GenByArgs(colDef.Name.Name.O, elem, strings.ToUpper(types.TypeStr(colDef.Tp.Tp)))
+		}
+		seen[key] = struct{}{}
+	}
+	return nil
+}
+
 // isNowSymFunc checks whether defaul value is a NOW() builtin function.
 func isDefaultValNowSymFunc(expr ast.ExprNode) bool {
 	if funcCall, ok := expr.(*ast.FuncCallExpr); ok {
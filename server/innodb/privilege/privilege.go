@@ -0,0 +1,52 @@
+// Copyright 2015 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package privilege
+
+import (
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+type keyType int
+
+func (k keyType) String() string {
+	return "privilege-key"
+}
+
+// Manager is the interface for providing privilege related operations.
+type Manager interface {
+	// RequestVerification verifies user privilege for the request.
+	// If table is "", only check global/db scope privileges.
+	// If table is not "", check global/db/table scope privileges.
+	RequestVerification(db, table, column string, priv mysql.PrivilegeType) bool
+
+	// DBIsVisible checks whether the user has any privilege on db, global
+	// or database level.
+	DBIsVisible(db string) bool
+}
+
+const key keyType = 0
+
+// BindPrivilegeManager binds Checker to context.
+func BindPrivilegeManager(ctx context.Context, pc Manager) {
+	ctx.SetValue(key, pc)
+}
+
+// GetPrivilegeManager gets Checker from context.
+func GetPrivilegeManager(ctx context.Context) Manager {
+	if v, ok := ctx.Value(key).(Manager); ok {
+		return v
+	}
+	return nil
+}
@@ -0,0 +1,319 @@
+package privileges
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/sqlexec"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/stringutil"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// UserRecord is a row of mysql.user, the global privileges of an account.
+type UserRecord struct {
+	Host       string
+	User       string
+	Password   string
+	Privileges mysql.PrivilegeType
+
+	hostPatChars, hostPatTypes []byte
+}
+
+// dbRecord is a row of mysql.db, the privileges of an account on the
+// databases matching DB.
+type dbRecord struct {
+	Host       string
+	DB         string
+	User       string
+	Privileges mysql.PrivilegeType
+
+	hostPatChars, hostPatTypes []byte
+	dbPatChars, dbPatTypes     []byte
+}
+
+// MySQLPrivilege is the cache of the privilege tables mysql.user and
+// mysql.db. Both are kept sorted the way MySQL searches them: the most
+// specific host first, then the most specific database, and named users
+// before the anonymous one.
+type MySQLPrivilege struct {
+	User []UserRecord
+	DB   []dbRecord
+}
+
+// LoadAll loads mysql.user and mysql.db through exec.
+func (p *MySQLPrivilege) LoadAll(ctx context.Context, exec sqlexec.RestrictedSQLExecutor) error {
+	if err := p.LoadUserTable(ctx, exec); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(p.LoadDBTable(ctx, exec))
+}
+
+// LoadUserTable loads mysql.user through exec.
+func (p *MySQLPrivilege) LoadUserTable(ctx context.Context, exec sqlexec.RestrictedSQLExecutor) error {
+	p.User = p.User[:0]
+	err := loadTable(ctx, exec, "SELECT * FROM mysql.user", func(cols map[string]string, privs mysql.PrivilegeType) {
+		p.User = append(p.User, newUserRecord(cols["host"], cols["user"], cols["password"], privs))
+	})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	sort.SliceStable(p.User, func(i, j int) bool {
+		a, b := p.User[i], p.User[j]
+		if x, y := patternWeight(a.Host), patternWeight(b.Host); x != y {
+			return x > y
+		}
+		return a.User != "" && b.User == ""
+	})
+	return nil
+}
+
+// LoadDBTable loads mysql.db through exec.
+func (p *MySQLPrivilege) LoadDBTable(ctx context.Context, exec sqlexec.RestrictedSQLExecutor) error {
+	p.DB = p.DB[:0]
+	err := loadTable(ctx, exec, "SELECT * FROM mysql.db", func(cols map[string]string, privs mysql.PrivilegeType) {
+		p.DB = append(p.DB, newDBRecord(cols["host"], cols["db"], cols["user"], privs))
+	})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	p.sortDB()
+	return nil
+}
+
+// sortDB sorts mysql.db the way MySQL searches it.
+func (p *MySQLPrivilege) sortDB() {
+	sort.SliceStable(p.DB, func(i, j int) bool {
+		a, b := p.DB[i], p.DB[j]
+		if x, y := patternWeight(a.Host), patternWeight(b.Host); x != y {
+			return x > y
+		}
+		if x, y := patternWeight(a.DB), patternWeight(b.DB); x != y {
+			return x > y
+		}
+		return a.User != "" && b.User == ""
+	})
+}
+
+// clone returns a copy of p whose records can be changed.
+func (p *MySQLPrivilege) clone() *MySQLPrivilege {
+	return &MySQLPrivilege{
+		User: append([]UserRecord(nil), p.User...),
+		DB:   append([]dbRecord(nil), p.DB...),
+	}
+}
+
+// findUser returns the row of mysql.user of the account user@host.
+func (p *MySQLPrivilege) findUser(user, host string) *UserRecord {
+	for i := range p.User {
+		if record := &p.User[i]; record.User == user && record.Host == host {
+			return record
+		}
+	}
+	return nil
+}
+
+// findDB returns the row of mysql.db of the account user@host on db.
+func (p *MySQLPrivilege) findDB(user, host, db string) *dbRecord {
+	for i := range p.DB {
+		if record := &p.DB[i]; record.User == user && record.Host == host && record.DB == db {
+			return record
+		}
+	}
+	return nil
+}
+
+// loadTable runs sql and calls add with every row, its columns by lower
+// case name and the privileges of its *_priv columns set to 'Y'.
+func loadTable(ctx context.Context, exec sqlexec.RestrictedSQLExecutor, sql string, add func(map[string]string, mysql.PrivilegeType)) error {
+	rs, err := exec.ExecRestrictedSQL(ctx, sql)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer rs.Close()
+	fs, err := rs.Fields()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for {
+		row, err := rs.Next()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if row == nil {
+			return nil
+		}
+		cols, privs, err := decodeRow(row, fs)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		add(cols, privs)
+	}
+}
+
+func decodeRow(row *ast.Row, fs []*ast.ResultField) (map[string]string, mysql.PrivilegeType, error) {
+	cols := make(map[string]string, len(fs))
+	var privs mysql.PrivilegeType
+	for i, f := range fs {
+		d := row.Data[i]
+		if d.IsNull() {
+			continue
+		}
+		val, err := d.ToString()
+		if err != nil {
+			return nil, 0, errors.Trace(err)
+		}
+		name := f.ColumnAsName.O
+		if priv, ok := mysql.Col2PrivType[name]; ok {
+			if val == "Y" {
+				privs |= priv
+			}
+			continue
+		}
+		cols[strings.ToLower(name)] = val
+	}
+	return cols, privs, nil
+}
+
+func newUserRecord(host, user, password string, privs mysql.PrivilegeType) UserRecord {
+	r := UserRecord{Host: host, User: user, Password: password, Privileges: privs}
+	r.hostPatChars, r.hostPatTypes = stringutil.CompilePattern(host, '\\')
+	return r
+}
+
+func newDBRecord(host, db, user string, privs mysql.PrivilegeType) dbRecord {
+	r := dbRecord{Host: host, DB: db, User: user, Privileges: privs}
+	r.hostPatChars, r.hostPatTypes = stringutil.CompilePattern(host, '\\')
+	r.dbPatChars, r.dbPatTypes = stringutil.CompilePattern(db, '\\')
+	return r
+}
+
+// patternWeight orders host and database patterns by specificity, like
+// MySQL's get_sort(): a name without wildcards comes first, then patterns
+// by the length of the text before their first wildcard. '%' alone, like
+// the empty host, comes last.
+func patternWeight(pattern string) int {
+	if i := strings.IndexAny(pattern, "%_"); i >= 0 {
+		return i
+	}
+	if pattern == "" {
+		return -1
+	}
+	return len(pattern) + 1<<16
+}
+
+func (r *dbRecord) match(user, host, db string) bool {
+	return r.User == user && stringutil.DoMatch(host, r.hostPatChars, r.hostPatTypes) &&
+		stringutil.DoMatch(db, r.dbPatChars, r.dbPatTypes)
+}
+
+// connectionVerification returns the account user@host logs in as: the
+// first row of mysql.user matching it, named or anonymous.
+func (p *MySQLPrivilege) connectionVerification(user, host string) *UserRecord {
+	for i := range p.User {
+		record := &p.User[i]
+		if (record.User == user || record.User == "") && stringutil.DoMatch(host, record.hostPatChars, record.hostPatTypes) {
+			return record
+		}
+	}
+	return nil
+}
+
+// matchDB returns the first row of mysql.db granting the account to db.
+func (p *MySQLPrivilege) matchDB(user, host, db string) *dbRecord {
+	for i := range p.DB {
+		if record := &p.DB[i]; record.match(user, host, db) {
+			return record
+		}
+	}
+	return nil
+}
+
+// RequestVerification checks whether user@host has priv on db.table.column,
+// granted globally or on the database.
+func (p *MySQLPrivilege) RequestVerification(user, host, db, table, column string, priv mysql.PrivilegeType) bool {
+	record := p.connectionVerification(user, host)
+	if record == nil {
+		return false
+	}
+	if record.Privileges&priv > 0 {
+		return true
+	}
+	if db == "" {
+		return false
+	}
+	dbRecord := p.matchDB(record.User, host, db)
+	return dbRecord != nil && dbRecord.Privileges&priv > 0
+}
+
+// DBIsVisible checks whether user@host has any privilege on db, globally or
+// on the database.
+func (p *MySQLPrivilege) DBIsVisible(user, host, db string) bool {
+	if strings.EqualFold(db, "information_schema") {
+		return true
+	}
+	record := p.connectionVerification(user, host)
+	if record == nil {
+		return false
+	}
+	if record.Privileges&mysql.AllPrivMask > 0 {
+		return true
+	}
+	dbRecord := p.matchDB(record.User, host, db)
+	return dbRecord != nil && dbRecord.Privileges > 0
+}
+
+// showGrants returns the GRANT statements that give the account user@host
+// its global and database privileges, as SHOW GRANTS shows them.
+func (p *MySQLPrivilege) showGrants(user, host string) []string {
+	account := fmt.Sprintf("'%s'@'%s'", user, host)
+	var global mysql.PrivilegeType
+	for _, record := range p.User {
+		if record.User == user && record.Host == host {
+			global = record.Privileges
+			break
+		}
+	}
+	grants := []string{grantString(global, mysql.AllGlobalPrivs, "*.*", account)}
+	for _, record := range p.DB {
+		if record.User == user && record.Host == host && record.Privileges > 0 {
+			grants = append(grants, grantString(record.Privileges, mysql.AllDBPrivs, "`"+record.DB+"`.*", account))
+		}
+	}
+	return grants
+}
+
+func grantString(privs mysql.PrivilegeType, all []mysql.PrivilegeType, level, account string) string {
+	grant := fmt.Sprintf("GRANT %s ON %s TO %s", privsString(privs, all), level, account)
+	if privs&mysql.GrantPriv > 0 {
+		grant += " WITH GRANT OPTION"
+	}
+	return grant
+}
+
+// privsString formats privs the way SHOW GRANTS does, ALL PRIVILEGES when
+// they are every one of all.
+func privsString(privs mysql.PrivilegeType, all []mysql.PrivilegeType) string {
+	var names []string
+	hasAll := true
+	for _, priv := range all {
+		if priv == mysql.GrantPriv {
+			continue
+		}
+		if privs&priv > 0 {
+			names = append(names, strings.ToUpper(mysql.Priv2Str[priv]))
+		} else {
+			hasAll = false
+		}
+	}
+	switch {
+	case len(names) == 0:
+		return "USAGE"
+	case hasAll:
+		return mysql.AllPrivilegeLiteral
+	}
+	return strings.Join(names, ", ")
+}
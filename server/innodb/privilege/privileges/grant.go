@@ -0,0 +1,270 @@
+package privileges
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/terror"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// CreateUserTable is the SQL creating mysql.user, the global privileges.
+const CreateUserTable = `CREATE TABLE IF NOT EXISTS mysql.user (
+	Host			CHAR(64),
+	User			CHAR(32),
+	Password		CHAR(41),
+	Select_priv		ENUM('N','Y') NOT NULL DEFAULT 'N',
+	Insert_priv		ENUM('N','Y') NOT NULL DEFAULT 'N',
+	Update_priv		ENUM('N','Y') NOT NULL DEFAULT 'N',
+	Delete_priv		ENUM('N','Y') NOT NULL DEFAULT 'N',
+	Create_priv		ENUM('N','Y') NOT NULL DEFAULT 'N',
+	Drop_priv		ENUM('N','Y') NOT NULL DEFAULT 'N',
+	Process_priv		ENUM('N','Y') NOT NULL DEFAULT 'N',
+	Grant_priv		ENUM('N','Y') NOT NULL DEFAULT 'N',
+	References_priv		ENUM('N','Y') NOT NULL DEFAULT 'N',
+	Alter_priv		ENUM('N','Y') NOT NULL DEFAULT 'N',
+	Show_db_priv		ENUM('N','Y') NOT NULL DEFAULT 'N',
+	Super_priv		ENUM('N','Y') NOT NULL DEFAULT 'N',
+	Create_tmp_table_priv	ENUM('N','Y') NOT NULL DEFAULT 'N',
+	Lock_tables_priv	ENUM('N','Y') NOT NULL DEFAULT 'N',
+	Execute_priv		ENUM('N','Y') NOT NULL DEFAULT 'N',
+	Create_view_priv	ENUM('N','Y') NOT NULL DEFAULT 'N',
+	Show_view_priv		ENUM('N','Y') NOT NULL DEFAULT 'N',
+	Create_routine_priv	ENUM('N','Y') NOT NULL DEFAULT 'N',
+	Alter_routine_priv	ENUM('N','Y') NOT NULL DEFAULT 'N',
+	Index_priv		ENUM('N','Y') NOT NULL DEFAULT 'N',
+	Create_user_priv	ENUM('N','Y') NOT NULL DEFAULT 'N',
+	Event_priv		ENUM('N','Y') NOT NULL DEFAULT 'N',
+	Trigger_priv		ENUM('N','Y') NOT NULL DEFAULT 'N',
+	File_priv		ENUM('N','Y') NOT NULL DEFAULT 'N',
+	PRIMARY KEY (Host, User));`
+
+// CreateDBPrivTable is the SQL creating mysql.db, the privileges of the
+// accounts on the databases matching DB.
+const CreateDBPrivTable = `CREATE TABLE IF NOT EXISTS mysql.db (
+	Host			CHAR(60),
+	DB			CHAR(64),
+	User			CHAR(32),
+	Select_priv		ENUM('N','Y') NOT NULL DEFAULT 'N',
+	Insert_priv		ENUM('N','Y') NOT NULL DEFAULT 'N',
+	Update_priv		ENUM('N','Y') NOT NULL DEFAULT 'N',
+	Delete_priv		ENUM('N','Y') NOT NULL DEFAULT 'N',
+	Create_priv		ENUM('N','Y') NOT NULL DEFAULT 'N',
+	Drop_priv		ENUM('N','Y') NOT NULL DEFAULT 'N',
+	Grant_priv		ENUM('N','Y') NOT NULL DEFAULT 'N',
+	References_priv		ENUM('N','Y') NOT NULL DEFAULT 'N',
+	Index_priv		ENUM('N','Y') NOT NULL DEFAULT 'N',
+	Alter_priv		ENUM('N','Y') NOT NULL DEFAULT 'N',
+	Create_tmp_table_priv	ENUM('N','Y') NOT NULL DEFAULT 'N',
+	Lock_tables_priv	ENUM('N','Y') NOT NULL DEFAULT 'N',
+	Create_view_priv	ENUM('N','Y') NOT NULL DEFAULT 'N',
+	Show_view_priv		ENUM('N','Y') NOT NULL DEFAULT 'N',
+	Create_routine_priv	ENUM('N','Y') NOT NULL DEFAULT 'N',
+	Alter_routine_priv	ENUM('N','Y') NOT NULL DEFAULT 'N',
+	Execute_priv		ENUM('N','Y') NOT NULL DEFAULT 'N',
+	Event_priv		ENUM('N','Y') NOT NULL DEFAULT 'N',
+	Trigger_priv		ENUM('N','Y') NOT NULL DEFAULT 'N',
+	PRIMARY KEY (Host, DB, User));`
+
+// Privilege error codes.
+const (
+	codeNoDB                    terror.ErrCode = terror.ErrCode(mysql.ErrNoDB)
+	codeIllegalGrantForTable    terror.ErrCode = terror.ErrCode(mysql.ErrIllegalGrantForTable)
+	codeNonexistingGrant        terror.ErrCode = terror.ErrCode(mysql.ErrNonexistingGrant)
+	codeCantCreateUserWithGrant terror.ErrCode = terror.ErrCode(mysql.ErrCantCreateUserWithGrant)
+	codeUnsupportedGrantLevel   terror.ErrCode = 1
+)
+
+// Privilege errors.
+var (
+	ErrNoDB                  = terror.ClassPrivilege.New(codeNoDB, "No database selected")
+	ErrIllegalGrantForTable  = terror.ClassPrivilege.New(codeIllegalGrantForTable, mysql.MySQLErrName[mysql.ErrIllegalGrantForTable])
+	ErrUnsupportedGrantLevel = terror.ClassPrivilege.New(codeUnsupportedGrantLevel, "Table level privileges are not supported")
+	// ErrNonexistingGrant is returned by REVOKE of an account without the
+	// grant.
+	ErrNonexistingGrant = terror.ClassPrivilege.New(codeNonexistingGrant, mysql.MySQLErrName[mysql.ErrNonexistingGrant])
+	// ErrCantCreateUserWithGrant is returned by GRANT to an account which
+	// doesn't exist.
+	ErrCantCreateUserWithGrant = terror.ClassPrivilege.New(codeCantCreateUserWithGrant, mysql.MySQLErrName[mysql.ErrCantCreateUserWithGrant])
+)
+
+func init() {
+	privilegeMySQLErrCodes := map[terror.ErrCode]uint16{
+		codeNoDB:                    mysql.ErrNoDB,
+		codeIllegalGrantForTable:    mysql.ErrIllegalGrantForTable,
+		codeNonexistingGrant:        mysql.ErrNonexistingGrant,
+		codeCantCreateUserWithGrant: mysql.ErrCantCreateUserWithGrant,
+	}
+	terror.ErrClassToMySQLCodes[terror.ClassPrivilege] = privilegeMySQLErrCodes
+}
+
+// GrantSQL returns the statements writing the privileges granted by stmt to
+// the privilege tables: mysql.user for *.*, mysql.db for db.* or * in the
+// current database currentDB.
+func GrantSQL(stmt *ast.GrantStmt, currentDB string) ([]string, error) {
+	privs := stmt.Privs
+	if stmt.WithGrant {
+		privs = append(privs[:len(privs):len(privs)], &ast.PrivElem{Priv: mysql.GrantPriv})
+	}
+	return privilegeSQL(stmt.Level, privs, stmt.Users, currentDB, "Y")
+}
+
+// RevokeSQL returns the statements removing the privileges revoked by stmt
+// from the privilege tables, like GrantSQL.
+func RevokeSQL(stmt *ast.RevokeStmt, currentDB string) ([]string, error) {
+	return privilegeSQL(stmt.Level, stmt.Privs, stmt.Users, currentDB, "N")
+}
+
+// levelPrivileges returns the database of level, empty for *.*, and the
+// privileges privs grant on it.
+func levelPrivileges(level *ast.GrantLevel, privs []*ast.PrivElem, currentDB string) (string, []mysql.PrivilegeType, error) {
+	var all []mysql.PrivilegeType
+	db := level.DBName
+	switch level.Level {
+	case ast.GrantLevelGlobal:
+		all = mysql.AllGlobalPrivs
+	case ast.GrantLevelDB:
+		all = mysql.AllDBPrivs
+		if db == "" {
+			db = currentDB
+		}
+		if db == "" {
+			return "", nil, ErrNoDB.GenByArgs()
+		}
+	default:
+		return "", nil, ErrUnsupportedGrantLevel.GenByArgs()
+	}
+	var granted []mysql.PrivilegeType
+	for _, priv := range privs {
+		if priv.Priv == mysql.AllPriv {
+			for _, p := range all {
+				if p != mysql.GrantPriv {
+					granted = append(granted, p)
+				}
+			}
+			continue
+		}
+		if !hasPriv(all, priv.Priv) {
+			return "", nil, ErrIllegalGrantForTable.GenByArgs()
+		}
+		granted = append(granted, priv.Priv)
+	}
+	return db, granted, nil
+}
+
+func privilegeSQL(level *ast.GrantLevel, privs []*ast.PrivElem, users []*ast.UserSpec, currentDB, value string) ([]string, error) {
+	db, granted, err := levelPrivileges(level, privs, currentDB)
+	if err != nil {
+		return nil, err
+	}
+	cols := make([]string, 0, len(granted))
+	for _, priv := range granted {
+		cols = append(cols, mysql.Priv2UserCol[priv])
+	}
+	assigns := make([]string, 0, len(cols))
+	for _, col := range cols {
+		assigns = append(assigns, fmt.Sprintf("%s='%s'", col, value))
+	}
+	set := strings.Join(assigns, ", ")
+
+	sqls := make([]string, 0, len(users))
+	for _, spec := range users {
+		user, host := quote(spec.User.Username), quote(spec.User.Hostname)
+		switch {
+		case level.Level == ast.GrantLevelGlobal:
+			sqls = append(sqls, fmt.Sprintf("UPDATE mysql.user SET %s WHERE User=%s AND Host=%s", set, user, host))
+		case value == "Y":
+			values := make([]string, len(cols))
+			for i := range values {
+				values[i] = "'Y'"
+			}
+			sqls = append(sqls, fmt.Sprintf("INSERT INTO mysql.db (Host, DB, User, %s) VALUES (%s, %s, %s, %s) ON DUPLICATE KEY UPDATE %s",
+				strings.Join(cols, ", "), host, quote(db), user, strings.Join(values, ", "), set))
+		default:
+			sqls = append(sqls, fmt.Sprintf("UPDATE mysql.db SET %s WHERE Host=%s AND DB=%s AND User=%s", set, host, quote(db), user))
+		}
+	}
+	return sqls, nil
+}
+
+// Grant applies stmt to the cache the way the statements of GrantSQL
+// change the privilege tables: it sets the global privileges of the
+// accounts, or their row of mysql.db, added when they have none. The
+// sessions check the new privileges from their next statement on.
+func (h *Handle) Grant(stmt *ast.GrantStmt, currentDB string) error {
+	privs := stmt.Privs
+	if stmt.WithGrant {
+		privs = append(privs[:len(privs):len(privs)], &ast.PrivElem{Priv: mysql.GrantPriv})
+	}
+	return h.applyPrivileges(stmt.Level, privs, stmt.Users, currentDB, true)
+}
+
+// Revoke applies stmt to the cache like Grant.
+func (h *Handle) Revoke(stmt *ast.RevokeStmt, currentDB string) error {
+	return h.applyPrivileges(stmt.Level, stmt.Privs, stmt.Users, currentDB, false)
+}
+
+// applyPrivileges grants or revokes privs on level to users in a copy of
+// the cache, which replaces it once every account is changed.
+func (h *Handle) applyPrivileges(level *ast.GrantLevel, privs []*ast.PrivElem, users []*ast.UserSpec, currentDB string, grant bool) error {
+	db, granted, err := levelPrivileges(level, privs, currentDB)
+	if err != nil {
+		return err
+	}
+	var mask mysql.PrivilegeType
+	for _, priv := range granted {
+		mask |= priv
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	priv := h.Get().clone()
+	for _, spec := range users {
+		user, host := spec.User.Username, spec.User.Hostname
+		record := priv.findUser(user, host)
+		if record == nil {
+			if grant {
+				return ErrCantCreateUserWithGrant.GenByArgs()
+			}
+			return ErrNonexistingGrant.GenByArgs(user, host)
+		}
+		if level.Level == ast.GrantLevelGlobal {
+			record.Privileges = applyMask(record.Privileges, mask, grant)
+			continue
+		}
+		dbRecord := priv.findDB(user, host, db)
+		switch {
+		case dbRecord != nil:
+			dbRecord.Privileges = applyMask(dbRecord.Privileges, mask, grant)
+		case grant:
+			priv.DB = append(priv.DB, newDBRecord(host, db, user, mask))
+		default:
+			return ErrNonexistingGrant.GenByArgs(user, host)
+		}
+	}
+	priv.sortDB()
+	h.priv.Store(priv)
+	return nil
+}
+
+func applyMask(privs, mask mysql.PrivilegeType, grant bool) mysql.PrivilegeType {
+	if grant {
+		return privs | mask
+	}
+	return privs &^ mask
+}
+
+func hasPriv(privs []mysql.PrivilegeType, priv mysql.PrivilegeType) bool {
+	for _, p := range privs {
+		if p == priv {
+			return true
+		}
+	}
+	return false
+}
+
+// quote returns s as a string literal.
+func quote(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	return "'" + strings.Replace(s, "'", `\'`, -1) + "'"
+}
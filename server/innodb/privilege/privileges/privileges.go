@@ -0,0 +1,81 @@
+package privileges
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/juju/errors"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/privilege"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/auth"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/sqlexec"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// Handle keeps the cache of the privilege tables shared by all sessions.
+type Handle struct {
+	priv atomic.Value
+	// mu serializes the changes of GRANT and REVOKE.
+	mu sync.Mutex
+}
+
+// NewHandle returns a Handle with an empty cache.
+func NewHandle() *Handle {
+	h := &Handle{}
+	h.priv.Store(&MySQLPrivilege{})
+	return h
+}
+
+// Get returns the current cache.
+func (h *Handle) Get() *MySQLPrivilege {
+	return h.priv.Load().(*MySQLPrivilege)
+}
+
+// Update reloads mysql.user and mysql.db, as FLUSH PRIVILEGES does. The
+// sessions keep the former cache until both tables are loaded.
+func (h *Handle) Update(ctx context.Context, exec sqlexec.RestrictedSQLExecutor) error {
+	priv := &MySQLPrivilege{}
+	if err := priv.LoadAll(ctx, exec); err != nil {
+		return errors.Trace(err)
+	}
+	h.mu.Lock()
+	h.priv.Store(priv)
+	h.mu.Unlock()
+	return nil
+}
+
+// UserPrivileges checks the privileges of the account of a session.
+type UserPrivileges struct {
+	User string
+	Host string
+	*Handle
+}
+
+var _ privilege.Manager = (*UserPrivileges)(nil)
+
+// ConnectionVerification verifies the mysql_native_password response
+// authentication of user@host to salt, the salt of its handshake. On
+// success the privileges of user@host are checked from then on.
+func (p *UserPrivileges) ConnectionVerification(user, host string, authentication, salt []byte) bool {
+	record := p.Get().connectionVerification(user, host)
+	if record == nil || !auth.VerifyNativePassword(record.Password, salt, authentication) {
+		return false
+	}
+	p.User, p.Host = user, host
+	return true
+}
+
+// RequestVerification implements the Manager interface.
+func (p *UserPrivileges) RequestVerification(db, table, column string, priv mysql.PrivilegeType) bool {
+	return p.Get().RequestVerification(p.User, p.Host, db, table, column, priv)
+}
+
+// DBIsVisible implements the Manager interface.
+func (p *UserPrivileges) DBIsVisible(db string) bool {
+	return p.Get().DBIsVisible(p.User, p.Host, db)
+}
+
+// ShowGrants returns the statements SHOW GRANTS FOR user@host shows.
+func (p *UserPrivileges) ShowGrants(user, host string) []string {
+	return p.Get().showGrants(user, host)
+}
@@ -0,0 +1,319 @@
+package privileges
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/parser"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/terror"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/auth"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// testTables answers SELECT * FROM mysql.user and mysql.db with its rows.
+type testTables map[string][][]string
+
+func (tables testTables) ExecRestrictedSQL(ctx context.Context, sql string) (ast.RecordSet, error) {
+	name := sql[strings.LastIndex(sql, ".")+1:]
+	rows := tables[name]
+	return &testRecordSet{cols: rows[0], rows: rows[1:]}, nil
+}
+
+type testRecordSet struct {
+	cols []string
+	rows [][]string
+}
+
+func (rs *testRecordSet) Fields() ([]*ast.ResultField, error) {
+	fs := make([]*ast.ResultField, 0, len(rs.cols))
+	for _, col := range rs.cols {
+		fs = append(fs, &ast.ResultField{ColumnAsName: model.NewCIStr(col)})
+	}
+	return fs, nil
+}
+
+func (rs *testRecordSet) Next() (*ast.Row, error) {
+	if len(rs.rows) == 0 {
+		return nil, nil
+	}
+	row := &ast.Row{}
+	for _, val := range rs.rows[0] {
+		row.Data = append(row.Data, basic.NewStringDatum(val))
+	}
+	rs.rows = rs.rows[1:]
+	return row, nil
+}
+
+func (rs *testRecordSet) Close() error {
+	return nil
+}
+
+func newTestHandle(t *testing.T, tables testTables) *Handle {
+	h := NewHandle()
+	if err := h.Update(nil, tables); err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func TestDBPrivileges(t *testing.T) {
+	h := newTestHandle(t, testTables{
+		"user": {
+			{"Host", "User", "Password", "Select_priv", "Insert_priv"},
+			{"%", "root", "", "Y", "Y"},
+			{"%", "report", "", "N", "N"},
+			{"localhost", "report", "", "N", "N"},
+		},
+		"db": {
+			{"Host", "DB", "User", "Select_priv", "Insert_priv"},
+			{"%", "sales", "report", "Y", "N"},
+			{"%", "sales%", "report", "Y", "Y"},
+			{"%", "saleseu", "report", "N", "N"},
+			{"10.0.%", "hr", "report", "Y", "N"},
+		},
+	})
+	tests := []struct {
+		user, host, db string
+		priv           mysql.PrivilegeType
+		granted        bool
+	}{
+		{"root", "10.0.0.1", "anything", mysql.InsertPriv, true},
+		{"report", "10.0.0.1", "sales", mysql.SelectPriv, true},
+		{"report", "10.0.0.1", "sales", mysql.InsertPriv, false},
+		{"report", "10.0.0.1", "salesus", mysql.InsertPriv, true},
+		{"report", "10.0.0.1", "sales", mysql.DeletePriv, false},
+		{"report", "10.0.0.1", "sale", mysql.SelectPriv, false},
+		// The exact database name is more specific than the pattern.
+		{"report", "10.0.0.1", "saleseu", mysql.SelectPriv, false},
+		{"report", "10.0.0.1", "hr", mysql.SelectPriv, true},
+		{"report", "192.168.0.1", "hr", mysql.SelectPriv, false},
+		{"report", "10.0.0.1", "", mysql.SelectPriv, false},
+		{"nobody", "10.0.0.1", "sales", mysql.SelectPriv, false},
+	}
+	for _, tt := range tests {
+		pm := &UserPrivileges{User: tt.user, Host: tt.host, Handle: h}
+		if granted := pm.RequestVerification(tt.db, "t", "", tt.priv); granted != tt.granted {
+			t.Fatalf("%s@%s on %s: expect %v, got %v", tt.user, tt.host, tt.db, tt.granted, granted)
+		}
+	}
+
+	pm := &UserPrivileges{User: "report", Host: "10.0.0.1", Handle: h}
+	for db, visible := range map[string]bool{"sales": true, "salesus": true, "saleseu": false, "test": false, "INFORMATION_SCHEMA": true} {
+		if pm.DBIsVisible(db) != visible {
+			t.Fatalf("%s: expect visible %v", db, visible)
+		}
+	}
+	if !(&UserPrivileges{User: "root", Host: "h", Handle: h}).DBIsVisible("test") {
+		t.Fatal("expect global privileges to see every database")
+	}
+
+	expected := []string{
+		"GRANT USAGE ON *.* TO 'report'@'%'",
+		"GRANT SELECT ON `sales`.* TO 'report'@'%'",
+		"GRANT SELECT, INSERT ON `sales%`.* TO 'report'@'%'",
+	}
+	if grants := pm.ShowGrants("report", "%"); !reflect.DeepEqual(grants, expected) {
+		t.Fatalf("expect grants %q, got %q", expected, grants)
+	}
+
+	// FLUSH PRIVILEGES reloads both tables.
+	if err := h.Update(nil, testTables{
+		"user": {{"Host", "User", "Select_priv"}, {"%", "report", "N"}},
+		"db":   {{"Host", "DB", "User", "Select_priv"}, {"%", "test", "report", "Y"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if pm.DBIsVisible("sales") || !pm.RequestVerification("test", "t", "", mysql.SelectPriv) {
+		t.Fatal("expect the reloaded privileges")
+	}
+}
+
+func TestConnectionVerification(t *testing.T) {
+	h := newTestHandle(t, testTables{
+		"user": {
+			{"Host", "User", "Password", "Select_priv"},
+			{"%", "app", auth.EncodePassword("secret"), "N"},
+			{"%", "guest", "", "N"},
+		},
+		"db": {{"Host", "DB", "User", "Select_priv"}},
+	})
+	salt, err := auth.NewScrambleManager(nil).NewSalt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		user, password string
+		ok             bool
+	}{
+		{"app", "secret", true},
+		{"app", "wrong", false},
+		{"app", "", false},
+		{"guest", "", true},
+		{"guest", "secret", false},
+		{"nobody", "", false},
+	}
+	for _, tt := range tests {
+		p := &UserPrivileges{Handle: h}
+		if ok := p.ConnectionVerification(tt.user, "10.0.0.1", auth.ScrambleNativePassword(tt.password, salt), salt); ok != tt.ok {
+			t.Errorf("%s with password %q: expect %v, got %v", tt.user, tt.password, tt.ok, ok)
+		}
+		if tt.ok && (p.User != tt.user || p.Host != "10.0.0.1") {
+			t.Errorf("%s: expect the session to be %s@10.0.0.1, got %s@%s", tt.user, tt.user, p.User, p.Host)
+		}
+	}
+}
+
+func TestGrantSQL(t *testing.T) {
+	p := parser.New()
+	tests := []struct {
+		sql      string
+		expected []string
+	}{
+		{"GRANT SELECT ON sales.* TO 'report'@'%'", []string{
+			"INSERT INTO mysql.db (Host, DB, User, Select_priv) VALUES ('%', 'sales', 'report', 'Y') ON DUPLICATE KEY UPDATE Select_priv='Y'"}},
+		{"GRANT SELECT, INSERT ON * TO 'report'@'%' WITH GRANT OPTION", []string{
+			"INSERT INTO mysql.db (Host, DB, User, Select_priv, Insert_priv, Grant_priv) VALUES ('%', 'test', 'report', 'Y', 'Y', 'Y') ON DUPLICATE KEY UPDATE Select_priv='Y', Insert_priv='Y', Grant_priv='Y'"}},
+		{"GRANT SELECT ON *.* TO 'a'@'%', 'b'@'localhost'", []string{
+			"UPDATE mysql.user SET Select_priv='Y' WHERE User='a' AND Host='%'",
+			"UPDATE mysql.user SET Select_priv='Y' WHERE User='b' AND Host='localhost'"}},
+		{"REVOKE SELECT ON sales.* FROM 'report'@'%'", []string{
+			"UPDATE mysql.db SET Select_priv='N' WHERE Host='%' AND DB='sales' AND User='report'"}},
+	}
+	for _, tt := range tests {
+		stmt, err := p.ParseOneStmt(tt.sql, "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var sqls []string
+		switch x := stmt.(type) {
+		case *ast.GrantStmt:
+			sqls, err = GrantSQL(x, "test")
+		case *ast.RevokeStmt:
+			sqls, err = RevokeSQL(x, "test")
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tt.sql, err)
+		}
+		if !reflect.DeepEqual(sqls, tt.expected) {
+			t.Fatalf("%s: expect %q, got %q", tt.sql, tt.expected, sqls)
+		}
+	}
+
+	stmt, _ := p.ParseOneStmt("GRANT SUPER ON sales.* TO 'report'@'%'", "", "")
+	if _, err := GrantSQL(stmt.(*ast.GrantStmt), "test"); !terror.ErrorEqual(err, ErrIllegalGrantForTable) {
+		t.Fatalf("expect error %d, got %v", mysql.ErrIllegalGrantForTable, err)
+	}
+	stmt, _ = p.ParseOneStmt("GRANT SELECT ON * TO 'report'@'%'", "", "")
+	if _, err := GrantSQL(stmt.(*ast.GrantStmt), ""); !terror.ErrorEqual(err, ErrNoDB) {
+		t.Fatalf("expect error %d, got %v", mysql.ErrNoDB, err)
+	}
+}
+
+func TestGrantDBPrivileges(t *testing.T) {
+	h := newTestHandle(t, testTables{
+		"user": {
+			{"Host", "User", "Password", "Select_priv"},
+			{"%", "report", "", "N"},
+		},
+		"db": {{"Host", "DB", "User", "Select_priv"}},
+	})
+	p := parser.New()
+	exec := func(sql string) error {
+		stmt, err := p.ParseOneStmt(sql, "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch x := stmt.(type) {
+		case *ast.GrantStmt:
+			return h.Grant(x, "test")
+		case *ast.RevokeStmt:
+			return h.Revoke(x, "test")
+		}
+		return nil
+	}
+	pm := &UserPrivileges{User: "report", Host: "10.0.0.1", Handle: h}
+	if pm.RequestVerification("sales", "t", "", mysql.SelectPriv) {
+		t.Fatal("expect no SELECT before the grant")
+	}
+
+	if err := exec("GRANT SELECT ON sales.* TO 'report'@'%'"); err != nil {
+		t.Fatal(err)
+	}
+	if !pm.RequestVerification("sales", "t", "", mysql.SelectPriv) {
+		t.Fatal("expect SELECT on sales")
+	}
+	if pm.RequestVerification("hr", "t", "", mysql.SelectPriv) || pm.RequestVerification("sales", "t", "", mysql.InsertPriv) {
+		t.Fatal("expect only SELECT on sales")
+	}
+	if !pm.DBIsVisible("sales") || pm.DBIsVisible("hr") {
+		t.Fatal("expect only sales to be visible")
+	}
+
+	// The database name may be a pattern.
+	if err := exec("GRANT INSERT ON `hr%`.* TO 'report'@'%'"); err != nil {
+		t.Fatal(err)
+	}
+	if !pm.RequestVerification("hr_eu", "t", "", mysql.InsertPriv) {
+		t.Fatal("expect INSERT on the databases matching hr%")
+	}
+
+	if err := exec("REVOKE SELECT ON sales.* FROM 'report'@'%'"); err != nil {
+		t.Fatal(err)
+	}
+	if pm.RequestVerification("sales", "t", "", mysql.SelectPriv) {
+		t.Fatal("expect SELECT on sales to be revoked")
+	}
+
+	if err := exec("GRANT SELECT ON sales.* TO 'nobody'@'%'"); !terror.ErrorEqual(err, ErrCantCreateUserWithGrant) {
+		t.Fatalf("expect error %d, got %v", mysql.ErrCantCreateUserWithGrant, err)
+	}
+	if err := exec("REVOKE SELECT ON hr.* FROM 'report'@'%'"); !terror.ErrorEqual(err, ErrNonexistingGrant) {
+		t.Fatalf("expect error %d, got %v", mysql.ErrNonexistingGrant, err)
+	}
+}
+
+func TestTables(t *testing.T) {
+	root := NewRootPrivilege(auth.EncodePassword("secret"))
+	tables := testTables{}
+	for _, tbl := range Tables {
+		info, err := tbl.TableInfo()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Name.L != tbl.Name || info.ID != int64(tbl.SpaceID) {
+			t.Fatalf("expect table %s with id %d, got %s %d", tbl.Name, tbl.SpaceID, info.Name.O, info.ID)
+		}
+		var cols []string
+		for _, col := range info.Columns {
+			cols = append(cols, col.Name.O)
+		}
+		rows := [][]string{cols}
+		for _, row := range root.Rows(info) {
+			var vals []string
+			for _, d := range row {
+				val, _ := d.ToString()
+				vals = append(vals, val)
+			}
+			rows = append(rows, vals)
+		}
+		tables[tbl.Name] = rows
+	}
+	// The rows read back give the same cache.
+	got := newTestHandle(t, tables).Get()
+	if len(got.User) != 1 || len(got.DB) != 0 {
+		t.Fatalf("expect root only, got %v %v", got.User, got.DB)
+	}
+	if record := got.User[0]; record.Host != "localhost" || record.User != "root" || record.Password != auth.EncodePassword("secret") {
+		t.Fatalf("expect root@localhost, got %+v", record)
+	}
+	for _, priv := range mysql.AllGlobalPrivs {
+		if got.User[0].Privileges&priv == 0 {
+			t.Fatalf("expect root to have %s", mysql.Priv2Str[priv])
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package privileges
+
+import (
+	"github.com/juju/errors"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ddl"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/parser"
+	// plan evaluates the DEFAULT of the columns for ddl.
+	_ "github.com/zhukovaskychina/xmysql-server/server/innodb/plan"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// Table is a privilege table of the mysql database.
+type Table struct {
+	Name string
+	// CreateSQL is the CREATE TABLE of the table.
+	CreateSQL string
+	// SpaceID is the id of the tablespace of the table.
+	SpaceID uint32
+}
+
+// Tables are the privilege tables, mysql.user and mysql.db.
+var Tables = []Table{
+	{"user", CreateUserTable, 1},
+	{"db", CreateDBPrivTable, 2},
+}
+
+// TableInfo returns the definition of t.
+func (t Table) TableInfo() (*model.TableInfo, error) {
+	stmt, err := parser.New().ParseOneStmt(t.CreateSQL, mysql.UTF8Charset, mysql.UTF8DefaultCollation)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	info, err := ddl.BuildTableInfo(nil, stmt.(*ast.CreateTableStmt))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	info.ID = int64(t.SpaceID)
+	return info, nil
+}
+
+// NewRootPrivilege returns the cache of the privilege tables of a new data
+// directory: root@localhost, identified by password as auth.EncodePassword
+// encodes it, with every global privilege.
+func NewRootPrivilege(password string) *MySQLPrivilege {
+	return &MySQLPrivilege{User: []UserRecord{newUserRecord("localhost", "root", password, mysql.AllPrivMask)}}
+}
+
+// Rows returns the rows of info, mysql.user or mysql.db, in p. The
+// privileges without a column of their own are 'N'.
+func (p *MySQLPrivilege) Rows(info *model.TableInfo) [][]basic.Datum {
+	var rows [][]basic.Datum
+	switch info.Name.L {
+	case "user":
+		for _, record := range p.User {
+			rows = append(rows, privilegeRow(info, map[string]string{
+				"host": record.Host, "user": record.User, "password": record.Password,
+			}, record.Privileges))
+		}
+	case "db":
+		for _, record := range p.DB {
+			rows = append(rows, privilegeRow(info, map[string]string{
+				"host": record.Host, "db": record.DB, "user": record.User,
+			}, record.Privileges))
+		}
+	}
+	return rows
+}
+
+// privilegeRow returns the row of info with the values of cols, by lower
+// case name, and 'Y' in the *_priv columns of privs, as decodeRow reads it.
+func privilegeRow(info *model.TableInfo, cols map[string]string, privs mysql.PrivilegeType) []basic.Datum {
+	row := make([]basic.Datum, len(info.Columns))
+	for i, col := range info.Columns {
+		if val, ok := cols[col.Name.L]; ok {
+			row[i] = basic.NewStringDatum(val)
+			continue
+		}
+		val := "N"
+		if priv, ok := mysql.Col2PrivType[col.Name.O]; ok && privs&priv > 0 {
+			val = "Y"
+		}
+		row[i] = basic.NewStringDatum(val)
+	}
+	return row
+}
@@ -2,16 +2,27 @@ package resolver
 
 import (
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/terror"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
 )
 
 // Optimizer error codes.
 const (
 	CodeInvalidGroupFuncUse terror.ErrCode = 5
 	CodeIllegalReference    terror.ErrCode = 6
+
+	CodeDerivedMustHaveAlias terror.ErrCode = mysql.ErrDerivedMustHaveAlias
+	CodeUnknownColumn        terror.ErrCode = mysql.ErrBadField
+	CodeUnknownTable         terror.ErrCode = mysql.ErrBadTable
+	CodeNonuniqTable         terror.ErrCode = mysql.ErrNonuniqTable
 )
 
 // Optimizer base errors.
 var (
 	ErrInvalidGroupFuncUse = terror.ClassOptimizer.New(CodeInvalidGroupFuncUse, "Invalid use of group function")
 	ErrIllegalReference    = terror.ClassOptimizer.New(CodeIllegalReference, "Illegal reference")
+
+	ErrDerivedMustHaveAlias = terror.ClassOptimizer.New(CodeDerivedMustHaveAlias, mysql.MySQLErrName[mysql.ErrDerivedMustHaveAlias])
+	ErrUnknownColumn        = terror.ClassOptimizerPlan.New(CodeUnknownColumn, mysql.MySQLErrName[mysql.ErrBadField])
+	ErrUnknownTable         = terror.ClassOptimizerPlan.New(CodeUnknownTable, mysql.MySQLErrName[mysql.ErrBadTable])
+	ErrNonuniqTable         = terror.ClassOptimizer.New(CodeNonuniqTable, mysql.MySQLErrName[mysql.ErrNonuniqTable])
 )
@@ -16,6 +16,7 @@ package resolver
 import (
 	"fmt"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/schemas"
+	"strconv"
 
 	"github.com/juju/errors"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
@@ -25,6 +26,17 @@ import (
 	"github.com/zhukovaskychina/xmysql-server/server/mysql"
 )
 
+const (
+	unknownClause    = ""
+	fieldList        = "field list"
+	havingClause     = "having clause"
+	onClause         = "on clause"
+	orderByClause    = "order clause"
+	whereClause      = "where clause"
+	groupByStatement = "group statement"
+	showStatement    = "show statement"
+)
+
 // ResolveName resolves table name and column name.
 // It generates ResultFields for ResultSetNode and resolves ColumnNameExpr to a ResultField.
 func ResolveName(node ast.Node, info schemas.InfoSchema, ctx context.Context) error {
@@ -144,6 +156,9 @@ func (nr *nameResolver) Enter(inNode ast.Node) (outNode ast.Node, skipChildren b
 		if ctx.inHaving {
 			ctx.inHavingAgg = true
 		}
+		if v.Order != nil {
+			nr.handleAggregateOrder(v)
+		}
 	case *ast.AlterTableStmt:
 		nr.pushContext()
 		for _, spec := range v.Specs {
@@ -167,11 +182,18 @@ func (nr *nameResolver) Enter(inNode ast.Node) (outNode ast.Node, skipChildren b
 				return inNode, true
 			}
 		}
+	case *ast.ChecksumTableStmt:
+		// The checksum of a table that doesn't exist is NULL.
+		nr.pushContext()
+		nr.currentContext().inCreateOrDropTable = true
 	case *ast.CreateIndexStmt:
 		nr.pushContext()
 	case *ast.CreateTableStmt:
 		nr.pushContext()
 		nr.currentContext().inCreateOrDropTable = true
+	case *ast.CreateViewStmt:
+		nr.pushContext()
+		nr.currentContext().inCreateOrDropTable = true
 	case *ast.DeleteStmt:
 		nr.pushContext()
 	case *ast.DeleteTableList:
@@ -191,6 +213,8 @@ func (nr *nameResolver) Enter(inNode ast.Node) (outNode ast.Node, skipChildren b
 		nr.currentContext().inHaving = true
 	case *ast.InsertStmt:
 		nr.pushContext()
+	case *ast.ImportTableStmt:
+		nr.pushContext()
 	case *ast.LoadDataStmt:
 		nr.pushContext()
 	case *ast.Join:
@@ -246,10 +270,14 @@ func (nr *nameResolver) Leave(inNode ast.Node) (node ast.Node, ok bool) {
 		nr.handleTableName(v)
 	case *ast.ColumnNameExpr:
 		nr.handleColumnName(v)
+	case *ast.ChecksumTableStmt:
+		nr.popContext()
 	case *ast.CreateIndexStmt:
 		nr.popContext()
 	case *ast.CreateTableStmt:
 		nr.popContext()
+	case *ast.CreateViewStmt:
+		nr.popContext()
 	case *ast.DeleteTableList:
 		nr.currentContext().inDeleteTableList = false
 	case *ast.DoStmt:
@@ -320,6 +348,8 @@ func (nr *nameResolver) Leave(inNode ast.Node) (node ast.Node, ok bool) {
 		nr.handleUnionSelectList(v)
 	case *ast.InsertStmt:
 		nr.popContext()
+	case *ast.ImportTableStmt:
+		nr.popContext()
 	case *ast.LoadDataStmt:
 		nr.popContext()
 	case *ast.DeleteStmt:
@@ -354,7 +384,7 @@ func (nr *nameResolver) handleTableName(tn *ast.TableName) {
 		tn.SetResultFields(tableName.GetResultFields())
 		return
 	}
-	table, err := nr.Info.TableByName(tn.Schema, tn.Name)
+	table, err := schemas.TableByName(nr.Info, tn.Schema, tn.Name)
 	if err != nil {
 		nr.Err = errors.Trace(err)
 		return
@@ -399,24 +429,41 @@ func (nr *nameResolver) handleTableSource(ts *ast.TableSource) {
 	switch ts.Source.(type) {
 	case *ast.TableName:
 		var name string
+		// alias is the name the table is known by in the statement.
+		alias := ts.AsName
 		if ts.AsName.L != "" {
 			name = ts.AsName.L
 		} else {
 			tableName := ts.Source.(*ast.TableName)
 			name = nr.tableUniqueName(tableName.Schema, tableName.Name)
+			alias = tableName.Name
 		}
 		if _, ok := ctx.tableMap[name]; ok {
-			nr.Err = errors.Errorf("duplicated table/alias name %s", name)
+			nr.Err = ErrNonuniqTable.GenByArgs(alias.O)
 			return
 		}
 		ctx.tableMap[name] = len(ctx.tables)
-	case *ast.SelectStmt:
+	case *ast.SelectStmt, *ast.UnionStmt:
 		name := ts.AsName.L
+		if name == "" {
+			nr.Err = ErrDerivedMustHaveAlias.GenByArgs()
+			return
+		}
 		if _, ok := ctx.derivedTableMap[name]; ok {
-			nr.Err = errors.Errorf("duplicated table/alias name %s", name)
+			nr.Err = ErrNonuniqTable.GenByArgs(ts.AsName.O)
 			return
 		}
 		ctx.derivedTableMap[name] = len(ctx.tables)
+		if len(ts.ColumnNames) > 0 {
+			// The column list of a common table expression renames its columns.
+			if len(ts.ColumnNames) != len(ts.GetResultFields()) {
+				nr.Err = schemas.ErrViewWrongList.GenByArgs()
+				return
+			}
+			for i, rf := range ts.GetResultFields() {
+				rf.ColumnAsName = ts.ColumnNames[i]
+			}
+		}
 	}
 	dupNames := make(map[string]struct{}, len(ts.GetResultFields()))
 	for _, f := range ts.GetResultFields() {
@@ -461,8 +508,11 @@ func (nr *nameResolver) handleColumnName(cn *ast.ColumnNameExpr) {
 	}
 
 	// Try to resolve the column name form top to bottom in the context stack.
+	var where string
+	var ok bool
 	for i := len(nr.contextStack) - 1; i >= 0; i-- {
-		if nr.resolveColumnNameInContext(nr.contextStack[i], cn) {
+		where, ok = nr.resolveColumnNameInContext(nr.contextStack[i], cn)
+		if ok {
 			// Column is already resolved or encountered an error.
 			if i < len(nr.contextStack)-1 {
 				// If in subselect, the query use outer query.
@@ -471,18 +521,29 @@ func (nr *nameResolver) handleColumnName(cn *ast.ColumnNameExpr) {
 			return
 		}
 	}
-	nr.Err = errors.Errorf("unknown column %s", cn.Name.Name.L)
+	nr.Err = ErrUnknownColumn.GenByArgs(qualifiedColumnName(cn.Name), where)
+}
+
+// qualifiedColumnName returns the column name the way it is written in the statement.
+func qualifiedColumnName(name *ast.ColumnName) string {
+	if name.Table.L == "" {
+		return name.Name.O
+	}
+	if name.Schema.L == "" {
+		return fmt.Sprintf("%s.%s", name.Table.O, name.Name.O)
+	}
+	return fmt.Sprintf("%s.%s.%s", name.Schema.O, name.Table.O, name.Name.O)
 }
 
 // resolveColumnNameInContext looks up and sets ResultField for a column with the ctx.
-func (nr *nameResolver) resolveColumnNameInContext(ctx *resolverContext, cn *ast.ColumnNameExpr) bool {
+func (nr *nameResolver) resolveColumnNameInContext(ctx *resolverContext, cn *ast.ColumnNameExpr) (string, bool) {
 	if ctx.inTableRefs {
 		// In TableRefsClause, column reference only in join on condition which is handled before.
-		return false
+		return unknownClause, false
 	}
 	if ctx.inFieldList {
 		// only resolve column using tables.
-		return nr.resolveColumnInTableSources(cn, ctx.tables)
+		return fieldList, nr.resolveColumnInTableSources(cn, ctx.tables)
 	}
 	if ctx.inGroupBy {
 		// From tables first, then field list.
@@ -491,7 +552,7 @@ func (nr *nameResolver) resolveColumnNameInContext(ctx *resolverContext, cn *ast
 		if ctx.inByItemExpression {
 			// From table first, then field list.
 			if nr.resolveColumnInTableSources(cn, ctx.tables) {
-				return true
+				return groupByStatement, true
 			}
 			found := nr.resolveColumnInResultFields(ctx, cn, ctx.fieldList)
 			if nr.Err == nil && found {
@@ -500,12 +561,12 @@ func (nr *nameResolver) resolveColumnNameInContext(ctx *resolverContext, cn *ast
 					nr.Err = ErrIllegalReference.Gen("Reference '%s' not supported (reference to group function)", cn.Name.Name.O)
 				}
 			}
-			return found
+			return groupByStatement, found
 		}
 		// Resolve from table first, then from select list.
 		found := nr.resolveColumnInTableSources(cn, ctx.tables)
 		if nr.Err != nil {
-			return found
+			return groupByStatement, found
 		}
 		// We should copy the refer here.
 		// Because if the ByItem is an identifier, we should check if it
@@ -514,7 +575,7 @@ func (nr *nameResolver) resolveColumnNameInContext(ctx *resolverContext, cn *ast
 		r := cn.Refer
 		if nr.resolveColumnInResultFields(ctx, cn, ctx.fieldList) {
 			if nr.Err != nil {
-				return true
+				return groupByStatement, true
 			}
 			if r != nil {
 				// It is not ambiguous and already resolved from table source.
@@ -524,45 +585,45 @@ func (nr *nameResolver) resolveColumnNameInContext(ctx *resolverContext, cn *ast
 			if _, ok := cn.Refer.Expr.(*ast.AggregateFuncExpr); ok {
 				nr.Err = ErrIllegalReference.Gen("Reference '%s' not supported (reference to group function)", cn.Name.Name.O)
 			}
-			return true
+			return groupByStatement, true
 		}
-		return found
+		return groupByStatement, found
 	}
 	if ctx.inHaving {
 		// First group by, then field list.
 		if nr.resolveColumnInResultFields(ctx, cn, ctx.groupBy) {
-			return true
+			return havingClause, true
 		}
 		if ctx.inHavingAgg {
 			// If cn is in an aggregate function in having clause, check tablesource first.
 			if nr.resolveColumnInTableSources(cn, ctx.tables) {
-				return true
+				return havingClause, true
 			}
 		}
-		return nr.resolveColumnInResultFields(ctx, cn, ctx.fieldList)
+		return havingClause, nr.resolveColumnInResultFields(ctx, cn, ctx.fieldList)
 	}
 	if ctx.inOrderBy {
 		if nr.resolveColumnInResultFields(ctx, cn, ctx.groupBy) {
-			return true
+			return orderByClause, true
 		}
 		if ctx.inByItemExpression {
 			// From table first, then field list.
 			if nr.resolveColumnInTableSources(cn, ctx.tables) {
-				return true
+				return orderByClause, true
 			}
-			return nr.resolveColumnInResultFields(ctx, cn, ctx.fieldList)
+			return orderByClause, nr.resolveColumnInResultFields(ctx, cn, ctx.fieldList)
 		}
 		// Field list first, then from table.
 		if nr.resolveColumnInResultFields(ctx, cn, ctx.fieldList) {
-			return true
+			return orderByClause, true
 		}
-		return nr.resolveColumnInTableSources(cn, ctx.tables)
+		return orderByClause, nr.resolveColumnInTableSources(cn, ctx.tables)
 	}
 	if ctx.inShow {
-		return nr.resolveColumnInResultFields(ctx, cn, ctx.fieldList)
+		return showStatement, nr.resolveColumnInResultFields(ctx, cn, ctx.fieldList)
 	}
 	// In where clause.
-	return nr.resolveColumnInTableSources(cn, ctx.tables)
+	return whereClause, nr.resolveColumnInTableSources(cn, ctx.tables)
 }
 
 // resolveColumnNameInOnCondition resolves the column name in current join.
@@ -571,7 +632,7 @@ func (nr *nameResolver) resolveColumnNameInOnCondition(cn *ast.ColumnNameExpr) {
 	join := ctx.joinNodeStack[len(ctx.joinNodeStack)-1]
 	tableSources := appendTableSources(nil, join)
 	if !nr.resolveColumnInTableSources(cn, tableSources) {
-		nr.Err = errors.Errorf("unknown column name %s", cn.Name.Name.O)
+		nr.Err = ErrUnknownColumn.GenByArgs(qualifiedColumnName(cn.Name), onClause)
 	}
 }
 
@@ -713,9 +774,14 @@ func (nr *nameResolver) createResultFields(field *ast.SelectField) (rfs []*ast.R
 		} else {
 			name := nr.tableUniqueName(field.WildCard.Schema, field.WildCard.Table)
 			tableIdx, ok1 := ctx.tableMap[name]
+			if !ok1 && field.WildCard.Schema.L == "" {
+				// The table may be of another database, named without it.
+				tableIdx, ok1 = tableIndexByName(ctx, field.WildCard.Table)
+			}
 			derivedTableIdx, ok2 := ctx.derivedTableMap[name]
 			if !ok1 && !ok2 {
-				nr.Err = errors.Errorf("unknown table %s.", field.WildCard.Table.O)
+				nr.Err = ErrUnknownTable.GenByArgs(field.WildCard.Table.O)
+				return
 			}
 			if ok1 {
 				tableRfs = ctx.tables[tableIdx].GetResultFields()
@@ -753,6 +819,7 @@ func (nr *nameResolver) createResultFields(field *ast.SelectField) (rfs []*ast.R
 		rf.Column = v.Refer.Column
 		rf.Table = v.Refer.Table
 		rf.DBName = v.Refer.DBName
+		rf.TableAsName = v.Refer.TableAsName
 		rf.TableName = v.Refer.TableName
 		rf.Expr = v
 	default:
@@ -778,6 +845,17 @@ func (nr *nameResolver) createResultFields(field *ast.SelectField) (rfs []*ast.R
 	return
 }
 
+// tableIndexByName returns the index of the table named name in the FROM
+// clause of ctx, whatever its database.
+func tableIndexByName(ctx *resolverContext, name model.CIStr) (int, bool) {
+	for i, ts := range ctx.tables {
+		if tn, ok := ts.Source.(*ast.TableName); ok && ts.AsName.L == "" && tn.Name.L == name.L {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
 func appendTableSources(in []*ast.TableSource, resultSetNode ast.ResultSetNode) (out []*ast.TableSource) {
 	switch v := resultSetNode.(type) {
 	case *ast.TableSource:
@@ -821,6 +899,24 @@ func (nr *nameResolver) handlePosition(pos *ast.PositionExpr) {
 	}
 }
 
+// handleAggregateOrder replaces the positions in the ORDER BY of a
+// GROUP_CONCAT by the arguments they refer to, the separator excluded, as
+// MySQL numbers them among the arguments rather than the select fields.
+func (nr *nameResolver) handleAggregateOrder(agg *ast.AggregateFuncExpr) {
+	args := agg.Args[:len(agg.Args)-1]
+	for _, item := range agg.Order.Items {
+		pos, ok := item.Expr.(*ast.PositionExpr)
+		if !ok {
+			continue
+		}
+		if pos.N < 1 || pos.N > len(args) {
+			nr.Err = ErrUnknownColumn.GenByArgs(strconv.Itoa(pos.N), orderByClause)
+			return
+		}
+		item.Expr = args[pos.N-1]
+	}
+}
+
 func (nr *nameResolver) handleUnionSelectList(u *ast.UnionSelectList) {
 	firstSelFields := u.Selects[0].GetResultFields()
 	unionFields := make([]*ast.ResultField, len(firstSelFields))
@@ -839,7 +935,8 @@ func (nr *nameResolver) handleUnionSelectList(u *ast.UnionSelectList) {
 }
 
 func (nr *nameResolver) fillShowFields(s *ast.ShowStmt) {
-	if s.DBName == "" {
+	// SHOW OPEN TABLES without FROM lists the tables of every database.
+	if s.DBName == "" && s.Tp != ast.ShowOpenTables {
 		if s.Table != nil && s.Table.Schema.L != "" {
 			s.DBName = s.Table.Schema.O
 		} else {
@@ -909,10 +1006,19 @@ func (nr *nameResolver) fillShowFields(s *ast.ShowStmt) {
 		ftypes = []byte{mysql.TypeVarchar, mysql.TypeLonglong, mysql.TypeVarchar, mysql.TypeLonglong,
 			mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeLonglong, mysql.TypeLonglong,
 			mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeVarchar}
+	case ast.ShowOpenTables:
+		names = []string{"Database", "Table", "In_use", "Name_locked"}
+		ftypes = []byte{mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeLonglong, mysql.TypeLonglong}
 	case ast.ShowProcessList:
 		names = []string{"Id", "User", "Host", "db", "Command", "Time", "State", "Info"}
 		ftypes = []byte{mysql.TypeLonglong, mysql.TypeVarchar, mysql.TypeVarchar,
 			mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeLong, mysql.TypeVarchar, mysql.TypeString}
+	case ast.ShowProfiles:
+		names = []string{"Query_ID", "Duration", "Query"}
+		ftypes = []byte{mysql.TypeLonglong, mysql.TypeDouble, mysql.TypeVarchar}
+	case ast.ShowProfile:
+		names = []string{"Status", "Duration"}
+		ftypes = []byte{mysql.TypeVarchar, mysql.TypeDouble}
 	}
 	for i, name := range names {
 		f := &ast.ResultField{
@@ -934,6 +1040,10 @@ func (nr *nameResolver) fillShowFields(s *ast.ShowStmt) {
 
 	if s.Pattern != nil && s.Pattern.Expr == nil {
 		rf := fields[0]
+		if s.Tp == ast.ShowOpenTables {
+			// The pattern matches the names of the tables.
+			rf = fields[1]
+		}
 		s.Pattern.Expr = &ast.ColumnNameExpr{
 			Name: &ast.ColumnName{Name: rf.ColumnAsName},
 		}
@@ -0,0 +1,161 @@
+package schemas
+
+import (
+	"math"
+	"sort"
+	"sync/atomic"
+
+	types "github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+)
+
+// BufferPage is a page of the buffer pool.
+type BufferPage struct {
+	PoolID     int
+	Space      uint32
+	PageNumber uint32
+	PageType   string
+	// Table, as `db`.`table`, and Index are the table and index the page
+	// belongs to, empty when they aren't known.
+	Table    string
+	Index    string
+	DataSize int
+	// Dirty reports whether the page waits to be flushed, Old whether it is
+	// in the old sublist of the LRU list.
+	Dirty bool
+	Old   bool
+}
+
+// BufferPages is the source of the pages of the buffer pool.
+type BufferPages interface {
+	// BufferPages returns the pages of the pool and the number of pages
+	// the pool holds at most.
+	BufferPages() (pages []BufferPage, poolSize int)
+}
+
+// BufferPageOwners names the owners of the pages of the buffer pool.
+type BufferPageOwners interface {
+	// PageOwner returns the table, as `db`.`table`, and the index a page
+	// of space recorded as a page of index indexId belongs to, empty when
+	// they aren't known.
+	PageOwner(space uint32, indexId uint64) (table string, index string)
+}
+
+var bufferPages atomic.Value
+
+// RegisterBufferPages sets the pages information_schema.INNODB_BUFFER_PAGE
+// and the tables summing them up report.
+func RegisterBufferPages(pages BufferPages) {
+	bufferPages.Store(&pages)
+}
+
+func loadBufferPages() ([]BufferPage, int) {
+	pages, ok := bufferPages.Load().(*BufferPages)
+	if !ok || *pages == nil {
+		return nil, 0
+	}
+	return (*pages).BufferPages()
+}
+
+// yesNo returns YES for a true b and NO otherwise.
+func yesNo(b bool) string {
+	if b {
+		return "YES"
+	}
+	return "NO"
+}
+
+// nullIfEmpty returns nil, NULL, for an empty s.
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// BufferPageRows returns the rows of information_schema.INNODB_BUFFER_PAGE.
+func BufferPageRows() [][]types.Datum {
+	pages, _ := loadBufferPages()
+	rows := make([][]types.Datum, 0, len(pages))
+	for _, p := range pages {
+		rows = append(rows, types.MakeDatums(p.PoolID, int64(p.Space), int64(p.PageNumber), p.PageType,
+			nullIfEmpty(p.Table), nullIfEmpty(p.Index), p.DataSize, yesNo(p.Dirty), yesNo(p.Old)))
+	}
+	return rows
+}
+
+// bufferPoolUsage is the share of the pool taken by a table or an index.
+type bufferPoolUsage struct {
+	table, index string
+	pages, dirty int
+	dataSize     int
+}
+
+// bufferPoolUsages sums up the pages by table, and by index too when
+// byIndex, the owners with the most pages first.
+func bufferPoolUsages(pages []BufferPage, byIndex bool) []*bufferPoolUsage {
+	type key struct{ table, index string }
+	byOwner := make(map[key]*bufferPoolUsage)
+	var usages []*bufferPoolUsage
+	for _, p := range pages {
+		k := key{table: p.Table}
+		if byIndex {
+			k.index = p.Index
+		}
+		u, ok := byOwner[k]
+		if !ok {
+			u = &bufferPoolUsage{table: k.table, index: k.index}
+			byOwner[k] = u
+			usages = append(usages, u)
+		}
+		u.pages++
+		if p.Dirty {
+			u.dirty++
+		}
+		u.dataSize += p.DataSize
+	}
+	sort.Slice(usages, func(i, j int) bool {
+		if usages[i].pages != usages[j].pages {
+			return usages[i].pages > usages[j].pages
+		}
+		if usages[i].table != usages[j].table {
+			return usages[i].table < usages[j].table
+		}
+		return usages[i].index < usages[j].index
+	})
+	return usages
+}
+
+// percentOfPool returns the percentage of a pool of poolSize pages pages
+// take, to two decimals.
+func percentOfPool(pages int, poolSize int) float64 {
+	if poolSize <= 0 {
+		return 0
+	}
+	return math.Round(float64(pages)*10000/float64(poolSize)) / 100
+}
+
+// BufferPoolStatsByTableRows returns the rows of
+// information_schema.INNODB_BUFFER_POOL_STATS_BY_TABLE, the pages of the
+// pool summed up by table.
+func BufferPoolStatsByTableRows() [][]types.Datum {
+	pages, poolSize := loadBufferPages()
+	var rows [][]types.Datum
+	for _, u := range bufferPoolUsages(pages, false) {
+		rows = append(rows, types.MakeDatums(nullIfEmpty(u.table), u.pages, u.dirty, u.dataSize,
+			percentOfPool(u.pages, poolSize)))
+	}
+	return rows
+}
+
+// BufferPoolStatsByIndexRows returns the rows of
+// information_schema.INNODB_BUFFER_POOL_STATS_BY_INDEX, the pages of the
+// pool summed up by index.
+func BufferPoolStatsByIndexRows() [][]types.Datum {
+	pages, poolSize := loadBufferPages()
+	var rows [][]types.Datum
+	for _, u := range bufferPoolUsages(pages, true) {
+		rows = append(rows, types.MakeDatums(nullIfEmpty(u.table), nullIfEmpty(u.index), u.pages, u.dirty,
+			u.dataSize, percentOfPool(u.pages, poolSize)))
+	}
+	return rows
+}
@@ -18,10 +18,13 @@
 package schemas
 
 import (
+	"fmt"
+
 	log "github.com/sirupsen/logrus"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic/json"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/hack"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/stringutil"
 	"strings"
 	"unicode/utf8"
 
@@ -30,6 +33,7 @@ import (
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/expression"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
 	"github.com/zhukovaskychina/xmysql-server/server/mysql"
 )
 
@@ -140,12 +144,54 @@ func CastValues(ctx context.Context, rec []types.Datum, cols []*Column, ignoreEr
 	return nil
 }
 
+// tooLongAsTruncated replaces the Data Too Long warnings sc recorded after
+// its first from warnings by the Data truncated warning of the column name
+// at row.
+func tooLongAsTruncated(sc *variable.StatementContext, from int, name string, row uint64) {
+	warns := sc.GetWarnings()
+	if len(warns) <= from {
+		return
+	}
+	for i := from; i < len(warns); i++ {
+		if types.ErrDataTooLong.Equal(warns[i]) {
+			warns[i] = ErrWarnDataTruncated.GenByArgs(name, row)
+		}
+	}
+	sc.SetWarnings(warns)
+}
+
 // CastValue casts a value based on column type.
 func CastValue(ctx context.Context, val types.Datum, col *model.ColumnInfo) (casted types.Datum, err error) {
 	sc := ctx.GetSessionVars().StmtCtx
+	warns := len(sc.GetWarnings())
 	casted, err = val.ConvertTo(sc, &col.FieldType)
-	// TODO: make sure all truncate errors are handled by ConvertTo.
-	err = sc.HandleTruncate(err)
+	// Report overflow and over-length values against the column, the same way
+	// MySQL does under STRICT_TRANS_TABLES. Without a strict sql_mode the
+	// clamped or truncated value is kept and a warning is recorded instead.
+	row := sc.AffectedRows() + 1
+	switch {
+	case types.ErrOverflow.Equal(err) && col.Tp == mysql.TypeBit:
+		// A BIT value wider than the column is too long, kept as its largest
+		// value with a warning.
+		err = sc.HandleOverflow(ErrDataTooLong.GenByArgs(col.Name.O, row), ErrWarnDataOutOfRange.GenByArgs(col.Name.O, row))
+	case types.ErrOverflow.Equal(err):
+		outOfRange := ErrWarnDataOutOfRange.GenByArgs(col.Name.O, row)
+		err = sc.HandleOverflow(outOfRange, outOfRange)
+	case types.ErrDataTooLong.Equal(err):
+		err = ErrDataTooLong.GenByArgs(col.Name.O, row)
+	case err == nil:
+		// Without a strict sql_mode ConvertTo keeps the truncated string and
+		// records the generic Data Too Long, MySQL reports it as 1265 against
+		// the column.
+		tooLongAsTruncated(sc, warns, col.Name.O, row)
+	case types.ErrTruncated.Equal(err) && (col.Tp == mysql.TypeEnum || col.Tp == mysql.TypeSet):
+		// A value that isn't a member is stored as '', and a SET keeps the
+		// members it has.
+		err = sc.HandleTruncate(ErrWarnDataTruncated.GenByArgs(col.Name.O, row))
+	default:
+		// TODO: make sure all truncate errors are handled by ConvertTo.
+		err = sc.HandleTruncate(err)
+	}
 	if err != nil {
 		return casted, errors.Trace(err)
 	}
@@ -156,6 +202,9 @@ func CastValue(ctx context.Context, val types.Datum, col *model.ColumnInfo) (cas
 		return casted, nil
 	}
 	str := casted.GetString()
+	// utf8 (utf8mb3) stores at most 3 bytes per character, so supplementary
+	// characters such as emoji can only be stored in a utf8mb4 column.
+	mb3 := col.Charset == mysql.UTF8Charset
 	for i, r := range str {
 		if r == utf8.RuneError {
 			if strings.HasPrefix(str[i:], string(utf8.RuneError)) {
@@ -168,6 +217,13 @@ func CastValue(ctx context.Context, val types.Datum, col *model.ColumnInfo) (cas
 			err = sc.HandleTruncate(ErrTruncateWrongValue)
 			break
 		}
+		if mb3 && utf8.RuneLen(r) > 3 {
+			log.Errorf("[%d] incorrect utf8 value: %x for column %s",
+				ctx.GetSessionVars().ConnectionID, []byte(str), col.Name)
+			casted = types.NewStringDatum(str[:i])
+			err = sc.HandleTruncate(ErrTruncateWrongValue)
+			break
+		}
 	}
 	return casted, errors.Trace(err)
 }
@@ -254,6 +310,39 @@ func ColDescFieldNames(full bool) []string {
 	return []string{"Field", "Type", "Null", "Key", "Default", "Extra"}
 }
 
+// ShowColumnsRows returns the rows of SHOW [FULL] COLUMNS FROM tbl and of
+// DESCRIBE tbl, one per column in the order of the table, only the ones
+// whose name matches the LIKE pattern column when it isn't empty.
+func ShowColumnsRows(tbl Table, full bool, column string) [][]types.Datum {
+	var patChars, patTypes []byte
+	if column != "" {
+		patChars, patTypes = stringutil.CompilePattern(strings.ToLower(column), '\\')
+	}
+	var rows [][]types.Datum
+	for _, col := range tbl.Cols() {
+		if column != "" && !stringutil.DoMatch(col.Name.L, patChars, patTypes) {
+			continue
+		}
+		desc := NewColDesc(col)
+		var defaultValue interface{}
+		if desc.DefaultValue != nil {
+			defaultValue = fmt.Sprint(desc.DefaultValue)
+		}
+		if !full {
+			rows = append(rows, types.MakeDatums(desc.Field, desc.Type, desc.Null, desc.Key, defaultValue, desc.Extra))
+			continue
+		}
+		// Only the non-binary strings have a collation.
+		var collation interface{}
+		if types.IsNonBinaryStr(&col.FieldType) {
+			collation = desc.Collation
+		}
+		rows = append(rows, types.MakeDatums(desc.Field, desc.Type, collation, desc.Null, desc.Key, defaultValue,
+			desc.Extra, desc.Privileges, desc.Comment))
+	}
+	return rows
+}
+
 // CheckOnce checks if there are duplicated column names in cols.
 func CheckOnce(cols []*Column) error {
 	m := map[string]struct{}{}
@@ -273,7 +362,7 @@ func CheckOnce(cols []*Column) error {
 // CheckNotNull checks if nil value set to a column with NotNull flag is set.
 func (c *Column) CheckNotNull(data types.Datum) error {
 	if mysql.HasNotNullFlag(c.Flag) && data.IsNull() {
-		return errColumnCantNull.Gen("Column %s can't be null.", c.Name)
+		return errColumnCantNull.Gen("Column '%s' cannot be null", c.Name)
 	}
 	return nil
 }
@@ -339,12 +428,43 @@ func getColDefaultValueFromNil(ctx context.Context, col *model.ColumnInfo) (type
 	}
 	if !ctx.GetSessionVars().StrictSQLMode {
 		// Non strict mode use zero value.
-		// TODO: add warning.
+		ctx.GetSessionVars().StmtCtx.AppendWarning(errNoDefaultValue.Gen("Field '%s' doesn't have a default value", col.Name))
 		return GetZeroValue(col), nil
 	}
 	return types.Datum{}, errNoDefaultValue.Gen("Field '%s' doesn't have a default value", col.Name)
 }
 
+// Unflatten converts a value read back from the row format to the type of
+// col. ENUM and SET are stored as the ordinal of the member and the bitmask
+// of the members, and are given back with their names. BIT is stored as an
+// integer and given back as the bytes of its width.
+func Unflatten(d types.Datum, col *model.ColumnInfo) (types.Datum, error) {
+	if d.IsNull() {
+		return d, nil
+	}
+	switch col.Tp {
+	case mysql.TypeEnum:
+		var e types.Enum
+		// 0 is the '' stored for a value that isn't a member.
+		if v := d.GetUint64(); v != 0 {
+			var err error
+			if e, err = types.ParseEnumValue(col.Elems, v); err != nil {
+				return d, errors.Trace(err)
+			}
+		}
+		d.SetMysqlEnum(e)
+	case mysql.TypeSet:
+		s, err := types.ParseSetValue(col.Elems, d.GetUint64())
+		if err != nil {
+			return d, errors.Trace(err)
+		}
+		d.SetMysqlSet(s)
+	case mysql.TypeBit:
+		d.SetMysqlBit(types.NewBinaryLiteralFromUint(d.GetUint64(), (col.Flen+7)>>3))
+	}
+	return d, nil
+}
+
 // GetZeroValue gets zero value for given column type.
 func GetZeroValue(col *model.ColumnInfo) types.Datum {
 	var d types.Datum
@@ -0,0 +1,309 @@
+package schemas
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/juju/errors"
+	types "github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/varsutil"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/terror"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/codec"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/mock"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// newTestCtx returns a context set up the way an INSERT statement is run
+// under the given sql_mode.
+func newTestCtx(strict bool) *mock.Context {
+	ctx := mock.NewContext()
+	vars := ctx.GetSessionVars()
+	sqlMode := ""
+	if strict {
+		sqlMode = "STRICT_TRANS_TABLES"
+	}
+	if err := varsutil.SetSessionSystemVar(vars, variable.SQLModeVar, types.NewStringDatum(sqlMode)); err != nil {
+		panic(err)
+	}
+	vars.StmtCtx.InInsertStmt = true
+	vars.StmtCtx.TruncateAsWarning = !vars.StrictSQLMode
+	vars.StmtCtx.OverflowAsWarning = !vars.StrictSQLMode
+	return ctx
+}
+
+func newVarcharCol(flen int, cs string) *model.ColumnInfo {
+	col := &model.ColumnInfo{Name: model.NewCIStr("c")}
+	col.FieldType = *types.NewFieldType(mysql.TypeVarchar)
+	col.Flen = flen
+	col.Charset = cs
+	return col
+}
+
+func TestCastValueUTF8MB4CharacterLength(t *testing.T) {
+	// Four emoji are 4 characters but 16 bytes.
+	emoji := "\U0001F600\U0001F601\U0001F602\U0001F603"
+	col := newVarcharCol(4, mysql.UTF8MB4Charset)
+
+	casted, err := CastValue(newTestCtx(true), types.NewStringDatum(emoji), col)
+	if err != nil {
+		t.Fatalf("VARCHAR(4) should accept 4 emoji: %v", err)
+	}
+	if got := casted.GetString(); got != emoji || len(got) != 16 {
+		t.Fatalf("round trip mismatch: %q (%d bytes)", got, len(got))
+	}
+
+	_, err = CastValue(newTestCtx(true), types.NewStringDatum(emoji+"a"), col)
+	if err == nil {
+		t.Fatal("5 characters into VARCHAR(4) should fail in strict mode")
+	}
+
+	ctx := newTestCtx(false)
+	casted, err = CastValue(ctx, types.NewStringDatum("a"+emoji), col)
+	if err != nil {
+		t.Fatalf("non-strict mode should truncate, got %v", err)
+	}
+	if got := casted.GetString(); got != "a\U0001F600\U0001F601\U0001F602" {
+		t.Fatalf("truncated to %q, want whole characters", got)
+	}
+	if ctx.GetSessionVars().StmtCtx.WarningCount() != 1 {
+		t.Fatalf("expected one truncation warning, got %d", ctx.GetSessionVars().StmtCtx.WarningCount())
+	}
+}
+
+func TestCastValueUTF8RejectsSupplementaryCharacters(t *testing.T) {
+	col := newVarcharCol(32, mysql.UTF8Charset)
+
+	if _, err := CastValue(newTestCtx(true), types.NewStringDatum("ok é中"), col); err != nil {
+		t.Fatalf("BMP characters fit in utf8: %v", err)
+	}
+	if _, err := CastValue(newTestCtx(true), types.NewStringDatum("hi \U0001F600"), col); err == nil {
+		t.Fatal("emoji into a utf8 column should fail in strict mode")
+	}
+
+	casted, err := CastValue(newTestCtx(false), types.NewStringDatum("hi \U0001F600 there"), col)
+	if err != nil {
+		t.Fatalf("non-strict mode should truncate, got %v", err)
+	}
+	if got := casted.GetString(); got != "hi " {
+		t.Fatalf("truncated to %q", got)
+	}
+}
+
+func TestCastValueTooLongWarning(t *testing.T) {
+	ctx := newTestCtx(false)
+	if _, err := CastValue(ctx, types.NewStringDatum("abcdefgh"), newVarcharCol(5, mysql.UTF8MB4Charset)); err != nil {
+		t.Fatal(err)
+	}
+	warns := ctx.GetSessionVars().StmtCtx.GetWarnings()
+	if len(warns) != 1 {
+		t.Fatalf("expected one warning, got %v", warns)
+	}
+	if code := sqlErrCode(warns[0]); code != mysql.WarnDataTruncated {
+		t.Fatalf("expected warning %d, got %d (%v)", mysql.WarnDataTruncated, code, warns[0])
+	}
+	if msg := warns[0].Error(); !strings.Contains(msg, "Data truncated for column 'c' at row 1") {
+		t.Fatalf("unexpected warning %q", msg)
+	}
+}
+
+func newIntCol(tp byte, unsigned bool) *model.ColumnInfo {
+	col := &model.ColumnInfo{Name: model.NewCIStr("c")}
+	col.FieldType = *types.NewFieldType(tp)
+	if unsigned {
+		col.Flag |= mysql.UnsignedFlag
+	}
+	return col
+}
+
+func newEnumCol(tp byte, elems ...string) *model.ColumnInfo {
+	col := &model.ColumnInfo{Name: model.NewCIStr("c")}
+	col.FieldType = *types.NewFieldType(tp)
+	col.Elems = elems
+	return col
+}
+
+func newBitCol(width int) *model.ColumnInfo {
+	col := &model.ColumnInfo{Name: model.NewCIStr("c")}
+	col.FieldType = *types.NewFieldType(mysql.TypeBit)
+	col.Flen = width
+	col.Flag |= mysql.UnsignedFlag
+	return col
+}
+
+func sqlErrCode(err error) uint16 {
+	if e, ok := errors.Cause(err).(*terror.Error); ok {
+		return e.ToSQLError().Code
+	}
+	return 0
+}
+
+func TestCastValueStrictMode(t *testing.T) {
+	tests := []struct {
+		col    *model.ColumnInfo
+		val    types.Datum
+		code   uint16
+		result string
+	}{
+		{newIntCol(mysql.TypeTiny, false), types.NewIntDatum(300), mysql.ErrWarnDataOutOfRange, "127"},
+		{newIntCol(mysql.TypeTiny, false), types.NewIntDatum(-300), mysql.ErrWarnDataOutOfRange, "-128"},
+		{newIntCol(mysql.TypeTiny, true), types.NewIntDatum(300), mysql.ErrWarnDataOutOfRange, "255"},
+		{newIntCol(mysql.TypeLong, false), types.NewStringDatum("99999999999"), mysql.ErrWarnDataOutOfRange, "2147483647"},
+		{newVarcharCol(5, mysql.UTF8MB4Charset), types.NewStringDatum("abcdefgh"), mysql.ErrDataTooLong, "abcde"},
+		{newVarcharCol(2, mysql.DefaultCharset), types.NewStringDatum("中文字"), mysql.ErrDataTooLong, "中文"},
+		{newEnumCol(mysql.TypeEnum, "N", "Y"), types.NewStringDatum("X"), mysql.WarnDataTruncated, ""},
+		{newEnumCol(mysql.TypeEnum, "N", "Y"), types.NewIntDatum(3), mysql.WarnDataTruncated, ""},
+		{newEnumCol(mysql.TypeSet, "a", "b"), types.NewStringDatum("b,x"), mysql.WarnDataTruncated, "b"},
+		{newBitCol(8), types.NewIntDatum(300), mysql.ErrDataTooLong, "\xff"},
+		{newBitCol(4), types.NewStringDatum("a"), mysql.ErrDataTooLong, "\x0f"},
+	}
+	for i, tt := range tests {
+		_, err := CastValue(newTestCtx(true), tt.val, tt.col)
+		if err == nil {
+			t.Fatalf("#%d: strict mode should reject the value", i)
+		}
+		if code := sqlErrCode(err); code != tt.code {
+			t.Fatalf("#%d: got error code %d (%v), want %d", i, code, err, tt.code)
+		}
+
+		ctx := newTestCtx(false)
+		casted, err := CastValue(ctx, tt.val, tt.col)
+		if err != nil {
+			t.Fatalf("#%d: non-strict mode should not fail: %v", i, err)
+		}
+		if got, _ := casted.ToString(); got != tt.result {
+			t.Fatalf("#%d: got %q, want %q", i, got, tt.result)
+		}
+		if ctx.GetSessionVars().StmtCtx.WarningCount() != 1 {
+			t.Fatalf("#%d: expected one warning, got %d", i, ctx.GetSessionVars().StmtCtx.WarningCount())
+		}
+	}
+}
+
+func TestEnumSetValues(t *testing.T) {
+	enum := newEnumCol(mysql.TypeEnum, "small", "medium", "large")
+	set := newEnumCol(mysql.TypeSet, "a", "b", "c")
+	tests := []struct {
+		col    *model.ColumnInfo
+		val    types.Datum
+		name   string
+		number uint64
+	}{
+		{enum, types.NewStringDatum("MEDIUM"), "medium", 2},
+		{enum, types.NewIntDatum(3), "large", 3},
+		{enum, types.NewStringDatum("1"), "small", 1},
+		{set, types.NewStringDatum("c,a"), "a,c", 5},
+		{set, types.NewIntDatum(2), "b", 2},
+		{set, types.NewStringDatum(""), "", 0},
+	}
+	for _, tt := range tests {
+		casted, err := CastValue(newTestCtx(true), tt.val, tt.col)
+		if err != nil {
+			t.Fatalf("%v: %v", tt.val, err)
+		}
+		if name, _ := casted.ToString(); name != tt.name {
+			t.Fatalf("%v: got %q, want %q", tt.val, name, tt.name)
+		}
+
+		// The row keeps the ordinal or bitmask, read back as the name.
+		b, err := codec.EncodeValue(nil, casted)
+		if err != nil {
+			t.Fatal(err)
+		}
+		row, err := codec.Decode(b, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if row[0].GetUint64() != tt.number {
+			t.Fatalf("%v: stored %d, want %d", tt.val, row[0].GetUint64(), tt.number)
+		}
+		d, err := Unflatten(row[0], tt.col)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name, _ := d.ToString(); name != tt.name {
+			t.Fatalf("%v: read back %q, want %q", tt.val, name, tt.name)
+		}
+	}
+
+	// Values compare by ordinal, and by name against a string.
+	sc := newTestCtx(true).GetSessionVars().StmtCtx
+	large, _ := CastValue(newTestCtx(true), types.NewStringDatum("large"), enum)
+	small, _ := CastValue(newTestCtx(true), types.NewStringDatum("small"), enum)
+	if cmp, _ := large.CompareDatum(sc, &small); cmp <= 0 {
+		t.Fatal("expect large to sort after small")
+	}
+	str := types.NewStringDatum("large")
+	if cmp, _ := small.CompareDatum(sc, &str); cmp <= 0 {
+		t.Fatal("expect small to compare after the string large")
+	}
+
+	if str := enum.FieldType.InfoSchemaStr(); str != "enum('small','medium','large')" {
+		t.Fatalf("unexpected type %s", str)
+	}
+	if str := set.FieldType.InfoSchemaStr(); str != "set('a','b','c')" {
+		t.Fatalf("unexpected type %s", str)
+	}
+}
+
+func TestBitSetValues(t *testing.T) {
+	bit := newBitCol(8)
+	set := newEnumCol(mysql.TypeSet, "read", "write", "exec")
+	bits, err := types.ParseBitStr("b'10100101'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		col    *model.ColumnInfo
+		val    types.Datum
+		number int64
+		str    string
+	}{
+		{bit, types.NewIntDatum(5), 5, "\x05"},
+		{bit, types.NewBinaryLiteralDatum(bits), 165, "\xa5"},
+		{bit, types.NewStringDatum("a"), 97, "a"},
+		{bit, types.NewIntDatum(0), 0, "\x00"},
+		{newBitCol(12), types.NewIntDatum(4095), 4095, "\x0f\xff"},
+		{set, types.NewStringDatum("exec,read"), 5, "read,exec"},
+		{set, types.NewStringDatum("write,read,exec"), 7, "read,write,exec"},
+		{set, types.NewIntDatum(2), 2, "write"},
+	}
+	sc := newTestCtx(true).GetSessionVars().StmtCtx
+	for _, tt := range tests {
+		casted, err := CastValue(newTestCtx(true), tt.val, tt.col)
+		if err != nil {
+			t.Fatalf("%v: %v", tt.val, err)
+		}
+		b, err := codec.EncodeValue(nil, casted)
+		if err != nil {
+			t.Fatal(err)
+		}
+		row, err := codec.Decode(b, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d, err := Unflatten(row[0], tt.col)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Read back both as an integer, as in c+0, and as a string.
+		if number, err := d.ToInt64(sc); err != nil || number != tt.number {
+			t.Fatalf("%v: read back %d (%v), want %d", tt.val, number, err, tt.number)
+		}
+		if str, _ := d.ToString(); str != tt.str {
+			t.Fatalf("%v: read back %q, want %q", tt.val, str, tt.str)
+		}
+	}
+
+	// A value that isn't a member, or wider than the column, is rejected.
+	if _, err := CastValue(newTestCtx(true), types.NewStringDatum("read,delete"), set); sqlErrCode(err) != mysql.WarnDataTruncated {
+		t.Fatalf("expect error %d, got %v", mysql.WarnDataTruncated, err)
+	}
+	if _, err := CastValue(newTestCtx(true), types.NewIntDatum(256), bit); sqlErrCode(err) != mysql.ErrDataTooLong {
+		t.Fatalf("expect error %d, got %v", mysql.ErrDataTooLong, err)
+	}
+	if str := bit.FieldType.InfoSchemaStr(); str != "bit(8)" {
+		t.Fatalf("unexpected type %s", str)
+	}
+}
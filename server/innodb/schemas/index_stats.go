@@ -0,0 +1,104 @@
+package schemas
+
+import (
+	"sync/atomic"
+
+	types "github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// IndexStats is the source of the cardinality of the indexes, the statistics
+// kept by ANALYZE TABLE.
+type IndexStats interface {
+	// IndexCardinality returns the number of distinct values of the first
+	// seq columns of idx in tbl, or of its integer primary key when idx is
+	// nil. It is false when they have not been analyzed.
+	IndexCardinality(tbl *model.TableInfo, idx *model.IndexInfo, seq int) (int64, bool)
+}
+
+// IndexRowsEstimator is implemented by the tables that can estimate the
+// number of records of an index from the pages of its B+tree.
+type IndexRowsEstimator interface {
+	EstimateIndexRows(indexName string) (int64, bool)
+}
+
+var indexStats atomic.Value
+
+// RegisterIndexStats sets the statistics SHOW INDEX and
+// information_schema.STATISTICS read the cardinality from.
+func RegisterIndexStats(stats IndexStats) {
+	indexStats.Store(&stats)
+}
+
+// ShowIndexRows returns the rows of SHOW INDEX FROM tbl, one per column of
+// each index, the primary key first.
+func ShowIndexRows(tbl Table) [][]types.Datum {
+	meta := tbl.Meta()
+	var rows [][]types.Datum
+	if meta.PKIsHandle {
+		if pk := meta.GetPkColInfo(); pk != nil {
+			rows = append(rows, types.MakeDatums(meta.Name.O, 0, mysql.PrimaryKeyName, 1, pk.Name.O, "A",
+				indexCardinality(tbl, nil, 1), nil, nil, "", "BTREE", "", ""))
+		}
+	}
+	indices := make([]*model.IndexInfo, 0, len(meta.Indices))
+	for _, idx := range meta.Indices {
+		if idx.State == model.StatePublic && idx.Primary {
+			indices = append(indices, idx)
+		}
+	}
+	for _, idx := range meta.Indices {
+		if idx.State == model.StatePublic && !idx.Primary {
+			indices = append(indices, idx)
+		}
+	}
+	for _, idx := range indices {
+		nonUnique := 1
+		if idx.Primary || idx.Unique {
+			nonUnique = 0
+		}
+		for i, col := range idx.Columns {
+			var subPart interface{}
+			if col.Length != types.UnspecifiedLength {
+				subPart = col.Length
+			}
+			null := ""
+			if !idx.Primary && col.Offset < len(meta.Columns) {
+				null = nullable(meta.Columns[col.Offset])
+			}
+			rows = append(rows, types.MakeDatums(meta.Name.O, nonUnique, idx.Name.O, i+1, col.Name.O, "A",
+				indexCardinality(tbl, idx, i+1), subPart, nil, null, "BTREE", "", idx.Comment))
+		}
+	}
+	return rows
+}
+
+// indexCardinality returns the cardinality of the first seq columns of idx
+// of tbl: the analyzed one, else the number of records of the index
+// estimated from its pages, else NULL.
+func indexCardinality(tbl Table, idx *model.IndexInfo, seq int) interface{} {
+	meta := tbl.Meta()
+	if stats, ok := indexStats.Load().(*IndexStats); ok && *stats != nil {
+		if card, ok := (*stats).IndexCardinality(meta, idx, seq); ok {
+			return card
+		}
+	}
+	if estimator, ok := tbl.(IndexRowsEstimator); ok {
+		name := mysql.PrimaryKeyName
+		if idx != nil {
+			name = idx.Name.O
+		}
+		if rows, ok := estimator.EstimateIndexRows(name); ok {
+			return rows
+		}
+	}
+	return nil
+}
+
+func nullable(col *model.ColumnInfo) string {
+	if mysql.HasNotNullFlag(col.Flag) {
+		return ""
+	}
+	return "YES"
+}
@@ -1,5 +1,12 @@
 package schemas
 
+import (
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	types "github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
 //定义MySQL的information_schemas 用于管理各个
 //type InfoSchemas interface {
 //
@@ -21,3 +28,354 @@ package schemas
 //
 //	PutDatabaseCache(datacache Database)
 //}
+
+// InformationSchemaName is the name of the database describing the server.
+var InformationSchemaName = model.NewCIStr("information_schema")
+
+// memTableDefs are the tables of information_schema whose rows are made up
+// from the state of the server when they are read, instead of being kept
+// in pages.
+var memTableDefs = map[string]*model.TableInfo{
+	"optimizer_trace": newMemTableInfo("OPTIMIZER_TRACE", []memColumn{
+		{"QUERY", mysql.TypeLongBlob, 0, false},
+		{"TRACE", mysql.TypeLongBlob, 0, false},
+		{"MISSING_BYTES_BEYOND_MAX_MEM_SIZE", mysql.TypeLong, 20, false},
+		{"INSUFFICIENT_PRIVILEGES", mysql.TypeTiny, 1, false},
+	}),
+	"key_column_usage": newMemTableInfo("KEY_COLUMN_USAGE", []memColumn{
+		{"CONSTRAINT_CATALOG", mysql.TypeVarchar, 512, false},
+		{"CONSTRAINT_SCHEMA", mysql.TypeVarchar, 64, false},
+		{"CONSTRAINT_NAME", mysql.TypeVarchar, 64, false},
+		{"TABLE_CATALOG", mysql.TypeVarchar, 512, false},
+		{"TABLE_SCHEMA", mysql.TypeVarchar, 64, false},
+		{"TABLE_NAME", mysql.TypeVarchar, 64, false},
+		{"COLUMN_NAME", mysql.TypeVarchar, 64, false},
+		{"ORDINAL_POSITION", mysql.TypeLonglong, 10, false},
+		{"POSITION_IN_UNIQUE_CONSTRAINT", mysql.TypeLonglong, 10, true},
+		{"REFERENCED_TABLE_SCHEMA", mysql.TypeVarchar, 64, true},
+		{"REFERENCED_TABLE_NAME", mysql.TypeVarchar, 64, true},
+		{"REFERENCED_COLUMN_NAME", mysql.TypeVarchar, 64, true},
+	}),
+	"referential_constraints": newMemTableInfo("REFERENTIAL_CONSTRAINTS", []memColumn{
+		{"CONSTRAINT_CATALOG", mysql.TypeVarchar, 512, false},
+		{"CONSTRAINT_SCHEMA", mysql.TypeVarchar, 64, false},
+		{"CONSTRAINT_NAME", mysql.TypeVarchar, 64, false},
+		{"UNIQUE_CONSTRAINT_CATALOG", mysql.TypeVarchar, 512, false},
+		{"UNIQUE_CONSTRAINT_SCHEMA", mysql.TypeVarchar, 64, false},
+		{"UNIQUE_CONSTRAINT_NAME", mysql.TypeVarchar, 64, true},
+		{"MATCH_OPTION", mysql.TypeVarchar, 64, false},
+		{"UPDATE_RULE", mysql.TypeVarchar, 64, false},
+		{"DELETE_RULE", mysql.TypeVarchar, 64, false},
+		{"TABLE_NAME", mysql.TypeVarchar, 64, false},
+		{"REFERENCED_TABLE_NAME", mysql.TypeVarchar, 64, false},
+	}),
+	"tables": newMemTableInfo("TABLES", []memColumn{
+		{"TABLE_CATALOG", mysql.TypeVarchar, 512, false},
+		{"TABLE_SCHEMA", mysql.TypeVarchar, 64, false},
+		{"TABLE_NAME", mysql.TypeVarchar, 64, false},
+		{"TABLE_TYPE", mysql.TypeVarchar, 64, false},
+		{"ENGINE", mysql.TypeVarchar, 64, true},
+		{"VERSION", mysql.TypeLonglong, 21, true},
+		{"ROW_FORMAT", mysql.TypeVarchar, 10, true},
+		{"TABLE_ROWS", mysql.TypeLonglong, 21, true},
+		{"AVG_ROW_LENGTH", mysql.TypeLonglong, 21, true},
+		{"DATA_LENGTH", mysql.TypeLonglong, 21, true},
+		{"MAX_DATA_LENGTH", mysql.TypeLonglong, 21, true},
+		{"INDEX_LENGTH", mysql.TypeLonglong, 21, true},
+		{"DATA_FREE", mysql.TypeLonglong, 21, true},
+		{"AUTO_INCREMENT", mysql.TypeLonglong, 21, true},
+		{"CREATE_TIME", mysql.TypeDatetime, 0, true},
+		{"UPDATE_TIME", mysql.TypeDatetime, 0, true},
+		{"CHECK_TIME", mysql.TypeDatetime, 0, true},
+		{"TABLE_COLLATION", mysql.TypeVarchar, 32, true},
+		{"CHECKSUM", mysql.TypeLonglong, 21, true},
+		{"CREATE_OPTIONS", mysql.TypeVarchar, 255, true},
+		{"TABLE_COMMENT", mysql.TypeVarchar, 2048, false},
+	}),
+	"statistics": newMemTableInfo("STATISTICS", []memColumn{
+		{"TABLE_CATALOG", mysql.TypeVarchar, 512, false},
+		{"TABLE_SCHEMA", mysql.TypeVarchar, 64, false},
+		{"TABLE_NAME", mysql.TypeVarchar, 64, false},
+		{"NON_UNIQUE", mysql.TypeLonglong, 1, false},
+		{"INDEX_SCHEMA", mysql.TypeVarchar, 64, false},
+		{"INDEX_NAME", mysql.TypeVarchar, 64, false},
+		{"SEQ_IN_INDEX", mysql.TypeLonglong, 2, false},
+		{"COLUMN_NAME", mysql.TypeVarchar, 64, false},
+		{"COLLATION", mysql.TypeVarchar, 1, true},
+		{"CARDINALITY", mysql.TypeLonglong, 21, true},
+		{"SUB_PART", mysql.TypeLonglong, 3, true},
+		{"PACKED", mysql.TypeVarchar, 10, true},
+		{"NULLABLE", mysql.TypeVarchar, 3, false},
+		{"INDEX_TYPE", mysql.TypeVarchar, 16, false},
+		{"COMMENT", mysql.TypeVarchar, 16, true},
+		{"INDEX_COMMENT", mysql.TypeVarchar, 1024, false},
+	}),
+	"xmysql_stats_auto_recalc": newMemTableInfo("XMYSQL_STATS_AUTO_RECALC", []memColumn{
+		{"TABLE_SCHEMA", mysql.TypeVarchar, 64, false},
+		{"TABLE_NAME", mysql.TypeVarchar, 64, false},
+		{"STATE", mysql.TypeVarchar, 16, false},
+		{"MODIFIED_ROWS", mysql.TypeLonglong, 21, false},
+		{"LAST_RECALC", mysql.TypeDatetime, 0, true},
+		{"SAMPLED_ROWS", mysql.TypeLonglong, 21, true},
+		{"TABLE_ROWS", mysql.TypeLonglong, 21, true},
+		{"LAST_ERROR", mysql.TypeVarchar, 512, true},
+	}),
+	"innodb_buffer_page": newMemTableInfo("INNODB_BUFFER_PAGE", []memColumn{
+		{"POOL_ID", mysql.TypeLonglong, 21, false},
+		{"SPACE", mysql.TypeLonglong, 21, false},
+		{"PAGE_NUMBER", mysql.TypeLonglong, 21, false},
+		{"PAGE_TYPE", mysql.TypeVarchar, 64, false},
+		{"TABLE_NAME", mysql.TypeVarchar, 1024, true},
+		{"INDEX_NAME", mysql.TypeVarchar, 1024, true},
+		{"DATA_SIZE", mysql.TypeLonglong, 21, false},
+		{"IS_DIRTY", mysql.TypeVarchar, 3, false},
+		{"IS_OLD", mysql.TypeVarchar, 3, false},
+	}),
+	"innodb_buffer_pool_stats_by_table": newMemTableInfo("INNODB_BUFFER_POOL_STATS_BY_TABLE", []memColumn{
+		{"TABLE_NAME", mysql.TypeVarchar, 1024, true},
+		{"PAGES", mysql.TypeLonglong, 21, false},
+		{"DIRTY_PAGES", mysql.TypeLonglong, 21, false},
+		{"DATA_SIZE", mysql.TypeLonglong, 21, false},
+		{"PERCENT_OF_POOL", mysql.TypeDouble, 0, false},
+	}),
+	"innodb_buffer_pool_stats_by_index": newMemTableInfo("INNODB_BUFFER_POOL_STATS_BY_INDEX", []memColumn{
+		{"TABLE_NAME", mysql.TypeVarchar, 1024, true},
+		{"INDEX_NAME", mysql.TypeVarchar, 1024, true},
+		{"PAGES", mysql.TypeLonglong, 21, false},
+		{"DIRTY_PAGES", mysql.TypeLonglong, 21, false},
+		{"DATA_SIZE", mysql.TypeLonglong, 21, false},
+		{"PERCENT_OF_POOL", mysql.TypeDouble, 0, false},
+	}),
+}
+
+type memColumn struct {
+	name     string
+	tp       byte
+	flen     int
+	nullable bool
+}
+
+func newMemTableInfo(name string, cols []memColumn) *model.TableInfo {
+	info := &model.TableInfo{Name: model.NewCIStr(name), State: model.StatePublic}
+	for i, c := range cols {
+		col := &model.ColumnInfo{
+			ID:     int64(i + 1),
+			Name:   model.NewCIStr(c.name),
+			Offset: i,
+			State:  model.StatePublic,
+		}
+		col.FieldType = *types.NewFieldType(c.tp)
+		if c.flen > 0 {
+			col.Flen = c.flen
+		}
+		if !c.nullable {
+			col.Flag = mysql.NotNullFlag
+		}
+		info.Columns = append(info.Columns, col)
+	}
+	info.MaxColumnID = int64(len(info.Columns))
+	return info
+}
+
+// MemTable is the Table of an information_schema table made up by the
+// server. Like a View, it has no pages and only describes its columns.
+type MemTable struct {
+	Table
+	meta *model.TableInfo
+	cols []*Column
+}
+
+// Meta implements Table Meta interface.
+func (t *MemTable) Meta() *model.TableInfo {
+	return t.meta
+}
+
+// TableName implements Table TableName interface.
+func (t *MemTable) TableName() string {
+	return t.meta.Name.O
+}
+
+// ColNums implements Table ColNums interface.
+func (t *MemTable) ColNums() int {
+	return len(t.cols)
+}
+
+// CheckFieldName implements Table CheckFieldName interface.
+func (t *MemTable) CheckFieldName(fieldName string) bool {
+	return FindCol(t.cols, fieldName) != nil
+}
+
+// Cols implements Table Cols interface.
+func (t *MemTable) Cols() []*Column {
+	return t.cols
+}
+
+// WritableCols implements Table WritableCols interface. The tables of
+// information_schema can't be written to.
+func (t *MemTable) WritableCols() []*Column {
+	return nil
+}
+
+// IsMemTable returns whether schema.table is made up by the server.
+func IsMemTable(schema, table model.CIStr) bool {
+	return memTableDef(schema, table) != nil
+}
+
+// memTableDef returns the definition of schema.table when it is made up by
+// the server, nil otherwise.
+func memTableDef(schema, table model.CIStr) *model.TableInfo {
+	switch schema.L {
+	case InformationSchemaName.L:
+		return memTableDefs[table.L]
+	case PerformanceSchemaName.L:
+		return performanceSchemaTableDefs[table.L]
+	}
+	return nil
+}
+
+// TableByName returns the table schema.table of is, or the information_schema
+// table of that name made up by the server.
+func TableByName(is InfoSchema, schema, table model.CIStr) (Table, error) {
+	if meta := memTableDef(schema, table); meta != nil {
+		cols := make([]*Column, 0, len(meta.Columns))
+		for _, col := range meta.Columns {
+			cols = append(cols, ToColumn(col))
+		}
+		return &MemTable{meta: meta, cols: cols}, nil
+	}
+	return is.TableByName(schema, table)
+}
+
+// catalogName is the only catalog, "def".
+const catalogName = "def"
+
+// KeyColumnUsageRows returns the rows of information_schema.KEY_COLUMN_USAGE:
+// the columns of the primary keys, unique keys and foreign keys of the
+// tables of is.
+func KeyColumnUsageRows(is InfoSchema) [][]types.Datum {
+	var rows [][]types.Datum
+	forEachTable(is, func(db model.CIStr, t Table) {
+		tbl := t.Meta()
+		if tbl.PKIsHandle {
+			if pk := tbl.GetPkColInfo(); pk != nil {
+				rows = append(rows, types.MakeDatums(catalogName, db.O, mysql.PrimaryKeyName,
+					catalogName, db.O, tbl.Name.O, pk.Name.O, 1, nil, nil, nil, nil))
+			}
+		}
+		for _, idx := range tbl.Indices {
+			if !idx.Primary && !idx.Unique {
+				continue
+			}
+			for i, col := range idx.Columns {
+				rows = append(rows, types.MakeDatums(catalogName, db.O, idx.Name.O,
+					catalogName, db.O, tbl.Name.O, col.Name.O, i+1, nil, nil, nil, nil))
+			}
+		}
+		for _, fk := range tbl.ForeignKeys {
+			refSchema := fkRefSchema(db, fk)
+			for i, col := range fk.Cols {
+				rows = append(rows, types.MakeDatums(catalogName, db.O, fk.Name.O,
+					catalogName, db.O, tbl.Name.O, col.O, i+1, i+1, refSchema.O, fk.RefTable.O, fk.RefCols[i].O))
+			}
+		}
+	})
+	return rows
+}
+
+// ReferentialConstraintsRows returns the rows of
+// information_schema.REFERENTIAL_CONSTRAINTS, one per foreign key of the
+// tables of is.
+func ReferentialConstraintsRows(is InfoSchema) [][]types.Datum {
+	var rows [][]types.Datum
+	forEachTable(is, func(db model.CIStr, t Table) {
+		tbl := t.Meta()
+		for _, fk := range tbl.ForeignKeys {
+			refSchema := fkRefSchema(db, fk)
+			// The unique constraint is the key of the referenced columns.
+			var uniqueName interface{}
+			if refTbl, err := is.TableByName(refSchema, fk.RefTable); err == nil && refTbl != nil {
+				if name := referencedKeyName(refTbl.Meta(), fk.RefCols); name != "" {
+					uniqueName = name
+				}
+			}
+			rows = append(rows, types.MakeDatums(catalogName, db.O, fk.Name.O,
+				catalogName, refSchema.O, uniqueName, "NONE",
+				referRule(fk.OnUpdate), referRule(fk.OnDelete), tbl.Name.O, fk.RefTable.O))
+		}
+	})
+	return rows
+}
+
+// StatisticsRows returns the rows of information_schema.STATISTICS, the
+// rows of SHOW INDEX of every table of is.
+func StatisticsRows(is InfoSchema) [][]types.Datum {
+	var rows [][]types.Datum
+	forEachTable(is, func(db model.CIStr, tbl Table) {
+		for _, r := range ShowIndexRows(tbl) {
+			rows = append(rows, []types.Datum{types.NewStringDatum(catalogName), types.NewStringDatum(db.O),
+				r[0], r[1], types.NewStringDatum(db.O), r[2], r[3], r[4], r[5], r[6], r[7], r[8], r[9], r[10], r[11], r[12]})
+		}
+	})
+	return rows
+}
+
+func hasAutoIncrementColumn(tbl *model.TableInfo) bool {
+	for _, col := range tbl.Columns {
+		if mysql.HasAutoIncrementFlag(col.Flag) {
+			return true
+		}
+	}
+	return false
+}
+
+func forEachTable(is InfoSchema, fn func(db model.CIStr, tbl Table)) {
+	for _, db := range is.AllSchemas() {
+		for _, tbl := range is.SchemaTables(db.Name) {
+			if meta := tbl.Meta(); meta != nil && !meta.IsView() {
+				fn(db.Name, tbl)
+			}
+		}
+	}
+}
+
+func fkRefSchema(db model.CIStr, fk *model.FKInfo) model.CIStr {
+	if fk.RefSchema.L != "" {
+		return fk.RefSchema
+	}
+	return db
+}
+
+// referencedKeyName returns the name of the primary or unique key on cols
+// of tbl, empty if there is none.
+func referencedKeyName(tbl *model.TableInfo, cols []model.CIStr) string {
+	if tbl.PKIsHandle && len(cols) == 1 {
+		if pk := tbl.GetPkColInfo(); pk != nil && pk.Name.L == cols[0].L {
+			return mysql.PrimaryKeyName
+		}
+	}
+	for _, idx := range tbl.Indices {
+		if (!idx.Primary && !idx.Unique) || len(idx.Columns) != len(cols) {
+			continue
+		}
+		match := true
+		for i, col := range idx.Columns {
+			if col.Name.L != cols[i].L {
+				match = false
+				break
+			}
+		}
+		if match {
+			return idx.Name.O
+		}
+	}
+	return ""
+}
+
+// referRule returns the UPDATE_RULE or DELETE_RULE of a foreign key action,
+// RESTRICT when none is given.
+func referRule(opt int) string {
+	if opt := ast.ReferOptionType(opt); opt != ast.ReferOptionNoOption {
+		return opt.String()
+	}
+	return ast.ReferOptionRestrict.String()
+}
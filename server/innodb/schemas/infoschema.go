@@ -14,6 +14,7 @@
 package schemas
 
 import (
+	types "github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/terror"
 	"github.com/zhukovaskychina/xmysql-server/server/mysql"
@@ -48,6 +49,12 @@ var (
 	ErrMultiplePriKey = terror.ClassSchema.New(codeMultiplePriKey, "Multiple primary key defined")
 	// ErrTooManyKeyParts returns for too many key parts.
 	ErrTooManyKeyParts = terror.ClassSchema.New(codeTooManyKeyParts, "Too many key parts specified; max %d parts allowed")
+	// ErrWrongObject returns when a table is used as a view or the other way round.
+	ErrWrongObject = terror.ClassSchema.New(codeWrongObject, mysql.MySQLErrName[mysql.ErrWrongObject])
+	// ErrViewWrongList returns for a view column list not matching its SELECT.
+	ErrViewWrongList = terror.ClassSchema.New(codeViewWrongList, mysql.MySQLErrName[mysql.ErrViewWrongList])
+	// ErrViewInvalid returns for a view whose SELECT can't be planned any more.
+	ErrViewInvalid = terror.ClassSchema.New(codeViewInvalid, mysql.MySQLErrName[mysql.ErrViewInvalid])
 )
 
 // InfoSchema is the interface used to retrieve the schema information.
@@ -90,6 +97,32 @@ type InfoSchema interface {
 	AllocByID(id int64) (Allocator, bool)
 
 	SchemaMetaVersion() int64
+
+	// CreateView adds view to schema, replacing the view of the same name
+	// when orReplace is set.
+	CreateView(schema model.CIStr, view *model.TableInfo, orReplace bool) error
+
+	// DropView removes view from schema.
+	DropView(schema, view model.CIStr) error
+
+	// RenameTables applies renames in order, all of them or none.
+	RenameTables(renames []TableRename) error
+
+	// AlterTableRowFormat rebuilds table in rowFormat, in
+	// innodb_default_row_format when it is empty.
+	AlterTableRowFormat(schema, table model.CIStr, rowFormat string) error
+
+	// AlterTableColumns replaces the definition of table with tbl at once.
+	// Unless rows is nil, it also replaces the rows of the table with rows,
+	// in the order of the columns of tbl, and rebuilds its indexes from them.
+	AlterTableColumns(schema, table model.CIStr, tbl *model.TableInfo, rows [][]types.Datum) error
+}
+
+// TableRename renames the table or view OldSchema.OldName to
+// NewSchema.NewName.
+type TableRename struct {
+	OldSchema, OldName model.CIStr
+	NewSchema, NewName model.CIStr
 }
 
 // Information Schema Name.
@@ -214,6 +247,10 @@ const (
 	codeMultiplePriKey = 1068
 
 	codeTooManyKeyParts = 1070
+
+	codeWrongObject   = 1347
+	codeViewWrongList = 1353
+	codeViewInvalid   = 1356
 )
 
 func init() {
@@ -231,6 +268,9 @@ func init() {
 		codeColumnExists:        mysql.ErrDupFieldName,
 		codeIndexExists:         mysql.ErrDupIndex,
 		codeMultiplePriKey:      mysql.ErrMultiplePriKey,
+		codeWrongObject:         mysql.ErrWrongObject,
+		codeViewWrongList:       mysql.ErrViewWrongList,
+		codeViewInvalid:         mysql.ErrViewInvalid,
 	}
 	terror.ErrClassToMySQLCodes[terror.ClassSchema] = schemaMySQLErrCodes
 }
@@ -1,4 +1,85 @@
 package schemas
 
+import (
+	types "github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/memory"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
 type PerformanceSchemas interface {
 }
+
+// PerformanceSchemaName is the name of the database of the server
+// instrumentation.
+var PerformanceSchemaName = model.NewCIStr("performance_schema")
+
+// performanceSchemaTableDefs are the tables of performance_schema made up
+// by the server, like memTableDefs.
+var performanceSchemaTableDefs = map[string]*model.TableInfo{
+	"persisted_variables": newMemTableInfo("persisted_variables", []memColumn{
+		{"VARIABLE_NAME", mysql.TypeVarchar, 64, false},
+		{"VARIABLE_VALUE", mysql.TypeVarchar, 1024, true},
+	}),
+	"memory_summary_by_thread_by_event_name": newMemTableInfo("memory_summary_by_thread_by_event_name",
+		append([]memColumn{{"THREAD_ID", mysql.TypeLonglong, 20, false}}, memorySummaryColumns...)),
+	"memory_summary_global_by_event_name": newMemTableInfo("memory_summary_global_by_event_name", memorySummaryColumns),
+}
+
+// memorySummaryColumns are the columns of the memory summary tables besides
+// THREAD_ID.
+var memorySummaryColumns = []memColumn{
+	{"EVENT_NAME", mysql.TypeVarchar, 128, false},
+	{"COUNT_ALLOC", mysql.TypeLonglong, 20, false},
+	{"COUNT_FREE", mysql.TypeLonglong, 20, false},
+	{"SUM_NUMBER_OF_BYTES_ALLOC", mysql.TypeLonglong, 20, false},
+	{"SUM_NUMBER_OF_BYTES_FREE", mysql.TypeLonglong, 20, false},
+	{"CURRENT_COUNT_USED", mysql.TypeLonglong, 20, false},
+	{"CURRENT_NUMBER_OF_BYTES_USED", mysql.TypeLonglong, 20, false},
+	{"HIGH_NUMBER_OF_BYTES_USED", mysql.TypeLonglong, 20, false},
+}
+
+// MemoryEventName is the instrument of the memory the statements take, the
+// only one the memory summary tables show.
+const MemoryEventName = "memory/sql/statement"
+
+// memorySummaryRow returns the values of the memory summary columns of what
+// a tracker consumed.
+func memorySummaryRow(s memory.Summary) []interface{} {
+	return []interface{}{MemoryEventName, s.CountAlloc, s.CountFree, s.BytesAlloc, s.BytesFree,
+		s.CountAlloc - s.CountFree, s.BytesConsumed, s.MaxConsumed}
+}
+
+// MemorySummaryByThreadRows returns the rows of
+// performance_schema.memory_summary_by_thread_by_event_name, one per session
+// of a client, THREAD_ID being its connection id.
+func MemorySummaryByThreadRows(sessions []*variable.SessionVars) [][]types.Datum {
+	rows := make([][]types.Datum, 0, len(sessions))
+	for _, vars := range sessions {
+		values := append([]interface{}{int64(vars.ConnectionID)}, memorySummaryRow(vars.MemTracker.Summary())...)
+		rows = append(rows, types.MakeDatums(values...))
+	}
+	return rows
+}
+
+// MemorySummaryGlobalRows returns the rows of
+// performance_schema.memory_summary_global_by_event_name, what the
+// statements of all the sessions consumed.
+func MemorySummaryGlobalRows() [][]types.Datum {
+	return [][]types.Datum{types.MakeDatums(memorySummaryRow(memory.GlobalTracker.Summary())...)}
+}
+
+// PersistedVariablesRows returns the rows of
+// performance_schema.persisted_variables, one per variable set with SET
+// PERSIST.
+func PersistedVariablesRows(p *variable.PersistedVariables) [][]types.Datum {
+	if p == nil {
+		return nil
+	}
+	var rows [][]types.Datum
+	for _, v := range p.Variables() {
+		rows = append(rows, types.MakeDatums(v.Name, v.Value))
+	}
+	return rows
+}
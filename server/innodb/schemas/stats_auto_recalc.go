@@ -0,0 +1,64 @@
+package schemas
+
+import (
+	"sync/atomic"
+	"time"
+
+	types "github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+)
+
+// StatsRecalcState is the state of the automatic recalculation of the
+// statistics of a table.
+type StatsRecalcState struct {
+	DB, Table string
+	// State is PENDING while the table waits for its turn, RUNNING while
+	// its statistics are rebuilt and IDLE otherwise.
+	State string
+	// ModifiedRows are the rows changed since the table was last analyzed.
+	ModifiedRows int64
+	// LastRecalc is when the statistics were last rebuilt, from SampledRows
+	// of the TableRows rows, zero if they never were. LastError is the
+	// error it failed with.
+	LastRecalc  time.Time
+	SampledRows int64
+	TableRows   int64
+	LastError   string
+}
+
+// StatsRecalcStates is the source of the state of the automatic
+// recalculation of the statistics.
+type StatsRecalcStates interface {
+	// StatsRecalcStates returns the state of the tables pending or
+	// recalculated, by database and table name.
+	StatsRecalcStates() []StatsRecalcState
+}
+
+var statsRecalcStates atomic.Value
+
+// RegisterStatsRecalcStates sets the state information_schema.
+// XMYSQL_STATS_AUTO_RECALC reports.
+func RegisterStatsRecalcStates(states StatsRecalcStates) {
+	statsRecalcStates.Store(&states)
+}
+
+// StatsAutoRecalcRows returns the rows of
+// information_schema.XMYSQL_STATS_AUTO_RECALC.
+func StatsAutoRecalcRows() [][]types.Datum {
+	states, ok := statsRecalcStates.Load().(*StatsRecalcStates)
+	if !ok || *states == nil {
+		return nil
+	}
+	var rows [][]types.Datum
+	for _, s := range (*states).StatsRecalcStates() {
+		var sampled, total, lastError interface{}
+		if !s.LastRecalc.IsZero() {
+			sampled, total = s.SampledRows, s.TableRows
+		}
+		if s.LastError != "" {
+			lastError = s.LastError
+		}
+		rows = append(rows, types.MakeDatums(s.DB, s.Table, s.State, s.ModifiedRows,
+			statusTime(s.LastRecalc), sampled, total, lastError))
+	}
+	return rows
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/terror"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/tuple"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
 )
 
 var (
@@ -53,6 +54,12 @@ var (
 
 	// ErrTruncateWrongValue returns for truncate wrong value for field.
 	ErrTruncateWrongValue = terror.ClassTable.New(codeTruncateWrongValue, "Incorrect value")
+	// ErrWarnDataOutOfRange returns when a value overflows the column type.
+	ErrWarnDataOutOfRange = terror.ClassTable.New(codeWarnDataOutOfRange, mysql.MySQLErrName[mysql.ErrWarnDataOutOfRange])
+	// ErrDataTooLong returns when a string is longer than the column length.
+	ErrDataTooLong = terror.ClassTable.New(codeDataTooLong, mysql.MySQLErrName[mysql.ErrDataTooLong])
+	// ErrWarnDataTruncated returns when a value isn't a member of an ENUM or SET column.
+	ErrWarnDataTruncated = terror.ClassTable.New(codeWarnDataTruncated, "Data truncated for column '%s' at row %d")
 )
 
 // Table is used to retrieve and modify rows in table.
@@ -90,6 +97,14 @@ type Table interface {
 	WritableCols() []*Column
 }
 
+// RowWriter is implemented by the tables whose rows can be written.
+type RowWriter interface {
+	// AddRow adds row, the values of all the columns of the table.
+	AddRow(row []basic.Datum) error
+	// RemoveRow removes a row equal to row.
+	RemoveRow(row []basic.Datum) error
+}
+
 // MockTableFromMeta only serves for test.
 var MockTableFromMeta func(tableInfo *model.TableInfo) Table
 
@@ -108,6 +123,23 @@ const (
 	codeColumnCantNull     = 1048
 	codeUnknownColumn      = 1054
 	codeDuplicateColumn    = 1110
+	codeWarnDataOutOfRange = 1264
+	codeWarnDataTruncated  = 1265
 	codeNoDefaultValue     = 1364
 	codeTruncateWrongValue = 1366
+	codeDataTooLong        = 1406
 )
+
+func init() {
+	tableMySQLErrCodes := map[terror.ErrCode]uint16{
+		codeColumnCantNull:     mysql.ErrBadNull,
+		codeUnknownColumn:      mysql.ErrBadField,
+		codeDuplicateColumn:    mysql.ErrFieldSpecifiedTwice,
+		codeWarnDataOutOfRange: mysql.ErrWarnDataOutOfRange,
+		codeWarnDataTruncated:  mysql.WarnDataTruncated,
+		codeNoDefaultValue:     mysql.ErrNoDefaultForField,
+		codeTruncateWrongValue: mysql.ErrTruncatedWrongValueForField,
+		codeDataTooLong:        mysql.ErrDataTooLong,
+	}
+	terror.ErrClassToMySQLCodes[terror.ClassTable] = tableMySQLErrCodes
+}
@@ -0,0 +1,198 @@
+package schemas
+
+import (
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	types "github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+/**
+表的大小
+
+SHOW TABLE STATUS 和 information_schema.TABLES 报告同样的数据：Rows 是表的实时行数，
+没有实时行数时是主键 B+ 树估算的记录数，Data_length 是聚簇索引两个段（叶子段和非叶子段）分配的页面数乘以页大小，
+Index_length 是所有二级索引的段分配的页面，Avg_row_length 是 Data_length / Rows。
+Create_time 和 Update_time 取自表的 .frm 和 .ibd 文件。
+**/
+
+// TableStatus is the size and the times of a table's tablespace.
+type TableStatus struct {
+	// DataLength is the bytes allocated to the clustered index, IndexLength
+	// to the secondary indexes.
+	DataLength  int64
+	IndexLength int64
+	// CreateTime and UpdateTime are zero when they aren't known.
+	CreateTime time.Time
+	UpdateTime time.Time
+}
+
+// TableStatusReader is implemented by the tables that know the size of the
+// segments of their tablespace.
+type TableStatusReader interface {
+	TableStatus() TableStatus
+}
+
+// TableRowCounts is the source of the live row counts of the tables, kept
+// up to date by the committed transactions.
+type TableRowCounts interface {
+	// RowCount returns the live row count of the table id, false when it
+	// isn't known.
+	RowCount(tableID int64) (int64, bool)
+}
+
+var tableRowCounts atomic.Value
+
+// RegisterTableRowCounts sets the row counts SHOW TABLE STATUS and
+// information_schema.TABLES report.
+func RegisterTableRowCounts(counts TableRowCounts) {
+	tableRowCounts.Store(&counts)
+}
+
+// tableRowCount returns the live row count of tbl, else the number of
+// records of its primary key estimated from its pages.
+func tableRowCount(tbl Table) int64 {
+	if counts, ok := tableRowCounts.Load().(*TableRowCounts); ok && *counts != nil {
+		if rows, ok := (*counts).RowCount(tbl.Meta().ID); ok {
+			return rows
+		}
+	}
+	if estimator, ok := tbl.(IndexRowsEstimator); ok {
+		rows, _ := estimator.EstimateIndexRows(mysql.PrimaryKeyName)
+		return rows
+	}
+	return 0
+}
+
+// TablesRows returns the rows of information_schema.TABLES, one per table
+// and view of is. The tables created without ROW_FORMAT are reported in
+// defaultRowFormat, the value of innodb_default_row_format.
+func TablesRows(is InfoSchema, defaultRowFormat string) [][]types.Datum {
+	var rows [][]types.Datum
+	for _, db := range is.AllSchemas() {
+		for _, tbl := range is.SchemaTables(db.Name) {
+			if row := tableStatusRow(tbl, defaultRowFormat); row != nil {
+				tableType := "BASE TABLE"
+				if tbl.Meta().IsView() {
+					tableType = "VIEW"
+				}
+				rows = append(rows, append(types.MakeDatums(catalogName, db.Name.O, row[0].GetString(), tableType), row[1:]...))
+			}
+		}
+	}
+	return rows
+}
+
+// ShowDatabasesRows returns the rows of SHOW DATABASES, the names of the
+// databases of is and of the ones the server makes up, sorted.
+func ShowDatabasesRows(is InfoSchema) [][]types.Datum {
+	names := []string{InformationSchemaName.O, PerformanceSchemaName.O}
+	for _, db := range is.AllSchemas() {
+		if db.Name.L != InformationSchemaName.L && db.Name.L != PerformanceSchemaName.L {
+			names = append(names, db.Name.O)
+		}
+	}
+	sort.Strings(names)
+	rows := make([][]types.Datum, 0, len(names))
+	for _, name := range names {
+		rows = append(rows, types.MakeDatums(name))
+	}
+	return rows
+}
+
+// ShowTablesRows returns the rows of SHOW [FULL] TABLES FROM db, the names
+// of its tables and views as they are stored, sorted, with their
+// Table_type when full.
+func ShowTablesRows(is InfoSchema, db model.CIStr, full bool) [][]types.Datum {
+	var rows [][]types.Datum
+	for _, tbl := range is.SchemaTables(db) {
+		meta := tbl.Meta()
+		if meta == nil {
+			continue
+		}
+		row := types.MakeDatums(meta.Name.O)
+		if full {
+			tableType := "BASE TABLE"
+			if meta.IsView() {
+				tableType = "VIEW"
+			}
+			row = append(row, types.NewStringDatum(tableType))
+		}
+		rows = append(rows, row)
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i][0].GetString() < rows[j][0].GetString() })
+	return rows
+}
+
+// ShowTableStatusRows returns the rows of SHOW TABLE STATUS FROM db, the
+// columns of information_schema.TABLES from ENGINE on, after the name.
+func ShowTableStatusRows(is InfoSchema, db model.CIStr, defaultRowFormat string) [][]types.Datum {
+	var rows [][]types.Datum
+	for _, tbl := range is.SchemaTables(db) {
+		if row := tableStatusRow(tbl, defaultRowFormat); row != nil {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// tableStatusRow returns the row of SHOW TABLE STATUS of tbl, nil when it
+// has no definition.
+func tableStatusRow(tbl Table, defaultRowFormat string) []types.Datum {
+	meta := tbl.Meta()
+	if meta == nil {
+		return nil
+	}
+	if meta.IsView() {
+		return types.MakeDatums(meta.Name.O, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil,
+			nil, nil, nil, nil, nil, nil, "VIEW")
+	}
+	engine := meta.Engine
+	if engine == "" {
+		engine = "InnoDB"
+	}
+	rowFormat, createOptions := defaultRowFormat, interface{}(nil)
+	if meta.RowFormat != "" {
+		rowFormat, createOptions = meta.RowFormat, "row_format="+meta.RowFormat
+	}
+	tableRows := tableRowCount(tbl)
+	var status TableStatus
+	if reader, ok := tbl.(TableStatusReader); ok {
+		status = reader.TableStatus()
+	}
+	var avgRowLength int64
+	if tableRows > 0 {
+		avgRowLength = status.DataLength / tableRows
+	}
+	var autoIncrement, collation interface{}
+	if hasAutoIncrementColumn(meta) {
+		autoIncrement = meta.AutoIncID + 1
+	}
+	if meta.Collate != "" {
+		collation = meta.Collate
+	}
+	return types.MakeDatums(meta.Name.O, engine, 10, rowFormatTitle(rowFormat), tableRows, avgRowLength,
+		status.DataLength, 0, status.IndexLength, 0, autoIncrement, statusTime(status.CreateTime),
+		statusTime(status.UpdateTime), nil, collation, nil, createOptions, meta.Comment)
+}
+
+// statusTime returns t as a DATETIME, NULL when it is zero.
+func statusTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return types.Time{Time: types.FromGoTime(t), Type: mysql.TypeDatetime}
+}
+
+// rowFormatTitle returns the name of a row format as information_schema
+// reports it, Dynamic for DYNAMIC.
+func rowFormatTitle(name string) string {
+	if name == "" {
+		return ""
+	}
+	return strings.ToUpper(name[:1]) + strings.ToLower(name[1:])
+}
@@ -0,0 +1,54 @@
+package schemas
+
+import (
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+)
+
+// View is the Table of a view. A view has no pages of its own, its rows
+// come from planning the SELECT kept in its meta, so only the methods
+// describing the columns are implemented.
+type View struct {
+	Table
+	meta *model.TableInfo
+	cols []*Column
+}
+
+// NewView returns the Table of the view described by meta.
+func NewView(meta *model.TableInfo) *View {
+	cols := make([]*Column, 0, len(meta.Columns))
+	for _, col := range meta.Columns {
+		cols = append(cols, ToColumn(col))
+	}
+	return &View{meta: meta, cols: cols}
+}
+
+// Meta implements Table Meta interface.
+func (v *View) Meta() *model.TableInfo {
+	return v.meta
+}
+
+// TableName implements Table TableName interface.
+func (v *View) TableName() string {
+	return v.meta.Name.O
+}
+
+// ColNums implements Table ColNums interface.
+func (v *View) ColNums() int {
+	return len(v.cols)
+}
+
+// CheckFieldName implements Table CheckFieldName interface.
+func (v *View) CheckFieldName(fieldName string) bool {
+	return FindCol(v.cols, fieldName) != nil
+}
+
+// Cols implements Table Cols interface.
+func (v *View) Cols() []*Column {
+	return v.cols
+}
+
+// WritableCols implements Table WritableCols interface. A view can't be
+// written to.
+func (v *View) WritableCols() []*Column {
+	return nil
+}
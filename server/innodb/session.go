@@ -2,8 +2,10 @@ package innodb
 
 import (
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
 	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+	"github.com/zhukovaskychina/xmysql-server/server/protocol"
 	"time"
 )
 
@@ -20,6 +22,14 @@ type MySQLServerSession interface {
 
 	SendError(error *mysql.SQLError)
 
+	// SendResultSet sends the rows of rows as a text result set with the
+	// columns fields, as rows yields them.
+	SendResultSet(fields []protocol.Field, rows RowIterator) error
+
+	// SetPacketSequence sets the sequence id of the next packet sent to the
+	// client, the one following the last packet received from it.
+	SetPacketSequence(seq byte)
+
 	GetCurrentDataBase() string
 
 	SetCurrentDatabase(databaseName string)
@@ -32,5 +42,34 @@ type MySQLServerSession interface {
 
 	Commit()
 
+	// RollbackTxn rolls back the open transaction of the session, if any.
+	RollbackTxn() error
+
 	context.Context
 }
+
+// RowIterator yields the rows of a result set one at a time, so that they
+// are sent while the next ones are produced.
+type RowIterator interface {
+	// Next returns the next row, nil after the last one.
+	Next() ([]basic.Datum, error)
+}
+
+// sliceRows yields rows held in memory.
+type sliceRows struct {
+	rows [][]basic.Datum
+}
+
+// SliceRows returns a RowIterator yielding rows.
+func SliceRows(rows [][]basic.Datum) RowIterator {
+	return &sliceRows{rows: rows}
+}
+
+func (r *sliceRows) Next() ([]basic.Datum, error) {
+	if len(r.rows) == 0 {
+		return nil, nil
+	}
+	row := r.rows[0]
+	r.rows = r.rows[1:]
+	return row, nil
+}
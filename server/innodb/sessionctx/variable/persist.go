@@ -0,0 +1,176 @@
+package variable
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PersistFilename is the file in the data directory SET PERSIST writes to.
+const PersistFilename = "mysqld-auto.cnf"
+
+// PersistedVariable is a global variable set with SET PERSIST.
+type PersistedVariable struct {
+	Name  string
+	Value string
+	// SetTime, User and Host tell when and by whom it was persisted.
+	SetTime time.Time
+	User    string
+	Host    string
+}
+
+// PersistedVariables keeps the variables set with SET PERSIST in a JSON
+// file laid out like the mysqld-auto.cnf of MySQL 8.0:
+//
+//	{"Version": 1, "mysql_server": {"max_connections": {"Value": "200",
+//		"Metadata": {"Timestamp": 1519143212512335, "User": "root", "Host": "localhost"}}}}
+type PersistedVariables struct {
+	mu   sync.Mutex
+	path string
+	vars map[string]PersistedVariable
+}
+
+type persistFile struct {
+	Version     int                     `json:"Version"`
+	MySQLServer map[string]persistEntry `json:"mysql_server"`
+}
+
+type persistEntry struct {
+	Value    string          `json:"Value"`
+	Metadata persistMetadata `json:"Metadata"`
+}
+
+type persistMetadata struct {
+	// Timestamp is in microseconds since the epoch.
+	Timestamp int64  `json:"Timestamp"`
+	User      string `json:"User"`
+	Host      string `json:"Host"`
+}
+
+// NewPersistedVariables returns no variables, persisted to path.
+func NewPersistedVariables(path string) *PersistedVariables {
+	return &PersistedVariables{path: path, vars: make(map[string]PersistedVariable)}
+}
+
+// LoadPersistedVariables reads the variables persisted to path, none when
+// the file doesn't exist yet.
+func LoadPersistedVariables(path string) (*PersistedVariables, error) {
+	p := NewPersistedVariables(path)
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var file persistFile
+	if err = json.Unmarshal(content, &file); err != nil {
+		return nil, err
+	}
+	for name, entry := range file.MySQLServer {
+		name = strings.ToLower(name)
+		p.vars[name] = PersistedVariable{
+			Name:    name,
+			Value:   entry.Value,
+			SetTime: time.Unix(0, entry.Metadata.Timestamp*int64(time.Microsecond)),
+			User:    entry.Metadata.User,
+			Host:    entry.Metadata.Host,
+		}
+	}
+	return p, nil
+}
+
+// Variables returns the persisted variables ordered by name.
+func (p *PersistedVariables) Variables() []PersistedVariable {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	vars := make([]PersistedVariable, 0, len(p.vars))
+	for _, v := range p.vars {
+		vars = append(vars, v)
+	}
+	sort.Slice(vars, func(i, j int) bool { return vars[i].Name < vars[j].Name })
+	return vars
+}
+
+// Has reports whether the variable name is persisted.
+func (p *PersistedVariables) Has(name string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, ok := p.vars[strings.ToLower(name)]
+	return ok
+}
+
+// Set persists value as the value of the variable name.
+func (p *PersistedVariables) Set(name, value, user, host string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	name = strings.ToLower(name)
+	p.vars[name] = PersistedVariable{Name: name, Value: value, SetTime: time.Now(), User: user, Host: host}
+	return p.save()
+}
+
+// Reset removes the variable name from the file, every variable when name
+// is empty. It returns false when name isn't persisted.
+func (p *PersistedVariables) Reset(name string) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if name == "" {
+		p.vars = make(map[string]PersistedVariable)
+		return true, p.save()
+	}
+	name = strings.ToLower(name)
+	if _, ok := p.vars[name]; !ok {
+		return false, nil
+	}
+	delete(p.vars, name)
+	return true, p.save()
+}
+
+// save writes the variables to a new file that replaces the old one.
+func (p *PersistedVariables) save() error {
+	file := persistFile{Version: 1, MySQLServer: make(map[string]persistEntry, len(p.vars))}
+	for name, v := range p.vars {
+		file.MySQLServer[name] = persistEntry{
+			Value: v.Value,
+			Metadata: persistMetadata{
+				Timestamp: v.SetTime.UnixNano() / int64(time.Microsecond),
+				User:      v.User,
+				Host:      v.Host,
+			},
+		}
+	}
+	content, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+	tmp := p.path + ".tmp"
+	if err = ioutil.WriteFile(tmp, content, 0640); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p.path)
+}
+
+var persisted struct {
+	sync.RWMutex
+	vars *PersistedVariables
+}
+
+// RegisterPersistedVariables sets the variables SET PERSIST writes to and
+// performance_schema.persisted_variables reads.
+func RegisterPersistedVariables(p *PersistedVariables) {
+	persisted.Lock()
+	defer persisted.Unlock()
+	persisted.vars = p
+}
+
+// GetPersistedVariables returns the variables registered with
+// RegisterPersistedVariables, nil when there are none.
+func GetPersistedVariables() *PersistedVariables {
+	persisted.RLock()
+	defer persisted.RUnlock()
+	return persisted.vars
+}
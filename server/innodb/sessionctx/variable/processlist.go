@@ -0,0 +1,85 @@
+package variable
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// ProcessInfo is the state of a session SHOW PROCESSLIST shows, taken when
+// the session begins or ends a statement.
+type ProcessInfo struct {
+	ID   uint64
+	User string
+	Host string
+	DB   string
+	// Command is "Query" while the session runs a statement, "Sleep"
+	// otherwise.
+	Command string
+	// Time is when the command began.
+	Time time.Time
+	// Info is the statement the session runs, empty while it sleeps.
+	Info string
+}
+
+// SetProcessInfo records that the session begins to run query, or sleeps
+// when query is empty.
+func (s *SessionVars) SetProcessInfo(query string) {
+	info := ProcessInfo{ID: s.ConnectionID, DB: s.CurrentDB, Command: "Sleep", Time: time.Now(), Info: query}
+	if s.User != nil {
+		info.User, info.Host = s.User.Username, s.User.Hostname
+	}
+	if query != "" {
+		info.Command = "Query"
+	}
+	s.process.Lock()
+	s.process.ProcessInfo = info
+	s.process.Unlock()
+}
+
+// ProcessInfo returns the state of the session.
+func (s *SessionVars) ProcessInfo() ProcessInfo {
+	s.process.Lock()
+	defer s.process.Unlock()
+	return s.process.ProcessInfo
+}
+
+// processes are the sessions of the clients connected, by connection id.
+var processes = struct {
+	sync.RWMutex
+	sessions map[uint64]*SessionVars
+}{sessions: make(map[uint64]*SessionVars)}
+
+// AddProcess adds the session of vars, sleeping, to the sessions SHOW
+// PROCESSLIST lists.
+func AddProcess(vars *SessionVars) {
+	vars.SetProcessInfo("")
+	processes.Lock()
+	processes.sessions[vars.ConnectionID] = vars
+	processes.Unlock()
+}
+
+// RemoveProcess removes the session of vars from the sessions SHOW
+// PROCESSLIST lists.
+func RemoveProcess(vars *SessionVars) {
+	processes.Lock()
+	if processes.sessions[vars.ConnectionID] == vars {
+		delete(processes.sessions, vars.ConnectionID)
+	}
+	processes.Unlock()
+}
+
+// Processes returns the sessions SHOW PROCESSLIST lists, in the order of
+// their connection ids.
+func Processes() []*SessionVars {
+	processes.RLock()
+	list := make([]*SessionVars, 0, len(processes.sessions))
+	for _, vars := range processes.sessions {
+		list = append(list, vars)
+	}
+	processes.RUnlock()
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ConnectionID < list[j].ConnectionID
+	})
+	return list
+}
@@ -0,0 +1,64 @@
+package variable
+
+import "time"
+
+// QueryProfile is the profile of a statement run with profiling on, a row of
+// SHOW PROFILES. Its stages are the rows of SHOW PROFILE.
+type QueryProfile struct {
+	// QueryID numbers the statements profiled by the session from 1.
+	QueryID  uint64
+	Query    string
+	Duration time.Duration
+	Stages   []ProfileStage
+}
+
+// ProfileStage is a stage of a profiled statement and the time spent in it.
+type ProfileStage struct {
+	Status   string
+	Duration time.Duration
+}
+
+// AddProfile keeps p as the profile of the last statement of the session,
+// numbered after the one before. Only the last profiling_history_size
+// profiles are kept.
+func (s *SessionVars) AddProfile(p *QueryProfile) {
+	s.lastQueryID++
+	p.QueryID = s.lastQueryID
+	s.Profiles = append(s.Profiles, p)
+	s.trimProfiles()
+}
+
+// Profile returns the profile of the statement queryID, the one of the
+// last statement profiled when queryID is 0, or nil when it is not kept.
+func (s *SessionVars) Profile(queryID uint64) *QueryProfile {
+	if len(s.Profiles) == 0 {
+		return nil
+	}
+	if queryID == 0 {
+		return s.Profiles[len(s.Profiles)-1]
+	}
+	for _, p := range s.Profiles {
+		if p.QueryID == queryID {
+			return p
+		}
+	}
+	return nil
+}
+
+// SetProfilingHistorySize sets the number of profiles kept, from 0 to 100.
+func (s *SessionVars) SetProfilingHistorySize(size int) {
+	if size < 0 {
+		size = 0
+	}
+	if size > MaxProfilingHistorySize {
+		size = MaxProfilingHistorySize
+	}
+	s.ProfilingHistorySize = size
+	s.trimProfiles()
+}
+
+func (s *SessionVars) trimProfiles() {
+	if over := len(s.Profiles) - s.ProfilingHistorySize; over > 0 {
+		s.Profiles = append(s.Profiles[:0:0], s.Profiles[over:]...)
+	}
+}
@@ -17,6 +17,7 @@ import (
 	"crypto/tls"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/terror"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/auth"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/memory"
 	"github.com/zhukovaskychina/xmysql-server/server/mysql"
 	"math"
 	"sync"
@@ -131,6 +132,10 @@ type SessionVars struct {
 	LastInsertID     uint64 // LastInsertID is the auto-generated ID in the current statement.
 	InsertID         uint64 // InsertID is the given insert ID of an auto_increment column.
 
+	// Killed is set to 1, atomically, to stop the running statement of the
+	// session.
+	Killed uint32
+
 	// ClientCapability is client's capability.
 	ClientCapability uint32
 
@@ -190,6 +195,12 @@ type SessionVars struct {
 
 	SQLMode mysql.SQLMode
 
+	// ResultsCharset is the charset the strings of the results are sent in,
+	// set by SET NAMES and character_set_results. It is empty until then,
+	// or after character_set_results is set to NULL, and the strings are
+	// sent as they are stored.
+	ResultsCharset string
+
 	/* TiDB system variables */
 
 	// SkipConstraintCheck is true when importing data.
@@ -228,6 +239,51 @@ type SessionVars struct {
 
 	// MaxRowCountForINLJ defines max row count that the outer table of index nested loop join could be without force hint.
 	MaxRowCountForINLJ int
+
+	// EnableOptimizerTrace indicates if the optimizer traces the statements it plans.
+	EnableOptimizerTrace bool
+
+	// OptimizerTraceOneLine indicates if the trace is written without indentation.
+	OptimizerTraceOneLine bool
+
+	// OptimizerTraceMaxMemSize is the maximum size of a trace, the bytes beyond it are dropped.
+	OptimizerTraceMaxMemSize int
+
+	// LastOptimizerTrace is the trace of the last statement planned with optimizer_trace enabled.
+	LastOptimizerTrace *OptimizerTrace
+
+	// ForeignKeyChecks indicates if foreign key constraints are checked.
+	ForeignKeyChecks bool
+
+	// Profiling indicates if the statements are profiled.
+	Profiling bool
+
+	// ProfilingHistorySize is the number of the last profiles kept.
+	ProfilingHistorySize int
+
+	// Profiles are the profiles of the last statements profiled, the oldest first.
+	Profiles []*QueryProfile
+
+	// lastQueryID is the number of the last statement profiled.
+	lastQueryID uint64
+
+	// MemTracker tracks the memory the statements of the session take, at
+	// most max_session_memory bytes.
+	MemTracker *memory.Tracker
+
+	// process is what SHOW PROCESSLIST shows of the session.
+	process struct {
+		sync.Mutex
+		ProcessInfo
+	}
+}
+
+// OptimizerTrace is a row of information_schema.OPTIMIZER_TRACE.
+type OptimizerTrace struct {
+	Query                        string
+	Trace                        string
+	MissingBytesBeyondMaxMemSize int
+	InsufficientPrivileges       bool
 }
 
 // NewSessionVars creates a session vars object.
@@ -240,6 +296,7 @@ func NewSessionVars() *SessionVars {
 		TxnCtx:                     &TransactionContext{},
 		RetryInfo:                  &RetryInfo{},
 		StrictSQLMode:              true,
+		SQLMode:                    mysql.ModeStrictTransTables | mysql.ModeNoEngineSubstitution,
 		Status:                     mysql.ServerStatusAutocommit,
 		StmtCtx:                    new(StatementContext),
 		AllowAggPushDown:           false,
@@ -251,9 +308,20 @@ func NewSessionVars() *SessionVars {
 		DistSQLScanConcurrency:     DefDistSQLScanConcurrency,
 		MaxRowCountForINLJ:         DefMaxRowCountForINLJ,
 		DMLBatchSize:               DefDMLBatchSize,
+		OptimizerTraceMaxMemSize:   DefOptimizerTraceMaxMemSize,
+		ForeignKeyChecks:           true,
+		ProfilingHistorySize:       DefProfilingHistorySize,
+		MemTracker:                 newSessionMemTracker(),
 	}
 }
 
+// newSessionMemTracker creates the memory tracker of a session.
+func newSessionMemTracker() *memory.Tracker {
+	t := memory.NewTracker(memory.LabelSession, 0)
+	t.AttachTo(memory.GlobalTracker)
+	return t
+}
+
 // GetCharsetInfo gets charset and collation for current context.
 // What character set should the server translate a statement to after receiving it?
 // For this, the server uses the character_set_connection and collation_connection system variables.
@@ -324,6 +392,79 @@ const (
 	MaxAllowedPacket    = "max_allowed_packet"
 	TimeZone            = "time_zone"
 	TxnIsolation        = "tx_isolation"
+	SecureFilePriv      = "secure_file_priv"
+	ForeignKeyChecks    = "foreign_key_checks"
+	TableOpenCache      = "table_open_cache"
+	ReadOnly            = "read_only"
+	SuperReadOnly       = "super_read_only"
+	TxReadOnly          = "tx_read_only"
+
+	DefaultStorageEngine = "default_storage_engine"
+
+	InnodbAdaptiveHashIndex = "innodb_adaptive_hash_index"
+	InnodbChangeBuffering   = "innodb_change_buffering"
+	InnodbDefaultRowFormat  = "innodb_default_row_format"
+
+	InnodbChangeBufferMergeNow = "innodb_change_buffer_merge_now"
+
+	InnodbChecksumAlgorithm = "innodb_checksum_algorithm"
+	InnodbCorruptPageAction = "innodb_corrupt_page_action"
+
+	InnodbBufferPoolDumpAtShutdown = "innodb_buffer_pool_dump_at_shutdown"
+	InnodbBufferPoolLoadAtStartup  = "innodb_buffer_pool_load_at_startup"
+	InnodbBufferPoolFilename       = "innodb_buffer_pool_filename"
+	InnodbBufferPoolDumpNow        = "innodb_buffer_pool_dump_now"
+	InnodbBufferPoolLoadNow        = "innodb_buffer_pool_load_now"
+	InnodbBufferPoolInstances      = "innodb_buffer_pool_instances"
+	InnodbBufferPoolSize           = "innodb_buffer_pool_size"
+
+	InnodbPageSize = "innodb_page_size"
+
+	LowerCaseTableNames = "lower_case_table_names"
+
+	LogErrorVerbosity = "log_error_verbosity"
+
+	AuditLog             = "audit_log"
+	AuditLogFile         = "audit_log_file"
+	AuditLogPolicy       = "audit_log_policy"
+	AuditLogRotateOnSize = "audit_log_rotate_on_size"
+
+	OptimizerTraceVar        = "optimizer_trace"
+	OptimizerTraceMaxMemSize = "optimizer_trace_max_mem_size"
+
+	Profiling            = "profiling"
+	ProfilingHistorySize = "profiling_history_size"
+
+	SessionTrackSchema          = "session_track_schema"
+	SessionTrackSystemVariables = "session_track_system_variables"
+	SessionTrackStateChange     = "session_track_state_change"
+	SessionTrackTransactionInfo = "session_track_transaction_info"
+
+	NetBufferLength  = "net_buffer_length"
+	MaxExecutionTime = "max_execution_time"
+
+	MaxSessionMemory = "max_session_memory"
+	MaxServerMemory  = "max_server_memory"
+
+	GroupConcatMaxLen = "group_concat_max_len"
+
+	AutocommitDeadlockRetries = "xmysql_autocommit_deadlock_retries"
+
+	InnodbStatsAutoRecalc    = "innodb_stats_auto_recalc"
+	StatsAutoRecalcThreshold = "xmysql_stats_auto_recalc_threshold"
+)
+
+// DefSessionTrackSystemVariables is the default value of session_track_system_variables.
+const DefSessionTrackSystemVariables = "time_zone,autocommit,character_set_client,character_set_results,character_set_connection"
+
+// DefOptimizerTraceMaxMemSize is the default value of optimizer_trace_max_mem_size.
+const DefOptimizerTraceMaxMemSize = 16384
+
+// DefProfilingHistorySize is the default value of profiling_history_size,
+// MaxProfilingHistorySize its largest value.
+const (
+	DefProfilingHistorySize = 15
+	MaxProfilingHistorySize = 100
 )
 
 // TableDelta stands for the changed count for one table.
@@ -360,6 +501,39 @@ type StatementContext struct {
 	Priority     mysql.PriorityEnum
 	NotFillCache bool
 	BatchCheck   bool
+
+	// OptimizerTrace is the trace being recorded for the statement when
+	// optimizer_trace is enabled.
+	OptimizerTrace interface{}
+
+	// NowTs is the start time of the statement. NOW(), CURDATE() and the
+	// other current-time functions read it so that they return the same
+	// value for every row the statement touches.
+	NowTs time.Time
+
+	// StateChanges are the changes the statement made to the state of the
+	// session that the session tracks, in the order they were made.
+	StateChanges []SessionStateChange
+
+	// MemTracker tracks the memory the statement takes, a child of the
+	// tracker of its session. Nil outside of a statement.
+	MemTracker *memory.Tracker
+
+	// GroupConcatMaxLen is group_concat_max_len when the statement started,
+	// 0 for no limit.
+	GroupConcatMaxLen uint64
+
+	// DeadlockRetries counts the times the statement was rolled back and
+	// run again after a deadlock.
+	DeadlockRetries int
+}
+
+// GetNowTs returns the statement start time, fixing it on first use.
+func (sc *StatementContext) GetNowTs() time.Time {
+	if sc.NowTs.IsZero() {
+		sc.NowTs = time.Now()
+	}
+	return sc.NowTs
 }
 
 // AddAffectedRows adds affected rows.
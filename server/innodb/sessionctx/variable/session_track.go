@@ -0,0 +1,116 @@
+package variable
+
+import (
+	"strings"
+
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// SessionStateChange is a change to the state of a session, sent to the
+// clients tracking it in the OK packet of the statement making it.
+type SessionStateChange struct {
+	// Tracker is the type of the change, one of mysql.SessionTrack*.
+	Tracker byte
+	// Values are the strings making the data of the change.
+	Values []string
+}
+
+// TrackSystemVar records that the session value of the system variable name
+// changed to value, when session_track_system_variables lists it. Changing
+// the isolation level or the access mode records the new transaction
+// characteristics too when session_track_transaction_info asks for them.
+func (s *SessionVars) TrackSystemVar(name, value string) {
+	if tracked, ok := s.trackedSystemVar(name); ok {
+		s.StmtCtx.trackChange(SessionStateChange{
+			Tracker: mysql.SessionTrackSystemVariables,
+			Values:  []string{tracked, value},
+		})
+	}
+	switch name {
+	case TxnIsolation, "transaction_isolation", TxReadOnly, "transaction_read_only":
+		if strings.EqualFold(s.trackingValue(SessionTrackTransactionInfo), "CHARACTERISTICS") {
+			s.StmtCtx.trackChange(SessionStateChange{
+				Tracker: mysql.SessionTrackTransactionCharacteristics,
+				Values:  []string{s.txnCharacteristics()},
+			})
+		}
+	}
+	s.TrackStateChange()
+}
+
+// TrackSchema records that the current schema changed to name, when
+// session_track_schema is on.
+func (s *SessionVars) TrackSchema(name string) {
+	if s.trackingOn(SessionTrackSchema) {
+		s.StmtCtx.trackChange(SessionStateChange{Tracker: mysql.SessionTrackSchema, Values: []string{name}})
+	}
+	s.TrackStateChange()
+}
+
+// TrackStateChange records that the state of the session changed, when
+// session_track_state_change is on.
+func (s *SessionVars) TrackStateChange() {
+	if s.trackingOn(SessionTrackStateChange) {
+		s.StmtCtx.trackChange(SessionStateChange{Tracker: mysql.SessionTrackStateChange, Values: []string{"1"}})
+	}
+}
+
+// trackChange records change, replacing the change of the same variable, or
+// of the same tracker for the others, already recorded.
+func (sc *StatementContext) trackChange(change SessionStateChange) {
+	for i, c := range sc.StateChanges {
+		if c.Tracker != change.Tracker {
+			continue
+		}
+		if change.Tracker != mysql.SessionTrackSystemVariables || c.Values[0] == change.Values[0] {
+			sc.StateChanges[i] = change
+			return
+		}
+	}
+	sc.StateChanges = append(sc.StateChanges, change)
+}
+
+// trackedSystemVar returns the name the system variable name is listed
+// under in session_track_system_variables, a synonym of name or name.
+func (s *SessionVars) trackedSystemVar(name string) (string, bool) {
+	for _, item := range strings.Split(s.trackingValue(SessionTrackSystemVariables), ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item == "*" || item == name {
+			return name, true
+		}
+		for _, synonym := range SynonymsSysVariables[name] {
+			if item == synonym {
+				return synonym, true
+			}
+		}
+	}
+	return "", false
+}
+
+// txnCharacteristics returns the statements setting the characteristics of
+// the transactions of the session.
+func (s *SessionVars) txnCharacteristics() string {
+	level := strings.Replace(strings.ToUpper(s.trackingValue(TxnIsolation)), "-", " ", -1)
+	characteristics := "SET TRANSACTION ISOLATION LEVEL " + level + ";"
+	if s.trackingOn(TxReadOnly) {
+		characteristics += " SET TRANSACTION READ ONLY;"
+	}
+	return characteristics
+}
+
+func (s *SessionVars) trackingOn(name string) bool {
+	value := s.trackingValue(name)
+	return strings.EqualFold(value, "ON") || value == "1"
+}
+
+// trackingValue returns the session value of the system variable name, its
+// global one when the session hasn't set it.
+func (s *SessionVars) trackingValue(name string) string {
+	if value, ok := s.Systems[name]; ok {
+		return value
+	}
+	if sv := GetSysVar(name); sv != nil {
+		return sv.Value
+	}
+	return ""
+}
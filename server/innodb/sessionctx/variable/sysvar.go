@@ -18,6 +18,8 @@ import (
 	"github.com/zhukovaskychina/xmysql-server/server/mysql"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 )
 
 // ScopeFlag is for system variable whether can be changed in global/session dynamically or not.
@@ -53,22 +55,82 @@ func GetSysVar(name string) *SysVar {
 	return SysVars[name]
 }
 
+// SysVarGetter computes the value of a system variable when it is read.
+type SysVarGetter func(s *SessionVars) (string, error)
+
+// SysVarSetter applies the new global value of a system variable to the
+// server when it is set, returning ErrWrongValueForVar if value isn't valid.
+type SysVarSetter func(s *SessionVars, value string) error
+
+// sysVarTypes, sysVarGetters and sysVarSetters keep the types, getters and
+// setters of the system variables registered with them, by name.
+var (
+	sysVarTypes   = make(map[string]byte)
+	sysVarGetters = make(map[string]SysVarGetter)
+	sysVarSetters = make(map[string]SysVarSetter)
+)
+
+// RegisterSysVar adds the system variable sv, whose value has the type tp.
+// If getter isn't nil, it computes the value each time the variable is
+// read, instead of sv.Value. Registering a variable again replaces it.
+func RegisterSysVar(sv *SysVar, tp byte, getter SysVarGetter) {
+	name := strings.ToLower(sv.Name)
+	SysVars[name] = sv
+	sysVarTypes[name] = tp
+	if getter != nil {
+		sysVarGetters[name] = getter
+	} else {
+		delete(sysVarGetters, name)
+	}
+}
+
+// RegisterSysVarSetter makes setter apply the global value of the system
+// variable name each time SET GLOBAL changes it.
+func RegisterSysVarSetter(name string, setter SysVarSetter) {
+	sysVarSetters[strings.ToLower(name)] = setter
+}
+
+// GetSysVarSetter returns the setter applying the system variable name, nil
+// if it has none.
+func GetSysVarSetter(name string) SysVarSetter {
+	return sysVarSetters[strings.ToLower(name)]
+}
+
+// GetSysVarType returns the type of the value of the system variable name,
+// a string unless it is registered with another one.
+func GetSysVarType(name string) byte {
+	if tp, ok := sysVarTypes[strings.ToLower(name)]; ok {
+		return tp
+	}
+	return mysql.TypeVarString
+}
+
+// GetSysVarGetter returns the getter computing the system variable name,
+// nil if it has none.
+func GetSysVarGetter(name string) SysVarGetter {
+	return sysVarGetters[strings.ToLower(name)]
+}
+
 // Variable error codes.
 const (
 	CodeUnknownStatusVar terror.ErrCode = 1
 	CodeUnknownSystemVar terror.ErrCode = 1193
+	CodeWrongValueForVar terror.ErrCode = 1231
 	CodeIncorrectScope   terror.ErrCode = 1238
 	CodeUnknownTimeZone  terror.ErrCode = 1298
 	CodeReadOnly         terror.ErrCode = 1621
+	CodeVarDoesNotExist  terror.ErrCode = 3615
 )
 
 // Variable errors
 var (
-	UnknownStatusVar   = terror.ClassVariable.New(CodeUnknownStatusVar, "unknown status variable")
-	UnknownSystemVar   = terror.ClassVariable.New(CodeUnknownSystemVar, "unknown system variable '%s'")
-	ErrIncorrectScope  = terror.ClassVariable.New(CodeIncorrectScope, "Incorrect variable scope")
-	ErrUnknownTimeZone = terror.ClassVariable.New(CodeUnknownTimeZone, "unknown or incorrect time zone: %s")
-	ErrReadOnly        = terror.ClassVariable.New(CodeReadOnly, "variable is read only")
+	UnknownStatusVar    = terror.ClassVariable.New(CodeUnknownStatusVar, "unknown status variable")
+	UnknownSystemVar    = terror.ClassVariable.New(CodeUnknownSystemVar, "unknown system variable '%s'")
+	ErrIncorrectScope   = terror.ClassVariable.New(CodeIncorrectScope, "Incorrect variable scope")
+	ErrWrongValueForVar = terror.ClassVariable.New(CodeWrongValueForVar, mysql.MySQLErrName[mysql.ErrWrongValueForVar])
+	ErrUnknownTimeZone  = terror.ClassVariable.New(CodeUnknownTimeZone, "unknown or incorrect time zone: %s")
+	ErrReadOnly         = terror.ClassVariable.New(CodeReadOnly, "variable is read only")
+	ErrVarDoesNotExist  = terror.ClassVariable.New(CodeVarDoesNotExist, mysql.MySQLErrName[mysql.ErrVarDoesNotExist])
 )
 
 func init() {
@@ -77,13 +139,16 @@ func init() {
 		SysVars[v.Name] = v
 	}
 	initSynonymsSysVariables()
+	registerComputedSysVars()
 
 	// Register terror to mysql error map.
 	mySQLErrCodes := map[terror.ErrCode]uint16{
 		CodeUnknownSystemVar: mysql.ErrUnknownSystemVariable,
+		CodeWrongValueForVar: mysql.ErrWrongValueForVar,
 		CodeIncorrectScope:   mysql.ErrIncorrectGlobalLocalVar,
 		CodeUnknownTimeZone:  mysql.ErrUnknownTimeZone,
 		CodeReadOnly:         mysql.ErrVariableIsReadonly,
+		CodeVarDoesNotExist:  mysql.ErrVarDoesNotExist,
 	}
 	terror.ErrClassToMySQLCodes[terror.ClassVariable] = mySQLErrCodes
 }
@@ -121,7 +186,7 @@ var defaultSysVars = []*SysVar{
 	{ScopeGlobal | ScopeSession, "default_week_format", "0"},
 	{ScopeGlobal | ScopeSession, "binlog_error_action", "IGNORE_ERROR"},
 	{ScopeGlobal, "slave_transaction_retries", "10"},
-	{ScopeGlobal | ScopeSession, "default_storage_engine", "InnoDB"},
+	{ScopeGlobal | ScopeSession, DefaultStorageEngine, "InnoDB"},
 	{ScopeNone, "ft_query_expansion_limit", "20"},
 	{ScopeGlobal, "max_connect_errors", "100"},
 	{ScopeGlobal, "sync_binlog", "0"},
@@ -157,18 +222,18 @@ var defaultSysVars = []*SysVar{
 	{ScopeGlobal, "innodb_file_format", "Antelope"},
 	{ScopeGlobal, "innodb_compression_failure_threshold_pct", "5"},
 	{ScopeNone, "performance_schema_events_waits_history_long_size", "10000"},
-	{ScopeGlobal, "innodb_checksum_algorithm", "innodb"},
+	{ScopeGlobal, InnodbChecksumAlgorithm, "crc32"},
 	{ScopeNone, "innodb_ft_sort_pll_degree", "2"},
 	{ScopeNone, "thread_stack", "262144"},
 	{ScopeGlobal, "relay_log_info_repository", "FILE"},
 	{ScopeGlobal | ScopeSession, "sql_log_bin", "ON"},
-	{ScopeGlobal, "super_read_only", "OFF"},
+	{ScopeGlobal, SuperReadOnly, "OFF"},
 	{ScopeGlobal | ScopeSession, "max_delayed_threads", "20"},
 	{ScopeNone, "protocol_version", "10"},
 	{ScopeGlobal | ScopeSession, "new", "OFF"},
 	{ScopeGlobal | ScopeSession, "myisam_sort_buffer_size", "8388608"},
 	{ScopeGlobal | ScopeSession, "optimizer_trace_offset", "-1"},
-	{ScopeGlobal, "innodb_buffer_pool_dump_at_shutdown", "OFF"},
+	{ScopeGlobal, InnodbBufferPoolDumpAtShutdown, "ON"},
 	{ScopeGlobal | ScopeSession, "sql_notes", "ON"},
 	{ScopeGlobal, "innodb_cmp_per_index_enabled", "OFF"},
 	{ScopeGlobal, "innodb_ft_server_stopword_table", ""},
@@ -203,12 +268,11 @@ var defaultSysVars = []*SysVar{
 	{ScopeNone, "ssl_key", ""},
 	{ScopeNone, "ssl_cipher", ""},
 	{ScopeNone, "tls_version", "TLSv1,TLSv1.1,TLSv1.2"},
-	{ScopeNone, "system_time_zone", "CST"},
 	{ScopeGlobal, "innodb_print_all_deadlocks", "OFF"},
 	{ScopeNone, "innodb_autoinc_lock_mode", "1"},
 	{ScopeGlobal, "slave_net_timeout", "3600"},
 	{ScopeGlobal, "key_buffer_size", "8388608"},
-	{ScopeGlobal | ScopeSession, "foreign_key_checks", "ON"},
+	{ScopeGlobal | ScopeSession, ForeignKeyChecks, "ON"},
 	{ScopeGlobal, "host_cache_size", "279"},
 	{ScopeGlobal, "delay_key_write", "ON"},
 	{ScopeNone, "metadata_locks_cache_size", "1024"},
@@ -224,7 +288,7 @@ var defaultSysVars = []*SysVar{
 	{ScopeGlobal, "max_binlog_size", "1073741824"},
 	{ScopeGlobal, "sync_master_info", "10000"},
 	{ScopeGlobal, "concurrent_insert", "AUTO"},
-	{ScopeGlobal, "innodb_adaptive_hash_index", "ON"},
+	{ScopeGlobal, InnodbAdaptiveHashIndex, "ON"},
 	{ScopeGlobal, "innodb_ft_enable_stopword", "ON"},
 	{ScopeGlobal, "general_log_file", "/usr/local/mysql/data/localhost.log"},
 	{ScopeGlobal | ScopeSession, "innodb_support_xa", "ON"},
@@ -232,7 +296,7 @@ var defaultSysVars = []*SysVar{
 	{ScopeNone, "innodb_file_format_check", "ON"},
 	{ScopeNone, "myisam_mmap_size", "18446744073709551615"},
 	{ScopeGlobal, "init_slave", ""},
-	{ScopeNone, "innodb_buffer_pool_instances", "8"},
+	{ScopeNone, InnodbBufferPoolInstances, "8"},
 	{ScopeGlobal | ScopeSession, "block_encryption_mode", "aes-128-ecb"},
 	{ScopeGlobal | ScopeSession, "max_length_for_sort_data", "1024"},
 	{ScopeNone, "character_set_system", "utf8"},
@@ -294,7 +358,7 @@ var defaultSysVars = []*SysVar{
 	{ScopeGlobal | ScopeSession, "innodb_ft_user_stopword_table", ""},
 	{ScopeNone, "server_id_bits", "32"},
 	{ScopeGlobal, "innodb_log_checksum_algorithm", ""},
-	{ScopeNone, "innodb_buffer_pool_load_at_startup", "OFF"},
+	{ScopeNone, InnodbBufferPoolLoadAtStartup, "ON"},
 	{ScopeGlobal | ScopeSession, "sort_buffer_size", "262144"},
 	{ScopeGlobal, "innodb_flush_neighbors", "1"},
 	{ScopeNone, "innodb_use_sys_malloc", "ON"},
@@ -306,13 +370,16 @@ var defaultSysVars = []*SysVar{
 	{ScopeGlobal, "slave_checkpoint_group", "512"},
 	{ScopeGlobal | ScopeSession, "character_set_client", "latin1"},
 	{ScopeNone, "slave_load_tmpdir", "/var/tmp/"},
-	{ScopeGlobal, "innodb_buffer_pool_dump_now", "OFF"},
+	{ScopeGlobal, InnodbBufferPoolDumpNow, "OFF"},
 	{ScopeGlobal, "relay_log_purge", "ON"},
 	{ScopeGlobal, "ndb_distribution", ""},
 	{ScopeGlobal, "myisam_data_pointer_size", "6"},
 	{ScopeGlobal, "ndb_optimization_delay", ""},
 	{ScopeGlobal, "innodb_ft_num_word_optimize", "2000"},
 	{ScopeGlobal | ScopeSession, "max_join_size", "18446744073709551615"},
+	{ScopeGlobal | ScopeSession, MaxExecutionTime, "0"},
+	{ScopeGlobal | ScopeSession, MaxSessionMemory, "0"},
+	{ScopeGlobal, MaxServerMemory, "0"},
 	{ScopeNone, "core_file", "OFF"},
 	{ScopeGlobal | ScopeSession, "max_seeks_for_key", "18446744073709551615"},
 	{ScopeNone, "innodb_log_buffer_size", "8388608"},
@@ -339,13 +406,13 @@ var defaultSysVars = []*SysVar{
 	{ScopeNone, "innodb_api_disable_rowlock", "OFF"},
 	{ScopeGlobal, "innodb_adaptive_flushing_lwm", "10"},
 	{ScopeNone, "innodb_log_files_in_group", "2"},
-	{ScopeGlobal, "innodb_buffer_pool_load_now", "OFF"},
+	{ScopeGlobal, InnodbBufferPoolLoadNow, "OFF"},
 	{ScopeNone, "performance_schema_max_rwlock_classes", "40"},
 	{ScopeNone, "binlog_gtid_simple_recovery", "OFF"},
 	{ScopeNone, "port", "3306"},
 	{ScopeNone, "performance_schema_digests_size", "10000"},
-	{ScopeGlobal | ScopeSession, "profiling", "OFF"},
-	{ScopeNone, "lower_case_table_names", "2"},
+	{ScopeGlobal | ScopeSession, Profiling, "OFF"},
+	{ScopeNone, LowerCaseTableNames, "0"},
 	{ScopeSession, "rand_seed1", ""},
 	{ScopeGlobal, "sha256_password_proxy_users", ""},
 	{ScopeGlobal | ScopeSession, "sql_quote_show_create", "ON"},
@@ -367,25 +434,32 @@ var defaultSysVars = []*SysVar{
 	{ScopeNone, "innodb_ft_cache_size", "8000000"},
 	{ScopeNone, "log_bin", "OFF"},
 	{ScopeGlobal, "innodb_disable_sort_file_cache", "OFF"},
-	{ScopeGlobal, "log_error_verbosity", ""},
+	{ScopeGlobal, LogErrorVerbosity, "3"},
+	{ScopeGlobal, AuditLog, "OFF"},
+	{ScopeNone, AuditLogFile, "audit.log"},
+	{ScopeGlobal, AuditLogPolicy, "FULL"},
+	{ScopeGlobal, AuditLogRotateOnSize, "0"},
 	{ScopeNone, "performance_schema_hosts_size", "100"},
 	{ScopeGlobal, "innodb_replication_delay", "0"},
 	{ScopeGlobal, "slow_query_log", "OFF"},
 	{ScopeSession, "debug_sync", ""},
-	{ScopeGlobal, "innodb_stats_auto_recalc", "ON"},
+	{ScopeGlobal, InnodbStatsAutoRecalc, "ON"},
 	{ScopeGlobal, "timed_mutexes", "OFF"},
 	{ScopeGlobal | ScopeSession, "lc_messages", "en_US"},
 	{ScopeGlobal | ScopeSession, "bulk_insert_buffer_size", "8388608"},
 	{ScopeGlobal | ScopeSession, "binlog_direct_non_transactional_updates", "OFF"},
-	{ScopeGlobal, "innodb_change_buffering", "all"},
+	{ScopeGlobal, InnodbChangeBuffering, "all"},
+	{ScopeGlobal, InnodbChangeBufferMergeNow, "OFF"},
+	{ScopeGlobal, InnodbCorruptPageAction, "error"},
+	{ScopeGlobal, InnodbDefaultRowFormat, "dynamic"},
 	{ScopeGlobal | ScopeSession, "sql_big_selects", "ON"},
 	{ScopeGlobal | ScopeSession, CharacterSetResults, "latin1"},
 	{ScopeGlobal, "innodb_max_purge_lag_delay", "0"},
-	{ScopeGlobal | ScopeSession, "session_track_schema", ""},
+	{ScopeGlobal | ScopeSession, SessionTrackSchema, "ON"},
 	{ScopeGlobal, "innodb_io_capacity_max", "2000"},
 	{ScopeGlobal, "innodb_autoextend_increment", "64"},
 	{ScopeGlobal | ScopeSession, "binlog_format", "STATEMENT"},
-	{ScopeGlobal | ScopeSession, "optimizer_trace", "enabled=off,one_line=off"},
+	{ScopeGlobal | ScopeSession, OptimizerTraceVar, "enabled=off,one_line=off"},
 	{ScopeGlobal | ScopeSession, "read_rnd_buffer_size", "262144"},
 	{ScopeNone, "version_comment", "MySQL Community Server (Apache License 2.0)"},
 	{ScopeGlobal | ScopeSession, "net_write_timeout", "60"},
@@ -406,16 +480,17 @@ var defaultSysVars = []*SysVar{
 	{ScopeNone, "performance_schema_max_mutex_instances", "15906"},
 	{ScopeGlobal, "innodb_adaptive_max_sleep_delay", "150000"},
 	{ScopeNone, "large_pages", "OFF"},
-	{ScopeGlobal | ScopeSession, "session_track_system_variables", ""},
+	{ScopeGlobal | ScopeSession, SessionTrackSystemVariables, DefSessionTrackSystemVariables},
 	{ScopeGlobal, "innodb_change_buffer_max_size", "25"},
 	{ScopeGlobal, "log_bin_trust_function_creators", "OFF"},
 	{ScopeNone, "innodb_write_io_threads", "4"},
 	{ScopeGlobal, "mysql_native_password_proxy_users", ""},
-	{ScopeGlobal, "read_only", "OFF"},
+	{ScopeGlobal, ReadOnly, "OFF"},
 	{ScopeNone, "large_page_size", "0"},
 	{ScopeNone, "table_open_cache_instances", "1"},
 	{ScopeGlobal, "innodb_stats_persistent", "ON"},
-	{ScopeGlobal | ScopeSession, "session_track_state_change", ""},
+	{ScopeGlobal | ScopeSession, SessionTrackStateChange, "OFF"},
+	{ScopeGlobal | ScopeSession, SessionTrackTransactionInfo, "OFF"},
 	{ScopeNone, "optimizer_switch", "index_merge=on,index_merge_union=on,index_merge_sort_union=on,index_merge_intersection=on,engine_condition_pushdown=on,index_condition_pushdown=on,mrr=on,mrr_cost_based=on,block_nested_loop=on,batched_key_access=off,materialization=on,semijoin=on,loosescan=on,firstmatch=on,subquery_materialization_cost_based=on,use_index_extensions=on"},
 	{ScopeGlobal, "delayed_queue_size", "1000"},
 	{ScopeNone, "innodb_read_only", "OFF"},
@@ -486,7 +561,7 @@ var defaultSysVars = []*SysVar{
 	{ScopeGlobal | ScopeSession, "optimizer_search_depth", "62"},
 	{ScopeGlobal, "max_points_in_geometry", ""},
 	{ScopeGlobal, "innodb_stats_sample_pages", "8"},
-	{ScopeGlobal | ScopeSession, "profiling_history_size", "15"},
+	{ScopeGlobal | ScopeSession, ProfilingHistorySize, "15"},
 	{ScopeGlobal | ScopeSession, "character_set_database", "latin1"},
 	{ScopeNone, "have_symlink", "YES"},
 	{ScopeGlobal | ScopeSession, "storage_engine", "InnoDB"},
@@ -494,7 +569,7 @@ var defaultSysVars = []*SysVar{
 	{ScopeNone, "explicit_defaults_for_timestamp", "OFF"},
 	{ScopeNone, "performance_schema_events_waits_history_size", "10"},
 	{ScopeGlobal, "log_syslog_tag", ""},
-	{ScopeGlobal | ScopeSession, "tx_read_only", "0"},
+	{ScopeGlobal | ScopeSession, TxReadOnly, "0"},
 	{ScopeGlobal | ScopeSession, "transaction_read_only", "0"},
 	{ScopeGlobal, "rpl_semi_sync_master_wait_point", ""},
 	{ScopeGlobal, "innodb_undo_log_truncate", ""},
@@ -504,7 +579,7 @@ var defaultSysVars = []*SysVar{
 	{ScopeGlobal, "ndb_log_empty_epochs", ""},
 	{ScopeGlobal, "max_prepared_stmt_count", "16382"},
 	{ScopeNone, "have_geometry", "YES"},
-	{ScopeGlobal | ScopeSession, "optimizer_trace_max_mem_size", "16384"},
+	{ScopeGlobal | ScopeSession, OptimizerTraceMaxMemSize, "16384"},
 	{ScopeGlobal | ScopeSession, "net_retry_count", "10"},
 	{ScopeSession, "ndb_table_no_logging", ""},
 	{ScopeGlobal | ScopeSession, "optimizer_trace_features", "greedy_search=on,range_optimizer=on,dynamic_range=on,repeated_subselect=on"},
@@ -519,13 +594,13 @@ var defaultSysVars = []*SysVar{
 	{ScopeNone, "report_port", "3306"},
 	{ScopeGlobal | ScopeSession, "show_old_temporals", "OFF"},
 	{ScopeGlobal, "query_cache_limit", "1048576"},
-	{ScopeGlobal, "innodb_buffer_pool_size", "134217728"},
+	{ScopeGlobal, InnodbBufferPoolSize, "134217728"},
 	{ScopeGlobal, "innodb_adaptive_flushing", "ON"},
 	{ScopeNone, "datadir", "/usr/local/mysql/data/"},
 	{ScopeGlobal | ScopeSession, "wait_timeout", "28800"},
 	{ScopeGlobal, "innodb_monitor_enable", ""},
 	{ScopeNone, "date_format", "%Y-%m-%d"},
-	{ScopeGlobal, "innodb_buffer_pool_filename", "ib_buffer_pool"},
+	{ScopeGlobal, InnodbBufferPoolFilename, "ib_buffer_pool"},
 	{ScopeGlobal, "slow_launch_time", "2"},
 	{ScopeGlobal, "slave_max_allowed_packet", "1073741824"},
 	{ScopeGlobal | ScopeSession, "ndb_use_transactions", ""},
@@ -539,6 +614,7 @@ var defaultSysVars = []*SysVar{
 	{ScopeGlobal, "innodb_stats_method", "nulls_equal"},
 	{ScopeGlobal | ScopeSession, "innodb_lock_wait_timeout", "50"},
 	{ScopeGlobal, "local_infile", "ON"},
+	{ScopeNone, SecureFilePriv, "NULL"},
 	{ScopeGlobal | ScopeSession, "myisam_stats_method", "nulls_unequal"},
 	{ScopeNone, "version_compile_os", "osx10.8"},
 	{ScopeNone, "relay_log_recovery", "OFF"},
@@ -546,7 +622,7 @@ var defaultSysVars = []*SysVar{
 	{ScopeGlobal | ScopeSession, "innodb_table_locks", "ON"},
 	{ScopeNone, "performance_schema", "ON"},
 	{ScopeNone, "myisam_recover_options", "OFF"},
-	{ScopeGlobal | ScopeSession, "net_buffer_length", "16384"},
+	{ScopeGlobal | ScopeSession, NetBufferLength, "16384"},
 	{ScopeGlobal, "rpl_semi_sync_master_wait_for_slave_count", ""},
 	{ScopeGlobal | ScopeSession, "binlog_row_image", "FULL"},
 	{ScopeNone, "innodb_locks_unsafe_for_binlog", "OFF"},
@@ -555,7 +631,9 @@ var defaultSysVars = []*SysVar{
 	{ScopeNone, "back_log", "80"},
 	{ScopeNone, "lower_case_file_system", "ON"},
 	{ScopeGlobal, "rpl_semi_sync_master_wait_no_slave", ""},
-	{ScopeGlobal | ScopeSession, "group_concat_max_len", "1024"},
+	{ScopeGlobal | ScopeSession, GroupConcatMaxLen, "1024"},
+	{ScopeGlobal | ScopeSession, AutocommitDeadlockRetries, "0"},
+	{ScopeGlobal, StatsAutoRecalcThreshold, "0.1"},
 	{ScopeSession, "pseudo_thread_id", ""},
 	{ScopeNone, "socket", "/tmp/myssock"},
 	{ScopeNone, "have_dynamic_loading", "YES"},
@@ -575,7 +653,7 @@ var defaultSysVars = []*SysVar{
 	{ScopeGlobal, "slave_rows_search_algorithms", "TABLE_SCAN,INDEX_SCAN"},
 	{ScopeGlobal | ScopeSession, "ndbinfo_show_hidden", ""},
 	{ScopeGlobal | ScopeSession, "net_read_timeout", "30"},
-	{ScopeNone, "innodb_page_size", "16384"},
+	{ScopeNone, InnodbPageSize, "16384"},
 	{ScopeGlobal, MaxAllowedPacket, "67108864"},
 	{ScopeNone, "innodb_log_file_size", "50331648"},
 	{ScopeGlobal, "sync_relay_log_info", "10000"},
@@ -619,9 +697,21 @@ var defaultSysVars = []*SysVar{
 	{ScopeSession, TiDBBatchInsert, boolToIntStr(DefBatchInsert)},
 	{ScopeSession, TiDBBatchDelete, boolToIntStr(DefBatchDelete)},
 	{ScopeSession, TiDBDMLBatchSize, strconv.Itoa(DefDMLBatchSize)},
-	{ScopeSession, TiDBCurrentTS, strconv.Itoa(DefCurretTS)},
-	/* The following variable is defined as session scope but is actually server scope. */
-	{ScopeSession, TiDBGeneralLog, strconv.Itoa(DefTiDBGeneralLog)},
+}
+
+// registerComputedSysVars registers the system variables computed when read.
+func registerComputedSysVars() {
+	RegisterSysVar(&SysVar{ScopeNone, SystemTimeZone, ""}, mysql.TypeVarString, func(*SessionVars) (string, error) {
+		name, _ := time.Now().Zone()
+		return name, nil
+	})
+	RegisterSysVar(&SysVar{ScopeSession, TiDBCurrentTS, strconv.Itoa(DefCurretTS)}, mysql.TypeLonglong, func(s *SessionVars) (string, error) {
+		return strconv.FormatUint(s.TxnCtx.StartTS, 10), nil
+	})
+	// tidb_general_log is defined as session scope but is actually server scope.
+	RegisterSysVar(&SysVar{ScopeSession, TiDBGeneralLog, strconv.Itoa(DefTiDBGeneralLog)}, mysql.TypeLonglong, func(*SessionVars) (string, error) {
+		return strconv.FormatUint(uint64(atomic.LoadUint32(&ProcessGeneralLog)), 10), nil
+	})
 }
 
 // SynonymsSysVariables is synonyms of system variables.
@@ -654,6 +744,8 @@ const (
 	CharsetDatabase = "character_set_database"
 	// CollationDatabase is the name for collation_database system variable.
 	CollationDatabase = "collation_database"
+	// SystemTimeZone is the name for system_time_zone system variable.
+	SystemTimeZone = "system_time_zone"
 )
 
 // GlobalVarAccessor is the interface for accessing global scope system and status variables.
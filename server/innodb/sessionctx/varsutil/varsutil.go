@@ -35,7 +35,7 @@ func GetSessionSystemVar(s *variable.SessionVars, key string) (string, error) {
 	if err != nil || ok {
 		return gVal, errors.Trace(err)
 	}
-	gVal, err = s.GlobalVarsAccessor.GetGlobalSysVar(key)
+	gVal, err = getGlobalSysVar(s, key)
 	if err != nil {
 		return "", errors.Trace(err)
 	}
@@ -51,11 +51,9 @@ func GetSessionOnlySysVars(s *variable.SessionVars, key string) (string, bool, e
 		return "", false, variable.UnknownSystemVar.GenByArgs(key)
 	}
 	// For virtual system variables:
-	switch sysVar.Name {
-	case variable.TiDBCurrentTS:
-		return fmt.Sprintf("%d", s.TxnCtx.StartTS), true, nil
-	case variable.TiDBGeneralLog:
-		return fmt.Sprintf("%d", atomic.LoadUint32(&variable.ProcessGeneralLog)), true, nil
+	if getter := variable.GetSysVarGetter(key); getter != nil {
+		val, err := getter(s)
+		return val, true, errors.Trace(err)
 	}
 	sVal, ok := s.Systems[key]
 	if ok {
@@ -71,17 +69,30 @@ func GetSessionOnlySysVars(s *variable.SessionVars, key string) (string, bool, e
 // GetGlobalSystemVar gets a global system variable.
 func GetGlobalSystemVar(s *variable.SessionVars, key string) (string, error) {
 	key = strings.ToLower(key)
+	if getter := variable.GetSysVarGetter(key); getter != nil {
+		val, err := getter(s)
+		return val, errors.Trace(err)
+	}
 	gVal, ok, err := GetScopeNoneSystemVar(key)
 	if err != nil || ok {
 		return gVal, errors.Trace(err)
 	}
-	gVal, err = s.GlobalVarsAccessor.GetGlobalSysVar(key)
+	gVal, err = getGlobalSysVar(s, key)
 	if err != nil {
 		return "", errors.Trace(err)
 	}
 	return gVal, nil
 }
 
+// getGlobalSysVar reads the global value of the system variable key, the
+// default value defined in code when there is no storage for global values.
+func getGlobalSysVar(s *variable.SessionVars, key string) (string, error) {
+	if s.GlobalVarsAccessor == nil {
+		return variable.SysVars[key].Value, nil
+	}
+	return s.GlobalVarsAccessor.GetGlobalSysVar(key)
+}
+
 // GetScopeNoneSystemVar checks the validation of `key`,
 // and return the default value if its scope is `ScopeNone`.
 func GetScopeNoneSystemVar(key string) (string, bool, error) {
@@ -95,6 +106,39 @@ func GetScopeNoneSystemVar(key string) (string, bool, error) {
 	return "", false, nil
 }
 
+// SetGlobalSystemVar sets the global value of a system variable: its setter
+// applies it to the server, then it is stored for the sessions to come.
+func SetGlobalSystemVar(vars *variable.SessionVars, name string, value types.Datum) error {
+	name = strings.ToLower(name)
+	sysVar := variable.SysVars[name]
+	if sysVar == nil {
+		return variable.UnknownSystemVar.GenByArgs(name)
+	}
+	if sysVar.Scope&variable.ScopeGlobal == 0 {
+		return variable.ErrIncorrectScope
+	}
+	if value.IsNull() {
+		return variable.ErrCantSetToNull
+	}
+	sVal, err := value.ToString()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if setter := variable.GetSysVarSetter(name); setter != nil {
+		if err = setter(vars, sVal); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	if vars.GlobalVarsAccessor == nil {
+		sysVar.Value = sVal
+		for _, synonym := range variable.SynonymsSysVariables[name] {
+			variable.SysVars[synonym].Value = sVal
+		}
+		return nil
+	}
+	return errors.Trace(vars.GlobalVarsAccessor.SetGlobalSysVar(name, sVal))
+}
+
 // epochShiftBits is used to reserve logical part of the timestamp.
 const epochShiftBits = 18
 
@@ -110,6 +154,7 @@ func SetSessionSystemVar(vars *variable.SessionVars, name string, value types.Da
 			return variable.ErrCantSetToNull
 		}
 		delete(vars.Systems, name)
+		vars.ResultsCharset = ""
 		return nil
 	}
 	sVal, err := value.ToString()
@@ -117,6 +162,8 @@ func SetSessionSystemVar(vars *variable.SessionVars, name string, value types.Da
 		return errors.Trace(err)
 	}
 	switch name {
+	case variable.CharacterSetResults:
+		vars.ResultsCharset = strings.ToLower(sVal)
 	case variable.TimeZone:
 		vars.TimeZone, err = parseTimeZone(sVal)
 		if err != nil {
@@ -131,6 +178,7 @@ func SetSessionSystemVar(vars *variable.SessionVars, name string, value types.Da
 		}
 		vars.StrictSQLMode = sqlMode.HasStrictMode()
 		vars.SQLMode = sqlMode
+		vars.SetStatusFlag(mysql.ServerStatusNoBackslashEscaped, sqlMode.HasNoBackslashEscapesMode())
 	case variable.TiDBSnapshot:
 		err = setSnapshotTS(vars, sVal)
 		if err != nil {
@@ -172,8 +220,30 @@ func SetSessionSystemVar(vars *variable.SessionVars, name string, value types.Da
 		return variable.ErrReadOnly
 	case variable.TiDBGeneralLog:
 		atomic.StoreUint32(&variable.ProcessGeneralLog, uint32(tidbOptPositiveInt(sVal, variable.DefTiDBGeneralLog)))
+	case variable.OptimizerTraceVar:
+		sVal, err = setOptimizerTrace(vars, sVal)
+		if err != nil {
+			return errors.Trace(err)
+		}
+	case variable.OptimizerTraceMaxMemSize:
+		vars.OptimizerTraceMaxMemSize = tidbOptPositiveInt(sVal, variable.DefOptimizerTraceMaxMemSize)
+	case variable.ForeignKeyChecks:
+		vars.ForeignKeyChecks = tidbOptOn(sVal)
+	case variable.Profiling:
+		vars.Profiling = tidbOptOn(sVal)
+	case variable.ProfilingHistorySize:
+		size, err := strconv.Atoi(sVal)
+		if err != nil {
+			return variable.ErrWrongValueForVar.GenByArgs(name, sVal)
+		}
+		vars.SetProfilingHistorySize(size)
+		sVal = strconv.Itoa(vars.ProfilingHistorySize)
 	}
 	vars.Systems[name] = sVal
+	for _, synonym := range variable.SynonymsSysVariables[name] {
+		vars.Systems[synonym] = sVal
+	}
+	vars.TrackSystemVar(name, sVal)
 	return nil
 }
 
@@ -190,6 +260,41 @@ func tidbOptPositiveInt(opt string, defaultVal int) int {
 	return val
 }
 
+// setOptimizerTrace sets the flags of optimizer_trace given as a comma
+// separated list of flag=on|off, or as default. The flags left out keep
+// their value. It returns the value of the variable with all the flags.
+func setOptimizerTrace(vars *variable.SessionVars, s string) (string, error) {
+	enabled, oneLine := vars.EnableOptimizerTrace, vars.OptimizerTraceOneLine
+	for _, item := range strings.Split(s, ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item == "default" {
+			enabled, oneLine = false, false
+			continue
+		}
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 || (kv[1] != "on" && kv[1] != "off") {
+			return "", variable.ErrWrongValueForVar.GenByArgs(variable.OptimizerTraceVar, s)
+		}
+		switch strings.TrimSpace(kv[0]) {
+		case "enabled":
+			enabled = kv[1] == "on"
+		case "one_line":
+			oneLine = kv[1] == "on"
+		default:
+			return "", variable.ErrWrongValueForVar.GenByArgs(variable.OptimizerTraceVar, s)
+		}
+	}
+	vars.EnableOptimizerTrace, vars.OptimizerTraceOneLine = enabled, oneLine
+	return fmt.Sprintf("enabled=%s,one_line=%s", onOff(enabled), onOff(oneLine)), nil
+}
+
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
+
 func parseTimeZone(s string) (*time.Location, error) {
 	if s == "SYSTEM" {
 		// TODO: Support global time_zone variable, it should be set to global time_zone value.
@@ -0,0 +1,174 @@
+package statistics
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/juju/errors"
+	types "github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/codec"
+)
+
+// AnalyzeTable builds the statistics of tbl from all its rows, as ANALYZE
+// TABLE does, and replaces the cached ones.
+func (h *Handle) AnalyzeTable(sc *variable.StatementContext, tbl *model.TableInfo, rows [][]types.Datum) error {
+	t, err := BuildTable(sc, tbl, rows)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	// The rows counted are the new start of the live row count.
+	t.Version = h.rowCounts.set(tbl.ID, t.Count)
+	h.UpdateTableStats([]*Table{t}, nil)
+	return nil
+}
+
+// AnalyzeTableSample builds the statistics of tbl, which has count rows,
+// from sample, a uniform sample of its rows, and replaces the cached ones.
+func (h *Handle) AnalyzeTableSample(sc *variable.StatementContext, tbl *model.TableInfo, sample [][]types.Datum, count int64) error {
+	t, err := BuildTable(sc, tbl, sample)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	t.scale(count)
+	t.Version = h.rowCounts.set(tbl.ID, t.Count)
+	h.UpdateTableStats([]*Table{t}, nil)
+	return nil
+}
+
+// scale scales the statistics of t built from a sample of its rows to the
+// count rows of the table. The numbers of distinct values are scaled only
+// when the sample has no value twice, the mark of a unique key; a value
+// seen several times is assumed to repeat as often in the rest of the rows.
+func (t *Table) scale(count int64) {
+	n := t.Count
+	t.Count = count
+	if n == 0 || n >= count {
+		return
+	}
+	scale := func(v int64) int64 { return v * count / n }
+	scaleHist := func(hg *Histogram) {
+		for i := range hg.Buckets {
+			hg.Buckets[i].Count = scale(hg.Buckets[i].Count)
+			hg.Buckets[i].Repeats = scale(hg.Buckets[i].Repeats)
+		}
+		hg.NullCount = scale(hg.NullCount)
+		if hg.NDV == n {
+			hg.NDV = count
+		}
+	}
+	for _, col := range t.Columns {
+		scaleHist(&col.Histogram)
+	}
+	for _, idx := range t.Indices {
+		scaleHist(&idx.Histogram)
+		for i, ndv := range idx.PrefixNDV {
+			if ndv == n {
+				idx.PrefixNDV[i] = count
+			}
+		}
+	}
+}
+
+// BuildTable builds the statistics of tbl from all its rows: the histogram
+// of the integer primary key and of each public index.
+func BuildTable(sc *variable.StatementContext, tbl *model.TableInfo, rows [][]types.Datum) (*Table, error) {
+	t := &Table{
+		TableID: tbl.ID,
+		Count:   int64(len(rows)),
+		Columns: make(map[int64]*Column),
+		Indices: make(map[int64]*Index),
+	}
+	if tbl.PKIsHandle {
+		if pk := tbl.GetPkColInfo(); pk != nil {
+			hist, _, err := buildSortedHistogram(sc, pk.ID, rows, []int{pk.Offset})
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			t.Columns[pk.ID] = &Column{Histogram: *hist, Info: pk}
+		}
+	}
+	for _, idx := range tbl.Indices {
+		if idx.State != model.StatePublic {
+			continue
+		}
+		offsets := make([]int, 0, len(idx.Columns))
+		for _, col := range idx.Columns {
+			offsets = append(offsets, col.Offset)
+		}
+		hist, prefixNDV, err := buildSortedHistogram(sc, idx.ID, rows, offsets)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		t.Indices[idx.ID] = &Index{Histogram: *hist, Info: idx, PrefixNDV: prefixNDV}
+	}
+	return t, nil
+}
+
+// indexKey is the encoded key of a row in an index, with the end of the
+// encoding of each of its columns.
+type indexKey struct {
+	key  []byte
+	ends []int
+}
+
+// buildSortedHistogram builds the histogram of the values of the columns at
+// offsets in rows, and counts the distinct values of each leading prefix of
+// these columns. The encoding of a prefix is a prefix of the encoding of the
+// key, so equal prefixes are adjacent once the keys are sorted.
+func buildSortedHistogram(sc *variable.StatementContext, id int64, rows [][]types.Datum, offsets []int) (*Histogram, []int64, error) {
+	keys := make([]indexKey, 0, len(rows))
+	for _, row := range rows {
+		k := indexKey{ends: make([]int, 0, len(offsets))}
+		for _, offset := range offsets {
+			var err error
+			if k.key, err = codec.EncodeKey(k.key, row[offset]); err != nil {
+				return nil, nil, errors.Trace(err)
+			}
+			k.ends = append(k.ends, len(k.key))
+		}
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i].key, keys[j].key) < 0 })
+
+	b := NewSortedBuilder(sc, defaultBucketCount, id)
+	prefixNDV := make([]int64, len(offsets))
+	for i, k := range keys {
+		for j, end := range k.ends {
+			if i == 0 || !bytes.Equal(k.key[:end], keys[i-1].key[:keys[i-1].ends[j]]) {
+				prefixNDV[j]++
+			}
+		}
+		if err := b.Iterate(types.NewBytesDatum(k.key)); err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+	}
+	return b.Hist(), prefixNDV, nil
+}
+
+// IndexCardinality returns the number of distinct values of the first seq
+// columns of idx in tbl, or of its integer primary key when idx is nil. It
+// is false when ANALYZE TABLE has not measured them.
+func (h *Handle) IndexCardinality(tbl *model.TableInfo, idx *model.IndexInfo, seq int) (int64, bool) {
+	t, ok := h.statsCache.Load().(statsCache)[tbl.ID]
+	if !ok || t.Pseudo {
+		return 0, false
+	}
+	if idx == nil {
+		pk := tbl.GetPkColInfo()
+		if pk == nil {
+			return 0, false
+		}
+		col, ok := t.Columns[pk.ID]
+		if !ok {
+			return 0, false
+		}
+		return col.NDV, true
+	}
+	stats, ok := t.Indices[idx.ID]
+	if !ok || seq < 1 || seq > len(stats.PrefixNDV) {
+		return 0, false
+	}
+	return stats.PrefixNDV[seq-1], true
+}
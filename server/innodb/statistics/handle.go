@@ -44,6 +44,8 @@ type Handle struct {
 	listHead *SessionStatsCollector
 	// globalMap contains all the delta map from collectors when we dump them to KV.
 	globalMap tableDeltaMap
+	// rowCounts are the live row counts of the tables.
+	rowCounts *rowCounts
 
 	Lease time.Duration
 }
@@ -61,6 +63,7 @@ func (h *Handle) Clear() {
 	}
 	h.listHead = &SessionStatsCollector{mapper: make(tableDeltaMap)}
 	h.globalMap = make(tableDeltaMap)
+	h.rowCounts = &rowCounts{tables: make(map[int64]*liveRowCount)}
 }
 
 // NewHandle creates a Handle for update stats.
@@ -71,6 +74,7 @@ func NewHandle(ctx context.Context, lease time.Duration) *Handle {
 		analyzeResultCh: make(chan *AnalyzeResult, 100),
 		listHead:        &SessionStatsCollector{mapper: make(tableDeltaMap)},
 		globalMap:       make(tableDeltaMap),
+		rowCounts:       &rowCounts{tables: make(map[int64]*liveRowCount)},
 		Lease:           lease,
 	}
 	handle.statsCache.Store(statsCache{})
@@ -122,10 +126,15 @@ func (h *Handle) Update(is schemas.InfoSchema) error {
 }
 
 // GetTableStats retrieves the statistics table from cache, and the cache will be updated by a goroutine.
+// The live row count of the table replaces its count when it is fresher.
 func (h *Handle) GetTableStats(tblID int64) *Table {
 	tbl, ok := h.statsCache.Load().(statsCache)[tblID]
 	if !ok {
-		return PseudoTable(tblID)
+		tbl = PseudoTable(tblID)
+	}
+	if live, ok := h.rowCounts.get(tblID); ok && live.version > tbl.Version {
+		tbl = tbl.copy()
+		tbl.Count, tbl.ModifyCount, tbl.Version = live.Count, live.ModifyCount, live.version
 	}
 	return tbl
 }
@@ -487,6 +487,9 @@ func (c *Column) getColumnRowCount(sc *variable.StatementContext, ranges []*type
 type Index struct {
 	Histogram
 	Info *model.IndexInfo
+	// PrefixNDV is the number of distinct values of each leading prefix of
+	// the index columns, the last one equal to NDV.
+	PrefixNDV []int64
 }
 
 func (idx *Index) String() string {
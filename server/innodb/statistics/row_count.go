@@ -0,0 +1,158 @@
+package statistics
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/juju/errors"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+)
+
+/**
+表的实时行数
+
+INSERT 和 DELETE 把每个表增加和删除的行数记在事务的 TableDeltaMap 中，事务提交时由
+ApplyDelta 加到表的实时行数上，回滚时丢弃。ANALYZE TABLE 数出表的准确行数后，实时行数
+以它为新的起点。
+
+实时行数比表的统计信息新时，GetTableStats 用它作为表的行数，优化器的代价估算和
+SHOW TABLE STATUS 因此不必等到下一次 ANALYZE。COUNT(*) 仍然扫描表，这里只是估算。
+
+实时行数定期写入数据目录中的文件，重启后从文件读入，从接近的值开始。
+**/
+
+// liveRowCount is the row count of a table kept up to date by the
+// committed transactions.
+type liveRowCount struct {
+	Count       int64 `json:"count"`
+	ModifyCount int64 `json:"modify_count"`
+	// version orders the count with the statistics of the table: the
+	// count is fresher when its version is greater.
+	version uint64
+}
+
+// rowCounts are the live row counts of the tables.
+type rowCounts struct {
+	sync.Mutex
+	tables  map[int64]*liveRowCount
+	version uint64
+	// dirty is set when the counts changed since they were saved.
+	dirty bool
+}
+
+// set sets the count of the table id and returns its new version.
+func (c *rowCounts) set(id int64, count int64) uint64 {
+	c.Lock()
+	defer c.Unlock()
+	c.version++
+	c.tables[id] = &liveRowCount{Count: count, version: c.version}
+	c.dirty = true
+	return c.version
+}
+
+// get returns a copy of the count of the table id.
+func (c *rowCounts) get(id int64) (liveRowCount, bool) {
+	c.Lock()
+	defer c.Unlock()
+	live, ok := c.tables[id]
+	if !ok {
+		return liveRowCount{}, false
+	}
+	return *live, true
+}
+
+// ApplyDelta adds the rows inserted and deleted by a committed transaction
+// to the live row counts of their tables.
+func (h *Handle) ApplyDelta(deltas map[int64]variable.TableDelta) {
+	if len(deltas) == 0 {
+		return
+	}
+	c := h.rowCounts
+	c.Lock()
+	defer c.Unlock()
+	for id, delta := range deltas {
+		live, ok := c.tables[id]
+		if !ok {
+			live = &liveRowCount{}
+			c.tables[id] = live
+		}
+		live.Count += delta.Delta
+		if live.Count < 0 {
+			live.Count = 0
+		}
+		live.ModifyCount += delta.Count
+		c.version++
+		live.version = c.version
+	}
+	c.dirty = true
+}
+
+// RowCount returns the live row count of the table id. It is false when
+// no transaction changed the table and it wasn't analyzed.
+func (h *Handle) RowCount(tableID int64) (int64, bool) {
+	live, ok := h.rowCounts.get(tableID)
+	return live.Count, ok
+}
+
+// ModifiedRows returns the number of rows of the table id inserted, updated
+// and deleted since it was last analyzed, and its row count then, 0 when it
+// never was.
+func (h *Handle) ModifiedRows(tableID int64) (modified, analyzed int64) {
+	if tbl, ok := h.statsCache.Load().(statsCache)[tableID]; ok && !tbl.Pseudo {
+		analyzed = tbl.Count
+	}
+	live, _ := h.rowCounts.get(tableID)
+	return live.ModifyCount, analyzed
+}
+
+// SaveRowCounts writes the live row counts to path when they changed since
+// they were last saved. The file is replaced only once it is complete.
+func (h *Handle) SaveRowCounts(path string) (bool, error) {
+	c := h.rowCounts
+	c.Lock()
+	if !c.dirty {
+		c.Unlock()
+		return false, nil
+	}
+	content, err := json.Marshal(c.tables)
+	c.dirty = false
+	c.Unlock()
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	tmp := path + ".tmp"
+	if err = ioutil.WriteFile(tmp, content, 0640); err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		c.Lock()
+		c.dirty = true
+		c.Unlock()
+		return false, errors.Trace(err)
+	}
+	return true, nil
+}
+
+// LoadRowCounts reads the live row counts saved in path. They are fresher
+// than the statistics cached before.
+func (h *Handle) LoadRowCounts(path string) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	tables := make(map[int64]*liveRowCount)
+	if err = json.Unmarshal(content, &tables); err != nil {
+		return errors.Trace(err)
+	}
+	c := h.rowCounts
+	c.Lock()
+	defer c.Unlock()
+	for id, live := range tables {
+		c.version++
+		live.version = c.version
+		c.tables[id] = live
+	}
+	return nil
+}
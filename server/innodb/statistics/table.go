@@ -20,6 +20,7 @@ import (
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/model"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
 	"math"
+	"sort"
 	"strings"
 )
 
@@ -29,11 +30,13 @@ const (
 
 	// When we haven't analyzed a table, we use pseudo statistics to estimate costs.
 	// It has row count 10000, equal condition selects 1/1000 of total rows, less condition selects 1/3 of total rows,
-	// between condition selects 1/40 of total rows.
-	pseudoRowCount    = 10000
-	pseudoEqualRate   = 1000
-	pseudoLessRate    = 3
-	pseudoBetweenRate = 40
+	// between condition selects 1/40 of total rows. The columns of an index are taken to be correlated,
+	// so a column after the most selective one of a range selects at least 1/100 of the rows.
+	pseudoRowCount        = 10000
+	pseudoEqualRate       = 1000
+	pseudoLessRate        = 3
+	pseudoBetweenRate     = 40
+	pseudoCorrelationRate = 100
 )
 
 // Table represents statistics for a table.
@@ -254,7 +257,6 @@ func getPseudoRowCountByIndexRanges(sc *variable.StatementContext, indexRanges [
 	}
 	var totalCount float64
 	for _, indexRange := range indexRanges {
-		count := tableRowCount
 		i, err := indexRange.PrefixEqualLen(sc)
 		if err != nil {
 			return 0, errors.Trace(err)
@@ -262,18 +264,19 @@ func getPseudoRowCountByIndexRanges(sc *variable.StatementContext, indexRanges [
 		if i >= len(indexRange.LowVal) {
 			i = len(indexRange.LowVal) - 1
 		}
+		// The columns before the i-th one are equal to a value, the i-th one
+		// is in a range.
+		selectivities := make([]float64, 0, i+1)
+		for j := 0; j < i; j++ {
+			selectivities = append(selectivities, 1.0/pseudoEqualRate)
+		}
 		colRange := []*types.ColumnRange{{Low: indexRange.LowVal[i], High: indexRange.HighVal[i]}}
 		rowCount, err := getPseudoRowCountByColumnRanges(sc, tableRowCount, colRange)
 		if err != nil {
 			return 0, errors.Trace(err)
 		}
-		count = count / tableRowCount * rowCount
-		// If the condition is a = 1, b = 1, c = 1, d = 1, we think every a=1, b=1, c=1 only filtrate 1/100 data,
-		// so as to avoid collapsing too fast.
-		for j := 0; j < i; j++ {
-			count = count / float64(100)
-		}
-		totalCount += count
+		selectivities = append(selectivities, rowCount/tableRowCount)
+		totalCount += tableRowCount * combineSelectivities(selectivities)
 	}
 	if totalCount > tableRowCount {
 		totalCount = tableRowCount / 3.0
@@ -281,6 +284,24 @@ func getPseudoRowCountByIndexRanges(sc *variable.StatementContext, indexRanges [
 	return totalCount, nil
 }
 
+// combineSelectivities returns the selectivity of the conditions on the
+// columns of an index of the given selectivities. It is their product, but as
+// the columns are correlated, every column but the most selective one selects
+// at least 1/pseudoCorrelationRate of the rows, so as to avoid collapsing too
+// fast: a = 1 AND b = 1 selects 1/1000 * 1/100 of the rows, not 1/1000000.
+func combineSelectivities(selectivities []float64) float64 {
+	if len(selectivities) == 0 {
+		return 1
+	}
+	sorted := append([]float64(nil), selectivities...)
+	sort.Float64s(sorted)
+	result := sorted[0]
+	for _, s := range sorted[1:] {
+		result *= math.Max(s, 1.0/pseudoCorrelationRate)
+	}
+	return result
+}
+
 func getPseudoRowCountByColumnRanges(sc *variable.StatementContext, tableRowCount float64, columnRanges []*types.ColumnRange) (float64, error) {
 	var rowCount float64
 	var err error
@@ -1,6 +1,8 @@
 package tuple
 
 import (
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/charset"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
 	"github.com/zhukovaskychina/xmysql-server/util"
 )
 
@@ -85,8 +87,38 @@ type FormColumnsWrapper struct {
 	FieldLength       int16
 	FieldCommentValue string
 	FieldDefaultValue interface{}
+	//字符集，VARCHAR/CHAR 的 FieldLength 是字符数而不是字节数
+	FieldCharset string
+	//ENUM 和 SET 的成员，ENUM 的值在记录中保存为成员的序号
+	FieldElems []string
 }
 
+//GetMaxByteLength 返回列值在页面中最多占用的字节数。
+//VARCHAR(n)/CHAR(n) 的 n 是字符数，utf8mb4 下一个字符最多 4 个字节，
+//所以 VARCHAR(32) 需要预留 128 个字节。
+func (formColumnsWrapper *FormColumnsWrapper) GetMaxByteLength() int {
+	switch formColumnsWrapper.FieldType {
+	case "VARCHAR", "CHAR", "TEXT":
+		cs := formColumnsWrapper.FieldCharset
+		if cs == "" {
+			cs = charset.CharsetUTF8MB4
+		}
+		return int(formColumnsWrapper.FieldLength) * charset.GetMaxBytesPerChar(cs)
+	case "ENUM":
+		// FieldLength of ENUM and SET is the number of members.
+		return mysql.GetEnumSetStorageSize(mysql.TypeEnum, int(formColumnsWrapper.FieldLength))
+	case "SET":
+		return mysql.GetEnumSetStorageSize(mysql.TypeSet, int(formColumnsWrapper.FieldLength))
+	case "BIT":
+		// FieldLength of BIT is the number of bits.
+		return (int(formColumnsWrapper.FieldLength) + 7) / 8
+	default:
+		return int(formColumnsWrapper.FieldLength)
+	}
+}
+
+//ToBytes 返回列在 .frm 中的定义，ParseContent 按同样的顺序读回：
+//4 个标志位、类型名、列名、长度、注释、默认值和字符集。
 func (formColumnsWrapper *FormColumnsWrapper) ToBytes() []byte {
 	var buff = make([]byte, 0)
 
@@ -95,16 +127,13 @@ func (formColumnsWrapper *FormColumnsWrapper) ToBytes() []byte {
 	buff = append(buff, convertBoolToByte(formColumnsWrapper.NotNull))
 	buff = append(buff, convertBoolToByte(formColumnsWrapper.ZeroFill))
 	//buff = append(buff, convertBoolToByte(formColumnsWrapper.AutoIncrementVal))
-	buff = append(buff, util.ConvertUInt4Bytes(uint32(RefTypeValue[formColumnsWrapper.FieldType]))...)
-	buff = append(buff, util.ConvertInt4Bytes(int32(formColumnsWrapper.FieldTypeIntValue))...)
-	buff = append(buff, []byte(formColumnsWrapper.FieldName)...)
-	buff = append(buff, util.ConvertUInt2Bytes(uint16(formColumnsWrapper.FieldLength))...)
-	buff = append(buff, util.ConvertUInt4Bytes(uint32(len([]byte(formColumnsWrapper.FieldCommentValue))))...)
-	buff = append(buff, []byte(formColumnsWrapper.FieldCommentValue)...)
+	buff = util.WriteWithNull(buff, []byte(formColumnsWrapper.FieldType))
+	buff = util.WriteWithNull(buff, []byte(formColumnsWrapper.FieldName))
+	buff = util.WriteUB2(buff, uint16(formColumnsWrapper.FieldLength))
+	buff = util.WriteWithLength(buff, []byte(formColumnsWrapper.FieldCommentValue))
 	fieldDefaultValueBytes, _ := util.GetBytes(formColumnsWrapper.FieldDefaultValue)
-	buff = append(buff, fieldDefaultValueBytes...)
-
-	buff = append(buff)
+	buff = util.WriteWithLength(buff, fieldDefaultValueBytes)
+	buff = util.WriteWithNull(buff, []byte(formColumnsWrapper.FieldCharset))
 	return buff
 }
 
@@ -136,18 +165,20 @@ func (formColumnsWrapper *FormColumnsWrapper) ParseContent(content []byte) {
 	formColumnsWrapper.AutoIncrement = convertByteToBool(content[1])
 	formColumnsWrapper.NotNull = convertByteToBool(content[2])
 	formColumnsWrapper.ZeroFill = convertByteToBool(content[3])
-	var cursor = 0
-	cursor, fieldType := util.ReadStringWithNull(content[3:], cursor)
+	var cursor = 4
+	cursor, fieldType := util.ReadStringWithNull(content, cursor)
 	formColumnsWrapper.FieldType = fieldType
 	formColumnsWrapper.FieldTypeIntValue = int(RefTypeValue[fieldType])
-	cursor, fieldName := util.ReadStringWithNull(content[3:], cursor)
+	cursor, fieldName := util.ReadStringWithNull(content, cursor)
 	formColumnsWrapper.FieldName = fieldName
-	cursor, fieldLength := util.ReadUB2(content[3:], cursor)
+	cursor, fieldLength := util.ReadUB2(content, cursor)
 	formColumnsWrapper.FieldLength = int16(fieldLength)
-	cursor, fieldComment := util.ReadStringWithNull(content[3:], cursor)
+	cursor, fieldComment := util.ReadLengthString(content, cursor)
 	formColumnsWrapper.FieldCommentValue = fieldComment
-	cursor, defaultBytes := util.ReadBytesWithNull(content[3:], cursor)
+	cursor, defaultBytes := util.ReadBytesWithNull(content, cursor)
 	formColumnsWrapper.FieldDefaultValue = defaultBytes
+	_, fieldCharset := util.ReadStringWithNull(content, cursor)
+	formColumnsWrapper.FieldCharset = fieldCharset
 }
 
 func convertBoolToByte(val bool) byte {
@@ -0,0 +1,34 @@
+package tuple
+
+import "testing"
+
+func TestGetMaxByteLength(t *testing.T) {
+	tbl := []struct {
+		fieldType string
+		charset   string
+		length    int16
+		expect    int
+	}{
+		{"VARCHAR", "utf8mb4", 32, 128},
+		{"VARCHAR", "utf8", 32, 96},
+		{"VARCHAR", "latin1", 32, 32},
+		{"VARCHAR", "", 32, 128},
+		{"CHAR", "utf8mb4", 10, 40},
+		{"INT", "", 4, 4},
+		{"ENUM", "", 2, 1},
+		{"ENUM", "", 300, 2},
+		{"SET", "", 8, 1},
+		{"SET", "", 20, 3},
+		{"SET", "", 40, 8},
+		{"BIT", "", 1, 1},
+		{"BIT", "", 8, 1},
+		{"BIT", "", 12, 2},
+		{"BIT", "", 64, 8},
+	}
+	for _, test := range tbl {
+		col := &FormColumnsWrapper{FieldType: test.fieldType, FieldCharset: test.charset, FieldLength: test.length}
+		if got := col.GetMaxByteLength(); got != test.expect {
+			t.Errorf("%s(%d) %s: got %d bytes, want %d", test.fieldType, test.length, test.charset, got, test.expect)
+		}
+	}
+}
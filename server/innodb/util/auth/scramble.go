@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"io"
+
+	"github.com/juju/errors"
+)
+
+/**
+握手和认证使用同一个盐
+
+每个连接在握手时由 ScrambleManager 生成 20 个字节的随机盐，保存在连接的会话上：
+前 8 个字节放在握手报文的 auth-plugin-data-part-1，后 12 个字节放在
+auth-plugin-data-part-2。客户端用这个盐计算 mysql_native_password 的应答，服务端
+用 VerifyNativePassword 和同一个盐验证，盐不会在握手之后重新生成。
+**/
+
+// ScrambleLength is the length of the salt of mysql_native_password.
+const ScrambleLength = 20
+
+// ScrambleManager generates the salts sent in the handshakes.
+type ScrambleManager struct {
+	rand io.Reader
+}
+
+// NewScrambleManager returns a ScrambleManager reading random bytes from r,
+// crypto/rand when r is nil.
+func NewScrambleManager(r io.Reader) *ScrambleManager {
+	if r == nil {
+		r = rand.Reader
+	}
+	return &ScrambleManager{rand: r}
+}
+
+// NewSalt returns a new salt of ScrambleLength bytes. Like MySQL's, its
+// bytes are printable ASCII without '$', which clients may handle as
+// strings.
+func (m *ScrambleManager) NewSalt() ([]byte, error) {
+	salt := make([]byte, ScrambleLength)
+	if _, err := io.ReadFull(m.rand, salt); err != nil {
+		return nil, errors.Trace(err)
+	}
+	for i, b := range salt {
+		b = '!' + b%('~'-'!'+1)
+		if b == '$' {
+			b++
+		}
+		salt[i] = b
+	}
+	return salt, nil
+}
+
+// ScrambleNativePassword returns the mysql_native_password response of a
+// client to salt for password, nil for an empty password:
+// SHA1(password) XOR SHA1(salt, SHA1(SHA1(password))).
+func ScrambleNativePassword(password string, salt []byte) []byte {
+	if len(password) == 0 {
+		return nil
+	}
+	stage1 := Sha1Hash([]byte(password))
+	stage2 := Sha1Hash(stage1)
+	crypt := sha1.New()
+	crypt.Write(salt)
+	crypt.Write(stage2)
+	scramble := crypt.Sum(nil)
+	for i := range scramble {
+		scramble[i] ^= stage1[i]
+	}
+	return scramble
+}
+
+// VerifyNativePassword reports whether response is the mysql_native_password
+// response to salt for the password stored as EncodePassword encodes it.
+// An account without password accepts only an empty response.
+func VerifyNativePassword(stored string, salt, response []byte) bool {
+	if stored == "" {
+		return len(response) == 0
+	}
+	if len(response) != sha1.Size {
+		return false
+	}
+	hpwd, err := DecodePassword(stored)
+	if err != nil || len(hpwd) != sha1.Size {
+		return false
+	}
+	return CheckScrambledPassword(salt, hpwd, response)
+}
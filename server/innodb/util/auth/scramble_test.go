@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestScramble(t *testing.T) {
+	m := NewScrambleManager(nil)
+	salt, err := m.NewSalt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(salt) != ScrambleLength {
+		t.Fatalf("expect a salt of %d bytes, got %d", ScrambleLength, len(salt))
+	}
+	for _, b := range salt {
+		if b < '!' || b > '~' || b == '$' {
+			t.Fatalf("unexpected byte %q in salt %q", b, salt)
+		}
+	}
+	other, err := m.NewSalt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(salt, other) {
+		t.Fatalf("expect two salts to differ, got %q twice", salt)
+	}
+
+	stored := EncodePassword("secret")
+	response := ScrambleNativePassword("secret", salt)
+	if !VerifyNativePassword(stored, salt, response) {
+		t.Fatal("expect the response to the salt to verify")
+	}
+	if VerifyNativePassword(stored, other, response) {
+		t.Error("expect the response to another salt not to verify")
+	}
+	if VerifyNativePassword(stored, salt, ScrambleNativePassword("wrong", salt)) {
+		t.Error("expect a wrong password not to verify")
+	}
+	if VerifyNativePassword(stored, salt, nil) || VerifyNativePassword(stored, salt, response[:10]) {
+		t.Error("expect a short response not to verify")
+	}
+	if !VerifyNativePassword("", salt, nil) || VerifyNativePassword("", salt, response) {
+		t.Error("expect an account without password to accept only an empty response")
+	}
+}
@@ -381,6 +381,17 @@ var collations = []*Collation{
 	{247, "utf8mb4", "utf8mb4_vietnamese_ci", false},
 }
 
+// GetMaxBytesPerChar returns the maximum number of bytes one character of
+// charset cs can take, e.g. 4 for utf8mb4 and 3 for utf8. Unknown charsets
+// are treated as single-byte.
+func GetMaxBytesPerChar(cs string) int {
+	c, ok := charsets[strings.ToLower(cs)]
+	if !ok {
+		return 1
+	}
+	return c.Maxlen
+}
+
 // GetCharsetDesc gets charset descriptions in the local charsets.
 func GetCharsetDesc(cs string) (*Desc, error) {
 	c, ok := charsets[strings.ToLower(cs)]
@@ -0,0 +1,35 @@
+package charset
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// Encode returns s, a UTF-8 string, in the charset cs, with ? for the
+// characters cs can't represent. The UTF-8 charsets, binary and the
+// charsets unknown return s as it is.
+func Encode(cs string, s string) []byte {
+	var encodeRune func(r rune) (byte, bool)
+	switch strings.ToLower(cs) {
+	case "latin1":
+		// The latin1 of MySQL is cp1252.
+		encodeRune = charmap.Windows1252.EncodeRune
+	case "ascii":
+		encodeRune = func(r rune) (byte, bool) {
+			return byte(r), r < utf8.RuneSelf
+		}
+	default:
+		return []byte(s)
+	}
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		c, ok := encodeRune(r)
+		if !ok {
+			c = '?'
+		}
+		b = append(b, c)
+	}
+	return b
+}
@@ -62,10 +62,13 @@ func encode(b []byte, vals []basic.Datum, comparable bool) ([]byte, error) {
 		case basic.KindMysqlDecimal:
 			b = append(b, decimalFlag)
 			b = EncodeDecimal(b, val)
-		//case basic.KindMysqlHex:
-		//	b = encodeSignedInt(b, int64(val.GetMysqlHex().ToNumber()), comparable)
-		//case basic.KindMysqlBit:
-		//	b = encodeUnsignedInt(b, uint64(val.GetMysqlBit().ToNumber()), comparable)
+		case basic.KindBinaryLiteral, basic.KindMysqlBit:
+			// A BIT value fits in 64 bits, convertToMysqlBit checked it.
+			v, err := val.GetBinaryLiteral().ToInt()
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			b = encodeUnsignedInt(b, v, comparable)
 		case basic.KindMysqlEnum:
 			b = encodeUnsignedInt(b, uint64(val.GetMysqlEnum().ToNumber()), comparable)
 		case basic.KindMysqlSet:
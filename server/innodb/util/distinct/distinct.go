@@ -17,31 +17,120 @@ import (
 	"github.com/juju/errors"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
 	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/codec"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/memory"
 )
 
+// DefaultMemQuota is the number of bytes the keys of a checker keep in
+// memory before they are spilled to disk.
+const DefaultMemQuota = 64 << 20
+
+// keyOverhead is the memory a key takes in the map besides its bytes.
+const keyOverhead = 48
+
 // CreateDistinctChecker creates a new distinct checker.
 func CreateDistinctChecker() *Checker {
+	return NewChecker(DefaultMemQuota, "")
+}
+
+// NewChecker creates a distinct checker keeping memQuota bytes of keys in
+// memory, and the others in files of dir, the default directory for
+// temporary files when it is empty.
+func NewChecker(memQuota int64, dir string) *Checker {
 	return &Checker{
 		existingKeys: make(map[string]bool),
+		memQuota:     memQuota,
+		dir:          dir,
 	}
 }
 
-// Checker stores existing keys and checks if given data is distinct.
+// Checker stores existing keys and checks if given data is distinct. When
+// the keys in memory take more than its quota, or than its memory tracker
+// allows, they are moved to a sorted run on disk, which later checks search
+// too.
 type Checker struct {
 	existingKeys map[string]bool
+	memQuota     int64
+	memUsage     int64
+	memTracker   *memory.Tracker
+	dir          string
+	runs         []*run
+	buf          []byte
+}
+
+// SetMemTracker makes t track the memory the keys of d take.
+func (d *Checker) SetMemTracker(t *memory.Tracker) {
+	d.memTracker = t
 }
 
 // Check checks if values is distinct.
 func (d *Checker) Check(values []interface{}) (bool, error) {
-	bs, err := codec.EncodeValue([]byte{}, basic.MakeDatums(values...)...)
+	return d.CheckDatums(basic.MakeDatums(values...))
+}
+
+// CheckDatums checks if values is distinct.
+func (d *Checker) CheckDatums(values []basic.Datum) (bool, error) {
+	var err error
+	d.buf, err = codec.EncodeValue(d.buf[:0], values...)
 	if err != nil {
 		return false, errors.Trace(err)
 	}
-	key := string(bs)
-	_, ok := d.existingKeys[key]
-	if ok {
+	if d.existingKeys[string(d.buf)] {
 		return false, nil
 	}
-	d.existingKeys[key] = true
+	for _, r := range d.runs {
+		found, err := r.has(d.buf)
+		if err != nil {
+			return false, errors.Trace(err)
+		}
+		if found {
+			return false, nil
+		}
+	}
+	d.existingKeys[string(d.buf)] = true
+	size := int64(len(d.buf)) + keyOverhead
+	if err = d.memTracker.Consume(size); err == nil {
+		d.memUsage += size
+	}
+	if err != nil || d.memUsage > d.memQuota {
+		if err = d.spill(); err != nil {
+			return false, errors.Trace(err)
+		}
+	}
 	return true, nil
 }
+
+// Spilled returns the number of runs of keys the checker moved to disk.
+func (d *Checker) Spilled() int {
+	return len(d.runs)
+}
+
+// Close releases the files of the keys spilled.
+func (d *Checker) Close() error {
+	var firstErr error
+	for _, r := range d.runs {
+		if err := r.close(); err != nil && firstErr == nil {
+			firstErr = errors.Trace(err)
+		}
+	}
+	d.runs = nil
+	d.memTracker.Release(d.memUsage)
+	d.memUsage = 0
+	return firstErr
+}
+
+// spill moves the keys in memory to a new run.
+func (d *Checker) spill() error {
+	keys := make([]string, 0, len(d.existingKeys))
+	for key := range d.existingKeys {
+		keys = append(keys, key)
+	}
+	r, err := writeRun(d.dir, keys)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	d.runs = append(d.runs, r)
+	d.existingKeys = make(map[string]bool)
+	d.memTracker.Release(d.memUsage)
+	d.memUsage = 0
+	return nil
+}
@@ -0,0 +1,60 @@
+package distinct
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/memory"
+)
+
+func TestChecker(t *testing.T) {
+	// Every key spills the ones before it.
+	for _, quota := range []int64{DefaultMemQuota, 1, 100 * keyOverhead} {
+		d := NewChecker(quota, "")
+		for round := 0; round < 2; round++ {
+			for i := 0; i < 1000; i++ {
+				values := []interface{}{i % 500, fmt.Sprintf("k%d", i%500)}
+				ok, err := d.Check(values)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want := round == 0 && i < 500; ok != want {
+					t.Fatalf("quota %d: expect %v distinct to be %v, got %v", quota, values, want, ok)
+				}
+			}
+		}
+		if spilled := d.Spilled() > 0; spilled != (quota < DefaultMemQuota) {
+			t.Errorf("quota %d: unexpected %d runs", quota, d.Spilled())
+		}
+		if err := d.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCheckerMemTracker(t *testing.T) {
+	// The tracker limit spills the keys before the quota of the checker.
+	tracker := memory.NewTracker(memory.LabelStatement, 100*keyOverhead)
+	d := CreateDistinctChecker()
+	d.SetMemTracker(tracker)
+	for i := 0; i < 1000; i++ {
+		if ok, err := d.Check([]interface{}{i}); err != nil || !ok {
+			t.Fatalf("expect %d distinct, got %v, %v", i, ok, err)
+		}
+		if tracker.BytesConsumed() > tracker.BytesLimit() {
+			t.Fatalf("expect at most %d bytes tracked, got %d", tracker.BytesLimit(), tracker.BytesConsumed())
+		}
+	}
+	if ok, err := d.Check([]interface{}{10}); err != nil || ok {
+		t.Fatalf("expect 10 found after spilling, got %v, %v", ok, err)
+	}
+	if d.Spilled() == 0 {
+		t.Error("expect the keys spilled")
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if tracker.BytesConsumed() != 0 {
+		t.Errorf("expect the memory released, got %d bytes", tracker.BytesConsumed())
+	}
+}
@@ -0,0 +1,101 @@
+package distinct
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/juju/errors"
+)
+
+// runBlockKeys is the number of keys of a run between two entries of its
+// index.
+const runBlockKeys = 128
+
+// run is a file of sorted keys, each one prefixed by its length as a
+// uvarint. The first key of every block of runBlockKeys keys is kept in
+// memory to find the block a key would be in.
+type run struct {
+	file *os.File
+	size int64
+	// firstKeys and offsets are the first key and the offset of each block.
+	firstKeys [][]byte
+	offsets   []int64
+}
+
+// writeRun writes keys sorted in a new file of dir. The file is removed at
+// once, so that it is gone when the run is dropped without being closed.
+func writeRun(dir string, keys []string) (*run, error) {
+	sort.Strings(keys)
+	f, err := ioutil.TempFile(dir, "distinct")
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	// An open file can't be removed on some systems, it is then removed by
+	// close.
+	os.Remove(f.Name())
+	r := &run{file: f}
+	w := bufio.NewWriter(f)
+	var lenBuf [binary.MaxVarintLen64]byte
+	for i, key := range keys {
+		if i%runBlockKeys == 0 {
+			r.firstKeys = append(r.firstKeys, []byte(key))
+			r.offsets = append(r.offsets, r.size)
+		}
+		n := binary.PutUvarint(lenBuf[:], uint64(len(key)))
+		if _, err = w.Write(lenBuf[:n]); err == nil {
+			_, err = w.WriteString(key)
+		}
+		if err != nil {
+			r.close()
+			return nil, errors.Trace(err)
+		}
+		r.size += int64(n + len(key))
+	}
+	if err = w.Flush(); err != nil {
+		r.close()
+		return nil, errors.Trace(err)
+	}
+	return r, nil
+}
+
+// has reports whether key is in the run.
+func (r *run) has(key []byte) (bool, error) {
+	// The block of key is the last one starting at a key not after it.
+	i := sort.Search(len(r.firstKeys), func(i int) bool {
+		return bytes.Compare(r.firstKeys[i], key) > 0
+	}) - 1
+	if i < 0 {
+		return false, nil
+	}
+	end := r.size
+	if i+1 < len(r.offsets) {
+		end = r.offsets[i+1]
+	}
+	block := make([]byte, end-r.offsets[i])
+	if _, err := r.file.ReadAt(block, r.offsets[i]); err != nil {
+		return false, errors.Trace(err)
+	}
+	for len(block) > 0 {
+		l, n := binary.Uvarint(block)
+		if n <= 0 || uint64(len(block)-n) < l {
+			return false, errors.New("corrupted distinct run")
+		}
+		cmp := bytes.Compare(block[n:n+int(l)], key)
+		if cmp >= 0 {
+			return cmp == 0, nil
+		}
+		block = block[n+int(l):]
+	}
+	return false, nil
+}
+
+func (r *run) close() error {
+	name := r.file.Name()
+	err := r.file.Close()
+	os.Remove(name)
+	return errors.Trace(err)
+}
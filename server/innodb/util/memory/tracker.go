@@ -0,0 +1,187 @@
+// Package memory accounts the memory the statements take.
+//
+// The trackers make a tree: the tracker of a statement is the child of the
+// tracker of its session, which is the child of GlobalTracker. Memory
+// consumed by a tracker is consumed by its ancestors too, and is refused
+// when it takes one of them above its limit.
+package memory
+
+import (
+	"sync/atomic"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/terror"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+// Labels of the trackers, which name them in the errors.
+const (
+	LabelServer    = "server"
+	LabelSession   = "session"
+	LabelStatement = "statement"
+)
+
+// ErrMemoryExceeded is returned when consuming memory takes a tracker above
+// its limit.
+var ErrMemoryExceeded = terror.ClassGlobal.New(codeMemoryExceeded, mysql.MySQLErrName[mysql.ErrMemoryExceeded])
+
+const codeMemoryExceeded terror.ErrCode = terror.ErrCode(mysql.ErrMemoryExceeded)
+
+func init() {
+	terror.ErrClassToMySQLCodes[terror.ClassGlobal] = map[terror.ErrCode]uint16{
+		codeMemoryExceeded: mysql.ErrMemoryExceeded,
+	}
+}
+
+// GlobalTracker is the tracker of the whole server, the root of the trackers.
+var GlobalTracker = NewTracker(LabelServer, 0)
+
+// Tracker tracks the memory consumed by a part of the server. The methods of
+// a nil Tracker do nothing, so that code run outside of a statement needs no
+// tracker.
+type Tracker struct {
+	label string
+	// bytesLimit is the most bytes the tracker may consume, 0 for no limit.
+	bytesLimit    int64
+	bytesConsumed int64
+	maxConsumed   int64
+	// countAlloc and bytesAlloc count the memory consumed, countFree and
+	// bytesFree the memory released.
+	countAlloc int64
+	bytesAlloc int64
+	countFree  int64
+	bytesFree  int64
+	parent     *Tracker
+}
+
+// NewTracker creates a tracker of no parent consuming at most bytesLimit
+// bytes, 0 for no limit.
+func NewTracker(label string, bytesLimit int64) *Tracker {
+	return &Tracker{label: label, bytesLimit: bytesLimit}
+}
+
+// Label returns the label of t.
+func (t *Tracker) Label() string {
+	return t.label
+}
+
+// AttachTo makes parent the parent of t. The memory t consumed before is not
+// consumed by parent.
+func (t *Tracker) AttachTo(parent *Tracker) {
+	if t != nil {
+		t.parent = parent
+	}
+}
+
+// Detach releases the memory t consumes from its ancestors and cuts t from
+// them.
+func (t *Tracker) Detach() {
+	if t == nil || t.parent == nil {
+		return
+	}
+	t.parent.Release(atomic.LoadInt64(&t.bytesConsumed))
+	t.parent = nil
+}
+
+// SetBytesLimit sets the most bytes t may consume, 0 for no limit. The
+// memory already consumed stays consumed.
+func (t *Tracker) SetBytesLimit(bytesLimit int64) {
+	if t != nil {
+		atomic.StoreInt64(&t.bytesLimit, bytesLimit)
+	}
+}
+
+// BytesLimit returns the most bytes t may consume, 0 for no limit.
+func (t *Tracker) BytesLimit() int64 {
+	if t == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&t.bytesLimit)
+}
+
+// Consume consumes bytes by t and its ancestors. When that takes one of them
+// above its limit, nothing is consumed and ErrMemoryExceeded names the
+// tracker.
+func (t *Tracker) Consume(bytes int64) error {
+	if bytes <= 0 {
+		t.Release(-bytes)
+		return nil
+	}
+	for tracker := t; tracker != nil; tracker = tracker.parent {
+		consumed := atomic.AddInt64(&tracker.bytesConsumed, bytes)
+		if limit := atomic.LoadInt64(&tracker.bytesLimit); limit > 0 && consumed > limit {
+			for undo := t; undo != tracker.parent; undo = undo.parent {
+				atomic.AddInt64(&undo.bytesConsumed, -bytes)
+			}
+			return ErrMemoryExceeded.GenByArgs(tracker.label, limit)
+		}
+	}
+	for tracker := t; tracker != nil; tracker = tracker.parent {
+		atomic.AddInt64(&tracker.countAlloc, 1)
+		atomic.AddInt64(&tracker.bytesAlloc, bytes)
+		tracker.updateMax(atomic.LoadInt64(&tracker.bytesConsumed))
+	}
+	return nil
+}
+
+// updateMax raises the most bytes t consumed at once to consumed.
+func (t *Tracker) updateMax(consumed int64) {
+	for {
+		max := atomic.LoadInt64(&t.maxConsumed)
+		if consumed <= max || atomic.CompareAndSwapInt64(&t.maxConsumed, max, consumed) {
+			return
+		}
+	}
+}
+
+// Release releases bytes consumed by t and its ancestors.
+func (t *Tracker) Release(bytes int64) {
+	if bytes <= 0 {
+		return
+	}
+	for tracker := t; tracker != nil; tracker = tracker.parent {
+		atomic.AddInt64(&tracker.bytesConsumed, -bytes)
+		atomic.AddInt64(&tracker.countFree, 1)
+		atomic.AddInt64(&tracker.bytesFree, bytes)
+	}
+}
+
+// BytesConsumed returns the bytes t consumes.
+func (t *Tracker) BytesConsumed() int64 {
+	if t == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&t.bytesConsumed)
+}
+
+// MaxConsumed returns the most bytes t consumed at once.
+func (t *Tracker) MaxConsumed() int64 {
+	if t == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&t.maxConsumed)
+}
+
+// Summary is what a tracker consumed, as performance_schema shows it.
+type Summary struct {
+	CountAlloc    int64
+	CountFree     int64
+	BytesAlloc    int64
+	BytesFree     int64
+	BytesConsumed int64
+	MaxConsumed   int64
+}
+
+// Summary returns what t consumed.
+func (t *Tracker) Summary() Summary {
+	if t == nil {
+		return Summary{}
+	}
+	return Summary{
+		CountAlloc:    atomic.LoadInt64(&t.countAlloc),
+		CountFree:     atomic.LoadInt64(&t.countFree),
+		BytesAlloc:    atomic.LoadInt64(&t.bytesAlloc),
+		BytesFree:     atomic.LoadInt64(&t.bytesFree),
+		BytesConsumed: atomic.LoadInt64(&t.bytesConsumed),
+		MaxConsumed:   atomic.LoadInt64(&t.maxConsumed),
+	}
+}
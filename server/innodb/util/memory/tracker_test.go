@@ -0,0 +1,61 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/terror"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+func TestTracker(t *testing.T) {
+	server := NewTracker(LabelServer, 1000)
+	session := NewTracker(LabelSession, 600)
+	session.AttachTo(server)
+	stmt := NewTracker(LabelStatement, 0)
+	stmt.AttachTo(session)
+	other := NewTracker(LabelSession, 0)
+	other.AttachTo(server)
+
+	if err := stmt.Consume(500); err != nil {
+		t.Fatal(err)
+	}
+	// Above the limit of the session nothing is consumed.
+	err := stmt.Consume(200)
+	if !ErrMemoryExceeded.Equal(err) {
+		t.Fatalf("expect memory exceeded, got %v", err)
+	}
+	sqlErr := err.(*terror.Error).ToSQLError()
+	if sqlErr.Code != mysql.ErrMemoryExceeded || sqlErr.Message != "Query execution was interrupted, session memory limit of 600 bytes exceeded" {
+		t.Errorf("unexpected error %v", sqlErr)
+	}
+	for _, tracker := range []*Tracker{stmt, session, server} {
+		if got := tracker.BytesConsumed(); got != 500 {
+			t.Errorf("%s: expect 500 bytes consumed, got %d", tracker.Label(), got)
+		}
+	}
+	// The server limit is shared by the sessions.
+	if err := other.Consume(600); !ErrMemoryExceeded.Equal(err) {
+		t.Fatalf("expect memory exceeded, got %v", err)
+	}
+	if err := other.Consume(400); err != nil {
+		t.Fatal(err)
+	}
+
+	stmt.Release(100)
+	stmt.Detach()
+	if got := session.BytesConsumed(); got != 0 {
+		t.Errorf("expect the statement memory released, got %d", got)
+	}
+	if got := session.MaxConsumed(); got != 500 {
+		t.Errorf("expect peak 500, got %d", got)
+	}
+	if got := server.Summary(); got != (Summary{CountAlloc: 2, CountFree: 2, BytesAlloc: 900, BytesFree: 500, BytesConsumed: 400, MaxConsumed: 900}) {
+		t.Errorf("unexpected summary %+v", got)
+	}
+
+	// A nil tracker tracks nothing.
+	var none *Tracker
+	if err := none.Consume(1 << 40); err != nil || none.BytesConsumed() != 0 {
+		t.Errorf("expect nothing tracked, got %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+// Package mock is the context the tests build and evaluate expressions in,
+// without a session or a storage behind it.
+package mock
+
+import (
+	"fmt"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/context"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/sessionctx/variable"
+	goctx "golang.org/x/net/context"
+)
+
+var _ context.Context = (*Context)(nil)
+
+// Context represents mocked context.Context.
+type Context struct {
+	values      map[fmt.Stringer]interface{}
+	sessionVars *variable.SessionVars
+}
+
+// NewContext creates a new mocked context.Context with the default session
+// variables, the statement time zone set to the session one.
+func NewContext() *Context {
+	vars := variable.NewSessionVars()
+	vars.StmtCtx.TimeZone = vars.GetTimeZone()
+	return &Context{
+		values:      make(map[fmt.Stringer]interface{}),
+		sessionVars: vars,
+	}
+}
+
+// SetValue implements context.Context SetValue interface.
+func (c *Context) SetValue(key fmt.Stringer, value interface{}) {
+	c.values[key] = value
+}
+
+// Value implements context.Context Value interface.
+func (c *Context) Value(key fmt.Stringer) interface{} {
+	return c.values[key]
+}
+
+// ClearValue implements context.Context ClearValue interface.
+func (c *Context) ClearValue(key fmt.Stringer) {
+	delete(c.values, key)
+}
+
+// GetSessionVars implements the context.Context GetSessionVars interface.
+func (c *Context) GetSessionVars() *variable.SessionVars {
+	return c.sessionVars
+}
+
+// NewTxn implements the context.Context interface, there is no transaction.
+func (c *Context) NewTxn() error {
+	return nil
+}
+
+// Txn implements the context.Context Txn interface, there is no transaction.
+func (c *Context) Txn() basic.XMySQLTransaction {
+	return nil
+}
+
+// GoCtx implements the context.Context GoCtx interface.
+func (c *Context) GoCtx() goctx.Context {
+	return nil
+}
@@ -121,8 +121,19 @@ func refineRangeDatum(v *types.Datum, ic *model.IndexColumn) {
 
 // getEQFunctionOffset judge if the expression is a eq function like A = 1 where a is an index.
 // If so, it will return the offset of A in index columns. e.g. for index(C,B,A), A's offset is 2.
+// A IS NULL is a point too: the NULLs are together at the start of the index.
 func getEQFunctionOffset(expr expression.Expression, cols []*model.IndexColumn) int {
 	f, ok := expr.(*expression.ScalarFunction)
+	if ok && f.FuncName.L == ast.IsNull {
+		if c, ok := f.GetArgs()[0].(*expression.Column); ok {
+			for i, col := range cols {
+				if col.Name.L == c.ColName.L {
+					return i
+				}
+			}
+		}
+		return -1
+	}
 	if !ok || f.FuncName.L != ast.EQ {
 		return -1
 	}
@@ -53,6 +53,18 @@ const (
 	ServerStatusMetadataChanged    uint16 = 0x0400
 	ServerStatusWasSlow            uint16 = 0x0800
 	ServerPSOutParams              uint16 = 0x1000
+	ServerSessionStateChanged      uint16 = 0x4000
+)
+
+// Session state trackers, the types of the changes listed in the session
+// state information of an OK packet.
+const (
+	SessionTrackSystemVariables byte = iota
+	SessionTrackSchema
+	SessionTrackStateChange
+	SessionTrackGtids
+	SessionTrackTransactionCharacteristics
+	SessionTrackTransactionState
 )
 
 // Identifier length limitations.
@@ -212,6 +224,10 @@ const (
 	ExecutePriv
 	// IndexPriv is the privilege to create/drop index.
 	IndexPriv
+	// FilePriv is the privilege to read and write files on the server host.
+	FilePriv
+	// CreateViewPriv is the privilege to create a view.
+	CreateViewPriv
 	// AllPriv is the privilege for all actions.
 	AllPriv
 )
@@ -253,6 +269,9 @@ const (
 // MaxTypeSetMembers is the number of set members.
 const MaxTypeSetMembers = 64
 
+// MaxBitDisplayWidth is the largest width of a BIT column.
+const MaxBitDisplayWidth = 64
+
 // PWDHashLen is the length of password's hash.
 const PWDHashLen = 40
 
@@ -274,6 +293,8 @@ var Priv2UserCol = map[PrivilegeType]string{
 	AlterPriv:      "Alter_priv",
 	ExecutePriv:    "Execute_priv",
 	IndexPriv:      "Index_priv",
+	FilePriv:       "File_priv",
+	CreateViewPriv: "Create_view_priv",
 }
 
 // Col2PrivType is the privilege tables column name to privilege type.
@@ -294,10 +315,12 @@ var Col2PrivType = map[string]PrivilegeType{
 	"Alter_priv":       AlterPriv,
 	"Execute_priv":     ExecutePriv,
 	"Index_priv":       IndexPriv,
+	"File_priv":        FilePriv,
+	"Create_view_priv": CreateViewPriv,
 }
 
 // AllGlobalPrivs is all the privileges in global scope.
-var AllGlobalPrivs = []PrivilegeType{SelectPriv, InsertPriv, UpdatePriv, DeletePriv, CreatePriv, DropPriv, ProcessPriv, GrantPriv, ReferencesPriv, AlterPriv, ShowDBPriv, SuperPriv, ExecutePriv, IndexPriv, CreateUserPriv, TriggerPriv}
+var AllGlobalPrivs = []PrivilegeType{SelectPriv, InsertPriv, UpdatePriv, DeletePriv, CreatePriv, DropPriv, ProcessPriv, GrantPriv, ReferencesPriv, AlterPriv, ShowDBPriv, SuperPriv, ExecutePriv, IndexPriv, CreateUserPriv, TriggerPriv, FilePriv, CreateViewPriv}
 
 // Priv2Str is the map for privilege to string.
 var Priv2Str = map[PrivilegeType]string{
@@ -317,6 +340,8 @@ var Priv2Str = map[PrivilegeType]string{
 	AlterPriv:      "Alter",
 	ExecutePriv:    "Execute",
 	IndexPriv:      "Index",
+	FilePriv:       "File",
+	CreateViewPriv: "Create View",
 }
 
 // Priv2SetStr is the map for privilege to string.
@@ -414,6 +439,16 @@ func (m SQLMode) HasErrorForDivisionByZeroMode() bool {
 	return m&ModeErrorForDivisionByZero == ModeErrorForDivisionByZero
 }
 
+// HasNoUnsignedSubtractionMode detects if 'NO_UNSIGNED_SUBTRACTION' mode is set in SQLMode
+func (m SQLMode) HasNoUnsignedSubtractionMode() bool {
+	return m&ModeNoUnsignedSubtraction == ModeNoUnsignedSubtraction
+}
+
+// HasNoBackslashEscapesMode detects if 'NO_BACKSLASH_ESCAPES' mode is set in SQLMode
+func (m SQLMode) HasNoBackslashEscapesMode() bool {
+	return m&ModeNoBackslashEscapes == ModeNoBackslashEscapes
+}
+
 // HasStrictMode detects if 'STRICT_TRANS_TABLES' or 'STRICT_ALL_TABLES' mode is set in SQLMode
 func (m SQLMode) HasStrictMode() bool {
 	return m&ModeStrictTransTables == ModeStrictTransTables || m&ModeStrictAllTables == ModeStrictAllTables
@@ -883,6 +883,8 @@ const (
 	ErrRowInWrongPartition                                          = 1863
 	ErrErrorLast                                                    = 1863
 
+	ErrFkDepthExceeded              = 3008
+	ErrQueryTimeout                 = 3024
 	ErrBadGeneratedColumn           = 3105
 	ErrUnsupportedOnGeneratedColumn = 3106
 	ErrGeneratedColumnNonPrior      = 3107
@@ -891,4 +893,9 @@ const (
 	ErrInvalidJSONPath              = 3143
 	ErrInvalidJSONData              = 3146
 	ErrJSONUsedAsKey                = 3152
+	ErrVarDoesNotExist              = 3615
+
+	// ErrMemoryExceeded is raised by the server itself, MySQL has no such
+	// error.
+	ErrMemoryExceeded = 8001
 )
@@ -275,7 +275,7 @@ var MySQLErrName = map[uint16]string{
 	ErrZlibZMem:                                 "ZLIB: Not enough memory",
 	ErrZlibZBuf:                                 "ZLIB: Not enough room in the output buffer (probably, length of uncompressed data was corrupted)",
 	ErrZlibZData:                                "ZLIB: Input data corrupted",
-	ErrCutValueGroupConcat:                      "Row %u was cut by GROUPCONCAT()",
+	ErrCutValueGroupConcat:                      "Row %d was cut by GROUP_CONCAT()",
 	ErrWarnTooFewRecords:                        "Row %ld doesn't contain data for all columns",
 	ErrWarnTooManyRecords:                       "Row %ld was truncated; it contained more data than there were input columns",
 	ErrWarnNullToNotnull:                        "Column set to default value; NULL supplied to NOT NULL column '%s' at row %ld",
@@ -879,4 +879,8 @@ var MySQLErrName = map[uint16]string{
 	ErrAlterOperationNotSupportedReasonNotNull:               "cannot silently convert NULL values, as required in this SQLMODE",
 	ErrMustChangePasswordLogin:                               "Your password has expired. To log in you must change it using a client that supports expired passwords.",
 	ErrRowInWrongPartition:                                   "Found a row in wrong partition %s",
+	ErrFkDepthExceeded:                                       "Foreign key cascade delete/update exceeds max depth of %d.",
+	ErrQueryTimeout:                                          "Query execution was interrupted, maximum statement execution time exceeded",
+	ErrVarDoesNotExist:                                       "Variable %s does not exist in persisted config file",
+	ErrMemoryExceeded:                                        "Query execution was interrupted, %s memory limit of %d bytes exceeded",
 }
@@ -24,6 +24,7 @@ var MySQLState = map[uint16]string{
 	ErrDupKey:                              "23000",
 	ErrOutofMemory:                         "HY001",
 	ErrOutOfSortMemory:                     "HY001",
+	ErrMemoryExceeded:                      "HY001",
 	ErrConCount:                            "08004",
 	ErrBadHost:                             "08S01",
 	ErrHandshake:                           "08S01",
@@ -97,6 +97,27 @@ func IsIntegerType(tp byte) bool {
 	return false
 }
 
+// GetEnumSetStorageSize returns the bytes a value of an ENUM or SET with
+// the given number of members takes in a row: the 1-based ordinal of the
+// member, or a bit per member.
+// See https://dev.mysql.com/doc/refman/5.7/en/storage-requirements.html
+func GetEnumSetStorageSize(tp byte, members int) int {
+	if tp == TypeEnum {
+		if members > 255 {
+			return 2
+		}
+		return 1
+	}
+	switch size := (members + 7) / 8; {
+	case size == 0:
+		return 1
+	case size > 4:
+		return 8
+	default:
+		return size
+	}
+}
+
 // GetDefaultFieldLengthAndDecimal returns the default display length (flen) and decimal length for column.
 // Call this when no Flen assigned in ddl.
 // or column value is calculated from an expression.
@@ -1,7 +1,6 @@
 package protocol
 
 import (
-	"fmt"
 	"github.com/zhukovaskychina/xmysql-server/server/common"
 	"github.com/zhukovaskychina/xmysql-server/util"
 )
@@ -35,6 +34,9 @@ func GetCapabilitiesWithoutParams() uint32 {
 	capabilities |= common.CLIENT_IGNORE_SIGPIPE
 	capabilities |= common.CLIENT_TRANSACTIONS
 	capabilities |= common.CLIENT_SECURE_CONNECTION
+	capabilities |= common.CLIENT_SESSION_TRACK
+	capabilities |= common.CLIENT_DEPRECATE_EOF
+	capabilities |= common.CLIENT_PLUGIN_AUTH
 	//capabilities |=common.CLIENT_SSL
 	return capabilities
 }
@@ -83,58 +85,86 @@ type AuthPacket struct {
 	User          string
 	Password      []byte
 	Database      string
+	AuthPlugin    string
 }
 
-func (ap *AuthPacket) DecodeAuth(buff []byte) *AuthPacket {
-
-	//解析packetLength
-
-	var cursor = 0
-	var database = ""
-	cursor, packetLength := util.ReadUB3(buff, cursor)
-
-	if packetLength <= 32 {
-		cursor, packetId := util.ReadByte(buff, cursor)
-		fmt.Println(packetId)
-		cursor, maxPacketSize := util.ReadUB4(buff, cursor)
-
-		cursor, charsetIndex := util.ReadByte(buff, cursor)
-		fmt.Println(maxPacketSize, charsetIndex)
-		cursor, user := util.ReadStringWithNull(buff, cursor)
+// ClientFlag returns the capabilities of the client.
+func (ap *AuthPacket) ClientFlag() uint32 {
+	return uint32(ap.clientFlag)
+}
 
-		cursor, password := util.ReadBytesWithNull(buff, cursor-1)
-		cursor, database = util.ReadStringWithNull(buff, cursor-1)
-		fmt.Println(user)
-		fmt.Println(password)
-		fmt.Println(database)
-		return ap
+// DecodeAuth decodes payload, the handshake response of the client without
+// the packet header. It returns ErrMalformedPacket when a field runs past
+// the end of payload.
+func (ap *AuthPacket) DecodeAuth(payload []byte) error {
+	r := NewReader(payload)
+	clientFlag, err := r.ReadUint32()
+	if err != nil {
+		return err
 	}
-	cursor = 4
-
-	cursor, clientFlag := util.ReadUB4(buff, cursor)
-
-	cursor, maxPacketSize := util.ReadUB4(buff, cursor)
-
-	cursor, charsetIndex := util.ReadByte(buff, cursor)
-	var currentCursor = cursor
-	cursor, length := util.ReadLength(buff, cursor)
-	if currentCursor > 0 && length < 23 {
-		//获取extra
+	maxPacketSize, err := r.ReadUint32()
+	if err != nil {
+		return err
 	}
-	cursor = cursor + 22
-	cursor, user := util.ReadStringWithNull(buff, cursor)
-	cursor, password := util.ReadBytesWithNull(buff, cursor)
-
-	if (len(buff) > cursor) && (int32(clientFlag)&int32(common.CLIENT_CONNECT_WITH_DB)) != 0 {
-		_, database = util.ReadStringWithNull(buff, cursor)
+	charsetIndex, err := r.ReadByte()
+	if err != nil {
+		return err
+	}
+	// 23 个字节的保留位
+	if err = r.Skip(23); err != nil {
+		return err
+	}
+	user, err := r.ReadNullTerminated()
+	if err != nil {
+		return err
+	}
+	var password []byte
+	switch {
+	case clientFlag&common.CLIENT_PLUGIN_AUTH_LENENC_CLIENT_DATA != 0:
+		password, err = r.ReadLengthEncodedBytes()
+	case clientFlag&common.CLIENT_SECURE_CONNECTION != 0:
+		// The auth response is prefixed with its length.
+		var length byte
+		if length, err = r.ReadByte(); err == nil {
+			password, err = r.ReadBytes(int(length))
+		}
+	default:
+		password, err = r.ReadNullTerminated()
+	}
+	if err != nil {
+		return err
+	}
+	var database, plugin []byte
+	if r.Len() > 0 && clientFlag&common.CLIENT_CONNECT_WITH_DB != 0 {
+		if database, err = r.ReadNullTerminated(); err != nil {
+			return err
+		}
+	}
+	if r.Len() > 0 && clientFlag&common.CLIENT_PLUGIN_AUTH != 0 {
+		// Some clients leave out the 0 ending the name of the plugin.
+		if plugin, err = r.ReadNullTerminated(); err != nil {
+			plugin = r.ReadRest()
+		}
 	}
 
 	ap.clientFlag = int(clientFlag)
 	ap.CharsetIndex = int(charsetIndex)
 	ap.maxPacketSize = int(int32(maxPacketSize))
-	ap.User = user
-	ap.Password = password
-	ap.Database = database
+	ap.User = string(user)
+	ap.Password = append([]byte(nil), password...)
+	ap.Database = string(database)
+	ap.AuthPlugin = string(plugin)
+	return nil
+}
 
-	return ap
+// EncodeAuthSwitchRequest appends the AuthSwitchRequest asking the client to
+// authenticate again with plugin, answering salt.
+func EncodeAuthSwitchRequest(buff []byte, plugin string, salt []byte) []byte {
+	size := 1 + len(plugin) + 1 + len(salt) + 1
+	buff = util.WriteUB3(buff, uint32(size))
+	buff = util.WriteByte(buff, 0)
+	buff = util.WriteByte(buff, 0xfe)
+	buff = util.WriteWithNull(buff, []byte(plugin))
+	buff = util.WriteWithNull(buff, salt)
+	return buff
 }
@@ -0,0 +1,103 @@
+package protocol
+
+import (
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/common"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+	"github.com/zhukovaskychina/xmysql-server/util"
+)
+
+func testLogin() []byte {
+	hs := DecodeHandshake(EncodeHandshake(nil, []byte("01234567890123456789"))[4:])
+	return EncodeLogin(hs, "app", "secret", "test")
+}
+
+func TestDecodeAuth(t *testing.T) {
+	login := testLogin()
+	a := new(AuthPacket)
+	if err := a.DecodeAuth(login); err != nil {
+		t.Fatal(err)
+	}
+	if a.User != "app" || a.Database != "test" || len(a.Password) != 20 {
+		t.Fatalf("unexpected auth packet %+v", a)
+	}
+
+	// Every truncation of the packet before the database is malformed.
+	for n := 0; n < len(login)-len("test\x00"+mysql.AuthName+"\x00"); n++ {
+		if err := new(AuthPacket).DecodeAuth(login[:n]); err != ErrMalformedPacket {
+			t.Errorf("expect %d bytes to be malformed, got %v", n, err)
+		}
+	}
+
+	// A length of the auth response past the end of the packet.
+	buf := util.WriteUB4(nil, common.CLIENT_PROTOCOL_41|common.CLIENT_SECURE_CONNECTION)
+	buf = append(buf, make([]byte, 4+1+23)...)
+	buf = append(buf, "app\x00"...)
+	buf = append(buf, 20, 1, 2)
+	if err := new(AuthPacket).DecodeAuth(buf); err != ErrMalformedPacket {
+		t.Fatalf("expect the auth response to be malformed, got %v", err)
+	}
+	// A length encoded auth response of 2^64-1 bytes.
+	buf = util.WriteUB4(nil, common.CLIENT_PROTOCOL_41|common.CLIENT_PLUGIN_AUTH_LENENC_CLIENT_DATA)
+	buf = append(buf, make([]byte, 4+1+23)...)
+	buf = append(buf, "app\x00"...)
+	buf = append(buf, 0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff)
+	if err := new(AuthPacket).DecodeAuth(buf); err != ErrMalformedPacket {
+		t.Fatalf("expect the auth response to be malformed, got %v", err)
+	}
+}
+
+func TestDecodeCommand(t *testing.T) {
+	cmd, arg, err := DecodeCommand(EncodeQuery("SELECT 1")[:9])
+	if err != nil || cmd != 0x03 || string(arg) != "SELECT 1" {
+		t.Fatalf("unexpected command %d %q %v", cmd, arg, err)
+	}
+	if _, _, err = DecodeCommand(nil); err != ErrMalformedPacket {
+		t.Fatalf("expect an empty packet to be malformed, got %v", err)
+	}
+}
+
+func TestReader(t *testing.T) {
+	r := NewReader([]byte{0xfc, 0x01, 0x01, 'a', 0, 0xfd, 1})
+	if n, isNull, err := r.ReadLengthEncodedInt(); n != 257 || isNull || err != nil {
+		t.Fatalf("unexpected length %d %v %v", n, isNull, err)
+	}
+	if s, err := r.ReadNullTerminated(); string(s) != "a" || err != nil {
+		t.Fatalf("unexpected string %q %v", s, err)
+	}
+	if _, _, err := r.ReadLengthEncodedInt(); err != ErrMalformedPacket {
+		t.Fatalf("expect a truncated length to be malformed, got %v", err)
+	}
+	if _, err := NewReader([]byte("abc")).ReadNullTerminated(); err != ErrMalformedPacket {
+		t.Fatalf("expect a string without 0 to be malformed, got %v", err)
+	}
+	if _, err := NewReader([]byte("abc")).ReadBytes(-1); err != ErrMalformedPacket {
+		t.Fatalf("expect a negative length to be malformed, got %v", err)
+	}
+}
+
+func FuzzDecodeAuth(f *testing.F) {
+	f.Add(testLogin())
+	f.Add([]byte{})
+	f.Fuzz(func(t *testing.T, payload []byte) {
+		a := new(AuthPacket)
+		if err := a.DecodeAuth(payload); err != nil && err != ErrMalformedPacket {
+			t.Fatalf("unexpected error %v", err)
+		}
+	})
+}
+
+func FuzzDecodeCommand(f *testing.F) {
+	f.Add(EncodeQuery("SELECT 1"))
+	f.Add([]byte{})
+	f.Fuzz(func(t *testing.T, payload []byte) {
+		cmd, arg, err := DecodeCommand(payload)
+		if err != nil {
+			return
+		}
+		if cmd != payload[0] || len(arg) != len(payload)-1 {
+			t.Fatalf("unexpected command %d %q of %q", cmd, arg, payload)
+		}
+	})
+}
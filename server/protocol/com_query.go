@@ -9,3 +9,15 @@ func EncodeQuery(sql string) []byte {
 	buff = append(buff, 0)
 	return buff
 }
+
+// DecodeCommand splits payload, a command packet without its header, into
+// the command byte and its argument. It returns ErrMalformedPacket for an
+// empty payload.
+func DecodeCommand(payload []byte) (byte, []byte, error) {
+	r := NewReader(payload)
+	cmd, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	return cmd, r.ReadRest(), nil
+}
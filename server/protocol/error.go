@@ -22,14 +22,20 @@ type ErrorPacket struct {
 	mark       byte
 }
 
+// NewErrorPacket returns the ERR packet of err: its error code, SQLSTATE
+// and message. An error without a SQLSTATE is sent as HY000.
 func NewErrorPacket(err *mysql.SQLError) ErrorPacket {
+	sqlState := []byte(err.State)
+	if len(sqlState) != len(DefaultSqlstate) {
+		sqlState = DefaultSqlstate
+	}
 	return ErrorPacket{
 		MySQLPacket: nil,
 		message:     []byte(err.Message),
 		errorNo:     err.Code,
-		sqlState:    nil,
-		fieldCount:  0,
-		mark:        0,
+		sqlState:    sqlState,
+		fieldCount:  FieldCount,
+		mark:        SqlstateMarker,
 	}
 }
 
@@ -55,7 +61,7 @@ func (ep *ErrorPacket) EncodeErrorPacket(errorMsg string) []byte {
 	buff := make([]byte, 0)
 	buff = util.WriteUB3(buff, uint32(ep.CalculateErrorPacketSize()))
 	buff = util.WriteByte(buff, 0)
-	buff = util.WriteByte(buff, fieldCount)
+	buff = util.WriteByte(buff, ep.fieldCount)
 	buff = util.WriteUB2(buff, uint16(ep.errorNo))
 	buff = util.WriteByte(buff, ep.mark)
 	buff = util.WriteBytes(buff, ep.sqlState)
@@ -68,7 +74,7 @@ func (ep *ErrorPacket) EncodeErrorPackets() []byte {
 	buff := make([]byte, 0)
 	buff = util.WriteUB3(buff, uint32(ep.CalculateErrorPacketSize()))
 	buff = util.WriteByte(buff, 0)
-	buff = util.WriteByte(buff, fieldCount)
+	buff = util.WriteByte(buff, ep.fieldCount)
 	buff = util.WriteUB2(buff, uint16(ep.errorNo))
 	buff = util.WriteByte(buff, ep.mark)
 	buff = util.WriteBytes(buff, ep.sqlState)
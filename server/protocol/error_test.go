@@ -0,0 +1,43 @@
+package protocol
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+func TestErrorPacket(t *testing.T) {
+	for _, tt := range []struct {
+		err   *mysql.SQLError
+		code  uint16
+		state string
+	}{
+		{mysql.NewErr(mysql.ErrDupEntry, "1", "PRIMARY"), mysql.ErrDupEntry, "23000"},
+		{mysql.NewErr(mysql.ErrNoSuchTable, "test", "t"), mysql.ErrNoSuchTable, "42S02"},
+		{mysql.NewErr(mysql.ErrLockDeadlock), mysql.ErrLockDeadlock, "40001"},
+		{mysql.NewErrf(mysql.ErrUnknown, "%s", "boom"), mysql.ErrUnknown, "HY000"},
+		{&mysql.SQLError{Code: mysql.ErrUnknown, Message: "no state"}, mysql.ErrUnknown, "HY000"},
+	} {
+		packet := NewErrorPacket(tt.err)
+		buff := packet.EncodeErrorPackets()
+		// The header, then 0xff, the code, '#', the SQLSTATE and the message.
+		payload := buff[4:]
+		length := int(buff[0]) | int(buff[1])<<8 | int(buff[2])<<16
+		if length != len(payload) {
+			t.Fatalf("%v: expect a payload of %d bytes, got %d", tt.err, length, len(payload))
+		}
+		if payload[0] != 0xff {
+			t.Fatalf("%v: expect the ERR header 0xff, got %#x", tt.err, payload[0])
+		}
+		if code := uint16(payload[1]) | uint16(payload[2])<<8; code != tt.code {
+			t.Fatalf("%v: expect code %d, got %d", tt.err, tt.code, code)
+		}
+		if payload[3] != '#' || string(payload[4:9]) != tt.state {
+			t.Fatalf("%v: expect SQLSTATE %s, got %q", tt.err, tt.state, payload[3:9])
+		}
+		if !bytes.Equal(payload[9:], []byte(tt.err.Message)) {
+			t.Fatalf("%v: expect the message, got %q", tt.err, payload[9:])
+		}
+	}
+}
@@ -102,6 +102,20 @@ func (fp *FieldPacket) EncodeFieldPacket() []byte {
 	return buff
 }
 
+// GetColumnField returns the definition packet of the column field.
+func GetColumnField(field Field) *FieldPacket {
+	fieldPacket := GetField(field.Name, field.Types)
+	fieldPacket.DBName = []byte(field.Schema)
+	fieldPacket.TableName = []byte(field.Table)
+	fieldPacket.OrgTableName = []byte(field.OrgTable)
+	fieldPacket.OrgName = []byte(field.OrgName)
+	fieldPacket.flags = field.Flags
+	if field.Charset != 0 {
+		fieldPacket.CharsetIndex = field.Charset
+	}
+	return fieldPacket
+}
+
 func GetField(name string, fieldType int) *FieldPacket {
 
 	fieldPacket := new(FieldPacket)
@@ -2,6 +2,8 @@ package protocol
 
 import (
 	"fmt"
+
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
 	"github.com/zhukovaskychina/xmysql-server/util"
 )
 
@@ -28,12 +30,13 @@ type HandsharkProtocol struct {
 
 func CalHandShakePacketSize() int {
 	size := 1
-	size += len(ServerVersion)
-	size += 5
-	size += 20
-	size += 19
-	size += 12
-	size += 1
+	size += len(ServerVersion) + 1
+	size += 4
+	size += 8 + 1
+	size += 2 + 1 + 2
+	size += 13
+	size += 12 + 1
+	size += len(mysql.AuthName) + 1
 	return size
 }
 
@@ -53,20 +56,22 @@ func DecodeHandshake(buff []byte) HandsharkProtocol {
 	cursor, hs.ServerCapabilitiesHeight = util.ReadUB2(buff, cursor)
 	cursor, _ = util.ReadBytes(buff, cursor, 11)
 	cursor, hs.RestOfScrambleBuff = util.ReadWithNull(buff, cursor)
-	cursor, tmp = util.ReadWithNull(buff, cursor)
-	hs.Auth_plugin_name = string(tmp)
+	if cursor < len(buff) {
+		cursor, tmp = util.ReadWithNull(buff, cursor)
+		hs.Auth_plugin_name = string(tmp)
+	}
 
 	fmt.Printf("DecodeHanshark: %+v\n", hs)
 
 	return *hs
 }
 
-func EncodeHandshake(buff []byte) []byte {
+// EncodeHandshake appends the handshake carrying salt, its first 8 bytes
+// in auth-plugin-data-part-1 and the 12 others in auth-plugin-data-part-2.
+func EncodeHandshake(buff []byte, salt []byte) []byte {
 	ServerCapablities := GetCapabilitiesWithoutParams()
-	Filler13 := []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
-	//rand1
-	rand1 := util.RandomBytes(8)
-	rand2 := util.RandomBytes(12)
+	// The length of auth-plugin-data, then 10 reserved bytes.
+	Filler11 := []byte{byte(len(salt) + 1), 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
 
 	size := CalHandShakePacketSize()
 	buff = util.WriteUB3(buff, uint32(size))
@@ -74,12 +79,14 @@ func EncodeHandshake(buff []byte) []byte {
 	buff = util.WriteByte(buff, ProtocolVersion)
 	buff = util.WriteWithNull(buff, ([]byte)(ServerVersion))
 	buff = util.WriteUB4(buff, uint32(util.Goid()))
-	buff = util.WriteWithNull(buff, append(rand1, rand2...))
+	buff = util.WriteWithNull(buff, salt[:8])
 	buff = util.WriteUB2(buff, uint16(ServerCapablities))
 	buff = util.WriteByte(buff, CharSet)
 	buff = util.WriteUB2(buff, ServerStatus)
-	buff = util.WriteBytes(buff, Filler13)
-	buff = util.WriteWithNull(buff, rand2)
+	buff = util.WriteUB2(buff, uint16(ServerCapablities>>16))
+	buff = util.WriteBytes(buff, Filler11)
+	buff = util.WriteWithNull(buff, salt[8:])
+	buff = util.WriteWithNull(buff, []byte(mysql.AuthName))
 
 	return buff
 }
@@ -0,0 +1,30 @@
+package protocol
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/util/auth"
+	"github.com/zhukovaskychina/xmysql-server/util"
+)
+
+func TestHandshakeSalt(t *testing.T) {
+	salt, err := auth.NewScrambleManager(nil).NewSalt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	buff := EncodeHandshake(nil, salt)
+	if _, size := util.ReadUB3(buff, 0); int(size) != len(buff)-4 || int(size) != CalHandShakePacketSize() {
+		t.Fatalf("expect a payload of %d bytes, header says %d, got %d", CalHandShakePacketSize(), size, len(buff)-4)
+	}
+	hs := DecodeHandshake(buff[4:])
+	if got := append(append([]byte{}, hs.Seed...), hs.RestOfScrambleBuff...); !bytes.Equal(got, salt) {
+		t.Fatalf("expect the salt %q in the handshake, got %q", salt, got)
+	}
+
+	// The response of a client to the handshake verifies against the salt.
+	response := util.GetPassword([]byte("secret"), hs.Seed, hs.RestOfScrambleBuff)
+	if !auth.VerifyNativePassword(auth.EncodePassword("secret"), salt, response) {
+		t.Fatal("expect the client response to verify")
+	}
+}
@@ -1,6 +1,9 @@
 package protocol
 
-import "github.com/zhukovaskychina/xmysql-server/util"
+import (
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+	"github.com/zhukovaskychina/xmysql-server/util"
+)
 
 type OK struct {
 	MySQLPacket
@@ -23,28 +26,61 @@ func DecodeOk(buff []byte) OK {
 	return *ok
 }
 
-func EncodeOK(buff []byte, affectedRows int64, insertId int64, message []byte) []byte {
-	buff = util.WriteUB3(buff, uint32(CalOKPacketSize(affectedRows, insertId, message)))
+// EncodeOK encodes an OK packet with the SERVER_STATUS flags status.
+func EncodeOK(buff []byte, affectedRows int64, insertId int64, status uint16, message []byte) []byte {
+	return EncodeOKWithSessionState(buff, affectedRows, insertId, status, message, nil)
+}
+
+// EncodeOKWithSessionState encodes an OK packet for a client tracking the
+// state of its session. When sessionState, the changes made by
+// AppendSessionStateChange, isn't empty, it follows the message and
+// SERVER_SESSION_STATE_CHANGED is set.
+func EncodeOKWithSessionState(buff []byte, affectedRows int64, insertId int64, status uint16, message []byte, sessionState []byte) []byte {
+	if len(sessionState) > 0 {
+		status |= mysql.ServerSessionStateChanged
+	}
+	buff = util.WriteUB3(buff, uint32(calOKPacketSize(affectedRows, insertId, message, sessionState)))
 	buff = util.WriteByte(buff, 0)
 	buff = util.WriteByte(buff, 0x00)
 	buff = util.WriteLength(buff, affectedRows)
 	buff = util.WriteLength(buff, insertId)
-	buff = util.WriteUB2(buff, 2)
+	buff = util.WriteUB2(buff, status)
 	buff = util.WriteUB2(buff, 0)
-	if len(message) > 0 {
+	if len(message) > 0 || len(sessionState) > 0 {
 		buff = util.WriteWithLength(buff, message)
 	}
+	if len(sessionState) > 0 {
+		buff = util.WriteWithLength(buff, sessionState)
+	}
 	return buff
 }
 
+// AppendSessionStateChange appends to buff a change of the session state
+// information of an OK packet, of the type tracker and with the data values.
+func AppendSessionStateChange(buff []byte, tracker byte, values ...string) []byte {
+	var data []byte
+	for _, value := range values {
+		data = util.WriteWithLength(data, []byte(value))
+	}
+	buff = util.WriteByte(buff, tracker)
+	return util.WriteWithLength(buff, data)
+}
+
 func CalOKPacketSize(affectedRows int64, insertId int64, message []byte) int {
+	return calOKPacketSize(affectedRows, insertId, message, nil)
+}
+
+func calOKPacketSize(affectedRows int64, insertId int64, message []byte, sessionState []byte) int {
 	var i = 1
 
 	i += util.GetLength(affectedRows)
 	i += util.GetLength(insertId)
 	i += 4
-	if len(message) > 0 {
+	if len(message) > 0 || len(sessionState) > 0 {
 		i += util.GetLengthBytes(message)
 	}
+	if len(sessionState) > 0 {
+		i += util.GetLengthBytes(sessionState)
+	}
 	return i
 }
@@ -1,4 +1,93 @@
 package protocol
 
+import (
+	"errors"
+
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+	"github.com/zhukovaskychina/xmysql-server/util"
+)
+
+// MaxPayloadLen is the largest payload one packet can carry. Larger payloads
+// are split into packets of this size, the last one being shorter.
+const MaxPayloadLen = 1<<24 - 1
+
+// ErrIncompletePacket is returned by ReadPacket when the buffer doesn't hold
+// all the packets of a payload yet.
+var ErrIncompletePacket = errors.New("incomplete packet")
+
 type MySQLPacket interface {
 }
+
+// AddPacketHeader appends payload to buff as packets numbered from seq, and
+// returns the sequence id of the next packet. A payload of a multiple of
+// MaxPayloadLen bytes is ended with an empty packet, so the client knows
+// there is nothing left.
+func AddPacketHeader(buff []byte, payload []byte, seq byte) ([]byte, byte) {
+	for {
+		n := len(payload)
+		if n > MaxPayloadLen {
+			n = MaxPayloadLen
+		}
+		buff = util.WriteUB3(buff, uint32(n))
+		buff = util.WriteByte(buff, seq)
+		buff = util.WriteBytes(buff, payload[:n])
+		payload = payload[n:]
+		seq++
+		if n < MaxPayloadLen {
+			return buff, seq
+		}
+	}
+}
+
+// SetPacketSequence numbers the packets encoded in buff from seq, whatever
+// ids they were encoded with, and returns the sequence id of the packet
+// following them. The ids wrap around after 255.
+func SetPacketSequence(buff []byte, seq byte) byte {
+	cursor := 0
+	for len(buff)-cursor >= 4 {
+		var length uint32
+		cursor, length = util.ReadUB3(buff, cursor)
+		buff[cursor] = seq
+		seq++
+		cursor += 1 + int(length)
+	}
+	return seq
+}
+
+// ReadPacket reads the payload at the head of buff, joining the packets it
+// was split into. It returns the payload, the sequence id of its last packet
+// and the number of bytes read. A payload larger than maxAllowedPacket is
+// rejected with ErrNetPacketTooLarge as soon as its length is known; a
+// maxAllowedPacket of 0 means no limit.
+func ReadPacket(buff []byte, maxAllowedPacket int) ([]byte, byte, int, error) {
+	var (
+		payload []byte
+		seq     byte
+		cursor  int
+		length  uint32
+	)
+	for {
+		if len(buff)-cursor < 4 {
+			return nil, 0, 0, ErrIncompletePacket
+		}
+		cursor, length = util.ReadUB3(buff, cursor)
+		seq = buff[cursor]
+		cursor++
+		if maxAllowedPacket > 0 && len(payload)+int(length) > maxAllowedPacket {
+			return nil, seq, 0, mysql.NewErr(mysql.ErrNetPacketTooLarge)
+		}
+		if len(buff)-cursor < int(length) {
+			return nil, 0, 0, ErrIncompletePacket
+		}
+		if payload == nil && length < MaxPayloadLen {
+			// The common case of a single packet needs no copy.
+			payload = buff[cursor : cursor+int(length)]
+		} else {
+			payload = append(payload, buff[cursor:cursor+int(length)]...)
+		}
+		cursor += int(length)
+		if length < MaxPayloadLen {
+			return payload, seq, cursor, nil
+		}
+	}
+}
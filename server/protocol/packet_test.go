@@ -0,0 +1,163 @@
+package protocol
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+	"github.com/zhukovaskychina/xmysql-server/util"
+)
+
+func TestAddPacketHeader(t *testing.T) {
+	for _, tt := range []struct {
+		size    int
+		lengths []int
+	}{
+		{0, []int{0}},
+		{10, []int{10}},
+		{MaxPayloadLen - 1, []int{MaxPayloadLen - 1}},
+		{MaxPayloadLen, []int{MaxPayloadLen, 0}},
+		{MaxPayloadLen + 10, []int{MaxPayloadLen, 10}},
+		{2 * MaxPayloadLen, []int{MaxPayloadLen, MaxPayloadLen, 0}},
+	} {
+		payload := bytes.Repeat([]byte{'x'}, tt.size)
+		buff, next := AddPacketHeader(nil, payload, 3)
+		if int(next) != 3+len(tt.lengths) {
+			t.Fatalf("size %d: expect next sequence id %d, got %d", tt.size, 3+len(tt.lengths), next)
+		}
+		cursor := 0
+		for i, l := range tt.lengths {
+			var length uint32
+			cursor, length = util.ReadUB3(buff, cursor)
+			if int(length) != l || int(buff[cursor]) != 3+i {
+				t.Fatalf("size %d: packet %d has length %d and id %d", tt.size, i, length, buff[cursor])
+			}
+			cursor += 1 + l
+		}
+		if cursor != len(buff) {
+			t.Fatalf("size %d: %d bytes left", tt.size, len(buff)-cursor)
+		}
+
+		got, seq, n, err := ReadPacket(buff, 0)
+		if err != nil || n != len(buff) || int(seq) != 2+len(tt.lengths) || !bytes.Equal(got, payload) {
+			t.Fatalf("size %d: read back %d bytes of %d with id %d, err %v", tt.size, len(got), n, seq, err)
+		}
+	}
+}
+
+func TestReadPacket(t *testing.T) {
+	buff, _ := AddPacketHeader(nil, bytes.Repeat([]byte{'x'}, MaxPayloadLen+10), 0)
+	if _, _, _, err := ReadPacket(buff[:len(buff)-1], 0); err != ErrIncompletePacket {
+		t.Fatalf("expect an incomplete packet, got %v", err)
+	}
+	if _, _, _, err := ReadPacket(buff[:MaxPayloadLen+4], 0); err != ErrIncompletePacket {
+		t.Fatalf("expect an incomplete packet, got %v", err)
+	}
+	if _, _, _, err := ReadPacket(buff, MaxPayloadLen+10); err != nil {
+		t.Fatal(err)
+	}
+	_, _, _, err := ReadPacket(buff, MaxPayloadLen+9)
+	if sqlErr, ok := err.(*mysql.SQLError); !ok || sqlErr.Code != mysql.ErrNetPacketTooLarge {
+		t.Fatalf("expect error %d, got %v", mysql.ErrNetPacketTooLarge, err)
+	}
+	// The limit applies as soon as the header is read.
+	_, _, _, err = ReadPacket(buff[:4], 100)
+	if sqlErr, ok := err.(*mysql.SQLError); !ok || sqlErr.Code != mysql.ErrNetPacketTooLarge {
+		t.Fatalf("expect error %d, got %v", mysql.ErrNetPacketTooLarge, err)
+	}
+}
+
+func TestWriteLargeRow(t *testing.T) {
+	sp := NewSelectResponse(2)
+	sp.AddField("a", int(mysql.TypeLongBlob))
+	sp.AddField("b", int(mysql.TypeVarString))
+	sp.EncodeFields()
+	sp.EncodeEof()
+	large := string(bytes.Repeat([]byte{'x'}, 17<<20))
+	buff := sp.WriteStringRows([]string{large, "y"})
+	if sp.PackId != 5 {
+		t.Fatalf("expect the row to end at packet 5, got %d", sp.PackId)
+	}
+	payload, seq, n, err := ReadPacket(buff, 0)
+	if err != nil || n != len(buff) || seq != 5 {
+		t.Fatalf("read %d bytes of %d with id %d, err %v", n, len(buff), seq, err)
+	}
+	cursor, length := util.ReadLength(payload, 0)
+	if int(length) != len(large) {
+		t.Fatalf("expect first value of %d bytes, got %d", len(large), length)
+	}
+	cursor += int(length)
+	if payload[cursor] != 1 || payload[cursor+1] != 'y' || cursor+2 != len(payload) {
+		t.Fatalf("unexpected second value %v", payload[cursor:])
+	}
+	if next := sp.WriteStringRows([]string{"a", "b"}); next[3] != 6 {
+		t.Fatalf("expect the next row to be packet 6, got %d", next[3])
+	}
+}
+
+// packetIDs returns the sequence ids of the packets encoded in buff.
+func packetIDs(buff []byte) []byte {
+	var ids []byte
+	for cursor := 0; cursor < len(buff); {
+		var length uint32
+		cursor, length = util.ReadUB3(buff, cursor)
+		ids = append(ids, buff[cursor])
+		cursor += 1 + int(length)
+	}
+	return ids
+}
+
+func TestSetPacketSequence(t *testing.T) {
+	// A result set with a row split into two packets, and enough rows for
+	// the ids to wrap around.
+	sp := NewSelectResponse(1)
+	sp.AddField("a", int(mysql.TypeLongBlob))
+	buff := sp.Header.EncodeBuff()
+	buff = append(buff, sp.EncodeFields()...)
+	buff = append(buff, sp.EncodeEof()...)
+	buff = append(buff, sp.WriteStringRows([]string{string(bytes.Repeat([]byte{'x'}, MaxPayloadLen))})...)
+	for i := 0; i < 300; i++ {
+		buff = append(buff, sp.WriteStringRows([]string{"y"})...)
+	}
+	buff = append(buff, sp.EncodeLastEof()...)
+
+	// The response to a command follows its packet 0.
+	next := SetPacketSequence(buff, 1)
+	ids := packetIDs(buff)
+	if len(ids) != 306 {
+		t.Fatalf("expect 306 packets, got %d", len(ids))
+	}
+	for i, id := range ids {
+		if id != byte(i+1) {
+			t.Fatalf("expect packet %d to have id %d, got %d", i, byte(i+1), id)
+		}
+	}
+	if next != byte(len(ids)+1) {
+		t.Fatalf("expect next id %d, got %d", byte(len(ids)+1), next)
+	}
+
+	// The next command starts again from 0, whatever the ids encoded.
+	ok := EncodeOK(nil, 1, 0, mysql.ServerStatusAutocommit, nil)
+	if next = SetPacketSequence(ok, 1); next != 2 || packetIDs(ok)[0] != 1 {
+		t.Fatalf("expect the OK packet to have id 1, got %d", packetIDs(ok)[0])
+	}
+}
+
+func TestEncodeOKWithSessionState(t *testing.T) {
+	state := AppendSessionStateChange(nil, mysql.SessionTrackSchema, "test")
+	got := EncodeOKWithSessionState(nil, 0, 0, mysql.ServerStatusAutocommit, nil, state)
+	want := []byte{
+		16, 0, 0, 0, // header
+		0, 0, 0, // OK, affected rows, insert id
+		0x02, 0x40, 0, 0, // status with SERVER_SESSION_STATE_CHANGED, warnings
+		0,                              // message
+		7, 1, 5, 4, 't', 'e', 's', 't', // the schema changed to test
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("expect %v, got %v", want, got)
+	}
+	// Without changes the packet ends after the warnings.
+	if got := EncodeOKWithSessionState(nil, 0, 0, 0, nil, nil); len(got) != 11 {
+		t.Fatalf("expect no session state, got %v", got)
+	}
+}
@@ -0,0 +1,152 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+/**
+带边界检查的报文读取
+
+客户端发来的报文不可信：长度前缀可能超过报文剩余的字节，字符串可能没有结尾的 0。
+Reader 读取前检查剩余的长度，越界时返回 ErrMalformedPacket 而不是 panic，
+解析失败的连接由调用方回复错误报文后关闭。
+**/
+
+// ErrMalformedPacket is returned when a field runs past the end of a packet.
+var ErrMalformedPacket = errors.New("malformed packet")
+
+// Reader reads the fields of a packet payload.
+type Reader struct {
+	buf []byte
+	pos int
+}
+
+// NewReader returns a Reader of payload.
+func NewReader(payload []byte) *Reader {
+	return &Reader{buf: payload}
+}
+
+// Len returns the number of bytes left.
+func (r *Reader) Len() int {
+	return len(r.buf) - r.pos
+}
+
+// ReadByte reads one byte.
+func (r *Reader) ReadByte() (byte, error) {
+	if r.Len() < 1 {
+		return 0, ErrMalformedPacket
+	}
+	b := r.buf[r.pos]
+	r.pos++
+	return b, nil
+}
+
+// ReadUint16 reads a little endian 2 bytes integer.
+func (r *Reader) ReadUint16() (uint16, error) {
+	b, err := r.ReadBytes(2)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint16(b), nil
+}
+
+// ReadUint24 reads a little endian 3 bytes integer.
+func (r *Reader) ReadUint24() (uint32, error) {
+	b, err := r.ReadBytes(3)
+	if err != nil {
+		return 0, err
+	}
+	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16, nil
+}
+
+// ReadUint32 reads a little endian 4 bytes integer.
+func (r *Reader) ReadUint32() (uint32, error) {
+	b, err := r.ReadBytes(4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(b), nil
+}
+
+// ReadUint64 reads a little endian 8 bytes integer.
+func (r *Reader) ReadUint64() (uint64, error) {
+	b, err := r.ReadBytes(8)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(b), nil
+}
+
+// Skip skips n bytes.
+func (r *Reader) Skip(n int) error {
+	_, err := r.ReadBytes(n)
+	return err
+}
+
+// ReadBytes reads n bytes. The result shares the memory of the payload.
+func (r *Reader) ReadBytes(n int) ([]byte, error) {
+	if n < 0 || n > r.Len() {
+		return nil, ErrMalformedPacket
+	}
+	b := r.buf[r.pos : r.pos+n : r.pos+n]
+	r.pos += n
+	return b, nil
+}
+
+// ReadNullTerminated reads the bytes up to a 0 and skips the 0.
+func (r *Reader) ReadNullTerminated() ([]byte, error) {
+	i := bytes.IndexByte(r.buf[r.pos:], 0)
+	if i < 0 {
+		return nil, ErrMalformedPacket
+	}
+	b := r.buf[r.pos : r.pos+i : r.pos+i]
+	r.pos += i + 1
+	return b, nil
+}
+
+// ReadLengthEncodedInt reads a length encoded integer. isNull reports the
+// NULL marker 0xfb.
+func (r *Reader) ReadLengthEncodedInt() (n uint64, isNull bool, err error) {
+	first, err := r.ReadByte()
+	if err != nil {
+		return 0, false, err
+	}
+	switch first {
+	case 0xfb:
+		return 0, true, nil
+	case 0xfc:
+		v, err := r.ReadUint16()
+		return uint64(v), false, err
+	case 0xfd:
+		v, err := r.ReadUint24()
+		return uint64(v), false, err
+	case 0xfe:
+		v, err := r.ReadUint64()
+		return v, false, err
+	case 0xff:
+		return 0, false, ErrMalformedPacket
+	}
+	return uint64(first), false, nil
+}
+
+// ReadLengthEncodedBytes reads bytes prefixed with their length encoded
+// integer.
+func (r *Reader) ReadLengthEncodedBytes() ([]byte, error) {
+	n, isNull, err := r.ReadLengthEncodedInt()
+	if err != nil || isNull {
+		return nil, err
+	}
+	if n > uint64(r.Len()) {
+		return nil, ErrMalformedPacket
+	}
+	return r.ReadBytes(int(n))
+}
+
+// ReadRest reads all the bytes left.
+func (r *Reader) ReadRest() []byte {
+	b := r.buf[r.pos:]
+	r.pos = len(r.buf)
+	return b
+}
@@ -37,31 +37,33 @@ func (rd *RowDataPacket) CalculateFieldPacketSize() int {
 
 	for e := rd.FieldValues.Front(); e != nil; e = e.Next() {
 		v := e.Value.([]byte)
-		if v == nil {
-			size = size + 1
-		} else if len(v) == 0 {
+		if len(v) == 0 {
 			size = size + 1
 		} else {
-			size = util.GetLengthBytes(v)
+			size += util.GetLengthBytes(v)
 		}
 	}
 	return size
 }
 
+// EncodeRowPacket encodes the row in packets numbered from PacketId, which
+// is left at the id of the last one: a row of more than MaxPayloadLen bytes
+// takes several packets.
 func (rd *RowDataPacket) EncodeRowPacket() []byte {
-	buff := make([]byte, 0)
-	buff = util.WriteUB3(buff, uint32(rd.CalculateFieldPacketSize()))
-	buff = util.WriteByte(buff, rd.PacketId)
+	payload := make([]byte, 0, rd.CalculateFieldPacketSize())
 	for e := rd.FieldValues.Front(); e != nil; e = e.Next() {
 		v := e.Value.([]byte)
 		if v == nil {
-			buff = util.WriteByte(buff, NULL_MARK)
+			payload = util.WriteByte(payload, NULL_MARK)
 		} else if len(v) == 0 {
-			buff = util.WriteByte(buff, NULL_MARK)
+			// 空字符串不是 NULL，长度为 0
+			payload = util.WriteLength(payload, 0)
 		} else {
-			buff = util.WriteLength(buff, int64(len(v)))
-			buff = util.WriteBytes(buff, v)
+			payload = util.WriteLength(payload, int64(len(v)))
+			payload = util.WriteBytes(payload, v)
 		}
 	}
+	buff, next := AddPacketHeader(nil, payload, rd.PacketId)
+	rd.PacketId = next - 1
 	return buff
 }
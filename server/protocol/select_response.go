@@ -1,8 +1,24 @@
 package protocol
 
+import (
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+	"github.com/zhukovaskychina/xmysql-server/util"
+)
+
+// Field is a column of a result set. Table is the name the table has in the
+// statement, OrgTable and OrgName the names of the table and the column the
+// value is read from, all empty for the columns computed.
 type Field struct {
-	Name  string
-	Types int
+	Schema   string
+	Table    string
+	OrgTable string
+	Name     string
+	OrgName  string
+	Types    int
+	Flags    int
+	// Charset is the collation id the column is sent in, 0 for the default.
+	// Binary columns have 63, their values go out as raw bytes.
+	Charset int
 }
 
 type SelectResponse struct {
@@ -34,6 +50,21 @@ func (sp *SelectResponse) AddField(fieldName string, fieldType int) {
 
 }
 
+// AddColumn adds a column with its table metadata to the result set.
+func (sp *SelectResponse) AddColumn(field Field) {
+	sp.Fields = append(sp.Fields, field)
+}
+
+// SetServerStatus sets the SERVER_STATUS flags of the EOF packets.
+func (sp *SelectResponse) SetServerStatus(status uint16) {
+	sp.EOFPacket.Status = int(status)
+}
+
+// SetWarningCount sets the warning count of the packet closing the rows.
+func (sp *SelectResponse) SetWarningCount(count uint16) {
+	sp.EOFPacket.WarningCount = int(count)
+}
+
 func (sp *SelectResponse) EncodeEof() []byte {
 	sp.PackId++
 	sp.EOFPacket.PacketId = sp.PackId
@@ -42,11 +73,28 @@ func (sp *SelectResponse) EncodeEof() []byte {
 
 func (sp *SelectResponse) EncodeLastEof() []byte {
 	eof := NewEOFPacket()
+	eof.Status = sp.EOFPacket.Status
+	eof.WarningCount = sp.EOFPacket.WarningCount
 	sp.PackId++
 	eof.PacketId = sp.PackId
 	return eof.WriteEOF()
 }
 
+// EncodeLastOK encodes the packet closing the rows for a client with
+// CLIENT_DEPRECATE_EOF: an OK packet with the 0xFE header of an EOF, shorter
+// than 9 bytes so that it can't be taken for a row.
+func (sp *SelectResponse) EncodeLastOK() []byte {
+	sp.PackId++
+	buff := util.WriteUB3(nil, 7)
+	buff = util.WriteByte(buff, sp.PackId)
+	buff = util.WriteByte(buff, mysql.EOFHeader)
+	buff = util.WriteLength(buff, 0)
+	buff = util.WriteLength(buff, 0)
+	buff = util.WriteUB2(buff, uint16(sp.EOFPacket.Status))
+	buff = util.WriteUB2(buff, uint16(sp.EOFPacket.WarningCount))
+	return buff
+}
+
 func (sp *SelectResponse) WriteStringRows(data []string) []byte {
 	row := NewRowDataPacket(sp.FieldCount)
 	i := 0
@@ -55,14 +103,29 @@ func (sp *SelectResponse) WriteStringRows(data []string) []byte {
 	}
 	sp.PackId++
 	row.PacketId = sp.PackId
-	return row.EncodeRowPacket()
+	buff := row.EncodeRowPacket()
+	sp.PackId = row.PacketId
+	return buff
+}
+
+// WriteRow encodes a row of the result set, a nil value being NULL.
+func (sp *SelectResponse) WriteRow(values [][]byte) []byte {
+	row := NewRowDataPacket(sp.FieldCount)
+	for _, value := range values {
+		row.Add(value)
+	}
+	sp.PackId++
+	row.PacketId = sp.PackId
+	buff := row.EncodeRowPacket()
+	sp.PackId = row.PacketId
+	return buff
 }
 
 func (sp *SelectResponse) EncodeFields() []byte {
 	buff := make([]byte, 0)
 	i := 0
 	for i = 0; i < len(sp.Fields); i++ {
-		packet := GetField(sp.Fields[i].Name, sp.Fields[i].Types)
+		packet := GetColumnField(sp.Fields[i])
 		sp.PackId++
 		packet.PacketId = sp.PackId
 		buff = append(buff, packet.EncodeFieldPacket()...)
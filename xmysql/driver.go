@@ -0,0 +1,205 @@
+package xmysql
+
+import (
+	goctx "context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"math"
+	"sync"
+
+	"github.com/juju/errors"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+)
+
+// DriverName is the name of the database/sql driver of the embedded server.
+const DriverName = "xmysql-embedded"
+
+var (
+	registerOnce sync.Once
+	driverMu     sync.RWMutex
+	driverDB     *DB
+)
+
+// RegisterDriver registers db as the database/sql driver DriverName:
+// sql.Open(DriverName, database) opens sessions of db whose current
+// database is database. Registering another DB replaces it for the
+// connections opened from then on.
+func RegisterDriver(db *DB) {
+	driverMu.Lock()
+	driverDB = db
+	driverMu.Unlock()
+	registerOnce.Do(func() {
+		sql.Register(DriverName, embeddedDriver{})
+	})
+}
+
+type embeddedDriver struct{}
+
+// Open implements driver.Driver, name is the current database.
+func (embeddedDriver) Open(name string) (driver.Conn, error) {
+	driverMu.RLock()
+	db := driverDB
+	driverMu.RUnlock()
+	if db == nil {
+		return nil, errors.New("xmysql: no embedded server registered")
+	}
+	c, err := db.Conn()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	c.SetCurrentDatabase(name)
+	return &driverConn{c}, nil
+}
+
+// driverConn is a database/sql connection on an embedded session. The
+// statements take no arguments.
+type driverConn struct {
+	*Conn
+}
+
+var (
+	_ driver.QueryerContext = (*driverConn)(nil)
+	_ driver.ExecerContext  = (*driverConn)(nil)
+)
+
+func (c *driverConn) Prepare(query string) (driver.Stmt, error) {
+	return &driverStmt{conn: c, query: query}, nil
+}
+
+func (c *driverConn) Begin() (driver.Tx, error) {
+	if _, _, err := c.run(nil, "BEGIN"); err != nil {
+		return nil, err
+	}
+	return &driverTx{c}, nil
+}
+
+func (c *driverConn) QueryContext(ctx goctx.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if len(args) > 0 {
+		return nil, errNoArgs
+	}
+	rs, err := c.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &driverRows{rs: rs}, nil
+}
+
+func (c *driverConn) ExecContext(ctx goctx.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if len(args) > 0 {
+		return nil, errNoArgs
+	}
+	result, err := c.Exec(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return driverResult(result), nil
+}
+
+var errNoArgs = errors.New("xmysql: statements with arguments are not supported")
+
+type driverStmt struct {
+	conn  *driverConn
+	query string
+}
+
+func (s *driverStmt) Close() error {
+	return nil
+}
+
+func (s *driverStmt) NumInput() int {
+	return -1
+}
+
+func (s *driverStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if len(args) > 0 {
+		return nil, errNoArgs
+	}
+	return s.conn.ExecContext(goctx.Background(), s.query, nil)
+}
+
+func (s *driverStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if len(args) > 0 {
+		return nil, errNoArgs
+	}
+	return s.conn.QueryContext(goctx.Background(), s.query, nil)
+}
+
+type driverTx struct {
+	conn *driverConn
+}
+
+// Commit and Rollback run through the engine, which applies or forgets the
+// rows the transaction changed.
+func (tx *driverTx) Commit() error {
+	_, _, err := tx.conn.run(nil, "COMMIT")
+	return err
+}
+
+func (tx *driverTx) Rollback() error {
+	_, _, err := tx.conn.run(nil, "ROLLBACK")
+	return err
+}
+
+type driverResult Result
+
+func (r driverResult) LastInsertId() (int64, error) {
+	return int64(r.LastInsertID), nil
+}
+
+func (r driverResult) RowsAffected() (int64, error) {
+	return int64(r.AffectedRows), nil
+}
+
+type driverRows struct {
+	rs *ResultSet
+}
+
+func (r *driverRows) Columns() []string {
+	names := make([]string, 0, len(r.rs.Fields))
+	for _, f := range r.rs.Fields {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+func (r *driverRows) Close() error {
+	r.rs.Rows = nil
+	return nil
+}
+
+func (r *driverRows) Next(dest []driver.Value) error {
+	if len(r.rs.Rows) == 0 {
+		return io.EOF
+	}
+	row := r.rs.Rows[0]
+	r.rs.Rows = r.rs.Rows[1:]
+	for i := range dest {
+		v, err := driverValue(row[i])
+		if err != nil {
+			return errors.Trace(err)
+		}
+		dest[i] = v
+	}
+	return nil
+}
+
+// driverValue returns d as a driver.Value: NULL as nil, the integers and
+// the floats as numbers and the other values as their text.
+func driverValue(d basic.Datum) (driver.Value, error) {
+	switch d.Kind() {
+	case basic.KindNull:
+		return nil, nil
+	case basic.KindInt64:
+		return d.GetInt64(), nil
+	case basic.KindUint64:
+		if u := d.GetUint64(); u <= math.MaxInt64 {
+			return int64(u), nil
+		}
+	case basic.KindFloat32, basic.KindFloat64:
+		return d.GetFloat64(), nil
+	case basic.KindBytes:
+		return d.GetBytes(), nil
+	}
+	return d.ToString()
+}
@@ -0,0 +1,282 @@
+package xmysql
+
+import (
+	goctx "context"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/zhukovaskychina/xmysql-server/initdb"
+	"github.com/zhukovaskychina/xmysql-server/server/conf"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/ast"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/basic"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/engine"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/innodb_store/txn"
+	"github.com/zhukovaskychina/xmysql-server/server/innodb/parser"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+	"github.com/zhukovaskychina/xmysql-server/server/protocol"
+)
+
+/**
+嵌入式接口
+
+测试和工具（元数据迁移、备份）不需要监听端口、通过 localhost 的 TCP 连接执行 SQL，
+可以直接使用进程内的引擎：
+
+	db, err := xmysql.OpenEmbedded(cfg)
+	rs, err := db.Query(ctx, "SELECT 1")
+
+每个 Conn 是一个独立的会话，和网络连接的会话一样有自己的当前数据库、会话变量和
+事务，语句经过同一个 ExecuteQuery 执行，结果不编码成报文，直接交给调用者。
+DB 的 Query 和 Exec 每次使用一个新的会话，多个 goroutine 可以同时调用；需要在
+多条语句之间保持 USE、SET 或事务时使用 DB.Conn。
+
+RegisterDriver 把 DB 注册为 database/sql 的驱动 xmysql-embedded，
+sql.Open("xmysql-embedded", "库名") 的每个连接是一个 Conn。
+**/
+
+// DB is the engine of an in-process server, without the network layer.
+type DB struct {
+	engine *engine.XMySQLEngine
+	// database is the current database of the new sessions.
+	database string
+}
+
+// OpenEmbedded starts an engine with cfg, whose data directory must have
+// been initialized by initdb.Initialize, and returns it as a DB.
+func OpenEmbedded(cfg *conf.Cfg) (*DB, error) {
+	if cfg == nil {
+		return nil, errors.New("xmysql: no configuration")
+	}
+	if err := initdb.CheckInitialized(cfg); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return NewEmbedded(engine.NewXMySQLEngine(cfg)), nil
+}
+
+// NewEmbedded returns a DB running SQL on e, an engine already started,
+// the one of a network server for instance.
+func NewEmbedded(e *engine.XMySQLEngine) *DB {
+	return &DB{engine: e}
+}
+
+// SetDatabase sets the current database of the sessions created from then
+// on.
+func (db *DB) SetDatabase(database string) {
+	db.database = database
+}
+
+// Close stops the engine.
+func (db *DB) Close() error {
+	return errors.Trace(db.engine.Close())
+}
+
+// Conn returns a new session.
+func (db *DB) Conn() (*Conn, error) {
+	s, err := engine.CreateSession(db.engine.InfoSchema())
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	c := &Conn{Session: s, db: db, parser: parser.New(), lastActiveTime: time.Now()}
+	vars := s.GetSessionVars()
+	vars.ConnectionID = engine.NextConnectionID()
+	vars.CurrentDB = db.database
+	return c, nil
+}
+
+// Query runs sql in a new session and returns its rows.
+func (db *DB) Query(ctx goctx.Context, sql string) (*ResultSet, error) {
+	c, err := db.Conn()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer c.Close()
+	return c.Query(ctx, sql)
+}
+
+// Exec runs sql in a new session.
+func (db *DB) Exec(ctx goctx.Context, sql string) (Result, error) {
+	c, err := db.Conn()
+	if err != nil {
+		return Result{}, errors.Trace(err)
+	}
+	defer c.Close()
+	return c.Exec(ctx, sql)
+}
+
+// ResultSet is the result of a statement returning rows, SELECT or SHOW.
+type ResultSet struct {
+	Fields []protocol.Field
+	Rows   [][]basic.Datum
+}
+
+// Result is the result of a statement.
+type Result struct {
+	AffectedRows uint64
+	LastInsertID uint64
+	Warnings     uint16
+}
+
+// Conn is an embedded session. Its statements run one at a time.
+type Conn struct {
+	engine.Session
+	db             *DB
+	parser         *parser.Parser
+	lastActiveTime time.Time
+	// txn is the open transaction, nil when there is none.
+	txn basic.XMySQLTransaction
+
+	mu sync.Mutex
+	// The response of the running statement.
+	rs  *ResultSet
+	err *mysql.SQLError
+}
+
+// Query runs sql and returns its rows, no rows for a statement which
+// doesn't return any.
+func (c *Conn) Query(ctx goctx.Context, sql string) (*ResultSet, error) {
+	rs, _, err := c.run(ctx, sql)
+	if err != nil {
+		return nil, err
+	}
+	if rs == nil {
+		rs = &ResultSet{}
+	}
+	return rs, nil
+}
+
+// Exec runs sql and returns the rows it affected.
+func (c *Conn) Exec(ctx goctx.Context, sql string) (Result, error) {
+	_, result, err := c.run(ctx, sql)
+	return result, err
+}
+
+// run runs sql through the engine as a network session's COM_QUERY, and
+// returns what it sent: the rows or the result. The error is a
+// *mysql.SQLError with the code and the SQLSTATE a client would get.
+func (c *Conn) run(ctx goctx.Context, sql string) (*ResultSet, Result, error) {
+	if ctx != nil {
+		if err := ctx.Err(); err != nil {
+			return nil, Result{}, err
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rs, c.err = nil, nil
+	c.lastActiveTime = time.Now()
+	c.db.engine.ExecuteQuery(c, sql)
+	if c.err != nil {
+		return nil, Result{}, c.err
+	}
+	var result Result
+	vars := c.GetSessionVars()
+	if sc := vars.StmtCtx; sc != nil {
+		result.AffectedRows = sc.AffectedRows()
+		result.Warnings = sc.WarningCount()
+	}
+	result.LastInsertID = vars.LastInsertID
+	return c.rs, result, nil
+}
+
+// Close rolls back the open transaction of the session.
+func (c *Conn) Close() error {
+	return c.RollbackTxn()
+}
+
+// GetLastActiveTime implements the MySQLServerSession interface.
+func (c *Conn) GetLastActiveTime() time.Time {
+	return c.lastActiveTime
+}
+
+// SendOK implements the MySQLServerSession interface.
+func (c *Conn) SendOK() {}
+
+// SendHandleOk implements the MySQLServerSession interface, an embedded
+// session has no handshake.
+func (c *Conn) SendHandleOk() {}
+
+// SendError implements the MySQLServerSession interface.
+func (c *Conn) SendError(err *mysql.SQLError) {
+	c.err = err
+}
+
+// SendResultSet implements the MySQLServerSession interface. The rows are
+// kept whole for the caller.
+func (c *Conn) SendResultSet(fields []protocol.Field, rows innodb.RowIterator) error {
+	rs := &ResultSet{Fields: fields}
+	for {
+		row, err := rows.Next()
+		if err != nil {
+			return err
+		}
+		if row == nil {
+			break
+		}
+		rs.Rows = append(rs.Rows, row)
+	}
+	c.rs = rs
+	return nil
+}
+
+// SetPacketSequence implements the MySQLServerSession interface, an
+// embedded session sends no packet.
+func (c *Conn) SetPacketSequence(seq byte) {}
+
+// GetCurrentDataBase implements the MySQLServerSession interface.
+func (c *Conn) GetCurrentDataBase() string {
+	return c.GetSessionVars().CurrentDB
+}
+
+// SetCurrentDatabase implements the MySQLServerSession interface.
+func (c *Conn) SetCurrentDatabase(name string) {
+	c.GetSessionVars().CurrentDB = name
+}
+
+// ParseSQL implements the MySQLServerSession interface.
+func (c *Conn) ParseSQL(sql, charset, collation string) ([]ast.StmtNode, error) {
+	return c.parser.Parse(sql, charset, collation)
+}
+
+// ParseOneSQL implements the MySQLServerSession interface.
+func (c *Conn) ParseOneSQL(sql, charset, collation string) (ast.StmtNode, error) {
+	return c.parser.ParseOneStmt(sql, charset, collation)
+}
+
+// PrepareTxnCtx implements the MySQLServerSession interface.
+func (c *Conn) PrepareTxnCtx() {}
+
+// Commit implements the MySQLServerSession interface: it ends the open
+// transaction, if any. Like the network sessions' it has nothing to write:
+// the statements don't change the tables yet.
+func (c *Conn) Commit() {
+	c.txn = nil
+	c.GetSessionVars().SetStatusFlag(mysql.ServerStatusInTrans, false)
+}
+
+// NewTxn commits the open transaction and starts a new one.
+func (c *Conn) NewTxn() error {
+	c.Commit()
+	c.txn = txn.NewTxn()
+	c.GetSessionVars().SetStatusFlag(mysql.ServerStatusInTrans, true)
+	return nil
+}
+
+// RollbackTxn implements the MySQLServerSession interface.
+func (c *Conn) RollbackTxn() error {
+	if c.txn == nil {
+		return nil
+	}
+	err := c.txn.Rollback()
+	c.txn = nil
+	c.GetSessionVars().SetStatusFlag(mysql.ServerStatusInTrans, false)
+	return errors.Trace(err)
+}
+
+// Txn implements the context.Context interface.
+func (c *Conn) Txn() basic.XMySQLTransaction {
+	if c.txn != nil {
+		return c.txn
+	}
+	return txn.NewTxn()
+}
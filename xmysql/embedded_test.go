@@ -0,0 +1,180 @@
+package xmysql
+
+import (
+	goctx "context"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/zhukovaskychina/xmysql-server/initdb"
+	"github.com/zhukovaskychina/xmysql-server/server/conf"
+	"github.com/zhukovaskychina/xmysql-server/server/mysql"
+)
+
+var testDB *DB
+
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "xmysql")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	cfg := conf.NewCfg()
+	cfg.DataDir, cfg.BaseDir = dir, dir
+	if _, err = initdb.Initialize(cfg, true); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if testDB, err = OpenEmbedded(cfg); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func TestQuery(t *testing.T) {
+	ctx := goctx.Background()
+	rs, err := testDB.Query(ctx, "SELECT 1 + 1 AS two, 'x'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs.Fields) != 2 || rs.Fields[0].Name != "two" || len(rs.Rows) != 1 || rs.Rows[0][0].GetInt64() != 2 {
+		t.Fatalf("unexpected result %+v", rs)
+	}
+
+	_, err = testDB.Query(ctx, "SELECT * FROM test.missing")
+	if e, ok := err.(*mysql.SQLError); !ok || e.Code != mysql.ErrNoSuchTable {
+		t.Fatalf("expect error %d, got %v", mysql.ErrNoSuchTable, err)
+	}
+	if _, err = testDB.Exec(ctx, "USE nope"); err.(*mysql.SQLError).Code != mysql.ErrBadDB {
+		t.Fatalf("expect error %d, got %v", mysql.ErrBadDB, err)
+	}
+
+	canceled, cancel := goctx.WithCancel(ctx)
+	cancel()
+	if _, err = testDB.Query(canceled, "SELECT 1"); err != goctx.Canceled {
+		t.Fatalf("expect the canceled context's error, got %v", err)
+	}
+}
+
+func TestConnSessions(t *testing.T) {
+	ctx := goctx.Background()
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c, err := testDB.Conn()
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer c.Close()
+			if _, err = c.Exec(ctx, fmt.Sprintf("SET @v = %d", i)); err != nil {
+				errs <- err
+				return
+			}
+			rs, err := c.Query(ctx, "SELECT @v")
+			if err != nil {
+				errs <- err
+				return
+			}
+			if got, _ := rs.Rows[0][0].ToString(); got != fmt.Sprint(i) {
+				errs <- fmt.Errorf("session %d sees @v = %s", i, got)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	// A new session doesn't see the variables of another.
+	rs, err := testDB.Query(ctx, "SELECT @v")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rs.Rows[0][0].IsNull() {
+		t.Fatalf("expect @v to be NULL in a new session, got %v", rs.Rows[0][0].GetValue())
+	}
+}
+
+func TestDriver(t *testing.T) {
+	RegisterDriver(testDB)
+	db, err := sql.Open(DriverName, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var two int
+	var name string
+	if err = db.QueryRow("SELECT 1 + 1, 'x'").Scan(&two, &name); err != nil {
+		t.Fatal(err)
+	}
+	if two != 2 || name != "x" {
+		t.Fatalf("expect 2 and x, got %d and %s", two, name)
+	}
+	if _, err = db.Exec("SET @v = 1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = db.Query("SELECT ?", 1); err == nil {
+		t.Fatal("expect the arguments to be refused")
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInitialize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xmysql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cfg := conf.NewCfg()
+	cfg.DataDir, cfg.BaseDir = dir, dir
+	if _, err = OpenEmbedded(cfg); err == nil {
+		t.Fatal("expect an uninitialized directory to be refused")
+	}
+	password, err := initdb.Initialize(cfg, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(password) != 16 {
+		t.Errorf("expect a random password of 16 characters, got %q", password)
+	}
+	for _, name := range []string{initdb.MarkerFile, "ibdata1", initdb.RedoDir, initdb.UndoDir, "mysql/db.opt", "mysql/user.ibd", "mysql/db.ibd"} {
+		if _, err = os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Error(err)
+		}
+	}
+	if err = initdb.CheckInitialized(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = initdb.Initialize(cfg, true); err == nil {
+		t.Fatal("expect an initialized directory to be refused")
+	}
+
+	// A directory whose initialization didn't finish is refused both ways.
+	if err = os.Remove(filepath.Join(dir, initdb.MarkerFile)); err != nil {
+		t.Fatal(err)
+	}
+	if err = initdb.CheckInitialized(cfg); err == nil {
+		t.Fatal("expect a directory without the marker to be refused")
+	}
+	if _, err = initdb.Initialize(cfg, true); err == nil {
+		t.Fatal("expect a directory with data to be refused")
+	}
+}